@@ -56,7 +56,7 @@ function ChangePasswordPage() {
     },
     // eslint-disable-next-line @typescript-eslint/no-explicit-any
     onError: (err: any) => {
-      const message = err.response?.data?.error || 'Failed to change password';
+      const message = err.response?.data?.error?.message || 'Failed to change password';
       toast.error(message);
     },
   });
@@ -48,7 +48,7 @@ function Login() {
         },
         // eslint-disable-next-line @typescript-eslint/no-explicit-any
         onError: (err: any) => {
-            const message = err.response?.data?.error || 'Invalid username or password';
+            const message = err.response?.data?.error?.message || 'Invalid username or password';
             setError(message);
             toast.error(message);
         },
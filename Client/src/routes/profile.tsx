@@ -69,7 +69,7 @@ function ProfilePage() {
     },
     // eslint-disable-next-line @typescript-eslint/no-explicit-any
     onError: (err: any) => {
-      const message = err.response?.data?.error || 'Failed to update profile';
+      const message = err.response?.data?.error?.message || 'Failed to update profile';
       toast.error(message);
     },
   });
@@ -258,7 +258,7 @@ function DeleteAccountDialog({
     },
     // eslint-disable-next-line @typescript-eslint/no-explicit-any
     onError: (err: any) => {
-      const message = err.response?.data?.error || 'Failed to delete account';
+      const message = err.response?.data?.error?.message || 'Failed to delete account';
       toast.error(message);
     },
   });
@@ -49,7 +49,7 @@ function Register() {
         },
         // eslint-disable-next-line @typescript-eslint/no-explicit-any
         onError: (err: any) => {
-            const message = err.response?.data?.error || 'Registration failed';
+            const message = err.response?.data?.error?.message || 'Registration failed';
             setError(message);
             toast.error(message);
         },
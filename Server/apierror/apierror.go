@@ -0,0 +1,163 @@
+// Package apierror writes the body every failed API request is answered
+// with:
+//
+//	{"error": {"code": "not_found", "message": "not found", "details": ...}}
+//
+// Code is a stable, machine-readable name for the kind of failure, message
+// is meant for people and may change, and details, when present, narrows it
+// down, such as the invalid fields of a validation error. When tracing is
+// on, trace_id names the trace of the request, for finding its spans and
+// log lines.
+package apierror
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"starttech-server/i18n"
+	"starttech-server/tracing"
+)
+
+// Codes shared by many routes. Most follow from the status and need not be
+// given; see CodeFor.
+const (
+	CodeBadRequest           = "bad_request"
+	CodeInvalidJSON          = "invalid_json"
+	CodeValidation           = "validation_failed"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeConflict             = "conflict"
+	CodeGone                 = "gone"
+	CodePreconditionFailed   = "precondition_failed"
+	CodeTooLarge             = "request_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeUnprocessable        = "unprocessable"
+	CodeNotApplied           = "not_applied"
+	CodeRateLimited          = "rate_limited"
+	CodeQuotaExceeded        = "quota_exceeded"
+	CodeInternal             = "internal"
+	CodeBadGateway           = "bad_gateway"
+	CodeUnavailable          = "unavailable"
+)
+
+// Codes of particular failures that clients may want to handle.
+const (
+	CodeInvalidCredentials     = "invalid_credentials"
+	CodeEmailNotVerified       = "email_not_verified"
+	CodeAccountDisabled        = "account_disabled"
+	CodeInvalidToken           = "invalid_token"
+	CodeSessionEnded           = "session_ended"
+	CodeNotAMember             = "not_a_member"
+	CodeReadOnlyKey            = "read_only_key"
+	CodeIdempotencyReused      = "idempotency_key_reused"
+	CodeIdempotencyInFlight    = "idempotency_key_in_flight"
+	CodeTwoFactorRequired      = "two_factor_required"
+	CodeInvalidTwoFactorCode   = "invalid_two_factor_code"
+	CodeTwoFactorSetupRequired = "two_factor_setup_required"
+	CodeSSORequired            = "sso_required"
+	CodeNotScanned             = "not_scanned"
+	CodeQuarantined            = "quarantined"
+	CodeThumbnailPending       = "thumbnail_pending"
+	CodeFeatureDisabled        = "feature_disabled"
+)
+
+// Error is the error object of a response body.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+	TraceID string `json:"trace_id,omitempty"`
+}
+
+// Body is the whole response body.
+type Body struct {
+	Error Error `json:"error"`
+}
+
+// CodeFor returns the code of a failure with the given status that has no
+// more specific one.
+func CodeFor(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusGone:
+		return CodeGone
+	case http.StatusPreconditionFailed:
+		return CodePreconditionFailed
+	case http.StatusRequestEntityTooLarge:
+		return CodeTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMediaType
+	case http.StatusUnprocessableEntity:
+		return CodeUnprocessable
+	case http.StatusFailedDependency:
+		return CodeNotApplied
+	case http.StatusPaymentRequired:
+		return CodeQuotaExceeded
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusBadGateway:
+		return CodeBadGateway
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
+
+// Write answers with status and an error of the code that status implies.
+func Write(w http.ResponseWriter, status int, message string) {
+	WriteError(w, status, Error{Code: CodeFor(status), Message: message})
+}
+
+// Localizer is implemented by details that hold text for people, such as
+// the messages of invalid fields.
+type Localizer interface {
+	// Localize returns the details with their text in lang.
+	Localize(lang string) any
+}
+
+// Localize returns e with its message, and its details if they are a
+// Localizer, in lang.
+func Localize(e Error, lang string) Error {
+	e.Message = i18n.Translate(lang, e.Message)
+	if d, ok := e.Details.(Localizer); ok {
+		e.Details = d.Localize(lang)
+	}
+	return e
+}
+
+// WriteError answers with status and e, whose code defaults to the one
+// status implies, and whose trace ID is the one the tracing middleware set
+// on the response. Its text is put in the language named by the
+// Content-Language header of the response, which i18n sets.
+func WriteError(w http.ResponseWriter, status int, e Error) {
+	if e.Code == "" {
+		e.Code = CodeFor(status)
+	}
+	e = Localize(e, w.Header().Get("Content-Language"))
+	if e.TraceID == "" {
+		e.TraceID = w.Header().Get(tracing.TraceIDHeader)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(Body{Error: e}); err != nil {
+		slog.Error("encoding error response", "err", err)
+	}
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+)
+
+// KeyPrefix starts every API key, which is how the middleware tells them
+// from access tokens.
+const KeyPrefix = "stk_"
+
+// APIKey is what an API key authenticates as: its owner, working in the
+// organization the key was created in. SCIM keys are only good for the
+// SCIM routes, which check them themselves, and Middleware refuses them.
+type APIKey struct {
+	ID       string
+	UserID   string
+	OrgID    string
+	ReadOnly bool
+	SCIM     bool
+}
+
+// KeyFunc looks up an API key. It returns ErrInvalidToken for a key that
+// does not exist or has been revoked.
+type KeyFunc func(ctx context.Context, key string) (APIKey, error)
+
+// safeMethod reports whether r changes nothing, which is all a read-only key
+// may do.
+func safeMethod(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+// Package auth issues and verifies the JSON Web Tokens that authenticate API
+// requests.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken is returned for malformed, forged or expired tokens.
+var ErrInvalidToken = errors.New("auth: invalid token")
+
+// Claims is the payload of an access token. Org is the organization the
+// token works in; a user who belongs to several gets a token per
+// organization. Session is the sign-in the token was issued to, which
+// revoking the session invalidates it with.
+//
+// Admin marks a token of one of the server's administrators. Actor is set
+// when an administrator impersonates Subject: it is the administrator, and
+// Session is theirs.
+type Claims struct {
+	Subject   string `json:"sub"`
+	Org       string `json:"org"`
+	Session   string `json:"sid"`
+	Admin     bool   `json:"adm,omitempty"`
+	Actor     string `json:"act,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// SessionUser is the user whose session the token was issued to: the
+// impersonating administrator, if any, or else the subject.
+func (c Claims) SessionUser() string {
+	if c.Actor != "" {
+		return c.Actor
+	}
+	return c.Subject
+}
+
+// Issuer signs and verifies HS256 tokens with a shared secret.
+type Issuer struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+
+	// Keys, if set, lets the middleware accept API keys as well as access
+	// tokens.
+	Keys KeyFunc
+}
+
+// NewIssuer returns an Issuer whose tokens are valid for ttl.
+func NewIssuer(secret []byte, ttl time.Duration) *Issuer {
+	return &Issuer{secret: secret, ttl: ttl, now: time.Now}
+}
+
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Issue returns a signed token with claims c, whose issue and expiry times
+// it sets, and the expiry time.
+func (i *Issuer) Issue(c Claims) (string, time.Time, error) {
+	now := i.now()
+	exp := now.Add(i.ttl)
+	c.IssuedAt, c.ExpiresAt = now.Unix(), exp.Unix()
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + i.sign(unsigned), exp, nil
+}
+
+// Parse verifies token and returns its claims. Tokens issued before
+// organizations or sessions existed carry no org or sid claim and are
+// rejected.
+func (i *Issuer) Parse(token string) (Claims, error) {
+	var c Claims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return c, ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(i.sign(parts[0]+"."+parts[1]))) {
+		return c, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return c, ErrInvalidToken
+	}
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return c, ErrInvalidToken
+	}
+	if c.Subject == "" || c.Org == "" || c.Session == "" || i.now().Unix() >= c.ExpiresAt {
+		return c, ErrInvalidToken
+	}
+	return c, nil
+}
+
+func (i *Issuer) sign(unsigned string) string {
+	mac := hmac.New(sha256.New, i.secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
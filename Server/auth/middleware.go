@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"starttech-server/apierror"
+)
+
+// CookieName is the httpOnly cookie that carries the token for browser
+// clients.
+const CookieName = "token"
+
+type (
+	contextKey        struct{}
+	orgContextKey     struct{}
+	sessionContextKey struct{}
+	apiKeyContextKey  struct{}
+	adminContextKey   struct{}
+	actorContextKey   struct{}
+)
+
+// WithUserID returns a copy of ctx carrying the authenticated user's ID.
+func WithUserID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// UserID returns the authenticated user's ID stored in ctx, if any.
+func UserID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKey{}).(string)
+	return id, ok && id != ""
+}
+
+// WithOrgID returns a copy of ctx carrying the organization the request
+// works in.
+func WithOrgID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, orgContextKey{}, id)
+}
+
+// OrgID returns the organization stored in ctx, if any.
+func OrgID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(orgContextKey{}).(string)
+	return id, ok && id != ""
+}
+
+// WithSessionID returns a copy of ctx carrying the session the request's
+// token was issued to.
+func WithSessionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, id)
+}
+
+// SessionID returns the session stored in ctx, if any.
+func SessionID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(sessionContextKey{}).(string)
+	return id, ok && id != ""
+}
+
+// WithAPIKeyID returns a copy of ctx recording that the request was
+// authenticated with the API key id rather than a session's token.
+func WithAPIKeyID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, id)
+}
+
+// APIKeyID returns the API key stored in ctx, if any.
+func APIKeyID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(apiKeyContextKey{}).(string)
+	return id, ok && id != ""
+}
+
+// WithAdmin returns a copy of ctx recording that the request was made by
+// one of the server's administrators.
+func WithAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminContextKey{}, true)
+}
+
+// IsAdmin reports whether ctx records an administrator's request.
+func IsAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminContextKey{}).(bool)
+	return admin
+}
+
+// WithActorID returns a copy of ctx recording that the administrator id is
+// impersonating the request's user.
+func WithActorID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, id)
+}
+
+// ActorID returns the impersonating administrator stored in ctx, if any.
+func ActorID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(actorContextKey{}).(string)
+	return id, ok && id != ""
+}
+
+// Middleware rejects requests without a valid token and records the token's
+// subject, organization and session in the request context, along with
+// its admin and actor claims. The token is
+// read from a Bearer Authorization header, falling back to the session
+// cookie. Whether the session is still active is up to the caller to
+// check, since that takes a store.
+//
+// With Keys set, the token may instead be an API key, whose ID is recorded
+// in place of a session; read-only keys are refused any request but GET,
+// HEAD and OPTIONS, and SCIM keys are refused altogether.
+func (i *Issuer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if token == "" {
+			unauthorized(w)
+			return
+		}
+		if i.Keys != nil && strings.HasPrefix(token, KeyPrefix) {
+			i.serveKey(w, r, token, next)
+			return
+		}
+		claims, err := i.Parse(token)
+		if err != nil {
+			unauthorized(w)
+			return
+		}
+		ctx := WithSessionID(WithOrgID(WithUserID(r.Context(), claims.Subject), claims.Org), claims.Session)
+		if claims.Admin {
+			ctx = WithAdmin(ctx)
+		}
+		if claims.Actor != "" {
+			ctx = WithActorID(ctx, claims.Actor)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireAdmin refuses requests but those whose token carries the admin
+// claim. It must run behind Middleware.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAdmin(r.Context()) {
+			apierror.Write(w, http.StatusForbidden, "only administrators may do that")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (i *Issuer) serveKey(w http.ResponseWriter, r *http.Request, token string, next http.Handler) {
+	key, err := i.Keys(r.Context(), token)
+	if errors.Is(err, ErrInvalidToken) {
+		unauthorized(w)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "looking up API key", "err", err)
+		apierror.Write(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if key.SCIM {
+		apierror.Write(w, http.StatusForbidden, "this API key only works with the SCIM routes")
+		return
+	}
+	if key.ReadOnly && !safeMethod(r) {
+		apierror.WriteError(w, http.StatusForbidden, apierror.Error{Code: apierror.CodeReadOnlyKey, Message: "this API key is read-only"})
+		return
+	}
+	ctx := WithAPIKeyID(WithOrgID(WithUserID(r.Context(), key.UserID), key.OrgID), key.ID)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// QueryToken lets clients that cannot set headers, such as browser
+// WebSocket and EventSource connections, pass the token as the access_token
+// query parameter. Only mount it on streaming endpoints: URLs end up in logs.
+func QueryToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if t := r.URL.Query().Get("access_token"); t != "" && r.Header.Get("Authorization") == "" {
+			r = r.Clone(r.Context())
+			r.Header.Set("Authorization", "Bearer "+t)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func bearerToken(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		scheme, token, ok := strings.Cut(h, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return ""
+		}
+		return strings.TrimSpace(token)
+	}
+	if c, err := r.Cookie(CookieName); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	apierror.Write(w, http.StatusUnauthorized, "authentication required")
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the test vectors of RFC 4226 and RFC
+// 6238, "12345678901234567890", in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	key := []byte("12345678901234567890")
+	// RFC 4226, appendix D: HOTP values for counters 0 to 9.
+	hotp := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for i, want := range hotp {
+		if got := totpCode(key, int64(i)); got != want {
+			t.Errorf("counter %d: got %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestCheckTOTP(t *testing.T) {
+	// RFC 6238, appendix B, for SHA-1, with the last six of the eight
+	// digits there.
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		now := time.Unix(tt.unix, 0)
+		step, ok := CheckTOTP(rfcSecret, tt.code, now)
+		if !ok || step != tt.unix/totpPeriod {
+			t.Errorf("CheckTOTP(%s) at %d = %d, %v, want %d, true", tt.code, tt.unix, step, ok, tt.unix/totpPeriod)
+		}
+	}
+}
+
+func TestCheckTOTPWindow(t *testing.T) {
+	// 1111111109 is in period 37037036, whose code is 081804.
+	base := int64(37037036) * totpPeriod
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		unix   int64
+		ok     bool
+	}{
+		{"start of the period", rfcSecret, "081804", base, true},
+		{"end of the period", rfcSecret, "081804", base + totpPeriod - 1, true},
+		{"one period late", rfcSecret, "081804", base + totpPeriod, true},
+		{"one period early", rfcSecret, "081804", base - 1, true},
+		{"two periods late", rfcSecret, "081804", base + 2*totpPeriod, false},
+		{"two periods early", rfcSecret, "081804", base - totpPeriod - 1, false},
+		{"lower case secret with spaces", " " + strings.ToLower(rfcSecret) + " ", "081804", base, true},
+		{"wrong code", rfcSecret, "081805", base, false},
+		{"eight digits", rfcSecret, "07081804", base, false},
+		{"short code", rfcSecret, "81804", base, false},
+		{"empty code", rfcSecret, "", base, false},
+		{"bad secret", "not base32!", "081804", base, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := CheckTOTP(tt.secret, tt.code, time.Unix(tt.unix, 0)); ok != tt.ok {
+				t.Errorf("CheckTOTP = %v, want %v", ok, tt.ok)
+			}
+		})
+	}
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	a, b := NewTOTPSecret(), NewTOTPSecret()
+	if a == b {
+		t.Error("two secrets are the same")
+	}
+	key, err := totpEncoding.DecodeString(a)
+	if err != nil || len(key) != 20 {
+		t.Errorf("secret %q decodes to %d bytes, %v", a, len(key), err)
+	}
+	now := time.Now()
+	if _, ok := CheckTOTP(a, totpCode(key, now.Unix()/totpPeriod), now); !ok {
+		t.Error("a new secret's current code is refused")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("Start Tech", "ada@example.com", rfcSecret)
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Start Tech:ada@example.com" {
+		t.Errorf("URI = %s", uri)
+	}
+	want := map[string]string{"secret": rfcSecret, "issuer": "Start Tech", "algorithm": "SHA1", "digits": "6", "period": "30"}
+	for k, v := range want {
+		if got := u.Query().Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+}
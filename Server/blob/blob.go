@@ -0,0 +1,36 @@
+// Package blob stores the contents of uploaded files. The database keeps
+// their metadata; a Store keeps the bytes, on local disk or in an
+// S3-compatible bucket.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned when no blob has the requested key.
+var ErrNotFound = errors.New("blob: not found")
+
+// Store saves and serves blobs by key. Keys are slash-separated paths
+// chosen by the caller, such as "tasks/<task id>/<attachment id>".
+type Store interface {
+	// Put stores size bytes read from r under key, replacing any blob
+	// already there.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open returns the blob stored under key. If the result also
+	// implements io.Seeker, callers may serve ranges of it.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob under key. Deleting a missing blob is not
+	// an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Presigner is implemented by stores that can hand out URLs clients
+// download from directly, without going through the API server.
+type Presigner interface {
+	// PresignGet returns a URL for the blob under key that is valid for
+	// ttl. Downloads through it are offered as a file named filename.
+	PresignGet(key, filename string, ttl time.Duration) (string, error)
+}
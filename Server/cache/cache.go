@@ -0,0 +1,20 @@
+// Package cache keeps copies of hot reads, so repeated requests for the
+// same data are answered without asking the database. Store wraps a
+// storage.Store and caches the task lists of projects and user profiles,
+// dropping them as writes go through it.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache holds values by key until they expire.
+type Cache interface {
+	// Get returns the value of key, and false if there is none.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes keys. Missing keys are not an error.
+	Delete(ctx context.Context, keys ...string) error
+}
@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"starttech-server/redis"
+)
+
+// Redis keeps entries in a Redis server, so every instance using it shares
+// them and sees the others' writes. Keys are prefixed with "cache:".
+type Redis struct {
+	Client *redis.Client
+}
+
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := c.Client.Do(ctx, "GET", "cache:"+key)
+	if err != nil {
+		return nil, false, err
+	}
+	switch v := reply.(type) {
+	case nil:
+		return nil, false, nil
+	case []byte:
+		return v, true, nil
+	}
+	return nil, false, fmt.Errorf("cache: unexpected reply %v", reply)
+}
+
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := c.Client.Do(ctx, "SET", "cache:"+key, value, "PX", max(ttl.Milliseconds(), 1))
+	return err
+}
+
+func (c *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := make([]any, 0, len(keys)+1)
+	args = append(args, "DEL")
+	for _, k := range keys {
+		args = append(args, "cache:"+k)
+	}
+	_, err := c.Client.Do(ctx, args...)
+	return err
+}
@@ -0,0 +1,345 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"starttech-server/metrics"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+var lookups = metrics.NewCounterVec("cache_requests_total",
+	"Reads answered from the cache (hit) or the database (miss), by what was read.", "cache", "result")
+
+// Store is a storage.Store that caches the task lists and counts of a
+// project and GetUser. Each project and user has a generation, a random
+// key its entries are stored under; writes through Store delete the
+// generation, so entries stored before them are never read again and
+// expire on their own. A read takes the generation before asking the
+// database, so one racing a write at worst stores its result under a
+// generation the write has already dropped.
+//
+// Writes made in a transaction drop the generations once it commits, and
+// reads in one go to the database. If the cache fails, reads go to the
+// database and a warning is logged.
+type Store struct {
+	storage.Store
+	cache   Cache
+	taskTTL time.Duration
+	userTTL time.Duration
+	// pending collects, within a transaction, the scopes to drop once it
+	// commits. It is nil outside one.
+	pending *[]string
+}
+
+// Wrap returns store with task lists cached in c for taskTTL and users for
+// userTTL.
+func Wrap(store storage.Store, c Cache, taskTTL, userTTL time.Duration) *Store {
+	return &Store{Store: store, cache: c, taskTTL: taskTTL, userTTL: userTTL}
+}
+
+// projectScope and userScope name the generations of a project's tasks
+// and of a user.
+func projectScope(id string) string { return "project:" + id }
+func userScope(id string) string    { return "user:" + id }
+
+// cacheable reports whether the results for f are cached: those of one
+// project, unless sorted by urgency, which changes with the time of day.
+func cacheable(f storage.TaskFilter) bool {
+	return f.ProjectID != "" && f.Sort.Field != storage.SortUrgency
+}
+
+// filterKey identifies the results for f within its project.
+func filterKey(kind string, f storage.TaskFilter) string {
+	b, _ := json.Marshal(f)
+	sum := sha256.Sum256(b)
+	return kind + ":" + hex.EncodeToString(sum[:16])
+}
+
+func (s *Store) ListTasks(ctx context.Context, f storage.TaskFilter) ([]model.Task, error) {
+	if !cacheable(f) {
+		return s.Store.ListTasks(ctx, f)
+	}
+	tasks, err := read(ctx, s, "tasks", projectScope(f.ProjectID), filterKey("list", f), s.taskTTL, func() ([]model.Task, error) {
+		return s.Store.ListTasks(ctx, f)
+	})
+	if tasks == nil && err == nil {
+		// gob does not tell an empty list from none.
+		tasks = []model.Task{}
+	}
+	return tasks, err
+}
+
+func (s *Store) CountTasks(ctx context.Context, f storage.TaskFilter) (int, error) {
+	if !cacheable(f) {
+		return s.Store.CountTasks(ctx, f)
+	}
+	f.Limit, f.Offset, f.Sort = 0, 0, storage.Sort{}
+	return read(ctx, s, "tasks", projectScope(f.ProjectID), filterKey("count", f), s.taskTTL, func() (int, error) {
+		return s.Store.CountTasks(ctx, f)
+	})
+}
+
+func (s *Store) GetUser(ctx context.Context, id string) (model.User, error) {
+	return read(ctx, s, "users", userScope(id), "profile", s.userTTL, func() (model.User, error) {
+		return s.Store.GetUser(ctx, id)
+	})
+}
+
+func (s *Store) CreateTask(ctx context.Context, t *model.Task) error {
+	if err := s.Store.CreateTask(ctx, t); err != nil {
+		return err
+	}
+	s.drop(ctx, taskScopes(*t)...)
+	return nil
+}
+
+// UpdateTask drops the lists of the project the task was in as well as
+// the one it is in now, as the update may have moved it.
+func (s *Store) UpdateTask(ctx context.Context, t *model.Task) error {
+	prev, prevErr := s.Store.GetTask(ctx, t.ID)
+	if err := s.Store.UpdateTask(ctx, t); err != nil {
+		return err
+	}
+	scopes := taskScopes(*t)
+	if prevErr == nil {
+		scopes = append(scopes, taskScopes(prev)...)
+	}
+	s.drop(ctx, scopes...)
+	return nil
+}
+
+func (s *Store) DeleteTask(ctx context.Context, id string) error {
+	t, err := s.Store.GetTask(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.Store.DeleteTask(ctx, id); err != nil {
+		return err
+	}
+	s.drop(ctx, taskScopes(t)...)
+	return nil
+}
+
+func (s *Store) DeleteProject(ctx context.Context, id string) error {
+	if err := s.Store.DeleteProject(ctx, id); err != nil {
+		return err
+	}
+	s.drop(ctx, projectScope(id))
+	return nil
+}
+
+func (s *Store) ReorderTasks(ctx context.Context, projectID string, taskIDs []string) error {
+	if err := s.Store.ReorderTasks(ctx, projectID, taskIDs); err != nil {
+		return err
+	}
+	s.drop(ctx, projectScope(projectID))
+	return nil
+}
+
+// SaveMember and RemoveMember drop the project's lists, which are cached
+// per member.
+func (s *Store) SaveMember(ctx context.Context, m *model.Member) error {
+	if err := s.Store.SaveMember(ctx, m); err != nil {
+		return err
+	}
+	s.drop(ctx, projectScope(m.ProjectID))
+	return nil
+}
+
+func (s *Store) RemoveMember(ctx context.Context, projectID, userID string) error {
+	if err := s.Store.RemoveMember(ctx, projectID, userID); err != nil {
+		return err
+	}
+	s.drop(ctx, projectScope(projectID))
+	return nil
+}
+
+func (s *Store) RemoveOrgMember(ctx context.Context, orgID, userID string) error {
+	projects, err := s.Store.ListProjects(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.Store.RemoveOrgMember(ctx, orgID, userID); err != nil {
+		return err
+	}
+	scopes := make([]string, len(projects))
+	for i, p := range projects {
+		scopes[i] = projectScope(p.ID)
+	}
+	s.drop(ctx, scopes...)
+	return nil
+}
+
+// DeleteTag drops the lists of the projects whose tasks carried the tag.
+func (s *Store) DeleteTag(ctx context.Context, id string) error {
+	var scopes []string
+	for _, trashed := range []bool{false, true} {
+		tasks, err := s.Store.ListTasks(ctx, storage.TaskFilter{TagIDs: []string{id}, Trashed: trashed})
+		if err != nil {
+			return err
+		}
+		for _, t := range tasks {
+			scopes = append(scopes, taskScopes(t)...)
+		}
+	}
+	if err := s.Store.DeleteTag(ctx, id); err != nil {
+		return err
+	}
+	s.drop(ctx, scopes...)
+	return nil
+}
+
+func (s *Store) UpdateUser(ctx context.Context, u model.User) error {
+	if err := s.Store.UpdateUser(ctx, u); err != nil {
+		return err
+	}
+	s.drop(ctx, userScope(u.ID))
+	return nil
+}
+
+// EraseUser drops the user and the lists of the projects the user left.
+func (s *Store) EraseUser(ctx context.Context, u model.User) error {
+	orgs, err := s.Store.ListOrgs(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	scopes := []string{userScope(u.ID)}
+	for _, o := range orgs {
+		projects, err := s.Store.ListProjects(ctx, o.ID, u.ID)
+		if err != nil {
+			return err
+		}
+		for _, p := range projects {
+			scopes = append(scopes, projectScope(p.ID))
+		}
+	}
+	if err := s.Store.EraseUser(ctx, u); err != nil {
+		return err
+	}
+	s.drop(ctx, scopes...)
+	return nil
+}
+
+// MergeTask drops the lists of the projects of both tasks.
+func (s *Store) MergeTask(ctx context.Context, fromID, intoID string) error {
+	var scopes []string
+	for _, id := range []string{fromID, intoID} {
+		t, err := s.Store.GetTask(ctx, id)
+		if err != nil {
+			return err
+		}
+		scopes = append(scopes, taskScopes(t)...)
+	}
+	if err := s.Store.MergeTask(ctx, fromID, intoID); err != nil {
+		return err
+	}
+	s.drop(ctx, scopes...)
+	return nil
+}
+
+// InTx runs fn with a Store that reads from the transaction without the
+// cache and drops what it wrote once the transaction commits.
+func (s *Store) InTx(ctx context.Context, fn func(tx storage.Store) error) error {
+	if s.pending != nil {
+		return s.Store.InTx(ctx, func(tx storage.Store) error {
+			return fn(&Store{Store: tx, cache: s.cache, taskTTL: s.taskTTL, userTTL: s.userTTL, pending: s.pending})
+		})
+	}
+	var scopes []string
+	err := s.Store.InTx(ctx, func(tx storage.Store) error {
+		scopes = scopes[:0]
+		return fn(&Store{Store: tx, cache: s.cache, taskTTL: s.taskTTL, userTTL: s.userTTL, pending: &scopes})
+	})
+	if err != nil {
+		return err
+	}
+	s.drop(ctx, scopes...)
+	return nil
+}
+
+// taskScopes returns the scope of t's project, if it has one.
+func taskScopes(t model.Task) []string {
+	if t.ProjectID == nil {
+		return nil
+	}
+	return []string{projectScope(*t.ProjectID)}
+}
+
+// drop deletes the generations of scopes, or in a transaction, keeps them
+// to be deleted when it commits.
+func (s *Store) drop(ctx context.Context, scopes ...string) {
+	if s.pending != nil {
+		*s.pending = append(*s.pending, scopes...)
+		return
+	}
+	if len(scopes) == 0 {
+		return
+	}
+	keys := make([]string, len(scopes))
+	for i, scope := range scopes {
+		keys[i] = "gen:" + scope
+	}
+	if err := s.cache.Delete(ctx, keys...); err != nil {
+		slog.Warn("cache unavailable; entries may be stale until they expire", "err", err, "keys", keys)
+	}
+}
+
+// generation returns the current generation of scope, starting a new one
+// if it has none.
+func (s *Store) generation(ctx context.Context, scope string, ttl time.Duration) (string, error) {
+	gen, ok, err := s.cache.Get(ctx, "gen:"+scope)
+	if err != nil || ok {
+		return string(gen), err
+	}
+	id := storage.NewID()
+	return id, s.cache.Set(ctx, "gen:"+scope, []byte(id), ttl)
+}
+
+// read returns the value cached under key in the current generation of
+// scope, or loads it and caches it for ttl. kind labels the metrics.
+func read[T any](ctx context.Context, s *Store, kind, scope, key string, ttl time.Duration, load func() (T, error)) (T, error) {
+	if s.pending != nil {
+		return load()
+	}
+	gen, err := s.generation(ctx, scope, ttl)
+	if err != nil {
+		slog.Warn("cache unavailable; reading from the database", "err", err)
+		return load()
+	}
+	key = scope + ":" + gen + ":" + key
+
+	b, ok, err := s.cache.Get(ctx, key)
+	if err != nil {
+		slog.Warn("cache unavailable; reading from the database", "err", err)
+	}
+	if ok {
+		var v T
+		if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&v); err == nil {
+			lookups.With(kind, "hit").Inc()
+			return v, nil
+		}
+	}
+	lookups.With(kind, "miss").Inc()
+
+	v, err := load()
+	if err != nil {
+		return v, err
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return v, nil
+	}
+	if err := s.cache.Set(ctx, key, buf.Bytes(), ttl); err != nil && !errors.Is(err, context.Canceled) {
+		slog.Warn("cache unavailable; result not cached", "err", err)
+	}
+	return v, nil
+}
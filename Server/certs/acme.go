@@ -0,0 +1,17 @@
+//go:build acme
+
+package certs
+
+import "golang.org/x/crypto/acme/autocert"
+
+func init() {
+	newACME = func(domains []string, email, cacheDir string) Source {
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(domains...),
+			Email:      email,
+			Cache:      autocert.DirCache(cacheDir),
+		}
+		return Source{TLS: m.TLSConfig(), HTTP: m.HTTPHandler}
+	}
+}
@@ -0,0 +1,88 @@
+// Package certs supplies the TLS configuration of the HTTPS server, from
+// certificate files or from Let's Encrypt, and the plain HTTP handler that
+// sends browsers over to HTTPS.
+package certs
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// Source is where the HTTPS server gets its certificates.
+type Source struct {
+	// TLS is the server's TLS configuration.
+	TLS *tls.Config
+	// HTTP wraps the handler of the plain HTTP port, which with Let's
+	// Encrypt must answer its challenges.
+	HTTP func(http.Handler) http.Handler
+}
+
+// ErrNoACME is returned by ACME when the binary was built without it.
+var ErrNoACME = errors.New("certs: Let's Encrypt support is not compiled in; build with -tags acme")
+
+// newACME is set by acme.go, which is only built with -tags acme, so that
+// the default build keeps to the standard library.
+var newACME func(domains []string, email, cacheDir string) Source
+
+// ACME gets certificates for domains from Let's Encrypt, agreeing to its
+// terms of service, and renews them before they expire. They are kept in
+// cacheDir, so restarts do not request new ones.
+func ACME(domains []string, email, cacheDir string) (Source, error) {
+	if newACME == nil {
+		return Source{}, ErrNoACME
+	}
+	s := newACME(domains, email, cacheDir)
+	harden(s.TLS)
+	return s, nil
+}
+
+// Files serves the certificate chain in certFile with the key in keyFile,
+// both PEM encoded.
+func Files(certFile, keyFile string) (Source, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return Source{}, fmt.Errorf("certs: loading %s: %w", certFile, err)
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+	harden(cfg)
+	return Source{TLS: cfg, HTTP: func(h http.Handler) http.Handler { return h }}, nil
+}
+
+// harden keeps cfg to TLS 1.2 and later, and to forward-secret AEAD cipher
+// suites for 1.2; the suites of 1.3 are not configurable and all fine.
+func harden(cfg *tls.Config) {
+	cfg.MinVersion = tls.VersionTLS12
+	cfg.CurvePreferences = []tls.CurveID{tls.X25519MLKEM768, tls.X25519, tls.CurveP256}
+	cfg.CipherSuites = []uint16{
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+	}
+}
+
+// Redirect sends every request to the same URL over HTTPS on httpsPort.
+// GET and HEAD are moved permanently with 301; other methods get 308, so
+// clients repeat them with the same body.
+func Redirect(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+		status := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			status = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), status)
+	})
+}
@@ -0,0 +1,189 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"starttech-server/model"
+)
+
+// The methods below need an administrator's token.
+
+// AdminStats counts what the whole server holds.
+func (c *Client) AdminStats(ctx context.Context) (*model.Usage, error) {
+	return call[model.Usage](ctx, c, request{method: "GET", path: "/admin/stats"})
+}
+
+// AdminUsers returns a page of the users whose email or username contains
+// q, or of every user if q is empty.
+func (c *Client) AdminUsers(ctx context.Context, q string, opts PageOptions) (*model.UserPage, error) {
+	return call[model.UserPage](ctx, c, request{method: "GET", path: "/admin/users", query: searchQuery(q, opts)})
+}
+
+// AdminUser returns a user with their organizations and session count.
+func (c *Client) AdminUser(ctx context.Context, id string) (*model.AdminUser, error) {
+	return call[model.AdminUser](ctx, c, request{method: "GET", path: adminUserPath(id)})
+}
+
+// DisableUser keeps a user from signing in and ends their sessions.
+func (c *Client) DisableUser(ctx context.Context, id string) (*model.User, error) {
+	return call[model.User](ctx, c, request{method: "POST", path: adminUserPath(id) + "/disable"})
+}
+
+// EnableUser lets a disabled user sign in again.
+func (c *Client) EnableUser(ctx context.Context, id string) (*model.User, error) {
+	return call[model.User](ctx, c, request{method: "POST", path: adminUserPath(id) + "/enable"})
+}
+
+// SendPasswordReset emails a user a token to reset their password with.
+func (c *Client) SendPasswordReset(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: "POST", path: adminUserPath(id) + "/reset-password"}, nil)
+}
+
+// Impersonate returns a session signed in as a user. Pass it to
+// UseSession, on this client or another, to act as them.
+func (c *Client) Impersonate(ctx context.Context, id string) (*model.Session, error) {
+	return call[model.Session](ctx, c, request{method: "POST", path: adminUserPath(id) + "/impersonate"})
+}
+
+// AdminOrgs returns a page of the organizations whose name contains q, or
+// of every organization if q is empty.
+func (c *Client) AdminOrgs(ctx context.Context, q string, opts PageOptions) (*model.AdminOrgPage, error) {
+	return call[model.AdminOrgPage](ctx, c, request{method: "GET", path: "/admin/orgs", query: searchQuery(q, opts)})
+}
+
+// AdminOrg returns an organization with its usage.
+func (c *Client) AdminOrg(ctx context.Context, id string) (*model.AdminOrg, error) {
+	return call[model.AdminOrg](ctx, c, request{method: "GET", path: "/admin/orgs/" + escape(id)})
+}
+
+// Backup writes an archive of the organization orgID, or of the client's
+// own if it is empty, to w. Big organizations take longer than the
+// default HTTPClient's 30 second timeout allows.
+func (c *Client) Backup(ctx context.Context, orgID string, w io.Writer) error {
+	req := request{
+		method: "POST",
+		path:   "/admin/backup",
+		body:   model.BackupInput{OrgID: orgID},
+		header: http.Header{"Accept": {"application/gzip"}},
+	}
+	return c.download(ctx, req, w)
+}
+
+// Restore rebuilds the organization in a backup archive read from r, as a
+// new organization.
+func (c *Client) Restore(ctx context.Context, r io.Reader) (*model.RestoreResult, error) {
+	return call[model.RestoreResult](ctx, c, request{method: "POST", path: "/admin/restore", upload: r, contentType: "application/gzip"})
+}
+
+// JobListOptions narrows ListJobs. Zero fields are left to the server.
+type JobListOptions struct {
+	Status model.JobStatus
+	// Kind is a job kind, such as "email.send".
+	Kind  string
+	Limit int
+}
+
+// ListJobs returns background jobs, newest first.
+func (c *Client) ListJobs(ctx context.Context, opts JobListOptions) ([]model.Job, error) {
+	q := url.Values{}
+	if opts.Status != "" {
+		q.Set("status", string(opts.Status))
+	}
+	if opts.Kind != "" {
+		q.Set("kind", opts.Kind)
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	return list[model.Job](ctx, c, request{method: "GET", path: "/admin/jobs", query: q})
+}
+
+// GetJob returns a background job.
+func (c *Client) GetJob(ctx context.Context, id string) (*model.Job, error) {
+	return call[model.Job](ctx, c, request{method: "GET", path: "/admin/jobs/" + escape(id)})
+}
+
+// RetryJob gives a dead job a fresh set of attempts.
+func (c *Client) RetryJob(ctx context.Context, id string) (*model.Job, error) {
+	return call[model.Job](ctx, c, request{method: "POST", path: "/admin/jobs/" + escape(id) + "/retry"})
+}
+
+// DeleteJob discards a background job.
+func (c *Client) DeleteJob(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: "DELETE", path: "/admin/jobs/" + escape(id)}, nil)
+}
+
+// PreviewPurges counts what each scheduled purge would delete if it ran
+// now. Nothing is deleted.
+func (c *Client) PreviewPurges(ctx context.Context) ([]model.PurgePreview, error) {
+	return list[model.PurgePreview](ctx, c, request{method: "GET", path: "/admin/purges"})
+}
+
+// AuditOptions narrows the audit log. Zero fields match every event.
+type AuditOptions struct {
+	// From and To bound when events happened, From inclusive and To
+	// exclusive.
+	From, To time.Time
+	// Action is an audit action, such as model.AuditLoginFailed.
+	Action  string
+	ActorID string
+	OrgID   string
+}
+
+func (o AuditOptions) values(page PageOptions) url.Values {
+	q := page.values()
+	if !o.From.IsZero() {
+		q.Set("from", o.From.Format(time.RFC3339Nano))
+	}
+	if !o.To.IsZero() {
+		q.Set("to", o.To.Format(time.RFC3339Nano))
+	}
+	for name, v := range map[string]string{"action": o.Action, "actor": o.ActorID, "org_id": o.OrgID} {
+		if v != "" {
+			q.Set(name, v)
+		}
+	}
+	return q
+}
+
+// AuditLog returns a page of the audit log events matching opts, newest
+// first.
+func (c *Client) AuditLog(ctx context.Context, opts AuditOptions, page PageOptions) (*model.AuditPage, error) {
+	return call[model.AuditPage](ctx, c, request{method: "GET", path: "/admin/audit", query: opts.values(page)})
+}
+
+// ExportAuditLog writes the audit log events matching opts to w as CSV,
+// oldest first.
+func (c *Client) ExportAuditLog(ctx context.Context, opts AuditOptions, w io.Writer) error {
+	req := request{
+		method: "GET",
+		path:   "/admin/audit/export",
+		query:  opts.values(PageOptions{}),
+		header: http.Header{"Accept": {"text/csv"}},
+	}
+	return c.download(ctx, req, w)
+}
+
+// VerifyAuditLog checks that no stored audit log event has been changed,
+// removed or reordered.
+func (c *Client) VerifyAuditLog(ctx context.Context) (*model.AuditVerification, error) {
+	return call[model.AuditVerification](ctx, c, request{method: "GET", path: "/admin/audit/verify"})
+}
+
+func adminUserPath(id string) string {
+	return "/admin/users/" + escape(id)
+}
+
+// searchQuery is the query of a paged listing filtered by q.
+func searchQuery(q string, opts PageOptions) url.Values {
+	query := opts.values()
+	if q != "" {
+		query.Set("q", q)
+	}
+	return query
+}
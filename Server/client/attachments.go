@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"starttech-server/model"
+)
+
+// ListAttachments returns the files attached to a task, each with a fresh
+// download link.
+func (c *Client) ListAttachments(ctx context.Context, taskID string) ([]model.Attachment, error) {
+	return list[model.Attachment](ctx, c, request{method: "GET", path: taskPath(taskID) + "/attachments"})
+}
+
+// UploadAttachment attaches the contents of r to a task as a file called
+// filename. The upload is not retried, as r can only be read once.
+func (c *Client) UploadAttachment(ctx context.Context, taskID, filename string, r io.Reader) (*model.Attachment, error) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		part, err := mw.CreateFormFile("file", filename)
+		if err == nil {
+			_, err = io.Copy(part, r)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	a, err := call[model.Attachment](ctx, c, request{
+		method:      "POST",
+		path:        taskPath(taskID) + "/attachments",
+		upload:      pr,
+		contentType: mw.FormDataContentType(),
+	})
+	// Unblock the writer if the request ended before reading it all.
+	pr.Close()
+	return a, err
+}
+
+// GetAttachment returns an attachment with a fresh download link.
+func (c *Client) GetAttachment(ctx context.Context, taskID, id string) (*model.Attachment, error) {
+	return call[model.Attachment](ctx, c, request{method: "GET", path: attachmentPath(taskID, id)})
+}
+
+// DownloadAttachment copies the contents of an attachment to w.
+func (c *Client) DownloadAttachment(ctx context.Context, taskID, id string, w io.Writer) error {
+	req := request{method: "GET", path: attachmentPath(taskID, id) + "/download", header: http.Header{"Accept": {"*/*"}}}
+	return c.download(ctx, req, w)
+}
+
+// DeleteAttachment deletes an attachment.
+func (c *Client) DeleteAttachment(ctx context.Context, taskID, id string) error {
+	return c.do(ctx, request{method: "DELETE", path: attachmentPath(taskID, id)}, nil)
+}
+
+func attachmentPath(taskID, id string) string {
+	return taskPath(taskID) + "/attachments/" + escape(id)
+}
@@ -0,0 +1,275 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+
+	"starttech-server/apierror"
+	"starttech-server/model"
+)
+
+// Register creates an account and emails a token to verify its address
+// with; the account cannot sign in until VerifyEmail is called with it.
+func (c *Client) Register(ctx context.Context, in model.RegisterInput) (*model.User, error) {
+	return call[model.User](ctx, c, request{method: "POST", path: "/auth/register", body: in, public: true})
+}
+
+// VerifyEmail verifies an address with the token emailed to it and signs
+// the client in.
+func (c *Client) VerifyEmail(ctx context.Context, token string) (*model.Session, error) {
+	s, err := call[model.Session](ctx, c, request{method: "POST", path: "/auth/verify-email", body: model.TokenInput{Token: token}, public: true})
+	if err != nil {
+		return nil, err
+	}
+	c.UseSession(s)
+	return s, nil
+}
+
+// ResendVerification emails another verification token.
+func (c *Client) ResendVerification(ctx context.Context, email string) error {
+	return c.do(ctx, request{method: "POST", path: "/auth/resend-verification", body: model.EmailInput{Email: email}, public: true}, nil)
+}
+
+// ForgotPassword emails a token to reset the account's password with.
+func (c *Client) ForgotPassword(ctx context.Context, email string) error {
+	return c.do(ctx, request{method: "POST", path: "/auth/forgot-password", body: model.EmailInput{Email: email}, public: true}, nil)
+}
+
+// ResetPassword sets a new password with the token ForgotPassword emailed.
+func (c *Client) ResetPassword(ctx context.Context, token, password string) error {
+	in := model.ResetPasswordInput{Token: token, Password: password}
+	return c.do(ctx, request{method: "POST", path: "/auth/reset-password", body: in, public: true}, nil)
+}
+
+// Login signs in with an email and password. The client then authenticates
+// with the session in place of its API key. If the account has two-factor
+// authentication on, Login fails with an error TwoFactorChallenge takes
+// the challenge for VerifyTwoFactor from.
+func (c *Client) Login(ctx context.Context, in model.LoginInput) (*model.Session, error) {
+	s, err := call[model.Session](ctx, c, request{method: "POST", path: "/auth/login", body: in, public: true})
+	if err != nil {
+		return nil, err
+	}
+	c.UseSession(s)
+	return s, nil
+}
+
+// TwoFactorChallenge returns the challenge from the error of a sign-in that
+// needs a second factor, and false for any other error.
+func TwoFactorChallenge(err error) (string, bool) {
+	var e *Error
+	if !errors.As(err, &e) || e.Code != apierror.CodeTwoFactorRequired {
+		return "", false
+	}
+	details, _ := e.Details.(map[string]any)
+	challenge, ok := details["challenge"].(string)
+	return challenge, ok
+}
+
+// VerifyTwoFactor finishes a sign-in with the challenge it was answered
+// with and a code from the authenticator app, or a recovery code. The
+// client then authenticates with the session, as after Login.
+func (c *Client) VerifyTwoFactor(ctx context.Context, in model.TwoFactorLoginInput) (*model.Session, error) {
+	s, err := call[model.Session](ctx, c, request{method: "POST", path: "/auth/2fa/verify", body: in, public: true})
+	if err != nil {
+		return nil, err
+	}
+	c.UseSession(s)
+	return s, nil
+}
+
+// UseSession makes the client authenticate with s, such as a session saved
+// from an earlier run, or with its API key again if s is nil.
+func (c *Client) UseSession(s *model.Session) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s == nil {
+		c.session = nil
+		return
+	}
+	copied := *s
+	c.session = &copied
+}
+
+// Session returns the client's current session, with the tokens of its
+// latest refresh, or nil if it has none.
+func (c *Client) Session() *model.Session {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.session == nil {
+		return nil
+	}
+	copied := *c.session
+	return &copied
+}
+
+// Refresh exchanges the session's refresh token for new tokens. The client
+// does so by itself when a request finds its token expired.
+func (c *Client) Refresh(ctx context.Context) (*model.Session, error) {
+	c.refreshing.Lock()
+	defer c.refreshing.Unlock()
+	c.mu.Lock()
+	var refreshToken string
+	if c.session != nil {
+		refreshToken = c.session.RefreshToken
+	}
+	c.mu.Unlock()
+
+	s, err := call[model.Session](ctx, c, request{method: "POST", path: "/auth/refresh", body: model.RefreshInput{RefreshToken: refreshToken}, public: true})
+	if err != nil {
+		return nil, err
+	}
+	c.UseSession(s)
+	return s, nil
+}
+
+// SwitchOrg moves the session to another organization the user belongs to.
+func (c *Client) SwitchOrg(ctx context.Context, orgID string) (*model.Session, error) {
+	s, err := call[model.Session](ctx, c, request{method: "POST", path: "/auth/switch", body: model.SwitchInput{OrgID: orgID}})
+	if err != nil {
+		return nil, err
+	}
+	// The refresh token stays the same, so the response leaves it out.
+	c.mu.Lock()
+	if c.session != nil && s.RefreshToken == "" {
+		s.RefreshToken = c.session.RefreshToken
+	}
+	c.mu.Unlock()
+	c.UseSession(s)
+	return s, nil
+}
+
+// Logout ends the session, after which the client uses its API key again.
+func (c *Client) Logout(ctx context.Context) error {
+	if err := c.do(ctx, request{method: "POST", path: "/auth/logout"}, nil); err != nil {
+		return err
+	}
+	c.UseSession(nil)
+	return nil
+}
+
+// token returns the bearer token to send.
+func (c *Client) token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.session != nil {
+		return c.session.Token
+	}
+	return c.APIKey
+}
+
+// canRefresh reports whether the client has a session it can refresh.
+func (c *Client) canRefresh() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.session != nil && c.session.RefreshToken != ""
+}
+
+// refresh refreshes the session after a request sent with token was
+// refused, unless another request has refreshed it since.
+func (c *Client) refresh(ctx context.Context, token string) error {
+	c.refreshing.Lock()
+	current := c.token()
+	c.refreshing.Unlock()
+	if current != token {
+		return nil
+	}
+	_, err := c.Refresh(ctx)
+	return err
+}
+
+// Profile returns the signed-in user's account.
+func (c *Client) Profile(ctx context.Context) (*model.User, error) {
+	return call[model.User](ctx, c, request{method: "GET", path: "/me"})
+}
+
+// UpdateProfile changes the signed-in user's account, such as their
+// timezone or language.
+func (c *Client) UpdateProfile(ctx context.Context, patch model.ProfilePatch) (*model.User, error) {
+	return call[model.User](ctx, c, request{method: "PATCH", path: "/me", body: patch})
+}
+
+// DeleteAccount asks for the signed-in user's account to be deleted, given
+// its password if it has one. It is erased once the returned DeleteAt has
+// passed, unless RestoreAccount keeps it first.
+func (c *Client) DeleteAccount(ctx context.Context, password string) (*model.AccountDeletion, error) {
+	return call[model.AccountDeletion](ctx, c, request{method: "DELETE", path: "/users/me", body: model.DeleteAccountInput{Password: password}})
+}
+
+// RestoreAccount keeps the signed-in user's account, whose deletion they
+// asked for.
+func (c *Client) RestoreAccount(ctx context.Context) (*model.User, error) {
+	return call[model.User](ctx, c, request{method: "POST", path: "/users/me/restore"})
+}
+
+// ExportData writes the signed-in user's data to w as a zip archive of
+// data.json and their attachments.
+func (c *Client) ExportData(ctx context.Context, w io.Writer) error {
+	return c.download(ctx, request{method: "GET", path: "/users/me/export", header: http.Header{"Accept": {"application/zip"}}}, w)
+}
+
+// TwoFactorStatus reports whether the user has two-factor authentication
+// on.
+func (c *Client) TwoFactorStatus(ctx context.Context) (*model.TwoFactorStatus, error) {
+	return call[model.TwoFactorStatus](ctx, c, request{method: "GET", path: "/auth/2fa"})
+}
+
+// SetupTwoFactor returns a new secret for an authenticator app, which
+// EnableTwoFactor then confirms.
+func (c *Client) SetupTwoFactor(ctx context.Context) (*model.TwoFactorSetup, error) {
+	return call[model.TwoFactorSetup](ctx, c, request{method: "POST", path: "/auth/2fa/setup"})
+}
+
+// EnableTwoFactor turns on two-factor authentication with the app's current
+// code and returns the recovery codes, which are not shown again.
+func (c *Client) EnableTwoFactor(ctx context.Context, code string) (*model.RecoveryCodes, error) {
+	return call[model.RecoveryCodes](ctx, c, request{method: "POST", path: "/auth/2fa/enable", body: model.TwoFactorCodeInput{Code: code}})
+}
+
+// DisableTwoFactor turns off two-factor authentication, given a code.
+func (c *Client) DisableTwoFactor(ctx context.Context, code string) error {
+	return c.do(ctx, request{method: "POST", path: "/auth/2fa/disable", body: model.TwoFactorCodeInput{Code: code}}, nil)
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes, given a code.
+func (c *Client) RegenerateRecoveryCodes(ctx context.Context, code string) (*model.RecoveryCodes, error) {
+	return call[model.RecoveryCodes](ctx, c, request{method: "POST", path: "/auth/2fa/recovery-codes", body: model.TwoFactorCodeInput{Code: code}})
+}
+
+// ListSessions returns the devices the user is signed in on.
+func (c *Client) ListSessions(ctx context.Context) ([]model.AuthSession, error) {
+	return list[model.AuthSession](ctx, c, request{method: "GET", path: "/me/sessions"})
+}
+
+// DeleteSessions signs the user out everywhere, or everywhere but the
+// client's own session if others is set.
+func (c *Client) DeleteSessions(ctx context.Context, others bool) error {
+	var q url.Values
+	if others {
+		q = url.Values{"others": {"true"}}
+	}
+	return c.do(ctx, request{method: "DELETE", path: "/me/sessions", query: q}, nil)
+}
+
+// DeleteSession signs the user out on one device.
+func (c *Client) DeleteSession(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: "DELETE", path: "/me/sessions/" + escape(id)}, nil)
+}
+
+// ListAPIKeys returns the user's API keys, without their secrets.
+func (c *Client) ListAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	return list[model.APIKey](ctx, c, request{method: "GET", path: "/settings/api-keys"})
+}
+
+// CreateAPIKey creates an API key. Its secret is only returned here.
+func (c *Client) CreateAPIKey(ctx context.Context, in model.APIKeyInput) (*model.APIKey, error) {
+	return call[model.APIKey](ctx, c, request{method: "POST", path: "/settings/api-keys", body: in})
+}
+
+// DeleteAPIKey revokes an API key.
+func (c *Client) DeleteAPIKey(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: "DELETE", path: "/settings/api-keys/" + escape(id)}, nil)
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"context"
+
+	"starttech-server/model"
+)
+
+// Checklist returns the checklist of a task, in order.
+func (c *Client) Checklist(ctx context.Context, taskID string) ([]model.ChecklistItem, error) {
+	return list[model.ChecklistItem](ctx, c, request{method: "GET", path: taskPath(taskID) + "/checklist"})
+}
+
+// AddChecklistItem adds an item to the checklist of a task.
+func (c *Client) AddChecklistItem(ctx context.Context, taskID string, in model.ChecklistItemInput) (*model.ChecklistItem, error) {
+	return call[model.ChecklistItem](ctx, c, request{method: "POST", path: taskPath(taskID) + "/checklist", body: in})
+}
+
+// OrderChecklist puts every item of a task's checklist in the order of
+// itemIDs.
+func (c *Client) OrderChecklist(ctx context.Context, taskID string, itemIDs []string) ([]model.ChecklistItem, error) {
+	in := model.ChecklistOrder{ItemIDs: itemIDs}
+	return list[model.ChecklistItem](ctx, c, request{method: "PUT", path: taskPath(taskID) + "/checklist/order", body: in})
+}
+
+// UpdateChecklistItem changes the fields of a checklist item that patch
+// sets.
+func (c *Client) UpdateChecklistItem(ctx context.Context, taskID, id string, patch model.ChecklistItemPatch) (*model.ChecklistItem, error) {
+	return call[model.ChecklistItem](ctx, c, request{method: "PATCH", path: checklistPath(taskID, id), body: patch})
+}
+
+// DeleteChecklistItem removes an item from a checklist.
+func (c *Client) DeleteChecklistItem(ctx context.Context, taskID, id string) error {
+	return c.do(ctx, request{method: "DELETE", path: checklistPath(taskID, id)}, nil)
+}
+
+// ToggleChecklistItem ticks a checklist item, or unticks it.
+func (c *Client) ToggleChecklistItem(ctx context.Context, taskID, id string) (*model.ChecklistItem, error) {
+	return call[model.ChecklistItem](ctx, c, request{method: "POST", path: checklistPath(taskID, id) + "/toggle"})
+}
+
+func checklistPath(taskID, id string) string {
+	return taskPath(taskID) + "/checklist/" + escape(id)
+}
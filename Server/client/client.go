@@ -0,0 +1,344 @@
+// Package client calls the Starttech API from Go. Requests and responses
+// are the types of package model, so programs built on it stay in step with
+// the server.
+//
+//	c := client.New("https://tasks.example.com/api/v1", os.Getenv("STARTTECH_API_KEY"))
+//	for t, err := range c.AllTasks(ctx, client.TaskListOptions{Assignee: "me"}) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(t.Title)
+//	}
+//
+// A Client authenticates with an API key, or with the session Login or
+// UseSession gives it, which it refreshes when its access token expires.
+// Requests that fail on the way, are rate limited or find the server
+// unavailable are retried with backoff when that is safe: GET, PUT and
+// DELETE always, and POST because every authenticated POST carries an
+// Idempotency-Key.
+//
+// Browser sign-in through OAuth, the webhooks the integrations receive, the
+// realtime streams, calendar feeds, profiling and the unversioned health
+// and metrics routes are not covered.
+package client
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"starttech-server/apierror"
+	"starttech-server/graphql"
+	"starttech-server/model"
+)
+
+// Client sends requests to one server. Its methods are safe for concurrent
+// use; set its fields before the first request.
+type Client struct {
+	// BaseURL is the root of an API version, such as
+	// https://tasks.example.com/api/v1.
+	BaseURL string
+	// APIKey is sent as a bearer token while the client has no session.
+	APIKey string
+	// HTTPClient sends the requests.
+	HTTPClient *http.Client
+	// UserAgent names the program in the User-Agent header.
+	UserAgent string
+	// MaxRetries is how many times a request is retried; 0 disables
+	// retries.
+	MaxRetries int
+	// RetryWait is the wait before the first retry, which doubles with
+	// each one, up to MaxRetryWait. A Retry-After header overrides it.
+	RetryWait    time.Duration
+	MaxRetryWait time.Duration
+
+	mu      sync.Mutex
+	session *model.Session
+	// refreshing is held while the session is refreshed, so that one
+	// refresh serves every request that found the token expired.
+	refreshing sync.Mutex
+}
+
+// New returns a Client for the API at baseURL, authenticating with apiKey,
+// which may be empty to sign in with Login instead.
+func New(baseURL, apiKey string) *Client {
+	return &Client{
+		BaseURL:      strings.TrimSuffix(baseURL, "/"),
+		APIKey:       apiKey,
+		HTTPClient:   &http.Client{Timeout: 30 * time.Second},
+		UserAgent:    "starttech-go",
+		MaxRetries:   3,
+		RetryWait:    250 * time.Millisecond,
+		MaxRetryWait: 10 * time.Second,
+	}
+}
+
+// Error is a request the server refused or failed, with the error object
+// of its response; see package apierror for the codes.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Details    any
+	TraceID    string
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Message == "":
+		return fmt.Sprintf("starttech: %s", http.StatusText(e.StatusCode))
+	case e.Code == "":
+		return "starttech: " + e.Message
+	}
+	return fmt.Sprintf("starttech: %s (%s)", e.Message, e.Code)
+}
+
+// StatusCode returns the status of the response err describes, or 0 if it
+// is not an *Error.
+func StatusCode(err error) int {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.StatusCode
+	}
+	return 0
+}
+
+// request is one call of the API.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	// public requests are sent without a token.
+	public bool
+	// body is sent as JSON.
+	body any
+	// upload is sent as it is, with contentType, in place of body. It
+	// cannot be sent twice, so the request is not retried.
+	upload      io.Reader
+	contentType string
+}
+
+// do sends req and decodes the response into out, if any.
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("starttech: decoding %s %s: %w", req.method, req.path, err)
+	}
+	return nil
+}
+
+// call is do for a response decoded into a new T.
+func call[T any](ctx context.Context, c *Client, req request) (*T, error) {
+	var out T
+	if err := c.do(ctx, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// list is call for a response that is a JSON array.
+func list[T any](ctx context.Context, c *Client, req request) ([]T, error) {
+	var out []T
+	if err := c.do(ctx, req, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// download sends req and copies the response body to w.
+func (c *Client) download(ctx context.Context, req request, w io.Writer) error {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// send sends req, retrying and refreshing the session as needed, and
+// returns a successful response, whose body the caller must close.
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return nil, err
+		}
+	}
+	u := c.BaseURL + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+	// One key for every attempt, so the server runs the request once.
+	var idempotencyKey string
+	if req.method == http.MethodPost {
+		idempotencyKey = newKey()
+	}
+
+	refreshed := false
+	for attempt := 0; ; attempt++ {
+		hreq, err := http.NewRequestWithContext(ctx, req.method, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range req.header {
+			hreq.Header[k] = v
+		}
+		switch {
+		case req.upload != nil:
+			hreq.Body = io.NopCloser(req.upload)
+			hreq.Header.Set("Content-Type", req.contentType)
+		case body != nil:
+			hreq.Body = io.NopCloser(bytes.NewReader(body))
+			hreq.ContentLength = int64(len(body))
+			hreq.Header.Set("Content-Type", "application/json")
+		}
+		if hreq.Header.Get("Accept") == "" {
+			hreq.Header.Set("Accept", "application/json")
+		}
+		if c.UserAgent != "" {
+			hreq.Header.Set("User-Agent", c.UserAgent)
+		}
+		var token string
+		if !req.public {
+			token = c.token()
+		}
+		if token != "" {
+			hreq.Header.Set("Authorization", "Bearer "+token)
+			if idempotencyKey != "" {
+				hreq.Header.Set("Idempotency-Key", idempotencyKey)
+			}
+		}
+
+		resp, err := c.HTTPClient.Do(hreq)
+		if err != nil {
+			if ctx.Err() != nil || !c.retryable(req, token, attempt) {
+				return nil, err
+			}
+			if err := c.wait(ctx, attempt, nil); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if resp.StatusCode < 400 {
+			return resp, nil
+		}
+		apiErr := readError(resp)
+
+		// An expired access token: refresh the session once and try again.
+		if resp.StatusCode == http.StatusUnauthorized && !refreshed && token != "" && req.upload == nil && c.canRefresh() {
+			refreshed = true
+			if err := c.refresh(ctx, token); err != nil {
+				return nil, apiErr
+			}
+			attempt--
+			continue
+		}
+		if retryStatus(apiErr) && c.retryable(req, token, attempt) {
+			if err := c.wait(ctx, attempt, resp.Header); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return nil, apiErr
+	}
+}
+
+// retryable reports whether req, sent with token, may be sent again after
+// attempt failed.
+func (c *Client) retryable(req request, token string, attempt int) bool {
+	if attempt >= c.MaxRetries || req.upload != nil {
+		return false
+	}
+	switch req.method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		// The server only remembers the keys of authenticated requests.
+		return token != ""
+	}
+	return false
+}
+
+// retryStatus reports whether e is worth trying again after a wait.
+func retryStatus(e *Error) bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusConflict:
+		return e.Code == apierror.CodeIdempotencyInFlight
+	}
+	return false
+}
+
+// wait sleeps before the retry after attempt, for as long as header's
+// Retry-After asks or else with exponential backoff and jitter.
+func (c *Client) wait(ctx context.Context, attempt int, header http.Header) error {
+	d := c.RetryWait << attempt
+	if c.MaxRetryWait > 0 && d > c.MaxRetryWait {
+		d = c.MaxRetryWait
+	}
+	d = d/2 + rand.N(d/2+1)
+	if s, err := strconv.Atoi(header.Get("Retry-After")); err == nil && s >= 0 {
+		d = time.Duration(s) * time.Second
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// readError reads the error body of resp and closes it.
+func readError(resp *http.Response) *Error {
+	defer resp.Body.Close()
+	e := &Error{StatusCode: resp.StatusCode}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	var b apierror.Body
+	if json.Unmarshal(data, &b) == nil && b.Error.Message != "" {
+		e.Code, e.Message, e.Details, e.TraceID = b.Error.Code, b.Error.Message, b.Error.Details, b.Error.TraceID
+		return e
+	}
+	// GraphQL requests that cannot run answer with GraphQL's errors.
+	var g graphql.Response
+	if json.Unmarshal(data, &g) == nil && len(g.Errors) > 0 {
+		e.Message, e.Details = g.Errors[0].Message, g.Errors
+	}
+	return e
+}
+
+// newKey returns a random Idempotency-Key.
+func newKey() string {
+	b := make([]byte, 16)
+	crand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// escape escapes an ID for use as a path segment.
+func escape(id string) string {
+	return url.PathEscape(id)
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+
+	"starttech-server/model"
+)
+
+// ListComments returns the comments on a task, oldest first.
+func (c *Client) ListComments(ctx context.Context, taskID string) ([]model.Comment, error) {
+	return list[model.Comment](ctx, c, request{method: "GET", path: taskPath(taskID) + "/comments"})
+}
+
+// CreateComment comments on a task, or replies to one of its comments.
+func (c *Client) CreateComment(ctx context.Context, taskID string, in model.CommentInput) (*model.Comment, error) {
+	return call[model.Comment](ctx, c, request{method: "POST", path: taskPath(taskID) + "/comments", body: in})
+}
+
+// GetComment returns a comment on a task.
+func (c *Client) GetComment(ctx context.Context, taskID, id string) (*model.Comment, error) {
+	return call[model.Comment](ctx, c, request{method: "GET", path: commentPath(taskID, id)})
+}
+
+// UpdateComment edits one of the caller's comments.
+func (c *Client) UpdateComment(ctx context.Context, taskID, id string, patch model.CommentPatch) (*model.Comment, error) {
+	return call[model.Comment](ctx, c, request{method: "PATCH", path: commentPath(taskID, id), body: patch})
+}
+
+// DeleteComment deletes a comment; its replies move up a level.
+func (c *Client) DeleteComment(ctx context.Context, taskID, id string) error {
+	return c.do(ctx, request{method: "DELETE", path: commentPath(taskID, id)}, nil)
+}
+
+// CommentHistory returns the earlier versions of an edited comment.
+func (c *Client) CommentHistory(ctx context.Context, taskID, id string) ([]model.CommentEdit, error) {
+	return list[model.CommentEdit](ctx, c, request{method: "GET", path: commentPath(taskID, id) + "/history"})
+}
+
+// RenderMarkdown returns the HTML the server renders text to, as it would
+// for a description or comment.
+func (c *Client) RenderMarkdown(ctx context.Context, text string) (string, error) {
+	out, err := call[model.RenderedMarkdown](ctx, c, request{method: "POST", path: "/markdown", body: model.MarkdownInput{Text: text}})
+	if err != nil {
+		return "", err
+	}
+	return out.HTML, nil
+}
+
+func commentPath(taskID, id string) string {
+	return taskPath(taskID) + "/comments/" + escape(id)
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+
+	"starttech-server/model"
+)
+
+// Dependencies returns the tasks blocking a task and those it blocks.
+func (c *Client) Dependencies(ctx context.Context, taskID string) (*model.Dependencies, error) {
+	return call[model.Dependencies](ctx, c, request{method: "GET", path: taskPath(taskID) + "/dependencies"})
+}
+
+// DependencyGraph returns every task a task depends on, or that depends on
+// it, however indirectly.
+func (c *Client) DependencyGraph(ctx context.Context, taskID string) (*model.DependencyGraph, error) {
+	return call[model.DependencyGraph](ctx, c, request{method: "GET", path: taskPath(taskID) + "/dependencies/graph"})
+}
+
+// AddBlocker records that a task cannot start before blockerID is done.
+func (c *Client) AddBlocker(ctx context.Context, taskID, blockerID string) (*model.Dependencies, error) {
+	return call[model.Dependencies](ctx, c, request{method: "PUT", path: taskPath(taskID) + "/blockers/" + escape(blockerID)})
+}
+
+// RemoveBlocker undoes AddBlocker.
+func (c *Client) RemoveBlocker(ctx context.Context, taskID, blockerID string) (*model.Dependencies, error) {
+	return call[model.Dependencies](ctx, c, request{method: "DELETE", path: taskPath(taskID) + "/blockers/" + escape(blockerID)})
+}
@@ -0,0 +1,14 @@
+package client
+
+import (
+	"context"
+
+	"starttech-server/graphql"
+)
+
+// GraphQL runs a query or mutation. Errors met while running it come in
+// the response; a request that cannot run at all fails with an *Error
+// whose Details are the GraphQL errors.
+func (c *Client) GraphQL(ctx context.Context, req graphql.Request) (*graphql.Response, error) {
+	return call[graphql.Response](ctx, c, request{method: "POST", path: "/graphql", body: req})
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/url"
+
+	"starttech-server/model"
+)
+
+// ImportTrello imports a Trello board, from the JSON export read from r,
+// as a new project. The import runs in the background; poll GetImport for
+// its outcome.
+func (c *Client) ImportTrello(ctx context.Context, r io.Reader) (*model.BoardImport, error) {
+	return call[model.BoardImport](ctx, c, request{method: "POST", path: "/import/trello", upload: r, contentType: "application/json"})
+}
+
+// ImportJira imports Jira issues read from r, as a search result in JSON
+// if contentType is application/json or as an issue navigator export if it
+// is text/csv. Like ImportTrello it runs in the background.
+func (c *Client) ImportJira(ctx context.Context, r io.Reader, contentType string) (*model.BoardImport, error) {
+	return call[model.BoardImport](ctx, c, request{method: "POST", path: "/import/jira", upload: r, contentType: contentType})
+}
+
+// PreviewImport reports what ImportTrello, for source trello, or
+// ImportJira, for source jira, would create from the export read from r,
+// without importing anything.
+func (c *Client) PreviewImport(ctx context.Context, source model.ImportSource, r io.Reader, contentType string) (*model.BoardImportPreview, error) {
+	return call[model.BoardImportPreview](ctx, c, request{
+		method:      "POST",
+		path:        "/import/" + escape(string(source)),
+		query:       url.Values{"dry_run": {"true"}},
+		upload:      r,
+		contentType: contentType,
+	})
+}
+
+// ListImports returns the caller's board imports.
+func (c *Client) ListImports(ctx context.Context) ([]model.BoardImport, error) {
+	return list[model.BoardImport](ctx, c, request{method: "GET", path: "/imports"})
+}
+
+// GetImport returns a board import, with its summary once it is done.
+func (c *Client) GetImport(ctx context.Context, id string) (*model.BoardImport, error) {
+	return call[model.BoardImport](ctx, c, request{method: "GET", path: "/imports/" + escape(id)})
+}
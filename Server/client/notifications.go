@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+
+	"starttech-server/model"
+)
+
+// NotificationPrefs returns which emails the caller receives.
+func (c *Client) NotificationPrefs(ctx context.Context) (*model.NotificationPrefs, error) {
+	return call[model.NotificationPrefs](ctx, c, request{method: "GET", path: "/me/notifications"})
+}
+
+// UpdateNotificationPrefs opts the caller in to or out of kinds of email.
+func (c *Client) UpdateNotificationPrefs(ctx context.Context, patch model.NotificationPrefsPatch) (*model.NotificationPrefs, error) {
+	return call[model.NotificationPrefs](ctx, c, request{method: "PATCH", path: "/me/notifications", body: patch})
+}
+
+// Notifications returns a page of the caller's inbox in the current
+// organization, newest first, or only what they have not read if unread is
+// set.
+func (c *Client) Notifications(ctx context.Context, unread bool, opts PageOptions) (*model.NotificationPage, error) {
+	q := opts.values()
+	if unread {
+		q.Set("unread", "true")
+	}
+	return call[model.NotificationPage](ctx, c, request{method: "GET", path: "/notifications", query: q})
+}
+
+// MarkRead marks a notification read.
+func (c *Client) MarkRead(ctx context.Context, id string) (*model.Notification, error) {
+	return call[model.Notification](ctx, c, request{method: "POST", path: "/notifications/" + escape(id) + "/read"})
+}
+
+// MarkAllRead marks every notification in the current organization read.
+func (c *Client) MarkAllRead(ctx context.Context) error {
+	return c.do(ctx, request{method: "POST", path: "/notifications/read"}, nil)
+}
+
+// CalendarFeed returns the caller's calendar feed, without its token.
+func (c *Client) CalendarFeed(ctx context.Context) (*model.CalendarFeed, error) {
+	return call[model.CalendarFeed](ctx, c, request{method: "GET", path: "/me/calendar"})
+}
+
+// CreateCalendarFeed creates the caller's calendar feed, or replaces its
+// URL. Only the feed returned here carries the URL to subscribe to.
+func (c *Client) CreateCalendarFeed(ctx context.Context) (*model.CalendarFeed, error) {
+	return call[model.CalendarFeed](ctx, c, request{method: "POST", path: "/me/calendar"})
+}
+
+// DeleteCalendarFeed turns the caller's calendar feed off.
+func (c *Client) DeleteCalendarFeed(ctx context.Context) error {
+	return c.do(ctx, request{method: "DELETE", path: "/me/calendar"}, nil)
+}
+
+// InboundAddress returns the caller's email-to-task address, without its
+// token.
+func (c *Client) InboundAddress(ctx context.Context) (*model.InboundAddress, error) {
+	return call[model.InboundAddress](ctx, c, request{method: "GET", path: "/me/inbound-email"})
+}
+
+// CreateInboundAddress creates the caller's email-to-task address, or
+// replaces it. Only the result carries the address to send emails to.
+func (c *Client) CreateInboundAddress(ctx context.Context) (*model.InboundAddress, error) {
+	return call[model.InboundAddress](ctx, c, request{method: "POST", path: "/me/inbound-email"})
+}
+
+// DeleteInboundAddress turns the caller's email-to-task address off.
+func (c *Client) DeleteInboundAddress(ctx context.Context) error {
+	return c.do(ctx, request{method: "DELETE", path: "/me/inbound-email"}, nil)
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+
+	"starttech-server/model"
+)
+
+// ListOrgs returns the caller's organizations, with their role in each.
+func (c *Client) ListOrgs(ctx context.Context) ([]model.Org, error) {
+	return list[model.Org](ctx, c, request{method: "GET", path: "/orgs"})
+}
+
+// CreateOrg creates an organization owned by the caller. SwitchOrg moves
+// the session to it.
+func (c *Client) CreateOrg(ctx context.Context, in model.OrgInput) (*model.Org, error) {
+	return call[model.Org](ctx, c, request{method: "POST", path: "/orgs", body: in})
+}
+
+// GetOrg returns the organization with the given ID.
+func (c *Client) GetOrg(ctx context.Context, id string) (*model.Org, error) {
+	return call[model.Org](ctx, c, request{method: "GET", path: orgPath(id)})
+}
+
+// RenameOrg renames an organization.
+func (c *Client) RenameOrg(ctx context.Context, id string, in model.OrgInput) (*model.Org, error) {
+	return call[model.Org](ctx, c, request{method: "PATCH", path: orgPath(id), body: in})
+}
+
+// ListOrgMembers returns an organization's members.
+func (c *Client) ListOrgMembers(ctx context.Context, id string) ([]model.OrgMembership, error) {
+	return list[model.OrgMembership](ctx, c, request{method: "GET", path: orgPath(id) + "/members"})
+}
+
+// UpdateOrgMember changes a member's role.
+func (c *Client) UpdateOrgMember(ctx context.Context, id, userID string, patch model.OrgMemberPatch) (*model.OrgMembership, error) {
+	return call[model.OrgMembership](ctx, c, request{method: "PATCH", path: orgPath(id) + "/members/" + escape(userID), body: patch})
+}
+
+// RemoveOrgMember removes a member from an organization; callers remove
+// themselves to leave it.
+func (c *Client) RemoveOrgMember(ctx context.Context, id, userID string) error {
+	return c.do(ctx, request{method: "DELETE", path: orgPath(id) + "/members/" + escape(userID)}, nil)
+}
+
+// ListInvitations returns an organization's pending invitations.
+func (c *Client) ListInvitations(ctx context.Context, id string) ([]model.Invitation, error) {
+	return list[model.Invitation](ctx, c, request{method: "GET", path: orgPath(id) + "/invitations"})
+}
+
+// Invite invites somebody to an organization by email. The invitation
+// returned carries the token they accept it with.
+func (c *Client) Invite(ctx context.Context, id string, in model.InvitationInput) (*model.Invitation, error) {
+	return call[model.Invitation](ctx, c, request{method: "POST", path: orgPath(id) + "/invitations", body: in})
+}
+
+// RevokeInvitation revokes a pending invitation.
+func (c *Client) RevokeInvitation(ctx context.Context, id, invitationID string) error {
+	return c.do(ctx, request{method: "DELETE", path: orgPath(id) + "/invitations/" + escape(invitationID)}, nil)
+}
+
+// AcceptInvitation joins the organization an invitation sent to the
+// caller's email is for.
+func (c *Client) AcceptInvitation(ctx context.Context, token string) (*model.OrgMembership, error) {
+	return call[model.OrgMembership](ctx, c, request{method: "POST", path: "/invitations/accept", body: model.AcceptInput{Token: token}})
+}
+
+// ListDomains returns the email domains an organization claims.
+func (c *Client) ListDomains(ctx context.Context, id string) ([]model.Domain, error) {
+	return list[model.Domain](ctx, c, request{method: "GET", path: orgPath(id) + "/domains"})
+}
+
+// AddDomain claims an email domain for an organization. The domain
+// returned names the TXT record that verifies it.
+func (c *Client) AddDomain(ctx context.Context, id, domain string) (*model.Domain, error) {
+	return call[model.Domain](ctx, c, request{method: "POST", path: orgPath(id) + "/domains", body: model.DomainInput{Domain: domain}})
+}
+
+// VerifyDomain checks the TXT record of a domain an organization claims
+// and marks the claim verified if it holds the domain's token.
+func (c *Client) VerifyDomain(ctx context.Context, id, domain string) (*model.Domain, error) {
+	return call[model.Domain](ctx, c, request{method: "POST", path: orgPath(id) + "/domains/" + escape(domain) + "/verify"})
+}
+
+// RemoveDomain drops an organization's claim to an email domain.
+func (c *Client) RemoveDomain(ctx context.Context, id, domain string) error {
+	return c.do(ctx, request{method: "DELETE", path: orgPath(id) + "/domains/" + escape(domain)}, nil)
+}
+
+func orgPath(id string) string {
+	return "/orgs/" + escape(id)
+}
+
+// Usage returns what the organization of the token uses against its
+// quotas. Only its admins and owners may ask.
+func (c *Client) Usage(ctx context.Context) (*model.OrgUsage, error) {
+	return call[model.OrgUsage](ctx, c, request{method: "GET", path: "/usage"})
+}
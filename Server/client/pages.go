@@ -0,0 +1,194 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/url"
+	"strconv"
+
+	"starttech-server/model"
+)
+
+// PageOptions pages through a listing. Listings return at most Limit
+// items, 50 if it is 0, and a cursor to pass as Cursor for the next page.
+type PageOptions struct {
+	Limit  int
+	Cursor string
+}
+
+func (o PageOptions) values() url.Values {
+	q := url.Values{}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Cursor != "" {
+		q.Set("cursor", o.Cursor)
+	}
+	return q
+}
+
+// limitQuery is the query of listings that only take a limit.
+func limitQuery(limit int) url.Values {
+	if limit <= 0 {
+		return nil
+	}
+	return url.Values{"limit": {strconv.Itoa(limit)}}
+}
+
+// iterate yields the items of every page fetch returns, starting at the
+// cursor start, until a page has no next cursor. It stops at the first
+// error, which it yields with a zero item.
+func iterate[T any](fetch func(cursor string) ([]T, string, error), start string) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		cursor := start
+		for {
+			items, next, err := fetch(cursor)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if next == "" {
+				return
+			}
+			cursor = next
+		}
+	}
+}
+
+// taskPages iterates over the tasks of the listing at path.
+func (c *Client) taskPages(ctx context.Context, path string, opts TaskListOptions) iter.Seq2[model.Task, error] {
+	return iterate(func(cursor string) ([]model.Task, string, error) {
+		opts.Cursor = cursor
+		p, err := call[model.TaskPage](ctx, c, request{method: "GET", path: path, query: opts.values()})
+		if err != nil {
+			return nil, "", err
+		}
+		return p.Items, p.NextCursor, nil
+	}, opts.Cursor)
+}
+
+// AllTasks iterates over every task ListTasks would return, page by page;
+// opts.Limit sets the size of the pages.
+func (c *Client) AllTasks(ctx context.Context, opts TaskListOptions) iter.Seq2[model.Task, error] {
+	return c.taskPages(ctx, "/tasks", opts)
+}
+
+// AllTrash iterates over the tasks in the trash.
+func (c *Client) AllTrash(ctx context.Context, opts TaskListOptions) iter.Seq2[model.Task, error] {
+	return c.taskPages(ctx, "/trash", opts)
+}
+
+// AllSubtasks iterates over the subtasks of a task.
+func (c *Client) AllSubtasks(ctx context.Context, id string, opts TaskListOptions) iter.Seq2[model.Task, error] {
+	return c.taskPages(ctx, taskPath(id)+"/subtasks", opts)
+}
+
+// AllProjectTasks iterates over the tasks of a project.
+func (c *Client) AllProjectTasks(ctx context.Context, id string, opts TaskListOptions) iter.Seq2[model.Task, error] {
+	opts.ProjectID = ""
+	return c.taskPages(ctx, projectPath(id)+"/tasks", opts)
+}
+
+// AllViewTasks iterates over the tasks a saved view matches.
+func (c *Client) AllViewTasks(ctx context.Context, id string, opts PageOptions) iter.Seq2[model.Task, error] {
+	return iterate(func(cursor string) ([]model.Task, string, error) {
+		opts.Cursor = cursor
+		p, err := c.ViewTasks(ctx, id, opts)
+		if err != nil {
+			return nil, "", err
+		}
+		return p.Items, p.NextCursor, nil
+	}, opts.Cursor)
+}
+
+// AllSearch iterates over every match of a search, best first.
+func (c *Client) AllSearch(ctx context.Context, q string, opts TaskListOptions) iter.Seq2[model.SearchHit, error] {
+	return iterate(func(cursor string) ([]model.SearchHit, string, error) {
+		opts.Cursor = cursor
+		p, err := c.Search(ctx, q, opts)
+		if err != nil {
+			return nil, "", err
+		}
+		return p.Items, p.NextCursor, nil
+	}, opts.Cursor)
+}
+
+// AllRevisions iterates over the revisions of a task, newest first.
+func (c *Client) AllRevisions(ctx context.Context, id string, opts PageOptions) iter.Seq2[model.Revision, error] {
+	return iterate(func(cursor string) ([]model.Revision, string, error) {
+		opts.Cursor = cursor
+		p, err := c.Revisions(ctx, id, opts)
+		if err != nil {
+			return nil, "", err
+		}
+		return p.Items, p.NextCursor, nil
+	}, opts.Cursor)
+}
+
+// AllTaskActivity iterates over the activity of a task, newest first.
+func (c *Client) AllTaskActivity(ctx context.Context, id string, opts PageOptions) iter.Seq2[model.Activity, error] {
+	return iterate(func(cursor string) ([]model.Activity, string, error) {
+		opts.Cursor = cursor
+		p, err := c.TaskActivity(ctx, id, opts)
+		if err != nil {
+			return nil, "", err
+		}
+		return p.Items, p.NextCursor, nil
+	}, opts.Cursor)
+}
+
+// AllProjectActivity iterates over the activity of a project, newest first.
+func (c *Client) AllProjectActivity(ctx context.Context, id string, opts PageOptions) iter.Seq2[model.Activity, error] {
+	return iterate(func(cursor string) ([]model.Activity, string, error) {
+		opts.Cursor = cursor
+		p, err := c.ProjectActivity(ctx, id, opts)
+		if err != nil {
+			return nil, "", err
+		}
+		return p.Items, p.NextCursor, nil
+	}, opts.Cursor)
+}
+
+// AllNotifications iterates over the user's notifications, newest first.
+func (c *Client) AllNotifications(ctx context.Context, unread bool, opts PageOptions) iter.Seq2[model.Notification, error] {
+	return iterate(func(cursor string) ([]model.Notification, string, error) {
+		opts.Cursor = cursor
+		p, err := c.Notifications(ctx, unread, opts)
+		if err != nil {
+			return nil, "", err
+		}
+		return p.Items, p.NextCursor, nil
+	}, opts.Cursor)
+}
+
+// AllAdminUsers iterates over the users whose email or username contains
+// q, or every user if q is empty.
+func (c *Client) AllAdminUsers(ctx context.Context, q string, opts PageOptions) iter.Seq2[model.User, error] {
+	return iterate(func(cursor string) ([]model.User, string, error) {
+		opts.Cursor = cursor
+		p, err := c.AdminUsers(ctx, q, opts)
+		if err != nil {
+			return nil, "", err
+		}
+		return p.Items, p.NextCursor, nil
+	}, opts.Cursor)
+}
+
+// AllAdminOrgs iterates over the organizations whose name contains q, or
+// every organization if q is empty.
+func (c *Client) AllAdminOrgs(ctx context.Context, q string, opts PageOptions) iter.Seq2[model.AdminOrg, error] {
+	return iterate(func(cursor string) ([]model.AdminOrg, string, error) {
+		opts.Cursor = cursor
+		p, err := c.AdminOrgs(ctx, q, opts)
+		if err != nil {
+			return nil, "", err
+		}
+		return p.Items, p.NextCursor, nil
+	}, opts.Cursor)
+}
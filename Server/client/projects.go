@@ -0,0 +1,242 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"starttech-server/model"
+)
+
+// ListProjects returns the projects the caller belongs to, or the archived
+// ones if archived is set.
+func (c *Client) ListProjects(ctx context.Context, archived bool) ([]model.Project, error) {
+	var q url.Values
+	if archived {
+		q = url.Values{"archived": {"true"}}
+	}
+	return list[model.Project](ctx, c, request{method: "GET", path: "/projects", query: q})
+}
+
+// CreateProject creates a project owned by the caller.
+func (c *Client) CreateProject(ctx context.Context, in model.ProjectInput) (*model.Project, error) {
+	return call[model.Project](ctx, c, request{method: "POST", path: "/projects", body: in})
+}
+
+// GetProject returns the project with the given ID.
+func (c *Client) GetProject(ctx context.Context, id string) (*model.Project, error) {
+	return call[model.Project](ctx, c, request{method: "GET", path: projectPath(id)})
+}
+
+// ReplaceProject replaces every field of a project with those of in.
+func (c *Client) ReplaceProject(ctx context.Context, id string, in model.ProjectInput) (*model.Project, error) {
+	return call[model.Project](ctx, c, request{method: "PUT", path: projectPath(id), body: in})
+}
+
+// UpdateProject changes the fields of a project that patch sets.
+func (c *Client) UpdateProject(ctx context.Context, id string, patch model.ProjectPatch) (*model.Project, error) {
+	return call[model.Project](ctx, c, request{method: "PATCH", path: projectPath(id), body: patch})
+}
+
+// DeleteProject deletes a project.
+func (c *Client) DeleteProject(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: "DELETE", path: projectPath(id)}, nil)
+}
+
+// ArchiveProject moves a project out of the list, with its tasks.
+func (c *Client) ArchiveProject(ctx context.Context, id string) (*model.Project, error) {
+	return call[model.Project](ctx, c, request{method: "POST", path: projectPath(id) + "/archive"})
+}
+
+// UnarchiveProject brings an archived project back into the list.
+func (c *Client) UnarchiveProject(ctx context.Context, id string) (*model.Project, error) {
+	return call[model.Project](ctx, c, request{method: "POST", path: projectPath(id) + "/unarchive"})
+}
+
+// ListProjectTasks returns a page of a project's tasks in board order.
+// opts.ProjectID is ignored.
+func (c *Client) ListProjectTasks(ctx context.Context, id string, opts TaskListOptions) (*model.TaskPage, error) {
+	opts.ProjectID = ""
+	return call[model.TaskPage](ctx, c, request{method: "GET", path: projectPath(id) + "/tasks", query: opts.values()})
+}
+
+// OrderProject sets the order of every task in a project, first to last.
+func (c *Client) OrderProject(ctx context.Context, id string, taskIDs []string) error {
+	return c.do(ctx, request{method: "PUT", path: projectPath(id) + "/order", body: model.TaskOrder{TaskIDs: taskIDs}}, nil)
+}
+
+// ExportProject returns every task of a project as import records.
+func (c *Client) ExportProject(ctx context.Context, id string) ([]model.TaskRecord, error) {
+	return list[model.TaskRecord](ctx, c, request{method: "GET", path: projectPath(id) + "/export"})
+}
+
+// ExportProjectCSV writes every task of a project to w as CSV, with a
+// header row.
+func (c *Client) ExportProjectCSV(ctx context.Context, id string, w io.Writer) error {
+	req := request{
+		method: "GET",
+		path:   projectPath(id) + "/export",
+		query:  url.Values{"format": {"csv"}},
+		header: http.Header{"Accept": {"text/csv"}},
+	}
+	return c.download(ctx, req, w)
+}
+
+// ImportOptions controls how ImportProject and ImportProjectCSV create
+// tasks.
+type ImportOptions struct {
+	// Columns reads a field, such as "title", from a differently named
+	// CSV column.
+	Columns map[string]string
+	// DryRun reports what would be created without creating it.
+	DryRun bool
+	// KeepDuplicates imports rows matching an existing task, which are
+	// skipped otherwise.
+	KeepDuplicates bool
+}
+
+func (o ImportOptions) values() url.Values {
+	q := url.Values{}
+	for field, column := range o.Columns {
+		q.Add("map", field+":"+column)
+	}
+	if o.DryRun {
+		q.Set("dry_run", "true")
+	}
+	if o.KeepDuplicates {
+		q.Set("duplicates", "keep")
+	}
+	return q
+}
+
+// ImportProject creates tasks in a project from records. If any record is
+// invalid nothing is created, and the error has status 422.
+func (c *Client) ImportProject(ctx context.Context, id string, records []model.TaskRecord, opts ImportOptions) (*model.ImportResult, error) {
+	return call[model.ImportResult](ctx, c, request{method: "POST", path: projectPath(id) + "/import", query: opts.values(), body: records})
+}
+
+// ImportProjectCSV is ImportProject for CSV read from r, with a header row
+// naming the columns.
+func (c *Client) ImportProjectCSV(ctx context.Context, id string, r io.Reader, opts ImportOptions) (*model.ImportResult, error) {
+	return call[model.ImportResult](ctx, c, request{
+		method:      "POST",
+		path:        projectPath(id) + "/import",
+		query:       opts.values(),
+		upload:      r,
+		contentType: "text/csv",
+	})
+}
+
+// ListMembers returns who a project is shared with.
+func (c *Client) ListMembers(ctx context.Context, projectID string) ([]model.Member, error) {
+	return list[model.Member](ctx, c, request{method: "GET", path: projectPath(projectID) + "/members"})
+}
+
+// AddMember shares a project with a user.
+func (c *Client) AddMember(ctx context.Context, projectID string, in model.MemberInput) (*model.Member, error) {
+	return call[model.Member](ctx, c, request{method: "POST", path: projectPath(projectID) + "/members", body: in})
+}
+
+// UpdateMember changes a member's role.
+func (c *Client) UpdateMember(ctx context.Context, projectID, userID string, patch model.MemberPatch) (*model.Member, error) {
+	return call[model.Member](ctx, c, request{method: "PATCH", path: projectPath(projectID) + "/members/" + escape(userID), body: patch})
+}
+
+// RemoveMember removes a member from a project; callers remove themselves
+// to leave it.
+func (c *Client) RemoveMember(ctx context.Context, projectID, userID string) error {
+	return c.do(ctx, request{method: "DELETE", path: projectPath(projectID) + "/members/" + escape(userID)}, nil)
+}
+
+// ListFields returns the custom fields of a project's tasks.
+func (c *Client) ListFields(ctx context.Context, projectID string) ([]model.CustomField, error) {
+	return list[model.CustomField](ctx, c, request{method: "GET", path: projectPath(projectID) + "/fields"})
+}
+
+// CreateField defines a custom field for a project's tasks.
+func (c *Client) CreateField(ctx context.Context, projectID string, in model.CustomFieldInput) (*model.CustomField, error) {
+	return call[model.CustomField](ctx, c, request{method: "POST", path: projectPath(projectID) + "/fields", body: in})
+}
+
+// UpdateField renames a custom field or changes its options.
+func (c *Client) UpdateField(ctx context.Context, projectID, fieldID string, patch model.CustomFieldPatch) (*model.CustomField, error) {
+	return call[model.CustomField](ctx, c, request{method: "PATCH", path: projectPath(projectID) + "/fields/" + escape(fieldID), body: patch})
+}
+
+// DeleteField deletes a custom field and its value on every task.
+func (c *Client) DeleteField(ctx context.Context, projectID, fieldID string) error {
+	return c.do(ctx, request{method: "DELETE", path: projectPath(projectID) + "/fields/" + escape(fieldID)}, nil)
+}
+
+// ListRules returns the automation rules of a project.
+func (c *Client) ListRules(ctx context.Context, projectID string) ([]model.Rule, error) {
+	return list[model.Rule](ctx, c, request{method: "GET", path: projectPath(projectID) + "/rules"})
+}
+
+// GetRule returns an automation rule of a project.
+func (c *Client) GetRule(ctx context.Context, projectID, ruleID string) (*model.Rule, error) {
+	return call[model.Rule](ctx, c, request{method: "GET", path: projectPath(projectID) + "/rules/" + escape(ruleID)})
+}
+
+// CreateRule defines an automation rule for a project's tasks.
+func (c *Client) CreateRule(ctx context.Context, projectID string, in model.RuleInput) (*model.Rule, error) {
+	return call[model.Rule](ctx, c, request{method: "POST", path: projectPath(projectID) + "/rules", body: in})
+}
+
+// UpdateRule changes an automation rule, or enables or disables it.
+func (c *Client) UpdateRule(ctx context.Context, projectID, ruleID string, patch model.RulePatch) (*model.Rule, error) {
+	return call[model.Rule](ctx, c, request{method: "PATCH", path: projectPath(projectID) + "/rules/" + escape(ruleID), body: patch})
+}
+
+// DeleteRule deletes an automation rule.
+func (c *Client) DeleteRule(ctx context.Context, projectID, ruleID string) error {
+	return c.do(ctx, request{method: "DELETE", path: projectPath(projectID) + "/rules/" + escape(ruleID)}, nil)
+}
+
+// ProjectActivity returns a page of who changed what in a project, newest
+// first.
+func (c *Client) ProjectActivity(ctx context.Context, id string, opts PageOptions) (*model.ActivityPage, error) {
+	return call[model.ActivityPage](ctx, c, request{method: "GET", path: projectPath(id) + "/activity", query: opts.values()})
+}
+
+// GitHubLink returns the GitHub repository a project is linked to.
+func (c *Client) GitHubLink(ctx context.Context, projectID string) (*model.GitHubLink, error) {
+	return call[model.GitHubLink](ctx, c, request{method: "GET", path: projectPath(projectID) + "/github"})
+}
+
+// LinkGitHub links a project to a GitHub repository. The webhook secret is
+// only returned here.
+func (c *Client) LinkGitHub(ctx context.Context, projectID string, in model.GitHubLinkInput) (*model.GitHubLink, error) {
+	return call[model.GitHubLink](ctx, c, request{method: "PUT", path: projectPath(projectID) + "/github", body: in})
+}
+
+// UnlinkGitHub unlinks a project from its repository, keeping its tasks.
+func (c *Client) UnlinkGitHub(ctx context.Context, projectID string) error {
+	return c.do(ctx, request{method: "DELETE", path: projectPath(projectID) + "/github"}, nil)
+}
+
+// SyncGitHub imports the linked repository's issues as tasks, or updates
+// the tasks imported before.
+func (c *Client) SyncGitHub(ctx context.Context, projectID string) (*model.GitHubSyncResult, error) {
+	return call[model.GitHubSyncResult](ctx, c, request{method: "POST", path: projectPath(projectID) + "/github/sync"})
+}
+
+// SlackLink returns the Slack channel a project is linked to.
+func (c *Client) SlackLink(ctx context.Context, projectID string) (*model.SlackLink, error) {
+	return call[model.SlackLink](ctx, c, request{method: "GET", path: projectPath(projectID) + "/slack"})
+}
+
+// LinkSlack links a project to a Slack channel's incoming webhook.
+func (c *Client) LinkSlack(ctx context.Context, projectID string, in model.SlackLinkInput) (*model.SlackLink, error) {
+	return call[model.SlackLink](ctx, c, request{method: "PUT", path: projectPath(projectID) + "/slack", body: in})
+}
+
+// UnlinkSlack unlinks a project from Slack.
+func (c *Client) UnlinkSlack(ctx context.Context, projectID string) error {
+	return c.do(ctx, request{method: "DELETE", path: projectPath(projectID) + "/slack"}, nil)
+}
+
+func projectPath(id string) string {
+	return "/projects/" + escape(id)
+}
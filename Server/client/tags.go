@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+
+	"starttech-server/model"
+)
+
+// ListTags returns the caller's tags.
+func (c *Client) ListTags(ctx context.Context) ([]model.Tag, error) {
+	return list[model.Tag](ctx, c, request{method: "GET", path: "/tags"})
+}
+
+// CreateTag creates a tag.
+func (c *Client) CreateTag(ctx context.Context, in model.TagInput) (*model.Tag, error) {
+	return call[model.Tag](ctx, c, request{method: "POST", path: "/tags", body: in})
+}
+
+// GetTag returns the tag with the given ID.
+func (c *Client) GetTag(ctx context.Context, id string) (*model.Tag, error) {
+	return call[model.Tag](ctx, c, request{method: "GET", path: "/tags/" + escape(id)})
+}
+
+// UpdateTag changes the fields of a tag that patch sets.
+func (c *Client) UpdateTag(ctx context.Context, id string, patch model.TagPatch) (*model.Tag, error) {
+	return call[model.Tag](ctx, c, request{method: "PATCH", path: "/tags/" + escape(id), body: patch})
+}
+
+// DeleteTag deletes a tag, detaching it from its tasks.
+func (c *Client) DeleteTag(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: "DELETE", path: "/tags/" + escape(id)}, nil)
+}
@@ -0,0 +1,238 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"starttech-server/model"
+)
+
+// TaskListOptions narrows and orders a task listing, as the query
+// parameters of GET /tasks do. Zero fields are left to the server.
+type TaskListOptions struct {
+	Limit  int
+	Cursor string
+	// Offset skips rows when there is no cursor.
+	Offset int
+	// Sort is a sort key, such as "due_date" or "field:" and the ID of a
+	// custom field, prefixed with "-" for descending order.
+	Sort      string
+	Status    model.Status
+	ProjectID string
+	// Assignee is a user ID, "me" or "none".
+	Assignee  string
+	DueBefore time.Time
+	DueAfter  time.Time
+	// TagIDs lists tags every task must have.
+	TagIDs []string
+	// Archived, if set, lists only archived tasks or only the others.
+	Archived *bool
+	// Fields keeps the tasks whose custom fields, by ID, hold these
+	// values, written as in a query: 3.5, 2025-07-01 or true.
+	Fields map[string]string
+}
+
+func (o TaskListOptions) values() url.Values {
+	q := url.Values{}
+	set := func(k, v string) {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	set("cursor", o.Cursor)
+	if o.Offset > 0 {
+		q.Set("offset", strconv.Itoa(o.Offset))
+	}
+	set("sort", o.Sort)
+	set("status", string(o.Status))
+	set("project_id", o.ProjectID)
+	set("assignee", o.Assignee)
+	if !o.DueBefore.IsZero() {
+		q.Set("due_before", o.DueBefore.Format(time.RFC3339))
+	}
+	if !o.DueAfter.IsZero() {
+		q.Set("due_after", o.DueAfter.Format(time.RFC3339))
+	}
+	for _, id := range o.TagIDs {
+		q.Add("tag", id)
+	}
+	if o.Archived != nil {
+		q.Set("archived", strconv.FormatBool(*o.Archived))
+	}
+	for id, v := range o.Fields {
+		q.Set("field."+id, v)
+	}
+	return q
+}
+
+// ListTasks returns a page of the tasks the caller can see. Pass the
+// page's NextCursor as opts.Cursor for the next, or use AllTasks.
+func (c *Client) ListTasks(ctx context.Context, opts TaskListOptions) (*model.TaskPage, error) {
+	return call[model.TaskPage](ctx, c, request{method: "GET", path: "/tasks", query: opts.values()})
+}
+
+// Search returns a page of the tasks matching q, best first. opts.Sort is
+// ignored.
+func (c *Client) Search(ctx context.Context, q string, opts TaskListOptions) (*model.SearchPage, error) {
+	opts.Sort = ""
+	query := opts.values()
+	query.Set("q", q)
+	return call[model.SearchPage](ctx, c, request{method: "GET", path: "/search", query: query})
+}
+
+// GetTask returns the task with the given ID.
+func (c *Client) GetTask(ctx context.Context, id string) (*model.Task, error) {
+	return call[model.Task](ctx, c, request{method: "GET", path: taskPath(id)})
+}
+
+// CreateTask creates a task.
+func (c *Client) CreateTask(ctx context.Context, in model.TaskInput) (*model.Task, error) {
+	return call[model.Task](ctx, c, request{method: "POST", path: "/tasks", body: in})
+}
+
+// ReplaceTask replaces every field of a task with those of in.
+func (c *Client) ReplaceTask(ctx context.Context, id string, in model.TaskInput) (*model.Task, error) {
+	return call[model.Task](ctx, c, request{method: "PUT", path: taskPath(id), body: in})
+}
+
+// ReplaceTaskIfVersion is ReplaceTask that fails with 412 Precondition
+// Failed if the task has changed since it was at version.
+func (c *Client) ReplaceTaskIfVersion(ctx context.Context, id string, version int64, in model.TaskInput) (*model.Task, error) {
+	return call[model.Task](ctx, c, request{method: "PUT", path: taskPath(id), header: ifMatch(version), body: in})
+}
+
+// UpdateTask changes the fields of a task that patch sets.
+func (c *Client) UpdateTask(ctx context.Context, id string, patch model.TaskPatch) (*model.Task, error) {
+	return call[model.Task](ctx, c, request{method: "PATCH", path: taskPath(id), body: patch})
+}
+
+// UpdateTaskIfVersion is UpdateTask that fails with 412 Precondition
+// Failed if the task has changed since it was at version.
+func (c *Client) UpdateTaskIfVersion(ctx context.Context, id string, version int64, patch model.TaskPatch) (*model.Task, error) {
+	return call[model.Task](ctx, c, request{method: "PATCH", path: taskPath(id), header: ifMatch(version), body: patch})
+}
+
+// CompleteTask marks a task done.
+func (c *Client) CompleteTask(ctx context.Context, id string) (*model.Task, error) {
+	done := true
+	return c.UpdateTask(ctx, id, model.TaskPatch{Completed: &done})
+}
+
+// DeleteTask moves a task to the trash. Its subtasks move up a level,
+// unless cascade is set, which trashes them too.
+func (c *Client) DeleteTask(ctx context.Context, id string, cascade bool) error {
+	var q url.Values
+	if cascade {
+		q = url.Values{"children": {"cascade"}}
+	}
+	return c.do(ctx, request{method: "DELETE", path: taskPath(id), query: q}, nil)
+}
+
+// RestoreTask brings a task back from the trash.
+func (c *Client) RestoreTask(ctx context.Context, id string) (*model.Task, error) {
+	return call[model.Task](ctx, c, request{method: "POST", path: taskPath(id) + "/restore"})
+}
+
+// ListTrash returns a page of the tasks in the trash.
+func (c *Client) ListTrash(ctx context.Context, opts TaskListOptions) (*model.TaskPage, error) {
+	return call[model.TaskPage](ctx, c, request{method: "GET", path: "/trash", query: opts.values()})
+}
+
+// Revisions returns a page of the earlier versions of a task, newest
+// first.
+func (c *Client) Revisions(ctx context.Context, id string, opts PageOptions) (*model.RevisionPage, error) {
+	return call[model.RevisionPage](ctx, c, request{method: "GET", path: taskPath(id) + "/revisions", query: opts.values()})
+}
+
+// RevertTask restores the fields a task had at a revision.
+func (c *Client) RevertTask(ctx context.Context, id string, revision int64) (*model.Task, error) {
+	return call[model.Task](ctx, c, request{method: "POST", path: taskPath(id) + "/revert/" + strconv.FormatInt(revision, 10)})
+}
+
+// ArchiveTask hides a task from listings without deleting it.
+func (c *Client) ArchiveTask(ctx context.Context, id string) (*model.Task, error) {
+	return call[model.Task](ctx, c, request{method: "POST", path: taskPath(id) + "/archive"})
+}
+
+// UnarchiveTask brings an archived task back into listings.
+func (c *Client) UnarchiveTask(ctx context.Context, id string) (*model.Task, error) {
+	return call[model.Task](ctx, c, request{method: "POST", path: taskPath(id) + "/unarchive"})
+}
+
+// MoveTask moves a task to another column of its board, or another place
+// in its column.
+func (c *Client) MoveTask(ctx context.Context, id string, in model.MoveInput) (*model.Task, error) {
+	return call[model.Task](ctx, c, request{method: "PATCH", path: taskPath(id) + "/move", body: in})
+}
+
+// ReorderTask puts a task before or after another task of its project,
+// keeping its column.
+func (c *Client) ReorderTask(ctx context.Context, id string, in model.ReorderInput) (*model.Task, error) {
+	return call[model.Task](ctx, c, request{method: "POST", path: taskPath(id) + "/reorder", body: in})
+}
+
+// ListSubtasks returns a page of the subtasks of a task.
+func (c *Client) ListSubtasks(ctx context.Context, id string, opts TaskListOptions) (*model.TaskPage, error) {
+	return call[model.TaskPage](ctx, c, request{method: "GET", path: taskPath(id) + "/subtasks", query: opts.values()})
+}
+
+// CreateSubtask creates a task below another.
+func (c *Client) CreateSubtask(ctx context.Context, id string, in model.TaskInput) (*model.Task, error) {
+	return call[model.Task](ctx, c, request{method: "POST", path: taskPath(id) + "/subtasks", body: in})
+}
+
+// Rollup returns how far the subtasks below a task are done.
+func (c *Client) Rollup(ctx context.Context, id string) (*model.Rollup, error) {
+	return call[model.Rollup](ctx, c, request{method: "GET", path: taskPath(id) + "/rollup"})
+}
+
+// TagTask attaches a tag to a task.
+func (c *Client) TagTask(ctx context.Context, id, tagID string) (*model.Task, error) {
+	return call[model.Task](ctx, c, request{method: "PUT", path: taskPath(id) + "/tags/" + escape(tagID)})
+}
+
+// UntagTask detaches a tag from a task.
+func (c *Client) UntagTask(ctx context.Context, id, tagID string) (*model.Task, error) {
+	return call[model.Task](ctx, c, request{method: "DELETE", path: taskPath(id) + "/tags/" + escape(tagID)})
+}
+
+// AssignTask assigns a task to the user with the given ID, or to the
+// caller for "me".
+func (c *Client) AssignTask(ctx context.Context, id, userID string) (*model.Task, error) {
+	return call[model.Task](ctx, c, request{method: "PUT", path: taskPath(id) + "/assignee", body: model.AssignInput{UserID: userID}})
+}
+
+// UnassignTask leaves a task unassigned.
+func (c *Client) UnassignTask(ctx context.Context, id string) (*model.Task, error) {
+	return call[model.Task](ctx, c, request{method: "DELETE", path: taskPath(id) + "/assignee"})
+}
+
+// Bulk applies several operations to tasks in one request.
+func (c *Client) Bulk(ctx context.Context, in model.BulkInput) (*model.BulkResult, error) {
+	return call[model.BulkResult](ctx, c, request{method: "POST", path: "/tasks/bulk", body: in})
+}
+
+// BulkDryRun reports what Bulk would do with in, without doing it.
+func (c *Client) BulkDryRun(ctx context.Context, in model.BulkInput) (*model.BulkResult, error) {
+	return call[model.BulkResult](ctx, c, request{method: "POST", path: "/tasks/bulk", query: url.Values{"dry_run": {"true"}}, body: in})
+}
+
+// TaskActivity returns a page of who changed what on a task, newest first.
+func (c *Client) TaskActivity(ctx context.Context, id string, opts PageOptions) (*model.ActivityPage, error) {
+	return call[model.ActivityPage](ctx, c, request{method: "GET", path: taskPath(id) + "/activity", query: opts.values()})
+}
+
+func taskPath(id string) string {
+	return "/tasks/" + escape(id)
+}
+
+// ifMatch is the header that makes a change conditional on version.
+func ifMatch(version int64) http.Header {
+	return http.Header{"If-Match": {`"` + strconv.FormatInt(version, 10) + `"`}}
+}
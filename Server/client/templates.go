@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+
+	"starttech-server/model"
+)
+
+// SaveTaskTemplate saves a task and its subtasks as a template.
+func (c *Client) SaveTaskTemplate(ctx context.Context, taskID string, in model.TemplateInput) (*model.Template, error) {
+	return call[model.Template](ctx, c, request{method: "POST", path: taskPath(taskID) + "/template", body: in})
+}
+
+// SaveProjectTemplate saves a project, its columns and tasks as a
+// template.
+func (c *Client) SaveProjectTemplate(ctx context.Context, projectID string, in model.TemplateInput) (*model.Template, error) {
+	return call[model.Template](ctx, c, request{method: "POST", path: projectPath(projectID) + "/template", body: in})
+}
+
+// ListTemplates returns the caller's templates.
+func (c *Client) ListTemplates(ctx context.Context) ([]model.Template, error) {
+	return list[model.Template](ctx, c, request{method: "GET", path: "/templates"})
+}
+
+// GetTemplate returns the template with the given ID.
+func (c *Client) GetTemplate(ctx context.Context, id string) (*model.Template, error) {
+	return call[model.Template](ctx, c, request{method: "GET", path: "/templates/" + escape(id)})
+}
+
+// UpdateTemplate changes the fields of a template that patch sets.
+func (c *Client) UpdateTemplate(ctx context.Context, id string, patch model.TemplatePatch) (*model.Template, error) {
+	return call[model.Template](ctx, c, request{method: "PATCH", path: "/templates/" + escape(id), body: patch})
+}
+
+// DeleteTemplate deletes a template.
+func (c *Client) DeleteTemplate(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: "DELETE", path: "/templates/" + escape(id)}, nil)
+}
+
+// UseTemplate makes a project or tasks from a template.
+func (c *Client) UseTemplate(ctx context.Context, id string, in model.TemplateUse) (*model.TemplateResult, error) {
+	return call[model.TemplateResult](ctx, c, request{method: "POST", path: "/templates/" + escape(id) + "/use", body: in})
+}
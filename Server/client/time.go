@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"starttech-server/model"
+)
+
+// StartTimer starts the caller's timer on a task, or returns the one
+// already running there.
+func (c *Client) StartTimer(ctx context.Context, taskID string) (*model.TimeEntry, error) {
+	return call[model.TimeEntry](ctx, c, request{method: "POST", path: taskPath(taskID) + "/timer/start"})
+}
+
+// StopTimer stops the caller's timer on a task.
+func (c *Client) StopTimer(ctx context.Context, taskID string) (*model.TimeEntry, error) {
+	return call[model.TimeEntry](ctx, c, request{method: "POST", path: taskPath(taskID) + "/timer/stop"})
+}
+
+// RunningTimer returns the caller's running timer, on whichever task.
+func (c *Client) RunningTimer(ctx context.Context) (*model.TimeEntry, error) {
+	return call[model.TimeEntry](ctx, c, request{method: "GET", path: "/me/timer"})
+}
+
+// ListTimeEntries returns the time logged on a task.
+func (c *Client) ListTimeEntries(ctx context.Context, taskID string) ([]model.TimeEntry, error) {
+	return list[model.TimeEntry](ctx, c, request{method: "GET", path: taskPath(taskID) + "/time-entries"})
+}
+
+// LogTime records time spent on a task after the fact.
+func (c *Client) LogTime(ctx context.Context, taskID string, in model.TimeEntryInput) (*model.TimeEntry, error) {
+	return call[model.TimeEntry](ctx, c, request{method: "POST", path: taskPath(taskID) + "/time-entries", body: in})
+}
+
+// UpdateTimeEntry changes the fields of a time entry that patch sets.
+func (c *Client) UpdateTimeEntry(ctx context.Context, taskID, id string, patch model.TimeEntryPatch) (*model.TimeEntry, error) {
+	return call[model.TimeEntry](ctx, c, request{method: "PATCH", path: taskPath(taskID) + "/time-entries/" + escape(id), body: patch})
+}
+
+// DeleteTimeEntry deletes a time entry.
+func (c *Client) DeleteTimeEntry(ctx context.Context, taskID, id string) error {
+	return c.do(ctx, request{method: "DELETE", path: taskPath(taskID) + "/time-entries/" + escape(id)}, nil)
+}
+
+// TimeTotalsOptions selects the time TimeTotals sums. Zero fields are left
+// to the server.
+type TimeTotalsOptions struct {
+	// By groups the totals by model.TimeByTask, TimeByProject or
+	// TimeByUser.
+	By string
+	// From and To bound when the time was logged; To is exclusive.
+	From, To  time.Time
+	ProjectID string
+	// User is a user ID, or "me".
+	User string
+}
+
+// TimeTotals sums the time logged on the tasks the caller can see.
+func (c *Client) TimeTotals(ctx context.Context, opts TimeTotalsOptions) (*model.TimeTotals, error) {
+	q := url.Values{}
+	set := func(k, v string) {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	set("by", opts.By)
+	if !opts.From.IsZero() {
+		q.Set("from", opts.From.Format(time.RFC3339))
+	}
+	if !opts.To.IsZero() {
+		q.Set("to", opts.To.Format(time.RFC3339))
+	}
+	set("project_id", opts.ProjectID)
+	set("user", opts.User)
+	return call[model.TimeTotals](ctx, c, request{method: "GET", path: "/time/totals", query: q})
+}
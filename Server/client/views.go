@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+
+	"starttech-server/model"
+)
+
+// ListViews returns the caller's saved views.
+func (c *Client) ListViews(ctx context.Context) ([]model.View, error) {
+	return list[model.View](ctx, c, request{method: "GET", path: "/views"})
+}
+
+// CreateView saves a view.
+func (c *Client) CreateView(ctx context.Context, in model.ViewInput) (*model.View, error) {
+	return call[model.View](ctx, c, request{method: "POST", path: "/views", body: in})
+}
+
+// GetView returns the view with the given ID.
+func (c *Client) GetView(ctx context.Context, id string) (*model.View, error) {
+	return call[model.View](ctx, c, request{method: "GET", path: "/views/" + escape(id)})
+}
+
+// UpdateView changes the fields of a view that patch sets.
+func (c *Client) UpdateView(ctx context.Context, id string, patch model.ViewPatch) (*model.View, error) {
+	return call[model.View](ctx, c, request{method: "PATCH", path: "/views/" + escape(id), body: patch})
+}
+
+// DeleteView deletes a view.
+func (c *Client) DeleteView(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: "DELETE", path: "/views/" + escape(id)}, nil)
+}
+
+// ViewTasks returns a page of the tasks a view matches, in its order.
+func (c *Client) ViewTasks(ctx context.Context, id string, opts PageOptions) (*model.TaskPage, error) {
+	return call[model.TaskPage](ctx, c, request{method: "GET", path: "/views/" + escape(id) + "/tasks", query: opts.values()})
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+
+	"starttech-server/model"
+)
+
+// ListWebhooks returns the caller's webhooks.
+func (c *Client) ListWebhooks(ctx context.Context) ([]model.Webhook, error) {
+	return list[model.Webhook](ctx, c, request{method: "GET", path: "/webhooks"})
+}
+
+// CreateWebhook subscribes a URL to events. The signing secret is only
+// returned here.
+func (c *Client) CreateWebhook(ctx context.Context, in model.WebhookInput) (*model.Webhook, error) {
+	return call[model.Webhook](ctx, c, request{method: "POST", path: "/webhooks", body: in})
+}
+
+// GetWebhook returns the webhook with the given ID.
+func (c *Client) GetWebhook(ctx context.Context, id string) (*model.Webhook, error) {
+	return call[model.Webhook](ctx, c, request{method: "GET", path: webhookPath(id)})
+}
+
+// UpdateWebhook changes the fields of a webhook that patch sets.
+func (c *Client) UpdateWebhook(ctx context.Context, id string, patch model.WebhookPatch) (*model.Webhook, error) {
+	return call[model.Webhook](ctx, c, request{method: "PATCH", path: webhookPath(id), body: patch})
+}
+
+// DeleteWebhook deletes a webhook.
+func (c *Client) DeleteWebhook(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: "DELETE", path: webhookPath(id)}, nil)
+}
+
+// ListDeliveries returns a webhook's latest deliveries, at most limit of
+// them, or 50 if limit is 0.
+func (c *Client) ListDeliveries(ctx context.Context, id string, limit int) ([]model.Delivery, error) {
+	return list[model.Delivery](ctx, c, request{method: "GET", path: webhookPath(id) + "/deliveries", query: limitQuery(limit)})
+}
+
+// ReplayDelivery sends a delivery again.
+func (c *Client) ReplayDelivery(ctx context.Context, id, deliveryID string) (*model.Delivery, error) {
+	return call[model.Delivery](ctx, c, request{method: "POST", path: webhookPath(id) + "/deliveries/" + escape(deliveryID) + "/replay"})
+}
+
+func webhookPath(id string) string {
+	return "/webhooks/" + escape(id)
+}
@@ -0,0 +1,375 @@
+// Command taskctl manages tasks from the command line through the API,
+// authenticating with an API key:
+//
+//	taskctl login -url https://tasks.example.com/api/v1 -key stk_...
+//	taskctl use Website
+//	taskctl create -priority high -due 2025-07-01 Fix the footer
+//	taskctl list -assignee me
+//	taskctl assign 3f2a... me
+//	taskctl complete 3f2a...
+//
+// The server, key and current project are kept in taskctl/config.json
+// under the user's configuration directory. TASKCTL_URL and TASKCTL_API_KEY
+// override the saved server and key.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"starttech-server/client"
+	"starttech-server/model"
+)
+
+const usage = `usage: taskctl <command> [flags] [args]
+
+commands:
+  login -url URL -key KEY   save the API's URL and an API key
+  projects                  list your projects; * marks the current one
+  use PROJECT               switch to a project, by ID or name; "none" leaves it
+  list                      list the tasks of the current project, or all with -all
+  create TITLE...           create a task in the current project
+  complete ID...            mark tasks done
+  assign ID USER            assign a task to a user ID, or "me"
+  unassign ID               unassign a task
+
+Run taskctl <command> -h for a command's flags.
+`
+
+// errUsage reports a mistake on the command line, already explained.
+var errUsage = errors.New("usage")
+
+// settings is what taskctl remembers between runs.
+type settings struct {
+	URL       string `json:"url"`
+	APIKey    string `json:"api_key"`
+	ProjectID string `json:"project_id,omitempty"`
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := run(ctx, os.Args[1], os.Args[2:], os.Stdout)
+	stop()
+	switch {
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "taskctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, command string, args []string, out io.Writer) error {
+	path, err := settingsPath()
+	if err != nil {
+		return err
+	}
+	saved, err := loadSettings(path)
+	if err != nil {
+		return err
+	}
+	s := saved.withEnv()
+
+	fs := flag.NewFlagSet("taskctl "+command, flag.ContinueOnError)
+	switch command {
+	case "login":
+		url := fs.String("url", s.URL, "root of the API, such as https://tasks.example.com/api/v1")
+		key := fs.String("key", "", "API key, created with POST /settings/api-keys")
+		if err := parse(fs, args, 0); err != nil {
+			return err
+		}
+		if *url == "" || *key == "" {
+			fmt.Fprintln(os.Stderr, "taskctl login: -url and -key are required")
+			return errUsage
+		}
+		saved.URL, saved.APIKey = *url, *key
+		if _, err := newClient(saved).ListProjects(ctx, false); err != nil {
+			return err
+		}
+		if err := saveSettings(path, saved); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "Logged in to", saved.URL)
+		return nil
+
+	case "projects":
+		if err := parse(fs, args, 0); err != nil {
+			return err
+		}
+		c, err := connect(s)
+		if err != nil {
+			return err
+		}
+		projects, err := c.ListProjects(ctx, false)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "\tID\tNAME")
+		for _, p := range projects {
+			mark := ""
+			if p.ID == s.ProjectID {
+				mark = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", mark, p.ID, p.Name)
+		}
+		return w.Flush()
+
+	case "use":
+		if err := parse(fs, args, 1); err != nil {
+			return err
+		}
+		if name := fs.Arg(0); name == "none" {
+			saved.ProjectID = ""
+		} else {
+			c, err := connect(s)
+			if err != nil {
+				return err
+			}
+			p, err := findProject(ctx, c, name)
+			if err != nil {
+				return err
+			}
+			saved.ProjectID = p.ID
+			fmt.Fprintf(out, "Switched to %s\n", p.Name)
+		}
+		return saveSettings(path, saved)
+
+	case "list":
+		all := fs.Bool("all", false, "list tasks of every project, and those in none")
+		status := fs.String("status", "", "only tasks with this status")
+		assignee := fs.String("assignee", "", `only tasks assigned to this user ID, "me" or "none"`)
+		limit := fs.Int("limit", 50, "most tasks to list")
+		if err := parse(fs, args, 0); err != nil {
+			return err
+		}
+		c, err := connect(s)
+		if err != nil {
+			return err
+		}
+		opts := client.TaskListOptions{Status: model.Status(*status), Assignee: *assignee, Limit: min(*limit, 200)}
+		listed := c.AllTasks(ctx, opts)
+		if s.ProjectID != "" && !*all {
+			listed = c.AllProjectTasks(ctx, s.ProjectID, opts)
+		}
+		var tasks []model.Task
+		for t, err := range listed {
+			if err != nil {
+				return err
+			}
+			if tasks = append(tasks, t); len(tasks) >= *limit {
+				break
+			}
+		}
+		return printTasks(out, tasks)
+
+	case "create":
+		description := fs.String("d", "", "description")
+		priority := fs.String("priority", "", "none, low, medium, high or urgent")
+		due := fs.String("due", "", "due date, as YYYY-MM-DD")
+		assign := fs.String("assign", "", `user ID to assign the task to, or "me"`)
+		if err := parse(fs, args, -1); err != nil {
+			return err
+		}
+		in := model.TaskInput{
+			Title:       strings.Join(fs.Args(), " "),
+			Description: *description,
+			Priority:    model.Priority(*priority),
+		}
+		if *due != "" {
+			d, err := time.ParseInLocation(time.DateOnly, *due, time.Local)
+			if err != nil {
+				return fmt.Errorf("-due: %w", err)
+			}
+			in.DueDate = &d
+		}
+		if *assign != "" {
+			in.AssigneeID = assign
+		}
+		if s.ProjectID != "" {
+			in.ProjectID = &s.ProjectID
+		}
+		c, err := connect(s)
+		if err != nil {
+			return err
+		}
+		t, err := c.CreateTask(ctx, in)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, t.ID)
+		return nil
+
+	case "complete":
+		if err := parse(fs, args, -1); err != nil {
+			return err
+		}
+		c, err := connect(s)
+		if err != nil {
+			return err
+		}
+		for _, id := range fs.Args() {
+			t, err := c.CompleteTask(ctx, id)
+			if err != nil {
+				return fmt.Errorf("%s: %w", id, err)
+			}
+			fmt.Fprintf(out, "Completed %s\n", t.Title)
+		}
+		return nil
+
+	case "assign", "unassign":
+		n := 2
+		if command == "unassign" {
+			n = 1
+		}
+		if err := parse(fs, args, n); err != nil {
+			return err
+		}
+		c, err := connect(s)
+		if err != nil {
+			return err
+		}
+		var t *model.Task
+		if command == "assign" {
+			t, err = c.AssignTask(ctx, fs.Arg(0), fs.Arg(1))
+		} else {
+			t, err = c.UnassignTask(ctx, fs.Arg(0))
+		}
+		if err != nil {
+			return err
+		}
+		return printTasks(out, []model.Task{*t})
+
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(out, usage)
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "taskctl: unknown command %q\n\n%s", command, usage)
+	return errUsage
+}
+
+// parse parses args with fs, which must leave n arguments, or at least one
+// if n is negative.
+func parse(fs *flag.FlagSet, args []string, n int) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if (n < 0 && fs.NArg() == 0) || (n >= 0 && fs.NArg() != n) {
+		fmt.Fprintf(os.Stderr, "%s: wrong number of arguments\n", fs.Name())
+		return errUsage
+	}
+	return nil
+}
+
+func findProject(ctx context.Context, c *client.Client, name string) (*model.Project, error) {
+	projects, err := c.ListProjects(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	var found *model.Project
+	for i, p := range projects {
+		if p.ID == name {
+			return &projects[i], nil
+		}
+		if strings.EqualFold(p.Name, name) {
+			if found != nil {
+				return nil, fmt.Errorf("more than one project is named %q; use its ID", name)
+			}
+			found = &projects[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no project %q", name)
+	}
+	return found, nil
+}
+
+func printTasks(out io.Writer, tasks []model.Task) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tPRIORITY\tDUE\tASSIGNEE\tTITLE")
+	for _, t := range tasks {
+		due, assignee := "-", "-"
+		if t.DueDate != nil {
+			due = t.DueDate.Local().Format(time.DateOnly)
+		}
+		if t.AssigneeID != nil {
+			assignee = *t.AssigneeID
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Status, t.Priority, due, assignee, t.Title)
+	}
+	return w.Flush()
+}
+
+func connect(s settings) (*client.Client, error) {
+	if s.URL == "" || s.APIKey == "" {
+		return nil, errors.New("not logged in; run taskctl login, or set TASKCTL_URL and TASKCTL_API_KEY")
+	}
+	return newClient(s), nil
+}
+
+func newClient(s settings) *client.Client {
+	c := client.New(s.URL, s.APIKey)
+	c.UserAgent = "taskctl"
+	return c
+}
+
+func settingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "taskctl", "config.json"), nil
+}
+
+// loadSettings reads the saved settings, if any.
+func loadSettings(path string) (settings, error) {
+	var s settings
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &s); err != nil {
+			return s, fmt.Errorf("%s: %w", path, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return s, err
+	}
+	return s, nil
+}
+
+// withEnv returns s with the server and key of the environment, if set.
+func (s settings) withEnv() settings {
+	if v := os.Getenv("TASKCTL_URL"); v != "" {
+		s.URL = v
+	}
+	if v := os.Getenv("TASKCTL_API_KEY"); v != "" {
+		s.APIKey = v
+	}
+	return s
+}
+
+// saveSettings writes s where only the user can read it, since it holds
+// the API key.
+func saveSettings(path string, s settings) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
@@ -0,0 +1,590 @@
+// Package config assembles the server configuration from, in increasing
+// order of precedence, built-in defaults, an optional TOML file,
+// environment variables and command-line flags.
+//
+// Every setting is a field of Config tagged with its file key, environment
+// variable and flag name:
+//
+//	Port int `toml:"port" env:"PORT" flag:"port" usage:"..."`
+//
+// Nested structs become TOML tables, so the field above, inside Server, is
+// written as
+//
+//	[server]
+//	port = 8080
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Config is the complete server configuration.
+type Config struct {
+	Server       Server       `toml:"server"`
+	TLS          TLS          `toml:"tls"`
+	GRPC         GRPC         `toml:"grpc"`
+	Database     Database     `toml:"database"`
+	Auth         Auth         `toml:"auth"`
+	OAuth        OAuth        `toml:"oauth"`
+	CORS         CORS         `toml:"cors"`
+	Log          Log          `toml:"log"`
+	Tracing      Tracing      `toml:"tracing"`
+	Scheduler    Scheduler    `toml:"scheduler"`
+	SMTP         SMTP         `toml:"smtp"`
+	InboundEmail InboundEmail `toml:"inbound_email"`
+	Webhooks     Webhooks     `toml:"webhooks"`
+	GitHub       GitHub       `toml:"github"`
+	Jobs         Jobs         `toml:"jobs"`
+	Attachments  Attachments  `toml:"attachments"`
+	Tasks        Tasks        `toml:"tasks"`
+	Rules        Rules        `toml:"rules"`
+	Trash        Trash        `toml:"trash"`
+	Shares       Shares       `toml:"shares"`
+	Flags        Flags        `toml:"flags"`
+	Idempotency  Idempotency  `toml:"idempotency"`
+	Audit        Audit        `toml:"audit"`
+	Accounts     Accounts     `toml:"accounts"`
+	RateLimit    RateLimit    `toml:"rate_limit"`
+	Quotas       Quotas       `toml:"quotas"`
+	Cache        Cache        `toml:"cache"`
+	Realtime     Realtime     `toml:"realtime"`
+	Outbox       Outbox       `toml:"outbox"`
+	Admin        Admin        `toml:"admin"`
+	Debug        Debug        `toml:"debug"`
+}
+
+type Server struct {
+	Port                 int           `toml:"port" env:"PORT" flag:"port" usage:"TCP port to listen on"`
+	ReadTimeout          time.Duration `toml:"read_timeout" env:"HTTP_READ_TIMEOUT" usage:"maximum time to read a request"`
+	WriteTimeout         time.Duration `toml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" usage:"maximum time to write a response"`
+	IdleTimeout          time.Duration `toml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" usage:"how long keep-alive connections may idle"`
+	ShutdownTimeout      time.Duration `toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"how long to drain requests on shutdown"`
+	MaxBodySize          int64         `toml:"max_body_size" env:"HTTP_MAX_BODY_SIZE" usage:"largest request body accepted, in bytes, except by uploads and imports"`
+	CompressMinSize      int           `toml:"compress_min_size" env:"HTTP_COMPRESS_MIN_SIZE" usage:"smallest response compressed, in bytes; 0 disables compression"`
+	H2C                  bool          `toml:"h2c" env:"HTTP_H2C" usage:"serve HTTP/2 without TLS (h2c) to clients that speak it from the start"`
+	MaxConcurrentStreams int           `toml:"max_concurrent_streams" env:"HTTP2_MAX_CONCURRENT_STREAMS" usage:"most requests a client may have in flight on one HTTP/2 connection"`
+	PingInterval         time.Duration `toml:"ping_interval" env:"HTTP2_PING_INTERVAL" usage:"how long an HTTP/2 connection may be silent before it is pinged, and closed if the ping goes unanswered; 0 disables pings"`
+	TCPKeepAlive         time.Duration `toml:"tcp_keepalive" env:"HTTP_TCP_KEEPALIVE" usage:"interval of TCP keep-alive probes on accepted connections; negative disables them"`
+}
+
+// TLS serves HTTPS on server.port. With Domains set, certificates are
+// obtained from Let's Encrypt and renewed automatically; otherwise CertFile
+// and KeyFile are used. HTTPPort serves plain HTTP alongside, redirecting to
+// HTTPS and answering Let's Encrypt's challenges.
+type TLS struct {
+	Domains  []string `toml:"domains" env:"TLS_DOMAINS" usage:"comma-separated domains to get Let's Encrypt certificates for"`
+	Email    string   `toml:"email" env:"TLS_EMAIL" usage:"contact address given to Let's Encrypt for expiry notices"`
+	CacheDir string   `toml:"cache_dir" env:"TLS_CACHE_DIR" usage:"directory Let's Encrypt certificates are kept in across restarts"`
+	CertFile string   `toml:"cert_file" env:"TLS_CERT_FILE" usage:"PEM certificate chain to serve when no domains are set"`
+	KeyFile  string   `toml:"key_file" env:"TLS_KEY_FILE" usage:"PEM private key of cert_file"`
+	HTTPPort int      `toml:"http_port" env:"TLS_HTTP_PORT" usage:"TCP port redirecting plain HTTP to HTTPS; 0 disables it"`
+}
+
+// Enabled reports whether HTTPS is configured.
+func (t TLS) Enabled() bool {
+	return len(t.Domains) > 0 || t.CertFile != ""
+}
+
+type GRPC struct {
+	Port int `toml:"port" env:"GRPC_PORT" flag:"grpc-port" usage:"TCP port of the gRPC API; 0 disables it"`
+}
+
+type Database struct {
+	URL             string        `toml:"url" env:"DATABASE_URL" flag:"database-url" usage:"sqlite:// or postgres:// URL; empty keeps data in memory"`
+	MaxOpenConns    int           `toml:"max_open_conns" env:"DATABASE_MAX_OPEN_CONNS" usage:"most connections open at once; 0 is unlimited"`
+	MaxIdleConns    int           `toml:"max_idle_conns" env:"DATABASE_MAX_IDLE_CONNS" usage:"most idle connections kept open for reuse"`
+	ConnMaxLifetime time.Duration `toml:"conn_max_lifetime" env:"DATABASE_CONN_MAX_LIFETIME" usage:"how long a connection is used before it is replaced; 0 keeps it"`
+	ConnMaxIdleTime time.Duration `toml:"conn_max_idle_time" env:"DATABASE_CONN_MAX_IDLE_TIME" usage:"how long a connection may idle before it is closed; 0 keeps it"`
+	QueryTimeout    time.Duration `toml:"query_timeout" env:"DATABASE_QUERY_TIMEOUT" usage:"longest a single statement may run; 0 is unbounded"`
+	MigrateOnStart  bool          `toml:"migrate_on_start" env:"DATABASE_MIGRATE_ON_START" usage:"apply pending schema migrations at startup instead of refusing to start"`
+}
+
+// Persistent reports whether URL names a database that outlives the
+// process, rather than the in-memory store or an in-memory SQLite one.
+func (d Database) Persistent() bool {
+	if d.URL == "" {
+		return false
+	}
+	dsn, sqlite := strings.CutPrefix(d.URL, "sqlite://")
+	return !sqlite || !(strings.Contains(dsn, ":memory:") || strings.Contains(dsn, "mode=memory"))
+}
+
+type Auth struct {
+	JWTSecret  string        `toml:"jwt_secret" env:"JWT_SECRET" usage:"key used to sign access tokens; required with a persistent database"`
+	TokenTTL   time.Duration `toml:"token_ttl" env:"JWT_TTL" usage:"lifetime of access tokens"`
+	RefreshTTL time.Duration `toml:"refresh_ttl" env:"REFRESH_TOKEN_TTL" usage:"how long a session lasts without its refresh token being used"`
+}
+
+// OAuth lets users sign in with Google or GitHub. A provider is offered
+// once both its client ID and secret are set. Setting BaseURL also lets
+// organizations sign their people in through their own identity provider.
+type OAuth struct {
+	BaseURL            string `toml:"base_url" env:"OAUTH_BASE_URL" usage:"public URL of the API, such as https://tasks.example.com/api/v1, that providers send users back to; also turns on single sign-on"`
+	SuccessURL         string `toml:"success_url" env:"OAUTH_SUCCESS_URL" usage:"page browsers are sent to once signed in; empty answers with the session as JSON"`
+	GoogleClientID     string `toml:"google_client_id" env:"OAUTH_GOOGLE_CLIENT_ID" usage:"client ID of the Google OAuth client"`
+	GoogleClientSecret string `toml:"google_client_secret" env:"OAUTH_GOOGLE_CLIENT_SECRET" usage:"client secret of the Google OAuth client"`
+	GitHubClientID     string `toml:"github_client_id" env:"OAUTH_GITHUB_CLIENT_ID" usage:"client ID of the GitHub OAuth app"`
+	GitHubClientSecret string `toml:"github_client_secret" env:"OAUTH_GITHUB_CLIENT_SECRET" usage:"client secret of the GitHub OAuth app"`
+}
+
+// Enabled reports whether any provider is configured.
+func (o OAuth) Enabled() bool {
+	return o.GoogleClientID != "" || o.GitHubClientID != ""
+}
+
+type CORS struct {
+	AllowedOrigins   []string `toml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS" usage:"comma-separated origins allowed to call the API"`
+	AllowCredentials bool     `toml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS" usage:"let listed origins send cookies"`
+}
+
+type Scheduler struct {
+	Interval time.Duration `toml:"interval" env:"SCHEDULER_INTERVAL" usage:"how often to check for due reminders"`
+}
+
+type SMTP struct {
+	Host     string `toml:"host" env:"SMTP_HOST" usage:"mail server for notification emails; empty logs them instead"`
+	Port     int    `toml:"port" env:"SMTP_PORT" usage:"mail server submission port"`
+	Username string `toml:"username" env:"SMTP_USERNAME" usage:"mail server login, if it requires one"`
+	Password string `toml:"password" env:"SMTP_PASSWORD" usage:"mail server password"`
+	From     string `toml:"from" env:"SMTP_FROM" usage:"sender address of notification emails"`
+}
+
+// InboundEmail turns emails sent to users' private addresses at Domain
+// into tasks. The mail server that receives them posts each message to
+// POST /integrations/email with Secret.
+type InboundEmail struct {
+	Domain  string `toml:"domain" env:"INBOUND_EMAIL_DOMAIN" usage:"domain of the addresses emails become tasks through, such as tasks.example.com; empty disables email-to-task"`
+	Secret  string `toml:"secret" env:"INBOUND_EMAIL_SECRET" usage:"shared secret the mail server sends to POST /integrations/email"`
+	MaxSize int64  `toml:"max_size" env:"INBOUND_EMAIL_MAX_SIZE" usage:"largest message accepted, attachments included, in bytes"`
+}
+
+type Webhooks struct {
+	Timeout     time.Duration `toml:"timeout" env:"WEBHOOK_TIMEOUT" usage:"how long to wait for a webhook endpoint to respond"`
+	MaxAttempts int           `toml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS" usage:"delivery attempts before giving up"`
+	// AllowPrivate lets webhooks point at loopback, private and link-local
+	// addresses, which are refused by default so that users cannot reach
+	// the server's own network through them.
+	AllowPrivate bool `toml:"allow_private" env:"WEBHOOK_ALLOW_PRIVATE" usage:"let webhooks deliver to loopback, private and link-local addresses; for development only"`
+}
+
+// GitHub configures the integration that links projects to GitHub
+// repositories.
+type GitHub struct {
+	APIURL string `toml:"api_url" env:"GITHUB_API_URL" usage:"root of the GitHub REST API; GitHub Enterprise serves it under /api/v3"`
+}
+
+// Jobs sizes the background job queue, which sends emails and webhook
+// deliveries, fires reminders and purges expired records.
+type Jobs struct {
+	Workers     int           `toml:"workers" env:"JOBS_WORKERS" usage:"background jobs run at once"`
+	MaxAttempts int           `toml:"max_attempts" env:"JOBS_MAX_ATTEMPTS" usage:"attempts at a job before it is marked dead"`
+	Retention   time.Duration `toml:"retention" env:"JOBS_RETENTION" usage:"how long finished and dead jobs are kept; 0 keeps them"`
+}
+
+type Attachments struct {
+	Backend      string        `toml:"backend" env:"ATTACHMENTS_BACKEND" usage:"where uploaded files are kept: disk or s3"`
+	Dir          string        `toml:"dir" env:"ATTACHMENTS_DIR" usage:"directory of the disk backend"`
+	MaxSize      int64         `toml:"max_size" env:"ATTACHMENTS_MAX_SIZE" usage:"largest upload accepted, in bytes"`
+	AllowedTypes []string      `toml:"allowed_types" env:"ATTACHMENTS_ALLOWED_TYPES" usage:"comma-separated media types accepted, such as image/*; empty accepts any"`
+	URLTTL       time.Duration `toml:"url_ttl" env:"ATTACHMENTS_URL_TTL" usage:"how long signed download links stay valid"`
+	ClamAV       string        `toml:"clamav" env:"ATTACHMENTS_CLAMAV" usage:"clamd socket uploads are scanned with, a unix socket path or host:port; empty skips scanning"`
+	ScanTimeout  time.Duration `toml:"scan_timeout" env:"ATTACHMENTS_SCAN_TIMEOUT" usage:"how long a virus scan may take"`
+	S3           S3            `toml:"s3"`
+}
+
+type S3 struct {
+	Endpoint  string `toml:"endpoint" env:"S3_ENDPOINT" usage:"base URL of the S3-compatible service"`
+	Region    string `toml:"region" env:"S3_REGION" usage:"region the bucket is in"`
+	Bucket    string `toml:"bucket" env:"S3_BUCKET" usage:"bucket attachments are stored in"`
+	AccessKey string `toml:"access_key" env:"S3_ACCESS_KEY" usage:"access key ID"`
+	SecretKey string `toml:"secret_key" env:"S3_SECRET_KEY" usage:"secret access key"`
+	PathStyle bool   `toml:"path_style" env:"S3_PATH_STYLE" usage:"address the bucket in the path rather than the host name"`
+}
+
+type Tasks struct {
+	BlockCompletion bool          `toml:"block_completion" env:"TASKS_BLOCK_COMPLETION" usage:"refuse to complete a task while a task blocking it is open"`
+	ArchiveAfter    time.Duration `toml:"archive_after" env:"TASKS_ARCHIVE_AFTER" usage:"archive completed tasks left unchanged this long; 0 never does"`
+	UndoWindow      time.Duration `toml:"undo_window" env:"TASKS_UNDO_WINDOW" usage:"how long a change to tasks can be undone; 0 turns undo off"`
+}
+
+type Rules struct {
+	Interval time.Duration `toml:"interval" env:"RULES_INTERVAL" usage:"how often project automation rules are evaluated"`
+}
+
+type Trash struct {
+	Retention time.Duration `toml:"retention" env:"TRASH_RETENTION" usage:"how long deleted tasks can be restored before they are purged; 0 keeps them"`
+}
+
+type Shares struct {
+	ExpiredRetention time.Duration `toml:"expired_retention" env:"SHARES_EXPIRED_RETENTION" usage:"how long expired share links stay listed before they are deleted; 0 keeps them"`
+}
+
+type Flags struct {
+	Refresh time.Duration `toml:"refresh" env:"FLAGS_REFRESH" usage:"how often feature flags are read again, so that changes made on other instances apply"`
+}
+
+type Idempotency struct {
+	TTL time.Duration `toml:"ttl" env:"IDEMPOTENCY_TTL" usage:"how long the response to a request with an Idempotency-Key is replayed to retries"`
+}
+
+type Audit struct {
+	Retention time.Duration `toml:"retention" env:"AUDIT_RETENTION" usage:"how long audit log events are kept; 0 keeps them"`
+}
+
+type Accounts struct {
+	DeletionGrace time.Duration `toml:"deletion_grace" env:"ACCOUNTS_DELETION_GRACE" usage:"how long after asking for their account to be deleted a user can still restore it"`
+	EraseContent  bool          `toml:"erase_content" env:"ACCOUNTS_ERASE_CONTENT" usage:"delete the comments and attachments of deleted accounts instead of keeping them under an anonymous name"`
+}
+
+// RateLimit sizes the request buckets of each group: ip counts every
+// request by client address, auth the sign-up and sign-in attempts of an
+// address, share the views of share links from an address, and user the
+// API calls of a signed-in user. A group whose burst is 0 is not limited.
+type RateLimit struct {
+	Backend        string `toml:"backend" env:"RATE_LIMIT_BACKEND" usage:"where buckets are kept: memory, redis, or off to disable rate limiting"`
+	RedisURL       string `toml:"redis_url" env:"RATE_LIMIT_REDIS_URL" usage:"redis:// URL of the server shared by the redis backend"`
+	TrustProxy     bool   `toml:"trust_proxy" env:"RATE_LIMIT_TRUST_PROXY" usage:"take client addresses from the X-Forwarded-For header of a reverse proxy"`
+	IPPerMinute    int    `toml:"ip_per_minute" env:"RATE_LIMIT_IP_PER_MINUTE" usage:"requests a minute from one address"`
+	IPBurst        int    `toml:"ip_burst" env:"RATE_LIMIT_IP_BURST" usage:"requests from one address allowed at once"`
+	AuthPerMinute  int    `toml:"auth_per_minute" env:"RATE_LIMIT_AUTH_PER_MINUTE" usage:"sign-up and sign-in attempts a minute from one address"`
+	AuthBurst      int    `toml:"auth_burst" env:"RATE_LIMIT_AUTH_BURST" usage:"sign-up and sign-in attempts from one address allowed at once"`
+	SharePerMinute int    `toml:"share_per_minute" env:"RATE_LIMIT_SHARE_PER_MINUTE" usage:"views of share links a minute from one address"`
+	ShareBurst     int    `toml:"share_burst" env:"RATE_LIMIT_SHARE_BURST" usage:"views of share links from one address allowed at once"`
+	UserPerMinute  int    `toml:"user_per_minute" env:"RATE_LIMIT_USER_PER_MINUTE" usage:"API requests a minute by one user"`
+	UserBurst      int    `toml:"user_burst" env:"RATE_LIMIT_USER_BURST" usage:"API requests by one user allowed at once"`
+}
+
+// Quotas caps what each organization uses. Zero leaves a quota off.
+type Quotas struct {
+	TasksPerProject int   `toml:"tasks_per_project" env:"QUOTA_TASKS_PER_PROJECT" usage:"tasks a project may hold, trashed ones included; 0 for no limit"`
+	AttachmentBytes int64 `toml:"attachment_bytes" env:"QUOTA_ATTACHMENT_BYTES" usage:"bytes of attachments an organization may store; 0 for no limit"`
+	RequestsPerDay  int64 `toml:"requests_per_day" env:"QUOTA_REQUESTS_PER_DAY" usage:"API requests an organization may make in a UTC day; 0 for no limit"`
+}
+
+// Cache keeps the task lists of projects and user profiles out of the
+// database for a while.
+type Cache struct {
+	Backend  string        `toml:"backend" env:"CACHE_BACKEND" usage:"where cached reads are kept: memory, redis, or off to read everything from the database"`
+	RedisURL string        `toml:"redis_url" env:"CACHE_REDIS_URL" usage:"redis:// URL of the server used by the redis backend"`
+	TaskTTL  time.Duration `toml:"task_ttl" env:"CACHE_TASK_TTL" usage:"how long the task lists of a project are cached"`
+	UserTTL  time.Duration `toml:"user_ttl" env:"CACHE_USER_TTL" usage:"how long user profiles are cached"`
+}
+
+// Realtime chooses how events reach the clients of other server
+// instances.
+type Realtime struct {
+	Backend string `toml:"backend" env:"REALTIME_BACKEND" usage:"how events reach clients: memory for this instance's only, or redis or nats to relay them to every instance"`
+	URL     string `toml:"url" env:"REALTIME_URL" usage:"redis:// or nats:// URL of the server relaying events"`
+	Channel string `toml:"channel" env:"REALTIME_CHANNEL" usage:"Redis channel or NATS subject events are relayed on"`
+}
+
+type Outbox struct {
+	Interval time.Duration `toml:"interval" env:"OUTBOX_INTERVAL" usage:"how often the outbox is checked for events left by other instances"`
+}
+
+// Debug serves profiles and runtime variables without authentication, for
+// tools such as go tool pprof that cannot send a token.
+type Debug struct {
+	Addr string `toml:"addr" env:"DEBUG_ADDR" usage:"listen address of the unauthenticated pprof and expvar endpoints, such as 127.0.0.1:6060; keep it off public networks. Empty disables it"`
+}
+
+type Admin struct {
+	Emails         []string `toml:"emails" env:"ADMIN_EMAILS" usage:"comma-separated emails of the users allowed to use the /admin routes"`
+	MaxRestoreSize int64    `toml:"max_restore_size" env:"ADMIN_MAX_RESTORE_SIZE" usage:"largest backup archive POST /admin/restore accepts, in bytes"`
+}
+
+type Log struct {
+	Level  string `toml:"level" env:"LOG_LEVEL" flag:"log-level" usage:"debug, info, warn or error"`
+	Format string `toml:"format" env:"LOG_FORMAT" flag:"log-format" usage:"json or text"`
+}
+
+// Tracing exports OpenTelemetry spans of requests, queries, jobs and
+// webhook deliveries.
+type Tracing struct {
+	Endpoint    string   `toml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" usage:"base URL of the OTLP/HTTP collector spans are sent to, such as http://localhost:4318; empty turns tracing off"`
+	Headers     []string `toml:"headers" env:"OTEL_EXPORTER_OTLP_HEADERS" usage:"comma-separated key=value headers sent with every export, such as an API key"`
+	ServiceName string   `toml:"service_name" env:"OTEL_SERVICE_NAME" usage:"service.name the spans are exported under"`
+	SampleRatio float64  `toml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" usage:"share of the traces started here that are recorded, from 0 to 1"`
+}
+
+// Default returns the configuration used when nothing is overridden.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Port:                 8080,
+			ReadTimeout:          15 * time.Second,
+			WriteTimeout:         30 * time.Second,
+			IdleTimeout:          120 * time.Second,
+			ShutdownTimeout:      20 * time.Second,
+			MaxBodySize:          1 << 20,
+			CompressMinSize:      1024,
+			MaxConcurrentStreams: 250,
+			TCPKeepAlive:         15 * time.Second,
+		},
+		TLS:  TLS{CacheDir: "data/certs", HTTPPort: 80},
+		GRPC: GRPC{Port: 9090},
+		Database: Database{
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			QueryTimeout:    30 * time.Second,
+		},
+		Auth:         Auth{TokenTTL: 15 * time.Minute, RefreshTTL: 30 * 24 * time.Hour},
+		CORS:         CORS{AllowedOrigins: []string{"*"}},
+		Log:          Log{Level: "info", Format: "json"},
+		Scheduler:    Scheduler{Interval: 30 * time.Second},
+		SMTP:         SMTP{Port: 587, From: "Starttech <no-reply@localhost>"},
+		InboundEmail: InboundEmail{MaxSize: 30 << 20},
+		Webhooks:     Webhooks{Timeout: 10 * time.Second, MaxAttempts: 8},
+		GitHub:       GitHub{APIURL: "https://api.github.com"},
+		Jobs:         Jobs{Workers: 4, MaxAttempts: 5, Retention: 7 * 24 * time.Hour},
+		Admin:        Admin{MaxRestoreSize: 1 << 30},
+		Attachments: Attachments{
+			Backend: "disk",
+			Dir:     "data/attachments",
+			MaxSize: 25 << 20,
+			AllowedTypes: []string{
+				"image/*", "audio/*", "video/*", "text/plain", "text/csv", "text/markdown", "application/pdf",
+				"application/zip", "application/json", "application/vnd.openxmlformats-officedocument.*",
+				"application/vnd.oasis.opendocument.*", "application/msword", "application/vnd.ms-excel",
+			},
+			URLTTL:      15 * time.Minute,
+			ScanTimeout: time.Minute,
+			S3:          S3{Region: "us-east-1"},
+		},
+		Tasks:       Tasks{BlockCompletion: true, UndoWindow: 10 * time.Minute},
+		Rules:       Rules{Interval: 5 * time.Minute},
+		Trash:       Trash{Retention: 30 * 24 * time.Hour},
+		Shares:      Shares{ExpiredRetention: 7 * 24 * time.Hour},
+		Flags:       Flags{Refresh: 30 * time.Second},
+		Idempotency: Idempotency{TTL: 24 * time.Hour},
+		Audit:       Audit{Retention: 365 * 24 * time.Hour},
+		Accounts:    Accounts{DeletionGrace: 30 * 24 * time.Hour},
+		RateLimit: RateLimit{
+			Backend:        "memory",
+			IPPerMinute:    1200,
+			IPBurst:        200,
+			AuthPerMinute:  10,
+			AuthBurst:      10,
+			SharePerMinute: 60,
+			ShareBurst:     20,
+			UserPerMinute:  600,
+			UserBurst:      100,
+		},
+		Tracing:  Tracing{ServiceName: "starttech", SampleRatio: 1},
+		Cache:    Cache{Backend: "off", TaskTTL: time.Minute, UserTTL: 5 * time.Minute},
+		Realtime: Realtime{Backend: "memory", Channel: "starttech.events"},
+		Outbox:   Outbox{Interval: 5 * time.Second},
+	}
+}
+
+// Addr is the listen address for the HTTP server.
+func (c Config) Addr() string {
+	return fmt.Sprintf(":%d", c.Server.Port)
+}
+
+// RedirectAddr is the listen address of the plain HTTP server redirecting
+// to HTTPS, or "" if there is none.
+func (c Config) RedirectAddr() string {
+	if !c.TLS.Enabled() || c.TLS.HTTPPort == 0 {
+		return ""
+	}
+	return fmt.Sprintf(":%d", c.TLS.HTTPPort)
+}
+
+// GRPCAddr is the listen address for the gRPC server, or "" if it is
+// disabled.
+func (c Config) GRPCAddr() string {
+	if c.GRPC.Port == 0 {
+		return ""
+	}
+	return fmt.Sprintf(":%d", c.GRPC.Port)
+}
+
+// Validate reports every invalid setting at once.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port < 65536, "server.port: %d is not a valid port", c.Server.Port)
+	check(c.GRPC.Port >= 0 && c.GRPC.Port < 65536, "grpc.port: %d is not a valid port", c.GRPC.Port)
+	check(c.GRPC.Port != c.Server.Port, "grpc.port: must differ from server.port")
+	if t := c.TLS; t.Enabled() {
+		check(t.HTTPPort >= 0 && t.HTTPPort < 65536, "tls.http_port: %d is not a valid port", t.HTTPPort)
+		check(t.HTTPPort != c.Server.Port && (t.HTTPPort == 0 || t.HTTPPort != c.GRPC.Port),
+			"tls.http_port: must differ from server.port and grpc.port")
+		check(len(t.Domains) == 0 || t.CertFile == "", "tls: domains and cert_file cannot both be set")
+		check(len(t.Domains) == 0 || t.CacheDir != "", "tls.cache_dir: required to get certificates from Let's Encrypt")
+		if t.Email != "" {
+			_, err := mail.ParseAddress(t.Email)
+			check(err == nil, "tls.email: %q is not an email address", t.Email)
+		}
+	}
+	check((c.TLS.CertFile == "") == (c.TLS.KeyFile == ""), "tls: cert_file and key_file must be set together")
+	for name, d := range map[string]time.Duration{
+		"server.read_timeout":      c.Server.ReadTimeout,
+		"server.write_timeout":     c.Server.WriteTimeout,
+		"server.idle_timeout":      c.Server.IdleTimeout,
+		"server.shutdown_timeout":  c.Server.ShutdownTimeout,
+		"auth.token_ttl":           c.Auth.TokenTTL,
+		"auth.refresh_ttl":         c.Auth.RefreshTTL,
+		"scheduler.interval":       c.Scheduler.Interval,
+		"outbox.interval":          c.Outbox.Interval,
+		"rules.interval":           c.Rules.Interval,
+		"webhooks.timeout":         c.Webhooks.Timeout,
+		"attachments.url_ttl":      c.Attachments.URLTTL,
+		"attachments.scan_timeout": c.Attachments.ScanTimeout,
+		"cache.task_ttl":           c.Cache.TaskTTL,
+		"cache.user_ttl":           c.Cache.UserTTL,
+	} {
+		check(d > 0, "%s: must be positive", name)
+	}
+
+	if u := c.Database.URL; u != "" {
+		check(strings.HasPrefix(u, "sqlite://") || strings.HasPrefix(u, "postgres://") || strings.HasPrefix(u, "postgresql://"),
+			"database.url: unsupported scheme in %q", u)
+	}
+	for name, n := range map[string]int{
+		"database.max_open_conns": c.Database.MaxOpenConns,
+		"database.max_idle_conns": c.Database.MaxIdleConns,
+	} {
+		check(n >= 0, "%s: must not be negative", name)
+	}
+	for name, d := range map[string]time.Duration{
+		"database.conn_max_lifetime":  c.Database.ConnMaxLifetime,
+		"database.conn_max_idle_time": c.Database.ConnMaxIdleTime,
+		"database.query_timeout":      c.Database.QueryTimeout,
+	} {
+		check(d >= 0, "%s: must not be negative", name)
+	}
+	check(c.Auth.JWTSecret == "" || len(c.Auth.JWTSecret) >= 32, "auth.jwt_secret: must be at least 32 bytes")
+	// Two-factor and SSO client secrets are sealed, and the audit log
+	// chained, with keys derived from it: a random key would lose them all
+	// on the next restart.
+	check(c.Auth.JWTSecret != "" || !c.Database.Persistent(),
+		"auth.jwt_secret: must be set when database.url keeps data across restarts")
+
+	if o := c.OAuth; o.Enabled() || o.BaseURL != "" {
+		check(strings.HasPrefix(o.BaseURL, "https://") || strings.HasPrefix(o.BaseURL, "http://"),
+			"oauth.base_url: an http:// or https:// URL is required to sign in with a provider")
+		check(o.SuccessURL == "" || strings.HasPrefix(o.SuccessURL, "https://") || strings.HasPrefix(o.SuccessURL, "http://"),
+			"oauth.success_url: must be an http:// or https:// URL")
+	}
+	check((c.OAuth.GoogleClientID == "") == (c.OAuth.GoogleClientSecret == ""),
+		"oauth: google_client_id and google_client_secret must be set together")
+	check((c.OAuth.GitHubClientID == "") == (c.OAuth.GitHubClientSecret == ""),
+		"oauth: github_client_id and github_client_secret must be set together")
+	if tr := c.Tracing; tr.Endpoint != "" {
+		check(strings.HasPrefix(tr.Endpoint, "http://") || strings.HasPrefix(tr.Endpoint, "https://"),
+			"tracing.endpoint: must be an http:// or https:// URL")
+		check(tr.ServiceName != "", "tracing.service_name: must not be empty")
+		for _, h := range tr.Headers {
+			k, _, ok := strings.Cut(h, "=")
+			check(ok && strings.TrimSpace(k) != "", "tracing.headers: each header must be key=value")
+		}
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio: must be between 0 and 1")
+	check(c.Webhooks.MaxAttempts > 0, "webhooks.max_attempts: must be positive")
+	check(strings.HasPrefix(c.GitHub.APIURL, "https://") || strings.HasPrefix(c.GitHub.APIURL, "http://"),
+		"github.api_url: must be an http:// or https:// URL")
+	if c.Debug.Addr != "" {
+		_, port, err := net.SplitHostPort(c.Debug.Addr)
+		check(err == nil && port != "", "debug.addr: must be host:port, such as 127.0.0.1:6060")
+	}
+	check(c.Jobs.Workers > 0, "jobs.workers: must be positive")
+	check(c.Jobs.MaxAttempts > 0, "jobs.max_attempts: must be positive")
+	check(c.Jobs.Retention >= 0, "jobs.retention: must not be negative")
+	check(c.Tasks.ArchiveAfter >= 0, "tasks.archive_after: must not be negative")
+	check(c.Tasks.UndoWindow >= 0, "tasks.undo_window: must not be negative")
+	check(c.Trash.Retention >= 0, "trash.retention: must not be negative")
+	check(c.Shares.ExpiredRetention >= 0, "shares.expired_retention: must not be negative")
+	check(c.Idempotency.TTL > 0, "idempotency.ttl: must be positive")
+	check(c.Flags.Refresh > 0, "flags.refresh: must be positive")
+	check(c.Audit.Retention >= 0, "audit.retention: must not be negative")
+	check(c.Accounts.DeletionGrace > 0, "accounts.deletion_grace: must be positive")
+	switch rl := c.RateLimit; rl.Backend {
+	case "memory", "off":
+	case "redis":
+		check(strings.HasPrefix(rl.RedisURL, "redis://") || strings.HasPrefix(rl.RedisURL, "rediss://"),
+			"rate_limit.redis_url: a redis:// URL is required by the redis backend")
+	default:
+		check(false, "rate_limit.backend: must be memory, redis or off")
+	}
+	switch cc := c.Cache; cc.Backend {
+	case "memory", "off":
+	case "redis":
+		check(strings.HasPrefix(cc.RedisURL, "redis://") || strings.HasPrefix(cc.RedisURL, "rediss://"),
+			"cache.redis_url: a redis:// URL is required by the redis backend")
+	default:
+		check(false, "cache.backend: must be memory, redis or off")
+	}
+	switch rt := c.Realtime; rt.Backend {
+	case "memory":
+	case "redis":
+		check(strings.HasPrefix(rt.URL, "redis://") || strings.HasPrefix(rt.URL, "rediss://"),
+			"realtime.url: a redis:// URL is required by the redis backend")
+	case "nats":
+		check(strings.HasPrefix(rt.URL, "nats://") || strings.HasPrefix(rt.URL, "tls://"),
+			"realtime.url: a nats:// URL is required by the nats backend")
+	default:
+		check(false, "realtime.backend: must be memory, redis or nats")
+	}
+	check(c.Realtime.Channel != "" && !strings.ContainsAny(c.Realtime.Channel, " \t\r\n"),
+		"realtime.channel: must be a name without spaces")
+	for name, n := range map[string]int{
+		"rate_limit.ip_per_minute":   c.RateLimit.IPPerMinute,
+		"rate_limit.ip_burst":        c.RateLimit.IPBurst,
+		"rate_limit.auth_per_minute": c.RateLimit.AuthPerMinute,
+		"rate_limit.auth_burst":      c.RateLimit.AuthBurst,
+		"rate_limit.user_per_minute": c.RateLimit.UserPerMinute,
+		"rate_limit.user_burst":      c.RateLimit.UserBurst,
+	} {
+		check(n >= 0, "%s: must not be negative", name)
+	}
+	check(c.Quotas.TasksPerProject >= 0, "quotas.tasks_per_project: must not be negative")
+	check(c.Quotas.AttachmentBytes >= 0, "quotas.attachment_bytes: must not be negative")
+	check(c.Quotas.RequestsPerDay >= 0, "quotas.requests_per_day: must not be negative")
+	check(c.Server.MaxBodySize > 0, "server.max_body_size: must be positive")
+	check(c.Server.CompressMinSize >= 0, "server.compress_min_size: must not be negative")
+	check(c.Server.MaxConcurrentStreams > 0, "server.max_concurrent_streams: must be positive")
+	check(c.Server.PingInterval >= 0, "server.ping_interval: must not be negative")
+	check(c.Attachments.MaxSize > 0, "attachments.max_size: must be positive")
+	check(c.Admin.MaxRestoreSize > 0, "admin.max_restore_size: must be positive")
+	switch a := c.Attachments; a.Backend {
+	case "disk":
+		check(a.Dir != "", "attachments.dir: required by the disk backend")
+	case "s3":
+		check(a.S3.Endpoint != "" && a.S3.Bucket != "" && a.S3.Region != "", "attachments.s3: endpoint, region and bucket are required")
+		check(a.S3.AccessKey != "" && a.S3.SecretKey != "", "attachments.s3: access_key and secret_key are required")
+	default:
+		check(false, "attachments.backend: must be disk or s3")
+	}
+	if c.SMTP.Host != "" {
+		check(c.SMTP.Port > 0 && c.SMTP.Port < 65536, "smtp.port: %d is not a valid port", c.SMTP.Port)
+		_, err := mail.ParseAddress(c.SMTP.From)
+		check(err == nil, "smtp.from: %q is not an email address", c.SMTP.From)
+	}
+
+	if in := c.InboundEmail; in.Domain != "" {
+		check(!strings.ContainsAny(in.Domain, "@ \t") && strings.Contains(in.Domain, "."), "inbound_email.domain: %q is not a domain", in.Domain)
+		check(len(in.Secret) >= 16, "inbound_email.secret: must be at least 16 characters")
+		check(in.MaxSize > 0, "inbound_email.max_size: must be positive")
+	}
+
+	var lvl slog.Level
+	check(lvl.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level: unknown level %q", c.Log.Level)
+	check(c.Log.Format == "json" || c.Log.Format == "text", "log.format: must be json or text")
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigFileEnv names the environment variable that may point at a config
+// file when the -config flag is not given.
+const ConfigFileEnv = "CONFIG_FILE"
+
+// Load builds the configuration from defaults, the config file, the
+// environment (looked up with getenv) and args, which excludes the program
+// name, then validates it.
+func Load(args []string, getenv func(string) string) (Config, error) {
+	cfg := Default()
+	fields := leaves(reflect.ValueOf(&cfg).Elem(), "")
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	configPath := fs.String("config", getenv(ConfigFileEnv), "path to a TOML config file")
+	flagValues := map[string]*string{}
+	for _, f := range fields {
+		if name := f.tag.Get("flag"); name != "" {
+			flagValues[name] = fs.String(name, "", f.tag.Get("usage"))
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if *configPath != "" {
+		if err := loadFile(*configPath, fields); err != nil {
+			return cfg, err
+		}
+	}
+
+	for _, f := range fields {
+		name := f.tag.Get("env")
+		if name == "" {
+			continue
+		}
+		if v := getenv(name); v != "" {
+			if err := setString(f.value, v); err != nil {
+				return cfg, fmt.Errorf("config: %s: %w", name, err)
+			}
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(fl *flag.Flag) {
+		if flagErr != nil || fl.Name == "config" {
+			return
+		}
+		for _, f := range fields {
+			if f.tag.Get("flag") == fl.Name {
+				if err := setString(f.value, *flagValues[fl.Name]); err != nil {
+					flagErr = fmt.Errorf("config: -%s: %w", fl.Name, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return cfg, flagErr
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func loadFile(path string, fields []field) error {
+	if ext := filepath.Ext(path); ext != ".toml" {
+		return fmt.Errorf("config: %s: unsupported file type %q, expected .toml", path, ext)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	values, err := parseTOML(string(data))
+	if err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+
+	byKey := make(map[string]field, len(fields))
+	for _, f := range fields {
+		byKey[f.key] = f
+	}
+	for key, v := range values {
+		f, ok := byKey[key]
+		if !ok {
+			return fmt.Errorf("config: %s: unknown setting %q", path, key)
+		}
+		if err := setValue(f.value, v); err != nil {
+			return fmt.Errorf("config: %s: %s: %w", path, key, err)
+		}
+	}
+	return nil
+}
+
+// field is a settable leaf of Config.
+type field struct {
+	key   string // dotted TOML key, e.g. "server.port"
+	tag   reflect.StructTag
+	value reflect.Value
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func leaves(v reflect.Value, prefix string) []field {
+	var out []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key := prefix + sf.Tag.Get("toml")
+		fv := v.Field(i)
+		if sf.Type.Kind() == reflect.Struct {
+			out = append(out, leaves(fv, key+".")...)
+			continue
+		}
+		out = append(out, field{key: key, tag: sf.Tag, value: fv})
+	}
+	return out
+}
+
+// setString parses s, as found in flags and environment variables, into v.
+// Lists are comma-separated.
+func setString(v reflect.Value, s string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Int || v.Kind() == reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case v.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+// setValue stores a parsed TOML value into v.
+func setValue(v reflect.Value, raw any) error {
+	switch x := raw.(type) {
+	case string:
+		if v.Kind() == reflect.Slice {
+			return fmt.Errorf("expected an array")
+		}
+		return setString(v, x)
+	case bool:
+		if v.Kind() != reflect.Bool {
+			return fmt.Errorf("expected %s, got a boolean", v.Type())
+		}
+		v.SetBool(x)
+	case int64:
+		switch {
+		case v.Type() == durationType:
+			return fmt.Errorf("durations are strings such as \"30s\"")
+		case v.Kind() == reflect.Int || v.Kind() == reflect.Int64:
+			v.SetInt(x)
+		case v.Kind() == reflect.Float64:
+			v.SetFloat(float64(x))
+		default:
+			return fmt.Errorf("expected %s, got an integer", v.Type())
+		}
+	case float64:
+		if v.Kind() != reflect.Float64 {
+			return fmt.Errorf("expected %s, got a float", v.Type())
+		}
+		v.SetFloat(x)
+	case []any:
+		if v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("expected %s, got an array", v.Type())
+		}
+		items := make([]string, len(x))
+		for i, item := range x {
+			s, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("array items must be strings")
+			}
+			items[i] = s
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported value %v", raw)
+	}
+	return nil
+}
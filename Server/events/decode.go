@@ -0,0 +1,57 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+// decoders read the Data that events of each type carry. A type missing
+// here cannot cross between server instances.
+var decoders = map[Type]func(json.RawMessage) (any, error){
+	TaskCreated:         decode[model.Task],
+	TaskUpdated:         decode[model.Task],
+	TaskDeleted:         decode[Deleted],
+	TaskRestored:        decode[model.Task],
+	TaskMerged:          decode[Merged],
+	TaskReminder:        decode[Reminder],
+	TaskDue:             decode[Reminder],
+	TaskAssigned:        decode[model.Task],
+	TaskMentioned:       decode[model.Task],
+	CommentCreated:      decode[model.Comment],
+	CommentUpdated:      decode[model.Comment],
+	CommentDeleted:      decode[Deleted],
+	AttachmentCreated:   decode[model.Attachment],
+	AttachmentScanned:   decode[model.Attachment],
+	AttachmentDeleted:   decode[Deleted],
+	ProjectCreated:      decode[model.Project],
+	ProjectUpdated:      decode[model.Project],
+	ProjectDeleted:      decode[Deleted],
+	TasksReordered:      decode[Reordered],
+	MemberAdded:         decode[model.Member],
+	MemberUpdated:       decode[model.Member],
+	MemberRemoved:       decode[model.Member],
+	NotificationCreated: decode[model.Notification],
+	NotificationRead:    decode[Read],
+}
+
+func decode[T any](raw json.RawMessage) (any, error) {
+	var v T
+	err := json.Unmarshal(raw, &v)
+	return v, err
+}
+
+// DecodeData decodes the JSON Data of an event of type typ into the value
+// services publish with it, such as a model.Task for task.created.
+func DecodeData(typ Type, raw json.RawMessage) (any, error) {
+	dec, ok := decoders[typ]
+	if !ok {
+		return nil, fmt.Errorf("events: unknown type %q", typ)
+	}
+	v, err := dec(raw)
+	if err != nil {
+		return nil, fmt.Errorf("events: decoding %s: %w", typ, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,172 @@
+// Package events defines the change notifications emitted when resources are
+// mutated. Services publish them; transports such as the realtime hub
+// deliver them to interested users.
+package events
+
+import (
+	"sync"
+	"time"
+
+	"starttech-server/model"
+)
+
+// Type names a kind of event, for example "task.created".
+type Type string
+
+const (
+	TaskCreated Type = "task.created"
+	TaskUpdated Type = "task.updated"
+	TaskDeleted Type = "task.deleted"
+	// TaskRestored carries the task taken back out of the trash.
+	TaskRestored Type = "task.restored"
+	// TaskMerged carries a Merged, once a task has been merged into
+	// another and moved to the trash.
+	TaskMerged Type = "task.merged"
+	// TaskReminder fires at a task's remind_at, TaskDue at its due_date.
+	TaskReminder Type = "task.reminder"
+	TaskDue      Type = "task.due"
+	// TaskAssigned and TaskMentioned carry the task as Data and are
+	// addressed to the users who were assigned or mentioned.
+	TaskAssigned  Type = "task.assigned"
+	TaskMentioned Type = "task.mentioned"
+	// Comment events carry the model.Comment, or its ID once deleted.
+	CommentCreated Type = "comment.created"
+	CommentUpdated Type = "comment.updated"
+	CommentDeleted Type = "comment.deleted"
+	// Attachment events carry the model.Attachment, or its ID once
+	// deleted. AttachmentScanned follows the virus scan of an upload.
+	AttachmentCreated Type = "attachment.created"
+	AttachmentScanned Type = "attachment.scanned"
+	AttachmentDeleted Type = "attachment.deleted"
+
+	ProjectCreated Type = "project.created"
+	ProjectUpdated Type = "project.updated"
+	ProjectDeleted Type = "project.deleted"
+	TasksReordered Type = "project.tasks_reordered"
+	// Member events carry the model.Member concerned.
+	MemberAdded   Type = "project.member_added"
+	MemberUpdated Type = "project.member_updated"
+	MemberRemoved Type = "project.member_removed"
+
+	// Notification events are addressed to the owner of the inbox only.
+	// NotificationCreated carries the model.Notification, and
+	// NotificationRead a Read. They are not offered to webhooks.
+	NotificationCreated Type = "notification.created"
+	NotificationRead    Type = "notification.read"
+)
+
+// All lists every event type that services publish.
+var All = []Type{
+	TaskCreated, TaskUpdated, TaskDeleted, TaskRestored, TaskMerged, TaskReminder, TaskDue, TaskAssigned, TaskMentioned,
+	CommentCreated, CommentUpdated, CommentDeleted, AttachmentCreated, AttachmentScanned, AttachmentDeleted,
+	ProjectCreated, ProjectUpdated, ProjectDeleted, TasksReordered, MemberAdded, MemberUpdated, MemberRemoved,
+}
+
+// Event is a single change notification.
+type Event struct {
+	// ID is assigned by the realtime hub and increases with every event.
+	ID   uint64    `json:"id,omitempty"`
+	Type Type      `json:"type"`
+	Time time.Time `json:"time"`
+	// Data is the resource after the change; for deletions it identifies
+	// the removed resource.
+	Data any `json:"data"`
+	// OrgID is the organization the change happened in. Recipients only
+	// see the event while working in that organization.
+	OrgID string `json:"org_id,omitempty"`
+	// Recipients are the IDs of the users allowed to see the event.
+	Recipients []string `json:"-"`
+	// TraceParent is the traceparent of the span the event was published
+	// in, so that work it leads to, such as webhook deliveries, joins its
+	// trace.
+	TraceParent string `json:"-"`
+}
+
+// Publisher accepts events for delivery. Implementations must not block the
+// caller for long; services publish while handling requests.
+type Publisher interface {
+	Publish(Event)
+}
+
+// Deleted is the Data of a *.deleted event.
+type Deleted struct {
+	ID string `json:"id"`
+}
+
+// Merged is the Data of a task.merged event: the task that was merged,
+// and the one it was merged into.
+type Merged struct {
+	ID     string `json:"id"`
+	IntoID string `json:"into_id"`
+}
+
+// Read is the Data of a notification.read event: the notifications that
+// were marked read.
+type Read struct {
+	IDs []string `json:"ids"`
+}
+
+// Reminder is the Data of task.reminder and task.due events.
+type Reminder struct {
+	Kind   model.ReminderKind `json:"kind"`
+	FireAt time.Time          `json:"fire_at"`
+	Task   model.Task         `json:"task"`
+}
+
+// Reordered is the Data of a project.tasks_reordered event.
+type Reordered struct {
+	ProjectID string   `json:"project_id"`
+	TaskIDs   []string `json:"task_ids"`
+}
+
+// Fanout is a Publisher that hands every event to each of its members in
+// turn.
+type Fanout []Publisher
+
+func (f Fanout) Publish(e Event) {
+	for _, p := range f {
+		p.Publish(e)
+	}
+}
+
+// Buffer is a Publisher that holds events until they are flushed, so that
+// the changes made in a transaction are only announced once it commits.
+type Buffer struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (b *Buffer) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, e)
+}
+
+// Flush hands the held events to p in the order they were published and
+// forgets them.
+func (b *Buffer) Flush(p Publisher) {
+	held := b.Take()
+	if p == nil {
+		return
+	}
+	for _, e := range held {
+		p.Publish(e)
+	}
+}
+
+// Take returns the held events in the order they were published and
+// forgets them.
+func (b *Buffer) Take() []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	held := b.events
+	b.events = nil
+	return held
+}
+
+// Discard is a Publisher that drops every event.
+var Discard Publisher = discard{}
+
+type discard struct{}
+
+func (discard) Publish(Event) {}
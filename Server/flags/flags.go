@@ -0,0 +1,183 @@
+// Package flags decides which features are on for whom, so that risky ones
+// can be rolled out to a few organizations or users at a time and turned
+// off again without a deploy. Flags are kept in the database and changed
+// by administrators; a Set reads them all at once and keeps them for a
+// while, so checking a flag costs no query.
+//
+// The flags the server checks itself are listed in Known with their
+// defaults, which apply until a flag is stored. Clients may read other
+// flags, made up by administrators, through GET /me/flags.
+package flags
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"starttech-server/apierror"
+	"starttech-server/auth"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// The flags the server checks.
+const (
+	// Realtime gates the WebSocket and server-sent event streams.
+	Realtime = "realtime"
+	// GraphQL gates the GraphQL endpoint.
+	GraphQL = "graphql"
+)
+
+// Known lists the flags the server checks as they are until an
+// administrator stores them. Features that were there before they had a
+// flag stay on by default.
+var Known = []model.Flag{
+	{Key: GraphQL, Description: "The GraphQL endpoint", Enabled: true},
+	{Key: Realtime, Description: "WebSocket and server-sent event streams", Enabled: true},
+}
+
+// On reports whether f is on for the user userID in the organization
+// orgID.
+func On(f model.Flag, orgID, userID string) bool {
+	if on, ok := f.Users[userID]; ok && userID != "" {
+		return on
+	}
+	if on, ok := f.Orgs[orgID]; ok && orgID != "" {
+		return on
+	}
+	if f.Enabled {
+		return true
+	}
+	subject := orgID
+	if subject == "" {
+		subject = userID
+	}
+	return f.Rollout > 0 && bucket(f.Key, subject) < f.Rollout
+}
+
+// bucket places subject in one of 100 buckets for the flag key, the same
+// one every time, and a different one for each flag.
+func bucket(key, subject string) int {
+	sum := sha256.Sum256([]byte(key + "\x00" + subject))
+	return int(binary.BigEndian.Uint64(sum[:8]) % 100)
+}
+
+// Set evaluates flags, reading them from Store at most every TTL.
+type Set struct {
+	Store storage.FlagStore
+	TTL   time.Duration
+
+	mu     sync.Mutex
+	flags  map[string]model.Flag
+	loaded time.Time
+}
+
+// NewSet returns a Set reading from store.
+func NewSet(store storage.FlagStore, ttl time.Duration) *Set {
+	return &Set{Store: store, TTL: ttl}
+}
+
+// List returns every flag, the known ones with their defaults unless they
+// are stored, by key.
+func (s *Set) List(ctx context.Context) []model.Flag {
+	flags := s.snapshot(ctx)
+	return slices.SortedFunc(maps.Values(flags), func(a, b model.Flag) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+}
+
+// Get returns the flag with the given key, and whether there is one.
+func (s *Set) Get(ctx context.Context, key string) (model.Flag, bool) {
+	f, ok := s.snapshot(ctx)[key]
+	return f, ok
+}
+
+// Enabled reports whether the flag with the given key is on for the user
+// and organization ctx acts for. Unknown flags are off.
+func (s *Set) Enabled(ctx context.Context, key string) bool {
+	f, ok := s.Get(ctx, key)
+	if !ok {
+		return false
+	}
+	userID, _ := auth.UserID(ctx)
+	orgID, _ := auth.OrgID(ctx)
+	return On(f, orgID, userID)
+}
+
+// For returns whether each flag is on for the user userID in the
+// organization orgID.
+func (s *Set) For(ctx context.Context, orgID, userID string) map[string]bool {
+	on := make(map[string]bool)
+	for key, f := range s.snapshot(ctx) {
+		on[key] = On(f, orgID, userID)
+	}
+	return on
+}
+
+// Invalidate makes the next check read the flags again, as after an
+// administrator changed one. Other instances see the change within TTL.
+func (s *Set) Invalidate() {
+	s.mu.Lock()
+	s.loaded = time.Time{}
+	s.mu.Unlock()
+}
+
+// snapshot returns the flags by key, reading them if they are older than
+// TTL. If they cannot be read, those read before, or the defaults, are
+// used.
+func (s *Set) snapshot(ctx context.Context) map[string]model.Flag {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.flags != nil && time.Since(s.loaded) < s.TTL {
+		return s.flags
+	}
+	stored, err := s.Store.ListFlags(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "reading feature flags", "err", err)
+		if s.flags == nil {
+			return defaults()
+		}
+		return s.flags
+	}
+	flags := defaults()
+	for _, f := range stored {
+		if _, ok := flags[f.Key]; ok {
+			f.Known = true
+		}
+		flags[f.Key] = f
+	}
+	s.flags, s.loaded = flags, time.Now()
+	return flags
+}
+
+// defaults returns the Known flags by key.
+func defaults() map[string]model.Flag {
+	flags := make(map[string]model.Flag, len(Known))
+	for _, f := range Known {
+		f.Known = true
+		f.Orgs, f.Users = map[string]bool{}, map[string]bool{}
+		flags[f.Key] = f
+	}
+	return flags
+}
+
+// Require refuses requests with 403 unless the flag with the given key is
+// on for the caller. It must run behind the auth middleware.
+func (s *Set) Require(key string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !s.Enabled(r.Context(), key) {
+				apierror.WriteError(w, http.StatusForbidden, apierror.Error{Code: apierror.CodeFeatureDisabled, Message: "this feature is not enabled for you"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package graphql
+
+// The syntax tree of a query document, as produced by Parse. Type system
+// definitions are not supported; schemas are built in Go.
+
+// Location is a position in the query text, counted from 1.
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Document is a parsed query document.
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation kinds.
+const (
+	Query        = "query"
+	Mutation     = "mutation"
+	Subscription = "subscription"
+)
+
+type Operation struct {
+	Kind       string
+	Name       string
+	Vars       []*VarDef
+	Directives []*Directive
+	Selections []Selection
+	Loc        Location
+}
+
+// VarDef declares a variable of an operation.
+type VarDef struct {
+	Name    string
+	Type    *TypeRef
+	Default *Value
+	Loc     Location
+}
+
+// TypeRef names a type in a variable definition: a named type, or a list
+// of Elem, either of which may be non-null.
+type TypeRef struct {
+	Name    string
+	Elem    *TypeRef
+	NonNull bool
+}
+
+func (t *TypeRef) String() string {
+	s := t.Name
+	if t.Elem != nil {
+		s = "[" + t.Elem.String() + "]"
+	}
+	if t.NonNull {
+		s += "!"
+	}
+	return s
+}
+
+// Selection is a *Field, *FragmentSpread or *InlineFragment.
+type Selection interface {
+	location() Location
+}
+
+type Field struct {
+	Alias      string
+	Name       string
+	Args       []*Argument
+	Directives []*Directive
+	Selections []Selection
+	Loc        Location
+}
+
+// Key is the name the field's value has in the response.
+func (f *Field) Key() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+type FragmentSpread struct {
+	Name       string
+	Directives []*Directive
+	Loc        Location
+}
+
+type InlineFragment struct {
+	TypeCond   string
+	Directives []*Directive
+	Selections []Selection
+	Loc        Location
+}
+
+func (f *Field) location() Location          { return f.Loc }
+func (f *FragmentSpread) location() Location { return f.Loc }
+func (f *InlineFragment) location() Location { return f.Loc }
+
+type Fragment struct {
+	Name       string
+	TypeCond   string
+	Directives []*Directive
+	Selections []Selection
+	Loc        Location
+}
+
+type Argument struct {
+	Name  string
+	Value *Value
+	Loc   Location
+}
+
+type Directive struct {
+	Name string
+	Args []*Argument
+	Loc  Location
+}
+
+// ValueKind tells the kinds of literal apart.
+type ValueKind int
+
+const (
+	VariableValue ValueKind = iota
+	IntValue
+	FloatValue
+	StringValue
+	BooleanValue
+	NullValue
+	EnumValue
+	ListValue
+	ObjectValue
+)
+
+// Value is a literal, or a reference to a variable. Raw holds the text of
+// scalars, enum values and variable names; strings are unescaped.
+type Value struct {
+	Kind   ValueKind
+	Raw    string
+	List   []*Value
+	Fields []*ObjectField
+	Loc    Location
+}
+
+type ObjectField struct {
+	Name  string
+	Value *Value
+	Loc   Location
+}
@@ -0,0 +1,542 @@
+// Package graphql runs GraphQL requests against a schema built in Go. It
+// parses and validates query documents, executes queries and mutations
+// field by field through resolvers, streams subscriptions, and answers the
+// introspection queries tools use to read the schema. Interfaces, unions
+// and custom directives are not supported.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Request is a GraphQL request, as clients send it.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response is the result of a request. Data is absent when the request
+// failed before execution began.
+type Response struct {
+	Data   any      `json:"data,omitempty"`
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+// Error is an error reported in a response. Resolvers may return one to
+// choose the message and extensions clients see.
+type Error struct {
+	Message    string         `json:"message"`
+	Locations  []Location     `json:"locations,omitempty"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+	// Err is the error a resolver returned, if any.
+	Err error `json:"-"`
+}
+
+func (e *Error) Error() string { return e.Message }
+func (e *Error) Unwrap() error { return e.Err }
+
+// Errorf returns an *Error with a formatted message.
+func Errorf(format string, args ...any) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...)}
+}
+
+// null is the data of a response whose root was nulled by an error.
+var null = json.RawMessage("null")
+
+// Operation returns the operation of doc a request names: the one called
+// name, or the only one when name is empty.
+func (d *Document) Operation(name string) (*Operation, error) {
+	if name == "" {
+		if len(d.Operations) != 1 {
+			return nil, Errorf("operationName is required when the document has several operations")
+		}
+		return d.Operations[0], nil
+	}
+	for _, op := range d.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+	return nil, Errorf("unknown operation %q", name)
+}
+
+type executor struct {
+	ctx    context.Context
+	schema *Schema
+	doc    *Document
+	vars   map[string]any
+	errs   []*Error
+}
+
+// prepare parses and validates a request, and readies the operation it
+// names for execution.
+func (s *Schema) prepare(ctx context.Context, req Request) (*executor, *Operation, *Response) {
+	doc, err := Parse(req.Query)
+	if err != nil {
+		return nil, nil, &Response{Errors: []*Error{err.(*Error)}}
+	}
+	if errs := s.Validate(doc); len(errs) > 0 {
+		return nil, nil, &Response{Errors: errs}
+	}
+	op, err := doc.Operation(req.OperationName)
+	if err != nil {
+		return nil, nil, &Response{Errors: []*Error{err.(*Error)}}
+	}
+	vars, errs := s.variables(op, req.Variables)
+	if len(errs) > 0 {
+		return nil, nil, &Response{Errors: errs}
+	}
+	return &executor{ctx: ctx, schema: s, doc: doc, vars: vars}, op, nil
+}
+
+// variables checks the values given for an operation's variables, and
+// fills in defaults. The values are kept in input form.
+func (s *Schema) variables(op *Operation, given map[string]any) (map[string]any, []*Error) {
+	vars := map[string]any{}
+	var errs []*Error
+	for _, d := range op.Vars {
+		t := s.resolve(d.Type)
+		v, present := given[d.Name]
+		if !present && d.Default != nil {
+			v, present = literal(d.Default, nil)
+		}
+		if !present {
+			if _, ok := t.(*NonNull); ok {
+				errs = append(errs, &Error{Message: fmt.Sprintf("variable $%s of type %s is required", d.Name, d.Type), Locations: []Location{d.Loc}})
+			}
+			continue
+		}
+		if _, err := coerceInput(v, t, "variable $"+d.Name); err != nil {
+			errs = append(errs, &Error{Message: err.Error(), Locations: []Location{d.Loc}})
+			continue
+		}
+		vars[d.Name] = v
+	}
+	return vars, errs
+}
+
+// Execute runs a query or mutation. Subscriptions must go through
+// Subscribe.
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	e, op, resp := s.prepare(ctx, req)
+	if resp != nil {
+		return resp
+	}
+	if op.Kind == Subscription {
+		return &Response{Errors: []*Error{{Message: "subscriptions cannot be executed as a single request", Locations: []Location{op.Loc}}}}
+	}
+	root := s.root(op.Kind)
+	data, ok := e.selections(root, nil, op.Selections, nil)
+	out := &Response{Data: data, Errors: e.errs}
+	if !ok {
+		out.Data = null
+	}
+	return out
+}
+
+// Subscribe starts a subscription. It returns a channel delivering a
+// response for each event, which is closed when the event stream ends or
+// ctx is canceled, or a response holding the errors that prevented the
+// subscription from starting.
+func (s *Schema) Subscribe(ctx context.Context, req Request) (<-chan *Response, *Response) {
+	e, op, resp := s.prepare(ctx, req)
+	if resp != nil {
+		return nil, resp
+	}
+	if op.Kind != Subscription {
+		return nil, &Response{Errors: []*Error{{Message: "not a subscription operation", Locations: []Location{op.Loc}}}}
+	}
+	keys, fields := e.collect(s.Subscription, op.Selections, map[string]bool{})
+	if len(keys) != 1 {
+		return nil, &Response{Errors: []*Error{{Message: "a subscription must select exactly one field", Locations: []Location{op.Loc}}}}
+	}
+	nodes := fields[keys[0]]
+	def := s.Subscription.Field(nodes[0].Name)
+	if def == nil {
+		return nil, &Response{Errors: []*Error{{Message: "__typename cannot be subscribed to", Locations: []Location{nodes[0].Loc}}}}
+	}
+	args, err := coerceArgs(def.Args, nodes[0].Args, e.vars)
+	if err != nil {
+		return nil, &Response{Errors: []*Error{e.fieldError(err, nodes[0], nil)}}
+	}
+	src, err := def.Subscribe(Params{Context: ctx, Args: args})
+	if err != nil {
+		return nil, &Response{Errors: []*Error{e.fieldError(err, nodes[0], nil)}}
+	}
+
+	out := make(chan *Response)
+	go func() {
+		defer close(out)
+		for {
+			var ev any
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-src:
+				if !ok {
+					return
+				}
+				ev = v
+			}
+			ee := &executor{ctx: ctx, schema: s, doc: e.doc, vars: e.vars}
+			data, ok := ee.selections(s.Subscription, ev, op.Selections, nil)
+			resp := &Response{Data: data, Errors: ee.errs}
+			if !ok {
+				resp.Data = null
+			}
+			select {
+			case out <- resp:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// path is the position of a value in the response, innermost last.
+type path struct {
+	parent *path
+	key    any
+}
+
+func (p *path) with(key any) *path { return &path{parent: p, key: key} }
+
+func (p *path) slice() []any {
+	var out []any
+	for ; p != nil; p = p.parent {
+		out = append(out, p.key)
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// fieldError turns an error met while resolving a field into the error
+// reported for it.
+func (e *executor) fieldError(err error, f *Field, at *path) *Error {
+	out, ok := err.(*Error)
+	if ok {
+		cp := *out
+		out = &cp
+	} else if e.schema.PresentError != nil {
+		out = e.schema.PresentError(e.ctx, err)
+	} else {
+		out = &Error{Message: err.Error(), Err: err}
+	}
+	out.Locations = []Location{f.Loc}
+	out.Path = at.slice()
+	return out
+}
+
+func (e *executor) fail(err error, f *Field, at *path) {
+	e.errs = append(e.errs, e.fieldError(err, f, at))
+}
+
+// selections resolves the fields selected on an object. It reports false
+// when a non-null field was null, which makes the object itself null.
+func (e *executor) selections(t *Object, src any, sels []Selection, at *path) (*orderedMap, bool) {
+	keys, fields := e.collect(t, sels, map[string]bool{})
+	out := &orderedMap{}
+	for _, k := range keys {
+		v, ok := e.field(t, src, fields[k], at.with(k))
+		if !ok {
+			return nil, false
+		}
+		out.set(k, v)
+	}
+	return out, true
+}
+
+// collect gathers the fields selected on t by sels and the fragments they
+// spread, grouped by response key in the order they first appear.
+func (e *executor) collect(t *Object, sels []Selection, visited map[string]bool) ([]string, map[string][]*Field) {
+	var keys []string
+	fields := map[string][]*Field{}
+	var walk func(sels []Selection)
+	walk = func(sels []Selection) {
+		for _, sel := range sels {
+			switch sel := sel.(type) {
+			case *Field:
+				if !e.included(sel.Directives) {
+					continue
+				}
+				k := sel.Key()
+				if _, ok := fields[k]; !ok {
+					keys = append(keys, k)
+				}
+				fields[k] = append(fields[k], sel)
+			case *InlineFragment:
+				if !e.included(sel.Directives) || sel.TypeCond != "" && sel.TypeCond != t.Name {
+					continue
+				}
+				walk(sel.Selections)
+			case *FragmentSpread:
+				if visited[sel.Name] || !e.included(sel.Directives) {
+					continue
+				}
+				visited[sel.Name] = true
+				f := e.doc.Fragments[sel.Name]
+				if f == nil || f.TypeCond != t.Name {
+					continue
+				}
+				walk(f.Selections)
+			}
+		}
+	}
+	walk(sels)
+	return keys, fields
+}
+
+// included applies @skip and @include.
+func (e *executor) included(ds []*Directive) bool {
+	for _, d := range ds {
+		def := directives[d.Name]
+		if def == nil {
+			continue
+		}
+		args, err := coerceArgs(def.Args, d.Args, e.vars)
+		if err != nil {
+			continue
+		}
+		cond, _ := args["if"].(bool)
+		if d.Name == "skip" && cond || d.Name == "include" && !cond {
+			return false
+		}
+	}
+	return true
+}
+
+// field resolves one field of an object and completes its value. Like
+// the other completion functions it reports false when a null must
+// propagate to the enclosing value.
+func (e *executor) field(t *Object, src any, nodes []*Field, at *path) (any, bool) {
+	f := nodes[0]
+	if f.Name == "__typename" {
+		return t.Name, true
+	}
+	def := t.Field(f.Name)
+	_, nonNull := def.Type.(*NonNull)
+	if err := e.ctx.Err(); err != nil {
+		e.fail(err, f, at)
+		return nil, !nonNull
+	}
+	args, err := coerceArgs(def.Args, f.Args, e.vars)
+	if err != nil {
+		e.fail(err, f, at)
+		return nil, !nonNull
+	}
+	v, err := e.resolve(def, Params{Context: e.ctx, Source: src, Args: args})
+	if err != nil {
+		e.fail(err, f, at)
+		return nil, !nonNull
+	}
+	return e.complete(def.Type, nodes, v, at)
+}
+
+// resolve calls a field's resolver, turning a panic into an error.
+func (e *executor) resolve(def *FieldDef, p Params) (v any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("graphql: resolver for %s panicked: %v", def.Name, r)
+		}
+	}()
+	if def.Resolve != nil {
+		return def.Resolve(p)
+	}
+	if def.Subscribe != nil {
+		return p.Source, nil
+	}
+	return defaultResolve(p.Source, def.Name)
+}
+
+func (e *executor) complete(t Type, nodes []*Field, v any, at *path) (any, bool) {
+	if nn, ok := t.(*NonNull); ok {
+		out, ok := e.completeValue(nn.Of, nodes, v, at)
+		if !ok {
+			return nil, false
+		}
+		if out == nil {
+			e.fail(fmt.Errorf("cannot return null for non-nullable field %s", nodes[0].Name), nodes[0], at)
+			return nil, false
+		}
+		return out, true
+	}
+	out, ok := e.completeValue(t, nodes, v, at)
+	if !ok {
+		return nil, true
+	}
+	return out, true
+}
+
+func (e *executor) completeValue(t Type, nodes []*Field, v any, at *path) (any, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, true
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil, true
+	}
+	switch t := t.(type) {
+	case *List:
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			e.fail(fmt.Errorf("expected a list for field %s, got %s", nodes[0].Name, rv.Type()), nodes[0], at)
+			return nil, false
+		}
+		out := make([]any, rv.Len())
+		for i := range out {
+			var ok bool
+			if out[i], ok = e.complete(t.Of, nodes, rv.Index(i).Interface(), at.with(i)); !ok {
+				return nil, false
+			}
+		}
+		return out, true
+	case *Scalar:
+		out, err := t.Serialize(rv.Interface())
+		if err != nil {
+			e.fail(err, nodes[0], at)
+			return nil, false
+		}
+		return out, true
+	case *Enum:
+		for _, ev := range t.Values {
+			if ev.Value != nil && reflect.DeepEqual(ev.Value, rv.Interface()) ||
+				ev.Value == nil && rv.Kind() == reflect.String && rv.String() == ev.Name {
+				return ev.Name, true
+			}
+		}
+		e.fail(fmt.Errorf("%v is not a value of %s", rv.Interface(), t.Name), nodes[0], at)
+		return nil, false
+	case *Object:
+		var sels []Selection
+		for _, n := range nodes {
+			sels = append(sels, n.Selections...)
+		}
+		return e.selections(t, v, sels, at)
+	}
+	return nil, false
+}
+
+// defaultResolve reads a field from a map or struct source.
+func defaultResolve(src any, name string) (any, error) {
+	rv := reflect.ValueOf(src)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			v := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+			if !v.IsValid() {
+				return nil, nil
+			}
+			return v.Interface(), nil
+		}
+	case reflect.Struct:
+		if i, ok := structField(rv.Type(), name); ok {
+			return rv.Field(i).Interface(), nil
+		}
+	}
+	return nil, fmt.Errorf("graphql: no resolver for field %s of %T", name, src)
+}
+
+var structFields sync.Map // reflect.Type -> map[string]int
+
+// structField finds the field of a struct type whose JSON name is name in
+// snake case (dueDate reads `json:"due_date"`), or whose Go name is name.
+func structField(t reflect.Type, name string) (int, bool) {
+	if m, ok := structFields.Load(t); ok {
+		i, ok := m.(map[string]int)[name]
+		return i, ok
+	}
+	m := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if tag != "" && tag != "-" {
+			m[camel(tag)] = i
+		}
+		if _, ok := m[lowerFirst(sf.Name)]; !ok {
+			m[lowerFirst(sf.Name)] = i
+		}
+	}
+	structFields.Store(t, m)
+	i, ok := m[name]
+	return i, ok
+}
+
+// camel turns a snake_case name into camelCase.
+func camel(s string) string {
+	var b strings.Builder
+	up := false
+	for _, r := range s {
+		switch {
+		case r == '_':
+			up = true
+		case up:
+			b.WriteRune(unicode.ToUpper(r))
+			up = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// orderedMap is an object of the response, which keeps its keys in the
+// order the query selected them.
+type orderedMap struct {
+	keys   []string
+	values []any
+}
+
+func (m *orderedMap) set(k string, v any) {
+	m.keys = append(m.keys, k)
+	m.values = append(m.values, v)
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		b.Write(key)
+		b.WriteByte(':')
+		v, err := json.Marshal(m.values[i])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
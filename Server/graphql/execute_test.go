@@ -0,0 +1,353 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testTask struct {
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	Status   string   `json:"status"`
+	Tags     []string `json:"tags"`
+	ParentID string   `json:"parent_id"`
+}
+
+var testTasks = map[string]*testTask{
+	"1": {ID: "1", Title: "Write tests", Status: "TODO", Tags: []string{"go"}},
+	"2": {ID: "2", Title: "Run them", Status: "DONE", Tags: []string{}, ParentID: "1"},
+}
+
+// testSchema returns a small task schema for the tests, bounded to
+// maxDepth levels of selections.
+func testSchema(t *testing.T, maxDepth int) *Schema {
+	t.Helper()
+	status := &Enum{Name: "Status", Values: []*EnumValueDef{{Name: "TODO"}, {Name: "DONE"}}}
+	task := &Object{Name: "Task"}
+	task.Fields = []*FieldDef{
+		{Name: "id", Type: &NonNull{Of: ID}},
+		{Name: "title", Type: &NonNull{Of: String}},
+		{Name: "status", Type: status},
+		{Name: "tags", Type: &NonNull{Of: &List{Of: &NonNull{Of: String}}}},
+		{Name: "parentId", Type: ID},
+		{Name: "parent", Type: task, Resolve: func(p Params) (any, error) {
+			return testTasks[p.Source.(*testTask).ParentID], nil
+		}},
+		{Name: "broken", Type: String, Resolve: func(p Params) (any, error) {
+			return nil, errors.New("broken on purpose")
+		}},
+		{Name: "missing", Type: &NonNull{Of: String}, Resolve: func(p Params) (any, error) {
+			return nil, nil
+		}},
+		{Name: "panics", Type: String, Resolve: func(p Params) (any, error) {
+			panic("oops")
+		}},
+	}
+	filter := &InputObject{Name: "TaskFilter", Fields: []*Arg{
+		{Name: "status", Type: status},
+		{Name: "limit", Type: Int, Default: 10},
+	}}
+	query := &Object{Name: "Query", Fields: []*FieldDef{
+		{Name: "task", Type: task, Args: []*Arg{{Name: "id", Type: &NonNull{Of: ID}}},
+			Resolve: func(p Params) (any, error) {
+				if tk, ok := testTasks[p.Args["id"].(string)]; ok {
+					return tk, nil
+				}
+				return nil, nil
+			}},
+		{Name: "tasks", Type: &NonNull{Of: &List{Of: &NonNull{Of: task}}}, Args: []*Arg{{Name: "filter", Type: filter}},
+			Resolve: func(p Params) (any, error) {
+				var want string
+				if f, ok := p.Args["filter"].(map[string]any); ok {
+					want, _ = f["status"].(string)
+				}
+				var out []*testTask
+				for _, id := range []string{"1", "2"} {
+					if want == "" || testTasks[id].Status == want {
+						out = append(out, testTasks[id])
+					}
+				}
+				return out, nil
+			}},
+		{Name: "echo", Type: String, Args: []*Arg{{Name: "text", Type: String, Default: "nothing"}},
+			Resolve: func(p Params) (any, error) { return p.Args["text"], nil }},
+		{Name: "sum", Type: Int, Args: []*Arg{{Name: "of", Type: &List{Of: &NonNull{Of: Int}}}},
+			Resolve: func(p Params) (any, error) {
+				n := 0
+				for _, v := range p.Args["of"].([]any) {
+					n += v.(int)
+				}
+				return n, nil
+			}},
+	}}
+	mutation := &Object{Name: "Mutation", Fields: []*FieldDef{
+		{Name: "rename", Type: task, Args: []*Arg{{Name: "id", Type: &NonNull{Of: ID}}, {Name: "title", Type: &NonNull{Of: String}}},
+			Resolve: func(p Params) (any, error) {
+				cp := *testTasks[p.Args["id"].(string)]
+				cp.Title = p.Args["title"].(string)
+				return &cp, nil
+			}},
+	}}
+	s, err := NewSchema(query, mutation, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.MaxDepth = maxDepth
+	return s
+}
+
+func TestExecute(t *testing.T) {
+	s := testSchema(t, 0)
+	tests := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{
+			name: "fields and aliases",
+			req:  Request{Query: `{ first: task(id: "1") { id title status tags } second: task(id: 2) { title } }`},
+			want: `{"data":{"first":{"id":"1","title":"Write tests","status":"TODO","tags":["go"]},"second":{"title":"Run them"}}}`,
+		},
+		{
+			name: "default resolver in camel case",
+			req:  Request{Query: `{ task(id: "2") { parentId } }`},
+			want: `{"data":{"task":{"parentId":"1"}}}`,
+		},
+		{
+			name: "nested objects",
+			req:  Request{Query: `{ task(id: "2") { parent { title parent { id } } } }`},
+			want: `{"data":{"task":{"parent":{"title":"Write tests","parent":null}}}}`,
+		},
+		{
+			name: "typename",
+			req:  Request{Query: `{ __typename task(id: "1") { __typename } }`},
+			want: `{"data":{"__typename":"Query","task":{"__typename":"Task"}}}`,
+		},
+		{
+			name: "fragments merged in order",
+			req: Request{Query: `query { task(id: "1") { id ...F ... on Task { status } title } }
+				fragment F on Task { title tags }`},
+			want: `{"data":{"task":{"id":"1","title":"Write tests","tags":["go"],"status":"TODO"}}}`,
+		},
+		{
+			name: "skip and include",
+			req: Request{
+				Query:     `query($no: Boolean!) { task(id: "1") { id @skip(if: true) title @include(if: $no) status @skip(if: $no) } }`,
+				Variables: map[string]any{"no": false},
+			},
+			want: `{"data":{"task":{"status":"TODO"}}}`,
+		},
+		{
+			name: "input object with enum and default",
+			req:  Request{Query: `{ tasks(filter: {status: DONE}) { id } }`},
+			want: `{"data":{"tasks":[{"id":"2"}]}}`,
+		},
+		{
+			name: "variables",
+			req: Request{
+				Query:     `query($f: TaskFilter, $text: String) { tasks(filter: $f) { id } echo(text: $text) }`,
+				Variables: map[string]any{"f": map[string]any{"status": "TODO"}, "text": "hi"},
+			},
+			want: `{"data":{"tasks":[{"id":"1"}],"echo":"hi"}}`,
+		},
+		{
+			name: "argument default",
+			req:  Request{Query: `{ echo }`},
+			want: `{"data":{"echo":"nothing"}}`,
+		},
+		{
+			name: "explicit null argument",
+			req:  Request{Query: `{ echo(text: null) }`},
+			want: `{"data":{"echo":null}}`,
+		},
+		{
+			name: "variable default",
+			req:  Request{Query: `query($text: String = "fallback") { echo(text: $text) }`},
+			want: `{"data":{"echo":"fallback"}}`,
+		},
+		{
+			name: "single value for a list",
+			req:  Request{Query: `{ sum(of: 5) }`},
+			want: `{"data":{"sum":5}}`,
+		},
+		{
+			name: "list of ints from JSON variables",
+			req:  Request{Query: `query($of: [Int!]) { sum(of: $of) }`, Variables: map[string]any{"of": []any{1.0, 2.0, 3.0}}},
+			want: `{"data":{"sum":6}}`,
+		},
+		{
+			name: "named operation",
+			req:  Request{Query: `query A { echo(text: "a") } query B { echo(text: "b") }`, OperationName: "B"},
+			want: `{"data":{"echo":"b"}}`,
+		},
+		{
+			name: "mutation",
+			req:  Request{Query: `mutation { rename(id: "1", title: "Renamed") { id title } }`},
+			want: `{"data":{"rename":{"id":"1","title":"Renamed"}}}`,
+		},
+		{
+			name: "resolver error nulls a nullable field",
+			req:  Request{Query: `{ task(id: "1") { id broken } }`},
+			want: `{"data":{"task":{"id":"1","broken":null}},"errors":[{"message":"broken on purpose","locations":[{"line":1,"column":22}],"path":["task","broken"]}]}`,
+		},
+		{
+			name: "null in a non-null field nulls its parent",
+			req:  Request{Query: `{ task(id: "1") { id missing } }`},
+			want: `{"data":{"task":null},"errors":[{"message":"cannot return null for non-nullable field missing","locations":[{"line":1,"column":22}],"path":["task","missing"]}]}`,
+		},
+		{
+			name: "null propagates through non-null lists to the root",
+			req:  Request{Query: `{ tasks { missing } }`},
+			want: `{"data":null,"errors":[{"message":"cannot return null for non-nullable field missing","locations":[{"line":1,"column":11}],"path":["tasks",0,"missing"]}]}`,
+		},
+		{
+			name: "panicking resolver",
+			req:  Request{Query: `{ task(id: "1") { panics } }`},
+			want: `{"data":{"task":{"panics":null}},"errors":[{"message":"graphql: resolver for panics panicked: oops","locations":[{"line":1,"column":19}],"path":["task","panics"]}]}`,
+		},
+		{
+			name: "missing required variable",
+			req:  Request{Query: `query($id: ID!) { task(id: $id) { id } }`},
+			want: `{"errors":[{"message":"variable $id of type ID! is required","locations":[{"line":1,"column":7}]}]}`,
+		},
+		{
+			name: "variable of the wrong type",
+			req:  Request{Query: `query($of: [Int!]) { sum(of: $of) }`, Variables: map[string]any{"of": []any{"x"}}},
+			want: `{"errors":[{"message":"variable $of[0]: expected an integer, found \"x\"","locations":[{"line":1,"column":7}]}]}`,
+		},
+		{
+			name: "several operations without a name",
+			req:  Request{Query: `query A { echo } query B { echo }`},
+			want: `{"errors":[{"message":"operationName is required when the document has several operations"}]}`,
+		},
+		{
+			name: "syntax error",
+			req:  Request{Query: `{ task(id: "1") { id }`},
+			want: `{"errors":[{"message":"syntax error: expected a name, found end of document","locations":[{"line":1,"column":23}]}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(s.Execute(context.Background(), tt.req))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteCanceled(t *testing.T) {
+	s := testSchema(t, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	resp := s.Execute(ctx, Request{Query: `{ echo }`})
+	if len(resp.Errors) != 1 || !errors.Is(resp.Errors[0], context.Canceled) {
+		t.Errorf("errors = %v, want context.Canceled", resp.Errors)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s := testSchema(t, 0)
+	tests := []struct {
+		name  string
+		query string
+		want  string // a message among the errors; empty for none
+	}{
+		{"valid", `{ task(id: "1") { id } }`, ""},
+		{"non-null variable in nullable position", `query($t: String!) { echo(text: $t) }`, ""},
+		{"unknown field", `{ nope }`, `cannot query field "nope" on type "Query"`},
+		{"leaf with selections", `{ echo { x } }`, `field "echo" of type String cannot have selections`},
+		{"object without selections", `{ task(id: "1") }`, `field "task" of type Task must have selections`},
+		{"missing argument", `{ task { id } }`, `argument "id" of field "task" is required`},
+		{"unknown argument", `{ echo(nope: 1) }`, `unknown argument "nope" of field "echo"`},
+		{"repeated argument", `{ echo(text: "a", text: "b") }`, `argument "text" of field "echo" is given more than once`},
+		{"wrong literal", `{ sum(of: ["a"]) }`, `argument "of"[0]: expected an integer, found "a"`},
+		{"int out of range", `{ sum(of: 2147483648) }`, `expected a 32-bit integer`},
+		{"unknown enum value", `{ tasks(filter: {status: LATER}) { id } }`, `LATER is not a value of Status`},
+		{"unknown input field", `{ tasks(filter: {nope: 1}) { id } }`, `TaskFilter has no field "nope"`},
+		{"conflicting alias", `{ task(id: "1") { x: id x: title } }`, `"x" selects both "id" and "title"`},
+		{"unknown directive", `{ echo @nope }`, `unknown directive @nope on field`},
+		{"repeated directive", `{ echo @skip(if: true) @skip(if: false) }`, `@skip is given more than once`},
+		{"directive on operation", `query @skip(if: true) { echo }`, `unknown directive @skip on an operation`},
+		{"undefined variable", `query Q { echo(text: $t) }`, `variable $t is not defined by operation "Q"`},
+		{"unused variable", `query($t: String) { echo }`, `variable $t is never used`},
+		{"nullable variable in non-null position", `query($id: ID) { task(id: $id) { id } }`, `variable $id of type ID cannot be used where ID! is expected`},
+		{"variable of another type", `query($t: Int) { echo(text: $t) }`, `variable $t of type Int cannot be used where String is expected`},
+		{"non-null list variable", `query($of: [Int!]!) { sum(of: $of) }`, ""},
+		{"list of nullables in a list of non-nulls", `query($of: [Int]) { sum(of: $of) }`, `variable $of of type [Int] cannot be used where [Int!] is expected`},
+		{"variable of unknown type", `query($t: Nope) { echo(text: $t) }`, `variable $t has unknown type Nope`},
+		{"variable of output type", `query($t: Task) { echo }`, `variable $t cannot be of non-input type Task`},
+		{"bad variable default", `query($t: String = 1) { echo(text: $t) }`, `default of $t: expected a string`},
+		{"duplicate variable", `query($t: String, $t: String) { echo(text: $t) }`, `only one variable named $t`},
+		{"anonymous among several", `{ echo } query B { echo }`, `an anonymous operation must be the only operation`},
+		{"duplicate operation", `query A { echo } query A { echo }`, `only one operation named "A"`},
+		{"unsupported subscription", `subscription { echo }`, `does not support subscription operations`},
+		{"unknown fragment", `{ ...F }`, `unknown fragment "F"`},
+		{"fragment on unknown type", `{ ...F } fragment F on Nope { id }`, `fragment "F" is on unknown type "Nope"`},
+		{"fragment on a scalar", `{ echo } fragment F on String { id }`, `cannot be on non-object type "String"`},
+		{"fragment on another type", `{ ...F } fragment F on Task { id }`, `fragment "F" on "Task" cannot be spread within "Query"`},
+		{"fragment cycle", `{ task(id: "1") { ...A } } fragment A on Task { ...B } fragment B on Task { parent { ...A } }`, `leads to a cycle`},
+		{"typename with selections", `{ __typename { x } }`, `__typename takes no arguments or selections`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			errs := s.Validate(doc)
+			if tt.want == "" {
+				if len(errs) > 0 {
+					t.Fatalf("errors = %v", errs)
+				}
+				return
+			}
+			var msgs []string
+			for _, e := range errs {
+				if strings.Contains(e.Message, tt.want) {
+					return
+				}
+				msgs = append(msgs, e.Message)
+			}
+			t.Errorf("errors = %q, want one containing %q", msgs, tt.want)
+		})
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxDepth int
+		query    string
+		ok       bool
+	}{
+		{"at the limit", 3, `{ task(id: "1") { parent { id } } }`, true},
+		{"over the limit", 3, `{ task(id: "1") { parent { parent { id } } } }`, false},
+		{"no limit", 0, `{ task(id: "1") { parent { parent { parent { parent { id } } } } } }`, true},
+		{"through a fragment", 2, `{ task(id: "1") { ...F } } fragment F on Task { parent { id } }`, false},
+		{"through an inline fragment", 2, `{ task(id: "1") { ... on Task { parent { id } } } }`, false},
+		{"introspection is not counted", 1, `{ __schema { types { fields { type { ofType { name } } } } } }`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testSchema(t, tt.maxDepth)
+			doc, err := Parse(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			errs := s.Validate(doc)
+			if tt.ok && len(errs) > 0 {
+				t.Fatalf("errors = %v", errs)
+			}
+			if !tt.ok && (len(errs) == 0 || !strings.Contains(errs[0].Message, "levels deep")) {
+				t.Fatalf("errors = %v, want the depth limit", errs)
+			}
+		})
+	}
+}
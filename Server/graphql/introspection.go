@@ -0,0 +1,277 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The introspection types, through which tools such as GraphiQL and code
+// generators read the schema.
+
+var typeKind = &Enum{
+	Name:        "__TypeKind",
+	Description: "The kinds of type in the schema.",
+	Values: enumValues("SCALAR", "OBJECT", "INTERFACE", "UNION", "ENUM",
+		"INPUT_OBJECT", "LIST", "NON_NULL"),
+}
+
+var directiveLocation = &Enum{
+	Name:        "__DirectiveLocation",
+	Description: "The places a directive may be used.",
+	Values: enumValues("QUERY", "MUTATION", "SUBSCRIPTION", "FIELD",
+		"FRAGMENT_DEFINITION", "FRAGMENT_SPREAD", "INLINE_FRAGMENT",
+		"VARIABLE_DEFINITION", "SCHEMA", "SCALAR", "OBJECT",
+		"FIELD_DEFINITION", "ARGUMENT_DEFINITION", "INTERFACE", "UNION",
+		"ENUM", "ENUM_VALUE", "INPUT_OBJECT", "INPUT_FIELD_DEFINITION"),
+}
+
+func enumValues(names ...string) []*EnumValueDef {
+	out := make([]*EnumValueDef, len(names))
+	for i, n := range names {
+		out[i] = &EnumValueDef{Name: n}
+	}
+	return out
+}
+
+var (
+	schemaType     = &Object{Name: "__Schema", Description: "The schema of this service."}
+	typeType       = &Object{Name: "__Type", Description: "A type of the schema."}
+	fieldType      = &Object{Name: "__Field", Description: "A field of an object type."}
+	inputValueType = &Object{Name: "__InputValue", Description: "An argument, or a field of an input object."}
+	enumValueType  = &Object{Name: "__EnumValue", Description: "A value of an enum type."}
+	directiveType  = &Object{Name: "__Directive", Description: "A directive the service supports."}
+)
+
+func init() {
+	nn := func(t Type) Type { return &NonNull{Of: t} }
+	list := func(t Type) Type { return &List{Of: t} }
+	includeDeprecated := []*Arg{{Name: "includeDeprecated", Type: Boolean, Default: false}}
+	str := func(fn func(p Params) string) Resolver {
+		return func(p Params) (any, error) {
+			if s := fn(p); s != "" {
+				return s, nil
+			}
+			return nil, nil
+		}
+	}
+
+	schemaType.Fields = []*FieldDef{
+		{Name: "description", Type: String, Resolve: func(Params) (any, error) { return nil, nil }},
+		{Name: "types", Type: nn(list(nn(typeType))), Resolve: func(p Params) (any, error) {
+			s := p.Source.(*Schema)
+			names := make([]string, 0, len(s.types))
+			for n := range s.types {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			out := make([]any, len(names))
+			for i, n := range names {
+				out[i] = s.types[n]
+			}
+			return out, nil
+		}},
+		{Name: "queryType", Type: nn(typeType), Resolve: func(p Params) (any, error) {
+			return p.Source.(*Schema).Query, nil
+		}},
+		{Name: "mutationType", Type: typeType, Resolve: func(p Params) (any, error) {
+			if t := p.Source.(*Schema).Mutation; t != nil {
+				return t, nil
+			}
+			return nil, nil
+		}},
+		{Name: "subscriptionType", Type: typeType, Resolve: func(p Params) (any, error) {
+			if t := p.Source.(*Schema).Subscription; t != nil {
+				return t, nil
+			}
+			return nil, nil
+		}},
+		{Name: "directives", Type: nn(list(nn(directiveType))), Resolve: func(Params) (any, error) {
+			return []*DirectiveDef{directives["include"], directives["skip"]}, nil
+		}},
+	}
+
+	typeType.Fields = []*FieldDef{
+		{Name: "kind", Type: nn(typeKind), Resolve: func(p Params) (any, error) {
+			switch p.Source.(type) {
+			case *Scalar:
+				return "SCALAR", nil
+			case *Object:
+				return "OBJECT", nil
+			case *Enum:
+				return "ENUM", nil
+			case *InputObject:
+				return "INPUT_OBJECT", nil
+			case *List:
+				return "LIST", nil
+			case *NonNull:
+				return "NON_NULL", nil
+			}
+			return nil, fmt.Errorf("unknown type %T", p.Source)
+		}},
+		{Name: "name", Type: String, Resolve: str(func(p Params) string {
+			if t, ok := p.Source.(NamedType); ok {
+				return t.TypeName()
+			}
+			return ""
+		})},
+		{Name: "description", Type: String, Resolve: str(func(p Params) string {
+			if t, ok := p.Source.(NamedType); ok {
+				return t.TypeDescription()
+			}
+			return ""
+		})},
+		{Name: "specifiedByURL", Type: String, Resolve: func(Params) (any, error) { return nil, nil }},
+		{Name: "fields", Type: list(nn(fieldType)), Args: includeDeprecated, Resolve: func(p Params) (any, error) {
+			t, ok := p.Source.(*Object)
+			if !ok {
+				return nil, nil
+			}
+			var out []*FieldDef
+			for _, f := range t.Fields {
+				if strings.HasPrefix(f.Name, "__") || f.Deprecation != "" && p.Args["includeDeprecated"] != true {
+					continue
+				}
+				out = append(out, f)
+			}
+			return out, nil
+		}},
+		{Name: "interfaces", Type: list(nn(typeType)), Resolve: func(p Params) (any, error) {
+			if _, ok := p.Source.(*Object); ok {
+				return []any{}, nil
+			}
+			return nil, nil
+		}},
+		{Name: "possibleTypes", Type: list(nn(typeType)), Resolve: func(Params) (any, error) { return nil, nil }},
+		{Name: "enumValues", Type: list(nn(enumValueType)), Args: includeDeprecated, Resolve: func(p Params) (any, error) {
+			if t, ok := p.Source.(*Enum); ok {
+				return t.Values, nil
+			}
+			return nil, nil
+		}},
+		{Name: "inputFields", Type: list(nn(inputValueType)), Args: includeDeprecated, Resolve: func(p Params) (any, error) {
+			if t, ok := p.Source.(*InputObject); ok {
+				return t.Fields, nil
+			}
+			return nil, nil
+		}},
+		{Name: "ofType", Type: typeType, Resolve: func(p Params) (any, error) {
+			switch t := p.Source.(type) {
+			case *List:
+				return t.Of, nil
+			case *NonNull:
+				return t.Of, nil
+			}
+			return nil, nil
+		}},
+		{Name: "isOneOf", Type: Boolean, Resolve: func(p Params) (any, error) {
+			if _, ok := p.Source.(*InputObject); ok {
+				return false, nil
+			}
+			return nil, nil
+		}},
+	}
+
+	fieldType.Fields = []*FieldDef{
+		{Name: "name", Type: nn(String)},
+		{Name: "description", Type: String, Resolve: str(func(p Params) string { return p.Source.(*FieldDef).Description })},
+		{Name: "args", Type: nn(list(nn(inputValueType))), Args: includeDeprecated},
+		{Name: "type", Type: nn(typeType)},
+		{Name: "isDeprecated", Type: nn(Boolean), Resolve: func(p Params) (any, error) {
+			return p.Source.(*FieldDef).Deprecation != "", nil
+		}},
+		{Name: "deprecationReason", Type: String, Resolve: str(func(p Params) string { return p.Source.(*FieldDef).Deprecation })},
+	}
+
+	inputValueType.Fields = []*FieldDef{
+		{Name: "name", Type: nn(String)},
+		{Name: "description", Type: String, Resolve: str(func(p Params) string { return p.Source.(*Arg).Description })},
+		{Name: "type", Type: nn(typeType)},
+		{Name: "defaultValue", Type: String, Resolve: func(p Params) (any, error) {
+			a := p.Source.(*Arg)
+			if a.Default == nil {
+				return nil, nil
+			}
+			return printValue(a.Default, a.Type), nil
+		}},
+		{Name: "isDeprecated", Type: nn(Boolean), Resolve: func(Params) (any, error) { return false, nil }},
+		{Name: "deprecationReason", Type: String, Resolve: func(Params) (any, error) { return nil, nil }},
+	}
+
+	enumValueType.Fields = []*FieldDef{
+		{Name: "name", Type: nn(String)},
+		{Name: "description", Type: String, Resolve: str(func(p Params) string { return p.Source.(*EnumValueDef).Description })},
+		{Name: "isDeprecated", Type: nn(Boolean), Resolve: func(Params) (any, error) { return false, nil }},
+		{Name: "deprecationReason", Type: String, Resolve: func(Params) (any, error) { return nil, nil }},
+	}
+
+	directiveType.Fields = []*FieldDef{
+		{Name: "name", Type: nn(String)},
+		{Name: "description", Type: String, Resolve: str(func(p Params) string { return p.Source.(*DirectiveDef).Description })},
+		{Name: "locations", Type: nn(list(nn(directiveLocation)))},
+		{Name: "args", Type: nn(list(nn(inputValueType))), Args: includeDeprecated},
+		{Name: "isRepeatable", Type: nn(Boolean), Resolve: func(Params) (any, error) { return false, nil }},
+	}
+}
+
+// introspectionFields are the __schema and __type fields of the query
+// type of s.
+func introspectionFields(s *Schema) []*FieldDef {
+	return []*FieldDef{
+		{Name: "__schema", Type: &NonNull{Of: schemaType}, Resolve: func(Params) (any, error) {
+			return s, nil
+		}},
+		{Name: "__type", Type: typeType, Args: []*Arg{{Name: "name", Type: &NonNull{Of: String}}}, Resolve: func(p Params) (any, error) {
+			if t := s.Type(p.Args["name"].(string)); t != nil {
+				return t, nil
+			}
+			return nil, nil
+		}},
+	}
+}
+
+// printValue writes an input value in JSON form as a GraphQL literal.
+func printValue(v any, t Type) string {
+	if nn, ok := t.(*NonNull); ok {
+		t = nn.Of
+	}
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		if _, ok := t.(*Enum); ok {
+			return v
+		}
+		return strconv.Quote(v)
+	case []any:
+		var elem Type = String
+		if l, ok := t.(*List); ok {
+			elem = l.Of
+		}
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = printValue(item, elem)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			var ft Type = String
+			if io, ok := t.(*InputObject); ok && io.field(k) != nil {
+				ft = io.field(k).Type
+			}
+			parts[i] = k + ": " + printValue(v[k], ft)
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,702 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// token kinds. Punctuators are their own text.
+const (
+	tokEOF    = "<EOF>"
+	tokName   = "Name"
+	tokInt    = "Int"
+	tokFloat  = "Float"
+	tokString = "String"
+	tokPunct  = "Punctuator"
+)
+
+type token struct {
+	kind string
+	text string // unescaped, for strings
+	loc  Location
+}
+
+type lexer struct {
+	src       string
+	pos       int
+	line      int
+	lineStart int
+}
+
+func (l *lexer) here() Location {
+	return Location{Line: l.line, Column: l.pos - l.lineStart + 1}
+}
+
+func (l *lexer) errorf(loc Location, format string, args ...any) error {
+	return &Error{Message: "syntax error: " + fmt.Sprintf(format, args...), Locations: []Location{loc}}
+}
+
+// skip passes over whitespace, commas and comments, all of which GraphQL
+// ignores.
+func (l *lexer) skip() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; c {
+		case ' ', '\t', ',':
+			l.pos++
+		case '\n', '\r':
+			l.pos++
+			if c == '\r' && l.pos < len(l.src) && l.src[l.pos] == '\n' {
+				l.pos++
+			}
+			l.line++
+			l.lineStart = l.pos
+		case '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			if strings.HasPrefix(l.src[l.pos:], "\ufeff") {
+				l.pos += len("\ufeff")
+				continue
+			}
+			return
+		}
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	l.skip()
+	loc := l.here()
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, loc: loc}, nil
+	}
+	c := l.src[l.pos]
+	switch {
+	case strings.IndexByte("!$&()=:@[]{}|", c) >= 0:
+		l.pos++
+		return token{kind: tokPunct, text: string(c), loc: loc}, nil
+	case c == '.':
+		if !strings.HasPrefix(l.src[l.pos:], "...") {
+			return token{}, l.errorf(loc, "unexpected %q", ".")
+		}
+		l.pos += 3
+		return token{kind: tokPunct, text: "...", loc: loc}, nil
+	case c == '_' || isLetter(c):
+		start := l.pos
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokName, text: l.src[start:l.pos], loc: loc}, nil
+	case c == '-' || isDigit(c):
+		return l.number(loc)
+	case c == '"':
+		if strings.HasPrefix(l.src[l.pos:], `"""`) {
+			return l.blockString(loc)
+		}
+		return l.string(loc)
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return token{}, l.errorf(loc, "unexpected character %q", r)
+}
+
+func (l *lexer) number(loc Location) (token, error) {
+	start := l.pos
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	digits := func() int {
+		n := 0
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+			n++
+		}
+		return n
+	}
+	intStart := l.pos
+	if digits() == 0 {
+		return token{}, l.errorf(loc, "invalid number")
+	}
+	if l.src[intStart] == '0' && l.pos-intStart > 1 {
+		return token{}, l.errorf(loc, "invalid number: leading zero")
+	}
+	kind := tokInt
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		l.pos++
+		kind = tokFloat
+		if digits() == 0 {
+			return token{}, l.errorf(loc, "invalid number")
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		l.pos++
+		kind = tokFloat
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		if digits() == 0 {
+			return token{}, l.errorf(loc, "invalid number")
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == '.' || l.src[l.pos] == '_' || isLetter(l.src[l.pos])) {
+		return token{}, l.errorf(loc, "invalid number")
+	}
+	return token{kind: kind, text: l.src[start:l.pos], loc: loc}, nil
+}
+
+func (l *lexer) string(loc Location) (token, error) {
+	l.pos++ // opening quote
+	var b strings.Builder
+	for {
+		if l.pos >= len(l.src) || l.src[l.pos] == '\n' || l.src[l.pos] == '\r' {
+			return token{}, l.errorf(loc, "unterminated string")
+		}
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return token{kind: tokString, text: b.String(), loc: loc}, nil
+		case c == '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, l.errorf(loc, "unterminated string")
+			}
+			esc := l.src[l.pos+1]
+			l.pos += 2
+			switch esc {
+			case '"', '\\', '/':
+				b.WriteByte(esc)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				r, err := l.unicodeEscape()
+				if err != nil {
+					return token{}, err
+				}
+				b.WriteRune(r)
+			default:
+				return token{}, l.errorf(l.here(), "invalid escape \\%c", esc)
+			}
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+}
+
+// unicodeEscape reads the digits of a \u escape, combining surrogate pairs.
+func (l *lexer) unicodeEscape() (rune, error) {
+	hex := func() (rune, bool) {
+		if l.pos+4 > len(l.src) {
+			return 0, false
+		}
+		n, err := strconv.ParseUint(l.src[l.pos:l.pos+4], 16, 32)
+		if err != nil {
+			return 0, false
+		}
+		l.pos += 4
+		return rune(n), true
+	}
+	loc := l.here()
+	r, ok := hex()
+	if !ok {
+		return 0, l.errorf(loc, "invalid unicode escape")
+	}
+	if r >= 0xD800 && r < 0xDC00 && strings.HasPrefix(l.src[l.pos:], `\u`) {
+		l.pos += 2
+		lo, ok := hex()
+		if !ok || lo < 0xDC00 || lo > 0xDFFF {
+			return 0, l.errorf(loc, "invalid unicode escape")
+		}
+		r = (r-0xD800)<<10 + (lo - 0xDC00) + 0x10000
+	}
+	// A surrogate left without its other half encodes no character.
+	if r >= 0xD800 && r <= 0xDFFF {
+		return 0, l.errorf(loc, "invalid unicode escape")
+	}
+	return r, nil
+}
+
+func (l *lexer) blockString(loc Location) (token, error) {
+	l.pos += 3
+	var raw strings.Builder
+	for {
+		if l.pos >= len(l.src) {
+			return token{}, l.errorf(loc, "unterminated string")
+		}
+		rest := l.src[l.pos:]
+		switch {
+		case strings.HasPrefix(rest, `"""`):
+			l.pos += 3
+			return token{kind: tokString, text: blockValue(raw.String()), loc: loc}, nil
+		case strings.HasPrefix(rest, `\"""`):
+			raw.WriteString(`"""`)
+			l.pos += 4
+		default:
+			c := l.src[l.pos]
+			raw.WriteByte(c)
+			l.pos++
+			if c == '\n' || c == '\r' && !strings.HasPrefix(l.src[l.pos:], "\n") {
+				l.line++
+				l.lineStart = l.pos
+			}
+		}
+	}
+}
+
+// blockValue strips the common indentation and the blank first and last
+// lines of a block string, as the spec's BlockStringValue does.
+func blockValue(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(strings.ReplaceAll(raw, "\r\n", "\n"), "\r", "\n"), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if n < len(line) && (indent < 0 || n < indent) {
+			indent = n
+		}
+	}
+	if indent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= indent {
+				lines[i] = lines[i][indent:]
+			} else {
+				lines[i] = ""
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[0], " \t") == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[len(lines)-1], " \t") == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// maxTokens bounds the work a single document can ask of the parser.
+const maxTokens = 10000
+
+type parser struct {
+	lex    lexer
+	tok    token
+	tokens int
+}
+
+// Parse parses a query document. It reports the first syntax error as an
+// *Error.
+func Parse(src string) (*Document, error) {
+	p := &parser{lex: lexer{src: src, line: 1}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	doc := &Document{Fragments: map[string]*Fragment{}}
+	if p.tok.kind == tokEOF {
+		return nil, p.unexpected()
+	}
+	for p.tok.kind != tokEOF {
+		if p.peek("{") {
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, &Operation{Kind: Query, Selections: sels, Loc: sels[0].location()})
+			continue
+		}
+		if p.tok.kind != tokName {
+			return nil, p.unexpected()
+		}
+		switch p.tok.text {
+		case Query, Mutation, Subscription:
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, op)
+		case "fragment":
+			f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := doc.Fragments[f.Name]; dup {
+				return nil, &Error{Message: fmt.Sprintf("there can be only one fragment named %q", f.Name), Locations: []Location{f.Loc}}
+			}
+			doc.Fragments[f.Name] = f
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	return doc, nil
+}
+
+func (p *parser) advance() error {
+	p.tokens++
+	if p.tokens > maxTokens {
+		return &Error{Message: fmt.Sprintf("document has more than %d tokens", maxTokens)}
+	}
+	t, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = t
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokEOF {
+		return p.lex.errorf(p.tok.loc, "unexpected end of document")
+	}
+	return p.lex.errorf(p.tok.loc, "unexpected %q", p.tok.text)
+}
+
+// peek reports whether the current token is the punctuator s.
+func (p *parser) peek(s string) bool {
+	return p.tok.kind == tokPunct && p.tok.text == s
+}
+
+// skip consumes the punctuator s if it is next.
+func (p *parser) skip(s string) (bool, error) {
+	if !p.peek(s) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+func (p *parser) expect(s string) error {
+	if !p.peek(s) {
+		return p.lex.errorf(p.tok.loc, "expected %q, found %s", s, p.describe())
+	}
+	return p.advance()
+}
+
+func (p *parser) describe() string {
+	switch p.tok.kind {
+	case tokEOF:
+		return "end of document"
+	case tokString:
+		return "string " + strconv.Quote(p.tok.text)
+	}
+	return strconv.Quote(p.tok.text)
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokName {
+		return "", p.lex.errorf(p.tok.loc, "expected a name, found %s", p.describe())
+	}
+	s := p.tok.text
+	return s, p.advance()
+}
+
+func (p *parser) operation() (*Operation, error) {
+	op := &Operation{Kind: p.tok.text, Loc: p.tok.loc}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokName {
+		op.Name = p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek("(") {
+		vars, err := p.varDefs()
+		if err != nil {
+			return nil, err
+		}
+		op.Vars = vars
+	}
+	var err error
+	if op.Directives, err = p.directives(false); err != nil {
+		return nil, err
+	}
+	if op.Selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (p *parser) varDefs() ([]*VarDef, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var defs []*VarDef
+	for {
+		if ok, err := p.skip(")"); err != nil || ok {
+			return defs, err
+		}
+		d := &VarDef{Loc: p.tok.loc}
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		var err error
+		if d.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if d.Type, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if ok, err := p.skip("="); err != nil {
+			return nil, err
+		} else if ok {
+			if d.Default, err = p.value(true); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := p.directives(true); err != nil {
+			return nil, err
+		}
+		defs = append(defs, d)
+	}
+}
+
+func (p *parser) typeRef() (*TypeRef, error) {
+	t := &TypeRef{}
+	if ok, err := p.skip("["); err != nil {
+		return nil, err
+	} else if ok {
+		if t.Elem, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	} else {
+		if t.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	ok, err := p.skip("!")
+	t.NonNull = ok
+	return t, err
+}
+
+func (p *parser) directives(isConst bool) ([]*Directive, error) {
+	var ds []*Directive
+	for p.peek("@") {
+		d := &Directive{Loc: p.tok.loc}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		var err error
+		if d.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if d.Args, err = p.arguments(isConst); err != nil {
+			return nil, err
+		}
+		ds = append(ds, d)
+	}
+	return ds, nil
+}
+
+func (p *parser) arguments(isConst bool) ([]*Argument, error) {
+	if ok, err := p.skip("("); err != nil || !ok {
+		return nil, err
+	}
+	var args []*Argument
+	for {
+		if ok, err := p.skip(")"); err != nil {
+			return nil, err
+		} else if ok {
+			if len(args) == 0 {
+				return nil, p.lex.errorf(p.tok.loc, "expected an argument")
+			}
+			return args, nil
+		}
+		a := &Argument{Loc: p.tok.loc}
+		var err error
+		if a.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if a.Value, err = p.value(isConst); err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+	}
+}
+
+func (p *parser) selectionSet() ([]Selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sels []Selection
+	for {
+		if ok, err := p.skip("}"); err != nil {
+			return nil, err
+		} else if ok {
+			if len(sels) == 0 {
+				return nil, p.lex.errorf(p.tok.loc, "expected a selection")
+			}
+			return sels, nil
+		}
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, s)
+	}
+}
+
+func (p *parser) selection() (Selection, error) {
+	loc := p.tok.loc
+	if ok, err := p.skip("..."); err != nil {
+		return nil, err
+	} else if ok {
+		if p.tok.kind == tokName && p.tok.text != "on" {
+			s := &FragmentSpread{Loc: loc, Name: p.tok.text}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			s.Directives, err = p.directives(false)
+			return s, err
+		}
+		f := &InlineFragment{Loc: loc}
+		if p.tok.kind == tokName {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if f.TypeCond, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if f.Directives, err = p.directives(false); err != nil {
+			return nil, err
+		}
+		f.Selections, err = p.selectionSet()
+		return f, err
+	}
+
+	f := &Field{Loc: loc}
+	var err error
+	if f.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		f.Alias = f.Name
+		if f.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if f.Args, err = p.arguments(false); err != nil {
+		return nil, err
+	}
+	if f.Directives, err = p.directives(false); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		if f.Selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (p *parser) fragment() (*Fragment, error) {
+	f := &Fragment{Loc: p.tok.loc}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var err error
+	if f.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if f.Name == "on" {
+		return nil, p.lex.errorf(f.Loc, "a fragment cannot be named \"on\"")
+	}
+	if p.tok.kind != tokName || p.tok.text != "on" {
+		return nil, p.lex.errorf(p.tok.loc, "expected \"on\", found %s", p.describe())
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if f.TypeCond, err = p.name(); err != nil {
+		return nil, err
+	}
+	if f.Directives, err = p.directives(false); err != nil {
+		return nil, err
+	}
+	f.Selections, err = p.selectionSet()
+	return f, err
+}
+
+// value parses a literal; in constant contexts variables are not allowed.
+func (p *parser) value(isConst bool) (*Value, error) {
+	v := &Value{Loc: p.tok.loc}
+	switch t := p.tok; {
+	case t.kind == tokPunct && t.text == "$" && !isConst:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		v.Kind = VariableValue
+		var err error
+		v.Raw, err = p.name()
+		return v, err
+	case t.kind == tokPunct && t.text == "[":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		v.Kind = ListValue
+		for !p.peek("]") {
+			item, err := p.value(isConst)
+			if err != nil {
+				return nil, err
+			}
+			v.List = append(v.List, item)
+		}
+		return v, p.advance()
+	case t.kind == tokPunct && t.text == "{":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		v.Kind = ObjectValue
+		for !p.peek("}") {
+			f := &ObjectField{Loc: p.tok.loc}
+			var err error
+			if f.Name, err = p.name(); err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if f.Value, err = p.value(isConst); err != nil {
+				return nil, err
+			}
+			v.Fields = append(v.Fields, f)
+		}
+		return v, p.advance()
+	case t.kind == tokInt:
+		v.Kind = IntValue
+	case t.kind == tokFloat:
+		v.Kind = FloatValue
+	case t.kind == tokString:
+		v.Kind = StringValue
+	case t.kind == tokName && (t.text == "true" || t.text == "false"):
+		v.Kind = BooleanValue
+	case t.kind == tokName && t.text == "null":
+		v.Kind = NullValue
+	case t.kind == tokName:
+		v.Kind = EnumValue
+	default:
+		return nil, p.unexpected()
+	}
+	v.Raw = p.tok.text
+	return v, p.advance()
+}
@@ -0,0 +1,277 @@
+package graphql
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLexer(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []token // kinds and texts; locations are checked separately
+	}{
+		{"punctuators", "{ ( ) } ! $ : = @ [ ] |", []token{
+			{kind: tokPunct, text: "{"}, {kind: tokPunct, text: "("}, {kind: tokPunct, text: ")"},
+			{kind: tokPunct, text: "}"}, {kind: tokPunct, text: "!"}, {kind: tokPunct, text: "$"},
+			{kind: tokPunct, text: ":"}, {kind: tokPunct, text: "="}, {kind: tokPunct, text: "@"},
+			{kind: tokPunct, text: "["}, {kind: tokPunct, text: "]"}, {kind: tokPunct, text: "|"},
+		}},
+		{"spread", "...on", []token{{kind: tokPunct, text: "..."}, {kind: tokName, text: "on"}}},
+		{"names", "_a b2 __typename", []token{{kind: tokName, text: "_a"}, {kind: tokName, text: "b2"}, {kind: tokName, text: "__typename"}}},
+		{"ignored", "\ufeff a,,b # comment\r\n\tc", []token{{kind: tokName, text: "a"}, {kind: tokName, text: "b"}, {kind: tokName, text: "c"}}},
+		{"ints", "0 -0 42 -7", []token{{kind: tokInt, text: "0"}, {kind: tokInt, text: "-0"}, {kind: tokInt, text: "42"}, {kind: tokInt, text: "-7"}}},
+		{"floats", "1.5 -0.25 1e3 2E-2 3.0e+1", []token{
+			{kind: tokFloat, text: "1.5"}, {kind: tokFloat, text: "-0.25"}, {kind: tokFloat, text: "1e3"},
+			{kind: tokFloat, text: "2E-2"}, {kind: tokFloat, text: "3.0e+1"},
+		}},
+		{"string escapes", `"a\"b\\c\/d\n\t\u00e9"`, []token{{kind: tokString, text: "a\"b\\c/d\n\té"}}},
+		{"surrogate pair", `"\ud83d\ude00"`, []token{{kind: tokString, text: "😀"}}},
+		{"unicode", `"héllo"`, []token{{kind: tokString, text: "héllo"}}},
+		{"block string", "\"\"\"\n    first\n      second\n    \"\"\"", []token{{kind: tokString, text: "first\n  second"}}},
+		{"block string quotes", `"""a \""" b"""`, []token{{kind: tokString, text: `a """ b`}}},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := lexer{src: tt.src, line: 1}
+			var got []token
+			for {
+				tok, err := l.next()
+				if err != nil {
+					t.Fatalf("next: %v", err)
+				}
+				if tok.kind == tokEOF {
+					break
+				}
+				got = append(got, token{kind: tok.kind, text: tok.text})
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d tokens %v, want %d %v", len(got), got, len(tt.want), tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("token %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+		loc  Location
+	}{
+		{"lone dot", "..", `unexpected "."`, Location{1, 1}},
+		{"bad character", "a ?", `unexpected character '?'`, Location{1, 3}},
+		{"leading zero", "01", "leading zero", Location{1, 1}},
+		{"bare minus", "-", "invalid number", Location{1, 1}},
+		{"no fraction", "1.", "invalid number", Location{1, 1}},
+		{"no exponent", "1e", "invalid number", Location{1, 1}},
+		{"name after number", "12ab", "invalid number", Location{1, 1}},
+		{"unterminated", `"abc`, "unterminated string", Location{1, 1}},
+		{"newline in string", "\"ab\ncd\"", "unterminated string", Location{1, 1}},
+		{"bad escape", `"\x"`, `invalid escape \x`, Location{1, 4}},
+		{"short unicode", `"\u12"`, "invalid unicode escape", Location{1, 4}},
+		{"lone high surrogate", `"\ud83dA"`, "invalid unicode escape", Location{1, 4}},
+		{"lone low surrogate", `"\ude00"`, "invalid unicode escape", Location{1, 4}},
+		{"unterminated block", `"""abc`, "unterminated string", Location{1, 1}},
+		{"on a later line", "a\n  b\r\n   ?", "unexpected character", Location{3, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := lexer{src: tt.src, line: 1}
+			var err error
+			for {
+				var tok token
+				if tok, err = l.next(); err != nil || tok.kind == tokEOF {
+					break
+				}
+			}
+			var gerr *Error
+			if !errors.As(err, &gerr) {
+				t.Fatalf("err = %v, want an *Error", err)
+			}
+			if !strings.Contains(gerr.Message, tt.want) {
+				t.Errorf("message = %q, want it to contain %q", gerr.Message, tt.want)
+			}
+			if len(gerr.Locations) != 1 || gerr.Locations[0] != tt.loc {
+				t.Errorf("locations = %v, want [%v]", gerr.Locations, tt.loc)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	doc, err := Parse(`
+		query Tasks($first: Int = 10, $ids: [ID!]!) @skip(if: false) {
+			all: tasks(first: $first, filter: {ids: $ids, done: false, tags: ["a", B]}) {
+				id
+				...Details
+				... on Task @include(if: true) { title }
+				... { status }
+			}
+		}
+		mutation { add(title: "x", weight: 1.5, due: null) { id } }
+		fragment Details on Task { description }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Operations) != 2 || len(doc.Fragments) != 1 {
+		t.Fatalf("got %d operations and %d fragments, want 2 and 1", len(doc.Operations), len(doc.Fragments))
+	}
+
+	op := doc.Operations[0]
+	if op.Kind != Query || op.Name != "Tasks" || op.Loc != (Location{2, 3}) {
+		t.Errorf("operation = %s %s at %v", op.Kind, op.Name, op.Loc)
+	}
+	if len(op.Vars) != 2 {
+		t.Fatalf("got %d variables, want 2", len(op.Vars))
+	}
+	if got := op.Vars[0].Type.String(); got != "Int" || op.Vars[0].Default.Raw != "10" {
+		t.Errorf("$first: %s = %v", got, op.Vars[0].Default)
+	}
+	if got := op.Vars[1].Type.String(); got != "[ID!]!" {
+		t.Errorf("$ids has type %s, want [ID!]!", got)
+	}
+	if len(op.Directives) != 1 || op.Directives[0].Name != "skip" {
+		t.Errorf("directives = %v", op.Directives)
+	}
+
+	all := op.Selections[0].(*Field)
+	if all.Alias != "all" || all.Name != "tasks" || all.Key() != "all" {
+		t.Errorf("field = %s: %s", all.Alias, all.Name)
+	}
+	filter := all.Args[1].Value
+	if filter.Kind != ObjectValue || len(filter.Fields) != 3 {
+		t.Fatalf("filter = %+v", filter)
+	}
+	if v := filter.Fields[0].Value; v.Kind != VariableValue || v.Raw != "ids" {
+		t.Errorf("filter.ids = %+v", v)
+	}
+	if v := filter.Fields[1].Value; v.Kind != BooleanValue || v.Raw != "false" {
+		t.Errorf("filter.done = %+v", v)
+	}
+	if v := filter.Fields[2].Value; v.Kind != ListValue || v.List[0].Kind != StringValue || v.List[1].Kind != EnumValue {
+		t.Errorf("filter.tags = %+v", v)
+	}
+
+	sels := all.Selections
+	if len(sels) != 4 {
+		t.Fatalf("got %d selections, want 4", len(sels))
+	}
+	if s, ok := sels[1].(*FragmentSpread); !ok || s.Name != "Details" {
+		t.Errorf("selection 1 = %#v", sels[1])
+	}
+	if s, ok := sels[2].(*InlineFragment); !ok || s.TypeCond != "Task" || len(s.Directives) != 1 {
+		t.Errorf("selection 2 = %#v", sels[2])
+	}
+	if s, ok := sels[3].(*InlineFragment); !ok || s.TypeCond != "" {
+		t.Errorf("selection 3 = %#v", sels[3])
+	}
+
+	add := doc.Operations[1].Selections[0].(*Field)
+	kinds := []ValueKind{StringValue, FloatValue, NullValue}
+	for i, a := range add.Args {
+		if a.Value.Kind != kinds[i] {
+			t.Errorf("argument %s has kind %d, want %d", a.Name, a.Value.Kind, kinds[i])
+		}
+	}
+	if f := doc.Fragments["Details"]; f.TypeCond != "Task" {
+		t.Errorf("fragment is on %q", f.TypeCond)
+	}
+}
+
+func TestParseShorthand(t *testing.T) {
+	doc, err := Parse("{ a b { c } }")
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := doc.Operations[0]
+	if op.Kind != Query || op.Name != "" || len(op.Selections) != 2 {
+		t.Errorf("operation = %+v", op)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"empty document", "", "unexpected end of document"},
+		{"only a comment", "# nothing", "unexpected end of document"},
+		{"empty selection set", "{ }", "expected a selection"},
+		{"unclosed selection set", "{ a", "expected a name, found end of document"},
+		{"empty arguments", "{ a() }", "expected an argument"},
+		{"argument without value", "{ a(b:) }", `unexpected ")"`},
+		{"variable in default", "query($a: Int = $b) { a }", `unexpected "$"`},
+		{"variable in directive of a definition", "query($a: Int @d(x: $y)) { a }", `unexpected "$"`},
+		{"unclosed list", "{ a(b: [1, 2) }", `unexpected ")"`},
+		{"unclosed list type", "query($a: [Int) { a }", `expected "]"`},
+		{"fragment named on", "fragment on on T { a }", `cannot be named "on"`},
+		{"fragment without type", "fragment F { a }", `expected "on", found "{"`},
+		{"duplicate fragment", "fragment F on T { a } fragment F on T { b }", `only one fragment named "F"`},
+		{"type definition", "type T { a: Int }", `unexpected "type"`},
+		{"stray token", "{ a } }", `unexpected "}"`},
+		{"alias without name", "{ a: }", `expected a name, found "}"`},
+		{"string as name", `{ "a" }`, `expected a name, found string "a"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.src)
+			if err == nil {
+				t.Fatal("Parse succeeded")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTokenLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields int
+		ok     bool
+	}{
+		{"under the limit", maxTokens - 3, true},
+		{"over the limit", maxTokens, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Braces and the end of the document take three tokens.
+			src := "{" + strings.Repeat(" a", tt.fields) + " }"
+			_, err := Parse(src)
+			if tt.ok && err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !tt.ok && (err == nil || !strings.Contains(err.Error(), "more than")) {
+				t.Fatalf("err = %v, want the token limit", err)
+			}
+		})
+	}
+}
+
+func TestBlockValue(t *testing.T) {
+	tests := []struct {
+		raw, want string
+	}{
+		{"hello", "hello"},
+		{"\n  a\n    b\n  ", "a\n  b"},
+		{"first\n  second\n  third", "first\nsecond\nthird"},
+		{"\r\n  a\r\n\r\n  b\r\n", "a\n\nb"},
+		{"  \n\n", ""},
+	}
+	for _, tt := range tests {
+		if got := blockValue(tt.raw); got != tt.want {
+			t.Errorf("blockValue(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
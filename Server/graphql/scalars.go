@@ -0,0 +1,190 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// The built-in scalars, and DateTime.
+var (
+	String = &Scalar{
+		Name:        "String",
+		Description: "UTF-8 text.",
+		Serialize: func(v any) (any, error) {
+			if rv := reflect.ValueOf(v); rv.Kind() == reflect.String {
+				return rv.String(), nil
+			}
+			if s, ok := v.(fmt.Stringer); ok {
+				return s.String(), nil
+			}
+			return nil, fmt.Errorf("cannot represent %T as String", v)
+		},
+		Parse: func(v any) (any, error) {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			return nil, fmt.Errorf("expected a string, found %s", describe(v))
+		},
+	}
+
+	Int = &Scalar{
+		Name:        "Int",
+		Description: "A signed 32-bit integer.",
+		Serialize: func(v any) (any, error) {
+			var n int64
+			rv := reflect.ValueOf(v)
+			switch rv.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				n = rv.Int()
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				if rv.Uint() > math.MaxInt32 {
+					return nil, fmt.Errorf("%d does not fit an Int", rv.Uint())
+				}
+				n = int64(rv.Uint())
+			default:
+				return nil, fmt.Errorf("cannot represent %T as Int", v)
+			}
+			if n < math.MinInt32 || n > math.MaxInt32 {
+				return nil, fmt.Errorf("%d does not fit an Int", n)
+			}
+			return n, nil
+		},
+		Parse: func(v any) (any, error) {
+			num, ok := v.(json.Number)
+			if !ok {
+				return nil, fmt.Errorf("expected an integer, found %s", describe(v))
+			}
+			n, err := strconv.ParseInt(string(num), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("expected a 32-bit integer, found %s", num)
+			}
+			return int(n), nil
+		},
+	}
+
+	Float = &Scalar{
+		Name:        "Float",
+		Description: "A double-precision number.",
+		Serialize: func(v any) (any, error) {
+			rv := reflect.ValueOf(v)
+			switch rv.Kind() {
+			case reflect.Float32, reflect.Float64:
+				f := rv.Float()
+				if math.IsInf(f, 0) || math.IsNaN(f) {
+					return nil, fmt.Errorf("cannot represent %v as Float", f)
+				}
+				return f, nil
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				return float64(rv.Int()), nil
+			}
+			return nil, fmt.Errorf("cannot represent %T as Float", v)
+		},
+		Parse: func(v any) (any, error) {
+			num, ok := v.(json.Number)
+			if !ok {
+				return nil, fmt.Errorf("expected a number, found %s", describe(v))
+			}
+			f, err := num.Float64()
+			if err != nil || math.IsInf(f, 0) {
+				return nil, fmt.Errorf("expected a number, found %s", num)
+			}
+			return f, nil
+		},
+	}
+
+	Boolean = &Scalar{
+		Name:        "Boolean",
+		Description: "true or false.",
+		Serialize: func(v any) (any, error) {
+			if rv := reflect.ValueOf(v); rv.Kind() == reflect.Bool {
+				return rv.Bool(), nil
+			}
+			return nil, fmt.Errorf("cannot represent %T as Boolean", v)
+		},
+		Parse: func(v any) (any, error) {
+			if b, ok := v.(bool); ok {
+				return b, nil
+			}
+			return nil, fmt.Errorf("expected a boolean, found %s", describe(v))
+		},
+	}
+
+	ID = &Scalar{
+		Name:        "ID",
+		Description: "An opaque identifier, serialized as a string.",
+		Serialize: func(v any) (any, error) {
+			rv := reflect.ValueOf(v)
+			switch rv.Kind() {
+			case reflect.String:
+				return rv.String(), nil
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				return strconv.FormatInt(rv.Int(), 10), nil
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				return strconv.FormatUint(rv.Uint(), 10), nil
+			}
+			return nil, fmt.Errorf("cannot represent %T as ID", v)
+		},
+		Parse: func(v any) (any, error) {
+			switch v := v.(type) {
+			case string:
+				return v, nil
+			case json.Number:
+				if _, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+					return string(v), nil
+				}
+			}
+			return nil, fmt.Errorf("expected an ID, found %s", describe(v))
+		},
+	}
+
+	// DateTime is an RFC 3339 timestamp. Resolvers receive a time.Time.
+	DateTime = &Scalar{
+		Name:        "DateTime",
+		Description: "An RFC 3339 timestamp, such as 2024-05-01T09:00:00Z.",
+		Serialize: func(v any) (any, error) {
+			switch t := v.(type) {
+			case time.Time:
+				return t.Format(time.RFC3339Nano), nil
+			case *time.Time:
+				return t.Format(time.RFC3339Nano), nil
+			}
+			return nil, fmt.Errorf("cannot represent %T as DateTime", v)
+		},
+		Parse: func(v any) (any, error) {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected an RFC 3339 timestamp, found %s", describe(v))
+			}
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return nil, fmt.Errorf("expected an RFC 3339 timestamp, found %q", s)
+			}
+			return t, nil
+		},
+	}
+)
+
+// describe names the kind of an input value for error messages.
+func describe(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	case json.Number:
+		return string(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case enumName:
+		return string(v)
+	case []any:
+		return "a list"
+	case map[string]any:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,353 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+)
+
+// Type is a type of the schema: a named type (*Scalar, *Enum, *Object or
+// *InputObject), or a *List or *NonNull wrapping one.
+type Type interface {
+	String() string
+}
+
+// NamedType is a type other than a wrapper.
+type NamedType interface {
+	Type
+	TypeName() string
+	TypeDescription() string
+}
+
+type List struct{ Of Type }
+
+type NonNull struct{ Of Type }
+
+func (t *List) String() string    { return "[" + t.Of.String() + "]" }
+func (t *NonNull) String() string { return t.Of.String() + "!" }
+
+// Scalar is a leaf type. Serialize turns a resolved Go value into its JSON
+// form. Parse turns an input into the Go value resolvers receive; inputs
+// arrive as strings, bools, json.Numbers and nil, whether they were
+// written in the query or passed as variables.
+type Scalar struct {
+	Name        string
+	Description string
+	Serialize   func(v any) (any, error)
+	Parse       func(v any) (any, error)
+}
+
+func (t *Scalar) String() string          { return t.Name }
+func (t *Scalar) TypeName() string        { return t.Name }
+func (t *Scalar) TypeDescription() string { return t.Description }
+
+// Enum is a leaf type taking one of a fixed set of names. Resolvers
+// receive, and return, the Value of an EnumValueDef, or its name when Value
+// is nil.
+type Enum struct {
+	Name        string
+	Description string
+	Values      []*EnumValueDef
+}
+
+type EnumValueDef struct {
+	Name        string
+	Description string
+	Value       any
+}
+
+func (t *Enum) String() string          { return t.Name }
+func (t *Enum) TypeName() string        { return t.Name }
+func (t *Enum) TypeDescription() string { return t.Description }
+
+func (t *Enum) byName(name string) (*EnumValueDef, bool) {
+	for _, v := range t.Values {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func (v *EnumValueDef) value() any {
+	if v.Value == nil {
+		return v.Name
+	}
+	return v.Value
+}
+
+// Object is a type with fields. Its fields may refer to objects defined
+// later, so they can be assigned after the Object is created.
+type Object struct {
+	Name        string
+	Description string
+	Fields      []*FieldDef
+}
+
+func (t *Object) String() string          { return t.Name }
+func (t *Object) TypeName() string        { return t.Name }
+func (t *Object) TypeDescription() string { return t.Description }
+
+// Field returns the field named name, or nil.
+func (t *Object) Field(name string) *FieldDef {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// FieldDef defines a field of an object. A field without a resolver
+// reads the source value: the key of a map, or the struct field whose
+// JSON name, in camel case, is the field's name.
+type FieldDef struct {
+	Name        string
+	Description string
+	Type        Type
+	Args        []*Arg
+	Resolve     Resolver
+	// Subscribe starts the event stream of a field of the subscription
+	// type. The field is resolved once for each value received, with the
+	// value as its source; without a resolver, the value is the field's.
+	Subscribe func(p Params) (<-chan any, error)
+	// Deprecation, when set, marks the field deprecated for that reason.
+	Deprecation string
+}
+
+// Resolver returns the value of a field.
+type Resolver func(p Params) (any, error)
+
+// Params are the inputs of a resolver.
+type Params struct {
+	Context context.Context
+	// Source is the value of the object the field belongs to; nil for
+	// the fields of the root types.
+	Source any
+	// Args holds the arguments given, and the defaults of those that were
+	// not. An argument given as null is present with a nil value.
+	Args map[string]any
+}
+
+// Arg is an argument of a field, or a field of an input object.
+type Arg struct {
+	Name        string
+	Description string
+	Type        Type
+	// Default, if not nil, is used when the argument is not given. It is
+	// given in input form, like a variable's value.
+	Default any
+}
+
+// InputObject is a structured argument type. Resolvers receive it as a
+// map[string]any holding the fields that were given.
+type InputObject struct {
+	Name        string
+	Description string
+	Fields      []*Arg
+}
+
+func (t *InputObject) String() string          { return t.Name }
+func (t *InputObject) TypeName() string        { return t.Name }
+func (t *InputObject) TypeDescription() string { return t.Description }
+
+func (t *InputObject) field(name string) *Arg {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Schema is a validated set of root types.
+type Schema struct {
+	Query        *Object
+	Mutation     *Object
+	Subscription *Object
+	types        map[string]NamedType
+	// MaxDepth bounds how deeply selections may nest; 0 means no bound.
+	MaxDepth int
+	// PresentError, if set, turns the errors resolvers return into the
+	// ones reported to clients. Errors of type *Error are reported as they
+	// are.
+	PresentError func(ctx context.Context, err error) *Error
+}
+
+// NewSchema checks the types reachable from the roots, and adds the
+// introspection fields __schema and __type to query. mutation and
+// subscription may be nil.
+func NewSchema(query, mutation, subscription *Object) (*Schema, error) {
+	s := &Schema{Query: query, Mutation: mutation, Subscription: subscription, types: map[string]NamedType{}}
+	for _, t := range []NamedType{String, Int, Float, Boolean, ID} {
+		s.types[t.TypeName()] = t
+	}
+	if query == nil {
+		return nil, fmt.Errorf("graphql: schema has no query type")
+	}
+	query.Fields = append(query.Fields, introspectionFields(s)...)
+	for _, root := range []*Object{query, mutation, subscription} {
+		if root != nil {
+			if err := s.add(root); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if subscription != nil {
+		for _, f := range subscription.Fields {
+			if f.Subscribe == nil {
+				return nil, fmt.Errorf("graphql: subscription field %s has no Subscribe function", f.Name)
+			}
+		}
+	}
+	return s, nil
+}
+
+// add registers t and the types it refers to.
+func (s *Schema) add(t Type) error {
+	n := named(t)
+	if prev, ok := s.types[n.TypeName()]; ok {
+		if prev != n {
+			return fmt.Errorf("graphql: two types named %s", n.TypeName())
+		}
+		return nil
+	}
+	if !validName(n.TypeName()) {
+		return fmt.Errorf("graphql: invalid type name %q", n.TypeName())
+	}
+	s.types[n.TypeName()] = n
+	switch n := n.(type) {
+	case *Object:
+		if len(n.Fields) == 0 {
+			return fmt.Errorf("graphql: object %s has no fields", n.Name)
+		}
+		for _, f := range n.Fields {
+			if !validName(f.Name) {
+				return fmt.Errorf("graphql: invalid field name %s.%s", n.Name, f.Name)
+			}
+			if f.Type == nil {
+				return fmt.Errorf("graphql: field %s.%s has no type", n.Name, f.Name)
+			}
+			if isInputType(f.Type) && !isOutputType(f.Type) {
+				return fmt.Errorf("graphql: field %s.%s has input type %s", n.Name, f.Name, f.Type)
+			}
+			if err := s.add(f.Type); err != nil {
+				return err
+			}
+			if err := s.addArgs(n.Name+"."+f.Name, f.Args); err != nil {
+				return err
+			}
+		}
+	case *InputObject:
+		if err := s.addArgs(n.Name, n.Fields); err != nil {
+			return err
+		}
+	case *Enum:
+		if len(n.Values) == 0 {
+			return fmt.Errorf("graphql: enum %s has no values", n.Name)
+		}
+	case *Scalar:
+		if n.Serialize == nil || n.Parse == nil {
+			return fmt.Errorf("graphql: scalar %s needs Serialize and Parse", n.Name)
+		}
+	}
+	return nil
+}
+
+func (s *Schema) addArgs(owner string, args []*Arg) error {
+	for _, a := range args {
+		if a.Type == nil || !isInputType(a.Type) {
+			return fmt.Errorf("graphql: argument %s(%s) does not have an input type", owner, a.Name)
+		}
+		if err := s.add(a.Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Type returns the named type called name, or nil.
+func (s *Schema) Type(name string) NamedType {
+	return s.types[name]
+}
+
+// root returns the root type of an operation kind, or nil.
+func (s *Schema) root(kind string) *Object {
+	switch kind {
+	case Mutation:
+		return s.Mutation
+	case Subscription:
+		return s.Subscription
+	}
+	return s.Query
+}
+
+// resolve finds the schema type a variable definition names.
+func (s *Schema) resolve(ref *TypeRef) Type {
+	var t Type
+	if ref.Elem != nil {
+		elem := s.resolve(ref.Elem)
+		if elem == nil {
+			return nil
+		}
+		t = &List{Of: elem}
+	} else if n := s.types[ref.Name]; n != nil {
+		t = n
+	} else {
+		return nil
+	}
+	if ref.NonNull {
+		t = &NonNull{Of: t}
+	}
+	return t
+}
+
+// named unwraps lists and non-nulls.
+func named(t Type) NamedType {
+	for {
+		switch w := t.(type) {
+		case *List:
+			t = w.Of
+		case *NonNull:
+			t = w.Of
+		default:
+			return t.(NamedType)
+		}
+	}
+}
+
+func isInputType(t Type) bool {
+	switch named(t).(type) {
+	case *Scalar, *Enum, *InputObject:
+		return true
+	}
+	return false
+}
+
+func isOutputType(t Type) bool {
+	switch named(t).(type) {
+	case *Scalar, *Enum, *Object:
+		return true
+	}
+	return false
+}
+
+func isLeaf(t Type) bool {
+	switch named(t).(type) {
+	case *Scalar, *Enum:
+		return true
+	}
+	return false
+}
+
+func validName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '_' && !isLetter(c) && (i == 0 || !isDigit(c)) {
+			return false
+		}
+	}
+	return true
+}
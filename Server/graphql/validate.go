@@ -0,0 +1,396 @@
+package graphql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DirectiveDef is a directive the executor understands.
+type DirectiveDef struct {
+	Name        string
+	Description string
+	Locations   []string
+	Args        []*Arg
+}
+
+// directives are the directives supported in queries: @skip and @include.
+var directives = map[string]*DirectiveDef{
+	"skip": {
+		Name:        "skip",
+		Description: "Leaves out the selection when if is true.",
+		Locations:   []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		Args:        []*Arg{{Name: "if", Type: &NonNull{Of: Boolean}}},
+	},
+	"include": {
+		Name:        "include",
+		Description: "Includes the selection only when if is true.",
+		Locations:   []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		Args:        []*Arg{{Name: "if", Type: &NonNull{Of: Boolean}}},
+	},
+}
+
+type validator struct {
+	schema *Schema
+	doc    *Document
+	errs   []*Error
+	// The operation being checked, and the variables it uses.
+	op   *Operation
+	vars map[string]*VarDef
+	used map[string]bool
+}
+
+func (v *validator) errorf(loc Location, format string, args ...any) {
+	v.errs = append(v.errs, &Error{Message: fmt.Sprintf(format, args...), Locations: []Location{loc}})
+}
+
+// Validate checks a document against the schema, reporting every problem
+// found. Only documents without errors may be executed.
+func (s *Schema) Validate(doc *Document) []*Error {
+	v := &validator{schema: s, doc: doc}
+	names := map[string]bool{}
+	for _, op := range doc.Operations {
+		if op.Name == "" && len(doc.Operations) > 1 {
+			v.errorf(op.Loc, "an anonymous operation must be the only operation in the document")
+		}
+		if op.Name != "" {
+			if names[op.Name] {
+				v.errorf(op.Loc, "there can be only one operation named %q", op.Name)
+			}
+			names[op.Name] = true
+		}
+		v.operation(op)
+	}
+	for name, f := range doc.Fragments {
+		if s.Type(f.TypeCond) == nil {
+			v.errorf(f.Loc, "fragment %q is on unknown type %q", name, f.TypeCond)
+		} else if _, ok := s.Type(f.TypeCond).(*Object); !ok {
+			v.errorf(f.Loc, "fragment %q cannot be on non-object type %q", name, f.TypeCond)
+		}
+		if cycle := v.cycle(f, map[string]bool{}); cycle {
+			v.errorf(f.Loc, "fragment %q leads to a cycle of fragment spreads", name)
+		}
+	}
+	return v.errs
+}
+
+func (v *validator) operation(op *Operation) {
+	root := v.schema.root(op.Kind)
+	if root == nil {
+		v.errorf(op.Loc, "the schema does not support %s operations", op.Kind)
+		return
+	}
+	v.op = op
+	v.vars = map[string]*VarDef{}
+	v.used = map[string]bool{}
+	for _, d := range op.Vars {
+		if v.vars[d.Name] != nil {
+			v.errorf(d.Loc, "there can be only one variable named $%s", d.Name)
+		}
+		v.vars[d.Name] = d
+		t := v.schema.resolve(d.Type)
+		switch {
+		case t == nil:
+			v.errorf(d.Loc, "variable $%s has unknown type %s", d.Name, d.Type)
+		case !isInputType(t):
+			v.errorf(d.Loc, "variable $%s cannot be of non-input type %s", d.Name, d.Type)
+		case d.Default != nil:
+			v.value(d.Default, t, "default of $"+d.Name)
+		}
+	}
+	for _, d := range op.Directives {
+		v.errorf(d.Loc, "unknown directive @%s on an operation", d.Name)
+	}
+	v.selections(root, op.Selections, map[string]*Field{}, 1, map[string]bool{})
+	for _, d := range op.Vars {
+		if !v.used[d.Name] {
+			v.errorf(d.Loc, "variable $%s is never used", d.Name)
+		}
+	}
+}
+
+// selections checks the selections on t. seen maps the response keys
+// already selected on t to their fields; spread is the chain of fragments
+// being expanded. depth is 0 below the introspection fields, whose nesting
+// the schema itself bounds.
+func (v *validator) selections(t *Object, sels []Selection, seen map[string]*Field, depth int, spread map[string]bool) {
+	if max := v.schema.MaxDepth; max > 0 && depth > max {
+		v.errorf(sels[0].location(), "selections nest more than %d levels deep", max)
+		return
+	}
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *Field:
+			v.field(t, sel, seen, depth, spread)
+		case *InlineFragment:
+			v.directives(sel.Directives, "inline fragment")
+			if sel.TypeCond != "" && sel.TypeCond != t.Name {
+				v.spreadError(sel.Loc, "an inline fragment", sel.TypeCond, t)
+				continue
+			}
+			v.selections(t, sel.Selections, seen, depth, spread)
+		case *FragmentSpread:
+			v.directives(sel.Directives, "fragment spread")
+			f := v.doc.Fragments[sel.Name]
+			if f == nil {
+				v.errorf(sel.Loc, "unknown fragment %q", sel.Name)
+				continue
+			}
+			if spread[sel.Name] {
+				continue // reported as a cycle
+			}
+			if f.TypeCond != t.Name {
+				v.spreadError(sel.Loc, fmt.Sprintf("fragment %q", sel.Name), f.TypeCond, t)
+				continue
+			}
+			spread[sel.Name] = true
+			v.selections(t, f.Selections, seen, depth, spread)
+			delete(spread, sel.Name)
+		}
+	}
+}
+
+func (v *validator) spreadError(loc Location, what, cond string, t *Object) {
+	if v.schema.Type(cond) == nil {
+		v.errorf(loc, "%s is on unknown type %q", what, cond)
+		return
+	}
+	v.errorf(loc, "%s on %q cannot be spread within %q", what, cond, t.Name)
+}
+
+func (v *validator) field(t *Object, f *Field, seen map[string]*Field, depth int, spread map[string]bool) {
+	v.directives(f.Directives, "field")
+	if prev := seen[f.Key()]; prev != nil && prev.Name != f.Name {
+		v.errorf(f.Loc, "%q selects both %q and %q; use an alias for one", f.Key(), prev.Name, f.Name)
+	}
+	seen[f.Key()] = f
+	if f.Name == "__typename" {
+		if len(f.Args) > 0 || len(f.Selections) > 0 {
+			v.errorf(f.Loc, "__typename takes no arguments or selections")
+		}
+		return
+	}
+	def := t.Field(f.Name)
+	if def == nil {
+		v.errorf(f.Loc, "cannot query field %q on type %q", f.Name, t.Name)
+		return
+	}
+	v.args(def.Args, f.Args, fmt.Sprintf("field %q", f.Name), f.Loc)
+	obj, _ := named(def.Type).(*Object)
+	switch {
+	case obj == nil && len(f.Selections) > 0:
+		v.errorf(f.Loc, "field %q of type %s cannot have selections", f.Name, def.Type)
+	case obj != nil && len(f.Selections) == 0:
+		v.errorf(f.Loc, "field %q of type %s must have selections", f.Name, def.Type)
+	case obj != nil:
+		next := depth + 1
+		if depth == 0 || strings.HasPrefix(f.Name, "__") {
+			next = 0
+		}
+		v.selections(obj, f.Selections, map[string]*Field{}, next, spread)
+	}
+}
+
+func (v *validator) directives(ds []*Directive, where string) {
+	names := map[string]bool{}
+	for _, d := range ds {
+		def := directives[d.Name]
+		if def == nil {
+			v.errorf(d.Loc, "unknown directive @%s on %s", d.Name, where)
+			continue
+		}
+		if names[d.Name] {
+			v.errorf(d.Loc, "@%s is given more than once", d.Name)
+		}
+		names[d.Name] = true
+		v.args(def.Args, d.Args, "@"+d.Name, d.Loc)
+	}
+}
+
+func (v *validator) args(defs []*Arg, given []*Argument, owner string, loc Location) {
+	names := map[string]bool{}
+	for _, a := range given {
+		if names[a.Name] {
+			v.errorf(a.Loc, "argument %q of %s is given more than once", a.Name, owner)
+		}
+		names[a.Name] = true
+		var def *Arg
+		for _, d := range defs {
+			if d.Name == a.Name {
+				def = d
+			}
+		}
+		if def == nil {
+			v.errorf(a.Loc, "unknown argument %q of %s", a.Name, owner)
+			continue
+		}
+		v.value(a.Value, def.Type, fmt.Sprintf("argument %q", a.Name))
+	}
+	for _, d := range defs {
+		if _, ok := d.Type.(*NonNull); ok && d.Default == nil && !names[d.Name] {
+			v.errorf(loc, "argument %q of %s is required", d.Name, owner)
+		}
+	}
+}
+
+// value checks a literal against the type it is given for. Variables are
+// checked for compatibility with their position.
+func (v *validator) value(val *Value, t Type, at string) {
+	if val.Kind == VariableValue {
+		v.variable(val, t, false)
+		return
+	}
+	if !hasVariables(val) {
+		lit, _ := literal(val, nil)
+		if _, err := coerceInput(lit, t, at); err != nil {
+			v.errorf(val.Loc, "%v", err)
+		}
+		return
+	}
+	// Check the parts around the variables.
+	nn, nonNull := t.(*NonNull)
+	if nonNull {
+		t = nn.Of
+	}
+	switch t := t.(type) {
+	case *List:
+		if val.Kind != ListValue {
+			v.value(val, t.Of, at)
+			return
+		}
+		for i, item := range val.List {
+			if item.Kind == VariableValue {
+				v.variable(item, t.Of, false)
+				continue
+			}
+			v.value(item, t.Of, fmt.Sprintf("%s[%d]", at, i))
+		}
+	case *InputObject:
+		if val.Kind != ObjectValue {
+			v.errorf(val.Loc, "%s: expected an object", at)
+			return
+		}
+		given := map[string]bool{}
+		for _, f := range val.Fields {
+			given[f.Name] = true
+			def := t.field(f.Name)
+			if def == nil {
+				v.errorf(f.Loc, "%s: %s has no field %q", at, t.Name, f.Name)
+				continue
+			}
+			if f.Value.Kind == VariableValue {
+				v.variable(f.Value, def.Type, def.Default != nil)
+				continue
+			}
+			v.value(f.Value, def.Type, at+"."+f.Name)
+		}
+		for _, def := range t.Fields {
+			if _, ok := def.Type.(*NonNull); ok && def.Default == nil && !given[def.Name] {
+				v.errorf(val.Loc, "%s: field %q is required", at, def.Name)
+			}
+		}
+	default:
+		v.errorf(val.Loc, "%s: expected %s", at, t)
+	}
+}
+
+// variable checks that a variable is defined, and that values of its type
+// may be used where a value of type t is expected.
+func (v *validator) variable(val *Value, t Type, hasDefault bool) {
+	v.used[val.Raw] = true
+	d := v.vars[val.Raw]
+	if d == nil {
+		v.errorf(val.Loc, "variable $%s is not defined by operation %q", val.Raw, v.op.Name)
+		return
+	}
+	vt := v.schema.resolve(d.Type)
+	if vt == nil {
+		return // reported with the definition
+	}
+	if nn, ok := t.(*NonNull); ok {
+		if _, ok := vt.(*NonNull); !ok && d.Default == nil && !hasDefault {
+			v.errorf(val.Loc, "variable $%s of type %s cannot be used where %s is expected", val.Raw, d.Type, t)
+			return
+		}
+		t = nn.Of
+	}
+	if !compatible(vt, t) {
+		v.errorf(val.Loc, "variable $%s of type %s cannot be used where %s is expected", val.Raw, d.Type, t)
+	}
+}
+
+// compatible reports whether a variable of type vt fits a nullable
+// position of type t.
+func compatible(vt, t Type) bool {
+	if nn, ok := t.(*NonNull); ok {
+		inner, isNN := vt.(*NonNull)
+		if !isNN {
+			return false
+		}
+		return compatible(inner.Of, nn.Of)
+	}
+	if nn, ok := vt.(*NonNull); ok {
+		vt = nn.Of
+	}
+	switch t := t.(type) {
+	case *List:
+		if vl, ok := vt.(*List); ok {
+			return compatible(vl.Of, t.Of)
+		}
+		return false
+	}
+	if _, ok := vt.(*List); ok {
+		return false
+	}
+	return named(vt) == named(t)
+}
+
+func hasVariables(val *Value) bool {
+	switch val.Kind {
+	case VariableValue:
+		return true
+	case ListValue:
+		for _, item := range val.List {
+			if hasVariables(item) {
+				return true
+			}
+		}
+	case ObjectValue:
+		for _, f := range val.Fields {
+			if hasVariables(f.Value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cycle reports whether f spreads itself, directly or through other
+// fragments.
+func (v *validator) cycle(f *Fragment, visiting map[string]bool) bool {
+	if visiting[f.Name] {
+		return true
+	}
+	visiting[f.Name] = true
+	defer delete(visiting, f.Name)
+	var walk func(sels []Selection) bool
+	walk = func(sels []Selection) bool {
+		for _, sel := range sels {
+			switch sel := sel.(type) {
+			case *Field:
+				if walk(sel.Selections) {
+					return true
+				}
+			case *InlineFragment:
+				if walk(sel.Selections) {
+					return true
+				}
+			case *FragmentSpread:
+				if next := v.doc.Fragments[sel.Name]; next != nil && v.cycle(next, visiting) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return walk(f.Selections)
+}
@@ -0,0 +1,178 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// enumName is an enum value written in a query, which unlike a string may
+// only be used for enum types.
+type enumName string
+
+// coerceInput converts an input in JSON form, a variable or a default,
+// into the Go value of type t. at names the input in error messages.
+func coerceInput(v any, t Type, at string) (any, error) {
+	if nn, ok := t.(*NonNull); ok {
+		if v == nil {
+			return nil, fmt.Errorf("%s must not be null", at)
+		}
+		return coerceInput(v, nn.Of, at)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	switch t := t.(type) {
+	case *List:
+		items, ok := v.([]any)
+		if !ok {
+			// A single value stands for a list of one.
+			item, err := coerceInput(v, t.Of, at)
+			if err != nil {
+				return nil, err
+			}
+			return []any{item}, nil
+		}
+		out := make([]any, len(items))
+		for i, item := range items {
+			var err error
+			if out[i], err = coerceInput(item, t.Of, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case *Scalar:
+		out, err := t.Parse(normalize(v))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", at, err)
+		}
+		return out, nil
+	case *Enum:
+		var name string
+		switch v := v.(type) {
+		case string:
+			name = v
+		case enumName:
+			name = string(v)
+		}
+		ev, ok := t.byName(name)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s is not a value of %s", at, describe(v), t.Name)
+		}
+		return ev.value(), nil
+	case *InputObject:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected an object, found %s", at, describe(v))
+		}
+		for k := range m {
+			if t.field(k) == nil {
+				return nil, fmt.Errorf("%s: %s has no field %q", at, t.Name, k)
+			}
+		}
+		out := map[string]any{}
+		for _, f := range t.Fields {
+			fv, present := m[f.Name]
+			if !present {
+				if f.Default != nil {
+					fv, present = f.Default, true
+				} else if _, ok := f.Type.(*NonNull); ok {
+					return nil, fmt.Errorf("%s: field %q is required", at, f.Name)
+				}
+			}
+			if !present {
+				continue
+			}
+			var err error
+			if out[f.Name], err = coerceInput(fv, f.Type, at+"."+f.Name); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("%s: %s is not an input type", at, t)
+}
+
+// normalize gives the numbers of inputs decoded without json.Number the
+// form scalars expect.
+func normalize(v any) any {
+	switch n := v.(type) {
+	case float64:
+		return json.Number(strconv.FormatFloat(n, 'f', -1, 64))
+	case int:
+		return json.Number(strconv.Itoa(n))
+	case int64:
+		return json.Number(strconv.FormatInt(n, 10))
+	}
+	return v
+}
+
+// literal converts a value written in a query into JSON form, taking
+// variables from vars. It reports false for a variable that has no value,
+// which counts as not given.
+func literal(v *Value, vars map[string]any) (any, bool) {
+	switch v.Kind {
+	case VariableValue:
+		val, ok := vars[v.Raw]
+		return val, ok
+	case IntValue, FloatValue:
+		return json.Number(v.Raw), true
+	case StringValue:
+		return v.Raw, true
+	case BooleanValue:
+		return v.Raw == "true", true
+	case NullValue:
+		return nil, true
+	case EnumValue:
+		return enumName(v.Raw), true
+	case ListValue:
+		out := make([]any, 0, len(v.List))
+		for _, item := range v.List {
+			// A missing variable in a list stands for null.
+			val, _ := literal(item, vars)
+			out = append(out, val)
+		}
+		return out, true
+	case ObjectValue:
+		out := map[string]any{}
+		for _, f := range v.Fields {
+			if val, ok := literal(f.Value, vars); ok {
+				out[f.Name] = val
+			}
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// coerceArgs returns the arguments of a field or directive, with defaults
+// filled in.
+func coerceArgs(defs []*Arg, given []*Argument, vars map[string]any) (map[string]any, error) {
+	args := map[string]any{}
+	for _, def := range defs {
+		var (
+			v       any
+			present bool
+		)
+		for _, a := range given {
+			if a.Name == def.Name {
+				v, present = literal(a.Value, vars)
+				break
+			}
+		}
+		if !present {
+			if def.Default == nil {
+				if _, ok := def.Type.(*NonNull); ok {
+					return nil, fmt.Errorf("argument %q is required", def.Name)
+				}
+				continue
+			}
+			v = def.Default
+		}
+		var err error
+		if args[def.Name], err = coerceInput(v, def.Type, "argument "+strconv.Quote(def.Name)); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
@@ -0,0 +1,215 @@
+package grpc
+
+import (
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// The messages of tasks.proto, with the field numbers given there. Requests
+// are decoded straight into the model and storage types the service takes,
+// and responses encoded from the ones it returns.
+
+func encodeTask(e *encoder, t model.Task) {
+	e.string(1, t.ID)
+	e.string(2, t.OrgID)
+	e.string(3, t.OwnerID)
+	e.optional(4, t.ProjectID)
+	e.optional(5, t.ParentID)
+	e.double(6, t.Position)
+	e.string(7, t.Title)
+	e.string(8, t.Description)
+	e.string(9, string(t.Status))
+	e.bool(10, t.Completed)
+	e.timestamp(11, t.DueDate)
+	e.timestamp(12, t.RemindAt)
+	e.string(13, t.Recurrence)
+	e.strings(14, t.TagIDs)
+	e.timestamp(15, &t.CreatedAt)
+	e.timestamp(16, &t.UpdatedAt)
+	e.timestamp(17, t.DeletedAt)
+	e.int(18, t.Version)
+	e.optional(19, t.AssigneeID)
+	e.string(20, string(t.Priority))
+	e.message(21, func(e *encoder) {
+		e.int(1, int64(t.Checklist.Done))
+		e.int(2, int64(t.Checklist.Total))
+	})
+	e.timestamp(22, t.ArchivedAt)
+}
+
+func decodeTaskInput(b []byte) (model.TaskInput, error) {
+	var in model.TaskInput
+	err := decode(b, func(field int, v value) error {
+		var err error
+		switch field {
+		case 1:
+			in.Title = v.string()
+		case 2:
+			in.Description = v.string()
+		case 3:
+			in.Status = model.Status(v.string())
+		case 4:
+			in.Completed = v.num != 0
+		case 5:
+			in.DueDate, err = decodeTimestamp(v.bytes)
+		case 6:
+			in.RemindAt, err = decodeTimestamp(v.bytes)
+		case 7:
+			in.Recurrence = v.string()
+		case 8:
+			id := v.string()
+			in.ProjectID = &id
+		case 9:
+			id := v.string()
+			in.ParentID = &id
+		case 10:
+			in.TagIDs = append(in.TagIDs, v.string())
+		case 11:
+			id := v.string()
+			in.AssigneeID = &id
+		case 12:
+			in.Priority = model.Priority(v.string())
+		}
+		return err
+	})
+	return in, err
+}
+
+// listRequest is a ListTasksRequest. Its sort is checked by the caller.
+type listRequest struct {
+	filter storage.TaskFilter
+	cursor string
+	sort   string
+}
+
+func decodeListRequest(b []byte) (listRequest, error) {
+	var req listRequest
+	err := decode(b, func(field int, v value) error {
+		var err error
+		switch field {
+		case 1:
+			req.filter.Limit = int(int32(v.num))
+		case 2:
+			req.cursor = v.string()
+		case 3:
+			req.filter.Status = model.Status(v.string())
+		case 4:
+			req.filter.ProjectID = v.string()
+		case 5:
+			req.filter.TagIDs = append(req.filter.TagIDs, v.string())
+		case 6:
+			req.filter.DueBefore, err = decodeTimestamp(v.bytes)
+		case 7:
+			req.filter.DueAfter, err = decodeTimestamp(v.bytes)
+		case 8:
+			req.sort = v.string()
+		case 9:
+			req.filter.AssigneeID = v.string()
+		}
+		return err
+	})
+	return req, err
+}
+
+func encodeTaskPage(e *encoder, page model.TaskPage) {
+	for _, t := range page.Items {
+		e.message(1, func(e *encoder) { encodeTask(e, t) })
+	}
+	e.string(2, page.NextCursor)
+}
+
+// decodeID reads the id of a GetTaskRequest.
+func decodeID(b []byte) (string, error) {
+	var id string
+	err := decode(b, func(field int, v value) error {
+		if field == 1 {
+			id = v.string()
+		}
+		return nil
+	})
+	return id, err
+}
+
+// decodeCreateRequest reads the task of a CreateTaskRequest.
+func decodeCreateRequest(b []byte) (model.TaskInput, error) {
+	var in model.TaskInput
+	err := decode(b, func(field int, v value) error {
+		var err error
+		if field == 1 {
+			in, err = decodeTaskInput(v.bytes)
+		}
+		return err
+	})
+	return in, err
+}
+
+type updateRequest struct {
+	id      string
+	task    model.TaskInput
+	ifMatch []int64
+}
+
+func decodeUpdateRequest(b []byte) (updateRequest, error) {
+	var req updateRequest
+	err := decode(b, func(field int, v value) error {
+		var err error
+		switch field {
+		case 1:
+			req.id = v.string()
+		case 2:
+			req.task, err = decodeTaskInput(v.bytes)
+		case 3:
+			req.ifMatch = []int64{int64(v.num)}
+		}
+		return err
+	})
+	return req, err
+}
+
+type deleteRequest struct {
+	id       string
+	children string
+}
+
+func decodeDeleteRequest(b []byte) (deleteRequest, error) {
+	var req deleteRequest
+	err := decode(b, func(field int, v value) error {
+		switch field {
+		case 1:
+			req.id = v.string()
+		case 2:
+			req.children = v.string()
+		}
+		return nil
+	})
+	return req, err
+}
+
+// decodeWatchRequest reads the last_event_id of a WatchRequest.
+func decodeWatchRequest(b []byte) (uint64, error) {
+	var lastID uint64
+	err := decode(b, func(field int, v value) error {
+		if field == 1 {
+			lastID = v.num
+		}
+		return nil
+	})
+	return lastID, err
+}
+
+// taskEvent is a TaskEvent; task is nil for deletions and resets.
+type taskEvent struct {
+	id     uint64
+	typ    string
+	task   *model.Task
+	taskID string
+}
+
+func encodeTaskEvent(e *encoder, ev taskEvent) {
+	e.uint(1, ev.id)
+	e.string(2, ev.typ)
+	if ev.task != nil {
+		e.message(3, func(e *encoder) { encodeTask(e, *ev.task) })
+	}
+	e.string(4, ev.taskID)
+}
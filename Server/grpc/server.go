@@ -0,0 +1,363 @@
+// Package grpc serves the TaskService of tasks.proto, so internal services
+// can work with tasks without going through JSON. It speaks gRPC over
+// cleartext HTTP/2 using only the standard library: messages are encoded by
+// hand in the protobuf wire format, and every call goes to the same service
+// layer as the REST API, under the same access rules.
+package grpc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"starttech-server/auth"
+	"starttech-server/events"
+	"starttech-server/metrics"
+	"starttech-server/model"
+	"starttech-server/realtime"
+	"starttech-server/service"
+	"starttech-server/storage"
+)
+
+// servicePath prefixes the path of every TaskService method.
+const servicePath = "/starttech.tasks.v1.TaskService/"
+
+// maxMessageSize bounds request messages, as gRPC clients do by default.
+const maxMessageSize = 4 << 20
+
+var grpcRequests = metrics.NewCounterVec("grpc_requests_total",
+	"gRPC calls served, by method and status code.", "method", "code")
+
+// Server answers TaskService calls. Callers authenticate with the access
+// tokens of the REST API, sent as "authorization: Bearer" metadata.
+type Server struct {
+	Tasks  *service.Tasks
+	Orgs   *service.Orgs
+	Issuer *auth.Issuer
+	// Sessions rejects tokens whose session has been revoked.
+	Sessions *service.Sessions
+	// Hub feeds Watch streams.
+	Hub *realtime.Hub
+}
+
+// unary is a method taking and returning one message.
+type unary func(ctx context.Context, userID string, req []byte, resp *encoder) error
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ct := r.Header.Get("Content-Type")
+	if r.Method != http.MethodPost || ct != "application/grpc" && ct != "application/grpc+proto" {
+		http.Error(w, "this port only serves gRPC", http.StatusUnsupportedMediaType)
+		return
+	}
+	method, _ := strings.CutPrefix(r.URL.Path, servicePath)
+	w.Header().Set("Content-Type", "application/grpc")
+
+	ctx := r.Context()
+	if d, ok := parseTimeout(r.Header.Get("Grpc-Timeout")); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
+	st := &stream{ResponseWriter: w}
+	w = st
+	var err error
+	switch method {
+	case "List":
+		err = s.unary(ctx, w, r, s.list)
+	case "Get":
+		err = s.unary(ctx, w, r, s.get)
+	case "Create":
+		err = s.unary(ctx, w, r, s.create)
+	case "Update":
+		err = s.unary(ctx, w, r, s.update)
+	case "Delete":
+		err = s.unary(ctx, w, r, s.delete)
+	case "Watch":
+		err = s.watch(ctx, w, r)
+	default:
+		method = "unknown"
+		err = errorf(codeUnimplemented, "unknown method %s", r.URL.Path)
+	}
+
+	res := statusOf(err)
+	if res.code == codeInternal {
+		slog.ErrorContext(ctx, "grpc call failed", "method", method, "err", err)
+	}
+	grpcRequests.With(method, res.code.String()).Inc()
+	// A call that failed before responding ends with the status in the
+	// headers, the others with it in the trailers.
+	prefix := http.TrailerPrefix
+	if !st.started {
+		prefix = ""
+	}
+	w.Header().Set(prefix+"Grpc-Status", strconv.Itoa(int(res.code)))
+	if res.msg != "" {
+		w.Header().Set(prefix+"Grpc-Message", encodeMessage(res.msg))
+	}
+	if !st.started {
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// stream notes whether a response has been started. Unwrap lets
+// http.ResponseController reach the underlying writer for flushing.
+type stream struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (s *stream) WriteHeader(code int) {
+	s.started = true
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *stream) Write(b []byte) (int, error) {
+	s.started = true
+	return s.ResponseWriter.Write(b)
+}
+
+func (s *stream) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+func (s *Server) unary(ctx context.Context, w http.ResponseWriter, r *http.Request, fn unary) error {
+	ctx, userID, err := s.authenticate(ctx, r)
+	if err != nil {
+		return err
+	}
+	req, err := readMessage(r.Body)
+	if err != nil {
+		return err
+	}
+	var resp encoder
+	if err := fn(ctx, userID, req, &resp); err != nil {
+		return err
+	}
+	return writeMessage(w, resp.b)
+}
+
+// authenticate checks the caller's token, its session and membership of
+// the token's organization, as the REST API's middleware does, and returns
+// ctx carrying the user, organization and session.
+func (s *Server) authenticate(ctx context.Context, r *http.Request) (context.Context, string, error) {
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return ctx, "", errorf(codeUnauthenticated, "authentication required")
+	}
+	claims, err := s.Issuer.Parse(strings.TrimSpace(token))
+	if err != nil {
+		return ctx, "", errorf(codeUnauthenticated, "authentication required")
+	}
+	if _, err := s.Sessions.Active(ctx, claims.SessionUser(), claims.Session); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ctx, "", errorf(codeUnauthenticated, "your session has ended; sign in again")
+		}
+		return ctx, "", err
+	}
+	if err := s.Orgs.Admit(ctx, claims.Subject, claims.Org); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			return ctx, "", errorf(codeUnauthenticated, "you are no longer a member of this organization; sign in again")
+		case errors.Is(err, service.ErrTwoFactorSetupRequired):
+			return ctx, "", errorf(codePermissionDenied, "this organization requires two-factor authentication; set it up first")
+		}
+		return ctx, "", err
+	}
+	ctx = auth.WithSessionID(auth.WithOrgID(auth.WithUserID(ctx, claims.Subject), claims.Org), claims.Session)
+	if claims.Actor != "" {
+		ctx = auth.WithActorID(ctx, claims.Actor)
+	}
+	return ctx, claims.Subject, nil
+}
+
+func (s *Server) list(ctx context.Context, userID string, b []byte, resp *encoder) error {
+	req, err := decodeListRequest(b)
+	if err != nil {
+		return err
+	}
+	var v model.ValidationError
+	if req.filter.Limit < 0 {
+		v.Add("limit", "must not be negative")
+	}
+	if req.sort != "" {
+		req.filter.Sort.Desc = strings.HasPrefix(req.sort, "-")
+		req.filter.Sort.Field = strings.TrimPrefix(req.sort, "-")
+		if !slices.Contains(storage.TaskSortKeys, req.filter.Sort.Field) {
+			v.Add("sort", "must be one of "+strings.Join(storage.TaskSortKeys, ", "))
+		}
+	}
+	if req.filter.Status != "" && !model.ValidStatusKey(req.filter.Status) {
+		v.Add("status", "is not a valid status key")
+	}
+	if err := v.Err(); err != nil {
+		return err
+	}
+	page, err := s.Tasks.List(ctx, userID, req.filter, req.cursor)
+	if err != nil {
+		return err
+	}
+	encodeTaskPage(resp, page)
+	return nil
+}
+
+func (s *Server) get(ctx context.Context, userID string, b []byte, resp *encoder) error {
+	id, err := decodeID(b)
+	if err != nil {
+		return err
+	}
+	t, err := s.Tasks.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	encodeTask(resp, t)
+	return nil
+}
+
+func (s *Server) create(ctx context.Context, userID string, b []byte, resp *encoder) error {
+	in, err := decodeCreateRequest(b)
+	if err != nil {
+		return err
+	}
+	t, err := s.Tasks.Create(ctx, userID, in)
+	if err != nil {
+		return err
+	}
+	encodeTask(resp, t)
+	return nil
+}
+
+func (s *Server) update(ctx context.Context, userID string, b []byte, resp *encoder) error {
+	req, err := decodeUpdateRequest(b)
+	if err != nil {
+		return err
+	}
+	t, err := s.Tasks.Update(ctx, userID, req.id, req.ifMatch, req.task.Apply)
+	if err != nil {
+		return err
+	}
+	encodeTask(resp, t)
+	return nil
+}
+
+func (s *Server) delete(ctx context.Context, userID string, b []byte, resp *encoder) error {
+	req, err := decodeDeleteRequest(b)
+	if err != nil {
+		return err
+	}
+	policy := service.ReparentChildren
+	if req.children != "" {
+		policy = service.ChildPolicy(req.children)
+		if !policy.Valid() {
+			var v model.ValidationError
+			v.Add("children", "must be reparent or cascade")
+			return v.Err()
+		}
+	}
+	return s.Tasks.Delete(ctx, userID, req.id, policy)
+}
+
+// watch streams the caller's task events until the call ends. A stream
+// that ends for any other reason fails with UNAVAILABLE, and the client
+// should call again with the last id it received.
+func (s *Server) watch(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ctx, userID, err := s.authenticate(ctx, r)
+	if err != nil {
+		return err
+	}
+	b, err := readMessage(r.Body)
+	if err != nil {
+		return err
+	}
+	lastID, err := decodeWatchRequest(b)
+	if err != nil {
+		return err
+	}
+	orgID, _ := auth.OrgID(ctx)
+	sub := s.Hub.Subscribe(userID, orgID, lastID)
+	if sub == nil {
+		return errorf(codeUnavailable, "server shutting down")
+	}
+	defer sub.Close()
+
+	// Send the headers now so the client knows the stream is open.
+	w.WriteHeader(http.StatusOK)
+	http.NewResponseController(w).Flush()
+	for {
+		e, ok := sub.Next(ctx)
+		if !ok {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return errorf(codeUnavailable, "event stream interrupted; watch again from the last event")
+		}
+		ev := taskEvent{id: e.ID, typ: string(e.Type)}
+		switch data := e.Data.(type) {
+		case model.Task:
+			ev.task = &data
+		case events.Deleted:
+			ev.taskID = data.ID
+		}
+		switch e.Type {
+		case events.TaskCreated, events.TaskUpdated, events.TaskRestored, events.TaskDeleted, realtime.StreamReset:
+		default:
+			continue
+		}
+		var enc encoder
+		encodeTaskEvent(&enc, ev)
+		if err := writeMessage(w, enc.b); err != nil {
+			return err
+		}
+	}
+}
+
+// readMessage reads the single length-prefixed message of a request.
+func readMessage(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, errorf(codeInvalidArgument, "missing request message")
+	}
+	if prefix[0] != 0 {
+		return nil, errorf(codeUnimplemented, "compressed messages are not supported")
+	}
+	n := binary.BigEndian.Uint32(prefix[1:])
+	if n > maxMessageSize {
+		return nil, errorf(codeResourceExhausted, "request message larger than %d bytes", maxMessageSize)
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, errorf(codeInvalidArgument, "truncated request message")
+	}
+	return msg, nil
+}
+
+// writeMessage sends msg, length-prefixed, and flushes it to the client.
+func writeMessage(w http.ResponseWriter, msg []byte) error {
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	if _, err := w.Write(append(frame, msg...)); err != nil {
+		return err
+	}
+	return http.NewResponseController(w).Flush()
+}
+
+// parseTimeout reads a grpc-timeout header, such as "500m" or "10S".
+func parseTimeout(s string) (time.Duration, bool) {
+	if len(s) < 2 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	units := map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second, 'm': time.Millisecond, 'u': time.Microsecond, 'n': time.Nanosecond}
+	unit, ok := units[s[len(s)-1]]
+	return time.Duration(n) * unit, ok
+}
@@ -0,0 +1,116 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"starttech-server/model"
+	"starttech-server/service"
+	"starttech-server/storage"
+)
+
+// code is a gRPC status code.
+type code int
+
+const (
+	codeOK                 code = 0
+	codeCanceled           code = 1
+	codeInvalidArgument    code = 3
+	codeDeadlineExceeded   code = 4
+	codeNotFound           code = 5
+	codePermissionDenied   code = 7
+	codeResourceExhausted  code = 8
+	codeFailedPrecondition code = 9
+	codeAborted            code = 10
+	codeUnimplemented      code = 12
+	codeInternal           code = 13
+	codeUnavailable        code = 14
+	codeUnauthenticated    code = 16
+)
+
+var codeNames = map[code]string{
+	codeOK:                 "OK",
+	codeCanceled:           "CANCELED",
+	codeInvalidArgument:    "INVALID_ARGUMENT",
+	codeDeadlineExceeded:   "DEADLINE_EXCEEDED",
+	codeNotFound:           "NOT_FOUND",
+	codePermissionDenied:   "PERMISSION_DENIED",
+	codeResourceExhausted:  "RESOURCE_EXHAUSTED",
+	codeFailedPrecondition: "FAILED_PRECONDITION",
+	codeAborted:            "ABORTED",
+	codeUnimplemented:      "UNIMPLEMENTED",
+	codeInternal:           "INTERNAL",
+	codeUnavailable:        "UNAVAILABLE",
+	codeUnauthenticated:    "UNAUTHENTICATED",
+}
+
+func (c code) String() string { return codeNames[c] }
+
+// status is the outcome of a call, sent in the grpc-status and
+// grpc-message trailers.
+type status struct {
+	code code
+	msg  string
+}
+
+func (s *status) Error() string { return s.code.String() + ": " + s.msg }
+
+func errorf(c code, format string, args ...any) error {
+	return &status{code: c, msg: fmt.Sprintf(format, args...)}
+}
+
+// statusOf maps an error returned by a method, or by the service or store
+// behind it, onto the status reported to the client, as writeServiceError
+// does for HTTP. Unexpected errors become a bare INTERNAL.
+func statusOf(err error) status {
+	var (
+		st    *status
+		verr  *model.ValidationError
+		quota *service.QuotaError
+	)
+	switch {
+	case err == nil:
+		return status{code: codeOK}
+	case errors.As(err, &st):
+		return *st
+	case errors.As(err, &verr):
+		var msgs []string
+		for _, f := range verr.Fields {
+			msgs = append(msgs, f.Field+" "+f.Message)
+		}
+		return status{codeInvalidArgument, "validation failed: " + strings.Join(msgs, "; ")}
+	case errors.Is(err, errMalformed):
+		return status{codeInvalidArgument, err.Error()}
+	case errors.Is(err, storage.ErrNotFound):
+		return status{codeNotFound, "not found"}
+	case errors.Is(err, storage.ErrConflict), errors.Is(err, storage.ErrStale):
+		return status{codeAborted, "conflict"}
+	case errors.Is(err, service.ErrPreconditionFailed):
+		return status{codeFailedPrecondition, "the task has changed since you read it"}
+	case errors.Is(err, service.ErrForbidden):
+		return status{codePermissionDenied, "your role in this project does not allow that"}
+	case errors.As(err, &quota):
+		return status{codeResourceExhausted, fmt.Sprintf("the %s quota of %d is used up", quota.Quota, quota.Limit)}
+	case errors.Is(err, context.Canceled):
+		return status{codeCanceled, "call canceled"}
+	case errors.Is(err, context.DeadlineExceeded):
+		return status{codeDeadlineExceeded, "deadline exceeded"}
+	}
+	return status{codeInternal, "internal error"}
+}
+
+// encodeMessage percent-encodes msg for the grpc-message trailer.
+func encodeMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,179 @@
+package grpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+// Protocol buffer wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errMalformed = errors.New("malformed protobuf message")
+
+// encoder appends the fields of a message in the protobuf wire format.
+// Fields holding their zero value are left out, as proto3 does.
+type encoder struct {
+	b []byte
+}
+
+func (e *encoder) tag(field, wire int) {
+	e.b = binary.AppendUvarint(e.b, uint64(field)<<3|uint64(wire))
+}
+
+func (e *encoder) uint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.b = binary.AppendUvarint(e.b, v)
+}
+
+func (e *encoder) int(field int, v int64) {
+	e.uint(field, uint64(v))
+}
+
+func (e *encoder) bool(field int, v bool) {
+	if v {
+		e.uint(field, 1)
+	}
+}
+
+func (e *encoder) double(field int, v float64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireFixed64)
+	e.b = binary.LittleEndian.AppendUint64(e.b, math.Float64bits(v))
+}
+
+func (e *encoder) string(field int, s string) {
+	if s == "" {
+		return
+	}
+	e.tag(field, wireBytes)
+	e.b = binary.AppendUvarint(e.b, uint64(len(s)))
+	e.b = append(e.b, s...)
+}
+
+// optional writes s, when not nil, even if it is empty, for proto3
+// optional fields.
+func (e *encoder) optional(field int, s *string) {
+	if s == nil {
+		return
+	}
+	e.tag(field, wireBytes)
+	e.b = binary.AppendUvarint(e.b, uint64(len(*s)))
+	e.b = append(e.b, *s...)
+}
+
+func (e *encoder) strings(field int, ss []string) {
+	for _, s := range ss {
+		e.tag(field, wireBytes)
+		e.b = binary.AppendUvarint(e.b, uint64(len(s)))
+		e.b = append(e.b, s...)
+	}
+}
+
+// message writes the message encoded by fn as a field.
+func (e *encoder) message(field int, fn func(*encoder)) {
+	var sub encoder
+	fn(&sub)
+	e.tag(field, wireBytes)
+	e.b = binary.AppendUvarint(e.b, uint64(len(sub.b)))
+	e.b = append(e.b, sub.b...)
+}
+
+// timestamp writes t, when not nil, as a google.protobuf.Timestamp.
+func (e *encoder) timestamp(field int, t *time.Time) {
+	if t == nil {
+		return
+	}
+	e.message(field, func(e *encoder) {
+		e.int(1, t.Unix())
+		e.int(2, int64(t.Nanosecond()))
+	})
+}
+
+// value is one field read by decode. Only the part matching the wire type
+// is set.
+type value struct {
+	wire  int
+	num   uint64 // varint, fixed64 and fixed32 fields
+	bytes []byte // length-delimited fields
+}
+
+func (v value) string() string { return string(v.bytes) }
+
+func (v value) double() float64 { return math.Float64frombits(v.num) }
+
+// decode calls fn with every field of the message in b, in order.
+func decode(b []byte, fn func(field int, v value) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errMalformed
+		}
+		b = b[n:]
+		field, wire := int(key>>3), int(key&7)
+		if field <= 0 {
+			return errMalformed
+		}
+		v := value{wire: wire}
+		switch wire {
+		case wireVarint:
+			v.num, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errMalformed
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return errMalformed
+			}
+			v.num, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return errMalformed
+			}
+			v.num, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return errMalformed
+			}
+			v.bytes, b = b[n:n+int(size)], b[n+int(size):]
+		default:
+			return errMalformed
+		}
+		if err := fn(field, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeTimestamp reads a google.protobuf.Timestamp.
+func decodeTimestamp(b []byte) (*time.Time, error) {
+	var secs, nanos int64
+	err := decode(b, func(field int, v value) error {
+		switch field {
+		case 1:
+			secs = int64(v.num)
+		case 2:
+			nanos = int64(v.num)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	t := time.Unix(secs, nanos).UTC()
+	return &t, nil
+}
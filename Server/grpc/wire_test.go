@@ -0,0 +1,297 @@
+package grpc
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"starttech-server/model"
+)
+
+func TestEncoder(t *testing.T) {
+	empty := ""
+	tests := []struct {
+		name string
+		fn   func(e *encoder)
+		want []byte
+	}{
+		// The examples of the protobuf encoding guide.
+		{"varint", func(e *encoder) { e.uint(1, 150) }, []byte{0x08, 0x96, 0x01}},
+		{"string", func(e *encoder) { e.string(2, "testing") }, []byte{0x12, 0x07, 't', 'e', 's', 't', 'i', 'n', 'g'}},
+		{"negative int64", func(e *encoder) { e.int(1, -2) }, []byte{0x08, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{"bool", func(e *encoder) { e.bool(3, true) }, []byte{0x18, 0x01}},
+		{"double", func(e *encoder) { e.double(1, 1) }, []byte{0x09, 0, 0, 0, 0, 0, 0, 0xf0, 0x3f}},
+		{"large field number", func(e *encoder) { e.uint(16, 1) }, []byte{0x80, 0x01, 0x01}},
+		{"repeated", func(e *encoder) { e.strings(4, []string{"a", ""}) }, []byte{0x22, 0x01, 'a', 0x22, 0x00}},
+		{"empty optional", func(e *encoder) { e.optional(5, &empty) }, []byte{0x2a, 0x00}},
+		{"nested message", func(e *encoder) { e.message(3, func(e *encoder) { e.uint(1, 150) }) }, []byte{0x1a, 0x03, 0x08, 0x96, 0x01}},
+		{"empty message", func(e *encoder) { e.message(1, func(*encoder) {}) }, []byte{0x0a, 0x00}},
+		{"zero values", func(e *encoder) {
+			e.uint(1, 0)
+			e.int(2, 0)
+			e.bool(3, false)
+			e.double(4, 0)
+			e.string(5, "")
+			e.optional(6, nil)
+			e.strings(7, nil)
+			e.timestamp(8, nil)
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e encoder
+			tt.fn(&e)
+			if !bytes.Equal(e.b, tt.want) {
+				t.Errorf("got % x, want % x", e.b, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	s := "optional"
+	var e encoder
+	e.uint(1, math.MaxUint64)
+	e.int(2, math.MinInt64)
+	e.bool(3, true)
+	e.double(4, -0.1)
+	e.string(5, "héllo")
+	e.optional(6, &s)
+	e.strings(7, []string{"a", "b"})
+	e.message(8, func(e *encoder) { e.string(1, "inner") })
+
+	type field struct {
+		num  int
+		wire int
+		v    any
+	}
+	var got []field
+	err := decode(e.b, func(num int, v value) error {
+		f := field{num: num, wire: v.wire}
+		switch num {
+		case 1:
+			f.v = v.num
+		case 2:
+			f.v = int64(v.num)
+		case 3:
+			f.v = v.num != 0
+		case 4:
+			f.v = v.double()
+		case 8:
+			var inner string
+			if err := decode(v.bytes, func(_ int, v value) error { inner = v.string(); return nil }); err != nil {
+				return err
+			}
+			f.v = inner
+		default:
+			f.v = v.string()
+		}
+		got = append(got, f)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []field{
+		{1, wireVarint, uint64(math.MaxUint64)},
+		{2, wireVarint, int64(math.MinInt64)},
+		{3, wireVarint, true},
+		{4, wireFixed64, -0.1},
+		{5, wireBytes, "héllo"},
+		{6, wireBytes, "optional"},
+		{7, wireBytes, "a"},
+		{7, wireBytes, "b"},
+		{8, wireBytes, "inner"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestTimestampRoundTrip(t *testing.T) {
+	tests := []time.Time{
+		time.Unix(0, 0),
+		time.Unix(0, 1),
+		time.Date(2024, 2, 29, 23, 59, 59, 999999999, time.UTC),
+		time.Date(1969, 7, 20, 20, 17, 40, 0, time.UTC),
+		time.Date(2024, 6, 1, 9, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60)),
+	}
+	for _, ts := range tests {
+		var e encoder
+		e.timestamp(1, &ts)
+		var got *time.Time
+		err := decode(e.b, func(field int, v value) error {
+			var err error
+			got, err = decodeTimestamp(v.bytes)
+			return err
+		})
+		if err != nil || got == nil || !got.Equal(ts) || got.Location() != time.UTC {
+			t.Errorf("%v came back as %v, %v", ts, got, err)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	var got []value
+	err := decode([]byte{0x0d, 0x01, 0x02, 0x03, 0x04, 0x10, 0x00}, func(field int, v value) error {
+		got = append(got, v)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []value{{wire: wireFixed32, num: 0x04030201}, {wire: wireVarint}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = decode([]byte{0x08, 0x01, 0x08, 0x02}, func(int, value) error { calls++; return stop })
+	if err != stop || calls != 1 {
+		t.Errorf("decode returned %v after %d calls, want the callback's error after 1", err, calls)
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{"truncated key", []byte{0x80}},
+		{"field zero", []byte{0x00, 0x01}},
+		{"truncated varint", []byte{0x08, 0x96}},
+		{"overlong varint", []byte{0x08, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{"truncated fixed64", []byte{0x09, 0x01, 0x02, 0x03}},
+		{"truncated fixed32", []byte{0x0d, 0x01}},
+		{"missing length", []byte{0x0a}},
+		{"length past the end", []byte{0x0a, 0x05, 'a', 'b'}},
+		{"huge length", []byte{0x0a, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}},
+		{"start group", []byte{0x0b}},
+		{"end group", []byte{0x0c}},
+		{"unknown wire type", []byte{0x0e}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := decode(tt.b, func(int, value) error { return nil }); err != errMalformed {
+				t.Errorf("err = %v, want %v", err, errMalformed)
+			}
+		})
+	}
+}
+
+func TestTaskInputRoundTrip(t *testing.T) {
+	due := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	project, parent, nobody := "p1", "t0", ""
+	want := model.TaskInput{
+		Title:       "Write tests",
+		Description: "for the wire format",
+		Status:      model.Status("in_progress"),
+		Completed:   true,
+		DueDate:     &due,
+		Recurrence:  "FREQ=WEEKLY",
+		ProjectID:   &project,
+		ParentID:    &parent,
+		TagIDs:      []string{"a", "b"},
+		AssigneeID:  &nobody,
+		Priority:    model.Priority("high"),
+	}
+	// Encoded with the field numbers of TaskInput in tasks.proto, inside
+	// a CreateTaskRequest.
+	var e encoder
+	e.message(1, func(e *encoder) {
+		e.string(1, want.Title)
+		e.string(2, want.Description)
+		e.string(3, string(want.Status))
+		e.bool(4, want.Completed)
+		e.timestamp(5, want.DueDate)
+		e.timestamp(6, want.RemindAt)
+		e.string(7, want.Recurrence)
+		e.optional(8, want.ProjectID)
+		e.optional(9, want.ParentID)
+		e.strings(10, want.TagIDs)
+		e.optional(11, want.AssigneeID)
+		e.string(12, string(want.Priority))
+		e.uint(99, 7) // unknown fields are skipped
+	})
+	got, err := decodeCreateRequest(e.b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestEncodeTask(t *testing.T) {
+	created := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	project := "p1"
+	task := model.Task{
+		ID:        "t1",
+		OrgID:     "o1",
+		OwnerID:   "u1",
+		ProjectID: &project,
+		Position:  1.5,
+		Title:     "Write tests",
+		TagIDs:    []string{"a"},
+		CreatedAt: created,
+		UpdatedAt: created,
+		Version:   3,
+	}
+	task.Checklist.Done, task.Checklist.Total = 1, 2
+
+	var e encoder
+	encodeTaskPage(&e, model.TaskPage{Items: []model.Task{task}, NextCursor: "next"})
+
+	var items [][]byte
+	var cursor string
+	err := decode(e.b, func(field int, v value) error {
+		switch field {
+		case 1:
+			items = append(items, v.bytes)
+		case 2:
+			cursor = v.string()
+		}
+		return nil
+	})
+	if err != nil || len(items) != 1 || cursor != "next" {
+		t.Fatalf("got %d items and cursor %q, %v", len(items), cursor, err)
+	}
+
+	got := map[int]any{}
+	err = decode(items[0], func(field int, v value) error {
+		switch field {
+		case 6:
+			got[field] = v.double()
+		case 15, 16:
+			ts, err := decodeTimestamp(v.bytes)
+			if err != nil {
+				return err
+			}
+			got[field] = *ts
+		case 18:
+			got[field] = int64(v.num)
+		case 21:
+			var progress []uint64
+			err := decode(v.bytes, func(_ int, v value) error { progress = append(progress, v.num); return nil })
+			got[field] = progress
+			return err
+		default:
+			got[field] = v.string()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]any{
+		1: "t1", 2: "o1", 3: "u1", 4: "p1", 6: 1.5, 7: "Write tests", 14: "a",
+		15: created, 16: created, 18: int64(3), 21: []uint64{1, 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded\n%v\nwant\n%v", got, want)
+	}
+}
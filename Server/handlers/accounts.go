@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"starttech-server/apierror"
+	"starttech-server/model"
+	"starttech-server/service"
+)
+
+// verifyEmail activates the account a verification token was sent to and
+// signs its user in.
+func (h *Auth) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	var in model.TokenInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	u, err := h.Accounts.VerifyEmail(r.Context(), in.Token)
+	if err != nil {
+		writeAccountError(w, r, err)
+		return
+	}
+	org, err := h.Orgs.Default(r.Context(), u)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	h.startSession(w, r, http.StatusOK, u, org)
+}
+
+// resendVerification answers 202 whether or not the address has an
+// account, so that it cannot be used to find out.
+func (h *Auth) resendVerification(w http.ResponseWriter, r *http.Request) {
+	var in model.EmailInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if err := h.Accounts.ResendVerification(r.Context(), strings.TrimSpace(in.Email)); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// forgotPassword answers 202 whether or not the address has an account, as
+// resendVerification does.
+func (h *Auth) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	var in model.EmailInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if err := h.Accounts.ForgotPassword(r.Context(), strings.TrimSpace(in.Email)); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *Auth) resetPassword(w http.ResponseWriter, r *http.Request) {
+	var in model.ResetPasswordInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if len(in.Password) < minPasswordLength {
+		writeError(w, http.StatusBadRequest, passwordTooShort)
+		return
+	}
+	if err := h.Accounts.ResetPassword(r.Context(), in.Token, in.Password); err != nil {
+		writeAccountError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeAccountError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidToken):
+		writeErrorCode(w, http.StatusBadRequest, apierror.CodeInvalidToken, "the token is invalid or has expired")
+	case errors.Is(err, service.ErrEmailNotVerified):
+		writeErrorCode(w, http.StatusForbidden, apierror.CodeEmailNotVerified, "verify your email address first; POST /auth/resend-verification sends a new token")
+	case errors.Is(err, service.ErrAccountDisabled):
+		writeErrorCode(w, http.StatusForbidden, apierror.CodeAccountDisabled, "this account has been disabled")
+	default:
+		writeServiceError(w, r, err)
+	}
+}
+
+func (h *Auth) profile(w http.ResponseWriter, r *http.Request) {
+	u, err := h.Accounts.Profile(r.Context(), currentUser(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+func (h *Auth) patchProfile(w http.ResponseWriter, r *http.Request) {
+	var p model.ProfilePatch
+	if err := decodeJSON(r, &p); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	u, err := h.Accounts.UpdateProfile(r.Context(), currentUser(r), p)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
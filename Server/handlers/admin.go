@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"starttech-server/auth"
+	"starttech-server/middleware"
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+	"starttech-server/storage"
+)
+
+// Admin serves the /admin endpoints, which only the server's administrators
+// may use: those whose token carries the admin claim. Routes must be
+// mounted behind the auth middleware.
+type Admin struct {
+	Service *service.Admin
+	Jobs    *service.Jobs
+	// Orgs picks the organization an impersonation works in.
+	Orgs *service.Orgs
+	// Issuer signs impersonation tokens.
+	Issuer *auth.Issuer
+	// Backups writes and restores organizations, and MaxRestoreSize
+	// bounds the archives restored, in bytes.
+	Backups        *service.Backups
+	MaxRestoreSize int64
+	// Audit records the actions that change users, organizations and jobs.
+	Audit *Audit
+	// Purges counts what the scheduled purges would delete as of the given
+	// time; see scheduler.Purgers.Preview.
+	Purges func(ctx context.Context, now time.Time) ([]model.PurgePreview, error)
+}
+
+// Register mounts the admin routes on mux, refusing everyone but
+// administrators.
+func (h *Admin) Register(mux router.Routes) {
+	admin := router.NewGroup(mux, auth.RequireAdmin)
+	admin.HandleFunc("GET /admin/stats", h.stats)
+	admin.HandleFunc("GET /admin/users", h.listUsers)
+	admin.HandleFunc("GET /admin/users/{id}", h.getUser)
+	admin.HandleFunc("POST /admin/users/{id}/disable", h.disableUser)
+	admin.HandleFunc("POST /admin/users/{id}/enable", h.enableUser)
+	admin.HandleFunc("POST /admin/users/{id}/reset-password", h.resetPassword)
+	admin.HandleFunc("POST /admin/users/{id}/impersonate", sessionOnly(h.impersonate))
+	admin.HandleFunc("GET /admin/orgs", h.listOrgs)
+	admin.HandleFunc("GET /admin/orgs/{id}", h.getOrg)
+	admin.HandleFunc("POST /admin/backup", h.backup)
+	admin.HandleFunc("POST /admin/restore", h.restore)
+	admin.HandleFunc("GET /admin/jobs", h.listJobs)
+	admin.HandleFunc("GET /admin/jobs/{id}", h.getJob)
+	admin.HandleFunc("POST /admin/jobs/{id}/retry", h.retryJob)
+	admin.HandleFunc("DELETE /admin/jobs/{id}", h.deleteJob)
+	admin.HandleFunc("GET /admin/purges", h.previewPurges)
+	Debug{Prefix: "/admin/debug"}.Register(admin)
+}
+
+func (h *Admin) stats(w http.ResponseWriter, r *http.Request) {
+	usage, err := h.Service.Stats(r.Context())
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, usage)
+}
+
+func (h *Admin) listUsers(w http.ResponseWriter, r *http.Request) {
+	limit, cursor, err := parsePage(r)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	page, err := h.Service.ListUsers(r.Context(), r.URL.Query().Get("q"), limit, cursor)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (h *Admin) getUser(w http.ResponseWriter, r *http.Request) {
+	u, err := h.Service.GetUser(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+func (h *Admin) disableUser(w http.ResponseWriter, r *http.Request) {
+	u, err := h.Service.DisableUser(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	h.Audit.record(r, model.AuditEvent{Action: model.AuditUserDisabled, TargetID: u.ID})
+	writeJSON(w, http.StatusOK, u)
+}
+
+func (h *Admin) enableUser(w http.ResponseWriter, r *http.Request) {
+	u, err := h.Service.EnableUser(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	h.Audit.record(r, model.AuditEvent{Action: model.AuditUserEnabled, TargetID: u.ID})
+	writeJSON(w, http.StatusOK, u)
+}
+
+func (h *Admin) resetPassword(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.ResetPassword(r.Context(), currentUser(r), r.PathValue("id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	h.Audit.record(r, model.AuditEvent{Action: model.AuditPasswordReset, TargetID: r.PathValue("id")})
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// impersonate answers with a token that acts as the user in their default
+// organization. It hangs off the administrator's own session, so signing
+// out ends it, and carries no admin claim. No cookie is set, which would
+// replace the administrator's.
+func (h *Admin) impersonate(w http.ResponseWriter, r *http.Request) {
+	adminID := currentUser(r)
+	u, err := h.Service.Impersonate(r.Context(), adminID, r.PathValue("id"))
+	if err != nil {
+		writeAccountError(w, r, err)
+		return
+	}
+	org, err := h.Orgs.Default(r.Context(), u)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	sessionID, _ := auth.SessionID(r.Context())
+	token, exp, err := h.Issuer.Issue(auth.Claims{Subject: u.ID, Org: org.ID, Session: sessionID, Actor: adminID})
+	if err != nil {
+		writeInternalError(w, r, "issuing token", err)
+		return
+	}
+	h.Audit.record(r, model.AuditEvent{Action: model.AuditImpersonation, TargetID: u.ID, Detail: "in organization " + org.ID})
+	writeJSON(w, http.StatusOK, model.Session{Token: token, ExpiresAt: exp, User: u, Org: org})
+}
+
+func (h *Admin) listOrgs(w http.ResponseWriter, r *http.Request) {
+	limit, cursor, err := parsePage(r)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	page, err := h.Service.ListOrgs(r.Context(), r.URL.Query().Get("q"), limit, cursor)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (h *Admin) getOrg(w http.ResponseWriter, r *http.Request) {
+	o, err := h.Service.GetOrg(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, o)
+}
+
+// backup streams an archive of an organization, by default the
+// administrator's own. Once the archive has started, errors can only be
+// logged; the client sees a truncated gzip stream.
+func (h *Admin) backup(w http.ResponseWriter, r *http.Request) {
+	var in model.BackupInput
+	if err := decodeJSON(r, &in); err != nil && !errors.Is(err, io.EOF) {
+		writeDecodeError(w, err)
+		return
+	}
+	if in.OrgID == "" {
+		in.OrgID, _ = auth.OrgID(r.Context())
+	}
+	archive, err := h.Backups.Create(r.Context(), currentUser(r), in.OrgID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	defer archive.Close()
+	h.Audit.record(r, model.AuditEvent{Action: model.AuditBackup, TargetID: archive.Org.ID})
+
+	// Big organizations take longer to send than the write timeout allows.
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	name := "backup-" + archive.Org.ID + "-" + time.Now().UTC().Format("20060102-150405") + ".tar.gz"
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	if err := archive.WriteTo(r.Context(), w); err != nil {
+		slog.ErrorContext(r.Context(), "writing backup", "org_id", archive.Org.ID, "err", err)
+	}
+}
+
+// restore rebuilds an organization from the archive in the body, as a new
+// organization.
+func (h *Admin) restore(w http.ResponseWriter, r *http.Request) {
+	middleware.AllowBodySize(r, h.MaxRestoreSize)
+	r.Body = http.MaxBytesReader(w, r.Body, h.MaxRestoreSize)
+	http.NewResponseController(w).SetReadDeadline(time.Time{})
+	result, err := h.Backups.Restore(r.Context(), currentUser(r), r.Body)
+	var tooBig *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooBig):
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("archives may be at most %d bytes", h.MaxRestoreSize))
+	case errors.Is(err, service.ErrInvalidBackup):
+		writeError(w, http.StatusBadRequest, strings.TrimPrefix(err.Error(), "service: "))
+	case err != nil:
+		writeServiceError(w, r, err)
+	default:
+		h.Audit.record(r, model.AuditEvent{Action: model.AuditRestore, TargetID: result.Org.ID})
+		writeJSON(w, http.StatusCreated, result)
+	}
+}
+
+func (h *Admin) listJobs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := storage.JobFilter{Status: model.JobStatus(q.Get("status")), Kind: q.Get("kind")}
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			var v model.ValidationError
+			v.Add("limit", "must be a positive integer")
+			writeServiceError(w, r, v.Err())
+			return
+		}
+		f.Limit = n
+	}
+	jobs, err := h.Jobs.List(r.Context(), f)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+func (h *Admin) getJob(w http.ResponseWriter, r *http.Request) {
+	j, err := h.Jobs.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, j)
+}
+
+func (h *Admin) retryJob(w http.ResponseWriter, r *http.Request) {
+	j, err := h.Jobs.Retry(r.Context(), r.PathValue("id"))
+	if errors.Is(err, storage.ErrConflict) {
+		writeError(w, http.StatusConflict, "only dead jobs can be retried")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	h.Audit.record(r, model.AuditEvent{Action: model.AuditJobRetried, TargetID: j.ID, Detail: j.Kind})
+	writeJSON(w, http.StatusAccepted, j)
+}
+
+func (h *Admin) deleteJob(w http.ResponseWriter, r *http.Request) {
+	if err := h.Jobs.Discard(r.Context(), r.PathValue("id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	h.Audit.record(r, model.AuditEvent{Action: model.AuditJobDeleted, TargetID: r.PathValue("id")})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// previewPurges answers what each scheduled purge would delete if it swept
+// now, deleting nothing.
+func (h *Admin) previewPurges(w http.ResponseWriter, r *http.Request) {
+	previews, err := h.Purges(r.Context(), time.Now().UTC())
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, previews)
+}
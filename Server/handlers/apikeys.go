@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+)
+
+// APIKeys serves the /settings/api-keys endpoints. Routes must be mounted
+// behind the auth middleware.
+type APIKeys struct {
+	Service *service.APIKeys
+}
+
+// Register mounts the API key routes on mux. They need a signed-in user, so
+// that a leaked key cannot be used to mint more.
+func (h *APIKeys) Register(mux router.Routes) {
+	mux.HandleFunc("GET /settings/api-keys", sessionOnly(h.list))
+	mux.HandleFunc("POST /settings/api-keys", sessionOnly(h.create))
+	mux.HandleFunc("DELETE /settings/api-keys/{id}", sessionOnly(h.revoke))
+}
+
+func (h *APIKeys) list(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.Service.List(r.Context(), currentUser(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+func (h *APIKeys) create(w http.ResponseWriter, r *http.Request) {
+	var in model.APIKeyInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	k, err := h.Service.Create(r.Context(), currentUser(r), in)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, k)
+}
+
+func (h *APIKeys) revoke(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.Revoke(r.Context(), currentUser(r), r.PathValue("id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"starttech-server/model"
+)
+
+// archive and unarchive honour If-Match like update.
+func (h *Tasks) archive(w http.ResponseWriter, r *http.Request) {
+	t, err := h.Service.Archive(r.Context(), currentUser(r), r.PathValue("id"), ifMatch(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeTask(w, http.StatusOK, t)
+}
+
+func (h *Tasks) unarchive(w http.ResponseWriter, r *http.Request) {
+	t, err := h.Service.Unarchive(r.Context(), currentUser(r), r.PathValue("id"), ifMatch(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeTask(w, http.StatusOK, t)
+}
+
+func (h *Projects) archive(w http.ResponseWriter, r *http.Request) {
+	p, err := h.Service.Archive(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (h *Projects) unarchive(w http.ResponseWriter, r *http.Request) {
+	p, err := h.Service.Unarchive(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// parseArchived reads the archived query parameter, which is true or false,
+// into *dst. It leaves *dst alone when the parameter is absent.
+func parseArchived(r *http.Request, dst **bool, v *model.ValidationError) {
+	s := r.URL.Query().Get("archived")
+	if s == "" {
+		return
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.Add("archived", "must be true or false")
+		return
+	}
+	*dst = &b
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"starttech-server/apierror"
+	"starttech-server/middleware"
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+	"starttech-server/thumb"
+)
+
+// Attachments serves the files uploaded to tasks. The routes from Register
+// must be mounted behind the auth middleware, those from RegisterPublic
+// must not: signed links are opened by browsers without a token.
+type Attachments struct {
+	Service *service.Attachments
+}
+
+// Register mounts the attachment routes of /tasks on mux.
+func (h *Attachments) Register(mux router.Routes) {
+	mux.HandleFunc("GET /tasks/{id}/attachments", h.list)
+	mux.HandleFunc("POST /tasks/{id}/attachments", h.upload)
+	mux.HandleFunc("GET /tasks/{id}/attachments/{attachment_id}", h.get)
+	mux.HandleFunc("DELETE /tasks/{id}/attachments/{attachment_id}", h.delete)
+	mux.HandleFunc("GET /tasks/{id}/attachments/{attachment_id}/download", h.download)
+}
+
+// RegisterPublic mounts the routes signed download and thumbnail links
+// point to.
+func (h *Attachments) RegisterPublic(mux router.Routes) {
+	mux.HandleFunc("GET /attachments/{id}", h.content)
+	mux.HandleFunc("GET /attachments/{id}/thumb", h.thumbnail)
+}
+
+func (h *Attachments) list(w http.ResponseWriter, r *http.Request) {
+	attachments, err := h.Service.List(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, attachments)
+}
+
+// multipartOverhead is what the form around the file may add to the body.
+const multipartOverhead = 1 << 20
+
+func (h *Attachments) upload(w http.ResponseWriter, r *http.Request) {
+	middleware.AllowBodySize(r, h.Service.MaxSize+multipartOverhead)
+	r.Body = http.MaxBytesReader(w, r.Body, h.Service.MaxSize+multipartOverhead)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "expected a multipart/form-data body with a file field")
+		return
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			var v model.ValidationError
+			v.Add("file", "is required")
+			writeServiceError(w, r, v.Err())
+			return
+		}
+		if err != nil {
+			writeUploadError(w, r, h.Service, err)
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+		a, err := h.Service.Upload(r.Context(), currentUser(r), r.PathValue("id"), part.FileName(), part)
+		part.Close()
+		if err != nil {
+			writeUploadError(w, r, h.Service, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, a)
+		return
+	}
+}
+
+func writeUploadError(w http.ResponseWriter, r *http.Request, s *service.Attachments, err error) {
+	var tooBig *http.MaxBytesError
+	switch {
+	case errors.Is(err, service.ErrTooLarge), errors.As(err, &tooBig):
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("files may be at most %d bytes", s.MaxSize))
+	case errors.Is(err, service.ErrUnsupportedType):
+		writeError(w, http.StatusUnsupportedMediaType, "files of this type are not accepted")
+	default:
+		writeServiceError(w, r, err)
+	}
+}
+
+func (h *Attachments) get(w http.ResponseWriter, r *http.Request) {
+	a, err := h.Service.Get(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("attachment_id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, a)
+}
+
+func (h *Attachments) delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.Delete(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("attachment_id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// download redirects to a fresh signed link, for clients that would rather
+// follow one request than read the URL from the metadata.
+func (h *Attachments) download(w http.ResponseWriter, r *http.Request) {
+	a, err := h.Service.Link(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("attachment_id"))
+	if err != nil {
+		writeDownloadError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, a.URL, http.StatusFound)
+}
+
+func (h *Attachments) content(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	a, rc, err := h.Service.Open(r.Context(), r.PathValue("id"), q.Get("expires"), q.Get("signature"))
+	if errors.Is(err, service.ErrInvalidLink) {
+		writeError(w, http.StatusForbidden, "the download link is invalid or has expired")
+		return
+	}
+	if err != nil {
+		writeDownloadError(w, r, err)
+		return
+	}
+	defer rc.Close()
+
+	// Uploads are untrusted: never let a browser render them as part of
+	// the site.
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	w.Header().Set("Cache-Control", "private, no-store")
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", a.CreatedAt, rs)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	io.Copy(w, rc)
+}
+
+// defaultThumbWidth is the width of the thumbnails served to requests that
+// name none.
+const defaultThumbWidth = 256
+
+func (h *Attachments) thumbnail(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	width := defaultThumbWidth
+	if v := q.Get("w"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			var verr model.ValidationError
+			verr.Add("w", "must be a positive number of pixels")
+			writeServiceError(w, r, verr.Err())
+			return
+		}
+		width = n
+	}
+	a, rc, err := h.Service.OpenThumbnail(r.Context(), r.PathValue("id"), width, q.Get("expires"), q.Get("signature"))
+	switch {
+	case errors.Is(err, service.ErrInvalidLink):
+		writeError(w, http.StatusForbidden, "the download link is invalid or has expired")
+		return
+	case errors.Is(err, service.ErrNoThumbnail):
+		writeError(w, http.StatusNotFound, "only images have thumbnails")
+		return
+	case errors.Is(err, service.ErrThumbnailPending):
+		w.Header().Set("Retry-After", "2")
+		writeErrorCode(w, http.StatusConflict, apierror.CodeThumbnailPending, "the thumbnail is being made; try again shortly")
+		return
+	case err != nil:
+		writeDownloadError(w, r, err)
+		return
+	}
+	defer rc.Close()
+
+	// Thumbnails are re-encoded by the server, so they may be shown
+	// inline, and never change, so browsers may keep them.
+	w.Header().Set("Content-Type", thumb.ContentType(a.ContentType))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	w.Header().Set("Cache-Control", "private, max-age=86400, immutable")
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", a.CreatedAt, rs)
+		return
+	}
+	io.Copy(w, rc)
+}
+
+// writeDownloadError answers a download of an attachment that cannot be
+// downloaded, or failed otherwise.
+func writeDownloadError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotScanned):
+		w.Header().Set("Retry-After", "5")
+		writeErrorCode(w, http.StatusConflict, apierror.CodeNotScanned, "the file is still being scanned for viruses; try again shortly")
+	case errors.Is(err, service.ErrQuarantined):
+		writeErrorCode(w, http.StatusForbidden, apierror.CodeQuarantined, "the virus scan flagged the file, so it cannot be downloaded")
+	default:
+		writeServiceError(w, r, err)
+	}
+}
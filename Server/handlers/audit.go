@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"starttech-server/auth"
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+	"starttech-server/storage"
+)
+
+// auditFields are the columns of an audit log export, in order.
+var auditFields = []string{"seq", "id", "created_at", "action", "actor_id", "org_id", "target_id", "ip", "user_agent", "detail", "prev_hash", "hash"}
+
+// Audit records security-relevant events in the audit log and serves it
+// to administrators. A nil *Audit records nothing, so handlers that record
+// events work without one.
+type Audit struct {
+	Service *service.Audit
+	// ClientIP returns the address a request came from.
+	ClientIP func(*http.Request) string
+}
+
+// Register mounts the audit log routes on mux, which must be behind the
+// auth middleware, refusing everyone but administrators.
+func (h *Audit) Register(mux router.Routes) {
+	admin := router.NewGroup(mux, auth.RequireAdmin)
+	admin.HandleFunc("GET /admin/audit", h.list)
+	admin.HandleFunc("GET /admin/audit/export", h.export)
+	admin.HandleFunc("GET /admin/audit/verify", h.verify)
+}
+
+// Watch records every request answered with 403 Forbidden as an
+// access.denied event. It must run after the auth middleware, and outside
+// whatever may refuse the request.
+func (h *Audit) Watch(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &deniedRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == http.StatusForbidden {
+			h.record(r, model.AuditEvent{Action: model.AuditAccessDenied, Detail: r.Method + " " + r.URL.Path})
+		}
+	})
+}
+
+// record stamps e with the caller, organization, address and user agent
+// of r, where e does not name them, and appends it to the log. What an
+// administrator does while impersonating someone is noted in the detail.
+func (h *Audit) record(r *http.Request, e model.AuditEvent) {
+	if h == nil {
+		return
+	}
+	ctx := r.Context()
+	if e.ActorID == "" {
+		e.ActorID, _ = auth.UserID(ctx)
+	}
+	if admin, ok := auth.ActorID(ctx); ok {
+		e.Detail = strings.TrimSpace(e.Detail + " (impersonated by " + admin + ")")
+	}
+	if e.OrgID == "" {
+		e.OrgID, _ = auth.OrgID(ctx)
+	}
+	if h.ClientIP != nil {
+		e.IP = h.ClientIP(r)
+	}
+	e.UserAgent = r.UserAgent()
+	h.Service.Record(ctx, e)
+}
+
+// parseAuditFilter reads the query parameters of the audit log routes:
+//
+//	from    RFC 3339 timestamp or YYYY-MM-DD date in UTC (inclusive)
+//	to      RFC 3339 timestamp or YYYY-MM-DD date in UTC (exclusive)
+//	action  exact action match, such as auth.login_failed
+//	actor   ID of the user who acted
+//	org_id  ID of the organization acted in
+func parseAuditFilter(r *http.Request) (storage.AuditFilter, error) {
+	q := r.URL.Query()
+	var v model.ValidationError
+	f := storage.AuditFilter{
+		From:    parseTimeParam(q.Get("from"), "from", time.UTC, &v),
+		To:      parseTimeParam(q.Get("to"), "to", time.UTC, &v),
+		Action:  q.Get("action"),
+		ActorID: q.Get("actor"),
+		OrgID:   q.Get("org_id"),
+	}
+	return f, v.Err()
+}
+
+func (h *Audit) list(w http.ResponseWriter, r *http.Request) {
+	f, err := parseAuditFilter(r)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	limit, cursor, err := parsePage(r)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	page, err := h.Service.List(r.Context(), f, limit, cursor)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// export streams the events matching the filter as CSV, oldest first,
+// with a header row of auditFields.
+func (h *Audit) export(w http.ResponseWriter, r *http.Request) {
+	f, err := parseAuditFilter(r)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	// Refuse a bad range before any of the body is sent.
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		var v model.ValidationError
+		v.Add("to", "must be after from")
+		writeServiceError(w, r, v.Err())
+		return
+	}
+
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	name := "audit-" + time.Now().UTC().Format("20060102-150405") + ".csv"
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	cw.Write(auditFields)
+	err = h.Service.Export(r.Context(), f, func(e model.AuditEvent) error {
+		return cw.Write([]string{
+			strconv.FormatInt(e.Seq, 10), e.ID, e.CreatedAt.Format(time.RFC3339Nano), e.Action, e.ActorID, e.OrgID,
+			e.TargetID, csvText(e.IP), csvText(e.UserAgent), csvText(e.Detail), e.PrevHash, e.Hash,
+		})
+	})
+	if err != nil {
+		// The status is sent by now, so all that can be done is to cut the
+		// body short.
+		slog.ErrorContext(r.Context(), "audit export failed", "err", err)
+		panic(http.ErrAbortHandler)
+	}
+	cw.Flush()
+}
+
+// csvText keeps a spreadsheet from reading a cell that comes from a client,
+// such as a user agent, as a formula.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (h *Audit) verify(w http.ResponseWriter, r *http.Request) {
+	v, err := h.Service.Verify(r.Context())
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// deniedRecorder passes a response through, noting its status.
+type deniedRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *deniedRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *deniedRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *deniedRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"starttech-server/apierror"
+	"starttech-server/auth"
+	"starttech-server/i18n"
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+	"starttech-server/storage"
+)
+
+// Auth serves the account registration, login, session and organization
+// switching endpoints.
+type Auth struct {
+	Users  storage.UserStore
+	Issuer *auth.Issuer
+	// Orgs picks the organization each session works in.
+	Orgs *service.Orgs
+	// Sessions keeps the sign-ins that tokens are issued to.
+	Sessions *service.Sessions
+	// Accounts verifies email addresses and resets passwords.
+	Accounts *service.Accounts
+	// OAuth holds the providers users may sign in with, by name; routes for
+	// any other name answer 404.
+	OAuth OAuth
+	// Admins get the admin claim in their tokens.
+	Admins service.Admins
+	// Audit records sign-ins and failed attempts.
+	Audit *Audit
+	// TwoFactor asks users who have enabled it for a second factor when
+	// they sign in.
+	TwoFactor *service.TwoFactor
+	// SSO signs in the people of organizations' email domains through
+	// their identity providers; nil turns single sign-on off.
+	SSO *service.SSO
+}
+
+// Register mounts the auth routes on mux.
+func (h *Auth) Register(mux router.Routes) {
+	mux.HandleFunc("POST /auth/register", h.register)
+	mux.HandleFunc("POST /auth/login", h.login)
+	mux.HandleFunc("POST /auth/2fa/verify", h.verifyTwoFactor)
+	mux.HandleFunc("POST /auth/refresh", h.refresh)
+	mux.HandleFunc("POST /auth/verify-email", h.verifyEmail)
+	mux.HandleFunc("POST /auth/resend-verification", h.resendVerification)
+	mux.HandleFunc("POST /auth/forgot-password", h.forgotPassword)
+	mux.HandleFunc("POST /auth/reset-password", h.resetPassword)
+	mux.HandleFunc("GET /auth/oauth/{provider}/start", h.oauthStart)
+	mux.HandleFunc("GET /auth/oauth/{provider}/callback", h.oauthCallback)
+	if h.SSO != nil {
+		h.registerSSO(mux)
+	}
+}
+
+// RegisterProtected mounts the auth routes that need a valid token on mux,
+// which must be behind the auth middleware.
+func (h *Auth) RegisterProtected(mux router.Routes) {
+	mux.HandleFunc("POST /auth/switch", sessionOnly(h.switchOrg))
+	mux.HandleFunc("POST /auth/logout", sessionOnly(h.logout))
+	mux.HandleFunc("GET /me", h.profile)
+	mux.HandleFunc("PATCH /me", h.patchProfile)
+	// The account is also served under /users/me, beside its deletion and
+	// export.
+	mux.HandleFunc("GET /users/me", h.profile)
+	mux.HandleFunc("PATCH /users/me", h.patchProfile)
+	mux.HandleFunc("GET /me/sessions", sessionOnly(h.listSessions))
+	mux.HandleFunc("DELETE /me/sessions", sessionOnly(h.revokeSessions))
+	mux.HandleFunc("DELETE /me/sessions/{id}", sessionOnly(h.revokeSession))
+}
+
+// minPasswordLength is the fewest bytes a password may have.
+const minPasswordLength = 8
+
+const passwordTooShort = "password must be at least 8 characters"
+
+const unknownTimezone = "timezone must be an IANA timezone name, such as Europe/Paris"
+
+// register creates an account, which can be signed in to once its email
+// address is verified, and sends the verification email.
+func (h *Auth) register(w http.ResponseWriter, r *http.Request) {
+	var in model.RegisterInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	in.Email = strings.TrimSpace(in.Email)
+	in.Username = strings.TrimSpace(in.Username)
+	if in.Timezone = strings.TrimSpace(in.Timezone); in.Timezone == "" {
+		in.Timezone = model.DefaultTimezone
+	}
+	switch {
+	case !strings.Contains(in.Email, "@"):
+		writeError(w, http.StatusBadRequest, "a valid email is required")
+		return
+	case in.Username == "":
+		writeError(w, http.StatusBadRequest, "username is required")
+		return
+	case len(in.Password) < minPasswordLength:
+		writeError(w, http.StatusBadRequest, passwordTooShort)
+		return
+	}
+	if _, ok := model.LoadTimezone(in.Timezone); !ok {
+		writeError(w, http.StatusBadRequest, unknownTimezone)
+		return
+	}
+	if in.Language == "" {
+		in.Language, _ = i18n.FromContext(r.Context())
+	} else if lang, ok := i18n.Match(in.Language); ok {
+		in.Language = lang
+	} else {
+		writeError(w, http.StatusBadRequest, "language must be one of "+strings.Join(i18n.Supported(), ", "))
+		return
+	}
+
+	hash, err := auth.HashPassword(in.Password)
+	if err != nil {
+		writeInternalError(w, r, "hashing password", err)
+		return
+	}
+	u := model.User{
+		Email:        in.Email,
+		Username:     in.Username,
+		PasswordHash: hash,
+		Timezone:     in.Timezone,
+		Language:     in.Language,
+		CreatedAt:    time.Now().UTC(),
+	}
+	if err := h.Users.CreateUser(r.Context(), &u); err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			writeError(w, http.StatusConflict, "email or username already registered")
+			return
+		}
+		writeServiceError(w, r, err)
+		return
+	}
+	if _, err := h.Orgs.Default(r.Context(), u); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	h.Accounts.SendVerification(r.Context(), u)
+	writeJSON(w, http.StatusCreated, u)
+}
+
+func (h *Auth) login(w http.ResponseWriter, r *http.Request) {
+	var in model.LoginInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	u, err := h.Users.GetUserByEmail(r.Context(), strings.TrimSpace(in.Email))
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		writeServiceError(w, r, err)
+		return
+	}
+	if err != nil || !auth.CheckPassword(u.PasswordHash, in.Password) {
+		h.Audit.record(r, model.AuditEvent{Action: model.AuditLoginFailed, ActorID: u.ID, Detail: "invalid credentials for " + in.Email})
+		writeErrorCode(w, http.StatusUnauthorized, apierror.CodeInvalidCredentials, "invalid email or password")
+		return
+	}
+	if u.EmailVerifiedAt == nil {
+		h.Audit.record(r, model.AuditEvent{Action: model.AuditLoginFailed, ActorID: u.ID, Detail: "email not verified"})
+		writeAccountError(w, r, service.ErrEmailNotVerified)
+		return
+	}
+	if h.requireSSO(w, r, u) {
+		return
+	}
+	if h.challengeSecondFactor(w, r, u, "") {
+		return
+	}
+	org, err := h.loginOrg(r, u, in.OrgID)
+	if err != nil {
+		writeOrgError(w, r, err)
+		return
+	}
+	h.startSession(w, r, http.StatusOK, u, org)
+}
+
+// loginOrg returns the organization a sign-in of u asked for by orgID, or
+// their default one.
+func (h *Auth) loginOrg(r *http.Request, u model.User, orgID string) (model.Org, error) {
+	if orgID != "" {
+		return h.Orgs.Get(r.Context(), u.ID, orgID)
+	}
+	return h.Orgs.Default(r.Context(), u)
+}
+
+// switchOrg moves the caller's session to another organization they belong
+// to, issuing a token for it. The refresh token stays the same.
+func (h *Auth) switchOrg(w http.ResponseWriter, r *http.Request) {
+	var in model.SwitchInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	org, err := h.Orgs.Get(r.Context(), currentUser(r), in.OrgID)
+	if err != nil {
+		writeOrgError(w, r, err)
+		return
+	}
+	u, err := h.Users.GetUser(r.Context(), currentUser(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	sessionID, _ := auth.SessionID(r.Context())
+	if err := h.Sessions.Switch(r.Context(), u.ID, sessionID, org.ID); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if sess, ok := h.issueTokens(w, r, u, org, sessionID, ""); ok {
+		writeJSON(w, http.StatusOK, sess)
+	}
+}
+
+// startSession signs u in to org on a new session, returning its tokens in
+// the body and as httpOnly cookies for browser clients.
+func (h *Auth) startSession(w http.ResponseWriter, r *http.Request, status int, u model.User, org model.Org) {
+	sess, ok := h.newSession(w, r, u, org)
+	if !ok {
+		return
+	}
+	writeJSON(w, status, sess)
+}
+
+// newSession creates a session of u in org and sets its cookies, and
+// records the sign-in. If that fails, or u has been disabled, it writes the
+// error and returns false.
+func (h *Auth) newSession(w http.ResponseWriter, r *http.Request, u model.User, org model.Org) (model.Session, bool) {
+	if u.DisabledAt != nil {
+		h.Audit.record(r, model.AuditEvent{Action: model.AuditLoginFailed, ActorID: u.ID, Detail: "account disabled"})
+		writeAccountError(w, r, service.ErrAccountDisabled)
+		return model.Session{}, false
+	}
+	s, refresh, err := h.Sessions.Start(r.Context(), u.ID, org.ID, r.UserAgent())
+	if err != nil {
+		writeServiceError(w, r, err)
+		return model.Session{}, false
+	}
+	sess, ok := h.issueTokens(w, r, u, org, s.ID, refresh)
+	if ok {
+		h.Audit.record(r, model.AuditEvent{Action: model.AuditLogin, ActorID: u.ID, OrgID: org.ID, Detail: "via " + r.URL.Path})
+	}
+	return sess, ok
+}
+
+// issueTokens issues an access token for u working in org on session
+// sessionID and sets it as the session cookie, along with refresh, the
+// session's new refresh token, unless that is empty. If issuing fails, it
+// writes the error and returns false.
+func (h *Auth) issueTokens(w http.ResponseWriter, r *http.Request, u model.User, org model.Org, sessionID, refresh string) (model.Session, bool) {
+	token, exp, err := h.Issuer.Issue(auth.Claims{Subject: u.ID, Org: org.ID, Session: sessionID, Admin: h.Admins.Contains(u)})
+	if err != nil {
+		writeInternalError(w, r, "issuing token", err)
+		return model.Session{}, false
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.CookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  exp,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	if refresh != "" {
+		h.setRefreshCookie(w, r, refresh, h.Sessions.TTL)
+	}
+	return model.Session{Token: token, ExpiresAt: exp, RefreshToken: refresh, User: u, Org: org}, true
+}
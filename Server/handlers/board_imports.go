@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"starttech-server/integrations"
+	"starttech-server/middleware"
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+)
+
+// maxBoardImportBytes bounds the body of POST /import/trello and
+// /import/jira. Trello exports carry the board's recent actions too.
+const maxBoardImportBytes = 20 << 20
+
+// BoardImports serves the endpoints that import boards from Trello and
+// Jira.
+type BoardImports struct {
+	Service *service.BoardImports
+}
+
+// Register mounts the board import routes on mux.
+func (h *BoardImports) Register(mux router.Routes) {
+	mux.HandleFunc("POST /import/trello", h.trello)
+	mux.HandleFunc("POST /import/jira", h.jira)
+	mux.HandleFunc("GET /imports", h.list)
+	mux.HandleFunc("GET /imports/{id}", h.get)
+}
+
+func (h *BoardImports) trello(w http.ResponseWriter, r *http.Request) {
+	h.start(w, r, model.ImportTrello, integrations.ParseTrello)
+}
+
+// jira reads CSV when the Content-Type is text/csv, and JSON otherwise.
+func (h *BoardImports) jira(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	h.start(w, r, model.ImportJira, func(body io.Reader) ([]model.Board, error) {
+		return integrations.ParseJira(body, mediaType == "text/csv")
+	})
+}
+
+// start reads the export in the body with parse and answers 202 with the
+// queued import, whose progress GET /imports/{id} reports. With
+// ?dry_run=true it answers 200 with what the import would create instead.
+func (h *BoardImports) start(w http.ResponseWriter, r *http.Request, source model.ImportSource, parse func(io.Reader) ([]model.Board, error)) {
+	preview, err := dryRun(r)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	middleware.AllowBodySize(r, maxBoardImportBytes)
+	boards, err := parse(http.MaxBytesReader(w, r.Body, maxBoardImportBytes))
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("exports may be at most %d bytes", maxBoardImportBytes))
+		return
+	}
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if preview {
+		p, err := h.Service.Preview(r.Context(), currentUser(r), source, boards)
+		if err != nil {
+			writeServiceError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, p)
+		return
+	}
+	im, err := h.Service.Start(r.Context(), currentUser(r), source, boards)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, im)
+}
+
+func (h *BoardImports) list(w http.ResponseWriter, r *http.Request) {
+	imports, err := h.Service.List(r.Context(), currentUser(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, imports)
+}
+
+func (h *BoardImports) get(w http.ResponseWriter, r *http.Request) {
+	im, err := h.Service.Get(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, im)
+}
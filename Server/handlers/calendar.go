@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"starttech-server/ical"
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+)
+
+// calendarRefresh is how often calendar apps are asked to fetch a feed.
+const calendarRefresh = time.Hour
+
+// Calendar serves iCalendar feeds of tasks. Routes from Register must be
+// mounted behind the auth middleware, the feed from RegisterPublic must
+// not: calendar apps fetch it with nothing but its URL.
+type Calendar struct {
+	Service *service.Calendar
+}
+
+// Register mounts the routes that manage the caller's feed on mux.
+func (h *Calendar) Register(mux router.Routes) {
+	mux.HandleFunc("GET /me/calendar", h.get)
+	mux.HandleFunc("POST /me/calendar", h.create)
+	mux.HandleFunc("DELETE /me/calendar", h.delete)
+}
+
+// RegisterPublic mounts the route feed URLs point to.
+func (h *Calendar) RegisterPublic(mux router.Routes) {
+	mux.HandleFunc("GET /calendar/{file}", h.feed)
+}
+
+func (h *Calendar) get(w http.ResponseWriter, r *http.Request) {
+	f, err := h.Service.Get(r.Context(), currentUser(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, f)
+}
+
+func (h *Calendar) create(w http.ResponseWriter, r *http.Request) {
+	f, err := h.Service.Create(r.Context(), currentUser(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, f)
+}
+
+func (h *Calendar) delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.Delete(r.Context(), currentUser(r)); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// feed answers /calendar/{token}.ics with one event per task.
+func (h *Calendar) feed(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutSuffix(r.PathValue("file"), ".ics")
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	f, tasks, err := h.Service.Feed(r.Context(), token)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	loc := h.Service.Tasks.Zone(r.Context(), f.UserID)
+	cw := ical.NewWriter(w, "StartTech tasks", calendarRefresh)
+	for _, t := range tasks {
+		cw.Event(taskEvent(t, loc))
+	}
+	if err := cw.Close(); err != nil {
+		slog.WarnContext(r.Context(), "writing calendar feed", "user_id", f.UserID, "err", err)
+	}
+}
+
+// taskEvent is the event for t, which must have a due date. A due date at
+// midnight in loc, the feed owner's timezone, in which a bare date is
+// stored, makes an all-day event.
+func taskEvent(t model.Task, loc *time.Location) ical.Event {
+	due := t.DueDate.In(loc)
+	y, m, d := due.Date()
+	return ical.Event{
+		UID:         t.ID + "@starttech",
+		Summary:     t.Title,
+		Description: t.Description,
+		Start:       due,
+		AllDay:      due.Equal(time.Date(y, m, d, 0, 0, 0, 0, loc)),
+		Modified:    t.UpdatedAt,
+	}
+}
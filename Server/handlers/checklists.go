@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+
+	"starttech-server/model"
+)
+
+func (h *Tasks) checklist(w http.ResponseWriter, r *http.Request) {
+	items, err := h.Service.Checklist(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h *Tasks) addChecklistItem(w http.ResponseWriter, r *http.Request) {
+	var in model.ChecklistItemInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	c, err := h.Service.AddChecklistItem(r.Context(), currentUser(r), r.PathValue("id"), in)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, c)
+}
+
+func (h *Tasks) patchChecklistItem(w http.ResponseWriter, r *http.Request) {
+	var p model.ChecklistItemPatch
+	if err := decodeJSON(r, &p); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	c, err := h.Service.UpdateChecklistItem(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("item_id"), p)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (h *Tasks) toggleChecklistItem(w http.ResponseWriter, r *http.Request) {
+	c, err := h.Service.ToggleChecklistItem(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("item_id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (h *Tasks) deleteChecklistItem(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.DeleteChecklistItem(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("item_id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Tasks) reorderChecklist(w http.ResponseWriter, r *http.Request) {
+	var o model.ChecklistOrder
+	if err := decodeJSON(r, &o); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	items, err := h.Service.ReorderChecklist(r.Context(), currentUser(r), r.PathValue("id"), o.ItemIDs)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
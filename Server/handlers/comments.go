@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"starttech-server/model"
+	"starttech-server/service"
+)
+
+func (h *Tasks) listComments(w http.ResponseWriter, r *http.Request) {
+	comments, err := h.Service.ListComments(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, comments)
+}
+
+func (h *Tasks) createComment(w http.ResponseWriter, r *http.Request) {
+	var in model.CommentInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	c, err := h.Service.AddComment(r.Context(), currentUser(r), r.PathValue("id"), in)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, c)
+}
+
+func (h *Tasks) getComment(w http.ResponseWriter, r *http.Request) {
+	c, err := h.Service.GetComment(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("comment_id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (h *Tasks) patchComment(w http.ResponseWriter, r *http.Request) {
+	var p model.CommentPatch
+	if err := decodeJSON(r, &p); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	c, err := h.Service.EditComment(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("comment_id"), p)
+	if errors.Is(err, service.ErrForbidden) {
+		writeError(w, http.StatusForbidden, "only the author may edit a comment")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (h *Tasks) deleteComment(w http.ResponseWriter, r *http.Request) {
+	err := h.Service.DeleteComment(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("comment_id"))
+	if errors.Is(err, service.ErrForbidden) {
+		writeError(w, http.StatusForbidden, "only the author or a project owner may delete a comment")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Tasks) commentHistory(w http.ResponseWriter, r *http.Request) {
+	edits, err := h.Service.CommentHistory(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("comment_id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, edits)
+}
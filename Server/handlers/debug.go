@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+
+	"starttech-server/router"
+)
+
+// Debug serves the runtime's profiles, as net/http/pprof does, and its
+// expvar variables, under Prefix. Anyone who can reach them can read the
+// process's command line and stall it with profiling, so they are only
+// mounted behind admin authentication or on an internal listener.
+type Debug struct {
+	Prefix string
+}
+
+// Register mounts the debug routes on mux.
+func (h Debug) Register(mux router.Routes) {
+	mux.HandleFunc("GET "+h.Prefix+"/pprof/{$}", pprof.Index)
+	mux.HandleFunc("GET "+h.Prefix+"/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("GET "+h.Prefix+"/pprof/profile", pprof.Profile)
+	mux.HandleFunc("GET "+h.Prefix+"/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("POST "+h.Prefix+"/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("GET "+h.Prefix+"/pprof/trace", pprof.Trace)
+	// Heap, goroutine, allocs, block, mutex and threadcreate.
+	mux.HandleFunc("GET "+h.Prefix+"/pprof/{profile}", func(w http.ResponseWriter, r *http.Request) {
+		pprof.Handler(r.PathValue("profile")).ServeHTTP(w, r)
+	})
+	mux.Handle("GET "+h.Prefix+"/vars", expvar.Handler())
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import "net/http"
+
+func (h *Tasks) dependencies(w http.ResponseWriter, r *http.Request) {
+	deps, err := h.Service.Dependencies(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, deps)
+}
+
+func (h *Tasks) dependencyGraph(w http.ResponseWriter, r *http.Request) {
+	g, err := h.Service.DependencyGraph(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, g)
+}
+
+func (h *Tasks) addBlocker(w http.ResponseWriter, r *http.Request) {
+	deps, err := h.Service.AddBlocker(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("blocker_id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, deps)
+}
+
+func (h *Tasks) removeBlocker(w http.ResponseWriter, r *http.Request) {
+	deps, err := h.Service.RemoveBlocker(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("blocker_id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, deps)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// etag renders a resource version as a strong entity tag.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatch parses the If-Match header into the versions it names. It returns
+// nil when the header is absent or "*", so any version will do, and an
+// empty list when no tag can match; weak tags never do.
+func ifMatch(r *http.Request) []int64 {
+	h := strings.TrimSpace(r.Header.Get("If-Match"))
+	if h == "" || h == "*" {
+		return nil
+	}
+	versions := []int64{}
+	for _, tag := range strings.Split(h, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		if v, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+// writeList sends the list v as JSON with a weak entity tag derived from
+// the body, or 304 with no body when If-None-Match already names it. A
+// client polling a list then only downloads it again once it has changed.
+// The tag is weak as it names the JSON rather than a stored version.
+func writeList(w http.ResponseWriter, r *http.Request, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "encoding response", "err", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	body = append(body, '\n')
+	sum := sha256.Sum256(body)
+	tag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+
+	h := w.Header()
+	h.Set("ETag", tag)
+	// Browsers may keep the list, but must check it is current each time.
+	h.Set("Cache-Control", "private, no-cache")
+	if ifNoneMatch(r, tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	h.Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// ifNoneMatch reports whether the If-None-Match header is "*" or names tag,
+// compared weakly as conditional GETs are.
+func ifNoneMatch(r *http.Request, tag string) bool {
+	h := r.Header.Get("If-None-Match")
+	if h == "" {
+		return false
+	}
+	for _, t := range strings.Split(h, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(tag, "W/") {
+			return true
+		}
+	}
+	return false
+}
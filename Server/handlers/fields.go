@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+func (h *Projects) listFields(w http.ResponseWriter, r *http.Request) {
+	fields, err := h.Service.Fields(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fields)
+}
+
+func (h *Projects) createField(w http.ResponseWriter, r *http.Request) {
+	var in model.CustomFieldInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	f, err := h.Service.CreateField(r.Context(), currentUser(r), r.PathValue("id"), in)
+	if err != nil {
+		writeFieldError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, f)
+}
+
+func (h *Projects) patchField(w http.ResponseWriter, r *http.Request) {
+	var p model.CustomFieldPatch
+	if err := decodeJSON(r, &p); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	f, err := h.Service.UpdateField(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("field_id"), p)
+	if err != nil {
+		writeFieldError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, f)
+}
+
+func (h *Projects) deleteField(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.DeleteField(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("field_id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeFieldError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, storage.ErrConflict) {
+		writeError(w, http.StatusConflict, "the project already has a field of that name")
+		return
+	}
+	writeServiceError(w, r, err)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"starttech-server/auth"
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+)
+
+// Flags serves feature flags: GET /me/flags to everyone, and the
+// /admin/flags endpoints to administrators. Routes must be mounted behind
+// the auth middleware.
+type Flags struct {
+	Service *service.Flags
+	// Audit records the changes administrators make.
+	Audit *Audit
+}
+
+// Register mounts the flag routes on mux.
+func (h *Flags) Register(mux router.Routes) {
+	mux.HandleFunc("GET /me/flags", h.mine)
+	admin := router.NewGroup(mux, auth.RequireAdmin)
+	admin.HandleFunc("GET /admin/flags", h.list)
+	admin.HandleFunc("GET /admin/flags/{key}", h.get)
+	admin.HandleFunc("PUT /admin/flags/{key}", h.save)
+	admin.HandleFunc("DELETE /admin/flags/{key}", h.delete)
+	admin.HandleFunc("PUT /admin/flags/{key}/orgs/{org_id}", h.setOverride(model.FlagOrg, "org_id"))
+	admin.HandleFunc("DELETE /admin/flags/{key}/orgs/{org_id}", h.deleteOverride(model.FlagOrg, "org_id"))
+	admin.HandleFunc("PUT /admin/flags/{key}/users/{user_id}", h.setOverride(model.FlagUser, "user_id"))
+	admin.HandleFunc("DELETE /admin/flags/{key}/users/{user_id}", h.deleteOverride(model.FlagUser, "user_id"))
+}
+
+func (h *Flags) mine(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.Service.Mine(r.Context(), currentUser(r)))
+}
+
+func (h *Flags) list(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.Service.List(r.Context()))
+}
+
+func (h *Flags) get(w http.ResponseWriter, r *http.Request) {
+	f, err := h.Service.Get(r.Context(), r.PathValue("key"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, f)
+}
+
+func (h *Flags) save(w http.ResponseWriter, r *http.Request) {
+	var in model.FlagInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	f, err := h.Service.Save(r.Context(), r.PathValue("key"), in)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	h.Audit.record(r, model.AuditEvent{Action: model.AuditFlagChanged, TargetID: f.Key,
+		Detail: fmt.Sprintf("enabled %t, rollout %d%%", f.Enabled, f.Rollout)})
+	writeJSON(w, http.StatusOK, f)
+}
+
+func (h *Flags) delete(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	if err := h.Service.Delete(r.Context(), key); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	h.Audit.record(r, model.AuditEvent{Action: model.AuditFlagChanged, TargetID: key, Detail: "deleted"})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setOverride answers PUT requests turning a flag on or off for the
+// organization or user named by the path value param.
+func (h *Flags) setOverride(subject model.FlagSubject, param string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var in model.FlagOverrideInput
+		if err := decodeJSON(r, &in); err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+		id := r.PathValue(param)
+		f, err := h.Service.SetOverride(r.Context(), r.PathValue("key"), subject, id, in)
+		if err != nil {
+			writeServiceError(w, r, err)
+			return
+		}
+		h.Audit.record(r, model.AuditEvent{Action: model.AuditFlagChanged, TargetID: f.Key,
+			Detail: fmt.Sprintf("enabled %t for %s %s", *in.Enabled, subject, id)})
+		writeJSON(w, http.StatusOK, f)
+	}
+}
+
+func (h *Flags) deleteOverride(subject model.FlagSubject, param string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, id := r.PathValue("key"), r.PathValue(param)
+		if err := h.Service.DeleteOverride(r.Context(), key, subject, id); err != nil {
+			writeServiceError(w, r, err)
+			return
+		}
+		h.Audit.record(r, model.AuditEvent{Action: model.AuditFlagChanged, TargetID: key,
+			Detail: fmt.Sprintf("no longer set for %s %s", subject, id)})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"starttech-server/integrations"
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+)
+
+// GitHub serves the endpoints that link projects to GitHub repositories.
+// The routes from Register must be mounted behind the auth middleware, the
+// webhook from RegisterPublic must not: GitHub signs its deliveries
+// instead.
+type GitHub struct {
+	Service *service.GitHub
+}
+
+// Register mounts the routes that manage a project's link on mux.
+func (h *GitHub) Register(mux router.Routes) {
+	mux.HandleFunc("GET /projects/{id}/github", h.get)
+	mux.HandleFunc("PUT /projects/{id}/github", h.link)
+	mux.HandleFunc("DELETE /projects/{id}/github", h.unlink)
+	mux.HandleFunc("POST /projects/{id}/github/sync", h.sync)
+}
+
+// RegisterPublic mounts the route link webhook URLs point to.
+func (h *GitHub) RegisterPublic(mux router.Routes) {
+	mux.HandleFunc("POST /integrations/github/{project_id}", h.webhook)
+}
+
+func (h *GitHub) get(w http.ResponseWriter, r *http.Request) {
+	l, err := h.Service.Get(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, l)
+}
+
+func (h *GitHub) link(w http.ResponseWriter, r *http.Request) {
+	var in model.GitHubLinkInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	l, err := h.Service.Link(r.Context(), currentUser(r), r.PathValue("id"), in)
+	if err != nil {
+		writeGitHubError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, l)
+}
+
+func (h *GitHub) unlink(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.Unlink(r.Context(), currentUser(r), r.PathValue("id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *GitHub) sync(w http.ResponseWriter, r *http.Request) {
+	res, err := h.Service.Sync(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeGitHubError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// webhook answers 404 for a project without a link and 401 for a delivery
+// it did not sign, so GitHub shows them as failed.
+func (h *GitHub) webhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	err = h.Service.HandleWebhook(r.Context(), r.PathValue("project_id"),
+		r.Header.Get(integrations.HeaderGitHubEvent), r.Header.Get(integrations.HeaderGitHubSignature), body)
+	switch {
+	case errors.Is(err, service.ErrBadSignature):
+		writeError(w, http.StatusUnauthorized, "the signature does not match")
+	case err != nil:
+		writeGitHubError(w, r, err)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// writeGitHubError reports a failure to reach GitHub as 502 Bad Gateway.
+func writeGitHubError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, integrations.ErrUnauthorized):
+		writeError(w, http.StatusBadGateway, "GitHub no longer accepts the token of this link")
+	case errors.Is(err, integrations.ErrUnavailable):
+		writeError(w, http.StatusBadGateway, "GitHub could not be reached")
+	default:
+		writeServiceError(w, r, err)
+	}
+}
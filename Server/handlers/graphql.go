@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"starttech-server/graphql"
+	"starttech-server/i18n"
+	"starttech-server/realtime"
+	"starttech-server/router"
+	"starttech-server/service"
+	"starttech-server/storage"
+)
+
+// graphqlMaxDepth bounds how deeply a query may nest, so one request
+// cannot walk project → tasks → project → tasks without end.
+const graphqlMaxDepth = 10
+
+// graphqlHeartbeat keeps idle subscription streams from being closed by
+// proxies.
+const graphqlHeartbeat = 25 * time.Second
+
+// GraphQL serves /graphql, a GraphQL view of tasks, projects, tags,
+// comments and users over the same services as the REST routes. Routes
+// must be mounted behind the auth middleware.
+type GraphQL struct {
+	Tasks    *service.Tasks
+	Projects *service.Projects
+	Tags     *service.Tags
+	Users    storage.UserStore
+	// Hub feeds subscriptions.
+	Hub *realtime.Hub
+
+	schema *graphql.Schema
+}
+
+// Register mounts the GraphQL routes on mux.
+func (h *GraphQL) Register(mux router.Routes) {
+	s, err := h.newSchema()
+	if err != nil {
+		panic(err)
+	}
+	h.schema = s
+	mux.HandleFunc("GET /graphql", h.serve)
+	mux.HandleFunc("POST /graphql", h.serve)
+}
+
+// serve answers queries, and mutations sent by POST, with a JSON response.
+// Subscriptions are answered with an event stream, in the format of the
+// graphql-sse protocol: a "next" event for each result, then "complete".
+func (h *GraphQL) serve(w http.ResponseWriter, r *http.Request) {
+	req, err := readGraphQLRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	kind := graphql.Query
+	if doc, err := graphql.Parse(req.Query); err == nil {
+		if op, err := doc.Operation(req.OperationName); err == nil {
+			kind = op.Kind
+		}
+	}
+	if kind == graphql.Mutation && r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "mutations must be sent with POST")
+		return
+	}
+	if kind == graphql.Subscription {
+		h.subscribe(w, r, req)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), loaderKey{}, &loader{results: map[string]loaded{}})
+	resp := h.schema.Execute(ctx, req)
+	status := http.StatusOK
+	if resp.Data == nil {
+		// The request could not be executed at all.
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, resp)
+}
+
+// readGraphQLRequest reads a request from a JSON body, or from the query,
+// operationName and variables parameters of a GET.
+func readGraphQLRequest(r *http.Request) (graphql.Request, error) {
+	var req graphql.Request
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if s := q.Get("variables"); s != "" {
+			dec := json.NewDecoder(strings.NewReader(s))
+			dec.UseNumber()
+			if err := dec.Decode(&req.Variables); err != nil {
+				return req, errors.New("variables must be a JSON object")
+			}
+		}
+	} else {
+		// Clients may send fields of their own, such as extensions, so
+		// unknown fields are not rejected as decodeJSON would.
+		dec := json.NewDecoder(r.Body)
+		dec.UseNumber()
+		if err := dec.Decode(&req); err != nil {
+			return req, errors.New("invalid JSON body")
+		}
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		return req, errors.New("query is required")
+	}
+	return req, nil
+}
+
+func (h *GraphQL) subscribe(w http.ResponseWriter, r *http.Request, req graphql.Request) {
+	rc := http.NewResponseController(w)
+	// The stream outlives the server's write timeout.
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	results, resp := h.schema.Subscribe(r.Context(), req)
+	if resp != nil {
+		writeJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	hdr := w.Header()
+	hdr.Set("Content-Type", "text/event-stream")
+	hdr.Set("Cache-Control", "no-cache")
+	hdr.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	ticker := time.NewTicker(graphqlHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case resp, ok := <-results:
+			if !ok {
+				fmt.Fprint(w, "event: complete\ndata:\n\n")
+				rc.Flush()
+				return
+			}
+			data, err := json.Marshal(resp)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "encoding subscription result", "err", err)
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: next\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			rc.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			rc.Flush()
+		}
+	}
+}
+
+// graphqlCodes name the statuses of serviceError in the code extension of
+// GraphQL errors.
+var graphqlCodes = map[int]string{
+	http.StatusBadRequest:          "VALIDATION_FAILED",
+	http.StatusNotFound:            "NOT_FOUND",
+	http.StatusConflict:            "CONFLICT",
+	http.StatusPreconditionFailed:  "PRECONDITION_FAILED",
+	http.StatusForbidden:           "FORBIDDEN",
+	http.StatusFailedDependency:    "NOT_APPLIED",
+	http.StatusPaymentRequired:     "QUOTA_EXCEEDED",
+	http.StatusTooManyRequests:     "QUOTA_EXCEEDED",
+	http.StatusInternalServerError: "INTERNAL",
+}
+
+// presentGraphQLError reports an error returned by a resolver as
+// writeServiceError would: with the same message, in the language of ctx,
+// the HTTP status as a code, and the invalid fields of a validation error.
+func presentGraphQLError(ctx context.Context, err error) *graphql.Error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return &graphql.Error{Message: "request canceled", Extensions: map[string]any{"code": "CANCELED"}, Err: err}
+	}
+	status, msg, fields := serviceError(err)
+	if status == http.StatusInternalServerError {
+		slog.ErrorContext(ctx, "graphql field failed", "err", err)
+	}
+	lang := i18n.Language(ctx)
+	ext := map[string]any{"code": graphqlCodes[status]}
+	if fields != nil {
+		ext["fields"] = fields.Localize(lang)
+	}
+	return &graphql.Error{Message: i18n.Translate(lang, msg), Extensions: ext, Err: err}
+}
+
+// loader remembers the users, projects and tags looked up while one
+// request runs, so a list of tasks fetches each owner or tag once.
+type loader struct {
+	mu      sync.Mutex
+	results map[string]loaded
+}
+
+type loaded struct {
+	v   any
+	err error
+}
+
+type loaderKey struct{}
+
+// load returns fetch's result for key, calling it only the first time key
+// is asked for in the request. Outside a request, as in a subscription
+// that lives for hours, it always calls fetch.
+func load[T any](ctx context.Context, key string, fetch func() (T, error)) (T, error) {
+	l, _ := ctx.Value(loaderKey{}).(*loader)
+	if l == nil {
+		return fetch()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if r, ok := l.results[key]; ok {
+		return r.v.(T), r.err
+	}
+	v, err := fetch()
+	l.results[key] = loaded{v, err}
+	return v, err
+}
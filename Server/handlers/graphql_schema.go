@@ -0,0 +1,665 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"starttech-server/auth"
+	"starttech-server/events"
+	"starttech-server/graphql"
+	"starttech-server/model"
+	"starttech-server/realtime"
+	"starttech-server/service"
+	"starttech-server/storage"
+)
+
+func nonNull(t graphql.Type) graphql.Type { return &graphql.NonNull{Of: t} }
+
+// listOf is a non-null list of non-null items, the shape of every list in
+// the schema.
+func listOf(t graphql.Type) graphql.Type {
+	return nonNull(&graphql.List{Of: nonNull(t)})
+}
+
+func userOf(p graphql.Params) string {
+	id, _ := auth.UserID(p.Context)
+	return id
+}
+
+// pageArgs are the paging arguments of task lists.
+var pageArgs = []*graphql.Arg{
+	{Name: "limit", Type: graphql.Int, Description: "Page size; the server's default when omitted."},
+	{Name: "cursor", Type: graphql.String, Description: "nextCursor of the previous page."},
+}
+
+// taskFilterArgs are the arguments of GET /tasks.
+var taskFilterArgs = append([]*graphql.Arg{
+	{Name: "status", Type: graphql.String},
+	{Name: "projectId", Type: graphql.ID},
+	{Name: "assignee", Type: graphql.ID, Description: `Only tasks assigned to this user, to the caller with "me", or to nobody with "none".`},
+	{Name: "tagIds", Type: &graphql.List{Of: nonNull(graphql.ID)}, Description: "Only tasks carrying every one of these tags."},
+	{Name: "dueBefore", Type: graphql.DateTime},
+	{Name: "dueAfter", Type: graphql.DateTime},
+	{Name: "archived", Type: graphql.Boolean, Description: "Only archived tasks if true, only the others if false. Archived tasks are left out by default."},
+	{Name: "sort", Type: graphql.String, Description: "One of " + strings.Join(storage.TaskSortKeys, ", ") + `, prefixed with "-" for descending order.`},
+}, pageArgs...)
+
+// taskFilter reads the filtering and paging arguments of a task list, as
+// parseTaskFilter reads those of GET /tasks.
+func taskFilter(args map[string]any) (storage.TaskFilter, string, error) {
+	var (
+		f storage.TaskFilter
+		v model.ValidationError
+	)
+	if n, ok := args["limit"].(int); ok {
+		if n < 1 {
+			v.Add("limit", "must be a positive integer")
+		}
+		f.Limit = n
+	}
+	if s, _ := args["sort"].(string); s != "" {
+		f.Sort.Desc = strings.HasPrefix(s, "-")
+		f.Sort.Field = strings.TrimPrefix(s, "-")
+		if !slices.Contains(storage.TaskSortKeys, f.Sort.Field) {
+			v.Add("sort", "must be one of "+strings.Join(storage.TaskSortKeys, ", "))
+		}
+	}
+	if s, _ := args["status"].(string); s != "" {
+		f.Status = model.Status(s)
+		if !model.ValidStatusKey(f.Status) {
+			v.Add("status", "is not a valid status key")
+		}
+	}
+	f.ProjectID, _ = args["projectId"].(string)
+	f.AssigneeID, _ = args["assignee"].(string)
+	f.DueBefore = timeArg(args, "dueBefore")
+	f.DueAfter = timeArg(args, "dueAfter")
+	f.TagIDs = stringsArg(args, "tagIds")
+	if b, ok := args["archived"].(bool); ok {
+		f.Archived = &b
+	}
+	cursor, _ := args["cursor"].(string)
+	return f, cursor, v.Err()
+}
+
+func timeArg(args map[string]any, name string) *time.Time {
+	if t, ok := args[name].(time.Time); ok {
+		t = t.UTC()
+		return &t
+	}
+	return nil
+}
+
+func stringsArg(args map[string]any, name string) []string {
+	items, _ := args[name].([]any)
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		out = append(out, item.(string))
+	}
+	return out
+}
+
+func stringPtr(args map[string]any, name string) *string {
+	if s, ok := args[name].(string); ok {
+		return &s
+	}
+	return nil
+}
+
+// optional reads a nullable field of a patch: absent, null or a value.
+func optional[T any](args map[string]any, name string) model.Optional[T] {
+	v, present := args[name]
+	if !present {
+		return model.Optional[T]{}
+	}
+	if v == nil {
+		return model.Optional[T]{Set: true, Null: true}
+	}
+	return model.Optional[T]{Set: true, Value: v.(T)}
+}
+
+func workflowArg(args map[string]any, name string) model.Workflow {
+	items, _ := args[name].([]any)
+	var w model.Workflow
+	for _, item := range items {
+		m := item.(map[string]any)
+		done, _ := m["done"].(bool)
+		w = append(w, model.Column{Key: model.Status(m["key"].(string)), Name: m["name"].(string), Done: done})
+	}
+	return w
+}
+
+// mutation wraps the resolver of a mutation, forgetting what the request
+// has loaded so far, which the mutation may have changed.
+func mutation(fn graphql.Resolver) graphql.Resolver {
+	return func(p graphql.Params) (any, error) {
+		if l, _ := p.Context.Value(loaderKey{}).(*loader); l != nil {
+			l.mu.Lock()
+			clear(l.results)
+			l.mu.Unlock()
+		}
+		return fn(p)
+	}
+}
+
+// publicUser is a user as others see them. Email is only filled in for
+// the caller.
+type publicUser struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Email     *string   `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (h *GraphQL) user(ctx context.Context, id string) (publicUser, error) {
+	u, err := load(ctx, "user:"+id, func() (model.User, error) { return h.Users.GetUser(ctx, id) })
+	if err != nil {
+		return publicUser{}, err
+	}
+	pu := publicUser{ID: u.ID, Username: u.Username, CreatedAt: u.CreatedAt}
+	if caller, _ := auth.UserID(ctx); caller == u.ID {
+		pu.Email = &u.Email
+	}
+	return pu, nil
+}
+
+func (h *GraphQL) project(ctx context.Context, userID, id string) (model.Project, error) {
+	return load(ctx, "project:"+id, func() (model.Project, error) { return h.Projects.Get(ctx, userID, id) })
+}
+
+// newSchema builds the schema served at /graphql. Its types mirror the
+// JSON of the REST API, with fields in camel case, plus fields that follow
+// the IDs to the objects they name.
+func (h *GraphQL) newSchema() (*graphql.Schema, error) {
+	var (
+		userType      = &graphql.Object{Name: "User", Description: "A user, as seen by the other members of their organization."}
+		taskType      = &graphql.Object{Name: "Task"}
+		taskPage      = &graphql.Object{Name: "TaskPage", Description: "One page of a task list."}
+		projectType   = &graphql.Object{Name: "Project"}
+		columnType    = &graphql.Object{Name: "Column", Description: "One status of a project's workflow."}
+		memberType    = &graphql.Object{Name: "Member", Description: "A user's membership in a project."}
+		tagType       = &graphql.Object{Name: "Tag"}
+		commentType   = &graphql.Object{Name: "Comment"}
+		progressType  = &graphql.Object{Name: "ChecklistProgress", Description: "How many items of a task's checklist are done."}
+		checklistType = &graphql.Object{Name: "ChecklistItem"}
+		eventType     = &graphql.Object{Name: "Event", Description: "A change to something the caller can see, as delivered by GET /events."}
+	)
+
+	userType.Fields = []*graphql.FieldDef{
+		{Name: "id", Type: nonNull(graphql.ID)},
+		{Name: "username", Type: nonNull(graphql.String)},
+		{Name: "email", Type: graphql.String, Description: "Only shown to the user themselves."},
+		{Name: "createdAt", Type: nonNull(graphql.DateTime)},
+	}
+
+	ownerField := func(ownerID func(any) string) *graphql.FieldDef {
+		return &graphql.FieldDef{Name: "owner", Type: nonNull(userType), Resolve: func(p graphql.Params) (any, error) {
+			return h.user(p.Context, ownerID(p.Source))
+		}}
+	}
+
+	taskType.Fields = []*graphql.FieldDef{
+		{Name: "id", Type: nonNull(graphql.ID)},
+		{Name: "orgId", Type: nonNull(graphql.ID)},
+		{Name: "ownerId", Type: nonNull(graphql.ID)},
+		ownerField(func(src any) string { return src.(model.Task).OwnerID }),
+		{Name: "assigneeId", Type: graphql.ID},
+		{Name: "assignee", Type: userType, Resolve: func(p graphql.Params) (any, error) {
+			t := p.Source.(model.Task)
+			if t.AssigneeID == nil {
+				return nil, nil
+			}
+			return h.user(p.Context, *t.AssigneeID)
+		}},
+		{Name: "projectId", Type: graphql.ID},
+		{Name: "project", Type: projectType, Resolve: func(p graphql.Params) (any, error) {
+			t := p.Source.(model.Task)
+			if t.ProjectID == nil {
+				return nil, nil
+			}
+			return h.project(p.Context, userOf(p), *t.ProjectID)
+		}},
+		{Name: "parentId", Type: graphql.ID},
+		{Name: "parent", Type: taskType, Resolve: func(p graphql.Params) (any, error) {
+			t := p.Source.(model.Task)
+			if t.ParentID == nil {
+				return nil, nil
+			}
+			return h.Tasks.Get(p.Context, userOf(p), *t.ParentID)
+		}},
+		{Name: "subtasks", Type: nonNull(taskPage), Args: pageArgs, Resolve: func(p graphql.Params) (any, error) {
+			f, cursor, err := taskFilter(p.Args)
+			if err != nil {
+				return nil, err
+			}
+			return h.Tasks.Children(p.Context, userOf(p), p.Source.(model.Task).ID, f, cursor)
+		}},
+		{Name: "position", Type: nonNull(graphql.Float)},
+		{Name: "title", Type: nonNull(graphql.String)},
+		{Name: "description", Type: nonNull(graphql.String)},
+		{Name: "status", Type: nonNull(graphql.String)},
+		{Name: "completed", Type: nonNull(graphql.Boolean)},
+		{Name: "priority", Type: nonNull(graphql.String)},
+		{Name: "dueDate", Type: graphql.DateTime},
+		{Name: "remindAt", Type: graphql.DateTime},
+		{Name: "recurrence", Type: nonNull(graphql.String)},
+		{Name: "tagIds", Type: listOf(graphql.ID)},
+		{Name: "tags", Type: listOf(tagType), Resolve: func(p graphql.Params) (any, error) {
+			var tags []model.Tag
+			for _, id := range p.Source.(model.Task).TagIDs {
+				tag, err := load(p.Context, "tag:"+id, func() (model.Tag, error) { return h.Tags.Get(p.Context, userOf(p), id) })
+				if errors.Is(err, storage.ErrNotFound) {
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+				tags = append(tags, tag)
+			}
+			return tags, nil
+		}},
+		{Name: "comments", Type: listOf(commentType), Resolve: func(p graphql.Params) (any, error) {
+			return h.Tasks.ListComments(p.Context, userOf(p), p.Source.(model.Task).ID)
+		}},
+		{Name: "checklist", Type: nonNull(progressType)},
+		{Name: "checklistItems", Type: listOf(checklistType), Resolve: func(p graphql.Params) (any, error) {
+			return h.Tasks.Checklist(p.Context, userOf(p), p.Source.(model.Task).ID)
+		}},
+		{Name: "createdAt", Type: nonNull(graphql.DateTime)},
+		{Name: "updatedAt", Type: nonNull(graphql.DateTime)},
+		{Name: "deletedAt", Type: graphql.DateTime},
+		{Name: "archivedAt", Type: graphql.DateTime},
+		{Name: "version", Type: nonNull(graphql.Int), Description: "Pass to updateTask to fail if the task has changed since, like If-Match."},
+	}
+
+	progressType.Fields = []*graphql.FieldDef{
+		{Name: "done", Type: nonNull(graphql.Int)},
+		{Name: "total", Type: nonNull(graphql.Int)},
+	}
+
+	checklistType.Fields = []*graphql.FieldDef{
+		{Name: "id", Type: nonNull(graphql.ID)},
+		{Name: "taskId", Type: nonNull(graphql.ID)},
+		{Name: "title", Type: nonNull(graphql.String)},
+		{Name: "done", Type: nonNull(graphql.Boolean)},
+		{Name: "position", Type: nonNull(graphql.Int)},
+		{Name: "createdAt", Type: nonNull(graphql.DateTime)},
+		{Name: "updatedAt", Type: nonNull(graphql.DateTime)},
+	}
+
+	taskPage.Fields = []*graphql.FieldDef{
+		{Name: "items", Type: listOf(taskType)},
+		{Name: "nextCursor", Type: graphql.String, Resolve: func(p graphql.Params) (any, error) {
+			if c := p.Source.(model.TaskPage).NextCursor; c != "" {
+				return c, nil
+			}
+			return nil, nil
+		}},
+	}
+
+	columnType.Fields = []*graphql.FieldDef{
+		{Name: "key", Type: nonNull(graphql.String)},
+		{Name: "name", Type: nonNull(graphql.String)},
+		{Name: "done", Type: nonNull(graphql.Boolean)},
+	}
+
+	projectType.Fields = []*graphql.FieldDef{
+		{Name: "id", Type: nonNull(graphql.ID)},
+		{Name: "orgId", Type: nonNull(graphql.ID)},
+		{Name: "ownerId", Type: nonNull(graphql.ID)},
+		ownerField(func(src any) string { return src.(model.Project).OwnerID }),
+		{Name: "name", Type: nonNull(graphql.String)},
+		{Name: "description", Type: nonNull(graphql.String)},
+		{Name: "statuses", Type: listOf(columnType), Resolve: func(p graphql.Params) (any, error) {
+			if w := p.Source.(model.Project).Statuses; len(w) > 0 {
+				return w, nil
+			}
+			return model.DefaultWorkflow, nil
+		}},
+		{Name: "tasks", Type: nonNull(taskPage), Args: taskFilterArgs, Resolve: func(p graphql.Params) (any, error) {
+			f, cursor, err := taskFilter(p.Args)
+			if err != nil {
+				return nil, err
+			}
+			return h.Tasks.InProject(p.Context, userOf(p), p.Source.(model.Project).ID, f, cursor)
+		}},
+		{Name: "members", Type: listOf(memberType), Resolve: func(p graphql.Params) (any, error) {
+			return h.Projects.Members(p.Context, userOf(p), p.Source.(model.Project).ID)
+		}},
+		{Name: "createdAt", Type: nonNull(graphql.DateTime)},
+		{Name: "updatedAt", Type: nonNull(graphql.DateTime)},
+		{Name: "archivedAt", Type: graphql.DateTime},
+	}
+
+	memberType.Fields = []*graphql.FieldDef{
+		{Name: "projectId", Type: nonNull(graphql.ID)},
+		{Name: "userId", Type: nonNull(graphql.ID)},
+		{Name: "user", Type: nonNull(userType), Resolve: func(p graphql.Params) (any, error) {
+			return h.user(p.Context, p.Source.(model.Member).UserID)
+		}},
+		{Name: "username", Type: nonNull(graphql.String)},
+		{Name: "role", Type: nonNull(graphql.String), Description: "viewer, editor or owner."},
+		{Name: "createdAt", Type: nonNull(graphql.DateTime)},
+	}
+
+	tagType.Fields = []*graphql.FieldDef{
+		{Name: "id", Type: nonNull(graphql.ID)},
+		{Name: "orgId", Type: nonNull(graphql.ID)},
+		{Name: "ownerId", Type: nonNull(graphql.ID)},
+		{Name: "name", Type: nonNull(graphql.String)},
+		{Name: "color", Type: nonNull(graphql.String)},
+		{Name: "createdAt", Type: nonNull(graphql.DateTime)},
+	}
+
+	commentType.Fields = []*graphql.FieldDef{
+		{Name: "id", Type: nonNull(graphql.ID)},
+		{Name: "taskId", Type: nonNull(graphql.ID)},
+		{Name: "authorId", Type: nonNull(graphql.ID)},
+		{Name: "author", Type: nonNull(userType), Resolve: func(p graphql.Params) (any, error) {
+			return h.user(p.Context, p.Source.(model.Comment).AuthorID)
+		}},
+		{Name: "username", Type: nonNull(graphql.String)},
+		{Name: "replyTo", Type: graphql.ID, Description: "The comment this one answers."},
+		{Name: "body", Type: nonNull(graphql.String)},
+		{Name: "replies", Type: nonNull(graphql.Int)},
+		{Name: "createdAt", Type: nonNull(graphql.DateTime)},
+		{Name: "editedAt", Type: graphql.DateTime},
+	}
+
+	eventType.Fields = []*graphql.FieldDef{
+		{Name: "id", Type: nonNull(graphql.ID), Description: "Pass as lastEventId to resume after this event."},
+		{Name: "type", Type: nonNull(graphql.String), Description: `The event type, such as "task.updated". "stream.reset" means events were missed.`},
+		{Name: "task", Type: taskType, Resolve: eventData[model.Task]},
+		{Name: "comment", Type: commentType, Resolve: eventData[model.Comment]},
+		{Name: "project", Type: projectType, Resolve: eventData[model.Project]},
+		{Name: "member", Type: memberType, Resolve: eventData[model.Member]},
+		{Name: "deletedId", Type: graphql.ID, Description: "The ID of what a *.deleted event removed.", Resolve: func(p graphql.Params) (any, error) {
+			if d, ok := p.Source.(events.Event).Data.(events.Deleted); ok {
+				return d.ID, nil
+			}
+			return nil, nil
+		}},
+	}
+
+	query := &graphql.Object{Name: "Query", Fields: []*graphql.FieldDef{
+		{Name: "me", Type: nonNull(userType), Resolve: func(p graphql.Params) (any, error) {
+			return h.user(p.Context, userOf(p))
+		}},
+		{Name: "task", Type: taskType, Args: []*graphql.Arg{{Name: "id", Type: nonNull(graphql.ID)}}, Resolve: func(p graphql.Params) (any, error) {
+			return h.Tasks.Get(p.Context, userOf(p), p.Args["id"].(string))
+		}},
+		{Name: "tasks", Type: nonNull(taskPage), Args: taskFilterArgs, Description: "The tasks visible to the caller, like GET /tasks.", Resolve: func(p graphql.Params) (any, error) {
+			f, cursor, err := taskFilter(p.Args)
+			if err != nil {
+				return nil, err
+			}
+			return h.Tasks.List(p.Context, userOf(p), f, cursor)
+		}},
+		{Name: "project", Type: projectType, Args: []*graphql.Arg{{Name: "id", Type: nonNull(graphql.ID)}}, Resolve: func(p graphql.Params) (any, error) {
+			return h.project(p.Context, userOf(p), p.Args["id"].(string))
+		}},
+		{Name: "projects", Type: listOf(projectType), Args: []*graphql.Arg{
+			{Name: "archived", Type: graphql.Boolean, Default: false, Description: "List the archived projects instead of the others."},
+		}, Resolve: func(p graphql.Params) (any, error) {
+			archived, _ := p.Args["archived"].(bool)
+			return h.Projects.List(p.Context, userOf(p), archived)
+		}},
+		{Name: "tag", Type: tagType, Args: []*graphql.Arg{{Name: "id", Type: nonNull(graphql.ID)}}, Resolve: func(p graphql.Params) (any, error) {
+			return h.Tags.Get(p.Context, userOf(p), p.Args["id"].(string))
+		}},
+		{Name: "tags", Type: listOf(tagType), Resolve: func(p graphql.Params) (any, error) {
+			return h.Tags.List(p.Context, userOf(p))
+		}},
+	}}
+
+	taskInput := &graphql.InputObject{Name: "TaskInput", Description: "A new task. Status is derived from completed when omitted.", Fields: []*graphql.Arg{
+		{Name: "title", Type: nonNull(graphql.String)},
+		{Name: "description", Type: graphql.String},
+		{Name: "status", Type: graphql.String},
+		{Name: "completed", Type: graphql.Boolean},
+		{Name: "priority", Type: graphql.String, Description: "One of none, low, medium, high or urgent."},
+		{Name: "dueDate", Type: graphql.DateTime},
+		{Name: "remindAt", Type: graphql.DateTime},
+		{Name: "recurrence", Type: graphql.String},
+		{Name: "projectId", Type: graphql.ID},
+		{Name: "parentId", Type: graphql.ID},
+		{Name: "assigneeId", Type: graphql.ID},
+		{Name: "tagIds", Type: &graphql.List{Of: nonNull(graphql.ID)}},
+	}}
+	taskPatch := &graphql.InputObject{Name: "TaskPatch", Description: "Changes to a task, like the body of PATCH /tasks/{id}: omitted fields are kept, and null clears dueDate, remindAt, projectId, parentId and assigneeId.", Fields: []*graphql.Arg{
+		{Name: "title", Type: graphql.String},
+		{Name: "description", Type: graphql.String},
+		{Name: "status", Type: graphql.String},
+		{Name: "completed", Type: graphql.Boolean},
+		{Name: "priority", Type: graphql.String, Description: "One of none, low, medium, high or urgent."},
+		{Name: "dueDate", Type: graphql.DateTime},
+		{Name: "remindAt", Type: graphql.DateTime},
+		{Name: "recurrence", Type: graphql.String},
+		{Name: "projectId", Type: graphql.ID},
+		{Name: "parentId", Type: graphql.ID},
+		{Name: "assigneeId", Type: graphql.ID},
+		{Name: "tagIds", Type: &graphql.List{Of: nonNull(graphql.ID)}},
+	}}
+	columnInput := &graphql.InputObject{Name: "ColumnInput", Fields: []*graphql.Arg{
+		{Name: "key", Type: nonNull(graphql.String)},
+		{Name: "name", Type: nonNull(graphql.String)},
+		{Name: "done", Type: graphql.Boolean, Default: false},
+	}}
+	projectInput := &graphql.InputObject{Name: "ProjectInput", Fields: []*graphql.Arg{
+		{Name: "name", Type: nonNull(graphql.String)},
+		{Name: "description", Type: graphql.String},
+		{Name: "statuses", Type: &graphql.List{Of: nonNull(columnInput)}},
+	}}
+	projectPatch := &graphql.InputObject{Name: "ProjectPatch", Fields: []*graphql.Arg{
+		{Name: "name", Type: graphql.String},
+		{Name: "description", Type: graphql.String},
+		{Name: "statuses", Type: &graphql.List{Of: nonNull(columnInput)}},
+	}}
+	tagInput := &graphql.InputObject{Name: "TagInput", Fields: []*graphql.Arg{
+		{Name: "name", Type: nonNull(graphql.String)},
+		{Name: "color", Type: graphql.String},
+	}}
+	tagPatch := &graphql.InputObject{Name: "TagPatch", Fields: []*graphql.Arg{
+		{Name: "name", Type: graphql.String},
+		{Name: "color", Type: graphql.String},
+	}}
+	commentInput := &graphql.InputObject{Name: "CommentInput", Fields: []*graphql.Arg{
+		{Name: "body", Type: nonNull(graphql.String)},
+		{Name: "replyTo", Type: graphql.ID},
+	}}
+	childPolicy := &graphql.Enum{Name: "ChildPolicy", Description: "What happens to the subtasks of a deleted task.", Values: []*graphql.EnumValueDef{
+		{Name: "REPARENT", Value: service.ReparentChildren, Description: "Move them up to the deleted task's parent."},
+		{Name: "CASCADE", Value: service.CascadeChildren, Description: "Delete them too."},
+	}}
+
+	id := &graphql.Arg{Name: "id", Type: nonNull(graphql.ID)}
+	taskID := &graphql.Arg{Name: "taskId", Type: nonNull(graphql.ID)}
+	deleted := func(err error) (any, error) { return err == nil, err }
+
+	mutations := &graphql.Object{Name: "Mutation", Fields: []*graphql.FieldDef{
+		{Name: "createTask", Type: nonNull(taskType), Args: []*graphql.Arg{{Name: "input", Type: nonNull(taskInput)}}, Resolve: mutation(func(p graphql.Params) (any, error) {
+			in := p.Args["input"].(map[string]any)
+			t := model.TaskInput{
+				Title:      in["title"].(string),
+				DueDate:    timeArg(in, "dueDate"),
+				RemindAt:   timeArg(in, "remindAt"),
+				ProjectID:  stringPtr(in, "projectId"),
+				ParentID:   stringPtr(in, "parentId"),
+				AssigneeID: stringPtr(in, "assigneeId"),
+				TagIDs:     stringsArg(in, "tagIds"),
+			}
+			t.Description, _ = in["description"].(string)
+			status, _ := in["status"].(string)
+			t.Status = model.Status(status)
+			t.Completed, _ = in["completed"].(bool)
+			priority, _ := in["priority"].(string)
+			t.Priority = model.Priority(priority)
+			t.Recurrence, _ = in["recurrence"].(string)
+			return h.Tasks.Create(p.Context, userOf(p), t)
+		})},
+		{Name: "updateTask", Type: nonNull(taskType), Args: []*graphql.Arg{
+			id,
+			{Name: "input", Type: nonNull(taskPatch)},
+			{Name: "version", Type: graphql.Int, Description: "Fail with PRECONDITION_FAILED unless the task is still at this version."},
+		}, Resolve: mutation(func(p graphql.Params) (any, error) {
+			in := p.Args["input"].(map[string]any)
+			patch := model.TaskPatch{
+				Title:       stringPtr(in, "title"),
+				Description: stringPtr(in, "description"),
+				DueDate:     optional[time.Time](in, "dueDate"),
+				RemindAt:    optional[time.Time](in, "remindAt"),
+				Recurrence:  stringPtr(in, "recurrence"),
+				ProjectID:   optional[string](in, "projectId"),
+				ParentID:    optional[string](in, "parentId"),
+				AssigneeID:  optional[string](in, "assigneeId"),
+			}
+			if s := stringPtr(in, "status"); s != nil {
+				status := model.Status(*s)
+				patch.Status = &status
+			}
+			if c, ok := in["completed"].(bool); ok {
+				patch.Completed = &c
+			}
+			if s := stringPtr(in, "priority"); s != nil {
+				priority := model.Priority(*s)
+				patch.Priority = &priority
+			}
+			if in["tagIds"] != nil {
+				ids := stringsArg(in, "tagIds")
+				patch.TagIDs = &ids
+			}
+			var match []int64
+			if v, ok := p.Args["version"].(int); ok {
+				match = []int64{int64(v)}
+			}
+			return h.Tasks.Update(p.Context, userOf(p), p.Args["id"].(string), match, patch.Apply)
+		})},
+		{Name: "deleteTask", Type: nonNull(graphql.Boolean), Description: "Moves a task to the trash.", Args: []*graphql.Arg{
+			id,
+			{Name: "children", Type: childPolicy, Default: "REPARENT"},
+		}, Resolve: mutation(func(p graphql.Params) (any, error) {
+			return deleted(h.Tasks.Delete(p.Context, userOf(p), p.Args["id"].(string), p.Args["children"].(service.ChildPolicy)))
+		})},
+		{Name: "restoreTask", Type: nonNull(taskType), Args: []*graphql.Arg{id}, Resolve: mutation(func(p graphql.Params) (any, error) {
+			return h.Tasks.Restore(p.Context, userOf(p), p.Args["id"].(string))
+		})},
+		{Name: "addComment", Type: nonNull(commentType), Args: []*graphql.Arg{taskID, {Name: "input", Type: nonNull(commentInput)}}, Resolve: mutation(func(p graphql.Params) (any, error) {
+			in := p.Args["input"].(map[string]any)
+			c := model.CommentInput{Body: in["body"].(string), ReplyTo: stringPtr(in, "replyTo")}
+			return h.Tasks.AddComment(p.Context, userOf(p), p.Args["taskId"].(string), c)
+		})},
+		{Name: "editComment", Type: nonNull(commentType), Args: []*graphql.Arg{taskID, id, {Name: "body", Type: nonNull(graphql.String)}}, Resolve: mutation(func(p graphql.Params) (any, error) {
+			return h.Tasks.EditComment(p.Context, userOf(p), p.Args["taskId"].(string), p.Args["id"].(string), model.CommentPatch{Body: p.Args["body"].(string)})
+		})},
+		{Name: "deleteComment", Type: nonNull(graphql.Boolean), Args: []*graphql.Arg{taskID, id}, Resolve: mutation(func(p graphql.Params) (any, error) {
+			return deleted(h.Tasks.DeleteComment(p.Context, userOf(p), p.Args["taskId"].(string), p.Args["id"].(string)))
+		})},
+		{Name: "createProject", Type: nonNull(projectType), Args: []*graphql.Arg{{Name: "input", Type: nonNull(projectInput)}}, Resolve: mutation(func(p graphql.Params) (any, error) {
+			in := p.Args["input"].(map[string]any)
+			pi := model.ProjectInput{Name: in["name"].(string), Statuses: workflowArg(in, "statuses")}
+			pi.Description, _ = in["description"].(string)
+			return h.Projects.Create(p.Context, userOf(p), pi)
+		})},
+		{Name: "updateProject", Type: nonNull(projectType), Args: []*graphql.Arg{id, {Name: "input", Type: nonNull(projectPatch)}}, Resolve: mutation(func(p graphql.Params) (any, error) {
+			in := p.Args["input"].(map[string]any)
+			patch := model.ProjectPatch{Name: stringPtr(in, "name"), Description: stringPtr(in, "description")}
+			if in["statuses"] != nil {
+				w := workflowArg(in, "statuses")
+				patch.Statuses = &w
+			}
+			return h.Projects.Update(p.Context, userOf(p), p.Args["id"].(string), patch.Apply)
+		})},
+		{Name: "deleteProject", Type: nonNull(graphql.Boolean), Args: []*graphql.Arg{id}, Resolve: mutation(func(p graphql.Params) (any, error) {
+			return deleted(h.Projects.Delete(p.Context, userOf(p), p.Args["id"].(string)))
+		})},
+		{Name: "createTag", Type: nonNull(tagType), Args: []*graphql.Arg{{Name: "input", Type: nonNull(tagInput)}}, Resolve: mutation(func(p graphql.Params) (any, error) {
+			in := p.Args["input"].(map[string]any)
+			ti := model.TagInput{Name: in["name"].(string)}
+			ti.Color, _ = in["color"].(string)
+			return tagResult(h.Tags.Create(p.Context, userOf(p), ti))
+		})},
+		{Name: "updateTag", Type: nonNull(tagType), Args: []*graphql.Arg{id, {Name: "input", Type: nonNull(tagPatch)}}, Resolve: mutation(func(p graphql.Params) (any, error) {
+			in := p.Args["input"].(map[string]any)
+			patch := model.TagPatch{Name: stringPtr(in, "name"), Color: stringPtr(in, "color")}
+			return tagResult(h.Tags.Update(p.Context, userOf(p), p.Args["id"].(string), patch))
+		})},
+		{Name: "deleteTag", Type: nonNull(graphql.Boolean), Args: []*graphql.Arg{id}, Resolve: mutation(func(p graphql.Params) (any, error) {
+			return deleted(h.Tags.Delete(p.Context, userOf(p), p.Args["id"].(string)))
+		})},
+	}}
+
+	subscriptions := &graphql.Object{Name: "Subscription", Fields: []*graphql.FieldDef{
+		{Name: "events", Type: nonNull(eventType), Description: "The caller's events, as GET /events streams them.", Args: []*graphql.Arg{
+			{Name: "types", Type: &graphql.List{Of: nonNull(graphql.String)}, Description: "Only events of these types; stream.reset is always sent."},
+			{Name: "lastEventId", Type: graphql.ID, Description: "Resume after this event."},
+		}, Subscribe: h.events},
+	}}
+
+	s, err := graphql.NewSchema(query, mutations, subscriptions)
+	if err != nil {
+		return nil, err
+	}
+	s.MaxDepth = graphqlMaxDepth
+	s.PresentError = presentGraphQLError
+	return s, nil
+}
+
+// eventData resolves the Data of an event if it is a T.
+func eventData[T any](p graphql.Params) (any, error) {
+	if v, ok := p.Source.(events.Event).Data.(T); ok {
+		return v, nil
+	}
+	return nil, nil
+}
+
+// tagResult reports a duplicate tag name as writeTagError does.
+func tagResult(t model.Tag, err error) (any, error) {
+	if errors.Is(err, storage.ErrConflict) {
+		return nil, &graphql.Error{Message: "a tag with that name already exists", Extensions: map[string]any{"code": "CONFLICT"}}
+	}
+	return t, err
+}
+
+// events subscribes to the hub for the caller's events.
+func (h *GraphQL) events(p graphql.Params) (<-chan any, error) {
+	var lastID uint64
+	if s, ok := p.Args["lastEventId"].(string); ok {
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			var v model.ValidationError
+			v.Add("lastEventId", "must be the id of an event")
+			return nil, v.Err()
+		}
+		lastID = n
+	}
+	types := stringsArg(p.Args, "types")
+	orgID, _ := auth.OrgID(p.Context)
+	sub := h.Hub.Subscribe(userOf(p), orgID, lastID)
+	if sub == nil {
+		return nil, errors.New("server shutting down")
+	}
+	out := make(chan any)
+	go func() {
+		defer close(out)
+		defer sub.Close()
+		for {
+			e, ok := sub.Next(p.Context)
+			if !ok {
+				return
+			}
+			if len(types) > 0 && e.Type != realtime.StreamReset && !slices.Contains(types, string(e.Type)) {
+				continue
+			}
+			select {
+			case out <- e:
+			case <-p.Context.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
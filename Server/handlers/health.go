@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"starttech-server/apierror"
+	"starttech-server/health"
+	"starttech-server/model"
+)
+
+// Health reports that the server is up, for liveness probes: it answers as
+// long as the process can serve at all, whatever its dependencies are
+// doing. It is mounted on the root mux, whose catch-all for the API would
+// answer other methods with 404, so it refuses them itself.
+func Health(w http.ResponseWriter, r *http.Request) {
+	if !getOnly(w, r) {
+		return
+	}
+	writeJSON(w, http.StatusOK, model.Health{Status: model.HealthOK})
+}
+
+// Readiness serves GET /readyz, for readiness probes. It answers 200 when
+// every component of Checker is fine and 503 otherwise, listing each
+// component's status. Why one is down is logged rather than returned, as
+// the endpoint is public.
+type Readiness struct {
+	Checker *health.Checker
+}
+
+func (h *Readiness) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !getOnly(w, r) {
+		return
+	}
+	body := model.Health{Status: model.HealthOK, Components: map[string]string{}}
+	for name, err := range h.Checker.Check(r.Context()) {
+		if err == nil {
+			body.Components[name] = model.HealthOK
+			continue
+		}
+		slog.WarnContext(r.Context(), "readiness check failed", "component", name, "err", err)
+		body.Components[name] = model.HealthDown
+		body.Status = model.HealthDown
+	}
+	status := http.StatusOK
+	if body.Status != model.HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, body)
+}
+
+// getOnly answers requests other than GET and HEAD with 405, reporting
+// whether r may go on.
+func getOnly(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	apierror.WriteError(w, http.StatusMethodNotAllowed, apierror.Error{
+		Message: r.Method + " is not allowed on " + r.URL.Path,
+		Details: map[string][]string{"allow": {"GET", "HEAD"}},
+	})
+	return false
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"starttech-server/apierror"
+	"starttech-server/auth"
+	"starttech-server/storage"
+)
+
+// maxIdempotencyKeyLen bounds the Idempotency-Key header.
+const maxIdempotencyKeyLen = 255
+
+// maxUnreadBody bounds what is left of a body for the middleware to read
+// after the handler; bigger bodies were rejected anyway, and the response
+// is not kept.
+const maxUnreadBody = 1 << 20
+
+// maxKeptBody bounds the responses kept for replay. Bigger ones, such as
+// backups, are not kept, so a retry runs the request again.
+const maxKeptBody = 1 << 20
+
+// replayedHeaders are the response headers kept alongside the body of a
+// request that may be replayed.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// Idempotency answers a POST sent again with the same Idempotency-Key header
+// with the response to the first one, so a client retrying after a lost
+// reply does not create a second resource. Keys belong to the user; reusing
+// one for a different request is refused. Responses with a 5xx status are
+// not kept, so those requests can be retried. It must run after the auth
+// middleware.
+type Idempotency struct {
+	Store storage.IdempotencyStore
+}
+
+// Middleware guards the POST routes of next.
+func (h *Idempotency) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			writeError(w, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+			return
+		}
+
+		// The fingerprint is finished once the body has been read, by the
+		// handler or after it.
+		orgID, _ := auth.OrgID(r.Context())
+		sum := sha256.New()
+		io.WriteString(sum, orgID+"\n"+r.Method+" "+r.URL.RequestURI()+"\n")
+
+		rec := storage.IdempotencyRecord{UserID: currentUser(r), Key: key, CreatedAt: time.Now().UTC()}
+		prev, claimed, err := h.Store.ClaimIdempotencyKey(r.Context(), rec)
+		if err != nil {
+			writeInternalError(w, r, "claiming idempotency key", err)
+			return
+		}
+		if !claimed {
+			if _, err := io.Copy(sum, r.Body); err != nil {
+				writeError(w, http.StatusBadRequest, "could not read the request body")
+				return
+			}
+			switch {
+			case !prev.Done:
+				writeErrorCode(w, http.StatusConflict, apierror.CodeIdempotencyInFlight, "a request with this Idempotency-Key is still in progress")
+			case prev.Fingerprint != fingerprint(sum):
+				writeErrorCode(w, http.StatusUnprocessableEntity, apierror.CodeIdempotencyReused, "this Idempotency-Key was used for a different request")
+			default:
+				replay(w, prev)
+			}
+			return
+		}
+
+		// The client may be gone by the time the response is ready, which
+		// is when it most needs the response kept for its retry.
+		ctx := context.WithoutCancel(r.Context())
+		done := false
+		defer func() {
+			if !done {
+				h.release(ctx, rec)
+			}
+		}()
+
+		body := r.Body
+		tee := io.TeeReader(body, sum)
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{tee, body}
+		resp := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(resp, r)
+		done = true
+
+		if resp.status >= 500 || resp.overflow {
+			h.release(ctx, rec)
+			return
+		}
+		if n, err := io.CopyN(io.Discard, tee, maxUnreadBody+1); n > maxUnreadBody || err != nil && err != io.EOF {
+			h.release(ctx, rec)
+			return
+		}
+		rec.Fingerprint = fingerprint(sum)
+		rec.Status = resp.status
+		rec.Header = map[string]string{}
+		for _, name := range replayedHeaders {
+			if v := resp.Header().Get(name); v != "" {
+				rec.Header[name] = v
+			}
+		}
+		rec.Body = resp.body.Bytes()
+		if err := h.Store.FinishIdempotencyKey(ctx, rec); err != nil {
+			slog.ErrorContext(ctx, "saving idempotent response", "err", err)
+			h.release(ctx, rec)
+		}
+	})
+}
+
+// release forgets the claim on rec's key so the request can be retried.
+func (h *Idempotency) release(ctx context.Context, rec storage.IdempotencyRecord) {
+	if err := h.Store.ReleaseIdempotencyKey(ctx, rec.UserID, rec.Key); err != nil {
+		slog.ErrorContext(ctx, "releasing idempotency key", "err", err)
+	}
+}
+
+func fingerprint(sum hash.Hash) string {
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// replay writes the response kept in rec.
+func replay(w http.ResponseWriter, rec storage.IdempotencyRecord) {
+	for name, v := range rec.Header {
+		w.Header().Set(name, v)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(rec.Status)
+	w.Write(rec.Body)
+}
+
+// responseRecorder passes a response through while keeping a copy of up
+// to maxKeptBody bytes.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	overflow    bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	switch {
+	case r.overflow:
+	case r.body.Len()+len(b) > maxKeptBody:
+		r.overflow = true
+		r.body = bytes.Buffer{}
+	default:
+		r.body.Write(b)
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
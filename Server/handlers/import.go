@@ -0,0 +1,396 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"starttech-server/middleware"
+	"starttech-server/model"
+	"starttech-server/service"
+)
+
+// maxImportBytes bounds the body of POST /projects/{id}/import.
+const maxImportBytes = 5 << 20
+
+// tagSeparator joins the tag names of a task in a CSV cell.
+const tagSeparator = ";"
+
+// importFields are the fields of a model.TaskRecord an import reads; the
+// timestamps are only exported.
+var importFields = []string{"id", "title", "description", "status", "completed", "due_date", "recurrence", "tags"}
+
+// export writes the tasks of a project as they are read from the store,
+// as a JSON array or, with format=csv, as CSV with a header row of
+// model.RecordFields.
+func (h *Projects) export(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		var v model.ValidationError
+		v.Add("format", "must be csv or json")
+		writeServiceError(w, r, v.Err())
+		return
+	}
+	// Refuse before any of the body is sent.
+	p, err := h.Service.Get(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	contentType := "application/json"
+	if format == "csv" {
+		contentType = "text/csv; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "tasks-" + p.ID + "." + format}))
+	w.WriteHeader(http.StatusOK)
+
+	var (
+		write  func(model.TaskRecord) error
+		finish func()
+	)
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(model.RecordFields)
+		write = func(rec model.TaskRecord) error { return cw.Write(csvRecord(rec)) }
+		finish = cw.Flush
+	default:
+		n := 0
+		enc := json.NewEncoder(w)
+		write = func(rec model.TaskRecord) error {
+			sep := ",\n"
+			if n == 0 {
+				sep = "[\n"
+			}
+			n++
+			if _, err := io.WriteString(w, sep); err != nil {
+				return err
+			}
+			return enc.Encode(rec)
+		}
+		finish = func() {
+			if n == 0 {
+				io.WriteString(w, "[")
+			}
+			io.WriteString(w, "]\n")
+		}
+	}
+	if err := h.Tasks.Export(r.Context(), currentUser(r), p.ID, write); err != nil {
+		// The status is sent by now, so all that can be done is to cut the
+		// body short, where a complete one would end with "]" or a flush.
+		slog.ErrorContext(r.Context(), "export failed", "project_id", p.ID, "err", err)
+		panic(http.ErrAbortHandler)
+	}
+	finish()
+}
+
+// csvRecord is rec as a row of an export, in the order of
+// model.RecordFields.
+func csvRecord(rec model.TaskRecord) []string {
+	timestamp := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	return []string{
+		rec.ID,
+		rec.Title,
+		rec.Description,
+		string(rec.Status),
+		strconv.FormatBool(rec.Completed),
+		timestamp(rec.DueDate),
+		rec.Recurrence,
+		strings.Join(rec.Tags, tagSeparator),
+		timestamp(rec.CreatedAt),
+		timestamp(rec.UpdatedAt),
+	}
+}
+
+// importTasks creates tasks in a project from a body in the format of an
+// export: CSV with a header row when the Content-Type is text/csv, and a
+// JSON array of objects otherwise. Its query parameters are:
+//
+//	map         field:column, to read a field from a column of another
+//	            name; repeat for several fields
+//	dry_run     true to report what would be created without creating it
+//	duplicates  skip (the default) or keep
+//
+// It answers 200 with a model.ImportResult unless a row was invalid, in
+// which case nothing was created and the same report comes with 422.
+func (h *Projects) importTasks(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var (
+		v    model.ValidationError
+		opts service.ImportOptions
+	)
+	var err error
+	if opts.DryRun, err = dryRun(r); err != nil {
+		v.Add("dry_run", "must be true or false")
+	}
+	switch q.Get("duplicates") {
+	case "", "skip":
+	case "keep":
+		opts.KeepDuplicates = true
+	default:
+		v.Add("duplicates", "must be skip or keep")
+	}
+	columns := map[string]string{}
+	for _, m := range q["map"] {
+		field, column, ok := strings.Cut(m, ":")
+		switch {
+		case !ok || strings.TrimSpace(column) == "":
+			v.Add("map", "must be field:column")
+		case !slices.Contains(importFields, field):
+			v.Add("map", "unknown field "+field)
+		default:
+			columns[field] = strings.TrimSpace(column)
+		}
+	}
+	if err := v.Err(); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	read := readJSONRecords
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+		read = readCSVRecords
+	}
+	middleware.AllowBodySize(r, maxImportBytes)
+	loc := h.Tasks.Zone(r.Context(), currentUser(r))
+	records, err := read(http.MaxBytesReader(w, r.Body, maxImportBytes), columns, loc)
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("imports may be at most %d bytes", maxImportBytes))
+		return
+	}
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	items, committed, err := h.Tasks.Import(r.Context(), currentUser(r), r.PathValue("id"), records, opts)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	res := model.ImportResult{DryRun: opts.DryRun, Committed: committed, Rows: make([]model.ImportRow, len(items))}
+	for i, item := range items {
+		row := &res.Rows[i]
+		row.Row, row.Action, row.Task, row.DuplicateOf = i+1, item.Action, item.Task, item.DuplicateOf
+		switch item.Action {
+		case model.ImportCreate:
+			res.Created++
+		case model.ImportDuplicate:
+			res.Duplicates++
+		case model.ImportInvalid:
+			res.Invalid++
+			_, e := localError(r, item.Err)
+			row.Error = &e
+		}
+	}
+	status := http.StatusOK
+	if res.Invalid > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, res)
+}
+
+// dryRun reads the dry_run query parameter of destructive endpoints, which
+// asks what they would do without doing it.
+func dryRun(r *http.Request) (bool, error) {
+	s := r.URL.Query().Get("dry_run")
+	if s == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		var v model.ValidationError
+		v.Add("dry_run", "must be true or false")
+		return false, v.Err()
+	}
+	return b, nil
+}
+
+// column returns the column that field is read from.
+func column(columns map[string]string, field string) string {
+	if c, ok := columns[field]; ok {
+		return c
+	}
+	return field
+}
+
+// readCSVRecords reads an import in CSV. Columns are matched to fields by
+// their header, ignoring case; columns that match no field are ignored.
+// Bare dates are read in loc.
+func readCSVRecords(body io.Reader, columns map[string]string, loc *time.Location) ([]model.TaskRecord, error) {
+	cr := csv.NewReader(body)
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+	// Spreadsheets tend to start their exports with a byte order mark.
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	index := map[string]int{}
+	for _, field := range importFields {
+		want := column(columns, field)
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), want) {
+				index[field] = i
+				break
+			}
+		}
+	}
+	var v model.ValidationError
+	if _, ok := index["title"]; !ok {
+		v.Add("columns", fmt.Sprintf("must include a %q column", column(columns, "title")))
+		return nil, v.Err()
+	}
+
+	var records []model.TaskRecord
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+		if len(records) == model.MaxImportRows {
+			v.Add("rows", fmt.Sprintf("must hold at most %d rows", model.MaxImportRows))
+			return nil, v.Err()
+		}
+		var rec model.TaskRecord
+		at := fmt.Sprintf("rows[%d].", len(records)+1)
+		for field, i := range index {
+			setRecordField(&rec, field, row[i], at+field, loc, &v)
+		}
+		records = append(records, rec)
+	}
+	return records, v.Err()
+}
+
+// csvError reports a malformed CSV body, keeping a read error such as an
+// oversized body as it is.
+func csvError(err error) error {
+	var parseErr *csv.ParseError
+	if !errors.As(err, &parseErr) {
+		return err
+	}
+	var v model.ValidationError
+	v.Add("body", "is not valid CSV: "+parseErr.Error())
+	return v.Err()
+}
+
+// readJSONRecords reads an import in JSON: an array of objects whose keys
+// name columns as a CSV header would. Values may be strings in the form
+// CSV takes, or of the JSON type of the field. Bare dates are read in loc.
+func readJSONRecords(body io.Reader, columns map[string]string, loc *time.Location) ([]model.TaskRecord, error) {
+	var (
+		objects []map[string]json.RawMessage
+		v       model.ValidationError
+	)
+	if err := json.NewDecoder(body).Decode(&objects); err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			return nil, err
+		}
+		v.Add("body", "must be a JSON array of objects")
+		return nil, v.Err()
+	}
+	if len(objects) > model.MaxImportRows {
+		v.Add("rows", fmt.Sprintf("must hold at most %d rows", model.MaxImportRows))
+		return nil, v.Err()
+	}
+	records := make([]model.TaskRecord, len(objects))
+	for i, obj := range objects {
+		at := fmt.Sprintf("rows[%d].", i+1)
+		for _, field := range importFields {
+			raw, ok := obj[column(columns, field)]
+			if !ok {
+				continue
+			}
+			var s string
+			if err := json.Unmarshal(raw, &s); err == nil {
+				setRecordField(&records[i], field, s, at+field, loc, &v)
+				continue
+			}
+			if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+				continue
+			}
+			if err := json.Unmarshal(raw, recordTarget(&records[i], field)); err != nil {
+				v.Add(at+field, "has the wrong type")
+			}
+		}
+	}
+	return records, v.Err()
+}
+
+// recordTarget returns the field of rec named field, for a JSON value
+// to be decoded into.
+func recordTarget(rec *model.TaskRecord, field string) any {
+	switch field {
+	case "id":
+		return &rec.ID
+	case "title":
+		return &rec.Title
+	case "description":
+		return &rec.Description
+	case "status":
+		return &rec.Status
+	case "completed":
+		return &rec.Completed
+	case "due_date":
+		return &rec.DueDate
+	case "recurrence":
+		return &rec.Recurrence
+	case "tags":
+		return &rec.Tags
+	}
+	panic("handlers: no record field " + field)
+}
+
+// setRecordField sets field of rec from s as a CSV cell holds it, reading a
+// bare date in loc and recording a problem under name in v.
+func setRecordField(rec *model.TaskRecord, field, s, name string, loc *time.Location, v *model.ValidationError) {
+	switch dst := recordTarget(rec, field).(type) {
+	case *string:
+		*dst = s
+	case *model.Status:
+		*dst = model.Status(strings.TrimSpace(s))
+	case *bool:
+		if s = strings.TrimSpace(s); s != "" {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				v.Add(name, "must be true or false")
+			}
+			*dst = b
+		}
+	case **time.Time:
+		*dst = parseTimeParam(strings.TrimSpace(s), name, loc, v)
+	case *[]string:
+		for _, tag := range strings.Split(s, tagSeparator) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				*dst = append(*dst, tag)
+			}
+		}
+	}
+}
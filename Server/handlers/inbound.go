@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"starttech-server/middleware"
+	"starttech-server/router"
+	"starttech-server/service"
+)
+
+// InboundEmail serves the addresses emails become tasks through. Routes
+// from Register must be mounted behind the auth middleware, the one from
+// RegisterPublic must not: the mail server authenticates with the shared
+// secret instead.
+type InboundEmail struct {
+	Service *service.InboundEmail
+	// MaxSize bounds the messages accepted, attachments included.
+	MaxSize int64
+}
+
+// Register mounts the routes that manage the caller's address on mux.
+func (h *InboundEmail) Register(mux router.Routes) {
+	mux.HandleFunc("GET /me/inbound-email", h.get)
+	mux.HandleFunc("POST /me/inbound-email", h.create)
+	mux.HandleFunc("DELETE /me/inbound-email", h.delete)
+}
+
+// RegisterPublic mounts the route the mail server posts messages to.
+func (h *InboundEmail) RegisterPublic(mux router.Routes) {
+	mux.HandleFunc("POST /integrations/email", h.receive)
+}
+
+func (h *InboundEmail) get(w http.ResponseWriter, r *http.Request) {
+	a, err := h.Service.Get(r.Context(), currentUser(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, a)
+}
+
+func (h *InboundEmail) create(w http.ResponseWriter, r *http.Request) {
+	a, err := h.Service.Create(r.Context(), currentUser(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, a)
+}
+
+func (h *InboundEmail) delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.Delete(r.Context(), currentUser(r)); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// receive takes a raw RFC 5322 message as the body. The secret comes as a
+// bearer token or as the password of basic authentication, which is what
+// mail providers that post to a URL can send.
+func (h *InboundEmail) receive(w http.ResponseWriter, r *http.Request) {
+	secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		_, secret, _ = r.BasicAuth()
+	}
+	middleware.AllowBodySize(r, h.MaxSize)
+	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.MaxSize))
+	var tooBig *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooBig):
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("messages may be at most %d bytes", h.MaxSize))
+		return
+	case err != nil:
+		writeDecodeError(w, err)
+		return
+	}
+	res, err := h.Service.Receive(r.Context(), secret, r.URL.Query().Get("recipient"), bytes.NewReader(raw))
+	switch {
+	case errors.Is(err, service.ErrBadSignature):
+		writeError(w, http.StatusUnauthorized, "the secret does not match")
+	case err != nil:
+		writeServiceError(w, r, err)
+	default:
+		writeJSON(w, http.StatusCreated, res)
+	}
+}
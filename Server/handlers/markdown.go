@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"starttech-server/markdown"
+	"starttech-server/model"
+	"starttech-server/router"
+)
+
+// Markdown renders text for clients to preview, so that what they show
+// while it is written matches what the server returns once it is saved.
+type Markdown struct{}
+
+// Register mounts the route on mux.
+func (h Markdown) Register(mux router.Routes) {
+	mux.HandleFunc("POST /markdown", h.render)
+}
+
+func (h Markdown) render(w http.ResponseWriter, r *http.Request) {
+	var in model.MarkdownInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if err := in.Validate(); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, model.RenderedMarkdown{HTML: markdown.Render(in.Text)})
+}
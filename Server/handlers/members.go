@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"starttech-server/model"
+	"starttech-server/service"
+	"starttech-server/storage"
+)
+
+func (h *Projects) listMembers(w http.ResponseWriter, r *http.Request) {
+	members, err := h.Service.Members(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, members)
+}
+
+func (h *Projects) addMember(w http.ResponseWriter, r *http.Request) {
+	var in model.MemberInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	m, err := h.Service.AddMember(r.Context(), currentUser(r), r.PathValue("id"), in)
+	if errors.Is(err, storage.ErrConflict) {
+		writeError(w, http.StatusConflict, "the user is already a member")
+		return
+	}
+	if err != nil {
+		writeMemberError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, m)
+}
+
+func (h *Projects) patchMember(w http.ResponseWriter, r *http.Request) {
+	var p model.MemberPatch
+	if err := decodeJSON(r, &p); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	m, err := h.Service.UpdateMember(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("user_id"), p)
+	if err != nil {
+		writeMemberError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+func (h *Projects) removeMember(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.RemoveMember(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("user_id")); err != nil {
+		writeMemberError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeMemberError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, service.ErrLastOwner) {
+		writeError(w, http.StatusConflict, "a project must keep at least one owner")
+		return
+	}
+	writeServiceError(w, r, err)
+}
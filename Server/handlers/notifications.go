@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+)
+
+// Notifications serves the caller's email preferences and inbox. Routes
+// must be mounted behind the auth middleware.
+type Notifications struct {
+	Service *service.Notifications
+}
+
+// Register mounts the notification routes on mux.
+func (h *Notifications) Register(mux router.Routes) {
+	mux.HandleFunc("GET /me/notifications", h.get)
+	mux.HandleFunc("PATCH /me/notifications", h.patch)
+	mux.HandleFunc("GET /notifications", h.inbox)
+	mux.HandleFunc("POST /notifications/read", h.markAllRead)
+	mux.HandleFunc("POST /notifications/{id}/read", h.markRead)
+}
+
+func (h *Notifications) get(w http.ResponseWriter, r *http.Request) {
+	prefs, err := h.Service.Prefs(r.Context(), currentUser(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, prefs)
+}
+
+func (h *Notifications) patch(w http.ResponseWriter, r *http.Request) {
+	var p model.NotificationPrefsPatch
+	if err := decodeJSON(r, &p); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	prefs, err := h.Service.Update(r.Context(), currentUser(r), p)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, prefs)
+}
+
+// inbox reads the query parameters of GET /notifications: limit, cursor and
+// unread=true to leave out what was read.
+func (h *Notifications) inbox(w http.ResponseWriter, r *http.Request) {
+	limit, cursor, err := parsePage(r)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	var unread bool
+	if s := r.URL.Query().Get("unread"); s != "" {
+		if unread, err = strconv.ParseBool(s); err != nil {
+			var v model.ValidationError
+			v.Add("unread", "must be true or false")
+			writeServiceError(w, r, v.Err())
+			return
+		}
+	}
+	page, err := h.Service.Inbox(r.Context(), currentUser(r), unread, limit, cursor)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeList(w, r, page)
+}
+
+func (h *Notifications) markRead(w http.ResponseWriter, r *http.Request) {
+	n, err := h.Service.MarkRead(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, n)
+}
+
+func (h *Notifications) markAllRead(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.MarkAllRead(r.Context(), currentUser(r)); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
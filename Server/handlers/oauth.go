@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"starttech-server/model"
+	"starttech-server/oauth"
+	"starttech-server/storage"
+)
+
+// OAuth configures sign-in through OAuth providers.
+type OAuth struct {
+	Providers map[string]*oauth.Provider
+	// BaseURL is the public URL of the API; providers send users back to
+	// BaseURL + /auth/oauth/{provider}/callback.
+	BaseURL string
+	// SuccessURL is where browsers are sent once signed in. If it is empty,
+	// the callback answers with the session as login does.
+	SuccessURL string
+}
+
+// oauthCookie holds the state and PKCE verifier of a sign-in between its
+// start and the provider sending the browser back.
+const oauthCookie = "oauth_state"
+
+// oauthTimeout bounds how long a user may take at the provider.
+const oauthTimeout = 10 * time.Minute
+
+// provider returns the provider named in the path, writing a 404 if there
+// is none.
+func (h *Auth) provider(w http.ResponseWriter, r *http.Request) (*oauth.Provider, bool) {
+	p, ok := h.OAuth.Providers[r.PathValue("provider")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown sign-in provider")
+	}
+	return p, ok
+}
+
+func (h *Auth) callbackURL(p *oauth.Provider) string {
+	return strings.TrimSuffix(h.OAuth.BaseURL, "/") + "/auth/oauth/" + p.Name + "/callback"
+}
+
+// oauthStart sends the browser to the provider to sign in.
+func (h *Auth) oauthStart(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.provider(w, r)
+	if !ok {
+		return
+	}
+	state, verifier := oauth.NewSecret(), oauth.NewSecret()
+	h.setOAuthCookie(w, state+"."+verifier, oauthTimeout)
+	http.Redirect(w, r, p.AuthCodeURL(h.callbackURL(p), state, verifier), http.StatusFound)
+}
+
+// setOAuthCookie sets the sign-in cookie; a maxAge of 0 clears it.
+func (h *Auth) setOAuthCookie(w http.ResponseWriter, value string, maxAge time.Duration) {
+	c := &http.Cookie{
+		Name:     oauthCookie,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(maxAge / time.Second),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(h.OAuth.BaseURL, "https://"),
+		// Lax, so that the cookie comes along when the provider redirects
+		// the browser back.
+		SameSite: http.SameSiteLaxMode,
+	}
+	if maxAge == 0 {
+		c.MaxAge = -1
+	}
+	http.SetCookie(w, c)
+}
+
+// oauthCallback finishes a sign-in the provider has sent the browser back
+// from, signing in the user linked to the provider account. An account
+// seen for the first time is linked to the user with its email address,
+// and otherwise gets a new user without a password.
+func (h *Auth) oauthCallback(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.provider(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		writeError(w, http.StatusUnauthorized, "sign-in was not completed: "+e)
+		return
+	}
+	var state, verifier string
+	if c, err := r.Cookie(oauthCookie); err == nil {
+		state, verifier, _ = strings.Cut(c.Value, ".")
+	}
+	if state == "" || verifier == "" || subtle.ConstantTimeCompare([]byte(state), []byte(q.Get("state"))) != 1 {
+		writeError(w, http.StatusBadRequest, "sign-in has expired or was started elsewhere; start again")
+		return
+	}
+	h.setOAuthCookie(w, "", 0)
+	code := q.Get("code")
+	if code == "" {
+		writeError(w, http.StatusBadRequest, "code is required")
+		return
+	}
+
+	token, err := p.Exchange(r.Context(), code, h.callbackURL(p), verifier)
+	if err != nil {
+		writeProviderError(w, r, err)
+		return
+	}
+	id, err := p.Identify(r.Context(), token)
+	if errors.Is(err, oauth.ErrNoEmail) {
+		writeError(w, http.StatusForbidden, "the "+p.Name+" account has no verified email address")
+		return
+	}
+	if err != nil {
+		writeProviderError(w, r, err)
+		return
+	}
+
+	u, err := h.oauthUser(r.Context(), p.Name, id)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if h.requireSSO(w, r, u) {
+		return
+	}
+	if h.challengeSecondFactor(w, r, u, h.OAuth.SuccessURL) {
+		return
+	}
+	org, err := h.Orgs.Default(r.Context(), u)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	sess, ok := h.newSession(w, r, u, org)
+	if !ok {
+		return
+	}
+	if h.OAuth.SuccessURL != "" {
+		http.Redirect(w, r, h.OAuth.SuccessURL, http.StatusFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, sess)
+}
+
+// writeProviderError reports a failed call to a provider, which is no
+// fault of the client's.
+func writeProviderError(w http.ResponseWriter, r *http.Request, err error) {
+	slog.ErrorContext(r.Context(), "oauth sign-in failed", "err", err)
+	writeError(w, http.StatusBadGateway, "the sign-in provider could not be reached")
+}
+
+// oauthUser returns the user linked to a provider account, linking or
+// creating one the first time the account is seen.
+func (h *Auth) oauthUser(ctx context.Context, provider string, id oauth.Identity) (model.User, error) {
+	link, err := h.Users.GetIdentity(ctx, provider, id.Subject)
+	if err == nil {
+		return h.Users.GetUser(ctx, link.UserID)
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return model.User{}, err
+	}
+
+	u, err := h.Users.GetUserByEmail(ctx, id.Email)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		u, err = h.Accounts.CreateVerified(ctx, id.Email, id.Login)
+	case err == nil && u.EmailVerifiedAt == nil:
+		// The provider has verified the address, but whoever registered
+		// with it never did, so their password is not trusted.
+		now := time.Now().UTC()
+		u.EmailVerifiedAt, u.PasswordHash = &now, ""
+		err = h.Users.UpdateUser(ctx, u)
+	}
+	if err != nil {
+		return model.User{}, err
+	}
+	return h.linkIdentity(ctx, provider, id, u)
+}
+
+// linkIdentity links a provider account to u and returns u, or the user a
+// concurrent sign-in linked it to first.
+func (h *Auth) linkIdentity(ctx context.Context, provider string, id oauth.Identity, u model.User) (model.User, error) {
+	err := h.Users.CreateIdentity(ctx, model.Identity{
+		Provider:  provider,
+		Subject:   id.Subject,
+		UserID:    u.ID,
+		Email:     id.Email,
+		CreatedAt: time.Now().UTC(),
+	})
+	if errors.Is(err, storage.ErrConflict) {
+		// A concurrent sign-in with the same account linked it first.
+		link, err := h.Users.GetIdentity(ctx, provider, id.Subject)
+		if err != nil {
+			return model.User{}, err
+		}
+		return h.Users.GetUser(ctx, link.UserID)
+	}
+	return u, err
+}
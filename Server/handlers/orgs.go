@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"starttech-server/apierror"
+	"starttech-server/auth"
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+	"starttech-server/storage"
+)
+
+// Orgs serves the /orgs and /invitations endpoints. Routes must be mounted
+// behind the auth middleware.
+type Orgs struct {
+	Service *service.Orgs
+	Audit   *Audit
+}
+
+// Register mounts the organization routes on mux.
+func (h *Orgs) Register(mux router.Routes) {
+	mux.HandleFunc("GET /orgs", h.list)
+	mux.HandleFunc("POST /orgs", h.create)
+	mux.HandleFunc("GET /orgs/{id}", h.get)
+	mux.HandleFunc("PATCH /orgs/{id}", h.patch)
+	mux.HandleFunc("GET /orgs/{id}/members", h.listMembers)
+	mux.HandleFunc("PATCH /orgs/{id}/members/{user_id}", h.patchMember)
+	mux.HandleFunc("DELETE /orgs/{id}/members/{user_id}", h.removeMember)
+	mux.HandleFunc("GET /orgs/{id}/invitations", h.listInvitations)
+	mux.HandleFunc("POST /orgs/{id}/invitations", h.invite)
+	mux.HandleFunc("DELETE /orgs/{id}/invitations/{invitation_id}", h.revoke)
+	mux.HandleFunc("GET /orgs/{id}/domains", h.listDomains)
+	mux.HandleFunc("POST /orgs/{id}/domains", h.addDomain)
+	mux.HandleFunc("POST /orgs/{id}/domains/{domain}/verify", h.verifyDomain)
+	mux.HandleFunc("DELETE /orgs/{id}/domains/{domain}", h.removeDomain)
+	mux.HandleFunc("POST /invitations/accept", h.accept)
+}
+
+// RequireMember rejects tokens for an organization the user has since been
+// removed from, so that removal takes effect before the token expires, and
+// turns away members of an organization that requires two-factor
+// authentication until they have set it up, except from the routes they
+// need to do so. It must run after the auth middleware.
+func (h *Orgs) RequireMember(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		orgID, _ := auth.OrgID(r.Context())
+		err := h.Service.Admit(r.Context(), currentUser(r), orgID)
+		if errors.Is(err, service.ErrTwoFactorSetupRequired) && twoFactorSetupRoutes[r.Pattern] {
+			err = nil
+		}
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			writeErrorCode(w, http.StatusUnauthorized, apierror.CodeNotAMember, "you are no longer a member of this organization; sign in again")
+			return
+		case errors.Is(err, service.ErrTwoFactorSetupRequired):
+			writeErrorCode(w, http.StatusForbidden, apierror.CodeTwoFactorSetupRequired, "this organization requires two-factor authentication; set it up with POST /auth/2fa/setup")
+			return
+		case err != nil:
+			writeServiceError(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// twoFactorSetupRoutes are the routes left open to members who have yet to
+// set up the two-factor authentication their organization requires: those
+// that set it up, and those that sign out or move to another organization.
+var twoFactorSetupRoutes = map[string]bool{
+	"GET /auth/2fa":         true,
+	"POST /auth/2fa/setup":  true,
+	"POST /auth/2fa/enable": true,
+	"GET /me":               true,
+	"GET /users/me":         true,
+	"GET /orgs":             true,
+	"POST /auth/switch":     true,
+	"POST /auth/logout":     true,
+}
+
+func (h *Orgs) list(w http.ResponseWriter, r *http.Request) {
+	orgs, err := h.Service.List(r.Context(), currentUser(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, orgs)
+}
+
+func (h *Orgs) create(w http.ResponseWriter, r *http.Request) {
+	var in model.OrgInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	o, err := h.Service.Create(r.Context(), currentUser(r), in)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, o)
+}
+
+func (h *Orgs) get(w http.ResponseWriter, r *http.Request) {
+	o, err := h.Service.Get(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeOrgError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, o)
+}
+
+func (h *Orgs) patch(w http.ResponseWriter, r *http.Request) {
+	var in model.OrgInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	o, err := h.Service.Update(r.Context(), currentUser(r), r.PathValue("id"), in)
+	if err != nil {
+		writeOrgError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, o)
+}
+
+func (h *Orgs) listMembers(w http.ResponseWriter, r *http.Request) {
+	members, err := h.Service.Members(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeOrgError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, members)
+}
+
+func (h *Orgs) patchMember(w http.ResponseWriter, r *http.Request) {
+	var p model.OrgMemberPatch
+	if err := decodeJSON(r, &p); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	m, err := h.Service.UpdateMember(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("user_id"), p)
+	if err != nil {
+		writeOrgError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+func (h *Orgs) removeMember(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.RemoveMember(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("user_id")); err != nil {
+		writeOrgError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Orgs) listInvitations(w http.ResponseWriter, r *http.Request) {
+	invs, err := h.Service.Invitations(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeOrgError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, invs)
+}
+
+func (h *Orgs) invite(w http.ResponseWriter, r *http.Request) {
+	var in model.InvitationInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	inv, err := h.Service.Invite(r.Context(), currentUser(r), r.PathValue("id"), in)
+	if errors.Is(err, storage.ErrConflict) {
+		writeError(w, http.StatusConflict, "the user is already a member")
+		return
+	}
+	if err != nil {
+		writeOrgError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, inv)
+}
+
+func (h *Orgs) revoke(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.Revoke(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("invitation_id")); err != nil {
+		writeOrgError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Orgs) listDomains(w http.ResponseWriter, r *http.Request) {
+	domains, err := h.Service.Domains(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeOrgError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, domains)
+}
+
+func (h *Orgs) addDomain(w http.ResponseWriter, r *http.Request) {
+	var in model.DomainInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	d, err := h.Service.AddDomain(r.Context(), currentUser(r), r.PathValue("id"), in)
+	if errors.Is(err, storage.ErrConflict) {
+		writeError(w, http.StatusConflict, "the organization already claims that domain")
+		return
+	}
+	if err != nil {
+		writeOrgError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, d)
+}
+
+func (h *Orgs) verifyDomain(w http.ResponseWriter, r *http.Request) {
+	d, err := h.Service.VerifyDomain(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("domain"))
+	if errors.Is(err, storage.ErrConflict) {
+		writeError(w, http.StatusConflict, "another organization has verified that domain")
+		return
+	}
+	if err != nil {
+		writeOrgError(w, r, err)
+		return
+	}
+	h.Audit.record(r, model.AuditEvent{Action: model.AuditDomainVerified, ActorID: currentUser(r), OrgID: d.OrgID, TargetID: d.OrgID, Detail: d.Domain})
+	writeJSON(w, http.StatusOK, d)
+}
+
+func (h *Orgs) removeDomain(w http.ResponseWriter, r *http.Request) {
+	id, domain := r.PathValue("id"), r.PathValue("domain")
+	if err := h.Service.RemoveDomain(r.Context(), currentUser(r), id, domain); err != nil {
+		writeOrgError(w, r, err)
+		return
+	}
+	h.Audit.record(r, model.AuditEvent{Action: model.AuditDomainRemoved, ActorID: currentUser(r), OrgID: id, TargetID: id, Detail: domain})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Orgs) accept(w http.ResponseWriter, r *http.Request) {
+	var in model.AcceptInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	m, err := h.Service.Accept(r.Context(), currentUser(r), in)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		writeError(w, http.StatusNotFound, "no pending invitation has that token")
+	case errors.Is(err, storage.ErrConflict):
+		writeError(w, http.StatusConflict, "you are already a member of this organization")
+	case errors.Is(err, service.ErrInvitationExpired):
+		writeError(w, http.StatusGone, "the invitation has expired")
+	case errors.Is(err, service.ErrWrongInvitee):
+		writeError(w, http.StatusForbidden, "the invitation was sent to another email address")
+	case err != nil:
+		writeServiceError(w, r, err)
+	default:
+		writeJSON(w, http.StatusCreated, m)
+	}
+}
+
+func writeOrgError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrLastOrgOwner):
+		writeError(w, http.StatusConflict, "an organization must keep at least one owner")
+	case errors.Is(err, service.ErrForbidden):
+		writeError(w, http.StatusForbidden, "your role in this organization does not allow that")
+	default:
+		writeServiceError(w, r, err)
+	}
+}
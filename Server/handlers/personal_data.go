@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"time"
+
+	"starttech-server/apierror"
+	"starttech-server/auth"
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+)
+
+// PersonalData serves the endpoints that export a user's data and delete
+// their account. They take a signed-in session, not an API key.
+type PersonalData struct {
+	Service *service.PersonalData
+	// Audit records deletions asked for and called off.
+	Audit *Audit
+}
+
+// Register mounts the personal data routes on mux, which must be behind
+// the auth middleware.
+func (h *PersonalData) Register(mux router.Routes) {
+	mux.HandleFunc("GET /users/me/export", sessionOnly(h.export))
+	mux.HandleFunc("DELETE /users/me", sessionOnly(h.requestDeletion))
+	mux.HandleFunc("POST /users/me/restore", sessionOnly(h.cancelDeletion))
+}
+
+// export answers with the caller's data as a zip archive, or as JSON alone
+// with ?format=json. Once the archive has started, errors can only be
+// logged; the client sees a truncated zip file.
+func (h *PersonalData) export(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "zip" && format != "json" {
+		writeError(w, http.StatusBadRequest, "format must be zip or json")
+		return
+	}
+	d, err := h.Service.Export(r.Context(), currentUser(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if format == "json" {
+		writeJSON(w, http.StatusOK, d)
+		return
+	}
+
+	// Many attachments take longer to send than the write timeout allows.
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	name := "personal-data-" + d.User.ID + "-" + d.ExportedAt.Format("20060102-150405") + ".zip"
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	if err := h.Service.WriteZip(r.Context(), w, d); err != nil {
+		slog.ErrorContext(r.Context(), "writing personal data export", "user_id", d.User.ID, "err", err)
+	}
+}
+
+// requestDeletion schedules the caller's account to be erased, signing
+// out their other sessions.
+func (h *PersonalData) requestDeletion(w http.ResponseWriter, r *http.Request) {
+	var in model.DeleteAccountInput
+	if err := decodeJSON(r, &in); err != nil && !errors.Is(err, io.EOF) {
+		writeDecodeError(w, err)
+		return
+	}
+	sessionID, _ := auth.SessionID(r.Context())
+	d, err := h.Service.RequestDeletion(r.Context(), currentUser(r), in.Password, sessionID)
+	switch {
+	case errors.Is(err, service.ErrWrongPassword):
+		writeErrorCode(w, http.StatusForbidden, apierror.CodeInvalidCredentials, "the password is wrong")
+	case errors.Is(err, service.ErrLastOrgOwner):
+		writeError(w, http.StatusConflict, "hand over the organizations you alone own to another member first")
+	case err != nil:
+		writeServiceError(w, r, err)
+	default:
+		h.Audit.record(r, model.AuditEvent{Action: model.AuditDeletionRequested, TargetID: currentUser(r),
+			Detail: "erasure due " + d.DeleteAt.Format(time.RFC3339)})
+		writeJSON(w, http.StatusAccepted, d)
+	}
+}
+
+// cancelDeletion keeps the caller's account, answering 404 if its
+// deletion was not requested.
+func (h *PersonalData) cancelDeletion(w http.ResponseWriter, r *http.Request) {
+	u, err := h.Service.CancelDeletion(r.Context(), currentUser(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	h.Audit.record(r, model.AuditEvent{Action: model.AuditDeletionCancelled, TargetID: u.ID})
+	writeJSON(w, http.StatusOK, u)
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"net/http"
+
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+)
+
+// Projects serves the /projects endpoints. Routes must be mounted behind the
+// auth middleware.
+type Projects struct {
+	Service *service.Projects
+	Tasks   *service.Tasks
+}
+
+// Register mounts the project routes on mux.
+func (h *Projects) Register(mux router.Routes) {
+	mux.HandleFunc("GET /projects", h.list)
+	mux.HandleFunc("POST /projects", h.create)
+	mux.HandleFunc("GET /projects/{id}", h.get)
+	mux.HandleFunc("PUT /projects/{id}", h.replace)
+	mux.HandleFunc("PATCH /projects/{id}", h.patch)
+	mux.HandleFunc("DELETE /projects/{id}", h.delete)
+	mux.HandleFunc("POST /projects/{id}/archive", h.archive)
+	mux.HandleFunc("POST /projects/{id}/unarchive", h.unarchive)
+	mux.HandleFunc("GET /projects/{id}/tasks", h.listTasks)
+	mux.HandleFunc("PUT /projects/{id}/order", h.reorder)
+	mux.HandleFunc("GET /projects/{id}/export", h.export)
+	mux.HandleFunc("POST /projects/{id}/import", h.importTasks)
+	mux.HandleFunc("GET /projects/{id}/members", h.listMembers)
+	mux.HandleFunc("POST /projects/{id}/members", h.addMember)
+	mux.HandleFunc("PATCH /projects/{id}/members/{user_id}", h.patchMember)
+	mux.HandleFunc("DELETE /projects/{id}/members/{user_id}", h.removeMember)
+	mux.HandleFunc("GET /projects/{id}/fields", h.listFields)
+	mux.HandleFunc("POST /projects/{id}/fields", h.createField)
+	mux.HandleFunc("PATCH /projects/{id}/fields/{field_id}", h.patchField)
+	mux.HandleFunc("DELETE /projects/{id}/fields/{field_id}", h.deleteField)
+	mux.HandleFunc("GET /projects/{id}/rules", h.listRules)
+	mux.HandleFunc("POST /projects/{id}/rules", h.createRule)
+	mux.HandleFunc("GET /projects/{id}/rules/{rule_id}", h.getRule)
+	mux.HandleFunc("PATCH /projects/{id}/rules/{rule_id}", h.patchRule)
+	mux.HandleFunc("DELETE /projects/{id}/rules/{rule_id}", h.deleteRule)
+	mux.HandleFunc("GET /projects/{id}/activity", h.activity)
+	mux.HandleFunc("GET /projects/{id}/stats", h.stats)
+}
+
+func (h *Projects) activity(w http.ResponseWriter, r *http.Request) {
+	limit, cursor, err := parsePage(r)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	page, err := h.Service.Activity(r.Context(), currentUser(r), r.PathValue("id"), limit, cursor)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (h *Projects) stats(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	stats, err := h.Service.Stats(r.Context(), currentUser(r), r.PathValue("id"), q.Get("from"), q.Get("to"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (h *Projects) list(w http.ResponseWriter, r *http.Request) {
+	var (
+		archived *bool
+		v        model.ValidationError
+	)
+	parseArchived(r, &archived, &v)
+	if err := v.Err(); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	projects, err := h.Service.List(r.Context(), currentUser(r), archived != nil && *archived)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, projects)
+}
+
+func (h *Projects) create(w http.ResponseWriter, r *http.Request) {
+	var in model.ProjectInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	p, err := h.Service.Create(r.Context(), currentUser(r), in)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, p)
+}
+
+func (h *Projects) get(w http.ResponseWriter, r *http.Request) {
+	p, err := h.Service.Get(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (h *Projects) replace(w http.ResponseWriter, r *http.Request) {
+	var in model.ProjectInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	h.update(w, r, in.Apply)
+}
+
+func (h *Projects) patch(w http.ResponseWriter, r *http.Request) {
+	var pp model.ProjectPatch
+	if err := decodeJSON(r, &pp); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	h.update(w, r, pp.Apply)
+}
+
+func (h *Projects) update(w http.ResponseWriter, r *http.Request, mutate func(*model.Project)) {
+	p, err := h.Service.Update(r.Context(), currentUser(r), r.PathValue("id"), mutate)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (h *Projects) delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.Delete(r.Context(), currentUser(r), r.PathValue("id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Projects) listTasks(w http.ResponseWriter, r *http.Request) {
+	f, cursor, err := parseTaskFilter(r, h.Tasks.Zone(r.Context(), currentUser(r)))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	page, err := h.Tasks.InProject(r.Context(), currentUser(r), r.PathValue("id"), f, cursor)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeList(w, r, page)
+}
+
+func (h *Projects) reorder(w http.ResponseWriter, r *http.Request) {
+	var order model.TaskOrder
+	if err := decodeJSON(r, &order); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if err := h.Tasks.Reorder(r.Context(), currentUser(r), r.PathValue("id"), order.TaskIDs); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// parseTaskFilter reads the list query parameters of GET /tasks:
+//
+//	limit       page size
+//	cursor      next_cursor from the previous page
+//	offset      rows to skip; ignored when cursor is set
+//	sort        one of storage.TaskSortKeys, or field:<id> for a custom field, prefixed
+//	            with "-" for descending order; urgency puts the most urgent tasks first
+//	status      exact status match
+//	project_id  only tasks in this project
+//	assignee    user ID, "me" for the caller or "none" for unassigned tasks
+//	due_before  RFC 3339 timestamp or YYYY-MM-DD date in loc (exclusive)
+//	due_after   RFC 3339 timestamp or YYYY-MM-DD date in loc (exclusive)
+//	tag         tag ID; repeat or comma-separate to require several tags
+//	archived    true for only archived tasks, false for only the others
+//	field.<id>  only tasks whose custom field <id> holds this value
+func parseTaskFilter(r *http.Request, loc *time.Location) (storage.TaskFilter, string, error) {
+	q := r.URL.Query()
+	var (
+		f storage.TaskFilter
+		v model.ValidationError
+	)
+
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			v.Add("limit", "must be a positive integer")
+		}
+		f.Limit = n
+	}
+	if s := q.Get("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			v.Add("offset", "must be a non-negative integer")
+		}
+		f.Offset = n
+	}
+	if s := q.Get("sort"); s != "" {
+		f.Sort.Desc = strings.HasPrefix(s, "-")
+		f.Sort.Field = strings.TrimPrefix(s, "-")
+		if id, ok := strings.CutPrefix(f.Sort.Field, "field:"); ok {
+			f.Sort.Field, f.Sort.CustomField = "", id
+		} else if !slices.Contains(storage.TaskSortKeys, f.Sort.Field) {
+			v.Add("sort", "must be one of "+strings.Join(storage.TaskSortKeys, ", ")+" or field:<id>")
+		}
+	}
+	if s := q.Get("status"); s != "" {
+		f.Status = model.Status(s)
+		if !model.ValidStatusKey(f.Status) {
+			v.Add("status", "is not a valid status key")
+		}
+	}
+	f.ProjectID = q.Get("project_id")
+	f.AssigneeID = q.Get("assignee")
+	f.DueBefore = parseTimeParam(q.Get("due_before"), "due_before", loc, &v)
+	f.DueAfter = parseTimeParam(q.Get("due_after"), "due_after", loc, &v)
+	for _, s := range q["tag"] {
+		for _, id := range strings.Split(s, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				f.TagIDs = append(f.TagIDs, id)
+			}
+		}
+	}
+	parseArchived(r, &f.Archived, &v)
+	for key, values := range q {
+		if id, ok := strings.CutPrefix(key, "field."); ok && id != "" {
+			if f.Fields == nil {
+				f.Fields = map[string]any{}
+			}
+			f.Fields[id] = values[0]
+		}
+	}
+
+	return f, q.Get("cursor"), v.Err()
+}
+
+// parseTimeParam accepts an RFC 3339 timestamp or a bare date, which is read
+// as midnight in loc.
+func parseTimeParam(s, field string, loc *time.Location, v *model.ValidationError) *time.Time {
+	if s == "" {
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			t = t.UTC()
+			return &t
+		}
+	}
+	v.Add(field, "must be an RFC 3339 timestamp or YYYY-MM-DD date")
+	return nil
+}
+
+// parsePage reads the limit and cursor parameters of feeds that only page.
+func parsePage(r *http.Request) (int, string, error) {
+	q := r.URL.Query()
+	var limit int
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			var v model.ValidationError
+			v.Add("limit", "must be a positive integer")
+			return 0, "", v.Err()
+		}
+		limit = n
+	}
+	return limit, q.Get("cursor"), nil
+}
@@ -0,0 +1,151 @@
+// Package handlers implements the HTTP endpoints of the API.
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"starttech-server/apierror"
+	"starttech-server/i18n"
+	"starttech-server/model"
+	"starttech-server/service"
+	"starttech-server/storage"
+)
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("encoding response", "err", err)
+	}
+}
+
+// writeError answers with an error whose code follows from status.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	apierror.Write(w, status, msg)
+}
+
+// writeErrorCode answers with an error of a more specific code than status
+// implies.
+func writeErrorCode(w http.ResponseWriter, status int, code, msg string) {
+	apierror.WriteError(w, status, apierror.Error{Code: code, Message: msg})
+}
+
+// writeServiceError maps an error returned by a service or store onto an
+// HTTP response.
+func writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	status, e := apiError(err)
+	if status == http.StatusInternalServerError {
+		writeInternalError(w, r, "request failed", err)
+		return
+	}
+	apierror.WriteError(w, status, e)
+}
+
+// apiError is serviceError as the error object of a response, with the
+// invalid fields of a validation error as its details.
+func apiError(err error) (int, apierror.Error) {
+	status, msg, fields := serviceError(err)
+	e := apierror.Error{Code: apierror.CodeFor(status), Message: msg}
+	var quota *service.QuotaError
+	switch {
+	case fields != nil:
+		e.Code, e.Details = apierror.CodeValidation, fields
+	case errors.As(err, &quota):
+		e.Code, e.Details = apierror.CodeQuotaExceeded, map[string]any{"quota": quota.Quota, "limit": quota.Limit}
+	}
+	return status, e
+}
+
+// localError is apiError in the language r asks for, for errors reported
+// within a response body rather than as the response.
+func localError(r *http.Request, err error) (int, apierror.Error) {
+	status, e := apiError(err)
+	return status, apierror.Localize(e, i18n.Language(r.Context()))
+}
+
+// serviceError returns the status and message that report err to clients,
+// and the invalid fields of a validation error. Unexpected errors become a
+// bare 500.
+func serviceError(err error) (status int, msg string, fields model.FieldErrors) {
+	var (
+		verr  *model.ValidationError
+		quota *service.QuotaError
+	)
+	switch {
+	case errors.As(err, &verr):
+		return http.StatusBadRequest, "validation failed", verr.Fields
+	case errors.As(err, &quota):
+		return quotaStatus(quota), quotaMessage(quota), nil
+	case errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound, "not found", nil
+	case errors.Is(err, storage.ErrConflict), errors.Is(err, storage.ErrStale):
+		return http.StatusConflict, "conflict", nil
+	case errors.Is(err, service.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed, "the task has changed since you read it", nil
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden, "your role in this project does not allow that", nil
+	case errors.Is(err, service.ErrNoTimer):
+		return http.StatusConflict, "no timer of yours is running on this task", nil
+	case errors.Is(err, service.ErrDependencyCycle):
+		return http.StatusConflict, "the task would end up waiting on itself", nil
+	case errors.Is(err, service.ErrBlocked):
+		return http.StatusConflict, "tasks blocking this one are still open", nil
+	case errors.Is(err, service.ErrNotAdjacent):
+		return http.StatusUnprocessableEntity, "after_id and before_id must name tasks next to each other", nil
+	case errors.Is(err, service.ErrUndoConflict):
+		return http.StatusConflict, "a task has changed since; the change can no longer be undone", nil
+	case errors.Is(err, service.ErrNotApplied):
+		return http.StatusFailedDependency, "not applied because another operation failed", nil
+	}
+	return http.StatusInternalServerError, "internal error", nil
+}
+
+// quotaStatus is 429 for the daily request quota, which frees up by itself,
+// and 402 for the others, which only a higher limit or deleting things
+// frees up.
+func quotaStatus(e *service.QuotaError) int {
+	if e.Quota == service.QuotaRequestsPerDay {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusPaymentRequired
+}
+
+func quotaMessage(e *service.QuotaError) string {
+	switch e.Quota {
+	case service.QuotaTasksPerProject:
+		return fmt.Sprintf("the project already holds its limit of %d tasks, counting those in the trash", e.Limit)
+	case service.QuotaAttachmentBytes:
+		return fmt.Sprintf("the organization's attachments would take more than its limit of %d bytes", e.Limit)
+	case service.QuotaRequestsPerDay:
+		return fmt.Sprintf("the organization has made its limit of %d requests today", e.Limit)
+	}
+	return "quota exceeded"
+}
+
+// writeInternalError logs err against the request and answers with a
+// generic 500 so internals are not exposed to clients.
+func writeInternalError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	slog.ErrorContext(r.Context(), msg, "err", err)
+	writeError(w, http.StatusInternalServerError, "internal error")
+}
+
+// writeDecodeError reports a body decodeJSON failed on: too big for the
+// limit on request bodies, or not the JSON expected.
+func writeDecodeError(w http.ResponseWriter, err error) {
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request bodies may be at most %d bytes", tooBig.Limit))
+		return
+	}
+	writeErrorCode(w, http.StatusBadRequest, apierror.CodeInvalidJSON, "invalid JSON body")
+}
+
+func decodeJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
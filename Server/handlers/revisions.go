@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"starttech-server/model"
+)
+
+func (h *Tasks) revisions(w http.ResponseWriter, r *http.Request) {
+	limit, cursor, err := parsePage(r)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	page, err := h.Service.Revisions(r.Context(), currentUser(r), r.PathValue("id"), limit, cursor)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeList(w, r, page)
+}
+
+// revert honours If-Match like update.
+func (h *Tasks) revert(w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.ParseInt(r.PathValue("revision"), 10, 64)
+	if err != nil || version < 1 {
+		var v model.ValidationError
+		v.Add("revision", "must be a version number")
+		writeServiceError(w, r, v.Err())
+		return
+	}
+	t, err := h.Service.Revert(r.Context(), currentUser(r), r.PathValue("id"), version, ifMatch(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeTask(w, http.StatusOK, t)
+}
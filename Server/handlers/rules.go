@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+
+	"starttech-server/model"
+)
+
+func (h *Projects) listRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.Service.Rules(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rules)
+}
+
+func (h *Projects) getRule(w http.ResponseWriter, r *http.Request) {
+	rule, err := h.Service.Rule(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("rule_id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rule)
+}
+
+func (h *Projects) createRule(w http.ResponseWriter, r *http.Request) {
+	var in model.RuleInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	rule, err := h.Service.CreateRule(r.Context(), currentUser(r), r.PathValue("id"), in)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, rule)
+}
+
+func (h *Projects) patchRule(w http.ResponseWriter, r *http.Request) {
+	var p model.RulePatch
+	if err := decodeJSON(r, &p); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	rule, err := h.Service.UpdateRule(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("rule_id"), p)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rule)
+}
+
+func (h *Projects) deleteRule(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.DeleteRule(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("rule_id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,364 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"starttech-server/auth"
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+)
+
+// SCIM serves the SCIM 2.0 routes under /scim/v2 that identity providers
+// provision users and groups through (RFC 7644). They authenticate with an
+// API key of the scim scope, not the auth middleware, and answer in SCIM's
+// own format rather than the API's, errors included.
+type SCIM struct {
+	Service *service.SCIM
+	// Keys looks up the API key a request bears.
+	Keys func(ctx context.Context, key string) (auth.APIKey, error)
+}
+
+// scimMaxCount is the most resources a list returns, whatever count asks.
+const scimMaxCount = 200
+
+// Register mounts the SCIM routes on mux, which must be behind
+// Authenticate.
+func (h *SCIM) Register(mux router.Routes) {
+	mux.HandleFunc("GET /scim/v2/ServiceProviderConfig", h.serviceProviderConfig)
+	mux.HandleFunc("GET /scim/v2/ResourceTypes", h.resourceTypes)
+	mux.HandleFunc("GET /scim/v2/Users", h.listUsers)
+	mux.HandleFunc("POST /scim/v2/Users", h.createUser)
+	mux.HandleFunc("GET /scim/v2/Users/{id}", h.getUser)
+	mux.HandleFunc("PUT /scim/v2/Users/{id}", h.replaceUser)
+	mux.HandleFunc("PATCH /scim/v2/Users/{id}", h.patchUser)
+	mux.HandleFunc("DELETE /scim/v2/Users/{id}", h.deleteUser)
+	mux.HandleFunc("GET /scim/v2/Groups", h.listGroups)
+	mux.HandleFunc("POST /scim/v2/Groups", h.createGroup)
+	mux.HandleFunc("GET /scim/v2/Groups/{id}", h.getGroup)
+	mux.HandleFunc("PUT /scim/v2/Groups/{id}", h.replaceGroup)
+	mux.HandleFunc("PATCH /scim/v2/Groups/{id}", h.patchGroup)
+	mux.HandleFunc("DELETE /scim/v2/Groups/{id}", h.deleteGroup)
+}
+
+// Authenticate refuses requests without a bearer API key of the scim
+// scope, and records the key, its owner and its organization in the
+// request context as the auth middleware does.
+func (h *SCIM) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !strings.HasPrefix(token, auth.KeyPrefix) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+			writeSCIMError(w, http.StatusUnauthorized, "", "a SCIM API key is required")
+			return
+		}
+		key, err := h.Keys(r.Context(), strings.TrimSpace(token))
+		switch {
+		case errors.Is(err, auth.ErrInvalidToken):
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+			writeSCIMError(w, http.StatusUnauthorized, "", "invalid API key")
+			return
+		case err != nil:
+			slog.ErrorContext(r.Context(), "looking up API key", "err", err)
+			writeSCIMError(w, http.StatusInternalServerError, "", "internal error")
+			return
+		case !key.SCIM:
+			writeSCIMError(w, http.StatusForbidden, "", "only API keys of the scim scope work with the SCIM routes")
+			return
+		}
+		ctx := auth.WithAPIKeyID(auth.WithOrgID(auth.WithUserID(r.Context(), key.UserID), key.OrgID), key.ID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *SCIM) listUsers(w http.ResponseWriter, r *http.Request) {
+	start, count, ok := scimPaging(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.Service.ListUsers(r.Context(), currentUser(r), r.URL.Query().Get("filter"), start, count)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	for i := range list.Resources {
+		list.Resources[i].Meta.Location = scimLocation(r, "Users", list.Resources[i].ID)
+	}
+	writeSCIM(w, http.StatusOK, list)
+}
+
+func (h *SCIM) getUser(w http.ResponseWriter, r *http.Request) {
+	u, err := h.Service.User(r.Context(), currentUser(r), r.PathValue("id"))
+	h.writeUser(w, r, http.StatusOK, u, err)
+}
+
+func (h *SCIM) createUser(w http.ResponseWriter, r *http.Request) {
+	var in model.SCIMUser
+	if !decodeSCIM(w, r, &in) {
+		return
+	}
+	u, err := h.Service.CreateUser(r.Context(), currentUser(r), in)
+	h.writeUser(w, r, http.StatusCreated, u, err)
+}
+
+func (h *SCIM) replaceUser(w http.ResponseWriter, r *http.Request) {
+	var in model.SCIMUser
+	if !decodeSCIM(w, r, &in) {
+		return
+	}
+	u, err := h.Service.ReplaceUser(r.Context(), currentUser(r), r.PathValue("id"), in)
+	h.writeUser(w, r, http.StatusOK, u, err)
+}
+
+func (h *SCIM) patchUser(w http.ResponseWriter, r *http.Request) {
+	var in model.SCIMPatch
+	if !decodeSCIM(w, r, &in) {
+		return
+	}
+	u, err := h.Service.PatchUser(r.Context(), currentUser(r), r.PathValue("id"), in)
+	h.writeUser(w, r, http.StatusOK, u, err)
+}
+
+func (h *SCIM) deleteUser(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.DeleteUser(r.Context(), currentUser(r), r.PathValue("id")); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SCIM) writeUser(w http.ResponseWriter, r *http.Request, status int, u model.SCIMUser, err error) {
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	u.Meta.Location = scimLocation(r, "Users", u.ID)
+	if status == http.StatusCreated {
+		w.Header().Set("Location", u.Meta.Location)
+	}
+	writeSCIM(w, status, u)
+}
+
+func (h *SCIM) listGroups(w http.ResponseWriter, r *http.Request) {
+	start, count, ok := scimPaging(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.Service.ListGroups(r.Context(), currentUser(r), r.URL.Query().Get("filter"), start, count)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	for i := range list.Resources {
+		list.Resources[i].Meta.Location = scimLocation(r, "Groups", list.Resources[i].ID)
+	}
+	writeSCIM(w, http.StatusOK, list)
+}
+
+func (h *SCIM) getGroup(w http.ResponseWriter, r *http.Request) {
+	g, err := h.Service.Group(r.Context(), currentUser(r), r.PathValue("id"))
+	h.writeGroup(w, r, http.StatusOK, g, err)
+}
+
+func (h *SCIM) createGroup(w http.ResponseWriter, r *http.Request) {
+	var in model.SCIMGroup
+	if !decodeSCIM(w, r, &in) {
+		return
+	}
+	g, err := h.Service.CreateGroup(r.Context(), currentUser(r), in)
+	h.writeGroup(w, r, http.StatusCreated, g, err)
+}
+
+func (h *SCIM) replaceGroup(w http.ResponseWriter, r *http.Request) {
+	var in model.SCIMGroup
+	if !decodeSCIM(w, r, &in) {
+		return
+	}
+	g, err := h.Service.ReplaceGroup(r.Context(), currentUser(r), r.PathValue("id"), in)
+	h.writeGroup(w, r, http.StatusOK, g, err)
+}
+
+func (h *SCIM) patchGroup(w http.ResponseWriter, r *http.Request) {
+	var in model.SCIMPatch
+	if !decodeSCIM(w, r, &in) {
+		return
+	}
+	g, err := h.Service.PatchGroup(r.Context(), currentUser(r), r.PathValue("id"), in)
+	h.writeGroup(w, r, http.StatusOK, g, err)
+}
+
+func (h *SCIM) deleteGroup(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.DeleteGroup(r.Context(), currentUser(r), r.PathValue("id")); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SCIM) writeGroup(w http.ResponseWriter, r *http.Request, status int, g model.SCIMGroup, err error) {
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	g.Meta.Location = scimLocation(r, "Groups", g.ID)
+	if status == http.StatusCreated {
+		w.Header().Set("Location", g.Meta.Location)
+	}
+	writeSCIM(w, status, g)
+}
+
+// serviceProviderConfig tells providers which parts of SCIM are supported:
+// PATCH and simple eq filters, but not bulk operations, sorting, ETags or
+// changing passwords.
+func (h *SCIM) serviceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	unsupported := map[string]bool{"supported": false}
+	writeSCIM(w, http.StatusOK, map[string]any{
+		"schemas":        []string{model.SCIMConfigSchema},
+		"patch":          map[string]bool{"supported": true},
+		"bulk":           map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]any{"supported": true, "maxResults": scimMaxCount},
+		"changePassword": unsupported,
+		"sort":           unsupported,
+		"etag":           unsupported,
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "API key",
+			"description": "An API key of the scim scope, as a bearer token",
+			"primary":     true,
+		}},
+		"meta": map[string]string{"resourceType": "ServiceProviderConfig", "location": scimLocation(r, "ServiceProviderConfig", "")},
+	})
+}
+
+func (h *SCIM) resourceTypes(w http.ResponseWriter, r *http.Request) {
+	types := []map[string]any{
+		{
+			"schemas":  []string{model.SCIMResourceSchema},
+			"id":       "User",
+			"name":     "User",
+			"endpoint": "/Users",
+			"schema":   model.SCIMUserSchema,
+			"meta":     map[string]string{"resourceType": "ResourceType", "location": scimLocation(r, "ResourceTypes", "User")},
+		},
+		{
+			"schemas":  []string{model.SCIMResourceSchema},
+			"id":       "Group",
+			"name":     "Group",
+			"endpoint": "/Groups",
+			"schema":   model.SCIMGroupSchema,
+			"meta":     map[string]string{"resourceType": "ResourceType", "location": scimLocation(r, "ResourceTypes", "Group")},
+		},
+	}
+	writeSCIM(w, http.StatusOK, model.SCIMList[map[string]any]{
+		Schemas:      []string{model.SCIMListSchema},
+		TotalResults: len(types),
+		StartIndex:   1,
+		ItemsPerPage: len(types),
+		Resources:    types,
+	})
+}
+
+// writeError reports err as a SCIM error, with the scimType that tells
+// providers what to do about it where there is one.
+func (h *SCIM) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, service.ErrSCIMFilter) {
+		writeSCIMError(w, http.StatusBadRequest, "invalidFilter", `only filters of the form attribute eq "value" are supported`)
+		return
+	}
+	status, msg, fields := serviceError(err)
+	scimType := ""
+	switch status {
+	case http.StatusInternalServerError:
+		slog.ErrorContext(r.Context(), "SCIM request failed", "err", err)
+	case http.StatusConflict:
+		scimType, msg = "uniqueness", "the resource exists already"
+	case http.StatusForbidden:
+		msg = "the API key's owner must be an admin or owner of the organization"
+	case http.StatusBadRequest:
+		scimType = "invalidValue"
+		parts := make([]string, len(fields))
+		for i, f := range fields {
+			parts[i] = f.Field + " " + f.Message
+		}
+		msg = strings.Join(parts, "; ")
+	}
+	writeSCIMError(w, status, scimType, msg)
+}
+
+// scimPaging reads startIndex, which counts from 1, and count. If either is
+// invalid it writes the error and returns false.
+func scimPaging(w http.ResponseWriter, r *http.Request) (start, count int, ok bool) {
+	start, count = 1, scimMaxCount
+	q := r.URL.Query()
+	if s := q.Get("startIndex"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			writeSCIMError(w, http.StatusBadRequest, "invalidValue", "startIndex must be an integer")
+			return 0, 0, false
+		}
+		start = max(n, 1)
+	}
+	if s := q.Get("count"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			writeSCIMError(w, http.StatusBadRequest, "invalidValue", "count must be an integer")
+			return 0, 0, false
+		}
+		count = min(max(n, 0), scimMaxCount)
+	}
+	return start, count, true
+}
+
+// scimLocation returns the URL of the resource of the given type and id,
+// under the same prefix as the request's.
+func scimLocation(r *http.Request, resourceType, id string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	prefix, _, _ := strings.Cut(r.URL.Path, "/scim/v2")
+	loc := scheme + "://" + r.Host + prefix + "/scim/v2/" + resourceType
+	if id != "" {
+		loc += "/" + id
+	}
+	return loc
+}
+
+// decodeSCIM decodes the body into v. Unlike decodeJSON it allows unknown
+// fields, since providers send attributes, such as name, that are not
+// kept. If decoding fails it writes the error and returns false.
+func decodeSCIM(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			writeSCIMError(w, http.StatusRequestEntityTooLarge, "", "the body is too large")
+			return false
+		}
+		writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", "invalid JSON body")
+		return false
+	}
+	return true
+}
+
+func writeSCIM(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("encoding SCIM response", "err", err)
+	}
+}
+
+func writeSCIMError(w http.ResponseWriter, status int, scimType, detail string) {
+	writeSCIM(w, status, model.SCIMError{
+		Schemas:  []string{model.SCIMErrorSchema},
+		Status:   strconv.Itoa(status),
+		SCIMType: scimType,
+		Detail:   detail,
+	})
+}
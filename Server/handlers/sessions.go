@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"starttech-server/apierror"
+	"starttech-server/auth"
+	"starttech-server/i18n"
+	"starttech-server/model"
+	"starttech-server/service"
+	"starttech-server/storage"
+)
+
+// refreshCookie carries the refresh token for browser clients.
+const refreshCookie = "refresh_token"
+
+// RequireSession rejects requests whose token was issued to a session that
+// has since been revoked or has expired. It must run behind the auth
+// middleware. Requests made with an API key have no session and pass; those
+// of an impersonating administrator depend on the administrator's session.
+func (h *Auth) RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := auth.APIKeyID(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		owner := currentUser(r)
+		if actor, ok := auth.ActorID(r.Context()); ok {
+			owner = actor
+		}
+		sessionID, _ := auth.SessionID(r.Context())
+		_, err := h.Sessions.Active(r.Context(), owner, sessionID)
+		if errors.Is(err, storage.ErrNotFound) {
+			writeErrorCode(w, http.StatusUnauthorized, apierror.CodeSessionEnded, "your session has ended; sign in again")
+			return
+		}
+		if err != nil {
+			writeServiceError(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// PreferLanguage answers requests in the language the signed-in user has
+// chosen, in place of the one their Accept-Language header asks for. It
+// must run behind the auth middleware. An impersonating administrator is
+// answered in their own language.
+func (h *Auth) PreferLanguage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reader := currentUser(r)
+		if actor, ok := auth.ActorID(r.Context()); ok {
+			reader = actor
+		}
+		if u, err := h.Users.GetUser(r.Context(), reader); err == nil && u.Language != "" {
+			r = i18n.Use(w, r, u.Language)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sessionOnly refuses requests made with an API key, for routes that only
+// make sense for, or should only be open to, a signed-in user.
+func sessionOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := auth.APIKeyID(r.Context()); ok {
+			writeError(w, http.StatusForbidden, "API keys cannot be used here; sign in instead")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// refresh renews a session, answering with a new access token and the
+// refresh token that replaces the one sent. The refresh token is read from
+// the body, falling back to the refresh cookie.
+func (h *Auth) refresh(w http.ResponseWriter, r *http.Request) {
+	var in model.RefreshInput
+	if err := decodeJSON(r, &in); err != nil && !errors.Is(err, io.EOF) {
+		writeDecodeError(w, err)
+		return
+	}
+	if in.RefreshToken == "" {
+		if c, err := r.Cookie(refreshCookie); err == nil {
+			in.RefreshToken = c.Value
+		}
+	}
+	if in.RefreshToken == "" {
+		writeError(w, http.StatusUnauthorized, "a refresh token is required")
+		return
+	}
+	sess, next, err := h.Sessions.Refresh(r.Context(), in.RefreshToken, r.UserAgent())
+	if errors.Is(err, storage.ErrNotFound) {
+		h.setRefreshCookie(w, r, "", 0)
+		writeErrorCode(w, http.StatusUnauthorized, apierror.CodeSessionEnded, "invalid or expired refresh token; sign in again")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	u, err := h.Users.GetUser(r.Context(), sess.UserID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if u.DisabledAt != nil {
+		writeAccountError(w, r, service.ErrAccountDisabled)
+		return
+	}
+	org, err := h.Orgs.Get(r.Context(), u.ID, sess.OrgID)
+	if errors.Is(err, storage.ErrNotFound) {
+		// The user has left the session's organization since signing in.
+		org, err = h.Orgs.Default(r.Context(), u)
+		if err == nil {
+			err = h.Sessions.Switch(r.Context(), u.ID, sess.ID, org.ID)
+		}
+	}
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if out, ok := h.issueTokens(w, r, u, org, sess.ID, next); ok {
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+// logout ends the caller's session and clears its cookies.
+func (h *Auth) logout(w http.ResponseWriter, r *http.Request) {
+	sessionID, _ := auth.SessionID(r.Context())
+	if err := h.Sessions.Revoke(r.Context(), currentUser(r), sessionID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		writeServiceError(w, r, err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: auth.CookieName, Path: "/", MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteLaxMode})
+	h.setRefreshCookie(w, r, "", 0)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Auth) listSessions(w http.ResponseWriter, r *http.Request) {
+	sessionID, _ := auth.SessionID(r.Context())
+	sessions, err := h.Sessions.List(r.Context(), currentUser(r), sessionID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+func (h *Auth) revokeSession(w http.ResponseWriter, r *http.Request) {
+	if err := h.Sessions.Revoke(r.Context(), currentUser(r), r.PathValue("id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeSessions signs the caller out everywhere, or, with others=true,
+// everywhere but the current session.
+func (h *Auth) revokeSessions(w http.ResponseWriter, r *http.Request) {
+	keep := ""
+	if s := r.URL.Query().Get("others"); s != "" {
+		others, err := strconv.ParseBool(s)
+		if err != nil {
+			var v model.ValidationError
+			v.Add("others", "must be true or false")
+			writeServiceError(w, r, v.Err())
+			return
+		}
+		if others {
+			keep, _ = auth.SessionID(r.Context())
+		}
+	}
+	if _, err := h.Sessions.RevokeAll(r.Context(), currentUser(r), keep); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setRefreshCookie sets the refresh cookie, scoped to the auth routes so
+// that it is not sent with every request; a maxAge of 0 clears it.
+func (h *Auth) setRefreshCookie(w http.ResponseWriter, r *http.Request, token string, maxAge time.Duration) {
+	c := &http.Cookie{
+		Name:     refreshCookie,
+		Value:    token,
+		Path:     authPath(r),
+		MaxAge:   int(maxAge / time.Second),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if maxAge == 0 {
+		c.MaxAge = -1
+	}
+	http.SetCookie(w, c)
+}
+
+// authPath is the path of the auth routes as the client sees them, which
+// includes the API version's prefix when the request came through one.
+func authPath(r *http.Request) string {
+	p, _, _ := strings.Cut(r.RequestURI, "?")
+	if i := strings.Index(p, "/auth/"); i >= 0 {
+		return p[:i] + "/auth"
+	}
+	return "/"
+}
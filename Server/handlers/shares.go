@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+)
+
+// Shares serves share links. Routes from Register must be mounted behind
+// the auth middleware, the view from RegisterPublic must not: anybody with
+// a link may open it.
+type Shares struct {
+	Service *service.Shares
+}
+
+// Register mounts the routes that manage share links on mux.
+func (h *Shares) Register(mux router.Routes) {
+	mux.HandleFunc("POST /projects/{id}/share", h.shareProject)
+	mux.HandleFunc("GET /projects/{id}/shares", h.listProject)
+	mux.HandleFunc("POST /tasks/{id}/share", h.shareTask)
+	mux.HandleFunc("GET /tasks/{id}/shares", h.listTask)
+	mux.HandleFunc("DELETE /shares/{id}", h.revoke)
+}
+
+// RegisterPublic mounts the route share URLs point to.
+func (h *Shares) RegisterPublic(mux router.Routes) {
+	mux.HandleFunc("GET /shared/{token}", h.view)
+}
+
+func (h *Shares) shareProject(w http.ResponseWriter, r *http.Request) {
+	var in model.ShareInput
+	if err := decodeJSON(r, &in); err != nil && !errors.Is(err, io.EOF) {
+		writeDecodeError(w, err)
+		return
+	}
+	l, err := h.Service.ShareProject(r.Context(), currentUser(r), r.PathValue("id"), in)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, l)
+}
+
+func (h *Shares) shareTask(w http.ResponseWriter, r *http.Request) {
+	var in model.ShareInput
+	if err := decodeJSON(r, &in); err != nil && !errors.Is(err, io.EOF) {
+		writeDecodeError(w, err)
+		return
+	}
+	l, err := h.Service.ShareTask(r.Context(), currentUser(r), r.PathValue("id"), in)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, l)
+}
+
+func (h *Shares) listProject(w http.ResponseWriter, r *http.Request) {
+	links, err := h.Service.ProjectLinks(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, links)
+}
+
+func (h *Shares) listTask(w http.ResponseWriter, r *http.Request) {
+	links, err := h.Service.TaskLinks(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, links)
+}
+
+func (h *Shares) revoke(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.Revoke(r.Context(), currentUser(r), r.PathValue("id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// view answers /shared/{token}. Revoking a link must take effect at once,
+// so the view is not cached, and search engines are asked not to index it.
+func (h *Shares) view(w http.ResponseWriter, r *http.Request) {
+	v, err := h.Service.View(r.Context(), r.PathValue("token"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	writeJSON(w, http.StatusOK, v)
+}
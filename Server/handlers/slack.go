@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"starttech-server/integrations"
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+)
+
+// Slack serves the endpoints that link projects to Slack channels. The
+// routes from Register must be mounted behind the auth middleware, the
+// slash command from RegisterPublic must not: Slack signs its requests
+// instead.
+type Slack struct {
+	Service *service.Slack
+}
+
+// Register mounts the routes that manage a project's link on mux.
+func (h *Slack) Register(mux router.Routes) {
+	mux.HandleFunc("GET /projects/{id}/slack", h.get)
+	mux.HandleFunc("PUT /projects/{id}/slack", h.link)
+	mux.HandleFunc("DELETE /projects/{id}/slack", h.unlink)
+}
+
+// RegisterPublic mounts the route link command URLs point to.
+func (h *Slack) RegisterPublic(mux router.Routes) {
+	mux.HandleFunc("POST /integrations/slack/{project_id}/command", h.command)
+}
+
+func (h *Slack) get(w http.ResponseWriter, r *http.Request) {
+	l, err := h.Service.Get(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, l)
+}
+
+func (h *Slack) link(w http.ResponseWriter, r *http.Request) {
+	var in model.SlackLinkInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	l, err := h.Service.Link(r.Context(), currentUser(r), r.PathValue("id"), in)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, l)
+}
+
+func (h *Slack) unlink(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.Unlink(r.Context(), currentUser(r), r.PathValue("id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// command answers 404 for a project without a link and 401 for a request
+// Slack did not sign. Anything the command itself refuses is explained in
+// a 200 reply, since Slack shows other responses as a bare failure.
+func (h *Slack) command(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 64<<10))
+	if err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	reply, err := h.Service.Command(r.Context(), r.PathValue("project_id"),
+		r.Header.Get(integrations.HeaderSlackTimestamp), r.Header.Get(integrations.HeaderSlackSignature), body)
+	switch {
+	case errors.Is(err, service.ErrBadSignature):
+		writeError(w, http.StatusUnauthorized, "the signature does not match")
+	case err != nil:
+		writeServiceError(w, r, err)
+	default:
+		writeJSON(w, http.StatusOK, reply)
+	}
+}
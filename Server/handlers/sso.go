@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"starttech-server/apierror"
+	"starttech-server/model"
+	"starttech-server/oauth"
+	"starttech-server/router"
+	"starttech-server/service"
+	"starttech-server/storage"
+)
+
+// registerSSO mounts the routes that sign people in through their
+// organization's identity provider on mux.
+func (h *Auth) registerSSO(mux router.Routes) {
+	mux.HandleFunc("GET /auth/sso", h.ssoLookup)
+	mux.HandleFunc("GET /auth/sso/start", h.ssoStart)
+	mux.HandleFunc("GET /auth/sso/callback", h.ssoCallback)
+}
+
+// RegisterSSO mounts the routes that manage an organization's single
+// sign-on on mux, which must be behind the auth middleware. They take a
+// session, not an API key. Without SSO set, it mounts nothing.
+func (h *Auth) RegisterSSO(mux router.Routes) {
+	if h.SSO == nil {
+		return
+	}
+	mux.HandleFunc("GET /orgs/{id}/sso", sessionOnly(h.getSSO))
+	mux.HandleFunc("PUT /orgs/{id}/sso", sessionOnly(h.putSSO))
+	mux.HandleFunc("DELETE /orgs/{id}/sso", sessionOnly(h.deleteSSO))
+	mux.HandleFunc("POST /auth/sso/link", sessionOnly(h.ssoLink))
+}
+
+func (h *Auth) getSSO(w http.ResponseWriter, r *http.Request) {
+	c, err := h.SSO.Get(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeOrgError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (h *Auth) putSSO(w http.ResponseWriter, r *http.Request) {
+	var in model.SSOConfigInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	c, err := h.SSO.Save(r.Context(), currentUser(r), r.PathValue("id"), in)
+	if errors.Is(err, storage.ErrConflict) {
+		writeError(w, http.StatusConflict, "another organization signs in the people of that domain")
+		return
+	}
+	if err != nil {
+		writeOrgError(w, r, err)
+		return
+	}
+	h.Audit.record(r, model.AuditEvent{Action: model.AuditSSOUpdated, ActorID: currentUser(r), OrgID: c.OrgID, TargetID: c.OrgID,
+		Detail: c.Domain + " through " + c.Issuer})
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (h *Auth) deleteSSO(w http.ResponseWriter, r *http.Request) {
+	orgID := r.PathValue("id")
+	if err := h.SSO.Delete(r.Context(), currentUser(r), orgID); err != nil {
+		writeOrgError(w, r, err)
+		return
+	}
+	h.Audit.record(r, model.AuditEvent{Action: model.AuditSSODeleted, ActorID: currentUser(r), OrgID: orgID, TargetID: orgID})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ssoLookup tells a sign-in form whether the email address typed into it
+// signs in through an identity provider, before it asks for a password.
+func (h *Auth) ssoLookup(w http.ResponseWriter, r *http.Request) {
+	email := r.URL.Query().Get("email")
+	c, err := h.SSO.Lookup(r.Context(), email)
+	if errors.Is(err, storage.ErrNotFound) {
+		writeJSON(w, http.StatusOK, model.SSOLookup{})
+		return
+	}
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, model.SSOLookup{SSO: true, Enforced: c.Enforced, StartURL: h.ssoStartURL(email, "")})
+}
+
+// ssoLink starts linking the signed-in user's account to the identity
+// provider of the organization that has their email domain. The start URL
+// it returns carries a ticket, so that the identity the sign-in comes back
+// with is linked to this account rather than refused for having the
+// address of an existing one.
+func (h *Auth) ssoLink(w http.ResponseWriter, r *http.Request) {
+	u, err := h.Users.GetUser(r.Context(), currentUser(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	c, err := h.SSO.Lookup(r.Context(), u.Email)
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no organization signs in the people of your email domain")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	ticket, err := h.SSO.LinkTicket(u.ID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, model.SSOLookup{SSO: true, Enforced: c.Enforced, StartURL: h.ssoStartURL(u.Email, ticket)})
+}
+
+// ssoStartURL returns where to send the browser to sign email in through
+// its organization's identity provider, linking the identity to the
+// account a ticket from SSO.LinkTicket names if one is given.
+func (h *Auth) ssoStartURL(email, ticket string) string {
+	q := url.Values{"email": {email}}
+	if ticket != "" {
+		q.Set("link", ticket)
+	}
+	return strings.TrimSuffix(h.OAuth.BaseURL, "/") + "/auth/sso/start?" + q.Encode()
+}
+
+func (h *Auth) ssoCallbackURL() string {
+	return strings.TrimSuffix(h.OAuth.BaseURL, "/") + "/auth/sso/callback"
+}
+
+// ssoStart sends the browser to the identity provider of the organization
+// that has the domain of the email parameter. A link parameter carries a
+// link ticket through to the callback.
+func (h *Auth) ssoStart(w http.ResponseWriter, r *http.Request) {
+	email := strings.TrimSpace(r.URL.Query().Get("email"))
+	ticket := r.URL.Query().Get("link")
+	if ticket != "" {
+		if _, err := h.SSO.OpenLinkTicket(ticket); err != nil {
+			writeError(w, http.StatusBadRequest, "the link has expired; sign in again to link your account")
+			return
+		}
+	}
+	c, err := h.SSO.Lookup(r.Context(), email)
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no organization signs in the people of that email domain")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	p, err := h.SSO.Provider(r.Context(), c)
+	if err != nil {
+		writeProviderError(w, r, err)
+		return
+	}
+	state, verifier := oauth.NewSecret(), oauth.NewSecret()
+	value := state + "." + verifier + "." + c.OrgID
+	if ticket != "" {
+		value += "." + ticket
+	}
+	h.setOAuthCookie(w, value, oauthTimeout)
+	// login_hint saves the user typing their address again at the provider.
+	target := p.AuthCodeURL(h.ssoCallbackURL(), state, verifier) + "&" + url.Values{"login_hint": {email}}.Encode()
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// ssoCallback finishes a sign-in the identity provider has sent the
+// browser back from. The user must have an address in the organization's
+// domain, is found or created by ssoUser, and joins the organization and
+// the projects their groups map to before the session starts there.
+func (h *Auth) ssoCallback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		writeError(w, http.StatusUnauthorized, "sign-in was not completed: "+e)
+		return
+	}
+	var parts []string
+	if c, err := r.Cookie(oauthCookie); err == nil {
+		parts = strings.Split(c.Value, ".")
+	}
+	if len(parts) != 3 && len(parts) != 4 || subtle.ConstantTimeCompare([]byte(parts[0]), []byte(q.Get("state"))) != 1 {
+		writeError(w, http.StatusBadRequest, "sign-in has expired or was started elsewhere; start again")
+		return
+	}
+	verifier, orgID := parts[1], parts[2]
+	h.setOAuthCookie(w, "", 0)
+	var linkUserID string
+	if len(parts) == 4 {
+		var err error
+		if linkUserID, err = h.SSO.OpenLinkTicket(parts[3]); err != nil {
+			writeError(w, http.StatusBadRequest, "the link has expired; sign in again to link your account")
+			return
+		}
+	}
+	code := q.Get("code")
+	if code == "" {
+		writeError(w, http.StatusBadRequest, "code is required")
+		return
+	}
+
+	c, err := h.SSO.ForOrg(r.Context(), orgID)
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(w, http.StatusBadRequest, "the organization has turned single sign-on off")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	p, err := h.SSO.Provider(r.Context(), c)
+	if err != nil {
+		writeProviderError(w, r, err)
+		return
+	}
+	token, err := p.Exchange(r.Context(), code, h.ssoCallbackURL(), verifier)
+	if err != nil {
+		writeProviderError(w, r, err)
+		return
+	}
+	id, err := p.Identify(r.Context(), token)
+	if errors.Is(err, oauth.ErrNoEmail) {
+		writeError(w, http.StatusForbidden, "the identity provider has no verified email address for you")
+		return
+	}
+	if err != nil {
+		writeProviderError(w, r, err)
+		return
+	}
+	if model.EmailDomain(id.Email) != c.Domain {
+		h.Audit.record(r, model.AuditEvent{Action: model.AuditLoginFailed, OrgID: c.OrgID, Detail: "single sign-on of " + id.Email + " outside " + c.Domain})
+		writeError(w, http.StatusForbidden, "only addresses at "+c.Domain+" sign in through this organization's identity provider")
+		return
+	}
+
+	u, err := h.ssoUser(r.Context(), c, id, linkUserID)
+	if errors.Is(err, storage.ErrConflict) {
+		h.Audit.record(r, model.AuditEvent{Action: model.AuditLoginFailed, OrgID: c.OrgID, Detail: "single sign-on of " + id.Email + " matches an unlinked account"})
+		writeError(w, http.StatusConflict, "an account already uses "+id.Email+"; sign in to it another way and link it to your identity provider from there")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if err := h.SSO.Join(r.Context(), c, u, id.Groups); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if h.challengeSecondFactor(w, r, u, h.OAuth.SuccessURL) {
+		return
+	}
+	org, err := h.Orgs.Get(r.Context(), u.ID, c.OrgID)
+	if err != nil {
+		writeOrgError(w, r, err)
+		return
+	}
+	sess, ok := h.newSession(w, r, u, org)
+	if !ok {
+		return
+	}
+	if h.OAuth.SuccessURL != "" {
+		http.Redirect(w, r, h.OAuth.SuccessURL, http.StatusFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, sess)
+}
+
+// ssoUser returns the user linked to id at the identity provider of c. The
+// first sign-in of an identity links it to the account linkUserID, whose
+// holder proved it is theirs by signing in to it another way, or without
+// one creates an account for it. It never links to an existing account by
+// email address alone: the provider's word for an address proves nothing
+// about who registered it here. For an address some account has, and no
+// linkUserID, it returns storage.ErrConflict.
+func (h *Auth) ssoUser(ctx context.Context, c model.SSOConfig, id oauth.Identity, linkUserID string) (model.User, error) {
+	provider := "sso:" + c.OrgID
+	link, err := h.Users.GetIdentity(ctx, provider, id.Subject)
+	if err == nil {
+		return h.Users.GetUser(ctx, link.UserID)
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return model.User{}, err
+	}
+
+	var u model.User
+	if linkUserID != "" {
+		u, err = h.Users.GetUser(ctx, linkUserID)
+	} else {
+		_, err = h.Users.GetUserByEmail(ctx, id.Email)
+		switch {
+		case err == nil:
+			return model.User{}, storage.ErrConflict
+		case errors.Is(err, storage.ErrNotFound):
+			u, err = h.Accounts.CreateVerified(ctx, id.Email, id.Login)
+		}
+	}
+	if err != nil {
+		return model.User{}, err
+	}
+	return h.linkIdentity(ctx, provider, id, u)
+}
+
+// requireSSO stops a sign-in of u other than through their organization's
+// identity provider, if the organization requires that, and returns true.
+// u has just proved who they are, so the start URL it responds with links
+// the identity to their account if it is not yet.
+func (h *Auth) requireSSO(w http.ResponseWriter, r *http.Request, u model.User) bool {
+	if h.SSO == nil {
+		return false
+	}
+	err := h.SSO.Enforced(r.Context(), u)
+	if errors.Is(err, service.ErrSSORequired) {
+		ticket, err := h.SSO.LinkTicket(u.ID)
+		if err != nil {
+			writeServiceError(w, r, err)
+			return true
+		}
+		h.Audit.record(r, model.AuditEvent{Action: model.AuditLoginFailed, ActorID: u.ID, Detail: "single sign-on required"})
+		apierror.WriteError(w, http.StatusForbidden, apierror.Error{
+			Code:    apierror.CodeSSORequired,
+			Message: "your organization requires signing in through its identity provider",
+			Details: map[string]any{"start_url": h.ssoStartURL(u.Email, ticket)},
+		})
+		return true
+	}
+	if err != nil {
+		writeServiceError(w, r, err)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"starttech-server/model"
+)
+
+// changes serves the change feed; ?since takes the cursor of the last page.
+func (h *Tasks) changes(w http.ResponseWriter, r *http.Request) {
+	limit, _, err := parsePage(r)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	page, err := h.Service.Changes(r.Context(), currentUser(r), r.URL.Query().Get("since"), limit)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// push answers 200 however each mutation turned out; the results tell.
+func (h *Tasks) push(w http.ResponseWriter, r *http.Request) {
+	var in model.SyncPushInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	items, err := h.Service.Push(r.Context(), currentUser(r), in)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	res := model.SyncPushResult{Results: make([]model.SyncOutcome, len(items))}
+	for i, item := range items {
+		res.Results[i] = model.SyncOutcome{
+			Resolution: item.Resolution,
+			ClientID:   in.Mutations[i].ClientID,
+			Task:       item.Task,
+			Conflicts:  item.Conflicts,
+		}
+		if item.Err != nil {
+			status, e := localError(r, item.Err)
+			res.Results[i].Error = &e
+			if status == http.StatusInternalServerError {
+				slog.ErrorContext(r.Context(), "sync mutation failed", "index", i, "err", item.Err)
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
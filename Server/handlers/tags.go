@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+	"starttech-server/storage"
+)
+
+// Tags serves the /tags endpoints. Routes must be mounted behind the auth
+// middleware.
+type Tags struct {
+	Service *service.Tags
+}
+
+// Register mounts the tag routes on mux.
+func (h *Tags) Register(mux router.Routes) {
+	mux.HandleFunc("GET /tags", h.list)
+	mux.HandleFunc("POST /tags", h.create)
+	mux.HandleFunc("GET /tags/{id}", h.get)
+	mux.HandleFunc("PATCH /tags/{id}", h.patch)
+	mux.HandleFunc("DELETE /tags/{id}", h.delete)
+}
+
+func (h *Tags) list(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.Service.List(r.Context(), currentUser(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tags)
+}
+
+func (h *Tags) create(w http.ResponseWriter, r *http.Request) {
+	var in model.TagInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	t, err := h.Service.Create(r.Context(), currentUser(r), in)
+	if err != nil {
+		writeTagError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, t)
+}
+
+func (h *Tags) get(w http.ResponseWriter, r *http.Request) {
+	t, err := h.Service.Get(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (h *Tags) patch(w http.ResponseWriter, r *http.Request) {
+	var p model.TagPatch
+	if err := decodeJSON(r, &p); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	t, err := h.Service.Update(r.Context(), currentUser(r), r.PathValue("id"), p)
+	if err != nil {
+		writeTagError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (h *Tags) delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.Delete(r.Context(), currentUser(r), r.PathValue("id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeTagError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, storage.ErrConflict) {
+		writeError(w, http.StatusConflict, "a tag with that name already exists")
+		return
+	}
+	writeServiceError(w, r, err)
+}
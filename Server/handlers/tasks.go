@@ -0,0 +1,403 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"starttech-server/apierror"
+	"starttech-server/auth"
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+)
+
+// Tasks serves the /tasks endpoints. Routes must be mounted behind the auth
+// middleware.
+type Tasks struct {
+	Service *service.Tasks
+}
+
+// Register mounts the task routes on mux.
+func (h *Tasks) Register(mux router.Routes) {
+	mux.HandleFunc("GET /tasks", h.list)
+	mux.HandleFunc("POST /tasks", h.create)
+	mux.HandleFunc("POST /tasks/bulk", h.bulk)
+	mux.HandleFunc("GET /tasks/{id}", h.get)
+	mux.HandleFunc("PUT /tasks/{id}", h.replace)
+	mux.HandleFunc("PATCH /tasks/{id}", h.patch)
+	mux.HandleFunc("DELETE /tasks/{id}", h.delete)
+	mux.HandleFunc("POST /tasks/{id}/restore", h.restore)
+	mux.HandleFunc("POST /tasks/{id}/merge/{other_id}", h.merge)
+	mux.HandleFunc("GET /tasks/{id}/revisions", h.revisions)
+	mux.HandleFunc("POST /tasks/{id}/revert/{revision}", h.revert)
+	mux.HandleFunc("POST /tasks/{id}/archive", h.archive)
+	mux.HandleFunc("POST /tasks/{id}/unarchive", h.unarchive)
+	mux.HandleFunc("GET /trash", h.trash)
+	mux.HandleFunc("PATCH /tasks/{id}/move", h.move)
+	mux.HandleFunc("POST /tasks/{id}/reorder", h.reorder)
+	mux.HandleFunc("GET /tasks/{id}/subtasks", h.listSubtasks)
+	mux.HandleFunc("POST /tasks/{id}/subtasks", h.createSubtask)
+	mux.HandleFunc("GET /tasks/{id}/rollup", h.rollup)
+	mux.HandleFunc("PUT /tasks/{id}/tags/{tag_id}", h.addTag)
+	mux.HandleFunc("DELETE /tasks/{id}/tags/{tag_id}", h.removeTag)
+	mux.HandleFunc("PUT /tasks/{id}/assignee", h.assign)
+	mux.HandleFunc("DELETE /tasks/{id}/assignee", h.unassign)
+	mux.HandleFunc("GET /tasks/{id}/comments", h.listComments)
+	mux.HandleFunc("POST /tasks/{id}/comments", h.createComment)
+	mux.HandleFunc("GET /tasks/{id}/comments/{comment_id}", h.getComment)
+	mux.HandleFunc("PATCH /tasks/{id}/comments/{comment_id}", h.patchComment)
+	mux.HandleFunc("DELETE /tasks/{id}/comments/{comment_id}", h.deleteComment)
+	mux.HandleFunc("GET /tasks/{id}/comments/{comment_id}/history", h.commentHistory)
+	mux.HandleFunc("GET /tasks/{id}/activity", h.activity)
+	mux.HandleFunc("POST /tasks/{id}/timer/start", h.startTimer)
+	mux.HandleFunc("POST /tasks/{id}/timer/stop", h.stopTimer)
+	mux.HandleFunc("GET /tasks/{id}/time-entries", h.listTimeEntries)
+	mux.HandleFunc("POST /tasks/{id}/time-entries", h.logTime)
+	mux.HandleFunc("PATCH /tasks/{id}/time-entries/{entry_id}", h.patchTimeEntry)
+	mux.HandleFunc("DELETE /tasks/{id}/time-entries/{entry_id}", h.deleteTimeEntry)
+	mux.HandleFunc("GET /me/timer", h.runningTimer)
+	mux.HandleFunc("GET /tasks/{id}/dependencies", h.dependencies)
+	mux.HandleFunc("GET /tasks/{id}/dependencies/graph", h.dependencyGraph)
+	mux.HandleFunc("PUT /tasks/{id}/blockers/{blocker_id}", h.addBlocker)
+	mux.HandleFunc("DELETE /tasks/{id}/blockers/{blocker_id}", h.removeBlocker)
+	mux.HandleFunc("GET /tasks/{id}/checklist", h.checklist)
+	mux.HandleFunc("POST /tasks/{id}/checklist", h.addChecklistItem)
+	mux.HandleFunc("PUT /tasks/{id}/checklist/order", h.reorderChecklist)
+	mux.HandleFunc("PATCH /tasks/{id}/checklist/{item_id}", h.patchChecklistItem)
+	mux.HandleFunc("DELETE /tasks/{id}/checklist/{item_id}", h.deleteChecklistItem)
+	mux.HandleFunc("POST /tasks/{id}/checklist/{item_id}/toggle", h.toggleChecklistItem)
+	mux.HandleFunc("GET /tasks/{id}/watchers", h.watchers)
+	mux.HandleFunc("POST /tasks/{id}/watch", h.watch)
+	mux.HandleFunc("DELETE /tasks/{id}/watch", h.unwatch)
+	mux.HandleFunc("POST /undo", h.undo)
+	mux.HandleFunc("GET /sync/changes", h.changes)
+	mux.HandleFunc("POST /sync/push", h.push)
+	mux.HandleFunc("GET /time/totals", h.timeTotals)
+	mux.HandleFunc("GET /search", h.search)
+}
+
+// currentUser returns the authenticated user's ID. The auth middleware
+// guarantees it is present.
+func currentUser(r *http.Request) string {
+	id, _ := auth.UserID(r.Context())
+	return id
+}
+
+// zone is the caller's timezone, in which bare dates are read.
+func (h *Tasks) zone(r *http.Request) *time.Location {
+	return h.Service.Zone(r.Context(), currentUser(r))
+}
+
+func (h *Tasks) list(w http.ResponseWriter, r *http.Request) {
+	f, cursor, err := parseTaskFilter(r, h.zone(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	page, err := h.Service.List(r.Context(), currentUser(r), f, cursor)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeList(w, r, page)
+}
+
+func (h *Tasks) trash(w http.ResponseWriter, r *http.Request) {
+	f, cursor, err := parseTaskFilter(r, h.zone(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	page, err := h.Service.Trash(r.Context(), currentUser(r), f, cursor)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeList(w, r, page)
+}
+
+func (h *Tasks) restore(w http.ResponseWriter, r *http.Request) {
+	t, err := h.Service.Restore(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeTask(w, http.StatusOK, t)
+}
+
+// merge folds the task other_id into the task id, which it answers with.
+func (h *Tasks) merge(w http.ResponseWriter, r *http.Request) {
+	t, err := h.Service.Merge(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("other_id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeTask(w, http.StatusOK, t)
+}
+
+func (h *Tasks) search(w http.ResponseWriter, r *http.Request) {
+	f, cursor, err := parseTaskFilter(r, h.zone(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	page, err := h.Service.Search(r.Context(), currentUser(r), r.URL.Query().Get("q"), f, cursor)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeList(w, r, page)
+}
+
+func (h *Tasks) activity(w http.ResponseWriter, r *http.Request) {
+	limit, cursor, err := parsePage(r)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	page, err := h.Service.TaskActivity(r.Context(), currentUser(r), r.PathValue("id"), limit, cursor)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (h *Tasks) create(w http.ResponseWriter, r *http.Request) {
+	var in model.TaskInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	t, err := h.Service.Create(r.Context(), currentUser(r), in)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeTask(w, http.StatusCreated, t)
+}
+
+func (h *Tasks) get(w http.ResponseWriter, r *http.Request) {
+	t, err := h.Service.Get(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeTask(w, http.StatusOK, t)
+}
+
+func (h *Tasks) replace(w http.ResponseWriter, r *http.Request) {
+	var in model.TaskInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	h.update(w, r, in.Apply)
+}
+
+func (h *Tasks) patch(w http.ResponseWriter, r *http.Request) {
+	var p model.TaskPatch
+	if err := decodeJSON(r, &p); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	h.update(w, r, p.Apply)
+}
+
+// update honours If-Match, answering 412 when the task has moved on from the
+// versions it names.
+func (h *Tasks) update(w http.ResponseWriter, r *http.Request, mutate func(*model.Task)) {
+	t, err := h.Service.Update(r.Context(), currentUser(r), r.PathValue("id"), ifMatch(r), mutate)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeTask(w, http.StatusOK, t)
+}
+
+// writeTask sends t with its version as the ETag.
+func writeTask(w http.ResponseWriter, status int, t model.Task) {
+	w.Header().Set("ETag", etag(t.Version))
+	writeJSON(w, status, t)
+}
+
+// bulk answers 200 when every operation succeeded and 422, with the same
+// body, when they were all rolled back.
+// bulk honours ?dry_run=true, which reports what the operations would do
+// without doing it.
+func (h *Tasks) bulk(w http.ResponseWriter, r *http.Request) {
+	var (
+		opts service.BulkOptions
+		err  error
+	)
+	if opts.DryRun, err = dryRun(r); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	var in model.BulkInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	items, committed, err := h.Service.Bulk(r.Context(), currentUser(r), in, opts)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	res := model.BulkResult{DryRun: opts.DryRun, Committed: committed, Results: make([]model.BulkOutcome, len(items))}
+	failed := false
+	for i, item := range items {
+		out := &res.Results[i]
+		if item.Err != nil {
+			var e apierror.Error
+			out.Status, e = localError(r, item.Err)
+			out.Error = &e
+			if out.Status == http.StatusInternalServerError {
+				slog.ErrorContext(r.Context(), "bulk operation failed", "index", i, "err", item.Err)
+			}
+			failed = true
+			continue
+		}
+		out.Task, out.Affected = item.Task, item.Affected
+		switch in.Operations[i].Op {
+		case model.BulkCreate:
+			out.Status = http.StatusCreated
+		case model.BulkDelete:
+			out.Status = http.StatusNoContent
+		default:
+			out.Status = http.StatusOK
+		}
+	}
+	status := http.StatusOK
+	if failed {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, res)
+}
+
+// delete honours ?children=reparent (the default) or ?children=cascade.
+func (h *Tasks) delete(w http.ResponseWriter, r *http.Request) {
+	policy := service.ReparentChildren
+	if s := r.URL.Query().Get("children"); s != "" {
+		policy = service.ChildPolicy(s)
+		if !policy.Valid() {
+			var v model.ValidationError
+			v.Add("children", "must be reparent or cascade")
+			writeServiceError(w, r, v.Err())
+			return
+		}
+	}
+	if err := h.Service.Delete(r.Context(), currentUser(r), r.PathValue("id"), policy); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Tasks) listSubtasks(w http.ResponseWriter, r *http.Request) {
+	f, cursor, err := parseTaskFilter(r, h.zone(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	page, err := h.Service.Children(r.Context(), currentUser(r), r.PathValue("id"), f, cursor)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeList(w, r, page)
+}
+
+func (h *Tasks) createSubtask(w http.ResponseWriter, r *http.Request) {
+	var in model.TaskInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	t, err := h.Service.CreateSubtask(r.Context(), currentUser(r), r.PathValue("id"), in)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, t)
+}
+
+func (h *Tasks) rollup(w http.ResponseWriter, r *http.Request) {
+	ru, err := h.Service.Rollup(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ru)
+}
+
+func (h *Tasks) addTag(w http.ResponseWriter, r *http.Request) {
+	tagID := r.PathValue("tag_id")
+	h.update(w, r, func(t *model.Task) {
+		if !slices.Contains(t.TagIDs, tagID) {
+			t.TagIDs = append(t.TagIDs, tagID)
+			slices.Sort(t.TagIDs)
+		}
+	})
+}
+
+func (h *Tasks) removeTag(w http.ResponseWriter, r *http.Request) {
+	tagID := r.PathValue("tag_id")
+	h.update(w, r, func(t *model.Task) {
+		t.TagIDs = slices.DeleteFunc(t.TagIDs, func(id string) bool { return id == tagID })
+	})
+}
+
+func (h *Tasks) assign(w http.ResponseWriter, r *http.Request) {
+	var in model.AssignInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	userID := strings.TrimSpace(in.UserID)
+	if userID == "" {
+		var v model.ValidationError
+		v.Add("user_id", "is required")
+		writeServiceError(w, r, v.Err())
+		return
+	}
+	if userID == service.AssigneeMe {
+		userID = currentUser(r)
+	}
+	h.update(w, r, func(t *model.Task) { t.AssigneeID = &userID })
+}
+
+func (h *Tasks) unassign(w http.ResponseWriter, r *http.Request) {
+	h.update(w, r, func(t *model.Task) { t.AssigneeID = nil })
+}
+
+func (h *Tasks) move(w http.ResponseWriter, r *http.Request) {
+	var in model.MoveInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	t, err := h.Service.Move(r.Context(), currentUser(r), r.PathValue("id"), in)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeTask(w, http.StatusOK, t)
+}
+
+func (h *Tasks) reorder(w http.ResponseWriter, r *http.Request) {
+	var in model.ReorderInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	t, err := h.Service.Place(r.Context(), currentUser(r), r.PathValue("id"), in)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeTask(w, http.StatusOK, t)
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+	"starttech-server/storage"
+)
+
+// Templates serves the /templates endpoints and the routes saving tasks and
+// projects as templates. Routes must be mounted behind the auth middleware.
+type Templates struct {
+	Service *service.Templates
+}
+
+// Register mounts the template routes on mux.
+func (h *Templates) Register(mux router.Routes) {
+	mux.HandleFunc("POST /tasks/{id}/template", h.saveTask)
+	mux.HandleFunc("POST /projects/{id}/template", h.saveProject)
+	mux.HandleFunc("GET /templates", h.list)
+	mux.HandleFunc("GET /templates/{id}", h.get)
+	mux.HandleFunc("PATCH /templates/{id}", h.patch)
+	mux.HandleFunc("DELETE /templates/{id}", h.delete)
+	mux.HandleFunc("POST /templates/{id}/use", h.use)
+}
+
+func (h *Templates) saveTask(w http.ResponseWriter, r *http.Request) {
+	var in model.TemplateInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	t, err := h.Service.SaveTask(r.Context(), currentUser(r), r.PathValue("id"), in)
+	if err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, t)
+}
+
+func (h *Templates) saveProject(w http.ResponseWriter, r *http.Request) {
+	var in model.TemplateInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	t, err := h.Service.SaveProject(r.Context(), currentUser(r), r.PathValue("id"), in)
+	if err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, t)
+}
+
+func (h *Templates) list(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.Service.List(r.Context(), currentUser(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, templates)
+}
+
+func (h *Templates) get(w http.ResponseWriter, r *http.Request) {
+	t, err := h.Service.Get(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (h *Templates) patch(w http.ResponseWriter, r *http.Request) {
+	var p model.TemplatePatch
+	if err := decodeJSON(r, &p); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	t, err := h.Service.Update(r.Context(), currentUser(r), r.PathValue("id"), p)
+	if err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (h *Templates) delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.Delete(r.Context(), currentUser(r), r.PathValue("id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Templates) use(w http.ResponseWriter, r *http.Request) {
+	var u model.TemplateUse
+	if err := decodeJSON(r, &u); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	res, err := h.Service.Use(r.Context(), currentUser(r), r.PathValue("id"), u)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, res)
+}
+
+func writeTemplateError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, storage.ErrConflict) {
+		writeError(w, http.StatusConflict, "a template with that name already exists")
+		return
+	}
+	writeServiceError(w, r, err)
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+
+	"starttech-server/model"
+	"starttech-server/service"
+	"starttech-server/storage"
+)
+
+// startTimer answers 201 with a new timer, or 200 with the one already
+// running on the task.
+func (h *Tasks) startTimer(w http.ResponseWriter, r *http.Request) {
+	e, started, err := h.Service.StartTimer(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	status := http.StatusOK
+	if started {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, e)
+}
+
+func (h *Tasks) stopTimer(w http.ResponseWriter, r *http.Request) {
+	e, err := h.Service.StopTimer(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+func (h *Tasks) runningTimer(w http.ResponseWriter, r *http.Request) {
+	e, err := h.Service.RunningTimer(r.Context(), currentUser(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+func (h *Tasks) listTimeEntries(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.Service.ListTimeEntries(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (h *Tasks) logTime(w http.ResponseWriter, r *http.Request) {
+	var in model.TimeEntryInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	e, err := h.Service.LogTime(r.Context(), currentUser(r), r.PathValue("id"), in)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, e)
+}
+
+func (h *Tasks) patchTimeEntry(w http.ResponseWriter, r *http.Request) {
+	var p model.TimeEntryPatch
+	if err := decodeJSON(r, &p); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	e, err := h.Service.EditTimeEntry(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("entry_id"), p)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+func (h *Tasks) deleteTimeEntry(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.DeleteTimeEntry(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("entry_id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// timeTotals reads the query parameters of GET /time/totals:
+//
+//	by          task (the default), project or user
+//	from        RFC 3339 timestamp or YYYY-MM-DD date in the caller's timezone
+//	to          RFC 3339 timestamp or YYYY-MM-DD date in the caller's timezone (exclusive)
+//	project_id  only time on tasks in this project
+//	user        only time logged by this user ID, or "me"
+func (h *Tasks) timeTotals(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var v model.ValidationError
+	loc := h.zone(r)
+	f := storage.TimeEntryFilter{
+		ProjectID: q.Get("project_id"),
+		UserID:    q.Get("user"),
+		From:      parseTimeParam(q.Get("from"), "from", loc, &v),
+		To:        parseTimeParam(q.Get("to"), "to", loc, &v),
+	}
+	if err := v.Err(); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if f.UserID == service.AssigneeMe {
+		f.UserID = currentUser(r)
+	}
+	totals, err := h.Service.TimeTotals(r.Context(), currentUser(r), q.Get("by"), f)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, totals)
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"starttech-server/apierror"
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+	"starttech-server/storage"
+)
+
+// RegisterTwoFactor mounts the routes that manage the caller's own
+// two-factor authentication on mux, which must be behind the auth
+// middleware. They take a session, not an API key.
+func (h *Auth) RegisterTwoFactor(mux router.Routes) {
+	mux.HandleFunc("GET /auth/2fa", sessionOnly(h.twoFactorStatus))
+	mux.HandleFunc("POST /auth/2fa/setup", sessionOnly(h.setupTwoFactor))
+	mux.HandleFunc("POST /auth/2fa/enable", sessionOnly(h.enableTwoFactor))
+	mux.HandleFunc("POST /auth/2fa/disable", sessionOnly(h.disableTwoFactor))
+	mux.HandleFunc("POST /auth/2fa/recovery-codes", sessionOnly(h.regenerateRecoveryCodes))
+}
+
+func (h *Auth) twoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	st, err := h.TwoFactor.Status(r.Context(), currentUser(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+func (h *Auth) setupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	setup, err := h.TwoFactor.Setup(r.Context(), currentUser(r))
+	if errors.Is(err, storage.ErrConflict) {
+		writeError(w, http.StatusConflict, "two-factor authentication is already enabled; disable it first")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, setup)
+}
+
+func (h *Auth) enableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var in model.TwoFactorCodeInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	codes, err := h.TwoFactor.Enable(r.Context(), currentUser(r), in.Code)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		writeError(w, http.StatusConflict, "POST /auth/2fa/setup first")
+		return
+	case errors.Is(err, storage.ErrConflict):
+		writeError(w, http.StatusConflict, "two-factor authentication is already enabled")
+		return
+	case err != nil:
+		writeTwoFactorError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	h.Audit.record(r, model.AuditEvent{Action: model.AuditTwoFactorOn, ActorID: currentUser(r), TargetID: currentUser(r)})
+	writeJSON(w, http.StatusOK, codes)
+}
+
+func (h *Auth) disableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var in model.TwoFactorCodeInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if err := h.TwoFactor.Disable(r.Context(), currentUser(r), in.Code); err != nil {
+		writeTwoFactorError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	h.Audit.record(r, model.AuditEvent{Action: model.AuditTwoFactorOff, ActorID: currentUser(r), TargetID: currentUser(r)})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Auth) regenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var in model.TwoFactorCodeInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	codes, err := h.TwoFactor.RegenerateRecoveryCodes(r.Context(), currentUser(r), in.Code)
+	if err != nil {
+		writeTwoFactorError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	h.Audit.record(r, model.AuditEvent{Action: model.AuditRecoveryCodes, ActorID: currentUser(r), TargetID: currentUser(r)})
+	writeJSON(w, http.StatusOK, codes)
+}
+
+// verifyTwoFactor finishes a sign-in that was answered with a challenge,
+// given a code, and starts the session.
+func (h *Auth) verifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var in model.TwoFactorLoginInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	u, err := h.TwoFactor.Verify(r.Context(), in.Challenge, in.Code)
+	if errors.Is(err, service.ErrInvalidTwoFactorCode) {
+		h.Audit.record(r, model.AuditEvent{Action: model.AuditLoginFailed, ActorID: u.ID, Detail: "invalid two-factor code"})
+	}
+	if err != nil {
+		writeTwoFactorError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+	org, err := h.loginOrg(r, u, in.OrgID)
+	if err != nil {
+		writeOrgError(w, r, err)
+		return
+	}
+	h.startSession(w, r, http.StatusOK, u, org)
+}
+
+// challengeSecondFactor stops a sign-in of u that needs a second factor,
+// answering with the challenge to send with the code to POST
+// /auth/2fa/verify, and returns true. If redirect is set, the answer is a
+// redirect there with the challenge in the two_factor_challenge parameter
+// instead. It returns false, writing nothing, if u has no second factor.
+func (h *Auth) challengeSecondFactor(w http.ResponseWriter, r *http.Request, u model.User, redirect string) bool {
+	on, err := h.TwoFactor.Enabled(r.Context(), u.ID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return true
+	}
+	if !on {
+		return false
+	}
+	challenge, err := h.TwoFactor.Challenge(u)
+	if err != nil {
+		writeInternalError(w, r, "issuing two-factor challenge", err)
+		return true
+	}
+	if redirect != "" {
+		target, err := url.Parse(redirect)
+		if err != nil {
+			writeInternalError(w, r, "parsing success URL", err)
+			return true
+		}
+		q := target.Query()
+		q.Set("two_factor_challenge", challenge)
+		target.RawQuery = q.Encode()
+		http.Redirect(w, r, target.String(), http.StatusFound)
+		return true
+	}
+	apierror.WriteError(w, http.StatusUnauthorized, apierror.Error{
+		Code:    apierror.CodeTwoFactorRequired,
+		Message: "enter the code from your authenticator app, or a recovery code, at POST /auth/2fa/verify",
+		Details: map[string]any{"challenge": challenge, "expires_in": int(service.TwoFactorChallengeTTL.Seconds())},
+	})
+	return true
+}
+
+// writeTwoFactorError reports a wrong code with status, which differs
+// between signing in and managing one's own settings.
+func writeTwoFactorError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if errors.Is(err, service.ErrInvalidTwoFactorCode) {
+		writeErrorCode(w, status, apierror.CodeInvalidTwoFactorCode, "the code is wrong or has already been used")
+		return
+	}
+	writeAccountError(w, r, err)
+}
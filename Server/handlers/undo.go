@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"starttech-server/storage"
+)
+
+func (h *Tasks) undo(w http.ResponseWriter, r *http.Request) {
+	undone, err := h.Service.Undo(r.Context(), currentUser(r))
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "there is nothing to undo")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, undone)
+}
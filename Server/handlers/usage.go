@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"starttech-server/apierror"
+	"starttech-server/auth"
+	"starttech-server/router"
+	"starttech-server/service"
+)
+
+// Usage reports what the caller's organization uses against its quotas and
+// holds its requests to the daily quota. Routes must be mounted behind the
+// auth middleware.
+type Usage struct {
+	Service *service.Usage
+}
+
+// Register mounts the routes on mux.
+func (h *Usage) Register(mux router.Routes) {
+	mux.HandleFunc("GET /usage", h.get)
+}
+
+// Meter counts each request against the daily quota of the organization it
+// is made in, and answers those over it with 429 Too Many Requests and a
+// Retry-After header that runs until midnight UTC. It must run after the
+// auth middleware.
+func (h *Usage) Meter(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		orgID, _ := auth.OrgID(r.Context())
+		err := h.Service.CountRequest(r.Context(), orgID)
+		var quota *service.QuotaError
+		if errors.As(err, &quota) {
+			retry := int(math.Ceil(quota.RetryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retry))
+			apierror.WriteError(w, http.StatusTooManyRequests, apierror.Error{
+				Code:    apierror.CodeQuotaExceeded,
+				Message: quotaMessage(quota),
+				Details: map[string]any{"quota": quota.Quota, "limit": quota.Limit, "retry_after": retry},
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Usage) get(w http.ResponseWriter, r *http.Request) {
+	u, err := h.Service.Get(r.Context(), currentUser(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
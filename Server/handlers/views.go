@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+	"starttech-server/storage"
+)
+
+// Views serves the /views endpoints. Routes must be mounted behind the auth
+// middleware.
+type Views struct {
+	Service *service.Views
+}
+
+// Register mounts the view routes on mux.
+func (h *Views) Register(mux router.Routes) {
+	mux.HandleFunc("GET /views", h.list)
+	mux.HandleFunc("POST /views", h.create)
+	mux.HandleFunc("GET /views/{id}", h.get)
+	mux.HandleFunc("PATCH /views/{id}", h.patch)
+	mux.HandleFunc("DELETE /views/{id}", h.delete)
+	mux.HandleFunc("GET /views/{id}/tasks", h.tasks)
+}
+
+func (h *Views) list(w http.ResponseWriter, r *http.Request) {
+	views, err := h.Service.List(r.Context(), currentUser(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (h *Views) create(w http.ResponseWriter, r *http.Request) {
+	var in model.ViewInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	v, err := h.Service.Create(r.Context(), currentUser(r), in)
+	if err != nil {
+		writeViewError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, v)
+}
+
+func (h *Views) get(w http.ResponseWriter, r *http.Request) {
+	v, err := h.Service.Get(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func (h *Views) patch(w http.ResponseWriter, r *http.Request) {
+	var p model.ViewPatch
+	if err := decodeJSON(r, &p); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	v, err := h.Service.Update(r.Context(), currentUser(r), r.PathValue("id"), p)
+	if err != nil {
+		writeViewError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func (h *Views) delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.Delete(r.Context(), currentUser(r), r.PathValue("id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// tasks runs the view, paging like GET /tasks.
+func (h *Views) tasks(w http.ResponseWriter, r *http.Request) {
+	limit, cursor, err := parsePage(r)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	page, err := h.Service.Run(r.Context(), currentUser(r), r.PathValue("id"), limit, cursor)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeList(w, r, page)
+}
+
+func writeViewError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, storage.ErrConflict) {
+		writeError(w, http.StatusConflict, "a view with that name already exists")
+		return
+	}
+	writeServiceError(w, r, err)
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import "net/http"
+
+func (h *Tasks) watchers(w http.ResponseWriter, r *http.Request) {
+	watchers, err := h.Service.ListWatchers(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, watchers)
+}
+
+func (h *Tasks) watch(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.Watch(r.Context(), currentUser(r), r.PathValue("id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Tasks) unwatch(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.Unwatch(r.Context(), currentUser(r), r.PathValue("id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
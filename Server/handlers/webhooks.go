@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"starttech-server/model"
+	"starttech-server/router"
+	"starttech-server/service"
+	"starttech-server/storage"
+)
+
+// Webhooks serves the /webhooks endpoints. Routes must be mounted behind the
+// auth middleware.
+type Webhooks struct {
+	Service *service.Webhooks
+}
+
+// Register mounts the webhook routes on mux.
+func (h *Webhooks) Register(mux router.Routes) {
+	mux.HandleFunc("GET /webhooks", h.list)
+	mux.HandleFunc("POST /webhooks", h.create)
+	mux.HandleFunc("GET /webhooks/{id}", h.get)
+	mux.HandleFunc("PATCH /webhooks/{id}", h.patch)
+	mux.HandleFunc("DELETE /webhooks/{id}", h.delete)
+	mux.HandleFunc("GET /webhooks/{id}/deliveries", h.deliveries)
+	mux.HandleFunc("POST /webhooks/{id}/deliveries/{delivery_id}/replay", h.replay)
+}
+
+func (h *Webhooks) list(w http.ResponseWriter, r *http.Request) {
+	hooks, err := h.Service.List(r.Context(), currentUser(r))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, hooks)
+}
+
+func (h *Webhooks) create(w http.ResponseWriter, r *http.Request) {
+	var in model.WebhookInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	hook, err := h.Service.Create(r.Context(), currentUser(r), in)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, hook)
+}
+
+func (h *Webhooks) get(w http.ResponseWriter, r *http.Request) {
+	hook, err := h.Service.Get(r.Context(), currentUser(r), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, hook)
+}
+
+func (h *Webhooks) patch(w http.ResponseWriter, r *http.Request) {
+	var p model.WebhookPatch
+	if err := decodeJSON(r, &p); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	hook, err := h.Service.Update(r.Context(), currentUser(r), r.PathValue("id"), p)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, hook)
+}
+
+func (h *Webhooks) delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.Delete(r.Context(), currentUser(r), r.PathValue("id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Webhooks) deliveries(w http.ResponseWriter, r *http.Request) {
+	var limit int
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			var v model.ValidationError
+			v.Add("limit", "must be a positive integer")
+			writeServiceError(w, r, v.Err())
+			return
+		}
+		limit = n
+	}
+	ds, err := h.Service.Deliveries(r.Context(), currentUser(r), r.PathValue("id"), limit)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ds)
+}
+
+func (h *Webhooks) replay(w http.ResponseWriter, r *http.Request) {
+	d, err := h.Service.Replay(r.Context(), currentUser(r), r.PathValue("id"), r.PathValue("delivery_id"))
+	if errors.Is(err, storage.ErrConflict) {
+		writeError(w, http.StatusConflict, "the delivery is still pending")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, d)
+}
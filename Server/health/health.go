@@ -0,0 +1,94 @@
+// Package health tracks what the server needs in order to serve: the
+// dependencies it checks on demand, such as the database, and the
+// background workers it started, which must keep running until shutdown.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Timeout bounds each check, so a hung dependency fails its check rather
+// than the probe.
+const Timeout = 2 * time.Second
+
+// ErrStopped is reported for a worker that has returned.
+var ErrStopped = errors.New("worker stopped")
+
+// Checker runs the readiness checks. Its zero value has none.
+type Checker struct {
+	mu      sync.Mutex
+	checks  map[string]func(context.Context) error
+	workers map[string]bool
+}
+
+// Add registers check under name. It is called on every Check, and an error
+// marks the component down.
+func (c *Checker) Add(name string, check func(context.Context) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.checks == nil {
+		c.checks = map[string]func(context.Context) error{}
+	}
+	c.checks[name] = check
+}
+
+// Go runs the worker run in a goroutine, reporting it under name as down
+// once it returns. Workers are expected to run until ctx is cancelled; one
+// with nothing to do should not be started.
+func (c *Checker) Go(ctx context.Context, name string, run func(context.Context)) {
+	c.setRunning(name, true)
+	go func() {
+		defer c.setRunning(name, false)
+		run(ctx)
+	}()
+}
+
+func (c *Checker) setRunning(name string, running bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.workers == nil {
+		c.workers = map[string]bool{}
+	}
+	c.workers[name] = running
+}
+
+// Check runs every check at once and reports each component by name, with
+// a nil error for those that are fine.
+func (c *Checker) Check(ctx context.Context) map[string]error {
+	c.mu.Lock()
+	results := make(map[string]error, len(c.checks)+len(c.workers))
+	for name, running := range c.workers {
+		if running {
+			results[name] = nil
+		} else {
+			results[name] = ErrStopped
+		}
+	}
+	checks := make(map[string]func(context.Context) error, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := check(ctx)
+			mu.Lock()
+			results[name] = err
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,241 @@
+// Package i18n translates the texts the server shows people: emails,
+// notifications and the messages of error responses. Texts are written in
+// English, which needs no catalog, and looked up by that English text in
+// the catalog of each other language, embedded from catalogs/. A text a
+// catalog lacks stays in English, so new messages need not wait for their
+// translations.
+//
+// A text may hold placeholders, {0}, {1} and so on, filled in by T. The
+// same placeholders let Translate recognise messages that were formatted
+// before they reached it, such as "must be at most 200 characters" from
+// the catalog entry "must be at most {0} characters".
+package i18n
+
+import (
+	"cmp"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Default is the language of the texts in the code, and of requests that
+// ask for none the server has.
+const Default = "en"
+
+//go:embed catalogs/*.json
+var files embed.FS
+
+// catalog holds the translations of one language.
+type catalog struct {
+	texts map[string]string
+	// patterns are the texts with placeholders, longest first, so that
+	// the most specific one matches.
+	patterns []pattern
+}
+
+type pattern struct {
+	re          *regexp.Regexp
+	translation string
+}
+
+var (
+	catalogs  = map[string]*catalog{}
+	languages = []string{Default}
+)
+
+var placeholder = regexp.MustCompile(`\{(\d+)\}`)
+
+func init() {
+	names, err := files.ReadDir("catalogs")
+	if err != nil {
+		panic(fmt.Sprintf("i18n: reading catalogs: %v", err))
+	}
+	for _, f := range names {
+		lang := strings.TrimSuffix(f.Name(), path.Ext(f.Name()))
+		b, err := files.ReadFile("catalogs/" + f.Name())
+		if err != nil {
+			panic(fmt.Sprintf("i18n: reading catalog %s: %v", lang, err))
+		}
+		c := &catalog{}
+		if err := json.Unmarshal(b, &c.texts); err != nil {
+			panic(fmt.Sprintf("i18n: decoding catalog %s: %v", lang, err))
+		}
+		for text, translation := range c.texts {
+			if placeholder.MatchString(text) {
+				c.patterns = append(c.patterns, pattern{compile(text), translation})
+			}
+		}
+		slices.SortFunc(c.patterns, func(a, b pattern) int {
+			return cmp.Compare(len(b.re.String()), len(a.re.String()))
+		})
+		catalogs[lang] = c
+		languages = append(languages, lang)
+	}
+	slices.Sort(languages)
+}
+
+// compile turns a text with placeholders into an expression matching the
+// texts it formats to, with a group named pN for placeholder {N}.
+func compile(text string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	last := 0
+	for _, m := range placeholder.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(regexp.QuoteMeta(text[last:m[0]]))
+		fmt.Fprintf(&b, "(?P<p%s>.+?)", text[m[2]:m[3]])
+		last = m[1]
+	}
+	b.WriteString(regexp.QuoteMeta(text[last:]))
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// Supported returns the languages there are texts in, in name order.
+func Supported() []string {
+	return slices.Clone(languages)
+}
+
+// Match returns the supported language of tag, a language tag such as
+// fr or fr-CA, by its primary subtag, and whether there is one.
+func Match(tag string) (string, bool) {
+	primary, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+	primary = strings.ToLower(primary)
+	if slices.Contains(languages, primary) {
+		return primary, true
+	}
+	return "", false
+}
+
+// Negotiate returns the supported language the client prefers most by the
+// Accept-Language header value header, and whether it accepts any of them.
+// A wildcard accepts Default.
+func Negotiate(header string) (string, bool) {
+	type choice struct {
+		tag string
+		q   float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		c := choice{tag: strings.TrimSpace(tag), q: 1}
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			c.q = q
+		}
+		if c.tag != "" && c.q > 0 {
+			choices = append(choices, c)
+		}
+	}
+	slices.SortStableFunc(choices, func(a, b choice) int { return cmp.Compare(b.q, a.q) })
+	for _, c := range choices {
+		if c.tag == "*" {
+			return Default, true
+		}
+		if lang, ok := Match(c.tag); ok {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+// T returns text in lang, with its placeholders replaced by args in order.
+// An unsupported lang gives Default.
+func T(lang, text string, args ...any) string {
+	if c := catalogs[lang]; c != nil {
+		if s, ok := c.texts[text]; ok {
+			text = s
+		}
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return placeholder.ReplaceAllStringFunc(text, func(p string) string {
+		i, _ := strconv.Atoi(p[1 : len(p)-1])
+		if i >= len(args) {
+			return p
+		}
+		return fmt.Sprint(args[i])
+	})
+}
+
+// Translate returns msg, a text already formatted in English, in lang. A
+// message that is in no catalog as it is may still match a text with
+// placeholders, whose translation then gets the values msg holds in their
+// place.
+func Translate(lang, msg string) string {
+	c := catalogs[lang]
+	if c == nil || msg == "" {
+		return msg
+	}
+	if s, ok := c.texts[msg]; ok {
+		return s
+	}
+	for _, p := range c.patterns {
+		m := p.re.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+		return placeholder.ReplaceAllStringFunc(p.translation, func(ph string) string {
+			if i := p.re.SubexpIndex("p" + ph[1:len(ph)-1]); i > 0 {
+				return m[i]
+			}
+			return ph
+		})
+	}
+	return msg
+}
+
+type languageKey struct{}
+
+// WithLanguage returns a copy of ctx asking for texts in lang.
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, languageKey{}, lang)
+}
+
+// FromContext returns the language ctx asks for, and whether it asks for
+// one at all.
+func FromContext(ctx context.Context) (string, bool) {
+	lang, ok := ctx.Value(languageKey{}).(string)
+	return lang, ok
+}
+
+// Language returns the language ctx asks for, or Default.
+func Language(ctx context.Context) string {
+	if lang, ok := FromContext(ctx); ok {
+		return lang
+	}
+	return Default
+}
+
+// Use makes r ask for texts in lang, and names lang in the
+// Content-Language header of the response, where apierror finds it.
+func Use(w http.ResponseWriter, r *http.Request, lang string) *http.Request {
+	w.Header().Set("Content-Language", lang)
+	return r.WithContext(WithLanguage(r.Context(), lang))
+}
+
+// Middleware picks the language of each request by its Accept-Language
+// header. Requests asking for no language the server has get Default, but
+// their context asks for none, so that a preference of the user may still
+// take its place.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Language")
+		if lang, ok := Negotiate(r.Header.Get("Accept-Language")); ok {
+			r = Use(w, r, lang)
+		} else {
+			w.Header().Set("Content-Language", Default)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,138 @@
+// Package ical writes RFC 5545 iCalendar data: a calendar of events, in
+// the form calendar apps accept as a subscription feed. Only what such
+// feeds need is supported; there are no time zones, alarms or recurrences,
+// and times are written in UTC.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLineLen is the length in octets, without the line break, beyond which
+// lines are folded.
+const maxLineLen = 75
+
+const (
+	dateLayout = "20060102"
+	timeLayout = "20060102T150405Z"
+)
+
+// Event is one VEVENT. A timed event takes no time: it starts and ends at
+// Start. An all-day event covers the day of Start, in Start's location.
+type Event struct {
+	// UID identifies the event across updates of the feed.
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	AllDay      bool
+	// Modified is when the event last changed; it is also given as the
+	// DTSTAMP the standard requires.
+	Modified time.Time
+}
+
+// Writer writes a VCALENDAR to an underlying writer. Errors are sticky:
+// once a write fails, later calls do nothing and return the same error.
+type Writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+// NewWriter starts a calendar named name on w. Clients are asked to
+// refresh it every refresh, which they treat as a hint.
+func NewWriter(w io.Writer, name string, refresh time.Duration) *Writer {
+	cw := &Writer{w: bufio.NewWriter(w)}
+	cw.line("BEGIN:VCALENDAR")
+	cw.line("VERSION:2.0")
+	cw.line("PRODID:-//StartTech//Tasks//EN")
+	cw.line("CALSCALE:GREGORIAN")
+	cw.line("METHOD:PUBLISH")
+	cw.line("X-WR-CALNAME:" + escape(name))
+	cw.line("REFRESH-INTERVAL;VALUE=DURATION:" + duration(refresh))
+	cw.line("X-PUBLISHED-TTL:" + duration(refresh))
+	return cw
+}
+
+// Event writes e.
+func (w *Writer) Event(e Event) error {
+	w.line("BEGIN:VEVENT")
+	w.line("UID:" + escape(e.UID))
+	w.line("DTSTAMP:" + e.Modified.UTC().Format(timeLayout))
+	if e.AllDay {
+		w.line("DTSTART;VALUE=DATE:" + e.Start.Format(dateLayout))
+		w.line("DTEND;VALUE=DATE:" + e.Start.AddDate(0, 0, 1).Format(dateLayout))
+	} else {
+		w.line("DTSTART:" + e.Start.UTC().Format(timeLayout))
+	}
+	w.line("SUMMARY:" + escape(e.Summary))
+	if e.Description != "" {
+		w.line("DESCRIPTION:" + escape(e.Description))
+	}
+	w.line("LAST-MODIFIED:" + e.Modified.UTC().Format(timeLayout))
+	w.line("END:VEVENT")
+	return w.err
+}
+
+// Close ends the calendar and flushes it to the underlying writer.
+func (w *Writer) Close() error {
+	w.line("END:VCALENDAR")
+	if w.err == nil {
+		w.err = w.w.Flush()
+	}
+	return w.err
+}
+
+// line writes a content line, folding it into lines of at most maxLineLen
+// octets without splitting a character.
+func (w *Writer) line(s string) {
+	if w.err != nil {
+		return
+	}
+	limit := maxLineLen
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.w.WriteString(s[:cut])
+		w.w.WriteString("\r\n ")
+		s = s[cut:]
+		// The leading space of a continuation counts against the limit.
+		limit = maxLineLen - 1
+	}
+	w.w.WriteString(s)
+	_, w.err = w.w.WriteString("\r\n")
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escape writes s as a TEXT value.
+func escape(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// duration writes d, to the second, as a DURATION value such as PT1H30M.
+func duration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d <= 0 {
+		return "PT0S"
+	}
+	var b strings.Builder
+	b.WriteString("PT")
+	for _, unit := range []struct {
+		size time.Duration
+		name byte
+	}{{time.Hour, 'H'}, {time.Minute, 'M'}, {time.Second, 'S'}} {
+		if n := d / unit.size; n > 0 {
+			b.WriteString(strconv.FormatInt(int64(n), 10))
+			b.WriteByte(unit.name)
+			d -= n * unit.size
+		}
+	}
+	return b.String()
+}
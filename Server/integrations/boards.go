@@ -0,0 +1,146 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"starttech-server/jobs"
+	"starttech-server/model"
+)
+
+// KindImport is the kind of the jobs that run board imports.
+const KindImport = "import.boards"
+
+// Importer runs board imports as jobs on the background queue.
+type Importer struct {
+	jobs *jobs.Queue
+}
+
+// NewImporter returns an Importer that queues its jobs on queue.
+func NewImporter(queue *jobs.Queue) *Importer {
+	return &Importer{jobs: queue}
+}
+
+// importJob is the payload of KindImport jobs.
+type importJob struct {
+	ImportID string `json:"import_id"`
+}
+
+// Register makes the job queue run imports with run, which carries out
+// the import with the given ID and is told when it is on its last attempt;
+// see service.BoardImports.Run.
+func (i *Importer) Register(run func(ctx context.Context, importID string, last bool) error) {
+	i.jobs.Handle(KindImport, 3, func(ctx context.Context, j model.Job) error {
+		var ij importJob
+		if err := jobs.Decode(j, &ij); err != nil {
+			return err
+		}
+		return run(ctx, ij.ImportID, j.Attempts >= j.MaxAttempts)
+	})
+}
+
+// Enqueue queues the import with the given ID to be run.
+func (i *Importer) Enqueue(ctx context.Context, importID string) error {
+	return i.jobs.EnqueueOnce(ctx, "import-"+importID, KindImport, importJob{importID})
+}
+
+// exportError reports an export that cannot be read.
+func exportError(format string, args ...any) error {
+	var v model.ValidationError
+	v.Add("body", fmt.Sprintf(format, args...))
+	return v.Err()
+}
+
+// isTooBig reports whether err is a body running past its limit, which is
+// not the export's fault and is reported as it is.
+func isTooBig(err error) bool {
+	var tooBig *http.MaxBytesError
+	return errors.As(err, &tooBig)
+}
+
+// boardColumns builds the workflow of a board named board from the names of
+// its lists, in order, and whether each holds finished cards. It returns
+// the status key of each list alongside. A board without a finished list
+// gets a Done column at the end, and one with only finished lists a To do
+// column at the start.
+func boardColumns(board string, names []string, done []bool) (model.Workflow, []model.Status, error) {
+	var w model.Workflow
+	keys := make([]model.Status, len(names))
+	seen := map[model.Status]bool{}
+	var open, finished bool
+	for i, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			name = fmt.Sprintf("List %d", i+1)
+		}
+		keys[i] = columnKey(name, seen)
+		w = append(w, model.Column{Key: keys[i], Name: clip(name, model.MaxColumnNameLen), Done: done[i]})
+		finished = finished || done[i]
+		open = open || !done[i]
+	}
+	if !finished {
+		w = append(w, model.Column{Key: columnKey("done", seen), Name: "Done", Done: true})
+	}
+	if !open {
+		w = append(model.Workflow{{Key: columnKey("todo", seen), Name: "To do"}}, w...)
+	}
+	if len(w) > model.MaxColumns {
+		return nil, nil, exportError("board %q has more lists than the %d columns a project can have", board, model.MaxColumns)
+	}
+	return w, keys, nil
+}
+
+// columnKey derives a status key from a column name that is not yet in
+// seen, and adds it.
+func columnKey(name string, seen map[model.Status]bool) model.Status {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+			underscore = false
+		case !underscore && b.Len() > 0:
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	base := strings.TrimSuffix(b.String(), "_")
+	if len(base) > 30 {
+		base = strings.TrimSuffix(base[:30], "_")
+	}
+	if base == "" {
+		base = "list"
+	}
+	key := model.Status(base)
+	for n := 2; seen[key]; n++ {
+		key = model.Status(fmt.Sprintf("%s_%d", base, n))
+	}
+	seen[key] = true
+	return key
+}
+
+// clip shortens s to at most n runes.
+func clip(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
+// checkCards reports an export with more cards than one import may hold.
+func checkCards(boards []model.Board) error {
+	n := 0
+	for _, b := range boards {
+		n += len(b.Cards)
+	}
+	if n > model.MaxBoardImportCards {
+		return exportError("holds %d cards; an import may hold at most %d", n, model.MaxBoardImportCards)
+	}
+	return nil
+}
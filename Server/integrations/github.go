@@ -0,0 +1,176 @@
+// Package integrations connects projects to outside services. GitHub links
+// a project to a repository: its issues become tasks, and Pusher closes or
+// reopens an issue when its task is completed or reopened. SlackNotifier
+// posts a project's task events to a Slack channel. Both services are
+// reached over plain HTTP with the standard library.
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnauthorized is returned when GitHub rejects the token, or the
+	// repository does not exist or is hidden from it.
+	ErrUnauthorized = errors.New("integrations: github refused access to the repository")
+	// ErrUnavailable wraps the other failures to talk to GitHub.
+	ErrUnavailable = errors.New("integrations: github is unavailable")
+)
+
+// DefaultGitHubAPI is the API of github.com.
+const DefaultGitHubAPI = "https://api.github.com"
+
+// MaxIssues bounds the issues Issues reads from one repository.
+const MaxIssues = 1000
+
+// issuesPerPage is the largest page GitHub serves.
+const issuesPerPage = 100
+
+// HeaderGitHubEvent and HeaderGitHubSignature are sent with every webhook
+// delivery from GitHub.
+const (
+	HeaderGitHubEvent     = "X-GitHub-Event"
+	HeaderGitHubSignature = "X-Hub-Signature-256"
+)
+
+// GitHub calls the GitHub REST API with a token per call, since every
+// linked repository has its own.
+type GitHub struct {
+	// BaseURL is the API root; it defaults to DefaultGitHubAPI. GitHub
+	// Enterprise serves it under /api/v3.
+	BaseURL string
+	// Client makes the calls; nil uses a client with a ten second
+	// timeout.
+	Client *http.Client
+}
+
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// Issue is a GitHub issue, as the REST API and webhooks describe it.
+// PullRequest is set on pull requests, which the issues endpoints list
+// too.
+type Issue struct {
+	Number      int             `json:"number"`
+	Title       string          `json:"title"`
+	Body        string          `json:"body"`
+	State       string          `json:"state"`
+	PullRequest json.RawMessage `json:"pull_request,omitempty"`
+}
+
+// IssuesEvent is the payload of an issues webhook delivery.
+type IssuesEvent struct {
+	Action     string `json:"action"`
+	Issue      Issue  `json:"issue"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// CheckRepo confirms that token can see repo, given as owner/name.
+func (g *GitHub) CheckRepo(ctx context.Context, token, repo string) error {
+	return g.call(ctx, http.MethodGet, "/repos/"+repo, token, nil, nil)
+}
+
+// Issues returns the issues of repo, open and closed, oldest first, and
+// without pull requests. At most MaxIssues are read.
+func (g *GitHub) Issues(ctx context.Context, token, repo string) ([]Issue, error) {
+	var out []Issue
+	for page := 1; len(out) < MaxIssues; page++ {
+		var batch []Issue
+		path := fmt.Sprintf("/repos/%s/issues?state=all&sort=created&direction=asc&per_page=%d&page=%d", repo, issuesPerPage, page)
+		if err := g.call(ctx, http.MethodGet, path, token, nil, &batch); err != nil {
+			return nil, err
+		}
+		for _, is := range batch {
+			if is.PullRequest == nil && len(out) < MaxIssues {
+				out = append(out, is)
+			}
+		}
+		if len(batch) < issuesPerPage {
+			break
+		}
+	}
+	return out, nil
+}
+
+// SetIssueState opens or closes issue number of repo.
+func (g *GitHub) SetIssueState(ctx context.Context, token, repo string, number int, state string) error {
+	body := map[string]string{"state": state}
+	return g.call(ctx, http.MethodPatch, fmt.Sprintf("/repos/%s/issues/%d", repo, number), token, body, nil)
+}
+
+// call sends a request to path with token and a JSON body, if any, and
+// decodes the JSON answer into v, if given.
+func (g *GitHub) call(ctx context.Context, method, path, token string, body, v any) error {
+	base := g.BaseURL
+	if base == "" {
+		base = DefaultGitHubAPI
+	}
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(base, "/")+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := g.Client
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound:
+		return ErrUnauthorized
+	case resp.StatusCode/100 != 2:
+		return fmt.Errorf("%w: %s %s answered %d", ErrUnavailable, method, req.URL.Path, resp.StatusCode)
+	case v == nil:
+		return nil
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 8<<20)).Decode(v); err != nil {
+		return fmt.Errorf("%w: decoding %s: %w", ErrUnavailable, req.URL.Path, err)
+	}
+	return nil
+}
+
+// VerifySignature reports whether signature, the X-Hub-Signature-256
+// header of a webhook delivery, is the HMAC-SHA256 of body under secret.
+func VerifySignature(secret string, body []byte, signature string) bool {
+	hexSum, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(hexSum)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
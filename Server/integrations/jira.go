@@ -0,0 +1,315 @@
+package integrations
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"starttech-server/model"
+)
+
+// jiraIssue is an issue of a Jira export, whichever format it came in.
+type jiraIssue struct {
+	key, summary, description string
+	project                   string
+	status                    string
+	// rank orders statuses: 0 for to do, 1 for in progress and 2 for
+	// done.
+	rank     int
+	priority model.Priority
+	due      *time.Time
+	labels   []string
+}
+
+// jiraSearch is the part of a Jira REST API search response that is
+// imported.
+type jiraSearch struct {
+	Issues []struct {
+		Key    string `json:"key"`
+		Fields struct {
+			Summary     string          `json:"summary"`
+			Description json.RawMessage `json:"description"`
+			Status      struct {
+				Name           string `json:"name"`
+				StatusCategory struct {
+					Key string `json:"key"`
+				} `json:"statusCategory"`
+			} `json:"status"`
+			Priority *struct {
+				Name string `json:"name"`
+			} `json:"priority"`
+			DueDate string   `json:"duedate"`
+			Labels  []string `json:"labels"`
+			Project struct {
+				Key  string `json:"key"`
+				Name string `json:"name"`
+			} `json:"project"`
+		} `json:"fields"`
+	} `json:"issues"`
+}
+
+// ParseJira reads the issues of a Jira export: the JSON answer of the REST
+// API's search, or with isCSV set, the CSV that the issue navigator exports.
+// Each Jira project becomes a board whose columns are the statuses of its
+// issues, to do first and done last. Issues become cards with their
+// priority, due date and labels.
+func ParseJira(r io.Reader, isCSV bool) ([]model.Board, error) {
+	read := readJiraJSON
+	if isCSV {
+		read = readJiraCSV
+	}
+	issues, err := read(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(issues) == 0 {
+		return nil, exportError("holds no issues")
+	}
+	if len(issues) > model.MaxBoardImportCards {
+		return nil, exportError("holds %d issues; an import may hold at most %d", len(issues), model.MaxBoardImportCards)
+	}
+
+	var projects []string
+	byProject := map[string][]jiraIssue{}
+	for _, is := range issues {
+		if _, ok := byProject[is.project]; !ok {
+			projects = append(projects, is.project)
+		}
+		byProject[is.project] = append(byProject[is.project], is)
+	}
+	boards := make([]model.Board, 0, len(projects))
+	for _, name := range projects {
+		b, err := jiraBoard(name, byProject[name])
+		if err != nil {
+			return nil, err
+		}
+		boards = append(boards, b)
+	}
+	return boards, nil
+}
+
+// jiraBoard turns the issues of the Jira project named name into a board.
+func jiraBoard(name string, issues []jiraIssue) (model.Board, error) {
+	type status struct {
+		name string
+		rank int
+	}
+	var statuses []status
+	for _, is := range issues {
+		if !slices.ContainsFunc(statuses, func(s status) bool { return s.name == is.status }) {
+			statuses = append(statuses, status{is.status, is.rank})
+		}
+	}
+	slices.SortStableFunc(statuses, func(a, b status) int { return cmp.Compare(a.rank, b.rank) })
+	names := make([]string, len(statuses))
+	done := make([]bool, len(statuses))
+	for i, s := range statuses {
+		names[i], done[i] = s.name, s.rank == 2
+	}
+	columns, keys, err := boardColumns(name, names, done)
+	if err != nil {
+		return model.Board{}, err
+	}
+
+	b := model.Board{Name: name, Columns: columns, Cards: make([]model.Card, 0, len(issues))}
+	for _, is := range issues {
+		i := slices.IndexFunc(statuses, func(s status) bool { return s.name == is.status })
+		card := model.Card{
+			Title:       is.summary,
+			Description: is.description,
+			Status:      keys[i],
+			Priority:    is.priority,
+			DueDate:     is.due,
+		}
+		if is.key != "" {
+			card.Description = strings.TrimSpace(card.Description + "\n\nImported from Jira: " + is.key)
+		}
+		for _, l := range is.labels {
+			if l = strings.TrimSpace(l); l != "" {
+				card.Labels = append(card.Labels, clip(l, model.MaxTagNameLen))
+			}
+		}
+		b.Cards = append(b.Cards, card)
+	}
+	return b, nil
+}
+
+func readJiraJSON(r io.Reader) ([]jiraIssue, error) {
+	var res jiraSearch
+	if err := json.NewDecoder(r).Decode(&res); err != nil {
+		if isTooBig(err) {
+			return nil, err
+		}
+		return nil, exportError("is not a Jira search result")
+	}
+	issues := make([]jiraIssue, len(res.Issues))
+	for i, is := range res.Issues {
+		f := is.Fields
+		issues[i] = jiraIssue{
+			key:         is.Key,
+			summary:     f.Summary,
+			description: jiraText(f.Description),
+			project:     cmp.Or(f.Project.Name, f.Project.Key, "Jira"),
+			status:      cmp.Or(f.Status.Name, "Open"),
+			labels:      f.Labels,
+		}
+		switch f.Status.StatusCategory.Key {
+		case "new":
+			issues[i].rank = 0
+		case "done":
+			issues[i].rank = 2
+		default:
+			issues[i].rank = 1
+		}
+		if f.Priority != nil {
+			issues[i].priority = jiraPriority(f.Priority.Name)
+		}
+		issues[i].due = jiraDate(f.DueDate)
+	}
+	return issues, nil
+}
+
+// jiraText returns the plain text of a description, which version 2 of the
+// REST API gives as a string and version 3 as an Atlassian Document
+// Format tree.
+func jiraText(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	type node struct {
+		Type    string `json:"type"`
+		Text    string `json:"text"`
+		Content []node `json:"content"`
+	}
+	var doc node
+	if json.Unmarshal(raw, &doc) != nil {
+		return ""
+	}
+	var b strings.Builder
+	var walk func(n node)
+	walk = func(n node) {
+		b.WriteString(n.Text)
+		if n.Type == "hardBreak" {
+			b.WriteByte('\n')
+		}
+		for _, c := range n.Content {
+			walk(c)
+		}
+		switch n.Type {
+		case "paragraph", "heading", "codeBlock", "listItem":
+			b.WriteByte('\n')
+		}
+	}
+	walk(doc)
+	return strings.TrimSpace(b.String())
+}
+
+// readJiraCSV reads the CSV export of the issue navigator. Its header names
+// fields in English, and repeats a multi-valued field such as Labels once
+// per value.
+func readJiraCSV(r io.Reader) ([]jiraIssue, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		if isTooBig(err) {
+			return nil, err
+		}
+		return nil, exportError("is not a Jira CSV export")
+	}
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	columns := map[string][]int{}
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(h))
+		columns[h] = append(columns[h], i)
+	}
+	if columns["summary"] == nil {
+		return nil, exportError("must include a Summary column")
+	}
+
+	var issues []jiraIssue
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if isTooBig(err) {
+				return nil, err
+			}
+			return nil, exportError("is not valid CSV")
+		}
+		cell := func(name string) string {
+			for _, i := range columns[name] {
+				if i < len(row) && strings.TrimSpace(row[i]) != "" {
+					return strings.TrimSpace(row[i])
+				}
+			}
+			return ""
+		}
+		is := jiraIssue{
+			key:         cell("issue key"),
+			summary:     cell("summary"),
+			description: cell("description"),
+			project:     cmp.Or(cell("project name"), cell("project key"), "Jira"),
+			status:      cmp.Or(cell("status"), "Open"),
+			priority:    jiraPriority(cell("priority")),
+			due:         jiraDate(cmp.Or(cell("due date"), cell("due"))),
+			rank:        1,
+		}
+		resolution := cell("resolution")
+		switch category := strings.ToLower(cell("status category")); {
+		case category == "done", category == "" && resolution != "" && !strings.EqualFold(resolution, "unresolved"),
+			category == "" && doneList.MatchString(is.status):
+			is.rank = 2
+		case category == "to do", category == "" && todoStatus.MatchString(is.status):
+			is.rank = 0
+		}
+		for _, i := range columns["labels"] {
+			if i < len(row) && strings.TrimSpace(row[i]) != "" {
+				is.labels = append(is.labels, row[i])
+			}
+		}
+		issues = append(issues, is)
+		if len(issues) > model.MaxBoardImportCards {
+			break
+		}
+	}
+	return issues, nil
+}
+
+// todoStatus matches the statuses Jira gives issues nobody has started.
+var todoStatus = regexp.MustCompile(`(?i)^(to do|open|backlog|new|selected for development)$`)
+
+// jiraPriority maps the priorities of Jira's default schemes.
+func jiraPriority(name string) model.Priority {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "highest", "blocker":
+		return model.PriorityUrgent
+	case "high", "critical", "major":
+		return model.PriorityHigh
+	case "medium":
+		return model.PriorityMedium
+	case "low", "lowest", "minor", "trivial":
+		return model.PriorityLow
+	}
+	return ""
+}
+
+// jiraDate parses a due date as the REST API or a CSV export writes it.
+// Dates that do not parse are dropped.
+func jiraDate(s string) *time.Time {
+	for _, layout := range []string{"2006-01-02", "02/Jan/06 3:04 PM", "02/Jan/06", time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"starttech-server/events"
+	"starttech-server/jobs"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// KindPush is the kind of the jobs that push a task's state to its issue.
+const KindPush = "github.push"
+
+const queueSize = 256
+
+// Pusher implements events.Publisher. It watches for tasks imported from
+// GitHub issues whose completion no longer matches their issue, and queues
+// a job to close or reopen the issue, which Push carries out.
+type Pusher struct {
+	links  storage.GitHubStore
+	tasks  storage.TaskStore
+	github *GitHub
+	jobs   *jobs.Queue
+	queue  chan model.Task
+}
+
+// NewPusher returns a Pusher that pushes through github on queue; call
+// Register to handle its jobs there, and Run to start watching.
+func NewPusher(links storage.GitHubStore, tasks storage.TaskStore, github *GitHub, queue *jobs.Queue) *Pusher {
+	return &Pusher{links: links, tasks: tasks, github: github, jobs: queue, queue: make(chan model.Task, queueSize)}
+}
+
+// Register makes the job queue push with p.
+func (p *Pusher) Register() {
+	p.jobs.Handle(KindPush, 0, p.Push)
+}
+
+// Publish hands the tasks of project task events to Run.
+func (p *Pusher) Publish(e events.Event) {
+	if e.Type != events.TaskUpdated && e.Type != events.TaskRestored {
+		return
+	}
+	t, ok := e.Data.(model.Task)
+	if !ok || t.ProjectID == nil {
+		return
+	}
+	select {
+	case p.queue <- t:
+	default:
+		slog.Warn("integrations: dropping task, queue is full", "task_id", t.ID)
+	}
+}
+
+// Run queues pushes for published tasks until ctx is cancelled.
+func (p *Pusher) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-p.queue:
+			p.enqueue(ctx, t)
+		}
+	}
+}
+
+// pushJob is the payload of KindPush jobs.
+type pushJob struct {
+	TaskID string `json:"task_id"`
+}
+
+// enqueue queues a push for t if it came from an issue whose state it no
+// longer matches.
+func (p *Pusher) enqueue(ctx context.Context, t model.Task) {
+	l, err := p.links.GetIssueLink(ctx, t.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		slog.Error("integrations: loading issue link", "task_id", t.ID, "err", err)
+		return
+	}
+	if l.State == model.IssueState(t) {
+		return
+	}
+	if err := p.jobs.Enqueue(ctx, KindPush, pushJob{t.ID}); err != nil {
+		slog.Error("integrations: queueing push", "task_id", t.ID, "err", err)
+	}
+}
+
+// Push is the handler of KindPush jobs. It sets the state of the task's
+// issue from the task as it is now, unless they already agree or the task
+// has since left the linked project or been deleted.
+func (p *Pusher) Push(ctx context.Context, j model.Job) error {
+	var pj pushJob
+	if err := jobs.Decode(j, &pj); err != nil {
+		return err
+	}
+	t, err := p.tasks.GetTask(ctx, pj.TaskID)
+	if errors.Is(err, storage.ErrNotFound) || err == nil && t.DeletedAt != nil {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("loading task: %w", err)
+	}
+	l, err := p.links.GetIssueLink(ctx, t.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("loading issue link: %w", err)
+	}
+	state := model.IssueState(t)
+	if l.State == state || t.ProjectID == nil || *t.ProjectID != l.ProjectID {
+		return nil
+	}
+	link, err := p.links.GetGitHubLink(ctx, l.ProjectID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("loading github link: %w", err)
+	}
+	if err := p.github.SetIssueState(ctx, link.Token, link.Repo, l.Number, state); err != nil {
+		err = fmt.Errorf("setting state of %s#%d: %w", link.Repo, l.Number, err)
+		// A refused token stays refused until the project is linked again.
+		if errors.Is(err, ErrUnauthorized) {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+	l.State = state
+	if err := p.links.SaveIssueLink(ctx, l); err != nil {
+		return fmt.Errorf("saving issue link: %w", err)
+	}
+	slog.Debug("issue state pushed", "repo", link.Repo, "number", l.Number, "state", state)
+	return nil
+}
@@ -0,0 +1,304 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/jobs"
+	"starttech-server/model"
+	"starttech-server/storage"
+	"starttech-server/tracing"
+)
+
+// KindSlackPost is the kind of the jobs that post messages to Slack.
+const KindSlackPost = "slack.post"
+
+// HeaderSlackTimestamp and HeaderSlackSignature are sent with every slash
+// command from Slack.
+const (
+	HeaderSlackTimestamp = "X-Slack-Request-Timestamp"
+	HeaderSlackSignature = "X-Slack-Signature"
+)
+
+// SlackMaxAge bounds how old a signed slash command may be, so a captured
+// one cannot be replayed later.
+const SlackMaxAge = 5 * time.Minute
+
+// SlackEvents lists the event types a Slack link may post.
+var SlackEvents = []string{
+	string(events.TaskCreated), string(events.TaskUpdated), string(events.TaskDeleted),
+	string(events.TaskRestored), string(events.CommentCreated),
+}
+
+// SlackNotifier implements events.Publisher. It turns the task events of
+// projects linked to Slack into messages and queues a job to post each to
+// the project's channel, which Post carries out.
+type SlackNotifier struct {
+	links    storage.SlackStore
+	tasks    storage.TaskStore
+	projects storage.ProjectStore
+	jobs     *jobs.Queue
+	queue    chan events.Event
+
+	// Client posts the messages. The default times out after 10 seconds,
+	// does not follow redirects or use a proxy, and only connects to
+	// public addresses.
+	Client *http.Client
+}
+
+// NewSlackNotifier returns a SlackNotifier that posts on queue; call
+// Register to handle its jobs there, and Run to start watching.
+func NewSlackNotifier(links storage.SlackStore, tasks storage.TaskStore, projects storage.ProjectStore, queue *jobs.Queue) *SlackNotifier {
+	return &SlackNotifier{
+		links:    links,
+		tasks:    tasks,
+		projects: projects,
+		jobs:     queue,
+		queue:    make(chan events.Event, queueSize),
+		Client:   newSlackClient(),
+	}
+}
+
+// errPrivateAddress is returned for a post to an address that is not
+// public.
+var errPrivateAddress = errors.New("integrations: address is not public")
+
+// newSlackClient returns the default Client of a SlackNotifier. Like the
+// webhook dispatcher's, it checks the address once resolved, as the
+// connection is made, so that a webhook URL cannot reach the server's own
+// network by resolving to a private address or redirecting to one.
+func newSlackClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: checkAddress}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// checkAddress refuses a connection to a loopback, private or link-local
+// address.
+func checkAddress(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil || !model.PublicAddr(ap.Addr()) {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+// Register makes the job queue post with n.
+func (n *SlackNotifier) Register() {
+	n.jobs.Handle(KindSlackPost, 0, n.Post)
+}
+
+// Publish hands the events in SlackEvents to Run.
+func (n *SlackNotifier) Publish(e events.Event) {
+	if !slices.Contains(SlackEvents, string(e.Type)) {
+		return
+	}
+	select {
+	case n.queue <- e:
+	default:
+		slog.Warn("integrations: dropping slack event, queue is full", "type", e.Type)
+	}
+}
+
+// Run queues messages for published events until ctx is cancelled.
+func (n *SlackNotifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-n.queue:
+			n.enqueue(tracing.WithRemoteParent(ctx, e.TraceParent), e)
+		}
+	}
+}
+
+// slackJob is the payload of KindSlackPost jobs. The text is written when
+// the event happens, so it describes the task as it was then.
+type slackJob struct {
+	ProjectID string `json:"project_id"`
+	Text      string `json:"text"`
+}
+
+// enqueue queues a message about e if its task belongs to a project whose
+// Slack link subscribes to it.
+func (n *SlackNotifier) enqueue(ctx context.Context, e events.Event) {
+	var t model.Task
+	var comment *model.Comment
+	var err error
+	switch d := e.Data.(type) {
+	case model.Task:
+		t = d
+	case events.Deleted:
+		t, err = n.tasks.GetTask(ctx, d.ID)
+	case model.Comment:
+		comment = &d
+		t, err = n.tasks.GetTask(ctx, d.TaskID)
+	default:
+		return
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		slog.Error("integrations: loading task for slack", "type", e.Type, "err", err)
+		return
+	}
+	if t.ProjectID == nil {
+		return
+	}
+	l, err := n.links.GetSlackLink(ctx, *t.ProjectID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		slog.Error("integrations: loading slack link", "project_id", *t.ProjectID, "err", err)
+		return
+	}
+	if !l.Subscribes(string(e.Type)) {
+		return
+	}
+	p, err := n.projects.GetProject(ctx, l.ProjectID)
+	if err != nil {
+		slog.Error("integrations: loading project for slack", "project_id", l.ProjectID, "err", err)
+		return
+	}
+	text := slackText(e.Type, p, t, comment)
+	if err := n.jobs.Enqueue(ctx, KindSlackPost, slackJob{l.ProjectID, text}); err != nil {
+		slog.Error("integrations: queueing slack message", "project_id", l.ProjectID, "err", err)
+	}
+}
+
+// slackText describes an event of type typ about t in project p, in Slack's
+// markup.
+func slackText(typ events.Type, p model.Project, t model.Task, c *model.Comment) string {
+	title := SlackEscape(t.Title)
+	var text string
+	switch typ {
+	case events.TaskCreated:
+		text = "New task: *" + title + "*"
+	case events.TaskUpdated:
+		text = "Task updated: *" + title + "*"
+		if t.Completed {
+			text += " (completed)"
+		}
+	case events.TaskDeleted:
+		text = "Task deleted: *" + title + "*"
+	case events.TaskRestored:
+		text = "Task restored: *" + title + "*"
+	case events.CommentCreated:
+		text = "New comment by " + SlackEscape(c.Username) + " on *" + title + "*:\n>" +
+			strings.ReplaceAll(SlackEscape(truncate(c.Body, 300)), "\n", "\n>")
+	}
+	return "[" + SlackEscape(p.Name) + "] " + text
+}
+
+// SlackEscape escapes the characters Slack reserves for its markup.
+func SlackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// Post is the handler of KindSlackPost jobs. It posts the message to the
+// incoming webhook of the project's link, unless the project has been
+// unlinked since.
+func (n *SlackNotifier) Post(ctx context.Context, j model.Job) error {
+	var sj slackJob
+	if err := jobs.Decode(j, &sj); err != nil {
+		return err
+	}
+	l, err := n.links.GetSlackLink(ctx, sj.ProjectID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("loading slack link: %w", err)
+	}
+	body, err := json.Marshal(map[string]string{"text": sj.Text})
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	switch {
+	case resp.StatusCode/100 == 2:
+		return nil
+	// Slack answers these for a webhook that was revoked or whose channel
+	// is gone, which will not change until the project is linked again.
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return jobs.Permanent(fmt.Errorf("slack answered %d: %s", resp.StatusCode, msg))
+	default:
+		return fmt.Errorf("slack answered %d: %s", resp.StatusCode, msg)
+	}
+}
+
+// VerifySlackSignature reports whether signature, the X-Slack-Signature
+// header of a request from Slack sent at timestamp, its
+// X-Slack-Request-Timestamp, is "v0=" and the hex HMAC-SHA256 of
+// "v0:<timestamp>:<body>" under secret, and was sent within SlackMaxAge of
+// now.
+func VerifySlackSignature(secret, timestamp string, body []byte, signature string, now time.Time) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > SlackMaxAge || age < -SlackMaxAge {
+		return false
+	}
+	hexSum, ok := strings.CutPrefix(signature, "v0=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(hexSum)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
@@ -0,0 +1,141 @@
+package integrations
+
+import (
+	"cmp"
+	"encoding/json"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"starttech-server/model"
+)
+
+// trelloBoard is the part of a Trello board's JSON export that is
+// imported. Archived lists and cards are marked closed.
+type trelloBoard struct {
+	Name       string            `json:"name"`
+	Desc       string            `json:"desc"`
+	Lists      []trelloList      `json:"lists"`
+	Cards      []trelloCard      `json:"cards"`
+	Checklists []trelloChecklist `json:"checklists"`
+}
+
+type trelloList struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Closed bool    `json:"closed"`
+	Pos    float64 `json:"pos"`
+}
+
+type trelloCard struct {
+	ID       string        `json:"id"`
+	Name     string        `json:"name"`
+	Desc     string        `json:"desc"`
+	IDList   string        `json:"idList"`
+	Closed   bool          `json:"closed"`
+	Pos      float64       `json:"pos"`
+	Due      *time.Time    `json:"due"`
+	ShortURL string        `json:"shortUrl"`
+	Labels   []trelloLabel `json:"labels"`
+}
+
+type trelloLabel struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type trelloChecklist struct {
+	IDCard     string            `json:"idCard"`
+	Pos        float64           `json:"pos"`
+	CheckItems []trelloCheckItem `json:"checkItems"`
+}
+
+type trelloCheckItem struct {
+	Name  string  `json:"name"`
+	State string  `json:"state"`
+	Pos   float64 `json:"pos"`
+}
+
+// doneList matches the names boards commonly give the list of finished
+// cards.
+var doneList = regexp.MustCompile(`(?i)\b(done|complete|completed|finished|closed|resolved|shipped)\b`)
+
+// ParseTrello reads the JSON export of a Trello board, as its menu's
+// "Print, export and share" offers it. Each open list becomes a column, in
+// board order; lists named like "Done" hold finished cards. Open cards
+// become cards with their labels, due date and checklist items. Labels
+// without a name are named after their color.
+func ParseTrello(r io.Reader) ([]model.Board, error) {
+	var tb trelloBoard
+	if err := json.NewDecoder(r).Decode(&tb); err != nil {
+		if isTooBig(err) {
+			return nil, err
+		}
+		return nil, exportError("is not a Trello board export")
+	}
+	if tb.Name == "" || tb.Lists == nil {
+		return nil, exportError("is not a Trello board export")
+	}
+
+	lists := slices.DeleteFunc(tb.Lists, func(l trelloList) bool { return l.Closed })
+	slices.SortStableFunc(lists, func(a, b trelloList) int { return cmp.Compare(a.Pos, b.Pos) })
+	names := make([]string, len(lists))
+	done := make([]bool, len(lists))
+	order := make(map[string]int, len(lists))
+	for i, l := range lists {
+		names[i], done[i], order[l.ID] = l.Name, doneList.MatchString(l.Name), i
+	}
+	columns, keys, err := boardColumns(tb.Name, names, done)
+	if err != nil {
+		return nil, err
+	}
+
+	// Checklists in the order the card shows them, then their items.
+	checklists := slices.Clone(tb.Checklists)
+	slices.SortStableFunc(checklists, func(a, b trelloChecklist) int { return cmp.Compare(a.Pos, b.Pos) })
+	items := map[string][]model.ChecklistItemInput{}
+	for _, cl := range checklists {
+		slices.SortStableFunc(cl.CheckItems, func(a, b trelloCheckItem) int { return cmp.Compare(a.Pos, b.Pos) })
+		for _, it := range cl.CheckItems {
+			title := clip(strings.TrimSpace(it.Name), model.MaxChecklistTitleLen)
+			if title != "" && len(items[cl.IDCard]) < model.MaxChecklistItems {
+				items[cl.IDCard] = append(items[cl.IDCard], model.ChecklistItemInput{Title: title, Done: it.State == "complete"})
+			}
+		}
+	}
+
+	cards := slices.DeleteFunc(tb.Cards, func(c trelloCard) bool {
+		_, ok := order[c.IDList]
+		return c.Closed || !ok
+	})
+	slices.SortStableFunc(cards, func(a, b trelloCard) int {
+		return cmp.Or(cmp.Compare(order[a.IDList], order[b.IDList]), cmp.Compare(a.Pos, b.Pos))
+	})
+	b := model.Board{Name: tb.Name, Description: tb.Desc, Columns: columns, Cards: make([]model.Card, 0, len(cards))}
+	for _, c := range cards {
+		card := model.Card{
+			Title:       c.Name,
+			Description: c.Desc,
+			Status:      keys[order[c.IDList]],
+			DueDate:     c.Due,
+			Checklist:   items[c.ID],
+		}
+		if c.ShortURL != "" {
+			card.Description = strings.TrimSpace(card.Description + "\n\nImported from Trello: " + c.ShortURL)
+		}
+		for _, l := range c.Labels {
+			name := cmp.Or(strings.TrimSpace(l.Name), l.Color)
+			if name != "" {
+				card.Labels = append(card.Labels, clip(name, model.MaxTagNameLen))
+			}
+		}
+		b.Cards = append(b.Cards, card)
+	}
+	boards := []model.Board{b}
+	if err := checkCards(boards); err != nil {
+		return nil, err
+	}
+	return boards, nil
+}
@@ -0,0 +1,324 @@
+// Package jobs runs deferrable work, such as sending emails and webhook
+// deliveries, on a pool of in-process workers. Jobs are kept in the store,
+// so they survive restarts and are shared by every instance of the server.
+// A job that fails is retried with exponential backoff, and one that runs
+// out of attempts is kept as dead until someone retries or discards it.
+// Each attempt runs in a span continuing the trace the job was queued in.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+
+	"starttech-server/metrics"
+	"starttech-server/model"
+	"starttech-server/storage"
+	"starttech-server/tracing"
+)
+
+const (
+	// Retries wait baseBackoff, doubling after every failure up to
+	// maxBackoff.
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+	// saveTimeout bounds recording the outcome of a job once the server is
+	// shutting down.
+	saveTimeout = 5 * time.Second
+)
+
+var jobsProcessed = metrics.NewCounterVec("jobs_processed_total",
+	"Background job attempts, by kind and result: succeeded, retrying or dead.", "kind", "result")
+
+// Handler does the work of a job. It is given the job with Attempts
+// counting the current attempt, and an error makes the job be retried,
+// unless it is Permanent or the job is out of attempts.
+type Handler func(ctx context.Context, job model.Job) error
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as one that retrying cannot fix, such as a payload
+// that does not decode, so the job is dead at once.
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+type kind struct {
+	handler     Handler
+	maxAttempts int
+}
+
+type periodic struct {
+	kind  string
+	every time.Duration
+	last  time.Time
+}
+
+// Queue hands the jobs in its store to its handlers. Kinds are registered
+// with Handle, and periodic jobs with Every; a job of a kind that has no
+// handler is dead at once.
+type Queue struct {
+	store    storage.JobStore
+	mu       sync.Mutex
+	kinds    map[string]kind
+	periodic []*periodic
+	wake     chan struct{}
+
+	// Workers bounds the jobs run at once; it defaults to 4.
+	Workers int
+	// MaxAttempts is the default number of attempts of a kind; it defaults
+	// to 5.
+	MaxAttempts int
+	// Lease is how long a worker holds a job before another may take it
+	// over, and so the most a single attempt may take. It defaults to five
+	// minutes.
+	Lease time.Duration
+	// Interval between checks for jobs that are due; it defaults to a
+	// second. Jobs enqueued by this instance are picked up at once.
+	Interval time.Duration
+}
+
+// NewQueue returns a Queue with no kinds; call Run to start working.
+func NewQueue(store storage.JobStore) *Queue {
+	return &Queue{
+		store:       store,
+		kinds:       map[string]kind{},
+		wake:        make(chan struct{}, 1),
+		Workers:     4,
+		MaxAttempts: 5,
+		Lease:       5 * time.Minute,
+		Interval:    time.Second,
+	}
+}
+
+// Handle registers h to run the jobs of kind, each for up to maxAttempts
+// attempts, or q.MaxAttempts if that is 0.
+func (q *Queue) Handle(kindName string, maxAttempts int, h Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.kinds[kindName] = kind{handler: h, maxAttempts: maxAttempts}
+}
+
+// Every enqueues a job of kind, with no payload, once per interval. Each
+// interval's job has a fixed ID, so with several instances running it
+// still runs once.
+func (q *Queue) Every(kindName string, interval time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.periodic = append(q.periodic, &periodic{kind: kindName, every: interval})
+}
+
+// Enqueue adds a job of kind whose payload is v encoded as JSON, due now.
+func (q *Queue) Enqueue(ctx context.Context, kindName string, v any) error {
+	return q.enqueue(ctx, "", kindName, v)
+}
+
+// EnqueueOnce is Enqueue for a job with the given ID. It does nothing if
+// that job exists already, even if it has since finished.
+func (q *Queue) EnqueueOnce(ctx context.Context, id, kindName string, v any) error {
+	err := q.enqueue(ctx, id, kindName, v)
+	if errors.Is(err, storage.ErrConflict) {
+		return nil
+	}
+	return err
+}
+
+func (q *Queue) enqueue(ctx context.Context, id, kindName string, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("jobs: encoding %s payload: %w", kindName, err)
+	}
+	maxAttempts := q.kind(kindName).maxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = q.MaxAttempts
+	}
+	now := time.Now().UTC()
+	j := model.Job{
+		ID:          id,
+		Kind:        kindName,
+		Payload:     payload,
+		Status:      model.JobPending,
+		MaxAttempts: maxAttempts,
+		RunAt:       now,
+		CreatedAt:   now,
+		TraceParent: tracing.FromContext(ctx).TraceParent(),
+	}
+	if err := q.store.CreateJob(ctx, &j); err != nil {
+		return err
+	}
+	q.poke()
+	return nil
+}
+
+func (q *Queue) kind(name string) kind {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.kinds[name]
+}
+
+// poke wakes Run to look for work.
+func (q *Queue) poke() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run works until ctx is cancelled, and then waits for the jobs in hand.
+// Those are put back as they were, to be taken up again on the next start.
+func (q *Queue) Run(ctx context.Context) {
+	ticker := time.NewTicker(q.Interval)
+	defer ticker.Stop()
+	slots := make(chan struct{}, max(q.Workers, 1))
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		now := time.Now().UTC()
+		q.schedule(ctx, now)
+		q.dispatch(ctx, now, slots, &wg)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// schedule enqueues the periodic jobs of the interval now falls in.
+func (q *Queue) schedule(ctx context.Context, now time.Time) {
+	q.mu.Lock()
+	periodic := slices.Clone(q.periodic)
+	q.mu.Unlock()
+	for _, p := range periodic {
+		window := now.Truncate(p.every)
+		if window.Equal(p.last) {
+			continue
+		}
+		id := fmt.Sprintf("%s@%d", p.kind, window.Unix())
+		if err := q.EnqueueOnce(ctx, id, p.kind, nil); err != nil {
+			if ctx.Err() == nil {
+				slog.Error("jobs: scheduling periodic job", "kind", p.kind, "err", err)
+			}
+			continue
+		}
+		p.last = window
+	}
+}
+
+// dispatch claims as many due jobs as there are free workers and starts
+// them.
+func (q *Queue) dispatch(ctx context.Context, now time.Time, slots chan struct{}, wg *sync.WaitGroup) {
+	free := cap(slots) - len(slots)
+	if free == 0 {
+		return
+	}
+	due, err := q.store.ClaimJobs(ctx, now, q.Lease, free)
+	if err != nil && ctx.Err() == nil {
+		slog.Error("jobs: claiming jobs", "err", err)
+	}
+	for _, j := range due {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+				q.poke()
+			}()
+			q.run(ctx, j)
+		}()
+	}
+}
+
+// run attempts j once and records the outcome.
+func (q *Queue) run(ctx context.Context, j model.Job) {
+	j.Attempts++
+	ctx, span := tracing.Start(tracing.WithRemoteParent(ctx, j.TraceParent), "job "+j.Kind, tracing.KindConsumer,
+		slog.String("job.id", j.ID),
+		slog.String("job.kind", j.Kind),
+		slog.Int("job.attempt", j.Attempts),
+	)
+	defer span.End()
+	err := q.attempt(ctx, j)
+	span.SetError(err)
+	now := time.Now().UTC()
+	j.LockedUntil = nil
+	result := "succeeded"
+	switch {
+	case err == nil:
+		j.Status, j.LastError, j.CompletedAt = model.JobSucceeded, "", &now
+	case ctx.Err() != nil:
+		// Interrupted by shutdown, which is no fault of the job's.
+		j.Attempts--
+		j.Status, j.RunAt = model.JobPending, now
+		result = ""
+	case errors.As(err, new(permanentError)) || j.Attempts >= j.MaxAttempts:
+		j.Status, j.LastError, j.CompletedAt = model.JobDead, err.Error(), &now
+		result = "dead"
+		slog.ErrorContext(ctx, "jobs: job is dead", "job_id", j.ID, "kind", j.Kind, "attempts", j.Attempts, "err", err)
+	default:
+		j.Status, j.LastError, j.RunAt = model.JobPending, err.Error(), now.Add(Backoff(j.Attempts))
+		result = "retrying"
+		slog.WarnContext(ctx, "jobs: job failed, will retry", "job_id", j.ID, "kind", j.Kind, "attempts", j.Attempts, "err", err)
+	}
+	if result != "" {
+		jobsProcessed.With(j.Kind, result).Inc()
+	}
+
+	saveCtx := ctx
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		saveCtx, cancel = context.WithTimeout(context.WithoutCancel(ctx), saveTimeout)
+		defer cancel()
+	}
+	if err := q.store.UpdateJob(saveCtx, &j); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		slog.ErrorContext(ctx, "jobs: recording attempt", "job_id", j.ID, "err", err)
+	}
+}
+
+// attempt calls the handler of j's kind, turning a panic into an error.
+func (q *Queue) attempt(ctx context.Context, j model.Job) (err error) {
+	k := q.kind(j.Kind)
+	if k.handler == nil {
+		return Permanent(fmt.Errorf("no handler for jobs of kind %q", j.Kind))
+	}
+	ctx, cancel := context.WithTimeout(ctx, q.Lease)
+	defer cancel()
+	defer func() {
+		if v := recover(); v != nil {
+			slog.ErrorContext(ctx, "jobs: handler panicked", "job_id", j.ID, "kind", j.Kind, "panic", v, "stack", string(debug.Stack()))
+			err = fmt.Errorf("handler panicked: %v", v)
+		}
+	}()
+	return k.handler(ctx, j)
+}
+
+// Backoff is the wait before the retry that follows the given number of
+// failed attempts.
+func Backoff(attempts int) time.Duration {
+	wait := baseBackoff
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, maxBackoff)
+}
+
+// Decode unmarshals the payload of j into v, failing permanently if it
+// does not fit.
+func Decode(j model.Job, v any) error {
+	if err := json.Unmarshal(j.Payload, v); err != nil {
+		return Permanent(fmt.Errorf("decoding %s payload: %w", j.Kind, err))
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+// Package mailin reads inbound email: the recipients, the subject, a plain
+// text body and the attached files of an RFC 5322 message. Multipart
+// messages are walked to any depth; base64 and quoted-printable parts are
+// decoded, and UTF-8, US-ASCII and ISO-8859-1 text is read. Text in other
+// charsets is kept with its invalid bytes replaced.
+package mailin
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxDepth bounds how deeply multiparts may nest.
+const maxDepth = 10
+
+// Message is the part of an email that becomes a task.
+type Message struct {
+	// Recipients lists the addresses of the To, Cc, Delivered-To and
+	// X-Original-To headers, in that order.
+	Recipients []string
+	From       string
+	Subject    string
+	// Text is the first text/plain part, or the text of the first
+	// text/html part when there is none.
+	Text        string
+	Attachments []Attachment
+}
+
+// Attachment is a file attached to a message.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+var decoder = mime.WordDecoder{CharsetReader: charsetReader}
+
+// Read parses the message read from r.
+func Read(r io.Reader) (Message, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return Message{}, fmt.Errorf("reading message: %w", err)
+	}
+	var m Message
+	for _, h := range []string{"To", "Cc", "Delivered-To", "X-Original-To"} {
+		list, err := msg.Header.AddressList(h)
+		if err != nil {
+			continue
+		}
+		for _, a := range list {
+			m.Recipients = append(m.Recipients, a.Address)
+		}
+	}
+	if from, err := mail.ParseAddress(msg.Header.Get("From")); err == nil {
+		m.From = from.Address
+	}
+	m.Subject = decodeHeader(msg.Header.Get("Subject"))
+
+	var w walker
+	if err := w.part(msg.Header, msg.Body, 0); err != nil {
+		return Message{}, err
+	}
+	m.Text = w.plain
+	if m.Text == "" {
+		m.Text = htmlText(w.html)
+	}
+	m.Text = strings.TrimSpace(strings.ReplaceAll(m.Text, "\r\n", "\n"))
+	m.Attachments = w.files
+	return m, nil
+}
+
+// header is the part of a MIME header walker reads, which mail.Header and
+// textproto.MIMEHeader both provide.
+type header interface {
+	Get(key string) string
+}
+
+// walker collects the text and files of the parts of a message.
+type walker struct {
+	plain, html string
+	files       []Attachment
+}
+
+func (w *walker) part(h header, body io.Reader, depth int) error {
+	if depth > maxDepth {
+		return errors.New("reading message: multiparts nest too deeply")
+	}
+	ct, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		ct, params = "text/plain", map[string]string{"charset": "us-ascii"}
+	}
+	if strings.HasPrefix(ct, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("reading message part: %w", err)
+			}
+			if err := w.part(p.Header, p, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decodeTransfer(h.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return fmt.Errorf("decoding message part: %w", err)
+	}
+	disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	filename := decodeHeader(dparams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+	switch {
+	case disposition == "attachment" || filename != "":
+		w.files = append(w.files, Attachment{Filename: filename, ContentType: ct, Data: data})
+	case ct == "text/plain" && w.plain == "":
+		w.plain = decodeText(data, params["charset"])
+	case ct == "text/html" && w.html == "":
+		w.html = decodeText(data, params["charset"])
+	}
+	return nil
+}
+
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+func decodeHeader(s string) string {
+	if d, err := decoder.DecodeHeader(s); err == nil {
+		s = d
+	}
+	return strings.TrimSpace(strings.ToValidUTF8(s, "�"))
+}
+
+func decodeText(data []byte, charset string) string {
+	if r, err := charsetReader(charset, bytes.NewReader(data)); err == nil {
+		if b, err := io.ReadAll(r); err == nil {
+			data = b
+		}
+	}
+	return strings.ToValidUTF8(string(data), "�")
+}
+
+// charsetReader converts ISO-8859-1 to UTF-8 and passes UTF-8 and US-ASCII
+// through; other charsets are refused.
+func charsetReader(charset string, r io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return r, nil
+	case "iso-8859-1", "latin1":
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, 0, len(b))
+		for _, c := range b {
+			out = utf8.AppendRune(out, rune(c))
+		}
+		return bytes.NewReader(out), nil
+	}
+	return nil, fmt.Errorf("unsupported charset %q", charset)
+}
+
+var (
+	htmlDrop  = regexp.MustCompile(`(?is)<(script|style|head)\b.*?</(script|style|head)>`)
+	htmlBreak = regexp.MustCompile(`(?i)<(br|/p|/div|/li|/tr|/h[1-6])\b[^>]*>`)
+	htmlTag   = regexp.MustCompile(`<[^>]*>`)
+	blankRuns = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlText reduces an HTML body to its text, keeping paragraphs apart.
+func htmlText(s string) string {
+	s = htmlDrop.ReplaceAllString(s, "")
+	s = htmlBreak.ReplaceAllString(s, "\n")
+	s = htmlTag.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+	return blankRuns.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+}
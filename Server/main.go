@@ -1,24 +1,710 @@
 package main
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	// Users' timezones must resolve on images without zoneinfo.
+	_ "time/tzdata"
+
+	"starttech-server/auth"
+	"starttech-server/blob"
+	"starttech-server/cache"
+	"starttech-server/certs"
+	"starttech-server/config"
+	"starttech-server/events"
+	"starttech-server/flags"
+	"starttech-server/grpc"
+	"starttech-server/handlers"
+	"starttech-server/health"
+	"starttech-server/i18n"
+	"starttech-server/integrations"
+	"starttech-server/jobs"
+	"starttech-server/logging"
+	"starttech-server/metrics"
+	"starttech-server/middleware"
+	"starttech-server/model"
+	"starttech-server/nats"
+	"starttech-server/notifications"
+	"starttech-server/oauth"
+	"starttech-server/openapi"
+	"starttech-server/outbox"
+	"starttech-server/ratelimit"
+	"starttech-server/realtime"
+	"starttech-server/redis"
+	"starttech-server/router"
+	"starttech-server/scan"
+	"starttech-server/scheduler"
+	"starttech-server/service"
+	"starttech-server/storage"
+	"starttech-server/thumb"
+	"starttech-server/tracing"
+	"starttech-server/web"
+	"starttech-server/webhooks"
 )
 
 func main() {
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, `{"status":"ok"}`)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(migrate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "seed" {
+		os.Exit(seedCommand(os.Args[2:]))
+	}
+
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	if err := run(cfg, logger); err != nil {
+		logger.Error("server failed", "err", err)
+		os.Exit(1)
+	}
+}
+
+func run(cfg config.Config, logger *slog.Logger) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	stopTracing, err := startTracing(cfg.Tracing)
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
+
+	db := cfg.Database
+	store, err := storage.Open(ctx, db.URL, storage.Pool{
+		MaxOpen:      db.MaxOpenConns,
+		MaxIdle:      db.MaxIdleConns,
+		MaxLifetime:  db.ConnMaxLifetime,
+		MaxIdleTime:  db.ConnMaxIdleTime,
+		QueryTimeout: db.QueryTimeout,
+	}, db.MigrateOnStart)
+	if errors.Is(err, storage.ErrPendingMigrations) {
+		return fmt.Errorf("%w; run `%s migrate up` or set database.migrate_on_start", err, os.Args[0])
+	}
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	// Readiness covers the store, Redis when it is used, and the background
+	// workers, each of which is started through checks.
+	checks := &health.Checker{}
+	checks.Add("database", store.Ready)
+
+	readCache, err := newCache(cfg.Cache)
+	if err != nil {
+		return err
+	}
+	if readCache != nil {
+		store = cache.Wrap(store, readCache, cfg.Cache.TaskTTL, cfg.Cache.UserTTL)
+	}
+	if r, ok := readCache.(*cache.Redis); ok {
+		checks.Add("cache", func(ctx context.Context) error {
+			_, err := r.Client.Do(ctx, "PING")
+			return err
+		})
+	}
+
+	limiter, err := rateLimiter(cfg.RateLimit)
+	if err != nil {
+		return err
+	}
+	if r, ok := limiter.(*ratelimit.Redis); ok {
+		checks.Add("redis", func(ctx context.Context) error {
+			_, err := r.Client.Do(ctx, "PING")
+			return err
+		})
+	}
+	rl := cfg.RateLimit
+	clientIP := ratelimit.ClientIP(rl.TrustProxy)
+	perIP := ratelimit.Middleware(limiter, "ip", ratelimit.PerMinute(rl.IPPerMinute, rl.IPBurst), clientIP)
+	perAttempt := ratelimit.Middleware(limiter, "auth", ratelimit.PerMinute(rl.AuthPerMinute, rl.AuthBurst), clientIP)
+	perShareView := ratelimit.Middleware(limiter, "share", ratelimit.PerMinute(rl.SharePerMinute, rl.ShareBurst), clientIP)
+	perUser := ratelimit.Middleware(limiter, "user", ratelimit.PerMinute(rl.UserPerMinute, rl.UserBurst), func(r *http.Request) string {
+		id, _ := auth.UserID(r.Context())
+		return id
 	})
 
-	http.HandleFunc("/tasks", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, `[]`)
+	secret := jwtSecret(cfg.Auth)
+	issuer := auth.NewIssuer(secret, cfg.Auth.TokenTTL)
+
+	root := router.New()
+	root.HandleFunc("/health", handlers.Health)
+	root.HandleFunc("/healthz", handlers.Health)
+	root.Handle("/readyz", &handlers.Readiness{Checker: checks})
+	root.Handle("GET /metrics", metrics.Default.Handler())
+	registerStoreMetrics(store)
+
+	// The routes of version 1 of the API.
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /openapi.json", openapi.Handler(openapi.Build(openapi.Routes())))
+	// Requests are checked against the document once a route has matched,
+	// so handlers get input of the shape they expect.
+	validate := openapi.Validate(openapi.Routes())
+	mux.HandleFunc("GET /docs", openapi.DocsHandler)
+
+	// Emails, webhook deliveries, reminders and purges run as jobs, which
+	// are started once everything has registered its kinds.
+	queue := jobs.NewQueue(store)
+	queue.Workers = cfg.Jobs.Workers
+	queue.MaxAttempts = cfg.Jobs.MaxAttempts
+	jobPurger := &scheduler.Purger{Kind: "jobs", Purge: store.PurgeJobs, Count: store.PurgeableJobs, Retention: cfg.Jobs.Retention}
+	jobPurger.Schedule(queue)
+
+	queue.Handle(notifications.KindSend, 0, notifications.SendJob(mailSender(cfg.SMTP)))
+	mail := notifications.Queued{Jobs: queue}
+	twoFactor := &service.TwoFactor{Store: store, Users: store, Tokens: issuer, Key: derivedKey(secret, "two-factor secrets"), Issuer: "Starttech"}
+	orgService := &service.Orgs{Store: store, Users: store, Mail: mail, TwoFactor: twoFactor}
+	sessions := &service.Sessions{Store: store, TTL: cfg.Auth.RefreshTTL}
+	sessionPurger := &scheduler.Purger{Kind: "sessions", Purge: store.PurgeSessions, Count: store.PurgeableSessions, Retention: cfg.Auth.RefreshTTL}
+	sessionPurger.Schedule(queue)
+	accounts := &service.Accounts{Users: store, Sessions: sessions, Tokens: issuer, Mail: mail}
+	audit := &handlers.Audit{Service: &service.Audit{Store: store, Key: derivedKey(secret, "audit log")}, ClientIP: clientIP}
+	auditPurger := &scheduler.Purger{Kind: "audit_events", Purge: audit.Service.Purge, Count: audit.Service.Purgeable, Retention: cfg.Audit.Retention}
+	auditPurger.Schedule(queue)
+	// Single sign-on needs a public URL for identity providers to send
+	// users back to.
+	var sso *service.SSO
+	if cfg.OAuth.BaseURL != "" {
+		sso = &service.SSO{Store: store, Orgs: orgService, Projects: store, Key: derivedKey(secret, "sso client secrets")}
+	}
+	authHandler := &handlers.Auth{
+		Users:     store,
+		Issuer:    issuer,
+		Orgs:      orgService,
+		Sessions:  sessions,
+		Accounts:  accounts,
+		OAuth:     oauthConfig(cfg.OAuth),
+		Admins:    cfg.Admin.Emails,
+		Audit:     audit,
+		TwoFactor: twoFactor,
+		SSO:       sso,
+	}
+	// Sign-up and sign-in are limited per address, so passwords cannot be
+	// guessed at the rate of the other routes.
+	authHandler.Register(router.NewGroup(mux, perAttempt, validate))
+
+	hub := realtime.NewHub()
+	if hub.Broker, err = eventBroker(cfg.Realtime); err != nil {
+		return err
+	}
+	if hub.Broker != nil {
+		checks.Add("realtime", hub.Ready)
+	}
+	checks.Go(ctx, "hub", hub.Run)
+
+	notifier := notifications.NewNotifier(store, store, mail)
+	checks.Go(ctx, "notifier", notifier.Run)
+	dispatcher := webhooks.NewDispatcher(store, queue)
+	dispatcher.Client.Timeout = cfg.Webhooks.Timeout
+	dispatcher.MaxAttempts = cfg.Webhooks.MaxAttempts
+	dispatcher.AllowPrivate = cfg.Webhooks.AllowPrivate
+	dispatcher.Register()
+	checks.Go(ctx, "webhook_dispatcher", dispatcher.Run)
+	github := &integrations.GitHub{BaseURL: cfg.GitHub.APIURL}
+	pusher := integrations.NewPusher(store, store, github, queue)
+	pusher.Register()
+	checks.Go(ctx, "github_pusher", pusher.Run)
+	slack := integrations.NewSlackNotifier(store, store, store, queue)
+	slack.Register()
+	checks.Go(ctx, "slack_notifier", slack.Run)
+	// The outbox relay records webhook deliveries itself, before it lets
+	// go of an event.
+	live := events.Fanout{hub, notifier, pusher, slack}
+	publisher := events.Fanout{live, dispatcher}
+	relay := outbox.NewRelay(store, live, dispatcher)
+	relay.Interval = cfg.Outbox.Interval
+	checks.Go(ctx, "outbox", relay.Run)
+
+	sched := &scheduler.Scheduler{Reminders: store, Tasks: store, Inbox: store, Events: publisher, Jobs: queue, Interval: cfg.Scheduler.Interval}
+	sched.Register()
+	checks.Go(ctx, "scheduler", sched.Run)
+
+	// Everything registered on protected requires a valid token. The
+	// middleware wraps each route, so the mux answers unknown paths and
+	// methods before the token is looked at.
+	orgs := &handlers.Orgs{Service: orgService, Audit: audit}
+	idempotency := &handlers.Idempotency{Store: store}
+	limits := model.Limits{
+		TasksPerProject: cfg.Quotas.TasksPerProject,
+		AttachmentBytes: cfg.Quotas.AttachmentBytes,
+		RequestsPerDay:  cfg.Quotas.RequestsPerDay,
+	}
+	// Daily request counts are kept where the rate limiter keeps its
+	// buckets, or in process when rate limiting is off.
+	requests, _ := limiter.(ratelimit.Counter)
+	if requests == nil {
+		requests = ratelimit.NewMemory()
+	}
+	usage := &handlers.Usage{Service: &service.Usage{Store: store, Orgs: store, Projects: store, Tasks: store, Requests: requests, Limits: limits}}
+	requireAuth := []router.Middleware{issuer.Middleware, audit.Watch, authHandler.RequireSession, authHandler.PreferLanguage, perUser, orgs.RequireMember, usage.Meter, validate, idempotency.Middleware}
+	protected := router.NewGroup(mux, requireAuth...)
+	usage.Register(protected)
+	// The in-memory store loses its events with everything else when the
+	// process stops, and its transactions copy the whole store, so its task
+	// changes are published as they are made rather than through the
+	// outbox.
+	taskOutbox := relay
+	if _, inMemory := store.(*storage.MemoryStore); inMemory {
+		taskOutbox = nil
+	}
+	taskService := &service.Tasks{Store: store, History: store, Tags: store, Projects: store, Reminders: store, Comments: store, Mentions: store, Inbox: store, Time: store, Deps: store, Checklists: store, Watchers: store, CustomFields: store, Automations: store, Users: store, Index: store, Log: store, Commands: store, ClientIDs: store, UndoWindow: cfg.Tasks.UndoWindow, Tx: store, Events: publisher, Outbox: taskOutbox, BlockCompletion: cfg.Tasks.BlockCompletion, MaxProjectTasks: limits.TasksPerProject}
+	tasks := &handlers.Tasks{Service: taskService}
+	tasks.Register(protected)
+	taskService.Attachments = &service.Attachments{
+		Store:        store,
+		Blobs:        blobStore(cfg.Attachments),
+		Tasks:        taskService,
+		MaxSize:      cfg.Attachments.MaxSize,
+		MaxOrgBytes:  limits.AttachmentBytes,
+		Usage:        store,
+		AllowedTypes: cfg.Attachments.AllowedTypes,
+		URLKey:       derivedKey(secret, "attachment links"),
+		URLTTL:       cfg.Attachments.URLTTL,
+	}
+	if cfg.Attachments.ClamAV != "" {
+		scans := scan.NewJobs(queue)
+		scans.Register(taskService.Attachments.Scan)
+		taskService.Attachments.Scanner = scan.ClamAV{Address: cfg.Attachments.ClamAV, Timeout: cfg.Attachments.ScanTimeout}
+		taskService.Attachments.Enqueue = scans.Enqueue
+	}
+	thumbnails := thumb.NewJobs(queue)
+	thumbnails.Register(taskService.Attachments.MakeThumbnails)
+	taskService.Attachments.EnqueueThumbnails = thumbnails.Enqueue
+	purger := &scheduler.Purger{Kind: "trash", Purge: taskService.PurgeTrash, Count: taskService.PurgeableTrash, Retention: cfg.Trash.Retention}
+	purger.Schedule(queue)
+	commandPurger := &scheduler.Purger{Kind: "undo_commands", Purge: store.PurgeCommands, Count: store.PurgeableCommands, Retention: cfg.Tasks.UndoWindow}
+	commandPurger.Schedule(queue)
+	archiver := &scheduler.Archiver{Archive: taskService.ArchiveCompleted, After: cfg.Tasks.ArchiveAfter}
+	archiver.Schedule(queue)
+	rules := &scheduler.Rules{Evaluate: taskService.EvaluateRules, Interval: cfg.Rules.Interval}
+	rules.Schedule(queue)
+	attachments := &handlers.Attachments{Service: taskService.Attachments}
+	attachments.Register(protected)
+	attachments.RegisterPublic(mux)
+	projectService := &service.Projects{Store: store, Tasks: store, History: store, Users: store, Orgs: store, CustomFields: store, Automations: store, Events: publisher, Log: store}
+	projects := &handlers.Projects{Service: projectService, Tasks: taskService}
+	projects.Register(protected)
+	tags := &handlers.Tags{Service: &service.Tags{Store: store}}
+	tags.Register(protected)
+	views := &handlers.Views{Service: &service.Views{Store: store, Tasks: taskService}}
+	views.Register(protected)
+	templates := &handlers.Templates{Service: &service.Templates{Store: store, Tasks: taskService, Projects: projectService}}
+	templates.Register(protected)
+	gql := &handlers.GraphQL{Tasks: taskService, Projects: projects.Service, Tags: tags.Service, Users: store, Hub: hub}
+	// Realtime and GraphQL can be turned off, for everyone or for some
+	// organizations and users, without a deploy.
+	flagSet := flags.NewSet(store, cfg.Flags.Refresh)
+	featureFlags := &handlers.Flags{Service: &service.Flags{Store: store, Set: flagSet, Orgs: store, Users: store}, Audit: audit}
+	featureFlags.Register(protected)
+	gql.Register(router.NewGroup(protected, flagSet.Require(flags.GraphQL)))
+	hooks := &handlers.Webhooks{Service: &service.Webhooks{Store: store, Redeliver: dispatcher.Redeliver, AllowPrivate: cfg.Webhooks.AllowPrivate}}
+	hooks.Register(protected)
+	githubLinks := &handlers.GitHub{Service: &service.GitHub{Store: store, Projects: store, Tasks: taskService, Client: github}}
+	githubLinks.Register(protected)
+	githubLinks.RegisterPublic(mux)
+	slackLinks := &handlers.Slack{Service: &service.Slack{Store: store, Projects: store, Tasks: taskService}}
+	slackLinks.Register(protected)
+	slackLinks.RegisterPublic(mux)
+	importer := integrations.NewImporter(queue)
+	boardImportService := &service.BoardImports{Store: store, Projects: projectService, Tasks: taskService, Tags: tags.Service, Enqueue: importer.Enqueue}
+	importer.Register(boardImportService.Run)
+	boardImports := &handlers.BoardImports{Service: boardImportService}
+	boardImports.Register(protected)
+	inbox := &handlers.Notifications{Service: &service.Notifications{Store: store, Events: publisher}}
+	inbox.Register(protected)
+	calendar := &handlers.Calendar{Service: &service.Calendar{Store: store, Orgs: store, Tasks: taskService}}
+	calendar.Register(protected)
+	calendar.RegisterPublic(mux)
+	shares := &handlers.Shares{Service: &service.Shares{Store: store, Projects: store, Orgs: store, Tasks: taskService}}
+	shares.Register(protected)
+	shares.RegisterPublic(router.NewGroup(mux, perShareView))
+	sharePurger := &scheduler.Purger{Kind: "share_links", Purge: store.PurgeShareLinks, Count: store.PurgeableShareLinks, Retention: cfg.Shares.ExpiredRetention}
+	sharePurger.Schedule(queue)
+	if in := cfg.InboundEmail; in.Domain != "" {
+		inbound := &handlers.InboundEmail{
+			Service: &service.InboundEmail{Store: store, Orgs: store, Tasks: taskService, Domain: in.Domain, Secret: in.Secret},
+			MaxSize: in.MaxSize,
+		}
+		inbound.Register(protected)
+		inbound.RegisterPublic(mux)
+	}
+	handlers.Markdown{}.Register(protected)
+	orgs.Register(protected)
+	authHandler.RegisterProtected(protected)
+	personalData := &handlers.PersonalData{
+		Service: &service.PersonalData{
+			Store: store, Tasks: taskService, Attachments: taskService.Attachments, Accounts: accounts, Audit: audit.Service,
+			Grace: cfg.Accounts.DeletionGrace, EraseContent: cfg.Accounts.EraseContent,
+		},
+		Audit: audit,
+	}
+	personalData.Register(protected)
+	// Accounts whose deletion was asked for are erased once their grace
+	// period is over.
+	erasure := &scheduler.Purger{Kind: "accounts", Purge: personalData.Service.EraseDue, Count: personalData.Service.ErasableDue, Retention: cfg.Accounts.DeletionGrace}
+	erasure.Schedule(queue)
+	authHandler.RegisterTwoFactor(protected)
+	authHandler.RegisterSSO(protected)
+	apiKeys := &handlers.APIKeys{Service: &service.APIKeys{Store: store, Users: store, Orgs: store}}
+	apiKeys.Register(protected)
+	scim := &handlers.SCIM{
+		Service: &service.SCIM{Links: store, Users: store, Accounts: accounts, Orgs: orgService, Projects: projectService},
+		Keys:    apiKeys.Service.Authenticate,
+	}
+	scim.Register(router.NewGroup(mux, scim.Authenticate, perUser))
+	keyPurger := &scheduler.Purger{Kind: "idempotency_keys", Purge: store.PurgeIdempotencyKeys, Count: store.PurgeableIdempotencyKeys, Retention: cfg.Idempotency.TTL}
+	keyPurger.Schedule(queue)
+	admin := &handlers.Admin{
+		Service:        &service.Admin{Users: store, Orgs: store, Usage: store, Sessions: sessions, Accounts: accounts},
+		Jobs:           &service.Jobs{Store: store},
+		Orgs:           orgService,
+		Issuer:         issuer,
+		Backups:        &service.Backups{Store: store, Blobs: taskService.Attachments.Blobs},
+		MaxRestoreSize: cfg.Admin.MaxRestoreSize,
+		Audit:          audit,
+		Purges:         scheduler.Purgers{jobPurger, sessionPurger, auditPurger, purger, commandPurger, sharePurger, erasure, keyPurger}.Preview,
+	}
+	admin.Register(protected)
+	audit.Register(protected)
+	issuer.Keys = apiKeys.Service.Authenticate
+	// Browsers cannot set headers on streams, so these take the token from
+	// the query too.
+	streams := router.NewGroup(mux, auth.QueryToken).With(requireAuth...).With(flagSet.Require(flags.Realtime))
+	streams.HandleFunc("GET /ws", hub.ServeWS)
+	streams.HandleFunc("GET /events", hub.ServeSSE)
+	checks.Go(ctx, "jobs", queue.Run)
+	v1 := middleware.RoutePattern(mux)
+	root.Version("v1", v1)
+	if app := web.Embedded(); app != nil {
+		root.Unversioned(app.Around(mux, v1))
+	} else {
+		root.Unversioned(v1)
+	}
+
+	compress := func(h http.Handler) http.Handler { return h }
+	if cfg.Server.CompressMinSize > 0 {
+		compress = middleware.Compress(cfg.Server.CompressMinSize)
+	}
+	srv := &http.Server{
+		Addr: cfg.Addr(),
+		Handler: middleware.RequestID(middleware.Trace(middleware.Logger(logger)(middleware.Recover(compress(middleware.Metrics(
+			middleware.CORS(corsOptions(cfg.CORS))(i18n.Middleware(perIP(middleware.MaxBodySize(cfg.Server.MaxBodySize)(middleware.RoutePattern(root.ServeMux))))))))))),
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		Protocols:         serverProtocols(cfg.Server),
+		HTTP2:             http2Config(cfg.Server),
+		ConnState:         middleware.ConnState("api"),
+	}
+
+	servers := []*http.Server{srv}
+	if cfg.TLS.Enabled() {
+		src, err := certSource(cfg.TLS)
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig = src.TLS
+		if addr := cfg.RedirectAddr(); addr != "" {
+			servers = append(servers, &http.Server{
+				Addr:              addr,
+				Handler:           src.HTTP(certs.Redirect(cfg.Server.Port)),
+				ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+				ReadHeaderTimeout: 5 * time.Second,
+				IdleTimeout:       cfg.Server.IdleTimeout,
+				ConnState:         middleware.ConnState("redirect"),
+			})
+		}
+	}
+	if addr := cfg.GRPCAddr(); addr != "" {
+		// gRPC needs HTTP/2, which without TLS has to be switched on.
+		var protocols http.Protocols
+		protocols.SetUnencryptedHTTP2(true)
+		tasksRPC := &grpc.Server{Tasks: taskService, Orgs: orgService, Sessions: sessions, Issuer: issuer, Hub: hub}
+		servers = append(servers, &http.Server{
+			Addr:              addr,
+			Handler:           middleware.RequestID(middleware.Trace(middleware.Logger(logger)(tasksRPC))),
+			Protocols:         &protocols,
+			HTTP2:             http2Config(cfg.Server),
+			ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+			ReadHeaderTimeout: 5 * time.Second,
+			IdleTimeout:       cfg.Server.IdleTimeout,
+			ConnState:         middleware.ConnState("grpc"),
+		})
+	}
+
+	if addr := cfg.Debug.Addr; addr != "" {
+		// Profiles for tools such as go tool pprof, which cannot send a
+		// token; the address should only be reachable from the host.
+		debug := http.NewServeMux()
+		handlers.Debug{Prefix: "/debug"}.Register(debug)
+		servers = append(servers, &http.Server{
+			Addr:              addr,
+			Handler:           debug,
+			ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+			ReadHeaderTimeout: 5 * time.Second,
+			IdleTimeout:       cfg.Server.IdleTimeout,
+			ConnState:         middleware.ConnState("debug"),
+		})
+	}
+
+	// Probes keep long-lived event streams open through NAT gateways and
+	// load balancers that drop quiet connections.
+	listen := net.ListenConfig{KeepAlive: cfg.Server.TCPKeepAlive}
+	errc := make(chan error, len(servers))
+	for _, s := range servers {
+		go func() {
+			ln, err := listen.Listen(ctx, "tcp", s.Addr)
+			if err != nil {
+				errc <- err
+				return
+			}
+			if s.TLSConfig != nil {
+				logger.Info("server listening", "addr", s.Addr, "tls", true)
+				errc <- s.ServeTLS(ln, "", "")
+				return
+			}
+			logger.Info("server listening", "addr", s.Addr, "h2c", s.Protocols != nil && s.Protocols.UnencryptedHTTP2())
+			errc <- s.Serve(ln)
+		}()
+	}
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Stop intercepting signals so a second Ctrl-C kills the process.
+	stop()
+	timeout := cfg.Server.ShutdownTimeout
+	logger.Info("shutting down, draining in-flight requests", "timeout", timeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for _, s := range servers {
+		if err := s.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+	}
+	for range servers {
+		if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+	}
+	logger.Info("server stopped")
+	return nil
+}
+
+// serverProtocols returns the protocols the API is served with: HTTP/1.1,
+// HTTP/2 over TLS, and HTTP/2 without TLS if c.H2C asks for it.
+func serverProtocols(c config.Server) *http.Protocols {
+	var p http.Protocols
+	p.SetHTTP1(true)
+	p.SetHTTP2(true)
+	p.SetUnencryptedHTTP2(c.H2C)
+	return &p
+}
+
+// http2Config returns the HTTP/2 settings of c. Unanswered pings close a
+// connection after 15 seconds.
+func http2Config(c config.Server) *http.HTTP2Config {
+	return &http.HTTP2Config{
+		MaxConcurrentStreams: c.MaxConcurrentStreams,
+		SendPingTimeout:      c.PingInterval,
+	}
+}
+
+var tasksStored = metrics.NewGaugeVec("tasks_stored", "Tasks in the store, by status.", "status")
+
+// registerStoreMetrics refreshes the task gauges on every scrape.
+func registerStoreMetrics(store storage.TaskStore) {
+	metrics.Default.OnScrape(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		for _, st := range []model.Status{model.StatusTodo, model.StatusInProgress, model.StatusDone} {
+			n, err := store.CountTasks(ctx, storage.TaskFilter{Status: st})
+			if err != nil {
+				slog.Warn("counting tasks for metrics", "err", err)
+				return
+			}
+			tasksStored.With(string(st)).Set(float64(n))
+		}
 	})
+}
+
+// startTracing enables tracing when a collector is configured, and returns
+// a function that sends the spans still queued once the server is done.
+func startTracing(c config.Tracing) (stop func(), err error) {
+	if c.Endpoint == "" {
+		return func() {}, nil
+	}
+	exporter, err := tracing.NewExporter(c.Endpoint, c.ServiceName, c.SampleRatio)
+	if err != nil {
+		return nil, err
+	}
+	if exporter.Headers, err = tracing.ParseHeaders(c.Headers); err != nil {
+		return nil, err
+	}
+	tracing.Enable(exporter)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		exporter.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}, nil
+}
+
+// certSource returns where HTTPS certificates come from: Let's Encrypt when
+// domains are listed, the configured files otherwise.
+func certSource(c config.TLS) (certs.Source, error) {
+	if len(c.Domains) > 0 {
+		return certs.ACME(c.Domains, c.Email, c.CacheDir)
+	}
+	return certs.Files(c.CertFile, c.KeyFile)
+}
+
+// oauthConfig returns the sign-in providers that have a client configured.
+func oauthConfig(c config.OAuth) handlers.OAuth {
+	providers := map[string]*oauth.Provider{}
+	if c.GoogleClientID != "" {
+		providers["google"] = oauth.Google(c.GoogleClientID, c.GoogleClientSecret)
+	}
+	if c.GitHubClientID != "" {
+		providers["github"] = oauth.GitHub(c.GitHubClientID, c.GitHubClientSecret)
+	}
+	return handlers.OAuth{Providers: providers, BaseURL: c.BaseURL, SuccessURL: c.SuccessURL}
+}
+
+// jwtSecret returns the configured token signing key, or a random one if
+// none is set, in which case tokens do not survive a restart, and nor do
+// the two-factor secrets sealed with a key derived from it.
+func jwtSecret(c config.Auth) []byte {
+	if c.JWTSecret != "" {
+		return []byte(c.JWTSecret)
+	}
+	slog.Warn("no JWT secret configured; using a random key")
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
+
+// derivedKey returns a key for purpose derived from secret, so one
+// configured secret can sign several kinds of token without a token of one
+// kind passing for another.
+func derivedKey(secret []byte, purpose string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(purpose))
+	return h.Sum(nil)
+}
+
+// blobStore returns the backend uploaded files are kept in.
+func blobStore(c config.Attachments) blob.Store {
+	if c.Backend == "s3" {
+		return &blob.S3{
+			Endpoint:  c.S3.Endpoint,
+			Region:    c.S3.Region,
+			Bucket:    c.S3.Bucket,
+			AccessKey: c.S3.AccessKey,
+			SecretKey: c.S3.SecretKey,
+			PathStyle: c.S3.PathStyle,
+		}
+	}
+	return blob.Disk{Dir: c.Dir}
+}
+
+// mailSender returns the transport for notification emails. Without a mail
+// server they are only logged.
+func mailSender(c config.SMTP) notifications.Sender {
+	if c.Host == "" {
+		return notifications.LogSender{}
+	}
+	return notifications.SMTPSender{Host: c.Host, Port: c.Port, Username: c.Username, Password: c.Password, From: c.From}
+}
+
+// rateLimiter returns where request buckets are kept, or nil when rate
+// limiting is off.
+func rateLimiter(c config.RateLimit) (ratelimit.Limiter, error) {
+	switch c.Backend {
+	case "off":
+		return nil, nil
+	case "redis":
+		client, err := redis.New(c.RedisURL)
+		if err != nil {
+			return nil, err
+		}
+		return &ratelimit.Redis{Client: client}, nil
+	}
+	return ratelimit.NewMemory(), nil
+}
+
+// newCache returns where hot reads are cached, or nil when caching is off.
+func newCache(c config.Cache) (cache.Cache, error) {
+	switch c.Backend {
+	case "memory":
+		return cache.NewMemory(), nil
+	case "redis":
+		client, err := redis.New(c.RedisURL)
+		if err != nil {
+			return nil, err
+		}
+		return &cache.Redis{Client: client}, nil
+	}
+	return nil, nil
+}
+
+// eventBroker returns what relays realtime events between instances, or
+// nil when each instance only serves its own.
+func eventBroker(c config.Realtime) (realtime.Broker, error) {
+	switch c.Backend {
+	case "redis":
+		client, err := redis.New(c.URL)
+		if err != nil {
+			return nil, err
+		}
+		return &realtime.RedisBroker{Client: client, Channel: c.Channel}, nil
+	case "nats":
+		client, err := nats.New(c.URL)
+		if err != nil {
+			return nil, err
+		}
+		return &realtime.NATSBroker{Client: client, Subject: c.Channel}, nil
+	}
+	return nil, nil
+}
 
-	log.Println("Server running on :8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
-}
\ No newline at end of file
+func corsOptions(c config.CORS) middleware.CORSOptions {
+	opts := middleware.DefaultCORSOptions()
+	opts.AllowedOrigins = c.AllowedOrigins
+	opts.AllowCredentials = c.AllowCredentials
+	return opts
+}
@@ -0,0 +1,398 @@
+package markdown
+
+import (
+	"strings"
+)
+
+// inline renders the text of a paragraph or heading. Inside a link's text,
+// no further links are made.
+func inline(b *strings.Builder, s string, depth int, inLink bool) {
+	if depth > maxDepth {
+		b.WriteString(escape(s))
+		return
+	}
+	// unclosed remembers the delimiters found to have no closer after
+	// some point, so that no later opener searches again.
+	unclosed := map[string]bool{}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			b.WriteString("<br>\n")
+			i += 2
+		case c == '\\' && i+1 < len(s) && isPunct(s[i+1]):
+			b.WriteString(escape(s[i+1 : i+2]))
+			i += 2
+		case c == '\n':
+			b.WriteString("<br>\n")
+			i++
+		case c == '`':
+			n := run(s, i)
+			if end := codeEnd(s, i); end > 0 {
+				code := strings.ReplaceAll(s[i+n:end-n], "\n", " ")
+				if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+					code = code[1 : len(code)-1]
+				}
+				b.WriteString("<code>" + escape(code) + "</code>")
+				i = end
+			} else {
+				b.WriteString(s[i : i+n])
+				i += n
+			}
+		case c == '<' && !inLink:
+			if end, href, text, ok := autolink(s, i); ok {
+				writeLink(b, href, func() { b.WriteString(escape(text)) })
+				i = end
+			} else {
+				b.WriteString("&lt;")
+				i++
+			}
+		case (c == '[' || c == '!' && i+1 < len(s) && s[i+1] == '[') && !inLink && !unclosed["]"]:
+			open := i
+			if c == '!' {
+				open++
+			}
+			l, ok := link(s, open)
+			switch {
+			case !ok:
+				if l.end < 0 {
+					unclosed["]"] = true
+				}
+				b.WriteString(s[i : open+1])
+				i = open + 1
+			case c == '!':
+				image(b, l)
+				i = l.end
+			default:
+				writeLink(b, l.dest, func() { inline(b, l.text, depth+1, true) })
+				i = l.end
+			}
+		case c == '*' || c == '_' || c == '~':
+			i = emphasis(b, s, i, depth, inLink, unclosed)
+		case !inLink && (i == 0 || !isAlnum(s[i-1])) && bareURLAt(s, i):
+			end := bareURLEnd(s, i)
+			href := s[i:end]
+			if strings.HasPrefix(href, "www.") {
+				href = "http://" + href
+			}
+			writeLink(b, href, func() { b.WriteString(escape(s[i:end])) })
+			i = end
+		default:
+			b.WriteString(escape(s[i : i+1]))
+			i++
+		}
+	}
+}
+
+// emphasis renders the run of *, _ or ~ at s[i] as <em>, <strong>, both,
+// or <del> when a matching run closes it, and as text otherwise. It
+// returns the index after what it rendered.
+func emphasis(b *strings.Builder, s string, i, depth int, inLink bool, unclosed map[string]bool) int {
+	c := s[i]
+	n := run(s, i)
+	open, tags := s[i:i+n], []string(nil)
+	switch {
+	case c == '~' && n == 2:
+		tags = []string{"del"}
+	case c == '~':
+	case n == 1:
+		tags = []string{"em"}
+	case n == 2:
+		tags = []string{"strong"}
+	case n == 3:
+		tags = []string{"em", "strong"}
+	}
+	after := i + n
+	canOpen := tags != nil && after < len(s) && !isSpace(s[after]) &&
+		(c != '_' || i == 0 || !isAlnum(s[i-1]))
+	if !canOpen || unclosed[open] {
+		b.WriteString(s[i:after])
+		return after
+	}
+	end := closer(s, after, c, n)
+	if end < 0 {
+		unclosed[open] = true
+		b.WriteString(s[i:after])
+		return after
+	}
+	for _, t := range tags {
+		b.WriteString("<" + t + ">")
+	}
+	inline(b, s[after:end], depth+1, inLink)
+	for j := len(tags) - 1; j >= 0; j-- {
+		b.WriteString("</" + tags[j] + ">")
+	}
+	return end + n
+}
+
+// closer returns the index of the first run of exactly n c's from s[from]
+// that can close emphasis, or -1. Code spans and escaped characters are
+// skipped.
+func closer(s string, from int, c byte, n int) int {
+	for j := from; j < len(s); {
+		switch s[j] {
+		case '\\':
+			j += 2
+			continue
+		case '`':
+			if end := codeEnd(s, j); end > 0 {
+				j = end
+			} else {
+				j += run(s, j)
+			}
+			continue
+		case c:
+			l := run(s, j)
+			if l == n && !isSpace(s[j-1]) && (c != '_' || j+l == len(s) || !isAlnum(s[j+l])) {
+				return j
+			}
+			j += l
+			continue
+		}
+		j++
+	}
+	return -1
+}
+
+// codeEnd returns the index after the code span that opens at s[i], or -1
+// when no run of as many backticks closes it.
+func codeEnd(s string, i int) int {
+	n := run(s, i)
+	for j := i + n; j < len(s); {
+		k := strings.IndexByte(s[j:], '`')
+		if k < 0 {
+			return -1
+		}
+		j += k
+		l := run(s, j)
+		if l == n {
+			return j + l
+		}
+		j += l
+	}
+	return -1
+}
+
+// run returns the length of the run of s[i] starting at i.
+func run(s string, i int) int {
+	n := 1
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+	return n
+}
+
+// inlineLink is a [text](dest "title") link or an image.
+type inlineLink struct {
+	text, dest string
+	// end is the index after the link, or -1 when no ] closes the text.
+	end int
+}
+
+// link parses the link whose text opens with the [ at s[i].
+func link(s string, i int) (inlineLink, bool) {
+	close, nest := -1, 0
+	for j := i; j < len(s) && close < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '`':
+			if end := codeEnd(s, j); end > 0 {
+				j = end - 1
+			}
+		case '[':
+			nest++
+		case ']':
+			if nest--; nest == 0 {
+				close = j
+			}
+		}
+	}
+	if close < 0 {
+		return inlineLink{end: -1}, false
+	}
+	l := inlineLink{text: s[i+1 : close]}
+	j := close + 1
+	if j >= len(s) || s[j] != '(' {
+		return l, false
+	}
+	j = skipSpace(s, j+1)
+	if j < len(s) && s[j] == '<' {
+		end := strings.IndexAny(s[j:], ">\n")
+		if end < 0 || s[j+end] != '>' {
+			return l, false
+		}
+		l.dest = s[j+1 : j+end]
+		j += end + 1
+	} else {
+		start, parens := j, 0
+		for ; j < len(s) && !isSpace(s[j]); j++ {
+			if s[j] == '\\' && j+1 < len(s) {
+				j++
+			} else if s[j] == '(' {
+				parens++
+			} else if s[j] == ')' {
+				if parens == 0 {
+					break
+				}
+				parens--
+			}
+		}
+		l.dest = s[start:j]
+	}
+	j = skipSpace(s, j)
+	// A title is allowed and ignored.
+	if j < len(s) && (s[j] == '"' || s[j] == '\'') {
+		end := strings.IndexByte(s[j+1:], s[j])
+		if end < 0 {
+			return l, false
+		}
+		j = skipSpace(s, j+end+2)
+	}
+	if j >= len(s) || s[j] != ')' {
+		return l, false
+	}
+	l.dest = unescapePunct(l.dest)
+	l.end = j + 1
+	return l, true
+}
+
+func image(b *strings.Builder, l inlineLink) {
+	alt := escape(unescapePunct(l.text))
+	src, ok := safeURL(l.dest)
+	if !ok || strings.HasPrefix(strings.ToLower(src), "mailto:") {
+		b.WriteString(alt)
+		return
+	}
+	b.WriteString(`<img src="` + escape(src) + `" alt="` + alt + `">`)
+}
+
+// autolink parses a <https://...> or <someone@example.com> link at s[i].
+func autolink(s string, i int) (end int, href, text string, ok bool) {
+	j := strings.IndexAny(s[i+1:], "<> \n")
+	if j <= 0 || s[i+1+j] != '>' {
+		return 0, "", "", false
+	}
+	text = s[i+1 : i+1+j]
+	lower := strings.ToLower(text)
+	switch {
+	case strings.HasPrefix(lower, "http://"), strings.HasPrefix(lower, "https://"), strings.HasPrefix(lower, "mailto:"):
+		href = text
+	case strings.Contains(text, "@") && !strings.Contains(text, ":"):
+		href = "mailto:" + text
+	default:
+		return 0, "", "", false
+	}
+	return i + j + 2, href, text, true
+}
+
+func bareURLAt(s string, i int) bool {
+	rest := s[i:]
+	for _, p := range []string{"https://", "http://", "www."} {
+		if len(rest) > len(p) && strings.EqualFold(rest[:len(p)], p) {
+			return true
+		}
+	}
+	return false
+}
+
+// bareURLEnd returns where the URL that starts at s[i] ends: at a space or
+// <, less trailing punctuation and a ) that no ( in it opened.
+func bareURLEnd(s string, i int) int {
+	end := i
+	for end < len(s) && !isSpace(s[end]) && s[end] != '<' {
+		end++
+	}
+	for end > i {
+		last := s[end-1]
+		if strings.IndexByte(`.,:;!?"'*_~`, last) >= 0 ||
+			last == ')' && strings.Count(s[i:end], "(") < strings.Count(s[i:end], ")") {
+			end--
+			continue
+		}
+		break
+	}
+	return end
+}
+
+// writeLink writes an <a> around what text writes, or only the text when
+// href is not a URL links may go to.
+func writeLink(b *strings.Builder, href string, text func()) {
+	u, ok := safeURL(href)
+	if !ok {
+		text()
+		return
+	}
+	b.WriteString(`<a href="` + escape(u) + `" rel="nofollow noopener noreferrer">`)
+	text()
+	b.WriteString("</a>")
+}
+
+// safeURL returns raw without the control characters browsers ignore, if
+// it is relative or an http, https or mailto URL.
+func safeURL(raw string) (string, bool) {
+	u := strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(raw))
+	if u == "" {
+		return "", false
+	}
+	if i := strings.IndexAny(u, ":/?#"); i >= 0 && u[i] == ':' {
+		switch strings.ToLower(u[:i]) {
+		case "http", "https", "mailto":
+		default:
+			return "", false
+		}
+	}
+	return u, true
+}
+
+// escape escapes the characters that are special in HTML text and
+// attribute values.
+func escape(s string) string {
+	return htmlEscaper.Replace(s)
+}
+
+var htmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+	"'", "&#39;",
+)
+
+func unescapePunct(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && isSpace(s[i]) {
+		i++
+	}
+	return i
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n'
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+func isPunct(c byte) bool {
+	return c < 0x80 && strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
@@ -0,0 +1,331 @@
+// Package markdown renders the Markdown of task descriptions and comments
+// as HTML that can be put into a page as it is. It covers what people
+// write in such fields: paragraphs, headings, emphasis, strikethrough,
+// inline and fenced code, block quotes, bulleted, numbered and task lists,
+// rules, links, images and bare URLs. Line breaks inside a paragraph are
+// kept.
+//
+// Safety does not depend on cleaning up afterwards: raw HTML in the input
+// is never passed through but shown as text, every character of text is
+// escaped, and links and images only keep URLs that are relative or use
+// http, https or (for links) mailto. Every tag is written together with
+// its closing tag, so the output is always balanced.
+package markdown
+
+import (
+	"strconv"
+	"strings"
+)
+
+// maxDepth bounds how deeply quotes, lists and inline spans nest; deeper
+// content is rendered as plain text.
+const maxDepth = 16
+
+// Render returns the HTML for src, or "" when src is blank.
+func Render(src string) string {
+	if strings.TrimSpace(src) == "" {
+		return ""
+	}
+	var b strings.Builder
+	blocks(&b, splitLines(src), 0, false)
+	return b.String()
+}
+
+func splitLines(src string) []string {
+	src = strings.NewReplacer("\r\n", "\n", "\r", "\n", "\t", "    ", "\x00", "�").Replace(src)
+	return strings.Split(src, "\n")
+}
+
+// blocks renders lines as a sequence of blocks. In a tight list item,
+// paragraphs are written without <p>.
+func blocks(b *strings.Builder, lines []string, depth int, tight bool) {
+	if depth > maxDepth {
+		paragraph(b, lines, depth, tight)
+		return
+	}
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case blank(line):
+			i++
+		case isFence(line):
+			i = codeBlock(b, lines, i)
+		case headingLevel(line) > 0:
+			heading(b, line, depth)
+			i++
+		case isRule(line):
+			b.WriteString("<hr>\n")
+			i++
+		case isQuote(line):
+			var inner []string
+			for ; i < len(lines) && isQuote(lines[i]); i++ {
+				s := strings.TrimLeft(lines[i], " ")[1:]
+				inner = append(inner, strings.TrimPrefix(s, " "))
+			}
+			b.WriteString("<blockquote>\n")
+			blocks(b, inner, depth+1, false)
+			b.WriteString("</blockquote>\n")
+		default:
+			if _, ok := listMarker(line); ok {
+				i = list(b, lines, i, depth)
+				continue
+			}
+			start := i
+			for i++; i < len(lines) && !blank(lines[i]) && !interrupts(lines[i]); i++ {
+			}
+			paragraph(b, lines[start:i], depth, tight)
+		}
+	}
+}
+
+func paragraph(b *strings.Builder, lines []string, depth int, tight bool) {
+	text := make([]string, len(lines))
+	for i, l := range lines {
+		text[i] = strings.TrimSpace(l)
+	}
+	if !tight {
+		b.WriteString("<p>")
+	}
+	inline(b, strings.Join(text, "\n"), depth, false)
+	if !tight {
+		b.WriteString("</p>")
+	}
+	b.WriteString("\n")
+}
+
+func blank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func indent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// interrupts reports whether line starts a block that ends a paragraph.
+func interrupts(line string) bool {
+	if isFence(line) || headingLevel(line) > 0 || isRule(line) || isQuote(line) {
+		return true
+	}
+	m, ok := listMarker(line)
+	return ok && (!m.ordered || m.start == 1)
+}
+
+func isQuote(line string) bool {
+	return indent(line) <= 3 && strings.HasPrefix(strings.TrimLeft(line, " "), ">")
+}
+
+func isRule(line string) bool {
+	if indent(line) > 3 {
+		return false
+	}
+	s := strings.ReplaceAll(strings.TrimSpace(line), " ", "")
+	if len(s) < 3 || strings.Trim(s, s[:1]) != "" {
+		return false
+	}
+	return s[0] == '-' || s[0] == '*' || s[0] == '_'
+}
+
+func headingLevel(line string) int {
+	if indent(line) > 3 {
+		return 0
+	}
+	s := strings.TrimLeft(line, " ")
+	n := len(s) - len(strings.TrimLeft(s, "#"))
+	if n < 1 || n > 6 || (len(s) > n && s[n] != ' ') {
+		return 0
+	}
+	return n
+}
+
+func heading(b *strings.Builder, line string, depth int) {
+	n := headingLevel(line)
+	text := strings.TrimSpace(strings.TrimLeft(line, " ")[n:])
+	// A closing run of #s is dropped when set off by a space.
+	if t := strings.TrimRight(text, "#"); t == "" || strings.HasSuffix(t, " ") {
+		text = strings.TrimSpace(t)
+	}
+	tag := "h" + strconv.Itoa(n)
+	b.WriteString("<" + tag + ">")
+	inline(b, text, depth, false)
+	b.WriteString("</" + tag + ">\n")
+}
+
+// fence returns the fence a line opens or closes a code block with.
+func fence(line string) string {
+	if indent(line) > 3 {
+		return ""
+	}
+	s := strings.TrimLeft(line, " ")
+	for _, c := range []string{"`", "~"} {
+		if n := len(s) - len(strings.TrimLeft(s, c)); n >= 3 {
+			return s[:n]
+		}
+	}
+	return ""
+}
+
+func isFence(line string) bool {
+	f := fence(line)
+	return f != "" && !(f[0] == '`' && strings.Contains(strings.TrimLeft(line, " ")[len(f):], "`"))
+}
+
+// codeBlock renders the fenced code block that opens at lines[i] and
+// returns the index of the line after it. An unclosed block runs to the
+// end.
+func codeBlock(b *strings.Builder, lines []string, i int) int {
+	open := fence(lines[i])
+	ind := indent(lines[i])
+	info := strings.Fields(strings.TrimLeft(lines[i], " ")[len(open):])
+	b.WriteString("<pre><code")
+	if len(info) > 0 && safeLanguage(info[0]) {
+		b.WriteString(` class="language-` + info[0] + `"`)
+	}
+	b.WriteString(">")
+	for i++; i < len(lines); i++ {
+		line := lines[i]
+		if f := fence(line); f != "" && f[0] == open[0] && len(f) >= len(open) && blank(strings.TrimLeft(line, " ")[len(f):]) {
+			i++
+			break
+		}
+		line = line[min(ind, indent(line)):]
+		b.WriteString(escape(line) + "\n")
+	}
+	b.WriteString("</code></pre>\n")
+	return i
+}
+
+func safeLanguage(s string) bool {
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '+') {
+			return false
+		}
+	}
+	return len(s) <= 30
+}
+
+// marker is the start of a list item.
+type marker struct {
+	ordered bool
+	start   int
+	// delim is the bullet, or the . or ) after the number.
+	delim byte
+	// width is where the item's content starts.
+	width int
+}
+
+func listMarker(line string) (marker, bool) {
+	ind := indent(line)
+	if ind > 3 {
+		return marker{}, false
+	}
+	s := line[ind:]
+	if s == "" {
+		return marker{}, false
+	}
+	if c := s[0]; c == '-' || c == '*' || c == '+' {
+		if len(s) == 1 || s[1] == ' ' {
+			return marker{delim: c, width: ind + min(len(s), 2)}, true
+		}
+		return marker{}, false
+	}
+	n := len(s) - len(strings.TrimLeft(s, "0123456789"))
+	if n == 0 || n > 9 || len(s) == n || (s[n] != '.' && s[n] != ')') || (len(s) > n+1 && s[n+1] != ' ') {
+		return marker{}, false
+	}
+	start, _ := strconv.Atoi(s[:n])
+	return marker{ordered: true, start: start, delim: s[n], width: ind + min(len(s), n+2)}, true
+}
+
+// list renders the list that starts at lines[i] and returns the index of
+// the line after it. A list is loose, with its items' paragraphs in <p>,
+// when blank lines separate its items or their blocks.
+func list(b *strings.Builder, lines []string, i, depth int) int {
+	first, _ := listMarker(lines[i])
+	var items [][]string
+	loose := false
+	for i < len(lines) {
+		m, ok := listMarker(lines[i])
+		if !ok || m.ordered != first.ordered || m.delim != first.delim || isRule(lines[i]) {
+			break
+		}
+		item := []string{lines[i][m.width:]}
+		for i++; i < len(lines); {
+			l := lines[i]
+			if blank(l) {
+				j := i
+				for j < len(lines) && blank(lines[j]) {
+					j++
+				}
+				if j == len(lines) || indent(lines[j]) < m.width {
+					break
+				}
+				for ; i < j; i++ {
+					item = append(item, "")
+				}
+				loose = true
+				continue
+			}
+			if indent(l) >= m.width {
+				item = append(item, l[m.width:])
+				i++
+				continue
+			}
+			if _, ok := listMarker(l); ok || interrupts(l) {
+				break
+			}
+			// A lazy continuation of the item's paragraph.
+			item = append(item, strings.TrimLeft(l, " "))
+			i++
+		}
+		items = append(items, item)
+
+		j := i
+		for j < len(lines) && blank(lines[j]) {
+			j++
+		}
+		if j > i && j < len(lines) {
+			if m, ok := listMarker(lines[j]); ok && m.ordered == first.ordered && m.delim == first.delim && !isRule(lines[j]) {
+				loose = true
+				i = j
+			}
+		}
+	}
+
+	tag := "ul"
+	if first.ordered {
+		tag = "ol"
+	}
+	b.WriteString("<" + tag)
+	if first.ordered && first.start != 1 {
+		b.WriteString(` start="` + strconv.Itoa(first.start) + `"`)
+	}
+	b.WriteString(">\n")
+	for _, item := range items {
+		b.WriteString("<li>")
+		if rest, checked, ok := taskItem(item[0]); ok {
+			if checked {
+				b.WriteString(`<input type="checkbox" checked disabled> `)
+			} else {
+				b.WriteString(`<input type="checkbox" disabled> `)
+			}
+			item[0] = rest
+		}
+		var inner strings.Builder
+		blocks(&inner, item, depth+1, !loose)
+		b.WriteString(strings.TrimSuffix(inner.String(), "\n"))
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// taskItem recognises the [ ] and [x] that start the items of a task list.
+func taskItem(s string) (rest string, checked, ok bool) {
+	switch {
+	case strings.HasPrefix(s, "[ ] "):
+		return s[4:], false, true
+	case strings.HasPrefix(s, "[x] "), strings.HasPrefix(s, "[X] "):
+		return s[4:], true, true
+	}
+	return s, false, false
+}
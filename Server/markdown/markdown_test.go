@@ -0,0 +1,143 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"blank", " \n\t\n", ""},
+		{"paragraphs", "a\nb\n\nc", "<p>a<br>\nb</p>\n<p>c</p>\n"},
+		{"heading", "## Title ##", "<h2>Title</h2>\n"},
+		{"not a heading", "#hashtag", "<p>#hashtag</p>\n"},
+		{"emphasis", "*a* **b** ***c*** ~~d~~", "<p><em>a</em> <strong>b</strong> <em><strong>c</strong></em> <del>d</del></p>\n"},
+		{"intraword underscore", "snake_case_name", "<p>snake_case_name</p>\n"},
+		{"unclosed emphasis", "**a", "<p>**a</p>\n"},
+		{"code span", "`a <b>` and `` ` ``", "<p><code>a &lt;b&gt;</code> and <code>`</code></p>\n"},
+		{"escapes", `\*a\* \<b\>`, "<p>*a* &lt;b&gt;</p>\n"},
+		{"fenced code", "```go\nif a < b {}\n```", "<pre><code class=\"language-go\">if a &lt; b {}\n</code></pre>\n"},
+		{"unclosed fence", "~~~\na", "<pre><code>a\n</code></pre>\n"},
+		{"quote", "> a\n> > b", "<blockquote>\n<p>a</p>\n<blockquote>\n<p>b</p>\n</blockquote>\n</blockquote>\n"},
+		{"rule", "a\n\n- - -", "<p>a</p>\n<hr>\n"},
+		{"bullets", "- a\n- b", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n"},
+		{"loose list", "1. a\n\n2. b", "<ol>\n<li><p>a</p></li>\n<li><p>b</p></li>\n</ol>\n"},
+		{"ordered start", "3) a", "<ol start=\"3\">\n<li>a</li>\n</ol>\n"},
+		{"task list", "- [ ] a\n- [x] b", "<ul>\n<li><input type=\"checkbox\" disabled> a</li>\n<li><input type=\"checkbox\" checked disabled> b</li>\n</ul>\n"},
+		{"nested list", "- a\n  - b", "<ul>\n<li>a\n<ul>\n<li>b</li>\n</ul></li>\n</ul>\n"},
+		{"link", `[the *docs*](https://example.com/a_(b) "Title")`, "<p><a href=\"https://example.com/a_(b)\" rel=\"nofollow noopener noreferrer\">the <em>docs</em></a></p>\n"},
+		{"relative link", "[x](/tasks?id=1&b=2)", "<p><a href=\"/tasks?id=1&amp;b=2\" rel=\"nofollow noopener noreferrer\">x</a></p>\n"},
+		{"image", "![a cat](cat.png)", "<p><img src=\"cat.png\" alt=\"a cat\"></p>\n"},
+		{"autolink", "<https://a.example> <me@example.com>", "<p><a href=\"https://a.example\" rel=\"nofollow noopener noreferrer\">https://a.example</a> <a href=\"mailto:me@example.com\" rel=\"nofollow noopener noreferrer\">me@example.com</a></p>\n"},
+		{"bare URL", "see www.example.com/a.", "<p>see <a href=\"http://www.example.com/a\" rel=\"nofollow noopener noreferrer\">www.example.com/a</a>.</p>\n"},
+		{"no link inside a link", "[https://a.example](https://b.example)", "<p><a href=\"https://b.example\" rel=\"nofollow noopener noreferrer\">https://a.example</a></p>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.src); got != tt.want {
+				t.Errorf("Render(%q) =\n%q\nwant\n%q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderXSS(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"script tag", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{"html block", "<div onclick=\"x()\">\n\nhi\n\n</div>", "<p>&lt;div onclick=&quot;x()&quot;&gt;</p>\n<p>hi</p>\n<p>&lt;/div&gt;</p>\n"},
+		{"entity", "&lt;b&gt; &#x3C;", "<p>&amp;lt;b&amp;gt; &amp;#x3C;</p>\n"},
+		{"javascript link", "[x](javascript:alert(1))", "<p>x</p>\n"},
+		{"mixed case scheme", "[x](JaVaScRiPt:alert(1))", "<p>x</p>\n"},
+		{"control characters in the scheme", "[x](java\x01script:alert(1))", "<p>x</p>\n"},
+		{"tab in the scheme", "[x](java\tscript:alert(1))", "<p>[x](java    script:alert(1))</p>\n"},
+		{"leading space", "[x]( javascript:alert(1))", "<p>x</p>\n"},
+		{"escaped colon", `[x](javascript\:alert(1))`, "<p>x</p>\n"},
+		{"angle bracket destination", "[x](<javascript:alert(1)>)", "<p>x</p>\n"},
+		{"data URL", "[x](data:text/html;base64,PHNjcmlwdD4=)", "<p>x</p>\n"},
+		{"vbscript", "[x](vbscript:msgbox)", "<p>x</p>\n"},
+		{"javascript image", "![x](javascript:alert(1))", "<p>x</p>\n"},
+		{"mailto image", "![x](mailto:a@b.c)", "<p>x</p>\n"},
+		{"javascript autolink", "<javascript:alert(1)>", "<p>&lt;javascript:alert(1)&gt;</p>\n"},
+		{"quote in the URL", `[x](https://a.example/"onmouseover="alert(1))`, "<p><a href=\"https://a.example/&quot;onmouseover=&quot;alert(1)\" rel=\"nofollow noopener noreferrer\">x</a></p>\n"},
+		{"quote in alt text", `![a" onerror="alert(1)](x.png)`, "<p><img src=\"x.png\" alt=\"a&quot; onerror=&quot;alert(1)\"></p>\n"},
+		{"code language", "```js\" onload=\"x\n```", "<pre><code></code></pre>\n"},
+		{"script in code", "`<script>`", "<p><code>&lt;script&gt;</code></p>\n"},
+		{"NUL", "a\x00b", "<p>a�b</p>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Render(tt.src)
+			if got != tt.want {
+				t.Errorf("Render(%q) =\n%q\nwant\n%q", tt.src, got, tt.want)
+			}
+			checkSafe(t, got)
+		})
+	}
+}
+
+// TestRenderBalanced renders awkward input and checks that only the tags
+// and attributes Render writes come out, each closed in order.
+func TestRenderBalanced(t *testing.T) {
+	tests := []string{
+		"*a **b* c**",
+		"[a *b](c) d*",
+		"> - a\n> - > b\n\n  c",
+		strings.Repeat("> ", 40) + "deep",
+		strings.Repeat("- ", 40) + "deep",
+		strings.Repeat("*", 40) + "a" + strings.Repeat("*", 40),
+		strings.Repeat("[", 40) + "a" + strings.Repeat("](b)", 40),
+		"- [ ] <img src=x onerror=alert(1)>",
+		"``` \n</code></pre><script>\n```",
+		"![<svg onload=alert(1)>](a.png)",
+		"[<b>](https://a.example) <a href=\"x\">",
+	}
+	for _, src := range tests {
+		checkSafe(t, Render(src))
+	}
+}
+
+var (
+	tagPattern = regexp.MustCompile(`<[^>]*>`)
+	urlPattern = regexp.MustCompile(`(?:href|src)="([^"]*)"`)
+	// safeTag matches every tag Render writes. Attribute values cannot
+	// hold a quote, so nothing can add an attribute of its own.
+	safeTag = regexp.MustCompile(`^<(/?(p|h[1-6]|em|strong|del|code|pre|blockquote|ul|ol|li)|br|hr|pre|ol start="\d+"|code class="language-[\w+-]+"|input type="checkbox"( checked)? disabled|a href="[^"<]*" rel="nofollow noopener noreferrer"|/a|img src="[^"<]*" alt="[^"<]*")>$`)
+)
+
+func checkSafe(t *testing.T, html string) {
+	t.Helper()
+	var open []string
+	for _, tag := range tagPattern.FindAllString(html, -1) {
+		if !safeTag.MatchString(tag) {
+			t.Errorf("unexpected tag %s in %q", tag, html)
+			continue
+		}
+		name := strings.Fields(strings.Trim(tag, "<>"))[0]
+		switch {
+		case name == "br" || name == "hr" || name == "input" || name == "img":
+		case strings.HasPrefix(name, "/"):
+			if len(open) == 0 || open[len(open)-1] != name[1:] {
+				t.Errorf("%s closes %v in %q", tag, open, html)
+				return
+			}
+			open = open[:len(open)-1]
+		default:
+			open = append(open, name)
+		}
+	}
+	if len(open) > 0 {
+		t.Errorf("%v left open in %q", open, html)
+	}
+	for _, m := range urlPattern.FindAllStringSubmatch(html, -1) {
+		if i := strings.IndexAny(m[1], ":/?#"); i >= 0 && m[1][i] == ':' {
+			if scheme := strings.ToLower(m[1][:i]); scheme != "http" && scheme != "https" && scheme != "mailto" {
+				t.Errorf("URL %q in %q", m[1], html)
+			}
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package metrics
+
+import (
+	"runtime"
+	"time"
+)
+
+var (
+	goroutines = NewGaugeVec("go_goroutines", "Number of goroutines that currently exist.")
+	heapAlloc  = NewGaugeVec("go_memstats_heap_alloc_bytes", "Bytes of allocated heap objects.")
+	uptime     = NewGaugeVec("process_uptime_seconds", "Seconds since the process started.")
+	started    = time.Now()
+)
+
+func init() {
+	Default.OnScrape(func() {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		goroutines.With().Set(float64(runtime.NumGoroutine()))
+		heapAlloc.With().Set(float64(m.HeapAlloc))
+		uptime.With().Set(time.Since(started).Seconds())
+	})
+}
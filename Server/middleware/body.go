@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+type bodyKey struct{}
+
+// limitedBody applies its limit when it is first read, so that handlers
+// further in can still change it.
+type limitedBody struct {
+	w     http.ResponseWriter
+	body  io.ReadCloser
+	limit int64
+	r     io.ReadCloser
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.r == nil {
+		b.r = http.MaxBytesReader(b.w, b.body, b.limit)
+	}
+	return b.r.Read(p)
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
+
+// MaxBodySize caps request bodies at limit bytes. Reading past the cap
+// fails with an *http.MaxBytesError, and the connection is closed after the
+// response. Routes that take bigger bodies, such as uploads, raise the cap
+// for their request with AllowBodySize.
+func MaxBodySize(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+			body := &limitedBody{w: w, body: r.Body, limit: limit}
+			r = r.WithContext(context.WithValue(r.Context(), bodyKey{}, body))
+			r.Body = body
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AllowBodySize sets the cap MaxBodySize put on r's body to n bytes. It
+// must be called before the body is read, and does nothing outside
+// MaxBodySize.
+func AllowBodySize(r *http.Request, n int64) {
+	if b, ok := r.Context().Value(bodyKey{}).(*limitedBody); ok && b.r == nil {
+		b.limit = n
+	}
+}
@@ -0,0 +1,14 @@
+//go:build brotli
+
+package middleware
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+func init() {
+	encodings["br"] = encoderPool(func(w io.Writer) encoder { return brotli.NewWriterLevel(w, brotli.DefaultCompression) })
+	preference = append([]string{"br"}, preference...)
+}
@@ -0,0 +1,231 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// encoder is a compressing writer that can be flushed mid-stream and reused.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encodings are the content codings Compress can answer with, by name, and
+// preference lists them best first. gzip is always there; brotli.go, built
+// with -tags brotli, adds br.
+var (
+	encodings  = map[string]*sync.Pool{"gzip": encoderPool(func(w io.Writer) encoder { return gzip.NewWriter(w) })}
+	preference = []string{"gzip"}
+)
+
+func encoderPool(newEncoder func(io.Writer) encoder) *sync.Pool {
+	return &sync.Pool{New: func() any { return newEncoder(io.Discard) }}
+}
+
+// Compress compresses responses of minSize bytes or more with the best
+// coding the client lists in Accept-Encoding. Only textual types, such as
+// JSON, HTML and CSS, are compressed; images, archives and other responses
+// that are compressed already, or that carry a Content-Encoding or
+// Content-Range, are passed as they are. Smaller responses are held back
+// until they reach minSize or end, so they go out with their Content-Length
+// as before. WebSocket upgrades are not touched.
+func Compress(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Accept-Encoding")
+			coding := negotiate(r.Header.Get("Accept-Encoding"))
+			if coding == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cw := &compressWriter{ResponseWriter: w, coding: coding, minSize: minSize}
+			next.ServeHTTP(cw, r)
+			// Not deferred: after a panic the response is Recover's to write.
+			cw.Close()
+		})
+	}
+}
+
+// negotiate picks the coding to answer a request with the given
+// Accept-Encoding header with, or "" for none.
+func negotiate(header string) string {
+	if header == "" {
+		return ""
+	}
+	accepted := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+	best, bestQ := "", 0.0
+	for _, name := range preference {
+		q, ok := accepted[name]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressible reports whether responses of contentType are worth
+// compressing. Event streams are left alone so each event is sent as it is
+// flushed.
+func compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml", "application/graphql-response+json", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// compressWriter holds back a response until it knows whether to compress
+// it: when minSize bytes have been written, or the handler returns or
+// flushes.
+type compressWriter struct {
+	http.ResponseWriter
+	coding  string
+	minSize int
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     encoder
+}
+
+func (c *compressWriter) WriteHeader(code int) {
+	// Informational responses, such as 103 Early Hints, go at once.
+	if c.decided || code < 200 {
+		c.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if c.status == 0 {
+		c.status = code
+	}
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) {
+	if !c.decided {
+		if c.status == 0 {
+			c.status = http.StatusOK
+		}
+		c.buf = append(c.buf, p...)
+		if len(c.buf) < c.minSize {
+			return len(p), nil
+		}
+		if err := c.decide(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if c.enc != nil {
+		return c.enc.Write(p)
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+// decide sends the header and what was held back, compressed if big is set
+// and the response is worth it.
+func (c *compressWriter) decide(big bool) error {
+	c.decided = true
+	h := c.Header()
+	if h.Get("Content-Type") == "" && len(c.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(c.buf))
+	}
+	if big && c.worthIt() {
+		h.Set("Content-Encoding", c.coding)
+		h.Del("Content-Length")
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			// The compressed bytes differ from those the strong ETag names.
+			h.Set("ETag", "W/"+etag)
+		}
+		c.enc = encodings[c.coding].Get().(encoder)
+		c.enc.Reset(c.ResponseWriter)
+	}
+	if c.status != 0 {
+		c.ResponseWriter.WriteHeader(c.status)
+	}
+	buf := c.buf
+	c.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if c.enc != nil {
+		_, err = c.enc.Write(buf)
+	} else {
+		_, err = c.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (c *compressWriter) worthIt() bool {
+	h := c.Header()
+	switch c.status {
+	case http.StatusNoContent, http.StatusPartialContent, http.StatusNotModified:
+		return false
+	}
+	return h.Get("Content-Encoding") == "" && h.Get("Content-Range") == "" && compressible(h.Get("Content-Type"))
+}
+
+// Flush sends what was held back, uncompressed as it is too small to
+// compress, and then flushes the connection.
+func (c *compressWriter) Flush() {
+	if !c.decided {
+		c.decide(false)
+	}
+	if c.enc != nil {
+		c.enc.Flush()
+	}
+	http.NewResponseController(c.ResponseWriter).Flush()
+}
+
+// Close ends the response, which was too small to compress if nothing has
+// been decided yet.
+func (c *compressWriter) Close() error {
+	if !c.decided {
+		if err := c.decide(false); err != nil {
+			return err
+		}
+	}
+	if c.enc == nil {
+		return nil
+	}
+	err := c.enc.Close()
+	c.enc.Reset(io.Discard)
+	encodings[c.coding].Put(c.enc)
+	c.enc = nil
+	return err
+}
+
+func (c *compressWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
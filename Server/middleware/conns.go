@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"starttech-server/metrics"
+)
+
+var (
+	httpConnections = metrics.NewCounterVec("http_connections_total",
+		"Connections accepted, by server.", "server")
+	httpConnectionsOpen = metrics.NewGaugeVec("http_connections_open",
+		"Connections open, by server and state: new, active while serving requests, or idle between them.", "server", "state")
+	httpConnectionsHijacked = metrics.NewCounterVec("http_connections_hijacked_total",
+		"Connections handed over to a handler, such as WebSockets, by server.", "server")
+	httpConnectionDuration = metrics.NewHistogramVec("http_connection_duration_seconds",
+		"How long connections stayed open, by server.", connectionBuckets, "server")
+)
+
+// connectionBuckets spans short REST calls to event streams held for hours.
+var connectionBuckets = []float64{.1, 1, 10, 60, 300, 900, 3600, 4 * 3600, 24 * 3600}
+
+type connInfo struct {
+	state  http.ConnState
+	opened time.Time
+}
+
+// ConnState returns a hook for http.Server.ConnState that keeps the
+// connection metrics of the server named server. A hijacked connection
+// leaves the metrics when it is hijacked, since the server no longer sees
+// it close.
+func ConnState(server string) func(net.Conn, http.ConnState) {
+	var (
+		mu    sync.Mutex
+		conns = make(map[net.Conn]connInfo)
+	)
+	return func(c net.Conn, state http.ConnState) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		info, ok := conns[c]
+		if ok {
+			httpConnectionsOpen.With(server, info.state.String()).Dec()
+		} else {
+			httpConnections.With(server).Inc()
+			info.opened = time.Now()
+		}
+		switch state {
+		case http.StateHijacked:
+			httpConnectionsHijacked.With(server).Inc()
+			delete(conns, c)
+		case http.StateClosed:
+			httpConnectionDuration.With(server).ObserveSince(info.opened)
+			delete(conns, c)
+		default:
+			info.state = state
+			conns[c] = info
+			httpConnectionsOpen.With(server, state.String()).Inc()
+		}
+	}
+}
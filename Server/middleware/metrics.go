@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"starttech-server/apierror"
+	"starttech-server/metrics"
+)
+
+var (
+	httpRequests = metrics.NewCounterVec("http_requests_total",
+		"HTTP requests served, by method, route pattern and status code.", "method", "route", "status")
+	httpDuration = metrics.NewHistogramVec("http_request_duration_seconds",
+		"Time to serve HTTP requests, by method and route pattern.", nil, "method", "route")
+	httpInFlight = metrics.NewGaugeVec("http_requests_in_flight",
+		"HTTP requests currently being served.")
+	httpProtocols = metrics.NewCounterVec("http_requests_protocol_total",
+		"HTTP requests served, by protocol: HTTP/1.1 or HTTP/2.0.", "protocol")
+)
+
+type routeKey struct{}
+
+// routeLabel is filled in by the innermost RoutePattern wrapper.
+type routeLabel struct{ pattern string }
+
+// Metrics records request counts, latencies and concurrency. Requests are
+// labelled with the ServeMux pattern that matched, as reported by
+// RoutePattern, so path parameters do not explode label cardinality.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		httpInFlight.With().Inc()
+		defer httpInFlight.With().Dec()
+
+		r, label := withRouteLabel(r)
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		route := label.pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		status := rec.status
+		if status == 0 {
+			status = http.StatusSwitchingProtocols
+		}
+		httpRequests.With(r.Method, route, strconv.Itoa(status)).Inc()
+		httpDuration.With(r.Method, route).ObserveSince(start)
+		httpProtocols.With(r.Proto).Inc()
+	})
+}
+
+// withRouteLabel returns r with a routeLabel for RoutePattern to fill in,
+// reusing the one an outer middleware added.
+func withRouteLabel(r *http.Request) (*http.Request, *routeLabel) {
+	if label, ok := r.Context().Value(routeKey{}).(*routeLabel); ok {
+		return r, label
+	}
+	label := &routeLabel{}
+	return r.WithContext(context.WithValue(r.Context(), routeKey{}, label)), label
+}
+
+// RoutePattern wraps a ServeMux so the pattern it matched is reported to
+// Metrics and Trace. When muxes are nested, the innermost match wins.
+// Requests the mux has no route for are answered with a JSON 404, or 405
+// when the path has routes for other methods, in place of the mux's plain
+// text.
+func RoutePattern(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern == "" {
+			notRouted(mux, w, r)
+			return
+		}
+		mux.ServeHTTP(w, r)
+		if label, ok := r.Context().Value(routeKey{}).(*routeLabel); ok && label.pattern == "" {
+			label.pattern = r.Pattern
+		}
+	})
+}
+
+// notRouted lets mux answer a request it has no route for, only to learn
+// whether that is a 404 or a 405 and which methods are allowed.
+func notRouted(mux *http.ServeMux, w http.ResponseWriter, r *http.Request) {
+	rec := &discardRecorder{header: http.Header{}}
+	mux.ServeHTTP(rec, r)
+	if rec.status != http.StatusMethodNotAllowed {
+		apierror.Write(w, http.StatusNotFound, "no route matches "+r.URL.Path)
+		return
+	}
+	allow := rec.header.Get("Allow")
+	w.Header().Set("Allow", allow)
+	apierror.WriteError(w, http.StatusMethodNotAllowed, apierror.Error{
+		Message: r.Method + " is not allowed on " + r.URL.Path,
+		Details: map[string][]string{"allow": strings.Split(allow, ", ")},
+	})
+}
+
+// discardRecorder keeps the status and headers of a response and drops its
+// body.
+type discardRecorder struct {
+	header http.Header
+	status int
+}
+
+func (d *discardRecorder) Header() http.Header         { return d.header }
+func (d *discardRecorder) Write(b []byte) (int, error) { return len(b), nil }
+func (d *discardRecorder) WriteHeader(code int) {
+	if d.status == 0 {
+		d.status = code
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"starttech-server/apierror"
+)
+
+// Recover turns a panic in a handler into a 500 JSON error, where the
+// response has not been started yet, and logs it with its stack trace.
+// Mount it inside RequestID and Logger, so the log line carries the request
+// ID and the access log records the 500. http.ErrAbortHandler is passed on,
+// since it is how handlers mean to abort.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			slog.ErrorContext(r.Context(), "handler panicked",
+				"method", r.Method, "path", r.URL.Path, "panic", v, "stack", string(debug.Stack()))
+			if rec.status != 0 {
+				// Part of the response is out; all that can be done is to
+				// cut it short.
+				panic(http.ErrAbortHandler)
+			}
+			w.Header().Set("Connection", "close")
+			apierror.Write(w, http.StatusInternalServerError, "internal error")
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"starttech-server/tracing"
+)
+
+// Trace serves every request in a server span, continuing the trace of a
+// traceparent header from the client, and names the trace in the
+// X-Trace-ID response header. Spans are named after the route pattern
+// reported by RoutePattern. Mount it inside RequestID and outside Logger,
+// so access log lines carry the trace ID. It does nothing while tracing is
+// off.
+func Trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tracing.WithRemoteParent(r.Context(), r.Header.Get("traceparent"))
+		ctx, span := tracing.Start(ctx, r.Method, tracing.KindServer,
+			slog.String("http.request.method", r.Method),
+			slog.String("url.path", r.URL.Path),
+			slog.String("user_agent.original", r.UserAgent()),
+			slog.String("client.address", r.RemoteAddr),
+		)
+		if span == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		defer span.End()
+		w.Header().Set(tracing.TraceIDHeader, span.Context().TraceID.String())
+
+		r, label := withRouteLabel(r.WithContext(ctx))
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusSwitchingProtocols
+		}
+		span.SetAttributes(slog.Int("http.response.status_code", status))
+		if route := label.pattern; route != "" {
+			// Patterns may or may not start with the method.
+			route = strings.TrimPrefix(route, r.Method+" ")
+			span.Rename(r.Method + " " + route)
+			span.SetAttributes(slog.String("http.route", route))
+		}
+		if status >= 500 {
+			span.SetError(fmt.Errorf("answered %d", status))
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"starttech-server/config"
+	"starttech-server/logging"
+	"starttech-server/storage"
+)
+
+const migrateUsage = `usage: %[1]s migrate up [VERSION] [flags]
+       %[1]s migrate down [VERSION] [flags]
+       %[1]s migrate status [flags]
+
+up applies the pending migrations, or those up to VERSION. down undoes the
+latest migration applied, or those after VERSION; down 0 undoes them all.
+status lists every migration and when it was applied. The flags are the
+server's, such as -config and -database-url.
+`
+
+// migrate runs the migrate subcommand with args, which follow "migrate",
+// and returns the exit status.
+func migrate(args []string) int {
+	usage := func() int {
+		fmt.Fprintf(os.Stderr, migrateUsage, os.Args[0])
+		return 2
+	}
+	if len(args) == 0 {
+		return usage()
+	}
+	action, args := args[0], args[1:]
+	target := -1
+	if len(args) > 0 && action != "status" {
+		if v, err := strconv.Atoi(args[0]); err == nil {
+			target, args = v, args[1:]
+		}
+	}
+	switch action {
+	case "up", "down", "status":
+	default:
+		return usage()
+	}
+
+	cfg, err := config.Load(args, os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if cfg.Database.URL == "" {
+		fmt.Fprintln(os.Stderr, "migrate: no database is configured; set DATABASE_URL")
+		return 2
+	}
+	logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	slog.SetDefault(logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	store, err := storage.OpenSQL(ctx, cfg.Database.URL, storage.Pool{MaxOpen: 1, MaxIdle: 1})
+	if err != nil {
+		logger.Error("migrate failed", "err", err)
+		return 1
+	}
+	defer store.Close()
+
+	switch action {
+	case "up":
+		if target < 0 {
+			target = storage.LatestMigration()
+		}
+		err = store.MigrateTo(ctx, target)
+	case "down":
+		if target < 0 {
+			target, err = previousVersion(ctx, store)
+		}
+		if err == nil {
+			err = store.MigrateTo(ctx, target)
+		}
+	case "status":
+		err = printMigrations(ctx, store)
+	}
+	if err != nil {
+		logger.Error("migrate failed", "err", err)
+		return 1
+	}
+	return 0
+}
+
+// previousVersion returns the version before the latest one applied.
+func previousVersion(ctx context.Context, store *storage.SQLStore) (int, error) {
+	ms, err := store.Migrations(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i := len(ms) - 1; i >= 0; i-- {
+		if ms[i].AppliedAt != nil {
+			return ms[i].Version - 1, nil
+		}
+	}
+	return 0, errors.New("no migration has been applied")
+}
+
+func printMigrations(ctx context.Context, store *storage.SQLStore) error {
+	ms, err := store.Migrations(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED\tREVERSIBLE")
+	for _, m := range ms {
+		applied := "pending"
+		if m.AppliedAt != nil {
+			applied = m.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		reversible := "yes"
+		if !m.Reversible {
+			reversible = "no"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", m.Version, m.Name, applied, reversible)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Activity records one change made through the API: who did what, when,
+// and for updates the values before and after. Action is the name of the
+// matching event type, such as "task.updated", and SubjectID the resource
+// it happened to: a task, comment, attachment, project, or the user whose
+// membership changed.
+//
+// TaskID and ProjectID place the entry in the feeds of a task and of a
+// project. Entries outlive what they describe, so deleting a task keeps its
+// history in the project's feed.
+type Activity struct {
+	ID        string    `json:"id"`
+	OrgID     string    `json:"org_id"`
+	ProjectID *string   `json:"project_id"`
+	TaskID    *string   `json:"task_id"`
+	ActorID   string    `json:"actor_id"`
+	Action    string    `json:"action"`
+	SubjectID string    `json:"subject_id"`
+	Changes   []Change  `json:"changes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Change is one field that an update modified, with its JSON values before
+// and after.
+type Change struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old"`
+	New   json.RawMessage `json:"new"`
+}
+
+// ActivityPage is one page of an activity feed, newest first. NextCursor is
+// empty on the last page.
+type ActivityPage struct {
+	Items      []Activity `json:"items"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// Usage counts what an organization, or the whole server, holds. Users
+// counts an organization's members; Orgs is only set for the whole server.
+// Trashed tasks are counted until they are purged.
+type Usage struct {
+	Orgs            int   `json:"orgs,omitempty"`
+	Users           int   `json:"users"`
+	Projects        int   `json:"projects"`
+	Tasks           int   `json:"tasks"`
+	Comments        int   `json:"comments"`
+	Attachments     int   `json:"attachments"`
+	AttachmentBytes int64 `json:"attachment_bytes"`
+}
+
+// AdminUser is a user as administrators see it, with the organizations
+// they belong to and how many sessions they are signed in with.
+type AdminUser struct {
+	User
+	Orgs     []Org `json:"orgs"`
+	Sessions int   `json:"sessions"`
+}
+
+// UserPage is one page of a user listing, oldest first. NextCursor is empty
+// on the last page.
+type UserPage struct {
+	Items      []User `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// AdminOrg is an organization as administrators see it, with its usage.
+type AdminOrg struct {
+	Org
+	Usage Usage `json:"usage"`
+}
+
+// AdminOrgPage is one page of an organization listing, oldest first.
+// NextCursor is empty on the last page.
+type AdminOrgPage struct {
+	Items      []AdminOrg `json:"items"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// PurgePreview is what a scheduled purge would delete if it swept now:
+// the Count records of Kind from before Before, which is RetentionSeconds
+// ago.
+type PurgePreview struct {
+	Kind             string    `json:"kind"`
+	RetentionSeconds int64     `json:"retention_seconds"`
+	Before           time.Time `json:"before"`
+	Count            int       `json:"count"`
+}
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxAPIKeyNameLen bounds APIKey.Name.
+const MaxAPIKeyNameLen = 100
+
+// KeyScope is what an API key may do.
+type KeyScope string
+
+const (
+	// ScopeRead keys may only make requests that change nothing: GET, HEAD
+	// and OPTIONS.
+	ScopeRead KeyScope = "read"
+	// ScopeReadWrite keys may do whatever their owner can.
+	ScopeReadWrite KeyScope = "read-write"
+	// ScopeSCIM keys are for identity providers, and only work on the SCIM
+	// routes that provision users and groups. Only organization admins
+	// and owners may create them.
+	ScopeSCIM KeyScope = "scim"
+)
+
+// Valid reports whether s is a known scope.
+func (s KeyScope) Valid() bool {
+	return s == ScopeRead || s == ScopeReadWrite || s == ScopeSCIM
+}
+
+// APIKey lets scripts and other services call the API as its owner, in the
+// organization it was created in, without signing in.
+type APIKey struct {
+	ID      string   `json:"id"`
+	OrgID   string   `json:"org_id"`
+	OwnerID string   `json:"owner_id"`
+	Name    string   `json:"name"`
+	Scope   KeyScope `json:"scope"`
+	// Prefix is the start of the key, enough to recognize it by.
+	Prefix string `json:"prefix"`
+	// Key is only included in the response that creates the key; only its
+	// hash is stored.
+	Key        string     `json:"key,omitempty"`
+	KeyHash    string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	// Unused is set on keys that have not been used for a long while, and
+	// are probably safe to revoke.
+	Unused bool `json:"unused"`
+}
+
+// Validate reports every field of k that breaks the API's rules.
+func (k *APIKey) Validate() error {
+	var v ValidationError
+	switch {
+	case k.Name == "":
+		v.Add("name", "is required")
+	case utf8.RuneCountInString(k.Name) > MaxAPIKeyNameLen:
+		v.Add("name", fmt.Sprintf("must be at most %d characters", MaxAPIKeyNameLen))
+	}
+	if !k.Scope.Valid() {
+		v.Add("scope", `must be "read", "read-write" or "scim"`)
+	}
+	return v.Err()
+}
+
+// APIKeyInput is the body accepted by POST /settings/api-keys. Scope
+// defaults to read.
+type APIKeyInput struct {
+	Name  string   `json:"name"`
+	Scope KeyScope `json:"scope,omitempty"`
+}
+
+// Apply copies in onto k.
+func (in APIKeyInput) Apply(k *APIKey) {
+	k.Name = strings.TrimSpace(in.Name)
+	k.Scope = in.Scope
+	if k.Scope == "" {
+		k.Scope = ScopeRead
+	}
+}
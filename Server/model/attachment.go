@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// MaxFilenameLen bounds Attachment.Filename.
+const MaxFilenameLen = 255
+
+// AttachmentStatus tells whether an attachment may be downloaded.
+type AttachmentStatus string
+
+const (
+	// AttachmentPending is an upload waiting for its virus scan.
+	AttachmentPending AttachmentStatus = "pending"
+	// AttachmentClean is an upload that passed its scan, or that was
+	// never scanned because no scanner is set up. Only clean attachments
+	// can be downloaded.
+	AttachmentClean AttachmentStatus = "clean"
+	// AttachmentQuarantined is an upload the scan flagged. Its bytes are
+	// kept apart, for an administrator to look into, until it is deleted.
+	AttachmentQuarantined AttachmentStatus = "quarantined"
+)
+
+// Attachment is a file uploaded to a task. The bytes live in blob storage
+// under Key; the rest is kept with the task. Threat names what the scan
+// found in a quarantined file.
+type Attachment struct {
+	ID          string           `json:"id"`
+	TaskID      string           `json:"task_id"`
+	UploaderID  string           `json:"uploader_id"`
+	Filename    string           `json:"filename"`
+	ContentType string           `json:"content_type"`
+	Size        int64            `json:"size"`
+	Key         string           `json:"-"`
+	Status      AttachmentStatus `json:"status"`
+	Threat      string           `json:"threat,omitempty"`
+	ScannedAt   *time.Time       `json:"scanned_at,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	// URL is a signed link the file can be downloaded from without a
+	// token until URLExpiresAt. It is issued with every response for
+	// clean attachments, never stored. Images also get ThumbnailURL,
+	// which takes a w parameter with the width wanted.
+	URL          string     `json:"url,omitempty"`
+	ThumbnailURL string     `json:"thumbnail_url,omitempty"`
+	URLExpiresAt *time.Time `json:"url_expires_at,omitempty"`
+}
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Audit actions name the security-relevant events the audit log keeps.
+const (
+	AuditLogin          = "auth.login"
+	AuditLoginFailed    = "auth.login_failed"
+	AuditAccessDenied   = "access.denied"
+	AuditTwoFactorOn    = "auth.2fa_enabled"
+	AuditTwoFactorOff   = "auth.2fa_disabled"
+	AuditRecoveryCodes  = "auth.recovery_codes_regenerated"
+	AuditSSOUpdated     = "org.sso_updated"
+	AuditSSODeleted     = "org.sso_deleted"
+	AuditDomainVerified = "org.domain_verified"
+	AuditDomainRemoved  = "org.domain_removed"
+	AuditUserDisabled   = "admin.user_disabled"
+	AuditUserEnabled    = "admin.user_enabled"
+	AuditPasswordReset  = "admin.password_reset"
+	AuditImpersonation  = "admin.impersonation"
+	AuditBackup         = "admin.backup"
+	AuditRestore        = "admin.restore"
+	AuditJobRetried     = "admin.job_retried"
+	AuditJobDeleted     = "admin.job_deleted"
+	AuditFlagChanged    = "admin.flag_changed"
+
+	AuditDeletionRequested = "account.deletion_requested"
+	AuditDeletionCancelled = "account.deletion_cancelled"
+	AuditAccountErased     = "account.erased"
+)
+
+// AuditEvent is one entry of the audit log: a sign-in, a failed one, a
+// change to a user's two-factor authentication or an organization's single
+// sign-on, a request refused for lack of permission, or an administrator's
+// action.
+// ActorID is whoever acted, empty when a failed sign-in named no known
+// account, and TargetID what they acted on, such as a user or job.
+//
+// Events form a chain in the order of Seq: Hash covers the event's fields
+// and PrevHash, the Hash of the event before it, so changing, removing or
+// reordering a stored event breaks every hash after it. Entries are never
+// changed once recorded, except by Redact when an account is erased, which
+// rehashes every event after the first one redacted and is itself recorded.
+type AuditEvent struct {
+	ID        string    `json:"id"`
+	Seq       int64     `json:"seq"`
+	Action    string    `json:"action"`
+	ActorID   string    `json:"actor_id,omitempty"`
+	OrgID     string    `json:"org_id,omitempty"`
+	TargetID  string    `json:"target_id,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	PrevHash  string    `json:"prev_hash"`
+	Hash      string    `json:"hash"`
+}
+
+// Redact clears what e says about the user with the given ID and email,
+// for when their account is erased: the address and browser they acted
+// from, and the detail of the events they acted in, were the target of,
+// or whose detail names their email. It reports whether it cleared
+// anything.
+func (e *AuditEvent) Redact(userID, email string) bool {
+	actor := e.ActorID == userID
+	if !actor && e.TargetID != userID && (email == "" || !strings.Contains(strings.ToLower(e.Detail), strings.ToLower(email))) {
+		return false
+	}
+	if actor {
+		e.IP, e.UserAgent = "", ""
+	}
+	e.Detail = ""
+	return true
+}
+
+// AuditPage is one page of the audit log, newest first. NextCursor is
+// empty on the last page.
+type AuditPage struct {
+	Items      []AuditEvent `json:"items"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// AuditVerification reports whether the hashes of the stored audit log
+// still match its events. Checked counts the events looked at; when OK is
+// false, BrokenAt is the Seq of the first event whose hash is wrong.
+type AuditVerification struct {
+	OK       bool  `json:"ok"`
+	Checked  int   `json:"checked"`
+	BrokenAt int64 `json:"broken_at,omitempty"`
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// BackupFormat is the version of the archives POST /admin/backup writes.
+// POST /admin/restore refuses archives of a later format.
+const BackupFormat = 1
+
+// Record types of a backup, in the order they are written. Every record
+// refers only to records written before it.
+const (
+	BackupHeaderRecord     = "header"
+	BackupUserRecord       = "user"
+	BackupOrgMemberRecord  = "org_member"
+	BackupProjectRecord    = "project"
+	BackupMemberRecord     = "project_member"
+	BackupFieldRecord      = "custom_field"
+	BackupTagRecord        = "tag"
+	BackupTaskRecord       = "task"
+	BackupDependencyRecord = "dependency"
+	BackupChecklistRecord  = "checklist_item"
+	BackupCommentRecord    = "comment"
+	BackupTimeEntryRecord  = "time_entry"
+	BackupAttachmentRecord = "attachment"
+	BackupViewRecord       = "view"
+	BackupTemplateRecord   = "template"
+)
+
+// BackupRecord is one line of the backup.jsonl file of a backup: a record
+// of the given type, whose data is the matching model type, such as a Task
+// for "task".
+type BackupRecord struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// BackupHeader is the first record of a backup: the organization backed up
+// and when.
+type BackupHeader struct {
+	Format    int       `json:"format"`
+	Org       Org       `json:"org"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BackupUser is a user the records of a backup refer to, with the
+// password hash they sign in with, so that a restore on another server
+// keeps their password.
+type BackupUser struct {
+	ID              string     `json:"id"`
+	Email           string     `json:"email"`
+	Username        string     `json:"username"`
+	PasswordHash    string     `json:"password_hash"`
+	Timezone        string     `json:"timezone"`
+	Language        string     `json:"language,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	DisabledAt      *time.Time `json:"disabled_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// BackupInput is the body accepted by POST /admin/backup. OrgID defaults to
+// the administrator's current organization.
+type BackupInput struct {
+	OrgID string `json:"org_id,omitempty"`
+}
+
+// RestoreResult is the response to POST /admin/restore: the organization
+// created, how many records of each type it was given, and the users who
+// had no account on this server before. Warnings describe records that
+// were left out.
+type RestoreResult struct {
+	Org          Org            `json:"org"`
+	Counts       map[string]int `json:"counts"`
+	UsersCreated int            `json:"users_created"`
+	Warnings     []string       `json:"warnings"`
+}
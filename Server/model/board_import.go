@@ -0,0 +1,102 @@
+package model
+
+import "time"
+
+// MaxBoardImportCards bounds the cards of one board import, across all of
+// its boards.
+const MaxBoardImportCards = 5000
+
+// ImportSource names the tool a board import reads the export of.
+type ImportSource string
+
+const (
+	ImportTrello ImportSource = "trello"
+	ImportJira   ImportSource = "jira"
+)
+
+// BoardImportStatus is where a board import stands.
+type BoardImportStatus string
+
+const (
+	BoardImportPending   BoardImportStatus = "pending"
+	BoardImportRunning   BoardImportStatus = "running"
+	BoardImportSucceeded BoardImportStatus = "succeeded"
+	BoardImportFailed    BoardImportStatus = "failed"
+)
+
+// BoardImport brings the boards of another tool into the organization, each
+// as a new project whose columns are the board's lists and whose tasks are
+// its cards. It runs in the background on behalf of UserID, who owns the
+// projects; Done counts the cards of Total handled so far. Boards is the
+// export as read when the import was started, and is dropped once it
+// finishes.
+type BoardImport struct {
+	ID         string             `json:"id"`
+	OrgID      string             `json:"org_id"`
+	UserID     string             `json:"user_id"`
+	Source     ImportSource       `json:"source"`
+	Status     BoardImportStatus  `json:"status"`
+	Total      int                `json:"total"`
+	Done       int                `json:"done"`
+	Summary    BoardImportSummary `json:"summary"`
+	Error      string             `json:"error,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	FinishedAt *time.Time         `json:"finished_at"`
+	Boards     []Board            `json:"-"`
+}
+
+// Finished reports whether the import has stopped for good.
+func (im *BoardImport) Finished() bool {
+	return im.Status == BoardImportSucceeded || im.Status == BoardImportFailed
+}
+
+// Board is a board of an export, ready to become a project. Every card's
+// Status is the key of one of Columns.
+type Board struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Columns     Workflow `json:"columns"`
+	Cards       []Card   `json:"cards"`
+}
+
+// Card is a card or issue of an export, ready to become a task. Labels are
+// tag names, created for the importing user as needed.
+type Card struct {
+	Title       string               `json:"title"`
+	Description string               `json:"description"`
+	Status      Status               `json:"status"`
+	Priority    Priority             `json:"priority,omitempty"`
+	DueDate     *time.Time           `json:"due_date,omitempty"`
+	Labels      []string             `json:"labels,omitempty"`
+	Checklist   []ChecklistItemInput `json:"checklist,omitempty"`
+}
+
+// BoardImportSummary reports what an import has done so far. Skipped counts
+// the cards that could not become tasks, and Warnings say why, up to
+// MaxImportWarnings of them.
+type BoardImportSummary struct {
+	Projects []ImportedProject `json:"projects"`
+	Created  int               `json:"created"`
+	Skipped  int               `json:"skipped"`
+	Warnings []string          `json:"warnings"`
+}
+
+// BoardImportPreview is what a board import would create, as answered to
+// POST /import/trello and /import/jira with dry_run=true.
+type BoardImportPreview struct {
+	DryRun  bool               `json:"dry_run"`
+	Source  ImportSource       `json:"source"`
+	Total   int                `json:"total"`
+	Summary BoardImportSummary `json:"summary"`
+}
+
+// MaxImportWarnings bounds BoardImportSummary.Warnings.
+const MaxImportWarnings = 50
+
+// ImportedProject is a project created by a board import, with the number
+// of tasks created in it.
+type ImportedProject struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Tasks int    `json:"tasks"`
+}
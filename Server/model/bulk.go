@@ -0,0 +1,57 @@
+package model
+
+import "starttech-server/apierror"
+
+// MaxBulkOperations bounds the operations of one POST /tasks/bulk.
+const MaxBulkOperations = 100
+
+// BulkOp names the kind of a BulkOperation.
+type BulkOp string
+
+const (
+	BulkCreate BulkOp = "create"
+	BulkUpdate BulkOp = "update"
+	BulkDelete BulkOp = "delete"
+	BulkMove   BulkOp = "move"
+)
+
+// BulkOperation is one item of POST /tasks/bulk. A create takes Task; the
+// others name the task by ID and take Patch, Children (as the query
+// parameter of DELETE /tasks/{id}) or Move. Version, when set, must be the
+// current version of the task, as with If-Match.
+type BulkOperation struct {
+	Op       BulkOp     `json:"op"`
+	ID       string     `json:"id,omitempty"`
+	Version  int64      `json:"version,omitempty"`
+	Task     *TaskInput `json:"task,omitempty"`
+	Patch    *TaskPatch `json:"patch,omitempty"`
+	Children string     `json:"children,omitempty"`
+	Move     *MoveInput `json:"move,omitempty"`
+}
+
+// BulkInput is the body accepted by POST /tasks/bulk. The operations run
+// in order, in a single transaction.
+type BulkInput struct {
+	Operations []BulkOperation `json:"operations"`
+}
+
+// BulkResult reports on every operation of a BulkInput, in the same order.
+// Committed is false for a dry run, and if one of them failed, in which
+// case nothing was changed.
+type BulkResult struct {
+	DryRun    bool          `json:"dry_run"`
+	Committed bool          `json:"committed"`
+	Results   []BulkOutcome `json:"results"`
+}
+
+// BulkOutcome is the result of one bulk operation: the HTTP status it would
+// have had on its own endpoint, and the task or the error. Operations left
+// undone because another one failed have status 424. For a dry run, Task
+// is also the task a delete would send to the trash, and Affected lists
+// the other tasks the operation would change, as they would be.
+type BulkOutcome struct {
+	Status   int             `json:"status"`
+	Task     *Task           `json:"task,omitempty"`
+	Affected []Task          `json:"affected,omitempty"`
+	Error    *apierror.Error `json:"error,omitempty"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// CalendarFeed is a user's private iCalendar feed of their tasks with due
+// dates in one organization, for calendar apps to subscribe to. Only a
+// hash of its token is stored; the token and the URL built from it are
+// returned once, when the feed is created.
+type CalendarFeed struct {
+	UserID    string    `json:"user_id"`
+	OrgID     string    `json:"org_id"`
+	Token     string    `json:"token,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	TokenHash string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits on checklists.
+const (
+	MaxChecklistItems    = 200
+	MaxChecklistTitleLen = 200
+)
+
+// ChecklistItem is one step of a task's checklist. Position orders the items
+// of a task, from 1.
+type ChecklistItem struct {
+	ID        string    `json:"id"`
+	TaskID    string    `json:"task_id"`
+	Title     string    `json:"title"`
+	Done      bool      `json:"done"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate reports every field of c that breaks the API's rules.
+func (c *ChecklistItem) Validate() error {
+	var v ValidationError
+	switch {
+	case c.Title == "":
+		v.Add("title", "is required")
+	case utf8.RuneCountInString(c.Title) > MaxChecklistTitleLen:
+		v.Add("title", fmt.Sprintf("must be at most %d characters", MaxChecklistTitleLen))
+	}
+	return v.Err()
+}
+
+// ChecklistProgress counts the items of a task's checklist and how many are
+// done.
+type ChecklistProgress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// ChecklistItemInput is the body accepted by POST /tasks/{id}/checklist.
+type ChecklistItemInput struct {
+	Title string `json:"title"`
+	Done  bool   `json:"done,omitempty"`
+}
+
+// Apply copies in onto c.
+func (in ChecklistItemInput) Apply(c *ChecklistItem) {
+	c.Title = strings.TrimSpace(in.Title)
+	c.Done = in.Done
+}
+
+// ChecklistItemPatch is the body accepted by PATCH
+// /tasks/{id}/checklist/{item_id}. Nil fields are left unchanged.
+type ChecklistItemPatch struct {
+	Title *string `json:"title"`
+	Done  *bool   `json:"done"`
+}
+
+// Apply copies the set fields of p onto c.
+func (p ChecklistItemPatch) Apply(c *ChecklistItem) {
+	if p.Title != nil {
+		c.Title = strings.TrimSpace(*p.Title)
+	}
+	if p.Done != nil {
+		c.Done = *p.Done
+	}
+}
+
+// ChecklistOrder is the body accepted by PUT /tasks/{id}/checklist/order:
+// every item of the checklist, first to last.
+type ChecklistOrder struct {
+	ItemIDs []string `json:"item_ids"`
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"starttech-server/markdown"
+)
+
+// MaxCommentLen bounds Comment.Body.
+const MaxCommentLen = 5000
+
+// Comment is a message left on a task. ReplyTo, when set, is the comment it
+// answers, which belongs to the same task; clients build threads from it.
+// EditedAt is nil until the author first changes the body, and every earlier
+// body is kept as a CommentEdit.
+type Comment struct {
+	ID       string  `json:"id"`
+	TaskID   string  `json:"task_id"`
+	AuthorID string  `json:"author_id"`
+	Username string  `json:"username"`
+	ReplyTo  *string `json:"reply_to"`
+	Body     string  `json:"body"`
+	// BodyHTML is Body, which is Markdown, rendered; it is filled in
+	// whenever the comment is encoded.
+	BodyHTML string `json:"body_html"`
+	// Replies counts the comments answering this one, so clients can
+	// show collapsed threads.
+	Replies   int        `json:"replies"`
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at"`
+}
+
+// MarshalJSON encodes c with its body rendered.
+func (c Comment) MarshalJSON() ([]byte, error) {
+	type plain Comment
+	p := plain(c)
+	p.BodyHTML = markdown.Render(c.Body)
+	return json.Marshal(p)
+}
+
+// Validate reports every field of c that breaks the API's rules.
+func (c *Comment) Validate() error {
+	var v ValidationError
+	switch {
+	case c.Body == "":
+		v.Add("body", "is required")
+	case utf8.RuneCountInString(c.Body) > MaxCommentLen:
+		v.Add("body", fmt.Sprintf("must be at most %d characters", MaxCommentLen))
+	}
+	return v.Err()
+}
+
+// CommentEdit is a body a comment had before it was edited. EditedAt is
+// when it was replaced.
+type CommentEdit struct {
+	CommentID string    `json:"comment_id"`
+	Body      string    `json:"body"`
+	EditedAt  time.Time `json:"edited_at"`
+}
+
+// CommentInput is the body accepted by POST /tasks/{id}/comments.
+type CommentInput struct {
+	Body    string  `json:"body"`
+	ReplyTo *string `json:"reply_to,omitempty"`
+}
+
+// Apply copies in onto c.
+func (in CommentInput) Apply(c *Comment) {
+	c.Body = strings.TrimSpace(in.Body)
+	c.ReplyTo = in.ReplyTo
+}
+
+// CommentPatch is the body accepted by PATCH
+// /tasks/{id}/comments/{comment_id}.
+type CommentPatch struct {
+	Body string `json:"body"`
+}
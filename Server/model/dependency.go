@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// Dependency records that the task TaskID is blocked by BlockerID: it
+// should not be done before BlockerID is.
+type Dependency struct {
+	TaskID    string    `json:"task_id"`
+	BlockerID string    `json:"blocker_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Dependencies are the tasks directly blocking a task and those it blocks
+// in turn. Tasks the caller cannot see are left out.
+type Dependencies struct {
+	BlockedBy []Task `json:"blocked_by"`
+	Blocking  []Task `json:"blocking"`
+}
+
+// DependencyGraph holds every task a task is connected to through
+// dependencies, in either direction and at any distance, with the edges
+// between them. Tasks the caller cannot see are left out, along with what
+// is only reachable through them.
+type DependencyGraph struct {
+	Tasks []Task       `json:"tasks"`
+	Edges []Dependency `json:"edges"`
+}
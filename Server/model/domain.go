@@ -0,0 +1,53 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Domain is an email domain an organization claims. The claim only counts
+// once VerifiedAt is set, after the organization proved it controls the
+// domain by publishing a DNS TXT record named by Record that holds
+// Value(): single sign-on and SCIM provisioning are limited to the
+// verified domains of the organization. One organization at most has a
+// domain verified.
+type Domain struct {
+	OrgID      string     `json:"org_id"`
+	Domain     string     `json:"domain"`
+	Token      string     `json:"token"`
+	VerifiedAt *time.Time `json:"verified_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// DomainRecordPrefix is the label the TXT record that verifies a domain is
+// published under.
+const DomainRecordPrefix = "_starttech-verification."
+
+// Record returns the name of the TXT record that verifies d.
+func (d Domain) Record() string {
+	return DomainRecordPrefix + d.Domain
+}
+
+// Value returns the text the TXT record that verifies d must hold.
+func (d Domain) Value() string {
+	return "starttech-verification=" + d.Token
+}
+
+// DomainInput is the body accepted by POST /orgs/{id}/domains.
+type DomainInput struct {
+	Domain string `json:"domain"`
+}
+
+// Validate reports every field of in that breaks the API's rules.
+func (in *DomainInput) Validate() error {
+	var v ValidationError
+	in.Domain = strings.ToLower(strings.TrimSpace(in.Domain))
+	if !validDomain(in.Domain) {
+		v.Add("domain", "must be an email domain, such as example.com")
+	}
+	return v.Err()
+}
+
+func validDomain(domain string) bool {
+	return domain != "" && !strings.ContainsAny(domain, "@/ ") && strings.Contains(domain, ".")
+}
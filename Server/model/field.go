@@ -0,0 +1,219 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldType is the kind of value a custom field holds.
+type FieldType string
+
+const (
+	FieldText     FieldType = "text"
+	FieldNumber   FieldType = "number"
+	FieldDate     FieldType = "date"
+	FieldSelect   FieldType = "select"
+	FieldCheckbox FieldType = "checkbox"
+)
+
+// FieldTypes lists every type a custom field may have.
+var FieldTypes = []FieldType{FieldText, FieldNumber, FieldDate, FieldSelect, FieldCheckbox}
+
+// Custom field limits enforced by CustomField.Validate and CustomField.Value.
+const (
+	MaxFields         = 50
+	MaxFieldNameLen   = 50
+	MaxFieldOptions   = 50
+	MaxFieldOptionLen = 50
+	MaxFieldTextLen   = 1000
+)
+
+// CustomField is a field the owners of a project define for its tasks,
+// which hold their values in Task.Fields under the field's ID. Text, date
+// and select values are strings, dates being YYYY-MM-DD and select values
+// one of Options; numbers are numbers and checkboxes booleans. Type cannot
+// change once the field is created. Names are unique within the project,
+// ignoring case.
+type CustomField struct {
+	ID        string    `json:"id"`
+	ProjectID string    `json:"project_id"`
+	Name      string    `json:"name"`
+	Type      FieldType `json:"type"`
+	Options   []string  `json:"options"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate reports every field of f that breaks the API's rules.
+func (f *CustomField) Validate() error {
+	var v ValidationError
+	switch n := utf8.RuneCountInString(f.Name); {
+	case n == 0:
+		v.Add("name", "is required")
+	case n > MaxFieldNameLen:
+		v.Add("name", fmt.Sprintf("must be at most %d characters", MaxFieldNameLen))
+	}
+	if !slices.Contains(FieldTypes, f.Type) {
+		v.Add("type", "must be one of "+describeFieldTypes())
+	}
+	switch {
+	case f.Type != FieldSelect && len(f.Options) > 0:
+		v.Add("options", "are only allowed on select fields")
+	case f.Type == FieldSelect && len(f.Options) == 0:
+		v.Add("options", "must list at least one choice")
+	case len(f.Options) > MaxFieldOptions:
+		v.Add("options", fmt.Sprintf("must list at most %d choices", MaxFieldOptions))
+	}
+	for i, o := range f.Options {
+		field := fmt.Sprintf("options[%d]", i)
+		switch n := utf8.RuneCountInString(o); {
+		case n == 0 || n > MaxFieldOptionLen:
+			v.Add(field, fmt.Sprintf("must be 1-%d characters", MaxFieldOptionLen))
+		case slices.Index(f.Options, o) < i:
+			v.Add(field, "is listed twice")
+		}
+	}
+	return v.Err()
+}
+
+func describeFieldTypes() string {
+	names := make([]string, len(FieldTypes))
+	for i, t := range FieldTypes {
+		names[i] = string(t)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Value checks v, as decoded from JSON, as a value of f and returns it in
+// the form tasks hold it in.
+func (f *CustomField) Value(v any) (any, error) {
+	switch f.Type {
+	case FieldNumber:
+		if n, ok := v.(float64); ok {
+			return n, nil
+		}
+		return nil, errors.New("must be a number")
+	case FieldCheckbox:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+		return nil, errors.New("must be true or false")
+	}
+	s, ok := v.(string)
+	if !ok {
+		return nil, errors.New("must be a string")
+	}
+	return f.ParseValue(s)
+}
+
+// ParseValue is Value for text, such as a query parameter.
+func (f *CustomField) ParseValue(s string) (any, error) {
+	switch f.Type {
+	case FieldText:
+		if utf8.RuneCountInString(s) > MaxFieldTextLen {
+			return nil, fmt.Errorf("must be at most %d characters", MaxFieldTextLen)
+		}
+		return s, nil
+	case FieldNumber:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, errors.New("must be a number")
+		}
+		return n, nil
+	case FieldDate:
+		if _, err := time.Parse(time.DateOnly, s); err != nil {
+			return nil, errors.New("must be a YYYY-MM-DD date")
+		}
+		return s, nil
+	case FieldSelect:
+		if !slices.Contains(f.Options, s) {
+			return nil, errors.New("must be one of " + strings.Join(f.Options, ", "))
+		}
+		return s, nil
+	case FieldCheckbox:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.New("must be true or false")
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("has unknown type %q", f.Type)
+}
+
+// CustomFieldInput is the body accepted by POST /projects/{id}/fields.
+// Options is required for select fields and refused for the others.
+type CustomFieldInput struct {
+	Name    string    `json:"name"`
+	Type    FieldType `json:"type"`
+	Options []string  `json:"options,omitempty"`
+}
+
+// Apply copies in onto f.
+func (in CustomFieldInput) Apply(f *CustomField) {
+	f.Name = strings.TrimSpace(in.Name)
+	f.Type = in.Type
+	f.Options = fieldOptions(in.Options)
+}
+
+// CustomFieldPatch is the body accepted by PATCH
+// /projects/{id}/fields/{field_id}. Nil fields are left unchanged; the type
+// cannot be changed. Tasks lose the values of the options a patch removes.
+type CustomFieldPatch struct {
+	Name    *string   `json:"name,omitempty"`
+	Options *[]string `json:"options,omitempty"`
+}
+
+// Apply copies the set fields of p onto f.
+func (p CustomFieldPatch) Apply(f *CustomField) {
+	if p.Name != nil {
+		f.Name = strings.TrimSpace(*p.Name)
+	}
+	if p.Options != nil {
+		f.Options = fieldOptions(*p.Options)
+	}
+}
+
+// fieldOptions trims the options of a select field, keeping their order.
+func fieldOptions(options []string) []string {
+	out := make([]string, len(options))
+	for i, o := range options {
+		out[i] = strings.TrimSpace(o)
+	}
+	return out
+}
+
+// fieldValues copies the custom field values of a task input, leaving out
+// nulls. It never returns nil.
+func fieldValues(in map[string]any) map[string]any {
+	out := make(map[string]any, len(in))
+	for id, v := range in {
+		if id = strings.TrimSpace(id); id != "" && v != nil {
+			out[id] = v
+		}
+	}
+	return out
+}
+
+// mergeFieldValues returns values with the values of p set over them; a
+// null in p removes the value.
+func mergeFieldValues(values, p map[string]any) map[string]any {
+	out := maps.Clone(values)
+	if out == nil {
+		out = make(map[string]any, len(p))
+	}
+	for id, v := range p {
+		if v == nil {
+			delete(out, id)
+		} else {
+			out[id] = v
+		}
+	}
+	return out
+}
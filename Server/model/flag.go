@@ -0,0 +1,82 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxFlagDescriptionLen bounds Flag.Description.
+const MaxFlagDescriptionLen = 200
+
+var flagKey = regexp.MustCompile(`^[a-z0-9_.-]{1,64}$`)
+
+// Flag is a feature flag: whether a feature is on, for whom. Orgs and Users
+// turn it on (true) or off (false) for particular organizations and users,
+// users first. Everyone else has it if Enabled is set, or if their
+// organization falls in the first Rollout percent of organizations, by a
+// hash of the flag's key and the organization's ID. Raising Rollout keeps
+// it on for the organizations that had it, so a feature can be rolled out
+// gradually.
+type Flag struct {
+	Key         string          `json:"key"`
+	Description string          `json:"description"`
+	Enabled     bool            `json:"enabled"`
+	Rollout     int             `json:"rollout"`
+	Orgs        map[string]bool `json:"orgs"`
+	Users       map[string]bool `json:"users"`
+	// Known is set for the flags the server checks itself; the others
+	// are there for clients to read.
+	Known     bool       `json:"known"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+// FlagSubject is what a flag is turned on or off for in particular.
+type FlagSubject string
+
+const (
+	FlagOrg  FlagSubject = "org"
+	FlagUser FlagSubject = "user"
+)
+
+// ValidFlagKey reports whether key may name a flag: up to 64 lowercase
+// letters, digits, dots, dashes and underscores.
+func ValidFlagKey(key string) bool {
+	return flagKey.MatchString(key)
+}
+
+// FlagInput is the body accepted by PUT /admin/flags/{key}. It replaces the
+// flag's settings, leaving its organizations and users be.
+type FlagInput struct {
+	Description string `json:"description,omitempty"`
+	Enabled     bool   `json:"enabled,omitempty"`
+	Rollout     int    `json:"rollout,omitempty"`
+}
+
+// Validate reports every field of in that breaks the API's rules.
+func (in FlagInput) Validate() error {
+	var v ValidationError
+	if utf8.RuneCountInString(in.Description) > MaxFlagDescriptionLen {
+		v.Add("description", fmt.Sprintf("must be at most %d characters", MaxFlagDescriptionLen))
+	}
+	if in.Rollout < 0 || in.Rollout > 100 {
+		v.Add("rollout", "must be between 0 and 100")
+	}
+	return v.Err()
+}
+
+// FlagOverrideInput is the body accepted by PUT
+// /admin/flags/{key}/orgs/{org_id} and /admin/flags/{key}/users/{user_id}.
+type FlagOverrideInput struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// Validate reports every field of in that breaks the API's rules.
+func (in FlagOverrideInput) Validate() error {
+	var v ValidationError
+	if in.Enabled == nil {
+		v.Add("enabled", "is required")
+	}
+	return v.Err()
+}
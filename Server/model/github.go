@@ -0,0 +1,92 @@
+package model
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// GitHubLink connects a project to a GitHub repository. The repository's
+// issues are imported as tasks of the project; closing or reopening one of
+// those tasks closes or reopens its issue, and the repository's webhook,
+// signed with Secret, keeps the tasks in step with the issues. CreatedBy
+// is the project owner who linked it, in whose name tasks are created and
+// updated. Token is never returned, and Secret only by the request that
+// links the repository. WebhookURL is relative to the API, like attachment
+// links.
+type GitHubLink struct {
+	ProjectID  string     `json:"project_id"`
+	OrgID      string     `json:"org_id"`
+	Repo       string     `json:"repo"`
+	Token      string     `json:"-"`
+	Secret     string     `json:"secret,omitempty"`
+	WebhookURL string     `json:"webhook_url"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	SyncedAt   *time.Time `json:"synced_at"`
+}
+
+var repoName = regexp.MustCompile(`^[A-Za-z0-9-]+/[A-Za-z0-9._-]+$`)
+
+// Validate reports every field of l that breaks the API's rules.
+func (l *GitHubLink) Validate() error {
+	var v ValidationError
+	switch {
+	case l.Repo == "":
+		v.Add("repo", "is required")
+	case !repoName.MatchString(l.Repo):
+		v.Add("repo", "must be owner/name")
+	}
+	if l.Token == "" {
+		v.Add("token", "is required")
+	}
+	return v.Err()
+}
+
+// GitHubLinkInput is the body accepted by PUT /projects/{id}/github. Token
+// is a GitHub access token that may read and write the repository's
+// issues.
+type GitHubLinkInput struct {
+	Repo  string `json:"repo"`
+	Token string `json:"token"`
+}
+
+// Apply copies in onto l.
+func (in GitHubLinkInput) Apply(l *GitHubLink) {
+	l.Repo = strings.TrimSpace(in.Repo)
+	l.Token = strings.TrimSpace(in.Token)
+}
+
+// Issue states, as GitHub names them.
+const (
+	IssueOpen   = "open"
+	IssueClosed = "closed"
+)
+
+// IssueLink ties a task to the GitHub issue it was imported from. State is
+// the issue's state as last seen or set, so that changes are only pushed
+// to GitHub when the task's completion no longer matches it.
+type IssueLink struct {
+	TaskID    string `json:"task_id"`
+	ProjectID string `json:"project_id"`
+	Number    int    `json:"number"`
+	State     string `json:"state"`
+}
+
+// IssueState is the state an issue should have for task t.
+func IssueState(t Task) string {
+	if t.Completed {
+		return IssueClosed
+	}
+	return IssueOpen
+}
+
+// GitHubSyncResult counts what importing a repository's issues did to
+// their tasks. Skipped counts the issues whose task refused the change,
+// such as one that may not be completed while it is blocked.
+type GitHubSyncResult struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Skipped   int `json:"skipped"`
+}
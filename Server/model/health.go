@@ -0,0 +1,15 @@
+package model
+
+// Health is the body returned by the health endpoints. Components, the
+// status of each dependency and background worker by name, is only set by
+// GET /readyz.
+type Health struct {
+	Status     string            `json:"status"`
+	Components map[string]string `json:"components,omitempty"`
+}
+
+// Health statuses.
+const (
+	HealthOK   = "ok"
+	HealthDown = "down"
+)
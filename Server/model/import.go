@@ -0,0 +1,64 @@
+package model
+
+import (
+	"time"
+
+	"starttech-server/apierror"
+)
+
+// MaxImportRows bounds the records of one POST /projects/{id}/import.
+const MaxImportRows = 1000
+
+// RecordFields are the fields of a TaskRecord, in the order of the columns
+// of a CSV export.
+var RecordFields = []string{"id", "title", "description", "status", "completed", "due_date", "recurrence", "tags", "created_at", "updated_at"}
+
+// TaskRecord is a task as GET /projects/{id}/export writes it and POST
+// /projects/{id}/import reads it back. Tags are given by name so records
+// can be moved between organizations. An import ignores CreatedAt and
+// UpdatedAt, and uses ID only to recognise tasks it has already imported.
+type TaskRecord struct {
+	ID          string     `json:"id,omitempty"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Status      Status     `json:"status,omitempty"`
+	Completed   bool       `json:"completed"`
+	DueDate     *time.Time `json:"due_date"`
+	Recurrence  string     `json:"recurrence"`
+	Tags        []string   `json:"tags"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// ImportAction is what an import does, or would do, with one record.
+type ImportAction string
+
+const (
+	ImportCreate    ImportAction = "create"
+	ImportDuplicate ImportAction = "duplicate"
+	ImportInvalid   ImportAction = "invalid"
+)
+
+// ImportResult reports on every record of an import, in order. Committed
+// is false for a dry run, and when any record is invalid, in which case no
+// task was created.
+type ImportResult struct {
+	DryRun     bool        `json:"dry_run"`
+	Committed  bool        `json:"committed"`
+	Created    int         `json:"created"`
+	Duplicates int         `json:"duplicates"`
+	Invalid    int         `json:"invalid"`
+	Rows       []ImportRow `json:"rows"`
+}
+
+// ImportRow is the outcome of one record. Row counts records from 1. Task
+// is the task created, or for a dry run the task that would be, without an
+// ID. DuplicateOf names the existing task a duplicate matches; it is empty
+// when the record repeats an earlier one of the same import.
+type ImportRow struct {
+	Row         int             `json:"row"`
+	Action      ImportAction    `json:"action"`
+	Task        *Task           `json:"task,omitempty"`
+	DuplicateOf string          `json:"duplicate_of,omitempty"`
+	Error       *apierror.Error `json:"error,omitempty"`
+}
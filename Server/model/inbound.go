@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// InboundAddress is a user's private email address in one organization.
+// Emails sent to it become tasks of theirs. Only a hash of its token is
+// stored, so the token and the address built from it are returned once,
+// when the address is created.
+type InboundAddress struct {
+	UserID    string    `json:"user_id"`
+	OrgID     string    `json:"org_id"`
+	Token     string    `json:"token,omitempty"`
+	Address   string    `json:"address,omitempty"`
+	TokenHash string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// InboundResult is the response to POST /integrations/email: the task an
+// email became and the files attached to it. Skipped names the
+// attachments that were left out, with the reason.
+type InboundResult struct {
+	Task        Task         `json:"task"`
+	Attachments []Attachment `json:"attachments"`
+	Skipped     []string     `json:"skipped"`
+}
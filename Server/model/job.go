@@ -0,0 +1,55 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// JobStatus is the state of a background job.
+type JobStatus string
+
+const (
+	// JobPending is waiting for a worker, for its first or a later
+	// attempt.
+	JobPending JobStatus = "pending"
+	// JobRunning has been taken by a worker, which holds it until
+	// LockedUntil; past that, another worker may take it over.
+	JobRunning JobStatus = "running"
+	// JobSucceeded is done. Its payload is dropped, as it may hold
+	// secrets such as the token in an email.
+	JobSucceeded JobStatus = "succeeded"
+	// JobDead ran out of attempts, or failed in a way retrying cannot fix,
+	// and waits for someone to retry or discard it.
+	JobDead JobStatus = "dead"
+)
+
+// Valid reports whether s is a known status.
+func (s JobStatus) Valid() bool {
+	switch s {
+	case JobPending, JobRunning, JobSucceeded, JobDead:
+		return true
+	}
+	return false
+}
+
+// Job is a unit of deferred work, such as sending an email, run by the
+// in-process workers and retried with backoff when it fails.
+type Job struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	// Payload is the JSON the job's handler is given. It is not shown, as
+	// it may hold secrets.
+	Payload     json.RawMessage `json:"-"`
+	Status      JobStatus       `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	LastError   string          `json:"last_error,omitempty"`
+	// RunAt is when a pending job is next due.
+	RunAt       time.Time  `json:"run_at"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// TraceParent is the traceparent of the span that queued the job,
+	// which the spans of its attempts continue.
+	TraceParent string `json:"-"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// MarkdownInput is the body of POST /markdown: text as it would be saved
+// as a description or comment.
+type MarkdownInput struct {
+	Text string `json:"text"`
+}
+
+// Validate bounds the text by the longest field that holds Markdown.
+func (in *MarkdownInput) Validate() error {
+	var v ValidationError
+	if utf8.RuneCountInString(in.Text) > MaxDescriptionLen {
+		v.Add("text", fmt.Sprintf("must be at most %d characters", MaxDescriptionLen))
+	}
+	return v.Err()
+}
+
+// RenderedMarkdown is the HTML the server renders text to, the same that
+// description_html and body_html hold.
+type RenderedMarkdown struct {
+	HTML string `json:"html"`
+}
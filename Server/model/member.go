@@ -0,0 +1,73 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Role is a member's level of access to a project. Each role includes the
+// permissions of the ones below it.
+type Role string
+
+const (
+	// RoleViewer can read the project and its tasks.
+	RoleViewer Role = "viewer"
+	// RoleEditor can also create, change, move and delete tasks.
+	RoleEditor Role = "editor"
+	// RoleOwner can also change the project, manage its members and delete
+	// it.
+	RoleOwner Role = "owner"
+)
+
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleEditor:
+		return 2
+	case RoleOwner:
+		return 3
+	}
+	return 0
+}
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool { return r.rank() > 0 }
+
+// Allows reports whether r grants everything need does.
+func (r Role) Allows(need Role) bool { return r.rank() >= need.rank() && r.Valid() }
+
+// Member is a user's membership in a project.
+type Member struct {
+	ProjectID string    `json:"project_id"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	Role      Role      `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MemberInput is the body accepted by POST /projects/{id}/members. The user
+// is named by user_id or email.
+type MemberInput struct {
+	UserID string `json:"user_id,omitempty"`
+	Email  string `json:"email,omitempty"`
+	Role   Role   `json:"role"`
+}
+
+// Validate reports every field of in that breaks the API's rules.
+func (in *MemberInput) Validate() error {
+	var v ValidationError
+	in.UserID, in.Email = strings.TrimSpace(in.UserID), strings.TrimSpace(in.Email)
+	if (in.UserID == "") == (in.Email == "") {
+		v.Add("user_id", "exactly one of user_id and email is required")
+	}
+	if !in.Role.Valid() {
+		v.Add("role", "must be owner, editor or viewer")
+	}
+	return v.Err()
+}
+
+// MemberPatch is the body accepted by PATCH /projects/{id}/members/{user_id}.
+type MemberPatch struct {
+	Role Role `json:"role"`
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Mention records that a comment named a user with @username. Only users
+// who can see the task are mentioned, and never the comment's author.
+type Mention struct {
+	ID        string    `json:"id"`
+	TaskID    string    `json:"task_id"`
+	CommentID string    `json:"comment_id"`
+	UserID    string    `json:"user_id"`
+	AuthorID  string    `json:"author_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// mentionPattern matches @username where the @ starts a word, so that email
+// addresses are not taken for mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([A-Za-z0-9_][A-Za-z0-9_.-]*)`)
+
+// ParseMentions returns the usernames mentioned in body, lowercased, each
+// once and in the order they first appear. Dots and hyphens ending a
+// mention are taken as punctuation.
+func ParseMentions(body string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		name := strings.ToLower(strings.TrimRight(m[1], ".-"))
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
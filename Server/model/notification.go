@@ -0,0 +1,100 @@
+package model
+
+import "time"
+
+// NotificationKind names a reason to notify a user.
+type NotificationKind string
+
+const (
+	// NotifyAssigned is sent when a task is assigned to the user.
+	NotifyAssigned NotificationKind = "assigned"
+	// NotifyDueSoon is sent when one of the user's reminders fires.
+	NotifyDueSoon NotificationKind = "due_soon"
+	// NotifyMentioned is sent when somebody mentions the user.
+	NotifyMentioned NotificationKind = "mentioned"
+	// NotifyCommented is sent when somebody comments on a task the user
+	// owns, is assigned or watches. It is only shown in the inbox, never
+	// emailed.
+	NotifyCommented NotificationKind = "commented"
+	// NotifyUpdated is sent when somebody changes a task the user watches.
+	// It is only shown in the inbox, never emailed.
+	NotifyUpdated NotificationKind = "updated"
+	// NotifyRule is sent when a rule of the project acts on a task the user
+	// is assigned. It is only shown in the inbox, never emailed.
+	NotifyRule NotificationKind = "rule"
+)
+
+// NotificationPrefs records which emails a user wants. Users who never saved
+// preferences get DefaultNotificationPrefs.
+type NotificationPrefs struct {
+	UserID    string `json:"-"`
+	Assigned  bool   `json:"assigned"`
+	DueSoon   bool   `json:"due_soon"`
+	Mentioned bool   `json:"mentioned"`
+}
+
+// DefaultNotificationPrefs enables every kind of email.
+func DefaultNotificationPrefs(userID string) NotificationPrefs {
+	return NotificationPrefs{UserID: userID, Assigned: true, DueSoon: true, Mentioned: true}
+}
+
+// Wants reports whether the user opted in to emails of kind k.
+func (p NotificationPrefs) Wants(k NotificationKind) bool {
+	switch k {
+	case NotifyAssigned:
+		return p.Assigned
+	case NotifyDueSoon:
+		return p.DueSoon
+	case NotifyMentioned:
+		return p.Mentioned
+	}
+	return false
+}
+
+// NotificationPrefsPatch is the body accepted by PATCH /me/notifications.
+// Omitted fields are left unchanged.
+type NotificationPrefsPatch struct {
+	Assigned  *bool `json:"assigned"`
+	DueSoon   *bool `json:"due_soon"`
+	Mentioned *bool `json:"mentioned"`
+}
+
+// Apply copies the fields set in p onto prefs.
+func (p NotificationPrefsPatch) Apply(prefs *NotificationPrefs) {
+	if p.Assigned != nil {
+		prefs.Assigned = *p.Assigned
+	}
+	if p.DueSoon != nil {
+		prefs.DueSoon = *p.DueSoon
+	}
+	if p.Mentioned != nil {
+		prefs.Mentioned = *p.Mentioned
+	}
+}
+
+// Notification is an entry in a user's in-app inbox, shown under the bell
+// icon. Each belongs to the organization of the task it is about, and
+// Title is that task's title when the notification was made. ActorID is
+// empty for notifications nobody caused, such as reminders. ReadAt is nil
+// until the user reads it.
+type Notification struct {
+	ID        string           `json:"id"`
+	OrgID     string           `json:"org_id"`
+	UserID    string           `json:"user_id"`
+	Kind      NotificationKind `json:"kind"`
+	ActorID   string           `json:"actor_id"`
+	TaskID    string           `json:"task_id"`
+	CommentID *string          `json:"comment_id"`
+	Title     string           `json:"title"`
+	ReadAt    *time.Time       `json:"read_at"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// NotificationPage is one page of an inbox, newest first, with the number
+// of unread notifications in the whole inbox. NextCursor is empty on the
+// last page.
+type NotificationPage struct {
+	Items      []Notification `json:"items"`
+	Unread     int            `json:"unread"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
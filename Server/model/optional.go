@@ -0,0 +1,54 @@
+package model
+
+import "encoding/json"
+
+// Optional distinguishes an absent JSON field from an explicit null in PATCH
+// bodies: Set is false when the field was omitted, and Null is true when it
+// was sent as null. Fields tagged omitzero are left out of encoded bodies
+// unless Set.
+type Optional[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+func (o *Optional[T]) UnmarshalJSON(b []byte) error {
+	o.Set = true
+	if string(b) == "null" {
+		o.Null = true
+		return nil
+	}
+	return json.Unmarshal(b, &o.Value)
+}
+
+// MarshalJSON encodes o as null or as its value.
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.Set || o.Null {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.Value)
+}
+
+// IsZero reports whether o was left unset, for omitzero.
+func (o Optional[T]) IsZero() bool {
+	return !o.Set
+}
+
+// Some returns an Optional set to v.
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{Set: true, Value: v}
+}
+
+// Null returns an Optional set to null.
+func Null[T any]() Optional[T] {
+	return Optional[T]{Set: true, Null: true}
+}
+
+// Ptr returns nil for a null value and a pointer to Value otherwise.
+func (o Optional[T]) Ptr() *T {
+	if o.Null {
+		return nil
+	}
+	v := o.Value
+	return &v
+}
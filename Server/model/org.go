@@ -0,0 +1,153 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxOrgNameLen bounds Org.Name.
+const MaxOrgNameLen = 100
+
+// InvitationTTL is how long an invitation can be accepted.
+const InvitationTTL = 7 * 24 * time.Hour
+
+// OrgRole is a member's standing in an organization. Each role includes the
+// permissions of the ones below it.
+type OrgRole string
+
+const (
+	// OrgMember can work in the organization's workspace.
+	OrgMember OrgRole = "member"
+	// OrgAdmin can also invite people and manage members below admin.
+	OrgAdmin OrgRole = "admin"
+	// OrgOwner can also rename the organization and appoint admins and
+	// owners.
+	OrgOwner OrgRole = "owner"
+)
+
+func (r OrgRole) rank() int {
+	switch r {
+	case OrgMember:
+		return 1
+	case OrgAdmin:
+		return 2
+	case OrgOwner:
+		return 3
+	}
+	return 0
+}
+
+// Valid reports whether r is a known role.
+func (r OrgRole) Valid() bool { return r.rank() > 0 }
+
+// Allows reports whether r grants everything need does.
+func (r OrgRole) Allows(need OrgRole) bool { return r.rank() >= need.rank() && r.Valid() }
+
+// Org is an organization: an isolated workspace whose tasks, tags, projects
+// and webhooks are invisible from every other organization. Every user has
+// a personal one, created when they register. Members of an organization
+// that requires two-factor authentication can do nothing in it but set up
+// their authenticator app until they have.
+type Org struct {
+	ID               string `json:"id"`
+	Name             string `json:"name,omitempty"`
+	RequireTwoFactor bool   `json:"require_two_factor"`
+	// Role is the caller's role, filled in when orgs are listed for a
+	// user.
+	Role      OrgRole   `json:"role,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate reports every field of o that breaks the API's rules.
+func (o *Org) Validate() error {
+	var v ValidationError
+	switch {
+	case o.Name == "":
+		v.Add("name", "is required")
+	case utf8.RuneCountInString(o.Name) > MaxOrgNameLen:
+		v.Add("name", fmt.Sprintf("must be at most %d characters", MaxOrgNameLen))
+	}
+	return v.Err()
+}
+
+// OrgInput is the body accepted by POST /orgs and PATCH /orgs/{id}. A
+// patch leaves out what it does not change.
+type OrgInput struct {
+	Name             string `json:"name,omitempty"`
+	RequireTwoFactor *bool  `json:"require_two_factor,omitempty"`
+}
+
+// Apply copies in onto o.
+func (in OrgInput) Apply(o *Org) {
+	if name := strings.TrimSpace(in.Name); name != "" {
+		o.Name = name
+	}
+	if in.RequireTwoFactor != nil {
+		o.RequireTwoFactor = *in.RequireTwoFactor
+	}
+}
+
+// OrgMembership is a user's membership in an organization.
+type OrgMembership struct {
+	OrgID     string    `json:"org_id"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	Role      OrgRole   `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OrgMemberPatch is the body accepted by PATCH /orgs/{id}/members/{user_id}.
+type OrgMemberPatch struct {
+	Role OrgRole `json:"role"`
+}
+
+// Invitation asks the holder of an email address to join an organization.
+// Only a hash of its token is stored; the token itself is returned once, to
+// whoever sent the invitation, and emailed to the invitee.
+type Invitation struct {
+	ID         string     `json:"id"`
+	OrgID      string     `json:"org_id"`
+	Email      string     `json:"email"`
+	Role       OrgRole    `json:"role"`
+	InvitedBy  string     `json:"invited_by"`
+	Token      string     `json:"token,omitempty"`
+	TokenHash  string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+}
+
+// InvitationInput is the body accepted by POST /orgs/{id}/invitations. Role
+// defaults to member.
+type InvitationInput struct {
+	Email string  `json:"email"`
+	Role  OrgRole `json:"role,omitempty"`
+}
+
+// Validate reports every field of in that breaks the API's rules.
+func (in *InvitationInput) Validate() error {
+	var v ValidationError
+	in.Email = strings.TrimSpace(in.Email)
+	if !strings.Contains(in.Email, "@") {
+		v.Add("email", "must be an email address")
+	}
+	if in.Role == "" {
+		in.Role = OrgMember
+	}
+	if !in.Role.Valid() {
+		v.Add("role", "must be owner, admin or member")
+	}
+	return v.Err()
+}
+
+// AcceptInput is the body accepted by POST /invitations/accept.
+type AcceptInput struct {
+	Token string `json:"token"`
+}
+
+// SwitchInput is the body accepted by POST /auth/switch.
+type SwitchInput struct {
+	OrgID string `json:"org_id"`
+}
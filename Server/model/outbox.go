@@ -0,0 +1,25 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEntry is an event waiting in the outbox. It is written in the
+// transaction of the change it announces, so it exists exactly when the
+// change was committed, and is deleted once it has been delivered.
+type OutboxEntry struct {
+	ID string `json:"id"`
+	// Seq orders the entries written together, which share a CreatedAt
+	// more often than not.
+	Seq         int             `json:"seq"`
+	Type        string          `json:"type"`
+	OrgID       string          `json:"org_id"`
+	Recipients  []string        `json:"recipients"`
+	Data        json.RawMessage `json:"data"`
+	TraceParent string          `json:"-"`
+	CreatedAt   time.Time       `json:"created_at"`
+	// LockedUntil is when the relay delivering the entry lets go of it,
+	// for another to take over.
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+}
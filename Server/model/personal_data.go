@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// DeleteAccountInput is the body accepted by DELETE /users/me. Password
+// is required of accounts that have one, so that a stolen session alone
+// cannot delete the account.
+type DeleteAccountInput struct {
+	Password string `json:"password,omitempty"`
+}
+
+// AccountDeletion answers DELETE /users/me: the account is erased at
+// DeleteAt unless the user restores it with POST /users/me/restore first.
+type AccountDeletion struct {
+	RequestedAt time.Time `json:"requested_at"`
+	DeleteAt    time.Time `json:"delete_at"`
+}
+
+// PersonalData is what the server keeps about a user, as GET /users/me/export
+// returns it: their account, memberships and sessions, the tasks they own,
+// the comments they wrote, the files they uploaded, their time entries,
+// tags, views and templates in every organization, and the audit events
+// they acted in. Other people's tasks the user was assigned to or commented
+// on are left out.
+type PersonalData struct {
+	ExportedAt  time.Time     `json:"exported_at"`
+	User        User          `json:"user"`
+	Orgs        []Org         `json:"orgs"`
+	Sessions    []AuthSession `json:"sessions"`
+	Tasks       []Task        `json:"tasks"`
+	Comments    []Comment     `json:"comments"`
+	Attachments []Attachment  `json:"attachments"`
+	TimeEntries []TimeEntry   `json:"time_entries"`
+	Tags        []Tag         `json:"tags"`
+	Views       []View        `json:"views"`
+	Templates   []Template    `json:"templates"`
+	AuditEvents []AuditEvent  `json:"audit_events"`
+}
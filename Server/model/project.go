@@ -0,0 +1,110 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxProjectNameLen bounds Project.Name.
+const MaxProjectNameLen = 100
+
+// Project groups related tasks, for example one board. Tasks in a project
+// are ordered by their Position and take their status from Statuses.
+// OwnerID is the creator; who else may use the project is recorded in its
+// members. ArchivedAt is set while the project is archived, which keeps it
+// out of the list of projects.
+type Project struct {
+	ID          string     `json:"id"`
+	OrgID       string     `json:"org_id"`
+	OwnerID     string     `json:"owner_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Statuses    Workflow   `json:"statuses"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	ArchivedAt  *time.Time `json:"archived_at"`
+}
+
+// Validate reports every field of p that breaks the API's rules.
+func (p *Project) Validate() error {
+	var v ValidationError
+	switch {
+	case p.Name == "":
+		v.Add("name", "is required")
+	case utf8.RuneCountInString(p.Name) > MaxProjectNameLen:
+		v.Add("name", fmt.Sprintf("must be at most %d characters", MaxProjectNameLen))
+	}
+	if utf8.RuneCountInString(p.Description) > MaxDescriptionLen {
+		v.Add("description", fmt.Sprintf("must be at most %d characters", MaxDescriptionLen))
+	}
+	p.Statuses.Validate(&v)
+	return v.Err()
+}
+
+// ProjectInput is the body accepted by POST /projects and PUT /projects/{id}.
+// Statuses defaults to DefaultWorkflow.
+type ProjectInput struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Statuses    Workflow `json:"statuses,omitempty"`
+}
+
+// Apply overwrites the client-editable fields of p with in.
+func (in ProjectInput) Apply(p *Project) {
+	p.Name = strings.TrimSpace(in.Name)
+	p.Description = in.Description
+	p.Statuses = in.Statuses
+	if len(p.Statuses) == 0 {
+		p.Statuses = DefaultWorkflow
+	}
+}
+
+// ProjectPatch is the body accepted by PATCH /projects/{id}. Nil fields are
+// left unchanged.
+type ProjectPatch struct {
+	Name        *string   `json:"name"`
+	Description *string   `json:"description"`
+	Statuses    *Workflow `json:"statuses"`
+}
+
+// Apply copies the set fields of pp onto p.
+func (pp ProjectPatch) Apply(p *Project) {
+	if pp.Name != nil {
+		p.Name = strings.TrimSpace(*pp.Name)
+	}
+	if pp.Description != nil {
+		p.Description = *pp.Description
+	}
+	if pp.Statuses != nil {
+		p.Statuses = *pp.Statuses
+	}
+}
+
+// MoveInput is the body accepted by PATCH /tasks/{id}/move. The task is put
+// in column Status, which defaults to its current status, directly after
+// AfterID or before BeforeID, or between them if both are set, in which case
+// they must be next to each other. With neither it goes to the end of the
+// column.
+type MoveInput struct {
+	Status   Status `json:"status,omitempty"`
+	AfterID  string `json:"after_id,omitempty"`
+	BeforeID string `json:"before_id,omitempty"`
+}
+
+// ReorderInput is the body accepted by POST /tasks/{id}/reorder. The task
+// keeps its status and is put directly after AfterID or before BeforeID in
+// its project's order, or between them if both are set, in which case they
+// must be next to each other. With neither it goes to the end of the
+// project.
+type ReorderInput struct {
+	AfterID  string `json:"after_id,omitempty"`
+	BeforeID string `json:"before_id,omitempty"`
+}
+
+// TaskOrder is the body accepted by PUT /projects/{id}/order: every task of
+// the project, first to last.
+type TaskOrder struct {
+	TaskIDs []string `json:"task_ids"`
+}
@@ -0,0 +1,27 @@
+package model
+
+// Limits are the quotas every organization is held to. Zero leaves a quota
+// off. Trashed tasks count against TasksPerProject until they are purged,
+// so that restoring them never goes over it.
+type Limits struct {
+	TasksPerProject int   `json:"tasks_per_project"`
+	AttachmentBytes int64 `json:"attachment_bytes"`
+	RequestsPerDay  int64 `json:"requests_per_day"`
+}
+
+// OrgUsage is what an organization uses against its Limits.
+// RequestsToday counts its API requests since midnight UTC, and Projects
+// the tasks of each of its projects, fullest first.
+type OrgUsage struct {
+	Usage
+	RequestsToday int64          `json:"requests_today"`
+	Projects      []ProjectUsage `json:"project_tasks"`
+	Limits        Limits         `json:"limits"`
+}
+
+// ProjectUsage counts the tasks of a project.
+type ProjectUsage struct {
+	ProjectID string `json:"project_id"`
+	Name      string `json:"name"`
+	Tasks     int    `json:"tasks"`
+}
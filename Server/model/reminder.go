@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// ReminderKind says why a reminder fires.
+type ReminderKind string
+
+const (
+	// ReminderRemind fires at a task's RemindAt.
+	ReminderRemind ReminderKind = "remind"
+	// ReminderDue fires when a task reaches its DueDate.
+	ReminderDue ReminderKind = "due"
+)
+
+// Reminder is a scheduled notification about a task. SentAt is nil until
+// the scheduler has delivered it.
+type Reminder struct {
+	ID      string       `json:"id"`
+	TaskID  string       `json:"task_id"`
+	OwnerID string       `json:"owner_id"`
+	Kind    ReminderKind `json:"kind"`
+	FireAt  time.Time    `json:"fire_at"`
+	SentAt  *time.Time   `json:"sent_at"`
+}
+
+// Reminders returns the reminders implied by t's RemindAt and DueDate.
+func (t *Task) Reminders() []Reminder {
+	var rs []Reminder
+	if t.RemindAt != nil {
+		rs = append(rs, Reminder{TaskID: t.ID, OwnerID: t.OwnerID, Kind: ReminderRemind, FireAt: *t.RemindAt})
+	}
+	if t.DueDate != nil {
+		rs = append(rs, Reminder{TaskID: t.ID, OwnerID: t.OwnerID, Kind: ReminderDue, FireAt: *t.DueDate})
+	}
+	return rs
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// Revision is a task as it was saved at one version. A revision is kept for
+// every version from the task's creation on, and never changes.
+type Revision struct {
+	TaskID    string    `json:"task_id"`
+	Version   int64     `json:"version"`
+	Task      Task      `json:"task"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RevisionPage is one page of a task's revisions, newest first. NextCursor
+// is empty on the last page.
+type RevisionPage struct {
+	Items      []Revision `json:"items"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,200 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// TriggerType is the condition under which a rule acts on a task.
+type TriggerType string
+
+const (
+	// TriggerDueDatePassed holds for open tasks whose due date passed at
+	// least Delay ago.
+	TriggerDueDatePassed TriggerType = "due_date_passed"
+	// TriggerInStatus holds for tasks in Status, and fires once they have
+	// been left unchanged there for Delay.
+	TriggerInStatus TriggerType = "in_status"
+	// TriggerBlocking holds for open tasks that block an open task of
+	// higher priority.
+	TriggerBlocking TriggerType = "blocking"
+)
+
+// TriggerTypes lists every trigger a rule may have.
+var TriggerTypes = []TriggerType{TriggerDueDatePassed, TriggerInStatus, TriggerBlocking}
+
+// ActionType is something a rule does to a task its trigger holds for.
+type ActionType string
+
+const (
+	// ActionSetPriority sets the task's priority to Priority.
+	ActionSetPriority ActionType = "set_priority"
+	// ActionInheritPriority raises the task's priority to that of the most
+	// important open task it blocks.
+	ActionInheritPriority ActionType = "inherit_priority"
+	// ActionSetStatus moves the task to Status.
+	ActionSetStatus ActionType = "set_status"
+	// ActionArchive archives the task.
+	ActionArchive ActionType = "archive"
+	// ActionNotifyAssignee adds a notification to the inbox of the task's
+	// assignee.
+	ActionNotifyAssignee ActionType = "notify_assignee"
+)
+
+// ActionTypes lists every action a rule may take.
+var ActionTypes = []ActionType{ActionSetPriority, ActionInheritPriority, ActionSetStatus, ActionArchive, ActionNotifyAssignee}
+
+// Rule limits enforced by Rule.Validate and service.Projects.CreateRule.
+const (
+	MaxRules            = 25
+	MaxRuleNameLen      = 100
+	MaxRuleActions      = 10
+	MaxRuleDelaySeconds = 365 * 24 * 60 * 60
+)
+
+// RuleTrigger says which tasks of its project a rule acts on. Status is
+// only given for in_status triggers, and DelaySeconds only applies to
+// due_date_passed and in_status ones.
+type RuleTrigger struct {
+	Type         TriggerType `json:"type"`
+	Status       Status      `json:"status,omitempty"`
+	DelaySeconds int64       `json:"delay_seconds,omitempty"`
+}
+
+// Delay is DelaySeconds as a duration.
+func (t RuleTrigger) Delay() time.Duration {
+	return time.Duration(t.DelaySeconds) * time.Second
+}
+
+// RuleAction is one step a rule takes. Priority is only given for
+// set_priority actions and Status for set_status ones.
+type RuleAction struct {
+	Type     ActionType `json:"type"`
+	Priority Priority   `json:"priority,omitempty"`
+	Status   Status     `json:"status,omitempty"`
+}
+
+// Rule is an automation the owners of a project define, which the
+// scheduler evaluates against the project's tasks. A rule acts on a task
+// once when its trigger comes to hold for it, taking its actions in order,
+// and again only after the trigger has stopped holding in between. Changes
+// a rule makes are recorded with no actor.
+type Rule struct {
+	ID        string       `json:"id"`
+	OrgID     string       `json:"org_id"`
+	ProjectID string       `json:"project_id"`
+	Name      string       `json:"name"`
+	Enabled   bool         `json:"enabled"`
+	Trigger   RuleTrigger  `json:"trigger"`
+	Actions   []RuleAction `json:"actions"`
+	CreatedBy string       `json:"created_by"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// Validate reports every field of r that breaks the API's rules, checking
+// statuses against w, the workflow of its project.
+func (r *Rule) Validate(w Workflow) error {
+	var v ValidationError
+	switch n := utf8.RuneCountInString(r.Name); {
+	case n == 0:
+		v.Add("name", "is required")
+	case n > MaxRuleNameLen:
+		v.Add("name", fmt.Sprintf("must be at most %d characters", MaxRuleNameLen))
+	}
+	t := r.Trigger
+	if !slices.Contains(TriggerTypes, t.Type) {
+		v.Add("trigger.type", "must be one of "+describe(TriggerTypes))
+	}
+	switch {
+	case t.Type == TriggerInStatus && !w.Has(t.Status):
+		v.Add("trigger.status", "must be one of "+w.describe())
+	case t.Type != TriggerInStatus && t.Status != "":
+		v.Add("trigger.status", "is only allowed on in_status triggers")
+	}
+	switch {
+	case t.DelaySeconds < 0 || t.DelaySeconds > MaxRuleDelaySeconds:
+		v.Add("trigger.delay_seconds", fmt.Sprintf("must be between 0 and %d", MaxRuleDelaySeconds))
+	case t.Type == TriggerBlocking && t.DelaySeconds != 0:
+		v.Add("trigger.delay_seconds", "is not allowed on blocking triggers")
+	}
+	switch n := len(r.Actions); {
+	case n == 0:
+		v.Add("actions", "must list at least one action")
+	case n > MaxRuleActions:
+		v.Add("actions", fmt.Sprintf("must list at most %d actions", MaxRuleActions))
+	}
+	for i, a := range r.Actions {
+		field := fmt.Sprintf("actions[%d]", i)
+		if !slices.Contains(ActionTypes, a.Type) {
+			v.Add(field+".type", "must be one of "+describe(ActionTypes))
+		}
+		switch {
+		case a.Type == ActionSetPriority && (a.Priority == "" || a.Priority.Rank() < 0):
+			v.Add(field+".priority", "must be one of "+describe(Priorities))
+		case a.Type != ActionSetPriority && a.Priority != "":
+			v.Add(field+".priority", "is only allowed on set_priority actions")
+		}
+		switch {
+		case a.Type == ActionSetStatus && !w.Has(a.Status):
+			v.Add(field+".status", "must be one of "+w.describe())
+		case a.Type != ActionSetStatus && a.Status != "":
+			v.Add(field+".status", "is only allowed on set_status actions")
+		}
+	}
+	return v.Err()
+}
+
+// describe lists values for an error message.
+func describe[T ~string](values []T) string {
+	names := make([]string, len(values))
+	for i, v := range values {
+		names[i] = string(v)
+	}
+	return strings.Join(names, ", ")
+}
+
+// RuleInput is the body accepted by POST /projects/{id}/rules. Enabled
+// defaults to true.
+type RuleInput struct {
+	Name    string       `json:"name"`
+	Enabled *bool        `json:"enabled,omitempty"`
+	Trigger RuleTrigger  `json:"trigger"`
+	Actions []RuleAction `json:"actions"`
+}
+
+// Apply copies in onto r.
+func (in RuleInput) Apply(r *Rule) {
+	r.Name = strings.TrimSpace(in.Name)
+	r.Enabled = in.Enabled == nil || *in.Enabled
+	r.Trigger = in.Trigger
+	r.Actions = slices.Clone(in.Actions)
+}
+
+// RulePatch is the body accepted by PATCH /projects/{id}/rules/{rule_id}.
+// Nil fields are left unchanged; Actions, when given, replaces the list.
+type RulePatch struct {
+	Name    *string       `json:"name,omitempty"`
+	Enabled *bool         `json:"enabled,omitempty"`
+	Trigger *RuleTrigger  `json:"trigger,omitempty"`
+	Actions *[]RuleAction `json:"actions,omitempty"`
+}
+
+// Apply copies the set fields of p onto r.
+func (p RulePatch) Apply(r *Rule) {
+	if p.Name != nil {
+		r.Name = strings.TrimSpace(*p.Name)
+	}
+	if p.Enabled != nil {
+		r.Enabled = *p.Enabled
+	}
+	if p.Trigger != nil {
+		r.Trigger = *p.Trigger
+	}
+	if p.Actions != nil {
+		r.Actions = slices.Clone(*p.Actions)
+	}
+}
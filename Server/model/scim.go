@@ -0,0 +1,132 @@
+package model
+
+import "time"
+
+// The schema URNs of SCIM 2.0 (RFC 7643 and 7644) that the /scim/v2
+// routes read and write.
+const (
+	SCIMUserSchema     = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIMGroupSchema    = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIMListSchema     = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMPatchSchema    = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIMErrorSchema    = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SCIMConfigSchema   = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SCIMResourceSchema = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+)
+
+// SCIMLink records that an identity provider provisioned a user into an
+// organization. UserName and ExternalID are the provider's names for
+// them. While Active is false the user is kept out of the organization,
+// but the link stays, so that the provider can bring them back.
+// CreatedAccount is set if provisioning created the user's account; the
+// holder of an account that existed before is invited instead of added.
+type SCIMLink struct {
+	OrgID          string
+	UserID         string
+	UserName       string
+	ExternalID     string
+	Active         bool
+	CreatedAccount bool
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// SCIMUser is the User resource. Of its attributes only userName,
+// externalId, emails and active are kept; others, such as name, are
+// accepted and ignored. Active is a pointer so that an omitted one can
+// default to true.
+type SCIMUser struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	ExternalID  string      `json:"externalId,omitempty"`
+	UserName    string      `json:"userName"`
+	DisplayName string      `json:"displayName,omitempty"`
+	Emails      []SCIMEmail `json:"emails,omitempty"`
+	Active      *bool       `json:"active,omitempty"`
+	Meta        *SCIMMeta   `json:"meta,omitempty"`
+}
+
+// Email returns the address in u's primary email, its first email
+// otherwise, or its userName if that is an address.
+func (u SCIMUser) Email() string {
+	for _, e := range u.Emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return u.UserName
+}
+
+// SCIMEmail is one of a SCIMUser's addresses.
+type SCIMEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// SCIMGroup is the Group resource, which is a project: its displayName is
+// the project's name and its members are the project's members.
+type SCIMGroup struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []SCIMMember `json:"members"`
+	Meta        *SCIMMeta    `json:"meta,omitempty"`
+}
+
+// SCIMMember is a member of a SCIMGroup. Value is the user's ID.
+type SCIMMember struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+// SCIMMeta describes a resource.
+type SCIMMeta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location,omitempty"`
+}
+
+// SCIMList is the body of a list of resources. StartIndex counts from 1.
+type SCIMList[T any] struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []T      `json:"Resources"`
+}
+
+// SCIMUserList is the body of GET /scim/v2/Users.
+type SCIMUserList SCIMList[SCIMUser]
+
+// SCIMGroupList is the body of GET /scim/v2/Groups.
+type SCIMGroupList SCIMList[SCIMGroup]
+
+// SCIMPatch is the body accepted by PATCH on a user or group.
+type SCIMPatch struct {
+	Schemas    []string      `json:"schemas"`
+	Operations []SCIMPatchOp `json:"Operations"`
+}
+
+// SCIMPatchOp is one operation of a SCIMPatch. Op is add, replace or
+// remove, in any case. Without a Path, Value is an object of the
+// attributes to set.
+type SCIMPatchOp struct {
+	Op    string `json:"op"`
+	Path  string `json:"path,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// SCIMError is the body of a failed SCIM request. Status is the HTTP
+// status as a string, as RFC 7644 has it.
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	SCIMType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
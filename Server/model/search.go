@@ -0,0 +1,29 @@
+package model
+
+// Kinds of SearchHit.
+const (
+	HitTask    = "task"
+	HitComment = "comment"
+)
+
+// SearchHit is one result of GET /search: a task whose title or
+// description matched, or a comment on it that did.
+type SearchHit struct {
+	Kind string `json:"kind"`
+	// Task is the task that matched, or the one the comment is on.
+	Task      Task    `json:"task"`
+	CommentID *string `json:"comment_id,omitempty"`
+	// Snippet is an HTML-escaped excerpt of the matching text with the
+	// matched words wrapped in <mark> elements.
+	Snippet string `json:"snippet"`
+	// Rank orders the hits; higher is more relevant. Values are only
+	// comparable within one search.
+	Rank float64 `json:"rank"`
+}
+
+// SearchPage is one page of search hits, best first. NextCursor is empty on
+// the last page.
+type SearchPage struct {
+	Items      []SearchHit `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"time"
+
+	"starttech-server/markdown"
+)
+
+// MaxSharedTasks bounds the tasks shown through one share link.
+const MaxSharedTasks = 1000
+
+// ShareLink gives anybody holding its token a read-only view of a task or
+// of a project's board, without signing in. Exactly one of ProjectID and
+// TaskID is set. The link stops working at ExpiresAt, if set, and when it
+// is deleted. Only a hash of its token is stored; the token and the URL
+// built from it are returned once, when the link is created.
+type ShareLink struct {
+	ID        string     `json:"id"`
+	OrgID     string     `json:"org_id"`
+	ProjectID *string    `json:"project_id"`
+	TaskID    *string    `json:"task_id"`
+	CreatedBy string     `json:"created_by"`
+	Token     string     `json:"token,omitempty"`
+	URL       string     `json:"url,omitempty"`
+	TokenHash string     `json:"-"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Expired reports whether l has stopped working by now.
+func (l ShareLink) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+// ShareInput is the body accepted by POST /projects/{id}/share and POST
+// /tasks/{id}/share. The link never expires without ExpiresAt.
+type ShareInput struct {
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Validate reports every field of in that breaks the API's rules.
+func (in ShareInput) Validate(now time.Time) error {
+	var v ValidationError
+	if in.ExpiresAt != nil && !in.ExpiresAt.After(now) {
+		v.Add("expires_at", "must be in the future")
+	}
+	return v.Err()
+}
+
+// SharedView is what GET /shared/{token} shows: a project and the tasks on
+// its board, or a task and its subtasks. It leaves out who works on them
+// and everything else private to the organization.
+type SharedView struct {
+	Project   *SharedProject `json:"project,omitempty"`
+	Task      *SharedTask    `json:"task,omitempty"`
+	Tasks     []SharedTask   `json:"tasks"`
+	ExpiresAt *time.Time     `json:"expires_at"`
+}
+
+// SharedProject is the part of a project a share link shows.
+type SharedProject struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Statuses    Workflow `json:"statuses"`
+}
+
+// NewSharedProject returns the part of p a share link shows.
+func NewSharedProject(p Project) SharedProject {
+	return SharedProject{ID: p.ID, Name: p.Name, Description: p.Description, Statuses: p.Statuses}
+}
+
+// SharedTask is the part of a task a share link shows.
+type SharedTask struct {
+	ID              string            `json:"id"`
+	ParentID        *string           `json:"parent_id"`
+	Position        float64           `json:"position"`
+	Title           string            `json:"title"`
+	Description     string            `json:"description"`
+	DescriptionHTML string            `json:"description_html"`
+	Status          Status            `json:"status"`
+	Completed       bool              `json:"completed"`
+	Priority        Priority          `json:"priority"`
+	DueDate         *time.Time        `json:"due_date"`
+	Checklist       ChecklistProgress `json:"checklist"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// NewSharedTask returns the part of t a share link shows.
+func NewSharedTask(t Task) SharedTask {
+	return SharedTask{
+		ID:              t.ID,
+		ParentID:        t.ParentID,
+		Position:        t.Position,
+		Title:           t.Title,
+		Description:     t.Description,
+		DescriptionHTML: markdown.Render(t.Description),
+		Status:          t.Status,
+		Completed:       t.Completed,
+		Priority:        t.Priority,
+		DueDate:         t.DueDate,
+		Checklist:       t.Checklist,
+		UpdatedAt:       t.UpdatedAt,
+	}
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SlackLink connects a project to a Slack channel. The project's task
+// events that it subscribes to are posted to the channel through the
+// incoming webhook at WebhookURL, and the channel's /task slash command,
+// pointed at CommandURL, creates tasks in the project. Slack signs the
+// commands with the app's SigningSecret. An empty Events list subscribes
+// to every event a link is offered. CreatedBy is the project owner who
+// linked it, in whose name tasks are created. WebhookURL and SigningSecret
+// are never returned; CommandURL is relative to the API, like attachment
+// links.
+type SlackLink struct {
+	ProjectID     string    `json:"project_id"`
+	OrgID         string    `json:"org_id"`
+	WebhookURL    string    `json:"-"`
+	SigningSecret string    `json:"-"`
+	Events        []string  `json:"events"`
+	CommandURL    string    `json:"command_url"`
+	CreatedBy     string    `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Validate reports every field of l that breaks the API's rules. Events must
+// be drawn from known.
+func (l *SlackLink) Validate(known []string) error {
+	var v ValidationError
+	u, err := url.Parse(l.WebhookURL)
+	switch {
+	case l.WebhookURL == "":
+		v.Add("webhook_url", "is required")
+	case err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "":
+		v.Add("webhook_url", "must be an absolute http or https URL")
+	case !publicHost(u.Hostname()):
+		v.Add("webhook_url", "must not point at a loopback, private or link-local address")
+	}
+	if l.SigningSecret == "" {
+		v.Add("signing_secret", "is required")
+	}
+	for _, e := range l.Events {
+		if !slices.Contains(known, e) {
+			v.Add("events", "unknown event type "+e)
+		}
+	}
+	return v.Err()
+}
+
+// Subscribes reports whether l should post events of type typ.
+func (l *SlackLink) Subscribes(typ string) bool {
+	return len(l.Events) == 0 || slices.Contains(l.Events, typ)
+}
+
+// SlackLinkInput is the body accepted by PUT /projects/{id}/slack.
+// WebhookURL is the incoming webhook Slack created for the channel, and
+// SigningSecret the signing secret of the Slack app.
+type SlackLinkInput struct {
+	WebhookURL    string   `json:"webhook_url"`
+	SigningSecret string   `json:"signing_secret"`
+	Events        []string `json:"events,omitempty"`
+}
+
+// Apply copies in onto l.
+func (in SlackLinkInput) Apply(l *SlackLink) {
+	l.WebhookURL = strings.TrimSpace(in.WebhookURL)
+	l.SigningSecret = strings.TrimSpace(in.SigningSecret)
+	l.Events = idSet(in.Events)
+}
+
+// Slack response types: an ephemeral reply is only shown to the user who
+// ran the command.
+const (
+	SlackEphemeral = "ephemeral"
+	SlackInChannel = "in_channel"
+)
+
+// SlackReply is the answer to a slash command, shown in the channel.
+type SlackReply struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
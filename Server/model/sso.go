@@ -0,0 +1,123 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultGroupsClaim is the claim an identity provider lists a user's
+// groups in unless an SSOConfig names another.
+const DefaultGroupsClaim = "groups"
+
+// SSOConfig signs in the people of an organization's email domain, which
+// must be one of its verified Domains, through its identity provider with
+// OpenID Connect. Issuer is the provider's
+// issuer URL, under which it publishes its discovery document. A sign-in
+// adds the user to the organization, and GroupRoles gives them roles in
+// its projects according to the groups the provider puts them in. While
+// Enforced is set, members of the organization whose addresses are at
+// Domain cannot sign in with a password.
+// ClientSecret is sealed so that the database alone does not reveal it.
+type SSOConfig struct {
+	OrgID        string         `json:"org_id"`
+	Domain       string         `json:"domain"`
+	Issuer       string         `json:"issuer"`
+	ClientID     string         `json:"client_id"`
+	ClientSecret string         `json:"-"`
+	GroupsClaim  string         `json:"groups_claim"`
+	GroupRoles   []SSOGroupRole `json:"group_roles,omitempty"`
+	Enforced     bool           `json:"enforced,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+// SSOGroupRole gives the members of an identity provider's group a role
+// in a project.
+type SSOGroupRole struct {
+	Group     string `json:"group"`
+	ProjectID string `json:"project_id"`
+	Role      Role   `json:"role"`
+}
+
+// Validate reports every field of c that breaks the API's rules.
+func (c *SSOConfig) Validate() error {
+	var v ValidationError
+	if !validDomain(c.Domain) {
+		v.Add("domain", "must be an email domain, such as example.com")
+	}
+	switch u, err := url.Parse(c.Issuer); {
+	case err != nil || u.Host == "" || u.Scheme != "https":
+		v.Add("issuer", "must be an https:// URL")
+	case !publicHost(u.Hostname()):
+		v.Add("issuer", "must not point at a loopback, private or link-local address")
+	}
+	if c.ClientID == "" {
+		v.Add("client_id", "is required")
+	}
+	if c.ClientSecret == "" {
+		v.Add("client_secret", "is required")
+	}
+	for i, g := range c.GroupRoles {
+		field := fmt.Sprintf("group_roles[%d]", i)
+		switch {
+		case g.Group == "":
+			v.Add(field+".group", "is required")
+		case g.ProjectID == "":
+			v.Add(field+".project_id", "is required")
+		case !g.Role.Valid():
+			v.Add(field+".role", `must be "viewer", "editor" or "owner"`)
+		}
+	}
+	return v.Err()
+}
+
+// SSOConfigInput is the body accepted by PUT /orgs/{id}/sso. The client
+// secret may be left out to keep the one saved.
+type SSOConfigInput struct {
+	Domain       string         `json:"domain"`
+	Issuer       string         `json:"issuer"`
+	ClientID     string         `json:"client_id"`
+	ClientSecret string         `json:"client_secret,omitempty"`
+	GroupsClaim  string         `json:"groups_claim,omitempty"`
+	GroupRoles   []SSOGroupRole `json:"group_roles,omitempty"`
+	Enforced     bool           `json:"enforced,omitempty"`
+}
+
+// Apply copies in onto c.
+func (in SSOConfigInput) Apply(c *SSOConfig) {
+	c.Domain = strings.ToLower(strings.TrimSpace(in.Domain))
+	c.Issuer = strings.TrimSuffix(strings.TrimSpace(in.Issuer), "/")
+	c.ClientID = strings.TrimSpace(in.ClientID)
+	if in.ClientSecret != "" {
+		c.ClientSecret = in.ClientSecret
+	}
+	if c.GroupsClaim = strings.TrimSpace(in.GroupsClaim); c.GroupsClaim == "" {
+		c.GroupsClaim = DefaultGroupsClaim
+	}
+	c.GroupRoles = in.GroupRoles
+	if c.GroupRoles == nil {
+		c.GroupRoles = []SSOGroupRole{}
+	}
+	c.Enforced = in.Enforced
+}
+
+// SSOLookup is the body returned by GET /auth/sso: whether an email
+// address signs in through its organization's identity provider, and
+// where to send the browser to do so.
+type SSOLookup struct {
+	SSO      bool   `json:"sso"`
+	Enforced bool   `json:"enforced"`
+	StartURL string `json:"start_url,omitempty"`
+}
+
+// EmailDomain returns the lower-cased part of email after the @, or "" if
+// there is none.
+func EmailDomain(email string) string {
+	_, domain, ok := strings.Cut(strings.TrimSpace(email), "@")
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(domain)
+}
@@ -0,0 +1,41 @@
+package model
+
+// ProjectStats charts a project day by day from From to To, both included
+// and given as YYYY-MM-DD dates in the caller's timezone.
+//
+// Throughput counts the tasks completed each day, and Burndown the tasks
+// open and done at the end of each day, or now for today. CycleTime gives,
+// for each open column of the project's workflow, the average time tasks
+// spent in it on the visits that ended in the range, by moving on to
+// another column.
+type ProjectStats struct {
+	ProjectID  string        `json:"project_id"`
+	From       string        `json:"from"`
+	To         string        `json:"to"`
+	Throughput []DayCount    `json:"throughput"`
+	CycleTime  []ColumnTime  `json:"cycle_time"`
+	Burndown   []BurndownDay `json:"burndown"`
+}
+
+// DayCount is the number of tasks completed on one day.
+type DayCount struct {
+	Date      string `json:"date"`
+	Completed int    `json:"completed"`
+}
+
+// ColumnTime is the average time tasks spent in one column. AverageSeconds
+// is zero when Visits is.
+type ColumnTime struct {
+	Status         Status `json:"status"`
+	Name           string `json:"name"`
+	AverageSeconds int64  `json:"average_seconds"`
+	Visits         int    `json:"visits"`
+}
+
+// BurndownDay is the number of a project's tasks open and done at the end
+// of one day.
+type BurndownDay struct {
+	Date string `json:"date"`
+	Open int    `json:"open"`
+	Done int    `json:"done"`
+}
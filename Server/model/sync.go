@@ -0,0 +1,100 @@
+package model
+
+import (
+	"time"
+
+	"starttech-server/apierror"
+)
+
+// MaxSyncMutations bounds the mutations of one POST /sync/push.
+const MaxSyncMutations = 100
+
+// CommandSync is the Action of the command of a POST /sync/push.
+const CommandSync = "sync.push"
+
+// ChangePage is one page of GET /sync/changes: the tasks changed since the
+// cursor the client passed, oldest change first, each as it is now, and
+// Deleted, the IDs of those the client should drop because they were
+// deleted for good or the user can no longer see them. A task in the trash
+// is listed with deleted_at set. Cursor picks up after the last entry and
+// is set on every page, the last included; HasMore tells whether to ask
+// for the next page right away.
+type ChangePage struct {
+	Items   []Task   `json:"items"`
+	Deleted []string `json:"deleted"`
+	Cursor  string   `json:"cursor"`
+	HasMore bool     `json:"has_more"`
+}
+
+// SyncStrategy names how POST /sync/push settles a mutation made to a task
+// that has changed on the server since the client last saw it.
+type SyncStrategy string
+
+const (
+	// SyncMerge applies the fields of the mutation the server has not
+	// changed since, and keeps the server's values for the others.
+	SyncMerge SyncStrategy = "merge"
+	// SyncLastWriteWins applies the whole mutation if it was made after
+	// the task was last saved, and none of it otherwise.
+	SyncLastWriteWins SyncStrategy = "last_write_wins"
+)
+
+// SyncMutation is one change a client made while offline: a create, which
+// takes Task and may carry a ClientID of the client's choosing to match the
+// new task up with its local copy and to create it only once, or an update
+// or delete of the task ID.
+// An update takes Patch. BaseVersion is the version of the task the
+// client changed, and ChangedAt when it changed it.
+type SyncMutation struct {
+	Op          BulkOp     `json:"op"`
+	ClientID    string     `json:"client_id,omitempty"`
+	ID          string     `json:"id,omitempty"`
+	BaseVersion int64      `json:"base_version,omitempty"`
+	ChangedAt   *time.Time `json:"changed_at,omitempty"`
+	Task        *TaskInput `json:"task,omitempty"`
+	Patch       *TaskPatch `json:"patch,omitempty"`
+}
+
+// SyncPushInput is the body accepted by POST /sync/push. The mutations are
+// applied in order, each on its own. Strategy defaults to SyncMerge.
+type SyncPushInput struct {
+	Strategy  SyncStrategy   `json:"strategy,omitempty"`
+	Mutations []SyncMutation `json:"mutations"`
+}
+
+// SyncResolution tells what became of a pushed mutation.
+type SyncResolution string
+
+const (
+	// SyncApplied means the task had not changed since BaseVersion, and
+	// the mutation was applied as it is.
+	SyncApplied SyncResolution = "applied"
+	// SyncMerged means the task had changed, and the mutation was
+	// applied on top of the change, in part or in whole.
+	SyncMerged SyncResolution = "merged"
+	// SyncRejected means the task had changed, and the server's version
+	// was kept as it is.
+	SyncRejected SyncResolution = "rejected"
+	// SyncFailed means the mutation was refused, as its own endpoint
+	// would have refused it.
+	SyncFailed SyncResolution = "failed"
+)
+
+// SyncPushResult reports on every mutation of a SyncPushInput, in the same
+// order.
+type SyncPushResult struct {
+	Results []SyncOutcome `json:"results"`
+}
+
+// SyncOutcome is the result of one pushed mutation, with the task as it
+// now stands, which the client should keep in place of its own copy; there
+// is none once the task is deleted. Conflicts lists the fields both the
+// client and the server changed since BaseVersion, which the strategy
+// settled. Error is set when the mutation failed.
+type SyncOutcome struct {
+	Resolution SyncResolution  `json:"resolution"`
+	ClientID   string          `json:"client_id,omitempty"`
+	Task       *Task           `json:"task,omitempty"`
+	Conflicts  []string        `json:"conflicts,omitempty"`
+	Error      *apierror.Error `json:"error,omitempty"`
+}
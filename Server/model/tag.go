@@ -0,0 +1,83 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxTagNameLen bounds Tag.Name.
+const MaxTagNameLen = 50
+
+// Tag is a user-defined label that can be attached to any number of tasks.
+// Names are unique per owner within an organization, ignoring case.
+type Tag struct {
+	ID        string    `json:"id"`
+	OrgID     string    `json:"org_id"`
+	OwnerID   string    `json:"owner_id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var hexColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Validate reports every field of t that breaks the API's rules.
+func (t *Tag) Validate() error {
+	var v ValidationError
+	switch {
+	case t.Name == "":
+		v.Add("name", "is required")
+	case utf8.RuneCountInString(t.Name) > MaxTagNameLen:
+		v.Add("name", fmt.Sprintf("must be at most %d characters", MaxTagNameLen))
+	}
+	if t.Color != "" && !hexColor.MatchString(t.Color) {
+		v.Add("color", "must be a #rrggbb hex color")
+	}
+	return v.Err()
+}
+
+// TagInput is the body accepted by POST /tags.
+type TagInput struct {
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+}
+
+// Apply copies in onto t.
+func (in TagInput) Apply(t *Tag) {
+	t.Name = strings.TrimSpace(in.Name)
+	t.Color = strings.ToLower(in.Color)
+}
+
+// TagPatch is the body accepted by PATCH /tags/{id}. Nil fields are left
+// unchanged.
+type TagPatch struct {
+	Name  *string `json:"name"`
+	Color *string `json:"color"`
+}
+
+// Apply copies the set fields of p onto t.
+func (p TagPatch) Apply(t *Tag) {
+	if p.Name != nil {
+		t.Name = strings.TrimSpace(*p.Name)
+	}
+	if p.Color != nil {
+		t.Color = strings.ToLower(*p.Color)
+	}
+}
+
+// idSet returns ids sorted and without blanks or duplicates, so every store
+// reports a task's tags in the same order.
+func idSet(ids []string) []string {
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); id != "" {
+			out = append(out, id)
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
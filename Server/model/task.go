@@ -0,0 +1,317 @@
+// Package model defines the resources exposed by the API.
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"starttech-server/markdown"
+	"starttech-server/recurrence"
+)
+
+// Status is the workflow state of a task.
+type Status string
+
+const (
+	StatusTodo       Status = "todo"
+	StatusInProgress Status = "in_progress"
+	StatusDone       Status = "done"
+)
+
+// Valid reports whether s is a known status.
+func (s Status) Valid() bool {
+	switch s {
+	case StatusTodo, StatusInProgress, StatusDone:
+		return true
+	}
+	return false
+}
+
+// Priority ranks how important a task is.
+type Priority string
+
+const (
+	PriorityNone   Priority = "none"
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// Priorities lists every priority from the least to the most important.
+var Priorities = []Priority{PriorityNone, PriorityLow, PriorityMedium, PriorityHigh, PriorityUrgent}
+
+// Rank returns the position of p in Priorities, or -1 if p is unknown.
+func (p Priority) Rank() int {
+	return slices.Index(Priorities, p)
+}
+
+// Field limits enforced by Task.Validate.
+const (
+	MaxTitleLen       = 200
+	MaxDescriptionLen = 10000
+)
+
+// Task is a single to-do item. Completed always mirrors Status == done; it is
+// kept for clients that only track a checkbox. A task with a ParentID is a
+// subtask of that task. TagIDs is sorted and never nil. Position orders the
+// task within its project and is assigned by the server. Recurrence is an
+// RRULE; completing the task creates the next occurrence, which takes the
+// rule over. Priority defaults to none. AssigneeID is the user the task is
+// assigned to, if any. Checklist counts the items of its checklist.
+// Fields holds the values of its project's custom fields by field ID and
+// is never nil.
+// DeletedAt is set while the task is in the trash, and ArchivedAt while it
+// is archived, which keeps it out of listings but not out of search.
+// Version goes up with every saved change and backs the task's ETag;
+// renumbering a project's positions leaves it alone.
+// Description is Markdown; DescriptionHTML is rendered from it whenever
+// the task is encoded and is ignored in requests.
+// PossibleDuplicates is only set in the response to creating the task: the
+// open tasks of its project whose titles are close to its own.
+type Task struct {
+	ID              string            `json:"id"`
+	OrgID           string            `json:"org_id"`
+	OwnerID         string            `json:"owner_id"`
+	AssigneeID      *string           `json:"assignee_id"`
+	ProjectID       *string           `json:"project_id"`
+	ParentID        *string           `json:"parent_id"`
+	Position        float64           `json:"position"`
+	Title           string            `json:"title"`
+	Description     string            `json:"description"`
+	DescriptionHTML string            `json:"description_html"`
+	Status          Status            `json:"status"`
+	Completed       bool              `json:"completed"`
+	Priority        Priority          `json:"priority"`
+	DueDate         *time.Time        `json:"due_date"`
+	RemindAt        *time.Time        `json:"remind_at"`
+	Recurrence      string            `json:"recurrence"`
+	TagIDs          []string          `json:"tag_ids"`
+	Fields          map[string]any    `json:"fields"`
+	Checklist       ChecklistProgress `json:"checklist"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	DeletedAt       *time.Time        `json:"deleted_at"`
+	ArchivedAt      *time.Time        `json:"archived_at"`
+	Version         int64             `json:"version"`
+
+	PossibleDuplicates []string `json:"possible_duplicates,omitempty"`
+}
+
+// MarshalJSON encodes t with its description rendered.
+func (t Task) MarshalJSON() ([]byte, error) {
+	type plain Task
+	p := plain(t)
+	p.DescriptionHTML = markdown.Render(t.Description)
+	return json.Marshal(p)
+}
+
+// Validate reports every field of t that breaks the API's rules, assuming
+// the default workflow.
+func (t *Task) Validate() error {
+	return t.ValidateIn(DefaultWorkflow)
+}
+
+// ValidateIn is Validate for a task whose status must belong to w.
+func (t *Task) ValidateIn(w Workflow) error {
+	var v ValidationError
+	switch n := utf8.RuneCountInString(t.Title); {
+	case strings.TrimSpace(t.Title) == "":
+		v.Add("title", "is required")
+	case n > MaxTitleLen:
+		v.Add("title", fmt.Sprintf("must be at most %d characters", MaxTitleLen))
+	}
+	if utf8.RuneCountInString(t.Description) > MaxDescriptionLen {
+		v.Add("description", fmt.Sprintf("must be at most %d characters", MaxDescriptionLen))
+	}
+	if !w.Has(t.Status) {
+		v.Add("status", "must be one of "+w.describe())
+	}
+	if t.Priority.Rank() < 0 {
+		v.Add("priority", "must be one of "+describePriorities())
+	}
+	if t.Recurrence != "" {
+		if _, err := recurrence.Parse(t.Recurrence); err != nil {
+			v.Add("recurrence", err.Error())
+		}
+	}
+	return v.Err()
+}
+
+func describePriorities() string {
+	names := make([]string, len(Priorities))
+	for i, p := range Priorities {
+		names[i] = string(p)
+	}
+	return strings.Join(names, ", ")
+}
+
+// setCompleted updates Completed and moves Status in or out of done when the
+// flag changes. Workflow.Conform maps these onto custom columns.
+func (t *Task) setCompleted(done bool) {
+	switch {
+	case done && !t.Completed:
+		t.Status = StatusDone
+	case !done && t.Completed:
+		t.Status = StatusTodo
+	}
+	t.Completed = done
+}
+
+// setStatus updates Status and derives Completed from it. Workflow.Conform
+// corrects Completed for custom done columns.
+func (t *Task) setStatus(s Status) {
+	t.Status = s
+	t.Completed = s == StatusDone
+}
+
+// TaskInput is the body accepted by POST /tasks and PUT /tasks/{id}. When
+// Status is omitted it is derived from Completed, and an omitted Priority is
+// none. Fields replaces every custom field value of the task.
+type TaskInput struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Status      Status         `json:"status,omitempty"`
+	Completed   bool           `json:"completed,omitempty"`
+	Priority    Priority       `json:"priority,omitempty"`
+	DueDate     *time.Time     `json:"due_date,omitempty"`
+	RemindAt    *time.Time     `json:"remind_at,omitempty"`
+	Recurrence  string         `json:"recurrence,omitempty"`
+	ProjectID   *string        `json:"project_id,omitempty"`
+	ParentID    *string        `json:"parent_id,omitempty"`
+	AssigneeID  *string        `json:"assignee_id,omitempty"`
+	TagIDs      []string       `json:"tag_ids,omitempty"`
+	Fields      map[string]any `json:"fields,omitempty"`
+}
+
+// Apply overwrites the client-editable fields of t with in.
+func (in TaskInput) Apply(t *Task) {
+	t.Title = strings.TrimSpace(in.Title)
+	t.Description = in.Description
+	t.DueDate = utcPtr(in.DueDate)
+	t.RemindAt = utcPtr(in.RemindAt)
+	t.Recurrence = strings.TrimSpace(in.Recurrence)
+	t.ProjectID = idPtr(in.ProjectID)
+	t.ParentID = idPtr(in.ParentID)
+	t.AssigneeID = idPtr(in.AssigneeID)
+	t.TagIDs = idSet(in.TagIDs)
+	t.Fields = fieldValues(in.Fields)
+	t.Priority = in.Priority
+	if t.Priority == "" {
+		t.Priority = PriorityNone
+	}
+	if in.Status != "" {
+		t.setStatus(in.Status)
+	} else {
+		t.Status, t.Completed = StatusTodo, false
+		t.setCompleted(in.Completed)
+	}
+}
+
+// TaskPatch is the body accepted by PATCH /tasks/{id}. Nil fields are left
+// unchanged. If both Status and Completed are set, Status wins. A null
+// parent_id moves the task to the top level, a null project_id takes it
+// out of its project, and a null assignee_id unassigns it. Fields sets the
+// custom field values it lists and leaves the others; a null value removes
+// one.
+type TaskPatch struct {
+	Title       *string             `json:"title,omitempty"`
+	Description *string             `json:"description,omitempty"`
+	Status      *Status             `json:"status,omitempty"`
+	Completed   *bool               `json:"completed,omitempty"`
+	Priority    *Priority           `json:"priority,omitempty"`
+	DueDate     Optional[time.Time] `json:"due_date,omitzero"`
+	RemindAt    Optional[time.Time] `json:"remind_at,omitzero"`
+	Recurrence  *string             `json:"recurrence,omitempty"`
+	ProjectID   Optional[string]    `json:"project_id,omitzero"`
+	ParentID    Optional[string]    `json:"parent_id,omitzero"`
+	AssigneeID  Optional[string]    `json:"assignee_id,omitzero"`
+	TagIDs      *[]string           `json:"tag_ids,omitempty"`
+	Fields      map[string]any      `json:"fields,omitempty"`
+}
+
+// Apply copies the set fields of p onto t.
+func (p TaskPatch) Apply(t *Task) {
+	if p.Title != nil {
+		t.Title = strings.TrimSpace(*p.Title)
+	}
+	if p.Description != nil {
+		t.Description = *p.Description
+	}
+	if p.Priority != nil {
+		t.Priority = *p.Priority
+	}
+	if p.DueDate.Set {
+		t.DueDate = utcPtr(p.DueDate.Ptr())
+	}
+	if p.RemindAt.Set {
+		t.RemindAt = utcPtr(p.RemindAt.Ptr())
+	}
+	if p.Recurrence != nil {
+		t.Recurrence = strings.TrimSpace(*p.Recurrence)
+	}
+	if p.ProjectID.Set {
+		t.ProjectID = idPtr(p.ProjectID.Ptr())
+	}
+	if p.ParentID.Set {
+		t.ParentID = idPtr(p.ParentID.Ptr())
+	}
+	if p.AssigneeID.Set {
+		t.AssigneeID = idPtr(p.AssigneeID.Ptr())
+	}
+	if p.TagIDs != nil {
+		t.TagIDs = idSet(*p.TagIDs)
+	}
+	if p.Fields != nil {
+		t.Fields = mergeFieldValues(t.Fields, p.Fields)
+	}
+	switch {
+	case p.Status != nil:
+		t.setStatus(*p.Status)
+	case p.Completed != nil:
+		t.setCompleted(*p.Completed)
+	}
+}
+
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// idPtr treats a blank ID the same as a missing one.
+func idPtr(id *string) *string {
+	if id == nil || strings.TrimSpace(*id) == "" {
+		return nil
+	}
+	v := strings.TrimSpace(*id)
+	return &v
+}
+
+// AssignInput is the body accepted by PUT /tasks/{id}/assignee. UserID may
+// be "me" for the caller.
+type AssignInput struct {
+	UserID string `json:"user_id"`
+}
+
+// TaskPage is one page of a task listing. NextCursor is empty on the last
+// page.
+type TaskPage struct {
+	Items      []Task `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Rollup summarises the completion of every subtask below a task, at any
+// depth. Percent is 0 when the task has no subtasks.
+type Rollup struct {
+	TaskID  string  `json:"task_id"`
+	Total   int     `json:"total"`
+	Done    int     `json:"done"`
+	Percent float64 `json:"percent"`
+}
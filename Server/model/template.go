@@ -0,0 +1,171 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits on templates.
+const (
+	MaxTemplateNameLen  = 100
+	MaxTemplateTasks    = 500
+	MaxTemplateVariable = 200
+)
+
+// TemplateKind tells what a template makes.
+type TemplateKind string
+
+const (
+	// TemplateKindTask makes a task and its subtasks.
+	TemplateKindTask TemplateKind = "task"
+	// TemplateKindProject makes a project with its columns and tasks.
+	TemplateKindProject TemplateKind = "project"
+)
+
+// Template is a task or a whole project saved to be made again. Titles,
+// descriptions and the project name may hold {{placeholders}}, filled in
+// when the template is used. Templates are private to their owner, and
+// names are unique per owner within an organization, ignoring case.
+type Template struct {
+	ID      string           `json:"id"`
+	OrgID   string           `json:"org_id"`
+	OwnerID string           `json:"owner_id"`
+	Name    string           `json:"name"`
+	Kind    TemplateKind     `json:"kind"`
+	Project *TemplateProject `json:"project"`
+	Tasks   []TemplateTask   `json:"tasks"`
+	// CreatedAt is also the day due dates are counted from.
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate reports every field of t that breaks the API's rules.
+func (t *Template) Validate() error {
+	var v ValidationError
+	switch {
+	case t.Name == "":
+		v.Add("name", "is required")
+	case utf8.RuneCountInString(t.Name) > MaxTemplateNameLen:
+		v.Add("name", fmt.Sprintf("must be at most %d characters", MaxTemplateNameLen))
+	}
+	if len(t.Tasks) > MaxTemplateTasks {
+		v.Add("tasks", fmt.Sprintf("must hold at most %d tasks", MaxTemplateTasks))
+	}
+	return v.Err()
+}
+
+// TemplateProject is the project of a project template.
+type TemplateProject struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Statuses    Workflow `json:"statuses"`
+}
+
+// TemplateTask is one task of a template. Ref names it within the
+// template so that ParentRef can make it a subtask of an earlier one. Tags
+// are given by name. A task with a due date has it DueInDays after the day
+// the template is used, at DueTime, an HH:MM time of day in UTC.
+type TemplateTask struct {
+	Ref         string   `json:"ref"`
+	ParentRef   string   `json:"parent_ref,omitempty"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Status      Status   `json:"status"`
+	Priority    Priority `json:"priority"`
+	DueInDays   *int     `json:"due_in_days"`
+	DueTime     string   `json:"due_time,omitempty"`
+	Recurrence  string   `json:"recurrence"`
+	Tags        []string `json:"tags"`
+}
+
+// SetDue records due as a date relative to day, which is midnight UTC.
+func (d *TemplateTask) SetDue(due *time.Time, day time.Time) {
+	if due == nil {
+		d.DueInDays, d.DueTime = nil, ""
+		return
+	}
+	u := due.UTC()
+	days := int(u.Truncate(24*time.Hour).Sub(day) / (24 * time.Hour))
+	d.DueInDays, d.DueTime = &days, u.Format("15:04")
+}
+
+// DueOn returns the due date of the task when the template is used on
+// start, which is midnight UTC.
+func (d TemplateTask) DueOn(start time.Time) *time.Time {
+	if d.DueInDays == nil {
+		return nil
+	}
+	due := start.AddDate(0, 0, *d.DueInDays)
+	if tod, err := time.Parse("15:04", d.DueTime); err == nil {
+		due = due.Add(time.Duration(tod.Hour())*time.Hour + time.Duration(tod.Minute())*time.Minute)
+	}
+	return &due
+}
+
+// TemplateInput is the body accepted by POST /tasks/{id}/template and POST
+// /projects/{id}/template.
+type TemplateInput struct {
+	Name string `json:"name"`
+}
+
+// TemplatePatch is the body accepted by PATCH /templates/{id}.
+type TemplatePatch struct {
+	Name *string `json:"name"`
+}
+
+// TemplateUse is the body accepted by POST /templates/{id}/use. Start is
+// the day due dates count from, today by default. Variables fill the
+// {{placeholders}} of the template; {{date}} is Start as YYYY-MM-DD and,
+// for a project template, {{project}} is the project's name. Name names
+// the new project, instead of the template's own project name. ProjectID
+// puts the tasks of a task template in a project.
+type TemplateUse struct {
+	Name      string            `json:"name,omitempty"`
+	ProjectID *string           `json:"project_id,omitempty"`
+	Start     *time.Time        `json:"start,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+// Validate reports every field of u that breaks the API's rules.
+func (u *TemplateUse) Validate() error {
+	var v ValidationError
+	for k, val := range u.Variables {
+		if !placeholderName.MatchString(k) {
+			v.Add("variables", fmt.Sprintf("%q is not a valid name", k))
+		}
+		if utf8.RuneCountInString(val) > MaxTemplateVariable {
+			v.Add("variables", fmt.Sprintf("%q must be at most %d characters", k, MaxTemplateVariable))
+		}
+	}
+	return v.Err()
+}
+
+// TemplateResult is what using a template made: a project for a project
+// template, and the tasks in the order they were created.
+type TemplateResult struct {
+	Project *Project `json:"project"`
+	Tasks   []Task   `json:"tasks"`
+}
+
+var (
+	placeholderName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	placeholder     = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+)
+
+// FillPlaceholders replaces each {{name}} in s with vars[name]. Unknown
+// placeholders are left as they are.
+func FillPlaceholders(s string, vars map[string]string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	return placeholder.ReplaceAllStringFunc(s, func(m string) string {
+		name := placeholder.FindStringSubmatch(m)[1]
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		return m
+	})
+}
@@ -0,0 +1,127 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxTimeNoteLen bounds TimeEntry.Note.
+const MaxTimeNoteLen = 1000
+
+// TimeEntry is a span of time a user spent on a task. EndedAt is nil while
+// the entry is a running timer, and a user has at most one of those.
+// ProjectID is the task's current project. Seconds is the length of the
+// entry, up to now for a running timer.
+type TimeEntry struct {
+	ID        string     `json:"id"`
+	OrgID     string     `json:"org_id"`
+	TaskID    string     `json:"task_id"`
+	ProjectID *string    `json:"project_id"`
+	UserID    string     `json:"user_id"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Seconds   int64      `json:"seconds"`
+	Note      string     `json:"note"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Running reports whether e is a timer that has not been stopped.
+func (e TimeEntry) Running() bool {
+	return e.EndedAt == nil
+}
+
+// Overlap returns how much of e falls between from and to, either of which
+// may be nil for an open end. A running entry lasts until now.
+func (e TimeEntry) Overlap(from, to *time.Time, now time.Time) time.Duration {
+	start, end := e.StartedAt, now
+	if e.EndedAt != nil {
+		end = *e.EndedAt
+	}
+	if from != nil && from.After(start) {
+		start = *from
+	}
+	if to != nil && to.Before(end) {
+		end = *to
+	}
+	return max(end.Sub(start), 0)
+}
+
+// Validate reports every field of e that breaks the API's rules.
+func (e *TimeEntry) Validate() error {
+	var v ValidationError
+	if e.StartedAt.IsZero() {
+		v.Add("started_at", "is required")
+	}
+	if e.EndedAt != nil && !e.EndedAt.After(e.StartedAt) {
+		v.Add("ended_at", "must be after started_at")
+	}
+	if utf8.RuneCountInString(e.Note) > MaxTimeNoteLen {
+		v.Add("note", fmt.Sprintf("must be at most %d characters", MaxTimeNoteLen))
+	}
+	return v.Err()
+}
+
+// TimeEntryInput is the body accepted by POST /tasks/{id}/time-entries,
+// which logs time after the fact. EndedAt is required.
+type TimeEntryInput struct {
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Note      string     `json:"note,omitempty"`
+}
+
+// Apply overwrites the client-editable fields of e with in.
+func (in TimeEntryInput) Apply(e *TimeEntry) {
+	e.StartedAt = in.StartedAt.UTC()
+	e.EndedAt = utcPtr(in.EndedAt)
+	e.Note = strings.TrimSpace(in.Note)
+}
+
+// TimeEntryPatch is the body accepted by PATCH
+// /tasks/{id}/time-entries/{entry_id}. Nil fields are left unchanged;
+// setting ended_at on a running timer stops it.
+type TimeEntryPatch struct {
+	StartedAt *time.Time `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Note      *string    `json:"note"`
+}
+
+// Apply copies the set fields of p onto e.
+func (p TimeEntryPatch) Apply(e *TimeEntry) {
+	if p.StartedAt != nil {
+		e.StartedAt = p.StartedAt.UTC()
+	}
+	if p.EndedAt != nil {
+		e.EndedAt = utcPtr(p.EndedAt)
+	}
+	if p.Note != nil {
+		e.Note = strings.TrimSpace(*p.Note)
+	}
+}
+
+// Groupings of TimeTotals.
+const (
+	TimeByTask    = "task"
+	TimeByProject = "project"
+	TimeByUser    = "user"
+)
+
+// TimeTotals sums the time logged between From and To, which are null for
+// an open end, grouped By task, project or user. Groups are sorted by
+// Seconds, largest first.
+type TimeTotals struct {
+	By      string      `json:"by"`
+	From    *time.Time  `json:"from"`
+	To      *time.Time  `json:"to"`
+	Seconds int64       `json:"seconds"`
+	Groups  []TimeTotal `json:"groups"`
+}
+
+// TimeTotal is the time logged on one task, project or user. ID is null
+// for the group of tasks outside any project.
+type TimeTotal struct {
+	ID      *string `json:"id"`
+	Seconds int64   `json:"seconds"`
+	Entries int     `json:"entries"`
+}
@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// RecoveryCodeCount is how many recovery codes a user is given at a time.
+const RecoveryCodeCount = 10
+
+// TwoFactor is a user's authenticator app. It is pending, and asks nothing
+// of sign-ins, until the user proves they have set it up by sending a code
+// and EnabledAt is set. Secret is the TOTP secret, sealed so that the
+// database alone does not reveal it. LastStep is the period of the last
+// code accepted; codes are only accepted for later periods, so none can be
+// used twice.
+type TwoFactor struct {
+	UserID    string
+	Secret    string
+	EnabledAt *time.Time
+	LastStep  int64
+	CreatedAt time.Time
+}
+
+// TwoFactorStatus is the body returned by GET /auth/2fa.
+type TwoFactorStatus struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+// TwoFactorSetup is the body returned by POST /auth/2fa/setup: the secret
+// to type into an authenticator app, and the otpauth:// URI to show as a
+// QR code for it to scan instead.
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// TwoFactorCodeInput is the body accepted by the /auth/2fa routes that
+// need a code: six digits from the authenticator app or, where the app is
+// lost, a recovery code.
+type TwoFactorCodeInput struct {
+	Code string `json:"code"`
+}
+
+// RecoveryCodes are the single-use codes that stand in for the
+// authenticator app. They are only ever shown when they are made.
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+// TwoFactorLoginInput is the body accepted by POST /auth/2fa/verify, which
+// finishes a sign-in that POST /auth/login answered with a challenge.
+// OrgID picks the organization as it does for login.
+type TwoFactorLoginInput struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+	OrgID     string `json:"org_id,omitempty"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// CommandBulk is the Action of the command of a POST /tasks/bulk.
+const CommandBulk = "tasks.bulk"
+
+// Command is one change a user made to tasks, kept for a short while so
+// that POST /undo can revert it. Action names it as the activity log does,
+// such as "task.updated", or is CommandBulk. Steps has an entry for every
+// task the change saved, in the order it first saved them.
+type Command struct {
+	ID        string        `json:"id"`
+	OrgID     string        `json:"org_id"`
+	UserID    string        `json:"user_id"`
+	Action    string        `json:"action"`
+	Steps     []CommandStep `json:"steps"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// CommandStep is what a Command did to one task: it took the task from
+// version Before, zero for a task it created, to version After.
+type CommandStep struct {
+	TaskID string `json:"task_id"`
+	Before int64  `json:"before"`
+	After  int64  `json:"after"`
+}
+
+// Undone is the response to POST /undo: the action reverted and the tasks
+// it touched, as they are now. Tasks the action created are in the trash.
+type Undone struct {
+	Action    string    `json:"action"`
+	CreatedAt time.Time `json:"created_at"`
+	Tasks     []Task    `json:"tasks"`
+}
@@ -0,0 +1,151 @@
+package model
+
+import (
+	"sync"
+	"time"
+)
+
+// User is an account that owns tasks. A user cannot sign in with a
+// password until their email address is verified, nor at all once an
+// administrator has disabled their account. Timezone is the IANA name of
+// the zone the user's days start in, such as Europe/Paris. Language is the
+// language the user is emailed and answered in, such as fr; when it is
+// empty, requests are answered in the language of their Accept-Language
+// header, and emails are in English. DeletionRequestedAt is set while the
+// user waits for their account to be erased, and DeletedAt once it has
+// been: what is left of an erased user keeps only the ID.
+type User struct {
+	ID                  string     `json:"id"`
+	Email               string     `json:"email"`
+	Username            string     `json:"username"`
+	PasswordHash        string     `json:"-"`
+	Timezone            string     `json:"timezone"`
+	Language            string     `json:"language"`
+	EmailVerifiedAt     *time.Time `json:"email_verified_at"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	DeletionRequestedAt *time.Time `json:"deletion_requested_at,omitempty"`
+	DeletedAt           *time.Time `json:"deleted_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+// DefaultTimezone is the timezone of users who have not chosen one.
+const DefaultTimezone = "UTC"
+
+// zones caches the locations LoadTimezone has read, by name.
+var zones sync.Map
+
+// LoadTimezone returns the location with the given IANA name. Unlike
+// time.LoadLocation it refuses "" and "Local", which name no zone of their
+// own.
+func LoadTimezone(name string) (*time.Location, bool) {
+	if loc, ok := zones.Load(name); ok {
+		return loc.(*time.Location), true
+	}
+	if name == "" || name == "Local" {
+		return nil, false
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, false
+	}
+	zones.Store(name, loc)
+	return loc, true
+}
+
+// Location returns the timezone of u, or UTC if it is unset or unknown.
+func (u User) Location() *time.Location {
+	if loc, ok := LoadTimezone(u.Timezone); ok {
+		return loc
+	}
+	return time.UTC
+}
+
+// Identity links a user to their account with an OAuth provider, such as
+// google or github. Subject is the provider's ID for the account, which,
+// unlike the email, never changes.
+type Identity struct {
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RegisterInput is the body accepted by POST /auth/register. Timezone
+// defaults to UTC, and Language to the one the request's Accept-Language
+// header asks for, if the server has it.
+type RegisterInput struct {
+	Email    string `json:"email"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Timezone string `json:"timezone,omitempty"`
+	Language string `json:"language,omitempty"`
+}
+
+// ProfilePatch is the body accepted by PATCH /me. Omitted fields are left
+// unchanged; an empty Language clears the preference.
+type ProfilePatch struct {
+	Timezone *string `json:"timezone"`
+	Language *string `json:"language"`
+}
+
+// LoginInput is the body accepted by POST /auth/login. OrgID picks the
+// organization the session works in; it defaults to the user's oldest
+// membership, normally their personal organization.
+type LoginInput struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	OrgID    string `json:"org_id,omitempty"`
+}
+
+// Session is returned by the register, login, refresh and switch
+// endpoints. The token is valid only within Org. RefreshToken renews the
+// token once it expires; it is replaced on every refresh, so a switch,
+// which keeps it, leaves it out.
+type Session struct {
+	Token        string    `json:"token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	User         User      `json:"user"`
+	Org          Org       `json:"org"`
+}
+
+// EmailInput is the body accepted by POST /auth/forgot-password and POST
+// /auth/resend-verification.
+type EmailInput struct {
+	Email string `json:"email"`
+}
+
+// TokenInput is the body accepted by POST /auth/verify-email.
+type TokenInput struct {
+	Token string `json:"token"`
+}
+
+// ResetPasswordInput is the body accepted by POST /auth/reset-password.
+type ResetPasswordInput struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// RefreshInput is the body accepted by POST /auth/refresh. Browser clients
+// may leave it empty and send the refresh cookie instead.
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// AuthSession is a sign-in of a user on one device, which lasts for as long
+// as its refresh token keeps being used. Only hashes of the current
+// refresh token and the one it replaced are stored. Current marks the
+// session of the request that lists it.
+type AuthSession struct {
+	ID            string    `json:"id"`
+	UserID        string    `json:"user_id"`
+	OrgID         string    `json:"org_id"`
+	UserAgent     string    `json:"user_agent"`
+	TokenHash     string    `json:"-"`
+	PrevTokenHash string    `json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+	LastUsedAt    time.Time `json:"last_used_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	Current       bool      `json:"current"`
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"strings"
+
+	"starttech-server/i18n"
+)
+
+// FieldError describes a single invalid input field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// FieldErrors are the invalid fields of an input.
+type FieldErrors []FieldError
+
+// Localize returns fs with their messages in lang; it makes fs an
+// apierror.Localizer.
+func (fs FieldErrors) Localize(lang string) any {
+	out := make(FieldErrors, len(fs))
+	for i, f := range fs {
+		out[i] = FieldError{Field: f.Field, Message: i18n.Translate(lang, f.Message)}
+	}
+	return out
+}
+
+// ValidationError collects every problem found in an input so clients can
+// report them all at once.
+type ValidationError struct {
+	Fields FieldErrors `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + " " + f.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Add records a problem with field.
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// Err returns e if any problems were recorded and nil otherwise.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
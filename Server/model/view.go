@@ -0,0 +1,112 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxViewNameLen bounds View.Name.
+const MaxViewNameLen = 100
+
+// Due windows of a ViewFilter, relative to when the view is run.
+const (
+	DueOverdue = "overdue" // due before now
+	DueToday   = "today"   // due today, in the user's timezone
+	DueWeek    = "week"    // due today or in the six days after
+)
+
+// View is a named task filter a user saves to run again later. Names are
+// unique per owner within an organization, ignoring case.
+type View struct {
+	ID        string     `json:"id"`
+	OrgID     string     `json:"org_id"`
+	OwnerID   string     `json:"owner_id"`
+	Name      string     `json:"name"`
+	Filter    ViewFilter `json:"filter"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// ViewFilter holds the query parameters of GET /tasks a view runs with.
+// Due is a window that moves with the current date; DueBefore and DueAfter
+// are fixed and narrow it further.
+type ViewFilter struct {
+	Status    Status     `json:"status,omitempty"`
+	ProjectID string     `json:"project_id,omitempty"`
+	Assignee  string     `json:"assignee,omitempty"`
+	TagIDs    []string   `json:"tag_ids,omitempty"`
+	Due       string     `json:"due,omitempty"`
+	DueBefore *time.Time `json:"due_before,omitempty"`
+	DueAfter  *time.Time `json:"due_after,omitempty"`
+	Sort      string     `json:"sort,omitempty"`
+}
+
+// Validate reports every field of v that breaks the API's rules. sortKeys
+// are the sort keys the store accepts.
+func (v *View) Validate(sortKeys []string) error {
+	var verr ValidationError
+	switch {
+	case v.Name == "":
+		verr.Add("name", "is required")
+	case utf8.RuneCountInString(v.Name) > MaxViewNameLen:
+		verr.Add("name", fmt.Sprintf("must be at most %d characters", MaxViewNameLen))
+	}
+	f := v.Filter
+	if f.Status != "" && !ValidStatusKey(f.Status) {
+		verr.Add("filter.status", "is not a valid status key")
+	}
+	switch f.Due {
+	case "", DueOverdue, DueToday, DueWeek:
+	default:
+		verr.Add("filter.due", "must be overdue, today or week")
+	}
+	if key := strings.TrimPrefix(f.Sort, "-"); f.Sort != "" && !slices.Contains(sortKeys, key) {
+		verr.Add("filter.sort", "must be one of "+strings.Join(sortKeys, ", "))
+	}
+	return verr.Err()
+}
+
+// ViewInput is the body accepted by POST /views.
+type ViewInput struct {
+	Name   string     `json:"name"`
+	Filter ViewFilter `json:"filter,omitempty"`
+}
+
+// Apply copies in onto v.
+func (in ViewInput) Apply(v *View) {
+	v.Name = strings.TrimSpace(in.Name)
+	v.Filter = in.Filter.normalize()
+}
+
+// ViewPatch is the body accepted by PATCH /views/{id}. Nil fields are left
+// unchanged; a filter replaces the whole of the old one.
+type ViewPatch struct {
+	Name   *string     `json:"name"`
+	Filter *ViewFilter `json:"filter"`
+}
+
+// Apply copies the set fields of p onto v.
+func (p ViewPatch) Apply(v *View) {
+	if p.Name != nil {
+		v.Name = strings.TrimSpace(*p.Name)
+	}
+	if p.Filter != nil {
+		v.Filter = p.Filter.normalize()
+	}
+}
+
+func (f ViewFilter) normalize() ViewFilter {
+	f.ProjectID = strings.TrimSpace(f.ProjectID)
+	f.Assignee = strings.TrimSpace(f.Assignee)
+	f.TagIDs = idSet(f.TagIDs)
+	if len(f.TagIDs) == 0 {
+		f.TagIDs = nil
+	}
+	f.DueBefore = utcPtr(f.DueBefore)
+	f.DueAfter = utcPtr(f.DueAfter)
+	f.Sort = strings.TrimSpace(f.Sort)
+	return f
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Watcher is a user who follows a task, whether or not they own it or are
+// assigned to it, and is notified of its changes and comments. Username is
+// filled in when watchers are listed.
+type Watcher struct {
+	TaskID    string    `json:"task_id"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,164 @@
+package model
+
+import (
+	"encoding/json"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Webhook is a URL that receives the owner's events in its organization as
+// signed JSON POSTs. An empty Events list subscribes to every event type.
+type Webhook struct {
+	ID      string   `json:"id"`
+	OrgID   string   `json:"org_id"`
+	OwnerID string   `json:"owner_id"`
+	URL     string   `json:"url"`
+	Events  []string `json:"events"`
+	Active  bool     `json:"active"`
+	// Secret signs the payloads. It is only included in the response that
+	// creates the webhook.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate reports every field of w that breaks the API's rules. Events must
+// be drawn from known. Unless allowPrivate is set, the URL may not name a
+// loopback, private or link-local address; names that resolve to one are
+// refused when a delivery connects.
+func (w *Webhook) Validate(known []string, allowPrivate bool) error {
+	var v ValidationError
+	u, err := url.Parse(w.URL)
+	switch {
+	case w.URL == "":
+		v.Add("url", "is required")
+	case err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "":
+		v.Add("url", "must be an absolute http or https URL")
+	case !allowPrivate && !publicHost(u.Hostname()):
+		v.Add("url", "must not point at a loopback, private or link-local address")
+	}
+	for _, e := range w.Events {
+		if !slices.Contains(known, e) {
+			v.Add("events", "unknown event type "+e)
+		}
+	}
+	return v.Err()
+}
+
+// publicHost reports whether host may be public: it is a public IP address,
+// or a name other than localhost.
+func publicHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	ip, err := netip.ParseAddr(host)
+	return err != nil || PublicAddr(ip)
+}
+
+// nonPublic lists the ranges beyond those the netip predicates cover that
+// are not reachable on the internet: "this network", carrier-grade NAT,
+// IETF protocol assignments, benchmarking, and IPv4 translated to IPv6.
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// PublicAddr reports whether ip is a global unicast address outside the
+// private, loopback, link-local and other non-public ranges, which is what
+// outgoing requests to user-given URLs may connect to.
+func PublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+		return false
+	}
+	for _, p := range nonPublic {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Subscribes reports whether w should receive events of type typ.
+func (w *Webhook) Subscribes(typ string) bool {
+	if !w.Active {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return true
+	}
+	return slices.Contains(w.Events, typ)
+}
+
+// WebhookInput is the body accepted by POST /webhooks. Active defaults to
+// true.
+type WebhookInput struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events,omitempty"`
+	Active *bool    `json:"active,omitempty"`
+}
+
+// Apply copies in onto w.
+func (in WebhookInput) Apply(w *Webhook) {
+	w.URL = strings.TrimSpace(in.URL)
+	w.Events = idSet(in.Events)
+	w.Active = in.Active == nil || *in.Active
+}
+
+// WebhookPatch is the body accepted by PATCH /webhooks/{id}. Nil fields are
+// left unchanged.
+type WebhookPatch struct {
+	URL    *string   `json:"url"`
+	Events *[]string `json:"events"`
+	Active *bool     `json:"active"`
+}
+
+// Apply copies the set fields of p onto w.
+func (p WebhookPatch) Apply(w *Webhook) {
+	if p.URL != nil {
+		w.URL = strings.TrimSpace(*p.URL)
+	}
+	if p.Events != nil {
+		w.Events = idSet(*p.Events)
+	}
+	if p.Active != nil {
+		w.Active = *p.Active
+	}
+}
+
+// DeliveryStatus is the state of a webhook delivery.
+type DeliveryStatus string
+
+const (
+	// DeliveryPending is waiting for its first or next attempt.
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliverySucceeded got a 2xx response.
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	// DeliveryFailed ran out of attempts. It can be replayed.
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+// Delivery is one event sent, or to be sent, to a webhook, with the outcome
+// of its latest attempt.
+type Delivery struct {
+	ID        string          `json:"id"`
+	WebhookID string          `json:"webhook_id"`
+	Event     string          `json:"event"`
+	Payload   json.RawMessage `json:"payload"`
+	Status    DeliveryStatus  `json:"status"`
+	Attempts  int             `json:"attempts"`
+	// ResponseStatus is the HTTP status of the latest attempt, or 0 if the
+	// request itself failed.
+	ResponseStatus int        `json:"response_status"`
+	LastError      string     `json:"last_error"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	CompletedAt    *time.Time `json:"completed_at"`
+}
@@ -0,0 +1,136 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Column is one status of a project's workflow, shown as a board column.
+// Tasks in a Done column count as completed.
+type Column struct {
+	Key  Status `json:"key"`
+	Name string `json:"name"`
+	Done bool   `json:"done"`
+}
+
+// Workflow is the ordered list of statuses a task may move through.
+type Workflow []Column
+
+// DefaultWorkflow applies to tasks outside a project and to projects that do
+// not define their own statuses.
+var DefaultWorkflow = Workflow{
+	{Key: StatusTodo, Name: "To do"},
+	{Key: StatusInProgress, Name: "In progress"},
+	{Key: StatusDone, Name: "Done", Done: true},
+}
+
+// Workflow limits enforced by Workflow.Validate.
+const (
+	MaxColumns       = 20
+	MaxColumnNameLen = 50
+)
+
+var statusKey = regexp.MustCompile(`^[a-z0-9_]{1,40}$`)
+
+// ValidStatusKey reports whether s is well-formed as a status key. It does
+// not check that any workflow defines it.
+func ValidStatusKey(s Status) bool {
+	return statusKey.MatchString(string(s))
+}
+
+// Validate checks w as the statuses field of a project. A workflow needs at
+// least one open and one done column.
+func (w Workflow) Validate(v *ValidationError) {
+	if len(w) > MaxColumns {
+		v.Add("statuses", fmt.Sprintf("must have at most %d columns", MaxColumns))
+		return
+	}
+	seen := make(map[Status]bool, len(w))
+	var open, done bool
+	for i, c := range w {
+		field := fmt.Sprintf("statuses[%d]", i)
+		switch {
+		case !ValidStatusKey(c.Key):
+			v.Add(field+".key", "must be 1-40 lowercase letters, digits or underscores")
+		case seen[c.Key]:
+			v.Add(field+".key", "is used by another column")
+		}
+		seen[c.Key] = true
+		if n := utf8.RuneCountInString(c.Name); n == 0 || n > MaxColumnNameLen {
+			v.Add(field+".name", fmt.Sprintf("must be 1-%d characters", MaxColumnNameLen))
+		}
+		if c.Done {
+			done = true
+		} else {
+			open = true
+		}
+	}
+	if !open || !done {
+		v.Add("statuses", "must include at least one open and one done column")
+	}
+}
+
+// Has reports whether w defines s.
+func (w Workflow) Has(s Status) bool {
+	for _, c := range w {
+		if c.Key == s {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDone reports whether s is a done column of w.
+func (w Workflow) IsDone(s Status) bool {
+	for _, c := range w {
+		if c.Key == s {
+			return c.Done
+		}
+	}
+	return false
+}
+
+// Keys returns the status keys of w in order.
+func (w Workflow) Keys() []Status {
+	keys := make([]Status, len(w))
+	for i, c := range w {
+		keys[i] = c.Key
+	}
+	return keys
+}
+
+// Initial returns the first column whose Done flag equals done.
+func (w Workflow) Initial(done bool) Status {
+	for _, c := range w {
+		if c.Done == done {
+			return c.Key
+		}
+	}
+	return w[0].Key
+}
+
+// Conform fits t's status to w after an edit and derives Completed from it.
+// The built-in todo and done statuses stand for the first open and first
+// done column of workflows that do not define them, so the completed flag
+// keeps working on custom boards.
+func (w Workflow) Conform(t *Task) {
+	if !w.Has(t.Status) {
+		switch t.Status {
+		case StatusTodo:
+			t.Status = w.Initial(false)
+		case StatusDone:
+			t.Status = w.Initial(true)
+		}
+	}
+	t.Completed = w.IsDone(t.Status)
+}
+
+func (w Workflow) describe() string {
+	keys := make([]string, len(w))
+	for i, c := range w {
+		keys[i] = string(c.Key)
+	}
+	return strings.Join(keys, ", ")
+}
@@ -0,0 +1,281 @@
+// Package nats is a small client for the NATS protocol, enough to publish
+// and subscribe to subjects without pulling in a third-party driver.
+package nats
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultTimeout bounds a publish whose context has no deadline.
+const defaultTimeout = 5 * time.Second
+
+// Error is an -ERR reply from the server, such as a failed authorization.
+type Error string
+
+func (e Error) Error() string { return "nats: " + string(e) }
+
+// Client publishes and subscribes through one NATS server. It is safe for
+// concurrent use.
+type Client struct {
+	addr     string
+	tls      bool
+	user     string
+	password string
+	token    string
+
+	mu   sync.Mutex
+	idle *conn
+}
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// New returns a client for rawURL, of the form
+// nats://[user:password@|token@]host[:port]. The tls scheme connects over
+// TLS. No connection is made until the first publish or subscription.
+func New(rawURL string) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("nats: %w", err)
+	}
+	if u.Scheme != "nats" && u.Scheme != "tls" {
+		return nil, fmt.Errorf("nats: unsupported scheme in %q", rawURL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("nats: no host in %q", rawURL)
+	}
+	c := &Client{addr: u.Host, tls: u.Scheme == "tls"}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			c.user, c.password = u.User.Username(), password
+		} else {
+			c.token = u.User.Username()
+		}
+	}
+	return c, nil
+}
+
+// Publish sends payload to subject and waits for the server to take it.
+func (c *Client) Publish(ctx context.Context, subject string, payload []byte) error {
+	msg := make([]byte, 0, len(subject)+len(payload)+32)
+	msg = fmt.Appendf(msg, "PUB %s %d\r\n", subject, len(payload))
+	msg = append(msg, payload...)
+	msg = append(msg, "\r\nPING\r\n"...)
+
+	c.mu.Lock()
+	cn := c.idle
+	c.idle = nil
+	c.mu.Unlock()
+	// An idle connection may have been dropped by the server, since nobody
+	// answered its pings; it gets one retry on a new connection.
+	for reused := cn != nil; ; reused = false {
+		if cn == nil {
+			var err error
+			if cn, err = c.dial(ctx); err != nil {
+				return err
+			}
+		}
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			deadline = time.Now().Add(defaultTimeout)
+		}
+		cn.SetDeadline(deadline)
+		err := cn.write(msg)
+		if err == nil {
+			// The PONG answering our PING follows any error about the
+			// PUB.
+			err = cn.waitPong()
+		}
+		if err == nil {
+			break
+		}
+		cn.Close()
+		cn = nil
+		var nerr Error
+		if !reused || errors.As(err, &nerr) {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.idle != nil {
+		cn.Close()
+		return nil
+	}
+	c.idle = cn
+	return nil
+}
+
+// Subscribe listens to subject on a connection of its own, calling fn with
+// the payload of each message published to it, until ctx is done or the
+// connection fails. It returns ctx's error in the first case.
+func (c *Client) Subscribe(ctx context.Context, subject string, fn func(payload []byte)) error {
+	cn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer cn.Close()
+	if err := cn.write([]byte("SUB " + subject + " 1\r\nPING\r\n")); err != nil {
+		return err
+	}
+	if err := cn.waitPong(); err != nil {
+		return err
+	}
+
+	// The server pings idle connections, which keeps this one checked.
+	cn.SetDeadline(time.Time{})
+	stop := context.AfterFunc(ctx, func() { cn.Close() })
+	defer stop()
+	for {
+		line, err := cn.line()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+		switch op, args, _ := strings.Cut(line, " "); strings.ToUpper(op) {
+		case "MSG":
+			// MSG <subject> <sid> [reply-to] <size>
+			fields := strings.Fields(args)
+			if len(fields) < 3 {
+				return fmt.Errorf("nats: malformed message %q", line)
+			}
+			n, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil || n < 0 {
+				return fmt.Errorf("nats: malformed message %q", line)
+			}
+			buf := make([]byte, n+2)
+			if _, err := io.ReadFull(cn.r, buf); err != nil {
+				return fmt.Errorf("nats: %w", err)
+			}
+			fn(buf[:n])
+		case "PING":
+			if err := cn.write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case "-ERR":
+			return Error(strings.Trim(args, "' "))
+		}
+	}
+}
+
+// Close closes the idle publishing connection.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.idle != nil {
+		c.idle.Close()
+		c.idle = nil
+	}
+	return nil
+}
+
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	nc, err := (&net.Dialer{}).DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("nats: %w", err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultTimeout)
+	}
+	nc.SetDeadline(deadline)
+	cn := &conn{Conn: nc, r: bufio.NewReader(nc)}
+
+	// The server speaks first, with an INFO line saying whether it wants
+	// TLS.
+	line, err := cn.line()
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	op, info, _ := strings.Cut(line, " ")
+	if !strings.EqualFold(op, "INFO") {
+		nc.Close()
+		return nil, fmt.Errorf("nats: unexpected greeting %q", line)
+	}
+	var server struct {
+		TLSRequired bool `json:"tls_required"`
+	}
+	json.Unmarshal([]byte(info), &server)
+	if c.tls || server.TLSRequired {
+		host, _, _ := net.SplitHostPort(c.addr)
+		tc := tls.Client(nc, &tls.Config{ServerName: host})
+		if err := tc.HandshakeContext(ctx); err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("nats: %w", err)
+		}
+		cn = &conn{Conn: tc, r: bufio.NewReader(tc)}
+	}
+
+	connect, _ := json.Marshal(map[string]any{
+		"verbose": false, "pedantic": false, "lang": "go", "version": "1", "protocol": 0,
+		"user": c.user, "pass": c.password, "auth_token": c.token,
+	})
+	if err := cn.write([]byte("CONNECT " + string(connect) + "\r\nPING\r\n")); err != nil {
+		cn.Close()
+		return nil, err
+	}
+	if err := cn.waitPong(); err != nil {
+		cn.Close()
+		return nil, err
+	}
+	return cn, nil
+}
+
+func (cn *conn) write(b []byte) error {
+	if _, err := cn.Write(b); err != nil {
+		return fmt.Errorf("nats: %w", err)
+	}
+	return nil
+}
+
+// line reads one control line without its CRLF.
+func (cn *conn) line() (string, error) {
+	line, err := cn.r.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("nats: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// waitPong reads until the server's PONG, answering its pings and
+// returning the first error it reports.
+func (cn *conn) waitPong() error {
+	for {
+		line, err := cn.line()
+		if err != nil {
+			return err
+		}
+		op, args, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(op) {
+		case "PONG":
+			return nil
+		case "PING":
+			if err := cn.write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case "-ERR":
+			return Error(strings.Trim(args, "' "))
+		}
+	}
+}
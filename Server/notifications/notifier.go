@@ -0,0 +1,161 @@
+package notifications
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/i18n"
+	"starttech-server/metrics"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+const (
+	// queueSize is the number of events buffered for the Run goroutine
+	// before new ones are dropped.
+	queueSize = 256
+	// sendTimeout bounds a single delivery.
+	sendTimeout = 30 * time.Second
+)
+
+var (
+	emailsSent   = metrics.NewCounterVec("emails_sent_total", "Notification emails handed to the mail transport, by kind.", "kind")
+	emailsFailed = metrics.NewCounterVec("emails_failed_total", "Notification emails the mail transport refused, by kind.", "kind")
+)
+
+// Notifier turns events into emails for the users who asked for them. It
+// implements events.Publisher; Publish only queues, and Run sends.
+type Notifier struct {
+	users  storage.UserStore
+	prefs  storage.NotificationStore
+	sender Sender
+	queue  chan events.Event
+}
+
+// NewNotifier returns a Notifier; call Run to start delivering.
+func NewNotifier(users storage.UserStore, prefs storage.NotificationStore, sender Sender) *Notifier {
+	return &Notifier{
+		users:  users,
+		prefs:  prefs,
+		sender: sender,
+		queue:  make(chan events.Event, queueSize),
+	}
+}
+
+// Publish queues e if it is one of the events users are emailed about.
+func (n *Notifier) Publish(e events.Event) {
+	if kindOf(e.Type) == "" {
+		return
+	}
+	select {
+	case n.queue <- e:
+	default:
+		slog.Warn("notifications: dropping event, queue is full", "type", e.Type)
+	}
+}
+
+// Run sends queued notifications until ctx is cancelled.
+func (n *Notifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-n.queue:
+			for _, userID := range e.Recipients {
+				n.notify(ctx, userID, e)
+			}
+		}
+	}
+}
+
+func (n *Notifier) notify(ctx context.Context, userID string, e events.Event) {
+	kind := kindOf(e.Type)
+	prefs, err := n.prefs.GetNotificationPrefs(ctx, userID)
+	if err != nil {
+		slog.Error("notifications: reading preferences", "user_id", userID, "err", err)
+		return
+	}
+	if !prefs.Wants(kind) {
+		return
+	}
+	u, err := n.users.GetUser(ctx, userID)
+	if err != nil {
+		slog.Error("notifications: looking up recipient", "user_id", userID, "err", err)
+		return
+	}
+	m, ok := message(e, u.Location(), u.Language)
+	if !ok {
+		return
+	}
+	m.To = u.Email
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	if err := n.sender.Send(ctx, m); err != nil {
+		emailsFailed.With(string(kind)).Inc()
+		slog.Error("notifications: sending email", "user_id", userID, "kind", kind, "err", err)
+		return
+	}
+	emailsSent.With(string(kind)).Inc()
+}
+
+// kindOf maps an event type to the preference that governs it, or "" if
+// users are not emailed about it.
+func kindOf(t events.Type) model.NotificationKind {
+	switch t {
+	case events.TaskAssigned:
+		return model.NotifyAssigned
+	case events.TaskReminder, events.TaskDue:
+		return model.NotifyDueSoon
+	case events.TaskMentioned:
+		return model.NotifyMentioned
+	}
+	return ""
+}
+
+// message writes the subject and body for e in lang, with times shown in
+// loc. It reports false if the event does not carry a task.
+func message(e events.Event, loc *time.Location, lang string) (Message, bool) {
+	var t model.Task
+	switch d := e.Data.(type) {
+	case model.Task:
+		t = d
+	case events.Reminder:
+		t = d.Task
+	default:
+		return Message{}, false
+	}
+
+	if lang == "" {
+		lang = i18n.Default
+	}
+	m := Message{Language: lang}
+	switch e.Type {
+	case events.TaskAssigned:
+		m.Subject = i18n.T(lang, "Assigned to you: {0}", t.Title)
+	case events.TaskMentioned:
+		m.Subject = i18n.T(lang, "You were mentioned on {0}", t.Title)
+	case events.TaskDue:
+		m.Subject = i18n.T(lang, "Due now: {0}", t.Title)
+	default:
+		m.Subject = i18n.T(lang, "Reminder: {0}", t.Title)
+	}
+
+	var b strings.Builder
+	b.WriteString(t.Title + "\n")
+	if t.Description != "" {
+		b.WriteString("\n" + t.Description + "\n")
+	}
+	b.WriteString("\n")
+	if t.DueDate != nil {
+		due := t.DueDate.In(loc).Format(i18n.T(lang, "Mon 2 Jan 2006 15:04 MST"))
+		b.WriteString(i18n.T(lang, "Due: {0}", due) + "\n")
+	}
+	b.WriteString(i18n.T(lang, "Status: {0}", t.Status) + "\n")
+	b.WriteString("\n" + i18n.T(lang, "You can change which emails you receive at /me/notifications.") + "\n")
+	m.Body = b.String()
+	return m, true
+}
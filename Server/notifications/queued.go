@@ -0,0 +1,36 @@
+package notifications
+
+import (
+	"context"
+
+	"starttech-server/jobs"
+	"starttech-server/model"
+)
+
+// KindSend is the kind of the jobs that send queued messages.
+const KindSend = "email.send"
+
+// Queued is a Sender that queues each message as a job, so the request or
+// event that sends it does not wait on the mail server, and a message the
+// server refuses is retried. Run SendJob on the queue to do the sending.
+type Queued struct {
+	Jobs *jobs.Queue
+}
+
+func (s Queued) Send(ctx context.Context, m Message) error {
+	return s.Jobs.Enqueue(ctx, KindSend, m)
+}
+
+// SendJob returns the handler of KindSend jobs, which sends each message
+// with sender.
+func SendJob(sender Sender) jobs.Handler {
+	return func(ctx context.Context, j model.Job) error {
+		var m Message
+		if err := jobs.Decode(j, &m); err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+		defer cancel()
+		return sender.Send(ctx, m)
+	}
+}
@@ -0,0 +1,41 @@
+// Package notifications emails users about events that concern them:
+// assignments, reminders and mentions. Delivery goes through a Sender, so
+// the SMTP transport can be swapped for a provider's API or, in
+// development, for the log.
+package notifications
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Message is a plain-text email to a single recipient. Language names the
+// language it is written in, if known.
+type Message struct {
+	To       string `json:"to"`
+	Subject  string `json:"subject"`
+	Body     string `json:"body"`
+	Language string `json:"language,omitempty"`
+}
+
+// Sender delivers messages.
+type Sender interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// LogSender writes messages to the log instead of sending them. It is used
+// when no mail server is configured. Bodies, which may hold tokens, are only
+// logged at debug level.
+type LogSender struct {
+	Logger *slog.Logger
+}
+
+func (s LogSender) Send(ctx context.Context, m Message) error {
+	logger := s.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.InfoContext(ctx, "email not sent, no mail server configured", "to", m.To, "subject", m.Subject)
+	logger.DebugContext(ctx, "unsent email body", "to", m.To, "body", m.Body)
+	return nil
+}
@@ -0,0 +1,216 @@
+package oauth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"starttech-server/model"
+)
+
+// OIDC describes an OpenID Connect provider and the client registered with
+// it. GroupsClaim names the claim that lists a user's groups. A nil Client
+// uses one that only connects to public addresses, does not follow
+// redirects or use a proxy, and times out after ten seconds.
+type OIDC struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	GroupsClaim  string
+	Client       *http.Client
+}
+
+// Discover returns the provider c describes, reading its endpoints from
+// the discovery document published under its issuer. The issuer and the
+// token and userinfo endpoints must be https URLs.
+func Discover(ctx context.Context, c OIDC) (*Provider, error) {
+	if !isHTTPS(c.Issuer) {
+		return nil, fmt.Errorf("oauth: %s issuer %q is not an https URL", c.Name, c.Issuer)
+	}
+	p := &Provider{Name: c.Name, Client: c.Client}
+	if p.Client == nil {
+		p.Client = publicClient
+	}
+	var doc struct {
+		Issuer           string `json:"issuer"`
+		AuthEndpoint     string `json:"authorization_endpoint"`
+		TokenEndpoint    string `json:"token_endpoint"`
+		UserInfoEndpoint string `json:"userinfo_endpoint"`
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	status, err := p.do(req, &doc)
+	switch {
+	case err != nil:
+		return nil, err
+	case status != http.StatusOK:
+		return nil, fmt.Errorf("oauth: %s discovery document answered %d", c.Name, status)
+	case strings.TrimSuffix(doc.Issuer, "/") != c.Issuer:
+		return nil, fmt.Errorf("oauth: %s discovery document is for issuer %q", c.Name, doc.Issuer)
+	case doc.AuthEndpoint == "" || doc.TokenEndpoint == "":
+		return nil, fmt.Errorf("oauth: %s discovery document has no authorization or token endpoint", c.Name)
+	case !isHTTPS(doc.TokenEndpoint) || doc.UserInfoEndpoint != "" && !isHTTPS(doc.UserInfoEndpoint):
+		return nil, fmt.Errorf("oauth: %s discovery document has a token or userinfo endpoint that is not https", c.Name)
+	}
+	p.AuthURL, p.TokenURL = doc.AuthEndpoint, doc.TokenEndpoint
+	p.Scopes = []string{"openid", "email", "profile"}
+	p.ClientID, p.ClientSecret = c.ClientID, c.ClientSecret
+	p.identify = func(ctx context.Context, p *Provider, token Token) (Identity, error) {
+		return identifyOIDC(ctx, p, c, doc.Issuer, doc.UserInfoEndpoint, token)
+	}
+	return p, nil
+}
+
+// isHTTPS reports whether rawURL is an absolute https URL.
+func isHTTPS(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && u.Scheme == "https" && u.Host != ""
+}
+
+// errPrivateAddress is returned for a call to an address that is not
+// public.
+var errPrivateAddress = errors.New("oauth: address is not public")
+
+// publicClient is the default client of OpenID Connect providers, whose
+// issuers organization admins choose. Like the webhook dispatcher's, it
+// checks the address once resolved, as the connection is made, so that an
+// issuer cannot reach the server's own network by resolving to a private
+// address or redirecting to one.
+var publicClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: 10 * time.Second, Control: checkAddress}).DialContext,
+		ForceAttemptHTTP2:   true,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConnsPerHost: 2,
+		IdleConnTimeout:     90 * time.Second,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// checkAddress refuses a connection to a loopback, private or link-local
+// address.
+func checkAddress(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil || !model.PublicAddr(ap.Addr()) {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+// identifyOIDC reads the user from the ID token, falling back to the
+// userinfo endpoint for an email address or groups it leaves out. The ID
+// token's signature is not checked: it came straight from the token
+// endpoint over TLS, which Discover insists on, and OpenID Connect Core
+// 3.1.3.7 allows that in place of one. Its issuer, audience and expiry are.
+func identifyOIDC(ctx context.Context, p *Provider, c OIDC, issuer, userInfo string, token Token) (Identity, error) {
+	payload, err := idTokenPayload(token.IDToken)
+	if err != nil {
+		return Identity{}, fmt.Errorf("oauth: %s: %w", p.Name, err)
+	}
+	var (
+		std struct {
+			Issuer   string          `json:"iss"`
+			Audience json.RawMessage `json:"aud"`
+			Expiry   int64           `json:"exp"`
+		}
+		claims map[string]json.RawMessage
+	)
+	if err := json.Unmarshal(payload, &std); err != nil {
+		return Identity{}, fmt.Errorf("oauth: %s: decoding ID token: %w", p.Name, err)
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return Identity{}, fmt.Errorf("oauth: %s: decoding ID token: %w", p.Name, err)
+	}
+	switch {
+	case std.Issuer != issuer:
+		return Identity{}, fmt.Errorf("oauth: %s: ID token issued by %q", p.Name, std.Issuer)
+	case !slices.Contains(stringList(std.Audience), c.ClientID):
+		return Identity{}, fmt.Errorf("oauth: %s: ID token is not for this client", p.Name)
+	case time.Now().Unix() >= std.Expiry:
+		return Identity{}, fmt.Errorf("oauth: %s: ID token has expired", p.Name)
+	}
+
+	groupsClaim := c.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	if (claims["email"] == nil || claims[groupsClaim] == nil) && userInfo != "" {
+		var info map[string]json.RawMessage
+		if err := p.get(ctx, userInfo, token.AccessToken, &info); err != nil {
+			return Identity{}, err
+		}
+		for k, v := range info {
+			if claims[k] == nil && k != "sub" {
+				claims[k] = v
+			}
+		}
+	}
+
+	var id Identity
+	decodeClaim(claims, "sub", &id.Subject)
+	decodeClaim(claims, "email", &id.Email)
+	var verified bool
+	decodeClaim(claims, "email_verified", &verified)
+	// An address the provider does not say it verified may be one anybody
+	// typed into their profile there, so it is not trusted.
+	if id.Subject == "" || id.Email == "" || !verified {
+		return Identity{}, ErrNoEmail
+	}
+	decodeClaim(claims, "preferred_username", &id.Login)
+	if id.Login == "" || strings.Contains(id.Login, "@") {
+		id.Login, _, _ = strings.Cut(id.Email, "@")
+	}
+	id.Groups = stringList(claims[groupsClaim])
+	return id, nil
+}
+
+// idTokenPayload returns the JSON payload of a JWT.
+func idTokenPayload(idToken string) ([]byte, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("no ID token was issued")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("decoding ID token: %w", err)
+	}
+	return payload, nil
+}
+
+// decodeClaim decodes the claim named name into v, leaving v alone if the
+// claim is missing or of another type.
+func decodeClaim(claims map[string]json.RawMessage, name string, v any) {
+	if raw, ok := claims[name]; ok {
+		json.Unmarshal(raw, v)
+	}
+}
+
+// stringList decodes a claim that is either a string or a list of them,
+// as aud and, with some providers, groups are.
+func stringList(raw json.RawMessage) []string {
+	var list []string
+	if json.Unmarshal(raw, &list) == nil {
+		return list
+	}
+	var one string
+	if json.Unmarshal(raw, &one) == nil && one != "" {
+		return []string{one}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package oauth
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// Google returns the Google provider for a client registered in the
+// Google Cloud console.
+func Google(clientID, clientSecret string) *Provider {
+	return &Provider{
+		Name:         "google",
+		AuthURL:      "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL:     "https://oauth2.googleapis.com/token",
+		Scopes:       []string{"openid", "email", "profile"},
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		identify:     identifyGoogle,
+	}
+}
+
+const googleUserInfo = "https://openidconnect.googleapis.com/v1/userinfo"
+
+func identifyGoogle(ctx context.Context, p *Provider, token Token) (Identity, error) {
+	var info struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		GivenName     string `json:"given_name"`
+	}
+	if err := p.get(ctx, googleUserInfo, token.AccessToken, &info); err != nil {
+		return Identity{}, err
+	}
+	if info.Email == "" || !info.EmailVerified {
+		return Identity{}, ErrNoEmail
+	}
+	login := info.GivenName
+	if login == "" {
+		login, _, _ = strings.Cut(info.Email, "@")
+	}
+	return Identity{Subject: info.Sub, Email: info.Email, Login: login}, nil
+}
+
+// GitHub returns the GitHub provider for an OAuth app registered under
+// Developer settings.
+func GitHub(clientID, clientSecret string) *Provider {
+	return &Provider{
+		Name:         "github",
+		AuthURL:      "https://github.com/login/oauth/authorize",
+		TokenURL:     "https://github.com/login/oauth/access_token",
+		Scopes:       []string{"read:user", "user:email"},
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		identify:     identifyGitHub,
+	}
+}
+
+const githubAPI = "https://api.github.com"
+
+// identifyGitHub takes the primary address from the user's email list,
+// since the profile only shows an address the user has made public.
+func identifyGitHub(ctx context.Context, p *Provider, token Token) (Identity, error) {
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+	}
+	if err := p.get(ctx, githubAPI+"/user", token.AccessToken, &user); err != nil {
+		return Identity{}, err
+	}
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.get(ctx, githubAPI+"/user/emails", token.AccessToken, &emails); err != nil {
+		return Identity{}, err
+	}
+	for _, e := range emails {
+		if e.Primary && e.Verified {
+			return Identity{Subject: strconv.FormatInt(user.ID, 10), Email: e.Email, Login: user.Login}, nil
+		}
+	}
+	return Identity{}, ErrNoEmail
+}
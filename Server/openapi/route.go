@@ -0,0 +1,166 @@
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"starttech-server/apierror"
+)
+
+// Route describes one operation of the API.
+type Route struct {
+	Method  string
+	Path    string
+	Tag     string
+	Summary string
+	// Public routes do not require a bearer token.
+	Public bool
+	Query  []Parameter
+	// Request is a value of the request body type, or nil.
+	Request any
+	// Streamed routes read big bodies as they go, so Validate leaves their
+	// bodies to the handler to check.
+	Streamed bool
+	// Upload names the multipart/form-data field that carries a file, for
+	// routes that take one instead of a JSON body.
+	Upload string
+	// Status is the success status code; it defaults to 200.
+	Status int
+	// Response is a value of the success body type, or nil for no body.
+	Response any
+	// Versioned routes answer with the resource's ETag; PUT and PATCH
+	// accept If-Match and fail with 412 when it no longer matches.
+	Versioned bool
+	// Cached routes answer with a weak ETag of the body, and with 304 when
+	// If-None-Match names it.
+	Cached bool
+}
+
+// ErrorResponse mirrors the body the handlers write on failure.
+type ErrorResponse struct {
+	Error apierror.Error `json:"error"`
+}
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+func (r Route) operation(g *generator) *Operation {
+	op := &Operation{
+		Summary:     r.Summary,
+		OperationID: operationID(r.Method, r.Path),
+		Responses:   map[string]Response{},
+	}
+	if r.Tag != "" {
+		op.Tags = []string{r.Tag}
+	}
+	if !r.Public {
+		op.Security = []map[string][]string{{"bearerAuth": {}}}
+	}
+
+	params := pathParam.FindAllStringSubmatch(r.Path, -1)
+	for _, m := range params {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"},
+		})
+	}
+	op.Parameters = append(op.Parameters, r.Query...)
+	idempotent := !r.Public && r.Method == http.MethodPost
+	if idempotent {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: "Idempotency-Key", In: "header", Description: "unique per request; a retry with the same key gets the first response",
+			Schema: &Schema{Type: "string"},
+		})
+	}
+	conditional := r.Versioned && (r.Method == http.MethodPut || r.Method == http.MethodPatch)
+	if conditional {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: "If-Match", In: "header", Description: "ETag the change is based on", Schema: &Schema{Type: "string"},
+		})
+	}
+
+	if r.Cached {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: "If-None-Match", In: "header", Description: "ETag of the copy you have; 304 if it is still current", Schema: &Schema{Type: "string"},
+		})
+	}
+
+	if r.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: g.schemaOf(r.Request)}},
+		}
+	}
+	if r.Upload != "" {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{"multipart/form-data": {Schema: &Schema{
+				Type:       "object",
+				Properties: map[string]*Schema{r.Upload: {Type: "string", Format: "binary"}},
+				Required:   []string{r.Upload},
+			}}},
+		}
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	ok := Response{Description: http.StatusText(status)}
+	if r.Response != nil {
+		ok.Content = map[string]MediaType{"application/json": {Schema: g.schemaOf(r.Response)}}
+	}
+	if r.Versioned {
+		ok.Headers = map[string]Header{"ETag": {Description: "version of the resource", Schema: &Schema{Type: "string"}}}
+	}
+	if r.Cached {
+		ok.Headers = map[string]Header{"ETag": {Description: "weak tag of the body", Schema: &Schema{Type: "string"}}}
+		op.Responses["304"] = Response{Description: http.StatusText(http.StatusNotModified)}
+	}
+	op.Responses[strconv.Itoa(status)] = ok
+
+	errResp := func(code int) {
+		op.Responses[strconv.Itoa(code)] = Response{
+			Description: http.StatusText(code),
+			Content:     map[string]MediaType{"application/json": {Schema: g.schemaOf(ErrorResponse{})}},
+		}
+	}
+	if r.Request != nil || r.Upload != "" || len(r.Query) > 0 {
+		errResp(http.StatusBadRequest)
+	}
+	if !r.Public {
+		errResp(http.StatusUnauthorized)
+	}
+	if len(params) > 0 {
+		errResp(http.StatusNotFound)
+	}
+	if idempotent {
+		errResp(http.StatusConflict)
+		errResp(http.StatusUnprocessableEntity)
+	}
+	if conditional {
+		errResp(http.StatusPreconditionFailed)
+	}
+	errResp(http.StatusTooManyRequests)
+	limited := op.Responses["429"]
+	limited.Headers = map[string]Header{"Retry-After": {Description: "seconds until the request may be retried", Schema: &Schema{Type: "integer"}}}
+	op.Responses["429"] = limited
+	return op
+}
+
+// operationID derives a camel-case identifier such as getTasksId.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == '{' || r == '}' || r == '-' || r == '_' || r == '.'
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// QueryParam returns an optional query parameter of the given schema type.
+func QueryParam(name, typ, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: typ}}
+}
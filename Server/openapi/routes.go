@@ -0,0 +1,584 @@
+package openapi
+
+import (
+	"net/http"
+
+	"starttech-server/graphql"
+	"starttech-server/model"
+)
+
+// Routes describes every endpoint served by the API. Keep it in step with
+// the Register methods in package handlers.
+func Routes() []Route {
+	return []Route{
+		{Method: "GET", Path: "/health", Tag: "system", Summary: "Report that the server is up", Public: true, Response: model.Health{}},
+		{Method: "GET", Path: "/healthz", Tag: "system", Summary: "Liveness probe", Public: true, Response: model.Health{}},
+		{Method: "GET", Path: "/readyz", Tag: "system", Summary: "Readiness probe, with the status of each dependency and worker", Public: true, Response: model.Health{}},
+		{Method: "GET", Path: "/openapi.json", Tag: "system", Summary: "This OpenAPI document", Public: true},
+		{Method: "GET", Path: "/docs", Tag: "system", Summary: "Interactive API documentation", Public: true},
+		{Method: "GET", Path: "/metrics", Tag: "system", Summary: "Prometheus metrics", Public: true},
+
+		{Method: "POST", Path: "/auth/register", Tag: "auth", Summary: "Create an account and email a token to verify its address", Public: true,
+			Request: model.RegisterInput{}, Status: http.StatusCreated, Response: model.User{}},
+		{Method: "POST", Path: "/auth/verify-email", Tag: "auth", Summary: "Verify your email address and sign in", Public: true,
+			Request: model.TokenInput{}, Response: model.Session{}},
+		{Method: "POST", Path: "/auth/resend-verification", Tag: "auth", Summary: "Email another verification token", Public: true,
+			Request: model.EmailInput{}, Status: http.StatusAccepted},
+		{Method: "POST", Path: "/auth/forgot-password", Tag: "auth", Summary: "Email a token to reset your password with", Public: true,
+			Request: model.EmailInput{}, Status: http.StatusAccepted},
+		{Method: "POST", Path: "/auth/reset-password", Tag: "auth", Summary: "Choose a new password, signing out everywhere", Public: true,
+			Request: model.ResetPasswordInput{}, Status: http.StatusNoContent},
+		{Method: "POST", Path: "/auth/login", Tag: "auth", Summary: "Sign in; with two-factor authentication on, answers 401 two_factor_required with a challenge", Public: true,
+			Request: model.LoginInput{}, Response: model.Session{}},
+		{Method: "POST", Path: "/auth/2fa/verify", Tag: "auth", Summary: "Finish signing in with a code from your authenticator app or a recovery code", Public: true,
+			Request: model.TwoFactorLoginInput{}, Response: model.Session{}},
+		{Method: "GET", Path: "/auth/oauth/{provider}/start", Tag: "auth", Summary: "Sign in with google or github: redirects to the provider", Public: true,
+			Status: http.StatusFound},
+		{Method: "GET", Path: "/auth/oauth/{provider}/callback", Tag: "auth", Summary: "Finish signing in with a provider, which redirects here",
+			Public: true, Query: []Parameter{QueryParam("code", "string", "authorization code from the provider"),
+				QueryParam("state", "string", "state given to the provider at the start")},
+			Response: model.Session{}},
+		{Method: "GET", Path: "/auth/sso", Tag: "auth", Summary: "Whether an email address signs in through its organization's identity provider",
+			Public: true, Query: []Parameter{QueryParam("email", "string", "the address typed into the sign-in form")}, Response: model.SSOLookup{}},
+		{Method: "GET", Path: "/auth/sso/start", Tag: "auth", Summary: "Sign in through the identity provider of the organization with the address's domain: redirects there",
+			Public: true, Query: []Parameter{QueryParam("email", "string", "the address to sign in"),
+				QueryParam("link", "string", "link ticket from a start_url that links the identity to your account")},
+			Status: http.StatusFound},
+		{Method: "GET", Path: "/auth/sso/callback", Tag: "auth", Summary: "Finish signing in through an identity provider, which redirects here",
+			Public: true, Query: []Parameter{QueryParam("code", "string", "authorization code from the provider"),
+				QueryParam("state", "string", "state given to the provider at the start")},
+			Response: model.Session{}},
+		{Method: "POST", Path: "/auth/refresh", Tag: "auth", Summary: "Renew a session; the refresh token may come from the refresh_token cookie",
+			Public: true, Request: model.RefreshInput{}, Response: model.Session{}},
+		{Method: "POST", Path: "/auth/sso/link", Tag: "auth", Summary: "Start linking your account to your organization's identity provider",
+			Response: model.SSOLookup{}},
+		{Method: "POST", Path: "/auth/switch", Tag: "auth", Summary: "Get a session token for another of your organizations",
+			Request: model.SwitchInput{}, Response: model.Session{}},
+		{Method: "POST", Path: "/auth/logout", Tag: "auth", Summary: "End your current session", Status: http.StatusNoContent},
+		{Method: "GET", Path: "/auth/2fa", Tag: "auth", Summary: "Whether you have two-factor authentication on", Response: model.TwoFactorStatus{}},
+		{Method: "POST", Path: "/auth/2fa/setup", Tag: "auth", Summary: "Get a secret, and its otpauth:// URI to show as a QR code, for an authenticator app",
+			Response: model.TwoFactorSetup{}},
+		{Method: "POST", Path: "/auth/2fa/enable", Tag: "auth", Summary: "Turn on two-factor authentication with a code from the app; returns recovery codes",
+			Request: model.TwoFactorCodeInput{}, Response: model.RecoveryCodes{}},
+		{Method: "POST", Path: "/auth/2fa/disable", Tag: "auth", Summary: "Turn off two-factor authentication, given a code",
+			Request: model.TwoFactorCodeInput{}, Status: http.StatusNoContent},
+		{Method: "POST", Path: "/auth/2fa/recovery-codes", Tag: "auth", Summary: "Replace your recovery codes, given a code",
+			Request: model.TwoFactorCodeInput{}, Response: model.RecoveryCodes{}},
+		{Method: "GET", Path: "/me", Tag: "auth", Summary: "Your account", Response: model.User{}},
+		{Method: "PATCH", Path: "/me", Tag: "auth", Summary: "Change your timezone or language",
+			Request: model.ProfilePatch{}, Response: model.User{}},
+		{Method: "GET", Path: "/me/flags", Tag: "auth", Summary: "Whether each feature flag is on for you", Response: map[string]bool{}},
+		{Method: "GET", Path: "/me/sessions", Tag: "auth", Summary: "Your active sessions", Response: []model.AuthSession{}},
+		{Method: "DELETE", Path: "/me/sessions", Tag: "auth", Summary: "Sign out everywhere",
+			Query:  []Parameter{QueryParam("others", "boolean", "keep the current session")},
+			Status: http.StatusNoContent},
+		{Method: "DELETE", Path: "/me/sessions/{id}", Tag: "auth", Summary: "End one of your sessions", Status: http.StatusNoContent},
+		{Method: "GET", Path: "/users/me", Tag: "auth", Summary: "Your account, as GET /me", Response: model.User{}},
+		{Method: "PATCH", Path: "/users/me", Tag: "auth", Summary: "Change your timezone or language, as PATCH /me",
+			Request: model.ProfilePatch{}, Response: model.User{}},
+		{Method: "DELETE", Path: "/users/me", Tag: "auth", Summary: "Delete your account once its grace period is over, signing out your other sessions",
+			Request: model.DeleteAccountInput{}, Status: http.StatusAccepted, Response: model.AccountDeletion{}},
+		{Method: "POST", Path: "/users/me/restore", Tag: "auth", Summary: "Keep your account, whose deletion you asked for", Response: model.User{}},
+		{Method: "GET", Path: "/users/me/export", Tag: "auth", Summary: "Download your data as a zip archive of data.json and your attachments",
+			Query: []Parameter{QueryParam("format", "string", "zip (the default), or json for data.json alone")}},
+		{Method: "GET", Path: "/settings/api-keys", Tag: "auth", Summary: "Your API keys in the organization, flagging unused ones", Response: []model.APIKey{}},
+		{Method: "POST", Path: "/settings/api-keys", Tag: "auth", Summary: "Create an API key; the response carries the key",
+			Request: model.APIKeyInput{}, Status: http.StatusCreated, Response: model.APIKey{}},
+		{Method: "DELETE", Path: "/settings/api-keys/{id}", Tag: "auth", Summary: "Revoke an API key", Status: http.StatusNoContent},
+
+		{Method: "GET", Path: "/tasks", Tag: "tasks", Summary: "List your tasks", Query: taskListParams(), Response: model.TaskPage{}, Cached: true},
+		{Method: "POST", Path: "/tasks", Tag: "tasks", Summary: "Create a task; possible_duplicates lists open tasks of its project with a similar title",
+			Request: model.TaskInput{}, Status: http.StatusCreated, Response: model.Task{}, Versioned: true},
+		{Method: "POST", Path: "/tasks/bulk", Tag: "tasks", Summary: "Create, update, delete and move tasks in one transaction",
+			Query:   []Parameter{QueryParam("dry_run", "boolean", "Report what the operations would change without changing it")},
+			Request: model.BulkInput{}, Response: model.BulkResult{}},
+		{Method: "POST", Path: "/undo", Tag: "tasks", Summary: "Revert your last change to tasks, if it was recent",
+			Response: model.Undone{}},
+		{Method: "GET", Path: "/sync/changes", Tag: "tasks", Summary: "List the tasks changed since a cursor, trashed ones included, and those to drop, for offline clients",
+			Query: []Parameter{
+				QueryParam("limit", "integer", "Page size, default 50, maximum 200"),
+				QueryParam("since", "string", "cursor from the previous page; omit to start from the beginning"),
+			},
+			Response: model.ChangePage{}},
+		{Method: "POST", Path: "/sync/push", Tag: "tasks", Summary: "Apply changes made offline, settling conflicts by merge or last_write_wins",
+			Request: model.SyncPushInput{}, Response: model.SyncPushResult{}},
+		{Method: "GET", Path: "/tasks/{id}", Tag: "tasks", Summary: "Get a task", Response: model.Task{}, Versioned: true},
+		{Method: "PUT", Path: "/tasks/{id}", Tag: "tasks", Summary: "Replace a task",
+			Request: model.TaskInput{}, Response: model.Task{}, Versioned: true},
+		{Method: "PATCH", Path: "/tasks/{id}", Tag: "tasks", Summary: "Update some fields of a task",
+			Request: model.TaskPatch{}, Response: model.Task{}, Versioned: true},
+		{Method: "DELETE", Path: "/tasks/{id}", Tag: "tasks", Summary: "Move a task to the trash",
+			Query:  []Parameter{QueryParam("children", "string", "reparent (default) moves subtasks up a level; cascade deletes them too")},
+			Status: http.StatusNoContent},
+		{Method: "POST", Path: "/tasks/{id}/restore", Tag: "tasks", Summary: "Take a task, and the subtasks deleted with it, out of the trash",
+			Response: model.Task{}, Versioned: true},
+		{Method: "POST", Path: "/tasks/{id}/merge/{other_id}", Tag: "tasks", Summary: "Move the comments, attachments and watchers of another task onto this one, and the other task to the trash",
+			Response: model.Task{}, Versioned: true},
+		{Method: "GET", Path: "/tasks/{id}/revisions", Tag: "tasks", Summary: "Every saved version of a task, newest first",
+			Query: pageParams(), Response: model.RevisionPage{}, Cached: true},
+		{Method: "POST", Path: "/tasks/{id}/revert/{revision}", Tag: "tasks", Summary: "Put a task back as it was at an earlier version",
+			Response: model.Task{}, Versioned: true},
+		{Method: "POST", Path: "/tasks/{id}/archive", Tag: "tasks", Summary: "Archive a task, keeping it out of lists but not out of search",
+			Response: model.Task{}, Versioned: true},
+		{Method: "POST", Path: "/tasks/{id}/unarchive", Tag: "tasks", Summary: "Bring an archived task back into lists",
+			Response: model.Task{}, Versioned: true},
+		{Method: "GET", Path: "/trash", Tag: "tasks", Summary: "List your deleted tasks, most recently deleted first",
+			Query: taskListParams(), Response: model.TaskPage{}, Cached: true},
+		{Method: "PATCH", Path: "/tasks/{id}/move", Tag: "tasks", Summary: "Move a task to a board column and position",
+			Request: model.MoveInput{}, Response: model.Task{}, Versioned: true},
+		{Method: "POST", Path: "/tasks/{id}/reorder", Tag: "tasks", Summary: "Put a task before, after or between others in its project's order",
+			Request: model.ReorderInput{}, Response: model.Task{}, Versioned: true},
+		{Method: "GET", Path: "/tasks/{id}/subtasks", Tag: "tasks", Summary: "List the direct subtasks of a task",
+			Query: taskListParams(), Response: model.TaskPage{}, Cached: true},
+		{Method: "POST", Path: "/tasks/{id}/subtasks", Tag: "tasks", Summary: "Create a subtask",
+			Request: model.TaskInput{}, Status: http.StatusCreated, Response: model.Task{}},
+		{Method: "GET", Path: "/tasks/{id}/rollup", Tag: "tasks", Summary: "Completion of all subtasks below a task", Response: model.Rollup{}},
+		{Method: "PUT", Path: "/tasks/{id}/tags/{tag_id}", Tag: "tasks", Summary: "Attach a tag to a task", Response: model.Task{}},
+		{Method: "DELETE", Path: "/tasks/{id}/tags/{tag_id}", Tag: "tasks", Summary: "Detach a tag from a task", Response: model.Task{}},
+		{Method: "PUT", Path: "/tasks/{id}/assignee", Tag: "tasks", Summary: "Assign a task to a user, who is notified",
+			Request: model.AssignInput{}, Response: model.Task{}},
+		{Method: "DELETE", Path: "/tasks/{id}/assignee", Tag: "tasks", Summary: "Unassign a task", Response: model.Task{}},
+		{Method: "GET", Path: "/tasks/{id}/attachments", Tag: "attachments", Summary: "List the files attached to a task", Response: []model.Attachment{}},
+		{Method: "POST", Path: "/tasks/{id}/attachments", Tag: "attachments", Summary: "Upload a file to a task",
+			Upload: "file", Status: http.StatusCreated, Response: model.Attachment{}},
+		{Method: "GET", Path: "/tasks/{id}/attachments/{attachment_id}", Tag: "attachments", Summary: "Get an attachment with a fresh download link",
+			Response: model.Attachment{}},
+		{Method: "DELETE", Path: "/tasks/{id}/attachments/{attachment_id}", Tag: "attachments", Summary: "Delete an attachment",
+			Status: http.StatusNoContent},
+		{Method: "GET", Path: "/tasks/{id}/attachments/{attachment_id}/download", Tag: "attachments", Summary: "Redirect to a signed download link",
+			Status: http.StatusFound},
+		{Method: "GET", Path: "/attachments/{id}", Tag: "attachments", Summary: "Download a file through a signed link", Public: true,
+			Query: []Parameter{
+				QueryParam("expires", "integer", "from the signed link"),
+				QueryParam("signature", "string", "from the signed link"),
+			}},
+		{Method: "GET", Path: "/attachments/{id}/thumb", Tag: "attachments", Summary: "Get the thumbnail of an image through a signed link", Public: true,
+			Query: []Parameter{
+				QueryParam("w", "integer", "width wanted in pixels, rounded up to 64, 128, 256 or 512 (default 256)"),
+				QueryParam("expires", "integer", "from the signed link"),
+				QueryParam("signature", "string", "from the signed link"),
+			}},
+		{Method: "GET", Path: "/tasks/{id}/comments", Tag: "comments", Summary: "List the comments on a task, oldest first", Response: []model.Comment{}},
+		{Method: "POST", Path: "/tasks/{id}/comments", Tag: "comments", Summary: "Comment on a task, optionally replying to another comment",
+			Request: model.CommentInput{}, Status: http.StatusCreated, Response: model.Comment{}},
+		{Method: "GET", Path: "/tasks/{id}/comments/{comment_id}", Tag: "comments", Summary: "Get a comment", Response: model.Comment{}},
+		{Method: "PATCH", Path: "/tasks/{id}/comments/{comment_id}", Tag: "comments", Summary: "Edit your comment",
+			Request: model.CommentPatch{}, Response: model.Comment{}},
+		{Method: "DELETE", Path: "/tasks/{id}/comments/{comment_id}", Tag: "comments", Summary: "Delete a comment; its replies move up a level",
+			Status: http.StatusNoContent},
+		{Method: "GET", Path: "/tasks/{id}/comments/{comment_id}/history", Tag: "comments", Summary: "Earlier versions of an edited comment",
+			Response: []model.CommentEdit{}},
+		{Method: "POST", Path: "/markdown", Tag: "comments", Summary: "Render Markdown as descriptions and comments are rendered, for previews",
+			Request: model.MarkdownInput{}, Response: model.RenderedMarkdown{}},
+		{Method: "GET", Path: "/tasks/{id}/activity", Tag: "activity", Summary: "Who changed what on a task and its comments, newest first",
+			Query: pageParams(), Response: model.ActivityPage{}},
+
+		{Method: "POST", Path: "/tasks/{id}/timer/start", Tag: "time", Summary: "Start a timer on a task, stopping the one you run elsewhere",
+			Response: model.TimeEntry{}, Status: http.StatusCreated},
+		{Method: "POST", Path: "/tasks/{id}/timer/stop", Tag: "time", Summary: "Stop your timer on a task", Response: model.TimeEntry{}},
+		{Method: "GET", Path: "/me/timer", Tag: "time", Summary: "The timer you are running", Response: model.TimeEntry{}},
+		{Method: "GET", Path: "/tasks/{id}/time-entries", Tag: "time", Summary: "The time logged on a task, oldest first",
+			Response: []model.TimeEntry{}},
+		{Method: "POST", Path: "/tasks/{id}/time-entries", Tag: "time", Summary: "Log time spent on a task",
+			Request: model.TimeEntryInput{}, Response: model.TimeEntry{}, Status: http.StatusCreated},
+		{Method: "PATCH", Path: "/tasks/{id}/time-entries/{entry_id}", Tag: "time", Summary: "Change one of your time entries",
+			Request: model.TimeEntryPatch{}, Response: model.TimeEntry{}},
+		{Method: "DELETE", Path: "/tasks/{id}/time-entries/{entry_id}", Tag: "time", Summary: "Delete one of your time entries",
+			Status: http.StatusNoContent},
+		{Method: "GET", Path: "/time/totals", Tag: "time", Summary: "Total time per task, project or user over a date range",
+			Query: []Parameter{
+				QueryParam("by", "string", "task (the default), project or user"),
+				QueryParam("from", "string", "RFC 3339 timestamp or YYYY-MM-DD date"),
+				QueryParam("to", "string", "RFC 3339 timestamp or YYYY-MM-DD date (exclusive)"),
+				QueryParam("project_id", "string", "Only time on tasks in this project"),
+				QueryParam("user", "string", `Only time logged by this user ID, or "me"`),
+			}, Response: model.TimeTotals{}},
+		{Method: "GET", Path: "/tasks/{id}/dependencies", Tag: "tasks", Summary: "The tasks blocking a task and those it blocks",
+			Response: model.Dependencies{}},
+		{Method: "GET", Path: "/tasks/{id}/dependencies/graph", Tag: "tasks", Summary: "Every task linked to a task through dependencies",
+			Response: model.DependencyGraph{}},
+		{Method: "PUT", Path: "/tasks/{id}/blockers/{blocker_id}", Tag: "tasks", Summary: "Mark a task as blocked by another",
+			Response: model.Dependencies{}},
+		{Method: "DELETE", Path: "/tasks/{id}/blockers/{blocker_id}", Tag: "tasks", Summary: "Stop a task being blocked by another",
+			Response: model.Dependencies{}},
+		{Method: "GET", Path: "/tasks/{id}/checklist", Tag: "tasks", Summary: "The checklist of a task, first item first",
+			Response: []model.ChecklistItem{}},
+		{Method: "POST", Path: "/tasks/{id}/checklist", Tag: "tasks", Summary: "Add an item to the end of a task's checklist",
+			Request: model.ChecklistItemInput{}, Response: model.ChecklistItem{}, Status: http.StatusCreated},
+		{Method: "PUT", Path: "/tasks/{id}/checklist/order", Tag: "tasks", Summary: "Reorder a task's checklist",
+			Request: model.ChecklistOrder{}, Response: []model.ChecklistItem{}},
+		{Method: "PATCH", Path: "/tasks/{id}/checklist/{item_id}", Tag: "tasks", Summary: "Rename a checklist item or mark it done",
+			Request: model.ChecklistItemPatch{}, Response: model.ChecklistItem{}},
+		{Method: "DELETE", Path: "/tasks/{id}/checklist/{item_id}", Tag: "tasks", Summary: "Remove an item from a task's checklist",
+			Status: http.StatusNoContent},
+		{Method: "POST", Path: "/tasks/{id}/checklist/{item_id}/toggle", Tag: "tasks", Summary: "Flip whether a checklist item is done",
+			Response: model.ChecklistItem{}},
+		{Method: "GET", Path: "/tasks/{id}/watchers", Tag: "tasks", Summary: "Who is watching a task, earliest first",
+			Response: []model.Watcher{}},
+		{Method: "POST", Path: "/tasks/{id}/watch", Tag: "tasks", Summary: "Be told of every change and comment on a task",
+			Status: http.StatusNoContent},
+		{Method: "DELETE", Path: "/tasks/{id}/watch", Tag: "tasks", Summary: "Stop watching a task",
+			Status: http.StatusNoContent},
+
+		{Method: "GET", Path: "/search", Tag: "search", Summary: "Search the titles, descriptions and comments of your tasks",
+			Query: searchParams(), Response: model.SearchPage{}, Cached: true},
+
+		{Method: "GET", Path: "/projects", Tag: "projects", Summary: "List your projects",
+			Query:    []Parameter{QueryParam("archived", "boolean", "list the archived projects instead of the others")},
+			Response: []model.Project{}},
+		{Method: "POST", Path: "/projects", Tag: "projects", Summary: "Create a project",
+			Request: model.ProjectInput{}, Status: http.StatusCreated, Response: model.Project{}},
+		{Method: "GET", Path: "/projects/{id}", Tag: "projects", Summary: "Get a project", Response: model.Project{}},
+		{Method: "PUT", Path: "/projects/{id}", Tag: "projects", Summary: "Replace a project",
+			Request: model.ProjectInput{}, Response: model.Project{}},
+		{Method: "PATCH", Path: "/projects/{id}", Tag: "projects", Summary: "Update some fields of a project",
+			Request: model.ProjectPatch{}, Response: model.Project{}},
+		{Method: "DELETE", Path: "/projects/{id}", Tag: "projects", Summary: "Delete a project, keeping its tasks", Status: http.StatusNoContent},
+		{Method: "POST", Path: "/projects/{id}/archive", Tag: "projects", Summary: "Archive a project, keeping it out of the list of projects",
+			Response: model.Project{}},
+		{Method: "POST", Path: "/projects/{id}/unarchive", Tag: "projects", Summary: "Bring an archived project back into the list", Response: model.Project{}},
+		{Method: "GET", Path: "/projects/{id}/tasks", Tag: "projects", Summary: "List the tasks of a project, by position by default",
+			Query: taskListParams(), Response: model.TaskPage{}, Cached: true},
+		{Method: "PUT", Path: "/projects/{id}/order", Tag: "projects", Summary: "Set the order of every task in a project",
+			Request: model.TaskOrder{}, Status: http.StatusNoContent},
+		{Method: "GET", Path: "/projects/{id}/export", Tag: "projects", Summary: "Download every task of a project as JSON or CSV",
+			Query: []Parameter{QueryParam("format", "string", "json (the default) or csv")}, Response: []model.TaskRecord{}},
+		{Method: "POST", Path: "/projects/{id}/import", Tag: "projects", Summary: "Create tasks from an export, in JSON or as text/csv",
+			Query: []Parameter{
+				QueryParam("map", "string", "field:column, to read a field from a differently named column; repeatable"),
+				QueryParam("dry_run", "boolean", "Report what would be created without creating it"),
+				QueryParam("duplicates", "string", "skip (the default) or keep"),
+			}, Request: []model.TaskRecord{}, Streamed: true, Response: model.ImportResult{}},
+		{Method: "GET", Path: "/projects/{id}/members", Tag: "projects", Summary: "List who the project is shared with", Response: []model.Member{}},
+		{Method: "POST", Path: "/projects/{id}/members", Tag: "projects", Summary: "Share the project with a user, by ID or email; owners only",
+			Request: model.MemberInput{}, Status: http.StatusCreated, Response: model.Member{}},
+		{Method: "PATCH", Path: "/projects/{id}/members/{user_id}", Tag: "projects", Summary: "Change a member's role; owners only",
+			Request: model.MemberPatch{}, Response: model.Member{}},
+		{Method: "DELETE", Path: "/projects/{id}/members/{user_id}", Tag: "projects", Summary: "Remove a member, or leave the project",
+			Status: http.StatusNoContent},
+		{Method: "GET", Path: "/projects/{id}/fields", Tag: "projects", Summary: "List the custom fields of the project's tasks", Response: []model.CustomField{}},
+		{Method: "POST", Path: "/projects/{id}/fields", Tag: "projects", Summary: "Define a custom field for the project's tasks; owners only",
+			Request: model.CustomFieldInput{}, Status: http.StatusCreated, Response: model.CustomField{}},
+		{Method: "PATCH", Path: "/projects/{id}/fields/{field_id}", Tag: "projects", Summary: "Rename a custom field or change its options; owners only",
+			Request: model.CustomFieldPatch{}, Response: model.CustomField{}},
+		{Method: "DELETE", Path: "/projects/{id}/fields/{field_id}", Tag: "projects", Summary: "Delete a custom field and its values; owners only",
+			Status: http.StatusNoContent},
+		{Method: "GET", Path: "/projects/{id}/rules", Tag: "projects", Summary: "List the project's automation rules", Response: []model.Rule{}},
+		{Method: "POST", Path: "/projects/{id}/rules", Tag: "projects", Summary: "Define an automation rule the scheduler runs on the project's tasks; owners only",
+			Request: model.RuleInput{}, Status: http.StatusCreated, Response: model.Rule{}},
+		{Method: "GET", Path: "/projects/{id}/rules/{rule_id}", Tag: "projects", Summary: "Get an automation rule", Response: model.Rule{}},
+		{Method: "PATCH", Path: "/projects/{id}/rules/{rule_id}", Tag: "projects", Summary: "Change, enable or disable an automation rule; owners only",
+			Request: model.RulePatch{}, Response: model.Rule{}},
+		{Method: "DELETE", Path: "/projects/{id}/rules/{rule_id}", Tag: "projects", Summary: "Delete an automation rule; owners only",
+			Status: http.StatusNoContent},
+		{Method: "GET", Path: "/projects/{id}/activity", Tag: "activity", Summary: "Who changed what in a project and its tasks, newest first",
+			Query: pageParams(), Response: model.ActivityPage{}},
+		{Method: "GET", Path: "/projects/{id}/stats", Tag: "projects", Summary: "Throughput, time per column and burndown of a project, day by day",
+			Query: []Parameter{
+				QueryParam("from", "string", "first YYYY-MM-DD date, in your timezone; 29 days before to by default"),
+				QueryParam("to", "string", "last YYYY-MM-DD date, in your timezone; today by default"),
+			}, Response: model.ProjectStats{}, Cached: true},
+		{Method: "GET", Path: "/projects/{id}/github", Tag: "github", Summary: "The GitHub repository the project is linked to, without its secret; owners only",
+			Response: model.GitHubLink{}},
+		{Method: "PUT", Path: "/projects/{id}/github", Tag: "github", Summary: "Link the project to a GitHub repository; the secret is only shown here",
+			Request: model.GitHubLinkInput{}, Response: model.GitHubLink{}},
+		{Method: "DELETE", Path: "/projects/{id}/github", Tag: "github", Summary: "Unlink the project; its tasks are kept", Status: http.StatusNoContent},
+		{Method: "POST", Path: "/projects/{id}/github/sync", Tag: "github", Summary: "Import the repository's issues as tasks, or update their tasks",
+			Response: model.GitHubSyncResult{}},
+		{Method: "POST", Path: "/integrations/github/{project_id}", Tag: "github", Summary: "Webhook for the linked repository's issues events, signed with the link's secret",
+			Public: true, Status: http.StatusNoContent},
+		{Method: "GET", Path: "/projects/{id}/slack", Tag: "slack", Summary: "The Slack channel the project is linked to; owners only",
+			Response: model.SlackLink{}},
+		{Method: "PUT", Path: "/projects/{id}/slack", Tag: "slack", Summary: "Link the project to a Slack channel's incoming webhook",
+			Request: model.SlackLinkInput{}, Response: model.SlackLink{}},
+		{Method: "DELETE", Path: "/projects/{id}/slack", Tag: "slack", Summary: "Unlink the project from Slack", Status: http.StatusNoContent},
+		{Method: "POST", Path: "/integrations/slack/{project_id}/command", Tag: "slack", Summary: "Slash command that adds a task to the project, signed with the Slack app's signing secret",
+			Public: true, Response: model.SlackReply{}},
+		{Method: "POST", Path: "/import/trello", Tag: "imports", Summary: "Import a Trello board from its JSON export as a new project, in the background",
+			Query: boardImportParams(), Status: http.StatusAccepted, Response: model.BoardImport{}},
+		{Method: "POST", Path: "/import/jira", Tag: "imports", Summary: "Import Jira issues, from a search result in JSON or an issue navigator export as text/csv, in the background",
+			Query: boardImportParams(), Status: http.StatusAccepted, Response: model.BoardImport{}},
+		{Method: "GET", Path: "/imports", Tag: "imports", Summary: "List the board imports you started, newest first", Response: []model.BoardImport{}},
+		{Method: "GET", Path: "/imports/{id}", Tag: "imports", Summary: "The progress of a board import", Response: model.BoardImport{}},
+
+		{Method: "GET", Path: "/tags", Tag: "tags", Summary: "List your tags", Response: []model.Tag{}},
+		{Method: "POST", Path: "/tags", Tag: "tags", Summary: "Create a tag",
+			Request: model.TagInput{}, Status: http.StatusCreated, Response: model.Tag{}},
+		{Method: "GET", Path: "/tags/{id}", Tag: "tags", Summary: "Get a tag", Response: model.Tag{}},
+		{Method: "PATCH", Path: "/tags/{id}", Tag: "tags", Summary: "Rename or recolor a tag",
+			Request: model.TagPatch{}, Response: model.Tag{}},
+		{Method: "DELETE", Path: "/tags/{id}", Tag: "tags", Summary: "Delete a tag and detach it from every task", Status: http.StatusNoContent},
+
+		{Method: "GET", Path: "/views", Tag: "views", Summary: "List your saved views", Response: []model.View{}},
+		{Method: "POST", Path: "/views", Tag: "views", Summary: "Save a view",
+			Request: model.ViewInput{}, Status: http.StatusCreated, Response: model.View{}},
+		{Method: "GET", Path: "/views/{id}", Tag: "views", Summary: "Get a view", Response: model.View{}},
+		{Method: "PATCH", Path: "/views/{id}", Tag: "views", Summary: "Rename a view or replace its filter",
+			Request: model.ViewPatch{}, Response: model.View{}},
+		{Method: "DELETE", Path: "/views/{id}", Tag: "views", Summary: "Delete a view", Status: http.StatusNoContent},
+		{Method: "GET", Path: "/views/{id}/tasks", Tag: "views", Summary: "The tasks a view selects",
+			Query: pageParams(), Response: model.TaskPage{}, Cached: true},
+
+		{Method: "POST", Path: "/tasks/{id}/template", Tag: "templates", Summary: "Save a task and its subtasks as a template",
+			Request: model.TemplateInput{}, Status: http.StatusCreated, Response: model.Template{}},
+		{Method: "POST", Path: "/projects/{id}/template", Tag: "templates", Summary: "Save a project, its columns and tasks as a template",
+			Request: model.TemplateInput{}, Status: http.StatusCreated, Response: model.Template{}},
+		{Method: "GET", Path: "/templates", Tag: "templates", Summary: "List your templates", Response: []model.Template{}},
+		{Method: "GET", Path: "/templates/{id}", Tag: "templates", Summary: "Get a template", Response: model.Template{}},
+		{Method: "PATCH", Path: "/templates/{id}", Tag: "templates", Summary: "Rename a template",
+			Request: model.TemplatePatch{}, Response: model.Template{}},
+		{Method: "DELETE", Path: "/templates/{id}", Tag: "templates", Summary: "Delete a template", Status: http.StatusNoContent},
+		{Method: "POST", Path: "/templates/{id}/use", Tag: "templates", Summary: "Make a project or tasks from a template",
+			Request: model.TemplateUse{}, Status: http.StatusCreated, Response: model.TemplateResult{}},
+
+		{Method: "GET", Path: "/webhooks", Tag: "webhooks", Summary: "List your webhooks", Response: []model.Webhook{}},
+		{Method: "POST", Path: "/webhooks", Tag: "webhooks", Summary: "Register a webhook; the response carries its signing secret",
+			Request: model.WebhookInput{}, Status: http.StatusCreated, Response: model.Webhook{}},
+		{Method: "GET", Path: "/webhooks/{id}", Tag: "webhooks", Summary: "Get a webhook", Response: model.Webhook{}},
+		{Method: "PATCH", Path: "/webhooks/{id}", Tag: "webhooks", Summary: "Change a webhook's URL, events or active flag",
+			Request: model.WebhookPatch{}, Response: model.Webhook{}},
+		{Method: "DELETE", Path: "/webhooks/{id}", Tag: "webhooks", Summary: "Delete a webhook and its delivery log", Status: http.StatusNoContent},
+		{Method: "GET", Path: "/webhooks/{id}/deliveries", Tag: "webhooks", Summary: "Recent deliveries, newest first",
+			Query: []Parameter{QueryParam("limit", "integer", "Maximum deliveries to return (default 50, max 200)")}, Response: []model.Delivery{}},
+		{Method: "POST", Path: "/webhooks/{id}/deliveries/{delivery_id}/replay", Tag: "webhooks", Summary: "Send a finished delivery again",
+			Status: http.StatusAccepted, Response: model.Delivery{}},
+
+		{Method: "GET", Path: "/usage", Tag: "orgs", Summary: "What your organization uses against its quotas; organization admins only",
+			Response: model.OrgUsage{}},
+
+		{Method: "GET", Path: "/admin/stats", Tag: "admin", Summary: "Count what the whole server holds; administrators only",
+			Response: model.Usage{}},
+		{Method: "GET", Path: "/admin/users", Tag: "admin", Summary: "List users, oldest first",
+			Query:    append(pageParams(), QueryParam("q", "string", "Only users whose email or username contains this")),
+			Response: model.UserPage{}},
+		{Method: "GET", Path: "/admin/users/{id}", Tag: "admin", Summary: "Get a user with their organizations and session count",
+			Response: model.AdminUser{}},
+		{Method: "POST", Path: "/admin/users/{id}/disable", Tag: "admin", Summary: "Keep a user from signing in and end their sessions",
+			Response: model.User{}},
+		{Method: "POST", Path: "/admin/users/{id}/enable", Tag: "admin", Summary: "Let a disabled user sign in again", Response: model.User{}},
+		{Method: "POST", Path: "/admin/users/{id}/reset-password", Tag: "admin",
+			Summary: "Clear a user's password, sign them out and email them a reset token", Status: http.StatusAccepted},
+		{Method: "POST", Path: "/admin/users/{id}/impersonate", Tag: "admin",
+			Summary: "Get a token that acts as a user, tied to your session", Response: model.Session{}},
+		{Method: "GET", Path: "/admin/orgs", Tag: "admin", Summary: "List organizations with their usage, oldest first",
+			Query:    append(pageParams(), QueryParam("q", "string", "Only organizations whose name contains this")),
+			Response: model.AdminOrgPage{}},
+		{Method: "GET", Path: "/admin/orgs/{id}", Tag: "admin", Summary: "Get an organization with its usage", Response: model.AdminOrg{}},
+		{Method: "POST", Path: "/admin/backup", Tag: "admin",
+			Summary: "Download an organization, by default yours, as a gzipped tar archive", Request: model.BackupInput{}},
+		{Method: "POST", Path: "/admin/restore", Tag: "admin",
+			Summary: "Rebuild an organization from a backup archive sent as application/gzip, as a new organization",
+			Status:  http.StatusCreated, Response: model.RestoreResult{}},
+		{Method: "GET", Path: "/admin/jobs", Tag: "admin", Summary: "List background jobs, newest first",
+			Query: []Parameter{
+				QueryParam("status", "string", "pending, running, succeeded or dead"),
+				QueryParam("kind", "string", "Only jobs of this kind, such as email.send"),
+				QueryParam("limit", "integer", "Maximum jobs to return (default 50, max 200)"),
+			}, Response: []model.Job{}},
+		{Method: "GET", Path: "/admin/jobs/{id}", Tag: "admin", Summary: "Get a background job", Response: model.Job{}},
+		{Method: "POST", Path: "/admin/jobs/{id}/retry", Tag: "admin", Summary: "Give a dead job a fresh set of attempts",
+			Status: http.StatusAccepted, Response: model.Job{}},
+		{Method: "DELETE", Path: "/admin/jobs/{id}", Tag: "admin", Summary: "Discard a background job", Status: http.StatusNoContent},
+		{Method: "GET", Path: "/admin/purges", Tag: "admin",
+			Summary: "Count what each scheduled purge would delete if it ran now, deleting nothing", Response: []model.PurgePreview{}},
+		{Method: "GET", Path: "/admin/flags", Tag: "admin", Summary: "List feature flags, by key", Response: []model.Flag{}},
+		{Method: "GET", Path: "/admin/flags/{key}", Tag: "admin", Summary: "Get a feature flag", Response: model.Flag{}},
+		{Method: "PUT", Path: "/admin/flags/{key}", Tag: "admin",
+			Summary: "Create a feature flag or change who has it, leaving its organizations and users be",
+			Request: model.FlagInput{}, Response: model.Flag{}},
+		{Method: "DELETE", Path: "/admin/flags/{key}", Tag: "admin",
+			Summary: "Delete a feature flag; a flag the server checks goes back to its default", Status: http.StatusNoContent},
+		{Method: "PUT", Path: "/admin/flags/{key}/orgs/{org_id}", Tag: "admin",
+			Summary: "Turn a feature flag on or off for an organization", Request: model.FlagOverrideInput{}, Response: model.Flag{}},
+		{Method: "DELETE", Path: "/admin/flags/{key}/orgs/{org_id}", Tag: "admin",
+			Summary: "Let an organization have a feature flag as everyone else does", Status: http.StatusNoContent},
+		{Method: "PUT", Path: "/admin/flags/{key}/users/{user_id}", Tag: "admin",
+			Summary: "Turn a feature flag on or off for a user", Request: model.FlagOverrideInput{}, Response: model.Flag{}},
+		{Method: "DELETE", Path: "/admin/flags/{key}/users/{user_id}", Tag: "admin",
+			Summary: "Let a user have a feature flag as the rest of their organization does", Status: http.StatusNoContent},
+		{Method: "GET", Path: "/admin/audit", Tag: "admin", Summary: "List audit log events, newest first",
+			Query: append(pageParams(), auditParams()...), Response: model.AuditPage{}},
+		{Method: "GET", Path: "/admin/audit/export", Tag: "admin",
+			Summary: "Download the audit log events matching the filters as CSV, oldest first", Query: auditParams()},
+		{Method: "GET", Path: "/admin/audit/verify", Tag: "admin",
+			Summary: "Check that no stored audit log event has been changed, removed or reordered", Response: model.AuditVerification{}},
+		{Method: "GET", Path: "/admin/debug/pprof/", Tag: "admin", Summary: "List the runtime profiles, as net/http/pprof does"},
+		{Method: "GET", Path: "/admin/debug/pprof/profile", Tag: "admin", Summary: "Record a CPU profile for go tool pprof",
+			Query: []Parameter{QueryParam("seconds", "integer", "How long to profile (default 30)")}},
+		{Method: "GET", Path: "/admin/debug/pprof/trace", Tag: "admin", Summary: "Record an execution trace for go tool trace",
+			Query: []Parameter{QueryParam("seconds", "number", "How long to trace (default 1)")}},
+		{Method: "GET", Path: "/admin/debug/pprof/{profile}", Tag: "admin",
+			Summary: "Take a heap, allocs, goroutine, block, mutex or threadcreate profile",
+			Query:   []Parameter{QueryParam("debug", "integer", "1 or 2 for text in place of the pprof format")}},
+		{Method: "GET", Path: "/admin/debug/vars", Tag: "admin", Summary: "The runtime's expvar variables, such as memstats"},
+
+		{Method: "GET", Path: "/orgs", Tag: "orgs", Summary: "List your organizations with your role in each", Response: []model.Org{}},
+		{Method: "POST", Path: "/orgs", Tag: "orgs", Summary: "Create an organization you own",
+			Request: model.OrgInput{}, Status: http.StatusCreated, Response: model.Org{}},
+		{Method: "GET", Path: "/orgs/{id}", Tag: "orgs", Summary: "Get an organization", Response: model.Org{}},
+		{Method: "PATCH", Path: "/orgs/{id}", Tag: "orgs", Summary: "Rename an organization, or require two-factor authentication of its members; owners only",
+			Request: model.OrgInput{}, Response: model.Org{}},
+		{Method: "GET", Path: "/orgs/{id}/members", Tag: "orgs", Summary: "List the organization's members", Response: []model.OrgMembership{}},
+		{Method: "PATCH", Path: "/orgs/{id}/members/{user_id}", Tag: "orgs", Summary: "Change a member's role; owners only",
+			Request: model.OrgMemberPatch{}, Response: model.OrgMembership{}},
+		{Method: "DELETE", Path: "/orgs/{id}/members/{user_id}", Tag: "orgs", Summary: "Remove a member, or leave the organization",
+			Status: http.StatusNoContent},
+		{Method: "GET", Path: "/orgs/{id}/invitations", Tag: "orgs", Summary: "List pending invitations; admins only", Response: []model.Invitation{}},
+		{Method: "POST", Path: "/orgs/{id}/invitations", Tag: "orgs", Summary: "Invite somebody by email; the response carries the token",
+			Request: model.InvitationInput{}, Status: http.StatusCreated, Response: model.Invitation{}},
+		{Method: "DELETE", Path: "/orgs/{id}/invitations/{invitation_id}", Tag: "orgs", Summary: "Revoke a pending invitation",
+			Status: http.StatusNoContent},
+		{Method: "GET", Path: "/orgs/{id}/domains", Tag: "orgs", Summary: "List the email domains the organization claims; admins only", Response: []model.Domain{}},
+		{Method: "POST", Path: "/orgs/{id}/domains", Tag: "orgs", Summary: "Claim an email domain; the response carries the token to publish in DNS",
+			Request: model.DomainInput{}, Status: http.StatusCreated, Response: model.Domain{}},
+		{Method: "POST", Path: "/orgs/{id}/domains/{domain}/verify", Tag: "orgs", Summary: "Verify a claimed domain by its TXT record; admins only",
+			Response: model.Domain{}},
+		{Method: "DELETE", Path: "/orgs/{id}/domains/{domain}", Tag: "orgs", Summary: "Drop the claim to an email domain; admins only",
+			Status: http.StatusNoContent},
+		{Method: "GET", Path: "/orgs/{id}/sso", Tag: "orgs", Summary: "The organization's single sign-on settings; admins only", Response: model.SSOConfig{}},
+		{Method: "PUT", Path: "/orgs/{id}/sso", Tag: "orgs", Summary: "Sign in the people of a verified domain through an OpenID Connect provider; admins only",
+			Request: model.SSOConfigInput{}, Response: model.SSOConfig{}},
+		{Method: "DELETE", Path: "/orgs/{id}/sso", Tag: "orgs", Summary: "Turn single sign-on off; admins only", Status: http.StatusNoContent},
+		{Method: "POST", Path: "/invitations/accept", Tag: "orgs", Summary: "Join the organization an invitation sent to your email is for",
+			Request: model.AcceptInput{}, Status: http.StatusCreated, Response: model.OrgMembership{}},
+
+		{Method: "GET", Path: "/me/notifications", Tag: "notifications", Summary: "Which emails you receive", Response: model.NotificationPrefs{}},
+		{Method: "PATCH", Path: "/me/notifications", Tag: "notifications", Summary: "Opt in to or out of kinds of email",
+			Request: model.NotificationPrefsPatch{}, Response: model.NotificationPrefs{}},
+		{Method: "GET", Path: "/notifications", Tag: "notifications", Summary: "Your inbox in this organization, newest first",
+			Query:    append(pageParams(), QueryParam("unread", "boolean", "Only notifications you have not read")),
+			Response: model.NotificationPage{}, Cached: true},
+		{Method: "POST", Path: "/notifications/{id}/read", Tag: "notifications", Summary: "Mark a notification read",
+			Response: model.Notification{}},
+		{Method: "POST", Path: "/notifications/read", Tag: "notifications", Summary: "Mark every notification in this organization read",
+			Status: http.StatusNoContent},
+
+		{Method: "GET", Path: "/me/calendar", Tag: "calendar", Summary: "Your calendar feed, without its token", Response: model.CalendarFeed{}},
+		{Method: "POST", Path: "/me/calendar", Tag: "calendar", Summary: "Create your calendar feed, or replace its URL",
+			Status: http.StatusCreated, Response: model.CalendarFeed{}},
+		{Method: "DELETE", Path: "/me/calendar", Tag: "calendar", Summary: "Turn your calendar feed off", Status: http.StatusNoContent},
+		{Method: "GET", Path: "/calendar/{file}", Tag: "calendar", Summary: "iCalendar feed of your open tasks with due dates; file is {token}.ics", Public: true},
+
+		{Method: "POST", Path: "/projects/{id}/share", Tag: "shares", Summary: "Create a read-only public link to a project's board; the response carries the token",
+			Request: model.ShareInput{}, Status: http.StatusCreated, Response: model.ShareLink{}},
+		{Method: "GET", Path: "/projects/{id}/shares", Tag: "shares", Summary: "List the share links of a project, without their tokens", Response: []model.ShareLink{}},
+		{Method: "POST", Path: "/tasks/{id}/share", Tag: "shares", Summary: "Create a read-only public link to a task; the response carries the token",
+			Request: model.ShareInput{}, Status: http.StatusCreated, Response: model.ShareLink{}},
+		{Method: "GET", Path: "/tasks/{id}/shares", Tag: "shares", Summary: "List the share links of a task, without their tokens", Response: []model.ShareLink{}},
+		{Method: "DELETE", Path: "/shares/{id}", Tag: "shares", Summary: "Revoke a share link", Status: http.StatusNoContent},
+		{Method: "GET", Path: "/shared/{token}", Tag: "shares", Summary: "The project or task a share link shows", Public: true, Response: model.SharedView{}},
+
+		{Method: "GET", Path: "/me/inbound-email", Tag: "email", Summary: "Your email-to-task address, without its token", Response: model.InboundAddress{}},
+		{Method: "POST", Path: "/me/inbound-email", Tag: "email", Summary: "Create your email-to-task address, or replace it",
+			Status: http.StatusCreated, Response: model.InboundAddress{}},
+		{Method: "DELETE", Path: "/me/inbound-email", Tag: "email", Summary: "Turn your email-to-task address off", Status: http.StatusNoContent},
+		{Method: "POST", Path: "/integrations/email", Tag: "email", Summary: "Raw email posted by the mail server, which becomes a task; authenticated with the inbound email secret",
+			Public: true, Query: []Parameter{QueryParam("recipient", "string", "envelope recipient, for emails that reach the address by Bcc")},
+			Status: http.StatusCreated, Response: model.InboundResult{}},
+
+		{Method: "GET", Path: "/scim/v2/ServiceProviderConfig", Tag: "scim", Summary: "The parts of SCIM 2.0 supported; SCIM routes take an API key of scope scim"},
+		{Method: "GET", Path: "/scim/v2/ResourceTypes", Tag: "scim", Summary: "The User and Group resource types"},
+		{Method: "GET", Path: "/scim/v2/Users", Tag: "scim", Summary: "Users provisioned into the organization",
+			Query: scimListParams(), Response: model.SCIMUserList{}},
+		{Method: "POST", Path: "/scim/v2/Users", Tag: "scim", Summary: "Provision a user, creating their account if there is none, into the organization",
+			Request: model.SCIMUser{}, Status: http.StatusCreated, Response: model.SCIMUser{}},
+		{Method: "GET", Path: "/scim/v2/Users/{id}", Tag: "scim", Summary: "A provisioned user", Response: model.SCIMUser{}},
+		{Method: "PUT", Path: "/scim/v2/Users/{id}", Tag: "scim", Summary: "Replace a user's userName, externalId and active; inactive users leave the organization",
+			Request: model.SCIMUser{}, Response: model.SCIMUser{}},
+		{Method: "PATCH", Path: "/scim/v2/Users/{id}", Tag: "scim", Summary: "Change a user's userName, externalId or active",
+			Request: model.SCIMPatch{}, Response: model.SCIMUser{}},
+		{Method: "DELETE", Path: "/scim/v2/Users/{id}", Tag: "scim", Summary: "Deprovision a user, removing them from the organization", Status: http.StatusNoContent},
+		{Method: "GET", Path: "/scim/v2/Groups", Tag: "scim", Summary: "Groups, which are the key owner's projects",
+			Query: scimListParams(), Response: model.SCIMGroupList{}},
+		{Method: "POST", Path: "/scim/v2/Groups", Tag: "scim", Summary: "Create a project with the group's members as editors",
+			Request: model.SCIMGroup{}, Status: http.StatusCreated, Response: model.SCIMGroup{}},
+		{Method: "GET", Path: "/scim/v2/Groups/{id}", Tag: "scim", Summary: "A group", Response: model.SCIMGroup{}},
+		{Method: "PUT", Path: "/scim/v2/Groups/{id}", Tag: "scim", Summary: "Rename a group and replace its members",
+			Request: model.SCIMGroup{}, Response: model.SCIMGroup{}},
+		{Method: "PATCH", Path: "/scim/v2/Groups/{id}", Tag: "scim", Summary: "Rename a group, or add, remove or replace members",
+			Request: model.SCIMPatch{}, Response: model.SCIMGroup{}},
+		{Method: "DELETE", Path: "/scim/v2/Groups/{id}", Tag: "scim", Summary: "Archive a group's project", Status: http.StatusNoContent},
+
+		{Method: "GET", Path: "/ws", Tag: "realtime", Summary: "WebSocket stream of task events; the token may be passed as access_token",
+			Query:  []Parameter{QueryParam("access_token", "string", "Bearer token for clients that cannot set headers")},
+			Status: http.StatusSwitchingProtocols},
+		{Method: "GET", Path: "/events", Tag: "realtime", Summary: "Server-Sent Events stream of task events; resumes from Last-Event-ID",
+			Query: []Parameter{
+				QueryParam("access_token", "string", "Bearer token for clients that cannot set headers"),
+				QueryParam("last_event_id", "integer", "Resume after this event when the header cannot be set"),
+			}},
+
+		{Method: "POST", Path: "/graphql", Tag: "graphql", Summary: "Run a GraphQL query or mutation; subscriptions answer with a text/event-stream",
+			Request: graphql.Request{}, Response: graphql.Response{}},
+		{Method: "GET", Path: "/graphql", Tag: "graphql", Summary: "Run a GraphQL query given in the URL",
+			Query: []Parameter{
+				QueryParam("query", "string", "The query document"),
+				QueryParam("operationName", "string", "The operation to run when the document has several"),
+				QueryParam("variables", "string", "Variables as a JSON object"),
+			}, Response: graphql.Response{}},
+	}
+}
+
+// taskListParams are the query parameters shared by every task listing.
+func taskListParams() []Parameter {
+	return []Parameter{
+		QueryParam("limit", "integer", "Page size, default 50, maximum 200"),
+		QueryParam("cursor", "string", "next_cursor from the previous page"),
+		QueryParam("offset", "integer", "Rows to skip when no cursor is given"),
+		QueryParam("sort", "string", "created_at, updated_at, due_date, title, status, position, priority, urgency (most urgent first) or field:<id> for a custom field; prefix with - for descending"),
+		QueryParam("status", "string", "Only tasks in this status"),
+		QueryParam("project_id", "string", "Only tasks in this project"),
+		QueryParam("assignee", "string", `Only tasks assigned to this user ID, to the caller with "me", or to nobody with "none"`),
+		QueryParam("due_before", "string", "RFC 3339 timestamp or YYYY-MM-DD date"),
+		QueryParam("due_after", "string", "RFC 3339 timestamp or YYYY-MM-DD date"),
+		QueryParam("tag", "string", "Only tasks with this tag ID; repeat or comma-separate to require several"),
+		QueryParam("archived", "boolean", "true for only archived tasks, false for only the others; lists leave them out and search includes them by default"),
+		QueryParam("field.{id}", "string", "Only tasks whose custom field {id} holds this value"),
+	}
+}
+
+func pageParams() []Parameter {
+	return []Parameter{
+		QueryParam("limit", "integer", "Page size, default 50, maximum 200"),
+		QueryParam("cursor", "string", "next_cursor from the previous page"),
+	}
+}
+
+// searchParams are the task list filters minus sort, which search replaces
+// with ranking.
+func searchParams() []Parameter {
+	params := []Parameter{QueryParam("q", "string", "Words to search for; the last also matches as a prefix")}
+	for _, p := range taskListParams() {
+		if p.Name != "sort" {
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+// boardImportParams are the parameters of the board import routes.
+func boardImportParams() []Parameter {
+	return []Parameter{
+		QueryParam("dry_run", "boolean", "Answer 200 with what the import would create, as dry_run, source, total and summary, without importing"),
+	}
+}
+
+// auditParams are the filters of the audit log routes.
+func auditParams() []Parameter {
+	return []Parameter{
+		QueryParam("from", "string", "Only events at or after this RFC 3339 timestamp or YYYY-MM-DD date"),
+		QueryParam("to", "string", "Only events before this RFC 3339 timestamp or YYYY-MM-DD date"),
+		QueryParam("action", "string", "Only events of this action, such as auth.login_failed"),
+		QueryParam("actor", "string", "Only events by this user ID"),
+		QueryParam("org_id", "string", "Only events in this organization"),
+	}
+}
+
+// scimListParams are the filter and paging of the SCIM list routes.
+func scimListParams() []Parameter {
+	return []Parameter{
+		QueryParam("filter", "string", `Only resources with an attribute equal to a value, as in userName eq "sam@example.com"`),
+		QueryParam("startIndex", "integer", "Position of the first resource, counting from 1"),
+		QueryParam("count", "integer", "Page size, default and maximum 200"),
+	}
+}
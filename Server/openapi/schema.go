@@ -0,0 +1,133 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// enums lists the allowed values of string types the generator should
+// render as enumerations.
+var enums = map[reflect.Type][]string{}
+
+// RegisterEnum declares the values allowed for the string type of zero.
+func RegisterEnum[T ~string](zero T, values ...T) {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = string(v)
+	}
+	enums[reflect.TypeOf(zero)] = s
+}
+
+// generator turns Go types into schemas, collecting named structs under
+// components/schemas.
+type generator struct {
+	schemas map[string]*Schema
+}
+
+func newGenerator() *generator {
+	return &generator{schemas: map[string]*Schema{}}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *generator) schemaOf(v any) *Schema {
+	if v == nil {
+		return nil
+	}
+	return g.schema(reflect.TypeOf(v))
+}
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	if vals, ok := enums[t]; ok {
+		return &Schema{Type: "string", Enum: vals}
+	}
+	if isOptional(t) {
+		f, _ := t.FieldByName("Value")
+		return nullable(g.schema(f.Type))
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(g.schema(t.Elem()))
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		if _, ok := g.schemas[t.Name()]; !ok {
+			g.schemas[t.Name()] = &Schema{} // placeholder for recursive types
+			g.schemas[t.Name()] = g.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	}
+	return &Schema{}
+}
+
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			embedded := g.object(f.Type)
+			for k, v := range embedded.Properties {
+				s.Properties[k] = v
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = g.schema(f.Type)
+		// Bodies decoded by the handlers treat omitempty fields as optional.
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer && !isOptional(f.Type) {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+// isOptional reports whether t is an instantiation of model.Optional, which
+// is encoded as its Value or null.
+func isOptional(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && strings.HasSuffix(t.PkgPath(), "/model") &&
+		strings.HasPrefix(t.Name(), "Optional[")
+}
+
+func nullable(s *Schema) *Schema {
+	if s.Ref != "" {
+		// OpenAPI 3.0 ignores siblings of $ref, so wrap it.
+		return &Schema{Nullable: true, AllOf: []*Schema{s}}
+	}
+	c := *s
+	c.Nullable = true
+	return &c
+}
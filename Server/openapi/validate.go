@@ -0,0 +1,280 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"starttech-server/apierror"
+	"starttech-server/model"
+)
+
+// Validate returns middleware that checks each request against the
+// operation routes document for it, found by the pattern the request
+// matched: that its path and query parameters have their types, and that
+// a JSON body has the fields of the request schema, of their types, and no
+// others. Requests that fail are answered 400 validation_failed with every
+// invalid field in details, so handlers can rely on the shape of their
+// input and need only check its meaning. Empty bodies, bodies that are not
+// JSON and the bodies of Streamed routes are left to the handlers. It must
+// be mounted on routes, inside the mux, for the pattern to be known.
+func Validate(routes []Route) func(http.Handler) http.Handler {
+	doc := Build(routes)
+	v := validator{schemas: doc.Components.Schemas, ops: map[string]*Operation{}, streamed: map[string]bool{}}
+	for _, r := range routes {
+		pattern := r.Method + " " + r.Path
+		v.ops[pattern] = (*doc.Paths[r.Path])[strings.ToLower(r.Method)]
+		v.streamed[pattern] = r.Streamed
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			op := v.ops[r.Pattern]
+			if op == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			var errs model.ValidationError
+			v.params(&errs, op, r)
+			if op.RequestBody != nil && !v.streamed[r.Pattern] {
+				if !v.body(w, &errs, op, r) {
+					return
+				}
+			}
+			if len(errs.Fields) > 0 {
+				apierror.WriteError(w, http.StatusBadRequest, apierror.Error{
+					Code: apierror.CodeValidation, Message: "validation failed", Details: errs.Fields,
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type validator struct {
+	schemas  map[string]*Schema
+	ops      map[string]*Operation
+	streamed map[string]bool
+}
+
+// params checks the path and query parameters of r. Empty query values
+// count as absent, as the handlers treat them, and parameters the
+// operation does not name are let through.
+func (v validator) params(errs *model.ValidationError, op *Operation, r *http.Request) {
+	query := r.URL.Query()
+	for _, p := range op.Parameters {
+		var values []string
+		switch p.In {
+		case "path":
+			values = []string{r.PathValue(p.Name)}
+		case "query":
+			values = query[p.Name]
+		}
+		for _, s := range values {
+			if s == "" {
+				continue
+			}
+			if msg := checkParam(p.Schema, s); msg != "" {
+				errs.Add(p.Name, msg)
+				break
+			}
+		}
+	}
+}
+
+func checkParam(s *Schema, value string) string {
+	var err error
+	switch s.Type {
+	case "integer":
+		_, err = strconv.ParseInt(value, 10, 64)
+	case "number":
+		_, err = strconv.ParseFloat(value, 64)
+	case "boolean":
+		_, err = strconv.ParseBool(value)
+	case "string":
+		return checkString(s, value)
+	}
+	if err != nil {
+		return "must be " + typeName(s.Type)
+	}
+	return ""
+}
+
+// body checks the JSON body of r against the request schema of op, leaving
+// it to be read again by the handler. It returns false if it has answered
+// the request itself, because the body could not be read.
+func (v validator) body(w http.ResponseWriter, errs *model.ValidationError, op *Operation, r *http.Request) bool {
+	media, ok := op.RequestBody.Content["application/json"]
+	if !ok || r.Body == nil || r.Body == http.NoBody {
+		return true
+	}
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		if t, _, err := mime.ParseMediaType(ct); err != nil || t != "application/json" && !strings.HasSuffix(t, "+json") {
+			return true
+		}
+	}
+	b, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	var tooBig *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooBig):
+		apierror.Write(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request bodies may be at most %d bytes", tooBig.Limit))
+		return false
+	case err != nil:
+		apierror.Write(w, http.StatusBadRequest, "reading the request body failed")
+		return false
+	}
+	r.Body = io.NopCloser(bytes.NewReader(b))
+	if len(bytes.TrimSpace(b)) == 0 {
+		return true
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.Error{Code: apierror.CodeInvalidJSON, Message: "invalid JSON body"})
+		return false
+	}
+	v.check(errs, media.Schema, value, "")
+	return true
+}
+
+// check adds a problem to errs for each part of value, found at field,
+// that does not match s.
+func (v validator) check(errs *model.ValidationError, s *Schema, value any, field string) {
+	if s.Ref != "" {
+		s = v.schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	if value == nil {
+		if !s.Nullable && (s.Type != "" || len(s.AllOf) > 0) {
+			errs.Add(label(field), "must not be null")
+		}
+		return
+	}
+	for _, sub := range s.AllOf {
+		v.check(errs, sub, value, field)
+	}
+
+	msg := ""
+	switch s.Type {
+	case "string":
+		if str, ok := value.(string); !ok {
+			msg = "must be a string"
+		} else {
+			msg = checkString(s, str)
+		}
+	case "integer":
+		if n, ok := value.(json.Number); !ok {
+			msg = "must be an integer"
+		} else if _, err := n.Int64(); err != nil {
+			msg = "must be an integer"
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			msg = "must be a number"
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			msg = "must be true or false"
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			msg = "must be an array"
+			break
+		}
+		for i, item := range items {
+			v.check(errs, s.Items, item, fmt.Sprintf("%s[%d]", field, i))
+		}
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			msg = "must be an object"
+			break
+		}
+		v.object(errs, s, obj, field)
+	}
+	if msg != "" {
+		errs.Add(label(field), msg)
+	}
+}
+
+// object checks the fields of obj. The handlers decode bodies into structs
+// that refuse fields they do not have, so those are reported too.
+func (v validator) object(errs *model.ValidationError, s *Schema, obj map[string]any, field string) {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			errs.Add(join(field, name), "is required")
+		}
+	}
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		switch {
+		case s.Properties[name] != nil:
+			v.check(errs, s.Properties[name], obj[name], join(field, name))
+		case s.AdditionalProperties != nil:
+			v.check(errs, s.AdditionalProperties, obj[name], join(field, name))
+		case s.Properties != nil:
+			errs.Add(join(field, name), "is not a field")
+		}
+	}
+}
+
+func checkString(s *Schema, value string) string {
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, value) {
+		return "must be one of " + strings.Join(s.Enum, ", ")
+	}
+	switch s.Format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339Nano, value); err != nil {
+			return "must be an RFC 3339 timestamp"
+		}
+	case "byte":
+		if _, err := base64.StdEncoding.DecodeString(value); err != nil {
+			return "must be base64"
+		}
+	}
+	return ""
+}
+
+func typeName(typ string) string {
+	switch typ {
+	case "integer":
+		return "an integer"
+	case "number":
+		return "a number"
+	case "boolean":
+		return "true or false"
+	}
+	return "a " + typ
+}
+
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+// label names field in an error; a problem with the body as a whole is
+// reported against "body".
+func label(field string) string {
+	if field == "" {
+		return "body"
+	}
+	return field
+}
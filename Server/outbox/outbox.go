@@ -0,0 +1,174 @@
+// Package outbox delivers the events of changes reliably. A change writes
+// its events to the store's outbox in its own transaction, so they are kept
+// exactly when the change is committed; a Relay then hands them on and
+// deletes them. Events that a process did not get to deliver before it
+// stopped are picked up by the next Relay to look, so every event is
+// delivered at least once.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/metrics"
+	"starttech-server/model"
+	"starttech-server/storage"
+	"starttech-server/tracing"
+)
+
+const (
+	// batchSize bounds the entries claimed at once.
+	batchSize = 100
+	// lease is how long a Relay holds the entries it claimed before
+	// another may take them over.
+	lease = 30 * time.Second
+)
+
+var relayed = metrics.NewCounterVec("outbox_events_total",
+	"Events taken from the outbox, by result: delivered, retrying or dropped.", "result")
+
+// Recorder keeps an event durably, as the webhook dispatcher does by
+// storing a delivery for each webhook. An event leaves the outbox only once
+// every Recorder has kept it.
+type Recorder interface {
+	Record(ctx context.Context, e events.Event) error
+}
+
+// Relay takes events out of the outbox, oldest first, has every Recorder
+// keep them and then publishes them, to the realtime hub among others.
+type Relay struct {
+	store     storage.OutboxStore
+	events    events.Publisher
+	recorders []Recorder
+	wake      chan struct{}
+
+	// Interval between checks for events written by other instances, or
+	// left behind by one that stopped; it defaults to five seconds. Events
+	// announced with Notify are relayed at once.
+	Interval time.Duration
+}
+
+// NewRelay returns a Relay that delivers the events in store to p and
+// recorders; call Run to start it.
+func NewRelay(store storage.OutboxStore, p events.Publisher, recorders ...Recorder) *Relay {
+	return &Relay{
+		store:     store,
+		events:    p,
+		recorders: recorders,
+		wake:      make(chan struct{}, 1),
+		Interval:  5 * time.Second,
+	}
+}
+
+// Write adds evs to the outbox of store, which should be the transaction
+// making the change they announce.
+func Write(ctx context.Context, store storage.OutboxStore, evs []events.Event) error {
+	if len(evs) == 0 {
+		return nil
+	}
+	entries := make([]model.OutboxEntry, len(evs))
+	for i, e := range evs {
+		data, err := json.Marshal(e.Data)
+		if err != nil {
+			return fmt.Errorf("outbox: encoding %s: %w", e.Type, err)
+		}
+		entries[i] = model.OutboxEntry{
+			Seq:         i,
+			Type:        string(e.Type),
+			OrgID:       e.OrgID,
+			Recipients:  e.Recipients,
+			Data:        data,
+			TraceParent: e.TraceParent,
+			CreatedAt:   e.Time,
+		}
+	}
+	return store.AppendOutbox(ctx, entries)
+}
+
+// Notify wakes Run to relay the events of a change that has just been
+// committed.
+func (r *Relay) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run relays events until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		// A full batch suggests more are waiting.
+		for r.relay(ctx) == batchSize {
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// relay delivers one batch of entries and returns how many it delivered.
+// It stops at the first that fails, so that events are not delivered out
+// of order; that one and those after it are retried once their lease runs
+// out.
+func (r *Relay) relay(ctx context.Context) int {
+	entries, err := r.store.ClaimOutbox(ctx, time.Now().UTC(), lease, batchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("outbox: claiming events", "err", err)
+		}
+		return 0
+	}
+	done := make([]string, 0, len(entries))
+	for _, en := range entries {
+		if err := r.deliver(ctx, en); err != nil {
+			relayed.With("retrying").Inc()
+			slog.Error("outbox: delivering event", "id", en.ID, "type", en.Type, "err", err)
+			break
+		}
+		done = append(done, en.ID)
+	}
+	if err := r.store.DeleteOutbox(ctx, done); err != nil {
+		// The entries are delivered again once their lease runs out.
+		slog.Error("outbox: deleting delivered events", "err", err)
+		return 0
+	}
+	return len(done)
+}
+
+// deliver hands en to the recorders, and then publishes it. An entry whose
+// data cannot be decoded is dropped.
+func (r *Relay) deliver(ctx context.Context, en model.OutboxEntry) error {
+	typ := events.Type(en.Type)
+	data, err := events.DecodeData(typ, en.Data)
+	if err != nil {
+		relayed.With("dropped").Inc()
+		slog.Error("outbox: dropping event", "id", en.ID, "type", en.Type, "err", err)
+		return nil
+	}
+	e := events.Event{
+		Type:        typ,
+		Time:        en.CreatedAt,
+		Data:        data,
+		OrgID:       en.OrgID,
+		Recipients:  en.Recipients,
+		TraceParent: en.TraceParent,
+	}
+	ctx = tracing.WithRemoteParent(ctx, e.TraceParent)
+	for _, rec := range r.recorders {
+		if err := rec.Record(ctx, e); err != nil {
+			return err
+		}
+	}
+	r.events.Publish(e)
+	relayed.With("delivered").Inc()
+	return nil
+}
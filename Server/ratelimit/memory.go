@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often Memory forgets buckets that have refilled.
+const sweepInterval = time.Minute
+
+// Memory keeps buckets and counts in process, so each server instance
+// limits and counts on its own.
+type Memory struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	counts    map[string]*count
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time // when tokens was last brought up to date
+	full   time.Time // when the bucket will be full again
+}
+
+type count struct {
+	n       int64
+	expires time.Time
+}
+
+// NewMemory returns a Memory with no buckets.
+func NewMemory() *Memory {
+	return &Memory{buckets: map[string]*bucket{}, counts: map[string]*count{}, lastSweep: time.Now()}
+}
+
+func (m *Memory) Allow(_ context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+	burst := float64(limit.Burst)
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst}
+		m.buckets[key] = b
+	} else {
+		b.tokens = min(burst, b.tokens+now.Sub(b.at).Seconds()*limit.Rate)
+	}
+	b.at = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = now.Add(seconds((burst - b.tokens) / limit.Rate))
+	if !allowed {
+		return false, seconds((1 - b.tokens) / limit.Rate), nil
+	}
+	return true, 0, nil
+}
+
+func (m *Memory) Incr(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+	c, ok := m.counts[key]
+	if !ok || !now.Before(c.expires) {
+		c = &count{expires: now.Add(ttl)}
+		m.counts[key] = c
+	}
+	c.n++
+	return c.n, nil
+}
+
+func (m *Memory) Count(_ context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c, ok := m.counts[key]; ok && time.Now().Before(c.expires) {
+		return c.n, nil
+	}
+	return 0, nil
+}
+
+// sweep forgets the buckets that have refilled and the counts that have
+// expired, at most once every sweepInterval. m.mu must be held.
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	// A full bucket is no different from a missing one.
+	for k, b := range m.buckets {
+		if !now.Before(b.full) {
+			delete(m.buckets, k)
+		}
+	}
+	for k, c := range m.counts {
+		if !now.Before(c.expires) {
+			delete(m.counts, k)
+		}
+	}
+	m.lastSweep = now
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryAllow(t *testing.T) {
+	m := NewMemory()
+	ctx := context.Background()
+	limit := PerMinute(60, 3) // a token a second, three at once
+
+	for i := range 3 {
+		if ok, wait, err := m.Allow(ctx, "a", limit); !ok || wait != 0 || err != nil {
+			t.Fatalf("request %d of the burst: %v, %v, %v", i+1, ok, wait, err)
+		}
+	}
+	ok, wait, err := m.Allow(ctx, "a", limit)
+	if ok || err != nil {
+		t.Fatalf("request past the burst: %v, %v", ok, err)
+	}
+	if wait <= 900*time.Millisecond || wait > time.Second {
+		t.Errorf("retry after %v, want just under a second", wait)
+	}
+	if ok, _, _ := m.Allow(ctx, "b", limit); !ok {
+		t.Error("another key shares the bucket")
+	}
+}
+
+func TestMemoryRefill(t *testing.T) {
+	m := NewMemory()
+	ctx := context.Background()
+	limit := Limit{Rate: 200, Burst: 2} // a token every 5ms
+
+	for range 2 {
+		m.Allow(ctx, "a", limit)
+	}
+	if ok, _, _ := m.Allow(ctx, "a", limit); ok {
+		t.Fatal("the empty bucket allowed a request")
+	}
+	time.Sleep(20 * time.Millisecond)
+	// The bucket refills up to its burst and no further.
+	for i := range 2 {
+		if ok, _, _ := m.Allow(ctx, "a", limit); !ok {
+			t.Fatalf("request %d after refilling was refused", i+1)
+		}
+	}
+	if ok, _, _ := m.Allow(ctx, "a", limit); ok {
+		t.Error("the bucket refilled past its burst")
+	}
+}
+
+func TestMemorySweep(t *testing.T) {
+	m := NewMemory()
+	ctx := context.Background()
+	m.Allow(ctx, "full", Limit{Rate: 1000, Burst: 1})
+	m.Allow(ctx, "empty", PerMinute(1, 1))
+
+	time.Sleep(5 * time.Millisecond)
+	m.lastSweep = time.Now().Add(-sweepInterval)
+	m.Allow(ctx, "other", PerMinute(1, 1))
+
+	if m.buckets["full"] != nil {
+		t.Error("a full bucket was not swept")
+	}
+	if m.buckets["empty"] == nil {
+		t.Error("a bucket still refilling was swept")
+	}
+	if ok, _, _ := m.Allow(ctx, "empty", PerMinute(1, 1)); ok {
+		t.Error("the swept store forgot an empty bucket")
+	}
+}
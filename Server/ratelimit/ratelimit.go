@@ -0,0 +1,112 @@
+// Package ratelimit throttles requests with token buckets. Every key has a
+// bucket holding up to Burst tokens that refills at Rate tokens a second;
+// each request takes one, and requests finding the bucket empty are
+// refused until it refills.
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"starttech-server/apierror"
+	"starttech-server/metrics"
+)
+
+// Limit sizes a bucket.
+type Limit struct {
+	Rate  float64 // tokens added per second
+	Burst int
+}
+
+// PerMinute returns the limit allowing n requests a minute on average and
+// burst at once.
+func PerMinute(n, burst int) Limit {
+	return Limit{Rate: float64(n) / 60, Burst: burst}
+}
+
+// Limiter keeps the buckets.
+type Limiter interface {
+	// Allow takes a token from the bucket of key. If there is none it
+	// reports how long until there will be.
+	Allow(ctx context.Context, key string, limit Limit) (ok bool, retryAfter time.Duration, err error)
+}
+
+// Counter counts events in fixed windows, such as the requests of a day.
+type Counter interface {
+	// Incr adds one to the count of key and returns the new count. A count
+	// is forgotten ttl after it started.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// Count returns the count of key, 0 once it is forgotten.
+	Count(ctx context.Context, key string) (int64, error)
+}
+
+var rateLimited = metrics.NewCounterVec("rate_limited_total",
+	"Requests refused by a rate limit, by limit group.", "group")
+
+// Middleware limits the requests of each key, as returned by key, to limit,
+// answering the excess with 429 Too Many Requests and a Retry-After header.
+// Buckets are kept per group, so routes limited by separate groups do not
+// draw on each other. Requests whose key is empty are not limited, nor is
+// anything when l is nil or the limit's burst is zero. If the limiter
+// fails, requests are let through rather than refused.
+func Middleware(l Limiter, group string, limit Limit, key func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l == nil || limit.Burst <= 0 || limit.Rate <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ok, wait, err := l.Allow(r.Context(), group+":"+k, limit)
+			if err != nil {
+				slog.WarnContext(r.Context(), "rate limiter unavailable", "group", group, "err", err)
+				ok = true
+			}
+			if !ok {
+				rateLimited.With(group).Inc()
+				retry := int(math.Ceil(wait.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(retry))
+				apierror.WriteError(w, http.StatusTooManyRequests, apierror.Error{
+					Message: "too many requests",
+					Details: map[string]int{"retry_after": retry},
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP keys requests by the address they came from. Behind a reverse
+// proxy, when trustProxy is set, that is the last address of the
+// X-Forwarded-For header, the one the proxy added; earlier ones are
+// whatever the client sent.
+func ClientIP(trustProxy bool) func(*http.Request) string {
+	return func(r *http.Request) string {
+		if trustProxy {
+			if fwd := r.Header.Values("X-Forwarded-For"); len(fwd) > 0 {
+				last := fwd[len(fwd)-1]
+				if i := strings.LastIndexByte(last, ','); i >= 0 {
+					last = last[i+1:]
+				}
+				if ip := strings.TrimSpace(last); ip != "" {
+					return ip
+				}
+			}
+		}
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr
+		}
+		return host
+	}
+}
@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// failing is a Limiter whose store is down.
+type failing struct{}
+
+func (failing) Allow(context.Context, string, Limit) (bool, time.Duration, error) {
+	return false, 0, errors.New("down")
+}
+
+func TestMiddleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	byHeader := func(r *http.Request) string { return r.Header.Get("X-Key") }
+	limit := PerMinute(1, 1)
+
+	tests := []struct {
+		name    string
+		limiter Limiter
+		limit   Limit
+		keys    []string
+		want    []int
+	}{
+		{"burst then refused", NewMemory(), limit, []string{"a", "a"}, []int{200, 429}},
+		{"keys apart", NewMemory(), limit, []string{"a", "b"}, []int{200, 200}},
+		{"empty key unlimited", NewMemory(), limit, []string{"", ""}, []int{200, 200}},
+		{"no limiter", nil, limit, []string{"a", "a"}, []int{200, 200}},
+		{"zero burst", NewMemory(), Limit{Rate: 1}, []string{"a", "a"}, []int{200, 200}},
+		{"limiter down", failing{}, limit, []string{"a", "a"}, []int{200, 200}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Middleware(tt.limiter, "test", tt.limit, byHeader)(ok)
+			for i, k := range tt.keys {
+				r := httptest.NewRequest("GET", "/", nil)
+				r.Header.Set("X-Key", k)
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+				if w.Code != tt.want[i] {
+					t.Fatalf("request %d: status %d, want %d", i+1, w.Code, tt.want[i])
+				}
+				if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "60" {
+					t.Errorf("Retry-After = %q, want 60", w.Header().Get("Retry-After"))
+				}
+			}
+		})
+	}
+
+	// Groups keep their own buckets.
+	m := NewMemory()
+	first := Middleware(m, "one", limit, byHeader)(ok)
+	second := Middleware(m, "two", limit, byHeader)(ok)
+	for _, h := range []http.Handler{first, second} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("X-Key", "a")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("status %d in a group of its own, want 200", w.Code)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy bool
+		remote     string
+		forwarded  []string
+		want       string
+	}{
+		{"remote address", false, "192.0.2.1:1234", nil, "192.0.2.1"},
+		{"forwarded for ignored", false, "192.0.2.1:1234", []string{"198.51.100.7"}, "192.0.2.1"},
+		{"proxy's entry", true, "10.0.0.1:1234", []string{"203.0.113.9, 198.51.100.7"}, "198.51.100.7"},
+		{"last header", true, "10.0.0.1:1234", []string{"203.0.113.9", "198.51.100.7"}, "198.51.100.7"},
+		{"empty header", true, "10.0.0.1:1234", []string{" "}, "10.0.0.1"},
+		{"no port", false, "192.0.2.1", nil, "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			for _, f := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", f)
+			}
+			if got := ClientIP(tt.trustProxy)(r); got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
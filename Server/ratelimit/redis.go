@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"starttech-server/redis"
+)
+
+// Redis keeps buckets and counts in a Redis server, so every instance
+// using it draws on the same ones. Buckets expire once they have refilled.
+type Redis struct {
+	Client *redis.Client
+}
+
+// takeToken refills and draws on the bucket in KEYS[1] by Redis's own
+// clock, so instances with skewed clocks agree. ARGV holds the rate per
+// second and the burst. It returns whether a token was taken and, if not,
+// the milliseconds until one can be.
+const takeToken = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'at')
+local tokens = tonumber(b[1])
+if tokens == nil then
+  tokens = burst
+else
+  tokens = math.min(burst, tokens + (now - tonumber(b[2])) * rate / 1000)
+end
+local allowed, wait = 0, 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'at', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) * 1000 / rate) + 1000)
+return {allowed, wait}
+`
+
+func (l *Redis) Allow(ctx context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	reply, err := l.Client.Do(ctx, "EVAL", takeToken, 1, "ratelimit:"+key, limit.Rate, limit.Burst)
+	if err != nil {
+		return false, 0, err
+	}
+	items, ok := reply.([]any)
+	if !ok || len(items) != 2 {
+		return false, 0, fmt.Errorf("ratelimit: unexpected reply %v", reply)
+	}
+	allowed, _ := items[0].(int64)
+	wait, _ := items[1].(int64)
+	return allowed == 1, time.Duration(wait) * time.Millisecond, nil
+}
+
+// incrCount adds one to the count in KEYS[1] and, when that starts it,
+// sets it to expire after ARGV[1] milliseconds.
+const incrCount = `
+local n = redis.call('INCR', KEYS[1])
+if n == 1 then
+  redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return n
+`
+
+func (l *Redis) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	reply, err := l.Client.Do(ctx, "EVAL", incrCount, 1, "count:"+key, ttl.Milliseconds())
+	if err != nil {
+		return 0, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("ratelimit: unexpected reply %v", reply)
+	}
+	return n, nil
+}
+
+func (l *Redis) Count(ctx context.Context, key string) (int64, error) {
+	reply, err := l.Client.Do(ctx, "GET", "count:"+key)
+	if err != nil {
+		return 0, err
+	}
+	switch v := reply.(type) {
+	case nil:
+		return 0, nil
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	}
+	return 0, fmt.Errorf("ratelimit: unexpected reply %v", reply)
+}
@@ -0,0 +1,149 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/nats"
+	"starttech-server/redis"
+)
+
+// Broker relays events between the hubs of every server instance.
+type Broker interface {
+	// Publish sends payload to every subscriber, on this instance too.
+	Publish(ctx context.Context, payload []byte) error
+	// Subscribe calls fn with every payload published until ctx is done
+	// or the connection is lost.
+	Subscribe(ctx context.Context, fn func(payload []byte)) error
+}
+
+// RedisBroker relays events over a Redis pub/sub channel.
+type RedisBroker struct {
+	Client  *redis.Client
+	Channel string
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, payload []byte) error {
+	_, err := b.Client.Do(ctx, "PUBLISH", b.Channel, payload)
+	return err
+}
+
+func (b *RedisBroker) Subscribe(ctx context.Context, fn func([]byte)) error {
+	return b.Client.Subscribe(ctx, b.Channel, fn)
+}
+
+// NATSBroker relays events over a NATS subject.
+type NATSBroker struct {
+	Client  *nats.Client
+	Subject string
+}
+
+func (b *NATSBroker) Publish(ctx context.Context, payload []byte) error {
+	return b.Client.Publish(ctx, b.Subject, payload)
+}
+
+func (b *NATSBroker) Subscribe(ctx context.Context, fn func([]byte)) error {
+	return b.Client.Subscribe(ctx, b.Subject, fn)
+}
+
+const (
+	// publishTimeout bounds handing one event to the Broker.
+	publishTimeout = 5 * time.Second
+	// The wait before listening to the Broker again after losing it
+	// doubles from minRetry up to maxRetry.
+	minRetry = time.Second
+	maxRetry = 30 * time.Second
+)
+
+// envelope is an event as it travels through the Broker, with its
+// recipients, which clients are not shown.
+type envelope struct {
+	Type       events.Type     `json:"type"`
+	Time       time.Time       `json:"time"`
+	Data       json.RawMessage `json:"data"`
+	OrgID      string          `json:"org_id,omitempty"`
+	Recipients []string        `json:"recipients"`
+}
+
+// relay hands the events in the outbox to the Broker until ctx is done.
+// An event the Broker does not take is delivered here alone.
+func (h *Hub) relay(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-h.outbox:
+			data, err := json.Marshal(e.Data)
+			if err != nil {
+				slog.Error("realtime: encoding event", "type", e.Type, "err", err)
+				continue
+			}
+			payload, _ := json.Marshal(envelope{Type: e.Type, Time: e.Time, Data: data, OrgID: e.OrgID, Recipients: e.Recipients})
+			pctx, cancel := context.WithTimeout(ctx, publishTimeout)
+			err = h.Broker.Publish(pctx, payload)
+			cancel()
+			if err != nil && ctx.Err() == nil {
+				slog.Warn("realtime: broker unavailable; event only reaches this instance", "type", e.Type, "err", err)
+				select {
+				case h.broadcast <- e:
+				default:
+					slog.Warn("realtime: dropping event, hub is backed up", "type", e.Type)
+				}
+			}
+		}
+	}
+}
+
+// listen feeds the events relayed by the Broker to Run until ctx is done,
+// subscribing again whenever the subscription is lost.
+func (h *Hub) listen(ctx context.Context) {
+	wait := minRetry
+	for {
+		h.subscribed.Store(true)
+		start := time.Now()
+		err := h.Broker.Subscribe(ctx, func(payload []byte) {
+			var env envelope
+			if err := json.Unmarshal(payload, &env); err != nil {
+				slog.Warn("realtime: skipping malformed event from broker", "err", err)
+				return
+			}
+			data, err := events.DecodeData(env.Type, env.Data)
+			if err != nil {
+				slog.Warn("realtime: skipping event from broker", "err", err)
+				return
+			}
+			e := events.Event{Type: env.Type, Time: env.Time, Data: data, OrgID: env.OrgID, Recipients: env.Recipients}
+			select {
+			case h.broadcast <- e:
+			case <-ctx.Done():
+			}
+		})
+		h.subscribed.Store(false)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > maxRetry {
+			wait = minRetry
+		}
+		slog.Warn("realtime: lost the broker; events from other instances are missed until it is back", "err", err, "retry_in", wait.String())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		wait = min(2*wait, maxRetry)
+	}
+}
+
+// Ready reports whether the hub hears the events of other instances: that
+// it is listening to its Broker, if it has one.
+func (h *Hub) Ready(ctx context.Context) error {
+	if h.Broker != nil && !h.subscribed.Load() {
+		return errors.New("realtime: not subscribed to the broker")
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+// Package realtime pushes change events to connected clients over
+// WebSockets (/ws) and Server-Sent Events (/events).
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"starttech-server/events"
+)
+
+const (
+	// sendBuffer is the number of events queued per client before it is
+	// considered too slow and disconnected.
+	sendBuffer = 64
+	// historySize is how many recent events are kept for clients resuming
+	// with Last-Event-ID.
+	historySize = 1024
+)
+
+// StreamReset is sent in place of a replay when a resuming client has
+// missed more events than the hub remembers; it should refetch its data.
+const StreamReset events.Type = "stream.reset"
+
+// message is an encoded event ready to be written to any transport.
+type message struct {
+	id         uint64
+	typ        events.Type
+	data       []byte
+	orgID      string
+	recipients []string
+	// event is what data encodes, for Subscription.
+	event events.Event
+}
+
+// client is one subscriber connection belonging to userID and working in
+// orgID.
+type client struct {
+	userID string
+	orgID  string
+	send   chan message
+	// lastID is the Last-Event-ID the client resumed from, or 0.
+	lastID uint64
+}
+
+// Hub fans events out to the connections of their recipients. All
+// subscription state is owned by the Run goroutine.
+//
+// With a Broker, events published on any instance are relayed through it
+// to the hubs of every instance, this one included, so each delivers them
+// to its own clients. Event IDs are still assigned by each hub, so a client
+// resuming on another instance than before is sent a StreamReset.
+type Hub struct {
+	// Broker, if set before Run, relays events between instances.
+	Broker Broker
+
+	register   chan *client
+	unregister chan *client
+	broadcast  chan events.Event
+	// outbox holds the events waiting to be handed to the Broker.
+	outbox chan events.Event
+	// subscribed is whether the hub is listening to the Broker.
+	subscribed atomic.Bool
+	// done is closed when Run returns.
+	done chan struct{}
+
+	// The fields below are only touched by Run.
+	clients map[string]map[*client]struct{}
+	seq     uint64
+	history []message // ring buffer, oldest first once full
+}
+
+// NewHub returns a Hub; call Run to start it.
+func NewHub() *Hub {
+	return &Hub{
+		register:   make(chan *client),
+		unregister: make(chan *client),
+		broadcast:  make(chan events.Event, 256),
+		outbox:     make(chan events.Event, 256),
+		done:       make(chan struct{}),
+		clients:    make(map[string]map[*client]struct{}),
+		// Seed the sequence from the clock so IDs keep increasing across
+		// restarts and stale Last-Event-IDs are detected as gaps.
+		seq: uint64(time.Now().UnixMicro()),
+	}
+}
+
+// Publish queues e for delivery, through the Broker if there is one. It
+// implements events.Publisher.
+func (h *Hub) Publish(e events.Event) {
+	queue := h.broadcast
+	if h.Broker != nil {
+		queue = h.outbox
+	}
+	select {
+	case queue <- e:
+	default:
+		slog.Warn("realtime: dropping event, hub is backed up", "type", e.Type)
+	}
+}
+
+// Run delivers events until ctx is canceled, then disconnects every client.
+func (h *Hub) Run(ctx context.Context) {
+	defer close(h.done)
+	if h.Broker != nil {
+		go h.relay(ctx)
+		go h.listen(ctx)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			for _, set := range h.clients {
+				for c := range set {
+					close(c.send)
+				}
+			}
+			h.clients = map[string]map[*client]struct{}{}
+			return
+
+		case c := <-h.register:
+			h.add(c)
+
+		case c := <-h.unregister:
+			h.remove(c)
+
+		case e := <-h.broadcast:
+			h.seq++
+			e.ID = h.seq
+			data, err := json.Marshal(e)
+			if err != nil {
+				slog.Error("realtime: encoding event", "type", e.Type, "err", err)
+				continue
+			}
+			m := message{id: e.ID, typ: e.Type, data: data, orgID: e.OrgID, recipients: e.Recipients, event: e}
+			h.remember(m)
+			for _, userID := range m.recipients {
+				for c := range h.clients[userID] {
+					if c.inOrg(m) {
+						h.deliver(c, m)
+					}
+				}
+			}
+		}
+	}
+}
+
+func (h *Hub) add(c *client) {
+	set := h.clients[c.userID]
+	if set == nil {
+		set = make(map[*client]struct{})
+		h.clients[c.userID] = set
+	}
+	set[c] = struct{}{}
+	if c.lastID != 0 {
+		h.replay(c)
+	}
+}
+
+func (h *Hub) remove(c *client) {
+	set := h.clients[c.userID]
+	if _, ok := set[c]; !ok {
+		return
+	}
+	delete(set, c)
+	close(c.send)
+	if len(set) == 0 {
+		delete(h.clients, c.userID)
+	}
+}
+
+// deliver queues m for c, dropping c if it cannot keep up so it reconnects
+// and resumes from its last event.
+func (h *Hub) deliver(c *client, m message) {
+	select {
+	case c.send <- m:
+	default:
+		h.remove(c)
+	}
+}
+
+func (h *Hub) remember(m message) {
+	if len(h.history) == historySize {
+		copy(h.history, h.history[1:])
+		h.history = h.history[:historySize-1]
+	}
+	h.history = append(h.history, m)
+}
+
+// replay sends c the events it missed since c.lastID, or a StreamReset if
+// some of them have already been forgotten.
+func (h *Hub) replay(c *client) {
+	if len(h.history) == 0 || c.lastID >= h.seq {
+		if c.lastID > h.seq {
+			h.reset(c)
+		}
+		return
+	}
+	if c.lastID+1 < h.history[0].id {
+		h.reset(c)
+		return
+	}
+	for _, m := range h.history {
+		if m.id <= c.lastID || !slices.Contains(m.recipients, c.userID) || !c.inOrg(m) {
+			continue
+		}
+		h.deliver(c, m)
+	}
+}
+
+func (h *Hub) reset(c *client) {
+	e := events.Event{ID: h.seq, Type: StreamReset, Time: time.Now().UTC()}
+	data, _ := json.Marshal(e)
+	h.deliver(c, message{id: h.seq, typ: StreamReset, data: data, event: e})
+}
+
+// inOrg reports whether m happened in the organization c works in.
+func (c *client) inOrg(m message) bool {
+	return m.orgID == "" || m.orgID == c.orgID
+}
+
+// subscribe registers a client for userID in orgID resuming after lastID. It
+// returns nil once the hub has stopped.
+func (h *Hub) subscribe(userID, orgID string, lastID uint64) *client {
+	c := &client{userID: userID, orgID: orgID, lastID: lastID, send: make(chan message, sendBuffer)}
+	select {
+	case h.register <- c:
+		return c
+	case <-h.done:
+		return nil
+	}
+}
+
+func (h *Hub) unsubscribe(c *client) {
+	select {
+	case h.unregister <- c:
+	case <-h.done:
+	}
+}
+
+// Subscription receives the events of one user, for transports outside
+// this package such as gRPC streams.
+type Subscription struct {
+	hub *Hub
+	c   *client
+}
+
+// Subscribe starts delivering the events of userID in orgID, first
+// replaying those after lastID unless it is 0. It returns nil once the hub
+// has stopped. The subscription must be closed.
+func (h *Hub) Subscribe(userID, orgID string, lastID uint64) *Subscription {
+	c := h.subscribe(userID, orgID, lastID)
+	if c == nil {
+		return nil
+	}
+	return &Subscription{hub: h, c: c}
+}
+
+// Next waits for the next event. It returns false once ctx is done, the hub
+// has stopped, or the subscriber fell so far behind that it was dropped and
+// should resubscribe from its last event.
+func (s *Subscription) Next(ctx context.Context) (events.Event, bool) {
+	select {
+	case m, ok := <-s.c.send:
+		return m.event, ok
+	case <-ctx.Done():
+		return events.Event{}, false
+	}
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() {
+	s.hub.unsubscribe(s.c)
+}
@@ -0,0 +1,83 @@
+package realtime
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"starttech-server/apierror"
+	"starttech-server/auth"
+)
+
+// heartbeatPeriod keeps idle SSE connections from being closed by proxies.
+const heartbeatPeriod = 25 * time.Second
+
+// ServeSSE streams the authenticated user's events as Server-Sent Events.
+// Clients resume after a disconnect by sending Last-Event-ID, which
+// EventSource does automatically; the last_event_id query parameter is
+// accepted for clients that cannot set headers.
+func (h *Hub) ServeSSE(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.UserID(r.Context())
+	orgID, _ := auth.OrgID(r.Context())
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	var resumeFrom uint64
+	if lastID != "" {
+		n, err := strconv.ParseUint(lastID, 10, 64)
+		if err != nil {
+			apierror.Write(w, http.StatusBadRequest, "invalid Last-Event-ID")
+			return
+		}
+		resumeFrom = n
+	}
+
+	rc := http.NewResponseController(w)
+	// The stream outlives the server's write timeout.
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	c := h.subscribe(userID, orgID, resumeFrom)
+	if c == nil {
+		apierror.Write(w, http.StatusServiceUnavailable, "server shutting down")
+		return
+	}
+	defer h.unsubscribe(c)
+
+	hdr := w.Header()
+	hdr.Set("Content-Type", "text/event-stream")
+	hdr.Set("Cache-Control", "no-cache")
+	hdr.Set("Connection", "keep-alive")
+	hdr.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	// Tell EventSource how long to wait before reconnecting.
+	fmt.Fprint(w, "retry: 3000\n\n")
+	rc.Flush()
+
+	ticker := time.NewTicker(heartbeatPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case m, ok := <-c.send:
+			if !ok {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", m.id, m.typ, m.data); err != nil {
+				return
+			}
+			rc.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			rc.Flush()
+		}
+	}
+}
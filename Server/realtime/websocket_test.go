@@ -0,0 +1,313 @@
+package realtime
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeConn is a connection whose writes are recorded.
+type fakeConn struct {
+	net.Conn
+	out    bytes.Buffer
+	closed bool
+}
+
+func (c *fakeConn) Write(b []byte) (int, error)      { return c.out.Write(b) }
+func (c *fakeConn) SetWriteDeadline(time.Time) error { return nil }
+func (c *fakeConn) Close() error                     { c.closed = true; return nil }
+
+// newTestConn returns a wsConn that reads the frames in in.
+func newTestConn(in []byte) (*wsConn, *fakeConn) {
+	fc := &fakeConn{}
+	return &wsConn{conn: fc, br: bufio.NewReader(bytes.NewReader(in))}, fc
+}
+
+// clientFrame returns a frame as a client sends it, masked.
+func clientFrame(fin bool, op byte, payload []byte) []byte {
+	b := []byte{op, 0x80}
+	if fin {
+		b[0] |= 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		b[1] |= byte(n)
+	case n <= 0xFFFF:
+		b[1] |= 126
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b[1] |= 127
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+	mask := [4]byte{0x37, 0xfa, 0x21, 0x3d}
+	b = append(b, mask[:]...)
+	for i, c := range payload {
+		b = append(b, c^mask[i%4])
+	}
+	return b
+}
+
+// serverFrame returns an unmasked, final frame as the server sends it.
+func serverFrame(op byte, payload []byte) []byte {
+	var c wsConn
+	fc := &fakeConn{}
+	c.conn = fc
+	c.writeFrame(op, payload)
+	return fc.out.Bytes()
+}
+
+func TestReadFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []byte
+		fin     bool
+		op      byte
+		payload []byte
+		err     error
+	}{
+		// RFC 6455, section 5.7: a masked "Hello".
+		{"rfc example", []byte{0x81, 0x85, 0x37, 0xfa, 0x21, 0x3d, 0x7f, 0x9f, 0x4d, 0x51, 0x58}, true, opText, []byte("Hello"), nil},
+		{"empty", clientFrame(true, opPing, nil), true, opPing, []byte{}, nil},
+		{"125 bytes", clientFrame(true, opBinary, bytes.Repeat([]byte{1}, 125)), true, opBinary, bytes.Repeat([]byte{1}, 125), nil},
+		{"16-bit length", clientFrame(false, opText, bytes.Repeat([]byte("a"), 126)), false, opText, bytes.Repeat([]byte("a"), 126), nil},
+		{"64-bit length", clientFrame(true, opText, bytes.Repeat([]byte("a"), maxMessageSize)), true, opText, bytes.Repeat([]byte("a"), maxMessageSize), nil},
+		{"too large", clientFrame(true, opText, bytes.Repeat([]byte("a"), maxMessageSize+1)), false, 0, nil, errMessageTooLarge},
+		{"unmasked", []byte{0x81, 0x05, 'H', 'e', 'l', 'l', 'o'}, false, 0, nil, errors.New("websocket: client frame is not masked")},
+		{"no header", nil, false, 0, nil, io.EOF},
+		{"short length", []byte{0x81, 0xfe, 0x00}, false, 0, nil, io.ErrUnexpectedEOF},
+		{"short mask", []byte{0x81, 0x81, 0x01}, false, 0, nil, io.ErrUnexpectedEOF},
+		{"short payload", []byte{0x81, 0x85, 0, 0, 0, 0, 'H'}, false, 0, nil, io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestConn(tt.in)
+			fin, op, payload, err := c.readFrame()
+			if tt.err != nil {
+				if err == nil || err.Error() != tt.err.Error() {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fin != tt.fin || op != tt.op || !bytes.Equal(payload, tt.payload) {
+				t.Errorf("got %v %d %q, want %v %d %q", fin, op, payload, tt.fin, tt.op, tt.payload)
+			}
+		})
+	}
+}
+
+func TestWriteFrame(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		hdr  []byte
+	}{
+		{"empty", 0, []byte{0x81, 0x00}},
+		{"7-bit length", 125, []byte{0x81, 125}},
+		{"16-bit length", 126, []byte{0x81, 126, 0x00, 0x7e}},
+		{"largest 16-bit length", 0xFFFF, []byte{0x81, 126, 0xff, 0xff}},
+		{"64-bit length", 0x10000, []byte{0x81, 127, 0, 0, 0, 0, 0, 1, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := bytes.Repeat([]byte("x"), tt.n)
+			got := serverFrame(opText, payload)
+			if !bytes.Equal(got[:len(tt.hdr)], tt.hdr) || !bytes.Equal(got[len(tt.hdr):], payload) {
+				t.Errorf("header % x, want % x", got[:min(len(got), 10)], tt.hdr)
+			}
+		})
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	frames := func(fs ...[]byte) []byte { return bytes.Join(fs, nil) }
+	tests := []struct {
+		name    string
+		in      []byte
+		op      byte
+		payload string
+		err     string
+		// out is what the server answers while reading.
+		out []byte
+	}{
+		{
+			name: "text", in: clientFrame(true, opText, []byte("hi")),
+			op: opText, payload: "hi",
+		},
+		{
+			name: "binary", in: clientFrame(true, opBinary, []byte{0, 1}),
+			op: opBinary, payload: "\x00\x01",
+		},
+		{
+			name: "fragmented",
+			in: frames(
+				clientFrame(false, opText, []byte("Hel")),
+				clientFrame(false, opContinuation, []byte("l")),
+				clientFrame(true, opContinuation, []byte("o")),
+			),
+			op: opText, payload: "Hello",
+		},
+		{
+			name: "ping between fragments",
+			in: frames(
+				clientFrame(false, opText, []byte("a")),
+				clientFrame(true, opPing, []byte("p")),
+				clientFrame(true, opContinuation, []byte("b")),
+			),
+			op: opText, payload: "ab", out: serverFrame(opPong, []byte("p")),
+		},
+		{
+			name: "pong is ignored",
+			in:   frames(clientFrame(true, opPong, nil), clientFrame(true, opText, []byte("x"))),
+			op:   opText, payload: "x",
+		},
+		{
+			name: "close is echoed",
+			in:   clientFrame(true, opClose, []byte{0x03, 0xe8}),
+			err:  io.EOF.Error(), out: serverFrame(opClose, []byte{0x03, 0xe8}),
+		},
+		{
+			name: "continuation without a message",
+			in:   clientFrame(true, opContinuation, []byte("x")),
+			err:  "websocket: unexpected continuation frame",
+		},
+		{
+			name: "unknown opcode",
+			in:   clientFrame(true, 0x3, nil),
+			err:  "websocket: unknown opcode 3",
+		},
+		{
+			name: "fragments too large together",
+			in: frames(
+				clientFrame(false, opText, bytes.Repeat([]byte("a"), maxMessageSize)),
+				clientFrame(true, opContinuation, []byte("a")),
+			),
+			err: errMessageTooLarge.Error(),
+		},
+		{
+			name: "connection ends mid-message",
+			in:   clientFrame(false, opText, []byte("a")),
+			err:  io.EOF.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, fc := newTestConn(tt.in)
+			op, payload, err := c.readMessage()
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("err = %v, want %s", err, tt.err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if op != tt.op || string(payload) != tt.payload {
+				t.Errorf("got %d %q, want %d %q", op, payload, tt.op, tt.payload)
+			}
+			if !bytes.Equal(fc.out.Bytes(), tt.out) {
+				t.Errorf("server wrote % x, want % x", fc.out.Bytes(), tt.out)
+			}
+		})
+	}
+}
+
+func TestClose(t *testing.T) {
+	c, fc := newTestConn(nil)
+	if err := c.close(); err != nil {
+		t.Fatal(err)
+	}
+	if want := serverFrame(opClose, []byte{0x03, 0xe8}); !bytes.Equal(fc.out.Bytes(), want) || !fc.closed {
+		t.Errorf("close wrote % x and closed=%v, want % x and true", fc.out.Bytes(), fc.closed, want)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	messages := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer c.conn.Close()
+		_, payload, err := c.readMessage()
+		if err != nil {
+			messages <- "error: " + err.Error()
+			return
+		}
+		messages <- string(payload)
+		c.writeFrame(opText, []byte("pong: "+string(payload)))
+	}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	// The handshake of RFC 6455, section 1.3.
+	io.WriteString(conn, "GET /ws HTTP/1.1\r\n"+
+		"Host: example.com\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: keep-alive, Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want 101", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept = %q", got)
+	}
+
+	conn.Write(clientFrame(true, opText, []byte("ping")))
+	if got := <-messages; got != "ping" {
+		t.Errorf("server read %q", got)
+	}
+	want := serverFrame(opText, []byte("pong: ping"))
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(br, got); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("client read % x, %v, want % x", got, err, want)
+	}
+}
+
+func TestUpgradeRefused(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"not an upgrade", map[string]string{"Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": "k"}, "expected a WebSocket upgrade"},
+		{"other protocol", map[string]string{"Connection": "Upgrade", "Upgrade": "h2c", "Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": "k"}, "expected a WebSocket upgrade"},
+		{"old version", map[string]string{"Connection": "Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "8", "Sec-WebSocket-Key": "k"}, "unsupported WebSocket version"},
+		{"no key", map[string]string{"Connection": "Upgrade", "Upgrade": "WebSocket", "Sec-WebSocket-Version": "13"}, "missing Sec-WebSocket-Key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			if _, err := upgrade(w, r); err == nil {
+				t.Fatal("upgrade succeeded")
+			}
+			if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("got %d %s, want 400 with %q", w.Code, w.Body, tt.want)
+			}
+		})
+	}
+}
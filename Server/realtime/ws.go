@@ -0,0 +1,77 @@
+package realtime
+
+import (
+	"net/http"
+	"time"
+
+	"starttech-server/auth"
+)
+
+const (
+	// writeWait bounds a single frame write.
+	writeWait = 10 * time.Second
+	// pongWait is how long a client may stay silent before it is dropped.
+	pongWait = 60 * time.Second
+	// pingPeriod must be shorter than pongWait.
+	pingPeriod = 30 * time.Second
+)
+
+// ServeWS upgrades an authenticated request to a WebSocket and streams the
+// user's events over it as JSON text messages.
+func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.UserID(r.Context())
+	orgID, _ := auth.OrgID(r.Context())
+	conn, err := upgrade(w, r)
+	if err != nil {
+		// upgrade has already answered the client.
+		return
+	}
+
+	c := h.subscribe(userID, orgID, 0)
+	if c == nil {
+		conn.close()
+		return
+	}
+	go writePump(conn, c)
+	h.readPump(conn, c)
+}
+
+// readPump consumes client frames so pings, pongs and close frames are
+// handled, and unsubscribes the client once the connection ends.
+func (h *Hub) readPump(conn *wsConn, c *client) {
+	defer func() {
+		h.unsubscribe(c)
+		conn.conn.Close()
+	}()
+	for {
+		conn.conn.SetReadDeadline(time.Now().Add(pongWait))
+		if _, _, err := conn.readMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// writePump sends queued events and periodic pings until the hub closes the
+// client's queue.
+func writePump(conn *wsConn, c *client) {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case m, ok := <-c.send:
+			if !ok {
+				conn.close()
+				return
+			}
+			if err := conn.writeFrame(opText, m.data); err != nil {
+				conn.conn.Close()
+				return
+			}
+		case <-ticker.C:
+			if err := conn.writeFrame(opPing, nil); err != nil {
+				conn.conn.Close()
+				return
+			}
+		}
+	}
+}
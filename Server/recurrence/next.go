@@ -0,0 +1,166 @@
+package recurrence
+
+import (
+	"slices"
+	"time"
+)
+
+// maxSearchDays bounds the scan for the next occurrence; a rule such as
+// "every 4 years on February 29" needs the longest reach.
+const maxSearchDays = 366 * 8 * 4
+
+// Next returns the first occurrence of the series starting at start that
+// falls strictly after after. It reports false when the series has ended
+// under UNTIL or never matches. COUNT is not applied here; see Advance.
+//
+// Dates and the time of day are those of start's location, so an
+// occurrence keeps its wall-clock time across daylight saving changes.
+func (r Rule) Next(start, after time.Time) (time.Time, bool) {
+	loc := start.Location()
+	after = after.In(loc)
+	hour, minute, sec := start.Clock()
+
+	day := midnight(after)
+	if day.Before(midnight(start)) {
+		day = midnight(start)
+	}
+	for i := 0; i < maxSearchDays; i, day = i+1, day.AddDate(0, 0, 1) {
+		t := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, sec, start.Nanosecond(), loc)
+		if !t.After(after) || !r.matches(start, day) {
+			continue
+		}
+		if r.Until != nil && t.After(*r.Until) {
+			return time.Time{}, false
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// Advance returns the rule that continues the series after one occurrence
+// has been used up, and false when none remain.
+func (r Rule) Advance() (Rule, bool) {
+	switch {
+	case r.Count == 1:
+		return Rule{}, false
+	case r.Count > 1:
+		r.Count--
+	}
+	return r, true
+}
+
+// midnight returns the date of t in its own location as a UTC midnight,
+// which days are counted in.
+func midnight(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// matches reports whether day, a UTC midnight, is an occurrence date.
+func (r Rule) matches(start, day time.Time) bool {
+	start = midnight(start)
+	switch r.Freq {
+	case Daily:
+		if daysBetween(start, day)%r.Interval != 0 {
+			return false
+		}
+		return r.monthOK(day) && r.monthDayOK(day) && r.weekdayOK(day, false)
+
+	case Weekly:
+		if daysBetween(weekStart(start), weekStart(day))/7%r.Interval != 0 {
+			return false
+		}
+		if len(r.ByDay) == 0 {
+			return day.Weekday() == start.Weekday()
+		}
+		return r.monthOK(day) && r.weekdayOK(day, false)
+
+	case Monthly:
+		months := (day.Year()-start.Year())*12 + int(day.Month()-start.Month())
+		if months%r.Interval != 0 || !r.monthOK(day) {
+			return false
+		}
+		if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+			return day.Day() == start.Day()
+		}
+		return r.monthDayOK(day) && r.weekdayOK(day, false)
+
+	case Yearly:
+		if (day.Year()-start.Year())%r.Interval != 0 {
+			return false
+		}
+		if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+			month := start.Month()
+			if len(r.ByMonth) > 0 {
+				month = day.Month()
+			}
+			return r.monthOK(day) && day.Month() == month && day.Day() == start.Day()
+		}
+		// Without BYMONTH, BYDAY ordinals count within the year.
+		return r.monthOK(day) && r.monthDayOK(day) && r.weekdayOK(day, len(r.ByMonth) == 0)
+	}
+	return false
+}
+
+func (r Rule) monthOK(day time.Time) bool {
+	return len(r.ByMonth) == 0 || slices.Contains(r.ByMonth, day.Month())
+}
+
+func (r Rule) monthDayOK(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := daysIn(day.Year(), day.Month())
+	for _, n := range r.ByMonthDay {
+		if n == day.Day() || n < 0 && last+n+1 == day.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// weekdayOK applies BYDAY. Ordinals count occurrences of the weekday within
+// the month, or within the year when inYear is set.
+func (r Rule) weekdayOK(day time.Time, inYear bool) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Day != day.Weekday() {
+			continue
+		}
+		if wd.N == 0 {
+			return true
+		}
+		var nth, fromEnd int
+		if inYear {
+			nth = (day.YearDay()-1)/7 + 1
+			days := 365
+			if daysIn(day.Year(), time.February) == 29 {
+				days = 366
+			}
+			fromEnd = -((days-day.YearDay())/7 + 1)
+		} else {
+			nth = (day.Day()-1)/7 + 1
+			fromEnd = -((daysIn(day.Year(), day.Month())-day.Day())/7 + 1)
+		}
+		if wd.N == nth || wd.N == fromEnd {
+			return true
+		}
+	}
+	return false
+}
+
+func daysIn(year int, m time.Month) int {
+	return time.Date(year, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func daysBetween(a, b time.Time) int {
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// weekStart returns the Monday on or before day.
+func weekStart(day time.Time) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
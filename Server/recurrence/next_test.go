@@ -0,0 +1,253 @@
+package recurrence
+
+import (
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// expand returns up to n occurrences of the series of rule starting at
+// start, using COUNT as tasks do: one occurrence per Advance.
+func expand(t *testing.T, rule string, start time.Time, n int) []time.Time {
+	t.Helper()
+	r, err := Parse(rule)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", rule, err)
+	}
+	var out []time.Time
+	after := start.Add(-time.Nanosecond)
+	for len(out) < n {
+		next, ok := r.Next(start, after)
+		if !ok {
+			break
+		}
+		out = append(out, next)
+		if r, ok = r.Advance(); !ok {
+			break
+		}
+		after = next
+	}
+	return out
+}
+
+// dates parses "2006-01-02" dates at 09:00 in loc.
+func dates(t *testing.T, loc *time.Location, days string) []time.Time {
+	t.Helper()
+	var out []time.Time
+	for _, d := range strings.Fields(days) {
+		day, err := time.ParseInLocation("2006-01-02 15:04", d+" 09:00", loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, day)
+	}
+	return out
+}
+
+func TestExpand(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Most cases are the examples of RFC 5545, section 3.8.5.3, starting
+	// at 09:00 in New York.
+	tests := []struct {
+		name  string
+		rule  string
+		start string
+		n     int
+		want  string
+	}{
+		{
+			name: "daily for 10 occurrences", rule: "FREQ=DAILY;COUNT=10", start: "1997-09-02", n: 20,
+			want: "1997-09-02 1997-09-03 1997-09-04 1997-09-05 1997-09-06 1997-09-07 1997-09-08 1997-09-09 1997-09-10 1997-09-11",
+		},
+		{
+			name: "daily until a date", rule: "FREQ=DAILY;UNTIL=19970905", start: "1997-09-02", n: 20,
+			want: "1997-09-02 1997-09-03 1997-09-04 1997-09-05",
+		},
+		{
+			name: "every other day", rule: "FREQ=DAILY;INTERVAL=2", start: "1997-09-02", n: 6,
+			want: "1997-09-02 1997-09-04 1997-09-06 1997-09-08 1997-09-10 1997-09-12",
+		},
+		{
+			name: "every 10 days, 5 occurrences", rule: "FREQ=DAILY;INTERVAL=10;COUNT=5", start: "1997-09-02", n: 20,
+			want: "1997-09-02 1997-09-12 1997-09-22 1997-10-02 1997-10-12",
+		},
+		{
+			name: "every day in January", rule: "FREQ=DAILY;BYMONTH=1", start: "1998-01-30", n: 4,
+			want: "1998-01-30 1998-01-31 1999-01-01 1999-01-02",
+		},
+		{
+			name: "weekly for 10 occurrences", rule: "FREQ=WEEKLY;COUNT=10", start: "1997-09-02", n: 20,
+			want: "1997-09-02 1997-09-09 1997-09-16 1997-09-23 1997-09-30 1997-10-07 1997-10-14 1997-10-21 1997-10-28 1997-11-04",
+		},
+		{
+			name: "weekly on Tuesday and Thursday", rule: "FREQ=WEEKLY;COUNT=10;BYDAY=TU,TH", start: "1997-09-02", n: 20,
+			want: "1997-09-02 1997-09-04 1997-09-09 1997-09-11 1997-09-16 1997-09-18 1997-09-23 1997-09-25 1997-09-30 1997-10-02",
+		},
+		{
+			name: "every other week on Monday, Wednesday and Friday", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE,FR", start: "1997-09-01", n: 9,
+			want: "1997-09-01 1997-09-03 1997-09-05 1997-09-15 1997-09-17 1997-09-19 1997-09-29 1997-10-01 1997-10-03",
+		},
+		{
+			name: "every other week on Tuesday and Thursday, 8 occurrences", rule: "FREQ=WEEKLY;INTERVAL=2;COUNT=8;BYDAY=TU,TH", start: "1997-09-02", n: 20,
+			want: "1997-09-02 1997-09-04 1997-09-16 1997-09-18 1997-09-30 1997-10-02 1997-10-14 1997-10-16",
+		},
+		{
+			name: "monthly on the first Friday", rule: "FREQ=MONTHLY;COUNT=10;BYDAY=1FR", start: "1997-09-05", n: 20,
+			want: "1997-09-05 1997-10-03 1997-11-07 1997-12-05 1998-01-02 1998-02-06 1998-03-06 1998-04-03 1998-05-01 1998-06-05",
+		},
+		{
+			name: "every other month on the first and last Sunday", rule: "FREQ=MONTHLY;INTERVAL=2;COUNT=10;BYDAY=1SU,-1SU", start: "1997-09-07", n: 20,
+			want: "1997-09-07 1997-09-28 1997-11-02 1997-11-30 1998-01-04 1998-01-25 1998-03-01 1998-03-29 1998-05-03 1998-05-31",
+		},
+		{
+			name: "monthly on the second-to-last Monday", rule: "FREQ=MONTHLY;COUNT=6;BYDAY=-2MO", start: "1997-09-22", n: 20,
+			want: "1997-09-22 1997-10-20 1997-11-17 1997-12-22 1998-01-19 1998-02-16",
+		},
+		{
+			name: "monthly on the third-to-last day", rule: "FREQ=MONTHLY;BYMONTHDAY=-3", start: "1997-09-28", n: 6,
+			want: "1997-09-28 1997-10-29 1997-11-28 1997-12-29 1998-01-29 1998-02-26",
+		},
+		{
+			name: "monthly on the 2nd and 15th", rule: "FREQ=MONTHLY;COUNT=10;BYMONTHDAY=2,15", start: "1997-09-02", n: 20,
+			want: "1997-09-02 1997-09-15 1997-10-02 1997-10-15 1997-11-02 1997-11-15 1997-12-02 1997-12-15 1998-01-02 1998-01-15",
+		},
+		{
+			name: "monthly on the first and last day", rule: "FREQ=MONTHLY;COUNT=10;BYMONTHDAY=1,-1", start: "1997-09-30", n: 20,
+			want: "1997-09-30 1997-10-01 1997-10-31 1997-11-01 1997-11-30 1997-12-01 1997-12-31 1998-01-01 1998-01-31 1998-02-01",
+		},
+		{
+			name: "every Friday the 13th", rule: "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13", start: "1997-09-02", n: 5,
+			want: "1998-02-13 1998-03-13 1998-11-13 1999-08-13 2000-10-13",
+		},
+		{
+			name: "monthly on the 31st skips short months", rule: "monthly", start: "2024-01-31", n: 4,
+			want: "2024-01-31 2024-03-31 2024-05-31 2024-07-31",
+		},
+		{
+			name: "yearly in June and July", rule: "FREQ=YEARLY;COUNT=10;BYMONTH=6,7", start: "1997-06-10", n: 20,
+			want: "1997-06-10 1997-07-10 1998-06-10 1998-07-10 1999-06-10 1999-07-10 2000-06-10 2000-07-10 2001-06-10 2001-07-10",
+		},
+		{
+			name: "every other year in January, February and March", rule: "FREQ=YEARLY;INTERVAL=2;COUNT=6;BYMONTH=1,2,3", start: "1997-03-10", n: 20,
+			want: "1997-03-10 1999-01-10 1999-02-10 1999-03-10 2001-01-10 2001-02-10",
+		},
+		{
+			name: "yearly on February 29", rule: "yearly", start: "2024-02-29", n: 3,
+			want: "2024-02-29 2028-02-29 2032-02-29",
+		},
+		{
+			name: "yearly on the last Thursday of November", rule: "FREQ=YEARLY;BYMONTH=11;BYDAY=-1TH", start: "2024-11-28", n: 3,
+			want: "2024-11-28 2025-11-27 2026-11-26",
+		},
+		{
+			name: "yearly on the first Monday of the year", rule: "FREQ=YEARLY;BYDAY=1MO", start: "2024-01-01", n: 3,
+			want: "2024-01-01 2025-01-06 2026-01-05",
+		},
+		{
+			name: "yearly on the last Friday of the year", rule: "FREQ=YEARLY;BYDAY=-1FR", start: "2024-12-27", n: 3,
+			want: "2024-12-27 2025-12-26 2026-12-25",
+		},
+		{
+			name: "the start need not match", rule: "FREQ=WEEKLY;BYDAY=FR", start: "2024-05-01", n: 2,
+			want: "2024-05-03 2024-05-10",
+		},
+		{
+			name: "never matches", rule: "FREQ=MONTHLY;BYMONTHDAY=30;BYMONTH=2", start: "2024-01-01", n: 3,
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := dates(t, ny, tt.start)[0]
+			got := expand(t, tt.rule, start, tt.n)
+			want := dates(t, ny, tt.want)
+			if len(got) != len(want) {
+				t.Fatalf("got %d occurrences %v, want %d", len(got), got, len(want))
+			}
+			for i := range got {
+				if !got[i].Equal(want[i]) {
+					t.Errorf("occurrence %d = %v, want %v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNextKeepsWallClockAcrossDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		start time.Time
+		want  []time.Time
+	}{
+		{
+			name:  "clocks go back",
+			start: time.Date(2025, 11, 1, 9, 0, 0, 0, ny),
+			want:  []time.Time{time.Date(2025, 11, 1, 13, 0, 0, 0, time.UTC), time.Date(2025, 11, 2, 14, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:  "clocks go forward",
+			start: time.Date(2025, 3, 8, 9, 0, 0, 0, ny),
+			want:  []time.Time{time.Date(2025, 3, 8, 14, 0, 0, 0, time.UTC), time.Date(2025, 3, 9, 13, 0, 0, 0, time.UTC)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := expand(t, "daily", tt.start, len(tt.want))
+			for i := range tt.want {
+				if !got[i].Equal(tt.want[i]) || got[i].Hour() != 9 {
+					t.Errorf("occurrence %d = %v, want %v at 09:00", i, got[i], tt.want[i].In(ny))
+				}
+			}
+		})
+	}
+}
+
+func TestNextAfter(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC) // a Monday
+	weekly, _ := Parse("FREQ=WEEKLY;BYDAY=MO")
+	tests := []struct {
+		name  string
+		after time.Time
+		want  time.Time
+	}{
+		{"before the start", time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), start},
+		{"at an occurrence", start, time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC)},
+		{"earlier the same day", time.Date(2024, 1, 15, 8, 59, 0, 0, time.UTC), time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)},
+		{"later the same day", time.Date(2024, 1, 15, 9, 1, 0, 0, time.UTC), time.Date(2024, 1, 22, 9, 0, 0, 0, time.UTC)},
+		{"missed occurrences are skipped", time.Date(2024, 6, 5, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := weekly.Next(start, tt.after)
+			if !ok || !got.Equal(tt.want) {
+				t.Errorf("Next = %v, %v, want %v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	tests := []struct {
+		count, want int
+		ok          bool
+	}{
+		{0, 0, true},
+		{3, 2, true},
+		{2, 1, true},
+		{1, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := Rule{Freq: Daily, Interval: 1, Count: tt.count}.Advance()
+		if ok != tt.ok || got.Count != tt.want {
+			t.Errorf("Advance with COUNT=%d = %d, %v, want %d, %v", tt.count, got.Count, ok, tt.want, tt.ok)
+		}
+	}
+}
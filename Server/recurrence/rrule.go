@@ -0,0 +1,222 @@
+// Package recurrence parses RFC 5545 recurrence rules and computes
+// occurrences. It supports the date-based frequencies (DAILY, WEEKLY,
+// MONTHLY, YEARLY) with INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY and
+// BYMONTH, which covers what task managers offer. Occurrences keep the time
+// of day of the rule's start and are computed in its location.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Freq is the base unit of a rule.
+type Freq string
+
+const (
+	Daily   Freq = "DAILY"
+	Weekly  Freq = "WEEKLY"
+	Monthly Freq = "MONTHLY"
+	Yearly  Freq = "YEARLY"
+)
+
+// WeekdayNum is a BYDAY entry such as MO, or 2TU for the second Tuesday of
+// the period. N is zero when no ordinal is given and negative when counting
+// from the end.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// Rule is a parsed recurrence rule.
+type Rule struct {
+	Freq       Freq
+	Interval   int
+	Count      int // zero for no limit
+	Until      *time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+}
+
+var dayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// Parse reads a rule. Besides RRULE strings such as
+// "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE" (with or without the "RRULE:"
+// prefix) it accepts the shorthands daily, weekly, monthly and yearly.
+func Parse(s string) (Rule, error) {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "daily", "weekly", "monthly", "yearly":
+		return Rule{Freq: Freq(strings.ToUpper(s)), Interval: 1}, nil
+	}
+	s = strings.TrimPrefix(strings.ToUpper(s), "RRULE:")
+
+	r := Rule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("malformed part %q", part)
+		}
+		var err error
+		switch name {
+		case "FREQ":
+			r.Freq = Freq(value)
+			if !slices.Contains([]Freq{Daily, Weekly, Monthly, Yearly}, r.Freq) {
+				err = errors.New("must be DAILY, WEEKLY, MONTHLY or YEARLY")
+			}
+		case "INTERVAL":
+			r.Interval, err = positive(value)
+		case "COUNT":
+			r.Count, err = positive(value)
+		case "UNTIL":
+			var t time.Time
+			t, err = parseUntil(value)
+			r.Until = &t
+		case "BYDAY":
+			r.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseInts(value, 1, 31)
+		case "BYMONTH":
+			var months []int
+			months, err = parseInts(value, 1, 12)
+			for _, m := range months {
+				if m < 0 {
+					err = errors.New("must be 1-12")
+				}
+				r.ByMonth = append(r.ByMonth, time.Month(m))
+			}
+		case "WKST":
+			if value != "MO" {
+				err = errors.New("only MO is supported")
+			}
+		default:
+			err = errors.New("is not supported")
+		}
+		if err != nil {
+			return Rule{}, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	if r.Freq == "" {
+		return Rule{}, errors.New("FREQ is required")
+	}
+	if r.Count > 0 && r.Until != nil {
+		return Rule{}, errors.New("COUNT and UNTIL cannot both be set")
+	}
+	for _, d := range r.ByDay {
+		if d.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return Rule{}, errors.New("BYDAY: ordinals need FREQ=MONTHLY or YEARLY")
+		}
+	}
+	return r, nil
+}
+
+func positive(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, errors.New("must be a positive integer")
+	}
+	return n, nil
+}
+
+func parseUntil(s string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			if layout == "20060102" {
+				t = t.Add(24*time.Hour - time.Second) // the whole day counts
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("must be YYYYMMDD or YYYYMMDDTHHMMSSZ")
+}
+
+func parseByDay(s string) ([]WeekdayNum, error) {
+	var out []WeekdayNum
+	for _, item := range strings.Split(s, ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid day %q", item)
+		}
+		day, ok := dayCodes[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q", item)
+		}
+		wd := WeekdayNum{Day: day}
+		if prefix := item[:len(item)-2]; prefix != "" {
+			n, err := strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("invalid ordinal in %q", item)
+			}
+			wd.N = n
+		}
+		out = append(out, wd)
+	}
+	return out, nil
+}
+
+// parseInts reads a comma-separated list of values in [-max, -min] or
+// [min, max].
+func parseInts(s string, min, max int) ([]int, error) {
+	var out []int
+	for _, item := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(item)
+		if err != nil || n == 0 || n > max || n < -max || (n > 0 && n < min) {
+			return nil, fmt.Errorf("invalid value %q", item)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// String renders r as an RRULE value without the "RRULE:" prefix.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = strings.ToUpper(d.Day.String()[:2])
+			if d.N != 0 {
+				days[i] = strconv.Itoa(d.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]int, len(r.ByMonth))
+		for i, m := range r.ByMonth {
+			months[i] = int(m)
+		}
+		parts = append(parts, "BYMONTH="+joinInts(months))
+	}
+	return strings.Join(parts, ";")
+}
+
+func joinInts(ns []int) string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ",")
+}
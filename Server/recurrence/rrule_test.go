@@ -0,0 +1,84 @@
+package recurrence
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	until := time.Date(1997, 12, 24, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want string // the rule in canonical form
+	}{
+		{"daily", "FREQ=DAILY"},
+		{" Weekly ", "FREQ=WEEKLY"},
+		{"FREQ=MONTHLY", "FREQ=MONTHLY"},
+		{"RRULE:FREQ=YEARLY", "FREQ=YEARLY"},
+		{"rrule:freq=weekly;interval=2;byday=mo,we,fr", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE,FR"},
+		{"FREQ=DAILY;INTERVAL=1", "FREQ=DAILY"},
+		{"FREQ=DAILY;COUNT=10", "FREQ=DAILY;COUNT=10"},
+		{"FREQ=DAILY;UNTIL=19971224T000000Z", "FREQ=DAILY;UNTIL=" + until.Format("20060102T150405Z")},
+		{"FREQ=DAILY;UNTIL=19971224", "FREQ=DAILY;UNTIL=19971224T235959Z"},
+		{"FREQ=MONTHLY;BYDAY=1FR,-1SU", "FREQ=MONTHLY;BYDAY=1FR,-1SU"},
+		{"FREQ=MONTHLY;BYDAY=+2TU", "FREQ=MONTHLY;BYDAY=2TU"},
+		{"FREQ=MONTHLY;BYMONTHDAY=2,15,-1", "FREQ=MONTHLY;BYMONTHDAY=2,15,-1"},
+		{"FREQ=YEARLY;BYMONTH=6,7", "FREQ=YEARLY;BYMONTH=6,7"},
+		{"FREQ=WEEKLY;WKST=MO;;BYDAY=TU", "FREQ=WEEKLY;BYDAY=TU"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			r, err := Parse(tt.in)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := r.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			again, err := Parse(r.String())
+			if err != nil || again.String() != tt.want {
+				t.Errorf("reparsing %q gave %q, %v", r, again, err)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", "FREQ is required"},
+		{"INTERVAL=2", "FREQ is required"},
+		{"hourly", "malformed part"},
+		{"FREQ=HOURLY", "FREQ: must be DAILY"},
+		{"FREQ=DAILY;INTERVAL=0", "INTERVAL: must be a positive integer"},
+		{"FREQ=DAILY;COUNT=-1", "COUNT: must be a positive integer"},
+		{"FREQ=DAILY;COUNT=", "malformed part"},
+		{"FREQ=DAILY;UNTIL=tomorrow", "UNTIL: must be YYYYMMDD"},
+		{"FREQ=DAILY;COUNT=3;UNTIL=19971224", "COUNT and UNTIL cannot both be set"},
+		{"FREQ=WEEKLY;BYDAY=XX", `BYDAY: invalid day "XX"`},
+		{"FREQ=WEEKLY;BYDAY=M", `BYDAY: invalid day "M"`},
+		{"FREQ=MONTHLY;BYDAY=0MO", "BYDAY: invalid ordinal"},
+		{"FREQ=MONTHLY;BYDAY=6MO", "BYDAY: invalid ordinal"},
+		{"FREQ=WEEKLY;BYDAY=1MO", "ordinals need FREQ=MONTHLY or YEARLY"},
+		{"FREQ=MONTHLY;BYMONTHDAY=32", "BYMONTHDAY: invalid value"},
+		{"FREQ=MONTHLY;BYMONTHDAY=0", "BYMONTHDAY: invalid value"},
+		{"FREQ=YEARLY;BYMONTH=13", "BYMONTH: invalid value"},
+		{"FREQ=YEARLY;BYMONTH=-1", "BYMONTH: must be 1-12"},
+		{"FREQ=WEEKLY;WKST=SU", "WKST: only MO is supported"},
+		{"FREQ=DAILY;BYHOUR=9", "BYHOUR: is not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			_, err := Parse(tt.in)
+			if err == nil {
+				t.Fatal("Parse succeeded")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
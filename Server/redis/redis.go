@@ -0,0 +1,299 @@
+// Package redis is a small client for the Redis protocol (RESP2), enough
+// for the state several server instances share without pulling in a
+// third-party driver.
+package redis
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxIdle bounds the connections kept open between commands.
+const maxIdle = 8
+
+// defaultTimeout bounds a command whose context has no deadline.
+const defaultTimeout = 5 * time.Second
+
+// Error is an error reply from the server, such as a failing script.
+type Error string
+
+func (e Error) Error() string { return "redis: " + string(e) }
+
+// Client sends commands to one Redis server over a pool of connections. It
+// is safe for concurrent use.
+type Client struct {
+	addr     string
+	tls      bool
+	username string
+	password string
+	db       int
+
+	mu   sync.Mutex
+	idle []*conn
+}
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// New returns a client for rawURL, of the form
+// redis://[[user]:password@]host[:port][/db]. The rediss scheme connects
+// over TLS. No connection is made until the first command.
+func New(rawURL string) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("redis: unsupported scheme in %q", rawURL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("redis: no host in %q", rawURL)
+	}
+	c := &Client{addr: u.Host, tls: u.Scheme == "rediss"}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil || c.db < 0 {
+			return nil, fmt.Errorf("redis: %q is not a database number", db)
+		}
+	}
+	return c, nil
+}
+
+// Do sends a command and returns its reply: a string for a status, int64
+// for an integer, []byte for a bulk string, []any for an array and nil for
+// a null. An error reply is returned as an Error. Arguments may be strings,
+// byte slices, integers or floats.
+func (c *Client) Do(ctx context.Context, args ...any) (any, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := cn.do(ctx, args)
+	var rerr Error
+	if err != nil && !errors.As(err, &rerr) {
+		cn.Close()
+		return nil, err
+	}
+	c.put(cn)
+	return reply, err
+}
+
+// Close closes the idle connections.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	idle := c.idle
+	c.idle = nil
+	c.mu.Unlock()
+	for _, cn := range idle {
+		cn.Close()
+	}
+	return nil
+}
+
+// Subscribe listens to channel on a connection of its own, calling fn with
+// the payload of each message published to it, until ctx is done or the
+// connection fails. It returns ctx's error in the first case.
+func (c *Client) Subscribe(ctx context.Context, channel string, fn func(payload []byte)) error {
+	cn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer cn.Close()
+	if _, err := cn.do(ctx, []any{"SUBSCRIBE", channel}); err != nil {
+		return err
+	}
+	// Wait for messages as long as it takes; closing the connection ends
+	// the wait.
+	cn.SetDeadline(time.Time{})
+	stop := context.AfterFunc(ctx, func() { cn.Close() })
+	defer stop()
+	for {
+		reply, err := cn.read()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+		items, ok := reply.([]any)
+		if !ok || len(items) != 3 {
+			continue
+		}
+		if kind, _ := items[0].([]byte); string(kind) != "message" {
+			continue
+		}
+		if payload, ok := items[2].([]byte); ok {
+			fn(payload)
+		}
+	}
+}
+
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	c.mu.Lock()
+	if n := len(c.idle); n > 0 {
+		cn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return cn, nil
+	}
+	c.mu.Unlock()
+	return c.dial(ctx)
+}
+
+func (c *Client) put(cn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.idle) >= maxIdle {
+		cn.Close()
+		return
+	}
+	c.idle = append(c.idle, cn)
+}
+
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	var nc net.Conn
+	var err error
+	if c.tls {
+		nc, err = (&tls.Dialer{}).DialContext(ctx, "tcp", c.addr)
+	} else {
+		nc, err = (&net.Dialer{}).DialContext(ctx, "tcp", c.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	cn := &conn{Conn: nc, r: bufio.NewReader(nc)}
+	if c.password != "" {
+		args := []any{"AUTH", c.password}
+		if c.username != "" {
+			args = []any{"AUTH", c.username, c.password}
+		}
+		if _, err := cn.do(ctx, args); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	if c.db != 0 {
+		if _, err := cn.do(ctx, []any{"SELECT", c.db}); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+func (cn *conn) do(ctx context.Context, args []any) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultTimeout)
+	}
+	cn.SetDeadline(deadline)
+
+	var b []byte
+	b = append(b, '*')
+	b = strconv.AppendInt(b, int64(len(args)), 10)
+	b = append(b, "\r\n"...)
+	for _, a := range args {
+		var s string
+		switch a := a.(type) {
+		case string:
+			s = a
+		case []byte:
+			s = string(a)
+		case int:
+			s = strconv.Itoa(a)
+		case int64:
+			s = strconv.FormatInt(a, 10)
+		case float64:
+			s = strconv.FormatFloat(a, 'f', -1, 64)
+		default:
+			return nil, fmt.Errorf("redis: unsupported argument type %T", a)
+		}
+		b = append(b, '$')
+		b = strconv.AppendInt(b, int64(len(s)), 10)
+		b = append(b, "\r\n"...)
+		b = append(b, s...)
+		b = append(b, "\r\n"...)
+	}
+	if _, err := cn.Write(b); err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	return cn.read()
+}
+
+// read parses one reply. An error reply is consumed in full, so the
+// connection stays usable.
+func (cn *conn) read() (any, error) {
+	line, err := cn.r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, line := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return line, nil
+	case '-':
+		return nil, Error(line)
+	case ':':
+		n, err := strconv.ParseInt(line, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed integer %q", line)
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(line)
+		if err != nil || n < -1 {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", line)
+		}
+		if n == -1 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(cn.r, buf); err != nil {
+			return nil, fmt.Errorf("redis: %w", err)
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line)
+		if err != nil || n < -1 {
+			return nil, fmt.Errorf("redis: malformed array length %q", line)
+		}
+		if n == -1 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		var first error
+		for i := range items {
+			items[i], err = cn.read()
+			var rerr Error
+			if err != nil && !errors.As(err, &rerr) {
+				return nil, err
+			}
+			if err != nil && first == nil {
+				first = err
+			}
+		}
+		return items, first
+	}
+	return nil, fmt.Errorf("redis: unexpected reply type %q", kind)
+}
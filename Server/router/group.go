@@ -0,0 +1,52 @@
+package router
+
+import "net/http"
+
+// Middleware wraps a handler, as the auth and rate limit middleware do.
+type Middleware func(http.Handler) http.Handler
+
+// Routes is what handlers register their routes on: an *http.ServeMux, or a
+// Group that wraps each route before handing it on.
+type Routes interface {
+	Handle(pattern string, handler http.Handler)
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+}
+
+// Group registers routes on other Routes with a shared middleware chain
+// around each of them. Since the middleware runs only once a route has
+// matched, unknown paths are answered 404, and other methods 405, before
+// anything checks the caller's token.
+type Group struct {
+	routes     Routes
+	middleware []Middleware
+}
+
+// NewGroup returns a Group that registers on routes, wrapping each route in
+// middleware, the first outermost.
+func NewGroup(routes Routes, middleware ...Middleware) *Group {
+	return &Group{routes: routes, middleware: middleware}
+}
+
+// With returns a Group that registers through g, adding middleware inside
+// g's own.
+func (g *Group) With(middleware ...Middleware) *Group {
+	return NewGroup(g, middleware...)
+}
+
+// Handle registers handler for pattern, wrapped in g's middleware.
+func (g *Group) Handle(pattern string, handler http.Handler) {
+	g.routes.Handle(pattern, Chain(handler, g.middleware...))
+}
+
+// HandleFunc registers handler for pattern, wrapped in g's middleware.
+func (g *Group) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	g.Handle(pattern, http.HandlerFunc(handler))
+}
+
+// Chain wraps h in middleware, the first outermost.
+func Chain(h http.Handler, middleware ...Middleware) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
@@ -0,0 +1,33 @@
+// Package router mounts the versions of the API under path prefixes. A
+// version's routes are registered without the prefix, as "GET /tasks", and
+// served at /api/v1/tasks, so a later version can change them while clients
+// of the earlier one keep working.
+package router
+
+import "net/http"
+
+// Prefix is the path all versions are mounted below.
+const Prefix = "/api/"
+
+// Router serves the API versions alongside the routes outside of the API,
+// such as /health, which are registered on the embedded ServeMux.
+type Router struct {
+	*http.ServeMux
+}
+
+// New returns a Router with no routes.
+func New() *Router {
+	return &Router{ServeMux: http.NewServeMux()}
+}
+
+// Version mounts api, the routes of version, at /api/<version>/.
+func (rt *Router) Version(version string, api http.Handler) {
+	prefix := Prefix + version
+	rt.Handle(prefix+"/", http.StripPrefix(prefix, api))
+}
+
+// Unversioned also serves api at the root, for clients written before the
+// API had versions.
+func (rt *Router) Unversioned(api http.Handler) {
+	rt.Handle("/", api)
+}
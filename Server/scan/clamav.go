@@ -0,0 +1,99 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// chunkSize is the most sent to clamd in one INSTREAM chunk.
+const chunkSize = 64 << 10
+
+// ClamAV scans with a clamd daemon over its INSTREAM command, so the
+// daemon needs no access to the files. Address is the path of clamd's unix
+// socket, such as /run/clamav/clamd.ctl, or the host:port of its TCP
+// socket. Files larger than clamd's StreamMaxLength cannot be scanned.
+type ClamAV struct {
+	Address string
+	// Timeout bounds a whole scan; it defaults to a minute.
+	Timeout time.Duration
+}
+
+func (c ClamAV) Scan(ctx context.Context, r io.Reader) (string, error) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	network := "tcp"
+	if strings.HasPrefix(c.Address, "/") {
+		network = "unix"
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, c.Address)
+	if err != nil {
+		return "", fmt.Errorf("clamav: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if err := stream(conn, r); err != nil {
+		return "", fmt.Errorf("clamav: %w", err)
+	}
+	reply, err := io.ReadAll(io.LimitReader(conn, 4<<10))
+	if err != nil {
+		return "", fmt.Errorf("clamav: reading reply: %w", err)
+	}
+	return verdict(string(bytes.TrimRight(reply, "\x00\n")))
+}
+
+// stream sends r to clamd as an INSTREAM command: length-prefixed chunks
+// ended by an empty one.
+func stream(w io.Writer, r io.Reader) error {
+	if _, err := io.WriteString(w, "zINSTREAM\x00"); err != nil {
+		return err
+	}
+	buf := make([]byte, 4+chunkSize)
+	for {
+		n, err := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, werr := w.Write(buf[:4+n]); werr != nil {
+				return werr
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := w.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// verdict reads clamd's reply to INSTREAM: "stream: OK", "stream: <name>
+// FOUND" or "<reason> ERROR".
+func verdict(reply string) (string, error) {
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return "", nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return strings.TrimSuffix(reply, " FOUND"), nil
+	case strings.HasSuffix(reply, " ERROR"):
+		return "", fmt.Errorf("clamav: %s", strings.TrimSuffix(reply, " ERROR"))
+	}
+	return "", fmt.Errorf("clamav: unexpected reply %q", reply)
+}
@@ -0,0 +1,44 @@
+package scan
+
+import (
+	"context"
+
+	"starttech-server/jobs"
+	"starttech-server/model"
+)
+
+// KindScan is the kind of the jobs that scan uploaded attachments.
+const KindScan = "attachment.scan"
+
+// Jobs runs scans on the job queue, so an upload does not wait for its
+// scan, and a scan the scanner fails is retried.
+type Jobs struct {
+	jobs *jobs.Queue
+}
+
+// NewJobs returns Jobs that queue on queue.
+func NewJobs(queue *jobs.Queue) *Jobs {
+	return &Jobs{jobs: queue}
+}
+
+// scanJob is the payload of KindScan jobs.
+type scanJob struct {
+	AttachmentID string `json:"attachment_id"`
+}
+
+// Register makes the job queue scan attachments with run, which scans the
+// attachment with the given ID; see service.Attachments.Scan.
+func (s *Jobs) Register(run func(ctx context.Context, attachmentID string) error) {
+	s.jobs.Handle(KindScan, 0, func(ctx context.Context, j model.Job) error {
+		var sj scanJob
+		if err := jobs.Decode(j, &sj); err != nil {
+			return err
+		}
+		return run(ctx, sj.AttachmentID)
+	})
+}
+
+// Enqueue queues the scan of the attachment with the given ID.
+func (s *Jobs) Enqueue(ctx context.Context, attachmentID string) error {
+	return s.jobs.EnqueueOnce(ctx, "scan-"+attachmentID, KindScan, scanJob{attachmentID})
+}
@@ -0,0 +1,18 @@
+// Package scan checks uploaded files for malware before anybody can
+// download them. A Scanner reads a file and names the threat it finds;
+// ClamAV asks a clamd daemon, and other engines can be plugged in by
+// implementing Scanner.
+package scan
+
+import (
+	"context"
+	"io"
+)
+
+// Scanner checks files for malware.
+type Scanner interface {
+	// Scan reads r to its end and returns the name of the threat found
+	// in it, or "" if it is clean. An error means the file could not be
+	// checked, not that it is infected.
+	Scan(ctx context.Context, r io.Reader) (threat string, err error)
+}
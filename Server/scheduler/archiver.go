@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"starttech-server/jobs"
+	"starttech-server/metrics"
+	"starttech-server/model"
+)
+
+// KindArchive is the kind of the jobs that archive completed tasks.
+const KindArchive = "archive.tasks"
+
+var tasksArchived = metrics.NewCounterVec("tasks_archived_total", "Completed tasks archived by the auto-archive policy.")
+
+// Archiver periodically archives the completed tasks that have been left
+// unchanged for longer than After.
+type Archiver struct {
+	// Archive archives the completed tasks last changed before the given
+	// time and returns how many there were; see
+	// service.Tasks.ArchiveCompleted.
+	Archive func(ctx context.Context, before time.Time) (int, error)
+	After   time.Duration
+	// Interval between sweeps; it defaults to an hour.
+	Interval time.Duration
+}
+
+// Schedule makes q sweep every Interval. A zero After turns the policy off,
+// and nothing is scheduled.
+func (a *Archiver) Schedule(q *jobs.Queue) {
+	if a.After <= 0 {
+		return
+	}
+	interval := a.Interval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	q.Handle(KindArchive, 3, func(ctx context.Context, _ model.Job) error {
+		return a.Sweep(ctx, time.Now().UTC())
+	})
+	q.Every(KindArchive, interval)
+}
+
+// Sweep archives what has been completed and untouched since After before
+// now.
+func (a *Archiver) Sweep(ctx context.Context, now time.Time) error {
+	n, err := a.Archive(ctx, now.Add(-a.After))
+	tasksArchived.With().Add(float64(n))
+	if err != nil {
+		return fmt.Errorf("archiving tasks: %w", err)
+	}
+	if n > 0 {
+		slog.Info("completed tasks archived", "count", n)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"starttech-server/jobs"
+	"starttech-server/metrics"
+	"starttech-server/model"
+)
+
+var recordsPurged = metrics.NewCounterVec("records_purged_total", "Expired records deleted for good, by kind.", "kind")
+
+// Purger periodically deletes the records of one kind, such as the tasks in
+// the trash, that are older than Retention.
+type Purger struct {
+	// Kind names the records in logs and metrics.
+	Kind string
+	// Purge permanently deletes the records from before the given time and
+	// returns how many there were; see service.Tasks.PurgeTrash.
+	Purge func(ctx context.Context, before time.Time) (int, error)
+	// Count returns how many records Purge would delete, without deleting
+	// them.
+	Count     func(ctx context.Context, before time.Time) (int, error)
+	Retention time.Duration
+	// Interval between sweeps; it defaults to an hour.
+	Interval time.Duration
+}
+
+// Schedule makes q sweep every Interval, under the job kind "purge." plus
+// Kind. A zero Retention keeps the records forever, and nothing is
+// scheduled.
+func (p *Purger) Schedule(q *jobs.Queue) {
+	if p.Retention <= 0 {
+		return
+	}
+	interval := p.Interval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	kind := "purge." + p.Kind
+	// A failed sweep is retried a few times; the next interval's covers
+	// the rest.
+	q.Handle(kind, 3, func(ctx context.Context, _ model.Job) error {
+		return p.Sweep(ctx, time.Now().UTC())
+	})
+	q.Every(kind, interval)
+}
+
+// Sweep purges what is more than Retention older than now.
+func (p *Purger) Sweep(ctx context.Context, now time.Time) error {
+	n, err := p.Purge(ctx, now.Add(-p.Retention))
+	recordsPurged.With(p.Kind).Add(float64(n))
+	if err != nil {
+		return fmt.Errorf("purging %s: %w", p.Kind, err)
+	}
+	if n > 0 {
+		slog.Info("expired records purged", "kind", p.Kind, "count", n)
+	}
+	return nil
+}
+
+// Preview counts what a sweep at now would purge, without purging it.
+func (p *Purger) Preview(ctx context.Context, now time.Time) (model.PurgePreview, error) {
+	before := now.Add(-p.Retention)
+	n, err := p.Count(ctx, before)
+	if err != nil {
+		return model.PurgePreview{}, fmt.Errorf("counting %s to purge: %w", p.Kind, err)
+	}
+	return model.PurgePreview{Kind: p.Kind, RetentionSeconds: int64(p.Retention / time.Second), Before: before, Count: n}, nil
+}
+
+// Purgers are the purgers a server runs.
+type Purgers []*Purger
+
+// Preview counts what each purger with a Retention would purge at now,
+// for the dry run of GET /admin/purges.
+func (ps Purgers) Preview(ctx context.Context, now time.Time) ([]model.PurgePreview, error) {
+	out := []model.PurgePreview{}
+	for _, p := range ps {
+		if p.Retention <= 0 {
+			continue
+		}
+		preview, err := p.Preview(ctx, now)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, preview)
+	}
+	return out, nil
+}
@@ -0,0 +1,52 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"starttech-server/jobs"
+	"starttech-server/metrics"
+	"starttech-server/model"
+)
+
+// KindRules is the kind of the jobs that evaluate automation rules.
+const KindRules = "rules.evaluate"
+
+var rulesFired = metrics.NewCounterVec("rules_fired_total", "Times project automation rules acted on a task.")
+
+// Rules periodically evaluates the automation rules of every project.
+type Rules struct {
+	// Evaluate runs the enabled rules against their projects' tasks as of
+	// the given time and returns how many times they acted on a task; see
+	// service.Tasks.EvaluateRules.
+	Evaluate func(ctx context.Context, now time.Time) (int, error)
+	// Interval between evaluations; it defaults to five minutes.
+	Interval time.Duration
+}
+
+// Schedule makes q evaluate the rules every Interval.
+func (r *Rules) Schedule(q *jobs.Queue) {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	q.Handle(KindRules, 3, func(ctx context.Context, _ model.Job) error {
+		return r.Run(ctx, time.Now().UTC())
+	})
+	q.Every(KindRules, interval)
+}
+
+// Run evaluates the rules as of now.
+func (r *Rules) Run(ctx context.Context, now time.Time) error {
+	n, err := r.Evaluate(ctx, now)
+	rulesFired.With().Add(float64(n))
+	if err != nil {
+		return fmt.Errorf("evaluating rules: %w", err)
+	}
+	if n > 0 {
+		slog.Info("automation rules acted on tasks", "count", n)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+// Package scheduler runs the server's periodic jobs. It delivers task
+// reminders when they come due: pending reminders live in the store, so a
+// restart only delays delivery until the next tick, and reminders missed
+// while the server was down fire on startup. Each due reminder is handed to
+// the job queue to be fanned out to its recipients. It also purges expired
+// records, such as old tasks in the trash.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/jobs"
+	"starttech-server/metrics"
+	"starttech-server/model"
+	"starttech-server/storage"
+	"starttech-server/tracing"
+)
+
+// batchSize bounds the reminders handled per store query.
+const batchSize = 100
+
+// KindFire is the kind of the jobs that publish due reminders.
+const KindFire = "reminder.fire"
+
+var remindersSent = metrics.NewCounterVec("reminders_sent_total", "Reminders delivered, by kind.", "kind")
+
+// Scheduler polls the store for due reminders and queues a job to publish
+// each as an event.
+type Scheduler struct {
+	Reminders storage.ReminderStore
+	Tasks     storage.TaskStore
+	Events    events.Publisher
+	Jobs      *jobs.Queue
+	// Inbox receives a due_soon notification for each reminder. It may be
+	// nil.
+	Inbox storage.NotificationStore
+	// Interval between polls; it defaults to 30 seconds.
+	Interval time.Duration
+}
+
+// Register makes the job queue publish reminders with s.
+func (s *Scheduler) Register() {
+	s.Jobs.Handle(KindFire, 0, s.Fire)
+}
+
+// Run polls until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.Tick(ctx, time.Now().UTC())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick delivers every reminder due at or before now.
+func (s *Scheduler) Tick(ctx context.Context, now time.Time) {
+	for {
+		due, err := s.Reminders.PendingReminders(ctx, now, batchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("scheduler: listing reminders", "err", err)
+			}
+			return
+		}
+		for _, r := range due {
+			s.deliver(ctx, r, now)
+		}
+		if len(due) < batchSize {
+			return
+		}
+	}
+}
+
+// deliver queues r to be published and marks it sent. The job is named
+// after r, so an instance that finds r due before it is marked queues
+// nothing more.
+func (s *Scheduler) deliver(ctx context.Context, r model.Reminder, now time.Time) {
+	if err := s.Jobs.EnqueueOnce(ctx, "reminder-"+r.ID, KindFire, r); err != nil {
+		if ctx.Err() == nil {
+			slog.Error("scheduler: queueing reminder", "reminder_id", r.ID, "err", err)
+		}
+		return
+	}
+	err := s.Reminders.MarkReminderSent(ctx, r.ID, now)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		slog.Error("scheduler: marking reminder sent", "reminder_id", r.ID, "err", err)
+	}
+}
+
+// Fire is the handler of KindFire jobs. It publishes the reminder, unless
+// the task has since been completed or deleted.
+func (s *Scheduler) Fire(ctx context.Context, j model.Job) error {
+	var r model.Reminder
+	if err := jobs.Decode(j, &r); err != nil {
+		return err
+	}
+	t, err := s.Tasks.GetTask(ctx, r.TaskID)
+	if errors.Is(err, storage.ErrNotFound) || err == nil && (t.Completed || t.DeletedAt != nil) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("loading task: %w", err)
+	}
+
+	typ := events.TaskReminder
+	if r.Kind == model.ReminderDue {
+		typ = events.TaskDue
+	}
+	s.Events.Publish(events.Event{
+		Type:        typ,
+		Time:        time.Now().UTC(),
+		Data:        events.Reminder{Kind: r.Kind, FireAt: r.FireAt, Task: t},
+		OrgID:       t.OrgID,
+		Recipients:  []string{t.OwnerID},
+		TraceParent: tracing.FromContext(ctx).TraceParent(),
+	})
+	s.notify(ctx, t)
+	remindersSent.With(string(r.Kind)).Inc()
+	slog.Debug("reminder sent", "reminder_id", r.ID, "task_id", t.ID, "kind", r.Kind)
+	return nil
+}
+
+// notify adds a due_soon notification about t to its owner's inbox and
+// pushes it to them.
+func (s *Scheduler) notify(ctx context.Context, t model.Task) {
+	if s.Inbox == nil {
+		return
+	}
+	n := model.Notification{
+		OrgID: t.OrgID, UserID: t.OwnerID, Kind: model.NotifyDueSoon,
+		TaskID: t.ID, Title: t.Title, CreatedAt: time.Now().UTC(),
+	}
+	if err := s.Inbox.CreateNotification(ctx, &n); err != nil {
+		slog.Error("scheduler: adding notification", "task_id", t.ID, "err", err)
+		return
+	}
+	s.Events.Publish(events.Event{
+		Type:        events.NotificationCreated,
+		Time:        n.CreatedAt,
+		Data:        n,
+		OrgID:       t.OrgID,
+		Recipients:  []string{t.OwnerID},
+		TraceParent: tracing.FromContext(ctx).TraceParent(),
+	})
+}
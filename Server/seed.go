@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"starttech-server/auth"
+	"starttech-server/config"
+	"starttech-server/logging"
+	"starttech-server/seed"
+	"starttech-server/storage"
+)
+
+const seedUsage = `usage: %[1]s seed [seed flags] [-- server flags]
+
+seed fills the configured database with made-up organizations, users,
+projects, tasks and comments, for load tests. The same flags make the same
+data. Every user gets the same password, and the owners of the
+organizations are printed. The server flags, such as -config and
+-database-url, follow --.
+
+`
+
+// seedCommand runs the seed subcommand with args, which follow "seed", and
+// returns the exit status.
+func seedCommand(args []string) int {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), seedUsage, os.Args[0])
+		fs.PrintDefaults()
+	}
+	var o seed.Options
+	fs.IntVar(&o.Orgs, "orgs", 1, "organizations to create")
+	fs.IntVar(&o.Users, "users", 50, "users in each organization")
+	fs.IntVar(&o.Projects, "projects", 10, "projects in each organization")
+	fs.IntVar(&o.Tasks, "tasks", 1000, "tasks in each project")
+	fs.Float64Var(&o.Comments, "comments", 2, "average comments on each task")
+	fs.Uint64Var(&o.Seed, "seed", 1, "picks the data generated")
+	fs.StringVar(&o.Prefix, "prefix", "seed", "start of the usernames, to seed a database more than once")
+	password := fs.String("password", "password123", "password of every user")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if err := o.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "seed:", err)
+		return 2
+	}
+
+	cfg, err := config.Load(fs.Args(), os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	// The in-memory store lives and dies with one process.
+	if cfg.Database.URL == "" {
+		fmt.Fprintln(os.Stderr, "seed: no database is configured; set DATABASE_URL")
+		return 2
+	}
+	logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	slog.SetDefault(logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	store, err := storage.Open(ctx, cfg.Database.URL, storage.Pool{MaxOpen: 1, MaxIdle: 1}, cfg.Database.MigrateOnStart)
+	if errors.Is(err, storage.ErrPendingMigrations) {
+		logger.Error("seed failed", "err", fmt.Errorf("%w; run `%s migrate up` first", err, os.Args[0]))
+		return 1
+	}
+	if err != nil {
+		logger.Error("seed failed", "err", err)
+		return 1
+	}
+	defer store.Close()
+
+	if o.PasswordHash, err = auth.HashPassword(*password); err != nil {
+		logger.Error("seed failed", "err", err)
+		return 1
+	}
+	start := time.Now()
+	res, err := seed.Run(ctx, store, o)
+	logger.Info("seeded", "orgs", res.Orgs, "users", res.Users, "projects", res.Projects, "tasks", res.Tasks,
+		"comments", res.Comments, "took", time.Since(start).Round(time.Millisecond))
+	if err != nil {
+		logger.Error("seed failed", "err", err)
+		return 1
+	}
+	for _, email := range res.Emails {
+		fmt.Println(email)
+	}
+	return 0
+}
@@ -0,0 +1,354 @@
+// Package seed fills a database with made-up organizations, users,
+// projects, tasks and comments, in the volumes and shapes of real use, for
+// measuring how the server performs. The same Options, Seed included, give
+// the same data every time, apart from IDs, so that runs can be compared.
+//
+// Records are written straight to the store, without events, emails,
+// activity or undo history.
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Options sets how much to generate. Counts are per organization, or per
+// project for tasks.
+type Options struct {
+	Orgs     int
+	Users    int
+	Projects int
+	Tasks    int
+	// Comments is the average number of comments on a task.
+	Comments float64
+	// Seed picks the data generated.
+	Seed uint64
+	// Prefix starts the names of the users, so that a database can be
+	// seeded more than once.
+	Prefix string
+	// PasswordHash is the password hash every user gets.
+	PasswordHash string
+	// Now is when the data is made; it defaults to the current time.
+	Now time.Time
+}
+
+// Validate reports the options that cannot be seeded.
+func (o Options) Validate() error {
+	var v model.ValidationError
+	if o.Orgs < 1 {
+		v.Add("orgs", "must be at least 1")
+	}
+	if o.Users < 1 {
+		v.Add("users", "must be at least 1")
+	}
+	if o.Projects < 0 {
+		v.Add("projects", "must not be negative")
+	}
+	if o.Tasks < 0 {
+		v.Add("tasks", "must not be negative")
+	}
+	if o.Comments < 0 {
+		v.Add("comments", "must not be negative")
+	}
+	if o.Prefix == "" || strings.ContainsAny(o.Prefix, "@ ") {
+		v.Add("prefix", "must be a name without spaces or @")
+	}
+	return v.Err()
+}
+
+// Result counts the records created.
+type Result struct {
+	Orgs     int
+	Users    int
+	Projects int
+	Tasks    int
+	Comments int
+	// Emails are those of the owners of the organizations, in order.
+	Emails []string
+}
+
+// Run generates what o asks for in store. Each project is written in a
+// transaction of its own, so a failed run keeps the projects done before.
+// A run whose users exist already fails with storage.ErrConflict.
+func Run(ctx context.Context, store storage.Store, o Options) (Result, error) {
+	if err := o.Validate(); err != nil {
+		return Result{}, err
+	}
+	if o.Now.IsZero() {
+		o.Now = time.Now().UTC()
+	}
+	g := &generator{store: store, o: o, rnd: rand.New(rand.NewPCG(o.Seed, o.Seed^0x9e3779b97f4a7c15))}
+	for i := range o.Orgs {
+		if err := g.org(ctx, i+1); err != nil {
+			return g.result, err
+		}
+	}
+	return g.result, nil
+}
+
+type generator struct {
+	store  storage.Store
+	o      Options
+	rnd    *rand.Rand
+	result Result
+}
+
+// orgData is what the projects of an organization are made from.
+type orgData struct {
+	org   model.Org
+	users []model.User
+	tags  []model.Tag
+}
+
+func (g *generator) org(ctx context.Context, n int) error {
+	var d orgData
+	for i := range g.o.Users {
+		u, err := g.user(ctx, n, i+1)
+		if err != nil {
+			return err
+		}
+		d.users = append(d.users, u)
+	}
+	d.org = model.Org{Name: fmt.Sprintf("%s %s", pick(g.rnd, companyNames), pick(g.rnd, companySuffixes)), CreatedAt: d.users[0].CreatedAt}
+	if err := g.store.CreateOrg(ctx, &d.org, d.users[0].ID); err != nil {
+		return err
+	}
+	g.result.Orgs++
+	g.result.Emails = append(g.result.Emails, d.users[0].Email)
+	for _, u := range d.users[1:] {
+		role := model.OrgMember
+		if g.rnd.IntN(20) == 0 {
+			role = model.OrgAdmin
+		}
+		m := model.OrgMembership{OrgID: d.org.ID, UserID: u.ID, Role: role, CreatedAt: u.CreatedAt}
+		if err := g.store.SaveOrgMember(ctx, &m); err != nil {
+			return err
+		}
+	}
+	for _, name := range tagNames {
+		t := model.Tag{OrgID: d.org.ID, OwnerID: d.users[0].ID, Name: name, Color: pick(g.rnd, tagColors), CreatedAt: d.org.CreatedAt}
+		if err := g.store.CreateTag(ctx, &t); err != nil {
+			return err
+		}
+		d.tags = append(d.tags, t)
+	}
+	for i := range g.o.Projects {
+		err := g.store.InTx(ctx, func(tx storage.Store) error {
+			return g.project(ctx, tx, d, i+1)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	slog.InfoContext(ctx, "seeded organization", "org_id", d.org.ID, "name", d.org.Name, "users", len(d.users), "projects", g.o.Projects)
+	return nil
+}
+
+func (g *generator) user(ctx context.Context, org, n int) (model.User, error) {
+	name := fmt.Sprintf("%s-%s-%d-%d", g.o.Prefix, strings.ToLower(pick(g.rnd, firstNames)), org, n)
+	created := g.o.Now.Add(-g.days(365))
+	u := model.User{
+		Email:           name + "@example.com",
+		Username:        name,
+		PasswordHash:    g.o.PasswordHash,
+		Timezone:        pick(g.rnd, timezones),
+		EmailVerifiedAt: &created,
+		CreatedAt:       created,
+	}
+	err := g.store.CreateUser(ctx, &u)
+	if errors.Is(err, storage.ErrConflict) {
+		return model.User{}, fmt.Errorf("user %s exists already, so the database was seeded with this prefix: %w", u.Username, err)
+	}
+	if err != nil {
+		return model.User{}, err
+	}
+	g.result.Users++
+	return u, nil
+}
+
+// project creates a project with its members, tasks and comments through
+// tx.
+func (g *generator) project(ctx context.Context, tx storage.Store, d orgData, n int) error {
+	owner := pick(g.rnd, d.users)
+	p := model.Project{
+		OrgID:       d.org.ID,
+		OwnerID:     owner.ID,
+		Name:        fmt.Sprintf("%s %d", pick(g.rnd, projectNames), n),
+		Description: sentence(g.rnd),
+		CreatedAt:   g.o.Now.Add(-g.days(200)),
+	}
+	p.UpdatedAt = p.CreatedAt
+	if err := tx.CreateProject(ctx, &p); err != nil {
+		return err
+	}
+	g.result.Projects++
+
+	// Projects are worked on by a handful of people each.
+	members := []model.User{owner}
+	for _, i := range g.rnd.Perm(len(d.users))[:min(len(d.users), 3+g.rnd.IntN(10))] {
+		u := d.users[i]
+		if u.ID == owner.ID {
+			continue
+		}
+		role := model.RoleEditor
+		if g.rnd.IntN(5) == 0 {
+			role = model.RoleViewer
+		}
+		if err := tx.SaveMember(ctx, &model.Member{ProjectID: p.ID, UserID: u.ID, Role: role, CreatedAt: p.CreatedAt}); err != nil {
+			return err
+		}
+		if role == model.RoleEditor {
+			members = append(members, u)
+		}
+	}
+
+	var parents []string
+	for i := range g.o.Tasks {
+		t := g.task(p, d, members, float64(i+1))
+		// A tenth of the tasks are subtasks of an earlier one.
+		if len(parents) > 0 && g.rnd.IntN(10) == 0 {
+			parent := parents[g.rnd.IntN(len(parents))]
+			t.ParentID = &parent
+		}
+		if err := tx.CreateTask(ctx, &t); err != nil {
+			return err
+		}
+		g.result.Tasks++
+		if t.ParentID == nil {
+			parents = append(parents, t.ID)
+		}
+		if err := g.comments(ctx, tx, t, members); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// task makes a task of p at position, skewed the way boards are: most
+// tasks are done or still to do, few are urgent, and most have somebody
+// on them.
+func (g *generator) task(p model.Project, d orgData, members []model.User, position float64) model.Task {
+	created := p.CreatedAt.Add(time.Duration(g.rnd.Int64N(int64(g.o.Now.Sub(p.CreatedAt)) + 1)))
+	t := model.Task{
+		OrgID:     p.OrgID,
+		OwnerID:   pick(g.rnd, members).ID,
+		ProjectID: &p.ID,
+		Position:  position,
+		Title:     fmt.Sprintf("%s %s", pick(g.rnd, verbs), pick(g.rnd, objects)),
+		Priority:  weighted(g.rnd, priorities),
+		CreatedAt: created,
+		UpdatedAt: created.Add(time.Duration(g.rnd.Int64N(int64(g.o.Now.Sub(created)) + 1))),
+	}
+	if g.rnd.IntN(3) > 0 {
+		t.Description = paragraph(g.rnd)
+	}
+	switch r := g.rnd.IntN(10); {
+	case r < 4:
+		t.Status = model.StatusDone
+		t.Completed = true
+	case r < 6:
+		t.Status = model.StatusInProgress
+	default:
+		t.Status = model.StatusTodo
+	}
+	if g.rnd.IntN(10) < 7 {
+		assignee := pick(g.rnd, members).ID
+		t.AssigneeID = &assignee
+	}
+	if g.rnd.IntN(10) < 6 {
+		due := g.o.Now.Add(g.days(90) - 30*24*time.Hour).Truncate(24 * time.Hour)
+		t.DueDate = &due
+	}
+	for _, i := range g.rnd.Perm(len(d.tags))[:g.rnd.IntN(3)] {
+		t.TagIDs = append(t.TagIDs, d.tags[i].ID)
+	}
+	return t
+}
+
+// comments adds about Options.Comments comments to t, some of them replies.
+func (g *generator) comments(ctx context.Context, tx storage.Store, t model.Task, members []model.User) error {
+	n := 0
+	if g.o.Comments > 0 {
+		// Spread evenly between none and twice the average.
+		n = g.rnd.IntN(int(2*g.o.Comments) + 1)
+	}
+	at := t.CreatedAt
+	var first *string
+	for range n {
+		at = at.Add(time.Duration(g.rnd.Int64N(int64(48 * time.Hour))))
+		c := model.Comment{TaskID: t.ID, AuthorID: pick(g.rnd, members).ID, Body: sentence(g.rnd), CreatedAt: at}
+		if first != nil && g.rnd.IntN(3) == 0 {
+			c.ReplyTo = first
+		}
+		if err := tx.CreateComment(ctx, &c); err != nil {
+			return err
+		}
+		g.result.Comments++
+		if first == nil {
+			first = &c.ID
+		}
+	}
+	return nil
+}
+
+// days returns a random duration of up to n days.
+func (g *generator) days(n int) time.Duration {
+	return time.Duration(g.rnd.Int64N(int64(n) * int64(24*time.Hour)))
+}
+
+func pick[T any](rnd *rand.Rand, from []T) T {
+	return from[rnd.IntN(len(from))]
+}
+
+// weighted picks one of the values of choices, as often as its weight says.
+func weighted[T any](rnd *rand.Rand, choices []choice[T]) T {
+	total := 0
+	for _, c := range choices {
+		total += c.weight
+	}
+	r := rnd.IntN(total)
+	for _, c := range choices {
+		if r < c.weight {
+			return c.value
+		}
+		r -= c.weight
+	}
+	return choices[len(choices)-1].value
+}
+
+type choice[T any] struct {
+	value  T
+	weight int
+}
+
+// sentence makes up a sentence of a status update.
+func sentence(rnd *rand.Rand) string {
+	return fmt.Sprintf("%s %s %s.", pick(rnd, openers), pick(rnd, objects), pick(rnd, endings))
+}
+
+// paragraph makes up a task description of a few sentences, sometimes with
+// a list, as descriptions are Markdown.
+func paragraph(rnd *rand.Rand) string {
+	var b strings.Builder
+	for i := range 1 + rnd.IntN(4) {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		b.WriteString(sentence(rnd))
+	}
+	if rnd.IntN(4) == 0 {
+		b.WriteString("\n\n")
+		for range 2 + rnd.IntN(3) {
+			fmt.Fprintf(&b, "- %s %s\n", pick(rnd, verbs), pick(rnd, objects))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,27 @@
+package seed
+
+import "starttech-server/model"
+
+// The words data is made of.
+var (
+	companyNames    = []string{"Acme", "Northwind", "Globex", "Initech", "Umbrella", "Hooli", "Vandelay", "Stark", "Wayne", "Tyrell", "Soylent", "Cyberdyne"}
+	companySuffixes = []string{"Labs", "Industries", "Software", "Group", "Studio", "Systems", "Works"}
+	firstNames      = []string{"Ada", "Alan", "Grace", "Linus", "Margaret", "Dennis", "Barbara", "Ken", "Frances", "Edsger", "Radia", "Donald", "Hedy", "John", "Katherine", "Tim", "Sophie", "Guido", "Yukihiro", "Anders"}
+	timezones       = []string{"UTC", "Europe/London", "Europe/Berlin", "America/New_York", "America/Los_Angeles", "Asia/Tokyo", "Asia/Kolkata", "Australia/Sydney", "Africa/Nairobi"}
+	projectNames    = []string{"Website redesign", "Mobile app", "Billing", "Onboarding", "Infrastructure", "Marketing site", "Data platform", "Customer portal", "Security review", "Q3 launch"}
+	tagNames        = []string{"bug", "feature", "design", "backend", "frontend", "docs", "research", "customer"}
+	tagColors       = []string{"#e11d48", "#f59e0b", "#10b981", "#3b82f6", "#8b5cf6", "#64748b"}
+
+	verbs   = []string{"Fix", "Add", "Update", "Remove", "Refactor", "Document", "Test", "Review", "Design", "Investigate", "Migrate", "Speed up"}
+	objects = []string{"the login page", "password reset emails", "the billing export", "search results", "the onboarding checklist", "dark mode", "the settings screen", "CSV import", "push notifications", "the API rate limits", "invoice PDFs", "the dashboard charts", "the signup form", "error reporting", "the release pipeline", "database backups", "the pricing page", "team invitations", "the audit log", "file uploads"}
+	openers = []string{"We should look at", "Customers keep asking about", "I started on", "Blocked on a decision about", "Design is ready for", "QA found a problem with", "Let's pair on", "Finished a first pass at"}
+	endings = []string{"before the release", "this sprint", "after the migration", "once the API is stable", "for the enterprise plan", "on mobile too", "as discussed in the standup", "when we have time"}
+
+	priorities = []choice[model.Priority]{
+		{model.PriorityNone, 35},
+		{model.PriorityLow, 20},
+		{model.PriorityMedium, 25},
+		{model.PriorityHigh, 15},
+		{model.PriorityUrgent, 5},
+	}
+)
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"starttech-server/auth"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+var (
+	// ErrForbidden is returned when the caller can see a resource but their
+	// role does not allow the requested change.
+	ErrForbidden = errors.New("service: forbidden")
+	// ErrLastOwner is returned when a change would leave a project without
+	// an owner.
+	ErrLastOwner = errors.New("service: a project must keep at least one owner")
+)
+
+// orgOf returns the organization the request in ctx works in. Every
+// resource a service touches must belong to it; the API middleware sets it
+// from the token.
+func orgOf(ctx context.Context) string {
+	id, _ := auth.OrgID(ctx)
+	return id
+}
+
+// require returns ErrForbidden unless have grants need.
+func require(have, need model.Role) error {
+	if !have.Allows(need) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// authorizeProject returns the project with the given id if userID holds at
+// least role need in it. Non-members and projects of other organizations get
+// storage.ErrNotFound.
+func authorizeProject(ctx context.Context, store storage.ProjectStore, userID, id string, need model.Role) (model.Project, error) {
+	m, err := store.GetMember(ctx, id, userID)
+	if err != nil {
+		return model.Project{}, err
+	}
+	p, err := store.GetProject(ctx, id)
+	if err != nil {
+		return model.Project{}, err
+	}
+	if p.OrgID != orgOf(ctx) {
+		return model.Project{}, storage.ErrNotFound
+	}
+	if err := require(m.Role, need); err != nil {
+		return model.Project{}, err
+	}
+	return p, nil
+}
+
+// audience returns the users told about a change: every member of the
+// project, or just ownerID when projectID is nil.
+func audience(ctx context.Context, store storage.ProjectStore, ownerID string, projectID *string) []string {
+	if projectID == nil {
+		return []string{ownerID}
+	}
+	members, err := store.ListMembers(ctx, *projectID)
+	if err != nil {
+		slog.WarnContext(ctx, "listing project members for an event", "project_id", *projectID, "err", err)
+		return []string{ownerID}
+	}
+	ids := make([]string, len(members))
+	for i, m := range members {
+		ids[i] = m.UserID
+	}
+	return ids
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode"
+
+	"starttech-server/auth"
+	"starttech-server/i18n"
+	"starttech-server/model"
+	"starttech-server/notifications"
+	"starttech-server/storage"
+)
+
+// Lifetimes of the tokens sent by email.
+const (
+	PasswordResetTTL = time.Hour
+	VerificationTTL  = 48 * time.Hour
+)
+
+// Purposes of the action tokens Accounts issues.
+const (
+	purposeResetPassword = "reset-password"
+	purposeVerifyEmail   = "verify-email"
+)
+
+var (
+	// ErrInvalidToken is returned for an emailed token that is malformed,
+	// expired or already used.
+	ErrInvalidToken = errors.New("service: the token is invalid or has expired")
+	// ErrEmailNotVerified is returned when a user whose email address is
+	// not verified yet tries to sign in with their password.
+	ErrEmailNotVerified = errors.New("service: the email address has not been verified")
+	// ErrAccountDisabled is returned when a user whose account an
+	// administrator has disabled tries to sign in.
+	ErrAccountDisabled = errors.New("service: the account has been disabled")
+)
+
+// Accounts verifies users' email addresses and resets forgotten passwords
+// with signed tokens sent by email. Nothing about a token is stored: a
+// verification token is bound to the address it was sent to, and a reset
+// token to the password it replaces, so it stops working once used.
+type Accounts struct {
+	Users    storage.UserStore
+	Sessions *Sessions
+	Tokens   *auth.Issuer
+	Mail     notifications.Sender
+}
+
+// SendVerification emails u a token that verifies their address. A failure
+// is only logged; the user can ask for another.
+func (s *Accounts) SendVerification(ctx context.Context, u model.User) {
+	token, err := s.Tokens.IssueAction(purposeVerifyEmail, u.ID, u.Email, VerificationTTL)
+	if err != nil {
+		slog.ErrorContext(ctx, "issuing verification token", "user_id", u.ID, "err", err)
+		return
+	}
+	s.send(ctx, u, "Verify your email address",
+		"Welcome, {0}.\n\nTo verify your email address and activate your account, send this token to POST /auth/verify-email:\n\n{1}\n\n"+
+			"The token expires in {2} hours. If you did not sign up, ignore this email.\n",
+		u.Username, token, int(VerificationTTL.Hours()))
+}
+
+// ResendVerification sends another verification email to the user with the
+// given address, if there is one and it is not verified yet. So as not to
+// reveal who has an account, it succeeds either way.
+func (s *Accounts) ResendVerification(ctx context.Context, email string) error {
+	u, err := s.Users.GetUserByEmail(ctx, email)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if u.EmailVerifiedAt == nil {
+		s.SendVerification(ctx, u)
+	}
+	return nil
+}
+
+// VerifyEmail marks the address of the user a verification token was sent
+// to as verified and returns the user.
+func (s *Accounts) VerifyEmail(ctx context.Context, token string) (model.User, error) {
+	u, err := s.verify(ctx, token, purposeVerifyEmail, func(u model.User) string { return u.Email })
+	if err != nil {
+		return model.User{}, err
+	}
+	if u.EmailVerifiedAt != nil {
+		return u, nil
+	}
+	now := time.Now().UTC()
+	u.EmailVerifiedAt = &now
+	if err := s.Users.UpdateUser(ctx, u); err != nil {
+		return model.User{}, err
+	}
+	return u, nil
+}
+
+// ForgotPassword emails the user with the given address a token to reset
+// their password with. Like ResendVerification, it succeeds whether or not
+// there is such a user.
+func (s *Accounts) ForgotPassword(ctx context.Context, email string) error {
+	u, err := s.Users.GetUserByEmail(ctx, email)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	token, err := s.Tokens.IssueAction(purposeResetPassword, u.ID, u.PasswordHash, PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+	s.send(ctx, u, "Reset your password",
+		"Somebody asked to reset the password of your account, {0}.\n\n"+
+			"To choose a new password, send this token with it to POST /auth/reset-password:\n\n{1}\n\n"+
+			"The token expires in {2} minutes and works once. If you did not ask for it, ignore this email; your password stays the same.\n",
+		u.Username, token, int(PasswordResetTTL.Minutes()))
+	return nil
+}
+
+// ForceReset clears u's password, signs them out everywhere and emails them
+// a token to choose a new one with. Administrators use it for accounts
+// that may have been taken over.
+func (s *Accounts) ForceReset(ctx context.Context, u model.User) error {
+	u.PasswordHash = ""
+	if err := s.Users.UpdateUser(ctx, u); err != nil {
+		return err
+	}
+	if _, err := s.Sessions.RevokeAll(ctx, u.ID, ""); err != nil {
+		return err
+	}
+	token, err := s.Tokens.IssueAction(purposeResetPassword, u.ID, u.PasswordHash, PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+	s.send(ctx, u, "Your password has been reset",
+		"An administrator has reset the password of your account, {0}, and signed you out everywhere.\n\n"+
+			"To choose a new password, send this token with it to POST /auth/reset-password:\n\n{1}\n\n"+
+			"The token expires in {2} minutes and works once. Another can be had from POST /auth/forgot-password.\n",
+		u.Username, token, int(PasswordResetTTL.Minutes()))
+	return nil
+}
+
+// ResetPassword sets the password of the user a reset token was sent to and
+// signs them out everywhere. Since the token arrived by email, it also
+// verifies their address.
+func (s *Accounts) ResetPassword(ctx context.Context, token, password string) error {
+	u, err := s.verify(ctx, token, purposeResetPassword, func(u model.User) string { return u.PasswordHash })
+	if err != nil {
+		return err
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = hash
+	if u.EmailVerifiedAt == nil {
+		now := time.Now().UTC()
+		u.EmailVerifiedAt = &now
+	}
+	if err := s.Users.UpdateUser(ctx, u); err != nil {
+		return err
+	}
+	_, err = s.Sessions.RevokeAll(ctx, u.ID, "")
+	return err
+}
+
+// verify checks an action token for purpose against the state of the user
+// it names and returns the user.
+func (s *Accounts) verify(ctx context.Context, token, purpose string, state func(model.User) string) (model.User, error) {
+	var u model.User
+	_, err := s.Tokens.VerifyAction(token, purpose, func(userID string) (string, error) {
+		var err error
+		u, err = s.Users.GetUser(ctx, userID)
+		return state(u), err
+	})
+	if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, storage.ErrNotFound) {
+		return model.User{}, ErrInvalidToken
+	}
+	return u, err
+}
+
+// send emails u subject and body, with the placeholders of body filled in
+// by args, both in u's language. A failure is only logged.
+func (s *Accounts) send(ctx context.Context, u model.User, subject, body string, args ...any) {
+	if s.Mail == nil {
+		return
+	}
+	lang := language(ctx, u)
+	m := notifications.Message{To: u.Email, Subject: i18n.T(lang, subject), Body: i18n.T(lang, body, args...), Language: lang}
+	if err := s.Mail.Send(ctx, m); err != nil {
+		slog.WarnContext(ctx, "sending account email", "user_id", u.ID, "subject", subject, "err", err)
+	}
+}
+
+// CreateVerified creates a user without a password, whose address someone
+// else, such as an OAuth or SCIM identity provider, has verified, named
+// after login, in the language the request asks for, if any. If the name
+// is taken, a random suffix is tried a few times.
+func (s *Accounts) CreateVerified(ctx context.Context, email, login string) (model.User, error) {
+	base := usernameFrom(login)
+	name := base
+	for range 5 {
+		now := time.Now().UTC()
+		u := model.User{Email: email, Username: name, Timezone: model.DefaultTimezone, EmailVerifiedAt: &now, CreatedAt: now}
+		u.Language, _ = i18n.FromContext(ctx)
+		err := s.Users.CreateUser(ctx, &u)
+		if !errors.Is(err, storage.ErrConflict) {
+			return u, err
+		}
+		b := make([]byte, 3)
+		rand.Read(b)
+		name = base + "-" + hex.EncodeToString(b)
+	}
+	return model.User{}, storage.ErrConflict
+}
+
+// usernameFrom turns a provider login into a username of letters, digits,
+// dashes and underscores.
+func usernameFrom(login string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			return r
+		case r == ' ' || r == '.':
+			return '-'
+		}
+		return -1
+	}, strings.TrimSpace(login))
+	if name == "" {
+		return "user"
+	}
+	return name
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// TaskActivity returns one page of the activity on the task with the given
+// id, newest first, including that on its comments and attachments.
+func (s *Tasks) TaskActivity(ctx context.Context, userID, id string, limit int, cursor string) (model.ActivityPage, error) {
+	if _, err := s.authorize(ctx, userID, id, model.RoleViewer); err != nil {
+		return model.ActivityPage{}, err
+	}
+	return activityPage(ctx, s.Log, storage.ActivityFilter{TaskID: id}, limit, cursor)
+}
+
+// Activity returns one page of the activity in the project with the given
+// id, newest first: changes to the project and its members, and to every
+// task that was in it at the time.
+func (s *Projects) Activity(ctx context.Context, userID, id string, limit int, cursor string) (model.ActivityPage, error) {
+	if _, err := authorizeProject(ctx, s.Store, userID, id, model.RoleViewer); err != nil {
+		return model.ActivityPage{}, err
+	}
+	return activityPage(ctx, s.Log, storage.ActivityFilter{ProjectID: id}, limit, cursor)
+}
+
+func activityPage(ctx context.Context, store storage.ActivityStore, f storage.ActivityFilter, limit int, cursor string) (model.ActivityPage, error) {
+	if store == nil {
+		return model.ActivityPage{Items: []model.Activity{}}, nil
+	}
+	switch {
+	case limit <= 0:
+		limit = DefaultPageSize
+	case limit > MaxPageSize:
+		limit = MaxPageSize
+	}
+	if cursor != "" {
+		offset, err := decodeCursor(cursor)
+		if err != nil {
+			return model.ActivityPage{}, err
+		}
+		f.Offset = offset
+	}
+	f.Limit = limit + 1
+	entries, err := store.ListActivity(ctx, f)
+	if err != nil {
+		return model.ActivityPage{}, err
+	}
+	page := model.ActivityPage{Items: entries}
+	if len(entries) > limit {
+		page.Items = entries[:limit]
+		page.NextCursor = encodeCursor(f.Offset + limit)
+	}
+	return page, nil
+}
+
+// record logs a change by userID to task t, or to subjectID on it such as
+// a comment. An empty subjectID stands for the task itself.
+func (s *Tasks) record(ctx context.Context, userID string, typ events.Type, t model.Task, subjectID string, changes []model.Change) {
+	if subjectID == "" {
+		subjectID = t.ID
+	}
+	recordActivity(ctx, s.Log, model.Activity{
+		ProjectID: t.ProjectID,
+		TaskID:    &t.ID,
+		ActorID:   userID,
+		Action:    string(typ),
+		SubjectID: subjectID,
+		Changes:   changes,
+	})
+}
+
+// record logs a change by userID to project p or, when subjectID is set, to
+// the member or setting identified by it.
+func (s *Projects) record(ctx context.Context, userID string, typ events.Type, p model.Project, subjectID string, changes []model.Change) {
+	if subjectID == "" {
+		subjectID = p.ID
+	}
+	recordActivity(ctx, s.Log, model.Activity{
+		ProjectID: &p.ID,
+		ActorID:   userID,
+		Action:    string(typ),
+		SubjectID: subjectID,
+		Changes:   changes,
+	})
+}
+
+// recordActivity stores a. The change it describes has already been made,
+// so a failure is logged instead of failing the request.
+func recordActivity(ctx context.Context, store storage.ActivityStore, a model.Activity) {
+	if store == nil {
+		return
+	}
+	a.OrgID = orgOf(ctx)
+	a.CreatedAt = time.Now().UTC()
+	if a.Changes == nil {
+		a.Changes = []model.Change{}
+	}
+	if err := store.RecordActivity(ctx, &a); err != nil {
+		slog.WarnContext(ctx, "recording activity", "action", a.Action, "subject_id", a.SubjectID, "err", err)
+	}
+}
+
+// unrecorded lists the fields whose changes are not worth an entry of their
+// own in the activity log.
+var unrecorded = []string{"updated_at", "version"}
+
+// diff compares the JSON encodings of old and new, which must be of the same
+// struct type, and returns the fields that differ in name order.
+func diff(old, new any) []model.Change {
+	before, after := fields(old), fields(new)
+	var changes []model.Change
+	for name, b := range before {
+		a := after[name]
+		if slices.Contains(unrecorded, name) || bytes.Equal(a, b) {
+			continue
+		}
+		changes = append(changes, model.Change{Field: name, Old: b, New: a})
+	}
+	slices.SortFunc(changes, func(x, y model.Change) int { return cmp.Compare(x.Field, y.Field) })
+	return changes
+}
+
+// fields splits the JSON object v encodes to into its members. Models
+// always encode cleanly, so errors are not expected.
+func fields(v any) map[string]json.RawMessage {
+	var m map[string]json.RawMessage
+	b, _ := json.Marshal(v)
+	json.Unmarshal(b, &m)
+	return m
+}
+
+// jsonNull stands for the value of a field before it was first set or after
+// it was removed.
+var jsonNull = json.RawMessage("null")
+
+func jsonValue(v any) json.RawMessage {
+	b, _ := json.Marshal(v)
+	return b
+}
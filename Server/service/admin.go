@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Admins are the emails of the server's administrators, matched without
+// regard to case. Their access tokens carry the admin claim that the
+// /admin routes require.
+type Admins []string
+
+// Contains reports whether u is an administrator. Only a verified address
+// counts, so that registering with an administrator's email is no use.
+func (a Admins) Contains(u model.User) bool {
+	if u.EmailVerifiedAt == nil {
+		return false
+	}
+	for _, email := range a {
+		if strings.EqualFold(strings.TrimSpace(email), u.Email) {
+			return true
+		}
+	}
+	return false
+}
+
+// Admin lets the server's administrators manage users and organizations.
+// It trusts its callers to be administrators; the routes check the admin
+// claim of their tokens.
+type Admin struct {
+	Users    storage.UserStore
+	Orgs     storage.OrgStore
+	Usage    storage.UsageStore
+	Sessions *Sessions
+	Accounts *Accounts
+}
+
+// ListUsers returns one page of the users matching query, oldest first.
+func (s *Admin) ListUsers(ctx context.Context, query string, limit int, cursor string) (model.UserPage, error) {
+	limit, offset, err := pageBounds(limit, cursor)
+	if err != nil {
+		return model.UserPage{}, err
+	}
+	users, err := s.Users.ListUsers(ctx, storage.UserFilter{Query: strings.TrimSpace(query), Limit: limit + 1, Offset: offset})
+	if err != nil {
+		return model.UserPage{}, err
+	}
+	page := model.UserPage{Items: users}
+	if len(users) > limit {
+		page.Items = users[:limit]
+		page.NextCursor = encodeCursor(offset + limit)
+	}
+	return page, nil
+}
+
+// GetUser returns the user with the given id, with their organizations and
+// the number of sessions they are signed in with.
+func (s *Admin) GetUser(ctx context.Context, id string) (model.AdminUser, error) {
+	u, err := s.Users.GetUser(ctx, id)
+	if err != nil {
+		return model.AdminUser{}, err
+	}
+	orgs, err := s.Orgs.ListOrgs(ctx, id)
+	if err != nil {
+		return model.AdminUser{}, err
+	}
+	sessions, err := s.Sessions.Store.ListSessions(ctx, id)
+	if err != nil {
+		return model.AdminUser{}, err
+	}
+	return model.AdminUser{User: u, Orgs: orgs, Sessions: len(sessions)}, nil
+}
+
+// DisableUser keeps the user with the given id from signing in and ends
+// their sessions; their API keys stop working too. adminID, who does it,
+// cannot disable themselves.
+func (s *Admin) DisableUser(ctx context.Context, adminID, id string) (model.User, error) {
+	if id == adminID {
+		var v model.ValidationError
+		v.Add("id", "you cannot disable your own account")
+		return model.User{}, v.Err()
+	}
+	u, err := s.Users.GetUser(ctx, id)
+	if err != nil {
+		return model.User{}, err
+	}
+	if u.DisabledAt == nil {
+		now := time.Now().UTC()
+		u.DisabledAt = &now
+		if err := s.Users.UpdateUser(ctx, u); err != nil {
+			return model.User{}, err
+		}
+	}
+	if _, err := s.Sessions.RevokeAll(ctx, id, ""); err != nil {
+		return model.User{}, err
+	}
+	slog.InfoContext(ctx, "user disabled", "user_id", id, "admin_id", adminID)
+	return u, nil
+}
+
+// EnableUser lets a disabled user sign in again.
+func (s *Admin) EnableUser(ctx context.Context, adminID, id string) (model.User, error) {
+	u, err := s.Users.GetUser(ctx, id)
+	if err != nil {
+		return model.User{}, err
+	}
+	if u.DisabledAt == nil {
+		return u, nil
+	}
+	u.DisabledAt = nil
+	if err := s.Users.UpdateUser(ctx, u); err != nil {
+		return model.User{}, err
+	}
+	slog.InfoContext(ctx, "user enabled", "user_id", id, "admin_id", adminID)
+	return u, nil
+}
+
+// ResetPassword clears the password of the user with the given id, signs
+// them out and emails them a token to choose a new one with.
+func (s *Admin) ResetPassword(ctx context.Context, adminID, id string) error {
+	u, err := s.Users.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.Accounts.ForceReset(ctx, u); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "password reset by administrator", "user_id", id, "admin_id", adminID)
+	return nil
+}
+
+// Impersonate checks that adminID may act as the user with the given id and
+// returns that user. Administrators cannot impersonate themselves, nor a
+// disabled user.
+func (s *Admin) Impersonate(ctx context.Context, adminID, id string) (model.User, error) {
+	var v model.ValidationError
+	if id == adminID {
+		v.Add("id", "you cannot impersonate yourself")
+		return model.User{}, v.Err()
+	}
+	u, err := s.Users.GetUser(ctx, id)
+	if err != nil {
+		return model.User{}, err
+	}
+	if u.DisabledAt != nil {
+		return model.User{}, ErrAccountDisabled
+	}
+	slog.InfoContext(ctx, "impersonation started", "user_id", id, "admin_id", adminID)
+	return u, nil
+}
+
+// ListOrgs returns one page of the organizations whose names match query,
+// oldest first, with their usage.
+func (s *Admin) ListOrgs(ctx context.Context, query string, limit int, cursor string) (model.AdminOrgPage, error) {
+	limit, offset, err := pageBounds(limit, cursor)
+	if err != nil {
+		return model.AdminOrgPage{}, err
+	}
+	orgs, err := s.Orgs.FindOrgs(ctx, storage.OrgFilter{Query: strings.TrimSpace(query), Limit: limit + 1, Offset: offset})
+	if err != nil {
+		return model.AdminOrgPage{}, err
+	}
+	page := model.AdminOrgPage{Items: make([]model.AdminOrg, 0, len(orgs))}
+	if len(orgs) > limit {
+		orgs = orgs[:limit]
+		page.NextCursor = encodeCursor(offset + limit)
+	}
+	for _, o := range orgs {
+		usage, err := s.Usage.Usage(ctx, o.ID)
+		if err != nil {
+			return model.AdminOrgPage{}, err
+		}
+		page.Items = append(page.Items, model.AdminOrg{Org: o, Usage: usage})
+	}
+	return page, nil
+}
+
+// GetOrg returns the organization with the given id and its usage.
+func (s *Admin) GetOrg(ctx context.Context, id string) (model.AdminOrg, error) {
+	o, err := s.Orgs.GetOrg(ctx, id)
+	if err != nil {
+		return model.AdminOrg{}, err
+	}
+	usage, err := s.Usage.Usage(ctx, id)
+	if err != nil {
+		return model.AdminOrg{}, err
+	}
+	return model.AdminOrg{Org: o, Usage: usage}, nil
+}
+
+// Stats counts what the whole server holds.
+func (s *Admin) Stats(ctx context.Context) (model.Usage, error) {
+	return s.Usage.Usage(ctx, "")
+}
+
+// pageBounds clamps a requested page size and decodes the cursor into the
+// offset the page starts at.
+func pageBounds(limit int, cursor string) (int, int, error) {
+	switch {
+	case limit <= 0:
+		limit = DefaultPageSize
+	case limit > MaxPageSize:
+		limit = MaxPageSize
+	}
+	offset := 0
+	if cursor != "" {
+		var err error
+		if offset, err = decodeCursor(cursor); err != nil {
+			return 0, 0, err
+		}
+	}
+	return limit, offset, nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"starttech-server/auth"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// UnusedKeyAge is how long an API key goes without being used before it is
+// flagged as unused.
+const UnusedKeyAge = 90 * 24 * time.Hour
+
+// keyTouchInterval limits how often a key's last use is written, so that a
+// busy key does not cost a write per request.
+const keyTouchInterval = time.Minute
+
+// apiKeyPrefixLen is how much of a key is kept in the clear to recognize it
+// by.
+const apiKeyPrefixLen = len(auth.KeyPrefix) + 8
+
+// APIKeys manages the keys users create for scripts and other services. A
+// key works in the organization it was created in, with its owner's rights
+// there or, for a read-only key, only the right to read.
+type APIKeys struct {
+	Store storage.APIKeyStore
+	// Users, if set, lets Authenticate refuse the keys of disabled users.
+	Users storage.UserStore
+	// Orgs confirms that whoever creates a SCIM key may manage the
+	// organization's members.
+	Orgs storage.OrgStore
+}
+
+// List returns userID's keys in the organization, flagging those that have
+// not been used for UnusedKeyAge.
+func (s *APIKeys) List(ctx context.Context, userID string) ([]model.APIKey, error) {
+	keys, err := s.Store.ListAPIKeys(ctx, orgOf(ctx), userID)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-UnusedKeyAge)
+	for i, k := range keys {
+		last := k.CreatedAt
+		if k.LastUsedAt != nil {
+			last = *k.LastUsedAt
+		}
+		keys[i].Unused = last.Before(cutoff)
+	}
+	if keys == nil {
+		keys = []model.APIKey{}
+	}
+	return keys, nil
+}
+
+// Create validates in and creates a key for userID. The returned key is the
+// only place its secret is shown.
+func (s *APIKeys) Create(ctx context.Context, userID string, in model.APIKeyInput) (model.APIKey, error) {
+	secret := auth.KeyPrefix + newToken()
+	k := model.APIKey{
+		OrgID:     orgOf(ctx),
+		OwnerID:   userID,
+		Prefix:    secret[:apiKeyPrefixLen],
+		KeyHash:   hashToken(secret),
+		CreatedAt: time.Now().UTC(),
+	}
+	in.Apply(&k)
+	if err := k.Validate(); err != nil {
+		return model.APIKey{}, err
+	}
+	if k.Scope == model.ScopeSCIM {
+		m, err := s.Orgs.GetOrgMember(ctx, k.OrgID, userID)
+		if err != nil {
+			return model.APIKey{}, err
+		}
+		if !m.Role.Allows(model.OrgAdmin) {
+			return model.APIKey{}, ErrForbidden
+		}
+	}
+	if err := s.Store.CreateAPIKey(ctx, &k); err != nil {
+		return model.APIKey{}, err
+	}
+	k.Key = secret
+	return k, nil
+}
+
+// Revoke deletes userID's key id; requests with it fail from then on.
+func (s *APIKeys) Revoke(ctx context.Context, userID, id string) error {
+	return s.Store.DeleteAPIKey(ctx, userID, id)
+}
+
+// Authenticate looks up key for the auth middleware and records its use.
+func (s *APIKeys) Authenticate(ctx context.Context, key string) (auth.APIKey, error) {
+	k, err := s.Store.GetAPIKeyByHash(ctx, hashToken(key))
+	if errors.Is(err, storage.ErrNotFound) {
+		return auth.APIKey{}, auth.ErrInvalidToken
+	}
+	if err != nil {
+		return auth.APIKey{}, err
+	}
+	if s.Users != nil {
+		u, err := s.Users.GetUser(ctx, k.OwnerID)
+		if errors.Is(err, storage.ErrNotFound) || err == nil && u.DisabledAt != nil {
+			return auth.APIKey{}, auth.ErrInvalidToken
+		}
+		if err != nil {
+			return auth.APIKey{}, err
+		}
+	}
+	now := time.Now().UTC()
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= keyTouchInterval {
+		if err := s.Store.TouchAPIKey(ctx, k.ID, now); err != nil {
+			slog.WarnContext(ctx, "recording API key use", "api_key_id", k.ID, "err", err)
+		}
+	}
+	return auth.APIKey{ID: k.ID, UserID: k.OwnerID, OrgID: k.OrgID, ReadOnly: k.Scope != model.ScopeReadWrite, SCIM: k.Scope == model.ScopeSCIM}, nil
+}
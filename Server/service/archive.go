@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"starttech-server/auth"
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Archive archives the task with the given id if userID may edit it, which
+// keeps it out of listings until it is unarchived. It stays searchable, and
+// archiving it again changes nothing.
+func (s *Tasks) Archive(ctx context.Context, userID, id string, ifMatch []int64) (model.Task, error) {
+	now := time.Now().UTC()
+	return s.Update(ctx, userID, id, ifMatch, func(t *model.Task) {
+		if t.ArchivedAt == nil {
+			t.ArchivedAt = &now
+		}
+	})
+}
+
+// Unarchive brings the task with the given id back into listings if userID
+// may edit it.
+func (s *Tasks) Unarchive(ctx context.Context, userID, id string, ifMatch []int64) (model.Task, error) {
+	return s.Update(ctx, userID, id, ifMatch, func(t *model.Task) {
+		t.ArchivedAt = nil
+	})
+}
+
+// archiveBatch bounds the tasks ArchiveCompleted loads at once.
+const archiveBatch = 100
+
+// ArchiveCompleted archives every completed task that has not changed since
+// before the given time, and returns how many there were. A task that
+// changes while it runs is left for the next time.
+func (s *Tasks) ArchiveCompleted(ctx context.Context, before time.Time) (int, error) {
+	no := false
+	n, skipped := 0, 0
+	for {
+		tasks, err := s.Store.ListTasks(ctx, storage.TaskFilter{
+			Archived: &no, Completed: true, UpdatedBefore: &before,
+			Sort: storage.Sort{Field: storage.SortUpdatedAt}, Offset: skipped, Limit: archiveBatch,
+		})
+		if err != nil {
+			return n, err
+		}
+		for _, t := range tasks {
+			now := time.Now().UTC()
+			t.ArchivedAt = &now
+			t.UpdatedAt = now
+			err := s.Store.UpdateTask(ctx, &t)
+			switch {
+			case errors.Is(err, storage.ErrStale) || errors.Is(err, storage.ErrNotFound):
+				skipped++
+				continue
+			case err != nil:
+				return n, fmt.Errorf("archiving task %s: %w", t.ID, err)
+			}
+			tctx := auth.WithOrgID(ctx, t.OrgID)
+			s.publish(tctx, events.TaskUpdated, s.audience(tctx, t), t)
+			n++
+		}
+		if len(tasks) < archiveBatch {
+			return n, nil
+		}
+	}
+}
+
+// Archive archives the project with the given id if userID is one of its
+// owners, which keeps it out of List until it is unarchived. Its tasks are
+// left as they are.
+func (s *Projects) Archive(ctx context.Context, userID, id string) (model.Project, error) {
+	now := time.Now().UTC()
+	return s.Update(ctx, userID, id, func(p *model.Project) {
+		if p.ArchivedAt == nil {
+			p.ArchivedAt = &now
+		}
+	})
+}
+
+// Unarchive brings the project with the given id back into List if userID
+// is one of its owners.
+func (s *Projects) Unarchive(ctx context.Context, userID, id string) (model.Project, error) {
+	return s.Update(ctx, userID, id, func(p *model.Project) {
+		p.ArchivedAt = nil
+	})
+}
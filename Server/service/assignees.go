@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Values of TaskFilter.AssigneeID that List and Search resolve for the
+// caller: AssigneeMe stands for the caller and AssigneeNone selects the
+// tasks assigned to nobody.
+const (
+	AssigneeMe   = "me"
+	AssigneeNone = "none"
+)
+
+// scopeAssignee resolves the special assignees of f for userID.
+func scopeAssignee(f *storage.TaskFilter, userID string) {
+	switch f.AssigneeID {
+	case AssigneeMe:
+		f.AssigneeID = userID
+	case AssigneeNone:
+		f.AssigneeID, f.Unassigned = "", true
+	}
+}
+
+// checkAssignee verifies that the assignee of t, if any, may see it: the
+// owner of a task outside any project, or a member of its project.
+func (s *Tasks) checkAssignee(ctx context.Context, t model.Task) error {
+	if t.AssigneeID == nil {
+		return nil
+	}
+	var v model.ValidationError
+	if t.ProjectID == nil {
+		if *t.AssigneeID != t.OwnerID {
+			v.Add("assignee_id", "must be the owner of a task outside any project")
+		}
+		return v.Err()
+	}
+	_, err := s.Projects.GetMember(ctx, *t.ProjectID, *t.AssigneeID)
+	if errors.Is(err, storage.ErrNotFound) {
+		v.Add("assignee_id", "is not a member of the project")
+		return v.Err()
+	}
+	return err
+}
+
+// notifyAssignee tells the assignee of t that userID handed them the task.
+// Users who assign themselves are not told.
+func (s *Tasks) notifyAssignee(ctx context.Context, userID string, t model.Task) {
+	if t.AssigneeID == nil || *t.AssigneeID == userID {
+		return
+	}
+	s.publish(ctx, events.TaskAssigned, []string{*t.AssigneeID}, t)
+	s.notify(ctx, model.Notification{
+		OrgID: t.OrgID, UserID: *t.AssigneeID, Kind: model.NotifyAssigned, ActorID: userID,
+		TaskID: t.ID, Title: t.Title, CreatedAt: time.Now().UTC(),
+	})
+}
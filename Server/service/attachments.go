@@ -0,0 +1,517 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"starttech-server/auth"
+	"starttech-server/blob"
+	"starttech-server/events"
+	"starttech-server/metrics"
+	"starttech-server/model"
+	"starttech-server/scan"
+	"starttech-server/storage"
+	"starttech-server/thumb"
+)
+
+var (
+	// ErrTooLarge is returned for uploads above Attachments.MaxSize.
+	ErrTooLarge = errors.New("service: the file is too large")
+	// ErrUnsupportedType is returned for uploads whose media type is not
+	// in Attachments.AllowedTypes.
+	ErrUnsupportedType = errors.New("service: files of this type are not accepted")
+	// ErrInvalidLink is returned for download links that were tampered
+	// with or have expired.
+	ErrInvalidLink = errors.New("service: the download link is invalid or has expired")
+	// ErrNotScanned is returned for downloads of an attachment waiting
+	// for its virus scan.
+	ErrNotScanned = errors.New("service: the file is waiting for its virus scan")
+	// ErrQuarantined is returned for downloads of an attachment the virus
+	// scan flagged.
+	ErrQuarantined = errors.New("service: the file was flagged by the virus scan")
+)
+
+var attachmentScans = metrics.NewCounterVec("attachment_scans_total",
+	"Uploads scanned for malware, by result: clean or quarantined.", "result")
+
+// quarantineDir is the blob key prefix flagged files are moved under.
+const quarantineDir = "quarantine/"
+
+// Attachments manages the files uploaded to tasks. Anybody who can see a
+// task may download its attachments; editors upload and delete them.
+type Attachments struct {
+	Store storage.AttachmentStore
+	Blobs blob.Store
+	// Tasks decides who may see and change the task a file belongs to.
+	Tasks *Tasks
+	// MaxSize is the largest upload accepted, in bytes.
+	MaxSize int64
+	// MaxOrgBytes caps the bytes of attachments each organization stores,
+	// as counted by Usage, with a QuotaError. Zero leaves it uncapped.
+	MaxOrgBytes int64
+	Usage       storage.UsageStore
+	// AllowedTypes lists the media types accepted, where "image/*" stands
+	// for every image type. An empty list accepts anything.
+	AllowedTypes []string
+	// URLKey signs the download links served by the API itself. Stores
+	// implementing blob.Presigner issue their own links instead.
+	URLKey []byte
+	// URLTTL is how long download links stay valid.
+	URLTTL time.Duration
+	// Scanner checks uploads for malware. With one, uploads stay pending,
+	// without a download link, until Scan has passed them, and Enqueue
+	// queues that scan. Without one, uploads are clean at once.
+	Scanner scan.Scanner
+	Enqueue func(ctx context.Context, id string) error
+	// EnqueueThumbnails queues MakeThumbnails for the image with the
+	// given id. Without it, images have no thumbnails.
+	EnqueueThumbnails func(ctx context.Context, id string) error
+}
+
+// List returns the attachments of a task userID can see, each with a fresh
+// download link.
+func (s *Attachments) List(ctx context.Context, userID, taskID string) ([]model.Attachment, error) {
+	if _, err := s.Tasks.authorize(ctx, userID, taskID, model.RoleViewer); err != nil {
+		return nil, err
+	}
+	attachments, err := s.Store.ListAttachments(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range attachments {
+		if err := s.sign(&attachments[i]); err != nil {
+			return nil, err
+		}
+	}
+	return attachments, nil
+}
+
+// Get returns one attachment of a task userID can see.
+func (s *Attachments) Get(ctx context.Context, userID, taskID, id string) (model.Attachment, error) {
+	if _, err := s.Tasks.authorize(ctx, userID, taskID, model.RoleViewer); err != nil {
+		return model.Attachment{}, err
+	}
+	a, err := s.Store.GetAttachment(ctx, id)
+	if err != nil {
+		return model.Attachment{}, err
+	}
+	if a.TaskID != taskID {
+		return model.Attachment{}, storage.ErrNotFound
+	}
+	return a, s.sign(&a)
+}
+
+// Upload stores the file read from r as an attachment of a task userID may
+// edit. Its media type is sniffed from the content rather than trusted from
+// the client. When a Scanner is set, the attachment is pending until its
+// scan is done.
+func (s *Attachments) Upload(ctx context.Context, userID, taskID, filename string, r io.Reader) (model.Attachment, error) {
+	t, err := s.Tasks.authorize(ctx, userID, taskID, model.RoleEditor)
+	if err != nil {
+		return model.Attachment{}, err
+	}
+	a := model.Attachment{
+		ID:         storage.NewID(),
+		TaskID:     taskID,
+		UploaderID: userID,
+		Filename:   cleanFilename(filename),
+		CreatedAt:  time.Now().UTC(),
+	}
+	var v model.ValidationError
+	switch {
+	case a.Filename == "":
+		v.Add("file", "must have a file name")
+	case utf8.RuneCountInString(a.Filename) > model.MaxFilenameLen:
+		v.Add("file", fmt.Sprintf("name must be at most %d characters", model.MaxFilenameLen))
+	}
+	if err := v.Err(); err != nil {
+		return model.Attachment{}, err
+	}
+
+	// Spool the upload to disk: the size must be known before it is
+	// stored, and the type is decided from its first bytes.
+	tmp, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		return model.Attachment{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	n, err := io.Copy(tmp, io.LimitReader(r, s.MaxSize+1))
+	if err != nil {
+		return model.Attachment{}, err
+	}
+	if n > s.MaxSize {
+		return model.Attachment{}, ErrTooLarge
+	}
+	head := make([]byte, 512)
+	k, err := tmp.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return model.Attachment{}, err
+	}
+	a.Size = n
+	a.ContentType = contentType(head[:k], a.Filename)
+	if !s.allowed(a.ContentType) {
+		return model.Attachment{}, ErrUnsupportedType
+	}
+	if s.MaxOrgBytes > 0 {
+		u, err := s.Usage.Usage(ctx, t.OrgID)
+		if err != nil {
+			return model.Attachment{}, err
+		}
+		if u.AttachmentBytes+a.Size > s.MaxOrgBytes {
+			return model.Attachment{}, &QuotaError{Quota: QuotaAttachmentBytes, Limit: s.MaxOrgBytes}
+		}
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return model.Attachment{}, err
+	}
+
+	a.Key = "tasks/" + taskID + "/" + a.ID
+	a.Status = model.AttachmentClean
+	if s.Scanner != nil {
+		a.Status = model.AttachmentPending
+	}
+	if err := s.Blobs.Put(ctx, a.Key, tmp, a.Size, a.ContentType); err != nil {
+		return model.Attachment{}, err
+	}
+	if err := s.Store.CreateAttachment(ctx, &a); err != nil {
+		s.removeBlobs(ctx, []model.Attachment{a})
+		return model.Attachment{}, err
+	}
+	if a.Status == model.AttachmentPending {
+		// An upload nobody will scan could never be downloaded.
+		if err := s.Enqueue(ctx, a.ID); err != nil {
+			if err := s.Store.DeleteAttachment(ctx, a.ID); err != nil {
+				slog.WarnContext(ctx, "deleting unscanned attachment", "attachment_id", a.ID, "err", err)
+			}
+			s.removeBlobs(ctx, []model.Attachment{a})
+			return model.Attachment{}, err
+		}
+	}
+	s.thumbnail(ctx, a)
+	if err := s.sign(&a); err != nil {
+		return model.Attachment{}, err
+	}
+	s.Tasks.publish(ctx, events.AttachmentCreated, s.Tasks.audience(ctx, t), a)
+	s.Tasks.record(ctx, userID, events.AttachmentCreated, t, a.ID, nil)
+	return a, nil
+}
+
+// Delete removes an attachment of a task userID may edit.
+func (s *Attachments) Delete(ctx context.Context, userID, taskID, id string) error {
+	t, err := s.Tasks.authorize(ctx, userID, taskID, model.RoleEditor)
+	if err != nil {
+		return err
+	}
+	a, err := s.Store.GetAttachment(ctx, id)
+	if err != nil {
+		return err
+	}
+	if a.TaskID != taskID {
+		return storage.ErrNotFound
+	}
+	if err := s.Store.DeleteAttachment(ctx, id); err != nil {
+		return err
+	}
+	s.removeBlobs(ctx, []model.Attachment{a})
+	s.Tasks.publish(ctx, events.AttachmentDeleted, s.Tasks.audience(ctx, t), events.Deleted{ID: id})
+	s.Tasks.record(ctx, userID, events.AttachmentDeleted, t, id, nil)
+	return nil
+}
+
+// Open returns the attachment a download link issued by sign points to,
+// and its contents. No token is needed: the signature is the proof of
+// access.
+func (s *Attachments) Open(ctx context.Context, id, expires, signature string) (model.Attachment, io.ReadCloser, error) {
+	if err := s.verify(id, expires, signature); err != nil {
+		return model.Attachment{}, nil, err
+	}
+	a, err := s.Store.GetAttachment(ctx, id)
+	if err != nil {
+		return model.Attachment{}, nil, err
+	}
+	if err := downloadable(a); err != nil {
+		return model.Attachment{}, nil, err
+	}
+	rc, err := s.Blobs.Open(ctx, a.Key)
+	if errors.Is(err, blob.ErrNotFound) {
+		return model.Attachment{}, nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return model.Attachment{}, nil, err
+	}
+	return a, rc, nil
+}
+
+// Link returns one attachment of a task userID can see, as Get does, or
+// ErrNotScanned or ErrQuarantined if it cannot be downloaded.
+func (s *Attachments) Link(ctx context.Context, userID, taskID, id string) (model.Attachment, error) {
+	a, err := s.Get(ctx, userID, taskID, id)
+	if err != nil {
+		return model.Attachment{}, err
+	}
+	return a, downloadable(a)
+}
+
+// downloadable returns why a cannot be downloaded, if it cannot.
+func downloadable(a model.Attachment) error {
+	switch a.Status {
+	case model.AttachmentPending:
+		return ErrNotScanned
+	case model.AttachmentQuarantined:
+		return ErrQuarantined
+	}
+	return nil
+}
+
+// Scan runs the virus scan of the pending attachment with the given id,
+// for the job Upload queued. A clean file becomes downloadable; a flagged
+// one is moved under quarantineDir and quarantined. Either way the task's
+// audience is told. Attachments deleted since, or scanned already, are
+// left be.
+func (s *Attachments) Scan(ctx context.Context, id string) error {
+	a, err := s.Store.GetAttachment(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if a.Status != model.AttachmentPending {
+		return nil
+	}
+	rc, err := s.Blobs.Open(ctx, a.Key)
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	threat, err := s.Scanner.Scan(ctx, rc)
+	rc.Close()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	a.ScannedAt = &now
+	a.Status = model.AttachmentClean
+	uploaded := a
+	if threat != "" {
+		// The file is copied before the record points to the copy, and
+		// removed after, so that no attempt loses it.
+		key := quarantineDir + a.Key
+		if err := s.copyBlob(ctx, a, key); err != nil {
+			return err
+		}
+		a.Key, a.Status, a.Threat = key, model.AttachmentQuarantined, threat
+	}
+	if err := s.Store.UpdateAttachment(ctx, a); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			s.removeBlobs(ctx, []model.Attachment{uploaded, a})
+			return nil
+		}
+		return err
+	}
+	attachmentScans.With(string(a.Status)).Inc()
+	if a.Key != uploaded.Key {
+		s.removeBlobs(ctx, []model.Attachment{uploaded})
+		slog.WarnContext(ctx, "attachment quarantined", "attachment_id", a.ID, "task_id", a.TaskID, "threat", threat)
+	}
+	s.thumbnail(ctx, a)
+
+	t, err := s.Tasks.Store.GetTask(ctx, a.TaskID)
+	if err != nil {
+		slog.WarnContext(ctx, "announcing attachment scan", "attachment_id", a.ID, "err", err)
+		return nil
+	}
+	ctx = auth.WithOrgID(ctx, t.OrgID)
+	if err := s.sign(&a); err != nil {
+		return err
+	}
+	s.Tasks.publish(ctx, events.AttachmentScanned, s.Tasks.audience(ctx, t), a)
+	return nil
+}
+
+// copyBlob copies the bytes of a to key, as a type no browser renders.
+func (s *Attachments) copyBlob(ctx context.Context, a model.Attachment, key string) error {
+	rc, err := s.Blobs.Open(ctx, a.Key)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return s.Blobs.Put(ctx, key, rc, a.Size, "application/octet-stream")
+}
+
+// sign sets a.URL to a download link valid for URLTTL, and
+// a.ThumbnailURL for images, if a can be downloaded.
+func (s *Attachments) sign(a *model.Attachment) error {
+	if downloadable(*a) != nil {
+		a.URL, a.ThumbnailURL, a.URLExpiresAt = "", "", nil
+		return nil
+	}
+	expires := time.Now().UTC().Add(s.URLTTL).Truncate(time.Second)
+	a.URLExpiresAt = &expires
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	q.Set("signature", s.signature(a.ID, expires.Unix()))
+	// Thumbnails are small, so they are always served by the API.
+	if thumb.Supported(a.ContentType) {
+		a.ThumbnailURL = "/attachments/" + a.ID + "/thumb?" + q.Encode()
+	}
+	if p, ok := s.Blobs.(blob.Presigner); ok {
+		u, err := p.PresignGet(a.Key, a.Filename, s.URLTTL)
+		a.URL = u
+		return err
+	}
+	a.URL = "/attachments/" + a.ID + "?" + q.Encode()
+	return nil
+}
+
+// verify returns ErrInvalidLink unless expires and signature, from a link
+// issued by sign for the attachment with the given id, are intact and have
+// not expired.
+func (s *Attachments) verify(id, expires, signature string) error {
+	at, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > at || !hmac.Equal([]byte(signature), []byte(s.signature(id, at))) {
+		return ErrInvalidLink
+	}
+	return nil
+}
+
+func (s *Attachments) signature(id string, expires int64) string {
+	h := hmac.New(sha256.New, s.URLKey)
+	fmt.Fprintf(h, "%s\n%d", id, expires)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// allowed reports whether uploads of the media type ct are accepted.
+func (s *Attachments) allowed(ct string) bool {
+	if len(s.AllowedTypes) == 0 {
+		return true
+	}
+	mt, _, _ := mime.ParseMediaType(ct)
+	for _, pattern := range s.AllowedTypes {
+		if ok, _ := path.Match(strings.ToLower(strings.TrimSpace(pattern)), mt); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// removeBlobs deletes the stored contents of attachments, and their
+// thumbnails, whose records are gone or were never written. Failures only leave orphaned blobs behind, so they are
+// logged rather than returned.
+func (s *Attachments) removeBlobs(ctx context.Context, attachments []model.Attachment) {
+	for _, a := range attachments {
+		keys := []string{a.Key}
+		if thumb.Supported(a.ContentType) {
+			for _, w := range thumb.Widths {
+				keys = append(keys, thumbKey(a, w))
+			}
+		}
+		for _, key := range keys {
+			if err := s.Blobs.Delete(ctx, key); err != nil {
+				slog.WarnContext(ctx, "deleting attachment blob", "attachment_id", a.ID, "key", key, "err", err)
+			}
+		}
+	}
+}
+
+// contentType decides the media type of a file from its first bytes. Where
+// sniffing only finds a generic type, such as a zip archive for a .docx
+// document, the file name's extension refines it, unless the file is a
+// program.
+func contentType(head []byte, filename string) string {
+	if ct := executableType(head); ct != "" {
+		return ct
+	}
+	ct := http.DetectContentType(head)
+	mt, _, _ := mime.ParseMediaType(ct)
+	if mt == "application/octet-stream" || mt == "application/zip" || mt == "text/plain" {
+		if byExt := mime.TypeByExtension(path.Ext(filename)); byExt != "" && refines(mt, byExt) {
+			return byExt
+		}
+	}
+	return ct
+}
+
+// executableType returns the media type of head if it starts a Windows,
+// Linux or macOS program, which http.DetectContentType takes for any other
+// binary, and "" otherwise.
+func executableType(head []byte) string {
+	switch {
+	case len(head) >= 0x40 && bytes.HasPrefix(head, []byte("MZ")):
+		// A DOS header pointing at the PE header of a Windows program.
+		off := int(binary.LittleEndian.Uint32(head[0x3c:]))
+		if off+4 <= len(head) && string(head[off:off+4]) == "PE\x00\x00" {
+			return "application/vnd.microsoft.portable-executable"
+		}
+	case bytes.HasPrefix(head, []byte("\x7fELF")):
+		return "application/x-elf"
+	case len(head) >= 4:
+		switch binary.BigEndian.Uint32(head) {
+		case 0xfeedface, 0xfeedfacf, 0xcefaedfe, 0xcffaedfe:
+			return "application/x-mach-binary"
+		}
+	}
+	return ""
+}
+
+// refines reports whether the extension's type byExt is a plausible
+// specialisation of the sniffed type mt: text stays text, and archives or
+// unrecognised binaries may be office documents, which sniffing cannot
+// tell apart.
+func refines(mt, byExt string) bool {
+	ext, _, _ := mime.ParseMediaType(byExt)
+	if mt == "text/plain" {
+		return ext == "application/json" || strings.HasPrefix(ext, "text/") && ext != "text/html" && ext != "text/javascript"
+	}
+	return strings.HasPrefix(ext, "application/vnd.") || ext == "application/msword" || ext == "application/epub+zip"
+}
+
+// cleanFilename keeps the last element of a client-supplied path and drops
+// control characters, so the name is safe to show and to send back in
+// Content-Disposition.
+func cleanFilename(name string) string {
+	name = name[strings.LastIndexAny(name, `/\`)+1:]
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	return strings.TrimSpace(name)
+}
+
+// deleteTask removes a task through store and then the blobs of its
+// attachments, which the store does not know how to reach.
+func (s *Attachments) deleteTask(ctx context.Context, store storage.TaskStore, id string) error {
+	attachments, err := s.Store.ListAttachments(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := store.DeleteTask(ctx, id); err != nil {
+		return err
+	}
+	s.removeBlobs(ctx, attachments)
+	return nil
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// auditBatch is how many events Export and Verify read at a time.
+const auditBatch = 500
+
+// maxAuditText bounds the user agent and detail kept, in bytes, since
+// either may come from whoever sends the request.
+const maxAuditText = 512
+
+// Audit keeps the audit log of security-relevant events. Each event's hash
+// is an HMAC, under Key, of the event and the hash before it, so someone
+// able to edit the database but not knowing the key cannot rewrite the
+// log without Verify noticing. A nil *Audit records nothing.
+type Audit struct {
+	Store storage.AuditStore
+	Key   []byte
+}
+
+// Record appends e to the log, stamped with the current time. The event
+// has already happened, so a failure is logged instead of failing the
+// request.
+func (s *Audit) Record(ctx context.Context, e model.AuditEvent) {
+	if s == nil || s.Store == nil {
+		return
+	}
+	// Databases keep microseconds; hashing more would not survive a
+	// round trip.
+	e.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	e.UserAgent, e.Detail = truncate(e.UserAgent, maxAuditText), truncate(e.Detail, maxAuditText)
+	if err := s.Store.AppendAuditEvent(ctx, &e, s.hash); err != nil {
+		slog.ErrorContext(ctx, "recording audit event", "action", e.Action, "actor_id", e.ActorID, "err", err)
+	}
+}
+
+// List returns one page of the events matching f, newest first.
+func (s *Audit) List(ctx context.Context, f storage.AuditFilter, limit int, cursor string) (model.AuditPage, error) {
+	limit, offset, err := pageBounds(limit, cursor)
+	if err != nil {
+		return model.AuditPage{}, err
+	}
+	if err := validAuditRange(f); err != nil {
+		return model.AuditPage{}, err
+	}
+	f.Oldest, f.Limit, f.Offset = false, limit+1, offset
+	events, err := s.Store.ListAuditEvents(ctx, f)
+	if err != nil {
+		return model.AuditPage{}, err
+	}
+	page := model.AuditPage{Items: events}
+	if len(events) > limit {
+		page.Items = events[:limit]
+		page.NextCursor = encodeCursor(offset + limit)
+	}
+	return page, nil
+}
+
+// Export calls fn with every event matching f, oldest first, stopping at
+// the first error.
+func (s *Audit) Export(ctx context.Context, f storage.AuditFilter, fn func(model.AuditEvent) error) error {
+	if err := validAuditRange(f); err != nil {
+		return err
+	}
+	f.Oldest, f.Limit = true, auditBatch
+	for f.Offset = 0; ; f.Offset += auditBatch {
+		events, err := s.Store.ListAuditEvents(ctx, f)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		if len(events) < auditBatch {
+			return nil
+		}
+	}
+}
+
+// Verify recomputes the hash of every stored event and checks that each
+// links to the one before it. Purging removes the oldest events, so the
+// chain is checked from the oldest one kept.
+func (s *Audit) Verify(ctx context.Context) (model.AuditVerification, error) {
+	var (
+		v    model.AuditVerification
+		prev *model.AuditEvent
+	)
+	f := storage.AuditFilter{Oldest: true, Limit: auditBatch}
+	for ; ; f.Offset += auditBatch {
+		events, err := s.Store.ListAuditEvents(ctx, f)
+		if err != nil {
+			return v, err
+		}
+		for i, e := range events {
+			v.Checked++
+			if e.Hash != s.hash(e) || prev != nil && (e.Seq != prev.Seq+1 || e.PrevHash != prev.Hash) {
+				v.BrokenAt = e.Seq
+				return v, nil
+			}
+			prev = &events[i]
+		}
+		if len(events) < auditBatch {
+			v.OK = true
+			return v, nil
+		}
+	}
+}
+
+// Purge deletes the events from before the given time and returns how
+// many there were.
+func (s *Audit) Purge(ctx context.Context, before time.Time) (int, error) {
+	return s.Store.PurgeAuditEvents(ctx, before)
+}
+
+// Purgeable counts the events Purge would delete.
+func (s *Audit) Purgeable(ctx context.Context, before time.Time) (int, error) {
+	return s.Store.PurgeableAuditEvents(ctx, before)
+}
+
+// hash returns the HMAC of e's fields, other than Hash itself, in a
+// fixed order.
+func (s *Audit) hash(e model.AuditEvent) string {
+	b, err := json.Marshal([]any{
+		e.Seq, e.ID, e.Action, e.ActorID, e.OrgID, e.TargetID, e.IP, e.UserAgent, e.Detail,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano), e.PrevHash,
+	})
+	if err != nil {
+		panic("service: encoding audit event: " + err.Error())
+	}
+	mac := hmac.New(sha256.New, s.Key)
+	mac.Write(b)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func validAuditRange(f storage.AuditFilter) error {
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		var v model.ValidationError
+		v.Add("to", "must be after from")
+		return v.Err()
+	}
+	return nil
+}
@@ -0,0 +1,1000 @@
+package service
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"starttech-server/blob"
+	"starttech-server/i18n"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// ErrInvalidBackup is returned by Restore for archives that are not
+// backups, are damaged or were written by a later version of the server.
+var ErrInvalidBackup = errors.New("service: not a valid backup")
+
+// Names of the entries of a backup archive.
+const (
+	backupRecordsName   = "backup.jsonl"
+	backupAttachmentDir = "attachments/"
+)
+
+// Backups writes whole organizations to archives and rebuilds them from
+// one, on this server or another. An archive is a gzipped tar file holding
+// backup.jsonl, one model.BackupRecord per line, followed by the file of
+// every attachment under attachments/<attachment id>.
+//
+// Sessions, API keys, notifications, mentions, activity, revisions,
+// comment history, webhooks, integration links, calendar feeds,
+// invitations and board imports are left out.
+type Backups struct {
+	Store storage.Store
+	Blobs blob.Store
+}
+
+// Archive is a backup ready to be written, with its records gathered in a
+// temporary file. Close removes it.
+type Archive struct {
+	Org model.Org
+	// head holds the header and the users, which are only known once
+	// every other record has been read.
+	head        []byte
+	records     *os.File
+	attachments []model.Attachment
+	blobs       blob.Store
+	createdAt   time.Time
+}
+
+// Create gathers the records of the organization orgID into an Archive.
+// Everything that can fail in the database does so here, before the
+// archive is written.
+func (s *Backups) Create(ctx context.Context, adminID, orgID string) (*Archive, error) {
+	org, err := s.Store.GetOrg(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp("", "backup-*.jsonl")
+	if err != nil {
+		return nil, err
+	}
+	a := &Archive{Org: org, records: f, blobs: s.Blobs, createdAt: time.Now().UTC()}
+	w := newBackupWriter(f)
+	if err := s.collect(ctx, org.ID, w, a); err != nil {
+		a.Close()
+		return nil, err
+	}
+	if err := w.buf.Flush(); err != nil {
+		a.Close()
+		return nil, err
+	}
+
+	var head bytes.Buffer
+	hw := newBackupWriter(&head)
+	hw.write(model.BackupHeaderRecord, model.BackupHeader{Format: model.BackupFormat, Org: org, CreatedAt: a.createdAt})
+	var users []model.BackupUser
+	for id := range w.users {
+		u, err := s.Store.GetUser(ctx, id)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			a.Close()
+			return nil, err
+		}
+		users = append(users, model.BackupUser{
+			ID:              u.ID,
+			Email:           u.Email,
+			Username:        u.Username,
+			PasswordHash:    u.PasswordHash,
+			Timezone:        u.Timezone,
+			Language:        u.Language,
+			EmailVerifiedAt: u.EmailVerifiedAt,
+			DisabledAt:      u.DisabledAt,
+			CreatedAt:       u.CreatedAt,
+		})
+	}
+	slices.SortFunc(users, func(a, b model.BackupUser) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	for _, u := range users {
+		hw.write(model.BackupUserRecord, u)
+	}
+	if err := hw.buf.Flush(); err != nil {
+		a.Close()
+		return nil, err
+	}
+	if hw.err != nil {
+		a.Close()
+		return nil, hw.err
+	}
+	a.head = head.Bytes()
+	slog.InfoContext(ctx, "backup created", "org_id", org.ID, "admin_id", adminID, "attachments", len(a.attachments))
+	return a, nil
+}
+
+// collect writes the records of the organization orgID, but for its
+// users, so that each refers only to those before it.
+func (s *Backups) collect(ctx context.Context, orgID string, w *backupWriter, a *Archive) error {
+	members, err := s.Store.ListOrgMembers(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	// Owners first: the restore creates the organization with the first
+	// member.
+	slices.SortStableFunc(members, func(a, b model.OrgMembership) int {
+		return boolOrder(a.Role == model.OrgOwner, b.Role == model.OrgOwner)
+	})
+	for _, m := range members {
+		w.refer(m.UserID)
+		w.write(model.BackupOrgMemberRecord, m)
+	}
+
+	projects, err := s.Store.OrgProjects(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	for _, p := range projects {
+		w.refer(p.OwnerID)
+		w.write(model.BackupProjectRecord, p)
+		members, err := s.Store.ListMembers(ctx, p.ID)
+		if err != nil {
+			return err
+		}
+		for _, m := range members {
+			w.refer(m.UserID)
+			w.write(model.BackupMemberRecord, m)
+		}
+		fields, err := s.Store.ListFields(ctx, p.ID)
+		if err != nil {
+			return err
+		}
+		for _, f := range fields {
+			w.write(model.BackupFieldRecord, f)
+		}
+	}
+
+	tags, err := s.Store.OrgTags(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	for _, t := range tags {
+		w.refer(t.OwnerID)
+		w.write(model.BackupTagRecord, t)
+	}
+
+	tasks, err := s.orgTasks(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	for _, t := range tasks {
+		w.refer(t.OwnerID)
+		w.referPtr(t.AssigneeID)
+		w.write(model.BackupTaskRecord, t)
+	}
+	for _, t := range tasks {
+		deps, err := s.Store.Blockers(ctx, t.ID)
+		if err != nil {
+			return err
+		}
+		for _, d := range deps {
+			w.write(model.BackupDependencyRecord, d)
+		}
+		items, err := s.Store.ListChecklist(ctx, t.ID)
+		if err != nil {
+			return err
+		}
+		for _, c := range items {
+			w.write(model.BackupChecklistRecord, c)
+		}
+		comments, err := s.Store.ListComments(ctx, t.ID)
+		if err != nil {
+			return err
+		}
+		for _, c := range comments {
+			w.refer(c.AuthorID)
+			w.write(model.BackupCommentRecord, c)
+		}
+		files, err := s.Store.ListAttachments(ctx, t.ID)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			// Files that have not passed their virus scan stay out.
+			if f.Status != model.AttachmentClean {
+				continue
+			}
+			w.refer(f.UploaderID)
+			w.write(model.BackupAttachmentRecord, f)
+			a.attachments = append(a.attachments, f)
+		}
+	}
+
+	entries, err := s.Store.ListTimeEntries(ctx, storage.TimeEntryFilter{OrgID: orgID})
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		w.refer(e.UserID)
+		w.write(model.BackupTimeEntryRecord, e)
+	}
+
+	views, err := s.Store.OrgViews(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	for _, v := range views {
+		w.refer(v.OwnerID)
+		w.write(model.BackupViewRecord, v)
+	}
+	templates, err := s.Store.OrgTemplates(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	for _, t := range templates {
+		w.refer(t.OwnerID)
+		w.write(model.BackupTemplateRecord, t)
+	}
+	return w.err
+}
+
+// orgTasks returns every task of the organization orgID, in the trash or
+// not, each after its parent.
+func (s *Backups) orgTasks(ctx context.Context, orgID string) ([]model.Task, error) {
+	byAge := storage.Sort{Field: storage.SortCreatedAt}
+	tasks, err := s.Store.ListTasks(ctx, storage.TaskFilter{OrgID: orgID, Sort: byAge})
+	if err != nil {
+		return nil, err
+	}
+	trashed, err := s.Store.ListTasks(ctx, storage.TaskFilter{OrgID: orgID, Trashed: true, Sort: byAge})
+	if err != nil {
+		return nil, err
+	}
+	tasks = append(tasks, trashed...)
+
+	children := make(map[string][]model.Task)
+	ids := make(map[string]bool, len(tasks))
+	for _, t := range tasks {
+		ids[t.ID] = true
+	}
+	var sorted []model.Task
+	for _, t := range tasks {
+		if t.ParentID != nil && ids[*t.ParentID] {
+			children[*t.ParentID] = append(children[*t.ParentID], t)
+		} else {
+			sorted = append(sorted, t)
+		}
+	}
+	for i := 0; i < len(sorted); i++ {
+		sorted = append(sorted, children[sorted[i].ID]...)
+	}
+	return sorted, nil
+}
+
+// boolOrder orders true before false.
+func boolOrder(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return -1
+	}
+	return 1
+}
+
+// backupWriter writes backup records as JSON lines, remembering the users
+// they refer to and the first error.
+type backupWriter struct {
+	buf   *bufio.Writer
+	enc   *json.Encoder
+	users map[string]bool
+	err   error
+}
+
+func newBackupWriter(w io.Writer) *backupWriter {
+	buf := bufio.NewWriter(w)
+	return &backupWriter{buf: buf, enc: json.NewEncoder(buf), users: make(map[string]bool)}
+}
+
+func (w *backupWriter) write(typ string, v any) {
+	if w.err != nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		w.err = err
+		return
+	}
+	w.err = w.enc.Encode(model.BackupRecord{Type: typ, Data: data})
+}
+
+func (w *backupWriter) refer(userID string) {
+	if userID != "" {
+		w.users[userID] = true
+	}
+}
+
+func (w *backupWriter) referPtr(userID *string) {
+	if userID != nil {
+		w.refer(*userID)
+	}
+}
+
+// WriteTo writes the archive to w, with the files of the attachments read
+// from the blob store. Files missing from the store are left out, and
+// their records skipped by the restore.
+func (a *Archive) WriteTo(ctx context.Context, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	info, err := a.records.Stat()
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:    backupRecordsName,
+		Mode:    0o644,
+		Size:    int64(len(a.head)) + info.Size(),
+		ModTime: a.createdAt,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := tw.Write(a.head); err != nil {
+		return err
+	}
+	if _, err := a.records.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(tw, a.records); err != nil {
+		return err
+	}
+
+	for _, att := range a.attachments {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rc, err := a.blobs.Open(ctx, att.Key)
+		if errors.Is(err, blob.ErrNotFound) {
+			slog.WarnContext(ctx, "attachment missing from backup", "attachment_id", att.ID, "key", att.Key)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = tw.WriteHeader(&tar.Header{
+			Name:    backupAttachmentDir + att.ID,
+			Mode:    0o644,
+			Size:    att.Size,
+			ModTime: att.CreatedAt,
+		})
+		if err == nil {
+			_, err = io.CopyN(tw, rc, att.Size)
+		}
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("copying attachment %s: %w", att.ID, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Close removes the archive's temporary file.
+func (a *Archive) Close() error {
+	a.records.Close()
+	return os.Remove(a.records.Name())
+}
+
+// Restore rebuilds the organization backed up in the archive read from r
+// as a new organization, in one transaction. Every record gets a new ID.
+// Users are matched by email with the accounts of this server, and those
+// without one are created with the password and verification of the
+// backup, renamed if their username is taken.
+func (s *Backups) Restore(ctx context.Context, adminID string, r io.Reader) (model.RestoreResult, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return model.RestoreResult{}, fmt.Errorf("%w: %w", ErrInvalidBackup, err)
+	}
+	tr := tar.NewReader(gz)
+	hdr, err := tr.Next()
+	if err != nil {
+		return model.RestoreResult{}, fmt.Errorf("%w: %w", ErrInvalidBackup, err)
+	}
+	if hdr.Name != backupRecordsName {
+		return model.RestoreResult{}, fmt.Errorf("%w: the archive does not start with %s", ErrInvalidBackup, backupRecordsName)
+	}
+
+	var (
+		rs  *restorer
+		put []string
+	)
+	err = s.Store.InTx(ctx, func(tx storage.Store) error {
+		rs = newRestorer(tx, adminID)
+		if err := rs.records(ctx, tr); err != nil {
+			return err
+		}
+		if _, err := rs.orgID(ctx); err != nil {
+			return err
+		}
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidBackup, err)
+			}
+			oldID, ok := strings.CutPrefix(hdr.Name, backupAttachmentDir)
+			a, pending := rs.attachments[oldID]
+			if !ok || !pending {
+				continue
+			}
+			delete(rs.attachments, oldID)
+			a.Size = hdr.Size
+			if err := s.Blobs.Put(ctx, a.Key, tr, a.Size, a.ContentType); err != nil {
+				return err
+			}
+			put = append(put, a.Key)
+			if err := tx.CreateAttachment(ctx, &a); err != nil {
+				return err
+			}
+			rs.result.Counts[model.BackupAttachmentRecord]++
+		}
+		for oldID := range rs.attachments {
+			rs.warn(model.BackupAttachmentRecord, oldID, "its file is not in the archive")
+		}
+		return nil
+	})
+	if err != nil {
+		for _, key := range put {
+			if err := s.Blobs.Delete(context.WithoutCancel(ctx), key); err != nil {
+				slog.WarnContext(ctx, "deleting blob of failed restore", "key", key, "err", err)
+			}
+		}
+		// An archive cut short.
+		if errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, ErrInvalidBackup) {
+			err = fmt.Errorf("%w: %w", ErrInvalidBackup, err)
+		}
+		return model.RestoreResult{}, err
+	}
+	slices.Sort(rs.result.Warnings)
+	slog.InfoContext(ctx, "backup restored", "org_id", rs.result.Org.ID, "from_org_id", rs.fromOrgID, "admin_id", adminID,
+		"users_created", rs.result.UsersCreated, "warnings", len(rs.result.Warnings))
+	return rs.result, nil
+}
+
+// restorer creates the records of a backup, mapping the IDs of the backup
+// to those of the records created.
+type restorer struct {
+	tx        storage.Store
+	adminID   string
+	now       time.Time
+	header    *model.BackupHeader
+	fromOrgID string
+	result    model.RestoreResult
+
+	users    map[string]string
+	projects map[string]string
+	tags     map[string]string
+	fields   map[string]string
+	tasks    map[string]string
+	comments map[string]string
+	// attachments wait for their file, by the ID in the backup.
+	attachments map[string]model.Attachment
+}
+
+func newRestorer(tx storage.Store, adminID string) *restorer {
+	return &restorer{
+		tx:          tx,
+		adminID:     adminID,
+		now:         time.Now().UTC(),
+		result:      model.RestoreResult{Counts: make(map[string]int), Warnings: []string{}},
+		users:       make(map[string]string),
+		projects:    make(map[string]string),
+		tags:        make(map[string]string),
+		fields:      make(map[string]string),
+		tasks:       make(map[string]string),
+		comments:    make(map[string]string),
+		attachments: make(map[string]model.Attachment),
+	}
+}
+
+// records creates the records of backup.jsonl, read from r.
+func (rs *restorer) records(ctx context.Context, r io.Reader) error {
+	dec := json.NewDecoder(r)
+	unknown := make(map[string]int)
+	for {
+		var rec model.BackupRecord
+		err := dec.Decode(&rec)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidBackup, err)
+		}
+		if rs.header == nil && rec.Type != model.BackupHeaderRecord {
+			return fmt.Errorf("%w: %s does not start with a header", ErrInvalidBackup, backupRecordsName)
+		}
+		if err := rs.record(ctx, rec); errors.Is(err, errUnknownRecord) {
+			unknown[rec.Type]++
+		} else if err != nil {
+			return err
+		}
+	}
+	if rs.header == nil {
+		return fmt.Errorf("%w: %s is empty", ErrInvalidBackup, backupRecordsName)
+	}
+	for typ, n := range unknown {
+		rs.result.Warnings = append(rs.result.Warnings, fmt.Sprintf("skipped %d records of unknown type %q", n, typ))
+	}
+	return nil
+}
+
+var errUnknownRecord = errors.New("unknown record type")
+
+// record creates the record rec describes.
+func (rs *restorer) record(ctx context.Context, rec model.BackupRecord) error {
+	switch rec.Type {
+	case model.BackupHeaderRecord:
+		var h model.BackupHeader
+		if err := decodeRecord(rec, &h); err != nil {
+			return err
+		}
+		if rs.header != nil {
+			return fmt.Errorf("%w: more than one header", ErrInvalidBackup)
+		}
+		if h.Format < 1 || h.Format > model.BackupFormat {
+			return fmt.Errorf("%w: format %d is not supported by this server, which reads up to %d", ErrInvalidBackup, h.Format, model.BackupFormat)
+		}
+		rs.header, rs.fromOrgID = &h, h.Org.ID
+		return nil
+
+	case model.BackupUserRecord:
+		var u model.BackupUser
+		if err := decodeRecord(rec, &u); err != nil {
+			return err
+		}
+		id, err := rs.user(ctx, u)
+		if err != nil {
+			return err
+		}
+		rs.users[u.ID] = id
+		rs.result.Counts[rec.Type]++
+		return nil
+
+	case model.BackupOrgMemberRecord:
+		var m model.OrgMembership
+		if err := decodeRecord(rec, &m); err != nil {
+			return err
+		}
+		userID, ok := rs.users[m.UserID]
+		if !ok {
+			rs.warn(rec.Type, m.UserID, "the user is not in the backup")
+			return nil
+		}
+		if rs.result.Org.ID == "" {
+			if err := rs.createOrg(ctx, userID); err != nil {
+				return err
+			}
+		}
+		m.OrgID, m.UserID = rs.result.Org.ID, userID
+		if err := rs.tx.SaveOrgMember(ctx, &m); err != nil {
+			return err
+		}
+		rs.result.Counts[rec.Type]++
+		return nil
+
+	case model.BackupProjectRecord:
+		var p model.Project
+		if err := decodeRecord(rec, &p); err != nil {
+			return err
+		}
+		oldID := p.ID
+		ownerID, ok := rs.users[p.OwnerID]
+		if !ok {
+			rs.warn(rec.Type, oldID, "its owner is not in the backup")
+			return nil
+		}
+		orgID, err := rs.orgID(ctx)
+		if err != nil {
+			return err
+		}
+		p.OrgID, p.OwnerID = orgID, ownerID
+		if err := rs.tx.CreateProject(ctx, &p); err != nil {
+			return err
+		}
+		rs.projects[oldID] = p.ID
+		rs.result.Counts[rec.Type]++
+		return nil
+
+	case model.BackupMemberRecord:
+		var m model.Member
+		if err := decodeRecord(rec, &m); err != nil {
+			return err
+		}
+		projectID, ok := rs.projects[m.ProjectID]
+		if !ok {
+			return nil // skipped with its project
+		}
+		userID, ok := rs.users[m.UserID]
+		if !ok {
+			rs.warn(rec.Type, m.UserID, "the user is not in the backup")
+			return nil
+		}
+		m.ProjectID, m.UserID = projectID, userID
+		if err := rs.tx.SaveMember(ctx, &m); err != nil {
+			return err
+		}
+		rs.result.Counts[rec.Type]++
+		return nil
+
+	case model.BackupFieldRecord:
+		var f model.CustomField
+		if err := decodeRecord(rec, &f); err != nil {
+			return err
+		}
+		oldID := f.ID
+		projectID, ok := rs.projects[f.ProjectID]
+		if !ok {
+			return nil // skipped with its project
+		}
+		f.ProjectID = projectID
+		if err := rs.tx.CreateField(ctx, &f); err != nil {
+			return err
+		}
+		rs.fields[oldID] = f.ID
+		rs.result.Counts[rec.Type]++
+		return nil
+
+	case model.BackupTagRecord:
+		var t model.Tag
+		if err := decodeRecord(rec, &t); err != nil {
+			return err
+		}
+		oldID := t.ID
+		ownerID, ok := rs.users[t.OwnerID]
+		if !ok {
+			rs.warn(rec.Type, oldID, "its owner is not in the backup")
+			return nil
+		}
+		orgID, err := rs.orgID(ctx)
+		if err != nil {
+			return err
+		}
+		t.OrgID, t.OwnerID = orgID, ownerID
+		if err := rs.tx.CreateTag(ctx, &t); err != nil {
+			return err
+		}
+		rs.tags[oldID] = t.ID
+		rs.result.Counts[rec.Type]++
+		return nil
+
+	case model.BackupTaskRecord:
+		var t model.Task
+		if err := decodeRecord(rec, &t); err != nil {
+			return err
+		}
+		oldID := t.ID
+		ownerID, ok := rs.users[t.OwnerID]
+		if !ok {
+			rs.warn(rec.Type, oldID, "its owner is not in the backup")
+			return nil
+		}
+		orgID, err := rs.orgID(ctx)
+		if err != nil {
+			return err
+		}
+		t.OrgID, t.OwnerID = orgID, ownerID
+		t.AssigneeID = mapRef(rs.users, t.AssigneeID)
+		t.ProjectID = mapRef(rs.projects, t.ProjectID)
+		t.ParentID = mapRef(rs.tasks, t.ParentID)
+		t.TagIDs = mapIDs(rs.tags, t.TagIDs)
+		fields := make(map[string]any, len(t.Fields))
+		for id, v := range t.Fields {
+			if newID, ok := rs.fields[id]; ok {
+				fields[newID] = v
+			}
+		}
+		t.Fields = fields
+		if err := rs.tx.CreateTask(ctx, &t); err != nil {
+			return err
+		}
+		// Reminders already due were sent, or missed, on the old server.
+		var reminders []model.Reminder
+		for _, r := range t.Reminders() {
+			if r.FireAt.After(rs.now) {
+				reminders = append(reminders, r)
+			}
+		}
+		if err := rs.tx.ScheduleReminders(ctx, t.ID, reminders); err != nil {
+			return err
+		}
+		rs.tasks[oldID] = t.ID
+		rs.result.Counts[rec.Type]++
+		return nil
+
+	case model.BackupDependencyRecord:
+		var d model.Dependency
+		if err := decodeRecord(rec, &d); err != nil {
+			return err
+		}
+		taskID, ok1 := rs.tasks[d.TaskID]
+		blockerID, ok2 := rs.tasks[d.BlockerID]
+		if !ok1 || !ok2 {
+			return nil // skipped with its tasks
+		}
+		d.TaskID, d.BlockerID = taskID, blockerID
+		if err := rs.tx.AddDependency(ctx, d); err != nil {
+			return err
+		}
+		rs.result.Counts[rec.Type]++
+		return nil
+
+	case model.BackupChecklistRecord:
+		var c model.ChecklistItem
+		if err := decodeRecord(rec, &c); err != nil {
+			return err
+		}
+		taskID, ok := rs.tasks[c.TaskID]
+		if !ok {
+			return nil
+		}
+		c.TaskID = taskID
+		if err := rs.tx.CreateChecklistItem(ctx, &c); err != nil {
+			return err
+		}
+		rs.result.Counts[rec.Type]++
+		return nil
+
+	case model.BackupCommentRecord:
+		var c model.Comment
+		if err := decodeRecord(rec, &c); err != nil {
+			return err
+		}
+		oldID := c.ID
+		taskID, ok := rs.tasks[c.TaskID]
+		if !ok {
+			return nil
+		}
+		authorID, ok := rs.users[c.AuthorID]
+		if !ok {
+			rs.warn(rec.Type, oldID, "its author is not in the backup")
+			return nil
+		}
+		c.TaskID, c.AuthorID = taskID, authorID
+		c.ReplyTo = mapRef(rs.comments, c.ReplyTo)
+		if err := rs.tx.CreateComment(ctx, &c); err != nil {
+			return err
+		}
+		rs.comments[oldID] = c.ID
+		rs.result.Counts[rec.Type]++
+		return nil
+
+	case model.BackupTimeEntryRecord:
+		var e model.TimeEntry
+		if err := decodeRecord(rec, &e); err != nil {
+			return err
+		}
+		oldID := e.ID
+		taskID, ok := rs.tasks[e.TaskID]
+		if !ok {
+			return nil
+		}
+		userID, ok := rs.users[e.UserID]
+		if !ok {
+			rs.warn(rec.Type, oldID, "its user is not in the backup")
+			return nil
+		}
+		orgID, err := rs.orgID(ctx)
+		if err != nil {
+			return err
+		}
+		e.OrgID, e.TaskID, e.UserID = orgID, taskID, userID
+		err = rs.tx.CreateTimeEntry(ctx, &e)
+		if errors.Is(err, storage.ErrConflict) {
+			rs.warn(rec.Type, oldID, "its user already has a timer running")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rs.result.Counts[rec.Type]++
+		return nil
+
+	case model.BackupAttachmentRecord:
+		var a model.Attachment
+		if err := decodeRecord(rec, &a); err != nil {
+			return err
+		}
+		oldID := a.ID
+		taskID, ok := rs.tasks[a.TaskID]
+		if !ok {
+			return nil
+		}
+		uploaderID, ok := rs.users[a.UploaderID]
+		if !ok {
+			rs.warn(rec.Type, oldID, "its uploader is not in the backup")
+			return nil
+		}
+		a.ID = storage.NewID()
+		a.TaskID, a.UploaderID = taskID, uploaderID
+		a.Key = "tasks/" + taskID + "/" + a.ID
+		a.URL, a.URLExpiresAt = "", nil
+		// Backups only hold files that passed their scan, and those made
+		// before uploads were scanned hold no status.
+		a.Status = model.AttachmentClean
+		rs.attachments[oldID] = a
+		return nil
+
+	case model.BackupViewRecord:
+		var v model.View
+		if err := decodeRecord(rec, &v); err != nil {
+			return err
+		}
+		oldID := v.ID
+		ownerID, ok := rs.users[v.OwnerID]
+		if !ok {
+			rs.warn(rec.Type, oldID, "its owner is not in the backup")
+			return nil
+		}
+		orgID, err := rs.orgID(ctx)
+		if err != nil {
+			return err
+		}
+		v.OrgID, v.OwnerID = orgID, ownerID
+		if v.Filter.ProjectID != "" {
+			v.Filter.ProjectID = rs.projects[v.Filter.ProjectID]
+		}
+		if id, ok := rs.users[v.Filter.Assignee]; ok {
+			v.Filter.Assignee = id
+		}
+		v.Filter.TagIDs = mapIDs(rs.tags, v.Filter.TagIDs)
+		if err := rs.tx.CreateView(ctx, &v); err != nil {
+			return err
+		}
+		rs.result.Counts[rec.Type]++
+		return nil
+
+	case model.BackupTemplateRecord:
+		var t model.Template
+		if err := decodeRecord(rec, &t); err != nil {
+			return err
+		}
+		oldID := t.ID
+		ownerID, ok := rs.users[t.OwnerID]
+		if !ok {
+			rs.warn(rec.Type, oldID, "its owner is not in the backup")
+			return nil
+		}
+		orgID, err := rs.orgID(ctx)
+		if err != nil {
+			return err
+		}
+		t.OrgID, t.OwnerID = orgID, ownerID
+		if err := rs.tx.CreateTemplate(ctx, &t); err != nil {
+			return err
+		}
+		rs.result.Counts[rec.Type]++
+		return nil
+	}
+	return errUnknownRecord
+}
+
+// user returns the ID of the account on this server with u's email,
+// creating it if there is none.
+func (rs *restorer) user(ctx context.Context, u model.BackupUser) (string, error) {
+	existing, err := rs.tx.GetUserByEmail(ctx, u.Email)
+	if err == nil {
+		return existing.ID, nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return "", err
+	}
+	name := u.Username
+	for range 5 {
+		created := model.User{
+			Email:           u.Email,
+			Username:        name,
+			PasswordHash:    u.PasswordHash,
+			Timezone:        u.Timezone,
+			EmailVerifiedAt: u.EmailVerifiedAt,
+			DisabledAt:      u.DisabledAt,
+			CreatedAt:       u.CreatedAt,
+		}
+		if _, ok := model.LoadTimezone(created.Timezone); !ok {
+			created.Timezone = model.DefaultTimezone
+		}
+		created.Language, _ = i18n.Match(u.Language)
+		err := rs.tx.CreateUser(ctx, &created)
+		if err == nil {
+			rs.result.UsersCreated++
+			return created.ID, nil
+		}
+		if !errors.Is(err, storage.ErrConflict) {
+			return "", err
+		}
+		b := make([]byte, 3)
+		rand.Read(b)
+		name = u.Username + "-" + hex.EncodeToString(b)
+	}
+	return "", fmt.Errorf("creating user %s: %w", u.Email, storage.ErrConflict)
+}
+
+// createOrg creates the organization of the backup, owned by ownerID.
+func (rs *restorer) createOrg(ctx context.Context, ownerID string) error {
+	o := model.Org{Name: rs.header.Org.Name, CreatedAt: rs.header.Org.CreatedAt}
+	if o.CreatedAt.IsZero() {
+		o.CreatedAt = time.Now().UTC()
+	}
+	if err := rs.tx.CreateOrg(ctx, &o, ownerID); err != nil {
+		return err
+	}
+	rs.result.Org = o
+	return nil
+}
+
+// orgID returns the ID of the organization restored, creating it, owned
+// by the administrator, if the backup has no members.
+func (rs *restorer) orgID(ctx context.Context) (string, error) {
+	if rs.result.Org.ID == "" {
+		if err := rs.createOrg(ctx, rs.adminID); err != nil {
+			return "", err
+		}
+	}
+	return rs.result.Org.ID, nil
+}
+
+// warn records that the record of type typ with the given id was skipped.
+func (rs *restorer) warn(typ, id, reason string) {
+	rs.result.Warnings = append(rs.result.Warnings, fmt.Sprintf("skipped %s %s: %s", typ, id, reason))
+}
+
+func decodeRecord(rec model.BackupRecord, v any) error {
+	if err := json.Unmarshal(rec.Data, v); err != nil {
+		return fmt.Errorf("%w: %s record: %w", ErrInvalidBackup, rec.Type, err)
+	}
+	return nil
+}
+
+// mapRef maps the ID ref points to through ids, or returns nil if it is
+// not there.
+func mapRef(ids map[string]string, ref *string) *string {
+	if ref == nil {
+		return nil
+	}
+	id, ok := ids[*ref]
+	if !ok {
+		return nil
+	}
+	return &id
+}
+
+// mapIDs maps every ID of list through ids, dropping those not there.
+func mapIDs(ids map[string]string, list []string) []string {
+	var mapped []string
+	for _, id := range list {
+		if newID, ok := ids[id]; ok {
+			mapped = append(mapped, newID)
+		}
+	}
+	return mapped
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"starttech-server/auth"
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// BoardImports brings boards exported from other tools into an
+// organization. Start records an import and queues it; Run, called from
+// the queue, creates a project for each board and a task for each card,
+// recording its progress after every card so a retried run carries on
+// where the last one stopped. Users only see their own imports.
+type BoardImports struct {
+	Store    storage.BoardImportStore
+	Projects *Projects
+	Tasks    *Tasks
+	Tags     *Tags
+	// Enqueue queues the import with the given ID to be run; see
+	// integrations.Importer.Enqueue.
+	Enqueue func(ctx context.Context, importID string) error
+}
+
+// List returns the imports userID started in the current organization,
+// newest first.
+func (s *BoardImports) List(ctx context.Context, userID string) ([]model.BoardImport, error) {
+	return s.Store.ListBoardImports(ctx, orgOf(ctx), userID)
+}
+
+// Get returns the import with the given id if userID started it.
+func (s *BoardImports) Get(ctx context.Context, userID, id string) (model.BoardImport, error) {
+	im, err := s.Store.GetBoardImport(ctx, id)
+	if err != nil {
+		return model.BoardImport{}, err
+	}
+	if im.UserID != userID || im.OrgID != orgOf(ctx) {
+		return model.BoardImport{}, storage.ErrNotFound
+	}
+	im.Boards = nil
+	return im, nil
+}
+
+// Start records an import of boards, read from an export of source, for
+// userID and queues it to run.
+func (s *BoardImports) Start(ctx context.Context, userID string, source model.ImportSource, boards []model.Board) (model.BoardImport, error) {
+	im := model.BoardImport{
+		OrgID:     orgOf(ctx),
+		UserID:    userID,
+		Source:    source,
+		Status:    model.BoardImportPending,
+		Summary:   model.BoardImportSummary{Projects: []model.ImportedProject{}, Warnings: []string{}},
+		CreatedAt: time.Now().UTC(),
+		Boards:    boards,
+	}
+	for _, b := range boards {
+		im.Total += len(b.Cards)
+	}
+	if err := s.Store.CreateBoardImport(ctx, &im); err != nil {
+		return model.BoardImport{}, err
+	}
+	if err := s.Enqueue(ctx, im.ID); err != nil {
+		return model.BoardImport{}, err
+	}
+	im.Boards = nil
+	return im, nil
+}
+
+// Preview runs an import of boards for userID as Run would, in a
+// transaction it then rolls back, and returns the summary of what it would
+// create. Nothing is kept or queued, and the projects in the summary have
+// no ID.
+func (s *BoardImports) Preview(ctx context.Context, userID string, source model.ImportSource, boards []model.Board) (model.BoardImportPreview, error) {
+	im := model.BoardImport{
+		OrgID:   orgOf(ctx),
+		UserID:  userID,
+		Source:  source,
+		Status:  model.BoardImportPending,
+		Summary: model.BoardImportSummary{Projects: []model.ImportedProject{}, Warnings: []string{}},
+		Boards:  boards,
+	}
+	for _, b := range boards {
+		im.Total += len(b.Cards)
+	}
+	var pending events.Buffer
+	err := s.Tasks.Tx.InTx(ctx, func(tx storage.Store) error {
+		inner := &BoardImports{
+			Store:    tx,
+			Projects: s.Projects.within(tx, &pending),
+			Tasks:    s.Tasks.within(tx, &pending),
+			Tags:     &Tags{Store: tx},
+		}
+		// run saves its progress, so the import must exist for the
+		// length of the transaction.
+		if err := tx.CreateBoardImport(ctx, &im); err != nil {
+			return err
+		}
+		if err := inner.run(ctx, &im); err != nil {
+			return err
+		}
+		return errRollBack
+	})
+	if !errors.Is(err, errRollBack) {
+		return model.BoardImportPreview{}, err
+	}
+	for i := range im.Summary.Projects {
+		im.Summary.Projects[i].ID = ""
+	}
+	return model.BoardImportPreview{DryRun: true, Source: source, Total: im.Total, Summary: im.Summary}, nil
+}
+
+// Run carries out the import with the given id in the name of the user who
+// started it. A failure is left for the queue to retry, unless last says
+// this is the final attempt, in which case the import is marked failed.
+func (s *BoardImports) Run(ctx context.Context, id string, last bool) error {
+	im, err := s.Store.GetBoardImport(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("loading import: %w", err)
+	}
+	if im.Finished() {
+		return nil
+	}
+	// Act as the user who started the import would through the API.
+	ctx = auth.WithOrgID(auth.WithUserID(ctx, im.UserID), im.OrgID)
+
+	im.Status = model.BoardImportRunning
+	err = s.run(ctx, &im)
+	if err != nil && (!last || ctx.Err() != nil) {
+		return err
+	}
+	im.Status = model.BoardImportSucceeded
+	if err != nil {
+		im.Status, im.Error = model.BoardImportFailed, err.Error()
+	}
+	now := time.Now().UTC()
+	im.FinishedAt, im.Boards = &now, nil
+	if uerr := s.Store.UpdateBoardImport(ctx, &im); uerr != nil {
+		return fmt.Errorf("saving import: %w", uerr)
+	}
+	return err
+}
+
+// run creates what im has not created yet, saving its progress as it
+// goes.
+func (s *BoardImports) run(ctx context.Context, im *model.BoardImport) error {
+	owned, err := s.Tags.List(ctx, im.UserID)
+	if err != nil {
+		return err
+	}
+	tags := make(map[string]string, len(owned))
+	for _, t := range owned {
+		tags[strings.ToLower(t.Name)] = t.ID
+	}
+
+	n := 0
+	for i, b := range im.Boards {
+		if i == len(im.Summary.Projects) {
+			in := model.ProjectInput{Name: truncate(strings.TrimSpace(b.Name), model.MaxProjectNameLen), Description: b.Description, Statuses: b.Columns}
+			if in.Name == "" {
+				in.Name = "Imported board"
+			}
+			p, err := s.Projects.Create(ctx, im.UserID, in)
+			if err != nil {
+				return fmt.Errorf("creating project for board %q: %w", b.Name, err)
+			}
+			im.Summary.Projects = append(im.Summary.Projects, model.ImportedProject{ID: p.ID, Name: p.Name})
+			if err := s.Store.UpdateBoardImport(ctx, im); err != nil {
+				return err
+			}
+		}
+		for _, c := range b.Cards {
+			n++
+			if n <= im.Done {
+				continue
+			}
+			if err := s.card(ctx, im, i, c, tags); err != nil {
+				return err
+			}
+			im.Done = n
+			if err := s.Store.UpdateBoardImport(ctx, im); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// card creates the task for c in the project of board i of im, with its
+// tags and checklist. tags maps the lowercased names of the user's tags to
+// their IDs, and gains the tags created for c's labels. A card the task
+// service refuses is skipped with a warning.
+func (s *BoardImports) card(ctx context.Context, im *model.BoardImport, i int, c model.Card, tags map[string]string) error {
+	project := &im.Summary.Projects[i]
+	in := model.TaskInput{
+		Title:       truncate(strings.TrimSpace(c.Title), model.MaxTitleLen),
+		Description: truncate(c.Description, model.MaxDescriptionLen),
+		Status:      c.Status,
+		Priority:    c.Priority,
+		DueDate:     c.DueDate,
+		ProjectID:   &project.ID,
+	}
+	if in.Title == "" {
+		in.Title = "Untitled"
+	}
+	for _, label := range c.Labels {
+		id, ok := tags[strings.ToLower(label)]
+		if !ok {
+			t, err := s.Tags.Create(ctx, im.UserID, model.TagInput{Name: label})
+			if rejected(err) {
+				warn(&im.Summary, fmt.Sprintf("label %q of %q was left out: %v", label, in.Title, err))
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			id = t.ID
+			tags[strings.ToLower(label)] = id
+		}
+		in.TagIDs = append(in.TagIDs, id)
+	}
+
+	t, err := s.Tasks.Create(ctx, im.UserID, in)
+	if rejected(err) {
+		im.Summary.Skipped++
+		warn(&im.Summary, fmt.Sprintf("%q was skipped: %v", in.Title, err))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	im.Summary.Created++
+	project.Tasks++
+	for _, item := range c.Checklist {
+		_, err := s.Tasks.AddChecklistItem(ctx, im.UserID, t.ID, item)
+		if rejected(err) {
+			warn(&im.Summary, fmt.Sprintf("the checklist of %q is incomplete: %v", in.Title, err))
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// warn adds msg to the warnings of sum while there is room.
+func warn(sum *model.BoardImportSummary, msg string) {
+	if len(sum.Warnings) < model.MaxImportWarnings {
+		sum.Warnings = append(sum.Warnings, msg)
+	}
+}
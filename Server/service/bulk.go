@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// ErrNotApplied is reported for the operations of a bulk request that were
+// undone, or never run, because another operation failed.
+var ErrNotApplied = errors.New("service: not applied because another operation failed")
+
+// BulkItem is the outcome of one bulk operation. Task is nil for a delete
+// and when Err is set. For a dry run, Task is also the task a delete would
+// send to the trash, and Affected the other tasks the operation would
+// save, such as the subtasks of a deleted task or those a move would
+// shift, as they would be.
+type BulkItem struct {
+	Task     *model.Task
+	Affected []model.Task
+	Err      error
+}
+
+// BulkOptions adjust Bulk.
+type BulkOptions struct {
+	// DryRun runs the operations and reports on them, then undoes them.
+	DryRun bool
+}
+
+// errRollBack aborts the transaction of a bulk request.
+var errRollBack = errors.New("service: rolling back")
+
+// Bulk runs the operations of in for userID, in order and in a single
+// transaction, and reports on each. If one fails, everything is undone: its
+// item carries the error, every other item ErrNotApplied, and committed is
+// false. Events are published only once the transaction commits, and the
+// request is undone as a whole. A dry run is never committed; if one of
+// its operations fails, those before it keep their preview.
+func (s *Tasks) Bulk(ctx context.Context, userID string, in model.BulkInput, opts BulkOptions) (items []BulkItem, committed bool, err error) {
+	if err := validateBulk(in); err != nil {
+		return nil, false, err
+	}
+	// The copy that runs the operations has its own moveMu.
+	s.moveMu.Lock()
+	defer s.moveMu.Unlock()
+
+	ctx, j := s.startJournal(ctx)
+	var pending events.Buffer
+	items = make([]BulkItem, len(in.Operations))
+	err = s.Tx.InTx(ctx, func(tx storage.Store) error {
+		inner := s.within(tx, &pending)
+		for i, op := range in.Operations {
+			var (
+				item BulkItem
+				err  error
+			)
+			if opts.DryRun {
+				item, err = inner.preview(ctx, userID, op)
+			} else {
+				item.Task, err = inner.apply(ctx, userID, op)
+			}
+			if err != nil {
+				// A dry run keeps the preview of the operations
+				// before the one that failed.
+				for j := range items {
+					if !opts.DryRun || j > i {
+						items[j] = BulkItem{Err: ErrNotApplied}
+					}
+				}
+				items[i] = BulkItem{Err: err}
+				return errRollBack
+			}
+			items[i] = item
+		}
+		if opts.DryRun {
+			return errRollBack
+		}
+		return s.stage(ctx, tx, &pending)
+	})
+	if errors.Is(err, errRollBack) {
+		return items, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	s.flush(&pending)
+	s.remember(ctx, userID, model.CommandBulk, j)
+	return items, true, nil
+}
+
+// within returns a copy of s that works through tx and holds its events in
+// pending. Purging, the only use of Attachments, is not available to it.
+func (s *Tasks) within(tx storage.Store, pending *events.Buffer) *Tasks {
+	inner := &Tasks{
+		Store:    tx,
+		History:  tx,
+		Tags:     tx,
+		Projects: tx,
+		Comments: tx,
+		Mentions: tx,
+		Inbox:    tx,
+		Time:     tx,
+		Deps:     tx,
+		Index:    tx,
+		Tx:       tx,
+		Events:   pending,
+
+		Checklists:      tx,
+		Watchers:        tx,
+		CustomFields:    tx,
+		Automations:     tx,
+		BlockCompletion: s.BlockCompletion,
+		MaxProjectTasks: s.MaxProjectTasks,
+	}
+	if s.Reminders != nil {
+		inner.Reminders = tx
+	}
+	if s.Log != nil {
+		inner.Log = tx
+	}
+	if s.Users != nil {
+		inner.Users = tx
+	}
+	return inner
+}
+
+// preview runs op as apply does, noting the tasks it saves in a journal of
+// its own, and returns them as they are then. A task it would create has
+// no ID.
+func (s *Tasks) preview(ctx context.Context, userID string, op model.BulkOperation) (BulkItem, error) {
+	j := &journal{}
+	t, err := s.apply(context.WithValue(ctx, journalKey{}, j), userID, op)
+	if err != nil {
+		return BulkItem{}, err
+	}
+	item := BulkItem{Task: t}
+	for _, step := range j.steps {
+		saved, err := s.Store.GetTask(ctx, step.TaskID)
+		if err != nil {
+			return BulkItem{}, err
+		}
+		switch {
+		case t != nil && saved.ID == t.ID:
+		case op.Op == model.BulkDelete && saved.ID == op.ID:
+			item.Task = &saved
+		default:
+			item.Affected = append(item.Affected, saved)
+		}
+	}
+	if op.Op == model.BulkCreate {
+		item.Task.ID = ""
+	}
+	return item, nil
+}
+
+// apply runs one bulk operation.
+func (s *Tasks) apply(ctx context.Context, userID string, op model.BulkOperation) (*model.Task, error) {
+	var ifMatch []int64
+	if op.Version != 0 {
+		t, err := s.Get(ctx, userID, op.ID)
+		if err != nil {
+			return nil, err
+		}
+		if t.Version != op.Version {
+			return nil, ErrPreconditionFailed
+		}
+		ifMatch = []int64{op.Version}
+	}
+
+	var (
+		t   model.Task
+		err error
+	)
+	switch op.Op {
+	case model.BulkCreate:
+		t, err = s.Create(ctx, userID, *op.Task)
+	case model.BulkUpdate:
+		t, err = s.Update(ctx, userID, op.ID, ifMatch, op.Patch.Apply)
+	case model.BulkMove:
+		t, err = s.Move(ctx, userID, op.ID, *op.Move)
+	case model.BulkDelete:
+		policy := ChildPolicy(op.Children)
+		if policy == "" {
+			policy = ReparentChildren
+		}
+		return nil, s.Delete(ctx, userID, op.ID, policy)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// validateBulk checks the shape of every operation before any of them runs.
+func validateBulk(in model.BulkInput) error {
+	var v model.ValidationError
+	switch n := len(in.Operations); {
+	case n == 0:
+		v.Add("operations", "is required")
+	case n > model.MaxBulkOperations:
+		v.Add("operations", fmt.Sprintf("must hold at most %d operations", model.MaxBulkOperations))
+	}
+	for i, op := range in.Operations {
+		field := fmt.Sprintf("operations[%d]", i)
+		switch op.Op {
+		case model.BulkUpdate, model.BulkMove, model.BulkDelete:
+			if op.ID == "" {
+				v.Add(field+".id", "is required")
+			}
+		}
+		switch op.Op {
+		case model.BulkCreate:
+			if op.Task == nil {
+				v.Add(field+".task", "is required")
+			}
+			if op.Version != 0 {
+				v.Add(field+".version", "does not apply to a new task")
+			}
+		case model.BulkUpdate:
+			if op.Patch == nil {
+				v.Add(field+".patch", "is required")
+			}
+		case model.BulkMove:
+			if op.Move == nil {
+				v.Add(field+".move", "is required")
+			}
+		case model.BulkDelete:
+			if op.Children != "" && !ChildPolicy(op.Children).Valid() {
+				v.Add(field+".children", "must be reparent or cascade")
+			}
+		default:
+			v.Add(field+".op", "must be create, update, delete or move")
+		}
+	}
+	return v.Err()
+}
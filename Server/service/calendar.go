@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"starttech-server/auth"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Calendar feed limits.
+const (
+	// CalendarPast is how long ago a task may have fallen due and still be
+	// shown, so overdue work stays on the calendar.
+	CalendarPast = 90 * 24 * time.Hour
+	// MaxCalendarTasks bounds the events of one feed.
+	MaxCalendarTasks = 1000
+)
+
+// Calendar manages the private feeds through which calendar apps show
+// users their tasks with due dates. Feeds are read without a session,
+// so their tokens are as good as a password to the tasks; creating a feed
+// again replaces a link that has leaked.
+type Calendar struct {
+	Store storage.CalendarStore
+	// Orgs confirms that a feed's owner still belongs to its organization.
+	Orgs  storage.OrgStore
+	Tasks *Tasks
+}
+
+// Get returns userID's feed in the request's organization, without its
+// token.
+func (s *Calendar) Get(ctx context.Context, userID string) (model.CalendarFeed, error) {
+	return s.Store.GetCalendarFeed(ctx, orgOf(ctx), userID)
+}
+
+// Create gives userID a feed in the request's organization with a new
+// token, replacing the one they had. The token and URL are only returned
+// here; the URL is relative to the API, like attachment links.
+func (s *Calendar) Create(ctx context.Context, userID string) (model.CalendarFeed, error) {
+	token := newToken()
+	f := model.CalendarFeed{
+		UserID:    userID,
+		OrgID:     orgOf(ctx),
+		TokenHash: hashToken(token),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.Store.SaveCalendarFeed(ctx, f); err != nil {
+		return model.CalendarFeed{}, err
+	}
+	f.Token = token
+	f.URL = "/calendar/" + token + ".ics"
+	return f, nil
+}
+
+// Delete turns userID's feed in the request's organization off.
+func (s *Calendar) Delete(ctx context.Context, userID string) error {
+	return s.Store.DeleteCalendarFeed(ctx, orgOf(ctx), userID)
+}
+
+// Feed returns the feed with the given token and the tasks it shows: the
+// open tasks its owner can see that fell due at most CalendarPast ago or
+// are due later, soonest first. An unknown token, or one whose owner has
+// left the organization, yields storage.ErrNotFound.
+func (s *Calendar) Feed(ctx context.Context, token string) (model.CalendarFeed, []model.Task, error) {
+	f, err := s.Store.GetCalendarFeedByToken(ctx, hashToken(token))
+	if err != nil {
+		return model.CalendarFeed{}, nil, err
+	}
+	if _, err := s.Orgs.GetOrgMember(ctx, f.OrgID, f.UserID); err != nil {
+		return model.CalendarFeed{}, nil, err
+	}
+	// Read as the owner would through the API.
+	ctx = auth.WithOrgID(auth.WithUserID(ctx, f.UserID), f.OrgID)
+
+	since := time.Now().UTC().Add(-CalendarPast)
+	filter := storage.TaskFilter{
+		DueAfter: &since,
+		Sort:     storage.Sort{Field: storage.SortDueDate},
+		Limit:    MaxPageSize,
+	}
+	var tasks []model.Task
+	cursor := ""
+	for len(tasks) < MaxCalendarTasks {
+		page, err := s.Tasks.List(ctx, f.UserID, filter, cursor)
+		if err != nil {
+			return model.CalendarFeed{}, nil, err
+		}
+		for _, t := range page.Items {
+			if !t.Completed && len(tasks) < MaxCalendarTasks {
+				tasks = append(tasks, t)
+			}
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	return f, tasks, nil
+}
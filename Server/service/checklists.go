@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Checklist returns the checklist of the task with the given id, first item
+// first.
+func (s *Tasks) Checklist(ctx context.Context, userID, id string) ([]model.ChecklistItem, error) {
+	t, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.Checklists.ListChecklist(ctx, t.ID)
+}
+
+// AddChecklistItem appends an item to the checklist of the task with the
+// given id, which userID must be able to edit.
+func (s *Tasks) AddChecklistItem(ctx context.Context, userID, id string, in model.ChecklistItemInput) (model.ChecklistItem, error) {
+	now := time.Now().UTC()
+	c := model.ChecklistItem{TaskID: id, CreatedAt: now, UpdatedAt: now}
+	in.Apply(&c)
+	if err := c.Validate(); err != nil {
+		return model.ChecklistItem{}, err
+	}
+	err := s.editChecklist(ctx, userID, id, func(tx storage.Store, items []model.ChecklistItem) error {
+		if len(items) >= model.MaxChecklistItems {
+			var v model.ValidationError
+			v.Add("checklist", fmt.Sprintf("may hold at most %d items", model.MaxChecklistItems))
+			return v.Err()
+		}
+		c.Position = 1
+		if len(items) > 0 {
+			c.Position = items[len(items)-1].Position + 1
+		}
+		return tx.CreateChecklistItem(ctx, &c)
+	})
+	if err != nil {
+		return model.ChecklistItem{}, err
+	}
+	return c, nil
+}
+
+// UpdateChecklistItem applies p to an item of the checklist of the task
+// with the given id, which userID must be able to edit.
+func (s *Tasks) UpdateChecklistItem(ctx context.Context, userID, id, itemID string, p model.ChecklistItemPatch) (model.ChecklistItem, error) {
+	return s.changeChecklistItem(ctx, userID, id, itemID, p.Apply)
+}
+
+// ToggleChecklistItem flips whether an item of the checklist of the task
+// with the given id is done.
+func (s *Tasks) ToggleChecklistItem(ctx context.Context, userID, id, itemID string) (model.ChecklistItem, error) {
+	return s.changeChecklistItem(ctx, userID, id, itemID, func(c *model.ChecklistItem) { c.Done = !c.Done })
+}
+
+func (s *Tasks) changeChecklistItem(ctx context.Context, userID, id, itemID string, mutate func(*model.ChecklistItem)) (model.ChecklistItem, error) {
+	var c model.ChecklistItem
+	err := s.editChecklist(ctx, userID, id, func(tx storage.Store, items []model.ChecklistItem) error {
+		i := checklistIndex(items, itemID)
+		if i < 0 {
+			return storage.ErrNotFound
+		}
+		c = items[i]
+		mutate(&c)
+		if err := c.Validate(); err != nil {
+			return err
+		}
+		c.UpdatedAt = time.Now().UTC()
+		return tx.UpdateChecklistItem(ctx, &c)
+	})
+	if err != nil {
+		return model.ChecklistItem{}, err
+	}
+	return c, nil
+}
+
+// DeleteChecklistItem removes an item from the checklist of the task with
+// the given id, which userID must be able to edit.
+func (s *Tasks) DeleteChecklistItem(ctx context.Context, userID, id, itemID string) error {
+	return s.editChecklist(ctx, userID, id, func(tx storage.Store, items []model.ChecklistItem) error {
+		if checklistIndex(items, itemID) < 0 {
+			return storage.ErrNotFound
+		}
+		return tx.DeleteChecklistItem(ctx, itemID)
+	})
+}
+
+// ReorderChecklist puts the checklist of the task with the given id in the
+// order of itemIDs, which must name each of its items once.
+func (s *Tasks) ReorderChecklist(ctx context.Context, userID, id string, itemIDs []string) ([]model.ChecklistItem, error) {
+	err := s.editChecklist(ctx, userID, id, func(tx storage.Store, items []model.ChecklistItem) error {
+		seen := make(map[string]bool, len(itemIDs))
+		for _, itemID := range itemIDs {
+			if seen[itemID] || checklistIndex(items, itemID) < 0 {
+				seen = nil
+				break
+			}
+			seen[itemID] = true
+		}
+		if len(seen) != len(items) {
+			var v model.ValidationError
+			v.Add("item_ids", "must list every item of the checklist once")
+			return v.Err()
+		}
+		return tx.ReorderChecklist(ctx, id, itemIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Checklists.ListChecklist(ctx, id)
+}
+
+// editChecklist runs change on the checklist of the task with the given id,
+// which userID must be able to edit, then saves the task with its new
+// counts, all in one transaction.
+func (s *Tasks) editChecklist(ctx context.Context, userID, id string, change func(tx storage.Store, items []model.ChecklistItem) error) error {
+	t, err := s.authorize(ctx, userID, id, model.RoleEditor)
+	if err != nil {
+		return err
+	}
+	var pending events.Buffer
+	err = s.Tx.InTx(ctx, func(tx storage.Store) error {
+		items, err := tx.ListChecklist(ctx, t.ID)
+		if err != nil {
+			return err
+		}
+		if err := change(tx, items); err != nil {
+			return err
+		}
+		if items, err = tx.ListChecklist(ctx, t.ID); err != nil {
+			return err
+		}
+		if t, err = tx.GetTask(ctx, t.ID); err != nil {
+			return err
+		}
+		t.Checklist = model.ChecklistProgress{Total: len(items)}
+		for _, c := range items {
+			if c.Done {
+				t.Checklist.Done++
+			}
+		}
+		t.UpdatedAt = time.Now().UTC()
+		if err := tx.UpdateTask(ctx, &t); err != nil {
+			return err
+		}
+		inner := s.within(tx, &pending)
+		inner.publish(ctx, events.TaskUpdated, inner.audience(ctx, t), t)
+		return s.stage(ctx, tx, &pending)
+	})
+	if err != nil {
+		return err
+	}
+	s.flush(&pending)
+	return nil
+}
+
+func checklistIndex(items []model.ChecklistItem, id string) int {
+	for i, c := range items {
+		if c.ID == id {
+			return i
+		}
+	}
+	return -1
+}
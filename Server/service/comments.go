@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// ListComments lists the comments on the task with the given id, oldest first,
+// each with the number of direct replies it has.
+func (s *Tasks) ListComments(ctx context.Context, userID, id string) ([]model.Comment, error) {
+	if _, err := s.authorize(ctx, userID, id, model.RoleViewer); err != nil {
+		return nil, err
+	}
+	comments, err := s.Comments.ListComments(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	replies := map[string]int{}
+	for _, c := range comments {
+		if c.ReplyTo != nil {
+			replies[*c.ReplyTo]++
+		}
+	}
+	for i := range comments {
+		comments[i].Replies = replies[comments[i].ID]
+	}
+	return comments, nil
+}
+
+// comment returns the comment with the given id on task t, counting its
+// replies.
+func (s *Tasks) comment(ctx context.Context, t model.Task, commentID string) (model.Comment, error) {
+	c, err := s.Comments.GetComment(ctx, commentID)
+	if err != nil {
+		return model.Comment{}, err
+	}
+	if c.TaskID != t.ID {
+		return model.Comment{}, storage.ErrNotFound
+	}
+	comments, err := s.Comments.ListComments(ctx, t.ID)
+	if err != nil {
+		return model.Comment{}, err
+	}
+	for _, other := range comments {
+		if other.ReplyTo != nil && *other.ReplyTo == c.ID {
+			c.Replies++
+		}
+	}
+	return c, nil
+}
+
+// GetComment returns one comment on the task with the given id.
+func (s *Tasks) GetComment(ctx context.Context, userID, id, commentID string) (model.Comment, error) {
+	t, err := s.authorize(ctx, userID, id, model.RoleViewer)
+	if err != nil {
+		return model.Comment{}, err
+	}
+	return s.comment(ctx, t, commentID)
+}
+
+// AddComment posts a comment by userID on the task with the given id, which
+// they must be able to edit. A reply must answer a comment on the same task.
+// Project members the body mentions with @username are notified, and so are
+// the task's owner, assignee and watchers. The author, and everyone the body
+// mentions, start watching the task.
+func (s *Tasks) AddComment(ctx context.Context, userID, id string, in model.CommentInput) (model.Comment, error) {
+	t, err := s.authorize(ctx, userID, id, model.RoleEditor)
+	if err != nil {
+		return model.Comment{}, err
+	}
+	c := model.Comment{TaskID: id, AuthorID: userID, CreatedAt: time.Now().UTC()}
+	in.Apply(&c)
+	if err := c.Validate(); err != nil {
+		return model.Comment{}, err
+	}
+	if c.ReplyTo != nil {
+		parent, err := s.Comments.GetComment(ctx, *c.ReplyTo)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return model.Comment{}, err
+		}
+		if err != nil || parent.TaskID != id {
+			var v model.ValidationError
+			v.Add("reply_to", "unknown comment "+*c.ReplyTo)
+			return model.Comment{}, v.Err()
+		}
+	}
+	if err := s.Comments.CreateComment(ctx, &c); err != nil {
+		return model.Comment{}, err
+	}
+	if c, err = s.Comments.GetComment(ctx, c.ID); err != nil {
+		return model.Comment{}, err
+	}
+	s.publish(ctx, events.CommentCreated, s.audience(ctx, t), c)
+	s.record(ctx, userID, events.CommentCreated, t, c.ID, nil)
+	s.autoWatch(ctx, userID, t)
+	mentioned, err := s.mention(ctx, userID, t, c)
+	if err != nil {
+		return model.Comment{}, err
+	}
+	s.notifyCommented(ctx, userID, t, c, mentioned)
+	return c, nil
+}
+
+// EditComment replaces the body of a comment. Only its author may edit it,
+// and the body it had before is kept in its history. Users the new body
+// mentions for the first time are notified.
+func (s *Tasks) EditComment(ctx context.Context, userID, id, commentID string, p model.CommentPatch) (model.Comment, error) {
+	t, err := s.authorize(ctx, userID, id, model.RoleEditor)
+	if err != nil {
+		return model.Comment{}, err
+	}
+	c, err := s.comment(ctx, t, commentID)
+	if err != nil {
+		return model.Comment{}, err
+	}
+	if c.AuthorID != userID {
+		return model.Comment{}, ErrForbidden
+	}
+	body := strings.TrimSpace(p.Body)
+	if body == c.Body {
+		return c, nil
+	}
+	now := time.Now().UTC()
+	prev := model.CommentEdit{CommentID: c.ID, Body: c.Body, EditedAt: now}
+	c.Body = body
+	c.EditedAt = &now
+	if err := c.Validate(); err != nil {
+		return model.Comment{}, err
+	}
+	if err := s.Comments.EditComment(ctx, &c, prev); err != nil {
+		return model.Comment{}, err
+	}
+	s.publish(ctx, events.CommentUpdated, s.audience(ctx, t), c)
+	s.record(ctx, userID, events.CommentUpdated, t, c.ID, diff(model.CommentPatch{Body: prev.Body}, model.CommentPatch{Body: c.Body}))
+	if _, err := s.mention(ctx, userID, t, c); err != nil {
+		return model.Comment{}, err
+	}
+	return c, nil
+}
+
+// DeleteComment removes a comment. Authors may delete their own comments
+// and project owners anybody's. Replies to it move up to the comment it
+// answered, so threads stay intact.
+func (s *Tasks) DeleteComment(ctx context.Context, userID, id, commentID string) error {
+	t, err := s.authorize(ctx, userID, id, model.RoleEditor)
+	if err != nil {
+		return err
+	}
+	c, err := s.comment(ctx, t, commentID)
+	if err != nil {
+		return err
+	}
+	if c.AuthorID != userID {
+		role, err := s.roleOn(ctx, userID, t)
+		if err != nil {
+			return err
+		}
+		if err := require(role, model.RoleOwner); err != nil {
+			return err
+		}
+	}
+	if err := s.Comments.DeleteComment(ctx, commentID); err != nil {
+		return err
+	}
+	s.publish(ctx, events.CommentDeleted, s.audience(ctx, t), events.Deleted{ID: commentID})
+	s.record(ctx, userID, events.CommentDeleted, t, commentID, nil)
+	return nil
+}
+
+// notifyCommented tells the owner, the assignee and the watchers of t about
+// comment c by userID, unless they wrote it, were mentioned in it or can no
+// longer see the task.
+func (s *Tasks) notifyCommented(ctx context.Context, userID string, t model.Task, c model.Comment, mentioned map[string]bool) {
+	to := []string{t.OwnerID}
+	if t.AssigneeID != nil {
+		to = append(to, *t.AssigneeID)
+	}
+	to = append(to, s.watchers(ctx, t)...)
+	audience := s.audience(ctx, t)
+	told := map[string]bool{}
+	for _, id := range to {
+		if id == userID || mentioned[id] || told[id] || !slices.Contains(audience, id) {
+			continue
+		}
+		told[id] = true
+		s.notify(ctx, model.Notification{
+			OrgID: t.OrgID, UserID: id, Kind: model.NotifyCommented, ActorID: userID,
+			TaskID: t.ID, CommentID: &c.ID, Title: t.Title, CreatedAt: c.CreatedAt,
+		})
+	}
+}
+
+// CommentHistory returns the earlier bodies of a comment, oldest first.
+func (s *Tasks) CommentHistory(ctx context.Context, userID, id, commentID string) ([]model.CommentEdit, error) {
+	t, err := s.authorize(ctx, userID, id, model.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.comment(ctx, t, commentID); err != nil {
+		return nil, err
+	}
+	return s.Comments.CommentHistory(ctx, commentID)
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Cursors are opaque to clients so the paging scheme can change without
+// breaking them.
+const cursorPrefix = "o:"
+
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	invalid := &model.ValidationError{}
+	invalid.Add("cursor", "is invalid")
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, invalid
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(string(raw), cursorPrefix))
+	if err != nil || n < 0 || !strings.HasPrefix(string(raw), cursorPrefix) {
+		return 0, invalid
+	}
+	return n, nil
+}
+
+// changeCursorPrefix marks the cursors of the change feed, which hold the
+// place of the last task seen rather than an offset, so that tasks saved
+// meanwhile are neither skipped nor repeated. Cursors that placed tasks by
+// when they were saved had another prefix and are refused.
+const changeCursorPrefix = "s:"
+
+func encodeChangeCursor(k storage.ChangeKey) string {
+	raw := changeCursorPrefix + strconv.FormatInt(k.Seq, 10) + " " + k.TaskID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeChangeCursor(cursor string) (storage.ChangeKey, error) {
+	invalid := &model.ValidationError{}
+	invalid.Add("since", "is invalid")
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return storage.ChangeKey{}, invalid
+	}
+	rest, ok := strings.CutPrefix(string(raw), changeCursorPrefix)
+	seq, id, found := strings.Cut(rest, " ")
+	if !ok || !found {
+		return storage.ChangeKey{}, invalid
+	}
+	n, err := strconv.ParseInt(seq, 10, 64)
+	if err != nil || n < 0 {
+		return storage.ChangeKey{}, invalid
+	}
+	return storage.ChangeKey{Seq: n, TaskID: id}, nil
+}
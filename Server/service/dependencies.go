@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+var (
+	// ErrDependencyCycle is returned when a new dependency would make a
+	// task wait, directly or not, on itself.
+	ErrDependencyCycle = errors.New("service: the dependency would create a cycle")
+	// ErrBlocked is returned when completing a task that open tasks still
+	// block, while Tasks.BlockCompletion is set.
+	ErrBlocked = errors.New("service: the task is blocked by open tasks")
+)
+
+// maxGraphTasks bounds the tasks DependencyGraph walks to.
+const maxGraphTasks = 500
+
+// Dependencies returns the tasks directly blocking the task with the given
+// id and those it blocks.
+func (s *Tasks) Dependencies(ctx context.Context, userID, id string) (model.Dependencies, error) {
+	t, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return model.Dependencies{}, err
+	}
+	return s.dependencies(ctx, userID, t.ID)
+}
+
+func (s *Tasks) dependencies(ctx context.Context, userID, id string) (model.Dependencies, error) {
+	blockers, err := s.Deps.Blockers(ctx, id)
+	if err != nil {
+		return model.Dependencies{}, err
+	}
+	dependents, err := s.Deps.Dependents(ctx, id)
+	if err != nil {
+		return model.Dependencies{}, err
+	}
+	deps := model.Dependencies{BlockedBy: []model.Task{}, Blocking: []model.Task{}}
+	for _, d := range blockers {
+		if t, ok, err := s.visible(ctx, userID, d.BlockerID); err != nil {
+			return model.Dependencies{}, err
+		} else if ok {
+			deps.BlockedBy = append(deps.BlockedBy, t)
+		}
+	}
+	for _, d := range dependents {
+		if t, ok, err := s.visible(ctx, userID, d.TaskID); err != nil {
+			return model.Dependencies{}, err
+		} else if ok {
+			deps.Blocking = append(deps.Blocking, t)
+		}
+	}
+	return deps, nil
+}
+
+// visible returns the task with the given id if userID may see it, and
+// false if it is missing, in the trash or hidden from them.
+func (s *Tasks) visible(ctx context.Context, userID, id string) (model.Task, bool, error) {
+	t, err := s.Get(ctx, userID, id)
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, ErrForbidden):
+		return model.Task{}, false, nil
+	case err != nil:
+		return model.Task{}, false, err
+	}
+	return t, true, nil
+}
+
+// AddBlocker makes the task with the given id, which userID must be able to
+// edit, wait on blockerID, which they must be able to see. Adding a
+// dependency that exists already changes nothing; one that would close a
+// cycle fails with ErrDependencyCycle.
+func (s *Tasks) AddBlocker(ctx context.Context, userID, id, blockerID string) (model.Dependencies, error) {
+	t, err := s.authorize(ctx, userID, id, model.RoleEditor)
+	if err != nil {
+		return model.Dependencies{}, err
+	}
+	if blockerID == t.ID {
+		return model.Dependencies{}, ErrDependencyCycle
+	}
+	if _, err := s.Get(ctx, userID, blockerID); err != nil {
+		return model.Dependencies{}, err
+	}
+	cycle, err := s.reaches(ctx, blockerID, t.ID)
+	if err != nil {
+		return model.Dependencies{}, err
+	}
+	if cycle {
+		return model.Dependencies{}, ErrDependencyCycle
+	}
+	err = s.Deps.AddDependency(ctx, model.Dependency{TaskID: t.ID, BlockerID: blockerID, CreatedAt: time.Now().UTC()})
+	switch {
+	case errors.Is(err, storage.ErrConflict):
+	case err != nil:
+		return model.Dependencies{}, err
+	default:
+		s.record(ctx, userID, events.TaskUpdated, t, "", []model.Change{{Field: "blocked_by", Old: jsonNull, New: jsonValue(blockerID)}})
+	}
+	return s.dependencies(ctx, userID, t.ID)
+}
+
+// RemoveBlocker undoes AddBlocker.
+func (s *Tasks) RemoveBlocker(ctx context.Context, userID, id, blockerID string) (model.Dependencies, error) {
+	t, err := s.authorize(ctx, userID, id, model.RoleEditor)
+	if err != nil {
+		return model.Dependencies{}, err
+	}
+	if err := s.Deps.RemoveDependency(ctx, t.ID, blockerID); err != nil {
+		return model.Dependencies{}, err
+	}
+	s.record(ctx, userID, events.TaskUpdated, t, "", []model.Change{{Field: "blocked_by", Old: jsonValue(blockerID), New: jsonNull}})
+	return s.dependencies(ctx, userID, t.ID)
+}
+
+// reaches reports whether from waits on to, directly or through other
+// tasks. Every dependency counts, whether or not the caller can see it.
+func (s *Tasks) reaches(ctx context.Context, from, to string) (bool, error) {
+	seen := map[string]bool{from: true}
+	queue := []string{from}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if id == to {
+			return true, nil
+		}
+		blockers, err := s.Deps.Blockers(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		for _, d := range blockers {
+			if !seen[d.BlockerID] {
+				seen[d.BlockerID] = true
+				queue = append(queue, d.BlockerID)
+			}
+		}
+	}
+	return false, nil
+}
+
+// DependencyGraph walks the dependencies of the task with the given id in
+// both directions, as far as userID can see.
+func (s *Tasks) DependencyGraph(ctx context.Context, userID, id string) (model.DependencyGraph, error) {
+	t, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return model.DependencyGraph{}, err
+	}
+	g := model.DependencyGraph{Tasks: []model.Task{t}, Edges: []model.Dependency{}}
+	seen := map[string]bool{t.ID: true}
+	edges := map[model.Dependency]bool{}
+	for i := 0; i < len(g.Tasks) && len(g.Tasks) < maxGraphTasks; i++ {
+		id := g.Tasks[i].ID
+		blockers, err := s.Deps.Blockers(ctx, id)
+		if err != nil {
+			return model.DependencyGraph{}, err
+		}
+		dependents, err := s.Deps.Dependents(ctx, id)
+		if err != nil {
+			return model.DependencyGraph{}, err
+		}
+		for _, d := range append(blockers, dependents...) {
+			other := d.BlockerID
+			if other == id {
+				other = d.TaskID
+			}
+			if !seen[other] {
+				seen[other] = true
+				ot, ok, err := s.visible(ctx, userID, other)
+				if err != nil {
+					return model.DependencyGraph{}, err
+				}
+				if !ok {
+					continue
+				}
+				g.Tasks = append(g.Tasks, ot)
+			} else if !slices.ContainsFunc(g.Tasks, func(t model.Task) bool { return t.ID == other }) {
+				continue
+			}
+			if !edges[d] {
+				edges[d] = true
+				g.Edges = append(g.Edges, d)
+			}
+		}
+	}
+	return g, nil
+}
+
+// checkCompletion returns ErrBlocked if the edit from old to t completes
+// the task while a task blocking it is open and s.BlockCompletion is set.
+// Blockers in the trash do not count.
+func (s *Tasks) checkCompletion(ctx context.Context, old, t model.Task) error {
+	if !s.BlockCompletion || old.Completed || !t.Completed {
+		return nil
+	}
+	blockers, err := s.Deps.Blockers(ctx, t.ID)
+	if err != nil {
+		return err
+	}
+	for _, d := range blockers {
+		b, err := s.Store.GetTask(ctx, d.BlockerID)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if b.DeletedAt == nil && !b.Completed {
+			return ErrBlocked
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"starttech-server/auth"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// newTestTasks returns a Tasks over an empty in-memory store, and a
+// context in which userID "u1" acts in organization "o1".
+func newTestTasks(t *testing.T) (*Tasks, context.Context) {
+	t.Helper()
+	store := storage.NewMemoryStore()
+	s := &Tasks{
+		Store: store, History: store, Tags: store, Projects: store, Reminders: store,
+		Comments: store, Mentions: store, Inbox: store, Time: store, Deps: store,
+		Checklists: store, Watchers: store, CustomFields: store, Automations: store,
+		Index: store, Log: store, Commands: store, ClientIDs: store, Tx: store,
+	}
+	return s, auth.WithOrgID(auth.WithUserID(context.Background(), "u1"), "o1")
+}
+
+// mustCreate creates a task titled title as "u1".
+func mustCreate(t *testing.T, s *Tasks, ctx context.Context, title string) model.Task {
+	t.Helper()
+	task, err := s.Create(ctx, "u1", model.TaskInput{Title: title})
+	if err != nil {
+		t.Fatalf("creating %q: %v", title, err)
+	}
+	return task
+}
+
+func TestAddBlockerCycles(t *testing.T) {
+	s, ctx := newTestTasks(t)
+	a, b, c, d := mustCreate(t, s, ctx, "a"), mustCreate(t, s, ctx, "b"), mustCreate(t, s, ctx, "c"), mustCreate(t, s, ctx, "d")
+	// a waits on b, which waits on c.
+	for _, dep := range [][2]model.Task{{a, b}, {b, c}} {
+		if _, err := s.AddBlocker(ctx, "u1", dep[0].ID, dep[1].ID); err != nil {
+			t.Fatalf("%s waiting on %s: %v", dep[0].Title, dep[1].Title, err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		task, block model.Task
+		err         error
+	}{
+		{"itself", a, a, ErrDependencyCycle},
+		{"direct cycle", b, a, ErrDependencyCycle},
+		{"cycle through another task", c, a, ErrDependencyCycle},
+		{"shortcut along the chain", a, c, nil},
+		{"again", a, b, nil},
+		{"unrelated task", c, d, nil},
+		{"cycle through the new dependency", d, a, ErrDependencyCycle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.AddBlocker(ctx, "u1", tt.task.ID, tt.block.ID)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s waiting on %s: err = %v, want %v", tt.task.Title, tt.block.Title, err, tt.err)
+			}
+		})
+	}
+
+	deps, err := s.Dependencies(ctx, "u1", a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, blocker := range deps.BlockedBy {
+		titles = append(titles, blocker.Title)
+	}
+	if len(titles) != 2 {
+		t.Errorf("a is blocked by %v, want b and c once each", titles)
+	}
+}
+
+func TestAddBlockerUnknownTask(t *testing.T) {
+	s, ctx := newTestTasks(t)
+	a := mustCreate(t, s, ctx, "a")
+	if _, err := s.AddBlocker(ctx, "u1", a.ID, "missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("err = %v, want %v", err, storage.ErrNotFound)
+	}
+	other := auth.WithOrgID(auth.WithUserID(context.Background(), "u2"), "o1")
+	if _, err := s.AddBlocker(other, "u2", a.ID, a.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("another user's task: err = %v, want %v", err, storage.ErrNotFound)
+	}
+}
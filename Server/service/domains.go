@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"time"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// TXTResolver looks up DNS TXT records, as net.Resolver does.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// Domains lists the email domains the organization id claims. Only its
+// admins and owners may see them.
+func (s *Orgs) Domains(ctx context.Context, userID, id string) ([]model.Domain, error) {
+	if _, err := s.authorize(ctx, userID, id, model.OrgAdmin); err != nil {
+		return nil, err
+	}
+	return s.Store.ListDomains(ctx, id)
+}
+
+// AddDomain claims an email domain for the organization id. The claim is
+// unverified until VerifyDomain finds its token in DNS. It returns
+// storage.ErrConflict if the organization already claims the domain.
+func (s *Orgs) AddDomain(ctx context.Context, userID, id string, in model.DomainInput) (model.Domain, error) {
+	if _, err := s.authorize(ctx, userID, id, model.OrgAdmin); err != nil {
+		return model.Domain{}, err
+	}
+	if err := in.Validate(); err != nil {
+		return model.Domain{}, err
+	}
+	d := model.Domain{OrgID: id, Domain: in.Domain, Token: newToken(), CreatedAt: time.Now().UTC()}
+	if err := s.Store.CreateDomain(ctx, d); err != nil {
+		return model.Domain{}, err
+	}
+	return d, nil
+}
+
+// VerifyDomain checks that the TXT record of a domain the organization id
+// claims holds its token, and if so marks the claim verified. It returns
+// storage.ErrConflict if another organization has verified the domain.
+func (s *Orgs) VerifyDomain(ctx context.Context, userID, id, domain string) (model.Domain, error) {
+	if _, err := s.authorize(ctx, userID, id, model.OrgAdmin); err != nil {
+		return model.Domain{}, err
+	}
+	d, err := s.Store.GetDomain(ctx, id, domain)
+	if err != nil || d.VerifiedAt != nil {
+		return d, err
+	}
+	resolver := s.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	// A failed lookup, such as for a record not yet published, is reported
+	// like a record without the token.
+	records, _ := resolver.LookupTXT(ctx, d.Record())
+	if !slices.Contains(records, d.Value()) {
+		var v model.ValidationError
+		v.Add("domain", "no TXT record at "+d.Record()+" holds "+d.Value())
+		return model.Domain{}, v.Err()
+	}
+	now := time.Now().UTC()
+	if err := s.Store.VerifyDomain(ctx, id, domain, now); err != nil {
+		return model.Domain{}, err
+	}
+	d.VerifiedAt = &now
+	return d, nil
+}
+
+// RemoveDomain drops the organization id's claim to a domain. Single
+// sign-on and SCIM provisioning for the domain stop with it.
+func (s *Orgs) RemoveDomain(ctx context.Context, userID, id, domain string) error {
+	if _, err := s.authorize(ctx, userID, id, model.OrgAdmin); err != nil {
+		return err
+	}
+	return s.Store.DeleteDomain(ctx, id, domain)
+}
+
+// domainVerified reports whether the organization id has verified its
+// claim to domain.
+func (s *Orgs) domainVerified(ctx context.Context, id, domain string) (bool, error) {
+	d, err := s.Store.GetDomain(ctx, id, domain)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil && d.VerifiedAt != nil, err
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Fields lists the custom fields of a project userID belongs to.
+func (s *Projects) Fields(ctx context.Context, userID, projectID string) ([]model.CustomField, error) {
+	if _, err := authorizeProject(ctx, s.Store, userID, projectID, model.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.CustomFields.ListFields(ctx, projectID)
+}
+
+// CreateField adds a custom field to a project. Only owners may define
+// fields; a name the project already uses yields storage.ErrConflict.
+func (s *Projects) CreateField(ctx context.Context, userID, projectID string, in model.CustomFieldInput) (model.CustomField, error) {
+	if _, err := authorizeProject(ctx, s.Store, userID, projectID, model.RoleOwner); err != nil {
+		return model.CustomField{}, err
+	}
+	now := time.Now().UTC()
+	f := model.CustomField{ProjectID: projectID, CreatedAt: now, UpdatedAt: now}
+	in.Apply(&f)
+	if err := f.Validate(); err != nil {
+		return model.CustomField{}, err
+	}
+	existing, err := s.CustomFields.ListFields(ctx, projectID)
+	if err != nil {
+		return model.CustomField{}, err
+	}
+	if len(existing) >= model.MaxFields {
+		var v model.ValidationError
+		v.Add("name", fmt.Sprintf("a project may have at most %d custom fields", model.MaxFields))
+		return model.CustomField{}, v.Err()
+	}
+	if err := s.CustomFields.CreateField(ctx, &f); err != nil {
+		return model.CustomField{}, err
+	}
+	return f, nil
+}
+
+// UpdateField applies p to a custom field of a project userID owns. Tasks
+// lose the values of the options it removes from a select field.
+func (s *Projects) UpdateField(ctx context.Context, userID, projectID, fieldID string, p model.CustomFieldPatch) (model.CustomField, error) {
+	f, err := s.field(ctx, userID, projectID, fieldID, model.RoleOwner)
+	if err != nil {
+		return model.CustomField{}, err
+	}
+	p.Apply(&f)
+	if err := f.Validate(); err != nil {
+		return model.CustomField{}, err
+	}
+	f.UpdatedAt = time.Now().UTC()
+	if err := s.CustomFields.UpdateField(ctx, &f); err != nil {
+		return model.CustomField{}, err
+	}
+	return f, nil
+}
+
+// DeleteField removes a custom field of a project userID owns, along with
+// its value on every task.
+func (s *Projects) DeleteField(ctx context.Context, userID, projectID, fieldID string) error {
+	if _, err := s.field(ctx, userID, projectID, fieldID, model.RoleOwner); err != nil {
+		return err
+	}
+	return s.CustomFields.DeleteField(ctx, fieldID)
+}
+
+// field returns the custom field fieldID of the project if userID holds at
+// least role need in it.
+func (s *Projects) field(ctx context.Context, userID, projectID, fieldID string, need model.Role) (model.CustomField, error) {
+	if _, err := authorizeProject(ctx, s.Store, userID, projectID, need); err != nil {
+		return model.CustomField{}, err
+	}
+	f, err := s.CustomFields.GetField(ctx, fieldID)
+	if err != nil {
+		return model.CustomField{}, err
+	}
+	if f.ProjectID != projectID {
+		return model.CustomField{}, storage.ErrNotFound
+	}
+	return f, nil
+}
+
+// checkFields validates the custom field values of t against the fields of
+// its project and puts them in the form tasks hold them in. Values kept
+// from prev whose field the project does not define, because the task
+// moved out of the field's project, are dropped.
+func (s *Tasks) checkFields(ctx context.Context, t *model.Task, prev map[string]any) error {
+	if len(t.Fields) == 0 {
+		t.Fields = map[string]any{}
+		return nil
+	}
+	t.Fields = maps.Clone(t.Fields)
+	defined := map[string]model.CustomField{}
+	if t.ProjectID != nil {
+		fields, err := s.CustomFields.ListFields(ctx, *t.ProjectID)
+		if err != nil {
+			return err
+		}
+		for _, f := range fields {
+			defined[f.ID] = f
+		}
+	}
+	var v model.ValidationError
+	for _, id := range slices.Sorted(maps.Keys(t.Fields)) {
+		value := t.Fields[id]
+		f, ok := defined[id]
+		if !ok {
+			if _, kept := prev[id]; kept {
+				delete(t.Fields, id)
+			} else {
+				v.Add("fields."+id, "is not a custom field of the task's project")
+			}
+			continue
+		}
+		normal, err := f.Value(value)
+		if err != nil {
+			v.Add("fields."+id, err.Error())
+			continue
+		}
+		t.Fields[id] = normal
+	}
+	return v.Err()
+}
+
+// resolveFields checks the custom fields f filters and sorts by, which
+// must belong to projects userID is a member of, and parses the values
+// f.Fields holds as query text by the type of their field.
+func (s *Tasks) resolveFields(ctx context.Context, userID string, f *storage.TaskFilter) error {
+	var v model.ValidationError
+	for _, id := range slices.Sorted(maps.Keys(f.Fields)) {
+		field, err := s.visibleField(ctx, userID, id)
+		if errors.Is(err, storage.ErrNotFound) {
+			v.Add("field."+id, "is not a custom field")
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		raw, _ := f.Fields[id].(string)
+		value, err := field.ParseValue(raw)
+		if err != nil {
+			v.Add("field."+id, err.Error())
+			continue
+		}
+		f.Fields[id] = value
+	}
+	if id := f.Sort.CustomField; id != "" {
+		_, err := s.visibleField(ctx, userID, id)
+		if errors.Is(err, storage.ErrNotFound) {
+			v.Add("sort", "field:"+id+" is not a custom field")
+		} else if err != nil {
+			return err
+		}
+	}
+	return v.Err()
+}
+
+// visibleField returns the custom field with the given id if userID is a
+// member of its project.
+func (s *Tasks) visibleField(ctx context.Context, userID, id string) (model.CustomField, error) {
+	f, err := s.CustomFields.GetField(ctx, id)
+	if err != nil {
+		return model.CustomField{}, err
+	}
+	if _, err := authorizeProject(ctx, s.Projects, userID, f.ProjectID, model.RoleViewer); err != nil {
+		return model.CustomField{}, err
+	}
+	return f, nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"starttech-server/flags"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Flags lets administrators turn feature flags on and off, for everyone,
+// a share of the organizations, or particular organizations and users.
+// Changes take effect at once on this instance, and within the Set's TTL
+// on the others.
+type Flags struct {
+	Store storage.FlagStore
+	Set   *flags.Set
+	// Orgs and Users confirm that what a flag is turned on for exists.
+	Orgs  storage.OrgStore
+	Users storage.UserStore
+}
+
+// List returns every flag, by key.
+func (s *Flags) List(ctx context.Context) []model.Flag {
+	s.Set.Invalidate()
+	return s.Set.List(ctx)
+}
+
+// Get returns the flag with the given key.
+func (s *Flags) Get(ctx context.Context, key string) (model.Flag, error) {
+	s.Set.Invalidate()
+	f, ok := s.Set.Get(ctx, key)
+	if !ok {
+		return model.Flag{}, storage.ErrNotFound
+	}
+	return f, nil
+}
+
+// Save creates the flag with the given key or replaces its settings.
+func (s *Flags) Save(ctx context.Context, key string, in model.FlagInput) (model.Flag, error) {
+	var v model.ValidationError
+	if !model.ValidFlagKey(key) {
+		v.Add("key", "must be 1 to 64 lowercase letters, digits, dots, dashes or underscores")
+	}
+	if err := v.Err(); err != nil {
+		return model.Flag{}, err
+	}
+	if err := in.Validate(); err != nil {
+		return model.Flag{}, err
+	}
+	now := time.Now().UTC()
+	f := model.Flag{Key: key, Description: in.Description, Enabled: in.Enabled, Rollout: in.Rollout, UpdatedAt: &now}
+	if err := s.Store.SaveFlag(ctx, f); err != nil {
+		return model.Flag{}, err
+	}
+	return s.Get(ctx, key)
+}
+
+// Delete deletes the flag with the given key, with its organizations and
+// users. A known flag goes back to its default.
+func (s *Flags) Delete(ctx context.Context, key string) error {
+	if err := s.Store.DeleteFlag(ctx, key); err != nil {
+		return err
+	}
+	s.Set.Invalidate()
+	return nil
+}
+
+// SetOverride turns the flag with the given key on or off for the
+// organization or user with the given id. A known flag that is not stored
+// yet is stored as it is by default first.
+func (s *Flags) SetOverride(ctx context.Context, key string, subject model.FlagSubject, id string, in model.FlagOverrideInput) (model.Flag, error) {
+	if err := in.Validate(); err != nil {
+		return model.Flag{}, err
+	}
+	var err error
+	if subject == model.FlagUser {
+		_, err = s.Users.GetUser(ctx, id)
+	} else {
+		_, err = s.Orgs.GetOrg(ctx, id)
+	}
+	if err != nil {
+		return model.Flag{}, err
+	}
+	err = s.Store.SetFlagOverride(ctx, key, subject, id, *in.Enabled)
+	if errors.Is(err, storage.ErrNotFound) {
+		f, ferr := s.Get(ctx, key)
+		if ferr != nil || !f.Known {
+			return model.Flag{}, storage.ErrNotFound
+		}
+		now := time.Now().UTC()
+		f.UpdatedAt = &now
+		if err := s.Store.SaveFlag(ctx, f); err != nil {
+			return model.Flag{}, err
+		}
+		err = s.Store.SetFlagOverride(ctx, key, subject, id, *in.Enabled)
+	}
+	if err != nil {
+		return model.Flag{}, err
+	}
+	return s.Get(ctx, key)
+}
+
+// DeleteOverride stops the flag with the given key from being turned on or
+// off for the organization or user with the given id in particular.
+func (s *Flags) DeleteOverride(ctx context.Context, key string, subject model.FlagSubject, id string) error {
+	if err := s.Store.DeleteFlagOverride(ctx, key, subject, id); err != nil {
+		return err
+	}
+	s.Set.Invalidate()
+	return nil
+}
+
+// Mine returns whether each flag is on for the user userID in the
+// organization ctx acts for.
+func (s *Flags) Mine(ctx context.Context, userID string) map[string]bool {
+	return s.Set.For(ctx, orgOf(ctx), userID)
+}
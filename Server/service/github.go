@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"starttech-server/auth"
+	"starttech-server/integrations"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// ErrBadSignature is returned for a GitHub webhook delivery or Slack command
+// that was not signed with the secret of the project's link.
+var ErrBadSignature = errors.New("service: the webhook signature does not match")
+
+// GitHub links projects to GitHub repositories and keeps their tasks in
+// step with the repositories' issues. Only project owners may manage a
+// link. Tasks are created and changed in the name of the owner who linked
+// the repository, so the usual rules for tasks apply.
+type GitHub struct {
+	Store    storage.GitHubStore
+	Projects storage.ProjectStore
+	Tasks    *Tasks
+	Client   *integrations.GitHub
+}
+
+// githubWebhookURL is where the repository of a project's link sends its
+// webhook deliveries, relative to the API.
+func githubWebhookURL(projectID string) string {
+	return "/integrations/github/" + projectID
+}
+
+// Get returns the link of the project with the given id, without its
+// secret, if userID owns the project.
+func (s *GitHub) Get(ctx context.Context, userID, projectID string) (model.GitHubLink, error) {
+	l, err := s.get(ctx, userID, projectID)
+	l.Secret = ""
+	return l, err
+}
+
+func (s *GitHub) get(ctx context.Context, userID, projectID string) (model.GitHubLink, error) {
+	if _, err := authorizeProject(ctx, s.Projects, userID, projectID, model.RoleOwner); err != nil {
+		return model.GitHubLink{}, err
+	}
+	l, err := s.Store.GetGitHubLink(ctx, projectID)
+	if err != nil {
+		return model.GitHubLink{}, err
+	}
+	l.WebhookURL = githubWebhookURL(projectID)
+	return l, nil
+}
+
+// Link links the project with the given id to the repository in, replacing
+// any link it had, if userID owns the project and the token can see the
+// repository. Linking another repository forgets which tasks came from
+// the old one's issues. The returned link is the only place its webhook
+// secret is shown.
+func (s *GitHub) Link(ctx context.Context, userID, projectID string, in model.GitHubLinkInput) (model.GitHubLink, error) {
+	p, err := authorizeProject(ctx, s.Projects, userID, projectID, model.RoleOwner)
+	if err != nil {
+		return model.GitHubLink{}, err
+	}
+	l := model.GitHubLink{ProjectID: p.ID, OrgID: p.OrgID, Secret: newSecret(), CreatedBy: userID, CreatedAt: time.Now().UTC()}
+	in.Apply(&l)
+	if err := l.Validate(); err != nil {
+		return model.GitHubLink{}, err
+	}
+	if err := s.Client.CheckRepo(ctx, l.Token, l.Repo); err != nil {
+		if errors.Is(err, integrations.ErrUnauthorized) {
+			var v model.ValidationError
+			v.Add("token", "cannot access "+l.Repo)
+			return model.GitHubLink{}, v.Err()
+		}
+		return model.GitHubLink{}, err
+	}
+
+	old, err := s.Store.GetGitHubLink(ctx, projectID)
+	switch {
+	case err == nil && strings.EqualFold(old.Repo, l.Repo):
+		l.SyncedAt = old.SyncedAt
+	case err == nil:
+		if err := s.Store.DeleteGitHubLink(ctx, projectID); err != nil {
+			return model.GitHubLink{}, err
+		}
+	case !errors.Is(err, storage.ErrNotFound):
+		return model.GitHubLink{}, err
+	}
+	if err := s.Store.SaveGitHubLink(ctx, l); err != nil {
+		return model.GitHubLink{}, err
+	}
+	l.WebhookURL = githubWebhookURL(projectID)
+	return l, nil
+}
+
+// Unlink removes the link of the project with the given id if userID owns
+// the project. The imported tasks are kept.
+func (s *GitHub) Unlink(ctx context.Context, userID, projectID string) error {
+	if _, err := authorizeProject(ctx, s.Projects, userID, projectID, model.RoleOwner); err != nil {
+		return err
+	}
+	return s.Store.DeleteGitHubLink(ctx, projectID)
+}
+
+// Sync imports every issue of the repository the project with the given id
+// is linked to, if userID owns the project: issues seen for the first time
+// become tasks, and the tasks of the others take their title, body and
+// state.
+func (s *GitHub) Sync(ctx context.Context, userID, projectID string) (model.GitHubSyncResult, error) {
+	l, err := s.get(ctx, userID, projectID)
+	if err != nil {
+		return model.GitHubSyncResult{}, err
+	}
+	issues, err := s.Client.Issues(ctx, l.Token, l.Repo)
+	if err != nil {
+		return model.GitHubSyncResult{}, err
+	}
+	var res model.GitHubSyncResult
+	for _, is := range issues {
+		if err := s.apply(ctx, l, is, &res); err != nil {
+			return res, err
+		}
+	}
+	now := time.Now().UTC()
+	l.SyncedAt = &now
+	if err := s.Store.SaveGitHubLink(ctx, l); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// HandleWebhook processes a delivery from the webhook of the repository
+// the project with the given id is linked to. event is its X-GitHub-Event
+// header and signature its X-Hub-Signature-256. Issues that are opened,
+// edited, closed or reopened are applied like Sync applies them; other
+// events, such as the ping sent when the webhook is added, are accepted
+// and ignored.
+func (s *GitHub) HandleWebhook(ctx context.Context, projectID, event, signature string, body []byte) error {
+	l, err := s.Store.GetGitHubLink(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if !integrations.VerifySignature(l.Secret, body, signature) {
+		return ErrBadSignature
+	}
+	if event != "issues" {
+		return nil
+	}
+	var e integrations.IssuesEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		var v model.ValidationError
+		v.Add("body", "is not an issues event")
+		return v.Err()
+	}
+	if !strings.EqualFold(e.Repository.FullName, l.Repo) {
+		return nil
+	}
+	switch e.Action {
+	case "opened", "edited", "closed", "reopened":
+	default:
+		return nil
+	}
+	// Act as the owner who linked the repository would through the API.
+	ctx = auth.WithOrgID(auth.WithUserID(ctx, l.CreatedBy), l.OrgID)
+	var res model.GitHubSyncResult
+	return s.apply(ctx, l, e.Issue, &res)
+}
+
+// apply brings the task imported from is up to date, or imports it, and
+// counts the outcome in res. A task that cannot take the change, such as
+// one that may not be completed yet or has been deleted, is logged and
+// skipped.
+func (s *GitHub) apply(ctx context.Context, l model.GitHubLink, is integrations.Issue, res *model.GitHubSyncResult) error {
+	title := truncate(is.Title, model.MaxTitleLen)
+	body := truncate(is.Body, model.MaxDescriptionLen)
+	done := is.State == model.IssueClosed
+
+	il, err := s.Store.FindIssueLink(ctx, l.ProjectID, is.Number)
+	if errors.Is(err, storage.ErrNotFound) {
+		t, err := s.Tasks.Create(ctx, l.CreatedBy, model.TaskInput{Title: title, Description: body, Completed: done, ProjectID: &l.ProjectID})
+		if rejected(err) {
+			slog.WarnContext(ctx, "github: issue not imported", "repo", l.Repo, "number", is.Number, "err", err)
+			res.Skipped++
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		res.Created++
+		return s.Store.SaveIssueLink(ctx, model.IssueLink{TaskID: t.ID, ProjectID: l.ProjectID, Number: is.Number, State: is.State})
+	}
+	if err != nil {
+		return err
+	}
+
+	// Record the state first, so the task's change is not pushed back.
+	il.State = is.State
+	if err := s.Store.SaveIssueLink(ctx, il); err != nil {
+		return err
+	}
+	t, err := s.Tasks.Get(ctx, l.CreatedBy, il.TaskID)
+	if err == nil && t.Title == title && t.Description == body && t.Completed == done {
+		res.Unchanged++
+		return nil
+	}
+	if err == nil {
+		_, err = s.Tasks.Update(ctx, l.CreatedBy, il.TaskID, nil, model.TaskPatch{Title: &title, Description: &body, Completed: &done}.Apply)
+	}
+	if rejected(err) {
+		slog.WarnContext(ctx, "github: issue not applied", "repo", l.Repo, "number", is.Number, "task_id", il.TaskID, "err", err)
+		res.Skipped++
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	res.Updated++
+	return nil
+}
+
+// rejected reports whether err is the refusal of a change to a task, as
+// opposed to a failure to make it.
+func rejected(err error) bool {
+	var v *model.ValidationError
+	return errors.As(err, &v) || errors.Is(err, storage.ErrNotFound) ||
+		errors.Is(err, ErrForbidden) || errors.Is(err, ErrBlocked)
+}
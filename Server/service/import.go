@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Export calls fn with every task of the project with the given id, in
+// position order, if userID is a member. It stops at the first error fn
+// returns, so a caller streaming the records can give up when its client
+// goes away.
+func (s *Tasks) Export(ctx context.Context, userID, projectID string, fn func(model.TaskRecord) error) error {
+	names := map[string]string{}
+	f := storage.TaskFilter{Limit: MaxPageSize}
+	cursor := ""
+	for {
+		page, err := s.InProject(ctx, userID, projectID, f, cursor)
+		if err != nil {
+			return err
+		}
+		for _, t := range page.Items {
+			rec, err := s.exportRecord(ctx, t, names)
+			if err != nil {
+				return err
+			}
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// exportRecord converts t, naming its tags from names and filling names in
+// as tags are looked up.
+func (s *Tasks) exportRecord(ctx context.Context, t model.Task, names map[string]string) (model.TaskRecord, error) {
+	rec := model.TaskRecord{
+		ID:          t.ID,
+		Title:       t.Title,
+		Description: t.Description,
+		Status:      t.Status,
+		Completed:   t.Completed,
+		DueDate:     t.DueDate,
+		Recurrence:  t.Recurrence,
+		Tags:        []string{},
+		CreatedAt:   &t.CreatedAt,
+		UpdatedAt:   &t.UpdatedAt,
+	}
+	for _, id := range t.TagIDs {
+		name, ok := names[id]
+		if !ok {
+			tag, err := s.Tags.GetTag(ctx, id)
+			if err != nil && !errors.Is(err, storage.ErrNotFound) {
+				return model.TaskRecord{}, err
+			}
+			name = tag.Name
+			names[id] = name
+		}
+		if name != "" {
+			rec.Tags = append(rec.Tags, name)
+		}
+	}
+	return rec, nil
+}
+
+// ImportOptions adjust Import.
+type ImportOptions struct {
+	// DryRun reports what would be created without creating anything.
+	DryRun bool
+	// KeepDuplicates creates the records that match an existing task
+	// instead of skipping them.
+	KeepDuplicates bool
+}
+
+// ImportItem is the outcome of one imported record. Err is set for an
+// invalid record, and is always a *model.ValidationError.
+type ImportItem struct {
+	Action      model.ImportAction
+	Task        *model.Task
+	DuplicateOf string
+	Err         error
+}
+
+// Import creates a task in the project with the given id for each of
+// records, if userID may edit the project, in a single transaction. A
+// record is a duplicate when it carries the ID of a task of the project, or
+// has the title and due date of one, or of an earlier record; duplicates
+// are skipped unless opts.KeepDuplicates is set. Tags are named and must
+// be userID's own. If any record is invalid, or opts.DryRun is set,
+// nothing is created and committed is false; the items still describe
+// what the import would have done.
+func (s *Tasks) Import(ctx context.Context, userID, projectID string, records []model.TaskRecord, opts ImportOptions) (items []ImportItem, committed bool, err error) {
+	var v model.ValidationError
+	switch n := len(records); {
+	case n == 0:
+		v.Add("rows", "is required")
+	case n > model.MaxImportRows:
+		v.Add("rows", fmt.Sprintf("must hold at most %d rows", model.MaxImportRows))
+	}
+	if err := v.Err(); err != nil {
+		return nil, false, err
+	}
+	if _, err := authorizeProject(ctx, s.Projects, userID, projectID, model.RoleEditor); err != nil {
+		return nil, false, err
+	}
+	owned, err := s.Tags.ListTags(ctx, orgOf(ctx), userID)
+	if err != nil {
+		return nil, false, err
+	}
+	tags := make(map[string]string, len(owned))
+	for _, t := range owned {
+		tags[strings.ToLower(t.Name)] = t.ID
+	}
+	// New tasks go to the end of their column, so they must not race a
+	// Move; holding the lock also keeps the duplicate check current.
+	s.moveMu.Lock()
+	defer s.moveMu.Unlock()
+	existing, err := s.Store.ListTasks(ctx, storage.TaskFilter{OrgID: orgOf(ctx), ProjectID: projectID})
+	if err != nil {
+		return nil, false, err
+	}
+	ids := make(map[string]bool, len(existing))
+	seen := make(map[string]string, len(existing))
+	for _, t := range existing {
+		ids[t.ID] = true
+		seen[duplicateKey(t.Title, t.DueDate)] = t.ID
+	}
+
+	var (
+		pending events.Buffer
+		invalid bool
+	)
+	items = make([]ImportItem, len(records))
+	err = s.Tx.InTx(ctx, func(tx storage.Store) error {
+		inner := s.within(tx, &pending)
+		for i, rec := range records {
+			item := &items[i]
+			key := duplicateKey(rec.Title, rec.DueDate)
+			if !opts.KeepDuplicates {
+				if ids[rec.ID] {
+					*item = ImportItem{Action: model.ImportDuplicate, DuplicateOf: rec.ID}
+					continue
+				}
+				if id, ok := seen[key]; ok {
+					*item = ImportItem{Action: model.ImportDuplicate, DuplicateOf: id}
+					continue
+				}
+			}
+			t, err := inner.importRecord(ctx, userID, projectID, rec, tags)
+			var invalidErr *model.ValidationError
+			if errors.As(err, &invalidErr) {
+				*item = ImportItem{Action: model.ImportInvalid, Err: err}
+				invalid = true
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			// Records after this one are matched against it, but
+			// DuplicateOf only ever names tasks that existed before.
+			if _, ok := seen[key]; !ok {
+				seen[key] = ""
+			}
+			if opts.DryRun {
+				t.ID = ""
+			}
+			*item = ImportItem{Action: model.ImportCreate, Task: &t}
+		}
+		if invalid || opts.DryRun {
+			return errRollBack
+		}
+		return s.stage(ctx, tx, &pending)
+	})
+	if errors.Is(err, errRollBack) {
+		return items, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	s.flush(&pending)
+	return items, true, nil
+}
+
+// importRecord creates the task for rec in the project with the given id.
+// tags maps the lowercased names of the caller's tags to their IDs. An
+// unknown tag makes the record invalid, along with anything Create
+// rejects; the task is left to the rollback that follows.
+func (s *Tasks) importRecord(ctx context.Context, userID, projectID string, rec model.TaskRecord, tags map[string]string) (model.Task, error) {
+	in := model.TaskInput{
+		Title:       rec.Title,
+		Description: rec.Description,
+		Status:      rec.Status,
+		Completed:   rec.Completed,
+		DueDate:     rec.DueDate,
+		Recurrence:  rec.Recurrence,
+		ProjectID:   &projectID,
+	}
+	var unknown model.ValidationError
+	for _, name := range rec.Tags {
+		if id, ok := tags[strings.ToLower(strings.TrimSpace(name))]; ok {
+			in.TagIDs = append(in.TagIDs, id)
+		} else {
+			unknown.Add("tags", "no tag named "+name)
+		}
+	}
+	t, err := s.Create(ctx, userID, in)
+	var v *model.ValidationError
+	switch {
+	case errors.As(err, &v):
+		v.Fields = append(v.Fields, unknown.Fields...)
+		return model.Task{}, v
+	case err != nil:
+		return model.Task{}, err
+	}
+	if err := unknown.Err(); err != nil {
+		return model.Task{}, err
+	}
+	return t, nil
+}
+
+// duplicateKey is what two tasks share when one duplicates the other.
+func duplicateKey(title string, due *time.Time) string {
+	key := strings.ToLower(strings.TrimSpace(title))
+	if due != nil {
+		key += "\x00" + due.UTC().Format(time.RFC3339)
+	}
+	return key
+}
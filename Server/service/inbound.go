@@ -0,0 +1,163 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"starttech-server/auth"
+	"starttech-server/mailin"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// InboundEmail turns emails sent to users' private addresses into tasks.
+// The mail server that receives mail for Domain hands each message to
+// Receive along with Secret; an address's token is as good as a password
+// for creating tasks, so creating the address again replaces one that
+// has leaked.
+type InboundEmail struct {
+	Store storage.InboundStore
+	// Orgs confirms that an address's owner still belongs to its
+	// organization.
+	Orgs   storage.OrgStore
+	Tasks  *Tasks
+	Domain string
+	Secret string
+}
+
+// Get returns userID's address in the request's organization, without
+// its token.
+func (s *InboundEmail) Get(ctx context.Context, userID string) (model.InboundAddress, error) {
+	return s.Store.GetInboundAddress(ctx, orgOf(ctx), userID)
+}
+
+// Create gives userID an address in the request's organization with a new
+// token, replacing the one they had. The token and address are only
+// returned here.
+func (s *InboundEmail) Create(ctx context.Context, userID string) (model.InboundAddress, error) {
+	// Local parts may be lowercased on the way, so the token is hex, and
+	// short enough to stay within their 64 characters.
+	b := make([]byte, 20)
+	rand.Read(b)
+	token := hex.EncodeToString(b)
+	a := model.InboundAddress{
+		UserID:    userID,
+		OrgID:     orgOf(ctx),
+		TokenHash: hashToken(token),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.Store.SaveInboundAddress(ctx, a); err != nil {
+		return model.InboundAddress{}, err
+	}
+	a.Token = token
+	a.Address = token + "@" + s.Domain
+	return a, nil
+}
+
+// Delete turns userID's address in the request's organization off.
+func (s *InboundEmail) Delete(ctx context.Context, userID string) error {
+	return s.Store.DeleteInboundAddress(ctx, orgOf(ctx), userID)
+}
+
+// Receive turns the email read from r into a task of the owner of the
+// first address of Domain it was sent to, whether named in its headers or
+// given as recipient, the envelope recipient, which Bcc leaves out of the
+// headers. The subject becomes the title and the text the description;
+// attachments the server does not accept are skipped. A secret that does
+// not match yields ErrBadSignature, and an email to no known address
+// storage.ErrNotFound.
+func (s *InboundEmail) Receive(ctx context.Context, secret, recipient string, r io.Reader) (model.InboundResult, error) {
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(s.Secret)) != 1 {
+		return model.InboundResult{}, ErrBadSignature
+	}
+	m, err := mailin.Read(r)
+	if err != nil {
+		var v model.ValidationError
+		v.Add("message", err.Error())
+		return model.InboundResult{}, v.Err()
+	}
+	a, err := s.address(ctx, append([]string{recipient}, m.Recipients...))
+	if err != nil {
+		return model.InboundResult{}, err
+	}
+	if _, err := s.Orgs.GetOrgMember(ctx, a.OrgID, a.UserID); err != nil {
+		return model.InboundResult{}, err
+	}
+	// Act as the owner would through the API.
+	ctx = auth.WithOrgID(auth.WithUserID(ctx, a.UserID), a.OrgID)
+
+	t, err := s.Tasks.Create(ctx, a.UserID, model.TaskInput{
+		Title:       clip(m.Subject, model.MaxTitleLen, "(no subject)"),
+		Description: clip(m.Text, model.MaxDescriptionLen, ""),
+	})
+	if err != nil {
+		return model.InboundResult{}, err
+	}
+	res := model.InboundResult{Task: t, Attachments: []model.Attachment{}, Skipped: []string{}}
+	if s.Tasks.Attachments == nil {
+		return res, nil
+	}
+	for _, f := range m.Attachments {
+		name := f.Filename
+		if name == "" {
+			name = "attachment"
+		}
+		att, err := s.Tasks.Attachments.Upload(ctx, a.UserID, t.ID, name, bytes.NewReader(f.Data))
+		var (
+			v     *model.ValidationError
+			quota *QuotaError
+		)
+		switch {
+		case errors.Is(err, ErrTooLarge):
+			res.Skipped = append(res.Skipped, name+": the file is too large")
+		case errors.Is(err, ErrUnsupportedType):
+			res.Skipped = append(res.Skipped, name+": files of this type are not accepted")
+		case errors.As(err, &quota):
+			res.Skipped = append(res.Skipped, name+": the organization's storage quota is used up")
+		case errors.As(err, &v):
+			res.Skipped = append(res.Skipped, name+": "+v.Error())
+		case err != nil:
+			return model.InboundResult{}, err
+		default:
+			res.Attachments = append(res.Attachments, att)
+		}
+	}
+	return res, nil
+}
+
+// address returns the first of the given addresses that belongs to a user.
+func (s *InboundEmail) address(ctx context.Context, recipients []string) (model.InboundAddress, error) {
+	for _, r := range recipients {
+		local, domain, ok := strings.Cut(strings.TrimSpace(r), "@")
+		if !ok || !strings.EqualFold(domain, s.Domain) {
+			continue
+		}
+		a, err := s.Store.GetInboundAddressByToken(ctx, hashToken(strings.ToLower(local)))
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		return a, err
+	}
+	return model.InboundAddress{}, storage.ErrNotFound
+}
+
+// clip shortens s to at most n characters, or returns empty when s is
+// blank.
+func clip(s string, n int, empty string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return empty
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Job list size bounds for Jobs.List.
+const (
+	DefaultJobLimit = 50
+	MaxJobLimit     = 200
+)
+
+// Jobs lets the server's administrators inspect the background job queue
+// and retry or discard the jobs in it. Like Admin, it trusts its callers to
+// be administrators.
+type Jobs struct {
+	Store storage.JobStore
+}
+
+// List returns the jobs matching f, newest first.
+func (s *Jobs) List(ctx context.Context, f storage.JobFilter) ([]model.Job, error) {
+	if f.Status != "" && !f.Status.Valid() {
+		var v model.ValidationError
+		v.Add("status", "must be pending, running, succeeded or dead")
+		return nil, v.Err()
+	}
+	switch {
+	case f.Limit <= 0:
+		f.Limit = DefaultJobLimit
+	case f.Limit > MaxJobLimit:
+		f.Limit = MaxJobLimit
+	}
+	return s.Store.ListJobs(ctx, f)
+}
+
+// Get returns the job with the given id.
+func (s *Jobs) Get(ctx context.Context, id string) (model.Job, error) {
+	return s.Store.GetJob(ctx, id)
+}
+
+// Retry gives a dead job a fresh set of attempts, starting now. A job that
+// is not dead yields storage.ErrConflict.
+func (s *Jobs) Retry(ctx context.Context, id string) (model.Job, error) {
+	j, err := s.Get(ctx, id)
+	if err != nil {
+		return model.Job{}, err
+	}
+	if j.Status != model.JobDead {
+		return model.Job{}, storage.ErrConflict
+	}
+	j.Status, j.Attempts, j.LastError = model.JobPending, 0, ""
+	j.RunAt, j.LockedUntil, j.CompletedAt = time.Now().UTC(), nil, nil
+	if err := s.Store.UpdateJob(ctx, &j); err != nil {
+		return model.Job{}, err
+	}
+	return j, nil
+}
+
+// Discard deletes the job with the given id, whatever its status. A job
+// that is running finishes its attempt, but nothing more is recorded.
+func (s *Jobs) Discard(ctx context.Context, id string) error {
+	return s.Store.DeleteJob(ctx, id)
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Members lists the members of a project userID belongs to.
+func (s *Projects) Members(ctx context.Context, userID, projectID string) ([]model.Member, error) {
+	if _, err := authorizeProject(ctx, s.Store, userID, projectID, model.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.Store.ListMembers(ctx, projectID)
+}
+
+// AddMember shares a project with another user. Only owners may add members;
+// adding somebody who is already a member yields storage.ErrConflict.
+func (s *Projects) AddMember(ctx context.Context, userID, projectID string, in model.MemberInput) (model.Member, error) {
+	p, err := authorizeProject(ctx, s.Store, userID, projectID, model.RoleOwner)
+	if err != nil {
+		return model.Member{}, err
+	}
+	if err := in.Validate(); err != nil {
+		return model.Member{}, err
+	}
+	u, err := s.lookupUser(ctx, in)
+	if err != nil {
+		return model.Member{}, err
+	}
+	if _, err := s.Store.GetMember(ctx, projectID, u.ID); err == nil {
+		return model.Member{}, storage.ErrConflict
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return model.Member{}, err
+	}
+
+	m := model.Member{ProjectID: projectID, UserID: u.ID, Username: u.Username, Role: in.Role, CreatedAt: time.Now().UTC()}
+	if err := s.Store.SaveMember(ctx, &m); err != nil {
+		return model.Member{}, err
+	}
+	s.publish(ctx, events.MemberAdded, s.members(ctx, p), m)
+	s.record(ctx, userID, events.MemberAdded, p, m.UserID, []model.Change{{Field: "role", Old: jsonNull, New: jsonValue(m.Role)}})
+	return m, nil
+}
+
+// lookupUser finds the user named by in, reporting an unknown one, or one
+// outside the request's organization, as a validation error.
+func (s *Projects) lookupUser(ctx context.Context, in model.MemberInput) (model.User, error) {
+	var (
+		u     model.User
+		err   error
+		field = "user_id"
+	)
+	if in.Email != "" {
+		field = "email"
+		u, err = s.Users.GetUserByEmail(ctx, in.Email)
+	} else {
+		u, err = s.Users.GetUser(ctx, in.UserID)
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		var v model.ValidationError
+		v.Add(field, "does not refer to a user")
+		return model.User{}, v.Err()
+	}
+	if err != nil {
+		return model.User{}, err
+	}
+	if _, err := s.Orgs.GetOrgMember(ctx, orgOf(ctx), u.ID); errors.Is(err, storage.ErrNotFound) {
+		var v model.ValidationError
+		v.Add(field, "is not a member of this organization")
+		return model.User{}, v.Err()
+	} else if err != nil {
+		return model.User{}, err
+	}
+	return u, nil
+}
+
+// UpdateMember changes the role of a member. Only owners may change roles,
+// and the last owner cannot be demoted.
+func (s *Projects) UpdateMember(ctx context.Context, userID, projectID, memberID string, patch model.MemberPatch) (model.Member, error) {
+	p, err := authorizeProject(ctx, s.Store, userID, projectID, model.RoleOwner)
+	if err != nil {
+		return model.Member{}, err
+	}
+	if !patch.Role.Valid() {
+		var v model.ValidationError
+		v.Add("role", "must be owner, editor or viewer")
+		return model.Member{}, v.Err()
+	}
+	m, err := s.Store.GetMember(ctx, projectID, memberID)
+	if err != nil {
+		return model.Member{}, err
+	}
+	if m.Role == model.RoleOwner && patch.Role != model.RoleOwner {
+		if err := s.keepAnOwner(ctx, projectID); err != nil {
+			return model.Member{}, err
+		}
+	}
+	before := m
+	m.Role = patch.Role
+	if err := s.Store.SaveMember(ctx, &m); err != nil {
+		return model.Member{}, err
+	}
+	s.publish(ctx, events.MemberUpdated, s.members(ctx, p), m)
+	if changes := diff(before, m); len(changes) > 0 {
+		s.record(ctx, userID, events.MemberUpdated, p, m.UserID, changes)
+	}
+	return m, nil
+}
+
+// RemoveMember takes a user out of a project. Owners may remove anybody and
+// every member may leave, but the last owner cannot go.
+func (s *Projects) RemoveMember(ctx context.Context, userID, projectID, memberID string) error {
+	need := model.RoleOwner
+	if memberID == userID {
+		need = model.RoleViewer
+	}
+	p, err := authorizeProject(ctx, s.Store, userID, projectID, need)
+	if err != nil {
+		return err
+	}
+	m, err := s.Store.GetMember(ctx, projectID, memberID)
+	if err != nil {
+		return err
+	}
+	if m.Role == model.RoleOwner {
+		if err := s.keepAnOwner(ctx, projectID); err != nil {
+			return err
+		}
+	}
+	// Tell the departing member too.
+	to := s.members(ctx, p)
+	if err := s.Store.RemoveMember(ctx, projectID, memberID); err != nil {
+		return err
+	}
+	if err := s.unassign(ctx, projectID, memberID); err != nil {
+		return err
+	}
+	s.publish(ctx, events.MemberRemoved, to, m)
+	s.record(ctx, userID, events.MemberRemoved, p, m.UserID, []model.Change{{Field: "role", Old: jsonValue(m.Role), New: jsonNull}})
+	return nil
+}
+
+// unassign clears userID from the tasks of a project they left, in and out
+// of the trash.
+func (s *Projects) unassign(ctx context.Context, projectID, userID string) error {
+	now := time.Now().UTC()
+	for _, trashed := range []bool{false, true} {
+		tasks, err := s.Tasks.ListTasks(ctx, storage.TaskFilter{ProjectID: projectID, AssigneeID: userID, Trashed: trashed})
+		if err != nil {
+			return err
+		}
+		for i := range tasks {
+			tasks[i].AssigneeID = nil
+			tasks[i].UpdatedAt = now
+			if err := s.Tasks.UpdateTask(ctx, &tasks[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// keepAnOwner returns ErrLastOwner unless the project has another owner
+// besides the one about to be demoted or removed.
+func (s *Projects) keepAnOwner(ctx context.Context, projectID string) error {
+	members, err := s.Store.ListMembers(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	owners := 0
+	for _, m := range members {
+		if m.Role == model.RoleOwner {
+			owners++
+		}
+	}
+	if owners < 2 {
+		return ErrLastOwner
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+func TestRoles(t *testing.T) {
+	// u1 owns the project; the others hold the role they are named after,
+	// and "outsider" is no member.
+	type fixture struct {
+		tasks    *Tasks
+		projects *Projects
+		project  model.Project
+		task     model.Task
+	}
+	setup := func(t *testing.T) (fixture, context.Context) {
+		s, ctx := newTestTasks(t)
+		store := s.Projects.(*storage.MemoryStore)
+		projects := &Projects{Store: store, Tasks: store, History: store, Users: store, Orgs: store, CustomFields: store, Automations: store}
+		p, err := projects.Create(ctx, "u1", model.ProjectInput{Name: "Shared"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range []model.Member{
+			{ProjectID: p.ID, UserID: "owner", Role: model.RoleOwner},
+			{ProjectID: p.ID, UserID: "editor", Role: model.RoleEditor},
+			{ProjectID: p.ID, UserID: "viewer", Role: model.RoleViewer},
+		} {
+			m.CreatedAt = time.Now().UTC()
+			if err := store.SaveMember(ctx, &m); err != nil {
+				t.Fatal(err)
+			}
+		}
+		task, err := s.Create(ctx, "u1", model.TaskInput{Title: "shared", ProjectID: &p.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fixture{s, projects, p, task}, ctx
+	}
+
+	actions := []struct {
+		name string
+		do   func(f fixture, ctx context.Context, user string) error
+		// least is the lowest role allowed to act.
+		least model.Role
+	}{
+		{"read a task", func(f fixture, ctx context.Context, user string) error {
+			_, err := f.tasks.Get(ctx, user, f.task.ID)
+			return err
+		}, model.RoleViewer},
+		{"read the project", func(f fixture, ctx context.Context, user string) error {
+			_, err := f.projects.Get(ctx, user, f.project.ID)
+			return err
+		}, model.RoleViewer},
+		{"create a task", func(f fixture, ctx context.Context, user string) error {
+			_, err := f.tasks.Create(ctx, user, model.TaskInput{Title: "new", ProjectID: &f.project.ID})
+			return err
+		}, model.RoleEditor},
+		{"edit a task", func(f fixture, ctx context.Context, user string) error {
+			_, err := f.tasks.Update(ctx, user, f.task.ID, nil, func(t *model.Task) { t.Title = "edited" })
+			return err
+		}, model.RoleEditor},
+		{"delete a task", func(f fixture, ctx context.Context, user string) error {
+			return f.tasks.Delete(ctx, user, f.task.ID, ReparentChildren)
+		}, model.RoleEditor},
+		{"rename the project", func(f fixture, ctx context.Context, user string) error {
+			_, err := f.projects.Update(ctx, user, f.project.ID, func(p *model.Project) { p.Name = "Renamed" })
+			return err
+		}, model.RoleOwner},
+		{"change a role", func(f fixture, ctx context.Context, user string) error {
+			_, err := f.projects.UpdateMember(ctx, user, f.project.ID, "viewer", model.MemberPatch{Role: model.RoleEditor})
+			return err
+		}, model.RoleOwner},
+		{"remove another member", func(f fixture, ctx context.Context, user string) error {
+			return f.projects.RemoveMember(ctx, user, f.project.ID, "u1")
+		}, model.RoleOwner},
+		{"delete the project", func(f fixture, ctx context.Context, user string) error {
+			return f.projects.Delete(ctx, user, f.project.ID)
+		}, model.RoleOwner},
+	}
+	// A new task naming a project the caller is not in is invalid rather
+	// than missing.
+	invalid := map[string]bool{"create a task": true}
+	for _, a := range actions {
+		for _, user := range []string{"viewer", "editor", "owner", "outsider"} {
+			t.Run(a.name+" as "+user, func(t *testing.T) {
+				f, ctx := setup(t)
+				err := a.do(f, ctx, user)
+				var verr *model.ValidationError
+				if user == "outsider" && invalid[a.name] {
+					if !errors.As(err, &verr) {
+						t.Errorf("err = %v, want a validation error", err)
+					}
+					return
+				}
+				var want error
+				switch {
+				case user == "outsider":
+					want = storage.ErrNotFound
+				case !model.Role(user).Allows(a.least):
+					want = ErrForbidden
+				}
+				if !errors.Is(err, want) {
+					t.Errorf("err = %v, want %v", err, want)
+				}
+			})
+		}
+	}
+}
+
+func TestLeaveProject(t *testing.T) {
+	s, ctx := newTestTasks(t)
+	store := s.Projects.(*storage.MemoryStore)
+	projects := &Projects{Store: store, Tasks: store, History: store, Users: store, Orgs: store}
+	p, err := projects.Create(ctx, "u1", model.ProjectInput{Name: "Mine"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	viewer := model.Member{ProjectID: p.ID, UserID: "viewer", Role: model.RoleViewer, CreatedAt: time.Now().UTC()}
+	if err := store.SaveMember(ctx, &viewer); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := projects.RemoveMember(ctx, "viewer", p.ID, "viewer"); err != nil {
+		t.Errorf("a viewer leaving: %v", err)
+	}
+	if err := projects.RemoveMember(ctx, "u1", p.ID, "u1"); !errors.Is(err, ErrLastOwner) {
+		t.Errorf("the last owner leaving: err = %v, want %v", err, ErrLastOwner)
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/model"
+)
+
+// mention records the users comment c on task t names with @username, adds
+// a notification to their inboxes, makes them watchers of t and sends them
+// a TaskMentioned event. It returns every user the comment has mentioned;
+// those it mentioned before an edit are not told again. Only members of the
+// task's project can be mentioned, so tasks outside any project, which nobody but
+// their owner sees, have no mentions. userID, the author, is never
+// mentioned.
+func (s *Tasks) mention(ctx context.Context, userID string, t model.Task, c model.Comment) (map[string]bool, error) {
+	names := model.ParseMentions(c.Body)
+	if len(names) == 0 || t.ProjectID == nil {
+		return nil, nil
+	}
+	members, err := s.Projects.ListMembers(ctx, *t.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]string, len(members))
+	for _, m := range members {
+		byName[strings.ToLower(m.Username)] = m.UserID
+	}
+	earlier, err := s.Mentions.ListMentions(ctx, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	told := map[string]bool{}
+	for _, m := range earlier {
+		told[m.UserID] = true
+	}
+
+	now := time.Now().UTC()
+	var to []string
+	for _, name := range names {
+		id, ok := byName[name]
+		if !ok || id == userID || told[id] {
+			continue
+		}
+		told[id] = true
+		m := model.Mention{TaskID: t.ID, CommentID: c.ID, UserID: id, AuthorID: userID, CreatedAt: now}
+		if err := s.Mentions.CreateMention(ctx, &m); err != nil {
+			return nil, err
+		}
+		s.autoWatch(ctx, id, t)
+		s.notify(ctx, model.Notification{
+			OrgID: t.OrgID, UserID: id, Kind: model.NotifyMentioned, ActorID: userID,
+			TaskID: t.ID, CommentID: &c.ID, Title: t.Title, CreatedAt: now,
+		})
+		to = append(to, id)
+	}
+	if len(to) > 0 {
+		s.publish(ctx, events.TaskMentioned, to, t)
+	}
+	return told, nil
+}
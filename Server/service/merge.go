@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode"
+
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Merge folds the task otherID into the task id, if userID may edit both:
+// the comments, attachments and watchers of otherID move to id, its
+// subtasks move up a level, and it goes to the trash. It returns the task merged into.
+func (s *Tasks) Merge(ctx context.Context, userID, id, otherID string) (model.Task, error) {
+	if id == otherID {
+		var v model.ValidationError
+		v.Add("other_id", "must be another task")
+		return model.Task{}, v.Err()
+	}
+	var (
+		pending events.Buffer
+		t       model.Task
+	)
+	err := s.Tx.InTx(ctx, func(tx storage.Store) error {
+		inner := s.within(tx, &pending)
+		var err error
+		if t, err = inner.authorize(ctx, userID, id, model.RoleEditor); err != nil {
+			return err
+		}
+		other, err := inner.authorize(ctx, userID, otherID, model.RoleEditor)
+		if err != nil {
+			return err
+		}
+		if err := tx.MergeTask(ctx, otherID, id); err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		if err := inner.detachChildren(ctx, userID, other, ReparentChildren, now); err != nil {
+			return err
+		}
+		if err := inner.trash(ctx, &other, now); err != nil {
+			return err
+		}
+		// Reparenting may have moved t, if it was a subtask of other.
+		if t, err = tx.GetTask(ctx, id); err != nil {
+			return err
+		}
+		inner.publish(ctx, events.TaskDeleted, inner.audience(ctx, other), events.Deleted{ID: otherID})
+		inner.publish(ctx, events.TaskMerged, inner.audience(ctx, t), events.Merged{ID: otherID, IntoID: id})
+		inner.record(ctx, userID, events.TaskDeleted, other, "", nil)
+		inner.record(ctx, userID, events.TaskMerged, t, otherID, nil)
+		return s.stage(ctx, tx, &pending)
+	})
+	if err != nil {
+		return model.Task{}, err
+	}
+	s.flush(&pending)
+	return t, nil
+}
+
+// maxDuplicates bounds the possible duplicates reported for a new task.
+const maxDuplicates = 5
+
+// duplicates returns the IDs of the open tasks of t's project, other than
+// t, whose titles are near enough to t's that it may be the same task
+// entered twice. Tasks outside a project are not checked. The check only
+// informs, so failing to list the project is logged and reports none.
+func (s *Tasks) duplicates(ctx context.Context, t model.Task) []string {
+	if t.ProjectID == nil {
+		return nil
+	}
+	open := false
+	tasks, err := s.Store.ListTasks(ctx, storage.TaskFilter{ProjectID: *t.ProjectID, Archived: &open})
+	if err != nil {
+		slog.WarnContext(ctx, "looking for duplicate tasks", "project_id", *t.ProjectID, "err", err)
+		return nil
+	}
+	title := titleKey(t.Title)
+	var ids []string
+	for _, other := range tasks {
+		if other.ID != t.ID && !other.Completed && similarTitles(title, titleKey(other.Title)) {
+			ids = append(ids, other.ID)
+			if len(ids) == maxDuplicates {
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// titleKey reduces a title to its lower-case words, so that case,
+// punctuation and spacing do not tell titles apart.
+func titleKey(title string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// similarTitles reports whether the title keys a and b are equal, or share
+// at least 80% of their pairs of adjacent characters (their Sørensen–Dice
+// coefficient), which catches typos and small rewordings.
+func similarTitles(a, b string) bool {
+	if a == b {
+		return a != ""
+	}
+	pa, pb := []rune(a), []rune(b)
+	if len(pa) < 2 || len(pb) < 2 {
+		return false
+	}
+	pairs := map[[2]rune]int{}
+	for i := 0; i+1 < len(pa); i++ {
+		pairs[[2]rune{pa[i], pa[i+1]}]++
+	}
+	shared := 0
+	for i := 0; i+1 < len(pb); i++ {
+		p := [2]rune{pb[i], pb[i+1]}
+		if pairs[p] > 0 {
+			pairs[p]--
+			shared++
+		}
+	}
+	return 2*shared*10 >= 8*(len(pa)-1+len(pb)-1)
+}
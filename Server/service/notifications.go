@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/storage"
+	"starttech-server/tracing"
+)
+
+// Notifications manages a user's email preferences and inbox.
+type Notifications struct {
+	Store storage.NotificationStore
+	// Events receives a notification.read event when notifications are
+	// marked read, so the user's other windows can update. It may be nil.
+	Events events.Publisher
+}
+
+// Prefs returns userID's notification preferences.
+func (s *Notifications) Prefs(ctx context.Context, userID string) (model.NotificationPrefs, error) {
+	return s.Store.GetNotificationPrefs(ctx, userID)
+}
+
+// Update applies p to userID's preferences and saves them.
+func (s *Notifications) Update(ctx context.Context, userID string, p model.NotificationPrefsPatch) (model.NotificationPrefs, error) {
+	prefs, err := s.Store.GetNotificationPrefs(ctx, userID)
+	if err != nil {
+		return model.NotificationPrefs{}, err
+	}
+	p.Apply(&prefs)
+	if err := s.Store.SaveNotificationPrefs(ctx, prefs); err != nil {
+		return model.NotificationPrefs{}, err
+	}
+	return prefs, nil
+}
+
+// Inbox returns one page of userID's notifications in the caller's
+// organization, newest first, only the unread ones if unread is set.
+func (s *Notifications) Inbox(ctx context.Context, userID string, unread bool, limit int, cursor string) (model.NotificationPage, error) {
+	switch {
+	case limit <= 0:
+		limit = DefaultPageSize
+	case limit > MaxPageSize:
+		limit = MaxPageSize
+	}
+	f := storage.NotificationFilter{OrgID: orgOf(ctx), UserID: userID, Unread: unread, Limit: limit + 1}
+	if cursor != "" {
+		offset, err := decodeCursor(cursor)
+		if err != nil {
+			return model.NotificationPage{}, err
+		}
+		f.Offset = offset
+	}
+	items, err := s.Store.ListNotifications(ctx, f)
+	if err != nil {
+		return model.NotificationPage{}, err
+	}
+	page := model.NotificationPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.NextCursor = encodeCursor(f.Offset + limit)
+	}
+	if page.Unread, err = s.Store.CountUnreadNotifications(ctx, f.OrgID, userID); err != nil {
+		return model.NotificationPage{}, err
+	}
+	return page, nil
+}
+
+// MarkRead marks one of userID's notifications read and returns it.
+func (s *Notifications) MarkRead(ctx context.Context, userID, id string) (model.Notification, error) {
+	n, err := s.Store.GetNotification(ctx, id)
+	if err != nil {
+		return model.Notification{}, err
+	}
+	if n.UserID != userID || n.OrgID != orgOf(ctx) {
+		return model.Notification{}, storage.ErrNotFound
+	}
+	if n.ReadAt != nil {
+		return n, nil
+	}
+	now := time.Now().UTC()
+	if err := s.Store.MarkNotificationsRead(ctx, []string{n.ID}, now); err != nil {
+		return model.Notification{}, err
+	}
+	n.ReadAt = &now
+	s.read(ctx, userID, []string{n.ID})
+	return n, nil
+}
+
+// MarkAllRead marks every unread notification of userID in the caller's
+// organization read.
+func (s *Notifications) MarkAllRead(ctx context.Context, userID string) error {
+	unread, err := s.Store.ListNotifications(ctx, storage.NotificationFilter{OrgID: orgOf(ctx), UserID: userID, Unread: true})
+	if err != nil {
+		return err
+	}
+	if len(unread) == 0 {
+		return nil
+	}
+	ids := make([]string, len(unread))
+	for i, n := range unread {
+		ids[i] = n.ID
+	}
+	if err := s.Store.MarkNotificationsRead(ctx, ids, time.Now().UTC()); err != nil {
+		return err
+	}
+	s.read(ctx, userID, ids)
+	return nil
+}
+
+func (s *Notifications) read(ctx context.Context, userID string, ids []string) {
+	if s.Events == nil {
+		return
+	}
+	s.Events.Publish(events.Event{
+		Type:        events.NotificationRead,
+		Time:        time.Now().UTC(),
+		Data:        events.Read{IDs: ids},
+		OrgID:       orgOf(ctx),
+		Recipients:  []string{userID},
+		TraceParent: tracing.FromContext(ctx).TraceParent(),
+	})
+}
+
+// notify adds n to its user's inbox and pushes it to them. A failure is
+// only logged: the change the notification is about stands without it.
+func (s *Tasks) notify(ctx context.Context, n model.Notification) {
+	if err := s.Inbox.CreateNotification(ctx, &n); err != nil {
+		slog.WarnContext(ctx, "adding a notification", "user_id", n.UserID, "kind", n.Kind, "err", err)
+		return
+	}
+	s.publish(ctx, events.NotificationCreated, []string{n.UserID}, n)
+}
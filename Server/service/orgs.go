@@ -0,0 +1,390 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"starttech-server/i18n"
+	"starttech-server/model"
+	"starttech-server/notifications"
+	"starttech-server/storage"
+)
+
+var (
+	// ErrLastOrgOwner is returned when a change would leave an organization
+	// without an owner.
+	ErrLastOrgOwner = errors.New("service: an organization must keep at least one owner")
+	// ErrInvitationExpired is returned when an invitation is accepted too
+	// late.
+	ErrInvitationExpired = errors.New("service: the invitation has expired")
+	// ErrWrongInvitee is returned when somebody other than the invitee
+	// accepts an invitation.
+	ErrWrongInvitee = errors.New("service: the invitation was sent to another email address")
+)
+
+// Orgs manages organizations, their members and the invitations to join
+// them. Every member can see their organization and its people; admins
+// invite and remove members; owners also rename it and change roles.
+// Organizations the caller does not belong to are reported as
+// storage.ErrNotFound.
+type Orgs struct {
+	Store storage.OrgStore
+	Users storage.UserStore
+	// Mail delivers invitations. It may be nil, in which case the inviter
+	// passes on the token from the response.
+	Mail notifications.Sender
+	// TwoFactor tells whether members have two-factor authentication, for
+	// the organizations that require it.
+	TwoFactor *TwoFactor
+	// Resolver looks up the TXT records that verify the domains
+	// organizations claim; nil uses net.DefaultResolver.
+	Resolver TXTResolver
+}
+
+// authorize returns the organization with the given id, with Role set, if
+// userID holds at least role need in it.
+func (s *Orgs) authorize(ctx context.Context, userID, id string, need model.OrgRole) (model.Org, error) {
+	m, err := s.Store.GetOrgMember(ctx, id, userID)
+	if err != nil {
+		return model.Org{}, err
+	}
+	if !m.Role.Allows(need) {
+		return model.Org{}, ErrForbidden
+	}
+	o, err := s.Store.GetOrg(ctx, id)
+	o.Role = m.Role
+	return o, err
+}
+
+// List returns the organizations userID belongs to, oldest membership
+// first.
+func (s *Orgs) List(ctx context.Context, userID string) ([]model.Org, error) {
+	return s.Store.ListOrgs(ctx, userID)
+}
+
+// Get returns the organization with the given id if userID belongs to it.
+func (s *Orgs) Get(ctx context.Context, userID, id string) (model.Org, error) {
+	return s.authorize(ctx, userID, id, model.OrgMember)
+}
+
+// Membership returns userID's membership in the organization with the
+// given id, or storage.ErrNotFound if they do not belong to it.
+func (s *Orgs) Membership(ctx context.Context, userID, id string) (model.OrgMembership, error) {
+	return s.Store.GetOrgMember(ctx, id, userID)
+}
+
+// Admit checks that userID may work in the organization with the given id:
+// it returns storage.ErrNotFound if they do not belong to it, and
+// ErrTwoFactorSetupRequired if it requires two-factor authentication and
+// they have not enabled it.
+func (s *Orgs) Admit(ctx context.Context, userID, id string) error {
+	if _, err := s.Store.GetOrgMember(ctx, id, userID); err != nil {
+		return err
+	}
+	o, err := s.Store.GetOrg(ctx, id)
+	if err != nil || !o.RequireTwoFactor {
+		return err
+	}
+	on, err := s.TwoFactor.Enabled(ctx, userID)
+	if err == nil && !on {
+		err = ErrTwoFactorSetupRequired
+	}
+	return err
+}
+
+// Default returns the organization a new session of u works in: their
+// oldest membership. A user without any, such as one who has just
+// registered, gets a personal organization.
+func (s *Orgs) Default(ctx context.Context, u model.User) (model.Org, error) {
+	orgs, err := s.Store.ListOrgs(ctx, u.ID)
+	if err != nil {
+		return model.Org{}, err
+	}
+	if len(orgs) > 0 {
+		return orgs[0], nil
+	}
+	return s.Create(ctx, u.ID, model.OrgInput{Name: u.Username + "'s workspace"})
+}
+
+// Create validates in and stores it as a new organization owned by userID.
+// As with Update, only a user who has enabled two-factor authentication
+// can create one that requires it.
+func (s *Orgs) Create(ctx context.Context, userID string, in model.OrgInput) (model.Org, error) {
+	o := model.Org{CreatedAt: time.Now().UTC()}
+	in.Apply(&o)
+	if err := o.Validate(); err != nil {
+		return model.Org{}, err
+	}
+	if o.RequireTwoFactor {
+		if err := s.canRequireTwoFactor(ctx, userID); err != nil {
+			return model.Org{}, err
+		}
+	}
+	if err := s.Store.CreateOrg(ctx, &o, userID); err != nil {
+		return model.Org{}, err
+	}
+	o.Role = model.OrgOwner
+	return o, nil
+}
+
+// Update renames the organization with the given id, or changes whether
+// it requires two-factor authentication, if userID owns it. Only an owner
+// who has enabled two-factor authentication can require it, so that they
+// do not lock themselves out.
+func (s *Orgs) Update(ctx context.Context, userID, id string, in model.OrgInput) (model.Org, error) {
+	o, err := s.authorize(ctx, userID, id, model.OrgOwner)
+	if err != nil {
+		return model.Org{}, err
+	}
+	if in.RequireTwoFactor != nil && *in.RequireTwoFactor && !o.RequireTwoFactor {
+		if err := s.canRequireTwoFactor(ctx, userID); err != nil {
+			return model.Org{}, err
+		}
+	}
+	in.Apply(&o)
+	if err := o.Validate(); err != nil {
+		return model.Org{}, err
+	}
+	if err := s.Store.UpdateOrg(ctx, &o); err != nil {
+		return model.Org{}, err
+	}
+	return o, nil
+}
+
+// canRequireTwoFactor refuses, as a validation error, to let userID
+// require two-factor authentication they have not enabled themselves.
+func (s *Orgs) canRequireTwoFactor(ctx context.Context, userID string) error {
+	on, err := s.TwoFactor.Enabled(ctx, userID)
+	if err != nil || on {
+		return err
+	}
+	var v model.ValidationError
+	v.Add("require_two_factor", "enable two-factor authentication on your own account first")
+	return v.Err()
+}
+
+// Members lists the members of an organization userID belongs to.
+func (s *Orgs) Members(ctx context.Context, userID, id string) ([]model.OrgMembership, error) {
+	if _, err := s.authorize(ctx, userID, id, model.OrgMember); err != nil {
+		return nil, err
+	}
+	return s.Store.ListOrgMembers(ctx, id)
+}
+
+// UpdateMember changes the role of a member. Only owners may change roles,
+// and the last owner cannot be demoted.
+func (s *Orgs) UpdateMember(ctx context.Context, userID, id, memberID string, p model.OrgMemberPatch) (model.OrgMembership, error) {
+	if _, err := s.authorize(ctx, userID, id, model.OrgOwner); err != nil {
+		return model.OrgMembership{}, err
+	}
+	if !p.Role.Valid() {
+		var v model.ValidationError
+		v.Add("role", "must be owner, admin or member")
+		return model.OrgMembership{}, v.Err()
+	}
+	m, err := s.Store.GetOrgMember(ctx, id, memberID)
+	if err != nil {
+		return model.OrgMembership{}, err
+	}
+	if m.Role == model.OrgOwner && p.Role != model.OrgOwner {
+		if err := s.keepAnOwner(ctx, id); err != nil {
+			return model.OrgMembership{}, err
+		}
+	}
+	m.Role = p.Role
+	if err := s.Store.SaveOrgMember(ctx, &m); err != nil {
+		return model.OrgMembership{}, err
+	}
+	return m, nil
+}
+
+// RemoveMember takes a user out of an organization and its projects.
+// Members may leave, admins may remove plain members, and owners anybody,
+// except that the last owner cannot go.
+func (s *Orgs) RemoveMember(ctx context.Context, userID, id, memberID string) error {
+	need := model.OrgAdmin
+	if memberID == userID {
+		need = model.OrgMember
+	}
+	o, err := s.authorize(ctx, userID, id, need)
+	if err != nil {
+		return err
+	}
+	m, err := s.Store.GetOrgMember(ctx, id, memberID)
+	if err != nil {
+		return err
+	}
+	if memberID != userID && m.Role != model.OrgMember && o.Role != model.OrgOwner {
+		return ErrForbidden
+	}
+	if m.Role == model.OrgOwner {
+		if err := s.keepAnOwner(ctx, id); err != nil {
+			return err
+		}
+	}
+	return s.Store.RemoveOrgMember(ctx, id, memberID)
+}
+
+// keepAnOwner returns ErrLastOrgOwner unless the organization has another
+// owner besides the one about to be demoted or removed.
+func (s *Orgs) keepAnOwner(ctx context.Context, id string) error {
+	members, err := s.Store.ListOrgMembers(ctx, id)
+	if err != nil {
+		return err
+	}
+	owners := 0
+	for _, m := range members {
+		if m.Role == model.OrgOwner {
+			owners++
+		}
+	}
+	if owners < 2 {
+		return ErrLastOrgOwner
+	}
+	return nil
+}
+
+// Invitations lists the invitations of an organization that have not been
+// accepted. Only admins and owners may see them.
+func (s *Orgs) Invitations(ctx context.Context, userID, id string) ([]model.Invitation, error) {
+	if _, err := s.authorize(ctx, userID, id, model.OrgAdmin); err != nil {
+		return nil, err
+	}
+	return s.Store.ListInvitations(ctx, id)
+}
+
+// Invite asks the owner of in.Email to join an organization and emails them
+// the token. Admins may invite members and admins, owners also owners.
+// Inviting somebody who is already a member yields storage.ErrConflict.
+func (s *Orgs) Invite(ctx context.Context, userID, id string, in model.InvitationInput) (model.Invitation, error) {
+	o, err := s.authorize(ctx, userID, id, model.OrgAdmin)
+	if err != nil {
+		return model.Invitation{}, err
+	}
+	if err := in.Validate(); err != nil {
+		return model.Invitation{}, err
+	}
+	if !o.Role.Allows(in.Role) {
+		return model.Invitation{}, ErrForbidden
+	}
+	if u, err := s.Users.GetUserByEmail(ctx, in.Email); err == nil {
+		if _, err := s.Store.GetOrgMember(ctx, id, u.ID); err == nil {
+			return model.Invitation{}, storage.ErrConflict
+		}
+	}
+
+	now := time.Now().UTC()
+	token := newToken()
+	inv := model.Invitation{
+		OrgID:     id,
+		Email:     in.Email,
+		Role:      in.Role,
+		InvitedBy: userID,
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(model.InvitationTTL),
+	}
+	if err := s.Store.CreateInvitation(ctx, &inv); err != nil {
+		return model.Invitation{}, err
+	}
+	inv.Token = token
+	s.sendInvitation(ctx, o, inv)
+	return inv, nil
+}
+
+// sendInvitation emails inv to the invitee. A failure is only logged: the
+// inviter still has the token to pass on.
+func (s *Orgs) sendInvitation(ctx context.Context, o model.Org, inv model.Invitation) {
+	if s.Mail == nil {
+		return
+	}
+	// Invitees with an account get the invitation in their language, others
+	// in the inviter's.
+	lang := i18n.Language(ctx)
+	if u, err := s.Users.GetUserByEmail(ctx, inv.Email); err == nil {
+		lang = language(ctx, u)
+	}
+	m := notifications.Message{
+		To:      inv.Email,
+		Subject: i18n.T(lang, "You're invited to join {0}", o.Name),
+		Body: i18n.T(lang, "You have been invited to join {0} as {1}.\n\n"+
+			"To accept, sign in with this email address and send this token to POST /invitations/accept:\n\n{2}\n\n"+
+			"The invitation expires on {3}.\n",
+			o.Name, inv.Role, inv.Token, inv.ExpiresAt.Format(i18n.T(lang, "2 January 2006"))),
+		Language: lang,
+	}
+	if err := s.Mail.Send(ctx, m); err != nil {
+		slog.WarnContext(ctx, "sending invitation", "invitation_id", inv.ID, "err", err)
+	}
+}
+
+// Revoke deletes an invitation that has not been accepted yet.
+func (s *Orgs) Revoke(ctx context.Context, userID, id, invitationID string) error {
+	if _, err := s.authorize(ctx, userID, id, model.OrgAdmin); err != nil {
+		return err
+	}
+	inv, err := s.Store.GetInvitation(ctx, invitationID)
+	if err != nil {
+		return err
+	}
+	if inv.OrgID != id || inv.AcceptedAt != nil {
+		return storage.ErrNotFound
+	}
+	return s.Store.DeleteInvitation(ctx, invitationID)
+}
+
+// Accept makes userID a member of the organization the invitation with the
+// given token is for. It must have been sent to their email address. An
+// unknown or already accepted token yields storage.ErrNotFound, and a user
+// who is already a member storage.ErrConflict.
+func (s *Orgs) Accept(ctx context.Context, userID string, in model.AcceptInput) (model.OrgMembership, error) {
+	inv, err := s.Store.GetInvitationByToken(ctx, hashToken(strings.TrimSpace(in.Token)))
+	if err != nil {
+		return model.OrgMembership{}, err
+	}
+	if inv.AcceptedAt != nil {
+		return model.OrgMembership{}, storage.ErrNotFound
+	}
+	now := time.Now().UTC()
+	if !now.Before(inv.ExpiresAt) {
+		return model.OrgMembership{}, ErrInvitationExpired
+	}
+	u, err := s.Users.GetUser(ctx, userID)
+	if err != nil {
+		return model.OrgMembership{}, err
+	}
+	if !strings.EqualFold(u.Email, inv.Email) {
+		return model.OrgMembership{}, ErrWrongInvitee
+	}
+	if _, err := s.Store.GetOrgMember(ctx, inv.OrgID, userID); err == nil {
+		return model.OrgMembership{}, storage.ErrConflict
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return model.OrgMembership{}, err
+	}
+
+	m := model.OrgMembership{OrgID: inv.OrgID, UserID: userID, Username: u.Username, Role: inv.Role, CreatedAt: now}
+	if err := s.Store.AcceptInvitation(ctx, inv.ID, &m); err != nil {
+		return model.OrgMembership{}, err
+	}
+	return m, nil
+}
+
+// newToken returns a random secret to hand out once and store hashed.
+func newToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// hashToken is how invitation and calendar tokens are stored, so a leaked
+// database does not let anybody use them.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+
+	"starttech-server/events"
+	"starttech-server/outbox"
+	"starttech-server/storage"
+)
+
+// atomically runs change on a copy of s that works in a transaction, whose
+// events are written to the outbox before it commits. Without an outbox, or
+// on a copy already working in a transaction, change runs on s as it is.
+func (s *Tasks) atomically(ctx context.Context, change func(context.Context, *Tasks) error) error {
+	if s.Outbox == nil {
+		return change(ctx, s)
+	}
+	var pending events.Buffer
+	err := s.Tx.InTx(ctx, func(tx storage.Store) error {
+		if err := change(ctx, s.within(tx, &pending)); err != nil {
+			return err
+		}
+		return s.stage(ctx, tx, &pending)
+	})
+	if err != nil {
+		return err
+	}
+	s.flush(&pending)
+	return nil
+}
+
+// stage moves the events held in pending to the outbox of tx, if s
+// delivers through one, so that they are delivered once tx commits and
+// never if it rolls back.
+func (s *Tasks) stage(ctx context.Context, tx storage.OutboxStore, pending *events.Buffer) error {
+	if s.Outbox == nil {
+		return nil
+	}
+	return outbox.Write(ctx, tx, pending.Take())
+}
+
+// flush delivers the events of a transaction that has committed: it wakes
+// the outbox if stage wrote them there, and publishes them to Events if
+// not.
+func (s *Tasks) flush(pending *events.Buffer) {
+	if s.Outbox != nil {
+		s.Outbox.Notify()
+	}
+	pending.Flush(s.Events)
+}
@@ -0,0 +1,453 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"slices"
+	"time"
+
+	"starttech-server/auth"
+	"starttech-server/blob"
+	"starttech-server/i18n"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// ErrWrongPassword is returned when a user confirms an action with a
+// password other than theirs.
+var ErrWrongPassword = errors.New("service: the password is wrong")
+
+// deletedOrgName renames the organizations an erased user was alone in.
+const deletedOrgName = "Deleted workspace"
+
+// Names of the entries of a personal data archive.
+const (
+	personalDataName = "data.json"
+	personalFilesDir = "attachments/"
+)
+
+// PersonalData exports what the server keeps about a user and deletes
+// accounts. A user who asks for their account to be deleted can restore it
+// for Grace; then EraseDue, which the scheduler runs, erases it: the
+// organizations they were alone in are emptied, their own tasks outside
+// projects deleted, tasks assigned to them unassigned, their audit events
+// redacted, and everything else that is theirs deleted, except what
+// others work with. That, such as their comments, attachments, time
+// entries and the tasks they created in shared projects, is kept under
+// the name of the erased account, deleted-<id>, unless EraseContent
+// deletes their comments and attachments too.
+type PersonalData struct {
+	Store storage.Store
+	// Tasks deletes tasks with the files of their attachments.
+	Tasks *Tasks
+	// Attachments reads the files exported and deletes those erased. It
+	// may be nil, in which case no file is exported.
+	Attachments *Attachments
+	// Accounts emails the notice of a deletion and signs the user out.
+	Accounts *Accounts
+	// Audit hashes the audit events rewritten by an erasure.
+	Audit        *Audit
+	Grace        time.Duration
+	EraseContent bool
+}
+
+// Export gathers the personal data of userID.
+func (s *PersonalData) Export(ctx context.Context, userID string) (model.PersonalData, error) {
+	u, err := s.Store.GetUser(ctx, userID)
+	if err != nil {
+		return model.PersonalData{}, err
+	}
+	d := model.PersonalData{ExportedAt: time.Now().UTC(), User: u}
+	if d.Orgs, err = s.Store.ListOrgs(ctx, userID); err != nil {
+		return model.PersonalData{}, err
+	}
+	if d.Sessions, err = s.Store.ListSessions(ctx, userID); err != nil {
+		return model.PersonalData{}, err
+	}
+	d.Tasks, d.Tags, d.Views, d.Templates = []model.Task{}, []model.Tag{}, []model.View{}, []model.Template{}
+	for _, o := range d.Orgs {
+		for _, trashed := range []bool{false, true} {
+			tasks, err := s.Store.ListTasks(ctx, storage.TaskFilter{OrgID: o.ID, OwnerID: userID, Trashed: trashed})
+			if err != nil {
+				return model.PersonalData{}, err
+			}
+			d.Tasks = append(d.Tasks, tasks...)
+		}
+		tags, err := s.Store.ListTags(ctx, o.ID, userID)
+		if err != nil {
+			return model.PersonalData{}, err
+		}
+		views, err := s.Store.ListViews(ctx, o.ID, userID)
+		if err != nil {
+			return model.PersonalData{}, err
+		}
+		templates, err := s.Store.ListTemplates(ctx, o.ID, userID)
+		if err != nil {
+			return model.PersonalData{}, err
+		}
+		d.Tags, d.Views, d.Templates = append(d.Tags, tags...), append(d.Views, views...), append(d.Templates, templates...)
+	}
+	if d.Comments, err = s.Store.ListUserComments(ctx, userID); err != nil {
+		return model.PersonalData{}, err
+	}
+	if d.Attachments, err = s.Store.ListUserAttachments(ctx, userID); err != nil {
+		return model.PersonalData{}, err
+	}
+	if d.TimeEntries, err = s.Store.ListTimeEntries(ctx, storage.TimeEntryFilter{UserID: userID}); err != nil {
+		return model.PersonalData{}, err
+	}
+	d.AuditEvents, err = s.Store.ListAuditEvents(ctx, storage.AuditFilter{ActorID: userID, Oldest: true})
+	if err != nil {
+		return model.PersonalData{}, err
+	}
+	return d, nil
+}
+
+// WriteZip writes d to w as a zip archive holding data.json and the file
+// of every attachment, under attachments/<attachment id>/<filename>.
+// Files that have not passed the virus scan, or are missing from the blob
+// store, are left out.
+func (s *PersonalData) WriteZip(ctx context.Context, w io.Writer, d model.PersonalData) error {
+	zw := zip.NewWriter(w)
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: personalDataName, Method: zip.Deflate, Modified: d.ExportedAt})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(d); err != nil {
+		return err
+	}
+	for _, a := range d.Attachments {
+		if s.Attachments == nil {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if a.Status != model.AttachmentClean {
+			continue
+		}
+		rc, err := s.Attachments.Blobs.Open(ctx, a.Key)
+		if errors.Is(err, blob.ErrNotFound) {
+			slog.WarnContext(ctx, "attachment missing from export", "attachment_id", a.ID, "key", a.Key)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		name := personalFilesDir + a.ID + "/" + path.Base("/"+a.Filename)
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: a.CreatedAt})
+		if err == nil {
+			_, err = io.CopyN(f, rc, a.Size)
+		}
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("copying attachment %s: %w", a.ID, err)
+		}
+	}
+	return zw.Close()
+}
+
+// RequestDeletion schedules the account of userID to be erased once Grace
+// has passed, given its password if it has one, signs it out of every
+// session but keepID and emails it a notice. The sole owner of an
+// organization others belong to must hand it over first, or gets
+// ErrLastOrgOwner. Asking again changes nothing.
+func (s *PersonalData) RequestDeletion(ctx context.Context, userID, password, keepID string) (model.AccountDeletion, error) {
+	u, err := s.Store.GetUser(ctx, userID)
+	if err != nil {
+		return model.AccountDeletion{}, err
+	}
+	if u.PasswordHash != "" && !auth.CheckPassword(u.PasswordHash, password) {
+		return model.AccountDeletion{}, ErrWrongPassword
+	}
+	if u.DeletionRequestedAt != nil {
+		return s.deletion(u), nil
+	}
+	orgs, err := s.Store.ListOrgs(ctx, userID)
+	if err != nil {
+		return model.AccountDeletion{}, err
+	}
+	for _, o := range orgs {
+		if o.Role != model.OrgOwner {
+			continue
+		}
+		members, err := s.Store.ListOrgMembers(ctx, o.ID)
+		if err != nil {
+			return model.AccountDeletion{}, err
+		}
+		if len(members) > 1 && soleOwner(members, userID) {
+			return model.AccountDeletion{}, ErrLastOrgOwner
+		}
+	}
+
+	now := time.Now().UTC()
+	u.DeletionRequestedAt = &now
+	if err := s.Store.UpdateUser(ctx, u); err != nil {
+		return model.AccountDeletion{}, err
+	}
+	if _, err := s.Accounts.Sessions.RevokeAll(ctx, userID, keepID); err != nil {
+		return model.AccountDeletion{}, err
+	}
+	d := s.deletion(u)
+	lang := language(ctx, u)
+	s.Accounts.send(ctx, u, "Your account will be deleted",
+		"As you asked, your account, {0}, will be deleted on {1}, with everything it holds.\n\n"+
+			"Until then you can sign in and keep it with POST /users/me/restore. If you did not ask for this, do so and change your password.\n",
+		u.Username, d.DeleteAt.In(u.Location()).Format(i18n.T(lang, "2 January 2006")))
+	return d, nil
+}
+
+// CancelDeletion keeps the account of userID, whose deletion was
+// requested. It returns storage.ErrNotFound if none was.
+func (s *PersonalData) CancelDeletion(ctx context.Context, userID string) (model.User, error) {
+	u, err := s.Store.GetUser(ctx, userID)
+	if err != nil {
+		return model.User{}, err
+	}
+	if u.DeletionRequestedAt == nil {
+		return model.User{}, storage.ErrNotFound
+	}
+	u.DeletionRequestedAt = nil
+	if err := s.Store.UpdateUser(ctx, u); err != nil {
+		return model.User{}, err
+	}
+	return u, nil
+}
+
+func (s *PersonalData) deletion(u model.User) model.AccountDeletion {
+	return model.AccountDeletion{RequestedAt: *u.DeletionRequestedAt, DeleteAt: u.DeletionRequestedAt.Add(s.Grace)}
+}
+
+// EraseDue erases the accounts whose deletion was requested before the
+// given time and returns how many there were. It suits scheduler.Purger,
+// with Grace as the retention.
+func (s *PersonalData) EraseDue(ctx context.Context, before time.Time) (int, error) {
+	users, err := s.Store.ListUsers(ctx, storage.UserFilter{DeletionRequestedBefore: &before})
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, u := range users {
+		if err := s.erase(ctx, u); err != nil {
+			return n, fmt.Errorf("erasing user %s: %w", u.ID, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// ErasableDue counts the accounts EraseDue would erase.
+func (s *PersonalData) ErasableDue(ctx context.Context, before time.Time) (int, error) {
+	users, err := s.Store.ListUsers(ctx, storage.UserFilter{DeletionRequestedBefore: &before})
+	return len(users), err
+}
+
+// erase erases the account u. Every step may be repeated, so an erasure
+// that fails halfway is finished by the next sweep.
+func (s *PersonalData) erase(ctx context.Context, u model.User) error {
+	orgs, err := s.Store.ListOrgs(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	for _, o := range orgs {
+		members, err := s.Store.ListOrgMembers(ctx, o.ID)
+		if err != nil {
+			return err
+		}
+		if len(members) == 1 {
+			err = s.emptyOrg(ctx, o, u.ID)
+		} else {
+			err = s.leaveOrg(ctx, o.ID, u.ID, members)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if s.EraseContent {
+		if err := s.eraseContent(ctx, u.ID); err != nil {
+			return err
+		}
+	}
+
+	erased := u
+	erased.Email = "deleted-" + u.ID + "@deleted.invalid"
+	erased.Username = "deleted-" + u.ID
+	erased.PasswordHash, erased.Timezone, erased.Language = "", model.DefaultTimezone, ""
+	now := time.Now().UTC()
+	erased.EmailVerifiedAt, erased.DeletionRequestedAt = nil, nil
+	erased.DisabledAt, erased.DeletedAt = &now, &now
+	e := model.AuditEvent{Action: model.AuditAccountErased, TargetID: u.ID, CreatedAt: now}
+	n, err := s.Store.RedactAuditEvents(ctx, u.ID, u.Email, &e, s.Audit.hash)
+	if err != nil {
+		return err
+	}
+	if err := s.Store.EraseUser(ctx, erased); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "account erased", "user_id", u.ID, "audit_events_redacted", n)
+	return nil
+}
+
+// emptyOrg deletes the projects and tasks of the organization o, which
+// userID alone belongs to, and renames it. The organization itself is
+// kept, empty, since its ID is referred to from elsewhere.
+func (s *PersonalData) emptyOrg(ctx context.Context, o model.Org, userID string) error {
+	projects, err := s.Store.ListProjects(ctx, o.ID, userID)
+	if err != nil {
+		return err
+	}
+	for _, p := range projects {
+		if err := s.Store.DeleteProject(ctx, p.ID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	}
+	if err := s.deleteTasks(ctx, storage.TaskFilter{OrgID: o.ID}, nil); err != nil {
+		return err
+	}
+	o.Name = deletedOrgName
+	return s.Store.UpdateOrg(ctx, &o)
+}
+
+// leaveOrg hands the organization orgID, and the projects in it, over to
+// another member where userID is their only owner, deletes the tasks
+// userID keeps there outside any project, and unassigns those assigned to
+// them.
+func (s *PersonalData) leaveOrg(ctx context.Context, orgID, userID string, members []model.OrgMembership) error {
+	if soleOwner(members, userID) {
+		heir := successor(members, userID)
+		heir.Role = model.OrgOwner
+		if err := s.Store.SaveOrgMember(ctx, &heir); err != nil {
+			return err
+		}
+	}
+	projects, err := s.Store.ListProjects(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	for _, p := range projects {
+		if err := s.leaveProject(ctx, p.ID, userID); err != nil {
+			return err
+		}
+	}
+	private := func(t model.Task) bool { return t.ProjectID == nil }
+	if err := s.deleteTasks(ctx, storage.TaskFilter{OrgID: orgID, OwnerID: userID}, private); err != nil {
+		return err
+	}
+	for _, trashed := range []bool{false, true} {
+		tasks, err := s.Store.ListTasks(ctx, storage.TaskFilter{OrgID: orgID, AssigneeID: userID, Trashed: trashed})
+		if err != nil {
+			return err
+		}
+		for _, t := range tasks {
+			t.AssigneeID = nil
+			t.UpdatedAt = time.Now().UTC()
+			if err := s.Store.UpdateTask(ctx, &t); err != nil && !errors.Is(err, storage.ErrStale) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// leaveProject makes the earliest other member of the project an owner if
+// userID is its only one.
+func (s *PersonalData) leaveProject(ctx context.Context, projectID, userID string) error {
+	members, err := s.Store.ListMembers(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	owners := 0
+	var heir *model.Member
+	for i, m := range members {
+		switch {
+		case m.Role == model.RoleOwner:
+			owners++
+		case m.UserID != userID && heir == nil:
+			heir = &members[i]
+		}
+	}
+	i := slices.IndexFunc(members, func(m model.Member) bool { return m.UserID == userID })
+	if i < 0 || members[i].Role != model.RoleOwner || owners > 1 || heir == nil {
+		return nil
+	}
+	heir.Role = model.RoleOwner
+	return s.Store.SaveMember(ctx, heir)
+}
+
+// deleteTasks deletes the tasks matching f, in the trash or not, that keep
+// does, or all of them if keep is nil. Tasks deleted along with a parent
+// before their turn are skipped.
+func (s *PersonalData) deleteTasks(ctx context.Context, f storage.TaskFilter, keep func(model.Task) bool) error {
+	for _, trashed := range []bool{false, true} {
+		f.Trashed = trashed
+		tasks, err := s.Store.ListTasks(ctx, f)
+		if err != nil {
+			return err
+		}
+		for _, t := range tasks {
+			if keep != nil && !keep(t) {
+				continue
+			}
+			if err := s.Tasks.deleteTask(ctx, t.ID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// eraseContent deletes the comments userID wrote and the files they
+// uploaded.
+func (s *PersonalData) eraseContent(ctx context.Context, userID string) error {
+	comments, err := s.Store.ListUserComments(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, c := range comments {
+		if err := s.Store.DeleteComment(ctx, c.ID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	}
+	attachments, err := s.Store.ListUserAttachments(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, a := range attachments {
+		if err := s.Store.DeleteAttachment(ctx, a.ID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	}
+	if s.Attachments != nil {
+		s.Attachments.removeBlobs(ctx, attachments)
+	}
+	return nil
+}
+
+// soleOwner reports whether userID is the only owner among members.
+func soleOwner(members []model.OrgMembership, userID string) bool {
+	for _, m := range members {
+		if m.Role == model.OrgOwner && m.UserID != userID {
+			return false
+		}
+	}
+	return slices.ContainsFunc(members, func(m model.OrgMembership) bool { return m.UserID == userID && m.Role == model.OrgOwner })
+}
+
+// successor returns the member to hand an organization over to when
+// userID leaves: the earliest admin, or else the earliest other member.
+// members, earliest first, must hold somebody else.
+func successor(members []model.OrgMembership, userID string) model.OrgMembership {
+	others := slices.DeleteFunc(slices.Clone(members), func(m model.OrgMembership) bool { return m.UserID == userID })
+	if i := slices.IndexFunc(others, func(m model.OrgMembership) bool { return m.Role == model.OrgAdmin }); i >= 0 {
+		return others[i]
+	}
+	return others[0]
+}
@@ -0,0 +1,358 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// ErrNotAdjacent is returned when a task is to be put between two tasks
+// that are not next to each other.
+var ErrNotAdjacent = errors.New("service: after_id and before_id are not neighbouring tasks")
+
+// InProject lists one page of the tasks in the project with the given id,
+// by position unless f asks for another order.
+func (s *Tasks) InProject(ctx context.Context, userID, projectID string, f storage.TaskFilter, cursor string) (model.TaskPage, error) {
+	if _, err := authorizeProject(ctx, s.Projects, userID, projectID, model.RoleViewer); err != nil {
+		return model.TaskPage{}, err
+	}
+	f.ProjectID = projectID
+	if f.Sort == (storage.Sort{}) {
+		f.Sort.Field = storage.SortPosition
+	}
+	return s.List(ctx, userID, f, cursor)
+}
+
+// Reorder sets the order of the tasks in a project. taskIDs must list every
+// task of the project exactly once.
+func (s *Tasks) Reorder(ctx context.Context, userID, projectID string, taskIDs []string) error {
+	p, err := authorizeProject(ctx, s.Projects, userID, projectID, model.RoleEditor)
+	if err != nil {
+		return err
+	}
+	current, err := s.Store.ListTasks(ctx, storage.TaskFilter{ProjectID: projectID})
+	if err != nil {
+		return err
+	}
+	want := make([]string, len(current))
+	for i, t := range current {
+		want[i] = t.ID
+	}
+	got := slices.Clone(taskIDs)
+	slices.Sort(want)
+	slices.Sort(got)
+	if !slices.Equal(want, got) {
+		var v model.ValidationError
+		v.Add("task_ids", "must list every task of the project exactly once")
+		return v.Err()
+	}
+
+	return s.atomically(ctx, func(ctx context.Context, tx *Tasks) error {
+		if err := tx.Projects.ReorderTasks(ctx, projectID, taskIDs); err != nil {
+			return err
+		}
+		tx.publish(ctx, events.TasksReordered, audience(ctx, tx.Projects, p.OwnerID, &p.ID), events.Reordered{ProjectID: projectID, TaskIDs: taskIDs})
+		recordActivity(ctx, tx.Log, model.Activity{ProjectID: &p.ID, ActorID: userID, Action: string(events.TasksReordered), SubjectID: p.ID})
+		return nil
+	})
+}
+
+// workflow returns the statuses available in the given project, in which
+// userID must be an editor, or the default workflow when projectID is nil.
+func (s *Tasks) workflow(ctx context.Context, userID string, projectID *string) (model.Workflow, error) {
+	if projectID == nil {
+		return model.DefaultWorkflow, nil
+	}
+	p, err := authorizeProject(ctx, s.Projects, userID, *projectID, model.RoleEditor)
+	if errors.Is(err, storage.ErrNotFound) {
+		var v model.ValidationError
+		v.Add("project_id", "does not refer to one of your projects")
+		return nil, v.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.Statuses, nil
+}
+
+// placeInProject moves t to the end of its project, if the project has
+// room for another task.
+func (s *Tasks) placeInProject(ctx context.Context, userID string, t *model.Task) error {
+	if t.ProjectID == nil {
+		t.Position = 0
+		return nil
+	}
+	if s.MaxProjectTasks > 0 {
+		n, err := projectTasks(ctx, s.Store, *t.ProjectID)
+		if err != nil {
+			return err
+		}
+		if n >= s.MaxProjectTasks {
+			return &QuotaError{Quota: QuotaTasksPerProject, Limit: int64(s.MaxProjectTasks)}
+		}
+	}
+	last, err := s.Store.ListTasks(ctx, storage.TaskFilter{
+		ProjectID: *t.ProjectID,
+		Sort:      storage.Sort{Field: storage.SortPosition, Desc: true},
+		Limit:     1,
+	})
+	if err != nil {
+		return err
+	}
+	t.Position = 1
+	if len(last) > 0 {
+		t.Position = last[0].Position + 1
+	}
+	return nil
+}
+
+// minGap is the smallest spacing between neighbours Move will split before
+// renumbering the whole project.
+const minGap = 1e-6
+
+// Move puts a task into a column of its project's board at the position
+// described by in. The status and position change in a single update; the
+// new position is the midpoint of the neighbours, and the project is
+// renumbered when they are too close to split.
+func (s *Tasks) Move(ctx context.Context, userID, id string, in model.MoveInput) (t model.Task, err error) {
+	s.moveMu.Lock()
+	defer s.moveMu.Unlock()
+
+	err = s.undoable(ctx, userID, events.TaskUpdated, func(ctx context.Context, tx *Tasks) error {
+		t, err = tx.move(ctx, userID, id, in)
+		return err
+	})
+	return t, err
+}
+
+func (s *Tasks) move(ctx context.Context, userID, id string, in model.MoveInput) (model.Task, error) {
+	t, err := s.authorize(ctx, userID, id, model.RoleEditor)
+	if err != nil {
+		return model.Task{}, err
+	}
+	var v model.ValidationError
+	if t.ProjectID == nil {
+		v.Add("project_id", "the task must belong to a project to be moved")
+		return model.Task{}, v.Err()
+	}
+	w, err := s.workflow(ctx, userID, t.ProjectID)
+	if err != nil {
+		return model.Task{}, err
+	}
+	status := in.Status
+	if status == "" {
+		status = t.Status
+	}
+	if !w.Has(status) {
+		v.Add("status", "is not a column of this project")
+		return model.Task{}, v.Err()
+	}
+
+	column, err := s.Store.ListTasks(ctx, storage.TaskFilter{
+		ProjectID: *t.ProjectID,
+		Status:    status,
+		Sort:      storage.Sort{Field: storage.SortPosition},
+	})
+	if err != nil {
+		return model.Task{}, err
+	}
+	column = slices.DeleteFunc(column, func(c model.Task) bool { return c.ID == t.ID })
+
+	idx, err := insertAt(column, in.AfterID, in.BeforeID, "must be another task in the target column")
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	pos, err := s.slot(ctx, t, column, idx)
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	old := t
+	t.Status = status
+	t.Position = pos
+	w.Conform(&t)
+	if err := s.checkCompletion(ctx, old, t); err != nil {
+		return model.Task{}, err
+	}
+	t.UpdatedAt = time.Now().UTC()
+	next := recur(&old, &t, t.UpdatedAt, zone(ctx, s.Users, t.OwnerID))
+	if err := s.Store.UpdateTask(ctx, &t); err != nil {
+		return model.Task{}, err
+	}
+	noteSaved(ctx, old.Version, t)
+	s.publish(ctx, events.TaskUpdated, s.audience(ctx, t), t)
+	s.recordUpdate(ctx, userID, old, t)
+	if next != nil {
+		if _, err := s.Create(ctx, userID, *next); err != nil {
+			return model.Task{}, err
+		}
+	}
+	return t, nil
+}
+
+// maxPlaceAttempts bounds how many times Place recomputes a position when
+// the task changes under it.
+const maxPlaceAttempts = 3
+
+// Place moves a task to another place in its project's order, keeping its
+// status: directly after in.AfterID or before in.BeforeID, or to the end
+// with neither. The position is computed on the server from the order as
+// it stands, so two clients dropping tasks into the same gap both land
+// there, one after the other. If the task itself was changed by someone
+// else meanwhile, the position is computed again from the fresh task.
+func (s *Tasks) Place(ctx context.Context, userID, id string, in model.ReorderInput) (t model.Task, err error) {
+	s.moveMu.Lock()
+	defer s.moveMu.Unlock()
+
+	err = s.undoable(ctx, userID, events.TaskUpdated, func(ctx context.Context, tx *Tasks) error {
+		for attempt := 1; ; attempt++ {
+			t, err = tx.place(ctx, userID, id, in)
+			if errors.Is(err, storage.ErrStale) && attempt < maxPlaceAttempts {
+				continue
+			}
+			return err
+		}
+	})
+	return t, err
+}
+
+func (s *Tasks) place(ctx context.Context, userID, id string, in model.ReorderInput) (model.Task, error) {
+	t, err := s.authorize(ctx, userID, id, model.RoleEditor)
+	if err != nil {
+		return model.Task{}, err
+	}
+	var v model.ValidationError
+	if t.ProjectID == nil {
+		v.Add("project_id", "the task must belong to a project to be reordered")
+		return model.Task{}, v.Err()
+	}
+	all, err := s.Store.ListTasks(ctx, storage.TaskFilter{
+		ProjectID: *t.ProjectID,
+		Sort:      storage.Sort{Field: storage.SortPosition},
+	})
+	if err != nil {
+		return model.Task{}, err
+	}
+	all = slices.DeleteFunc(all, func(c model.Task) bool { return c.ID == t.ID })
+
+	idx, err := insertAt(all, in.AfterID, in.BeforeID, "must be another task in the project")
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	pos, err := s.slot(ctx, t, all, idx)
+	if err != nil {
+		return model.Task{}, err
+	}
+	old := t
+	t.Position = pos
+	t.UpdatedAt = time.Now().UTC()
+	if err := s.Store.UpdateTask(ctx, &t); err != nil {
+		return model.Task{}, err
+	}
+	noteSaved(ctx, old.Version, t)
+	s.publish(ctx, events.TaskUpdated, s.audience(ctx, t), t)
+	s.recordUpdate(ctx, userID, old, t)
+	return t, nil
+}
+
+// slot returns the position for t inserted at index idx of column, which
+// does not hold t, renumbering the project when the neighbours are too
+// close to split.
+func (s *Tasks) slot(ctx context.Context, t model.Task, column []model.Task, idx int) (float64, error) {
+	if pos, ok := between(column, idx, t.Position); ok {
+		return pos, nil
+	}
+	return s.renumber(ctx, t, column, idx)
+}
+
+// between picks a position for a task inserted at index idx of column. It
+// reports false when the neighbours are too close together.
+func between(column []model.Task, idx int, current float64) (float64, bool) {
+	switch {
+	case len(column) == 0:
+		return current, true
+	case idx == 0:
+		return column[0].Position - 1, true
+	case idx == len(column):
+		return column[idx-1].Position + 1, true
+	}
+	prev, next := column[idx-1].Position, column[idx].Position
+	if next-prev < minGap {
+		return 0, false
+	}
+	return prev + (next-prev)/2, true
+}
+
+// renumber rewrites the positions of every task in t's project as 1, 2, 3...
+// with t slotted in next to its new neighbour in column, and returns t's new
+// position.
+func (s *Tasks) renumber(ctx context.Context, t model.Task, column []model.Task, idx int) (float64, error) {
+	all, err := s.Store.ListTasks(ctx, storage.TaskFilter{
+		ProjectID: *t.ProjectID,
+		Sort:      storage.Sort{Field: storage.SortPosition},
+	})
+	if err != nil {
+		return 0, err
+	}
+	all = slices.DeleteFunc(all, func(c model.Task) bool { return c.ID == t.ID })
+
+	// Both neighbours exist, or between would not have failed.
+	at := indexOf(all, column[idx-1].ID) + 1
+	ids := make([]string, 0, len(all)+1)
+	for _, c := range all[:at] {
+		ids = append(ids, c.ID)
+	}
+	ids = append(ids, t.ID)
+	for _, c := range all[at:] {
+		ids = append(ids, c.ID)
+	}
+
+	if err := s.Projects.ReorderTasks(ctx, *t.ProjectID, ids); err != nil {
+		return 0, err
+	}
+	s.publish(ctx, events.TasksReordered, s.audience(ctx, t), events.Reordered{ProjectID: *t.ProjectID, TaskIDs: ids})
+	return float64(at + 1), nil
+}
+
+// insertAt returns the index of tasks at which to insert a task so that it
+// comes directly after the task afterID and before the task beforeID, or
+// at the end if neither is set. unknown explains an ID not in tasks. When
+// both are set they must be neighbours in tasks, or ErrNotAdjacent is
+// returned, so a client working from a stale order is told rather than
+// having one of them ignored.
+func insertAt(tasks []model.Task, afterID, beforeID, unknown string) (int, error) {
+	after, before := indexOf(tasks, afterID), indexOf(tasks, beforeID)
+	switch {
+	case afterID != "" && beforeID != "":
+		if after < 0 || before != after+1 {
+			return 0, ErrNotAdjacent
+		}
+		return before, nil
+	case afterID != "":
+		if after < 0 {
+			var v model.ValidationError
+			v.Add("after_id", unknown)
+			return 0, v.Err()
+		}
+		return after + 1, nil
+	case beforeID != "":
+		if before < 0 {
+			var v model.ValidationError
+			v.Add("before_id", unknown)
+			return 0, v.Err()
+		}
+		return before, nil
+	}
+	return len(tasks), nil
+}
+
+func indexOf(tasks []model.Task, id string) int {
+	return slices.IndexFunc(tasks, func(t model.Task) bool { return t.ID == id })
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+func TestInsertAt(t *testing.T) {
+	tasks := []model.Task{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	tests := []struct {
+		name          string
+		after, before string
+		want          int
+		err           error
+		invalid       bool
+	}{
+		{"end", "", "", 3, nil, false},
+		{"after", "a", "", 1, nil, false},
+		{"after the last", "c", "", 3, nil, false},
+		{"before", "", "a", 0, nil, false},
+		{"between neighbours", "b", "c", 2, nil, false},
+		{"between strangers", "a", "c", 0, ErrNotAdjacent, false},
+		{"between in the wrong order", "c", "b", 0, ErrNotAdjacent, false},
+		{"after an unknown task", "x", "", 0, nil, true},
+		{"before an unknown task", "", "x", 0, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := insertAt(tasks, tt.after, tt.before, "unknown")
+			var verr *model.ValidationError
+			switch {
+			case tt.invalid:
+				if !errors.As(err, &verr) {
+					t.Errorf("err = %v, want a validation error", err)
+				}
+			case !errors.Is(err, tt.err) || err == nil && got != tt.want:
+				t.Errorf("insertAt = %d, %v, want %d, %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestBetween(t *testing.T) {
+	column := []model.Task{{Position: 1}, {Position: 2}, {Position: 2 + minGap/2}}
+	tests := []struct {
+		name string
+		idx  int
+		want float64
+		ok   bool
+	}{
+		{"first", 0, 0, true},
+		{"last", 3, 3 + minGap/2, true},
+		{"midpoint", 1, 1.5, true},
+		{"too close", 2, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, ok := between(column, tt.idx, 7); got != tt.want || ok != tt.ok {
+				t.Errorf("between = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+	if got, ok := between(nil, 0, 7); got != 7 || !ok {
+		t.Errorf("empty column: between = %v, %v, want the current position", got, ok)
+	}
+}
+
+// order returns the titles of the tasks of project p by position, and
+// fails t unless no two share one.
+func order(t *testing.T, s *Tasks, p string) []string {
+	t.Helper()
+	tasks, err := s.Store.ListTasks(t.Context(), storage.TaskFilter{ProjectID: p, Sort: storage.Sort{Field: storage.SortPosition}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for i, task := range tasks {
+		if i > 0 && task.Position <= tasks[i-1].Position {
+			t.Fatalf("%s and %s share position %v", tasks[i-1].Title, task.Title, task.Position)
+		}
+		titles = append(titles, task.Title)
+	}
+	return titles
+}
+
+func TestPlaceRebalances(t *testing.T) {
+	s, ctx := newTestTasks(t)
+	store := s.Projects.(*storage.MemoryStore)
+	p := model.Project{OrgID: "o1", OwnerID: "u1", Name: "Board", Statuses: model.DefaultWorkflow}
+	if err := store.CreateProject(ctx, &p); err != nil {
+		t.Fatal(err)
+	}
+	create := func(title string) model.Task {
+		task, err := s.Create(ctx, "u1", model.TaskInput{Title: title, ProjectID: &p.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return task
+	}
+	first, last := create("first"), create("last")
+
+	// Each task goes straight after first, halving the gap every time
+	// until it is too small to split and the project is renumbered.
+	want := []string{"first"}
+	renumbered := false
+	for i := range 30 {
+		title := string(rune('A' + i))
+		task := create(title)
+		placed, err := s.Place(ctx, "u1", task.ID, model.ReorderInput{AfterID: first.ID})
+		if err != nil {
+			t.Fatalf("placing %s: %v", title, err)
+		}
+		renumbered = renumbered || placed.Position == 2
+		want = slices.Insert(want, 1, title)
+		if got := order(t, s, p.ID); !slices.Equal(got, append(slices.Clone(want), "last")) {
+			t.Fatalf("after placing %s the order is %v", title, got)
+		}
+	}
+	if !renumbered {
+		t.Error("the project was never renumbered")
+	}
+
+	// Placing between two tasks that are no longer neighbours is refused.
+	if _, err := s.Place(ctx, "u1", last.ID, model.ReorderInput{AfterID: first.ID, BeforeID: last.ID}); !errors.Is(err, ErrNotAdjacent) {
+		t.Errorf("err = %v, want %v", err, ErrNotAdjacent)
+	}
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"starttech-server/i18n"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Profile returns the account of userID.
+func (s *Accounts) Profile(ctx context.Context, userID string) (model.User, error) {
+	return s.Users.GetUser(ctx, userID)
+}
+
+// UpdateProfile applies p to the account of userID.
+func (s *Accounts) UpdateProfile(ctx context.Context, userID string, p model.ProfilePatch) (model.User, error) {
+	u, err := s.Users.GetUser(ctx, userID)
+	if err != nil {
+		return model.User{}, err
+	}
+	if p.Timezone != nil {
+		name := strings.TrimSpace(*p.Timezone)
+		if _, ok := model.LoadTimezone(name); !ok {
+			var v model.ValidationError
+			v.Add("timezone", "must be an IANA timezone name, such as Europe/Paris")
+			return model.User{}, v.Err()
+		}
+		u.Timezone = name
+	}
+	if p.Language != nil {
+		lang, ok := i18n.Match(*p.Language)
+		if !ok && strings.TrimSpace(*p.Language) != "" {
+			var v model.ValidationError
+			v.Add("language", "must be one of "+strings.Join(i18n.Supported(), ", "))
+			return model.User{}, v.Err()
+		}
+		u.Language = lang
+	}
+	if err := s.Users.UpdateUser(ctx, u); err != nil {
+		return model.User{}, err
+	}
+	return u, nil
+}
+
+// zone returns the timezone of the user with the given id. It falls back
+// to UTC when users is nil or the user cannot be read, so that a missing
+// preference never fails the request it shapes.
+func zone(ctx context.Context, users storage.UserStore, userID string) *time.Location {
+	if users == nil {
+		return time.UTC
+	}
+	u, err := users.GetUser(ctx, userID)
+	if err != nil {
+		slog.WarnContext(ctx, "reading timezone, using UTC", "user_id", userID, "err", err)
+		return time.UTC
+	}
+	return u.Location()
+}
+
+// language returns the language to write to u in: their own, or else the
+// one the request being served asks for.
+func language(ctx context.Context, u model.User) string {
+	if u.Language != "" {
+		return u.Language
+	}
+	return i18n.Language(ctx)
+}
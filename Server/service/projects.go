@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/storage"
+	"starttech-server/tracing"
+)
+
+// Projects manages the projects that group tasks and the members who share
+// them. Every member can read a project; only owners can change it, manage
+// its members or delete it. Projects the caller is not a member of are
+// reported as storage.ErrNotFound, as are those of other organizations.
+type Projects struct {
+	Store storage.ProjectStore
+	// Tasks is consulted before a column is removed from a workflow.
+	Tasks storage.TaskStore
+	// History gives the changes of status that Stats charts.
+	History storage.RevisionStore
+	// Users resolves the people added as members.
+	Users storage.UserStore
+	// Orgs confirms that they belong to the project's organization.
+	Orgs storage.OrgStore
+	// CustomFields holds the fields owners define for the project's tasks.
+	CustomFields storage.FieldStore
+	// Automations holds the rules owners define for the project's tasks.
+	Automations storage.RuleStore
+	// Events may be nil.
+	Events events.Publisher
+	// Log records who changed what. It may be nil.
+	Log storage.ActivityStore
+}
+
+func (s *Projects) publish(ctx context.Context, typ events.Type, to []string, data any) {
+	if s.Events == nil {
+		return
+	}
+	s.Events.Publish(events.Event{
+		Type:        typ,
+		Time:        time.Now().UTC(),
+		Data:        data,
+		OrgID:       orgOf(ctx),
+		Recipients:  to,
+		TraceParent: tracing.FromContext(ctx).TraceParent(),
+	})
+}
+
+// within returns a copy of s that works through tx and holds its events in
+// pending.
+func (s *Projects) within(tx storage.Store, pending *events.Buffer) *Projects {
+	inner := &Projects{Store: tx, Tasks: tx, History: tx, Users: tx, Orgs: tx, CustomFields: tx, Events: pending}
+	if s.Log != nil {
+		inner.Log = tx
+	}
+	return inner
+}
+
+// members returns the IDs of everyone in project p.
+func (s *Projects) members(ctx context.Context, p model.Project) []string {
+	return audience(ctx, s.Store, p.OwnerID, &p.ID)
+}
+
+// List returns the projects of the organization userID is a member of,
+// ordered by name: the archived ones if archived is set, and the others
+// otherwise.
+func (s *Projects) List(ctx context.Context, userID string, archived bool) ([]model.Project, error) {
+	projects, err := s.Store.ListProjects(ctx, orgOf(ctx), userID)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(projects, func(p model.Project) bool {
+		return (p.ArchivedAt != nil) != archived
+	}), nil
+}
+
+// Get returns the project with the given id if userID is a member.
+func (s *Projects) Get(ctx context.Context, userID, id string) (model.Project, error) {
+	return authorizeProject(ctx, s.Store, userID, id, model.RoleViewer)
+}
+
+// Create validates in and stores it as a new project with userID as its
+// owner.
+func (s *Projects) Create(ctx context.Context, userID string, in model.ProjectInput) (model.Project, error) {
+	now := time.Now().UTC()
+	p := model.Project{OrgID: orgOf(ctx), OwnerID: userID, CreatedAt: now, UpdatedAt: now}
+	in.Apply(&p)
+	if err := p.Validate(); err != nil {
+		return model.Project{}, err
+	}
+	if err := s.Store.CreateProject(ctx, &p); err != nil {
+		return model.Project{}, err
+	}
+	s.publish(ctx, events.ProjectCreated, []string{p.OwnerID}, p)
+	s.record(ctx, userID, events.ProjectCreated, p, "", nil)
+	return p, nil
+}
+
+// Update applies mutate to the project with the given id if userID is one
+// of its owners.
+func (s *Projects) Update(ctx context.Context, userID, id string, mutate func(*model.Project)) (model.Project, error) {
+	p, err := authorizeProject(ctx, s.Store, userID, id, model.RoleOwner)
+	if err != nil {
+		return model.Project{}, err
+	}
+	before := p
+	old := p.Statuses
+	mutate(&p)
+	if err := p.Validate(); err != nil {
+		return model.Project{}, err
+	}
+	if err := s.checkRemovedColumns(ctx, p, old); err != nil {
+		return model.Project{}, err
+	}
+	p.UpdatedAt = time.Now().UTC()
+	if err := s.Store.UpdateProject(ctx, &p); err != nil {
+		return model.Project{}, err
+	}
+	s.publish(ctx, events.ProjectUpdated, s.members(ctx, p), p)
+	if changes := diff(before, p); len(changes) > 0 {
+		s.record(ctx, userID, events.ProjectUpdated, p, "", changes)
+	}
+	return p, nil
+}
+
+// Delete removes the project with the given id if userID is one of its
+// owners. Its tasks are kept outside any project, private to whoever created
+// them.
+func (s *Projects) Delete(ctx context.Context, userID, id string) error {
+	p, err := authorizeProject(ctx, s.Store, userID, id, model.RoleOwner)
+	if err != nil {
+		return err
+	}
+	to := s.members(ctx, p)
+	if err := s.Store.DeleteProject(ctx, id); err != nil {
+		return err
+	}
+	s.publish(ctx, events.ProjectDeleted, to, events.Deleted{ID: id})
+	s.record(ctx, userID, events.ProjectDeleted, p, "", nil)
+	return nil
+}
+
+// checkRemovedColumns refuses to drop a column from p's workflow while tasks
+// are still in it.
+func (s *Projects) checkRemovedColumns(ctx context.Context, p model.Project, old model.Workflow) error {
+	var v model.ValidationError
+	for _, c := range old {
+		if p.Statuses.Has(c.Key) {
+			continue
+		}
+		n, err := s.Tasks.CountTasks(ctx, storage.TaskFilter{ProjectID: p.ID, Status: c.Key})
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			v.Add("statuses", fmt.Sprintf("column %s still holds %d tasks", c.Key, n))
+		}
+	}
+	return v.Err()
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"starttech-server/model"
+	"starttech-server/ratelimit"
+	"starttech-server/storage"
+)
+
+// Names of the quotas of model.Limits, as QuotaError reports them.
+const (
+	QuotaTasksPerProject = "tasks_per_project"
+	QuotaAttachmentBytes = "attachment_bytes"
+	QuotaRequestsPerDay  = "requests_per_day"
+)
+
+// QuotaError reports that an operation would take an organization past one
+// of its model.Limits.
+type QuotaError struct {
+	// Quota is one of the Quota names.
+	Quota string
+	Limit int64
+	// RetryAfter is how long until the quota frees up by itself, for
+	// QuotaRequestsPerDay.
+	RetryAfter time.Duration
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("service: the %s quota of %d is used up", e.Quota, e.Limit)
+}
+
+// Usage reports what organizations use against Limits and counts their API
+// requests, which only its admins and owners may look at.
+type Usage struct {
+	Store    storage.UsageStore
+	Orgs     storage.OrgStore
+	Projects storage.BackupStore
+	Tasks    storage.TaskStore
+	// Requests keeps the daily request counts. It may be nil when
+	// Limits.RequestsPerDay is off, in which case requests are not
+	// counted.
+	Requests ratelimit.Counter
+	Limits   model.Limits
+}
+
+// Get returns the usage of the request's organization if userID is one of
+// its admins.
+func (s *Usage) Get(ctx context.Context, userID string) (model.OrgUsage, error) {
+	orgID := orgOf(ctx)
+	m, err := s.Orgs.GetOrgMember(ctx, orgID, userID)
+	if err != nil {
+		return model.OrgUsage{}, err
+	}
+	if !m.Role.Allows(model.OrgAdmin) {
+		return model.OrgUsage{}, ErrForbidden
+	}
+	u := model.OrgUsage{Projects: []model.ProjectUsage{}, Limits: s.Limits}
+	if u.Usage, err = s.Store.Usage(ctx, orgID); err != nil {
+		return model.OrgUsage{}, err
+	}
+	if s.Requests != nil {
+		if u.RequestsToday, err = s.Requests.Count(ctx, requestsKey(orgID, time.Now())); err != nil {
+			return model.OrgUsage{}, err
+		}
+	}
+	projects, err := s.Projects.OrgProjects(ctx, orgID)
+	if err != nil {
+		return model.OrgUsage{}, err
+	}
+	for _, p := range projects {
+		n, err := projectTasks(ctx, s.Tasks, p.ID)
+		if err != nil {
+			return model.OrgUsage{}, err
+		}
+		u.Projects = append(u.Projects, model.ProjectUsage{ProjectID: p.ID, Name: p.Name, Tasks: n})
+	}
+	slices.SortStableFunc(u.Projects, func(a, b model.ProjectUsage) int { return cmp.Compare(b.Tasks, a.Tasks) })
+	return u, nil
+}
+
+// CountRequest counts a request of the organization orgID against its
+// daily quota, and returns a QuotaError if it goes over. Requests are let
+// through when the count cannot be kept.
+func (s *Usage) CountRequest(ctx context.Context, orgID string) error {
+	if s.Requests == nil || s.Limits.RequestsPerDay <= 0 || orgID == "" {
+		return nil
+	}
+	now := time.Now()
+	n, err := s.Requests.Incr(ctx, requestsKey(orgID, now), 25*time.Hour)
+	if err != nil {
+		slog.WarnContext(ctx, "request counter unavailable", "err", err)
+		return nil
+	}
+	if n <= s.Limits.RequestsPerDay {
+		return nil
+	}
+	midnight := now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	return &QuotaError{Quota: QuotaRequestsPerDay, Limit: s.Limits.RequestsPerDay, RetryAfter: midnight.Sub(now)}
+}
+
+// requestsKey names the count of the requests of orgID on the UTC day of
+// t.
+func requestsKey(orgID string, t time.Time) string {
+	return "requests:" + orgID + ":" + t.UTC().Format(time.DateOnly)
+}
+
+// projectTasks counts the tasks of a project, trashed ones included.
+func projectTasks(ctx context.Context, tasks storage.TaskStore, projectID string) (int, error) {
+	live, err := tasks.CountTasks(ctx, storage.TaskFilter{ProjectID: projectID})
+	if err != nil {
+		return 0, err
+	}
+	trashed, err := tasks.CountTasks(ctx, storage.TaskFilter{ProjectID: projectID, Trashed: true})
+	if err != nil {
+		return 0, err
+	}
+	return live + trashed, nil
+}
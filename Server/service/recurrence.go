@@ -0,0 +1,93 @@
+package service
+
+import (
+	"time"
+
+	"starttech-server/model"
+	"starttech-server/recurrence"
+)
+
+// normalizeRecurrence rewrites a validated rule in canonical form, so that
+// "weekly" is stored as "FREQ=WEEKLY".
+func normalizeRecurrence(t *model.Task) {
+	if t.Recurrence == "" {
+		return
+	}
+	if r, err := recurrence.Parse(t.Recurrence); err == nil {
+		t.Recurrence = r.String()
+	}
+}
+
+// recur prepares the next occurrence when an edit completes a recurring
+// task. The rule moves from t to the returned input, so reopening and
+// completing t again does not create a second copy. It returns nil when the
+// series has ended.
+//
+// The occurrence is anchored on the due date, falling back to remind_at
+// and then to the time of completion. Occurrences that were missed while
+// the task was overdue are skipped, and remind_at keeps its distance from
+// the due date. Days are those of loc, the owner's timezone, so that a
+// task due at 9:00 stays due at 9:00 when the clocks change.
+func recur(old, t *model.Task, now time.Time, loc *time.Location) *model.TaskInput {
+	if old.Completed || !t.Completed || t.Recurrence == "" {
+		return nil
+	}
+	rule, err := recurrence.Parse(t.Recurrence)
+	t.Recurrence = ""
+	if err != nil {
+		return nil
+	}
+	rest, ok := rule.Advance()
+	if !ok {
+		return nil
+	}
+
+	anchor := now
+	switch {
+	case t.DueDate != nil:
+		anchor = *t.DueDate
+	case t.RemindAt != nil:
+		anchor = *t.RemindAt
+	}
+	anchor = anchor.In(loc)
+	after := anchor
+	if now.After(after) {
+		after = now
+	}
+	next, ok := rule.Next(anchor, after)
+	if !ok {
+		return nil
+	}
+	days := calendarDays(anchor, next)
+
+	in := &model.TaskInput{
+		Title:       t.Title,
+		Description: t.Description,
+		Recurrence:  rest.String(),
+		Priority:    t.Priority,
+		ProjectID:   t.ProjectID,
+		ParentID:    t.ParentID,
+		AssigneeID:  t.AssigneeID,
+		TagIDs:      t.TagIDs,
+		Fields:      t.Fields,
+	}
+	if t.DueDate != nil {
+		d := t.DueDate.In(loc).AddDate(0, 0, days).UTC()
+		in.DueDate = &d
+	}
+	if t.RemindAt != nil {
+		r := t.RemindAt.In(loc).AddDate(0, 0, days).UTC()
+		in.RemindAt = &r
+	}
+	return in
+}
+
+// calendarDays counts the days from the date of a to the date of b, both
+// read in a's location.
+func calendarDays(a, b time.Time) int {
+	date := func(t time.Time) time.Time {
+		y, m, d := t.In(a.Location()).Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	return int(date(b).Sub(date(a)) / (24 * time.Hour))
+}
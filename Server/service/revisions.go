@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Revisions returns one page of the revisions of the task with the given
+// id, newest first.
+func (s *Tasks) Revisions(ctx context.Context, userID, id string, limit int, cursor string) (model.RevisionPage, error) {
+	t, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return model.RevisionPage{}, err
+	}
+	switch {
+	case limit <= 0:
+		limit = DefaultPageSize
+	case limit > MaxPageSize:
+		limit = MaxPageSize
+	}
+	f := storage.RevisionFilter{TaskID: t.ID, Limit: limit + 1}
+	if cursor != "" {
+		if f.Offset, err = decodeCursor(cursor); err != nil {
+			return model.RevisionPage{}, err
+		}
+	}
+	revisions, err := s.History.ListRevisions(ctx, f)
+	if err != nil {
+		return model.RevisionPage{}, err
+	}
+	page := model.RevisionPage{Items: revisions}
+	if len(revisions) > limit {
+		page.Items = revisions[:limit]
+		page.NextCursor = encodeCursor(f.Offset + limit)
+	}
+	return page, nil
+}
+
+// Revert puts the fields of the task with the given id back as they were at
+// version, through Update, so the result is a new version that can itself
+// be reverted and ifMatch works as it does there. The task stays where it is
+// on its board unless it changes project, and tags deleted since are left
+// out, as are custom field values their field no longer accepts.
+func (s *Tasks) Revert(ctx context.Context, userID, id string, version int64, ifMatch []int64) (model.Task, error) {
+	if _, err := s.authorize(ctx, userID, id, model.RoleEditor); err != nil {
+		return model.Task{}, err
+	}
+	r, err := s.History.GetRevision(ctx, id, version)
+	if err != nil {
+		return model.Task{}, err
+	}
+	mutate, err := s.reverting(ctx, r.Task)
+	if err != nil {
+		return model.Task{}, err
+	}
+	return s.Update(ctx, userID, id, ifMatch, mutate)
+}
+
+// reverting returns a mutation for Update that puts back the fields of a
+// task as they were in old, leaving out tags deleted since and custom field
+// values their field no longer accepts.
+func (s *Tasks) reverting(ctx context.Context, old model.Task) (func(*model.Task), error) {
+	var tagIDs []string
+	for _, tagID := range old.TagIDs {
+		_, err := s.Tags.GetTag(ctx, tagID)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		tagIDs = append(tagIDs, tagID)
+	}
+	fields := map[string]any{}
+	for fieldID, v := range old.Fields {
+		f, err := s.CustomFields.GetField(ctx, fieldID)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Value(v); err == nil {
+			fields[fieldID] = v
+		}
+	}
+	return func(t *model.Task) {
+		t.Title = old.Title
+		t.Description = old.Description
+		t.Status = old.Status
+		t.Completed = old.Completed
+		t.Priority = old.Priority
+		t.DueDate = old.DueDate
+		t.RemindAt = old.RemindAt
+		t.Recurrence = old.Recurrence
+		t.ProjectID = old.ProjectID
+		t.ParentID = old.ParentID
+		t.AssigneeID = old.AssigneeID
+		t.TagIDs = tagIDs
+		t.Fields = fields
+	}, nil
+}
@@ -0,0 +1,324 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+
+	"starttech-server/auth"
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Rules lists the automation rules of a project userID belongs to.
+func (s *Projects) Rules(ctx context.Context, userID, projectID string) ([]model.Rule, error) {
+	if _, err := authorizeProject(ctx, s.Store, userID, projectID, model.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.Automations.ListRules(ctx, projectID)
+}
+
+// Rule returns an automation rule of a project userID belongs to.
+func (s *Projects) Rule(ctx context.Context, userID, projectID, ruleID string) (model.Rule, error) {
+	_, r, err := s.rule(ctx, userID, projectID, ruleID, model.RoleViewer)
+	return r, err
+}
+
+// CreateRule adds an automation rule to a project. Only owners may define
+// rules, and the statuses they name must be in the project's workflow.
+func (s *Projects) CreateRule(ctx context.Context, userID, projectID string, in model.RuleInput) (model.Rule, error) {
+	p, err := authorizeProject(ctx, s.Store, userID, projectID, model.RoleOwner)
+	if err != nil {
+		return model.Rule{}, err
+	}
+	now := time.Now().UTC()
+	r := model.Rule{OrgID: p.OrgID, ProjectID: projectID, CreatedBy: userID, CreatedAt: now, UpdatedAt: now}
+	in.Apply(&r)
+	if err := r.Validate(p.Statuses); err != nil {
+		return model.Rule{}, err
+	}
+	existing, err := s.Automations.ListRules(ctx, projectID)
+	if err != nil {
+		return model.Rule{}, err
+	}
+	if len(existing) >= model.MaxRules {
+		var v model.ValidationError
+		v.Add("name", fmt.Sprintf("a project may have at most %d rules", model.MaxRules))
+		return model.Rule{}, v.Err()
+	}
+	if err := s.Automations.CreateRule(ctx, &r); err != nil {
+		return model.Rule{}, err
+	}
+	return r, nil
+}
+
+// UpdateRule applies p to an automation rule of a project userID owns.
+func (s *Projects) UpdateRule(ctx context.Context, userID, projectID, ruleID string, p model.RulePatch) (model.Rule, error) {
+	project, r, err := s.rule(ctx, userID, projectID, ruleID, model.RoleOwner)
+	if err != nil {
+		return model.Rule{}, err
+	}
+	p.Apply(&r)
+	if err := r.Validate(project.Statuses); err != nil {
+		return model.Rule{}, err
+	}
+	r.UpdatedAt = time.Now().UTC()
+	if err := s.Automations.UpdateRule(ctx, &r); err != nil {
+		return model.Rule{}, err
+	}
+	return r, nil
+}
+
+// DeleteRule removes an automation rule of a project userID owns. The
+// changes it made stay.
+func (s *Projects) DeleteRule(ctx context.Context, userID, projectID, ruleID string) error {
+	if _, _, err := s.rule(ctx, userID, projectID, ruleID, model.RoleOwner); err != nil {
+		return err
+	}
+	return s.Automations.DeleteRule(ctx, ruleID)
+}
+
+// rule returns the project and its rule ruleID if userID holds at least
+// role need in the project.
+func (s *Projects) rule(ctx context.Context, userID, projectID, ruleID string, need model.Role) (model.Project, model.Rule, error) {
+	p, err := authorizeProject(ctx, s.Store, userID, projectID, need)
+	if err != nil {
+		return model.Project{}, model.Rule{}, err
+	}
+	r, err := s.Automations.GetRule(ctx, ruleID)
+	if err != nil {
+		return model.Project{}, model.Rule{}, err
+	}
+	if r.ProjectID != projectID {
+		return model.Project{}, model.Rule{}, storage.ErrNotFound
+	}
+	return p, r, nil
+}
+
+// ruleBatch bounds the tasks EvaluateRules loads at once.
+const ruleBatch = 100
+
+// EvaluateRules runs every enabled automation rule against the tasks of
+// its project as of now, and returns how many times rules acted on a task.
+// Rules of archived projects rest. A task that changes while a rule acts on
+// it, or that a rule cannot complete because it is blocked, is left for
+// the next time; a rule that fails is reported once the others have run.
+func (s *Tasks) EvaluateRules(ctx context.Context, now time.Time) (int, error) {
+	rules, err := s.Automations.ListEnabledRules(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	var errs []error
+	for _, r := range rules {
+		fired, err := s.evaluateRule(auth.WithOrgID(ctx, r.OrgID), r, now)
+		n += fired
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", r.ID, err))
+		}
+	}
+	return n, errors.Join(errs...)
+}
+
+// evaluateRule acts on the tasks r's trigger has come to hold for, and
+// forgets those it has stopped holding for, so that r acts on them again
+// if it comes to hold once more.
+func (s *Tasks) evaluateRule(ctx context.Context, r model.Rule, now time.Time) (int, error) {
+	p, err := s.Projects.GetProject(ctx, r.ProjectID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if p.ArchivedAt != nil {
+		return 0, nil
+	}
+	holding, err := s.ruleTasks(ctx, r, now)
+	if err != nil {
+		return 0, err
+	}
+	fired, err := s.Automations.RuleFirings(ctx, r.ID)
+	if err != nil {
+		return 0, err
+	}
+	done := make(map[string]bool, len(fired))
+	for _, id := range fired {
+		done[id] = true
+	}
+	var stopped []string
+	for _, id := range fired {
+		if _, ok := holding[id]; !ok {
+			stopped = append(stopped, id)
+		}
+	}
+	if err := s.Automations.RemoveRuleFirings(ctx, r.ID, stopped); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, id := range slices.Sorted(maps.Keys(holding)) {
+		if done[id] {
+			continue
+		}
+		t := holding[id]
+		// A task fires an in_status rule once it has been left
+		// unchanged in the status for the delay.
+		if r.Trigger.Type == model.TriggerInStatus && t.UpdatedAt.After(now.Add(-r.Trigger.Delay())) {
+			continue
+		}
+		err := s.atomically(ctx, func(ctx context.Context, tx *Tasks) error {
+			return tx.fire(ctx, r, p.Statuses, t, now)
+		})
+		switch {
+		case errors.Is(err, storage.ErrStale) || errors.Is(err, storage.ErrNotFound) || errors.Is(err, ErrBlocked):
+			continue
+		case err != nil:
+			return n, fmt.Errorf("acting on task %s: %w", t.ID, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// ruleTasks returns the tasks of r's project its trigger holds for, by ID.
+func (s *Tasks) ruleTasks(ctx context.Context, r model.Rule, now time.Time) (map[string]model.Task, error) {
+	no := false
+	f := storage.TaskFilter{
+		OrgID: r.OrgID, ProjectID: r.ProjectID, Archived: &no,
+		Sort: storage.Sort{Field: storage.SortCreatedAt}, Limit: ruleBatch,
+	}
+	switch r.Trigger.Type {
+	case model.TriggerDueDatePassed:
+		due := now.Add(-r.Trigger.Delay())
+		f.DueBefore = &due
+	case model.TriggerInStatus:
+		f.Status = r.Trigger.Status
+	}
+	holding := map[string]model.Task{}
+	for {
+		tasks, err := s.Store.ListTasks(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tasks {
+			switch r.Trigger.Type {
+			case model.TriggerDueDatePassed:
+				if t.Completed || t.DueDate == nil {
+					continue
+				}
+			case model.TriggerBlocking:
+				p, err := s.blockedPriority(ctx, t)
+				if err != nil {
+					return nil, err
+				}
+				if t.Completed || p.Rank() <= t.Priority.Rank() {
+					continue
+				}
+			}
+			holding[t.ID] = t
+		}
+		if len(tasks) < ruleBatch {
+			return holding, nil
+		}
+		f.Offset += ruleBatch
+	}
+}
+
+// blockedPriority returns the highest priority among the open tasks t
+// blocks, or none if it blocks none.
+func (s *Tasks) blockedPriority(ctx context.Context, t model.Task) (model.Priority, error) {
+	highest := model.PriorityNone
+	if t.Completed {
+		return highest, nil
+	}
+	deps, err := s.Deps.Dependents(ctx, t.ID)
+	if err != nil {
+		return "", err
+	}
+	for _, d := range deps {
+		blocked, err := s.Store.GetTask(ctx, d.TaskID)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if blocked.DeletedAt == nil && !blocked.Completed && blocked.Priority.Rank() > highest.Rank() {
+			highest = blocked.Priority
+		}
+	}
+	return highest, nil
+}
+
+// fire takes the actions of r on the task seen as t, in workflow w, and
+// notes that r acted on it. The changes are published and logged with no
+// actor. Completing a recurring task creates its next occurrence on behalf
+// of the task's owner.
+func (s *Tasks) fire(ctx context.Context, r model.Rule, w model.Workflow, seen model.Task, now time.Time) error {
+	t, err := s.Store.GetTask(ctx, seen.ID)
+	if err != nil {
+		return err
+	}
+	if t.DeletedAt != nil || t.Version != seen.Version {
+		return storage.ErrStale
+	}
+	old := t
+	notify := false
+	for _, a := range r.Actions {
+		switch a.Type {
+		case model.ActionSetPriority:
+			t.Priority = a.Priority
+		case model.ActionInheritPriority:
+			p, err := s.blockedPriority(ctx, t)
+			if err != nil {
+				return err
+			}
+			if p.Rank() > t.Priority.Rank() {
+				t.Priority = p
+			}
+		case model.ActionSetStatus:
+			// The column may have left the workflow since the rule was
+			// saved.
+			if w.Has(a.Status) {
+				t.Status = a.Status
+			}
+		case model.ActionArchive:
+			if t.ArchivedAt == nil {
+				t.ArchivedAt = &now
+			}
+		case model.ActionNotifyAssignee:
+			notify = true
+		}
+	}
+	w.Conform(&t)
+	if len(diff(old, t)) > 0 {
+		if err := s.checkCompletion(ctx, old, t); err != nil {
+			return err
+		}
+		t.UpdatedAt = now
+		next := recur(&old, &t, now, zone(ctx, s.Users, t.OwnerID))
+		if err := s.Store.UpdateTask(ctx, &t); err != nil {
+			return err
+		}
+		s.publish(ctx, events.TaskUpdated, s.audience(ctx, t), t)
+		s.recordUpdate(ctx, "", old, t)
+		if next != nil {
+			if _, err := s.create(ctx, t.OwnerID, *next); err != nil {
+				slog.WarnContext(ctx, "creating the next occurrence of a task", "task_id", t.ID, "err", err)
+			}
+		}
+	}
+	if notify && t.AssigneeID != nil {
+		s.notify(ctx, model.Notification{
+			OrgID: t.OrgID, UserID: *t.AssigneeID, Kind: model.NotifyRule,
+			TaskID: t.ID, Title: t.Title, CreatedAt: now,
+		})
+	}
+	return s.Automations.AddRuleFiring(ctx, r.ID, t.ID, now)
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+func TestEvaluateRuleRearms(t *testing.T) {
+	s, ctx := newTestTasks(t)
+	store := s.Projects.(*storage.MemoryStore)
+	p := model.Project{OrgID: "o1", OwnerID: "u1", Name: "Board", Statuses: model.DefaultWorkflow}
+	if err := store.CreateProject(ctx, &p); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	yesterday, tomorrow := now.Add(-24*time.Hour), now.Add(24*time.Hour)
+	overdue, err := s.Create(ctx, "u1", model.TaskInput{Title: "overdue", ProjectID: &p.ID, DueDate: &yesterday})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(ctx, "u1", model.TaskInput{Title: "upcoming", ProjectID: &p.ID, DueDate: &tomorrow}); err != nil {
+		t.Fatal(err)
+	}
+	r := model.Rule{
+		ID: "r1", OrgID: "o1", ProjectID: p.ID, Name: "Escalate", Enabled: true,
+		Trigger: model.RuleTrigger{Type: model.TriggerDueDatePassed},
+		Actions: []model.RuleAction{{Type: model.ActionSetPriority, Priority: model.PriorityHigh}},
+	}
+	due := func(d time.Time) {
+		t.Helper()
+		if _, err := s.Update(ctx, "u1", overdue.ID, nil, func(task *model.Task) {
+			task.DueDate, task.Priority = &d, model.PriorityNone
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	steps := []struct {
+		name   string
+		before func()
+		fired  int
+	}{
+		{"the trigger comes to hold", func() {}, 1},
+		{"it still holds", func() {}, 0},
+		{"it holds again without having stopped", func() { due(yesterday.Add(-time.Hour)) }, 0},
+		{"it stops holding", func() { due(tomorrow) }, 0},
+		{"it comes to hold once more", func() { due(yesterday) }, 1},
+	}
+	for _, step := range steps {
+		step.before()
+		n, err := s.evaluateRule(ctx, r, now)
+		if err != nil || n != step.fired {
+			t.Fatalf("%s: fired %d times, %v, want %d", step.name, n, err, step.fired)
+		}
+	}
+	got, err := s.Store.GetTask(ctx, overdue.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Priority != model.PriorityHigh {
+		t.Errorf("priority = %s, want %s", got.Priority, model.PriorityHigh)
+	}
+}
+
+func TestEvaluateRuleInStatusDelay(t *testing.T) {
+	s, ctx := newTestTasks(t)
+	store := s.Projects.(*storage.MemoryStore)
+	p := model.Project{OrgID: "o1", OwnerID: "u1", Name: "Board", Statuses: model.DefaultWorkflow}
+	if err := store.CreateProject(ctx, &p); err != nil {
+		t.Fatal(err)
+	}
+	task, err := s.Create(ctx, "u1", model.TaskInput{Title: "stuck", ProjectID: &p.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := model.Rule{
+		ID: "r1", OrgID: "o1", ProjectID: p.ID, Name: "Archive stale", Enabled: true,
+		Trigger: model.RuleTrigger{Type: model.TriggerInStatus, Status: task.Status, DelaySeconds: 3600},
+		Actions: []model.RuleAction{{Type: model.ActionArchive}},
+	}
+
+	tests := []struct {
+		name  string
+		after time.Duration
+		fired int
+	}{
+		{"before the delay", 59 * time.Minute, 0},
+		{"after the delay", 61 * time.Minute, 1},
+	}
+	for _, tt := range tests {
+		n, err := s.evaluateRule(ctx, r, task.UpdatedAt.Add(tt.after))
+		if err != nil || n != tt.fired {
+			t.Fatalf("%s: fired %d times, %v, want %d", tt.name, n, err, tt.fired)
+		}
+	}
+	got, err := s.Store.GetTask(ctx, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ArchivedAt == nil {
+		t.Error("the task was not archived")
+	}
+}
@@ -0,0 +1,699 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// ErrSCIMFilter is returned for a SCIM filter other than the
+// `attribute eq "value"` kind identity providers look resources up with.
+var ErrSCIMFilter = errors.New("service: unsupported SCIM filter")
+
+// SCIM provisions users into an organization, and members into its
+// projects, for identity providers such as Okta and Azure AD. It acts as
+// the owner of the SCIM key the provider was given, who must be an admin
+// or owner of the organization.
+//
+// Users are the ones the provider has provisioned, whose addresses must
+// be at a domain the organization has verified: creating one links the
+// account with its email address. If there is none, it is created and
+// added to the organization; the holder of an account that exists is
+// invited to join instead, since provisioning cannot speak for them.
+// Deactivating or deleting one takes it out again. Accounts are never
+// changed or removed, since they may belong to other organizations too.
+//
+// Groups are the projects the key's owner belongs to. Creating one
+// creates a project they own, and the members the provider sets join it as
+// editors. The key's owner is left out of the members, so that the
+// provider does not remove them.
+type SCIM struct {
+	Links    storage.SCIMStore
+	Users    storage.UserStore
+	Accounts *Accounts
+	Orgs     *Orgs
+	Projects *Projects
+}
+
+// ListUsers returns the count users provisioned into the organization from
+// start, which counts from 1, that match filter.
+func (s *SCIM) ListUsers(ctx context.Context, userID, filter string, start, count int) (model.SCIMUserList, error) {
+	f, err := parseSCIMFilter(filter, "userName", "externalId", "emails.value")
+	if err != nil {
+		return model.SCIMUserList{}, err
+	}
+	if err := s.authorize(ctx, userID); err != nil {
+		return model.SCIMUserList{}, err
+	}
+	links, err := s.Links.ListSCIMLinks(ctx, orgOf(ctx))
+	if err != nil {
+		return model.SCIMUserList{}, err
+	}
+	var users []model.SCIMUser
+	for _, l := range links {
+		u, err := s.user(ctx, l)
+		if err != nil {
+			return model.SCIMUserList{}, err
+		}
+		if f.matchUser(u) {
+			users = append(users, u)
+		}
+	}
+	return model.SCIMUserList(scimPage(users, start, count)), nil
+}
+
+// User returns the provisioned user with the given id.
+func (s *SCIM) User(ctx context.Context, userID, id string) (model.SCIMUser, error) {
+	if err := s.authorize(ctx, userID); err != nil {
+		return model.SCIMUser{}, err
+	}
+	l, err := s.Links.GetSCIMLink(ctx, orgOf(ctx), id)
+	if err != nil {
+		return model.SCIMUser{}, err
+	}
+	return s.user(ctx, l)
+}
+
+// CreateUser provisions in, whose address must be at a domain the
+// organization has verified, into the organization. It returns
+// storage.ErrConflict if its account was provisioned already.
+func (s *SCIM) CreateUser(ctx context.Context, userID string, in model.SCIMUser) (model.SCIMUser, error) {
+	if err := s.authorize(ctx, userID); err != nil {
+		return model.SCIMUser{}, err
+	}
+	in.UserName = strings.TrimSpace(in.UserName)
+	email := strings.TrimSpace(in.Email())
+	var v model.ValidationError
+	if in.UserName == "" {
+		v.Add("userName", "is required")
+	}
+	if !strings.Contains(email, "@") {
+		v.Add("emails", "an email address is required, in emails or as the userName")
+	}
+	if err := v.Err(); err != nil {
+		return model.SCIMUser{}, err
+	}
+	// Accounts are created verified, so the organization must control
+	// the mailboxes of the domain.
+	verified, err := s.Orgs.domainVerified(ctx, orgOf(ctx), model.EmailDomain(email))
+	if err != nil {
+		return model.SCIMUser{}, err
+	}
+	if !verified {
+		v.Add("emails", "must be at a domain the organization has verified")
+		return model.SCIMUser{}, v.Err()
+	}
+
+	created := false
+	u, err := s.Users.GetUserByEmail(ctx, email)
+	if errors.Is(err, storage.ErrNotFound) {
+		login, _, _ := strings.Cut(in.UserName, "@")
+		u, err = s.Accounts.CreateVerified(ctx, email, login)
+		created = true
+	}
+	if err != nil {
+		return model.SCIMUser{}, err
+	}
+	now := time.Now().UTC()
+	l := model.SCIMLink{
+		OrgID:          orgOf(ctx),
+		UserID:         u.ID,
+		UserName:       in.UserName,
+		ExternalID:     in.ExternalID,
+		Active:         in.Active == nil || *in.Active,
+		CreatedAccount: created,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if _, err := s.Links.GetSCIMLink(ctx, l.OrgID, u.ID); err == nil {
+		return model.SCIMUser{}, storage.ErrConflict
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return model.SCIMUser{}, err
+	}
+	if err := s.admit(ctx, userID, l, true); err != nil {
+		return model.SCIMUser{}, err
+	}
+	if err := s.Links.CreateSCIMLink(ctx, l); err != nil {
+		return model.SCIMUser{}, err
+	}
+	return s.user(ctx, l)
+}
+
+// ReplaceUser sets the userName, externalId and active of the provisioned
+// user with the given id to in's.
+func (s *SCIM) ReplaceUser(ctx context.Context, userID, id string, in model.SCIMUser) (model.SCIMUser, error) {
+	return s.updateUser(ctx, userID, id, func(l *model.SCIMLink) error {
+		if in.UserName = strings.TrimSpace(in.UserName); in.UserName == "" {
+			var v model.ValidationError
+			v.Add("userName", "is required")
+			return v.Err()
+		}
+		l.UserName, l.ExternalID = in.UserName, in.ExternalID
+		l.Active = in.Active == nil || *in.Active
+		return nil
+	})
+}
+
+// PatchUser applies the operations of p to the provisioned user with the
+// given id. Operations on attributes that are not kept are ignored.
+func (s *SCIM) PatchUser(ctx context.Context, userID, id string, p model.SCIMPatch) (model.SCIMUser, error) {
+	return s.updateUser(ctx, userID, id, func(l *model.SCIMLink) error {
+		for i, op := range p.Operations {
+			if err := patchUser(l, op); err != nil {
+				var v model.ValidationError
+				v.Add(fmt.Sprintf("Operations[%d]", i), err.Error())
+				return v.Err()
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteUser takes the provisioned user with the given id out of the
+// organization and forgets that they were provisioned.
+func (s *SCIM) DeleteUser(ctx context.Context, userID, id string) error {
+	if err := s.authorize(ctx, userID); err != nil {
+		return err
+	}
+	l, err := s.Links.GetSCIMLink(ctx, orgOf(ctx), id)
+	if err != nil {
+		return err
+	}
+	l.Active = false
+	if err := s.admit(ctx, userID, l, false); err != nil {
+		return err
+	}
+	return s.Links.DeleteSCIMLink(ctx, l.OrgID, l.UserID)
+}
+
+func (s *SCIM) updateUser(ctx context.Context, userID, id string, mutate func(*model.SCIMLink) error) (model.SCIMUser, error) {
+	if err := s.authorize(ctx, userID); err != nil {
+		return model.SCIMUser{}, err
+	}
+	l, err := s.Links.GetSCIMLink(ctx, orgOf(ctx), id)
+	if err != nil {
+		return model.SCIMUser{}, err
+	}
+	wasActive := l.Active
+	if err := mutate(&l); err != nil {
+		return model.SCIMUser{}, err
+	}
+	l.UpdatedAt = time.Now().UTC()
+	if err := s.admit(ctx, userID, l, !wasActive); err != nil {
+		return model.SCIMUser{}, err
+	}
+	if err := s.Links.UpdateSCIMLink(ctx, l); err != nil {
+		return model.SCIMUser{}, err
+	}
+	return s.user(ctx, l)
+}
+
+// admit makes the organization membership of l's user match l.Active:
+// an inactive user is removed, and an active one who is not a member is
+// added if provisioning created their account. Otherwise, if invite is
+// set, as when the user has just been provisioned or activated, they are
+// invited to join.
+func (s *SCIM) admit(ctx context.Context, userID string, l model.SCIMLink, invite bool) error {
+	_, err := s.Orgs.Store.GetOrgMember(ctx, l.OrgID, l.UserID)
+	switch {
+	case err == nil && !l.Active:
+		return s.Orgs.RemoveMember(ctx, userID, l.OrgID, l.UserID)
+	case errors.Is(err, storage.ErrNotFound) && l.Active && !l.CreatedAccount:
+		if !invite {
+			return nil
+		}
+		u, err := s.Users.GetUser(ctx, l.UserID)
+		if err != nil {
+			return err
+		}
+		_, err = s.Orgs.Invite(ctx, userID, l.OrgID, model.InvitationInput{Email: u.Email})
+		return err
+	case errors.Is(err, storage.ErrNotFound) && l.Active:
+		m := model.OrgMembership{OrgID: l.OrgID, UserID: l.UserID, Role: model.OrgMember, CreatedAt: time.Now().UTC()}
+		return s.Orgs.Store.SaveOrgMember(ctx, &m)
+	case errors.Is(err, storage.ErrNotFound):
+		return nil
+	}
+	return err
+}
+
+// user returns the User resource of l.
+func (s *SCIM) user(ctx context.Context, l model.SCIMLink) (model.SCIMUser, error) {
+	u, err := s.Users.GetUser(ctx, l.UserID)
+	if err != nil {
+		return model.SCIMUser{}, err
+	}
+	active := l.Active
+	return model.SCIMUser{
+		Schemas:     []string{model.SCIMUserSchema},
+		ID:          u.ID,
+		ExternalID:  l.ExternalID,
+		UserName:    l.UserName,
+		DisplayName: u.Username,
+		Emails:      []model.SCIMEmail{{Value: u.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta:        &model.SCIMMeta{ResourceType: "User", Created: &l.CreatedAt, LastModified: &l.UpdatedAt},
+	}, nil
+}
+
+// patchUser applies op to l.
+func patchUser(l *model.SCIMLink, op model.SCIMPatchOp) error {
+	kind := strings.ToLower(op.Op)
+	if kind != "add" && kind != "replace" && kind != "remove" {
+		return fmt.Errorf("op must be add, replace or remove")
+	}
+	if op.Path == "" {
+		if kind == "remove" {
+			return fmt.Errorf("remove needs a path")
+		}
+		attrs, ok := op.Value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("value must be an object of attributes when there is no path")
+		}
+		for path, value := range attrs {
+			if err := setUserAttr(l, path, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if kind == "remove" {
+		if strings.EqualFold(op.Path, "externalId") {
+			l.ExternalID = ""
+		}
+		return nil
+	}
+	return setUserAttr(l, op.Path, op.Value)
+}
+
+func setUserAttr(l *model.SCIMLink, path string, value any) error {
+	switch strings.ToLower(path) {
+	case "active":
+		active, ok := scimBool(value)
+		if !ok {
+			return fmt.Errorf("active must be true or false")
+		}
+		l.Active = active
+	case "username":
+		name, ok := value.(string)
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("userName must be a non-empty string")
+		}
+		l.UserName = strings.TrimSpace(name)
+	case "externalid":
+		id, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("externalId must be a string")
+		}
+		l.ExternalID = id
+	}
+	return nil
+}
+
+// scimBool reads a boolean, which Azure AD sends as the string "True" or
+// "False".
+func scimBool(v any) (bool, bool) {
+	switch v := v.(type) {
+	case bool:
+		return v, true
+	case string:
+		b, err := strconv.ParseBool(v)
+		return b, err == nil
+	}
+	return false, false
+}
+
+// ListGroups returns the count groups from start, which counts from 1, that
+// match filter.
+func (s *SCIM) ListGroups(ctx context.Context, userID, filter string, start, count int) (model.SCIMGroupList, error) {
+	f, err := parseSCIMFilter(filter, "displayName")
+	if err != nil {
+		return model.SCIMGroupList{}, err
+	}
+	if err := s.authorize(ctx, userID); err != nil {
+		return model.SCIMGroupList{}, err
+	}
+	projects, err := s.Projects.List(ctx, userID, false)
+	if err != nil {
+		return model.SCIMGroupList{}, err
+	}
+	var groups []model.SCIMGroup
+	for _, p := range projects {
+		if f.attr != "" && !strings.EqualFold(p.Name, f.value) {
+			continue
+		}
+		g, err := s.group(ctx, userID, p)
+		if err != nil {
+			return model.SCIMGroupList{}, err
+		}
+		groups = append(groups, g)
+	}
+	return model.SCIMGroupList(scimPage(groups, start, count)), nil
+}
+
+// Group returns the group with the given id.
+func (s *SCIM) Group(ctx context.Context, userID, id string) (model.SCIMGroup, error) {
+	if err := s.authorize(ctx, userID); err != nil {
+		return model.SCIMGroup{}, err
+	}
+	p, err := s.project(ctx, userID, id)
+	if err != nil {
+		return model.SCIMGroup{}, err
+	}
+	return s.group(ctx, userID, p)
+}
+
+// CreateGroup creates a project named after in, with its members.
+func (s *SCIM) CreateGroup(ctx context.Context, userID string, in model.SCIMGroup) (model.SCIMGroup, error) {
+	if err := s.authorize(ctx, userID); err != nil {
+		return model.SCIMGroup{}, err
+	}
+	p, err := s.Projects.Create(ctx, userID, model.ProjectInput{Name: in.DisplayName})
+	if err != nil {
+		return model.SCIMGroup{}, scimFields(err)
+	}
+	if err := s.setMembers(ctx, userID, p.ID, memberIDs(in.Members)); err != nil {
+		return model.SCIMGroup{}, err
+	}
+	return s.group(ctx, userID, p)
+}
+
+// ReplaceGroup renames the group with the given id and sets its members
+// to in's.
+func (s *SCIM) ReplaceGroup(ctx context.Context, userID, id string, in model.SCIMGroup) (model.SCIMGroup, error) {
+	if err := s.authorize(ctx, userID); err != nil {
+		return model.SCIMGroup{}, err
+	}
+	if _, err := s.project(ctx, userID, id); err != nil {
+		return model.SCIMGroup{}, err
+	}
+	p, err := s.Projects.Update(ctx, userID, id, func(p *model.Project) { p.Name = strings.TrimSpace(in.DisplayName) })
+	if err != nil {
+		return model.SCIMGroup{}, scimFields(err)
+	}
+	if err := s.setMembers(ctx, userID, id, memberIDs(in.Members)); err != nil {
+		return model.SCIMGroup{}, err
+	}
+	return s.group(ctx, userID, p)
+}
+
+// PatchGroup applies the operations of p to the group with the given id:
+// renaming it, and adding, removing or replacing members.
+func (s *SCIM) PatchGroup(ctx context.Context, userID, id string, patch model.SCIMPatch) (model.SCIMGroup, error) {
+	if err := s.authorize(ctx, userID); err != nil {
+		return model.SCIMGroup{}, err
+	}
+	p, err := s.project(ctx, userID, id)
+	if err != nil {
+		return model.SCIMGroup{}, err
+	}
+	members, err := s.members(ctx, userID, id)
+	if err != nil {
+		return model.SCIMGroup{}, err
+	}
+	name := p.Name
+	want := slices.Clone(members)
+	for i, op := range patch.Operations {
+		if err := patchGroup(&name, &want, op); err != nil {
+			var v model.ValidationError
+			v.Add(fmt.Sprintf("Operations[%d]", i), err.Error())
+			return model.SCIMGroup{}, v.Err()
+		}
+	}
+	if name != p.Name {
+		if p, err = s.Projects.Update(ctx, userID, id, func(p *model.Project) { p.Name = name }); err != nil {
+			return model.SCIMGroup{}, scimFields(err)
+		}
+	}
+	if err := s.setMembers(ctx, userID, id, want); err != nil {
+		return model.SCIMGroup{}, err
+	}
+	return s.group(ctx, userID, p)
+}
+
+// DeleteGroup archives the project with the given id, so that its tasks
+// are kept; it is no longer a group from then on.
+func (s *SCIM) DeleteGroup(ctx context.Context, userID, id string) error {
+	if err := s.authorize(ctx, userID); err != nil {
+		return err
+	}
+	if _, err := s.project(ctx, userID, id); err != nil {
+		return err
+	}
+	_, err := s.Projects.Archive(ctx, userID, id)
+	return err
+}
+
+// project returns the project with the given id unless it is archived.
+func (s *SCIM) project(ctx context.Context, userID, id string) (model.Project, error) {
+	p, err := s.Projects.Get(ctx, userID, id)
+	if err == nil && p.ArchivedAt != nil {
+		err = storage.ErrNotFound
+	}
+	return p, err
+}
+
+// members returns the IDs of the members of the project with the given
+// id, other than userID.
+func (s *SCIM) members(ctx context.Context, userID, id string) ([]string, error) {
+	members, err := s.Projects.Members(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, m := range members {
+		if m.UserID != userID {
+			ids = append(ids, m.UserID)
+		}
+	}
+	return ids, nil
+}
+
+// setMembers adds and removes members of the project with the given id
+// until, userID aside, they are those of want.
+func (s *SCIM) setMembers(ctx context.Context, userID, id string, want []string) error {
+	have, err := s.members(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	for _, m := range want {
+		if m == userID || slices.Contains(have, m) {
+			continue
+		}
+		_, err := s.Projects.AddMember(ctx, userID, id, model.MemberInput{UserID: m, Role: model.RoleEditor})
+		if err != nil && !errors.Is(err, storage.ErrConflict) {
+			return scimFields(err)
+		}
+	}
+	for _, m := range have {
+		if slices.Contains(want, m) {
+			continue
+		}
+		if err := s.Projects.RemoveMember(ctx, userID, id, m); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// group returns the Group resource of p.
+func (s *SCIM) group(ctx context.Context, userID string, p model.Project) (model.SCIMGroup, error) {
+	members, err := s.Projects.Members(ctx, userID, p.ID)
+	if err != nil {
+		return model.SCIMGroup{}, err
+	}
+	g := model.SCIMGroup{
+		Schemas:     []string{model.SCIMGroupSchema},
+		ID:          p.ID,
+		DisplayName: p.Name,
+		Members:     []model.SCIMMember{},
+		Meta:        &model.SCIMMeta{ResourceType: "Group", Created: &p.CreatedAt, LastModified: &p.UpdatedAt},
+	}
+	for _, m := range members {
+		if m.UserID != userID {
+			g.Members = append(g.Members, model.SCIMMember{Value: m.UserID, Display: m.Username})
+		}
+	}
+	return g, nil
+}
+
+// patchGroup applies op to a group's name and the IDs of its members.
+func patchGroup(name *string, members *[]string, op model.SCIMPatchOp) error {
+	kind := strings.ToLower(op.Op)
+	path := strings.TrimSpace(op.Path)
+	switch {
+	case kind != "add" && kind != "replace" && kind != "remove":
+		return fmt.Errorf("op must be add, replace or remove")
+	case path == "" && kind == "remove":
+		return fmt.Errorf("remove needs a path")
+	case path == "":
+		attrs, ok := op.Value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("value must be an object of attributes when there is no path")
+		}
+		for attr, value := range attrs {
+			if err := patchGroup(name, members, model.SCIMPatchOp{Op: kind, Path: attr, Value: value}); err != nil {
+				return err
+			}
+		}
+	case strings.EqualFold(path, "displayName"):
+		s, ok := op.Value.(string)
+		if !ok || kind == "remove" {
+			return fmt.Errorf("displayName must be replaced with a string")
+		}
+		*name = strings.TrimSpace(s)
+	case strings.EqualFold(path, "members"):
+		ids, ok := patchMemberIDs(op.Value)
+		if !ok {
+			return fmt.Errorf(`members must be a list of {"value": "<user id>"}`)
+		}
+		switch kind {
+		case "add":
+			*members = append(*members, ids...)
+		case "replace":
+			*members = ids
+		case "remove":
+			if op.Value == nil {
+				*members = nil
+			}
+			*members = slices.DeleteFunc(*members, func(id string) bool { return slices.Contains(ids, id) })
+		}
+	case kind == "remove" && strings.HasPrefix(strings.ToLower(path), "members["):
+		// members[value eq "id"], as Okta and Azure AD remove one member.
+		f, err := parseSCIMFilter(strings.TrimSuffix(path[len("members["):], "]"), "value")
+		if err != nil {
+			return err
+		}
+		*members = slices.DeleteFunc(*members, func(id string) bool { return id == f.value })
+	default:
+		return fmt.Errorf("path %q is not supported", path)
+	}
+	return nil
+}
+
+// patchMemberIDs reads the IDs from the value of a patch of members: a
+// list of objects with a value, a single one, or none at all.
+func patchMemberIDs(v any) ([]string, bool) {
+	var items []any
+	switch v := v.(type) {
+	case nil:
+		return nil, true
+	case []any:
+		items = v
+	case map[string]any:
+		items = []any{v}
+	default:
+		return nil, false
+	}
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		id, ok := m["value"].(string)
+		if !ok || id == "" {
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+func memberIDs(members []model.SCIMMember) []string {
+	ids := make([]string, len(members))
+	for i, m := range members {
+		ids[i] = m.Value
+	}
+	return ids
+}
+
+// scimFields renames the fields of a validation error from a project or
+// member to the attributes of a group.
+func scimFields(err error) error {
+	var verr *model.ValidationError
+	if !errors.As(err, &verr) {
+		return err
+	}
+	var v model.ValidationError
+	for _, f := range verr.Fields {
+		switch f.Field {
+		case "name":
+			f.Field = "displayName"
+		case "user_id":
+			f.Field = "members"
+		}
+		v.Add(f.Field, f.Message)
+	}
+	return v.Err()
+}
+
+// authorize returns ErrForbidden unless userID may manage the members of
+// the organization.
+func (s *SCIM) authorize(ctx context.Context, userID string) error {
+	_, err := s.Orgs.authorize(ctx, userID, orgOf(ctx), model.OrgAdmin)
+	return err
+}
+
+// scimFilter is a parsed `attribute eq "value"` filter; the zero one
+// matches everything.
+type scimFilter struct {
+	attr, value string
+}
+
+// parseSCIMFilter parses filter, which may only name one of attrs.
+func parseSCIMFilter(filter string, attrs ...string) (scimFilter, error) {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return scimFilter{}, nil
+	}
+	attr, rest, ok := strings.Cut(filter, " ")
+	if !ok {
+		return scimFilter{}, ErrSCIMFilter
+	}
+	op, value, ok := strings.Cut(strings.TrimSpace(rest), " ")
+	if !ok || !strings.EqualFold(op, "eq") {
+		return scimFilter{}, ErrSCIMFilter
+	}
+	value, err := strconv.Unquote(strings.TrimSpace(value))
+	if err != nil {
+		return scimFilter{}, ErrSCIMFilter
+	}
+	for _, a := range attrs {
+		if strings.EqualFold(attr, a) {
+			return scimFilter{attr: a, value: value}, nil
+		}
+	}
+	return scimFilter{}, ErrSCIMFilter
+}
+
+// matchUser reports whether u passes f. userName and emails compare
+// regardless of case, as RFC 7643 has them.
+func (f scimFilter) matchUser(u model.SCIMUser) bool {
+	switch f.attr {
+	case "userName":
+		return strings.EqualFold(u.UserName, f.value)
+	case "externalId":
+		return u.ExternalID == f.value
+	case "emails.value":
+		return slices.ContainsFunc(u.Emails, func(e model.SCIMEmail) bool { return strings.EqualFold(e.Value, f.value) })
+	}
+	return true
+}
+
+// scimPage returns count of items from start, which counts from 1.
+func scimPage[T any](items []T, start, count int) model.SCIMList[T] {
+	list := model.SCIMList[T]{Schemas: []string{model.SCIMListSchema}, TotalResults: len(items), StartIndex: start, Resources: []T{}}
+	if start--; start < len(items) {
+		list.Resources = items[start:min(start+count, len(items))]
+	}
+	list.ItemsPerPage = len(list.Resources)
+	return list
+}
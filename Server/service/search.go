@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Search returns the tasks and comments matching the words of query, best
+// first, among the tasks userID can see that also match f. f.Sort is
+// ignored: hits are always ordered by rank.
+func (s *Tasks) Search(ctx context.Context, userID, query string, f storage.TaskFilter, cursor string) (model.SearchPage, error) {
+	terms := storage.SearchTerms(query)
+	var v model.ValidationError
+	switch {
+	case len(terms) == 0:
+		v.Add("q", "is required")
+	case len(terms) > storage.MaxSearchTerms:
+		v.Add("q", fmt.Sprintf("must have at most %d words", storage.MaxSearchTerms))
+	}
+	if err := v.Err(); err != nil {
+		return model.SearchPage{}, err
+	}
+
+	f.OrgID = orgOf(ctx)
+	f.VisibleTo = userID
+	scopeAssignee(&f, userID)
+	if err := s.resolveFields(ctx, userID, &f); err != nil {
+		return model.SearchPage{}, err
+	}
+	switch {
+	case f.Limit <= 0:
+		f.Limit = DefaultPageSize
+	case f.Limit > MaxPageSize:
+		f.Limit = MaxPageSize
+	}
+	if cursor != "" {
+		offset, err := decodeCursor(cursor)
+		if err != nil {
+			return model.SearchPage{}, err
+		}
+		f.Offset = offset
+	}
+
+	limit := f.Limit
+	f.Limit++
+	hits, err := s.Index.Search(ctx, terms, f)
+	if err != nil {
+		return model.SearchPage{}, err
+	}
+	page := model.SearchPage{Items: hits}
+	if len(hits) > limit {
+		page.Items = hits[:limit]
+		page.NextCursor = encodeCursor(f.Offset + limit)
+	}
+	return page, nil
+}
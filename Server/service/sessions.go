@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// maxUserAgent bounds the User-Agent kept to tell a user's sessions apart.
+const maxUserAgent = 256
+
+// Sessions manages the sign-ins that access tokens are issued to. Each is
+// renewed with a refresh token that changes on every use; a token that
+// comes back after it was replaced may have been stolen, so it ends the
+// session rather than renewing it.
+type Sessions struct {
+	Store storage.SessionStore
+	// TTL is how long a session lasts without being refreshed.
+	TTL time.Duration
+}
+
+// Start creates a session of userID in orgID and returns it with its
+// refresh token.
+func (s *Sessions) Start(ctx context.Context, userID, orgID, userAgent string) (model.AuthSession, string, error) {
+	token := newToken()
+	now := time.Now().UTC()
+	sess := model.AuthSession{
+		UserID:     userID,
+		OrgID:      orgID,
+		UserAgent:  truncate(userAgent, maxUserAgent),
+		TokenHash:  hashToken(token),
+		CreatedAt:  now,
+		LastUsedAt: now,
+	}
+	if err := s.Store.CreateSession(ctx, &sess); err != nil {
+		return model.AuthSession{}, "", err
+	}
+	return s.withExpiry(sess), token, nil
+}
+
+// Refresh renews the session with the given refresh token, returning it
+// with the token that replaces this one. An unknown, expired or replaced
+// token yields storage.ErrNotFound.
+func (s *Sessions) Refresh(ctx context.Context, token, userAgent string) (model.AuthSession, string, error) {
+	hash := hashToken(token)
+	sess, err := s.Store.GetSessionByToken(ctx, hash)
+	if err != nil {
+		return model.AuthSession{}, "", err
+	}
+	if sess.TokenHash != hash || s.expired(sess) {
+		if err := s.Store.DeleteSession(ctx, sess.UserID, sess.ID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return model.AuthSession{}, "", err
+		}
+		return model.AuthSession{}, "", storage.ErrNotFound
+	}
+	next := newToken()
+	sess.PrevTokenHash, sess.TokenHash = hash, hashToken(next)
+	sess.LastUsedAt = time.Now().UTC()
+	if userAgent != "" {
+		sess.UserAgent = truncate(userAgent, maxUserAgent)
+	}
+	// Fails if the same token was just used to refresh concurrently.
+	if err := s.Store.UpdateSession(ctx, sess, hash); err != nil {
+		return model.AuthSession{}, "", err
+	}
+	return s.withExpiry(sess), next, nil
+}
+
+// Switch moves userID's session id to orgID, which later refreshes then
+// issue tokens for.
+func (s *Sessions) Switch(ctx context.Context, userID, id, orgID string) error {
+	sess, err := s.Active(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	sess.OrgID = orgID
+	return s.Store.UpdateSession(ctx, sess, sess.TokenHash)
+}
+
+// Active returns userID's session id, or storage.ErrNotFound if it has
+// been revoked or has expired.
+func (s *Sessions) Active(ctx context.Context, userID, id string) (model.AuthSession, error) {
+	sess, err := s.Store.GetSession(ctx, id)
+	if err != nil {
+		return model.AuthSession{}, err
+	}
+	if sess.UserID != userID || s.expired(sess) {
+		return model.AuthSession{}, storage.ErrNotFound
+	}
+	return s.withExpiry(sess), nil
+}
+
+// List returns userID's sessions, most recently used first, marking
+// currentID as the current one.
+func (s *Sessions) List(ctx context.Context, userID, currentID string) ([]model.AuthSession, error) {
+	all, err := s.Store.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions := []model.AuthSession{}
+	for _, sess := range all {
+		if s.expired(sess) {
+			continue
+		}
+		sess = s.withExpiry(sess)
+		sess.Current = sess.ID == currentID
+		sessions = append(sessions, sess)
+	}
+	return sessions, nil
+}
+
+// Revoke ends userID's session id; its tokens stop working at once.
+func (s *Sessions) Revoke(ctx context.Context, userID, id string) error {
+	return s.Store.DeleteSession(ctx, userID, id)
+}
+
+// RevokeAll ends userID's sessions, except keepID if it is not empty, and
+// returns how many there were.
+func (s *Sessions) RevokeAll(ctx context.Context, userID, keepID string) (int, error) {
+	return s.Store.DeleteSessions(ctx, userID, keepID)
+}
+
+func (s *Sessions) expired(sess model.AuthSession) bool {
+	return !time.Now().Before(sess.LastUsedAt.Add(s.TTL))
+}
+
+func (s *Sessions) withExpiry(sess model.AuthSession) model.AuthSession {
+	sess.ExpiresAt = sess.LastUsedAt.Add(s.TTL)
+	return sess
+}
+
+// truncate shortens s to at most n bytes without splitting a character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Shares manages the links that show a task, or a project's board, to
+// anybody who has one, without signing in. Their tokens are as good as a
+// password to what they show, so they only ever give a read-only view,
+// and can be made to expire and be revoked. Sharing a project takes its
+// owner; sharing a task, somebody who may edit it.
+type Shares struct {
+	Store    storage.ShareStore
+	Projects storage.ProjectStore
+	// Orgs confirms that a link's creator still belongs to its
+	// organization.
+	Orgs  storage.OrgStore
+	Tasks *Tasks
+}
+
+// ShareProject creates a link to the project with the given id on behalf
+// of userID. The token and URL are only returned here; the URL is
+// relative to the API, like attachment links.
+func (s *Shares) ShareProject(ctx context.Context, userID, id string, in model.ShareInput) (model.ShareLink, error) {
+	if _, err := authorizeProject(ctx, s.Projects, userID, id, model.RoleOwner); err != nil {
+		return model.ShareLink{}, err
+	}
+	return s.create(ctx, userID, model.ShareLink{ProjectID: &id}, in)
+}
+
+// ShareTask creates a link to the task with the given id on behalf of
+// userID, as ShareProject does.
+func (s *Shares) ShareTask(ctx context.Context, userID, id string, in model.ShareInput) (model.ShareLink, error) {
+	if _, err := s.Tasks.authorize(ctx, userID, id, model.RoleEditor); err != nil {
+		return model.ShareLink{}, err
+	}
+	return s.create(ctx, userID, model.ShareLink{TaskID: &id}, in)
+}
+
+func (s *Shares) create(ctx context.Context, userID string, l model.ShareLink, in model.ShareInput) (model.ShareLink, error) {
+	now := time.Now().UTC()
+	if err := in.Validate(now); err != nil {
+		return model.ShareLink{}, err
+	}
+	token := newToken()
+	l.OrgID = orgOf(ctx)
+	l.CreatedBy = userID
+	l.TokenHash = hashToken(token)
+	l.CreatedAt = now
+	if in.ExpiresAt != nil {
+		at := in.ExpiresAt.UTC()
+		l.ExpiresAt = &at
+	}
+	if err := s.Store.CreateShareLink(ctx, &l); err != nil {
+		return model.ShareLink{}, err
+	}
+	l.Token = token
+	l.URL = "/shared/" + token
+	return l, nil
+}
+
+// ProjectLinks lists the links to the project with the given id, expired
+// ones included, oldest first, if userID may share it.
+func (s *Shares) ProjectLinks(ctx context.Context, userID, id string) ([]model.ShareLink, error) {
+	if _, err := authorizeProject(ctx, s.Projects, userID, id, model.RoleOwner); err != nil {
+		return nil, err
+	}
+	return s.Store.ListShareLinks(ctx, storage.ShareFilter{ProjectID: id})
+}
+
+// TaskLinks lists the links to the task with the given id, as ProjectLinks
+// does.
+func (s *Shares) TaskLinks(ctx context.Context, userID, id string) ([]model.ShareLink, error) {
+	if _, err := s.Tasks.authorize(ctx, userID, id, model.RoleEditor); err != nil {
+		return nil, err
+	}
+	return s.Store.ListShareLinks(ctx, storage.ShareFilter{TaskID: id})
+}
+
+// Revoke deletes the link with the given id, if userID may share what it
+// shows; it stops working at once.
+func (s *Shares) Revoke(ctx context.Context, userID, id string) error {
+	l, err := s.Store.GetShareLink(ctx, id)
+	if err != nil {
+		return err
+	}
+	if l.OrgID != orgOf(ctx) {
+		return storage.ErrNotFound
+	}
+	if l.TaskID != nil {
+		_, err = s.Tasks.authorize(ctx, userID, *l.TaskID, model.RoleEditor)
+	} else {
+		_, err = authorizeProject(ctx, s.Projects, userID, *l.ProjectID, model.RoleOwner)
+	}
+	if err != nil {
+		return err
+	}
+	return s.Store.DeleteShareLink(ctx, id)
+}
+
+// View returns what the link with the given token shows: the project and
+// the tasks on its board, archived ones aside, or the task and its
+// subtasks, in board order. An unknown or expired token, one whose
+// creator has left the organization, or one whose task is in the trash
+// yields storage.ErrNotFound.
+func (s *Shares) View(ctx context.Context, token string) (model.SharedView, error) {
+	l, err := s.Store.GetShareLinkByToken(ctx, hashToken(token))
+	if err != nil {
+		return model.SharedView{}, err
+	}
+	if l.Expired(time.Now().UTC()) {
+		return model.SharedView{}, storage.ErrNotFound
+	}
+	if _, err := s.Orgs.GetOrgMember(ctx, l.OrgID, l.CreatedBy); err != nil {
+		return model.SharedView{}, err
+	}
+
+	view := model.SharedView{Tasks: []model.SharedTask{}, ExpiresAt: l.ExpiresAt}
+	open := false
+	f := storage.TaskFilter{
+		OrgID:    l.OrgID,
+		Archived: &open,
+		Sort:     storage.Sort{Field: storage.SortPosition},
+		Limit:    model.MaxSharedTasks,
+	}
+	if l.TaskID != nil {
+		t, err := s.Tasks.Store.GetTask(ctx, *l.TaskID)
+		if err != nil {
+			return model.SharedView{}, err
+		}
+		if t.DeletedAt != nil {
+			return model.SharedView{}, storage.ErrNotFound
+		}
+		shared := model.NewSharedTask(t)
+		view.Task = &shared
+		f.ParentID = t.ID
+	} else {
+		p, err := s.Projects.GetProject(ctx, *l.ProjectID)
+		if err != nil {
+			return model.SharedView{}, err
+		}
+		shared := model.NewSharedProject(p)
+		view.Project = &shared
+		f.ProjectID = p.ID
+	}
+	tasks, err := s.Tasks.Store.ListTasks(ctx, f)
+	if err != nil {
+		return model.SharedView{}, err
+	}
+	for _, t := range tasks {
+		view.Tasks = append(view.Tasks, model.NewSharedTask(t))
+	}
+	return view, nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"starttech-server/auth"
+	"starttech-server/integrations"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Slack links projects to Slack channels, whose /task slash command then
+// creates tasks in the project. Only project owners may manage a link.
+// Tasks are created in the name of the owner who linked the channel, so
+// the usual rules for tasks apply.
+type Slack struct {
+	Store    storage.SlackStore
+	Projects storage.ProjectStore
+	Tasks    *Tasks
+}
+
+// slackCommandURL is where the slash command of a project's link sends its
+// requests, relative to the API.
+func slackCommandURL(projectID string) string {
+	return "/integrations/slack/" + projectID + "/command"
+}
+
+// Get returns the link of the project with the given id if userID owns the
+// project.
+func (s *Slack) Get(ctx context.Context, userID, projectID string) (model.SlackLink, error) {
+	if _, err := authorizeProject(ctx, s.Projects, userID, projectID, model.RoleOwner); err != nil {
+		return model.SlackLink{}, err
+	}
+	l, err := s.Store.GetSlackLink(ctx, projectID)
+	if err != nil {
+		return model.SlackLink{}, err
+	}
+	l.CommandURL = slackCommandURL(projectID)
+	return l, nil
+}
+
+// Link links the project with the given id to the Slack channel in,
+// replacing any link it had, if userID owns the project.
+func (s *Slack) Link(ctx context.Context, userID, projectID string, in model.SlackLinkInput) (model.SlackLink, error) {
+	p, err := authorizeProject(ctx, s.Projects, userID, projectID, model.RoleOwner)
+	if err != nil {
+		return model.SlackLink{}, err
+	}
+	l := model.SlackLink{ProjectID: p.ID, OrgID: p.OrgID, CreatedBy: userID, CreatedAt: time.Now().UTC()}
+	in.Apply(&l)
+	if err := l.Validate(integrations.SlackEvents); err != nil {
+		return model.SlackLink{}, err
+	}
+	if err := s.Store.SaveSlackLink(ctx, l); err != nil {
+		return model.SlackLink{}, err
+	}
+	l.CommandURL = slackCommandURL(projectID)
+	return l, nil
+}
+
+// Unlink removes the link of the project with the given id if userID owns
+// the project.
+func (s *Slack) Unlink(ctx context.Context, userID, projectID string) error {
+	if _, err := authorizeProject(ctx, s.Projects, userID, projectID, model.RoleOwner); err != nil {
+		return err
+	}
+	return s.Store.DeleteSlackLink(ctx, projectID)
+}
+
+// Command runs a slash command sent to the project with the given id.
+// timestamp and signature are its X-Slack-Request-Timestamp and
+// X-Slack-Signature headers, and body its form-encoded body. The command's
+// text becomes the title of a new task in the project; an empty text or
+// "help" explains the command instead. A task that cannot be created is
+// explained in a reply only its sender sees.
+func (s *Slack) Command(ctx context.Context, projectID, timestamp, signature string, body []byte) (model.SlackReply, error) {
+	l, err := s.Store.GetSlackLink(ctx, projectID)
+	if err != nil {
+		return model.SlackReply{}, err
+	}
+	if !integrations.VerifySlackSignature(l.SigningSecret, timestamp, body, signature, time.Now()) {
+		return model.SlackReply{}, ErrBadSignature
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		var v model.ValidationError
+		v.Add("body", "is not a slash command")
+		return model.SlackReply{}, v.Err()
+	}
+	p, err := s.Projects.GetProject(ctx, projectID)
+	if err != nil {
+		return model.SlackReply{}, err
+	}
+
+	name := integrations.SlackEscape(p.Name)
+	text := strings.TrimSpace(form.Get("text"))
+	if text == "" || strings.EqualFold(text, "help") {
+		return model.SlackReply{ResponseType: model.SlackEphemeral,
+			Text: integrations.SlackEscape(form.Get("command")+" <title>") + " adds a task to " + name + "."}, nil
+	}
+	in := model.TaskInput{Title: truncate(text, model.MaxTitleLen), ProjectID: &l.ProjectID}
+	if user := form.Get("user_name"); user != "" {
+		in.Description = "Created from Slack by @" + user + "."
+	}
+	// Act as the owner who linked the channel would through the API.
+	ctx = auth.WithOrgID(auth.WithUserID(ctx, l.CreatedBy), l.OrgID)
+	t, err := s.Tasks.Create(ctx, l.CreatedBy, in)
+	if rejected(err) {
+		slog.WarnContext(ctx, "slack: task not created", "project_id", projectID, "err", err)
+		return model.SlackReply{ResponseType: model.SlackEphemeral, Text: "The task could not be created: " + integrations.SlackEscape(err.Error())}, nil
+	}
+	if err != nil {
+		return model.SlackReply{}, err
+	}
+	return model.SlackReply{ResponseType: model.SlackInChannel, Text: "Added *" + integrations.SlackEscape(t.Title) + "* to " + name + "."}, nil
+}
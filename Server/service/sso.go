@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"starttech-server/model"
+	"starttech-server/oauth"
+	"starttech-server/storage"
+)
+
+// ErrSSORequired is returned when someone whose email domain must sign in
+// through their organization's identity provider tries another way.
+var ErrSSORequired = errors.New("service: sign in through your organization's identity provider")
+
+// ErrInvalidTicket is returned for a link ticket that is forged or has
+// expired.
+var ErrInvalidTicket = errors.New("service: invalid or expired link ticket")
+
+// SSO keeps organizations' single sign-on settings, and brings the people
+// who sign in through an identity provider into the organization and the
+// projects their groups are given roles in. Client secrets are sealed with
+// Key, which must be 32 bytes, before they are stored.
+type SSO struct {
+	Store    storage.SSOStore
+	Orgs     *Orgs
+	Projects storage.ProjectStore
+	Key      []byte
+	// Client makes the calls to identity providers; nil uses the oauth
+	// package's default, which only connects to public addresses.
+	Client *http.Client
+
+	mu sync.Mutex
+	// providers caches each organization's discovered provider, until its
+	// settings change.
+	providers map[string]ssoProvider
+}
+
+type ssoProvider struct {
+	updatedAt time.Time
+	provider  *oauth.Provider
+}
+
+// Get returns the single sign-on settings of the organization orgID. Only
+// its admins and owners may see them.
+func (s *SSO) Get(ctx context.Context, userID, orgID string) (model.SSOConfig, error) {
+	if _, err := s.Orgs.authorize(ctx, userID, orgID, model.OrgAdmin); err != nil {
+		return model.SSOConfig{}, err
+	}
+	return s.Store.GetSSOConfig(ctx, orgID)
+}
+
+// Save sets the single sign-on settings of the organization orgID. The
+// domain must be one the organization has verified, so that nobody can
+// claim the sign-ins of a domain they do not control, and the issuer must
+// publish a discovery document. It returns storage.ErrConflict if another
+// organization has the domain.
+func (s *SSO) Save(ctx context.Context, userID, orgID string, in model.SSOConfigInput) (model.SSOConfig, error) {
+	if _, err := s.Orgs.authorize(ctx, userID, orgID, model.OrgAdmin); err != nil {
+		return model.SSOConfig{}, err
+	}
+	now := time.Now().UTC()
+	c, err := s.Store.GetSSOConfig(ctx, orgID)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		c = model.SSOConfig{OrgID: orgID, CreatedAt: now}
+	case err != nil:
+		return model.SSOConfig{}, err
+	}
+	in.Apply(&c)
+	c.UpdatedAt = now
+	if err := c.Validate(); err != nil {
+		return model.SSOConfig{}, err
+	}
+
+	var v model.ValidationError
+	verified, err := s.Orgs.domainVerified(ctx, orgID, c.Domain)
+	if err != nil {
+		return model.SSOConfig{}, err
+	}
+	if !verified {
+		v.Add("domain", "must be a domain the organization has verified")
+	}
+	for i, g := range c.GroupRoles {
+		p, err := s.Projects.GetProject(ctx, g.ProjectID)
+		if errors.Is(err, storage.ErrNotFound) || err == nil && p.OrgID != orgID {
+			v.Add(fmt.Sprintf("group_roles[%d].project_id", i), "must be a project of the organization")
+		} else if err != nil {
+			return model.SSOConfig{}, err
+		}
+	}
+	if in.ClientSecret != "" {
+		if c.ClientSecret, err = seal(s.Key, in.ClientSecret); err != nil {
+			return model.SSOConfig{}, err
+		}
+	}
+	if err := v.Err(); err != nil {
+		return model.SSOConfig{}, err
+	}
+	// The reason stays in the log: it may describe what answered at an
+	// address the admin should not learn about.
+	if _, err := s.discover(ctx, c); err != nil {
+		slog.WarnContext(ctx, "discovering identity provider", "org_id", orgID, "issuer", c.Issuer, "err", err)
+		v.Add("issuer", "could not be discovered")
+		return model.SSOConfig{}, v.Err()
+	}
+	if err := s.Store.SaveSSOConfig(ctx, c); err != nil {
+		return model.SSOConfig{}, err
+	}
+	return s.Store.GetSSOConfig(ctx, orgID)
+}
+
+// Delete turns single sign-on off for the organization orgID.
+func (s *SSO) Delete(ctx context.Context, userID, orgID string) error {
+	if _, err := s.Orgs.authorize(ctx, userID, orgID, model.OrgAdmin); err != nil {
+		return err
+	}
+	return s.Store.DeleteSSOConfig(ctx, orgID)
+}
+
+// Lookup returns the settings of the organization that signs in the people
+// of email's domain, or storage.ErrNotFound if there is none.
+func (s *SSO) Lookup(ctx context.Context, email string) (model.SSOConfig, error) {
+	domain := model.EmailDomain(email)
+	if domain == "" {
+		return model.SSOConfig{}, storage.ErrNotFound
+	}
+	c, err := s.Store.GetSSOConfigByDomain(ctx, domain)
+	if err != nil {
+		return model.SSOConfig{}, err
+	}
+	return s.active(ctx, c)
+}
+
+// ForOrg returns the settings of the organization orgID, for a sign-in
+// that has come back from its identity provider.
+func (s *SSO) ForOrg(ctx context.Context, orgID string) (model.SSOConfig, error) {
+	c, err := s.Store.GetSSOConfig(ctx, orgID)
+	if err != nil {
+		return model.SSOConfig{}, err
+	}
+	return s.active(ctx, c)
+}
+
+// active passes on c if its organization still has its domain verified,
+// and otherwise reports it not found: settings saved before the domain was
+// verified, or kept after the claim was dropped, sign nobody in.
+func (s *SSO) active(ctx context.Context, c model.SSOConfig) (model.SSOConfig, error) {
+	verified, err := s.Orgs.domainVerified(ctx, c.OrgID, c.Domain)
+	if err != nil {
+		return model.SSOConfig{}, err
+	}
+	if !verified {
+		return model.SSOConfig{}, storage.ErrNotFound
+	}
+	return c, nil
+}
+
+// Enforced returns ErrSSORequired if u is a member of the organization that
+// has their email domain and must sign in through its identity provider.
+// People of the domain outside the organization sign in as they like, and
+// its owners may too, so that a broken provider cannot lock everyone out.
+func (s *SSO) Enforced(ctx context.Context, u model.User) error {
+	c, err := s.Lookup(ctx, u.Email)
+	if errors.Is(err, storage.ErrNotFound) || err == nil && !c.Enforced {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	m, err := s.Orgs.Store.GetOrgMember(ctx, c.OrgID, u.ID)
+	if errors.Is(err, storage.ErrNotFound) || err == nil && m.Role == model.OrgOwner {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return ErrSSORequired
+}
+
+// ssoLinkTTL is how long a link ticket lets its holder start a sign-in
+// through an identity provider.
+const ssoLinkTTL = 10 * time.Minute
+
+// LinkTicket returns a ticket to pass to the sign-in start, which links
+// the identity the sign-in comes back with to userID, who has just proved
+// they hold that account. Without one, an identity is never linked to an
+// account that already has its email address.
+func (s *SSO) LinkTicket(userID string) (string, error) {
+	return seal(s.Key, "link:"+userID+":"+strconv.FormatInt(time.Now().Add(ssoLinkTTL).Unix(), 10))
+}
+
+// OpenLinkTicket returns the user a ticket from LinkTicket was issued to,
+// or ErrInvalidTicket if it was not issued by s or has expired.
+func (s *SSO) OpenLinkTicket(ticket string) (string, error) {
+	plain, err := unseal(s.Key, ticket)
+	if err != nil {
+		return "", ErrInvalidTicket
+	}
+	rest, ok := strings.CutPrefix(plain, "link:")
+	userID, expiry, ok2 := strings.Cut(rest, ":")
+	exp, err := strconv.ParseInt(expiry, 10, 64)
+	if !ok || !ok2 || err != nil || time.Now().Unix() > exp {
+		return "", ErrInvalidTicket
+	}
+	return userID, nil
+}
+
+// Provider returns the identity provider of c, discovering it the first
+// time and again whenever c changes.
+func (s *SSO) Provider(ctx context.Context, c model.SSOConfig) (*oauth.Provider, error) {
+	s.mu.Lock()
+	cached, ok := s.providers[c.OrgID]
+	s.mu.Unlock()
+	if ok && cached.updatedAt.Equal(c.UpdatedAt) {
+		return cached.provider, nil
+	}
+	p, err := s.discover(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	if s.providers == nil {
+		s.providers = map[string]ssoProvider{}
+	}
+	s.providers[c.OrgID] = ssoProvider{updatedAt: c.UpdatedAt, provider: p}
+	s.mu.Unlock()
+	return p, nil
+}
+
+func (s *SSO) discover(ctx context.Context, c model.SSOConfig) (*oauth.Provider, error) {
+	secret, err := unseal(s.Key, c.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	return oauth.Discover(ctx, oauth.OIDC{
+		Name:         "sso",
+		Issuer:       c.Issuer,
+		ClientID:     c.ClientID,
+		ClientSecret: secret,
+		GroupsClaim:  c.GroupsClaim,
+		Client:       s.Client,
+	})
+}
+
+// Join brings u, who has signed in through the identity provider of c as
+// a member of groups, into the organization as a member if they are not
+// one, and gives them the roles c maps their groups to. Where several of
+// their groups map to one project, the highest role wins. Roles are only
+// raised: a member who already has a higher role in a project keeps it,
+// and nobody is removed from a project for leaving a group.
+func (s *SSO) Join(ctx context.Context, c model.SSOConfig, u model.User, groups []string) error {
+	now := time.Now().UTC()
+	_, err := s.Orgs.Store.GetOrgMember(ctx, c.OrgID, u.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		err = s.Orgs.Store.SaveOrgMember(ctx, &model.OrgMembership{OrgID: c.OrgID, UserID: u.ID, Role: model.OrgMember, CreatedAt: now})
+	}
+	if err != nil {
+		return err
+	}
+
+	roles := map[string]model.Role{}
+	for _, g := range c.GroupRoles {
+		if slices.Contains(groups, g.Group) && !roles[g.ProjectID].Allows(g.Role) {
+			roles[g.ProjectID] = g.Role
+		}
+	}
+	for projectID, role := range roles {
+		p, err := s.Projects.GetProject(ctx, projectID)
+		if errors.Is(err, storage.ErrNotFound) || err == nil && (p.OrgID != c.OrgID || p.ArchivedAt != nil) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		m, err := s.Projects.GetMember(ctx, projectID, u.ID)
+		switch {
+		case err == nil && m.Role.Allows(role):
+			continue
+		case errors.Is(err, storage.ErrNotFound):
+			m = model.Member{ProjectID: projectID, UserID: u.ID, CreatedAt: now}
+		case err != nil:
+			return err
+		}
+		m.Role = role
+		if err := s.Projects.SaveMember(ctx, &m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
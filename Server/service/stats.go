@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Bounds of the date range of Stats, in days.
+const (
+	DefaultStatsDays = 30
+	MaxStatsDays     = 366
+)
+
+// columnTime adds up the visits to one column.
+type columnTime struct {
+	total  time.Duration
+	visits int
+}
+
+// Stats charts the project with the given id over the days from and to,
+// both included, which are YYYY-MM-DD dates in userID's timezone. to
+// defaults to today and may not be later; from defaults to the
+// DefaultStatsDays days up to to. The figures are worked out from the
+// revisions of the tasks now in the project, so a task only counts while
+// it was in the project and out of the trash.
+func (s *Projects) Stats(ctx context.Context, userID, id, from, to string) (model.ProjectStats, error) {
+	p, err := authorizeProject(ctx, s.Store, userID, id, model.RoleViewer)
+	if err != nil {
+		return model.ProjectStats{}, err
+	}
+	now := time.Now()
+	start, end, err := statsRange(from, to, now.In(zone(ctx, s.Users, userID)))
+	if err != nil {
+		return model.ProjectStats{}, err
+	}
+	// bounds holds the start of each day and the end of the last.
+	var bounds []time.Time
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		bounds = append(bounds, d)
+	}
+	days := len(bounds) - 1
+	day := func(t time.Time) int {
+		i := sort.Search(len(bounds), func(i int) bool { return bounds[i].After(t) }) - 1
+		if i < 0 || i >= days {
+			return -1
+		}
+		return i
+	}
+
+	tasks, err := s.Tasks.ListTasks(ctx, storage.TaskFilter{OrgID: p.OrgID, ProjectID: p.ID})
+	if err != nil {
+		return model.ProjectStats{}, err
+	}
+	completed := make([]int, days)
+	open := make([]int, days)
+	done := make([]int, days)
+	columns := map[model.Status]*columnTime{}
+	inProject := func(t model.Task) bool {
+		return t.ProjectID != nil && *t.ProjectID == p.ID && t.DeletedAt == nil
+	}
+	for _, t := range tasks {
+		revs, err := s.History.ListRevisions(ctx, storage.RevisionFilter{TaskID: t.ID})
+		if err != nil {
+			return model.ProjectStats{}, err
+		}
+		if len(revs) == 0 {
+			continue
+		}
+		slices.Reverse(revs)
+
+		visit := 0 // the revision the current visit to a column began at
+		for i, r := range revs {
+			if inProject(r.Task) && r.Task.Completed && (i == 0 || !revs[i-1].Task.Completed) {
+				if d := day(r.CreatedAt); d >= 0 {
+					completed[d]++
+				}
+			}
+			if i == 0 || r.Task.Status == revs[visit].Task.Status {
+				continue
+			}
+			if v := revs[visit].Task; inProject(v) && day(r.CreatedAt) >= 0 {
+				c := columns[v.Status]
+				if c == nil {
+					c = &columnTime{}
+					columns[v.Status] = c
+				}
+				c.total += r.CreatedAt.Sub(revs[visit].CreatedAt)
+				c.visits++
+			}
+			visit = i
+		}
+
+		j := -1 // the last revision saved by the end of the day
+		for d := range days {
+			at := bounds[d+1]
+			if at.After(now) {
+				at = now
+			}
+			for j+1 < len(revs) && !revs[j+1].CreatedAt.After(at) {
+				j++
+			}
+			if j < 0 || !inProject(revs[j].Task) {
+				continue
+			}
+			if revs[j].Task.Completed {
+				done[d]++
+			} else {
+				open[d]++
+			}
+		}
+	}
+
+	stats := model.ProjectStats{
+		ProjectID:  p.ID,
+		From:       start.Format(time.DateOnly),
+		To:         bounds[days-1].Format(time.DateOnly),
+		Throughput: make([]model.DayCount, days),
+		CycleTime:  []model.ColumnTime{},
+		Burndown:   make([]model.BurndownDay, days),
+	}
+	for d := range days {
+		date := bounds[d].Format(time.DateOnly)
+		stats.Throughput[d] = model.DayCount{Date: date, Completed: completed[d]}
+		stats.Burndown[d] = model.BurndownDay{Date: date, Open: open[d], Done: done[d]}
+	}
+	for _, col := range p.Statuses {
+		if col.Done {
+			continue
+		}
+		ct := model.ColumnTime{Status: col.Key, Name: col.Name}
+		if c := columns[col.Key]; c != nil {
+			ct.Visits = c.visits
+			ct.AverageSeconds = int64(c.total.Seconds()) / int64(c.visits)
+		}
+		stats.CycleTime = append(stats.CycleTime, ct)
+	}
+	return stats, nil
+}
+
+// statsRange parses the dates from and to in the timezone of now, and
+// returns the start of from and the end of to.
+func statsRange(from, to string, now time.Time) (start, end time.Time, err error) {
+	var v model.ValidationError
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	last := today
+	if to != "" {
+		if last, err = time.ParseInLocation(time.DateOnly, to, now.Location()); err != nil {
+			v.Add("to", "must be a YYYY-MM-DD date")
+		} else if last.After(today) {
+			v.Add("to", "must not be after today")
+		}
+	}
+	start = last.AddDate(0, 0, 1-DefaultStatsDays)
+	if from != "" {
+		if start, err = time.ParseInLocation(time.DateOnly, from, now.Location()); err != nil {
+			v.Add("from", "must be a YYYY-MM-DD date")
+		}
+	}
+	if err := v.Err(); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end = last.AddDate(0, 0, 1)
+	switch {
+	case start.After(last):
+		v.Add("from", "must not be after to")
+	case start.AddDate(0, 0, MaxStatsDays).Before(end):
+		v.Add("from", fmt.Sprintf("must be at most %d days before to", MaxStatsDays-1))
+	}
+	return start, end, v.Err()
+}
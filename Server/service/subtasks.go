@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// ChildPolicy decides what happens to the subtasks of a deleted task.
+type ChildPolicy string
+
+const (
+	// ReparentChildren moves the direct subtasks up to the deleted task's
+	// own parent, or to the top level.
+	ReparentChildren ChildPolicy = "reparent"
+	// CascadeChildren deletes every subtask below the task.
+	CascadeChildren ChildPolicy = "cascade"
+)
+
+// Valid reports whether p is a known policy.
+func (p ChildPolicy) Valid() bool {
+	return p == ReparentChildren || p == CascadeChildren
+}
+
+// CreateSubtask creates a task under parentID, which userID must be able to
+// see. The subtask joins the parent's project unless in names another.
+func (s *Tasks) CreateSubtask(ctx context.Context, userID, parentID string, in model.TaskInput) (model.Task, error) {
+	parent, err := s.Get(ctx, userID, parentID)
+	if err != nil {
+		return model.Task{}, err
+	}
+	in.ParentID = &parentID
+	if in.ProjectID == nil {
+		in.ProjectID = parent.ProjectID
+	}
+	return s.Create(ctx, userID, in)
+}
+
+// Children lists one page of the direct subtasks of the task with the given
+// id.
+func (s *Tasks) Children(ctx context.Context, userID, id string, f storage.TaskFilter, cursor string) (model.TaskPage, error) {
+	if _, err := s.Get(ctx, userID, id); err != nil {
+		return model.TaskPage{}, err
+	}
+	f.ParentID = id
+	return s.List(ctx, userID, f, cursor)
+}
+
+// Rollup reports how many of the task's subtasks, at any depth, are done.
+func (s *Tasks) Rollup(ctx context.Context, userID, id string) (model.Rollup, error) {
+	t, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return model.Rollup{}, err
+	}
+	below, err := s.descendants(ctx, t)
+	if err != nil {
+		return model.Rollup{}, err
+	}
+	r := model.Rollup{TaskID: id, Total: len(below)}
+	for _, c := range below {
+		if c.Completed {
+			r.Done++
+		}
+	}
+	if r.Total > 0 {
+		r.Percent = math.Round(float64(r.Done)/float64(r.Total)*1000) / 10
+	}
+	return r, nil
+}
+
+// descendants returns every task below t, breadth first.
+func (s *Tasks) descendants(ctx context.Context, t model.Task) ([]model.Task, error) {
+	var out []model.Task
+	queue := []string{t.ID}
+	for len(queue) > 0 {
+		children, err := s.Store.ListTasks(ctx, storage.TaskFilter{ParentID: queue[0]})
+		if err != nil {
+			return nil, err
+		}
+		queue = queue[1:]
+		for _, c := range children {
+			out = append(out, c)
+			queue = append(queue, c.ID)
+		}
+	}
+	return out, nil
+}
+
+// detachChildren applies policy to the subtasks of t ahead of its deletion
+// by userID at the given time. Cascaded subtasks go to the trash with the
+// same time, so restoring t brings them back too.
+func (s *Tasks) detachChildren(ctx context.Context, userID string, t model.Task, policy ChildPolicy, at time.Time) error {
+	if policy == CascadeChildren {
+		below, err := s.descendants(ctx, t)
+		if err != nil {
+			return err
+		}
+		for _, c := range below {
+			if err := s.trash(ctx, &c, at); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				return fmt.Errorf("deleting subtask %s: %w", c.ID, err)
+			}
+			s.publish(ctx, events.TaskDeleted, s.audience(ctx, c), events.Deleted{ID: c.ID})
+			s.record(ctx, userID, events.TaskDeleted, c, "", nil)
+		}
+		return nil
+	}
+
+	children, err := s.Store.ListTasks(ctx, storage.TaskFilter{ParentID: t.ID})
+	if err != nil {
+		return err
+	}
+	for _, c := range children {
+		old := c
+		c.ParentID = t.ParentID
+		c.UpdatedAt = at
+		if err := s.Store.UpdateTask(ctx, &c); err != nil {
+			return fmt.Errorf("reparenting subtask %s: %w", c.ID, err)
+		}
+		noteSaved(ctx, old.Version, c)
+		s.publish(ctx, events.TaskUpdated, s.audience(ctx, c), c)
+		s.recordUpdate(ctx, userID, old, c)
+	}
+	return nil
+}
+
+// checkParent verifies that t's parent exists, is visible to userID and is
+// not t itself or one of its subtasks.
+func (s *Tasks) checkParent(ctx context.Context, userID string, t *model.Task) error {
+	if t.ParentID == nil {
+		return nil
+	}
+	var v model.ValidationError
+	parent, err := s.Get(ctx, userID, *t.ParentID)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		v.Add("parent_id", "does not refer to one of your tasks")
+		return v.Err()
+	case err != nil:
+		return err
+	}
+
+	// A new task has no ID yet and so cannot be its own ancestor.
+	for t.ID != "" {
+		if parent.ID == t.ID {
+			v.Add("parent_id", "would make the task its own subtask")
+			return v.Err()
+		}
+		if parent.ParentID == nil {
+			break
+		}
+		parent, err = s.Store.GetTask(ctx, *parent.ParentID)
+		if errors.Is(err, storage.ErrNotFound) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sameID(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
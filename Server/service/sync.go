@@ -0,0 +1,349 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// SyncItem is the outcome of one pushed mutation. Task is nil once the task
+// is deleted and when Err is set.
+type SyncItem struct {
+	Resolution model.SyncResolution
+	Task       *model.Task
+	Conflicts  []string
+	Err        error
+}
+
+// Changes returns the page of the change feed that follows the cursor
+// since: the tasks of the caller's organization that userID may see, in
+// the trash or not, changed since, oldest change first, and the IDs of
+// those deleted for good or that userID can no longer see. An empty since
+// starts from the first change. A task changed again moves to the end of
+// the feed, so it is listed once, as it is now, however often it changed.
+func (s *Tasks) Changes(ctx context.Context, userID, since string, limit int) (model.ChangePage, error) {
+	f := storage.ChangeFilter{OrgID: orgOf(ctx), VisibleTo: userID}
+	if since != "" {
+		k, err := decodeChangeCursor(since)
+		if err != nil {
+			return model.ChangePage{}, err
+		}
+		f.After = k
+	}
+	switch {
+	case limit <= 0:
+		limit = DefaultPageSize
+	case limit > MaxPageSize:
+		limit = MaxPageSize
+	}
+	f.Limit = limit + 1
+	changes, err := s.Store.ListChanges(ctx, f)
+	if err != nil {
+		return model.ChangePage{}, err
+	}
+	page := model.ChangePage{Items: []model.Task{}, Deleted: []string{}}
+	if len(changes) > limit {
+		changes = changes[:limit]
+		page.HasMore = true
+	}
+	for _, c := range changes {
+		if c.Task != nil {
+			page.Items = append(page.Items, *c.Task)
+		} else {
+			page.Deleted = append(page.Deleted, c.Key.TaskID)
+		}
+		f.After = c.Key
+	}
+	page.Cursor = encodeChangeCursor(f.After)
+	return page, nil
+}
+
+// maxClientIDLen bounds the client_id of a pushed create.
+const maxClientIDLen = 255
+
+// maxSyncAttempts bounds how often push settles a mutation again because
+// the task changed while it was being settled.
+const maxSyncAttempts = 3
+
+// Push applies the mutations of in, made by userID while offline, in order
+// and each on its own, so that one failing leaves the others be. A mutation
+// made to a version of a task that has changed since is settled by
+// in.Strategy. The mutations applied are published and logged as they are
+// applied, and undone together by Undo.
+func (s *Tasks) Push(ctx context.Context, userID string, in model.SyncPushInput) ([]SyncItem, error) {
+	if in.Strategy == "" {
+		in.Strategy = model.SyncMerge
+	}
+	if err := validatePush(in); err != nil {
+		return nil, err
+	}
+	ctx, j := s.startJournal(ctx)
+	items := make([]SyncItem, len(in.Mutations))
+	for i, m := range in.Mutations {
+		item, err := s.push(ctx, userID, in.Strategy, m)
+		if err != nil {
+			item = SyncItem{Resolution: model.SyncFailed, Err: err}
+		}
+		items[i] = item
+	}
+	s.remember(ctx, userID, model.CommandSync, j)
+	return items, nil
+}
+
+// push applies one mutation.
+func (s *Tasks) push(ctx context.Context, userID string, strategy model.SyncStrategy, m model.SyncMutation) (SyncItem, error) {
+	if m.Op == model.BulkCreate {
+		return s.pushCreate(ctx, userID, m)
+	}
+	for range maxSyncAttempts {
+		cur, err := s.Get(ctx, userID, m.ID)
+		if m.Op == model.BulkDelete && errors.Is(err, storage.ErrNotFound) {
+			// Deleted already, on the server or by an earlier push.
+			return SyncItem{Resolution: model.SyncApplied}, nil
+		}
+		if err != nil {
+			return SyncItem{}, err
+		}
+		item, err := s.settle(ctx, userID, strategy, m, cur)
+		if !errors.Is(err, ErrPreconditionFailed) {
+			return item, err
+		}
+	}
+	return SyncItem{}, ErrPreconditionFailed
+}
+
+// pushCreate applies the create m. One with a ClientID that userID pushed
+// before returns the task it made then, as it is now, so that a push
+// retried after its response was lost does not create the task twice.
+func (s *Tasks) pushCreate(ctx context.Context, userID string, m model.SyncMutation) (SyncItem, error) {
+	if m.ClientID == "" || s.ClientIDs == nil {
+		t, err := s.Create(ctx, userID, *m.Task)
+		if err != nil {
+			return SyncItem{}, err
+		}
+		return SyncItem{Resolution: model.SyncApplied, Task: &t}, nil
+	}
+
+	rec := storage.IdempotencyRecord{UserID: userID, Key: syncClientKey(ctx, m.ClientID), CreatedAt: time.Now().UTC()}
+	prev, claimed, err := s.ClientIDs.ClaimIdempotencyKey(ctx, rec)
+	if err != nil {
+		return SyncItem{}, err
+	}
+	if !claimed {
+		if !prev.Done {
+			// The first push is still creating it.
+			return SyncItem{}, storage.ErrConflict
+		}
+		t, err := s.Get(ctx, userID, string(prev.Body))
+		if err != nil {
+			return SyncItem{}, err
+		}
+		return SyncItem{Resolution: model.SyncApplied, Task: &t}, nil
+	}
+
+	t, err := s.Create(ctx, userID, *m.Task)
+	if err != nil {
+		if err := s.ClientIDs.ReleaseIdempotencyKey(ctx, userID, rec.Key); err != nil {
+			slog.WarnContext(ctx, "releasing sync client_id", "user_id", userID, "err", err)
+		}
+		return SyncItem{}, err
+	}
+	rec.Fingerprint, rec.Body = model.CommandSync, []byte(t.ID)
+	if err := s.ClientIDs.FinishIdempotencyKey(ctx, rec); err != nil {
+		slog.WarnContext(ctx, "remembering sync client_id", "user_id", userID, "task_id", t.ID, "err", err)
+	}
+	return SyncItem{Resolution: model.SyncApplied, Task: &t}, nil
+}
+
+// syncClientKey is the key under which ClientIDs holds the task created for
+// clientID in the request's organization. It is kept apart from the
+// Idempotency-Key headers the same user sends.
+func syncClientKey(ctx context.Context, clientID string) string {
+	return model.CommandSync + ":" + orgOf(ctx) + ":" + clientID
+}
+
+// settle applies the update or delete m to cur, the task as it is now. It
+// returns ErrPreconditionFailed if the task changes meanwhile.
+func (s *Tasks) settle(ctx context.Context, userID string, strategy model.SyncStrategy, m model.SyncMutation, cur model.Task) (SyncItem, error) {
+	ifMatch := []int64{cur.Version}
+	if cur.Version == m.BaseVersion {
+		if m.Op == model.BulkDelete {
+			return SyncItem{Resolution: model.SyncApplied}, s.Delete(ctx, userID, cur.ID, ReparentChildren)
+		}
+		t, err := s.Update(ctx, userID, cur.ID, ifMatch, m.Patch.Apply)
+		if err != nil {
+			return SyncItem{}, err
+		}
+		return SyncItem{Resolution: model.SyncApplied, Task: &t}, nil
+	}
+
+	base, err := s.History.GetRevision(ctx, cur.ID, m.BaseVersion)
+	if errors.Is(err, storage.ErrNotFound) || err == nil && m.BaseVersion > cur.Version {
+		var v model.ValidationError
+		v.Add("base_version", "is not a version of the task")
+		return SyncItem{}, v.Err()
+	}
+	if err != nil {
+		return SyncItem{}, err
+	}
+	theirs := changedFields(base.Task, cur)
+	later := strategy == model.SyncLastWriteWins && !cur.UpdatedAt.After(*m.ChangedAt)
+
+	if m.Op == model.BulkDelete {
+		// Deleting conflicts with every change made on the server.
+		if !later {
+			return SyncItem{Resolution: model.SyncRejected, Task: &cur, Conflicts: theirs}, nil
+		}
+		if err := s.Delete(ctx, userID, cur.ID, ReparentChildren); err != nil {
+			return SyncItem{}, err
+		}
+		return SyncItem{Resolution: model.SyncMerged, Conflicts: theirs}, nil
+	}
+
+	mine := base.Task
+	m.Patch.Apply(&mine)
+	ours := changedFields(base.Task, mine)
+	var conflicts, apply []string
+	now, wanted := taskFields(cur), taskFields(mine)
+	for _, name := range ours {
+		if slices.Contains(theirs, name) && !bytes.Equal(now[name], wanted[name]) {
+			conflicts = append(conflicts, name)
+			if !later {
+				continue
+			}
+		}
+		if strategy == model.SyncMerge || later {
+			apply = append(apply, name)
+		}
+	}
+	if len(apply) == 0 {
+		return SyncItem{Resolution: model.SyncRejected, Task: &cur, Conflicts: conflicts}, nil
+	}
+	t, err := s.Update(ctx, userID, cur.ID, ifMatch, func(t *model.Task) { overlay(t, mine, apply) })
+	if err != nil {
+		return SyncItem{}, err
+	}
+	return SyncItem{Resolution: model.SyncMerged, Task: &t, Conflicts: conflicts}, nil
+}
+
+// fieldPrefix names a custom field among the fields of a task, so that
+// changes to different custom fields do not conflict.
+const fieldPrefix = "fields."
+
+// taskFields splits the JSON encoding of t into its members, with one
+// member per custom field, named with fieldPrefix, in place of fields.
+func taskFields(t model.Task) map[string]json.RawMessage {
+	m := fields(t)
+	delete(m, "fields")
+	for id, v := range t.Fields {
+		m[fieldPrefix+id] = jsonValue(v)
+	}
+	return m
+}
+
+// derivedFields are the members of a task that follow from others, and
+// are never changed on their own.
+var derivedFields = []string{"description_html", "updated_at", "version"}
+
+// changedFields returns the members of taskFields that differ between old
+// and new, in name order.
+func changedFields(old, new model.Task) []string {
+	before, after := taskFields(old), taskFields(new)
+	var names []string
+	for name := range before {
+		if !slices.Contains(derivedFields, name) && !bytes.Equal(before[name], after[name]) {
+			names = append(names, name)
+		}
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// overlay copies the members names of taskFields from src onto t.
+func overlay(t *model.Task, src model.Task, names []string) {
+	dst, from := fields(*t), fields(src)
+	values := maps.Clone(t.Fields)
+	for _, name := range names {
+		if id, ok := strings.CutPrefix(name, fieldPrefix); ok {
+			if v, ok := src.Fields[id]; ok {
+				values[id] = v
+			} else {
+				delete(values, id)
+			}
+			continue
+		}
+		dst[name] = from[name]
+	}
+	delete(dst, "fields")
+	b, err := json.Marshal(dst)
+	if err != nil {
+		panic(fmt.Sprintf("service: encoding task: %v", err))
+	}
+	var out model.Task
+	if err := json.Unmarshal(b, &out); err != nil {
+		panic(fmt.Sprintf("service: decoding task: %v", err))
+	}
+	out.Fields = values
+	*t = out
+}
+
+// validatePush checks the shape of every mutation before any of them is
+// applied.
+func validatePush(in model.SyncPushInput) error {
+	var v model.ValidationError
+	if in.Strategy != model.SyncMerge && in.Strategy != model.SyncLastWriteWins {
+		v.Add("strategy", "must be merge or last_write_wins")
+	}
+	switch n := len(in.Mutations); {
+	case n == 0:
+		v.Add("mutations", "is required")
+	case n > model.MaxSyncMutations:
+		v.Add("mutations", fmt.Sprintf("must hold at most %d mutations", model.MaxSyncMutations))
+	}
+	for i, m := range in.Mutations {
+		field := fmt.Sprintf("mutations[%d]", i)
+		switch m.Op {
+		case model.BulkCreate:
+			if m.Task == nil {
+				v.Add(field+".task", "is required")
+			}
+			if len(m.ClientID) > maxClientIDLen {
+				v.Add(field+".client_id", fmt.Sprintf("must be at most %d characters", maxClientIDLen))
+			}
+			continue
+		case model.BulkUpdate:
+			if m.Patch == nil {
+				v.Add(field+".patch", "is required")
+			}
+		case model.BulkDelete:
+		default:
+			v.Add(field+".op", "must be create, update or delete")
+			continue
+		}
+		if m.ID == "" {
+			v.Add(field+".id", "is required")
+		}
+		if m.BaseVersion <= 0 {
+			v.Add(field+".base_version", "is required")
+		}
+		if in.Strategy == model.SyncLastWriteWins && m.ChangedAt == nil {
+			v.Add(field+".changed_at", "is required with last_write_wins")
+		}
+	}
+	return v.Err()
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"starttech-server/model"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestPushSettles(t *testing.T) {
+	hour := time.Hour
+	tests := []struct {
+		name     string
+		strategy model.SyncStrategy
+		// server is applied to the task on the server after the client
+		// read version 1 of it; nil leaves it at version 1.
+		server func(*model.Task)
+		op     model.BulkOp
+		patch  model.TaskPatch
+		// changedAt is when the client made its change, relative to now.
+		changedAt time.Duration
+
+		resolution model.SyncResolution
+		conflicts  []string
+		title      string
+		priority   model.Priority
+		deleted    bool
+	}{
+		{
+			name:       "unchanged task",
+			strategy:   model.SyncMerge,
+			op:         model.BulkUpdate,
+			patch:      model.TaskPatch{Title: ptr("mine")},
+			resolution: model.SyncApplied,
+			title:      "mine", priority: model.PriorityNone,
+		},
+		{
+			name:       "merge of different fields",
+			strategy:   model.SyncMerge,
+			server:     func(t *model.Task) { t.Priority = model.PriorityHigh },
+			op:         model.BulkUpdate,
+			patch:      model.TaskPatch{Title: ptr("mine")},
+			resolution: model.SyncMerged,
+			title:      "mine", priority: model.PriorityHigh,
+		},
+		{
+			name:       "merge keeps the server's value of a field both changed",
+			strategy:   model.SyncMerge,
+			server:     func(t *model.Task) { t.Title = "theirs" },
+			op:         model.BulkUpdate,
+			patch:      model.TaskPatch{Title: ptr("mine"), Priority: ptr(model.PriorityLow)},
+			resolution: model.SyncMerged,
+			conflicts:  []string{"title"},
+			title:      "theirs", priority: model.PriorityLow,
+		},
+		{
+			name:       "merge of the same value is no conflict",
+			strategy:   model.SyncMerge,
+			server:     func(t *model.Task) { t.Title = "same" },
+			op:         model.BulkUpdate,
+			patch:      model.TaskPatch{Title: ptr("same")},
+			resolution: model.SyncMerged,
+			title:      "same", priority: model.PriorityNone,
+		},
+		{
+			name:       "merge with nothing left to apply",
+			strategy:   model.SyncMerge,
+			server:     func(t *model.Task) { t.Title = "theirs" },
+			op:         model.BulkUpdate,
+			patch:      model.TaskPatch{Title: ptr("mine")},
+			resolution: model.SyncRejected,
+			conflicts:  []string{"title"},
+			title:      "theirs", priority: model.PriorityNone,
+		},
+		{
+			name:       "merged delete loses to a change",
+			strategy:   model.SyncMerge,
+			server:     func(t *model.Task) { t.Title = "theirs" },
+			op:         model.BulkDelete,
+			resolution: model.SyncRejected,
+			conflicts:  []string{"title"},
+			title:      "theirs", priority: model.PriorityNone,
+		},
+		{
+			name:       "later write wins",
+			strategy:   model.SyncLastWriteWins,
+			server:     func(t *model.Task) { t.Title = "theirs"; t.Priority = model.PriorityHigh },
+			op:         model.BulkUpdate,
+			patch:      model.TaskPatch{Title: ptr("mine")},
+			changedAt:  hour,
+			resolution: model.SyncMerged,
+			conflicts:  []string{"title"},
+			title:      "mine", priority: model.PriorityHigh,
+		},
+		{
+			name:       "earlier write loses whole",
+			strategy:   model.SyncLastWriteWins,
+			server:     func(t *model.Task) { t.Title = "theirs" },
+			op:         model.BulkUpdate,
+			patch:      model.TaskPatch{Title: ptr("mine"), Priority: ptr(model.PriorityLow)},
+			changedAt:  -hour,
+			resolution: model.SyncRejected,
+			conflicts:  []string{"title"},
+			title:      "theirs", priority: model.PriorityNone,
+		},
+		{
+			name:       "later delete wins",
+			strategy:   model.SyncLastWriteWins,
+			server:     func(t *model.Task) { t.Title = "theirs" },
+			op:         model.BulkDelete,
+			changedAt:  hour,
+			resolution: model.SyncMerged,
+			conflicts:  []string{"title"},
+			deleted:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ctx := newTestTasks(t)
+			task := mustCreate(t, s, ctx, "original")
+			if tt.server != nil {
+				if _, err := s.Update(ctx, "u1", task.ID, nil, tt.server); err != nil {
+					t.Fatal(err)
+				}
+			}
+			m := model.SyncMutation{Op: tt.op, ID: task.ID, BaseVersion: task.Version, ChangedAt: ptr(time.Now().Add(tt.changedAt))}
+			if tt.op == model.BulkUpdate {
+				m.Patch = &tt.patch
+			}
+			items, err := s.Push(ctx, "u1", model.SyncPushInput{Strategy: tt.strategy, Mutations: []model.SyncMutation{m}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := items[0]
+			if got.Err != nil || got.Resolution != tt.resolution || !reflect.DeepEqual(got.Conflicts, tt.conflicts) {
+				t.Errorf("got %s with conflicts %v, %v; want %s with %v", got.Resolution, got.Conflicts, got.Err, tt.resolution, tt.conflicts)
+			}
+
+			now, err := s.Get(ctx, "u1", task.ID)
+			if tt.deleted {
+				if err == nil {
+					t.Error("the task was not deleted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if now.Title != tt.title || now.Priority != tt.priority {
+				t.Errorf("task is %q at priority %q, want %q at %q", now.Title, now.Priority, tt.title, tt.priority)
+			}
+		})
+	}
+}
+
+func TestPushRetriedCreate(t *testing.T) {
+	s, ctx := newTestTasks(t)
+	in := model.SyncPushInput{Mutations: []model.SyncMutation{
+		{Op: model.BulkCreate, ClientID: "local-1", Task: &model.TaskInput{Title: "once"}},
+		{Op: model.BulkCreate, Task: &model.TaskInput{Title: "no client id"}},
+	}}
+	var ids [][2]string
+	for range 2 {
+		items, err := s.Push(ctx, "u1", in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range items {
+			if item.Err != nil || item.Resolution != model.SyncApplied {
+				t.Fatalf("got %s, %v", item.Resolution, item.Err)
+			}
+		}
+		ids = append(ids, [2]string{items[0].Task.ID, items[1].Task.ID})
+	}
+	if ids[0][0] != ids[1][0] {
+		t.Errorf("the create with a client_id made tasks %s and %s", ids[0][0], ids[1][0])
+	}
+	if ids[0][1] == ids[1][1] {
+		t.Error("the create without a client_id was not repeated")
+	}
+
+	// The same client_id is another user's own.
+	other, err := s.Push(ctx, "u2", model.SyncPushInput{Mutations: in.Mutations[:1]})
+	if err != nil || other[0].Err != nil || other[0].Task.ID == ids[0][0] {
+		t.Errorf("u2 pushing local-1 got %+v, %v", other, err)
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Tags manages the labels a user attaches to tasks. Each organization the
+// user works in has its own set. Like Tasks, it reports other users' tags as
+// storage.ErrNotFound.
+type Tags struct {
+	Store storage.TagStore
+}
+
+// List returns every tag owned by userID, ordered by name.
+func (s *Tags) List(ctx context.Context, userID string) ([]model.Tag, error) {
+	return s.Store.ListTags(ctx, orgOf(ctx), userID)
+}
+
+// Get returns the tag with the given id if userID owns it.
+func (s *Tags) Get(ctx context.Context, userID, id string) (model.Tag, error) {
+	t, err := s.Store.GetTag(ctx, id)
+	if err != nil {
+		return model.Tag{}, err
+	}
+	if t.OwnerID != userID || t.OrgID != orgOf(ctx) {
+		return model.Tag{}, storage.ErrNotFound
+	}
+	return t, nil
+}
+
+// Create validates in and stores it as a new tag owned by userID. A name the
+// user already has yields storage.ErrConflict.
+func (s *Tags) Create(ctx context.Context, userID string, in model.TagInput) (model.Tag, error) {
+	t := model.Tag{OrgID: orgOf(ctx), OwnerID: userID, CreatedAt: time.Now().UTC()}
+	in.Apply(&t)
+	if err := t.Validate(); err != nil {
+		return model.Tag{}, err
+	}
+	if err := s.Store.CreateTag(ctx, &t); err != nil {
+		return model.Tag{}, err
+	}
+	return t, nil
+}
+
+// Update applies p to the tag with the given id if userID owns it.
+func (s *Tags) Update(ctx context.Context, userID, id string, p model.TagPatch) (model.Tag, error) {
+	t, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return model.Tag{}, err
+	}
+	p.Apply(&t)
+	if err := t.Validate(); err != nil {
+		return model.Tag{}, err
+	}
+	if err := s.Store.UpdateTag(ctx, &t); err != nil {
+		return model.Tag{}, err
+	}
+	return t, nil
+}
+
+// Delete removes the tag with the given id from userID's account and from
+// every task that carried it.
+func (s *Tags) Delete(ctx context.Context, userID, id string) error {
+	if _, err := s.Get(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.Store.DeleteTag(ctx, id)
+}
@@ -0,0 +1,466 @@
+// Package service holds the business rules shared by every API transport.
+// Handlers translate requests into service calls; the services decide what
+// the caller may see and change, then delegate persistence to storage.
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/outbox"
+	"starttech-server/storage"
+	"starttech-server/tracing"
+)
+
+// ErrPreconditionFailed is returned when an update names versions of a task
+// and the current one is not among them.
+var ErrPreconditionFailed = errors.New("service: the task has changed")
+
+// Tasks manages tasks on behalf of an authenticated user, within the
+// organization of the request. Tasks outside any project are private to
+// their owner; tasks in a project are governed by the
+// caller's role there. A task the caller cannot see is reported as
+// storage.ErrNotFound so its existence is not leaked, and one they can see
+// but not change as ErrForbidden.
+type Tasks struct {
+	Store storage.TaskStore
+	// History reads the revisions Store keeps of each task.
+	History storage.RevisionStore
+	// Tags resolves the tag IDs attached to tasks.
+	Tags storage.TagStore
+	// Projects resolves the project a task belongs to.
+	Projects storage.ProjectStore
+	// Reminders receives the reminders implied by remind_at and due_date.
+	// It may be nil.
+	Reminders storage.ReminderStore
+	// Comments holds the discussion on each task.
+	Comments storage.CommentStore
+	// Mentions records the users comments mention.
+	Mentions storage.MentionStore
+	// Inbox holds the notifications of assignments, mentions and
+	// comments.
+	Inbox storage.NotificationStore
+	// Time holds the time logged on tasks.
+	Time storage.TimeStore
+	// Deps records which tasks block which.
+	Deps storage.DependencyStore
+	// Checklists holds the checklist items of tasks.
+	Checklists storage.ChecklistStore
+	// Watchers records who follows which tasks.
+	Watchers storage.WatcherStore
+	// CustomFields defines the custom fields whose values tasks hold.
+	CustomFields storage.FieldStore
+	// Automations holds the projects' automation rules, which
+	// EvaluateRules runs. It may be nil if EvaluateRules is never called.
+	Automations storage.RuleStore
+	// Users gives the timezones recurring tasks repeat in. It may be nil,
+	// in which case they repeat in UTC.
+	Users storage.UserStore
+	// BlockCompletion refuses to complete a task while a task blocking it
+	// is open.
+	BlockCompletion bool
+	// MaxProjectTasks caps the tasks of each project, trashed ones
+	// included, with a QuotaError. Zero leaves them uncapped.
+	MaxProjectTasks int
+	// Index finds tasks and comments by their text.
+	Index storage.SearchStore
+	// Attachments removes the files of deleted tasks. It may be nil.
+	Attachments *Attachments
+	// Log records who changed what. It may be nil.
+	Log storage.ActivityStore
+	// Commands keeps each change to tasks for UndoWindow after it is made,
+	// so that Undo can revert it. It may be nil, and a zero UndoWindow
+	// turns undo off.
+	Commands   storage.CommandStore
+	UndoWindow time.Duration
+	// Tx runs bulk operations in a single transaction.
+	Tx storage.Transactor
+	// ClientIDs remembers the task each create pushed with a client_id
+	// made, so that pushing it again returns that task instead of
+	// creating another. It may be nil.
+	ClientIDs storage.IdempotencyStore
+
+	// moveMu serialises Move so concurrent drags compute positions from
+	// the same ordering.
+	moveMu sync.Mutex
+	// Events receives a notification after every successful mutation. It
+	// may be nil.
+	Events events.Publisher
+	// Outbox, if set, delivers the events of changes to tasks in place of
+	// Events: each change runs in a transaction that writes its events to
+	// the store's outbox, so that none is lost if the process stops before
+	// they are published.
+	Outbox *outbox.Relay
+}
+
+func (s *Tasks) publish(ctx context.Context, typ events.Type, to []string, data any) {
+	if s.Events == nil {
+		return
+	}
+	s.Events.Publish(events.Event{
+		Type:        typ,
+		Time:        time.Now().UTC(),
+		Data:        data,
+		OrgID:       orgOf(ctx),
+		Recipients:  to,
+		TraceParent: tracing.FromContext(ctx).TraceParent(),
+	})
+}
+
+// audience returns the users told about changes to t.
+func (s *Tasks) audience(ctx context.Context, t model.Task) []string {
+	return audience(ctx, s.Projects, t.OwnerID, t.ProjectID)
+}
+
+// Page size bounds for List.
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// Zone returns the timezone of the user with the given id, in which their
+// bare dates are read, or UTC if it cannot be read.
+func (s *Tasks) Zone(ctx context.Context, userID string) *time.Location {
+	return zone(ctx, s.Users, userID)
+}
+
+// List returns one page of the tasks visible to userID that match f. f.Offset
+// is taken from cursor, which must be empty or a NextCursor from a previous
+// page. f.AssigneeID may be AssigneeMe or AssigneeNone. f.Fields holds the
+// values of custom fields as query text, which List parses by the type of
+// their field. Archived tasks are left out unless f.Archived or f.Trashed
+// asks for them.
+func (s *Tasks) List(ctx context.Context, userID string, f storage.TaskFilter, cursor string) (model.TaskPage, error) {
+	f.OrgID = orgOf(ctx)
+	f.VisibleTo = userID
+	scopeAssignee(&f, userID)
+	if f.Archived == nil && !f.Trashed {
+		f.Archived = new(bool)
+	}
+	switch {
+	case f.Limit <= 0:
+		f.Limit = DefaultPageSize
+	case f.Limit > MaxPageSize:
+		f.Limit = MaxPageSize
+	}
+	if err := s.resolveFields(ctx, userID, &f); err != nil {
+		return model.TaskPage{}, err
+	}
+	if cursor != "" {
+		offset, err := decodeCursor(cursor)
+		if err != nil {
+			return model.TaskPage{}, err
+		}
+		f.Offset = offset
+	}
+
+	// Fetch one extra row to learn whether another page exists.
+	limit := f.Limit
+	f.Limit++
+	tasks, err := s.Store.ListTasks(ctx, f)
+	if err != nil {
+		return model.TaskPage{}, err
+	}
+	page := model.TaskPage{Items: tasks}
+	if len(tasks) > limit {
+		page.Items = tasks[:limit]
+		page.NextCursor = encodeCursor(f.Offset + limit)
+	}
+	return page, nil
+}
+
+// Get returns the task with the given id if userID may see it.
+func (s *Tasks) Get(ctx context.Context, userID, id string) (model.Task, error) {
+	return s.authorize(ctx, userID, id, model.RoleViewer)
+}
+
+// authorize returns the task with the given id if userID holds at least role
+// need on it. Tasks in the trash are reported as storage.ErrNotFound.
+func (s *Tasks) authorize(ctx context.Context, userID, id string, need model.Role) (model.Task, error) {
+	return s.load(ctx, userID, id, need, false)
+}
+
+// load is authorize for a task that must be in the trash if trashed is set,
+// and out of it otherwise.
+func (s *Tasks) load(ctx context.Context, userID, id string, need model.Role, trashed bool) (model.Task, error) {
+	t, err := s.Store.GetTask(ctx, id)
+	if err != nil {
+		return model.Task{}, err
+	}
+	if t.OrgID != orgOf(ctx) || (t.DeletedAt != nil) != trashed {
+		return model.Task{}, storage.ErrNotFound
+	}
+	role, err := s.roleOn(ctx, userID, t)
+	if err != nil {
+		return model.Task{}, err
+	}
+	if err := require(role, need); err != nil {
+		return model.Task{}, err
+	}
+	return t, nil
+}
+
+// roleOn returns userID's role in t's project, or owner if t is their own
+// task outside any project.
+func (s *Tasks) roleOn(ctx context.Context, userID string, t model.Task) (model.Role, error) {
+	if t.ProjectID == nil {
+		if t.OwnerID != userID {
+			return "", storage.ErrNotFound
+		}
+		return model.RoleOwner, nil
+	}
+	m, err := s.Projects.GetMember(ctx, *t.ProjectID, userID)
+	if err != nil {
+		return "", err
+	}
+	return m.Role, nil
+}
+
+// Create validates in and stores it as a new task owned by userID.
+func (s *Tasks) Create(ctx context.Context, userID string, in model.TaskInput) (t model.Task, err error) {
+	err = s.undoable(ctx, userID, events.TaskCreated, func(ctx context.Context, tx *Tasks) error {
+		t, err = tx.create(ctx, userID, in)
+		return err
+	})
+	return t, err
+}
+
+func (s *Tasks) create(ctx context.Context, userID string, in model.TaskInput) (model.Task, error) {
+	now := time.Now().UTC()
+	t := model.Task{OrgID: orgOf(ctx), OwnerID: userID, CreatedAt: now, UpdatedAt: now}
+	in.Apply(&t)
+	w, err := s.workflow(ctx, userID, t.ProjectID)
+	if err != nil {
+		return model.Task{}, err
+	}
+	w.Conform(&t)
+	if err := t.ValidateIn(w); err != nil {
+		return model.Task{}, err
+	}
+	normalizeRecurrence(&t)
+	if err := s.checkParent(ctx, userID, &t); err != nil {
+		return model.Task{}, err
+	}
+	if err := s.checkTags(ctx, userID, t.TagIDs); err != nil {
+		return model.Task{}, err
+	}
+	if err := s.checkFields(ctx, &t, nil); err != nil {
+		return model.Task{}, err
+	}
+	if err := s.placeInProject(ctx, userID, &t); err != nil {
+		return model.Task{}, err
+	}
+	if err := s.checkAssignee(ctx, t); err != nil {
+		return model.Task{}, err
+	}
+	if err := s.Store.CreateTask(ctx, &t); err != nil {
+		return model.Task{}, err
+	}
+	noteSaved(ctx, 0, t)
+	if err := s.scheduleReminders(ctx, t); err != nil {
+		return model.Task{}, err
+	}
+	s.publish(ctx, events.TaskCreated, s.audience(ctx, t), t)
+	s.notifyAssignee(ctx, userID, t)
+	s.record(ctx, userID, events.TaskCreated, t, "", nil)
+	t.PossibleDuplicates = s.duplicates(ctx, t)
+	return t, nil
+}
+
+// Update applies mutate to the task with the given id if userID may edit it.
+// The result is validated against its project's workflow before it is saved.
+// A non-nil ifMatch lists the versions the caller based the change on; if
+// the task is at none of them, or changes before it is saved, the update
+// fails with ErrPreconditionFailed.
+func (s *Tasks) Update(ctx context.Context, userID, id string, ifMatch []int64, mutate func(*model.Task)) (t model.Task, err error) {
+	err = s.undoable(ctx, userID, events.TaskUpdated, func(ctx context.Context, tx *Tasks) error {
+		t, err = tx.update(ctx, userID, id, ifMatch, mutate)
+		return err
+	})
+	return t, err
+}
+
+func (s *Tasks) update(ctx context.Context, userID, id string, ifMatch []int64, mutate func(*model.Task)) (model.Task, error) {
+	t, err := s.authorize(ctx, userID, id, model.RoleEditor)
+	if err != nil {
+		return model.Task{}, err
+	}
+	if ifMatch != nil && !slices.Contains(ifMatch, t.Version) {
+		return model.Task{}, ErrPreconditionFailed
+	}
+	old := t
+	mutate(&t)
+	w, err := s.workflow(ctx, userID, t.ProjectID)
+	if err != nil {
+		return model.Task{}, err
+	}
+	// A status the edit did not touch may be missing from the workflow
+	// after a change of project; start over in the matching column.
+	if t.Status == old.Status && !w.Has(t.Status) {
+		t.Status = w.Initial(t.Completed)
+	}
+	w.Conform(&t)
+	if err := t.ValidateIn(w); err != nil {
+		return model.Task{}, err
+	}
+	if !sameID(old.ParentID, t.ParentID) {
+		if err := s.checkParent(ctx, userID, &t); err != nil {
+			return model.Task{}, err
+		}
+	}
+	if !slices.Equal(old.TagIDs, t.TagIDs) {
+		// Tags other members attached earlier stay; only the caller's
+		// additions must be their own.
+		if err := s.checkTags(ctx, userID, added(old.TagIDs, t.TagIDs)); err != nil {
+			return model.Task{}, err
+		}
+	}
+	if err := s.checkFields(ctx, &t, old.Fields); err != nil {
+		return model.Task{}, err
+	}
+	if !sameID(old.ProjectID, t.ProjectID) {
+		if err := s.placeInProject(ctx, userID, &t); err != nil {
+			return model.Task{}, err
+		}
+	}
+	// The assignee must still see the task after a change of project.
+	if !sameID(old.AssigneeID, t.AssigneeID) || !sameID(old.ProjectID, t.ProjectID) {
+		if err := s.checkAssignee(ctx, t); err != nil {
+			return model.Task{}, err
+		}
+	}
+	if err := s.checkCompletion(ctx, old, t); err != nil {
+		return model.Task{}, err
+	}
+	normalizeRecurrence(&t)
+	t.UpdatedAt = time.Now().UTC()
+	next := recur(&old, &t, t.UpdatedAt, zone(ctx, s.Users, t.OwnerID))
+	if err := s.Store.UpdateTask(ctx, &t); err != nil {
+		if ifMatch != nil && errors.Is(err, storage.ErrStale) {
+			err = ErrPreconditionFailed
+		}
+		return model.Task{}, err
+	}
+	noteSaved(ctx, old.Version, t)
+	if !sameTime(old.RemindAt, t.RemindAt) || !sameTime(old.DueDate, t.DueDate) {
+		if err := s.scheduleReminders(ctx, t); err != nil {
+			return model.Task{}, err
+		}
+	}
+	s.publish(ctx, events.TaskUpdated, s.audience(ctx, t), t)
+	if !sameID(old.AssigneeID, t.AssigneeID) {
+		s.notifyAssignee(ctx, userID, t)
+	}
+	s.recordUpdate(ctx, userID, old, t)
+	if next != nil {
+		if _, err := s.Create(ctx, userID, *next); err != nil {
+			return model.Task{}, err
+		}
+	}
+	return t, nil
+}
+
+// Delete moves the task with the given id to the trash if userID may edit
+// it. Its subtasks are handled according to children.
+func (s *Tasks) Delete(ctx context.Context, userID, id string, children ChildPolicy) error {
+	return s.undoable(ctx, userID, events.TaskDeleted, func(ctx context.Context, tx *Tasks) error {
+		t, err := tx.authorize(ctx, userID, id, model.RoleEditor)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		if err := tx.detachChildren(ctx, userID, t, children, now); err != nil {
+			return err
+		}
+		if err := tx.trash(ctx, &t, now); err != nil {
+			return err
+		}
+		tx.publish(ctx, events.TaskDeleted, tx.audience(ctx, t), events.Deleted{ID: id})
+		tx.record(ctx, userID, events.TaskDeleted, t, "", nil)
+		return nil
+	})
+}
+
+// recordUpdate logs the fields userID changed from old to t and tells the
+// task's watchers. A task that left its project stays in that project's
+// feed for this last entry.
+func (s *Tasks) recordUpdate(ctx context.Context, userID string, old, t model.Task) {
+	changes := diff(old, t)
+	if len(changes) == 0 {
+		return
+	}
+	s.notifyWatchers(ctx, userID, t)
+	if t.ProjectID == nil {
+		t.ProjectID = old.ProjectID
+	}
+	s.record(ctx, userID, events.TaskUpdated, t, "", changes)
+}
+
+// trash marks t as deleted at the given time.
+func (s *Tasks) trash(ctx context.Context, t *model.Task, at time.Time) error {
+	before := t.Version
+	t.DeletedAt = &at
+	t.UpdatedAt = at
+	if err := s.Store.UpdateTask(ctx, t); err != nil {
+		return err
+	}
+	noteSaved(ctx, before, *t)
+	return nil
+}
+
+// deleteTask removes one task from the store, along with its attachments.
+func (s *Tasks) deleteTask(ctx context.Context, id string) error {
+	if s.Attachments == nil {
+		return s.Store.DeleteTask(ctx, id)
+	}
+	return s.Attachments.deleteTask(ctx, s.Store, id)
+}
+
+// checkTags verifies that every tag in ids belongs to userID in the
+// request's organization.
+func (s *Tasks) checkTags(ctx context.Context, userID string, ids []string) error {
+	var v model.ValidationError
+	for _, id := range ids {
+		tag, err := s.Tags.GetTag(ctx, id)
+		if errors.Is(err, storage.ErrNotFound) || err == nil && (tag.OwnerID != userID || tag.OrgID != orgOf(ctx)) {
+			v.Add("tag_ids", "unknown tag "+id)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return v.Err()
+}
+
+// scheduleReminders replaces the pending reminders of t; changing a time
+// re-arms a reminder that has already been sent.
+func (s *Tasks) scheduleReminders(ctx context.Context, t model.Task) error {
+	if s.Reminders == nil {
+		return nil
+	}
+	return s.Reminders.ScheduleReminders(ctx, t.ID, t.Reminders())
+}
+
+// added returns the IDs in next that are not in prev.
+func added(prev, next []string) []string {
+	var out []string
+	for _, id := range next {
+		if !slices.Contains(prev, id) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Templates saves tasks and projects as templates and makes new ones from
+// them. Like tags, templates are private to their owner within an
+// organization; other users' templates are reported as
+// storage.ErrNotFound.
+type Templates struct {
+	Store    storage.TemplateStore
+	Tasks    *Tasks
+	Projects *Projects
+}
+
+// List returns every template owned by userID, ordered by name.
+func (s *Templates) List(ctx context.Context, userID string) ([]model.Template, error) {
+	return s.Store.ListTemplates(ctx, orgOf(ctx), userID)
+}
+
+// Get returns the template with the given id if userID owns it.
+func (s *Templates) Get(ctx context.Context, userID, id string) (model.Template, error) {
+	t, err := s.Store.GetTemplate(ctx, id)
+	if err != nil {
+		return model.Template{}, err
+	}
+	if t.OwnerID != userID || t.OrgID != orgOf(ctx) {
+		return model.Template{}, storage.ErrNotFound
+	}
+	return t, nil
+}
+
+// Update applies p to the template with the given id if userID owns it.
+func (s *Templates) Update(ctx context.Context, userID, id string, p model.TemplatePatch) (model.Template, error) {
+	t, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return model.Template{}, err
+	}
+	if p.Name != nil {
+		t.Name = strings.TrimSpace(*p.Name)
+	}
+	if err := t.Validate(); err != nil {
+		return model.Template{}, err
+	}
+	t.UpdatedAt = time.Now().UTC()
+	if err := s.Store.UpdateTemplate(ctx, &t); err != nil {
+		return model.Template{}, err
+	}
+	return t, nil
+}
+
+// Delete removes the template with the given id if userID owns it.
+func (s *Templates) Delete(ctx context.Context, userID, id string) error {
+	if _, err := s.Get(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.Store.DeleteTemplate(ctx, id)
+}
+
+// SaveTask saves the task with the given id, which userID must be able to
+// see, and its subtasks as a new template.
+func (s *Templates) SaveTask(ctx context.Context, userID, id string, in model.TemplateInput) (model.Template, error) {
+	t, err := s.Tasks.Get(ctx, userID, id)
+	if err != nil {
+		return model.Template{}, err
+	}
+	below, err := s.Tasks.descendants(ctx, t)
+	if err != nil {
+		return model.Template{}, err
+	}
+	w := model.DefaultWorkflow
+	if t.ProjectID != nil {
+		p, err := s.Tasks.Projects.GetProject(ctx, *t.ProjectID)
+		if err != nil {
+			return model.Template{}, err
+		}
+		w = p.Statuses
+	}
+	return s.save(ctx, userID, in, model.TemplateKindTask, nil, append([]model.Task{t}, below...), w)
+}
+
+// SaveProject saves the project with the given id, which userID must be a
+// member of, as a new template: its columns and every task outside the
+// trash.
+func (s *Templates) SaveProject(ctx context.Context, userID, id string, in model.TemplateInput) (model.Template, error) {
+	p, err := s.Projects.Get(ctx, userID, id)
+	if err != nil {
+		return model.Template{}, err
+	}
+	tasks, err := s.Tasks.Store.ListTasks(ctx, storage.TaskFilter{
+		OrgID:     p.OrgID,
+		ProjectID: p.ID,
+		Sort:      storage.Sort{Field: storage.SortPosition},
+	})
+	if err != nil {
+		return model.Template{}, err
+	}
+	project := &model.TemplateProject{Name: p.Name, Description: p.Description, Statuses: p.Statuses}
+	return s.save(ctx, userID, in, model.TemplateKindProject, project, parentsFirst(tasks), p.Statuses)
+}
+
+// save stores tasks, parents before their subtasks, as a template. Done
+// tasks are saved as open in the first open column of w.
+func (s *Templates) save(ctx context.Context, userID string, in model.TemplateInput, kind model.TemplateKind, project *model.TemplateProject, tasks []model.Task, w model.Workflow) (model.Template, error) {
+	now := time.Now().UTC()
+	tpl := model.Template{
+		OrgID:     orgOf(ctx),
+		OwnerID:   userID,
+		Name:      strings.TrimSpace(in.Name),
+		Kind:      kind,
+		Project:   project,
+		Tasks:     make([]model.TemplateTask, 0, len(tasks)),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	day := now.Truncate(24 * time.Hour)
+	refs := make(map[string]string, len(tasks))
+	tagNames := map[string]string{}
+	for i, t := range tasks {
+		ref := strconv.Itoa(i + 1)
+		refs[t.ID] = ref
+		d := model.TemplateTask{
+			Ref:         ref,
+			Title:       t.Title,
+			Description: t.Description,
+			Status:      t.Status,
+			Priority:    t.Priority,
+			Recurrence:  t.Recurrence,
+			Tags:        []string{},
+		}
+		if t.ParentID != nil {
+			d.ParentRef = refs[*t.ParentID]
+		}
+		if t.Completed {
+			d.Status = w.Initial(false)
+		}
+		d.SetDue(t.DueDate, day)
+		for _, id := range t.TagIDs {
+			name, ok := tagNames[id]
+			if !ok {
+				tag, err := s.Tasks.Tags.GetTag(ctx, id)
+				if err != nil && !errors.Is(err, storage.ErrNotFound) {
+					return model.Template{}, err
+				}
+				name = tag.Name
+				tagNames[id] = name
+			}
+			if name != "" {
+				d.Tags = append(d.Tags, name)
+			}
+		}
+		tpl.Tasks = append(tpl.Tasks, d)
+	}
+	if err := tpl.Validate(); err != nil {
+		return model.Template{}, err
+	}
+	if err := s.Store.CreateTemplate(ctx, &tpl); err != nil {
+		return model.Template{}, err
+	}
+	return tpl, nil
+}
+
+// parentsFirst orders tasks so that every task comes after its parent,
+// keeping their order otherwise.
+func parentsFirst(tasks []model.Task) []model.Task {
+	in := make(map[string]bool, len(tasks))
+	for _, t := range tasks {
+		in[t.ID] = true
+	}
+	out := make([]model.Task, 0, len(tasks))
+	done := make(map[string]bool, len(tasks))
+	for progress := true; progress; {
+		progress = false
+		for _, t := range tasks {
+			if !done[t.ID] && (t.ParentID == nil || !in[*t.ParentID] || done[*t.ParentID]) {
+				out = append(out, t)
+				done[t.ID] = true
+				progress = true
+			}
+		}
+	}
+	return out
+}
+
+// Use makes what the template with the given id describes, for userID, in
+// a single transaction: a project for a project template, and then its
+// tasks. Tags are found by name among userID's own and created when they
+// have none of that name.
+func (s *Templates) Use(ctx context.Context, userID, id string, u model.TemplateUse) (model.TemplateResult, error) {
+	tpl, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return model.TemplateResult{}, err
+	}
+	if err := u.Validate(); err != nil {
+		return model.TemplateResult{}, err
+	}
+	start := time.Now().UTC().Truncate(24 * time.Hour)
+	if u.Start != nil {
+		start = u.Start.UTC().Truncate(24 * time.Hour)
+	}
+	vars := map[string]string{"date": start.Format(time.DateOnly)}
+	for k, v := range u.Variables {
+		vars[k] = v
+	}
+
+	// New tasks go to the end of their column, so they must not race a
+	// Move.
+	s.Tasks.moveMu.Lock()
+	defer s.Tasks.moveMu.Unlock()
+
+	var (
+		taskEvents, projectEvents events.Buffer
+		res                       = model.TemplateResult{Tasks: []model.Task{}}
+	)
+	err = s.Tasks.Tx.InTx(ctx, func(tx storage.Store) error {
+		tasks := s.Tasks.within(tx, &taskEvents)
+		projectID := idOrNil(u.ProjectID)
+		if tpl.Kind == model.TemplateKindProject && tpl.Project != nil {
+			name := strings.TrimSpace(u.Name)
+			if name == "" {
+				name = tpl.Project.Name
+			}
+			name = model.FillPlaceholders(name, vars)
+			if _, ok := u.Variables["project"]; !ok {
+				vars["project"] = name
+			}
+			p, err := s.Projects.within(tx, &projectEvents).Create(ctx, userID, model.ProjectInput{
+				Name:        name,
+				Description: model.FillPlaceholders(tpl.Project.Description, vars),
+				Statuses:    tpl.Project.Statuses,
+			})
+			if err != nil {
+				return err
+			}
+			res.Project, projectID = &p, &p.ID
+		}
+		w, err := tasks.workflow(ctx, userID, projectID)
+		if err != nil {
+			return err
+		}
+		tags, err := templateTags(ctx, tx, userID, tpl.Tasks)
+		if err != nil {
+			return err
+		}
+		created := make(map[string]string, len(tpl.Tasks))
+		for _, d := range tpl.Tasks {
+			in := model.TaskInput{
+				Title:       model.FillPlaceholders(d.Title, vars),
+				Description: model.FillPlaceholders(d.Description, vars),
+				Priority:    d.Priority,
+				DueDate:     d.DueOn(start),
+				Recurrence:  d.Recurrence,
+				ProjectID:   projectID,
+			}
+			// Columns the target board lacks fall back to its first.
+			if w.Has(d.Status) {
+				in.Status = d.Status
+			}
+			if parent, ok := created[d.ParentRef]; ok {
+				in.ParentID = &parent
+			}
+			for _, name := range d.Tags {
+				in.TagIDs = append(in.TagIDs, tags[strings.ToLower(name)])
+			}
+			t, err := tasks.Create(ctx, userID, in)
+			if err != nil {
+				return err
+			}
+			created[d.Ref] = t.ID
+			res.Tasks = append(res.Tasks, t)
+		}
+		return s.Tasks.stage(ctx, tx, &taskEvents)
+	})
+	if err != nil {
+		return model.TemplateResult{}, err
+	}
+	projectEvents.Flush(s.Projects.Events)
+	s.Tasks.flush(&taskEvents)
+	return res, nil
+}
+
+// templateTags maps the lowercased names of the tags of defs to the IDs of
+// userID's tags, creating those they lack.
+func templateTags(ctx context.Context, tx storage.Store, userID string, defs []model.TemplateTask) (map[string]string, error) {
+	owned, err := tx.ListTags(ctx, orgOf(ctx), userID)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string, len(owned))
+	for _, t := range owned {
+		tags[strings.ToLower(t.Name)] = t.ID
+	}
+	for _, d := range defs {
+		for _, name := range d.Tags {
+			key := strings.ToLower(name)
+			if _, ok := tags[key]; ok {
+				continue
+			}
+			t := model.Tag{OrgID: orgOf(ctx), OwnerID: userID, Name: name, CreatedAt: time.Now().UTC()}
+			if err := t.Validate(); err != nil {
+				return nil, err
+			}
+			if err := tx.CreateTag(ctx, &t); err != nil {
+				return nil, err
+			}
+			tags[key] = t.ID
+		}
+	}
+	return tags, nil
+}
+
+func idOrNil(id *string) *string {
+	if id == nil || strings.TrimSpace(*id) == "" {
+		return nil
+	}
+	return id
+}
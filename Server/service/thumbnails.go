@@ -0,0 +1,120 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+
+	"starttech-server/blob"
+	"starttech-server/model"
+	"starttech-server/storage"
+	"starttech-server/thumb"
+)
+
+var (
+	// ErrNoThumbnail is returned for thumbnails of attachments that are
+	// not images thumbnails can be made of.
+	ErrNoThumbnail = errors.New("service: only images have thumbnails")
+	// ErrThumbnailPending is returned for thumbnails that have not been
+	// made yet.
+	ErrThumbnailPending = errors.New("service: the thumbnail is being made")
+)
+
+// thumbKey is the blob key of the thumbnail of a that is width pixels wide.
+func thumbKey(a model.Attachment, width int) string {
+	return "thumbnails/" + a.Key + "/" + strconv.Itoa(width)
+}
+
+// thumbnail queues making the thumbnails of a, if it is a clean image.
+// Thumbnails missing when asked for are queued again, so failing here is
+// only logged.
+func (s *Attachments) thumbnail(ctx context.Context, a model.Attachment) {
+	if s.EnqueueThumbnails == nil || a.Status != model.AttachmentClean || !thumb.Supported(a.ContentType) {
+		return
+	}
+	if err := s.EnqueueThumbnails(ctx, a.ID); err != nil {
+		slog.WarnContext(ctx, "queueing thumbnails", "attachment_id", a.ID, "err", err)
+	}
+}
+
+// MakeThumbnails makes the thumbnails of the image attachment with the
+// given id in every width, for the job thumbnail queued. Images that cannot
+// be decoded, or are too large, are logged and get none; attachments
+// deleted since are left be.
+func (s *Attachments) MakeThumbnails(ctx context.Context, id string) error {
+	a, err := s.Store.GetAttachment(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if a.Status != model.AttachmentClean || !thumb.Supported(a.ContentType) {
+		return nil
+	}
+	rc, err := s.Blobs.Open(ctx, a.Key)
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return err
+	}
+	thumbs, err := thumb.Make(data, a.ContentType)
+	if err != nil {
+		slog.WarnContext(ctx, "making thumbnails", "attachment_id", a.ID, "err", err)
+		return nil
+	}
+	ct := thumb.ContentType(a.ContentType)
+	for width, b := range thumbs {
+		if err := s.Blobs.Put(ctx, thumbKey(a, width), bytes.NewReader(b), int64(len(b)), ct); err != nil {
+			return err
+		}
+	}
+	// The attachment may have been deleted while they were made, after
+	// its blobs were removed.
+	if _, err := s.Store.GetAttachment(ctx, id); errors.Is(err, storage.ErrNotFound) {
+		s.removeBlobs(ctx, []model.Attachment{a})
+	}
+	return nil
+}
+
+// OpenThumbnail returns the attachment the thumbnail link issued by sign
+// points to, and its thumbnail that best fits width, as thumb.Fit picks.
+// The thumbnail is of type thumb.ContentType(a.ContentType). One that has
+// not been made yet is queued, and ErrThumbnailPending returned.
+func (s *Attachments) OpenThumbnail(ctx context.Context, id string, width int, expires, signature string) (model.Attachment, io.ReadCloser, error) {
+	if err := s.verify(id, expires, signature); err != nil {
+		return model.Attachment{}, nil, err
+	}
+	a, err := s.Store.GetAttachment(ctx, id)
+	if err != nil {
+		return model.Attachment{}, nil, err
+	}
+	if err := downloadable(a); err != nil {
+		return model.Attachment{}, nil, err
+	}
+	if !thumb.Supported(a.ContentType) || s.EnqueueThumbnails == nil {
+		return model.Attachment{}, nil, ErrNoThumbnail
+	}
+	rc, err := s.Blobs.Open(ctx, thumbKey(a, thumb.Fit(width)))
+	if errors.Is(err, blob.ErrNotFound) {
+		// Made before thumbnails were, restored from a backup, or
+		// queued when the queue could not be reached.
+		if err := s.EnqueueThumbnails(ctx, a.ID); err != nil {
+			return model.Attachment{}, nil, err
+		}
+		return model.Attachment{}, nil, ErrThumbnailPending
+	}
+	if err != nil {
+		return model.Attachment{}, nil, err
+	}
+	return a, rc, nil
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// ErrNoTimer is returned when stopping a timer the caller is not running.
+var ErrNoTimer = errors.New("service: no timer is running on the task")
+
+// StartTimer starts a timer for userID on the task with the given id, which
+// they must be able to edit. A timer they are running on another task is
+// stopped first; if it already runs on this one, it is returned as is and
+// started is false.
+func (s *Tasks) StartTimer(ctx context.Context, userID, id string) (e model.TimeEntry, started bool, err error) {
+	t, err := s.authorize(ctx, userID, id, model.RoleEditor)
+	if err != nil {
+		return model.TimeEntry{}, false, err
+	}
+	now := time.Now().UTC()
+	running, err := s.Time.ListTimeEntries(ctx, storage.TimeEntryFilter{UserID: userID, Running: true})
+	if err != nil {
+		return model.TimeEntry{}, false, err
+	}
+	for _, r := range running {
+		if r.TaskID == t.ID {
+			return withSeconds(r, now), false, nil
+		}
+		r.EndedAt = &now
+		if err := s.Time.UpdateTimeEntry(ctx, &r); err != nil {
+			return model.TimeEntry{}, false, err
+		}
+	}
+	e = model.TimeEntry{OrgID: t.OrgID, TaskID: t.ID, ProjectID: t.ProjectID, UserID: userID, StartedAt: now, CreatedAt: now}
+	if err := s.Time.CreateTimeEntry(ctx, &e); err != nil {
+		return model.TimeEntry{}, false, err
+	}
+	return e, true, nil
+}
+
+// StopTimer stops the timer userID runs on the task with the given id,
+// failing with ErrNoTimer if there is none.
+func (s *Tasks) StopTimer(ctx context.Context, userID, id string) (model.TimeEntry, error) {
+	if _, err := s.authorize(ctx, userID, id, model.RoleViewer); err != nil {
+		return model.TimeEntry{}, err
+	}
+	running, err := s.Time.ListTimeEntries(ctx, storage.TimeEntryFilter{TaskID: id, UserID: userID, Running: true})
+	if err != nil {
+		return model.TimeEntry{}, err
+	}
+	if len(running) == 0 {
+		return model.TimeEntry{}, ErrNoTimer
+	}
+	e := running[0]
+	now := time.Now().UTC()
+	e.EndedAt = &now
+	if err := s.Time.UpdateTimeEntry(ctx, &e); err != nil {
+		return model.TimeEntry{}, err
+	}
+	return withSeconds(e, now), nil
+}
+
+// RunningTimer returns the timer userID is running, in any organization, or
+// storage.ErrNotFound.
+func (s *Tasks) RunningTimer(ctx context.Context, userID string) (model.TimeEntry, error) {
+	running, err := s.Time.ListTimeEntries(ctx, storage.TimeEntryFilter{UserID: userID, Running: true})
+	if err != nil {
+		return model.TimeEntry{}, err
+	}
+	if len(running) == 0 {
+		return model.TimeEntry{}, storage.ErrNotFound
+	}
+	return withSeconds(running[0], time.Now().UTC()), nil
+}
+
+// ListTimeEntries lists the time logged on the task with the given id by
+// everyone, oldest first.
+func (s *Tasks) ListTimeEntries(ctx context.Context, userID, id string) ([]model.TimeEntry, error) {
+	if _, err := s.authorize(ctx, userID, id, model.RoleViewer); err != nil {
+		return nil, err
+	}
+	entries, err := s.Time.ListTimeEntries(ctx, storage.TimeEntryFilter{TaskID: id})
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	for i := range entries {
+		entries[i] = withSeconds(entries[i], now)
+	}
+	return entries, nil
+}
+
+// LogTime records time userID spent on the task with the given id, which
+// they must be able to edit, after the fact.
+func (s *Tasks) LogTime(ctx context.Context, userID, id string, in model.TimeEntryInput) (model.TimeEntry, error) {
+	t, err := s.authorize(ctx, userID, id, model.RoleEditor)
+	if err != nil {
+		return model.TimeEntry{}, err
+	}
+	now := time.Now().UTC()
+	e := model.TimeEntry{OrgID: t.OrgID, TaskID: t.ID, ProjectID: t.ProjectID, UserID: userID, CreatedAt: now}
+	in.Apply(&e)
+	if e.EndedAt == nil {
+		var v model.ValidationError
+		v.Add("ended_at", "is required")
+		return model.TimeEntry{}, v.Err()
+	}
+	if err := e.Validate(); err != nil {
+		return model.TimeEntry{}, err
+	}
+	if err := s.Time.CreateTimeEntry(ctx, &e); err != nil {
+		return model.TimeEntry{}, err
+	}
+	return withSeconds(e, now), nil
+}
+
+// EditTimeEntry changes an entry on the task with the given id. Only the
+// user who logged it may.
+func (s *Tasks) EditTimeEntry(ctx context.Context, userID, id, entryID string, p model.TimeEntryPatch) (model.TimeEntry, error) {
+	e, err := s.ownTimeEntry(ctx, userID, id, entryID)
+	if err != nil {
+		return model.TimeEntry{}, err
+	}
+	p.Apply(&e)
+	if err := e.Validate(); err != nil {
+		return model.TimeEntry{}, err
+	}
+	if err := s.Time.UpdateTimeEntry(ctx, &e); err != nil {
+		return model.TimeEntry{}, err
+	}
+	return withSeconds(e, time.Now().UTC()), nil
+}
+
+// DeleteTimeEntry removes an entry on the task with the given id. Only the
+// user who logged it may.
+func (s *Tasks) DeleteTimeEntry(ctx context.Context, userID, id, entryID string) error {
+	if _, err := s.ownTimeEntry(ctx, userID, id, entryID); err != nil {
+		return err
+	}
+	return s.Time.DeleteTimeEntry(ctx, entryID)
+}
+
+// ownTimeEntry returns the entry with the given entryID on the task with
+// the given id if userID logged it.
+func (s *Tasks) ownTimeEntry(ctx context.Context, userID, id, entryID string) (model.TimeEntry, error) {
+	if _, err := s.authorize(ctx, userID, id, model.RoleViewer); err != nil {
+		return model.TimeEntry{}, err
+	}
+	e, err := s.Time.GetTimeEntry(ctx, entryID)
+	if err != nil {
+		return model.TimeEntry{}, err
+	}
+	if e.TaskID != id {
+		return model.TimeEntry{}, storage.ErrNotFound
+	}
+	if e.UserID != userID {
+		return model.TimeEntry{}, ErrForbidden
+	}
+	return e, nil
+}
+
+// TimeTotals sums the time logged on the tasks userID can see in the
+// request's organization, grouped by model.TimeByTask, TimeByProject or
+// TimeByUser. f narrows the entries; its From and To clip them, so only the
+// part of an entry inside the span counts.
+func (s *Tasks) TimeTotals(ctx context.Context, userID, by string, f storage.TimeEntryFilter) (model.TimeTotals, error) {
+	var v model.ValidationError
+	switch by {
+	case "":
+		by = model.TimeByTask
+	case model.TimeByTask, model.TimeByProject, model.TimeByUser:
+	default:
+		v.Add("by", "must be task, project or user")
+	}
+	if f.From != nil && f.To != nil && !f.To.After(*f.From) {
+		v.Add("to", "must be after from")
+	}
+	if err := v.Err(); err != nil {
+		return model.TimeTotals{}, err
+	}
+	f.OrgID = orgOf(ctx)
+	f.VisibleTo = userID
+	entries, err := s.Time.ListTimeEntries(ctx, f)
+	if err != nil {
+		return model.TimeTotals{}, err
+	}
+
+	now := time.Now().UTC()
+	totals := model.TimeTotals{By: by, From: f.From, To: f.To, Groups: []model.TimeTotal{}}
+	index := map[string]int{}
+	for _, e := range entries {
+		var key *string
+		switch by {
+		case model.TimeByTask:
+			key = &e.TaskID
+		case model.TimeByProject:
+			key = e.ProjectID
+		case model.TimeByUser:
+			key = &e.UserID
+		}
+		k := ""
+		if key != nil {
+			k = *key
+		}
+		i, ok := index[k]
+		if !ok {
+			i = len(totals.Groups)
+			index[k] = i
+			totals.Groups = append(totals.Groups, model.TimeTotal{ID: key})
+		}
+		secs := int64(e.Overlap(f.From, f.To, now) / time.Second)
+		totals.Groups[i].Seconds += secs
+		totals.Groups[i].Entries++
+		totals.Seconds += secs
+	}
+	slices.SortFunc(totals.Groups, func(a, b model.TimeTotal) int {
+		if c := cmp.Compare(b.Seconds, a.Seconds); c != 0 {
+			return c
+		}
+		return cmp.Compare(deref(a.ID), deref(b.ID))
+	})
+	return totals, nil
+}
+
+// withSeconds fills in the length of e as of now.
+func withSeconds(e model.TimeEntry, now time.Time) model.TimeEntry {
+	e.Seconds = int64(e.Overlap(nil, nil, now) / time.Second)
+	return e
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Trash lists one page of the deleted tasks userID can see, most recently
+// deleted first unless f asks for another order.
+func (s *Tasks) Trash(ctx context.Context, userID string, f storage.TaskFilter, cursor string) (model.TaskPage, error) {
+	f.Trashed = true
+	if f.Sort == (storage.Sort{}) {
+		f.Sort = storage.Sort{Field: storage.SortDeletedAt, Desc: true}
+	}
+	return s.List(ctx, userID, f, cursor)
+}
+
+// Restore takes the task with the given id out of the trash if userID may
+// edit it, together with the subtasks deleted along with it. A task whose
+// parent is still in the trash, or gone, comes back at the top level.
+func (s *Tasks) Restore(ctx context.Context, userID, id string) (t model.Task, err error) {
+	err = s.undoable(ctx, userID, events.TaskRestored, func(ctx context.Context, tx *Tasks) error {
+		t, err = tx.restore(ctx, userID, id)
+		return err
+	})
+	return t, err
+}
+
+func (s *Tasks) restore(ctx context.Context, userID, id string) (model.Task, error) {
+	t, err := s.load(ctx, userID, id, model.RoleEditor, true)
+	if err != nil {
+		return model.Task{}, err
+	}
+	below, err := s.trashedWith(ctx, t)
+	if err != nil {
+		return model.Task{}, err
+	}
+	if err := s.untrash(ctx, userID, &t); err != nil {
+		return model.Task{}, err
+	}
+	for _, c := range below {
+		if err := s.untrash(ctx, userID, &c); err != nil {
+			return model.Task{}, fmt.Errorf("restoring subtask %s: %w", c.ID, err)
+		}
+	}
+	return t, nil
+}
+
+// trashedWith returns the subtasks below t, at every depth, that were
+// deleted at the same time as t.
+func (s *Tasks) trashedWith(ctx context.Context, t model.Task) ([]model.Task, error) {
+	var out []model.Task
+	queue := []string{t.ID}
+	for len(queue) > 0 {
+		children, err := s.Store.ListTasks(ctx, storage.TaskFilter{ParentID: queue[0], Trashed: true})
+		if err != nil {
+			return nil, err
+		}
+		queue = queue[1:]
+		for _, c := range children {
+			if sameTime(c.DeletedAt, t.DeletedAt) {
+				out = append(out, c)
+				queue = append(queue, c.ID)
+			}
+		}
+	}
+	return out, nil
+}
+
+// untrash clears t's deletion. A parent that is still in the trash, or gone,
+// is dropped, and a status that has left the project's workflow in the
+// meantime starts over in the matching column.
+func (s *Tasks) untrash(ctx context.Context, userID string, t *model.Task) error {
+	w, err := s.workflow(ctx, userID, t.ProjectID)
+	if err != nil {
+		return err
+	}
+	old := *t
+	if t.ParentID != nil {
+		parent, err := s.Store.GetTask(ctx, *t.ParentID)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		if err != nil || parent.DeletedAt != nil {
+			t.ParentID = nil
+		}
+	}
+	if !w.Has(t.Status) {
+		t.Status = w.Initial(t.Completed)
+	}
+	w.Conform(t)
+	t.DeletedAt = nil
+	t.UpdatedAt = time.Now().UTC()
+	if err := s.Store.UpdateTask(ctx, t); err != nil {
+		return err
+	}
+	noteSaved(ctx, old.Version, *t)
+	s.publish(ctx, events.TaskRestored, s.audience(ctx, *t), *t)
+	s.record(ctx, userID, events.TaskRestored, *t, "", diff(old, *t))
+	return nil
+}
+
+// purgeBatch bounds the tasks PurgeTrash loads at once.
+const purgeBatch = 100
+
+// PurgeTrash permanently deletes every task that went to the trash before
+// the given time, with its comments and attachments, and returns how many
+// there were.
+func (s *Tasks) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	n := 0
+	for {
+		tasks, err := s.Store.ListTasks(ctx, storage.TaskFilter{Trashed: true, DeletedBefore: &before, Limit: purgeBatch})
+		if err != nil {
+			return n, err
+		}
+		for _, t := range tasks {
+			if err := s.deleteTask(ctx, t.ID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				return n, fmt.Errorf("purging task %s: %w", t.ID, err)
+			}
+			n++
+		}
+		if len(tasks) < purgeBatch {
+			return n, nil
+		}
+	}
+}
+
+// PurgeableTrash counts the tasks PurgeTrash would delete.
+func (s *Tasks) PurgeableTrash(ctx context.Context, before time.Time) (int, error) {
+	return s.Store.CountTasks(ctx, storage.TaskFilter{Trashed: true, DeletedBefore: &before})
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"starttech-server/auth"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+var (
+	// ErrInvalidTwoFactorCode is returned for a code that is neither the
+	// authenticator app's current one nor an unused recovery code.
+	ErrInvalidTwoFactorCode = errors.New("service: the two-factor code is wrong or has been used")
+	// ErrTwoFactorSetupRequired is returned when an organization requires
+	// two-factor authentication of a member who has not set it up.
+	ErrTwoFactorSetupRequired = errors.New("service: the organization requires two-factor authentication")
+)
+
+// TwoFactorChallengeTTL is how long a sign-in may wait for its second
+// factor.
+const TwoFactorChallengeTTL = 5 * time.Minute
+
+const purposeTwoFactor = "2fa"
+
+// recoveryAlphabet leaves out letters and digits that are easily confused.
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// TwoFactor manages users' authenticator apps and recovery codes, and
+// checks the second factor of sign-ins. Secrets are sealed with Key, which
+// must be 32 bytes, before they are stored.
+type TwoFactor struct {
+	Store storage.TwoFactorStore
+	Users storage.UserStore
+	// Tokens signs the challenges that carry a sign-in from the password
+	// to the code.
+	Tokens *auth.Issuer
+	Key    []byte
+	// Issuer names the server in authenticator apps.
+	Issuer string
+}
+
+// Status reports whether userID has two-factor authentication enabled.
+func (s *TwoFactor) Status(ctx context.Context, userID string) (model.TwoFactorStatus, error) {
+	tf, err := s.Store.GetTwoFactor(ctx, userID)
+	if errors.Is(err, storage.ErrNotFound) || err == nil && tf.EnabledAt == nil {
+		return model.TwoFactorStatus{}, nil
+	}
+	if err != nil {
+		return model.TwoFactorStatus{}, err
+	}
+	n, err := s.Store.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return model.TwoFactorStatus{}, err
+	}
+	return model.TwoFactorStatus{Enabled: true, EnabledAt: tf.EnabledAt, RecoveryCodesLeft: n}, nil
+}
+
+// Enabled reports whether sign-ins of userID need a second factor.
+func (s *TwoFactor) Enabled(ctx context.Context, userID string) (bool, error) {
+	tf, err := s.Store.GetTwoFactor(ctx, userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil && tf.EnabledAt != nil, err
+}
+
+// Setup gives userID a new secret for their authenticator app, replacing
+// one they have not yet confirmed. It returns storage.ErrConflict if
+// two-factor authentication is already enabled.
+func (s *TwoFactor) Setup(ctx context.Context, userID string) (model.TwoFactorSetup, error) {
+	u, err := s.Users.GetUser(ctx, userID)
+	if err != nil {
+		return model.TwoFactorSetup{}, err
+	}
+	if on, err := s.Enabled(ctx, userID); err != nil || on {
+		if on {
+			err = storage.ErrConflict
+		}
+		return model.TwoFactorSetup{}, err
+	}
+	secret := auth.NewTOTPSecret()
+	sealed, err := seal(s.Key, secret)
+	if err != nil {
+		return model.TwoFactorSetup{}, err
+	}
+	tf := model.TwoFactor{UserID: userID, Secret: sealed, CreatedAt: time.Now().UTC()}
+	if err := s.Store.SaveTwoFactor(ctx, tf); err != nil {
+		return model.TwoFactorSetup{}, err
+	}
+	return model.TwoFactorSetup{Secret: secret, URI: auth.TOTPURI(s.Issuer, u.Email, secret)}, nil
+}
+
+// Enable turns on two-factor authentication for userID once code shows
+// their authenticator app has the secret from Setup, and returns their
+// first recovery codes. It returns storage.ErrNotFound if Setup was not
+// called, and storage.ErrConflict if it is already on.
+func (s *TwoFactor) Enable(ctx context.Context, userID, code string) (model.RecoveryCodes, error) {
+	tf, err := s.Store.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return model.RecoveryCodes{}, err
+	}
+	if tf.EnabledAt != nil {
+		return model.RecoveryCodes{}, storage.ErrConflict
+	}
+	if err := s.checkTOTP(ctx, tf, code); err != nil {
+		return model.RecoveryCodes{}, err
+	}
+	codes, err := s.newRecoveryCodes(ctx, userID)
+	if err != nil {
+		return model.RecoveryCodes{}, err
+	}
+	// Re-read, so as not to undo the step checkTOTP recorded.
+	if tf, err = s.Store.GetTwoFactor(ctx, userID); err != nil {
+		return model.RecoveryCodes{}, err
+	}
+	now := time.Now().UTC()
+	tf.EnabledAt = &now
+	if err := s.Store.SaveTwoFactor(ctx, tf); err != nil {
+		return model.RecoveryCodes{}, err
+	}
+	return codes, nil
+}
+
+// Disable turns off two-factor authentication for userID, given a code.
+func (s *TwoFactor) Disable(ctx context.Context, userID, code string) error {
+	if err := s.Check(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.Store.DeleteTwoFactor(ctx, userID)
+}
+
+// RegenerateRecoveryCodes replaces userID's recovery codes, given a code.
+func (s *TwoFactor) RegenerateRecoveryCodes(ctx context.Context, userID, code string) (model.RecoveryCodes, error) {
+	if err := s.Check(ctx, userID, code); err != nil {
+		return model.RecoveryCodes{}, err
+	}
+	return s.newRecoveryCodes(ctx, userID)
+}
+
+// Check accepts the authenticator app's current code or one of userID's
+// recovery codes, which is then used up. It returns
+// ErrInvalidTwoFactorCode otherwise, including when two-factor
+// authentication is off.
+func (s *TwoFactor) Check(ctx context.Context, userID, code string) error {
+	tf, err := s.Store.GetTwoFactor(ctx, userID)
+	if errors.Is(err, storage.ErrNotFound) || err == nil && tf.EnabledAt == nil {
+		return ErrInvalidTwoFactorCode
+	}
+	if err != nil {
+		return err
+	}
+	code = strings.TrimSpace(code)
+	if len(code) == 6 && strings.Trim(code, "0123456789") == "" {
+		return s.checkTOTP(ctx, tf, code)
+	}
+	err = s.Store.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(code)))
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrInvalidTwoFactorCode
+	}
+	return err
+}
+
+// Challenge returns the token that lets u finish signing in with a code.
+// It is tied to u's password, so changing the password voids it.
+func (s *TwoFactor) Challenge(u model.User) (string, error) {
+	return s.Tokens.IssueAction(purposeTwoFactor, u.ID, u.PasswordHash, TwoFactorChallengeTTL)
+}
+
+// Verify returns the user a challenge was issued to if code is good.
+func (s *TwoFactor) Verify(ctx context.Context, challenge, code string) (model.User, error) {
+	var u model.User
+	_, err := s.Tokens.VerifyAction(challenge, purposeTwoFactor, func(userID string) (string, error) {
+		var err error
+		u, err = s.Users.GetUser(ctx, userID)
+		return u.PasswordHash, err
+	})
+	if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, storage.ErrNotFound) {
+		return model.User{}, ErrInvalidToken
+	}
+	if err != nil {
+		return model.User{}, err
+	}
+	if err := s.Check(ctx, u.ID, code); err != nil {
+		return model.User{}, err
+	}
+	return u, nil
+}
+
+func (s *TwoFactor) checkTOTP(ctx context.Context, tf model.TwoFactor, code string) error {
+	secret, err := unseal(s.Key, tf.Secret)
+	if err != nil {
+		return err
+	}
+	step, ok := auth.CheckTOTP(secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	err = s.Store.UseTOTPStep(ctx, tf.UserID, step)
+	if errors.Is(err, storage.ErrConflict) {
+		return ErrInvalidTwoFactorCode
+	}
+	return err
+}
+
+// newRecoveryCodes makes and stores a fresh set of recovery codes for
+// userID, in the form xxxxx-xxxxx.
+func (s *TwoFactor) newRecoveryCodes(ctx context.Context, userID string) (model.RecoveryCodes, error) {
+	codes := make([]string, model.RecoveryCodeCount)
+	hashes := make([]string, len(codes))
+	for i := range codes {
+		b := make([]byte, 10)
+		rand.Read(b)
+		for j := range b {
+			b[j] = recoveryAlphabet[int(b[j])%len(recoveryAlphabet)]
+		}
+		codes[i] = string(b[:5]) + "-" + string(b[5:])
+		hashes[i] = hashToken(normalizeRecoveryCode(codes[i]))
+	}
+	if err := s.Store.SetRecoveryCodes(ctx, userID, hashes); err != nil {
+		return model.RecoveryCodes{}, err
+	}
+	return model.RecoveryCodes{Codes: codes}, nil
+}
+
+// normalizeRecoveryCode accepts a code typed with or without its dash,
+// spaces or capitals.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// ErrUndoConflict is returned by Undo when a task the change touched has
+// changed again since, so reverting it would lose the later change.
+var ErrUndoConflict = errors.New("service: a task has changed since; the change can no longer be undone")
+
+// journal collects the tasks saved by one undoable change.
+type journal struct {
+	mu    sync.Mutex
+	steps []model.CommandStep
+}
+
+type journalKey struct{}
+
+// startJournal returns a context whose saves are noted in a new journal,
+// and the journal. It returns ctx as it is, and no journal, when undo is
+// off or ctx already belongs to a change being journaled, which the saves
+// then count towards.
+func (s *Tasks) startJournal(ctx context.Context) (context.Context, *journal) {
+	if s.Commands == nil || s.UndoWindow <= 0 || ctx.Value(journalKey{}) != nil {
+		return ctx, nil
+	}
+	j := &journal{}
+	return context.WithValue(ctx, journalKey{}, j), j
+}
+
+// noteSaved adds to the journal of ctx, if there is one, that t has been
+// saved from version before; zero stands for a task just created.
+func noteSaved(ctx context.Context, before int64, t model.Task) {
+	j, _ := ctx.Value(journalKey{}).(*journal)
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i := range j.steps {
+		if j.steps[i].TaskID == t.ID {
+			j.steps[i].After = t.Version
+			return
+		}
+	}
+	j.steps = append(j.steps, model.CommandStep{TaskID: t.ID, Before: before, After: t.Version})
+}
+
+// remember keeps what j noted as userID's command named action. A failure
+// is only logged: the change stands, but cannot be undone.
+func (s *Tasks) remember(ctx context.Context, userID, action string, j *journal) {
+	if j == nil || len(j.steps) == 0 {
+		return
+	}
+	c := model.Command{OrgID: orgOf(ctx), UserID: userID, Action: action, Steps: j.steps, CreatedAt: time.Now().UTC()}
+	if err := s.Commands.SaveCommand(ctx, &c); err != nil {
+		slog.WarnContext(ctx, "keeping a change to undo", "user_id", userID, "action", action, "err", err)
+	}
+}
+
+// undoable runs change, by userID, atomically, and keeps the tasks it saved
+// as a command named action for Undo, if it succeeds. change must work
+// through the Tasks it is given.
+func (s *Tasks) undoable(ctx context.Context, userID string, action events.Type, change func(context.Context, *Tasks) error) error {
+	ctx, j := s.startJournal(ctx)
+	if err := s.atomically(ctx, change); err != nil {
+		return err
+	}
+	s.remember(ctx, userID, string(action), j)
+	return nil
+}
+
+// Undo reverts the last change userID made to tasks in the caller's
+// organization, if it was made within UndoWindow, and forgets it, so that
+// undoing again reverts the change before. Every task the change saved is
+// put back as it was, in one transaction: tasks it created go to the
+// trash, tasks it deleted come back, and the others get their fields,
+// position and archived state back through Update. The reverted changes are
+// published and logged as changes by userID. Undo returns
+// storage.ErrNotFound when there is nothing to undo, and ErrUndoConflict
+// if one of the tasks is no longer as the change left it, in which case
+// nothing is reverted but the change is forgotten all the same.
+func (s *Tasks) Undo(ctx context.Context, userID string) (model.Undone, error) {
+	if s.Commands == nil || s.UndoWindow <= 0 {
+		return model.Undone{}, storage.ErrNotFound
+	}
+	c, err := s.Commands.LastCommand(ctx, orgOf(ctx), userID, time.Now().UTC().Add(-s.UndoWindow))
+	if err != nil {
+		return model.Undone{}, err
+	}
+	var pending events.Buffer
+	undone := model.Undone{Action: c.Action, CreatedAt: c.CreatedAt, Tasks: []model.Task{}}
+	err = s.Tx.InTx(ctx, func(tx storage.Store) error {
+		inner := s.within(tx, &pending)
+		for _, step := range slices.Backward(c.Steps) {
+			t, err := inner.undoStep(ctx, userID, step)
+			if err != nil {
+				return err
+			}
+			undone.Tasks = append(undone.Tasks, t)
+		}
+		if err := tx.DeleteCommand(ctx, c.ID); err != nil {
+			return err
+		}
+		return s.stage(ctx, tx, &pending)
+	})
+	if errors.Is(err, ErrUndoConflict) {
+		if err := s.Commands.DeleteCommand(ctx, c.ID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			slog.WarnContext(ctx, "forgetting a change that cannot be undone", "command_id", c.ID, "err", err)
+		}
+	}
+	if err != nil {
+		return model.Undone{}, err
+	}
+	s.flush(&pending)
+	slices.Reverse(undone.Tasks)
+	return undone, nil
+}
+
+// undoStep puts one task back as it was before step.
+func (s *Tasks) undoStep(ctx context.Context, userID string, step model.CommandStep) (model.Task, error) {
+	// The task may be in the trash or out of it.
+	t, err := s.Store.GetTask(ctx, step.TaskID)
+	if errors.Is(err, storage.ErrNotFound) || err == nil && t.OrgID != orgOf(ctx) {
+		return model.Task{}, ErrUndoConflict
+	}
+	if err != nil {
+		return model.Task{}, err
+	}
+	if t.Version != step.After {
+		// Undoing later changes saves the task again, without changing
+		// it from what this change left.
+		r, err := s.History.GetRevision(ctx, t.ID, step.After)
+		if err != nil {
+			return model.Task{}, err
+		}
+		if len(diff(r.Task, t)) > 0 {
+			return model.Task{}, ErrUndoConflict
+		}
+	}
+	role, err := s.roleOn(ctx, userID, t)
+	if err != nil {
+		return model.Task{}, err
+	}
+	if err := require(role, model.RoleEditor); err != nil {
+		return model.Task{}, err
+	}
+	if step.Before == 0 {
+		return t, s.undoCreate(ctx, userID, &t)
+	}
+	r, err := s.History.GetRevision(ctx, t.ID, step.Before)
+	if err != nil {
+		return model.Task{}, err
+	}
+	old := r.Task
+	switch {
+	case old.DeletedAt != nil && t.DeletedAt == nil:
+		// The change took the task out of the trash.
+		return t, s.undoCreate(ctx, userID, &t)
+	case old.DeletedAt == nil && t.DeletedAt != nil:
+		if err := s.untrash(ctx, userID, &t); err != nil {
+			return model.Task{}, err
+		}
+		// Going to the trash may have been all the change did.
+		if step.After == step.Before+1 {
+			return t, nil
+		}
+	}
+	mutate, err := s.reverting(ctx, old)
+	if err != nil {
+		return model.Task{}, err
+	}
+	return s.Update(ctx, userID, t.ID, nil, func(t *model.Task) {
+		mutate(t)
+		t.Position = old.Position
+		t.ArchivedAt = old.ArchivedAt
+	})
+}
+
+// undoCreate sends t, which the change being undone brought into being or
+// out of the trash, to the trash.
+func (s *Tasks) undoCreate(ctx context.Context, userID string, t *model.Task) error {
+	if err := s.trash(ctx, t, time.Now().UTC()); err != nil {
+		return err
+	}
+	s.publish(ctx, events.TaskDeleted, s.audience(ctx, *t), events.Deleted{ID: t.ID})
+	s.record(ctx, userID, events.TaskDeleted, *t, "", nil)
+	return nil
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Views manages the task filters users save by name. Each organization the
+// user works in has its own set, and like tags they are private: other
+// users' views are reported as storage.ErrNotFound.
+type Views struct {
+	Store storage.ViewStore
+	// Tasks runs the views.
+	Tasks *Tasks
+}
+
+// List returns every view owned by userID, ordered by name.
+func (s *Views) List(ctx context.Context, userID string) ([]model.View, error) {
+	return s.Store.ListViews(ctx, orgOf(ctx), userID)
+}
+
+// Get returns the view with the given id if userID owns it.
+func (s *Views) Get(ctx context.Context, userID, id string) (model.View, error) {
+	v, err := s.Store.GetView(ctx, id)
+	if err != nil {
+		return model.View{}, err
+	}
+	if v.OwnerID != userID || v.OrgID != orgOf(ctx) {
+		return model.View{}, storage.ErrNotFound
+	}
+	return v, nil
+}
+
+// Create validates in and stores it as a new view owned by userID. A name
+// the user already has yields storage.ErrConflict.
+func (s *Views) Create(ctx context.Context, userID string, in model.ViewInput) (model.View, error) {
+	now := time.Now().UTC()
+	v := model.View{OrgID: orgOf(ctx), OwnerID: userID, CreatedAt: now, UpdatedAt: now}
+	in.Apply(&v)
+	if err := v.Validate(storage.TaskSortKeys); err != nil {
+		return model.View{}, err
+	}
+	if err := s.Store.CreateView(ctx, &v); err != nil {
+		return model.View{}, err
+	}
+	return v, nil
+}
+
+// Update applies p to the view with the given id if userID owns it.
+func (s *Views) Update(ctx context.Context, userID, id string, p model.ViewPatch) (model.View, error) {
+	v, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return model.View{}, err
+	}
+	p.Apply(&v)
+	if err := v.Validate(storage.TaskSortKeys); err != nil {
+		return model.View{}, err
+	}
+	v.UpdatedAt = time.Now().UTC()
+	if err := s.Store.UpdateView(ctx, &v); err != nil {
+		return model.View{}, err
+	}
+	return v, nil
+}
+
+// Delete removes the view with the given id if userID owns it.
+func (s *Views) Delete(ctx context.Context, userID, id string) error {
+	if _, err := s.Get(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.Store.DeleteView(ctx, id)
+}
+
+// Run lists a page of the tasks the view with the given id selects, as
+// Tasks.List does for its filter. Due windows are taken relative to now,
+// with days starting at midnight in userID's timezone.
+func (s *Views) Run(ctx context.Context, userID, id string, limit int, cursor string) (model.TaskPage, error) {
+	v, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return model.TaskPage{}, err
+	}
+	f := viewTaskFilter(v.Filter, time.Now().In(zone(ctx, s.Tasks.Users, userID)))
+	f.Limit = limit
+	return s.Tasks.List(ctx, userID, f, cursor)
+}
+
+// viewTaskFilter turns f into the filter of a listing run at now. Days
+// start at midnight in now's location.
+func viewTaskFilter(f model.ViewFilter, now time.Time) storage.TaskFilter {
+	tf := storage.TaskFilter{
+		Status:     f.Status,
+		ProjectID:  f.ProjectID,
+		AssigneeID: f.Assignee,
+		TagIDs:     f.TagIDs,
+		DueBefore:  f.DueBefore,
+		DueAfter:   f.DueAfter,
+	}
+	if f.Sort != "" {
+		tf.Sort = storage.Sort{Field: strings.TrimPrefix(f.Sort, "-"), Desc: strings.HasPrefix(f.Sort, "-")}
+	}
+
+	// DueAfter is exclusive, so windows starting at midnight begin just
+	// before it.
+	y, m, d := now.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	start := midnight.Add(-time.Nanosecond).UTC()
+	switch f.Due {
+	case model.DueOverdue:
+		narrow(&tf, nil, now.UTC())
+	case model.DueToday:
+		narrow(&tf, &start, midnight.AddDate(0, 0, 1).UTC())
+	case model.DueWeek:
+		narrow(&tf, &start, midnight.AddDate(0, 0, 7).UTC())
+	}
+	return tf
+}
+
+// narrow limits the due dates tf selects to between after, if set, and
+// before.
+func narrow(tf *storage.TaskFilter, after *time.Time, before time.Time) {
+	if tf.DueBefore == nil || before.Before(*tf.DueBefore) {
+		tf.DueBefore = &before
+	}
+	if after != nil && (tf.DueAfter == nil || after.After(*tf.DueAfter)) {
+		tf.DueAfter = after
+	}
+}
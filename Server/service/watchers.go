@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"starttech-server/model"
+)
+
+// Watch makes userID a watcher of the task with the given id, so that they
+// are told of its changes and comments as its owner and assignee are.
+// Anyone who can see the task may watch it; watching it again is not an
+// error.
+func (s *Tasks) Watch(ctx context.Context, userID, id string) error {
+	if _, err := s.authorize(ctx, userID, id, model.RoleViewer); err != nil {
+		return err
+	}
+	return s.Watchers.AddWatcher(ctx, model.Watcher{TaskID: id, UserID: userID, CreatedAt: time.Now().UTC()})
+}
+
+// Unwatch stops userID watching the task with the given id. It returns
+// storage.ErrNotFound if they were not watching it.
+func (s *Tasks) Unwatch(ctx context.Context, userID, id string) error {
+	if _, err := s.authorize(ctx, userID, id, model.RoleViewer); err != nil {
+		return err
+	}
+	return s.Watchers.RemoveWatcher(ctx, id, userID)
+}
+
+// ListWatchers returns the watchers of the task with the given id, oldest
+// first.
+func (s *Tasks) ListWatchers(ctx context.Context, userID, id string) ([]model.Watcher, error) {
+	if _, err := s.authorize(ctx, userID, id, model.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.Watchers.ListWatchers(ctx, id)
+}
+
+// autoWatch makes userID a watcher of t because they took part in it, by
+// commenting or being mentioned. A failure is only logged: the comment
+// stands without it.
+func (s *Tasks) autoWatch(ctx context.Context, userID string, t model.Task) {
+	if err := s.Watchers.AddWatcher(ctx, model.Watcher{TaskID: t.ID, UserID: userID, CreatedAt: time.Now().UTC()}); err != nil {
+		slog.WarnContext(ctx, "watching a task", "task_id", t.ID, "user_id", userID, "err", err)
+	}
+}
+
+// watchers returns the IDs of the users watching t who can still see it.
+func (s *Tasks) watchers(ctx context.Context, t model.Task) []string {
+	watchers, err := s.Watchers.ListWatchers(ctx, t.ID)
+	if err != nil {
+		slog.WarnContext(ctx, "listing watchers", "task_id", t.ID, "err", err)
+		return nil
+	}
+	audience := s.audience(ctx, t)
+	var ids []string
+	for _, w := range watchers {
+		if slices.Contains(audience, w.UserID) {
+			ids = append(ids, w.UserID)
+		}
+	}
+	return ids
+}
+
+// notifyWatchers tells the watchers of t, other than userID, that userID
+// has changed it.
+func (s *Tasks) notifyWatchers(ctx context.Context, userID string, t model.Task) {
+	for _, id := range s.watchers(ctx, t) {
+		if id == userID {
+			continue
+		}
+		s.notify(ctx, model.Notification{
+			OrgID: t.OrgID, UserID: id, Kind: model.NotifyUpdated, ActorID: userID,
+			TaskID: t.ID, Title: t.Title, CreatedAt: t.UpdatedAt,
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/model"
+	"starttech-server/storage"
+)
+
+// Delivery log page size bounds for Deliveries.
+const (
+	DefaultDeliveryLimit = 50
+	MaxDeliveryLimit     = 200
+)
+
+// Webhooks manages the webhooks a user registers and their delivery logs. A
+// webhook only hears about the organization it was registered in. Other
+// users' webhooks are reported as storage.ErrNotFound.
+type Webhooks struct {
+	Store storage.WebhookStore
+	// Redeliver queues a replayed delivery to be sent; see
+	// webhooks.Dispatcher.Redeliver.
+	Redeliver func(ctx context.Context, deliveryID string) error
+	// AllowPrivate lets webhooks point at loopback, private and link-local
+	// addresses, for development.
+	AllowPrivate bool
+}
+
+// List returns userID's webhooks without their secrets.
+func (s *Webhooks) List(ctx context.Context, userID string) ([]model.Webhook, error) {
+	hooks, err := s.Store.ListWebhooks(ctx, orgOf(ctx), userID)
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	return hooks, err
+}
+
+// Get returns the webhook with the given id, without its secret, if userID
+// owns it.
+func (s *Webhooks) Get(ctx context.Context, userID, id string) (model.Webhook, error) {
+	w, err := s.get(ctx, userID, id)
+	w.Secret = ""
+	return w, err
+}
+
+func (s *Webhooks) get(ctx context.Context, userID, id string) (model.Webhook, error) {
+	w, err := s.Store.GetWebhook(ctx, id)
+	if err != nil {
+		return model.Webhook{}, err
+	}
+	if w.OwnerID != userID || w.OrgID != orgOf(ctx) {
+		return model.Webhook{}, storage.ErrNotFound
+	}
+	return w, nil
+}
+
+// Create validates in and registers it for userID with a fresh signing
+// secret. The returned webhook is the only place the secret is shown.
+func (s *Webhooks) Create(ctx context.Context, userID string, in model.WebhookInput) (model.Webhook, error) {
+	w := model.Webhook{OrgID: orgOf(ctx), OwnerID: userID, Secret: newSecret(), CreatedAt: time.Now().UTC()}
+	in.Apply(&w)
+	if err := w.Validate(webhookEvents, s.AllowPrivate); err != nil {
+		return model.Webhook{}, err
+	}
+	if err := s.Store.CreateWebhook(ctx, &w); err != nil {
+		return model.Webhook{}, err
+	}
+	return w, nil
+}
+
+// Update applies p to the webhook with the given id if userID owns it.
+func (s *Webhooks) Update(ctx context.Context, userID, id string, p model.WebhookPatch) (model.Webhook, error) {
+	w, err := s.get(ctx, userID, id)
+	if err != nil {
+		return model.Webhook{}, err
+	}
+	p.Apply(&w)
+	if err := w.Validate(webhookEvents, s.AllowPrivate); err != nil {
+		return model.Webhook{}, err
+	}
+	if err := s.Store.UpdateWebhook(ctx, &w); err != nil {
+		return model.Webhook{}, err
+	}
+	w.Secret = ""
+	return w, nil
+}
+
+// Delete removes the webhook with the given id and its delivery log.
+func (s *Webhooks) Delete(ctx context.Context, userID, id string) error {
+	if _, err := s.get(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.Store.DeleteWebhook(ctx, id)
+}
+
+// Deliveries returns the most recent deliveries of a webhook, newest first.
+func (s *Webhooks) Deliveries(ctx context.Context, userID, id string, limit int) ([]model.Delivery, error) {
+	if _, err := s.get(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	switch {
+	case limit <= 0:
+		limit = DefaultDeliveryLimit
+	case limit > MaxDeliveryLimit:
+		limit = MaxDeliveryLimit
+	}
+	return s.Store.ListDeliveries(ctx, id, limit)
+}
+
+// Replay queues a finished delivery to be sent again with a fresh set of
+// attempts. A delivery that is still pending yields storage.ErrConflict.
+func (s *Webhooks) Replay(ctx context.Context, userID, id, deliveryID string) (model.Delivery, error) {
+	if _, err := s.get(ctx, userID, id); err != nil {
+		return model.Delivery{}, err
+	}
+	d, err := s.Store.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return model.Delivery{}, err
+	}
+	if d.WebhookID != id {
+		return model.Delivery{}, storage.ErrNotFound
+	}
+	if d.Status == model.DeliveryPending {
+		return model.Delivery{}, storage.ErrConflict
+	}
+	now := time.Now().UTC()
+	d.Status, d.Attempts, d.LastError = model.DeliveryPending, 0, ""
+	d.NextAttemptAt, d.CompletedAt = &now, nil
+	if err := s.Store.UpdateDelivery(ctx, &d); err != nil {
+		return model.Delivery{}, err
+	}
+	if s.Redeliver != nil {
+		if err := s.Redeliver(ctx, d.ID); err != nil {
+			return model.Delivery{}, err
+		}
+	}
+	return d, nil
+}
+
+// webhookEvents are the event types a webhook may subscribe to.
+var webhookEvents = func() []string {
+	names := make([]string, len(events.All))
+	for i, t := range events.All {
+		names[i] = string(t)
+	}
+	return names
+}()
+
+func newSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,6 @@
+//go:build sqlite
+
+package storage
+
+// Pure-Go SQLite driver, so the binary still builds with CGO_ENABLED=0.
+import _ "modernc.org/sqlite"
@@ -0,0 +1,3771 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"starttech-server/model"
+)
+
+// MemoryStore keeps everything in process memory. It is safe for concurrent
+// use and loses all data when the process exits.
+type MemoryStore struct {
+	mu sync.RWMutex
+	memoryData
+}
+
+// memoryData holds the records of a MemoryStore.
+type memoryData struct {
+	tasks        map[string]model.Task
+	revisions    map[string][]model.Revision // by task, oldest first
+	comments     map[string]model.Comment
+	commentEdits map[string][]model.CommentEdit // earlier bodies by comment, oldest first
+	mentions     map[string]model.Mention
+	timeEntries  map[string]model.TimeEntry
+	dependencies map[[2]string]model.Dependency // by task, then blocker
+	watchers     map[[2]string]model.Watcher    // by task, then user
+	checklist    map[string]model.ChecklistItem
+	attachments  map[string]model.Attachment
+	activity     []model.Activity                // oldest first
+	idempotency  map[[2]string]IdempotencyRecord // by user, then key
+	audit        []model.AuditEvent              // oldest first
+	commands     map[string]model.Command
+	tags         map[string]model.Tag
+	fields       map[string]model.CustomField
+	rules        map[string]model.Rule
+	firings      map[[2]string]time.Time // by rule, then task
+	views        map[string]model.View
+	templates    map[string]model.Template
+	projects     map[string]model.Project
+	members      map[string]map[string]model.Member // by project, then user
+	orgs         map[string]model.Org
+	orgMembers   map[string]map[string]model.OrgMembership // by org, then user
+	invites      map[string]model.Invitation
+	domains      map[[2]string]model.Domain   // by org, then domain
+	scimLinks    map[[2]string]model.SCIMLink // by org, then user
+	sso          map[string]model.SSOConfig   // by org
+	reminders    map[string]model.Reminder
+	prefs        map[string]model.NotificationPrefs
+	inbox        map[string]model.Notification
+	shares       map[string]model.ShareLink
+	flags        map[string]model.Flag
+	calendars    map[[2]string]model.CalendarFeed   // by org, then user
+	inbound      map[[2]string]model.InboundAddress // by org, then user
+	github       map[string]model.GitHubLink        // by project
+	issueLinks   map[string]model.IssueLink         // by task
+	slack        map[string]model.SlackLink         // by project
+	boardImports map[string]model.BoardImport
+	webhooks     map[string]model.Webhook
+	deliveries   map[string]model.Delivery
+	users        map[string]model.User
+	identities   map[[2]string]model.Identity // by provider, then subject
+	twoFactor    map[string]model.TwoFactor   // by user
+	recovery     map[string][]string          // code hashes by user
+	sessions     map[string]model.AuthSession
+	apiKeys      map[string]model.APIKey
+	jobs         map[string]model.Job
+	outbox       map[string]model.OutboxEntry
+	changes      []taskChange     // oldest first
+	changeSeqs   map[string]int64 // by org
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{memoryData: memoryData{
+		tasks:        make(map[string]model.Task),
+		revisions:    make(map[string][]model.Revision),
+		comments:     make(map[string]model.Comment),
+		commentEdits: make(map[string][]model.CommentEdit),
+		mentions:     make(map[string]model.Mention),
+		timeEntries:  make(map[string]model.TimeEntry),
+		dependencies: make(map[[2]string]model.Dependency),
+		watchers:     make(map[[2]string]model.Watcher),
+		checklist:    make(map[string]model.ChecklistItem),
+		attachments:  make(map[string]model.Attachment),
+		idempotency:  make(map[[2]string]IdempotencyRecord),
+		commands:     make(map[string]model.Command),
+		tags:         make(map[string]model.Tag),
+		fields:       make(map[string]model.CustomField),
+		rules:        make(map[string]model.Rule),
+		firings:      make(map[[2]string]time.Time),
+		views:        make(map[string]model.View),
+		templates:    make(map[string]model.Template),
+		projects:     make(map[string]model.Project),
+		members:      make(map[string]map[string]model.Member),
+		orgs:         make(map[string]model.Org),
+		orgMembers:   make(map[string]map[string]model.OrgMembership),
+		invites:      make(map[string]model.Invitation),
+		domains:      make(map[[2]string]model.Domain),
+		scimLinks:    make(map[[2]string]model.SCIMLink),
+		sso:          make(map[string]model.SSOConfig),
+		reminders:    make(map[string]model.Reminder),
+		prefs:        make(map[string]model.NotificationPrefs),
+		inbox:        make(map[string]model.Notification),
+		calendars:    make(map[[2]string]model.CalendarFeed),
+		shares:       make(map[string]model.ShareLink),
+		flags:        make(map[string]model.Flag),
+		inbound:      make(map[[2]string]model.InboundAddress),
+		github:       make(map[string]model.GitHubLink),
+		issueLinks:   make(map[string]model.IssueLink),
+		slack:        make(map[string]model.SlackLink),
+		boardImports: make(map[string]model.BoardImport),
+		webhooks:     make(map[string]model.Webhook),
+		deliveries:   make(map[string]model.Delivery),
+		users:        make(map[string]model.User),
+		identities:   make(map[[2]string]model.Identity),
+		twoFactor:    make(map[string]model.TwoFactor),
+		recovery:     make(map[string][]string),
+		sessions:     make(map[string]model.AuthSession),
+		apiKeys:      make(map[string]model.APIKey),
+		jobs:         make(map[string]model.Job),
+		outbox:       make(map[string]model.OutboxEntry),
+		changeSeqs:   make(map[string]int64),
+	}}
+}
+
+// InTx runs fn against a copy of s, which replaces s if fn succeeds and is
+// dropped if it fails. s is locked meanwhile, so no other write can come
+// between the copy and its replacing s, and fn must use only the Store it
+// is given, as with a single-connection SQLite database. The copy is of the
+// whole store, so InTx suits bulk changes rather than every write.
+func (s *MemoryStore) InTx(ctx context.Context, fn func(tx Store) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &MemoryStore{memoryData: s.memoryData.clone()}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	s.memoryData = tx.memoryData
+	return nil
+}
+
+// clone copies d deeply enough that writes to either copy leave the other
+// alone. Stored records are replaced rather than modified in place.
+func (d memoryData) clone() memoryData {
+	c := memoryData{
+		tasks:        maps.Clone(d.tasks),
+		revisions:    maps.Clone(d.revisions),
+		comments:     maps.Clone(d.comments),
+		commentEdits: maps.Clone(d.commentEdits),
+		mentions:     maps.Clone(d.mentions),
+		timeEntries:  maps.Clone(d.timeEntries),
+		dependencies: maps.Clone(d.dependencies),
+		watchers:     maps.Clone(d.watchers),
+		checklist:    maps.Clone(d.checklist),
+		attachments:  maps.Clone(d.attachments),
+		activity:     slices.Clip(d.activity),
+		idempotency:  maps.Clone(d.idempotency),
+		commands:     maps.Clone(d.commands),
+		audit:        slices.Clip(d.audit),
+		tags:         maps.Clone(d.tags),
+		fields:       maps.Clone(d.fields),
+		rules:        maps.Clone(d.rules),
+		firings:      maps.Clone(d.firings),
+		views:        maps.Clone(d.views),
+		templates:    maps.Clone(d.templates),
+		projects:     maps.Clone(d.projects),
+		members:      make(map[string]map[string]model.Member, len(d.members)),
+		orgs:         maps.Clone(d.orgs),
+		orgMembers:   make(map[string]map[string]model.OrgMembership, len(d.orgMembers)),
+		invites:      maps.Clone(d.invites),
+		domains:      maps.Clone(d.domains),
+		scimLinks:    maps.Clone(d.scimLinks),
+		sso:          maps.Clone(d.sso),
+		reminders:    maps.Clone(d.reminders),
+		prefs:        maps.Clone(d.prefs),
+		inbox:        maps.Clone(d.inbox),
+		calendars:    maps.Clone(d.calendars),
+		shares:       maps.Clone(d.shares),
+		flags:        maps.Clone(d.flags),
+		inbound:      maps.Clone(d.inbound),
+		github:       maps.Clone(d.github),
+		issueLinks:   maps.Clone(d.issueLinks),
+		slack:        maps.Clone(d.slack),
+		boardImports: maps.Clone(d.boardImports),
+		webhooks:     maps.Clone(d.webhooks),
+		deliveries:   maps.Clone(d.deliveries),
+		users:        maps.Clone(d.users),
+		identities:   maps.Clone(d.identities),
+		twoFactor:    maps.Clone(d.twoFactor),
+		recovery:     maps.Clone(d.recovery),
+		sessions:     maps.Clone(d.sessions),
+		apiKeys:      maps.Clone(d.apiKeys),
+		jobs:         maps.Clone(d.jobs),
+		outbox:       maps.Clone(d.outbox),
+		changes:      slices.Clip(d.changes),
+		changeSeqs:   maps.Clone(d.changeSeqs),
+	}
+	for id, m := range d.members {
+		c.members[id] = maps.Clone(m)
+	}
+	for id, m := range d.orgMembers {
+		c.orgMembers[id] = maps.Clone(m)
+	}
+	return c
+}
+
+func (s *MemoryStore) ListTasks(ctx context.Context, f TaskFilter) ([]model.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tasks := make([]model.Task, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		if matchTask(t, f, s.isMember) {
+			tasks = append(tasks, cloneTask(t))
+		}
+	}
+	sortTasks(tasks, f.Sort)
+	return page(tasks, f.Offset, f.Limit), nil
+}
+
+func (s *MemoryStore) CountTasks(ctx context.Context, f TaskFilter) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, t := range s.tasks {
+		if matchTask(t, f, s.isMember) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// taskChange is an entry of the change feed as SQLStore keeps it in
+// task_changes: the task, with the project and owner it had before the
+// change, and the one user the entry is for, if any.
+type taskChange struct {
+	orgID     string
+	seq       int64
+	taskID    string
+	projectID *string
+	ownerID   string
+	userID    string
+}
+
+// logChange adds t, as it is now, to the change feed of its organization,
+// for userID or, if empty, for everyone. The caller holds s.mu.
+func (s *MemoryStore) logChange(t model.Task, userID string) {
+	s.changeSeqs[t.OrgID]++
+	s.changes = append(s.changes, taskChange{
+		orgID: t.OrgID, seq: s.changeSeqs[t.OrgID], taskID: t.ID,
+		projectID: t.ProjectID, ownerID: t.OwnerID, userID: userID,
+	})
+}
+
+// canSee reports whether userID may see t. The caller holds s.mu.
+func (s *MemoryStore) canSee(t model.Task, userID string) bool {
+	if t.ProjectID == nil {
+		return t.OwnerID == userID
+	}
+	return s.isMember(*t.ProjectID, userID)
+}
+
+func (s *MemoryStore) ListChanges(ctx context.Context, f ChangeFilter) ([]TaskChange, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	last := map[string]ChangeKey{}
+	for _, c := range s.changes {
+		k := ChangeKey{Seq: c.seq, TaskID: c.taskID}
+		if c.orgID != f.OrgID || compareChange(k, f.After) <= 0 {
+			continue
+		}
+		t, ok := s.tasks[c.taskID]
+		switch {
+		case c.userID == f.VisibleTo:
+		case c.userID != "":
+			continue
+		case ok && s.canSee(t, f.VisibleTo):
+		case c.projectID == nil && c.ownerID == f.VisibleTo:
+		case c.projectID != nil && s.isMember(*c.projectID, f.VisibleTo):
+		default:
+			continue
+		}
+		last[c.taskID] = k
+	}
+	changes := make([]TaskChange, 0, len(last))
+	for _, k := range last {
+		changes = append(changes, TaskChange{Key: k})
+	}
+	slices.SortFunc(changes, func(a, b TaskChange) int { return compareChange(a.Key, b.Key) })
+	changes = page(changes, 0, f.Limit)
+	for i, c := range changes {
+		if t, ok := s.tasks[c.Key.TaskID]; ok && s.canSee(t, f.VisibleTo) {
+			t = cloneTask(t)
+			changes[i].Task = &t
+		}
+	}
+	return changes, nil
+}
+
+// compareChange orders a and b as the change feed does.
+func compareChange(a, b ChangeKey) int {
+	if c := cmp.Compare(a.Seq, b.Seq); c != 0 {
+		return c
+	}
+	return strings.Compare(a.TaskID, b.TaskID)
+}
+
+func (s *MemoryStore) GetTask(ctx context.Context, id string) (model.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tasks[id]
+	if !ok {
+		return model.Task{}, ErrNotFound
+	}
+	return cloneTask(t), nil
+}
+
+func (s *MemoryStore) CreateTask(ctx context.Context, t *model.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t.ID = NewID()
+	t.Version = 1
+	s.tasks[t.ID] = cloneTask(*t)
+	s.saveRevision(*t)
+	s.logChange(*t, "")
+	return nil
+}
+
+func (s *MemoryStore) UpdateTask(ctx context.Context, t *model.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur, ok := s.tasks[t.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if cur.Version != t.Version {
+		return ErrStale
+	}
+	s.logChange(cur, "")
+	t.Version++
+	s.tasks[t.ID] = cloneTask(*t)
+	s.saveRevision(*t)
+	return nil
+}
+
+// saveRevision keeps t as the revision of its version. The caller holds
+// s.mu.
+func (s *MemoryStore) saveRevision(t model.Task) {
+	r := model.Revision{TaskID: t.ID, Version: t.Version, Task: cloneTask(t), CreatedAt: t.UpdatedAt}
+	s.revisions[t.ID] = append(s.revisions[t.ID], r)
+}
+
+func (s *MemoryStore) ListRevisions(ctx context.Context, f RevisionFilter) ([]model.Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	revisions := slices.Clone(s.revisions[f.TaskID])
+	slices.Reverse(revisions)
+	for i := range revisions {
+		revisions[i].Task = cloneTask(revisions[i].Task)
+	}
+	if revisions == nil {
+		revisions = []model.Revision{}
+	}
+	return page(revisions, f.Offset, f.Limit), nil
+}
+
+func (s *MemoryStore) GetRevision(ctx context.Context, taskID string, version int64) (model.Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, r := range s.revisions[taskID] {
+		if r.Version == version {
+			r.Task = cloneTask(r.Task)
+			return r, nil
+		}
+	}
+	return model.Revision{}, ErrNotFound
+}
+
+func (s *MemoryStore) DeleteTask(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tasks[id]
+	if !ok {
+		return ErrNotFound
+	}
+	s.logChange(t, "")
+	delete(s.tasks, id)
+	delete(s.revisions, id)
+	delete(s.issueLinks, id)
+	for rid, r := range s.reminders {
+		if r.TaskID == id {
+			delete(s.reminders, rid)
+		}
+	}
+	for cid, c := range s.comments {
+		if c.TaskID == id {
+			delete(s.comments, cid)
+			delete(s.commentEdits, cid)
+		}
+	}
+	for eid, e := range s.timeEntries {
+		if e.TaskID == id {
+			delete(s.timeEntries, eid)
+		}
+	}
+	for key := range s.dependencies {
+		if key[0] == id || key[1] == id {
+			delete(s.dependencies, key)
+		}
+	}
+	for cid, c := range s.checklist {
+		if c.TaskID == id {
+			delete(s.checklist, cid)
+		}
+	}
+	for key := range s.watchers {
+		if key[0] == id {
+			delete(s.watchers, key)
+		}
+	}
+	for mid, m := range s.mentions {
+		if m.TaskID == id {
+			delete(s.mentions, mid)
+		}
+	}
+	for nid, n := range s.inbox {
+		if n.TaskID == id {
+			delete(s.inbox, nid)
+		}
+	}
+	for aid, a := range s.attachments {
+		if a.TaskID == id {
+			delete(s.attachments, aid)
+		}
+	}
+	for lid, l := range s.shares {
+		if l.TaskID != nil && *l.TaskID == id {
+			delete(s.shares, lid)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) MergeTask(ctx context.Context, fromID, intoID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, c := range s.comments {
+		if c.TaskID == fromID {
+			c.TaskID = intoID
+			s.comments[id] = c
+		}
+	}
+	for id, m := range s.mentions {
+		if m.TaskID == fromID {
+			m.TaskID = intoID
+			s.mentions[id] = m
+		}
+	}
+	for id, a := range s.attachments {
+		if a.TaskID == fromID {
+			a.TaskID = intoID
+			s.attachments[id] = a
+		}
+	}
+	for key, w := range s.watchers {
+		if key[0] != fromID {
+			continue
+		}
+		delete(s.watchers, key)
+		if _, ok := s.watchers[[2]string{intoID, w.UserID}]; !ok {
+			w.TaskID = intoID
+			s.watchers[[2]string{intoID, w.UserID}] = w
+		}
+	}
+	return nil
+}
+
+// cloneTask copies t so callers never share its slices and maps with the
+// store.
+func cloneTask(t model.Task) model.Task {
+	t.TagIDs = slices.Clone(t.TagIDs)
+	if t.TagIDs == nil {
+		t.TagIDs = []string{}
+	}
+	t.Fields = maps.Clone(t.Fields)
+	if t.Fields == nil {
+		t.Fields = map[string]any{}
+	}
+	return t
+}
+
+func (s *MemoryStore) ListComments(ctx context.Context, taskID string) ([]model.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comments := []model.Comment{}
+	for _, c := range s.comments {
+		if c.TaskID == taskID {
+			c.Username = s.users[c.AuthorID].Username
+			comments = append(comments, c)
+		}
+	}
+	slices.SortFunc(comments, func(a, b model.Comment) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return comments, nil
+}
+
+func (s *MemoryStore) GetComment(ctx context.Context, id string) (model.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.comments[id]
+	if !ok {
+		return model.Comment{}, ErrNotFound
+	}
+	c.Username = s.users[c.AuthorID].Username
+	return c, nil
+}
+
+func (s *MemoryStore) CreateComment(ctx context.Context, c *model.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c.ID = NewID()
+	stored := *c
+	stored.Username = ""
+	s.comments[c.ID] = stored
+	return nil
+}
+
+func (s *MemoryStore) EditComment(ctx context.Context, c *model.Comment, prev model.CommentEdit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.comments[c.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	old.Body, old.EditedAt = c.Body, c.EditedAt
+	s.comments[c.ID] = old
+	s.commentEdits[c.ID] = append(s.commentEdits[c.ID], prev)
+	return nil
+}
+
+func (s *MemoryStore) DeleteComment(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.comments[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.comments, id)
+	delete(s.commentEdits, id)
+	for mid, m := range s.mentions {
+		if m.CommentID == id {
+			delete(s.mentions, mid)
+		}
+	}
+	for rid, r := range s.comments {
+		if r.ReplyTo != nil && *r.ReplyTo == id {
+			r.ReplyTo = c.ReplyTo
+			s.comments[rid] = r
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) CommentHistory(ctx context.Context, commentID string) ([]model.CommentEdit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]model.CommentEdit{}, s.commentEdits[commentID]...), nil
+}
+
+func (s *MemoryStore) ListMentions(ctx context.Context, commentID string) ([]model.Mention, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	mentions := []model.Mention{}
+	for _, m := range s.mentions {
+		if m.CommentID == commentID {
+			mentions = append(mentions, m)
+		}
+	}
+	slices.SortFunc(mentions, func(a, b model.Mention) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return mentions, nil
+}
+
+func (s *MemoryStore) CreateMention(ctx context.Context, m *model.Mention) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m.ID = NewID()
+	s.mentions[m.ID] = *m
+	return nil
+}
+
+func (s *MemoryStore) Blockers(ctx context.Context, taskID string) ([]model.Dependency, error) {
+	return s.dependenciesWhere(func(d model.Dependency) bool { return d.TaskID == taskID }), nil
+}
+
+func (s *MemoryStore) Dependents(ctx context.Context, blockerID string) ([]model.Dependency, error) {
+	return s.dependenciesWhere(func(d model.Dependency) bool { return d.BlockerID == blockerID }), nil
+}
+
+func (s *MemoryStore) dependenciesWhere(match func(model.Dependency) bool) []model.Dependency {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deps := []model.Dependency{}
+	for _, d := range s.dependencies {
+		if match(d) {
+			deps = append(deps, d)
+		}
+	}
+	slices.SortFunc(deps, func(a, b model.Dependency) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.TaskID, b.TaskID); c != 0 {
+			return c
+		}
+		return strings.Compare(a.BlockerID, b.BlockerID)
+	})
+	return deps
+}
+
+func (s *MemoryStore) AddDependency(ctx context.Context, d model.Dependency) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := [2]string{d.TaskID, d.BlockerID}
+	if _, ok := s.dependencies[key]; ok {
+		return ErrConflict
+	}
+	s.dependencies[key] = d
+	return nil
+}
+
+func (s *MemoryStore) RemoveDependency(ctx context.Context, taskID, blockerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := [2]string{taskID, blockerID}
+	if _, ok := s.dependencies[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.dependencies, key)
+	return nil
+}
+
+func (s *MemoryStore) ListWatchers(ctx context.Context, taskID string) ([]model.Watcher, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	watchers := []model.Watcher{}
+	for _, w := range s.watchers {
+		if w.TaskID == taskID {
+			w.Username = s.users[w.UserID].Username
+			watchers = append(watchers, w)
+		}
+	}
+	slices.SortFunc(watchers, func(a, b model.Watcher) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.UserID, b.UserID)
+	})
+	return watchers, nil
+}
+
+func (s *MemoryStore) AddWatcher(ctx context.Context, w model.Watcher) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := [2]string{w.TaskID, w.UserID}
+	if _, ok := s.watchers[key]; !ok {
+		w.Username = ""
+		s.watchers[key] = w
+	}
+	return nil
+}
+
+func (s *MemoryStore) RemoveWatcher(ctx context.Context, taskID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := [2]string{taskID, userID}
+	if _, ok := s.watchers[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.watchers, key)
+	return nil
+}
+
+func (s *MemoryStore) ListChecklist(ctx context.Context, taskID string) ([]model.ChecklistItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := []model.ChecklistItem{}
+	for _, c := range s.checklist {
+		if c.TaskID == taskID {
+			items = append(items, c)
+		}
+	}
+	slices.SortFunc(items, func(a, b model.ChecklistItem) int {
+		if c := cmp.Compare(a.Position, b.Position); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return items, nil
+}
+
+func (s *MemoryStore) GetChecklistItem(ctx context.Context, id string) (model.ChecklistItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.checklist[id]
+	if !ok {
+		return model.ChecklistItem{}, ErrNotFound
+	}
+	return c, nil
+}
+
+func (s *MemoryStore) CreateChecklistItem(ctx context.Context, c *model.ChecklistItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c.ID = NewID()
+	s.checklist[c.ID] = *c
+	return nil
+}
+
+func (s *MemoryStore) UpdateChecklistItem(ctx context.Context, c *model.ChecklistItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.checklist[c.ID]; !ok {
+		return ErrNotFound
+	}
+	s.checklist[c.ID] = *c
+	return nil
+}
+
+func (s *MemoryStore) DeleteChecklistItem(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.checklist[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.checklist, id)
+	return nil
+}
+
+func (s *MemoryStore) ReorderChecklist(ctx context.Context, taskID string, itemIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range itemIDs {
+		if c, ok := s.checklist[id]; !ok || c.TaskID != taskID {
+			return ErrNotFound
+		}
+	}
+	for i, id := range itemIDs {
+		c := s.checklist[id]
+		c.Position = i + 1
+		s.checklist[id] = c
+	}
+	return nil
+}
+
+func (s *MemoryStore) ListTimeEntries(ctx context.Context, f TimeEntryFilter) ([]model.TimeEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := []model.TimeEntry{}
+	for _, e := range s.timeEntries {
+		t := s.tasks[e.TaskID]
+		e.ProjectID = t.ProjectID
+		switch {
+		case f.OrgID != "" && e.OrgID != f.OrgID,
+			f.TaskID != "" && e.TaskID != f.TaskID,
+			f.UserID != "" && e.UserID != f.UserID,
+			f.VisibleTo != "" && t.ProjectID == nil && t.OwnerID != f.VisibleTo,
+			f.VisibleTo != "" && t.ProjectID != nil && !s.isMember(*t.ProjectID, f.VisibleTo),
+			f.ProjectID != "" && (t.ProjectID == nil || *t.ProjectID != f.ProjectID),
+			f.From != nil && e.EndedAt != nil && !e.EndedAt.After(*f.From),
+			f.To != nil && !e.StartedAt.Before(*f.To),
+			f.Running && e.EndedAt != nil:
+			continue
+		}
+		entries = append(entries, e)
+	}
+	slices.SortFunc(entries, func(a, b model.TimeEntry) int {
+		if c := a.StartedAt.Compare(b.StartedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return entries, nil
+}
+
+func (s *MemoryStore) GetTimeEntry(ctx context.Context, id string) (model.TimeEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, ok := s.timeEntries[id]
+	if !ok {
+		return model.TimeEntry{}, ErrNotFound
+	}
+	e.ProjectID = s.tasks[e.TaskID].ProjectID
+	return e, nil
+}
+
+func (s *MemoryStore) CreateTimeEntry(ctx context.Context, e *model.TimeEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e.Running() {
+		for _, other := range s.timeEntries {
+			if other.UserID == e.UserID && other.Running() {
+				return ErrConflict
+			}
+		}
+	}
+	e.ID = NewID()
+	stored := *e
+	stored.ProjectID, stored.Seconds = nil, 0
+	s.timeEntries[e.ID] = stored
+	return nil
+}
+
+func (s *MemoryStore) UpdateTimeEntry(ctx context.Context, e *model.TimeEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.timeEntries[e.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	old.StartedAt, old.EndedAt, old.Note = e.StartedAt, e.EndedAt, e.Note
+	s.timeEntries[e.ID] = old
+	return nil
+}
+
+func (s *MemoryStore) DeleteTimeEntry(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.timeEntries[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.timeEntries, id)
+	return nil
+}
+
+func (s *MemoryStore) ListAttachments(ctx context.Context, taskID string) ([]model.Attachment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	attachments := []model.Attachment{}
+	for _, a := range s.attachments {
+		if a.TaskID == taskID {
+			attachments = append(attachments, a)
+		}
+	}
+	slices.SortFunc(attachments, func(a, b model.Attachment) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return attachments, nil
+}
+
+func (s *MemoryStore) GetAttachment(ctx context.Context, id string) (model.Attachment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	a, ok := s.attachments[id]
+	if !ok {
+		return model.Attachment{}, ErrNotFound
+	}
+	return a, nil
+}
+
+func (s *MemoryStore) CreateAttachment(ctx context.Context, a *model.Attachment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.attachments[a.ID]; ok {
+		return ErrConflict
+	}
+	s.attachments[a.ID] = *a
+	return nil
+}
+
+func (s *MemoryStore) UpdateAttachment(ctx context.Context, a model.Attachment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur, ok := s.attachments[a.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	cur.Key, cur.Status, cur.Threat, cur.ScannedAt = a.Key, a.Status, a.Threat, a.ScannedAt
+	s.attachments[a.ID] = cur
+	return nil
+}
+
+func (s *MemoryStore) DeleteAttachment(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.attachments[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.attachments, id)
+	return nil
+}
+
+func (s *MemoryStore) RecordActivity(ctx context.Context, a *model.Activity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a.ID = NewID()
+	stored := *a
+	stored.Changes = slices.Clone(a.Changes)
+	s.activity = append(s.activity, stored)
+	return nil
+}
+
+func (s *MemoryStore) ListActivity(ctx context.Context, f ActivityFilter) ([]model.Activity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := []model.Activity{}
+	for i := len(s.activity) - 1; i >= 0; i-- {
+		a := s.activity[i]
+		if f.TaskID != "" && !sameRef(a.TaskID, f.TaskID) || f.ProjectID != "" && !sameRef(a.ProjectID, f.ProjectID) {
+			continue
+		}
+		a.Changes = slices.Clone(a.Changes)
+		out = append(out, a)
+	}
+	return page(out, f.Offset, f.Limit), nil
+}
+
+func sameRef(p *string, id string) bool {
+	return p != nil && *p == id
+}
+
+func (s *MemoryStore) ClaimIdempotencyKey(ctx context.Context, r IdempotencyRecord) (IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := [2]string{r.UserID, r.Key}
+	if prev, ok := s.idempotency[k]; ok {
+		return cloneIdempotency(prev), false, nil
+	}
+	s.idempotency[k] = cloneIdempotency(r)
+	return r, true, nil
+}
+
+func (s *MemoryStore) FinishIdempotencyKey(ctx context.Context, r IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := [2]string{r.UserID, r.Key}
+	prev, ok := s.idempotency[k]
+	if !ok {
+		return ErrNotFound
+	}
+	r.CreatedAt = prev.CreatedAt
+	r.Done = true
+	s.idempotency[k] = cloneIdempotency(r)
+	return nil
+}
+
+func (s *MemoryStore) ReleaseIdempotencyKey(ctx context.Context, userID, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.idempotency, [2]string{userID, key})
+	return nil
+}
+
+func (s *MemoryStore) PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for k, r := range s.idempotency {
+		if r.CreatedAt.Before(before) {
+			delete(s.idempotency, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) PurgeableIdempotencyKeys(ctx context.Context, before time.Time) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, r := range s.idempotency {
+		if r.CreatedAt.Before(before) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) SaveCommand(ctx context.Context, c *model.Command) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c.ID = NewID()
+	saved := *c
+	saved.Steps = slices.Clone(c.Steps)
+	s.commands[c.ID] = saved
+	return nil
+}
+
+func (s *MemoryStore) LastCommand(ctx context.Context, orgID, userID string, since time.Time) (model.Command, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var last model.Command
+	found := false
+	for _, c := range s.commands {
+		if c.OrgID != orgID || c.UserID != userID || c.CreatedAt.Before(since) {
+			continue
+		}
+		if !found || c.CreatedAt.After(last.CreatedAt) || c.CreatedAt.Equal(last.CreatedAt) && c.ID > last.ID {
+			last, found = c, true
+		}
+	}
+	if !found {
+		return model.Command{}, ErrNotFound
+	}
+	last.Steps = slices.Clone(last.Steps)
+	return last, nil
+}
+
+func (s *MemoryStore) DeleteCommand(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.commands[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.commands, id)
+	return nil
+}
+
+func (s *MemoryStore) PurgeCommands(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for id, c := range s.commands {
+		if c.CreatedAt.Before(before) {
+			delete(s.commands, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) PurgeableCommands(ctx context.Context, before time.Time) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, c := range s.commands {
+		if c.CreatedAt.Before(before) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func cloneIdempotency(r IdempotencyRecord) IdempotencyRecord {
+	r.Header = maps.Clone(r.Header)
+	r.Body = slices.Clone(r.Body)
+	return r
+}
+
+func (s *MemoryStore) AppendAuditEvent(ctx context.Context, e *model.AuditEvent, hash func(model.AuditEvent) string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e.ID = NewID()
+	e.Seq, e.PrevHash = 1, ""
+	if n := len(s.audit); n > 0 {
+		e.Seq, e.PrevHash = s.audit[n-1].Seq+1, s.audit[n-1].Hash
+	}
+	e.Hash = hash(*e)
+	s.audit = append(s.audit, *e)
+	return nil
+}
+
+func (s *MemoryStore) ListAuditEvents(ctx context.Context, f AuditFilter) ([]model.AuditEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := []model.AuditEvent{}
+	for i := range s.audit {
+		e := s.audit[i]
+		if !f.Oldest {
+			e = s.audit[len(s.audit)-1-i]
+		}
+		if f.From != nil && e.CreatedAt.Before(*f.From) || f.To != nil && !e.CreatedAt.Before(*f.To) ||
+			f.Action != "" && e.Action != f.Action || f.ActorID != "" && e.ActorID != f.ActorID ||
+			f.OrgID != "" && e.OrgID != f.OrgID {
+			continue
+		}
+		out = append(out, e)
+	}
+	return page(out, f.Offset, f.Limit), nil
+}
+
+func (s *MemoryStore) PurgeAuditEvents(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := slices.DeleteFunc(slices.Clone(s.audit), func(e model.AuditEvent) bool {
+		return e.CreatedAt.Before(before)
+	})
+	n := len(s.audit) - len(kept)
+	s.audit = kept
+	return n, nil
+}
+
+func (s *MemoryStore) PurgeableAuditEvents(ctx context.Context, before time.Time) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, e := range s.audit {
+		if e.CreatedAt.Before(before) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) ListTags(ctx context.Context, orgID, ownerID string) ([]model.Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tags := []model.Tag{}
+	for _, t := range s.tags {
+		if t.OrgID == orgID && t.OwnerID == ownerID {
+			tags = append(tags, t)
+		}
+	}
+	slices.SortFunc(tags, func(a, b model.Tag) int {
+		if c := strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return tags, nil
+}
+
+func (s *MemoryStore) GetTag(ctx context.Context, id string) (model.Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tags[id]
+	if !ok {
+		return model.Tag{}, ErrNotFound
+	}
+	return t, nil
+}
+
+// tagNameTaken reports whether another of the owner's tags in the same
+// organization is called t.Name.
+func (s *MemoryStore) tagNameTaken(t *model.Tag) bool {
+	for _, existing := range s.tags {
+		if existing.ID != t.ID && existing.OrgID == t.OrgID && existing.OwnerID == t.OwnerID &&
+			strings.EqualFold(existing.Name, t.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MemoryStore) CreateTag(ctx context.Context, t *model.Tag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tagNameTaken(t) {
+		return ErrConflict
+	}
+	t.ID = NewID()
+	s.tags[t.ID] = *t
+	return nil
+}
+
+func (s *MemoryStore) UpdateTag(ctx context.Context, t *model.Tag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tags[t.ID]; !ok {
+		return ErrNotFound
+	}
+	if s.tagNameTaken(t) {
+		return ErrConflict
+	}
+	s.tags[t.ID] = *t
+	return nil
+}
+
+func (s *MemoryStore) DeleteTag(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tags[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.tags, id)
+	for taskID, t := range s.tasks {
+		if i := slices.Index(t.TagIDs, id); i >= 0 {
+			s.logChange(t, "")
+			t.TagIDs = slices.Delete(slices.Clone(t.TagIDs), i, i+1)
+			s.tasks[taskID] = t
+		}
+	}
+	return nil
+}
+
+func cloneField(f model.CustomField) model.CustomField {
+	f.Options = slices.Clone(f.Options)
+	return f
+}
+
+func (s *MemoryStore) ListFields(ctx context.Context, projectID string) ([]model.CustomField, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fields := []model.CustomField{}
+	for _, f := range s.fields {
+		if f.ProjectID == projectID {
+			fields = append(fields, cloneField(f))
+		}
+	}
+	slices.SortFunc(fields, func(a, b model.CustomField) int {
+		return oldestFirst(a.CreatedAt, b.CreatedAt, a.ID, b.ID)
+	})
+	return fields, nil
+}
+
+func (s *MemoryStore) GetField(ctx context.Context, id string) (model.CustomField, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f, ok := s.fields[id]
+	if !ok {
+		return model.CustomField{}, ErrNotFound
+	}
+	return cloneField(f), nil
+}
+
+// fieldNameTaken reports whether another field of the same project is
+// called f.Name.
+func (s *MemoryStore) fieldNameTaken(f *model.CustomField) bool {
+	for _, existing := range s.fields {
+		if existing.ID != f.ID && existing.ProjectID == f.ProjectID && strings.EqualFold(existing.Name, f.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MemoryStore) CreateField(ctx context.Context, f *model.CustomField) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fieldNameTaken(f) {
+		return ErrConflict
+	}
+	f.ID = NewID()
+	s.fields[f.ID] = cloneField(*f)
+	return nil
+}
+
+func (s *MemoryStore) UpdateField(ctx context.Context, f *model.CustomField) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.fields[f.ID]; !ok {
+		return ErrNotFound
+	}
+	if s.fieldNameTaken(f) {
+		return ErrConflict
+	}
+	s.fields[f.ID] = cloneField(*f)
+	if f.Type == model.FieldSelect {
+		s.clearFieldValues(f.ID, func(v any) bool {
+			o, _ := v.(string)
+			return !slices.Contains(f.Options, o)
+		})
+	}
+	return nil
+}
+
+func (s *MemoryStore) DeleteField(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.fields[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.fields, id)
+	s.clearFieldValues(id, func(any) bool { return true })
+	return nil
+}
+
+func cloneRule(r model.Rule) model.Rule {
+	r.Actions = slices.Clone(r.Actions)
+	return r
+}
+
+// listRules returns the rules keep reports true for, oldest first. The
+// caller holds s.mu.
+func (s *MemoryStore) listRules(keep func(model.Rule) bool) []model.Rule {
+	rules := []model.Rule{}
+	for _, r := range s.rules {
+		if keep(r) {
+			rules = append(rules, cloneRule(r))
+		}
+	}
+	slices.SortFunc(rules, func(a, b model.Rule) int {
+		return oldestFirst(a.CreatedAt, b.CreatedAt, a.ID, b.ID)
+	})
+	return rules
+}
+
+func (s *MemoryStore) ListRules(ctx context.Context, projectID string) ([]model.Rule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.listRules(func(r model.Rule) bool { return r.ProjectID == projectID }), nil
+}
+
+func (s *MemoryStore) ListEnabledRules(ctx context.Context) ([]model.Rule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.listRules(func(r model.Rule) bool { return r.Enabled }), nil
+}
+
+func (s *MemoryStore) GetRule(ctx context.Context, id string) (model.Rule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.rules[id]
+	if !ok {
+		return model.Rule{}, ErrNotFound
+	}
+	return cloneRule(r), nil
+}
+
+func (s *MemoryStore) CreateRule(ctx context.Context, r *model.Rule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.ID = NewID()
+	s.rules[r.ID] = cloneRule(*r)
+	return nil
+}
+
+func (s *MemoryStore) UpdateRule(ctx context.Context, r *model.Rule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.rules[r.ID]; !ok {
+		return ErrNotFound
+	}
+	s.rules[r.ID] = cloneRule(*r)
+	return nil
+}
+
+func (s *MemoryStore) DeleteRule(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.rules[id]; !ok {
+		return ErrNotFound
+	}
+	s.deleteRule(id)
+	return nil
+}
+
+// deleteRule removes a rule and its firings. The caller holds s.mu.
+func (s *MemoryStore) deleteRule(id string) {
+	delete(s.rules, id)
+	for key := range s.firings {
+		if key[0] == id {
+			delete(s.firings, key)
+		}
+	}
+}
+
+func (s *MemoryStore) RuleFirings(ctx context.Context, ruleID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var ids []string
+	for key := range s.firings {
+		if key[0] == ruleID {
+			ids = append(ids, key[1])
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (s *MemoryStore) AddRuleFiring(ctx context.Context, ruleID, taskID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.firings[[2]string{ruleID, taskID}]; !ok {
+		s.firings[[2]string{ruleID, taskID}] = at
+	}
+	return nil
+}
+
+func (s *MemoryStore) RemoveRuleFirings(ctx context.Context, ruleID string, taskIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range taskIDs {
+		delete(s.firings, [2]string{ruleID, id})
+	}
+	return nil
+}
+
+// clearFieldValues removes the values of the field fieldID for which drop
+// reports true from every task. The caller holds s.mu.
+func (s *MemoryStore) clearFieldValues(fieldID string, drop func(v any) bool) {
+	for taskID, t := range s.tasks {
+		if v, ok := t.Fields[fieldID]; ok && drop(v) {
+			s.logChange(t, "")
+			t.Fields = maps.Clone(t.Fields)
+			delete(t.Fields, fieldID)
+			s.tasks[taskID] = t
+		}
+	}
+}
+
+func cloneView(v model.View) model.View {
+	v.Filter.TagIDs = slices.Clone(v.Filter.TagIDs)
+	return v
+}
+
+func (s *MemoryStore) ListViews(ctx context.Context, orgID, ownerID string) ([]model.View, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	views := []model.View{}
+	for _, v := range s.views {
+		if v.OrgID == orgID && v.OwnerID == ownerID {
+			views = append(views, cloneView(v))
+		}
+	}
+	slices.SortFunc(views, func(a, b model.View) int {
+		if c := strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return views, nil
+}
+
+func (s *MemoryStore) GetView(ctx context.Context, id string) (model.View, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, ok := s.views[id]
+	if !ok {
+		return model.View{}, ErrNotFound
+	}
+	return cloneView(v), nil
+}
+
+// viewNameTaken reports whether another of the owner's views in the same
+// organization is called v.Name.
+func (s *MemoryStore) viewNameTaken(v *model.View) bool {
+	for _, existing := range s.views {
+		if existing.ID != v.ID && existing.OrgID == v.OrgID && existing.OwnerID == v.OwnerID &&
+			strings.EqualFold(existing.Name, v.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MemoryStore) CreateView(ctx context.Context, v *model.View) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.viewNameTaken(v) {
+		return ErrConflict
+	}
+	v.ID = NewID()
+	s.views[v.ID] = cloneView(*v)
+	return nil
+}
+
+func (s *MemoryStore) UpdateView(ctx context.Context, v *model.View) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.views[v.ID]; !ok {
+		return ErrNotFound
+	}
+	if s.viewNameTaken(v) {
+		return ErrConflict
+	}
+	s.views[v.ID] = cloneView(*v)
+	return nil
+}
+
+func (s *MemoryStore) DeleteView(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.views[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.views, id)
+	return nil
+}
+
+// cloneTemplate copies t deeply enough that it shares nothing with the
+// stored record.
+func cloneTemplate(t model.Template) model.Template {
+	if t.Project != nil {
+		p := *t.Project
+		p.Statuses = slices.Clone(p.Statuses)
+		t.Project = &p
+	}
+	t.Tasks = slices.Clone(t.Tasks)
+	for i, d := range t.Tasks {
+		t.Tasks[i].Tags = slices.Clone(d.Tags)
+		if d.DueInDays != nil {
+			days := *d.DueInDays
+			t.Tasks[i].DueInDays = &days
+		}
+	}
+	return t
+}
+
+func (s *MemoryStore) ListTemplates(ctx context.Context, orgID, ownerID string) ([]model.Template, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	templates := []model.Template{}
+	for _, t := range s.templates {
+		if t.OrgID == orgID && t.OwnerID == ownerID {
+			templates = append(templates, cloneTemplate(t))
+		}
+	}
+	slices.SortFunc(templates, func(a, b model.Template) int {
+		if c := strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return templates, nil
+}
+
+func (s *MemoryStore) GetTemplate(ctx context.Context, id string) (model.Template, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.templates[id]
+	if !ok {
+		return model.Template{}, ErrNotFound
+	}
+	return cloneTemplate(t), nil
+}
+
+// templateNameTaken reports whether another of the owner's templates in
+// the same organization is called t.Name.
+func (s *MemoryStore) templateNameTaken(t *model.Template) bool {
+	for _, existing := range s.templates {
+		if existing.ID != t.ID && existing.OrgID == t.OrgID && existing.OwnerID == t.OwnerID &&
+			strings.EqualFold(existing.Name, t.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MemoryStore) CreateTemplate(ctx context.Context, t *model.Template) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.templateNameTaken(t) {
+		return ErrConflict
+	}
+	t.ID = NewID()
+	s.templates[t.ID] = cloneTemplate(*t)
+	return nil
+}
+
+func (s *MemoryStore) UpdateTemplate(ctx context.Context, t *model.Template) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.templates[t.ID]; !ok {
+		return ErrNotFound
+	}
+	if s.templateNameTaken(t) {
+		return ErrConflict
+	}
+	s.templates[t.ID] = cloneTemplate(*t)
+	return nil
+}
+
+func (s *MemoryStore) DeleteTemplate(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.templates[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.templates, id)
+	return nil
+}
+
+func cloneProject(p model.Project) model.Project {
+	p.Statuses = slices.Clone(p.Statuses)
+	return p
+}
+
+func (s *MemoryStore) ListProjects(ctx context.Context, orgID, userID string) ([]model.Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	projects := []model.Project{}
+	for _, p := range s.projects {
+		if p.OrgID == orgID && s.isMember(p.ID, userID) {
+			projects = append(projects, cloneProject(p))
+		}
+	}
+	slices.SortFunc(projects, func(a, b model.Project) int {
+		if c := strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return projects, nil
+}
+
+func (s *MemoryStore) GetProject(ctx context.Context, id string) (model.Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.projects[id]
+	if !ok {
+		return model.Project{}, ErrNotFound
+	}
+	return cloneProject(p), nil
+}
+
+func (s *MemoryStore) CreateProject(ctx context.Context, p *model.Project) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p.ID = NewID()
+	s.projects[p.ID] = cloneProject(*p)
+	s.saveMember(model.Member{ProjectID: p.ID, UserID: p.OwnerID, Role: model.RoleOwner, CreatedAt: p.CreatedAt})
+	return nil
+}
+
+func (s *MemoryStore) UpdateProject(ctx context.Context, p *model.Project) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.projects[p.ID]; !ok {
+		return ErrNotFound
+	}
+	s.projects[p.ID] = cloneProject(*p)
+	return nil
+}
+
+func (s *MemoryStore) DeleteProject(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.projects[id]; !ok {
+		return ErrNotFound
+	}
+	for _, t := range s.tasks {
+		if t.ProjectID != nil && *t.ProjectID == id {
+			for userID := range s.members[id] {
+				s.logChange(t, userID)
+			}
+			s.logChange(t, "")
+		}
+	}
+	delete(s.projects, id)
+	delete(s.members, id)
+	for fieldID, f := range s.fields {
+		if f.ProjectID == id {
+			delete(s.fields, fieldID)
+			s.clearFieldValues(fieldID, func(any) bool { return true })
+		}
+	}
+	for ruleID, r := range s.rules {
+		if r.ProjectID == id {
+			s.deleteRule(ruleID)
+		}
+	}
+	s.deleteGitHubLink(id)
+	delete(s.slack, id)
+	for lid, l := range s.shares {
+		if l.ProjectID != nil && *l.ProjectID == id {
+			delete(s.shares, lid)
+		}
+	}
+	for taskID, t := range s.tasks {
+		if t.ProjectID != nil && *t.ProjectID == id {
+			t.ProjectID = nil
+			t.Version++
+			s.tasks[taskID] = t
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) ReorderTasks(ctx context.Context, projectID string, taskIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range taskIDs {
+		t, ok := s.tasks[id]
+		if !ok || t.ProjectID == nil || *t.ProjectID != projectID {
+			return ErrNotFound
+		}
+	}
+	for i, id := range taskIDs {
+		t := s.tasks[id]
+		s.logChange(t, "")
+		t.Position = float64(i + 1)
+		s.tasks[id] = t
+	}
+	return nil
+}
+
+// logRevoked adds to the change feed, for userID, the tasks of the
+// projects keep reports true for that userID belongs to, before it loses
+// access to them. The caller holds s.mu.
+func (s *MemoryStore) logRevoked(userID string, keep func(projectID string) bool) {
+	for _, t := range s.tasks {
+		if t.ProjectID != nil && keep(*t.ProjectID) && s.isMember(*t.ProjectID, userID) {
+			s.logChange(t, userID)
+		}
+	}
+}
+
+// isMember reports whether userID belongs to the project. The caller holds
+// s.mu.
+func (s *MemoryStore) isMember(projectID, userID string) bool {
+	_, ok := s.members[projectID][userID]
+	return ok
+}
+
+// withUsername fills in m.Username. The caller holds s.mu.
+func (s *MemoryStore) withUsername(m model.Member) model.Member {
+	m.Username = s.users[m.UserID].Username
+	return m
+}
+
+func (s *MemoryStore) ListMembers(ctx context.Context, projectID string) ([]model.Member, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	members := []model.Member{}
+	for _, m := range s.members[projectID] {
+		members = append(members, s.withUsername(m))
+	}
+	slices.SortFunc(members, func(a, b model.Member) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.UserID, b.UserID)
+	})
+	return members, nil
+}
+
+func (s *MemoryStore) GetMember(ctx context.Context, projectID, userID string) (model.Member, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m, ok := s.members[projectID][userID]
+	if !ok {
+		return model.Member{}, ErrNotFound
+	}
+	return s.withUsername(m), nil
+}
+
+func (s *MemoryStore) SaveMember(ctx context.Context, m *model.Member) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.saveMember(*m)
+	return nil
+}
+
+// saveMember adds or updates m, keeping the original join time. The caller
+// holds s.mu.
+func (s *MemoryStore) saveMember(m model.Member) {
+	if s.members[m.ProjectID] == nil {
+		s.members[m.ProjectID] = make(map[string]model.Member)
+	}
+	if old, ok := s.members[m.ProjectID][m.UserID]; ok {
+		m.CreatedAt = old.CreatedAt
+	}
+	m.Username = ""
+	s.members[m.ProjectID][m.UserID] = m
+}
+
+func (s *MemoryStore) RemoveMember(ctx context.Context, projectID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.isMember(projectID, userID) {
+		return ErrNotFound
+	}
+	s.logRevoked(userID, func(p string) bool { return p == projectID })
+	delete(s.members[projectID], userID)
+	return nil
+}
+
+func (s *MemoryStore) ListOrgs(ctx context.Context, userID string) ([]model.Org, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var joined []model.OrgMembership
+	for _, members := range s.orgMembers {
+		if m, ok := members[userID]; ok {
+			joined = append(joined, m)
+		}
+	}
+	slices.SortFunc(joined, func(a, b model.OrgMembership) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.OrgID, b.OrgID)
+	})
+	orgs := make([]model.Org, len(joined))
+	for i, m := range joined {
+		orgs[i] = s.orgs[m.OrgID]
+		orgs[i].Role = m.Role
+	}
+	return orgs, nil
+}
+
+func (s *MemoryStore) GetOrg(ctx context.Context, id string) (model.Org, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	o, ok := s.orgs[id]
+	if !ok {
+		return model.Org{}, ErrNotFound
+	}
+	return o, nil
+}
+
+func (s *MemoryStore) FindOrgs(ctx context.Context, f OrgFilter) ([]model.Org, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := strings.ToLower(f.Query)
+	out := []model.Org{}
+	for _, o := range s.orgs {
+		if strings.Contains(strings.ToLower(o.Name), query) {
+			out = append(out, o)
+		}
+	}
+	slices.SortFunc(out, func(a, b model.Org) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return page(out, f.Offset, f.Limit), nil
+}
+
+func (s *MemoryStore) CreateOrg(ctx context.Context, o *model.Org, ownerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o.ID = NewID()
+	s.orgs[o.ID] = *o
+	s.saveOrgMember(model.OrgMembership{OrgID: o.ID, UserID: ownerID, Role: model.OrgOwner, CreatedAt: o.CreatedAt})
+	return nil
+}
+
+func (s *MemoryStore) UpdateOrg(ctx context.Context, o *model.Org) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.orgs[o.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	old.Name, old.RequireTwoFactor = o.Name, o.RequireTwoFactor
+	s.orgs[o.ID] = old
+	return nil
+}
+
+func (s *MemoryStore) ListOrgMembers(ctx context.Context, orgID string) ([]model.OrgMembership, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	members := []model.OrgMembership{}
+	for _, m := range s.orgMembers[orgID] {
+		m.Username = s.users[m.UserID].Username
+		members = append(members, m)
+	}
+	slices.SortFunc(members, func(a, b model.OrgMembership) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.UserID, b.UserID)
+	})
+	return members, nil
+}
+
+func (s *MemoryStore) GetOrgMember(ctx context.Context, orgID, userID string) (model.OrgMembership, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m, ok := s.orgMembers[orgID][userID]
+	if !ok {
+		return model.OrgMembership{}, ErrNotFound
+	}
+	m.Username = s.users[m.UserID].Username
+	return m, nil
+}
+
+func (s *MemoryStore) SaveOrgMember(ctx context.Context, m *model.OrgMembership) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.saveOrgMember(*m)
+	return nil
+}
+
+// saveOrgMember adds or updates m, keeping the original join time. The
+// caller holds s.mu.
+func (s *MemoryStore) saveOrgMember(m model.OrgMembership) {
+	if s.orgMembers[m.OrgID] == nil {
+		s.orgMembers[m.OrgID] = make(map[string]model.OrgMembership)
+	}
+	if old, ok := s.orgMembers[m.OrgID][m.UserID]; ok {
+		m.CreatedAt = old.CreatedAt
+	}
+	m.Username = ""
+	s.orgMembers[m.OrgID][m.UserID] = m
+}
+
+func (s *MemoryStore) RemoveOrgMember(ctx context.Context, orgID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.orgMembers[orgID][userID]; !ok {
+		return ErrNotFound
+	}
+	s.logRevoked(userID, func(p string) bool { return s.projects[p].OrgID == orgID })
+	delete(s.orgMembers[orgID], userID)
+	for _, p := range s.projects {
+		if p.OrgID == orgID {
+			delete(s.members[p.ID], userID)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) ListInvitations(ctx context.Context, orgID string) ([]model.Invitation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	invs := []model.Invitation{}
+	for _, inv := range s.invites {
+		if inv.OrgID == orgID && inv.AcceptedAt == nil {
+			invs = append(invs, inv)
+		}
+	}
+	slices.SortFunc(invs, func(a, b model.Invitation) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(b.ID, a.ID)
+	})
+	return invs, nil
+}
+
+func (s *MemoryStore) GetInvitation(ctx context.Context, id string) (model.Invitation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	inv, ok := s.invites[id]
+	if !ok {
+		return model.Invitation{}, ErrNotFound
+	}
+	return inv, nil
+}
+
+func (s *MemoryStore) GetInvitationByToken(ctx context.Context, tokenHash string) (model.Invitation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, inv := range s.invites {
+		if inv.TokenHash == tokenHash {
+			return inv, nil
+		}
+	}
+	return model.Invitation{}, ErrNotFound
+}
+
+func (s *MemoryStore) CreateInvitation(ctx context.Context, inv *model.Invitation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inv.ID = NewID()
+	stored := *inv
+	stored.Token = ""
+	s.invites[inv.ID] = stored
+	return nil
+}
+
+func (s *MemoryStore) AcceptInvitation(ctx context.Context, id string, m *model.OrgMembership) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inv, ok := s.invites[id]
+	if !ok || inv.AcceptedAt != nil {
+		return ErrNotFound
+	}
+	at := m.CreatedAt
+	inv.AcceptedAt = &at
+	s.invites[id] = inv
+	s.saveOrgMember(*m)
+	return nil
+}
+
+func (s *MemoryStore) DeleteInvitation(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.invites[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.invites, id)
+	return nil
+}
+
+func (s *MemoryStore) ListDomains(ctx context.Context, orgID string) ([]model.Domain, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	domains := []model.Domain{}
+	for key, d := range s.domains {
+		if key[0] == orgID {
+			domains = append(domains, d)
+		}
+	}
+	slices.SortFunc(domains, func(a, b model.Domain) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Domain, b.Domain)
+	})
+	return domains, nil
+}
+
+func (s *MemoryStore) GetDomain(ctx context.Context, orgID, domain string) (model.Domain, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.domains[[2]string{orgID, domain}]
+	if !ok {
+		return model.Domain{}, ErrNotFound
+	}
+	return d, nil
+}
+
+func (s *MemoryStore) CreateDomain(ctx context.Context, d model.Domain) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := [2]string{d.OrgID, d.Domain}
+	if _, ok := s.domains[key]; ok {
+		return ErrConflict
+	}
+	s.domains[key] = d
+	return nil
+}
+
+func (s *MemoryStore) VerifyDomain(ctx context.Context, orgID, domain string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := [2]string{orgID, domain}
+	d, ok := s.domains[key]
+	if !ok {
+		return ErrNotFound
+	}
+	for other, o := range s.domains {
+		if other[1] == domain && other[0] != orgID && o.VerifiedAt != nil {
+			return ErrConflict
+		}
+	}
+	d.VerifiedAt = &at
+	s.domains[key] = d
+	return nil
+}
+
+func (s *MemoryStore) DeleteDomain(ctx context.Context, orgID, domain string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := [2]string{orgID, domain}
+	if _, ok := s.domains[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.domains, key)
+	return nil
+}
+
+func (s *MemoryStore) ListSCIMLinks(ctx context.Context, orgID string) ([]model.SCIMLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []model.SCIMLink
+	for _, l := range s.scimLinks {
+		if l.OrgID == orgID {
+			out = append(out, l)
+		}
+	}
+	slices.SortFunc(out, func(a, b model.SCIMLink) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.UserID, b.UserID)
+	})
+	return out, nil
+}
+
+func (s *MemoryStore) GetSCIMLink(ctx context.Context, orgID, userID string) (model.SCIMLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l, ok := s.scimLinks[[2]string{orgID, userID}]
+	if !ok {
+		return model.SCIMLink{}, ErrNotFound
+	}
+	return l, nil
+}
+
+func (s *MemoryStore) CreateSCIMLink(ctx context.Context, l model.SCIMLink) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := [2]string{l.OrgID, l.UserID}
+	if _, ok := s.scimLinks[key]; ok {
+		return ErrConflict
+	}
+	s.scimLinks[key] = l
+	return nil
+}
+
+func (s *MemoryStore) UpdateSCIMLink(ctx context.Context, l model.SCIMLink) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := [2]string{l.OrgID, l.UserID}
+	old, ok := s.scimLinks[key]
+	if !ok {
+		return ErrNotFound
+	}
+	l.CreatedAt = old.CreatedAt
+	s.scimLinks[key] = l
+	return nil
+}
+
+func (s *MemoryStore) DeleteSCIMLink(ctx context.Context, orgID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := [2]string{orgID, userID}
+	if _, ok := s.scimLinks[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.scimLinks, key)
+	return nil
+}
+
+func (s *MemoryStore) GetSSOConfig(ctx context.Context, orgID string) (model.SSOConfig, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.sso[orgID]
+	if !ok {
+		return model.SSOConfig{}, ErrNotFound
+	}
+	c.GroupRoles = slices.Clone(c.GroupRoles)
+	return c, nil
+}
+
+func (s *MemoryStore) GetSSOConfigByDomain(ctx context.Context, domain string) (model.SSOConfig, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, c := range s.sso {
+		if c.Domain == domain {
+			c.GroupRoles = slices.Clone(c.GroupRoles)
+			return c, nil
+		}
+	}
+	return model.SSOConfig{}, ErrNotFound
+}
+
+func (s *MemoryStore) SaveSSOConfig(ctx context.Context, c model.SSOConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, other := range s.sso {
+		if other.Domain == c.Domain && other.OrgID != c.OrgID {
+			return ErrConflict
+		}
+	}
+	if old, ok := s.sso[c.OrgID]; ok {
+		c.CreatedAt = old.CreatedAt
+	}
+	c.GroupRoles = slices.Clone(c.GroupRoles)
+	s.sso[c.OrgID] = c
+	return nil
+}
+
+func (s *MemoryStore) DeleteSSOConfig(ctx context.Context, orgID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sso[orgID]; !ok {
+		return ErrNotFound
+	}
+	delete(s.sso, orgID)
+	return nil
+}
+
+func (s *MemoryStore) ScheduleReminders(ctx context.Context, taskID string, rs []model.Reminder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, r := range s.reminders {
+		if r.TaskID == taskID && r.SentAt == nil {
+			delete(s.reminders, id)
+		}
+	}
+	for i := range rs {
+		rs[i].ID = NewID()
+		s.reminders[rs[i].ID] = rs[i]
+	}
+	return nil
+}
+
+func (s *MemoryStore) PendingReminders(ctx context.Context, now time.Time, limit int) ([]model.Reminder, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var due []model.Reminder
+	for _, r := range s.reminders {
+		if r.SentAt == nil && !r.FireAt.After(now) {
+			due = append(due, r)
+		}
+	}
+	slices.SortFunc(due, func(a, b model.Reminder) int {
+		if c := a.FireAt.Compare(b.FireAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return page(due, 0, limit), nil
+}
+
+func (s *MemoryStore) MarkReminderSent(ctx context.Context, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.reminders[id]
+	if !ok || r.SentAt != nil {
+		return ErrNotFound
+	}
+	r.SentAt = &at
+	s.reminders[id] = r
+	return nil
+}
+
+func (s *MemoryStore) GetNotificationPrefs(ctx context.Context, userID string) (model.NotificationPrefs, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if p, ok := s.prefs[userID]; ok {
+		return p, nil
+	}
+	return model.DefaultNotificationPrefs(userID), nil
+}
+
+func (s *MemoryStore) SaveNotificationPrefs(ctx context.Context, p model.NotificationPrefs) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prefs[p.UserID] = p
+	return nil
+}
+
+func (s *MemoryStore) ListNotifications(ctx context.Context, f NotificationFilter) ([]model.Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := []model.Notification{}
+	for _, n := range s.inbox {
+		if n.OrgID == f.OrgID && n.UserID == f.UserID && (!f.Unread || n.ReadAt == nil) {
+			out = append(out, n)
+		}
+	}
+	slices.SortFunc(out, func(a, b model.Notification) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(b.ID, a.ID)
+	})
+	return page(out, f.Offset, f.Limit), nil
+}
+
+func (s *MemoryStore) CountUnreadNotifications(ctx context.Context, orgID, userID string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, x := range s.inbox {
+		if x.OrgID == orgID && x.UserID == userID && x.ReadAt == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) GetNotification(ctx context.Context, id string) (model.Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n, ok := s.inbox[id]
+	if !ok {
+		return model.Notification{}, ErrNotFound
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) MarkNotificationsRead(ctx context.Context, ids []string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		if n, ok := s.inbox[id]; ok && n.ReadAt == nil {
+			n.ReadAt = &at
+			s.inbox[id] = n
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) CreateNotification(ctx context.Context, n *model.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n.ID = NewID()
+	s.inbox[n.ID] = *n
+	return nil
+}
+
+func (s *MemoryStore) GetCalendarFeed(ctx context.Context, orgID, userID string) (model.CalendarFeed, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f, ok := s.calendars[[2]string{orgID, userID}]
+	if !ok {
+		return model.CalendarFeed{}, ErrNotFound
+	}
+	return f, nil
+}
+
+func (s *MemoryStore) GetCalendarFeedByToken(ctx context.Context, tokenHash string) (model.CalendarFeed, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, f := range s.calendars {
+		if f.TokenHash == tokenHash {
+			return f, nil
+		}
+	}
+	return model.CalendarFeed{}, ErrNotFound
+}
+
+func (s *MemoryStore) SaveCalendarFeed(ctx context.Context, f model.CalendarFeed) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f.Token, f.URL = "", ""
+	s.calendars[[2]string{f.OrgID, f.UserID}] = f
+	return nil
+}
+
+func (s *MemoryStore) DeleteCalendarFeed(ctx context.Context, orgID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := [2]string{orgID, userID}
+	if _, ok := s.calendars[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.calendars, key)
+	return nil
+}
+
+func (s *MemoryStore) CreateShareLink(ctx context.Context, l *model.ShareLink) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l.ID = NewID()
+	stored := *l
+	stored.Token, stored.URL = "", ""
+	s.shares[l.ID] = stored
+	return nil
+}
+
+func (s *MemoryStore) GetShareLink(ctx context.Context, id string) (model.ShareLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l, ok := s.shares[id]
+	if !ok {
+		return model.ShareLink{}, ErrNotFound
+	}
+	return l, nil
+}
+
+func (s *MemoryStore) GetShareLinkByToken(ctx context.Context, tokenHash string) (model.ShareLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, l := range s.shares {
+		if l.TokenHash == tokenHash {
+			return l, nil
+		}
+	}
+	return model.ShareLink{}, ErrNotFound
+}
+
+func (s *MemoryStore) ListShareLinks(ctx context.Context, f ShareFilter) ([]model.ShareLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	links := []model.ShareLink{}
+	for _, l := range s.shares {
+		if f.TaskID != "" && sameID(l.TaskID, f.TaskID) || f.TaskID == "" && sameID(l.ProjectID, f.ProjectID) {
+			links = append(links, l)
+		}
+	}
+	slices.SortFunc(links, func(a, b model.ShareLink) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return links, nil
+}
+
+func (s *MemoryStore) DeleteShareLink(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.shares[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.shares, id)
+	return nil
+}
+
+func (s *MemoryStore) PurgeShareLinks(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for id, l := range s.shares {
+		if l.ExpiresAt != nil && l.ExpiresAt.Before(before) {
+			delete(s.shares, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) PurgeableShareLinks(ctx context.Context, before time.Time) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, l := range s.shares {
+		if l.ExpiresAt != nil && l.ExpiresAt.Before(before) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) ListFlags(ctx context.Context) ([]model.Flag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	flags := []model.Flag{}
+	for _, f := range s.flags {
+		flags = append(flags, copyFlag(f))
+	}
+	slices.SortFunc(flags, func(a, b model.Flag) int { return cmp.Compare(a.Key, b.Key) })
+	return flags, nil
+}
+
+func (s *MemoryStore) GetFlag(ctx context.Context, key string) (model.Flag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f, ok := s.flags[key]
+	if !ok {
+		return model.Flag{}, ErrNotFound
+	}
+	return copyFlag(f), nil
+}
+
+func (s *MemoryStore) SaveFlag(ctx context.Context, f model.Flag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := model.Flag{Key: f.Key, Description: f.Description, Enabled: f.Enabled, Rollout: f.Rollout, UpdatedAt: f.UpdatedAt,
+		Orgs: map[string]bool{}, Users: map[string]bool{}}
+	if old, ok := s.flags[f.Key]; ok {
+		stored.Orgs, stored.Users = old.Orgs, old.Users
+	}
+	s.flags[f.Key] = stored
+	return nil
+}
+
+func (s *MemoryStore) DeleteFlag(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.flags[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.flags, key)
+	return nil
+}
+
+func (s *MemoryStore) SetFlagOverride(ctx context.Context, key string, subject model.FlagSubject, id string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.flags[key]
+	if !ok {
+		return ErrNotFound
+	}
+	f = copyFlag(f)
+	if subject == model.FlagUser {
+		f.Users[id] = enabled
+	} else {
+		f.Orgs[id] = enabled
+	}
+	s.flags[key] = f
+	return nil
+}
+
+func (s *MemoryStore) DeleteFlagOverride(ctx context.Context, key string, subject model.FlagSubject, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.flags[key]
+	if !ok {
+		return ErrNotFound
+	}
+	f = copyFlag(f)
+	overrides := f.Orgs
+	if subject == model.FlagUser {
+		overrides = f.Users
+	}
+	if _, ok := overrides[id]; !ok {
+		return ErrNotFound
+	}
+	delete(overrides, id)
+	s.flags[key] = f
+	return nil
+}
+
+// copyFlag returns f with maps of its own, since stored flags are never
+// changed in place.
+func copyFlag(f model.Flag) model.Flag {
+	f.Orgs, f.Users = maps.Clone(f.Orgs), maps.Clone(f.Users)
+	return f
+}
+
+// sameID reports whether the optional ID p is id.
+func sameID(p *string, id string) bool {
+	return p != nil && *p == id
+}
+
+func (s *MemoryStore) GetInboundAddress(ctx context.Context, orgID, userID string) (model.InboundAddress, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	a, ok := s.inbound[[2]string{orgID, userID}]
+	if !ok {
+		return model.InboundAddress{}, ErrNotFound
+	}
+	return a, nil
+}
+
+func (s *MemoryStore) GetInboundAddressByToken(ctx context.Context, tokenHash string) (model.InboundAddress, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, a := range s.inbound {
+		if a.TokenHash == tokenHash {
+			return a, nil
+		}
+	}
+	return model.InboundAddress{}, ErrNotFound
+}
+
+func (s *MemoryStore) SaveInboundAddress(ctx context.Context, a model.InboundAddress) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a.Token, a.Address = "", ""
+	s.inbound[[2]string{a.OrgID, a.UserID}] = a
+	return nil
+}
+
+func (s *MemoryStore) DeleteInboundAddress(ctx context.Context, orgID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := [2]string{orgID, userID}
+	if _, ok := s.inbound[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.inbound, key)
+	return nil
+}
+
+func (s *MemoryStore) GetGitHubLink(ctx context.Context, projectID string) (model.GitHubLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l, ok := s.github[projectID]
+	if !ok {
+		return model.GitHubLink{}, ErrNotFound
+	}
+	return l, nil
+}
+
+func (s *MemoryStore) SaveGitHubLink(ctx context.Context, l model.GitHubLink) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l.WebhookURL = ""
+	s.github[l.ProjectID] = l
+	return nil
+}
+
+func (s *MemoryStore) DeleteGitHubLink(ctx context.Context, projectID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.github[projectID]; !ok {
+		return ErrNotFound
+	}
+	s.deleteGitHubLink(projectID)
+	return nil
+}
+
+// deleteGitHubLink removes the link of a project, if it has one, and its
+// issue links. The caller holds the lock.
+func (s *MemoryStore) deleteGitHubLink(projectID string) {
+	delete(s.github, projectID)
+	for taskID, l := range s.issueLinks {
+		if l.ProjectID == projectID {
+			delete(s.issueLinks, taskID)
+		}
+	}
+}
+
+func (s *MemoryStore) GetIssueLink(ctx context.Context, taskID string) (model.IssueLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l, ok := s.issueLinks[taskID]
+	if !ok {
+		return model.IssueLink{}, ErrNotFound
+	}
+	return l, nil
+}
+
+func (s *MemoryStore) FindIssueLink(ctx context.Context, projectID string, number int) (model.IssueLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, l := range s.issueLinks {
+		if l.ProjectID == projectID && l.Number == number {
+			return l, nil
+		}
+	}
+	return model.IssueLink{}, ErrNotFound
+}
+
+func (s *MemoryStore) SaveIssueLink(ctx context.Context, l model.IssueLink) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, other := range s.issueLinks {
+		if other.TaskID != l.TaskID && other.ProjectID == l.ProjectID && other.Number == l.Number {
+			return ErrConflict
+		}
+	}
+	s.issueLinks[l.TaskID] = l
+	return nil
+}
+
+func (s *MemoryStore) GetSlackLink(ctx context.Context, projectID string) (model.SlackLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l, ok := s.slack[projectID]
+	if !ok {
+		return model.SlackLink{}, ErrNotFound
+	}
+	l.Events = append([]string{}, l.Events...)
+	return l, nil
+}
+
+func (s *MemoryStore) SaveSlackLink(ctx context.Context, l model.SlackLink) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l.Events = append([]string{}, l.Events...)
+	l.CommandURL = ""
+	s.slack[l.ProjectID] = l
+	return nil
+}
+
+func (s *MemoryStore) DeleteSlackLink(ctx context.Context, projectID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.slack[projectID]; !ok {
+		return ErrNotFound
+	}
+	delete(s.slack, projectID)
+	return nil
+}
+
+// cloneBoardImport copies im deeply enough that neither copy changes the
+// other, except through Boards, which is only ever replaced whole.
+func cloneBoardImport(im model.BoardImport) model.BoardImport {
+	im.Summary.Projects = slices.Clone(im.Summary.Projects)
+	im.Summary.Warnings = slices.Clone(im.Summary.Warnings)
+	return im
+}
+
+func (s *MemoryStore) ListBoardImports(ctx context.Context, orgID, userID string) ([]model.BoardImport, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	imports := []model.BoardImport{}
+	for _, im := range s.boardImports {
+		if im.OrgID == orgID && im.UserID == userID {
+			im = cloneBoardImport(im)
+			im.Boards = nil
+			imports = append(imports, im)
+		}
+	}
+	slices.SortFunc(imports, func(a, b model.BoardImport) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(b.ID, a.ID)
+	})
+	return imports, nil
+}
+
+func (s *MemoryStore) GetBoardImport(ctx context.Context, id string) (model.BoardImport, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	im, ok := s.boardImports[id]
+	if !ok {
+		return model.BoardImport{}, ErrNotFound
+	}
+	return cloneBoardImport(im), nil
+}
+
+func (s *MemoryStore) CreateBoardImport(ctx context.Context, im *model.BoardImport) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	im.ID = NewID()
+	s.boardImports[im.ID] = cloneBoardImport(*im)
+	return nil
+}
+
+func (s *MemoryStore) UpdateBoardImport(ctx context.Context, im *model.BoardImport) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.boardImports[im.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	old.Status, old.Done, old.Summary, old.Error, old.FinishedAt = im.Status, im.Done, im.Summary, im.Error, im.FinishedAt
+	if im.Boards == nil {
+		old.Boards = nil
+	}
+	s.boardImports[im.ID] = cloneBoardImport(old)
+	return nil
+}
+
+func cloneWebhook(w model.Webhook) model.Webhook {
+	w.Events = append([]string{}, w.Events...)
+	return w
+}
+
+func (s *MemoryStore) ListWebhooks(ctx context.Context, orgID, ownerID string) ([]model.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hooks := []model.Webhook{}
+	for _, w := range s.webhooks {
+		if w.OrgID == orgID && w.OwnerID == ownerID {
+			hooks = append(hooks, cloneWebhook(w))
+		}
+	}
+	slices.SortFunc(hooks, func(a, b model.Webhook) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return hooks, nil
+}
+
+func (s *MemoryStore) GetWebhook(ctx context.Context, id string) (model.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	w, ok := s.webhooks[id]
+	if !ok {
+		return model.Webhook{}, ErrNotFound
+	}
+	return cloneWebhook(w), nil
+}
+
+func (s *MemoryStore) CreateWebhook(ctx context.Context, w *model.Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.ID = NewID()
+	s.webhooks[w.ID] = cloneWebhook(*w)
+	return nil
+}
+
+func (s *MemoryStore) UpdateWebhook(ctx context.Context, w *model.Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.webhooks[w.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	old.URL, old.Events, old.Active = w.URL, append([]string{}, w.Events...), w.Active
+	s.webhooks[w.ID] = old
+	return nil
+}
+
+func (s *MemoryStore) DeleteWebhook(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.webhooks[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.webhooks, id)
+	for did, d := range s.deliveries {
+		if d.WebhookID == id {
+			delete(s.deliveries, did)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]model.Delivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ds := []model.Delivery{}
+	for _, d := range s.deliveries {
+		if d.WebhookID == webhookID {
+			ds = append(ds, d)
+		}
+	}
+	slices.SortFunc(ds, func(a, b model.Delivery) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(b.ID, a.ID)
+	})
+	return page(ds, 0, limit), nil
+}
+
+func (s *MemoryStore) GetDelivery(ctx context.Context, id string) (model.Delivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.deliveries[id]
+	if !ok {
+		return model.Delivery{}, ErrNotFound
+	}
+	return d, nil
+}
+
+func (s *MemoryStore) CreateDelivery(ctx context.Context, d *model.Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d.ID = NewID()
+	s.deliveries[d.ID] = *d
+	return nil
+}
+
+func (s *MemoryStore) UpdateDelivery(ctx context.Context, d *model.Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deliveries[d.ID]; !ok {
+		return ErrNotFound
+	}
+	s.deliveries[d.ID] = *d
+	return nil
+}
+
+func (s *MemoryStore) GetUser(ctx context.Context, id string) (model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return model.User{}, ErrNotFound
+	}
+	return u, nil
+}
+
+func (s *MemoryStore) GetUserByEmail(ctx context.Context, email string) (model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if strings.EqualFold(u.Email, email) {
+			return u, nil
+		}
+	}
+	return model.User{}, ErrNotFound
+}
+
+func (s *MemoryStore) CreateUser(ctx context.Context, u *model.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if strings.EqualFold(existing.Email, u.Email) || strings.EqualFold(existing.Username, u.Username) {
+			return ErrConflict
+		}
+	}
+	u.ID = NewID()
+	s.users[u.ID] = *u
+	return nil
+}
+
+func (s *MemoryStore) UpdateUser(ctx context.Context, u model.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[u.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	for _, existing := range s.users {
+		if existing.ID != u.ID && (strings.EqualFold(existing.Email, u.Email) || strings.EqualFold(existing.Username, u.Username)) {
+			return ErrConflict
+		}
+	}
+	u.CreatedAt = stored.CreatedAt
+	s.users[u.ID] = u
+	return nil
+}
+
+func (s *MemoryStore) ListUsers(ctx context.Context, f UserFilter) ([]model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := strings.ToLower(f.Query)
+	out := []model.User{}
+	for _, u := range s.users {
+		if f.DeletionRequestedBefore != nil && (u.DeletionRequestedAt == nil || !u.DeletionRequestedAt.Before(*f.DeletionRequestedBefore)) {
+			continue
+		}
+		if strings.Contains(strings.ToLower(u.Email), query) || strings.Contains(strings.ToLower(u.Username), query) {
+			out = append(out, u)
+		}
+	}
+	slices.SortFunc(out, func(a, b model.User) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return page(out, f.Offset, f.Limit), nil
+}
+
+func (s *MemoryStore) GetIdentity(ctx context.Context, provider, subject string) (model.Identity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.identities[[2]string{provider, subject}]
+	if !ok {
+		return model.Identity{}, ErrNotFound
+	}
+	return id, nil
+}
+
+func (s *MemoryStore) CreateIdentity(ctx context.Context, id model.Identity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := [2]string{id.Provider, id.Subject}
+	if _, ok := s.identities[k]; ok {
+		return ErrConflict
+	}
+	s.identities[k] = id
+	return nil
+}
+
+func (s *MemoryStore) ListUserComments(ctx context.Context, userID string) ([]model.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comments := []model.Comment{}
+	for _, c := range s.comments {
+		if c.AuthorID == userID {
+			c.Username = s.users[c.AuthorID].Username
+			comments = append(comments, c)
+		}
+	}
+	slices.SortFunc(comments, func(a, b model.Comment) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return comments, nil
+}
+
+func (s *MemoryStore) ListUserAttachments(ctx context.Context, userID string) ([]model.Attachment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	attachments := []model.Attachment{}
+	for _, a := range s.attachments {
+		if a.UploaderID == userID {
+			attachments = append(attachments, a)
+		}
+	}
+	slices.SortFunc(attachments, func(a, b model.Attachment) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return attachments, nil
+}
+
+func (s *MemoryStore) EraseUser(ctx context.Context, u model.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[u.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	for _, existing := range s.users {
+		if existing.ID != u.ID && (strings.EqualFold(existing.Email, u.Email) || strings.EqualFold(existing.Username, u.Username)) {
+			return ErrConflict
+		}
+	}
+	id := u.ID
+	for tagID, t := range s.tags {
+		if t.OwnerID != id {
+			continue
+		}
+		delete(s.tags, tagID)
+		for taskID, task := range s.tasks {
+			if i := slices.Index(task.TagIDs, tagID); i >= 0 {
+				s.logChange(task, "")
+				task.TagIDs = slices.Delete(slices.Clone(task.TagIDs), i, i+1)
+				s.tasks[taskID] = task
+			}
+		}
+	}
+	maps.DeleteFunc(s.views, func(_ string, v model.View) bool { return v.OwnerID == id })
+	maps.DeleteFunc(s.templates, func(_ string, t model.Template) bool { return t.OwnerID == id })
+	maps.DeleteFunc(s.reminders, func(_ string, r model.Reminder) bool { return r.OwnerID == id })
+	delete(s.prefs, id)
+	maps.DeleteFunc(s.inbox, func(_ string, n model.Notification) bool { return n.UserID == id })
+	maps.DeleteFunc(s.mentions, func(_ string, m model.Mention) bool { return m.UserID == id })
+	for hookID, h := range s.webhooks {
+		if h.OwnerID == id {
+			delete(s.webhooks, hookID)
+			maps.DeleteFunc(s.deliveries, func(_ string, d model.Delivery) bool { return d.WebhookID == hookID })
+		}
+	}
+	for _, m := range s.members {
+		delete(m, id)
+	}
+	for _, m := range s.orgMembers {
+		delete(m, id)
+	}
+	maps.DeleteFunc(s.invites, func(_ string, inv model.Invitation) bool { return strings.EqualFold(inv.Email, stored.Email) })
+	maps.DeleteFunc(s.scimLinks, func(k [2]string, _ model.SCIMLink) bool { return k[1] == id })
+	maps.DeleteFunc(s.watchers, func(k [2]string, _ model.Watcher) bool { return k[1] == id })
+	maps.DeleteFunc(s.shares, func(_ string, l model.ShareLink) bool { return l.CreatedBy == id })
+	maps.DeleteFunc(s.idempotency, func(k [2]string, _ IdempotencyRecord) bool { return k[0] == id })
+	maps.DeleteFunc(s.calendars, func(k [2]string, _ model.CalendarFeed) bool { return k[1] == id })
+	maps.DeleteFunc(s.inbound, func(k [2]string, _ model.InboundAddress) bool { return k[1] == id })
+	maps.DeleteFunc(s.boardImports, func(_ string, b model.BoardImport) bool { return b.UserID == id })
+	maps.DeleteFunc(s.commands, func(_ string, c model.Command) bool { return c.UserID == id })
+	for key, f := range s.flags {
+		if _, ok := f.Users[id]; ok {
+			f = copyFlag(f)
+			delete(f.Users, id)
+			s.flags[key] = f
+		}
+	}
+	maps.DeleteFunc(s.identities, func(_ [2]string, i model.Identity) bool { return i.UserID == id })
+	delete(s.twoFactor, id)
+	delete(s.recovery, id)
+	maps.DeleteFunc(s.sessions, func(_ string, a model.AuthSession) bool { return a.UserID == id })
+	maps.DeleteFunc(s.apiKeys, func(_ string, k model.APIKey) bool { return k.OwnerID == id })
+	u.CreatedAt = stored.CreatedAt
+	s.users[id] = u
+	return nil
+}
+
+func (s *MemoryStore) RedactAuditEvents(ctx context.Context, userID, email string, e *model.AuditEvent, hash func(model.AuditEvent) string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	audit := slices.Clone(s.audit)
+	n, rewrite := 0, false
+	for i := range audit {
+		if audit[i].Redact(userID, email) {
+			n++
+			rewrite = true
+		}
+		if !rewrite {
+			continue
+		}
+		if i > 0 {
+			audit[i].PrevHash = audit[i-1].Hash
+		}
+		audit[i].Hash = hash(audit[i])
+	}
+	e.ID = NewID()
+	e.Seq, e.PrevHash = 1, ""
+	if n := len(audit); n > 0 {
+		e.Seq, e.PrevHash = audit[n-1].Seq+1, audit[n-1].Hash
+	}
+	e.Hash = hash(*e)
+	s.audit = append(audit, *e)
+	return n, nil
+}
+
+func (s *MemoryStore) GetTwoFactor(ctx context.Context, userID string) (model.TwoFactor, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tf, ok := s.twoFactor[userID]
+	if !ok {
+		return model.TwoFactor{}, ErrNotFound
+	}
+	return tf, nil
+}
+
+func (s *MemoryStore) SaveTwoFactor(ctx context.Context, tf model.TwoFactor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.twoFactor[tf.UserID] = tf
+	return nil
+}
+
+func (s *MemoryStore) DeleteTwoFactor(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.twoFactor[userID]; !ok {
+		return ErrNotFound
+	}
+	delete(s.twoFactor, userID)
+	delete(s.recovery, userID)
+	return nil
+}
+
+func (s *MemoryStore) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tf, ok := s.twoFactor[userID]
+	if !ok || tf.LastStep >= step {
+		return ErrConflict
+	}
+	tf.LastStep = step
+	s.twoFactor[userID] = tf
+	return nil
+}
+
+func (s *MemoryStore) SetRecoveryCodes(ctx context.Context, userID string, hashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.recovery[userID] = slices.Clone(hashes)
+	return nil
+}
+
+func (s *MemoryStore) UseRecoveryCode(ctx context.Context, userID, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	codes := s.recovery[userID]
+	i := slices.Index(codes, hash)
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.recovery[userID] = slices.Delete(slices.Clone(codes), i, i+1)
+	return nil
+}
+
+func (s *MemoryStore) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.recovery[userID]), nil
+}
+
+func (s *MemoryStore) CreateSession(ctx context.Context, sess *model.AuthSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess.ID = NewID()
+	s.sessions[sess.ID] = *sess
+	return nil
+}
+
+func (s *MemoryStore) GetSession(ctx context.Context, id string) (model.AuthSession, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sess, ok := s.sessions[id]
+	if !ok {
+		return model.AuthSession{}, ErrNotFound
+	}
+	return sess, nil
+}
+
+func (s *MemoryStore) GetSessionByToken(ctx context.Context, tokenHash string) (model.AuthSession, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, sess := range s.sessions {
+		if sess.TokenHash == tokenHash || sess.PrevTokenHash == tokenHash {
+			return sess, nil
+		}
+	}
+	return model.AuthSession{}, ErrNotFound
+}
+
+func (s *MemoryStore) ListSessions(ctx context.Context, userID string) ([]model.AuthSession, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []model.AuthSession
+	for _, sess := range s.sessions {
+		if sess.UserID == userID {
+			out = append(out, sess)
+		}
+	}
+	slices.SortFunc(out, func(a, b model.AuthSession) int {
+		if c := b.LastUsedAt.Compare(a.LastUsedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return out, nil
+}
+
+func (s *MemoryStore) UpdateSession(ctx context.Context, sess model.AuthSession, tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.sessions[sess.ID]
+	if !ok || stored.TokenHash != tokenHash {
+		return ErrNotFound
+	}
+	s.sessions[sess.ID] = sess
+	return nil
+}
+
+func (s *MemoryStore) DeleteSession(ctx context.Context, userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sess, ok := s.sessions[id]; !ok || sess.UserID != userID {
+		return ErrNotFound
+	}
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *MemoryStore) DeleteSessions(ctx context.Context, userID, keepID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for id, sess := range s.sessions {
+		if sess.UserID == userID && id != keepID {
+			delete(s.sessions, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) PurgeSessions(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for id, sess := range s.sessions {
+		if sess.LastUsedAt.Before(before) {
+			delete(s.sessions, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) PurgeableSessions(ctx context.Context, before time.Time) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, sess := range s.sessions {
+		if sess.LastUsedAt.Before(before) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) ListAPIKeys(ctx context.Context, orgID, ownerID string) ([]model.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []model.APIKey
+	for _, k := range s.apiKeys {
+		if k.OrgID == orgID && k.OwnerID == ownerID {
+			out = append(out, k)
+		}
+	}
+	slices.SortFunc(out, func(a, b model.APIKey) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return out, nil
+}
+
+func (s *MemoryStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (model.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, k := range s.apiKeys {
+		if k.KeyHash == keyHash {
+			return k, nil
+		}
+	}
+	return model.APIKey{}, ErrNotFound
+}
+
+func (s *MemoryStore) CreateAPIKey(ctx context.Context, k *model.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k.ID = NewID()
+	stored := *k
+	stored.Key = ""
+	s.apiKeys[k.ID] = stored
+	return nil
+}
+
+func (s *MemoryStore) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.apiKeys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	k.LastUsedAt = &at
+	s.apiKeys[id] = k
+	return nil
+}
+
+func (s *MemoryStore) DeleteAPIKey(ctx context.Context, ownerID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if k, ok := s.apiKeys[id]; !ok || k.OwnerID != ownerID {
+		return ErrNotFound
+	}
+	delete(s.apiKeys, id)
+	return nil
+}
+
+func (s *MemoryStore) CreateJob(ctx context.Context, j *model.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if j.ID == "" {
+		j.ID = NewID()
+	}
+	if _, ok := s.jobs[j.ID]; ok {
+		return ErrConflict
+	}
+	s.jobs[j.ID] = *j
+	return nil
+}
+
+func (s *MemoryStore) GetJob(ctx context.Context, id string) (model.Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return model.Job{}, ErrNotFound
+	}
+	return j, nil
+}
+
+func (s *MemoryStore) ListJobs(ctx context.Context, f JobFilter) ([]model.Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := []model.Job{}
+	for _, j := range s.jobs {
+		if (f.Status == "" || j.Status == f.Status) && (f.Kind == "" || j.Kind == f.Kind) {
+			out = append(out, j)
+		}
+	}
+	slices.SortFunc(out, func(a, b model.Job) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(b.ID, a.ID)
+	})
+	return page(out, 0, f.Limit), nil
+}
+
+func (s *MemoryStore) ClaimJobs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]model.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []model.Job
+	for _, j := range s.jobs {
+		if claimable(j, now) {
+			due = append(due, j)
+		}
+	}
+	slices.SortFunc(due, func(a, b model.Job) int {
+		if c := a.RunAt.Compare(b.RunAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	due = page(due, 0, limit)
+	until := now.Add(lease)
+	for i := range due {
+		due[i].Status, due[i].LockedUntil = model.JobRunning, &until
+		s.jobs[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+// claimable reports whether ClaimJobs may hand out j at now.
+func claimable(j model.Job, now time.Time) bool {
+	switch j.Status {
+	case model.JobPending:
+		return !j.RunAt.After(now)
+	case model.JobRunning:
+		return j.LockedUntil != nil && j.LockedUntil.Before(now)
+	}
+	return false
+}
+
+func (s *MemoryStore) UpdateJob(ctx context.Context, j *model.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.jobs[j.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Status, stored.Attempts, stored.LastError = j.Status, j.Attempts, j.LastError
+	stored.RunAt, stored.LockedUntil, stored.CompletedAt = j.RunAt, j.LockedUntil, j.CompletedAt
+	if j.Status == model.JobSucceeded {
+		stored.Payload = nil
+	}
+	s.jobs[j.ID] = stored
+	return nil
+}
+
+func (s *MemoryStore) DeleteJob(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.jobs, id)
+	return nil
+}
+
+func (s *MemoryStore) PurgeJobs(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for id, j := range s.jobs {
+		if j.CompletedAt != nil && j.CompletedAt.Before(before) {
+			delete(s.jobs, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) PurgeableJobs(ctx context.Context, before time.Time) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, j := range s.jobs {
+		if j.CompletedAt != nil && j.CompletedAt.Before(before) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) AppendOutbox(ctx context.Context, entries []model.OutboxEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range entries {
+		entries[i].ID = NewID()
+		e := entries[i]
+		e.Recipients = slices.Clone(e.Recipients)
+		s.outbox[e.ID] = e
+	}
+	return nil
+}
+
+func (s *MemoryStore) ClaimOutbox(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]model.OutboxEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []model.OutboxEntry
+	for _, e := range s.outbox {
+		if e.LockedUntil == nil || e.LockedUntil.Before(now) {
+			due = append(due, e)
+		}
+	}
+	slices.SortFunc(due, func(a, b model.OutboxEntry) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Seq, b.Seq); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	due = page(due, 0, limit)
+	until := now.Add(lease)
+	for i := range due {
+		due[i].LockedUntil = &until
+		s.outbox[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+func (s *MemoryStore) DeleteOutbox(ctx context.Context, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		delete(s.outbox, id)
+	}
+	return nil
+}
+
+func (s *MemoryStore) Usage(ctx context.Context, orgID string) (model.Usage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var u model.Usage
+	if orgID == "" {
+		u.Orgs, u.Users = len(s.orgs), len(s.users)
+	} else {
+		u.Users = len(s.orgMembers[orgID])
+	}
+	for _, p := range s.projects {
+		if orgID == "" || p.OrgID == orgID {
+			u.Projects++
+		}
+	}
+	inOrg := func(taskID string) bool {
+		return orgID == "" || s.tasks[taskID].OrgID == orgID
+	}
+	for _, t := range s.tasks {
+		if inOrg(t.ID) {
+			u.Tasks++
+		}
+	}
+	for _, c := range s.comments {
+		if inOrg(c.TaskID) {
+			u.Comments++
+		}
+	}
+	for _, a := range s.attachments {
+		if inOrg(a.TaskID) {
+			u.Attachments++
+			u.AttachmentBytes += a.Size
+		}
+	}
+	return u, nil
+}
+
+func (s *MemoryStore) OrgProjects(ctx context.Context, orgID string) ([]model.Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	projects := []model.Project{}
+	for _, p := range s.projects {
+		if p.OrgID == orgID {
+			projects = append(projects, cloneProject(p))
+		}
+	}
+	slices.SortFunc(projects, func(a, b model.Project) int { return oldestFirst(a.CreatedAt, b.CreatedAt, a.ID, b.ID) })
+	return projects, nil
+}
+
+func (s *MemoryStore) OrgTags(ctx context.Context, orgID string) ([]model.Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tags := []model.Tag{}
+	for _, t := range s.tags {
+		if t.OrgID == orgID {
+			tags = append(tags, t)
+		}
+	}
+	slices.SortFunc(tags, func(a, b model.Tag) int { return oldestFirst(a.CreatedAt, b.CreatedAt, a.ID, b.ID) })
+	return tags, nil
+}
+
+func (s *MemoryStore) OrgViews(ctx context.Context, orgID string) ([]model.View, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	views := []model.View{}
+	for _, v := range s.views {
+		if v.OrgID == orgID {
+			views = append(views, cloneView(v))
+		}
+	}
+	slices.SortFunc(views, func(a, b model.View) int { return oldestFirst(a.CreatedAt, b.CreatedAt, a.ID, b.ID) })
+	return views, nil
+}
+
+func (s *MemoryStore) OrgTemplates(ctx context.Context, orgID string) ([]model.Template, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	templates := []model.Template{}
+	for _, t := range s.templates {
+		if t.OrgID == orgID {
+			templates = append(templates, cloneTemplate(t))
+		}
+	}
+	slices.SortFunc(templates, func(a, b model.Template) int { return oldestFirst(a.CreatedAt, b.CreatedAt, a.ID, b.ID) })
+	return templates, nil
+}
+
+// oldestFirst orders records by creation time, then ID.
+func oldestFirst(a, b time.Time, aID, bID string) int {
+	if c := a.Compare(b); c != 0 {
+		return c
+	}
+	return strings.Compare(aID, bID)
+}
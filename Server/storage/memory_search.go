@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"starttech-server/model"
+)
+
+// Search matches words by prefix and ranks by how often the terms occur,
+// counting titles ten times. It has none of the stemming of the SQL
+// backends, which is enough for development and tests.
+func (s *MemoryStore) Search(ctx context.Context, terms []string, f TaskFilter) ([]model.SearchHit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hits := []model.SearchHit{}
+	if len(terms) == 0 {
+		return hits, nil
+	}
+	visible := map[string]model.Task{}
+	for _, t := range s.tasks {
+		if matchTask(t, f, s.isMember) {
+			visible[t.ID] = t
+		}
+	}
+	for _, t := range visible {
+		title, tn := matchText(t.Title, terms)
+		desc, dn := matchText(t.Description, terms)
+		if !covers(tn, dn) {
+			continue
+		}
+		snippet := title
+		if sum(dn) > sum(tn) {
+			snippet = desc
+		}
+		hits = append(hits, model.SearchHit{
+			Kind:    model.HitTask,
+			Task:    cloneTask(t),
+			Snippet: snippet,
+			Rank:    float64(10*sum(tn) + sum(dn)),
+		})
+	}
+	for _, c := range s.comments {
+		t, ok := visible[c.TaskID]
+		if !ok {
+			continue
+		}
+		snippet, n := matchText(c.Body, terms)
+		if !covers(n) {
+			continue
+		}
+		id := c.ID
+		hits = append(hits, model.SearchHit{
+			Kind:      model.HitComment,
+			Task:      cloneTask(t),
+			CommentID: &id,
+			Snippet:   snippet,
+			Rank:      float64(sum(n)),
+		})
+	}
+	slices.SortFunc(hits, func(a, b model.SearchHit) int {
+		if c := cmp.Compare(b.Rank, a.Rank); c != 0 {
+			return c
+		}
+		return strings.Compare(hitID(a), hitID(b))
+	})
+	return page(hits, f.Offset, f.Limit), nil
+}
+
+func hitID(h model.SearchHit) string {
+	if h.CommentID != nil {
+		return *h.CommentID
+	}
+	return h.Task.ID
+}
+
+// snippetWords bounds the words around the first match kept in a snippet.
+const snippetWords = 16
+
+// matchText counts how often each term occurs in text, the last one as a
+// word prefix, and returns an excerpt around the first match.
+func matchText(text string, terms []string) (string, []int) {
+	counts := make([]int, len(terms))
+	words := strings.Fields(text)
+	first := -1
+	marked := make([]string, len(words))
+	for i, w := range words {
+		marked[i] = w
+		for _, part := range strings.FieldsFunc(strings.ToLower(w), notWordRune) {
+			for j, term := range terms {
+				if part == term || j == len(terms)-1 && strings.HasPrefix(part, term) {
+					counts[j]++
+					marked[i] = snippetStart + w + snippetEnd
+					if first < 0 {
+						first = i
+					}
+				}
+			}
+		}
+	}
+	if first < 0 {
+		return "", counts
+	}
+	start := max(0, first-snippetWords/4)
+	end := min(len(words), start+snippetWords)
+	snippet := strings.Join(marked[start:end], " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(words) {
+		snippet += "…"
+	}
+	return markSnippet(snippet), counts
+}
+
+// covers reports whether every term was counted in at least one of the
+// fields.
+func covers(fields ...[]int) bool {
+	for j := range fields[0] {
+		found := false
+		for _, counts := range fields {
+			found = found || counts[j] > 0
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func sum(counts []int) int {
+	n := 0
+	for _, c := range counts {
+		n += c
+	}
+	return n
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"cmp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"starttech-server/model"
+)
+
+// matchTask reports whether t passes the non-paging criteria of f. member
+// reports whether a user belongs to a project.
+func matchTask(t model.Task, f TaskFilter, member func(projectID, userID string) bool) bool {
+	switch {
+	case f.Trashed != (t.DeletedAt != nil):
+		return false
+	case f.DeletedBefore != nil && (t.DeletedAt == nil || !t.DeletedAt.Before(*f.DeletedBefore)):
+		return false
+	case f.Archived != nil && *f.Archived != (t.ArchivedAt != nil):
+		return false
+	case f.Completed && !t.Completed:
+		return false
+	case f.UpdatedBefore != nil && !t.UpdatedAt.Before(*f.UpdatedBefore):
+		return false
+	case f.OrgID != "" && t.OrgID != f.OrgID:
+		return false
+	case f.OwnerID != "" && t.OwnerID != f.OwnerID:
+		return false
+	case f.VisibleTo != "" && t.ProjectID == nil && t.OwnerID != f.VisibleTo:
+		return false
+	case f.VisibleTo != "" && t.ProjectID != nil && !member(*t.ProjectID, f.VisibleTo):
+		return false
+	case f.AssigneeID != "" && (t.AssigneeID == nil || *t.AssigneeID != f.AssigneeID):
+		return false
+	case f.Unassigned && t.AssigneeID != nil:
+		return false
+	case f.ProjectID != "" && (t.ProjectID == nil || *t.ProjectID != f.ProjectID):
+		return false
+	case f.ParentID != "" && (t.ParentID == nil || *t.ParentID != f.ParentID):
+		return false
+	case f.Status != "" && t.Status != f.Status:
+		return false
+	case f.DueBefore != nil && (t.DueDate == nil || !t.DueDate.Before(*f.DueBefore)):
+		return false
+	case f.DueAfter != nil && (t.DueDate == nil || !t.DueDate.After(*f.DueAfter)):
+		return false
+	}
+	for _, id := range f.TagIDs {
+		if !slices.Contains(t.TagIDs, id) {
+			return false
+		}
+	}
+	for id, want := range f.Fields {
+		if v, ok := t.Fields[id]; !ok || v != want {
+			return false
+		}
+	}
+	return true
+}
+
+// sortTasks orders tasks the same way the SQL store does.
+func sortTasks(tasks []model.Task, by Sort) {
+	now := time.Now().UTC()
+	less := func(a, b model.Task) int {
+		if by.CustomField != "" {
+			return compareFieldValues(a.Fields[by.CustomField], b.Fields[by.CustomField])
+		}
+		switch by.Field {
+		case SortUpdatedAt:
+			return a.UpdatedAt.Compare(b.UpdatedAt)
+		case SortDueDate:
+			return compareTimes(a.DueDate, b.DueDate)
+		case SortTitle:
+			return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+		case SortStatus:
+			return strings.Compare(string(a.Status), string(b.Status))
+		case SortPosition:
+			return cmp.Compare(a.Position, b.Position)
+		case SortDeletedAt:
+			return compareTimes(a.DeletedAt, b.DeletedAt)
+		case SortPriority:
+			return cmp.Compare(a.Priority.Rank(), b.Priority.Rank())
+		case SortUrgency:
+			if c := cmp.Compare(urgency(b, now), urgency(a, now)); c != 0 {
+				return c
+			}
+			if c := compareTimes(a.DueDate, b.DueDate); c != 0 {
+				return c
+			}
+			return a.CreatedAt.Compare(b.CreatedAt)
+		}
+		return a.CreatedAt.Compare(b.CreatedAt)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		c := less(tasks[i], tasks[j])
+		if c == 0 {
+			c = strings.Compare(tasks[i].ID, tasks[j].ID)
+		}
+		if by.Desc {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+// compareTimes orders optional times with the missing ones last.
+func compareTimes(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return a.Compare(*b)
+}
+
+// compareFieldValues orders the values of one custom field with the
+// missing ones last.
+func compareFieldValues(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	switch a := a.(type) {
+	case float64:
+		b, _ := b.(float64)
+		return cmp.Compare(a, b)
+	case bool:
+		b, _ := b.(bool)
+		return cmp.Compare(boolRank(a), boolRank(b))
+	case string:
+		b, _ := b.(string)
+		return strings.Compare(a, b)
+	}
+	return 0
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// page applies an offset and limit to an already sorted slice. A zero limit
+// keeps everything after offset.
+func page[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return items[:0]
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
@@ -0,0 +1,314 @@
+package storage
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrPendingMigrations is returned by Open when the database has not been
+// migrated to the schema this build expects.
+var ErrPendingMigrations = errors.New("storage: migrations pending")
+
+// migrationFiles holds the schema changes, one pair of files per version:
+// NNNN_name.up.sql applies it and NNNN_name.down.sql undoes it. Statements
+// only one dialect understands go in NNNN_name.sqlite.up.sql and
+// NNNN_name.postgres.up.sql (and their down files) instead, and run after
+// the shared ones on the way up and before them on the way down. Applied
+// versions are recorded in the schema_migrations table, so files must
+// never change once released; change the schema with a new version.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migration is one version of the schema. Statements are keyed by dialect,
+// with "" holding those every dialect understands.
+type migration struct {
+	version  int
+	name     string
+	up, down map[Dialect][]string
+}
+
+// statements returns what m runs on d, up or down.
+func (m migration) statements(d Dialect, up bool) []string {
+	if up {
+		return append(append([]string(nil), m.up[""]...), m.up[d]...)
+	}
+	return append(append([]string(nil), m.down[d]...), m.down[""]...)
+}
+
+// reversible reports whether m has down files.
+func (m migration) reversible() bool {
+	return len(m.down) > 0
+}
+
+// migrations are the versions of the schema in order, the first being 1.
+var migrations = mustLoadMigrations(migrationFiles)
+
+func mustLoadMigrations(fsys fs.FS) []migration {
+	ms, err := loadMigrations(fsys)
+	if err != nil {
+		panic(err)
+	}
+	return ms
+}
+
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	names, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*migration{}
+	for _, file := range names {
+		base := strings.TrimSuffix(path.Base(file), ".sql")
+		base, up := strings.CutSuffix(base, ".up")
+		if !up {
+			var down bool
+			if base, down = strings.CutSuffix(base, ".down"); !down {
+				return nil, fmt.Errorf("storage: migration %s is neither .up.sql nor .down.sql", file)
+			}
+		}
+		var dialect Dialect
+		for _, d := range []Dialect{SQLite, Postgres} {
+			if b, ok := strings.CutSuffix(base, "."+string(d)); ok {
+				base, dialect = b, d
+			}
+		}
+		num, name, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if err != nil || version < 1 || name == "" {
+			return nil, fmt.Errorf("storage: migration %s is not named NNNN_name", file)
+		}
+
+		src, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		stmts, err := splitStatements(string(src))
+		if err != nil {
+			return nil, fmt.Errorf("storage: migration %s: %w", file, err)
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &migration{version: version, name: name, up: map[Dialect][]string{}, down: map[Dialect][]string{}}
+			byVersion[version] = m
+		} else if m.name != name {
+			return nil, fmt.Errorf("storage: migration %d is named both %s and %s", version, m.name, name)
+		}
+		if up {
+			m.up[dialect] = stmts
+		} else {
+			m.down[dialect] = stmts
+		}
+	}
+
+	ms := make([]migration, len(byVersion))
+	for v, m := range byVersion {
+		if v > len(ms) {
+			return nil, fmt.Errorf("storage: migrations skip from %d to %d", len(ms), v)
+		}
+		if len(m.up) == 0 {
+			return nil, fmt.Errorf("storage: migration %d has no up file", v)
+		}
+		ms[v-1] = *m
+	}
+	return ms, nil
+}
+
+// splitStatements splits a migration file into its statements, each ending
+// with a semicolon at the end of a line. Lines starting with "--" are
+// comments. A line ending in BEGIN opens a block, such as a trigger body,
+// that only a line reading "END;" closes.
+func splitStatements(src string) ([]string, error) {
+	var stmts []string
+	var b strings.Builder
+	inBlock := false
+	for _, line := range strings.Split(src, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" && b.Len() == 0 || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		b.WriteString(strings.TrimRight(line, " \t\r"))
+		b.WriteByte('\n')
+		upper := strings.ToUpper(trimmed)
+		if strings.HasSuffix(upper, "BEGIN") {
+			inBlock = true
+		}
+		if strings.HasSuffix(trimmed, ";") && (!inBlock || upper == "END;") {
+			stmts = append(stmts, strings.TrimSuffix(strings.TrimSpace(b.String()), ";"))
+			b.Reset()
+			inBlock = false
+		}
+	}
+	if strings.TrimSpace(b.String()) != "" {
+		return nil, errors.New("last statement does not end with a semicolon")
+	}
+	return stmts, nil
+}
+
+// LatestMigration is the schema version this build expects.
+func LatestMigration() int {
+	return migrations[len(migrations)-1].version
+}
+
+// MigrationStatus describes one version of the schema.
+type MigrationStatus struct {
+	Version int
+	Name    string
+	// AppliedAt is when the version was applied, or nil if it is pending.
+	AppliedAt *time.Time
+	// Reversible is whether the version can be undone.
+	Reversible bool
+}
+
+// Migrate applies any migrations that have not yet been run.
+func (s *SQLStore) Migrate(ctx context.Context) error {
+	return s.MigrateTo(ctx, LatestMigration())
+}
+
+// MigrateTo brings the schema to version target, applying the migrations
+// after the current version or undoing those after target, one transaction
+// per version. Target 0 undoes every migration.
+func (s *SQLStore) MigrateTo(ctx context.Context, target int) error {
+	if target < 0 || target > LatestMigration() {
+		return fmt.Errorf("no schema version %d; the latest is %d", target, LatestMigration())
+	}
+	if err := s.createMigrationsTable(ctx); err != nil {
+		return err
+	}
+	current, err := s.schemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	// Check the whole way down before taking the first step.
+	for v := current; v > target; v-- {
+		if !migrations[v-1].reversible() {
+			return fmt.Errorf("migration %d (%s) cannot be undone", v, migrations[v-1].name)
+		}
+	}
+
+	for ; current < target; current++ {
+		m := migrations[current]
+		if err := s.apply(ctx, m, true); err != nil {
+			return fmt.Errorf("applying migration %d (%s): %w", m.version, m.name, err)
+		}
+		slog.InfoContext(ctx, "applied migration", "version", m.version, "name", m.name)
+	}
+	for ; current > target; current-- {
+		m := migrations[current-1]
+		if err := s.apply(ctx, m, false); err != nil {
+			return fmt.Errorf("undoing migration %d (%s): %w", m.version, m.name, err)
+		}
+		slog.InfoContext(ctx, "undid migration", "version", m.version, "name", m.name)
+	}
+	return nil
+}
+
+// Migrations reports every version of the schema and whether it has been
+// applied.
+func (s *SQLStore) Migrations(ctx context.Context) ([]MigrationStatus, error) {
+	if err := s.createMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+	rs, err := s.query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema_migrations: %w", err)
+	}
+	defer rs.Close()
+	applied := map[int]time.Time{}
+	for rs.Next() {
+		var version int
+		var at time.Time
+		if err := rs.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	if err := rs.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		out[i] = MigrationStatus{Version: m.version, Name: m.name, Reversible: m.reversible()}
+		if at, ok := applied[m.version]; ok {
+			out[i].AppliedAt = &at
+		}
+	}
+	return out, nil
+}
+
+// Ready pings the database and checks that no migration is pending, as
+// happens while another instance is still migrating it.
+func (s *SQLStore) Ready(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return err
+	}
+	return s.checkSchema(ctx)
+}
+
+// checkSchema returns an error wrapping ErrPendingMigrations if the schema
+// is behind LatestMigration.
+func (s *SQLStore) checkSchema(ctx context.Context) error {
+	current, err := s.schemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if latest := LatestMigration(); current < latest {
+		return fmt.Errorf("%w: schema is at version %d of %d", ErrPendingMigrations, current, latest)
+	}
+	return nil
+}
+
+func (s *SQLStore) createMigrationsTable(ctx context.Context) error {
+	_, err := s.exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// schemaVersion returns the latest version applied, 0 for a new database.
+// The schema_migrations table must exist.
+func (s *SQLStore) schemaVersion(ctx context.Context) (int, error) {
+	var current int
+	if err := s.queryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return 0, fmt.Errorf("reading schema version: %w", err)
+	}
+	return current, nil
+}
+
+func (s *SQLStore) apply(ctx context.Context, m migration, up bool) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range m.statements(s.dialect, up) {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if up {
+		_, err = tx.ExecContext(ctx, s.rebind(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`),
+			m.version, time.Now().UTC())
+	} else {
+		_, err = tx.ExecContext(ctx, s.rebind(`DELETE FROM schema_migrations WHERE version = ?`), m.version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
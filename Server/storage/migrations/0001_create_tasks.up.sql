@@ -0,0 +1,10 @@
+CREATE TABLE tasks (
+	id          TEXT PRIMARY KEY,
+	title       TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	completed   BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at  TIMESTAMP NOT NULL,
+	updated_at  TIMESTAMP NOT NULL
+);
+
+CREATE INDEX tasks_created_at ON tasks (created_at);
@@ -0,0 +1,11 @@
+CREATE TABLE users (
+	id            TEXT PRIMARY KEY,
+	email         TEXT NOT NULL,
+	username      TEXT NOT NULL,
+	password_hash TEXT NOT NULL,
+	created_at    TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX users_email ON users (LOWER(email));
+
+CREATE UNIQUE INDEX users_username ON users (LOWER(username));
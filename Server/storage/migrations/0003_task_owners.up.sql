@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN owner_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX tasks_owner_id ON tasks (owner_id, created_at);
@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN status TEXT NOT NULL DEFAULT 'todo';
+
+UPDATE tasks SET status = 'done' WHERE completed;
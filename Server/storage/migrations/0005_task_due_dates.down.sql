@@ -0,0 +1,3 @@
+DROP INDEX tasks_owner_due_date;
+
+ALTER TABLE tasks DROP COLUMN due_date;
@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN due_date TIMESTAMP;
+
+CREATE INDEX tasks_owner_due_date ON tasks (owner_id, due_date);
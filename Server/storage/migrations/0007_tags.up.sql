@@ -0,0 +1,17 @@
+CREATE TABLE tags (
+	id         TEXT PRIMARY KEY,
+	owner_id   TEXT NOT NULL,
+	name       TEXT NOT NULL,
+	color      TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX tags_owner_name ON tags (owner_id, LOWER(name));
+
+CREATE TABLE task_tags (
+	task_id TEXT NOT NULL,
+	tag_id  TEXT NOT NULL,
+	PRIMARY KEY (task_id, tag_id)
+);
+
+CREATE INDEX task_tags_tag_id ON task_tags (tag_id);
@@ -0,0 +1,7 @@
+DROP INDEX tasks_project_position;
+
+ALTER TABLE tasks DROP COLUMN position;
+
+ALTER TABLE tasks DROP COLUMN project_id;
+
+DROP TABLE projects;
@@ -0,0 +1,16 @@
+CREATE TABLE projects (
+	id          TEXT PRIMARY KEY,
+	owner_id    TEXT NOT NULL,
+	name        TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	created_at  TIMESTAMP NOT NULL,
+	updated_at  TIMESTAMP NOT NULL
+);
+
+CREATE INDEX projects_owner_id ON projects (owner_id);
+
+ALTER TABLE tasks ADD COLUMN project_id TEXT;
+
+ALTER TABLE tasks ADD COLUMN position DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+CREATE INDEX tasks_project_position ON tasks (project_id, position);
@@ -0,0 +1 @@
+ALTER TABLE projects ADD COLUMN statuses TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+DROP TABLE reminders;
+
+ALTER TABLE tasks DROP COLUMN remind_at;
@@ -0,0 +1,14 @@
+ALTER TABLE tasks ADD COLUMN remind_at TIMESTAMP;
+
+CREATE TABLE reminders (
+	id       TEXT PRIMARY KEY,
+	task_id  TEXT NOT NULL,
+	owner_id TEXT NOT NULL,
+	kind     TEXT NOT NULL,
+	fire_at  TIMESTAMP NOT NULL,
+	sent_at  TIMESTAMP
+);
+
+CREATE INDEX reminders_task_id ON reminders (task_id);
+
+CREATE INDEX reminders_fire_at ON reminders (fire_at);
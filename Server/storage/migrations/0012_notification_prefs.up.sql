@@ -0,0 +1,6 @@
+CREATE TABLE notification_prefs (
+	user_id   TEXT PRIMARY KEY,
+	assigned  BOOLEAN NOT NULL,
+	due_soon  BOOLEAN NOT NULL,
+	mentioned BOOLEAN NOT NULL
+);
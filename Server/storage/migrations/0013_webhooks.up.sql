@@ -0,0 +1,29 @@
+CREATE TABLE webhooks (
+	id         TEXT PRIMARY KEY,
+	owner_id   TEXT NOT NULL,
+	url        TEXT NOT NULL,
+	events     TEXT NOT NULL DEFAULT '',
+	active     BOOLEAN NOT NULL,
+	secret     TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX webhooks_owner_id ON webhooks (owner_id, created_at);
+
+CREATE TABLE webhook_deliveries (
+	id              TEXT PRIMARY KEY,
+	webhook_id      TEXT NOT NULL,
+	event           TEXT NOT NULL,
+	payload         TEXT NOT NULL,
+	status          TEXT NOT NULL,
+	attempts        INTEGER NOT NULL DEFAULT 0,
+	response_status INTEGER NOT NULL DEFAULT 0,
+	last_error      TEXT NOT NULL DEFAULT '',
+	next_attempt_at TIMESTAMP,
+	created_at      TIMESTAMP NOT NULL,
+	completed_at    TIMESTAMP
+);
+
+CREATE INDEX webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id, created_at);
+
+CREATE INDEX webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
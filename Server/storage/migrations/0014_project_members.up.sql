@@ -0,0 +1,12 @@
+CREATE TABLE project_members (
+	project_id TEXT NOT NULL,
+	user_id    TEXT NOT NULL,
+	role       TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (project_id, user_id)
+);
+
+CREATE INDEX project_members_user_id ON project_members (user_id);
+
+INSERT INTO project_members (project_id, user_id, role, created_at)
+SELECT id, owner_id, 'owner', created_at FROM projects;
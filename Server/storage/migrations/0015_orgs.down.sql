@@ -0,0 +1,24 @@
+DROP INDEX webhooks_org_id;
+
+ALTER TABLE webhooks DROP COLUMN org_id;
+
+-- Fails if a user has tags of the same name in two organizations.
+DROP INDEX tags_org_owner_name;
+
+CREATE UNIQUE INDEX tags_owner_name ON tags (owner_id, LOWER(name));
+
+ALTER TABLE tags DROP COLUMN org_id;
+
+DROP INDEX tasks_org_id;
+
+ALTER TABLE tasks DROP COLUMN org_id;
+
+DROP INDEX projects_org_id;
+
+ALTER TABLE projects DROP COLUMN org_id;
+
+DROP TABLE org_invitations;
+
+DROP TABLE org_members;
+
+DROP TABLE orgs;
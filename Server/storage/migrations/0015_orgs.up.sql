@@ -0,0 +1,69 @@
+CREATE TABLE orgs (
+	id         TEXT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE org_members (
+	org_id     TEXT NOT NULL,
+	user_id    TEXT NOT NULL,
+	role       TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX org_members_user_id ON org_members (user_id, created_at);
+
+CREATE TABLE org_invitations (
+	id          TEXT PRIMARY KEY,
+	org_id      TEXT NOT NULL,
+	email       TEXT NOT NULL,
+	role        TEXT NOT NULL,
+	invited_by  TEXT NOT NULL,
+	token_hash  TEXT NOT NULL,
+	created_at  TIMESTAMP NOT NULL,
+	expires_at  TIMESTAMP NOT NULL,
+	accepted_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX org_invitations_token_hash ON org_invitations (token_hash);
+
+CREATE INDEX org_invitations_org_id ON org_invitations (org_id, created_at);
+
+-- Every existing user gets a personal organization sharing their
+-- ID, and everything they own moves into it. Members of a shared
+-- project join its owner's organization, as do the project's tasks.
+INSERT INTO orgs (id, name, created_at) SELECT id, username || '''s workspace', created_at FROM users;
+
+INSERT INTO org_members (org_id, user_id, role, created_at) SELECT id, id, 'owner', created_at FROM users;
+
+INSERT INTO org_members (org_id, user_id, role, created_at)
+SELECT p.owner_id, m.user_id, 'member', MIN(m.created_at)
+FROM project_members m JOIN projects p ON p.id = m.project_id
+WHERE m.user_id <> p.owner_id GROUP BY p.owner_id, m.user_id;
+
+ALTER TABLE projects ADD COLUMN org_id TEXT NOT NULL DEFAULT '';
+
+UPDATE projects SET org_id = owner_id;
+
+CREATE INDEX projects_org_id ON projects (org_id);
+
+ALTER TABLE tasks ADD COLUMN org_id TEXT NOT NULL DEFAULT '';
+
+UPDATE tasks SET org_id = COALESCE((SELECT p.org_id FROM projects p WHERE p.id = tasks.project_id), owner_id);
+
+CREATE INDEX tasks_org_id ON tasks (org_id, owner_id);
+
+ALTER TABLE tags ADD COLUMN org_id TEXT NOT NULL DEFAULT '';
+
+UPDATE tags SET org_id = owner_id;
+
+DROP INDEX tags_owner_name;
+
+CREATE UNIQUE INDEX tags_org_owner_name ON tags (org_id, owner_id, LOWER(name));
+
+ALTER TABLE webhooks ADD COLUMN org_id TEXT NOT NULL DEFAULT '';
+
+UPDATE webhooks SET org_id = owner_id;
+
+CREATE INDEX webhooks_org_id ON webhooks (org_id, owner_id);
@@ -0,0 +1,19 @@
+CREATE TABLE comments (
+	id         TEXT PRIMARY KEY,
+	task_id    TEXT NOT NULL,
+	author_id  TEXT NOT NULL,
+	reply_to   TEXT,
+	body       TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	edited_at  TIMESTAMP
+);
+
+CREATE INDEX comments_task_id ON comments (task_id, created_at);
+
+CREATE TABLE comment_edits (
+	comment_id TEXT NOT NULL,
+	body       TEXT NOT NULL,
+	edited_at  TIMESTAMP NOT NULL
+);
+
+CREATE INDEX comment_edits_comment_id ON comment_edits (comment_id, edited_at);
@@ -0,0 +1,12 @@
+CREATE TABLE attachments (
+	id           TEXT PRIMARY KEY,
+	task_id      TEXT NOT NULL,
+	uploader_id  TEXT NOT NULL,
+	filename     TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size         BIGINT NOT NULL,
+	blob_key     TEXT NOT NULL,
+	created_at   TIMESTAMP NOT NULL
+);
+
+CREATE INDEX attachments_task_id ON attachments (task_id, created_at);
@@ -0,0 +1,7 @@
+DROP INDEX comments_search;
+
+ALTER TABLE comments DROP COLUMN search;
+
+DROP INDEX tasks_search;
+
+ALTER TABLE tasks DROP COLUMN search;
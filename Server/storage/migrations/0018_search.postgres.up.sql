@@ -0,0 +1,10 @@
+-- Generated tsvector columns, titles weighted above descriptions.
+ALTER TABLE tasks ADD COLUMN search tsvector GENERATED ALWAYS AS (
+	setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', description), 'B')
+) STORED;
+
+CREATE INDEX tasks_search ON tasks USING GIN (search);
+
+ALTER TABLE comments ADD COLUMN search tsvector GENERATED ALWAYS AS (to_tsvector('english', body)) STORED;
+
+CREATE INDEX comments_search ON comments USING GIN (search);
@@ -0,0 +1,13 @@
+DROP TRIGGER comments_search_delete;
+
+DROP TRIGGER comments_search_update;
+
+DROP TRIGGER comments_search_insert;
+
+DROP TRIGGER tasks_search_delete;
+
+DROP TRIGGER tasks_search_update;
+
+DROP TRIGGER tasks_search_insert;
+
+DROP TABLE search_index;
@@ -0,0 +1,39 @@
+-- One FTS5 index over task titles and descriptions and comment bodies,
+-- kept current by triggers.
+CREATE VIRTUAL TABLE search_index USING fts5(
+	heading, body, kind UNINDEXED, ref_id UNINDEXED, task_id UNINDEXED,
+	tokenize = 'porter unicode61 remove_diacritics 2'
+);
+
+INSERT INTO search_index (heading, body, kind, ref_id, task_id)
+	SELECT title, description, 'task', id, id FROM tasks;
+
+INSERT INTO search_index (heading, body, kind, ref_id, task_id)
+	SELECT '', body, 'comment', id, task_id FROM comments;
+
+CREATE TRIGGER tasks_search_insert AFTER INSERT ON tasks BEGIN
+	INSERT INTO search_index (heading, body, kind, ref_id, task_id)
+		VALUES (new.title, new.description, 'task', new.id, new.id);
+END;
+
+CREATE TRIGGER tasks_search_update AFTER UPDATE OF title, description ON tasks BEGIN
+	UPDATE search_index SET heading = new.title, body = new.description
+		WHERE kind = 'task' AND ref_id = old.id;
+END;
+
+CREATE TRIGGER tasks_search_delete AFTER DELETE ON tasks BEGIN
+	DELETE FROM search_index WHERE kind = 'task' AND ref_id = old.id;
+END;
+
+CREATE TRIGGER comments_search_insert AFTER INSERT ON comments BEGIN
+	INSERT INTO search_index (heading, body, kind, ref_id, task_id)
+		VALUES ('', new.body, 'comment', new.id, new.task_id);
+END;
+
+CREATE TRIGGER comments_search_update AFTER UPDATE OF body ON comments BEGIN
+	UPDATE search_index SET body = new.body WHERE kind = 'comment' AND ref_id = old.id;
+END;
+
+CREATE TRIGGER comments_search_delete AFTER DELETE ON comments BEGIN
+	DELETE FROM search_index WHERE kind = 'comment' AND ref_id = old.id;
+END;
@@ -0,0 +1,15 @@
+CREATE TABLE activity (
+	id         TEXT PRIMARY KEY,
+	org_id     TEXT NOT NULL,
+	project_id TEXT,
+	task_id    TEXT,
+	actor_id   TEXT NOT NULL,
+	action     TEXT NOT NULL,
+	subject_id TEXT NOT NULL,
+	changes    TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX activity_task_id ON activity (task_id, created_at);
+
+CREATE INDEX activity_project_id ON activity (project_id, created_at);
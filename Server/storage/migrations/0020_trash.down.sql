@@ -0,0 +1,3 @@
+DROP INDEX tasks_deleted_at;
+
+ALTER TABLE tasks DROP COLUMN deleted_at;
@@ -0,0 +1,13 @@
+CREATE TABLE idempotency_keys (
+	user_id     TEXT NOT NULL,
+	key         TEXT NOT NULL,
+	fingerprint TEXT NOT NULL,
+	done        BOOLEAN NOT NULL DEFAULT FALSE,
+	status      INTEGER NOT NULL DEFAULT 0,
+	header      TEXT NOT NULL DEFAULT '{}',
+	body        TEXT NOT NULL DEFAULT '',
+	created_at  TIMESTAMP NOT NULL,
+	PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX idempotency_keys_created_at ON idempotency_keys (created_at);
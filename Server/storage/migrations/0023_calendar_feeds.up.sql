@@ -0,0 +1,9 @@
+CREATE TABLE calendar_feeds (
+	org_id     TEXT NOT NULL,
+	user_id    TEXT NOT NULL,
+	token_hash TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (org_id, user_id)
+);
+
+CREATE UNIQUE INDEX calendar_feeds_token_hash ON calendar_feeds (token_hash);
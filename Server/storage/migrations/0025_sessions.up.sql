@@ -0,0 +1,18 @@
+CREATE TABLE sessions (
+	id              TEXT PRIMARY KEY,
+	user_id         TEXT NOT NULL,
+	org_id          TEXT NOT NULL,
+	user_agent      TEXT NOT NULL DEFAULT '',
+	token_hash      TEXT NOT NULL,
+	prev_token_hash TEXT NOT NULL DEFAULT '',
+	created_at      TIMESTAMP NOT NULL,
+	last_used_at    TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX sessions_token_hash ON sessions (token_hash);
+
+CREATE INDEX sessions_prev_token_hash ON sessions (prev_token_hash);
+
+CREATE INDEX sessions_user_id ON sessions (user_id, last_used_at);
+
+CREATE INDEX sessions_last_used_at ON sessions (last_used_at);
@@ -0,0 +1,15 @@
+CREATE TABLE api_keys (
+	id           TEXT PRIMARY KEY,
+	org_id       TEXT NOT NULL,
+	owner_id     TEXT NOT NULL,
+	name         TEXT NOT NULL,
+	scope        TEXT NOT NULL,
+	prefix       TEXT NOT NULL,
+	key_hash     TEXT NOT NULL,
+	created_at   TIMESTAMP NOT NULL,
+	last_used_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX api_keys_key_hash ON api_keys (key_hash);
+
+CREATE INDEX api_keys_owner_id ON api_keys (org_id, owner_id, created_at);
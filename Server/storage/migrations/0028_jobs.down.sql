@@ -0,0 +1,3 @@
+-- Pending webhook deliveries keep their rows in webhook_deliveries, but
+-- nothing is left to send them.
+DROP TABLE jobs;
@@ -0,0 +1,26 @@
+CREATE TABLE jobs (
+	id           TEXT PRIMARY KEY,
+	kind         TEXT NOT NULL,
+	payload      TEXT,
+	status       TEXT NOT NULL,
+	attempts     INTEGER NOT NULL DEFAULT 0,
+	max_attempts INTEGER NOT NULL,
+	last_error   TEXT NOT NULL DEFAULT '',
+	run_at       TIMESTAMP NOT NULL,
+	locked_until TIMESTAMP,
+	created_at   TIMESTAMP NOT NULL,
+	completed_at TIMESTAMP
+);
+
+CREATE INDEX jobs_status_run_at ON jobs (status, run_at);
+
+CREATE INDEX jobs_created_at ON jobs (created_at);
+
+CREATE INDEX jobs_completed_at ON jobs (completed_at);
+
+-- Webhook deliveries are now sent by jobs; give the pending ones
+-- theirs.
+INSERT INTO jobs (id, kind, payload, status, attempts, max_attempts, last_error, run_at, created_at)
+SELECT 'webhook-' || id, 'webhook.deliver', '{"delivery_id":"' || id || '"}', 'pending', attempts, 8,
+	last_error, COALESCE(next_attempt_at, created_at), created_at
+FROM webhook_deliveries WHERE status = 'pending';
@@ -0,0 +1,3 @@
+DROP INDEX tasks_assignee_id;
+
+ALTER TABLE tasks DROP COLUMN assignee_id;
@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN assignee_id TEXT;
+
+CREATE INDEX tasks_assignee_id ON tasks (assignee_id);
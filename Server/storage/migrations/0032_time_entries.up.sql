@@ -0,0 +1,17 @@
+CREATE TABLE time_entries (
+	id         TEXT PRIMARY KEY,
+	org_id     TEXT NOT NULL,
+	task_id    TEXT NOT NULL,
+	user_id    TEXT NOT NULL,
+	started_at TIMESTAMP NOT NULL,
+	ended_at   TIMESTAMP,
+	note       TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX time_entries_task_id ON time_entries (task_id, started_at);
+
+CREATE INDEX time_entries_org_started_at ON time_entries (org_id, started_at);
+
+-- A user runs one timer at a time.
+CREATE UNIQUE INDEX time_entries_running ON time_entries (user_id) WHERE ended_at IS NULL;
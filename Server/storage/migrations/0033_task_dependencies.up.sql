@@ -0,0 +1,8 @@
+CREATE TABLE task_dependencies (
+	task_id    TEXT NOT NULL,
+	blocker_id TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (task_id, blocker_id)
+);
+
+CREATE INDEX task_dependencies_blocker_id ON task_dependencies (blocker_id);
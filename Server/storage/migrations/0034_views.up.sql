@@ -0,0 +1,11 @@
+CREATE TABLE views (
+	id         TEXT PRIMARY KEY,
+	org_id     TEXT NOT NULL,
+	owner_id   TEXT NOT NULL,
+	name       TEXT NOT NULL,
+	filter     TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX views_org_owner_name ON views (org_id, owner_id, LOWER(name));
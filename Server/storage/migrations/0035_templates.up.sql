@@ -0,0 +1,12 @@
+CREATE TABLE templates (
+	id         TEXT PRIMARY KEY,
+	org_id     TEXT NOT NULL,
+	owner_id   TEXT NOT NULL,
+	name       TEXT NOT NULL,
+	kind       TEXT NOT NULL,
+	body       TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX templates_org_owner_name ON templates (org_id, owner_id, LOWER(name));
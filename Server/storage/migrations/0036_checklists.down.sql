@@ -0,0 +1,5 @@
+ALTER TABLE tasks DROP COLUMN checklist_total;
+
+ALTER TABLE tasks DROP COLUMN checklist_done;
+
+DROP TABLE checklist_items;
@@ -0,0 +1,15 @@
+CREATE TABLE checklist_items (
+	id         TEXT PRIMARY KEY,
+	task_id    TEXT NOT NULL,
+	title      TEXT NOT NULL,
+	done       BOOLEAN NOT NULL,
+	position   INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX checklist_items_task_id ON checklist_items (task_id, position);
+
+ALTER TABLE tasks ADD COLUMN checklist_done INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE tasks ADD COLUMN checklist_total INTEGER NOT NULL DEFAULT 0;
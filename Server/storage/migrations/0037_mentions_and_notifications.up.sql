@@ -0,0 +1,29 @@
+CREATE TABLE mentions (
+	id         TEXT PRIMARY KEY,
+	task_id    TEXT NOT NULL,
+	comment_id TEXT NOT NULL,
+	user_id    TEXT NOT NULL,
+	author_id  TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX mentions_comment_id ON mentions (comment_id);
+
+CREATE INDEX mentions_task_id ON mentions (task_id);
+
+CREATE TABLE notifications (
+	id         TEXT PRIMARY KEY,
+	org_id     TEXT NOT NULL,
+	user_id    TEXT NOT NULL,
+	kind       TEXT NOT NULL,
+	actor_id   TEXT NOT NULL,
+	task_id    TEXT NOT NULL,
+	comment_id TEXT,
+	title      TEXT NOT NULL,
+	read_at    TIMESTAMP,
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX notifications_org_user ON notifications (org_id, user_id, created_at);
+
+CREATE INDEX notifications_task_id ON notifications (task_id);
@@ -0,0 +1,7 @@
+CREATE TABLE task_revisions (
+	task_id    TEXT NOT NULL,
+	version    BIGINT NOT NULL,
+	task       TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (task_id, version)
+);
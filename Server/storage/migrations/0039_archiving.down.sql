@@ -0,0 +1,3 @@
+ALTER TABLE projects DROP COLUMN archived_at;
+
+ALTER TABLE tasks DROP COLUMN archived_at;
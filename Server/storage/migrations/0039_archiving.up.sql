@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN archived_at TIMESTAMP;
+
+ALTER TABLE projects ADD COLUMN archived_at TIMESTAMP;
@@ -0,0 +1,3 @@
+DROP TABLE issue_links;
+
+DROP TABLE github_links;
@@ -0,0 +1,19 @@
+CREATE TABLE github_links (
+	project_id TEXT PRIMARY KEY,
+	org_id     TEXT NOT NULL,
+	repo       TEXT NOT NULL,
+	token      TEXT NOT NULL,
+	secret     TEXT NOT NULL,
+	created_by TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	synced_at  TIMESTAMP
+);
+
+CREATE TABLE issue_links (
+	task_id    TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	number     INTEGER NOT NULL,
+	state      TEXT NOT NULL
+);
+
+CREATE UNIQUE INDEX issue_links_project_number ON issue_links (project_id, number);
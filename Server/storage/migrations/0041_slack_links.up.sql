@@ -0,0 +1,9 @@
+CREATE TABLE slack_links (
+	project_id     TEXT PRIMARY KEY,
+	org_id         TEXT NOT NULL,
+	webhook_url    TEXT NOT NULL,
+	signing_secret TEXT NOT NULL,
+	events         TEXT NOT NULL,
+	created_by     TEXT NOT NULL,
+	created_at     TIMESTAMP NOT NULL
+);
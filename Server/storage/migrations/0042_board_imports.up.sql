@@ -0,0 +1,16 @@
+CREATE TABLE board_imports (
+	id          TEXT PRIMARY KEY,
+	org_id      TEXT NOT NULL,
+	user_id     TEXT NOT NULL,
+	source      TEXT NOT NULL,
+	status      TEXT NOT NULL,
+	total       INTEGER NOT NULL,
+	done        INTEGER NOT NULL,
+	summary     TEXT NOT NULL,
+	error       TEXT NOT NULL,
+	boards      TEXT NOT NULL,
+	created_at  TIMESTAMP NOT NULL,
+	finished_at TIMESTAMP
+);
+
+CREATE INDEX board_imports_user ON board_imports (org_id, user_id, created_at);
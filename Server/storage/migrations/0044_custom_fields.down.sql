@@ -0,0 +1,3 @@
+DROP TABLE task_field_values;
+
+DROP TABLE custom_fields;
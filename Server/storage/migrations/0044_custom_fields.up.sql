@@ -0,0 +1,21 @@
+CREATE TABLE custom_fields (
+	id         TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	name       TEXT NOT NULL,
+	type       TEXT NOT NULL,
+	options    TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX custom_fields_project_name ON custom_fields (project_id, LOWER(name));
+
+CREATE TABLE task_field_values (
+	task_id  TEXT NOT NULL,
+	field_id TEXT NOT NULL,
+	value    TEXT NOT NULL,
+	number   DOUBLE PRECISION,
+	PRIMARY KEY (task_id, field_id)
+);
+
+CREATE INDEX task_field_values_field ON task_field_values (field_id, value);
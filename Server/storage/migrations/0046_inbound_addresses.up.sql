@@ -0,0 +1,9 @@
+CREATE TABLE inbound_addresses (
+	org_id     TEXT NOT NULL,
+	user_id    TEXT NOT NULL,
+	token_hash TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (org_id, user_id)
+);
+
+CREATE UNIQUE INDEX inbound_addresses_token_hash ON inbound_addresses (token_hash);
@@ -0,0 +1,16 @@
+CREATE TABLE audit_events (
+	seq        BIGINT PRIMARY KEY,
+	id         TEXT NOT NULL UNIQUE,
+	action     TEXT NOT NULL,
+	actor_id   TEXT NOT NULL,
+	org_id     TEXT NOT NULL,
+	target_id  TEXT NOT NULL,
+	ip         TEXT NOT NULL,
+	user_agent TEXT NOT NULL,
+	detail     TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	prev_hash  TEXT NOT NULL,
+	hash       TEXT NOT NULL
+);
+
+CREATE INDEX audit_events_created_at ON audit_events (created_at);
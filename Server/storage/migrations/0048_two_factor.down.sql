@@ -0,0 +1,5 @@
+ALTER TABLE orgs DROP COLUMN require_two_factor;
+
+DROP TABLE recovery_codes;
+
+DROP TABLE user_two_factor;
@@ -0,0 +1,15 @@
+CREATE TABLE user_two_factor (
+	user_id    TEXT PRIMARY KEY,
+	secret     TEXT NOT NULL,
+	enabled_at TIMESTAMP,
+	last_step  BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE recovery_codes (
+	user_id   TEXT NOT NULL,
+	code_hash TEXT NOT NULL,
+	PRIMARY KEY (user_id, code_hash)
+);
+
+ALTER TABLE orgs ADD COLUMN require_two_factor BOOLEAN NOT NULL DEFAULT FALSE;
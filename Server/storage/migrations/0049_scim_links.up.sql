@@ -0,0 +1,10 @@
+CREATE TABLE scim_links (
+	org_id      TEXT NOT NULL,
+	user_id     TEXT NOT NULL,
+	user_name   TEXT NOT NULL,
+	external_id TEXT NOT NULL DEFAULT '',
+	active      BOOLEAN NOT NULL,
+	created_at  TIMESTAMP NOT NULL,
+	updated_at  TIMESTAMP NOT NULL,
+	PRIMARY KEY (org_id, user_id)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE sso_configs (
+	org_id        TEXT PRIMARY KEY,
+	domain        TEXT NOT NULL UNIQUE,
+	issuer        TEXT NOT NULL,
+	client_id     TEXT NOT NULL,
+	client_secret TEXT NOT NULL,
+	groups_claim  TEXT NOT NULL,
+	group_roles   TEXT NOT NULL DEFAULT '[]',
+	enforced      BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at    TIMESTAMP NOT NULL,
+	updated_at    TIMESTAMP NOT NULL
+);
@@ -0,0 +1 @@
+DROP TABLE task_watchers;
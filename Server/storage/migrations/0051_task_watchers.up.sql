@@ -0,0 +1,8 @@
+CREATE TABLE task_watchers (
+	task_id    TEXT NOT NULL,
+	user_id    TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (task_id, user_id)
+);
+
+CREATE INDEX task_watchers_user_id ON task_watchers (user_id);
@@ -0,0 +1,10 @@
+CREATE TABLE undo_commands (
+	id         TEXT PRIMARY KEY,
+	org_id     TEXT NOT NULL,
+	user_id    TEXT NOT NULL,
+	action     TEXT NOT NULL,
+	steps      TEXT NOT NULL DEFAULT '[]',
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX undo_commands_user_id ON undo_commands (user_id, org_id, created_at);
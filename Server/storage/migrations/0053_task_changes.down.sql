@@ -0,0 +1 @@
+DROP INDEX tasks_org_updated_at;
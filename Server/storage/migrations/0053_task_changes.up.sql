@@ -0,0 +1,3 @@
+-- The change feed of GET /sync/changes walks an organization's tasks in the
+-- order they were saved.
+CREATE INDEX tasks_org_updated_at ON tasks (org_id, updated_at, id);
@@ -0,0 +1,13 @@
+CREATE TABLE share_links (
+	id         TEXT PRIMARY KEY,
+	org_id     TEXT NOT NULL,
+	project_id TEXT,
+	task_id    TEXT,
+	created_by TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	expires_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX share_links_project_id ON share_links (project_id);
+CREATE INDEX share_links_task_id ON share_links (task_id);
@@ -0,0 +1,3 @@
+ALTER TABLE attachments DROP COLUMN scanned_at;
+ALTER TABLE attachments DROP COLUMN threat;
+ALTER TABLE attachments DROP COLUMN status;
@@ -0,0 +1,3 @@
+ALTER TABLE attachments ADD COLUMN status TEXT NOT NULL DEFAULT 'clean';
+ALTER TABLE attachments ADD COLUMN threat TEXT NOT NULL DEFAULT '';
+ALTER TABLE attachments ADD COLUMN scanned_at TIMESTAMP;
@@ -0,0 +1,3 @@
+DROP TABLE feature_flag_overrides;
+
+DROP TABLE feature_flags;
@@ -0,0 +1,15 @@
+CREATE TABLE feature_flags (
+	key         TEXT PRIMARY KEY,
+	description TEXT NOT NULL,
+	enabled     BOOLEAN NOT NULL,
+	rollout     INTEGER NOT NULL,
+	updated_at  TIMESTAMP NOT NULL
+);
+
+CREATE TABLE feature_flag_overrides (
+	flag_key   TEXT NOT NULL,
+	subject    TEXT NOT NULL,
+	subject_id TEXT NOT NULL,
+	enabled    BOOLEAN NOT NULL,
+	PRIMARY KEY (flag_key, subject, subject_id)
+);
@@ -0,0 +1,15 @@
+-- The events of changes to tasks, written in the transaction of the change
+-- and kept until the outbox relay has delivered them.
+CREATE TABLE outbox (
+	id           TEXT PRIMARY KEY,
+	seq          INTEGER NOT NULL,
+	type         TEXT NOT NULL,
+	org_id       TEXT NOT NULL,
+	recipients   TEXT NOT NULL,
+	data         TEXT NOT NULL,
+	trace_parent TEXT NOT NULL,
+	created_at   TIMESTAMP NOT NULL,
+	locked_until TIMESTAMP
+);
+
+CREATE INDEX outbox_created_at ON outbox (created_at, seq);
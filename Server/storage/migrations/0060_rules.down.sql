@@ -0,0 +1,3 @@
+DROP TABLE rule_firings;
+
+DROP TABLE rules;
@@ -0,0 +1,26 @@
+-- The automation rules of projects, with their actions as JSON text.
+CREATE TABLE rules (
+	id             TEXT PRIMARY KEY,
+	org_id         TEXT NOT NULL,
+	project_id     TEXT NOT NULL,
+	name           TEXT NOT NULL,
+	enabled        BOOLEAN NOT NULL,
+	trigger_type   TEXT NOT NULL,
+	trigger_status TEXT NOT NULL,
+	delay_seconds  BIGINT NOT NULL,
+	actions        TEXT NOT NULL,
+	created_by     TEXT NOT NULL,
+	created_at     TIMESTAMP NOT NULL,
+	updated_at     TIMESTAMP NOT NULL
+);
+
+CREATE INDEX rules_project ON rules (project_id, created_at);
+
+-- The tasks each rule has acted on, kept until its trigger stops holding
+-- for them so that it acts on each only once in the meantime.
+CREATE TABLE rule_firings (
+	rule_id  TEXT NOT NULL,
+	task_id  TEXT NOT NULL,
+	fired_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (rule_id, task_id)
+);
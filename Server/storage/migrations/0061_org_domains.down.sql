@@ -0,0 +1,3 @@
+DROP INDEX org_domains_verified;
+
+DROP TABLE org_domains;
@@ -0,0 +1,14 @@
+-- The email domains organizations claim. A claim counts once verified
+-- through DNS, and only one organization may have a domain verified.
+-- Single sign-on settings saved before domains were verified stay unused
+-- until their organization verifies the domain.
+CREATE TABLE org_domains (
+	org_id      TEXT NOT NULL,
+	domain      TEXT NOT NULL,
+	token       TEXT NOT NULL,
+	verified_at TIMESTAMP,
+	created_at  TIMESTAMP NOT NULL,
+	PRIMARY KEY (org_id, domain)
+);
+
+CREATE UNIQUE INDEX org_domains_verified ON org_domains (domain) WHERE verified_at IS NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE scim_links DROP COLUMN created_account;
@@ -0,0 +1,5 @@
+-- Whether SCIM created the account of a provisioned user. Only such users
+-- are added to the organization directly; the holders of accounts that
+-- existed before are invited. Links made before this was kept count as
+-- not having created the account.
+ALTER TABLE scim_links ADD COLUMN created_account BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+CREATE INDEX tasks_org_updated_at ON tasks (org_id, updated_at, id);
+DROP TABLE task_changes;
+DROP TABLE change_sequences;
@@ -0,0 +1,29 @@
+-- The change feed of GET /sync/changes. Every write to tasks adds a row per
+-- task in its transaction, numbered by the organization's sequence, whose
+-- row stays locked until the transaction ends, so the numbers commit in
+-- order. Rows hold the project and owner the task had before the write,
+-- and user_id names the one user a row is for when it only tells that user
+-- they lost access to the task.
+CREATE TABLE change_sequences (
+	org_id TEXT PRIMARY KEY,
+	seq    BIGINT NOT NULL
+);
+
+CREATE TABLE task_changes (
+	org_id     TEXT NOT NULL,
+	seq        BIGINT NOT NULL,
+	task_id    TEXT NOT NULL,
+	project_id TEXT,
+	owner_id   TEXT NOT NULL,
+	user_id    TEXT NOT NULL
+);
+
+CREATE INDEX task_changes_org_seq ON task_changes (org_id, seq, task_id);
+
+-- Existing tasks start the feed as its first change.
+INSERT INTO change_sequences (org_id, seq) SELECT DISTINCT org_id, 1 FROM tasks;
+INSERT INTO task_changes (org_id, seq, task_id, project_id, owner_id, user_id)
+	SELECT org_id, 1, id, project_id, owner_id, '' FROM tasks;
+
+-- The feed no longer walks tasks by when they were saved.
+DROP INDEX tasks_org_updated_at;
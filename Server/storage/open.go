@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Store is the full set of persistence operations used by the server.
+type Store interface {
+	TaskStore
+	RevisionStore
+	CommentStore
+	MentionStore
+	TimeStore
+	DependencyStore
+	WatcherStore
+	ChecklistStore
+	AttachmentStore
+	SearchStore
+	ActivityStore
+	IdempotencyStore
+	CommandStore
+	AuditStore
+	TagStore
+	FieldStore
+	RuleStore
+	ViewStore
+	TemplateStore
+	ProjectStore
+	OrgStore
+	SCIMStore
+	SSOStore
+	ReminderStore
+	NotificationStore
+	CalendarStore
+	ShareStore
+	FlagStore
+	InboundStore
+	GitHubStore
+	SlackStore
+	BoardImportStore
+	WebhookStore
+	UserStore
+	PersonalDataStore
+	TwoFactorStore
+	SessionStore
+	APIKeyStore
+	JobStore
+	OutboxStore
+	UsageStore
+	BackupStore
+	Transactor
+	// Ready reports whether the store can serve requests: that its
+	// database answers and has every migration applied.
+	Ready(ctx context.Context) error
+	Close() error
+}
+
+// Transactor groups writes into one transaction.
+type Transactor interface {
+	// InTx calls fn with a Store whose writes are all kept if fn returns
+	// nil and all undone otherwise. Everything fn does must go through tx.
+	InTx(ctx context.Context, fn func(tx Store) error) error
+}
+
+// Close is a no-op; it exists so MemoryStore satisfies Store.
+func (s *MemoryStore) Close() error { return nil }
+
+// Ready always succeeds: the memory store has nothing to connect to.
+func (s *MemoryStore) Ready(ctx context.Context) error { return nil }
+
+// Pool sizes the connection pool of a SQL database and bounds its
+// statements, as the matching sql.DB setters do: zero leaves MaxOpen and
+// the durations unlimited, and keeps no idle connections. A zero
+// QueryTimeout lets statements run as long as their context allows.
+type Pool struct {
+	MaxOpen      int
+	MaxIdle      int
+	MaxLifetime  time.Duration
+	MaxIdleTime  time.Duration
+	QueryTimeout time.Duration
+}
+
+// Open returns the store described by databaseURL. An empty URL selects
+// the in-memory store, which ignores pool and migrate. Supported schemes
+// are sqlite:// (for example sqlite://data/tasks.db) and postgres:// or
+// postgresql://. With migrate set, pending migrations are applied;
+// otherwise a database whose schema is behind fails with an error wrapping
+// ErrPendingMigrations.
+func Open(ctx context.Context, databaseURL string, pool Pool, migrate bool) (Store, error) {
+	if databaseURL == "" {
+		return NewMemoryStore(), nil
+	}
+
+	s, err := OpenSQL(ctx, databaseURL, pool)
+	if err != nil {
+		return nil, err
+	}
+	if migrate {
+		err = s.Migrate(ctx)
+	} else {
+		err = s.createMigrationsTable(ctx)
+	}
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("storage: %w", err)
+	}
+	if !migrate {
+		if err := s.checkSchema(ctx); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	// Migrations may rewrite whole tables, so only later statements are
+	// bounded.
+	s.timeout = pool.QueryTimeout
+	return s, nil
+}
+
+// OpenSQL connects to the SQL database described by databaseURL, as Open
+// does, but leaves its schema as it is and its statements unbounded, for
+// tools that migrate it.
+func OpenSQL(ctx context.Context, databaseURL string, pool Pool) (*SQLStore, error) {
+	dialect, driver, dsn, err := parseDatabaseURL(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	if !driverRegistered(driver) {
+		return nil, fmt.Errorf("storage: %s support is not compiled in; build with -tags %s", dialect, dialect)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("storage: opening %s database: %w", dialect, err)
+	}
+	db.SetMaxOpenConns(pool.MaxOpen)
+	db.SetMaxIdleConns(pool.MaxIdle)
+	db.SetConnMaxLifetime(pool.MaxLifetime)
+	db.SetConnMaxIdleTime(pool.MaxIdleTime)
+	if dialect == SQLite {
+		// SQLite allows a single writer; serialise access rather than
+		// surfacing "database is locked" errors.
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("storage: connecting to %s database: %w", dialect, err)
+	}
+	return NewSQLStore(db, dialect), nil
+}
+
+// Driver names registered by the driver_*.go files.
+var (
+	sqliteDriver   = "sqlite"
+	postgresDriver = "pgx"
+)
+
+func parseDatabaseURL(u string) (dialect Dialect, driver, dsn string, err error) {
+	switch {
+	case strings.HasPrefix(u, "sqlite://"):
+		return SQLite, sqliteDriver, strings.TrimPrefix(u, "sqlite://"), nil
+	case strings.HasPrefix(u, "postgres://"), strings.HasPrefix(u, "postgresql://"):
+		return Postgres, postgresDriver, u, nil
+	}
+	return "", "", "", fmt.Errorf("storage: unsupported DATABASE_URL scheme in %q", u)
+}
+
+func driverRegistered(name string) bool {
+	for _, d := range sql.Drivers() {
+		if d == name {
+			return true
+		}
+	}
+	return false
+}
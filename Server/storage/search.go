@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// MaxSearchTerms bounds the words of a search query that are used.
+const MaxSearchTerms = 16
+
+// SearchTerms splits a search query into the lower-cased words every
+// backend matches on. Punctuation separates words and is otherwise
+// ignored, so no query can break the backend's own query syntax.
+func SearchTerms(q string) []string {
+	terms := strings.FieldsFunc(strings.ToLower(q), notWordRune)
+	if len(terms) > MaxSearchTerms {
+		terms = terms[:MaxSearchTerms]
+	}
+	return terms
+}
+
+func notWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// The backends wrap matches in these control characters, and markSnippet
+// turns them into <mark> elements once the rest of the snippet is escaped.
+// Text that happens to contain them can at worst produce a stray <mark>.
+const (
+	snippetStart = "\x02"
+	snippetEnd   = "\x03"
+)
+
+var snippetMarks = strings.NewReplacer(snippetStart, "<mark>", snippetEnd, "</mark>")
+
+func markSnippet(raw string) string {
+	return snippetMarks.Replace(html.EscapeString(raw))
+}
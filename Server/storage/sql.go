@@ -0,0 +1,690 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"starttech-server/metrics"
+	"starttech-server/model"
+	"starttech-server/tracing"
+)
+
+// Dialect identifies the SQL flavour spoken by the database.
+type Dialect string
+
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// SQLStore persists records in a relational database through database/sql.
+// The database driver must be linked into the binary; see the driver_*.go
+// files for the supported build tags.
+type SQLStore struct {
+	db      *sql.DB
+	conn    querier // db, or the transaction opened by inTx
+	dialect Dialect
+	// timeout bounds each statement; zero leaves it to the caller's
+	// context.
+	timeout time.Duration
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// NewSQLStore wraps an open database handle. Call Migrate before use.
+func NewSQLStore(db *sql.DB, dialect Dialect) *SQLStore {
+	return &SQLStore{db: db, conn: db, dialect: dialect}
+}
+
+// inTx runs fn against a copy of s whose statements all belong to one
+// transaction, committing if fn succeeds. Nested calls join the outer
+// transaction.
+func (s *SQLStore) inTx(ctx context.Context, fn func(tx *SQLStore) error) error {
+	if _, ok := s.conn.(*sql.Tx); ok {
+		return fn(s)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	inner := *s
+	inner.conn = tx
+	if err := fn(&inner); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// InTx runs fn in a transaction, committing if fn succeeds.
+func (s *SQLStore) InTx(ctx context.Context, fn func(tx Store) error) error {
+	return s.inTx(ctx, func(tx *SQLStore) error { return fn(tx) })
+}
+
+// Close closes the underlying database handle.
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// rebind rewrites the ? placeholders in query into the form expected by the
+// dialect.
+func (s *SQLStore) rebind(query string) string {
+	if s.dialect != Postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+var queryDuration = metrics.NewHistogramVec("db_query_duration_seconds",
+	"Time spent executing SQL statements, by statement kind.", nil, "operation")
+
+// operation labels a statement by its leading keyword, such as "select".
+func operation(query string) string {
+	verb, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	return strings.ToLower(verb)
+}
+
+// statement starts the timeout of a statement run under ctx, and its span
+// if ctx is in a trace. The function returned ends both, recording err on
+// the span.
+func (s *SQLStore) statement(ctx context.Context, query string) (context.Context, func(err error)) {
+	system := string(s.dialect)
+	if s.dialect == Postgres {
+		system = "postgresql"
+	}
+	ctx, span := tracing.StartChild(ctx, strings.ToUpper(operation(query)), tracing.KindClient,
+		slog.String("db.system.name", system),
+		slog.String("db.query.text", strings.Join(strings.Fields(query), " ")),
+	)
+	cancel := context.CancelFunc(func() {})
+	if s.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+	}
+	return ctx, func(err error) {
+		cancel()
+		if !errors.Is(err, sql.ErrNoRows) {
+			span.SetError(err)
+		}
+		span.End()
+	}
+}
+
+func (s *SQLStore) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	defer queryDuration.With(operation(query)).ObserveSince(time.Now())
+	ctx, done := s.statement(ctx, query)
+	res, err := s.conn.ExecContext(ctx, s.rebind(query), args...)
+	done(err)
+	return res, err
+}
+
+// rows are the results of a query, whose timeout and span end when they
+// are closed.
+type rows struct {
+	*sql.Rows
+	done func(error)
+}
+
+func (r *rows) Close() error {
+	err := r.Rows.Close()
+	r.done(r.Rows.Err())
+	return err
+}
+
+func (s *SQLStore) query(ctx context.Context, query string, args ...any) (*rows, error) {
+	defer queryDuration.With(operation(query)).ObserveSince(time.Now())
+	ctx, done := s.statement(ctx, query)
+	rs, err := s.conn.QueryContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+	return &rows{Rows: rs, done: done}, nil
+}
+
+// row is the result of a query for a single row, whose timeout and span
+// end once it is scanned.
+type row struct {
+	*sql.Row
+	done func(error)
+}
+
+func (r *row) Scan(dest ...any) error {
+	err := r.Row.Scan(dest...)
+	r.done(err)
+	return err
+}
+
+func (s *SQLStore) queryRow(ctx context.Context, query string, args ...any) *row {
+	defer queryDuration.With(operation(query)).ObserveSince(time.Now())
+	ctx, done := s.statement(ctx, query)
+	return &row{Row: s.conn.QueryRowContext(ctx, s.rebind(query), args...), done: done}
+}
+
+// execOne runs a statement that must affect exactly one row.
+func (s *SQLStore) execOne(ctx context.Context, query string, args ...any) error {
+	res, err := s.exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// nullTime scans a nullable timestamp into a *time.Time field.
+type nullTime struct{ p **time.Time }
+
+func (n nullTime) Scan(v any) error {
+	var nt sql.NullTime
+	if err := nt.Scan(v); err != nil {
+		return err
+	}
+	if !nt.Valid {
+		*n.p = nil
+		return nil
+	}
+	t := nt.Time.UTC()
+	*n.p = &t
+	return nil
+}
+
+// nullString scans a nullable text column into a *string field.
+type nullString struct{ p **string }
+
+func (n nullString) Scan(v any) error {
+	var ns sql.NullString
+	if err := ns.Scan(v); err != nil {
+		return err
+	}
+	if !ns.Valid {
+		*n.p = nil
+		return nil
+	}
+	*n.p = &ns.String
+	return nil
+}
+
+// placeholders returns n comma-separated ? markers.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// assignments returns "a = ?, b = ?" for the given columns.
+func assignments(cols []string) string {
+	return strings.Join(cols, " = ?, ") + " = ?"
+}
+
+// taskFields lists the tasks columns in the order used by taskArgs and
+// scanTask. The primary key comes first.
+var taskFields = []string{
+	"id", "org_id", "owner_id", "assignee_id", "project_id", "parent_id", "position", "title", "description",
+	"status", "completed", "priority", "due_date", "remind_at", "recurrence", "checklist_done", "checklist_total",
+	"created_at", "updated_at", "deleted_at", "archived_at", "version",
+}
+
+var taskColumns = strings.Join(taskFields, ", ")
+
+func taskArgs(t *model.Task) []any {
+	return []any{
+		t.ID, t.OrgID, t.OwnerID, t.AssigneeID, t.ProjectID, t.ParentID, t.Position, t.Title, t.Description,
+		t.Status, t.Completed, t.Priority, t.DueDate, t.RemindAt, t.Recurrence, t.Checklist.Done, t.Checklist.Total,
+		t.CreatedAt, t.UpdatedAt, t.DeletedAt, t.ArchivedAt, t.Version,
+	}
+}
+
+// scanTask reads the taskFields of one row. TagIDs and Fields are left
+// empty; see loadTags and loadFields.
+func scanTask(row scanner) (model.Task, error) {
+	t := model.Task{TagIDs: []string{}, Fields: map[string]any{}}
+	err := row.Scan(
+		&t.ID, &t.OrgID, &t.OwnerID, nullString{&t.AssigneeID}, nullString{&t.ProjectID}, nullString{&t.ParentID}, &t.Position, &t.Title, &t.Description,
+		&t.Status, &t.Completed, &t.Priority, nullTime{&t.DueDate}, nullTime{&t.RemindAt}, &t.Recurrence,
+		&t.Checklist.Done, &t.Checklist.Total, &t.CreatedAt, &t.UpdatedAt, nullTime{&t.DeletedAt}, nullTime{&t.ArchivedAt}, &t.Version,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, ErrNotFound
+	}
+	return t, err
+}
+
+// taskSortColumns maps the public sort keys onto columns. Tasks without a
+// due date sort last in ascending order on every dialect.
+var taskSortColumns = map[string]string{
+	SortCreatedAt: "created_at",
+	SortUpdatedAt: "updated_at",
+	SortDueDate:   "CASE WHEN due_date IS NULL THEN 1 ELSE 0 END, due_date",
+	SortTitle:     "LOWER(title)",
+	SortStatus:    "status",
+	SortPosition:  "position",
+	SortDeletedAt: "deleted_at",
+	SortPriority:  priorityRank,
+}
+
+// taskWhere renders the filtering criteria of f as a WHERE clause. Tasks in
+// the trash are only ever matched by a filter asking for them.
+func taskWhere(f TaskFilter) (string, []any) {
+	var (
+		where = []string{"deleted_at IS NULL"}
+		args  []any
+	)
+	if f.Trashed {
+		where[0] = "deleted_at IS NOT NULL"
+	}
+	if f.DeletedBefore != nil {
+		where = append(where, "deleted_at < ?")
+		args = append(args, *f.DeletedBefore)
+	}
+	if f.Archived != nil {
+		if *f.Archived {
+			where = append(where, "archived_at IS NOT NULL")
+		} else {
+			where = append(where, "archived_at IS NULL")
+		}
+	}
+	if f.Completed {
+		where = append(where, "completed")
+	}
+	if f.UpdatedBefore != nil {
+		where = append(where, "updated_at < ?")
+		args = append(args, *f.UpdatedBefore)
+	}
+	if f.OrgID != "" {
+		where = append(where, "org_id = ?")
+		args = append(args, f.OrgID)
+	}
+	if f.OwnerID != "" {
+		where = append(where, "owner_id = ?")
+		args = append(args, f.OwnerID)
+	}
+	if f.VisibleTo != "" {
+		where = append(where, `(project_id IS NULL AND owner_id = ?
+			OR project_id IN (SELECT project_id FROM project_members WHERE user_id = ?))`)
+		args = append(args, f.VisibleTo, f.VisibleTo)
+	}
+	if f.AssigneeID != "" {
+		where = append(where, "assignee_id = ?")
+		args = append(args, f.AssigneeID)
+	}
+	if f.Unassigned {
+		where = append(where, "assignee_id IS NULL")
+	}
+	if f.ProjectID != "" {
+		where = append(where, "project_id = ?")
+		args = append(args, f.ProjectID)
+	}
+	if f.ParentID != "" {
+		where = append(where, "parent_id = ?")
+		args = append(args, f.ParentID)
+	}
+	if f.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, f.Status)
+	}
+	if f.DueBefore != nil {
+		where = append(where, "due_date < ?")
+		args = append(args, *f.DueBefore)
+	}
+	if f.DueAfter != nil {
+		where = append(where, "due_date > ?")
+		args = append(args, *f.DueAfter)
+	}
+	for _, id := range f.TagIDs {
+		where = append(where, "id IN (SELECT task_id FROM task_tags WHERE tag_id = ?)")
+		args = append(args, id)
+	}
+	for _, id := range slices.Sorted(maps.Keys(f.Fields)) {
+		value, _ := encodeFieldValue(f.Fields[id])
+		where = append(where, "id IN (SELECT task_id FROM task_field_values WHERE field_id = ? AND value = ?)")
+		args = append(args, id, value)
+	}
+
+	return ` WHERE ` + strings.Join(where, " AND "), args
+}
+
+func (s *SQLStore) ListTasks(ctx context.Context, f TaskFilter) ([]model.Task, error) {
+	where, args := taskWhere(f)
+	q := `SELECT ` + taskColumns + ` FROM tasks` + where
+	order, orderArgs := taskOrderBy(f.Sort, time.Now().UTC())
+	q += ` ORDER BY ` + order
+	args = append(args, orderArgs...)
+	if f.Limit > 0 {
+		q += ` LIMIT ? OFFSET ?`
+		args = append(args, f.Limit, f.Offset)
+	}
+
+	rows, err := s.query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := []model.Task{}
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning task: %w", err)
+		}
+		tasks = append(tasks, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Release the connection before loadTags needs it; SQLite has only one.
+	rows.Close()
+	if err := s.loadTags(ctx, tasks); err != nil {
+		return nil, err
+	}
+	if err := s.loadFields(ctx, tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// tagBatch bounds the number of placeholders in one loadTags query.
+const tagBatch = 500
+
+// loadTags fills in the TagIDs of tasks.
+func (s *SQLStore) loadTags(ctx context.Context, tasks []model.Task) error {
+	index := make(map[string]int, len(tasks))
+	for i := range tasks {
+		index[tasks[i].ID] = i
+	}
+	for start := 0; start < len(tasks); start += tagBatch {
+		batch := tasks[start:min(start+tagBatch, len(tasks))]
+		args := make([]any, len(batch))
+		for i, t := range batch {
+			args[i] = t.ID
+		}
+		rows, err := s.query(ctx, `SELECT task_id, tag_id FROM task_tags WHERE task_id IN (`+
+			placeholders(len(batch))+`) ORDER BY tag_id`, args...)
+		if err != nil {
+			return fmt.Errorf("loading task tags: %w", err)
+		}
+		for rows.Next() {
+			var taskID, tagID string
+			if err := rows.Scan(&taskID, &tagID); err != nil {
+				rows.Close()
+				return fmt.Errorf("scanning task tag: %w", err)
+			}
+			t := &tasks[index[taskID]]
+			t.TagIDs = append(t.TagIDs, tagID)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveTags replaces the tag associations of t.
+func (s *SQLStore) saveTags(ctx context.Context, t *model.Task) error {
+	if _, err := s.exec(ctx, `DELETE FROM task_tags WHERE task_id = ?`, t.ID); err != nil {
+		return fmt.Errorf("clearing task tags: %w", err)
+	}
+	for _, tagID := range t.TagIDs {
+		if _, err := s.exec(ctx, `INSERT INTO task_tags (task_id, tag_id) VALUES (?, ?)`, t.ID, tagID); err != nil {
+			return fmt.Errorf("tagging task: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *SQLStore) CountTasks(ctx context.Context, f TaskFilter) (int, error) {
+	where, args := taskWhere(f)
+	var n int
+	if err := s.queryRow(ctx, `SELECT COUNT(*) FROM tasks`+where, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting tasks: %w", err)
+	}
+	return n, nil
+}
+
+// orderBy renders an ORDER BY list for sort, breaking ties on id so pages
+// are stable.
+func orderBy(columns map[string]string, sort Sort) string {
+	expr, ok := columns[sort.Field]
+	if !ok {
+		expr = columns[SortCreatedAt]
+	}
+	dir := " ASC"
+	if sort.Desc {
+		dir = " DESC"
+	}
+	parts := strings.Split(expr, ", ")
+	for i := range parts {
+		parts[i] += dir
+	}
+	return strings.Join(parts, ", ") + ", id" + dir
+}
+
+// priorityRank is the Rank of the priority column.
+var priorityRank = func() string {
+	var b strings.Builder
+	b.WriteString("CASE priority")
+	for i, p := range model.Priorities {
+		fmt.Fprintf(&b, " WHEN '%s' THEN %d", p, i)
+	}
+	b.WriteString(" ELSE 0 END")
+	return b.String()
+}()
+
+// taskOrderBy is orderBy for tasks, which adds the urgency sort, whose score
+// depends on the time, and the sort by a custom field, both of which are
+// passed as arguments.
+func taskOrderBy(by Sort, now time.Time) (string, []any) {
+	dir := " ASC"
+	if by.Desc {
+		dir = " DESC"
+	}
+	if by.CustomField != "" {
+		// Numbers order by the number column, everything else by its JSON
+		// text, which orders strings and booleans as they are.
+		value := func(column string) string {
+			return "(SELECT " + column + " FROM task_field_values WHERE task_id = tasks.id AND field_id = ?)"
+		}
+		order := "CASE WHEN " + value("value") + " IS NULL THEN 1 ELSE 0 END" + dir +
+			", " + value("number") + dir + ", " + value("value") + dir + ", id" + dir
+		return order, []any{by.CustomField, by.CustomField, by.CustomField}
+	}
+	if by.Field != SortUrgency {
+		return orderBy(taskSortColumns, by), nil
+	}
+	var (
+		b    strings.Builder
+		args []any
+	)
+	// Negated so that the most urgent tasks come first in ascending order.
+	fmt.Fprintf(&b, "0 - (%d * %s", urgencyPriorityWeight, priorityRank)
+	b.WriteString(" + CASE WHEN due_date IS NULL THEN 0")
+	for _, w := range urgencyDue {
+		fmt.Fprintf(&b, " WHEN due_date < ? THEN %d", w.weight)
+		args = append(args, now.Add(w.span))
+	}
+	b.WriteString(" ELSE 0 END + CASE")
+	for _, w := range urgencyAge {
+		fmt.Fprintf(&b, " WHEN created_at < ? THEN %d", w.weight)
+		args = append(args, now.Add(-w.span))
+	}
+	b.WriteString(" ELSE 0 END)" + dir)
+	b.WriteString(", CASE WHEN due_date IS NULL THEN 1 ELSE 0 END" + dir + ", due_date" + dir + ", created_at" + dir + ", id" + dir)
+	return b.String(), args
+}
+
+func (s *SQLStore) GetTask(ctx context.Context, id string) (model.Task, error) {
+	t, err := scanTask(s.queryRow(ctx, `SELECT `+taskColumns+` FROM tasks WHERE id = ?`, id))
+	if err != nil {
+		return t, err
+	}
+	tasks := []model.Task{t}
+	if err := s.loadTags(ctx, tasks); err != nil {
+		return t, err
+	}
+	err = s.loadFields(ctx, tasks)
+	return tasks[0], err
+}
+
+func (s *SQLStore) CreateTask(ctx context.Context, t *model.Task) error {
+	t.ID = NewID()
+	t.Version = 1
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		_, err := tx.exec(ctx, `INSERT INTO tasks (`+taskColumns+`) VALUES (`+placeholders(len(taskFields))+`)`, taskArgs(t)...)
+		if err != nil {
+			return fmt.Errorf("inserting task: %w", err)
+		}
+		if err := tx.saveTags(ctx, t); err != nil {
+			return err
+		}
+		if err := tx.saveFields(ctx, t); err != nil {
+			return err
+		}
+		if err := tx.logTasks(ctx, `t.id = ?`, t.ID); err != nil {
+			return err
+		}
+		return tx.saveRevision(ctx, t)
+	})
+}
+
+func (s *SQLStore) UpdateTask(ctx context.Context, t *model.Task) error {
+	next := *t
+	next.Version++
+	args := append(taskArgs(&next)[1:], t.ID, t.Version)
+	err := s.inTx(ctx, func(tx *SQLStore) error {
+		if err := tx.logTasks(ctx, `t.id = ? AND t.version = ?`, t.ID, t.Version); err != nil {
+			return err
+		}
+		err := tx.execOne(ctx, `UPDATE tasks SET `+assignments(taskFields[1:])+` WHERE id = ? AND version = ?`, args...)
+		if errors.Is(err, ErrNotFound) {
+			var n int
+			if err := tx.queryRow(ctx, `SELECT COUNT(*) FROM tasks WHERE id = ?`, t.ID).Scan(&n); err != nil {
+				return err
+			}
+			if n > 0 {
+				return ErrStale
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.saveTags(ctx, t); err != nil {
+			return err
+		}
+		if err := tx.saveFields(ctx, t); err != nil {
+			return err
+		}
+		return tx.saveRevision(ctx, &next)
+	})
+	if err == nil {
+		t.Version = next.Version
+	}
+	return err
+}
+
+func (s *SQLStore) MergeTask(ctx context.Context, fromID, intoID string) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		if _, err := tx.exec(ctx, `UPDATE comments SET task_id = ? WHERE task_id = ?`, intoID, fromID); err != nil {
+			return fmt.Errorf("moving comments: %w", err)
+		}
+		if _, err := tx.exec(ctx, `UPDATE mentions SET task_id = ? WHERE task_id = ?`, intoID, fromID); err != nil {
+			return fmt.Errorf("moving mentions: %w", err)
+		}
+		if _, err := tx.exec(ctx, `UPDATE attachments SET task_id = ? WHERE task_id = ?`, intoID, fromID); err != nil {
+			return fmt.Errorf("moving attachments: %w", err)
+		}
+		_, err := tx.exec(ctx, `INSERT INTO task_watchers (task_id, user_id, created_at)
+			SELECT ?, user_id, created_at FROM task_watchers WHERE task_id = ?
+			ON CONFLICT (task_id, user_id) DO NOTHING`, intoID, fromID)
+		if err != nil {
+			return fmt.Errorf("moving watchers: %w", err)
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM task_watchers WHERE task_id = ?`, fromID); err != nil {
+			return fmt.Errorf("moving watchers: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *SQLStore) DeleteTask(ctx context.Context, id string) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		if err := tx.logTasks(ctx, `t.id = ?`, id); err != nil {
+			return err
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM task_tags WHERE task_id = ?`, id); err != nil {
+			return fmt.Errorf("clearing task tags: %w", err)
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM task_field_values WHERE task_id = ?`, id); err != nil {
+			return fmt.Errorf("clearing field values: %w", err)
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM reminders WHERE task_id = ?`, id); err != nil {
+			return fmt.Errorf("clearing reminders: %w", err)
+		}
+		if err := tx.deleteComments(ctx, id); err != nil {
+			return err
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM time_entries WHERE task_id = ?`, id); err != nil {
+			return fmt.Errorf("clearing time entries: %w", err)
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM task_dependencies WHERE task_id = ? OR blocker_id = ?`, id, id); err != nil {
+			return fmt.Errorf("clearing dependencies: %w", err)
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM checklist_items WHERE task_id = ?`, id); err != nil {
+			return fmt.Errorf("clearing checklist: %w", err)
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM task_watchers WHERE task_id = ?`, id); err != nil {
+			return fmt.Errorf("clearing watchers: %w", err)
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM task_revisions WHERE task_id = ?`, id); err != nil {
+			return fmt.Errorf("clearing revisions: %w", err)
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM mentions WHERE task_id = ?`, id); err != nil {
+			return fmt.Errorf("clearing mentions: %w", err)
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM notifications WHERE task_id = ?`, id); err != nil {
+			return fmt.Errorf("clearing notifications: %w", err)
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM issue_links WHERE task_id = ?`, id); err != nil {
+			return fmt.Errorf("clearing issue link: %w", err)
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM attachments WHERE task_id = ?`, id); err != nil {
+			return fmt.Errorf("clearing attachments: %w", err)
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM share_links WHERE task_id = ?`, id); err != nil {
+			return fmt.Errorf("clearing share links: %w", err)
+		}
+		return tx.execOne(ctx, `DELETE FROM tasks WHERE id = ?`, id)
+	})
+}
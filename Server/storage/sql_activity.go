@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+const activityColumns = `id, org_id, project_id, task_id, actor_id, action, subject_id, changes, created_at`
+
+func scanActivity(row scanner) (model.Activity, error) {
+	var (
+		a       model.Activity
+		changes string
+	)
+	if err := row.Scan(&a.ID, &a.OrgID, nullString{&a.ProjectID}, nullString{&a.TaskID}, &a.ActorID, &a.Action,
+		&a.SubjectID, &changes, &a.CreatedAt); err != nil {
+		return a, err
+	}
+	if err := json.Unmarshal([]byte(changes), &a.Changes); err != nil {
+		return a, fmt.Errorf("decoding changes: %w", err)
+	}
+	return a, nil
+}
+
+func (s *SQLStore) RecordActivity(ctx context.Context, a *model.Activity) error {
+	a.ID = NewID()
+	encoded, err := json.Marshal(a.Changes)
+	if err != nil {
+		return err
+	}
+	_, err = s.exec(ctx, `INSERT INTO activity (`+activityColumns+`) VALUES (`+placeholders(9)+`)`,
+		a.ID, a.OrgID, a.ProjectID, a.TaskID, a.ActorID, a.Action, a.SubjectID, string(encoded), a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting activity: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) ListActivity(ctx context.Context, f ActivityFilter) ([]model.Activity, error) {
+	q := `SELECT ` + activityColumns + ` FROM activity WHERE `
+	var args []any
+	if f.TaskID != "" {
+		q += `task_id = ?`
+		args = append(args, f.TaskID)
+	} else {
+		q += `project_id = ?`
+		args = append(args, f.ProjectID)
+	}
+	q += ` ORDER BY created_at DESC, id DESC`
+	if f.Limit > 0 {
+		q += ` LIMIT ? OFFSET ?`
+		args = append(args, f.Limit, f.Offset)
+	}
+
+	rows, err := s.query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing activity: %w", err)
+	}
+	defer rows.Close()
+
+	out := []model.Activity{}
+	for rows.Next() {
+		a, err := scanActivity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning activity: %w", err)
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
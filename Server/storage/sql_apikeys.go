@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"starttech-server/model"
+)
+
+const apiKeyColumns = `id, org_id, owner_id, name, scope, prefix, key_hash, created_at, last_used_at`
+
+func scanAPIKey(row scanner) (model.APIKey, error) {
+	var k model.APIKey
+	err := row.Scan(&k.ID, &k.OrgID, &k.OwnerID, &k.Name, &k.Scope, &k.Prefix, &k.KeyHash,
+		&k.CreatedAt, nullTime{&k.LastUsedAt})
+	if errors.Is(err, sql.ErrNoRows) {
+		return k, ErrNotFound
+	}
+	return k, err
+}
+
+func (s *SQLStore) ListAPIKeys(ctx context.Context, orgID, ownerID string) ([]model.APIKey, error) {
+	rows, err := s.query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE org_id = ? AND owner_id = ?
+		ORDER BY created_at, id`, orgID, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("listing api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []model.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning api key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func (s *SQLStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (model.APIKey, error) {
+	return scanAPIKey(s.queryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = ?`, keyHash))
+}
+
+func (s *SQLStore) CreateAPIKey(ctx context.Context, k *model.APIKey) error {
+	k.ID = NewID()
+	_, err := s.exec(ctx, `INSERT INTO api_keys (`+apiKeyColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		k.ID, k.OrgID, k.OwnerID, k.Name, k.Scope, k.Prefix, k.KeyHash, k.CreatedAt, k.LastUsedAt)
+	if err != nil {
+		return fmt.Errorf("inserting api key: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	return s.execOne(ctx, `UPDATE api_keys SET last_used_at = ? WHERE id = ?`, at, id)
+}
+
+func (s *SQLStore) DeleteAPIKey(ctx context.Context, ownerID, id string) error {
+	return s.execOne(ctx, `DELETE FROM api_keys WHERE id = ? AND owner_id = ?`, id, ownerID)
+}
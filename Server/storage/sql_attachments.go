@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+const attachmentColumns = `id, task_id, uploader_id, filename, content_type, size, blob_key, status, threat, scanned_at, created_at`
+
+func scanAttachment(row scanner) (model.Attachment, error) {
+	var a model.Attachment
+	err := row.Scan(&a.ID, &a.TaskID, &a.UploaderID, &a.Filename, &a.ContentType, &a.Size, &a.Key, &a.Status, &a.Threat, nullTime{&a.ScannedAt}, &a.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return a, ErrNotFound
+	}
+	return a, err
+}
+
+func (s *SQLStore) ListAttachments(ctx context.Context, taskID string) ([]model.Attachment, error) {
+	rows, err := s.query(ctx, `SELECT `+attachmentColumns+` FROM attachments WHERE task_id = ? ORDER BY created_at, id`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("listing attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := []model.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning attachment: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+func (s *SQLStore) GetAttachment(ctx context.Context, id string) (model.Attachment, error) {
+	return scanAttachment(s.queryRow(ctx, `SELECT `+attachmentColumns+` FROM attachments WHERE id = ?`, id))
+}
+
+func (s *SQLStore) CreateAttachment(ctx context.Context, a *model.Attachment) error {
+	_, err := s.exec(ctx, `INSERT INTO attachments (`+attachmentColumns+`) VALUES (`+placeholders(11)+`)`,
+		a.ID, a.TaskID, a.UploaderID, a.Filename, a.ContentType, a.Size, a.Key, a.Status, a.Threat, a.ScannedAt, a.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("inserting attachment: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) UpdateAttachment(ctx context.Context, a model.Attachment) error {
+	return s.execOne(ctx, `UPDATE attachments SET blob_key = ?, status = ?, threat = ?, scanned_at = ? WHERE id = ?`,
+		a.Key, a.Status, a.Threat, a.ScannedAt, a.ID)
+}
+
+func (s *SQLStore) DeleteAttachment(ctx context.Context, id string) error {
+	return s.execOne(ctx, `DELETE FROM attachments WHERE id = ?`, id)
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"starttech-server/model"
+)
+
+const auditColumns = `seq, id, action, actor_id, org_id, target_id, ip, user_agent, detail, created_at, prev_hash, hash`
+
+// auditAppendAttempts bounds how often an append is retried after losing
+// the race for the next sequence number to another server.
+const auditAppendAttempts = 5
+
+func scanAuditEvent(row scanner) (model.AuditEvent, error) {
+	var e model.AuditEvent
+	err := row.Scan(&e.Seq, &e.ID, &e.Action, &e.ActorID, &e.OrgID, &e.TargetID, &e.IP, &e.UserAgent, &e.Detail,
+		&e.CreatedAt, &e.PrevHash, &e.Hash)
+	e.CreatedAt = e.CreatedAt.UTC()
+	return e, err
+}
+
+func (s *SQLStore) AppendAuditEvent(ctx context.Context, e *model.AuditEvent, hash func(model.AuditEvent) string) error {
+	e.ID = NewID()
+	var err error
+	for range auditAppendAttempts {
+		err = s.inTx(ctx, func(tx *SQLStore) error {
+			var (
+				lastSeq  int64
+				lastHash string
+			)
+			err := tx.queryRow(ctx, `SELECT seq, hash FROM audit_events ORDER BY seq DESC LIMIT 1`).Scan(&lastSeq, &lastHash)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("reading last audit event: %w", err)
+			}
+			e.Seq, e.PrevHash = lastSeq+1, lastHash
+			e.Hash = hash(*e)
+			_, err = tx.exec(ctx, `INSERT INTO audit_events (`+auditColumns+`) VALUES (`+placeholders(12)+`)`,
+				e.Seq, e.ID, e.Action, e.ActorID, e.OrgID, e.TargetID, e.IP, e.UserAgent, e.Detail,
+				e.CreatedAt, e.PrevHash, e.Hash)
+			return err
+		})
+		if !isUniqueViolation(err) {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("inserting audit event: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) ListAuditEvents(ctx context.Context, f AuditFilter) ([]model.AuditEvent, error) {
+	q := `SELECT ` + auditColumns + ` FROM audit_events WHERE 1 = 1`
+	var args []any
+	if f.From != nil {
+		q += ` AND created_at >= ?`
+		args = append(args, *f.From)
+	}
+	if f.To != nil {
+		q += ` AND created_at < ?`
+		args = append(args, *f.To)
+	}
+	for _, c := range [][2]string{{"action", f.Action}, {"actor_id", f.ActorID}, {"org_id", f.OrgID}} {
+		if c[1] != "" {
+			q += ` AND ` + c[0] + ` = ?`
+			args = append(args, c[1])
+		}
+	}
+	if f.Oldest {
+		q += ` ORDER BY seq`
+	} else {
+		q += ` ORDER BY seq DESC`
+	}
+	if f.Limit > 0 {
+		q += ` LIMIT ? OFFSET ?`
+		args = append(args, f.Limit, f.Offset)
+	}
+
+	rows, err := s.query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing audit events: %w", err)
+	}
+	defer rows.Close()
+
+	out := []model.AuditEvent{}
+	for rows.Next() {
+		e, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning audit event: %w", err)
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLStore) PurgeAuditEvents(ctx context.Context, before time.Time) (int, error) {
+	res, err := s.exec(ctx, `DELETE FROM audit_events WHERE created_at < ?`, before)
+	if err != nil {
+		return 0, fmt.Errorf("purging audit events: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *SQLStore) PurgeableAuditEvents(ctx context.Context, before time.Time) (int, error) {
+	var n int
+	if err := s.queryRow(ctx, `SELECT COUNT(*) FROM audit_events WHERE created_at < ?`, before).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting purgeable audit events: %w", err)
+	}
+	return n, nil
+}
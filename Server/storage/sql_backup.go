@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+func (s *SQLStore) OrgProjects(ctx context.Context, orgID string) ([]model.Project, error) {
+	return listOrg(ctx, s, "projects", projectColumns, orgID, scanProject)
+}
+
+func (s *SQLStore) OrgTags(ctx context.Context, orgID string) ([]model.Tag, error) {
+	return listOrg(ctx, s, "tags", tagColumns, orgID, scanTag)
+}
+
+func (s *SQLStore) OrgViews(ctx context.Context, orgID string) ([]model.View, error) {
+	return listOrg(ctx, s, "views", viewColumns, orgID, scanView)
+}
+
+func (s *SQLStore) OrgTemplates(ctx context.Context, orgID string) ([]model.Template, error) {
+	return listOrg(ctx, s, "templates", templateColumns, orgID, scanTemplate)
+}
+
+// listOrg returns the rows of table that belong to the organization orgID,
+// oldest first.
+func listOrg[T any](ctx context.Context, s *SQLStore, table, columns, orgID string, scan func(scanner) (T, error)) ([]T, error) {
+	rows, err := s.query(ctx, `SELECT `+columns+` FROM `+table+` WHERE org_id = ? ORDER BY created_at, id`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	list := []T{}
+	for rows.Next() {
+		v, err := scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning %s: %w", table, err)
+		}
+		list = append(list, v)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+const boardImportColumns = `id, org_id, user_id, source, status, total, done, summary, error, created_at, finished_at`
+
+func scanBoardImport(row scanner, boards *string) (model.BoardImport, error) {
+	var (
+		im      model.BoardImport
+		summary string
+	)
+	dest := []any{&im.ID, &im.OrgID, &im.UserID, &im.Source, &im.Status, &im.Total, &im.Done,
+		&summary, &im.Error, &im.CreatedAt, nullTime{&im.FinishedAt}}
+	if boards != nil {
+		dest = append(dest, boards)
+	}
+	err := row.Scan(dest...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return im, ErrNotFound
+	}
+	if err != nil {
+		return im, err
+	}
+	if err := json.Unmarshal([]byte(summary), &im.Summary); err != nil {
+		return im, fmt.Errorf("decoding import summary: %w", err)
+	}
+	return im, nil
+}
+
+func encodeImportSummary(sum model.BoardImportSummary) string {
+	b, err := json.Marshal(sum)
+	if err != nil {
+		panic("storage: encoding import summary: " + err.Error())
+	}
+	return string(b)
+}
+
+func encodeBoards(boards []model.Board) string {
+	b, err := json.Marshal(boards)
+	if err != nil {
+		panic("storage: encoding import boards: " + err.Error())
+	}
+	return string(b)
+}
+
+func (s *SQLStore) ListBoardImports(ctx context.Context, orgID, userID string) ([]model.BoardImport, error) {
+	rows, err := s.query(ctx, `SELECT `+boardImportColumns+` FROM board_imports WHERE org_id = ? AND user_id = ?
+		ORDER BY created_at DESC, id DESC`, orgID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing imports: %w", err)
+	}
+	defer rows.Close()
+
+	imports := []model.BoardImport{}
+	for rows.Next() {
+		im, err := scanBoardImport(rows, nil)
+		if err != nil {
+			return nil, fmt.Errorf("scanning import: %w", err)
+		}
+		imports = append(imports, im)
+	}
+	return imports, rows.Err()
+}
+
+func (s *SQLStore) GetBoardImport(ctx context.Context, id string) (model.BoardImport, error) {
+	var boards string
+	im, err := scanBoardImport(s.queryRow(ctx, `SELECT `+boardImportColumns+`, boards FROM board_imports WHERE id = ?`, id), &boards)
+	if err != nil {
+		return im, err
+	}
+	if boards != "" {
+		if err := json.Unmarshal([]byte(boards), &im.Boards); err != nil {
+			return im, fmt.Errorf("decoding import boards: %w", err)
+		}
+	}
+	return im, nil
+}
+
+func (s *SQLStore) CreateBoardImport(ctx context.Context, im *model.BoardImport) error {
+	im.ID = NewID()
+	_, err := s.exec(ctx, `INSERT INTO board_imports (`+boardImportColumns+`, boards) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		im.ID, im.OrgID, im.UserID, im.Source, im.Status, im.Total, im.Done, encodeImportSummary(im.Summary), im.Error,
+		im.CreatedAt, im.FinishedAt, encodeBoards(im.Boards))
+	if err != nil {
+		return fmt.Errorf("inserting import: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) UpdateBoardImport(ctx context.Context, im *model.BoardImport) error {
+	return s.execOne(ctx, `UPDATE board_imports SET status = ?, done = ?, summary = ?, error = ?, finished_at = ?,
+			boards = CASE WHEN ? THEN '' ELSE boards END
+		WHERE id = ?`, im.Status, im.Done, encodeImportSummary(im.Summary), im.Error, im.FinishedAt, im.Boards == nil, im.ID)
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+func scanCalendarFeed(row scanner) (model.CalendarFeed, error) {
+	var f model.CalendarFeed
+	err := row.Scan(&f.UserID, &f.OrgID, &f.TokenHash, &f.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return f, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *SQLStore) GetCalendarFeed(ctx context.Context, orgID, userID string) (model.CalendarFeed, error) {
+	return scanCalendarFeed(s.queryRow(ctx, `SELECT user_id, org_id, token_hash, created_at FROM calendar_feeds
+		WHERE org_id = ? AND user_id = ?`, orgID, userID))
+}
+
+func (s *SQLStore) GetCalendarFeedByToken(ctx context.Context, tokenHash string) (model.CalendarFeed, error) {
+	return scanCalendarFeed(s.queryRow(ctx, `SELECT user_id, org_id, token_hash, created_at FROM calendar_feeds
+		WHERE token_hash = ?`, tokenHash))
+}
+
+func (s *SQLStore) SaveCalendarFeed(ctx context.Context, f model.CalendarFeed) error {
+	_, err := s.exec(ctx, `INSERT INTO calendar_feeds (org_id, user_id, token_hash, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (org_id, user_id) DO UPDATE SET
+			token_hash = excluded.token_hash, created_at = excluded.created_at`,
+		f.OrgID, f.UserID, f.TokenHash, f.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("saving calendar feed: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) DeleteCalendarFeed(ctx context.Context, orgID, userID string) error {
+	return s.execOne(ctx, `DELETE FROM calendar_feeds WHERE org_id = ? AND user_id = ?`, orgID, userID)
+}
@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+// The change feed is kept in task_changes, a row per task for every write
+// to it, numbered by the sequence of its organization in change_sequences.
+// A row holds the project and owner the task had before the write, so that
+// those who could see it then are told when they no longer can. A row with
+// user_id set is for that user alone, who lost access to the task while it
+// stayed where it was.
+
+// logTasks adds the tasks that where selects, as t, to the change feed.
+// Call it before a write that moves or removes the tasks, and after one
+// that creates them.
+func (s *SQLStore) logTasks(ctx context.Context, where string, args ...any) error {
+	return s.logChanges(ctx, `tasks t`, `''`, where, args...)
+}
+
+// logRevoked adds to the change feed, for each project member that where
+// selects, as m, the tasks of the project, as t, before the member loses
+// access to them.
+func (s *SQLStore) logRevoked(ctx context.Context, where string, args ...any) error {
+	return s.logChanges(ctx, `tasks t JOIN project_members m ON m.project_id = t.project_id`, `m.user_id`, where, args...)
+}
+
+// logChanges adds the tasks that where selects from source, as t, to the
+// change feed of their organizations, with user as the user each row is
+// for, under the next number of each organization's sequence.
+func (s *SQLStore) logChanges(ctx context.Context, source, user, where string, args ...any) error {
+	rows, err := s.query(ctx, `SELECT DISTINCT t.org_id FROM `+source+` WHERE `+where, args...)
+	if err != nil {
+		return fmt.Errorf("listing changed organizations: %w", err)
+	}
+	var orgs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("scanning changed organization: %w", err)
+		}
+		orgs = append(orgs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, org := range orgs {
+		seq, err := s.nextChangeSeq(ctx, org)
+		if err != nil {
+			return err
+		}
+		_, err = s.exec(ctx, `INSERT INTO task_changes (org_id, seq, task_id, project_id, owner_id, user_id)
+			SELECT t.org_id, ?, t.id, t.project_id, t.owner_id, `+user+` FROM `+source+`
+			WHERE t.org_id = ? AND (`+where+`)`, append([]any{seq, org}, args...)...)
+		if err != nil {
+			return fmt.Errorf("logging task changes: %w", err)
+		}
+	}
+	return nil
+}
+
+// nextChangeSeq advances the change sequence of the organization orgID and
+// returns its new value. The row stays locked until the transaction ends,
+// so a reader never sees a change before those numbered ahead of it.
+func (s *SQLStore) nextChangeSeq(ctx context.Context, orgID string) (int64, error) {
+	_, err := s.exec(ctx, `INSERT INTO change_sequences (org_id, seq) VALUES (?, 1)
+		ON CONFLICT (org_id) DO UPDATE SET seq = change_sequences.seq + 1`, orgID)
+	if err != nil {
+		return 0, fmt.Errorf("advancing change sequence: %w", err)
+	}
+	var seq int64
+	if err := s.queryRow(ctx, `SELECT seq FROM change_sequences WHERE org_id = ?`, orgID).Scan(&seq); err != nil {
+		return 0, fmt.Errorf("reading change sequence: %w", err)
+	}
+	return seq, nil
+}
+
+func (s *SQLStore) ListChanges(ctx context.Context, f ChangeFilter) ([]TaskChange, error) {
+	q := `SELECT c.task_id, MAX(c.seq) FROM task_changes c
+		WHERE c.org_id = ? AND (c.seq > ? OR c.seq = ? AND c.task_id > ?)
+		AND (c.user_id = ? OR c.user_id = '' AND (
+			c.project_id IS NULL AND c.owner_id = ?
+			OR c.project_id IN (SELECT project_id FROM project_members WHERE user_id = ?)
+			OR c.task_id IN (SELECT id FROM tasks WHERE project_id IS NULL AND owner_id = ?
+				OR project_id IN (SELECT project_id FROM project_members WHERE user_id = ?))))
+		GROUP BY c.task_id
+		ORDER BY MAX(c.seq), c.task_id`
+	u := f.VisibleTo
+	args := []any{f.OrgID, f.After.Seq, f.After.Seq, f.After.TaskID, u, u, u, u, u}
+	if f.Limit > 0 {
+		q += ` LIMIT ?`
+		args = append(args, f.Limit)
+	}
+
+	rows, err := s.query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []TaskChange{}
+	for rows.Next() {
+		var c TaskChange
+		if err := rows.Scan(&c.Key.TaskID, &c.Key.Seq); err != nil {
+			return nil, fmt.Errorf("scanning change: %w", err)
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if len(changes) == 0 {
+		return changes, nil
+	}
+
+	tasks, err := s.visibleTasks(ctx, changes, f.VisibleTo)
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range changes {
+		if t, ok := tasks[c.Key.TaskID]; ok {
+			changes[i].Task = &t
+		}
+	}
+	return changes, nil
+}
+
+// visibleTasks returns those of the tasks of changes that userID may see,
+// by ID.
+func (s *SQLStore) visibleTasks(ctx context.Context, changes []TaskChange, userID string) (map[string]model.Task, error) {
+	args := make([]any, 0, len(changes)+2)
+	for _, c := range changes {
+		args = append(args, c.Key.TaskID)
+	}
+	args = append(args, userID, userID)
+	rows, err := s.query(ctx, `SELECT `+taskColumns+` FROM tasks WHERE id IN (`+placeholders(len(changes))+`)
+		AND (project_id IS NULL AND owner_id = ?
+			OR project_id IN (SELECT project_id FROM project_members WHERE user_id = ?))`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing changed tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []model.Task
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning task: %w", err)
+		}
+		tasks = append(tasks, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Release the connection before loadTags needs it; SQLite has only one.
+	rows.Close()
+	if err := s.loadTags(ctx, tasks); err != nil {
+		return nil, err
+	}
+	if err := s.loadFields(ctx, tasks); err != nil {
+		return nil, err
+	}
+	byID := make(map[string]model.Task, len(tasks))
+	for _, t := range tasks {
+		byID[t.ID] = t
+	}
+	return byID, nil
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+const checklistColumns = `id, task_id, title, done, position, created_at, updated_at`
+
+func scanChecklistItem(row scanner) (model.ChecklistItem, error) {
+	var c model.ChecklistItem
+	err := row.Scan(&c.ID, &c.TaskID, &c.Title, &c.Done, &c.Position, &c.CreatedAt, &c.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, ErrNotFound
+	}
+	return c, err
+}
+
+func (s *SQLStore) ListChecklist(ctx context.Context, taskID string) ([]model.ChecklistItem, error) {
+	rows, err := s.query(ctx, `SELECT `+checklistColumns+` FROM checklist_items WHERE task_id = ? ORDER BY position, id`,
+		taskID)
+	if err != nil {
+		return nil, fmt.Errorf("listing checklist: %w", err)
+	}
+	defer rows.Close()
+
+	items := []model.ChecklistItem{}
+	for rows.Next() {
+		c, err := scanChecklistItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning checklist item: %w", err)
+		}
+		items = append(items, c)
+	}
+	return items, rows.Err()
+}
+
+func (s *SQLStore) GetChecklistItem(ctx context.Context, id string) (model.ChecklistItem, error) {
+	return scanChecklistItem(s.queryRow(ctx, `SELECT `+checklistColumns+` FROM checklist_items WHERE id = ?`, id))
+}
+
+func (s *SQLStore) CreateChecklistItem(ctx context.Context, c *model.ChecklistItem) error {
+	c.ID = NewID()
+	_, err := s.exec(ctx, `INSERT INTO checklist_items (`+checklistColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		c.ID, c.TaskID, c.Title, c.Done, c.Position, c.CreatedAt, c.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting checklist item: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) UpdateChecklistItem(ctx context.Context, c *model.ChecklistItem) error {
+	return s.execOne(ctx, `UPDATE checklist_items SET title = ?, done = ?, position = ?, updated_at = ? WHERE id = ?`,
+		c.Title, c.Done, c.Position, c.UpdatedAt, c.ID)
+}
+
+func (s *SQLStore) DeleteChecklistItem(ctx context.Context, id string) error {
+	return s.execOne(ctx, `DELETE FROM checklist_items WHERE id = ?`, id)
+}
+
+func (s *SQLStore) ReorderChecklist(ctx context.Context, taskID string, itemIDs []string) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		for i, id := range itemIDs {
+			err := tx.execOne(ctx, `UPDATE checklist_items SET position = ? WHERE id = ? AND task_id = ?`,
+				i+1, id, taskID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+const commentColumns = `c.id, c.task_id, c.author_id, u.username, c.reply_to, c.body, c.created_at, c.edited_at`
+
+// commentFrom joins the author so their username comes with the comment.
+// Comments by deleted accounts are still listed.
+const commentFrom = ` FROM comments c LEFT JOIN users u ON u.id = c.author_id`
+
+func scanComment(row scanner) (model.Comment, error) {
+	var c model.Comment
+	var username sql.NullString
+	err := row.Scan(&c.ID, &c.TaskID, &c.AuthorID, &username, nullString{&c.ReplyTo}, &c.Body,
+		&c.CreatedAt, nullTime{&c.EditedAt})
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, ErrNotFound
+	}
+	c.Username = username.String
+	return c, err
+}
+
+func (s *SQLStore) ListComments(ctx context.Context, taskID string) ([]model.Comment, error) {
+	rows, err := s.query(ctx, `SELECT `+commentColumns+commentFrom+` WHERE c.task_id = ? ORDER BY c.created_at, c.id`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("listing comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []model.Comment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning comment: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+func (s *SQLStore) GetComment(ctx context.Context, id string) (model.Comment, error) {
+	return scanComment(s.queryRow(ctx, `SELECT `+commentColumns+commentFrom+` WHERE c.id = ?`, id))
+}
+
+func (s *SQLStore) CreateComment(ctx context.Context, c *model.Comment) error {
+	c.ID = NewID()
+	_, err := s.exec(ctx, `INSERT INTO comments (id, task_id, author_id, reply_to, body, created_at, edited_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		c.ID, c.TaskID, c.AuthorID, c.ReplyTo, c.Body, c.CreatedAt, c.EditedAt)
+	if err != nil {
+		return fmt.Errorf("inserting comment: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) EditComment(ctx context.Context, c *model.Comment, prev model.CommentEdit) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		if err := tx.execOne(ctx, `UPDATE comments SET body = ?, edited_at = ? WHERE id = ?`, c.Body, c.EditedAt, c.ID); err != nil {
+			return err
+		}
+		_, err := tx.exec(ctx, `INSERT INTO comment_edits (comment_id, body, edited_at) VALUES (?, ?, ?)`,
+			prev.CommentID, prev.Body, prev.EditedAt)
+		if err != nil {
+			return fmt.Errorf("recording comment edit: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *SQLStore) DeleteComment(ctx context.Context, id string) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		_, err := tx.exec(ctx, `UPDATE comments SET reply_to = (SELECT reply_to FROM comments WHERE id = ?)
+			WHERE reply_to = ?`, id, id)
+		if err != nil {
+			return fmt.Errorf("moving replies: %w", err)
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM comment_edits WHERE comment_id = ?`, id); err != nil {
+			return fmt.Errorf("clearing comment history: %w", err)
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM mentions WHERE comment_id = ?`, id); err != nil {
+			return fmt.Errorf("clearing mentions: %w", err)
+		}
+		return tx.execOne(ctx, `DELETE FROM comments WHERE id = ?`, id)
+	})
+}
+
+// deleteComments removes every comment on a task along with their history.
+func (s *SQLStore) deleteComments(ctx context.Context, taskID string) error {
+	_, err := s.exec(ctx, `DELETE FROM comment_edits WHERE comment_id IN (SELECT id FROM comments WHERE task_id = ?)`, taskID)
+	if err != nil {
+		return fmt.Errorf("clearing comment history: %w", err)
+	}
+	if _, err := s.exec(ctx, `DELETE FROM comments WHERE task_id = ?`, taskID); err != nil {
+		return fmt.Errorf("clearing comments: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) CommentHistory(ctx context.Context, commentID string) ([]model.CommentEdit, error) {
+	rows, err := s.query(ctx, `SELECT comment_id, body, edited_at FROM comment_edits
+		WHERE comment_id = ? ORDER BY edited_at`, commentID)
+	if err != nil {
+		return nil, fmt.Errorf("listing comment history: %w", err)
+	}
+	defer rows.Close()
+
+	edits := []model.CommentEdit{}
+	for rows.Next() {
+		var e model.CommentEdit
+		if err := rows.Scan(&e.CommentID, &e.Body, &e.EditedAt); err != nil {
+			return nil, fmt.Errorf("scanning comment edit: %w", err)
+		}
+		edits = append(edits, e)
+	}
+	return edits, rows.Err()
+}
+
+const mentionColumns = `id, task_id, comment_id, user_id, author_id, created_at`
+
+func (s *SQLStore) ListMentions(ctx context.Context, commentID string) ([]model.Mention, error) {
+	rows, err := s.query(ctx, `SELECT `+mentionColumns+` FROM mentions WHERE comment_id = ? ORDER BY created_at, id`,
+		commentID)
+	if err != nil {
+		return nil, fmt.Errorf("listing mentions: %w", err)
+	}
+	defer rows.Close()
+
+	mentions := []model.Mention{}
+	for rows.Next() {
+		var m model.Mention
+		if err := rows.Scan(&m.ID, &m.TaskID, &m.CommentID, &m.UserID, &m.AuthorID, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning mention: %w", err)
+		}
+		mentions = append(mentions, m)
+	}
+	return mentions, rows.Err()
+}
+
+func (s *SQLStore) CreateMention(ctx context.Context, m *model.Mention) error {
+	m.ID = NewID()
+	_, err := s.exec(ctx, `INSERT INTO mentions (`+mentionColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		m.ID, m.TaskID, m.CommentID, m.UserID, m.AuthorID, m.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting mention: %w", err)
+	}
+	return nil
+}
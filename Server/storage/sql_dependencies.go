@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+func (s *SQLStore) Blockers(ctx context.Context, taskID string) ([]model.Dependency, error) {
+	return s.dependencies(ctx, `task_id = ?`, taskID)
+}
+
+func (s *SQLStore) Dependents(ctx context.Context, blockerID string) ([]model.Dependency, error) {
+	return s.dependencies(ctx, `blocker_id = ?`, blockerID)
+}
+
+func (s *SQLStore) dependencies(ctx context.Context, where string, id string) ([]model.Dependency, error) {
+	rows, err := s.query(ctx, `SELECT task_id, blocker_id, created_at FROM task_dependencies
+		WHERE `+where+` ORDER BY created_at, task_id, blocker_id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("listing dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	deps := []model.Dependency{}
+	for rows.Next() {
+		var d model.Dependency
+		if err := rows.Scan(&d.TaskID, &d.BlockerID, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning dependency: %w", err)
+		}
+		deps = append(deps, d)
+	}
+	return deps, rows.Err()
+}
+
+func (s *SQLStore) AddDependency(ctx context.Context, d model.Dependency) error {
+	_, err := s.exec(ctx, `INSERT INTO task_dependencies (task_id, blocker_id, created_at) VALUES (?, ?, ?)`,
+		d.TaskID, d.BlockerID, d.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("inserting dependency: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) RemoveDependency(ctx context.Context, taskID, blockerID string) error {
+	return s.execOne(ctx, `DELETE FROM task_dependencies WHERE task_id = ? AND blocker_id = ?`, taskID, blockerID)
+}
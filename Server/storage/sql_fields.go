@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+const fieldColumns = `id, project_id, name, type, options, created_at, updated_at`
+
+func scanField(row scanner) (model.CustomField, error) {
+	var f model.CustomField
+	err := row.Scan(&f.ID, &f.ProjectID, &f.Name, &f.Type, optionsColumn{&f.Options}, &f.CreatedAt, &f.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return f, ErrNotFound
+	}
+	return f, err
+}
+
+// optionsColumn stores the options of a custom field as JSON text.
+type optionsColumn struct{ p *[]string }
+
+func (c optionsColumn) Scan(v any) error {
+	var ns sql.NullString
+	if err := ns.Scan(v); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(ns.String), c.p)
+}
+
+func encodeOptions(options []string) string {
+	if options == nil {
+		options = []string{}
+	}
+	b, err := json.Marshal(options)
+	if err != nil {
+		panic("storage: encoding field options: " + err.Error())
+	}
+	return string(b)
+}
+
+func (s *SQLStore) ListFields(ctx context.Context, projectID string) ([]model.CustomField, error) {
+	rows, err := s.query(ctx, `SELECT `+fieldColumns+` FROM custom_fields WHERE project_id = ? ORDER BY created_at, id`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("listing custom fields: %w", err)
+	}
+	defer rows.Close()
+
+	fields := []model.CustomField{}
+	for rows.Next() {
+		f, err := scanField(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning custom field: %w", err)
+		}
+		fields = append(fields, f)
+	}
+	return fields, rows.Err()
+}
+
+func (s *SQLStore) GetField(ctx context.Context, id string) (model.CustomField, error) {
+	return scanField(s.queryRow(ctx, `SELECT `+fieldColumns+` FROM custom_fields WHERE id = ?`, id))
+}
+
+func (s *SQLStore) CreateField(ctx context.Context, f *model.CustomField) error {
+	f.ID = NewID()
+	_, err := s.exec(ctx, `INSERT INTO custom_fields (`+fieldColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		f.ID, f.ProjectID, f.Name, f.Type, encodeOptions(f.Options), f.CreatedAt, f.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("inserting custom field: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) UpdateField(ctx context.Context, f *model.CustomField) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		err := tx.execOne(ctx, `UPDATE custom_fields SET name = ?, options = ?, updated_at = ? WHERE id = ?`,
+			f.Name, encodeOptions(f.Options), f.UpdatedAt, f.ID)
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
+		if err != nil || f.Type != model.FieldSelect {
+			return err
+		}
+		args := []any{f.ID}
+		for _, o := range f.Options {
+			value, _ := encodeFieldValue(o)
+			args = append(args, value)
+		}
+		removed := `field_id = ? AND value NOT IN (` + placeholders(len(f.Options)) + `)`
+		if err := tx.logTasks(ctx, `t.id IN (SELECT task_id FROM task_field_values WHERE `+removed+`)`, args...); err != nil {
+			return err
+		}
+		_, err = tx.exec(ctx, `DELETE FROM task_field_values WHERE `+removed, args...)
+		if err != nil {
+			return fmt.Errorf("clearing removed options: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *SQLStore) DeleteField(ctx context.Context, id string) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		if err := tx.logTasks(ctx, `t.id IN (SELECT task_id FROM task_field_values WHERE field_id = ?)`, id); err != nil {
+			return err
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM task_field_values WHERE field_id = ?`, id); err != nil {
+			return fmt.Errorf("clearing field values: %w", err)
+		}
+		return tx.execOne(ctx, `DELETE FROM custom_fields WHERE id = ?`, id)
+	})
+}
+
+// encodeFieldValue returns the value column of a custom field value, its
+// JSON encoding, and the number column, which only numbers fill in so
+// that they sort by magnitude.
+func encodeFieldValue(v any) (string, *float64) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic("storage: encoding field value: " + err.Error())
+	}
+	if n, ok := v.(float64); ok {
+		return string(b), &n
+	}
+	return string(b), nil
+}
+
+// loadFields fills in the Fields of tasks.
+func (s *SQLStore) loadFields(ctx context.Context, tasks []model.Task) error {
+	index := make(map[string]int, len(tasks))
+	for i := range tasks {
+		index[tasks[i].ID] = i
+	}
+	for start := 0; start < len(tasks); start += tagBatch {
+		batch := tasks[start:min(start+tagBatch, len(tasks))]
+		args := make([]any, len(batch))
+		for i, t := range batch {
+			args[i] = t.ID
+		}
+		rows, err := s.query(ctx, `SELECT task_id, field_id, value FROM task_field_values WHERE task_id IN (`+
+			placeholders(len(batch))+`)`, args...)
+		if err != nil {
+			return fmt.Errorf("loading field values: %w", err)
+		}
+		for rows.Next() {
+			var taskID, fieldID, value string
+			if err := rows.Scan(&taskID, &fieldID, &value); err != nil {
+				rows.Close()
+				return fmt.Errorf("scanning field value: %w", err)
+			}
+			var v any
+			if err := json.Unmarshal([]byte(value), &v); err != nil {
+				rows.Close()
+				return fmt.Errorf("decoding field value: %w", err)
+			}
+			tasks[index[taskID]].Fields[fieldID] = v
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveFields replaces the custom field values of t.
+func (s *SQLStore) saveFields(ctx context.Context, t *model.Task) error {
+	if _, err := s.exec(ctx, `DELETE FROM task_field_values WHERE task_id = ?`, t.ID); err != nil {
+		return fmt.Errorf("clearing field values: %w", err)
+	}
+	for fieldID, v := range t.Fields {
+		value, number := encodeFieldValue(v)
+		_, err := s.exec(ctx, `INSERT INTO task_field_values (task_id, field_id, value, number) VALUES (?, ?, ?, ?)`,
+			t.ID, fieldID, value, number)
+		if err != nil {
+			return fmt.Errorf("saving field value: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+const flagColumns = `key, description, enabled, rollout, updated_at`
+
+func scanFlag(row scanner) (model.Flag, error) {
+	f := model.Flag{Orgs: map[string]bool{}, Users: map[string]bool{}}
+	err := row.Scan(&f.Key, &f.Description, &f.Enabled, &f.Rollout, nullTime{&f.UpdatedAt})
+	if errors.Is(err, sql.ErrNoRows) {
+		return f, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *SQLStore) ListFlags(ctx context.Context) ([]model.Flag, error) {
+	rows, err := s.query(ctx, `SELECT `+flagColumns+` FROM feature_flags ORDER BY key`)
+	if err != nil {
+		return nil, fmt.Errorf("listing flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := []model.Flag{}
+	for rows.Next() {
+		f, err := scanFlag(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning flag: %w", err)
+		}
+		flags = append(flags, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return flags, s.loadFlagOverrides(ctx, flags, `SELECT flag_key, subject, subject_id, enabled FROM feature_flag_overrides`)
+}
+
+func (s *SQLStore) GetFlag(ctx context.Context, key string) (model.Flag, error) {
+	f, err := scanFlag(s.queryRow(ctx, `SELECT `+flagColumns+` FROM feature_flags WHERE key = ?`, key))
+	if err != nil {
+		return model.Flag{}, err
+	}
+	flags := []model.Flag{f}
+	err = s.loadFlagOverrides(ctx, flags, `SELECT flag_key, subject, subject_id, enabled FROM feature_flag_overrides WHERE flag_key = ?`, key)
+	return flags[0], err
+}
+
+// loadFlagOverrides fills in the organizations and users of flags from the
+// overrides query q returns.
+func (s *SQLStore) loadFlagOverrides(ctx context.Context, flags []model.Flag, q string, args ...any) error {
+	byKey := make(map[string]*model.Flag, len(flags))
+	for i := range flags {
+		byKey[flags[i].Key] = &flags[i]
+	}
+	rows, err := s.query(ctx, q, args...)
+	if err != nil {
+		return fmt.Errorf("listing flag overrides: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			key, id string
+			subject model.FlagSubject
+			enabled bool
+		)
+		if err := rows.Scan(&key, &subject, &id, &enabled); err != nil {
+			return fmt.Errorf("scanning flag override: %w", err)
+		}
+		f := byKey[key]
+		if f == nil {
+			continue
+		}
+		if subject == model.FlagUser {
+			f.Users[id] = enabled
+		} else {
+			f.Orgs[id] = enabled
+		}
+	}
+	return rows.Err()
+}
+
+func (s *SQLStore) SaveFlag(ctx context.Context, f model.Flag) error {
+	_, err := s.exec(ctx, `INSERT INTO feature_flags (`+flagColumns+`) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			description = excluded.description, enabled = excluded.enabled,
+			rollout = excluded.rollout, updated_at = excluded.updated_at`,
+		f.Key, f.Description, f.Enabled, f.Rollout, f.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("saving flag: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) DeleteFlag(ctx context.Context, key string) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		if _, err := tx.exec(ctx, `DELETE FROM feature_flag_overrides WHERE flag_key = ?`, key); err != nil {
+			return fmt.Errorf("deleting flag overrides: %w", err)
+		}
+		return tx.execOne(ctx, `DELETE FROM feature_flags WHERE key = ?`, key)
+	})
+}
+
+func (s *SQLStore) SetFlagOverride(ctx context.Context, key string, subject model.FlagSubject, id string, enabled bool) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		var n int
+		if err := tx.queryRow(ctx, `SELECT COUNT(*) FROM feature_flags WHERE key = ?`, key).Scan(&n); err != nil {
+			return fmt.Errorf("finding flag: %w", err)
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+		_, err := tx.exec(ctx, `INSERT INTO feature_flag_overrides (flag_key, subject, subject_id, enabled) VALUES (?, ?, ?, ?)
+			ON CONFLICT (flag_key, subject, subject_id) DO UPDATE SET enabled = excluded.enabled`,
+			key, subject, id, enabled)
+		if err != nil {
+			return fmt.Errorf("saving flag override: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *SQLStore) DeleteFlagOverride(ctx context.Context, key string, subject model.FlagSubject, id string) error {
+	return s.execOne(ctx, `DELETE FROM feature_flag_overrides WHERE flag_key = ? AND subject = ? AND subject_id = ?`, key, subject, id)
+}
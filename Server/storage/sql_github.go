@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+const githubLinkColumns = `project_id, org_id, repo, token, secret, created_by, created_at, synced_at`
+
+func scanGitHubLink(row scanner) (model.GitHubLink, error) {
+	var l model.GitHubLink
+	err := row.Scan(&l.ProjectID, &l.OrgID, &l.Repo, &l.Token, &l.Secret, &l.CreatedBy, &l.CreatedAt, nullTime{&l.SyncedAt})
+	if errors.Is(err, sql.ErrNoRows) {
+		return l, ErrNotFound
+	}
+	return l, err
+}
+
+func (s *SQLStore) GetGitHubLink(ctx context.Context, projectID string) (model.GitHubLink, error) {
+	return scanGitHubLink(s.queryRow(ctx, `SELECT `+githubLinkColumns+` FROM github_links WHERE project_id = ?`, projectID))
+}
+
+func (s *SQLStore) SaveGitHubLink(ctx context.Context, l model.GitHubLink) error {
+	_, err := s.exec(ctx, `INSERT INTO github_links (`+githubLinkColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (project_id) DO UPDATE SET
+			repo = excluded.repo, token = excluded.token, secret = excluded.secret,
+			created_by = excluded.created_by, created_at = excluded.created_at, synced_at = excluded.synced_at`,
+		l.ProjectID, l.OrgID, l.Repo, l.Token, l.Secret, l.CreatedBy, l.CreatedAt, l.SyncedAt)
+	if err != nil {
+		return fmt.Errorf("saving github link: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) DeleteGitHubLink(ctx context.Context, projectID string) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		if _, err := tx.GetGitHubLink(ctx, projectID); err != nil {
+			return err
+		}
+		return tx.deleteGitHubLink(ctx, projectID)
+	})
+}
+
+// deleteGitHubLink removes the link of a project, if it has one, and its
+// issue links.
+func (s *SQLStore) deleteGitHubLink(ctx context.Context, projectID string) error {
+	if _, err := s.exec(ctx, `DELETE FROM issue_links WHERE project_id = ?`, projectID); err != nil {
+		return fmt.Errorf("clearing issue links: %w", err)
+	}
+	if _, err := s.exec(ctx, `DELETE FROM github_links WHERE project_id = ?`, projectID); err != nil {
+		return fmt.Errorf("deleting github link: %w", err)
+	}
+	return nil
+}
+
+func scanIssueLink(row scanner) (model.IssueLink, error) {
+	var l model.IssueLink
+	err := row.Scan(&l.TaskID, &l.ProjectID, &l.Number, &l.State)
+	if errors.Is(err, sql.ErrNoRows) {
+		return l, ErrNotFound
+	}
+	return l, err
+}
+
+func (s *SQLStore) GetIssueLink(ctx context.Context, taskID string) (model.IssueLink, error) {
+	return scanIssueLink(s.queryRow(ctx, `SELECT task_id, project_id, number, state FROM issue_links WHERE task_id = ?`, taskID))
+}
+
+func (s *SQLStore) FindIssueLink(ctx context.Context, projectID string, number int) (model.IssueLink, error) {
+	return scanIssueLink(s.queryRow(ctx, `SELECT task_id, project_id, number, state FROM issue_links
+		WHERE project_id = ? AND number = ?`, projectID, number))
+}
+
+func (s *SQLStore) SaveIssueLink(ctx context.Context, l model.IssueLink) error {
+	_, err := s.exec(ctx, `INSERT INTO issue_links (task_id, project_id, number, state) VALUES (?, ?, ?, ?)
+		ON CONFLICT (task_id) DO UPDATE SET
+			project_id = excluded.project_id, number = excluded.number, state = excluded.state`,
+		l.TaskID, l.ProjectID, l.Number, l.State)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("saving issue link: %w", err)
+	}
+	return nil
+}
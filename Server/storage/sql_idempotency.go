@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const idempotencyColumns = `user_id, key, fingerprint, done, status, header, body, created_at`
+
+func (s *SQLStore) ClaimIdempotencyKey(ctx context.Context, r IdempotencyRecord) (IdempotencyRecord, bool, error) {
+	_, err := s.exec(ctx, `INSERT INTO idempotency_keys (user_id, key, fingerprint, created_at) VALUES (?, ?, ?, ?)`,
+		r.UserID, r.Key, r.Fingerprint, r.CreatedAt)
+	if err == nil {
+		return r, true, nil
+	}
+	if !isUniqueViolation(err) {
+		return r, false, fmt.Errorf("claiming idempotency key: %w", err)
+	}
+
+	var (
+		prev   IdempotencyRecord
+		header string
+		body   string
+	)
+	err = s.queryRow(ctx, `SELECT `+idempotencyColumns+` FROM idempotency_keys WHERE user_id = ? AND key = ?`, r.UserID, r.Key).
+		Scan(&prev.UserID, &prev.Key, &prev.Fingerprint, &prev.Done, &prev.Status, &header, &body, &prev.CreatedAt)
+	if err != nil {
+		return prev, false, fmt.Errorf("reading idempotency key: %w", err)
+	}
+	if err := json.Unmarshal([]byte(header), &prev.Header); err != nil {
+		return prev, false, fmt.Errorf("decoding response header: %w", err)
+	}
+	prev.Body = []byte(body)
+	return prev, false, nil
+}
+
+func (s *SQLStore) FinishIdempotencyKey(ctx context.Context, r IdempotencyRecord) error {
+	header, err := json.Marshal(r.Header)
+	if err != nil {
+		return err
+	}
+	return s.execOne(ctx, `UPDATE idempotency_keys SET fingerprint = ?, done = ?, status = ?, header = ?, body = ?
+		WHERE user_id = ? AND key = ?`, r.Fingerprint, true, r.Status, string(header), string(r.Body), r.UserID, r.Key)
+}
+
+func (s *SQLStore) ReleaseIdempotencyKey(ctx context.Context, userID, key string) error {
+	if _, err := s.exec(ctx, `DELETE FROM idempotency_keys WHERE user_id = ? AND key = ?`, userID, key); err != nil {
+		return fmt.Errorf("releasing idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int, error) {
+	res, err := s.exec(ctx, `DELETE FROM idempotency_keys WHERE created_at < ?`, before)
+	if err != nil {
+		return 0, fmt.Errorf("purging idempotency keys: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *SQLStore) PurgeableIdempotencyKeys(ctx context.Context, before time.Time) (int, error) {
+	var n int
+	if err := s.queryRow(ctx, `SELECT COUNT(*) FROM idempotency_keys WHERE created_at < ?`, before).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting purgeable idempotency keys: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+func scanInboundAddress(row scanner) (model.InboundAddress, error) {
+	var a model.InboundAddress
+	err := row.Scan(&a.UserID, &a.OrgID, &a.TokenHash, &a.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return a, ErrNotFound
+	}
+	return a, err
+}
+
+func (s *SQLStore) GetInboundAddress(ctx context.Context, orgID, userID string) (model.InboundAddress, error) {
+	return scanInboundAddress(s.queryRow(ctx, `SELECT user_id, org_id, token_hash, created_at FROM inbound_addresses
+		WHERE org_id = ? AND user_id = ?`, orgID, userID))
+}
+
+func (s *SQLStore) GetInboundAddressByToken(ctx context.Context, tokenHash string) (model.InboundAddress, error) {
+	return scanInboundAddress(s.queryRow(ctx, `SELECT user_id, org_id, token_hash, created_at FROM inbound_addresses
+		WHERE token_hash = ?`, tokenHash))
+}
+
+func (s *SQLStore) SaveInboundAddress(ctx context.Context, a model.InboundAddress) error {
+	_, err := s.exec(ctx, `INSERT INTO inbound_addresses (org_id, user_id, token_hash, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (org_id, user_id) DO UPDATE SET
+			token_hash = excluded.token_hash, created_at = excluded.created_at`,
+		a.OrgID, a.UserID, a.TokenHash, a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("saving inbound address: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) DeleteInboundAddress(ctx context.Context, orgID, userID string) error {
+	return s.execOne(ctx, `DELETE FROM inbound_addresses WHERE org_id = ? AND user_id = ?`, orgID, userID)
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"starttech-server/model"
+)
+
+const jobColumns = `id, kind, payload, status, attempts, max_attempts, last_error, run_at, locked_until,
+	created_at, completed_at, trace_parent`
+
+func scanJob(row scanner) (model.Job, error) {
+	var j model.Job
+	var payload sql.NullString
+	err := row.Scan(&j.ID, &j.Kind, &payload, &j.Status, &j.Attempts, &j.MaxAttempts, &j.LastError, &j.RunAt,
+		nullTime{&j.LockedUntil}, &j.CreatedAt, nullTime{&j.CompletedAt}, &j.TraceParent)
+	if errors.Is(err, sql.ErrNoRows) {
+		return j, ErrNotFound
+	}
+	if payload.Valid {
+		j.Payload = []byte(payload.String)
+	}
+	return j, err
+}
+
+func (s *SQLStore) listJobs(ctx context.Context, where string, args ...any) ([]model.Job, error) {
+	rows, err := s.query(ctx, `SELECT `+jobColumns+` FROM jobs WHERE `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []model.Job{}
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning job: %w", err)
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+func (s *SQLStore) CreateJob(ctx context.Context, j *model.Job) error {
+	if j.ID == "" {
+		j.ID = NewID()
+	}
+	_, err := s.exec(ctx, `INSERT INTO jobs (`+jobColumns+`) VALUES (`+placeholders(12)+`)`,
+		j.ID, j.Kind, string(j.Payload), j.Status, j.Attempts, j.MaxAttempts, j.LastError, j.RunAt, j.LockedUntil,
+		j.CreatedAt, j.CompletedAt, j.TraceParent)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("inserting job: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) GetJob(ctx context.Context, id string) (model.Job, error) {
+	return scanJob(s.queryRow(ctx, `SELECT `+jobColumns+` FROM jobs WHERE id = ?`, id))
+}
+
+func (s *SQLStore) ListJobs(ctx context.Context, f JobFilter) ([]model.Job, error) {
+	var conds []string
+	var args []any
+	if f.Status != "" {
+		conds, args = append(conds, "status = ?"), append(args, f.Status)
+	}
+	if f.Kind != "" {
+		conds, args = append(conds, "kind = ?"), append(args, f.Kind)
+	}
+	where := "1 = 1"
+	if len(conds) > 0 {
+		where = strings.Join(conds, " AND ")
+	}
+	where += " ORDER BY created_at DESC, id DESC"
+	if f.Limit > 0 {
+		where, args = where+" LIMIT ?", append(args, f.Limit)
+	}
+	return s.listJobs(ctx, where, args...)
+}
+
+// ClaimJobs picks candidates and then takes each with an UPDATE that only
+// succeeds while the job is still claimable, so that of several instances
+// claiming at once only one gets a given job.
+func (s *SQLStore) ClaimJobs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]model.Job, error) {
+	due, err := s.listJobs(ctx, `(status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?)
+		ORDER BY run_at, id LIMIT ?`, model.JobPending, now, model.JobRunning, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	until := now.Add(lease)
+	claimed := due[:0]
+	for _, j := range due {
+		err := s.execOne(ctx, `UPDATE jobs SET status = ?, locked_until = ?
+			WHERE id = ? AND ((status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?))`,
+			model.JobRunning, until, j.ID, model.JobPending, now, model.JobRunning, now)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return claimed, fmt.Errorf("claiming job: %w", err)
+		}
+		j.Status, j.LockedUntil = model.JobRunning, &until
+		claimed = append(claimed, j)
+	}
+	return claimed, nil
+}
+
+// UpdateJob drops the payload of a job that has succeeded.
+func (s *SQLStore) UpdateJob(ctx context.Context, j *model.Job) error {
+	return s.execOne(ctx, `UPDATE jobs SET status = ?, attempts = ?, last_error = ?, run_at = ?, locked_until = ?,
+		completed_at = ?, payload = CASE WHEN ? THEN NULL ELSE payload END WHERE id = ?`,
+		j.Status, j.Attempts, j.LastError, j.RunAt, j.LockedUntil, j.CompletedAt, j.Status == model.JobSucceeded, j.ID)
+}
+
+func (s *SQLStore) DeleteJob(ctx context.Context, id string) error {
+	return s.execOne(ctx, `DELETE FROM jobs WHERE id = ?`, id)
+}
+
+func (s *SQLStore) PurgeJobs(ctx context.Context, before time.Time) (int, error) {
+	res, err := s.exec(ctx, `DELETE FROM jobs WHERE completed_at < ?`, before)
+	if err != nil {
+		return 0, fmt.Errorf("purging jobs: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *SQLStore) PurgeableJobs(ctx context.Context, before time.Time) (int, error) {
+	var n int
+	if err := s.queryRow(ctx, `SELECT COUNT(*) FROM jobs WHERE completed_at < ?`, before).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting purgeable jobs: %w", err)
+	}
+	return n, nil
+}
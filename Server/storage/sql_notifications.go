@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"starttech-server/model"
+)
+
+func (s *SQLStore) GetNotificationPrefs(ctx context.Context, userID string) (model.NotificationPrefs, error) {
+	p := model.NotificationPrefs{UserID: userID}
+	err := s.queryRow(ctx, `SELECT assigned, due_soon, mentioned FROM notification_prefs WHERE user_id = ?`, userID).
+		Scan(&p.Assigned, &p.DueSoon, &p.Mentioned)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.DefaultNotificationPrefs(userID), nil
+	}
+	if err != nil {
+		return p, fmt.Errorf("reading notification preferences: %w", err)
+	}
+	return p, nil
+}
+
+func (s *SQLStore) SaveNotificationPrefs(ctx context.Context, p model.NotificationPrefs) error {
+	_, err := s.exec(ctx, `INSERT INTO notification_prefs (user_id, assigned, due_soon, mentioned) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			assigned = excluded.assigned, due_soon = excluded.due_soon, mentioned = excluded.mentioned`,
+		p.UserID, p.Assigned, p.DueSoon, p.Mentioned)
+	if err != nil {
+		return fmt.Errorf("saving notification preferences: %w", err)
+	}
+	return nil
+}
+
+const notificationColumns = `id, org_id, user_id, kind, actor_id, task_id, comment_id, title, read_at, created_at`
+
+func scanNotification(row scanner) (model.Notification, error) {
+	var n model.Notification
+	err := row.Scan(&n.ID, &n.OrgID, &n.UserID, &n.Kind, &n.ActorID, &n.TaskID, nullString{&n.CommentID}, &n.Title,
+		nullTime{&n.ReadAt}, &n.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return n, ErrNotFound
+	}
+	return n, err
+}
+
+func (s *SQLStore) ListNotifications(ctx context.Context, f NotificationFilter) ([]model.Notification, error) {
+	q := `SELECT ` + notificationColumns + ` FROM notifications WHERE org_id = ? AND user_id = ?`
+	args := []any{f.OrgID, f.UserID}
+	if f.Unread {
+		q += ` AND read_at IS NULL`
+	}
+	q += ` ORDER BY created_at DESC, id DESC`
+	if f.Limit > 0 {
+		q += ` LIMIT ? OFFSET ?`
+		args = append(args, f.Limit, f.Offset)
+	}
+
+	rows, err := s.query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing notifications: %w", err)
+	}
+	defer rows.Close()
+
+	out := []model.Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning notification: %w", err)
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLStore) CountUnreadNotifications(ctx context.Context, orgID, userID string) (int, error) {
+	var n int
+	err := s.queryRow(ctx, `SELECT COUNT(*) FROM notifications WHERE org_id = ? AND user_id = ? AND read_at IS NULL`,
+		orgID, userID).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("counting notifications: %w", err)
+	}
+	return n, nil
+}
+
+func (s *SQLStore) GetNotification(ctx context.Context, id string) (model.Notification, error) {
+	return scanNotification(s.queryRow(ctx, `SELECT `+notificationColumns+` FROM notifications WHERE id = ?`, id))
+}
+
+func (s *SQLStore) CreateNotification(ctx context.Context, n *model.Notification) error {
+	n.ID = NewID()
+	_, err := s.exec(ctx, `INSERT INTO notifications (`+notificationColumns+`) VALUES (`+placeholders(10)+`)`,
+		n.ID, n.OrgID, n.UserID, n.Kind, n.ActorID, n.TaskID, n.CommentID, n.Title, n.ReadAt, n.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting notification: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) MarkNotificationsRead(ctx context.Context, ids []string, at time.Time) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		for _, id := range ids {
+			if _, err := tx.exec(ctx, `UPDATE notifications SET read_at = ? WHERE id = ? AND read_at IS NULL`, at, id); err != nil {
+				return fmt.Errorf("marking notification read: %w", err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"starttech-server/model"
+)
+
+const orgColumns = `id, name, require_two_factor, created_at`
+
+func scanOrg(row scanner) (model.Org, error) {
+	var o model.Org
+	err := row.Scan(&o.ID, &o.Name, &o.RequireTwoFactor, &o.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return o, ErrNotFound
+	}
+	return o, err
+}
+
+func (s *SQLStore) ListOrgs(ctx context.Context, userID string) ([]model.Org, error) {
+	rows, err := s.query(ctx, `SELECT o.id, o.name, o.require_two_factor, o.created_at, m.role FROM orgs o
+		JOIN org_members m ON m.org_id = o.id WHERE m.user_id = ? ORDER BY m.created_at, o.id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing orgs: %w", err)
+	}
+	defer rows.Close()
+
+	orgs := []model.Org{}
+	for rows.Next() {
+		var o model.Org
+		if err := rows.Scan(&o.ID, &o.Name, &o.RequireTwoFactor, &o.CreatedAt, &o.Role); err != nil {
+			return nil, fmt.Errorf("scanning org: %w", err)
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
+
+func (s *SQLStore) GetOrg(ctx context.Context, id string) (model.Org, error) {
+	return scanOrg(s.queryRow(ctx, `SELECT `+orgColumns+` FROM orgs WHERE id = ?`, id))
+}
+
+func (s *SQLStore) FindOrgs(ctx context.Context, f OrgFilter) ([]model.Org, error) {
+	q := `SELECT ` + orgColumns + ` FROM orgs`
+	var args []any
+	if f.Query != "" {
+		q += ` WHERE LOWER(name) LIKE ?`
+		args = append(args, "%"+strings.ToLower(f.Query)+"%")
+	}
+	q += ` ORDER BY created_at, id`
+	if f.Limit > 0 {
+		q += ` LIMIT ? OFFSET ?`
+		args = append(args, f.Limit, f.Offset)
+	}
+
+	rows, err := s.query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("finding orgs: %w", err)
+	}
+	defer rows.Close()
+
+	orgs := []model.Org{}
+	for rows.Next() {
+		o, err := scanOrg(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning org: %w", err)
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
+
+func (s *SQLStore) CreateOrg(ctx context.Context, o *model.Org, ownerID string) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		o.ID = NewID()
+		_, err := tx.exec(ctx, `INSERT INTO orgs (`+orgColumns+`) VALUES (?, ?, ?, ?)`, o.ID, o.Name, o.RequireTwoFactor, o.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("inserting org: %w", err)
+		}
+		return tx.SaveOrgMember(ctx, &model.OrgMembership{OrgID: o.ID, UserID: ownerID, Role: model.OrgOwner, CreatedAt: o.CreatedAt})
+	})
+}
+
+func (s *SQLStore) UpdateOrg(ctx context.Context, o *model.Org) error {
+	return s.execOne(ctx, `UPDATE orgs SET name = ?, require_two_factor = ? WHERE id = ?`, o.Name, o.RequireTwoFactor, o.ID)
+}
+
+const orgMemberColumns = `m.org_id, m.user_id, u.username, m.role, m.created_at`
+
+func scanOrgMember(row scanner) (model.OrgMembership, error) {
+	var m model.OrgMembership
+	err := row.Scan(&m.OrgID, &m.UserID, &m.Username, &m.Role, &m.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return m, ErrNotFound
+	}
+	return m, err
+}
+
+func (s *SQLStore) ListOrgMembers(ctx context.Context, orgID string) ([]model.OrgMembership, error) {
+	rows, err := s.query(ctx, `SELECT `+orgMemberColumns+` FROM org_members m JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = ? ORDER BY m.created_at, m.user_id`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("listing org members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []model.OrgMembership{}
+	for rows.Next() {
+		m, err := scanOrgMember(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning org member: %w", err)
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+func (s *SQLStore) GetOrgMember(ctx context.Context, orgID, userID string) (model.OrgMembership, error) {
+	return scanOrgMember(s.queryRow(ctx, `SELECT `+orgMemberColumns+` FROM org_members m JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = ? AND m.user_id = ?`, orgID, userID))
+}
+
+func (s *SQLStore) SaveOrgMember(ctx context.Context, m *model.OrgMembership) error {
+	_, err := s.exec(ctx, `INSERT INTO org_members (org_id, user_id, role, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (org_id, user_id) DO UPDATE SET role = excluded.role`,
+		m.OrgID, m.UserID, m.Role, m.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("saving org member: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) RemoveOrgMember(ctx context.Context, orgID, userID string) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		if err := tx.logRevoked(ctx, `t.org_id = ? AND m.user_id = ?`, orgID, userID); err != nil {
+			return err
+		}
+		_, err := tx.exec(ctx, `DELETE FROM project_members WHERE user_id = ?
+			AND project_id IN (SELECT id FROM projects WHERE org_id = ?)`, userID, orgID)
+		if err != nil {
+			return fmt.Errorf("removing project memberships: %w", err)
+		}
+		return tx.execOne(ctx, `DELETE FROM org_members WHERE org_id = ? AND user_id = ?`, orgID, userID)
+	})
+}
+
+const invitationColumns = `id, org_id, email, role, invited_by, token_hash, created_at, expires_at, accepted_at`
+
+func scanInvitation(row scanner) (model.Invitation, error) {
+	var inv model.Invitation
+	err := row.Scan(&inv.ID, &inv.OrgID, &inv.Email, &inv.Role, &inv.InvitedBy, &inv.TokenHash,
+		&inv.CreatedAt, &inv.ExpiresAt, nullTime{&inv.AcceptedAt})
+	if errors.Is(err, sql.ErrNoRows) {
+		return inv, ErrNotFound
+	}
+	return inv, err
+}
+
+func (s *SQLStore) ListInvitations(ctx context.Context, orgID string) ([]model.Invitation, error) {
+	rows, err := s.query(ctx, `SELECT `+invitationColumns+` FROM org_invitations
+		WHERE org_id = ? AND accepted_at IS NULL ORDER BY created_at DESC, id DESC`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("listing invitations: %w", err)
+	}
+	defer rows.Close()
+
+	invs := []model.Invitation{}
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning invitation: %w", err)
+		}
+		invs = append(invs, inv)
+	}
+	return invs, rows.Err()
+}
+
+func (s *SQLStore) GetInvitation(ctx context.Context, id string) (model.Invitation, error) {
+	return scanInvitation(s.queryRow(ctx, `SELECT `+invitationColumns+` FROM org_invitations WHERE id = ?`, id))
+}
+
+func (s *SQLStore) GetInvitationByToken(ctx context.Context, tokenHash string) (model.Invitation, error) {
+	return scanInvitation(s.queryRow(ctx, `SELECT `+invitationColumns+` FROM org_invitations WHERE token_hash = ?`, tokenHash))
+}
+
+func (s *SQLStore) CreateInvitation(ctx context.Context, inv *model.Invitation) error {
+	inv.ID = NewID()
+	_, err := s.exec(ctx, `INSERT INTO org_invitations (`+invitationColumns+`) VALUES (`+placeholders(9)+`)`,
+		inv.ID, inv.OrgID, inv.Email, inv.Role, inv.InvitedBy, inv.TokenHash, inv.CreatedAt, inv.ExpiresAt, inv.AcceptedAt)
+	if err != nil {
+		return fmt.Errorf("inserting invitation: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) AcceptInvitation(ctx context.Context, id string, m *model.OrgMembership) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		err := tx.execOne(ctx, `UPDATE org_invitations SET accepted_at = ? WHERE id = ? AND accepted_at IS NULL`,
+			m.CreatedAt, id)
+		if err != nil {
+			return err
+		}
+		return tx.SaveOrgMember(ctx, m)
+	})
+}
+
+func (s *SQLStore) DeleteInvitation(ctx context.Context, id string) error {
+	return s.execOne(ctx, `DELETE FROM org_invitations WHERE id = ?`, id)
+}
+
+const domainColumns = `org_id, domain, token, verified_at, created_at`
+
+func scanDomain(row scanner) (model.Domain, error) {
+	var d model.Domain
+	err := row.Scan(&d.OrgID, &d.Domain, &d.Token, nullTime{&d.VerifiedAt}, &d.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return d, ErrNotFound
+	}
+	return d, err
+}
+
+func (s *SQLStore) ListDomains(ctx context.Context, orgID string) ([]model.Domain, error) {
+	rows, err := s.query(ctx, `SELECT `+domainColumns+` FROM org_domains WHERE org_id = ? ORDER BY created_at, domain`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("listing domains: %w", err)
+	}
+	defer rows.Close()
+
+	domains := []model.Domain{}
+	for rows.Next() {
+		d, err := scanDomain(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning domain: %w", err)
+		}
+		domains = append(domains, d)
+	}
+	return domains, rows.Err()
+}
+
+func (s *SQLStore) GetDomain(ctx context.Context, orgID, domain string) (model.Domain, error) {
+	return scanDomain(s.queryRow(ctx, `SELECT `+domainColumns+` FROM org_domains WHERE org_id = ? AND domain = ?`, orgID, domain))
+}
+
+func (s *SQLStore) CreateDomain(ctx context.Context, d model.Domain) error {
+	_, err := s.exec(ctx, `INSERT INTO org_domains (`+domainColumns+`) VALUES (`+placeholders(5)+`)`,
+		d.OrgID, d.Domain, d.Token, d.VerifiedAt, d.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("inserting domain: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) VerifyDomain(ctx context.Context, orgID, domain string, at time.Time) error {
+	err := s.execOne(ctx, `UPDATE org_domains SET verified_at = ? WHERE org_id = ? AND domain = ?`, at, orgID, domain)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (s *SQLStore) DeleteDomain(ctx context.Context, orgID, domain string) error {
+	return s.execOne(ctx, `DELETE FROM org_domains WHERE org_id = ? AND domain = ?`, orgID, domain)
+}
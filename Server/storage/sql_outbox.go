@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"starttech-server/model"
+)
+
+const outboxColumns = `id, seq, type, org_id, recipients, data, trace_parent, created_at, locked_until`
+
+func scanOutboxEntry(row scanner) (model.OutboxEntry, error) {
+	var e model.OutboxEntry
+	var data string
+	err := row.Scan(&e.ID, &e.Seq, &e.Type, &e.OrgID, recipientsColumn{&e.Recipients}, &data, &e.TraceParent,
+		&e.CreatedAt, nullTime{&e.LockedUntil})
+	if errors.Is(err, sql.ErrNoRows) {
+		return e, ErrNotFound
+	}
+	e.Data = json.RawMessage(data)
+	return e, err
+}
+
+// recipientsColumn stores the recipients of an outbox entry as JSON text.
+type recipientsColumn struct{ p *[]string }
+
+func (c recipientsColumn) Scan(v any) error {
+	var ns sql.NullString
+	if err := ns.Scan(v); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(ns.String), c.p)
+}
+
+func encodeRecipients(ids []string) string {
+	if ids == nil {
+		ids = []string{}
+	}
+	b, err := json.Marshal(ids)
+	if err != nil {
+		panic("storage: encoding recipients: " + err.Error())
+	}
+	return string(b)
+}
+
+func (s *SQLStore) AppendOutbox(ctx context.Context, entries []model.OutboxEntry) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		for i := range entries {
+			e := &entries[i]
+			e.ID = NewID()
+			_, err := tx.exec(ctx, `INSERT INTO outbox (`+outboxColumns+`) VALUES (`+placeholders(9)+`)`,
+				e.ID, e.Seq, e.Type, e.OrgID, encodeRecipients(e.Recipients), string(e.Data), e.TraceParent,
+				e.CreatedAt, e.LockedUntil)
+			if err != nil {
+				return fmt.Errorf("inserting outbox entry: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// ClaimOutbox takes each candidate with an UPDATE that only succeeds while
+// it is still unlocked, as ClaimJobs does.
+func (s *SQLStore) ClaimOutbox(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]model.OutboxEntry, error) {
+	rows, err := s.query(ctx, `SELECT `+outboxColumns+` FROM outbox WHERE locked_until IS NULL OR locked_until < ?
+		ORDER BY created_at, seq, id LIMIT ?`, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("listing outbox: %w", err)
+	}
+	var due []model.OutboxEntry
+	for rows.Next() {
+		e, err := scanOutboxEntry(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scanning outbox entry: %w", err)
+		}
+		due = append(due, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	until := now.Add(lease)
+	claimed := due[:0]
+	for _, e := range due {
+		err := s.execOne(ctx, `UPDATE outbox SET locked_until = ?
+			WHERE id = ? AND (locked_until IS NULL OR locked_until < ?)`, until, e.ID, now)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return claimed, fmt.Errorf("claiming outbox entry: %w", err)
+		}
+		e.LockedUntil = &until
+		claimed = append(claimed, e)
+	}
+	return claimed, nil
+}
+
+func (s *SQLStore) DeleteOutbox(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	if _, err := s.exec(ctx, `DELETE FROM outbox WHERE id IN (`+placeholders(len(ids))+`)`, args...); err != nil {
+		return fmt.Errorf("deleting outbox entries: %w", err)
+	}
+	return nil
+}
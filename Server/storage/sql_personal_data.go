@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"starttech-server/model"
+)
+
+func (s *SQLStore) ListUserComments(ctx context.Context, userID string) ([]model.Comment, error) {
+	rows, err := s.query(ctx, `SELECT `+commentColumns+commentFrom+` WHERE c.author_id = ? ORDER BY c.created_at, c.id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing user comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []model.Comment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning comment: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+func (s *SQLStore) ListUserAttachments(ctx context.Context, userID string) ([]model.Attachment, error) {
+	rows, err := s.query(ctx, `SELECT `+attachmentColumns+` FROM attachments WHERE uploader_id = ? ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing user attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := []model.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning attachment: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+// userRows lists the statements EraseUser deletes a user's belongings
+// with, each taking the user's ID once.
+var userRows = []string{
+	`DELETE FROM task_tags WHERE tag_id IN (SELECT id FROM tags WHERE owner_id = ?)`,
+	`DELETE FROM tags WHERE owner_id = ?`,
+	`DELETE FROM views WHERE owner_id = ?`,
+	`DELETE FROM templates WHERE owner_id = ?`,
+	`DELETE FROM reminders WHERE owner_id = ?`,
+	`DELETE FROM notification_prefs WHERE user_id = ?`,
+	`DELETE FROM notifications WHERE user_id = ?`,
+	`DELETE FROM mentions WHERE user_id = ?`,
+	`DELETE FROM webhook_deliveries WHERE webhook_id IN (SELECT id FROM webhooks WHERE owner_id = ?)`,
+	`DELETE FROM webhooks WHERE owner_id = ?`,
+	`DELETE FROM project_members WHERE user_id = ?`,
+	`DELETE FROM org_members WHERE user_id = ?`,
+	`DELETE FROM scim_links WHERE user_id = ?`,
+	`DELETE FROM task_watchers WHERE user_id = ?`,
+	`DELETE FROM share_links WHERE created_by = ?`,
+	`DELETE FROM idempotency_keys WHERE user_id = ?`,
+	`DELETE FROM calendar_feeds WHERE user_id = ?`,
+	`DELETE FROM inbound_addresses WHERE user_id = ?`,
+	`DELETE FROM board_imports WHERE user_id = ?`,
+	`DELETE FROM undo_commands WHERE user_id = ?`,
+	`DELETE FROM feature_flag_overrides WHERE subject = 'user' AND subject_id = ?`,
+	`DELETE FROM user_identities WHERE user_id = ?`,
+	`DELETE FROM user_two_factor WHERE user_id = ?`,
+	`DELETE FROM recovery_codes WHERE user_id = ?`,
+	`DELETE FROM sessions WHERE user_id = ?`,
+	`DELETE FROM api_keys WHERE owner_id = ?`,
+}
+
+func (s *SQLStore) EraseUser(ctx context.Context, u model.User) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		stored, err := tx.GetUser(ctx, u.ID)
+		if err != nil {
+			return err
+		}
+		err = tx.logTasks(ctx, `t.id IN (SELECT task_id FROM task_tags
+			WHERE tag_id IN (SELECT id FROM tags WHERE owner_id = ?))`, u.ID)
+		if err != nil {
+			return err
+		}
+		for _, q := range userRows {
+			if _, err := tx.exec(ctx, q, u.ID); err != nil {
+				return fmt.Errorf("erasing user: %w", err)
+			}
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM org_invitations WHERE LOWER(email) = LOWER(?)`, stored.Email); err != nil {
+			return fmt.Errorf("erasing user invitations: %w", err)
+		}
+		return tx.UpdateUser(ctx, u)
+	})
+}
+
+func (s *SQLStore) RedactAuditEvents(ctx context.Context, userID, email string, e *model.AuditEvent, hash func(model.AuditEvent) string) (int, error) {
+	e.ID = NewID()
+	var (
+		n   int
+		err error
+	)
+	for range auditAppendAttempts {
+		n = 0
+		err = s.inTx(ctx, func(tx *SQLStore) error {
+			events, err := tx.auditTail(ctx, userID, email)
+			if err != nil {
+				return err
+			}
+			var (
+				lastSeq  int64
+				lastHash string
+			)
+			for i, ev := range events {
+				if i > 0 {
+					ev.PrevHash = lastHash
+				}
+				if ev.Redact(userID, email) {
+					n++
+				}
+				ev.Hash = hash(ev)
+				err := tx.execOne(ctx, `UPDATE audit_events SET ip = ?, user_agent = ?, detail = ?, prev_hash = ?, hash = ? WHERE seq = ?`,
+					ev.IP, ev.UserAgent, ev.Detail, ev.PrevHash, ev.Hash, ev.Seq)
+				if err != nil {
+					return fmt.Errorf("rewriting audit event: %w", err)
+				}
+				lastSeq, lastHash = ev.Seq, ev.Hash
+			}
+			if len(events) == 0 {
+				err := tx.queryRow(ctx, `SELECT seq, hash FROM audit_events ORDER BY seq DESC LIMIT 1`).Scan(&lastSeq, &lastHash)
+				if err != nil && !errors.Is(err, sql.ErrNoRows) {
+					return fmt.Errorf("reading last audit event: %w", err)
+				}
+			}
+			e.Seq, e.PrevHash = lastSeq+1, lastHash
+			e.Hash = hash(*e)
+			_, err = tx.exec(ctx, `INSERT INTO audit_events (`+auditColumns+`) VALUES (`+placeholders(12)+`)`,
+				e.Seq, e.ID, e.Action, e.ActorID, e.OrgID, e.TargetID, e.IP, e.UserAgent, e.Detail,
+				e.CreatedAt, e.PrevHash, e.Hash)
+			return err
+		})
+		if !isUniqueViolation(err) {
+			break
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("redacting audit events: %w", err)
+	}
+	return n, nil
+}
+
+// auditTail returns the audit events from the first one that may mention
+// the user on, oldest first, or none if no event does.
+func (s *SQLStore) auditTail(ctx context.Context, userID, email string) ([]model.AuditEvent, error) {
+	var first sql.NullInt64
+	err := s.queryRow(ctx, `SELECT MIN(seq) FROM audit_events WHERE actor_id = ? OR target_id = ? OR LOWER(detail) LIKE ?`,
+		userID, userID, "%"+strings.ToLower(email)+"%").Scan(&first)
+	if err != nil {
+		return nil, fmt.Errorf("finding audit events: %w", err)
+	}
+	if !first.Valid {
+		return nil, nil
+	}
+	rows, err := s.query(ctx, `SELECT `+auditColumns+` FROM audit_events WHERE seq >= ? ORDER BY seq`, first.Int64)
+	if err != nil {
+		return nil, fmt.Errorf("listing audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []model.AuditEvent
+	for rows.Next() {
+		e, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning audit event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
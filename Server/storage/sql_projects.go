@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+const projectColumns = `id, org_id, owner_id, name, description, statuses, created_at, updated_at, archived_at`
+
+func scanProject(row scanner) (model.Project, error) {
+	var p model.Project
+	err := row.Scan(&p.ID, &p.OrgID, &p.OwnerID, &p.Name, &p.Description, workflowColumn{&p.Statuses}, &p.CreatedAt, &p.UpdatedAt, nullTime{&p.ArchivedAt})
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, ErrNotFound
+	}
+	return p, err
+}
+
+// workflowColumn stores a Workflow as JSON text. Projects created before
+// custom statuses existed hold an empty string and read as the default.
+type workflowColumn struct{ p *model.Workflow }
+
+func (c workflowColumn) Scan(v any) error {
+	var ns sql.NullString
+	if err := ns.Scan(v); err != nil {
+		return err
+	}
+	if ns.String == "" {
+		*c.p = model.DefaultWorkflow
+		return nil
+	}
+	return json.Unmarshal([]byte(ns.String), c.p)
+}
+
+func encodeWorkflow(w model.Workflow) string {
+	b, err := json.Marshal(w)
+	if err != nil {
+		panic("storage: encoding workflow: " + err.Error())
+	}
+	return string(b)
+}
+
+func (s *SQLStore) ListProjects(ctx context.Context, orgID, userID string) ([]model.Project, error) {
+	rows, err := s.query(ctx, `SELECT `+projectColumns+` FROM projects
+		WHERE org_id = ? AND id IN (SELECT project_id FROM project_members WHERE user_id = ?)
+		ORDER BY LOWER(name), id`, orgID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing projects: %w", err)
+	}
+	defer rows.Close()
+
+	projects := []model.Project{}
+	for rows.Next() {
+		p, err := scanProject(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning project: %w", err)
+		}
+		projects = append(projects, p)
+	}
+	return projects, rows.Err()
+}
+
+func (s *SQLStore) GetProject(ctx context.Context, id string) (model.Project, error) {
+	return scanProject(s.queryRow(ctx, `SELECT `+projectColumns+` FROM projects WHERE id = ?`, id))
+}
+
+func (s *SQLStore) CreateProject(ctx context.Context, p *model.Project) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		p.ID = NewID()
+		_, err := tx.exec(ctx, `INSERT INTO projects (`+projectColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			p.ID, p.OrgID, p.OwnerID, p.Name, p.Description, encodeWorkflow(p.Statuses), p.CreatedAt, p.UpdatedAt, p.ArchivedAt)
+		if err != nil {
+			return fmt.Errorf("inserting project: %w", err)
+		}
+		return tx.SaveMember(ctx, &model.Member{ProjectID: p.ID, UserID: p.OwnerID, Role: model.RoleOwner, CreatedAt: p.CreatedAt})
+	})
+}
+
+func (s *SQLStore) UpdateProject(ctx context.Context, p *model.Project) error {
+	return s.execOne(ctx, `UPDATE projects SET name = ?, description = ?, statuses = ?, updated_at = ?, archived_at = ? WHERE id = ?`,
+		p.Name, p.Description, encodeWorkflow(p.Statuses), p.UpdatedAt, p.ArchivedAt, p.ID)
+}
+
+func (s *SQLStore) DeleteProject(ctx context.Context, id string) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		if err := tx.logRevoked(ctx, `t.project_id = ?`, id); err != nil {
+			return err
+		}
+		if err := tx.logTasks(ctx, `t.project_id = ?`, id); err != nil {
+			return err
+		}
+		if _, err := tx.exec(ctx, `UPDATE tasks SET project_id = NULL, version = version + 1 WHERE project_id = ?`, id); err != nil {
+			return fmt.Errorf("detaching project tasks: %w", err)
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM project_members WHERE project_id = ?`, id); err != nil {
+			return fmt.Errorf("removing project members: %w", err)
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM task_field_values WHERE field_id IN (SELECT id FROM custom_fields WHERE project_id = ?)`, id); err != nil {
+			return fmt.Errorf("clearing field values: %w", err)
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM custom_fields WHERE project_id = ?`, id); err != nil {
+			return fmt.Errorf("deleting custom fields: %w", err)
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM rule_firings WHERE rule_id IN (SELECT id FROM rules WHERE project_id = ?)`, id); err != nil {
+			return fmt.Errorf("deleting rule firings: %w", err)
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM rules WHERE project_id = ?`, id); err != nil {
+			return fmt.Errorf("deleting rules: %w", err)
+		}
+		if err := tx.deleteGitHubLink(ctx, id); err != nil {
+			return err
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM slack_links WHERE project_id = ?`, id); err != nil {
+			return fmt.Errorf("deleting slack link: %w", err)
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM share_links WHERE project_id = ?`, id); err != nil {
+			return fmt.Errorf("deleting share links: %w", err)
+		}
+		return tx.execOne(ctx, `DELETE FROM projects WHERE id = ?`, id)
+	})
+}
+
+func (s *SQLStore) ReorderTasks(ctx context.Context, projectID string, taskIDs []string) error {
+	if len(taskIDs) == 0 {
+		return nil
+	}
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		args := []any{projectID}
+		for _, id := range taskIDs {
+			args = append(args, id)
+		}
+		err := tx.logTasks(ctx, `t.project_id = ? AND t.id IN (`+placeholders(len(taskIDs))+`)`, args...)
+		if err != nil {
+			return err
+		}
+		for i, id := range taskIDs {
+			err := tx.execOne(ctx, `UPDATE tasks SET position = ? WHERE id = ? AND project_id = ?`,
+				float64(i+1), id, projectID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+const memberColumns = `m.project_id, m.user_id, u.username, m.role, m.created_at`
+
+func scanMember(row scanner) (model.Member, error) {
+	var m model.Member
+	err := row.Scan(&m.ProjectID, &m.UserID, &m.Username, &m.Role, &m.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return m, ErrNotFound
+	}
+	return m, err
+}
+
+func (s *SQLStore) ListMembers(ctx context.Context, projectID string) ([]model.Member, error) {
+	rows, err := s.query(ctx, `SELECT `+memberColumns+` FROM project_members m JOIN users u ON u.id = m.user_id
+		WHERE m.project_id = ? ORDER BY m.created_at, m.user_id`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("listing members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []model.Member{}
+	for rows.Next() {
+		m, err := scanMember(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning member: %w", err)
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+func (s *SQLStore) GetMember(ctx context.Context, projectID, userID string) (model.Member, error) {
+	return scanMember(s.queryRow(ctx, `SELECT `+memberColumns+` FROM project_members m JOIN users u ON u.id = m.user_id
+		WHERE m.project_id = ? AND m.user_id = ?`, projectID, userID))
+}
+
+func (s *SQLStore) SaveMember(ctx context.Context, m *model.Member) error {
+	_, err := s.exec(ctx, `INSERT INTO project_members (project_id, user_id, role, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (project_id, user_id) DO UPDATE SET role = excluded.role`,
+		m.ProjectID, m.UserID, m.Role, m.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("saving member: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) RemoveMember(ctx context.Context, projectID, userID string) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		if err := tx.logRevoked(ctx, `t.project_id = ? AND m.user_id = ?`, projectID, userID); err != nil {
+			return err
+		}
+		return tx.execOne(ctx, `DELETE FROM project_members WHERE project_id = ? AND user_id = ?`, projectID, userID)
+	})
+}
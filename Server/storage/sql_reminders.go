@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"starttech-server/model"
+)
+
+const reminderColumns = `id, task_id, owner_id, kind, fire_at, sent_at`
+
+func (s *SQLStore) ScheduleReminders(ctx context.Context, taskID string, rs []model.Reminder) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		if _, err := tx.exec(ctx, `DELETE FROM reminders WHERE task_id = ? AND sent_at IS NULL`, taskID); err != nil {
+			return fmt.Errorf("clearing reminders: %w", err)
+		}
+		for i := range rs {
+			r := &rs[i]
+			r.ID = NewID()
+			_, err := tx.exec(ctx, `INSERT INTO reminders (`+reminderColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+				r.ID, r.TaskID, r.OwnerID, r.Kind, r.FireAt, r.SentAt)
+			if err != nil {
+				return fmt.Errorf("inserting reminder: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLStore) PendingReminders(ctx context.Context, now time.Time, limit int) ([]model.Reminder, error) {
+	rows, err := s.query(ctx, `SELECT `+reminderColumns+` FROM reminders
+		WHERE sent_at IS NULL AND fire_at <= ? ORDER BY fire_at, id LIMIT ?`, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("listing reminders: %w", err)
+	}
+	defer rows.Close()
+
+	var rs []model.Reminder
+	for rows.Next() {
+		var r model.Reminder
+		if err := rows.Scan(&r.ID, &r.TaskID, &r.OwnerID, &r.Kind, &r.FireAt, nullTime{&r.SentAt}); err != nil {
+			return nil, fmt.Errorf("scanning reminder: %w", err)
+		}
+		rs = append(rs, r)
+	}
+	return rs, rows.Err()
+}
+
+func (s *SQLStore) MarkReminderSent(ctx context.Context, id string, at time.Time) error {
+	return s.execOne(ctx, `UPDATE reminders SET sent_at = ? WHERE id = ? AND sent_at IS NULL`, at, id)
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+const revisionColumns = `task_id, version, task, created_at`
+
+func scanRevision(row scanner) (model.Revision, error) {
+	var (
+		r    model.Revision
+		task string
+	)
+	err := row.Scan(&r.TaskID, &r.Version, &task, &r.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r, ErrNotFound
+	}
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal([]byte(task), &r.Task); err != nil {
+		return r, fmt.Errorf("decoding revision: %w", err)
+	}
+	if r.Task.TagIDs == nil {
+		r.Task.TagIDs = []string{}
+	}
+	return r, nil
+}
+
+// saveRevision stores t, as it was just saved, as the revision of its
+// version. Its tags must be filled in.
+func (s *SQLStore) saveRevision(ctx context.Context, t *model.Task) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		panic("storage: encoding revision: " + err.Error())
+	}
+	_, err = s.exec(ctx, `INSERT INTO task_revisions (`+revisionColumns+`) VALUES (?, ?, ?, ?)`,
+		t.ID, t.Version, string(b), t.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting revision: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) ListRevisions(ctx context.Context, f RevisionFilter) ([]model.Revision, error) {
+	q := `SELECT ` + revisionColumns + ` FROM task_revisions WHERE task_id = ? ORDER BY version DESC`
+	args := []any{f.TaskID}
+	if f.Limit > 0 {
+		q += ` LIMIT ? OFFSET ?`
+		args = append(args, f.Limit, f.Offset)
+	}
+	rows, err := s.query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []model.Revision{}
+	for rows.Next() {
+		r, err := scanRevision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning revision: %w", err)
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, rows.Err()
+}
+
+func (s *SQLStore) GetRevision(ctx context.Context, taskID string, version int64) (model.Revision, error) {
+	return scanRevision(s.queryRow(ctx, `SELECT `+revisionColumns+` FROM task_revisions WHERE task_id = ? AND version = ?`,
+		taskID, version))
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"starttech-server/model"
+)
+
+const ruleColumns = `id, org_id, project_id, name, enabled, trigger_type, trigger_status, delay_seconds, actions,
+	created_by, created_at, updated_at`
+
+func scanRule(row scanner) (model.Rule, error) {
+	var r model.Rule
+	err := row.Scan(&r.ID, &r.OrgID, &r.ProjectID, &r.Name, &r.Enabled, &r.Trigger.Type, &r.Trigger.Status,
+		&r.Trigger.DelaySeconds, actionsColumn{&r.Actions}, &r.CreatedBy, &r.CreatedAt, &r.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r, ErrNotFound
+	}
+	return r, err
+}
+
+// actionsColumn stores the actions of a rule as JSON text.
+type actionsColumn struct{ p *[]model.RuleAction }
+
+func (c actionsColumn) Scan(v any) error {
+	var ns sql.NullString
+	if err := ns.Scan(v); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(ns.String), c.p)
+}
+
+func encodeActions(actions []model.RuleAction) string {
+	if actions == nil {
+		actions = []model.RuleAction{}
+	}
+	b, err := json.Marshal(actions)
+	if err != nil {
+		panic("storage: encoding rule actions: " + err.Error())
+	}
+	return string(b)
+}
+
+func (s *SQLStore) listRules(ctx context.Context, where string, args ...any) ([]model.Rule, error) {
+	rows, err := s.query(ctx, `SELECT `+ruleColumns+` FROM rules WHERE `+where+` ORDER BY created_at, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []model.Rule{}
+	for rows.Next() {
+		r, err := scanRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning rule: %w", err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+func (s *SQLStore) ListRules(ctx context.Context, projectID string) ([]model.Rule, error) {
+	return s.listRules(ctx, `project_id = ?`, projectID)
+}
+
+func (s *SQLStore) ListEnabledRules(ctx context.Context) ([]model.Rule, error) {
+	return s.listRules(ctx, `enabled = ?`, true)
+}
+
+func (s *SQLStore) GetRule(ctx context.Context, id string) (model.Rule, error) {
+	return scanRule(s.queryRow(ctx, `SELECT `+ruleColumns+` FROM rules WHERE id = ?`, id))
+}
+
+func (s *SQLStore) CreateRule(ctx context.Context, r *model.Rule) error {
+	r.ID = NewID()
+	_, err := s.exec(ctx, `INSERT INTO rules (`+ruleColumns+`) VALUES (`+placeholders(12)+`)`,
+		r.ID, r.OrgID, r.ProjectID, r.Name, r.Enabled, r.Trigger.Type, r.Trigger.Status, r.Trigger.DelaySeconds,
+		encodeActions(r.Actions), r.CreatedBy, r.CreatedAt, r.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting rule: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) UpdateRule(ctx context.Context, r *model.Rule) error {
+	return s.execOne(ctx, `UPDATE rules SET name = ?, enabled = ?, trigger_type = ?, trigger_status = ?,
+		delay_seconds = ?, actions = ?, updated_at = ? WHERE id = ?`,
+		r.Name, r.Enabled, r.Trigger.Type, r.Trigger.Status, r.Trigger.DelaySeconds, encodeActions(r.Actions),
+		r.UpdatedAt, r.ID)
+}
+
+func (s *SQLStore) DeleteRule(ctx context.Context, id string) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		if _, err := tx.exec(ctx, `DELETE FROM rule_firings WHERE rule_id = ?`, id); err != nil {
+			return fmt.Errorf("deleting rule firings: %w", err)
+		}
+		return tx.execOne(ctx, `DELETE FROM rules WHERE id = ?`, id)
+	})
+}
+
+func (s *SQLStore) RuleFirings(ctx context.Context, ruleID string) ([]string, error) {
+	rows, err := s.query(ctx, `SELECT task_id FROM rule_firings WHERE rule_id = ? ORDER BY task_id`, ruleID)
+	if err != nil {
+		return nil, fmt.Errorf("listing rule firings: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scanning rule firing: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (s *SQLStore) AddRuleFiring(ctx context.Context, ruleID, taskID string, at time.Time) error {
+	_, err := s.exec(ctx, `INSERT INTO rule_firings (rule_id, task_id, fired_at) VALUES (?, ?, ?)
+		ON CONFLICT (rule_id, task_id) DO NOTHING`, ruleID, taskID, at)
+	if err != nil {
+		return fmt.Errorf("inserting rule firing: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) RemoveRuleFirings(ctx context.Context, ruleID string, taskIDs []string) error {
+	if len(taskIDs) == 0 {
+		return nil
+	}
+	args := []any{ruleID}
+	for _, id := range taskIDs {
+		args = append(args, id)
+	}
+	_, err := s.exec(ctx, `DELETE FROM rule_firings WHERE rule_id = ? AND task_id IN (`+placeholders(len(taskIDs))+`)`, args...)
+	if err != nil {
+		return fmt.Errorf("deleting rule firings: %w", err)
+	}
+	return nil
+}
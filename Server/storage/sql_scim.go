@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+const scimLinkColumns = `org_id, user_id, user_name, external_id, active, created_account, created_at, updated_at`
+
+func scanSCIMLink(row scanner) (model.SCIMLink, error) {
+	var l model.SCIMLink
+	err := row.Scan(&l.OrgID, &l.UserID, &l.UserName, &l.ExternalID, &l.Active, &l.CreatedAccount, &l.CreatedAt, &l.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return l, ErrNotFound
+	}
+	return l, err
+}
+
+func (s *SQLStore) ListSCIMLinks(ctx context.Context, orgID string) ([]model.SCIMLink, error) {
+	rows, err := s.query(ctx, `SELECT `+scimLinkColumns+` FROM scim_links WHERE org_id = ?
+		ORDER BY created_at, user_id`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("listing scim links: %w", err)
+	}
+	defer rows.Close()
+
+	var links []model.SCIMLink
+	for rows.Next() {
+		l, err := scanSCIMLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning scim link: %w", err)
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+func (s *SQLStore) GetSCIMLink(ctx context.Context, orgID, userID string) (model.SCIMLink, error) {
+	return scanSCIMLink(s.queryRow(ctx, `SELECT `+scimLinkColumns+` FROM scim_links WHERE org_id = ? AND user_id = ?`, orgID, userID))
+}
+
+func (s *SQLStore) CreateSCIMLink(ctx context.Context, l model.SCIMLink) error {
+	_, err := s.exec(ctx, `INSERT INTO scim_links (`+scimLinkColumns+`) VALUES (`+placeholders(8)+`)`,
+		l.OrgID, l.UserID, l.UserName, l.ExternalID, l.Active, l.CreatedAccount, l.CreatedAt, l.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("inserting scim link: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) UpdateSCIMLink(ctx context.Context, l model.SCIMLink) error {
+	return s.execOne(ctx, `UPDATE scim_links SET user_name = ?, external_id = ?, active = ?, updated_at = ?
+		WHERE org_id = ? AND user_id = ?`, l.UserName, l.ExternalID, l.Active, l.UpdatedAt, l.OrgID, l.UserID)
+}
+
+func (s *SQLStore) DeleteSCIMLink(ctx context.Context, orgID, userID string) error {
+	return s.execOne(ctx, `DELETE FROM scim_links WHERE org_id = ? AND user_id = ?`, orgID, userID)
+}
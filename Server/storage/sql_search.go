@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"starttech-server/model"
+)
+
+// qualifiedTaskColumns is taskColumns prefixed with the alias t.
+var qualifiedTaskColumns = "t." + strings.Join(taskFields, ", t.")
+
+func (s *SQLStore) Search(ctx context.Context, terms []string, f TaskFilter) ([]model.SearchHit, error) {
+	if len(terms) == 0 {
+		return []model.SearchHit{}, nil
+	}
+	// Hits only come from tasks matching f, which taskWhere filters in a
+	// subquery so its unqualified columns stay unambiguous.
+	where, whereArgs := taskWhere(f)
+	visible := `(SELECT ` + taskColumns + ` FROM tasks` + where + `) t`
+
+	var (
+		q    string
+		args []any
+	)
+	switch s.dialect {
+	case Postgres:
+		// Every term must match; the last one also as a prefix.
+		tsquery := strings.Join(terms, " & ") + ":*"
+		opts := "StartSel=" + snippetStart + ", StopSel=" + snippetEnd + ", MaxWords=24, MinWords=8"
+		q = `SELECT kind, ref_id, snippet, score, ` + taskColumns + ` FROM (
+			SELECT 'task' AS kind, t.id AS ref_id,
+				ts_headline('english', t.title || ' ' || t.description, q, ?) AS snippet,
+				ts_rank(x.search, q) AS score, ` + qualifiedTaskColumns + `
+			FROM ` + visible + ` JOIN tasks x ON x.id = t.id, to_tsquery('english', ?) q
+			WHERE x.search @@ q
+			UNION ALL
+			SELECT 'comment', c.id, ts_headline('english', c.body, q, ?), ts_rank(c.search, q), ` + qualifiedTaskColumns + `
+			FROM comments c JOIN ` + visible + ` ON t.id = c.task_id, to_tsquery('english', ?) q
+			WHERE c.search @@ q
+		) hits ORDER BY score DESC, ref_id`
+		args = append(args, opts)
+		args = append(args, whereArgs...)
+		args = append(args, tsquery, opts)
+		args = append(args, whereArgs...)
+		args = append(args, tsquery)
+	default:
+		// FTS5 ranks with bm25, where lower is better; titles count ten
+		// times as much as bodies.
+		quoted := make([]string, len(terms))
+		for i, term := range terms {
+			quoted[i] = `"` + term + `"`
+		}
+		match := strings.Join(quoted, " ") + "*"
+		q = `SELECT search_index.kind, search_index.ref_id,
+				snippet(search_index, -1, ?, ?, '…', 16),
+				-bm25(search_index, 10.0, 1.0) AS score, ` + qualifiedTaskColumns + `
+			FROM search_index JOIN ` + visible + ` ON t.id = search_index.task_id
+			WHERE search_index MATCH ?
+			ORDER BY score DESC, search_index.ref_id`
+		args = append(args, snippetStart, snippetEnd)
+		args = append(args, whereArgs...)
+		args = append(args, match)
+	}
+	if f.Limit > 0 {
+		q += ` LIMIT ? OFFSET ?`
+		args = append(args, f.Limit, f.Offset)
+	}
+
+	rows, err := s.query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("searching: %w", err)
+	}
+	defer rows.Close()
+
+	hits := []model.SearchHit{}
+	var tasks []model.Task
+	for rows.Next() {
+		var (
+			h     model.SearchHit
+			refID string
+		)
+		t, err := scanTask(prefixScanner{rows, []any{&h.Kind, &refID, &h.Snippet, &h.Rank}})
+		if err != nil {
+			return nil, fmt.Errorf("scanning search hit: %w", err)
+		}
+		if h.Kind == model.HitComment {
+			h.CommentID = &refID
+		}
+		h.Snippet = markSnippet(h.Snippet)
+		hits = append(hits, h)
+		tasks = append(tasks, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if err := s.loadTags(ctx, tasks); err != nil {
+		return nil, err
+	}
+	if err := s.loadFields(ctx, tasks); err != nil {
+		return nil, err
+	}
+	for i := range hits {
+		hits[i].Task = tasks[i]
+	}
+	return hits, nil
+}
+
+// prefixScanner reads the leading columns of a row into dest before
+// handing the rest to the wrapped scanner's caller.
+type prefixScanner struct {
+	row  scanner
+	dest []any
+}
+
+func (p prefixScanner) Scan(dest ...any) error {
+	return p.row.Scan(append(p.dest, dest...)...)
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"starttech-server/model"
+)
+
+const sessionColumns = `id, user_id, org_id, user_agent, token_hash, prev_token_hash, created_at, last_used_at`
+
+func scanSession(row scanner) (model.AuthSession, error) {
+	var sess model.AuthSession
+	err := row.Scan(&sess.ID, &sess.UserID, &sess.OrgID, &sess.UserAgent, &sess.TokenHash, &sess.PrevTokenHash,
+		&sess.CreatedAt, &sess.LastUsedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sess, ErrNotFound
+	}
+	return sess, err
+}
+
+func (s *SQLStore) CreateSession(ctx context.Context, sess *model.AuthSession) error {
+	sess.ID = NewID()
+	_, err := s.exec(ctx, `INSERT INTO sessions (`+sessionColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		sess.ID, sess.UserID, sess.OrgID, sess.UserAgent, sess.TokenHash, sess.PrevTokenHash, sess.CreatedAt, sess.LastUsedAt)
+	if err != nil {
+		return fmt.Errorf("inserting session: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) GetSession(ctx context.Context, id string) (model.AuthSession, error) {
+	return scanSession(s.queryRow(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE id = ?`, id))
+}
+
+func (s *SQLStore) GetSessionByToken(ctx context.Context, tokenHash string) (model.AuthSession, error) {
+	return scanSession(s.queryRow(ctx, `SELECT `+sessionColumns+` FROM sessions
+		WHERE token_hash = ? OR prev_token_hash = ?`, tokenHash, tokenHash))
+}
+
+func (s *SQLStore) ListSessions(ctx context.Context, userID string) ([]model.AuthSession, error) {
+	rows, err := s.query(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE user_id = ?
+		ORDER BY last_used_at DESC, id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var out []model.AuthSession
+	for rows.Next() {
+		sess, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sess)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLStore) UpdateSession(ctx context.Context, sess model.AuthSession, tokenHash string) error {
+	return s.execOne(ctx, `UPDATE sessions SET org_id = ?, user_agent = ?, token_hash = ?, prev_token_hash = ?, last_used_at = ?
+		WHERE id = ? AND token_hash = ?`,
+		sess.OrgID, sess.UserAgent, sess.TokenHash, sess.PrevTokenHash, sess.LastUsedAt, sess.ID, tokenHash)
+}
+
+func (s *SQLStore) DeleteSession(ctx context.Context, userID, id string) error {
+	return s.execOne(ctx, `DELETE FROM sessions WHERE id = ? AND user_id = ?`, id, userID)
+}
+
+func (s *SQLStore) DeleteSessions(ctx context.Context, userID, keepID string) (int, error) {
+	res, err := s.exec(ctx, `DELETE FROM sessions WHERE user_id = ? AND id <> ?`, userID, keepID)
+	if err != nil {
+		return 0, fmt.Errorf("deleting sessions: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *SQLStore) PurgeSessions(ctx context.Context, before time.Time) (int, error) {
+	res, err := s.exec(ctx, `DELETE FROM sessions WHERE last_used_at < ?`, before)
+	if err != nil {
+		return 0, fmt.Errorf("purging sessions: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *SQLStore) PurgeableSessions(ctx context.Context, before time.Time) (int, error) {
+	var n int
+	if err := s.queryRow(ctx, `SELECT COUNT(*) FROM sessions WHERE last_used_at < ?`, before).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting purgeable sessions: %w", err)
+	}
+	return n, nil
+}
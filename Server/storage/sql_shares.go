@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"starttech-server/model"
+)
+
+const shareLinkColumns = `id, org_id, project_id, task_id, created_by, token_hash, expires_at, created_at`
+
+func scanShareLink(row scanner) (model.ShareLink, error) {
+	var l model.ShareLink
+	err := row.Scan(&l.ID, &l.OrgID, nullString{&l.ProjectID}, nullString{&l.TaskID}, &l.CreatedBy, &l.TokenHash,
+		nullTime{&l.ExpiresAt}, &l.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return l, ErrNotFound
+	}
+	return l, err
+}
+
+func (s *SQLStore) CreateShareLink(ctx context.Context, l *model.ShareLink) error {
+	l.ID = NewID()
+	_, err := s.exec(ctx, `INSERT INTO share_links (`+shareLinkColumns+`) VALUES (`+placeholders(8)+`)`,
+		l.ID, l.OrgID, l.ProjectID, l.TaskID, l.CreatedBy, l.TokenHash, l.ExpiresAt, l.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting share link: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) GetShareLink(ctx context.Context, id string) (model.ShareLink, error) {
+	return scanShareLink(s.queryRow(ctx, `SELECT `+shareLinkColumns+` FROM share_links WHERE id = ?`, id))
+}
+
+func (s *SQLStore) GetShareLinkByToken(ctx context.Context, tokenHash string) (model.ShareLink, error) {
+	return scanShareLink(s.queryRow(ctx, `SELECT `+shareLinkColumns+` FROM share_links WHERE token_hash = ?`, tokenHash))
+}
+
+func (s *SQLStore) ListShareLinks(ctx context.Context, f ShareFilter) ([]model.ShareLink, error) {
+	q := `SELECT ` + shareLinkColumns + ` FROM share_links WHERE `
+	var arg string
+	if f.TaskID != "" {
+		q, arg = q+`task_id = ?`, f.TaskID
+	} else {
+		q, arg = q+`project_id = ?`, f.ProjectID
+	}
+	rows, err := s.query(ctx, q+` ORDER BY created_at, id`, arg)
+	if err != nil {
+		return nil, fmt.Errorf("listing share links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []model.ShareLink{}
+	for rows.Next() {
+		l, err := scanShareLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning share link: %w", err)
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+func (s *SQLStore) DeleteShareLink(ctx context.Context, id string) error {
+	return s.execOne(ctx, `DELETE FROM share_links WHERE id = ?`, id)
+}
+
+func (s *SQLStore) PurgeShareLinks(ctx context.Context, before time.Time) (int, error) {
+	res, err := s.exec(ctx, `DELETE FROM share_links WHERE expires_at < ?`, before)
+	if err != nil {
+		return 0, fmt.Errorf("purging share links: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *SQLStore) PurgeableShareLinks(ctx context.Context, before time.Time) (int, error) {
+	var n int
+	if err := s.queryRow(ctx, `SELECT COUNT(*) FROM share_links WHERE expires_at < ?`, before).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting purgeable share links: %w", err)
+	}
+	return n, nil
+}
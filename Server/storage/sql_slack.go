@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"starttech-server/model"
+)
+
+func (s *SQLStore) GetSlackLink(ctx context.Context, projectID string) (model.SlackLink, error) {
+	var l model.SlackLink
+	var evts string
+	err := s.queryRow(ctx, `SELECT project_id, org_id, webhook_url, signing_secret, events, created_by, created_at
+		FROM slack_links WHERE project_id = ?`, projectID).
+		Scan(&l.ProjectID, &l.OrgID, &l.WebhookURL, &l.SigningSecret, &evts, &l.CreatedBy, &l.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return l, ErrNotFound
+	}
+	l.Events = []string{}
+	if evts != "" {
+		l.Events = strings.Split(evts, ",")
+	}
+	return l, err
+}
+
+func (s *SQLStore) SaveSlackLink(ctx context.Context, l model.SlackLink) error {
+	_, err := s.exec(ctx, `INSERT INTO slack_links (project_id, org_id, webhook_url, signing_secret, events, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (project_id) DO UPDATE SET
+			webhook_url = excluded.webhook_url, signing_secret = excluded.signing_secret, events = excluded.events,
+			created_by = excluded.created_by, created_at = excluded.created_at`,
+		l.ProjectID, l.OrgID, l.WebhookURL, l.SigningSecret, strings.Join(l.Events, ","), l.CreatedBy, l.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("saving slack link: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) DeleteSlackLink(ctx context.Context, projectID string) error {
+	return s.execOne(ctx, `DELETE FROM slack_links WHERE project_id = ?`, projectID)
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+const ssoColumns = `org_id, domain, issuer, client_id, client_secret, groups_claim, group_roles, enforced, created_at, updated_at`
+
+func scanSSOConfig(row scanner) (model.SSOConfig, error) {
+	var c model.SSOConfig
+	err := row.Scan(&c.OrgID, &c.Domain, &c.Issuer, &c.ClientID, &c.ClientSecret, &c.GroupsClaim,
+		groupRolesColumn{&c.GroupRoles}, &c.Enforced, &c.CreatedAt, &c.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, ErrNotFound
+	}
+	return c, err
+}
+
+// groupRolesColumn stores the group roles of an SSO config as JSON text.
+type groupRolesColumn struct{ p *[]model.SSOGroupRole }
+
+func (c groupRolesColumn) Scan(v any) error {
+	var ns sql.NullString
+	if err := ns.Scan(v); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(ns.String), c.p)
+}
+
+func encodeGroupRoles(roles []model.SSOGroupRole) string {
+	if roles == nil {
+		roles = []model.SSOGroupRole{}
+	}
+	b, err := json.Marshal(roles)
+	if err != nil {
+		panic("storage: encoding group roles: " + err.Error())
+	}
+	return string(b)
+}
+
+func (s *SQLStore) GetSSOConfig(ctx context.Context, orgID string) (model.SSOConfig, error) {
+	return scanSSOConfig(s.queryRow(ctx, `SELECT `+ssoColumns+` FROM sso_configs WHERE org_id = ?`, orgID))
+}
+
+func (s *SQLStore) GetSSOConfigByDomain(ctx context.Context, domain string) (model.SSOConfig, error) {
+	return scanSSOConfig(s.queryRow(ctx, `SELECT `+ssoColumns+` FROM sso_configs WHERE domain = ?`, domain))
+}
+
+func (s *SQLStore) SaveSSOConfig(ctx context.Context, c model.SSOConfig) error {
+	_, err := s.exec(ctx, `INSERT INTO sso_configs (`+ssoColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (org_id) DO UPDATE SET
+			domain = excluded.domain, issuer = excluded.issuer, client_id = excluded.client_id,
+			client_secret = excluded.client_secret, groups_claim = excluded.groups_claim,
+			group_roles = excluded.group_roles, enforced = excluded.enforced, updated_at = excluded.updated_at`,
+		c.OrgID, c.Domain, c.Issuer, c.ClientID, c.ClientSecret, c.GroupsClaim,
+		encodeGroupRoles(c.GroupRoles), c.Enforced, c.CreatedAt, c.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("saving sso config: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) DeleteSSOConfig(ctx context.Context, orgID string) error {
+	return s.execOne(ctx, `DELETE FROM sso_configs WHERE org_id = ?`, orgID)
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+const tagColumns = `id, org_id, owner_id, name, color, created_at`
+
+func scanTag(row scanner) (model.Tag, error) {
+	var t model.Tag
+	err := row.Scan(&t.ID, &t.OrgID, &t.OwnerID, &t.Name, &t.Color, &t.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, ErrNotFound
+	}
+	return t, err
+}
+
+func (s *SQLStore) ListTags(ctx context.Context, orgID, ownerID string) ([]model.Tag, error) {
+	rows, err := s.query(ctx, `SELECT `+tagColumns+` FROM tags WHERE org_id = ? AND owner_id = ? ORDER BY LOWER(name), id`,
+		orgID, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("listing tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []model.Tag{}
+	for rows.Next() {
+		t, err := scanTag(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning tag: %w", err)
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+func (s *SQLStore) GetTag(ctx context.Context, id string) (model.Tag, error) {
+	return scanTag(s.queryRow(ctx, `SELECT `+tagColumns+` FROM tags WHERE id = ?`, id))
+}
+
+func (s *SQLStore) CreateTag(ctx context.Context, t *model.Tag) error {
+	t.ID = NewID()
+	_, err := s.exec(ctx, `INSERT INTO tags (`+tagColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		t.ID, t.OrgID, t.OwnerID, t.Name, t.Color, t.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("inserting tag: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) UpdateTag(ctx context.Context, t *model.Tag) error {
+	err := s.execOne(ctx, `UPDATE tags SET name = ?, color = ? WHERE id = ?`, t.Name, t.Color, t.ID)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (s *SQLStore) DeleteTag(ctx context.Context, id string) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		if err := tx.logTasks(ctx, `t.id IN (SELECT task_id FROM task_tags WHERE tag_id = ?)`, id); err != nil {
+			return err
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM task_tags WHERE tag_id = ?`, id); err != nil {
+			return fmt.Errorf("detaching tag: %w", err)
+		}
+		return tx.execOne(ctx, `DELETE FROM tags WHERE id = ?`, id)
+	})
+}
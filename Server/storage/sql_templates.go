@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+const templateColumns = `id, org_id, owner_id, name, kind, body, created_at, updated_at`
+
+// templateBody is what the body column holds.
+type templateBody struct {
+	Project *model.TemplateProject `json:"project"`
+	Tasks   []model.TemplateTask   `json:"tasks"`
+}
+
+func scanTemplate(row scanner) (model.Template, error) {
+	var (
+		t    model.Template
+		body string
+	)
+	err := row.Scan(&t.ID, &t.OrgID, &t.OwnerID, &t.Name, &t.Kind, &body, &t.CreatedAt, &t.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, ErrNotFound
+	}
+	if err != nil {
+		return t, err
+	}
+	var b templateBody
+	if err := json.Unmarshal([]byte(body), &b); err != nil {
+		return t, fmt.Errorf("decoding template: %w", err)
+	}
+	t.Project, t.Tasks = b.Project, b.Tasks
+	return t, nil
+}
+
+func encodeTemplateBody(t *model.Template) string {
+	b, err := json.Marshal(templateBody{Project: t.Project, Tasks: t.Tasks})
+	if err != nil {
+		panic("storage: encoding template: " + err.Error())
+	}
+	return string(b)
+}
+
+func (s *SQLStore) ListTemplates(ctx context.Context, orgID, ownerID string) ([]model.Template, error) {
+	rows, err := s.query(ctx, `SELECT `+templateColumns+` FROM templates WHERE org_id = ? AND owner_id = ? ORDER BY LOWER(name), id`,
+		orgID, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("listing templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []model.Template{}
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning template: %w", err)
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+func (s *SQLStore) GetTemplate(ctx context.Context, id string) (model.Template, error) {
+	return scanTemplate(s.queryRow(ctx, `SELECT `+templateColumns+` FROM templates WHERE id = ?`, id))
+}
+
+func (s *SQLStore) CreateTemplate(ctx context.Context, t *model.Template) error {
+	t.ID = NewID()
+	_, err := s.exec(ctx, `INSERT INTO templates (`+templateColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.ID, t.OrgID, t.OwnerID, t.Name, t.Kind, encodeTemplateBody(t), t.CreatedAt, t.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("inserting template: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) UpdateTemplate(ctx context.Context, t *model.Template) error {
+	err := s.execOne(ctx, `UPDATE templates SET name = ?, body = ?, updated_at = ? WHERE id = ?`,
+		t.Name, encodeTemplateBody(t), t.UpdatedAt, t.ID)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (s *SQLStore) DeleteTemplate(ctx context.Context, id string) error {
+	return s.execOne(ctx, `DELETE FROM templates WHERE id = ?`, id)
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"starttech-server/model"
+)
+
+const timeEntryColumns = `e.id, e.org_id, e.task_id, t.project_id, e.user_id, e.started_at, e.ended_at, e.note, e.created_at`
+
+// timeEntryFrom joins the task so entries come with its project.
+const timeEntryFrom = ` FROM time_entries e JOIN tasks t ON t.id = e.task_id`
+
+func scanTimeEntry(row scanner) (model.TimeEntry, error) {
+	var e model.TimeEntry
+	err := row.Scan(&e.ID, &e.OrgID, &e.TaskID, nullString{&e.ProjectID}, &e.UserID,
+		&e.StartedAt, nullTime{&e.EndedAt}, &e.Note, &e.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return e, ErrNotFound
+	}
+	return e, err
+}
+
+func (s *SQLStore) ListTimeEntries(ctx context.Context, f TimeEntryFilter) ([]model.TimeEntry, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if f.OrgID != "" {
+		where = append(where, "e.org_id = ?")
+		args = append(args, f.OrgID)
+	}
+	if f.TaskID != "" {
+		where = append(where, "e.task_id = ?")
+		args = append(args, f.TaskID)
+	}
+	if f.UserID != "" {
+		where = append(where, "e.user_id = ?")
+		args = append(args, f.UserID)
+	}
+	if f.VisibleTo != "" {
+		where = append(where, `(t.project_id IS NULL AND t.owner_id = ?
+			OR t.project_id IN (SELECT project_id FROM project_members WHERE user_id = ?))`)
+		args = append(args, f.VisibleTo, f.VisibleTo)
+	}
+	if f.ProjectID != "" {
+		where = append(where, "t.project_id = ?")
+		args = append(args, f.ProjectID)
+	}
+	if f.From != nil {
+		where = append(where, "(e.ended_at IS NULL OR e.ended_at > ?)")
+		args = append(args, *f.From)
+	}
+	if f.To != nil {
+		where = append(where, "e.started_at < ?")
+		args = append(args, *f.To)
+	}
+	if f.Running {
+		where = append(where, "e.ended_at IS NULL")
+	}
+
+	q := `SELECT ` + timeEntryColumns + timeEntryFrom
+	if len(where) > 0 {
+		q += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	rows, err := s.query(ctx, q+` ORDER BY e.started_at, e.id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing time entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []model.TimeEntry{}
+	for rows.Next() {
+		e, err := scanTimeEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning time entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (s *SQLStore) GetTimeEntry(ctx context.Context, id string) (model.TimeEntry, error) {
+	return scanTimeEntry(s.queryRow(ctx, `SELECT `+timeEntryColumns+timeEntryFrom+` WHERE e.id = ?`, id))
+}
+
+func (s *SQLStore) CreateTimeEntry(ctx context.Context, e *model.TimeEntry) error {
+	e.ID = NewID()
+	_, err := s.exec(ctx, `INSERT INTO time_entries (id, org_id, task_id, user_id, started_at, ended_at, note, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.ID, e.OrgID, e.TaskID, e.UserID, e.StartedAt, e.EndedAt, e.Note, e.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("inserting time entry: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) UpdateTimeEntry(ctx context.Context, e *model.TimeEntry) error {
+	return s.execOne(ctx, `UPDATE time_entries SET started_at = ?, ended_at = ?, note = ? WHERE id = ?`,
+		e.StartedAt, e.EndedAt, e.Note, e.ID)
+}
+
+func (s *SQLStore) DeleteTimeEntry(ctx context.Context, id string) error {
+	return s.execOne(ctx, `DELETE FROM time_entries WHERE id = ?`, id)
+}
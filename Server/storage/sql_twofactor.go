@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+func (s *SQLStore) GetTwoFactor(ctx context.Context, userID string) (model.TwoFactor, error) {
+	var tf model.TwoFactor
+	err := s.queryRow(ctx, `SELECT user_id, secret, enabled_at, last_step, created_at FROM user_two_factor WHERE user_id = ?`, userID).
+		Scan(&tf.UserID, &tf.Secret, &tf.EnabledAt, &tf.LastStep, &tf.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return tf, ErrNotFound
+	}
+	return tf, err
+}
+
+func (s *SQLStore) SaveTwoFactor(ctx context.Context, tf model.TwoFactor) error {
+	_, err := s.exec(ctx, `INSERT INTO user_two_factor (user_id, secret, enabled_at, last_step, created_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			secret = excluded.secret, enabled_at = excluded.enabled_at, last_step = excluded.last_step,
+			created_at = excluded.created_at`,
+		tf.UserID, tf.Secret, tf.EnabledAt, tf.LastStep, tf.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("saving two-factor secret: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) DeleteTwoFactor(ctx context.Context, userID string) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		if _, err := tx.exec(ctx, `DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+			return fmt.Errorf("deleting recovery codes: %w", err)
+		}
+		return tx.execOne(ctx, `DELETE FROM user_two_factor WHERE user_id = ?`, userID)
+	})
+}
+
+func (s *SQLStore) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	err := s.execOne(ctx, `UPDATE user_two_factor SET last_step = ? WHERE user_id = ? AND last_step < ?`, step, userID, step)
+	if errors.Is(err, ErrNotFound) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (s *SQLStore) SetRecoveryCodes(ctx context.Context, userID string, hashes []string) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		if _, err := tx.exec(ctx, `DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+			return fmt.Errorf("deleting recovery codes: %w", err)
+		}
+		for _, h := range hashes {
+			if _, err := tx.exec(ctx, `INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)`, userID, h); err != nil {
+				return fmt.Errorf("inserting recovery code: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLStore) UseRecoveryCode(ctx context.Context, userID, hash string) error {
+	return s.execOne(ctx, `DELETE FROM recovery_codes WHERE user_id = ? AND code_hash = ?`, userID, hash)
+}
+
+func (s *SQLStore) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var n int
+	err := s.queryRow(ctx, `SELECT COUNT(*) FROM recovery_codes WHERE user_id = ?`, userID).Scan(&n)
+	return n, err
+}
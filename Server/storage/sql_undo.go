@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"starttech-server/model"
+)
+
+const commandColumns = `id, org_id, user_id, action, steps, created_at`
+
+func scanCommand(row scanner) (model.Command, error) {
+	var c model.Command
+	err := row.Scan(&c.ID, &c.OrgID, &c.UserID, &c.Action, commandStepsColumn{&c.Steps}, &c.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, ErrNotFound
+	}
+	return c, err
+}
+
+// commandStepsColumn stores the steps of a command as JSON text.
+type commandStepsColumn struct{ p *[]model.CommandStep }
+
+func (c commandStepsColumn) Scan(v any) error {
+	var ns sql.NullString
+	if err := ns.Scan(v); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(ns.String), c.p)
+}
+
+func encodeCommandSteps(steps []model.CommandStep) string {
+	if steps == nil {
+		steps = []model.CommandStep{}
+	}
+	b, err := json.Marshal(steps)
+	if err != nil {
+		panic("storage: encoding command steps: " + err.Error())
+	}
+	return string(b)
+}
+
+func (s *SQLStore) SaveCommand(ctx context.Context, c *model.Command) error {
+	c.ID = NewID()
+	_, err := s.exec(ctx, `INSERT INTO undo_commands (`+commandColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		c.ID, c.OrgID, c.UserID, c.Action, encodeCommandSteps(c.Steps), c.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting command: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) LastCommand(ctx context.Context, orgID, userID string, since time.Time) (model.Command, error) {
+	return scanCommand(s.queryRow(ctx, `SELECT `+commandColumns+` FROM undo_commands
+		WHERE org_id = ? AND user_id = ? AND created_at >= ? ORDER BY created_at DESC, id DESC LIMIT 1`,
+		orgID, userID, since))
+}
+
+func (s *SQLStore) DeleteCommand(ctx context.Context, id string) error {
+	return s.execOne(ctx, `DELETE FROM undo_commands WHERE id = ?`, id)
+}
+
+func (s *SQLStore) PurgeCommands(ctx context.Context, before time.Time) (int, error) {
+	res, err := s.exec(ctx, `DELETE FROM undo_commands WHERE created_at < ?`, before)
+	if err != nil {
+		return 0, fmt.Errorf("purging commands: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *SQLStore) PurgeableCommands(ctx context.Context, before time.Time) (int, error) {
+	var n int
+	if err := s.queryRow(ctx, `SELECT COUNT(*) FROM undo_commands WHERE created_at < ?`, before).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting purgeable commands: %w", err)
+	}
+	return n, nil
+}
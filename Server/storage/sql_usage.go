@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+func (s *SQLStore) Usage(ctx context.Context, orgID string) (model.Usage, error) {
+	var u model.Usage
+	var err error
+	if orgID == "" {
+		err = s.queryRow(ctx, `SELECT
+			(SELECT COUNT(*) FROM orgs),
+			(SELECT COUNT(*) FROM users),
+			(SELECT COUNT(*) FROM projects),
+			(SELECT COUNT(*) FROM tasks),
+			(SELECT COUNT(*) FROM comments),
+			(SELECT COUNT(*) FROM attachments),
+			(SELECT COALESCE(SUM(size), 0) FROM attachments)`).
+			Scan(&u.Orgs, &u.Users, &u.Projects, &u.Tasks, &u.Comments, &u.Attachments, &u.AttachmentBytes)
+	} else {
+		err = s.queryRow(ctx, `SELECT
+			(SELECT COUNT(*) FROM org_members WHERE org_id = ?),
+			(SELECT COUNT(*) FROM projects WHERE org_id = ?),
+			(SELECT COUNT(*) FROM tasks WHERE org_id = ?),
+			(SELECT COUNT(*) FROM comments c JOIN tasks t ON t.id = c.task_id WHERE t.org_id = ?),
+			(SELECT COUNT(*) FROM attachments a JOIN tasks t ON t.id = a.task_id WHERE t.org_id = ?),
+			(SELECT COALESCE(SUM(a.size), 0) FROM attachments a JOIN tasks t ON t.id = a.task_id WHERE t.org_id = ?)`,
+			orgID, orgID, orgID, orgID, orgID, orgID).
+			Scan(&u.Users, &u.Projects, &u.Tasks, &u.Comments, &u.Attachments, &u.AttachmentBytes)
+	}
+	if err != nil {
+		return u, fmt.Errorf("counting usage: %w", err)
+	}
+	return u, nil
+}
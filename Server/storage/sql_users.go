@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"starttech-server/model"
+)
+
+const userColumns = `id, email, username, password_hash, timezone, language, email_verified_at, disabled_at, deletion_requested_at, deleted_at, created_at`
+
+func scanUser(row scanner) (model.User, error) {
+	var u model.User
+	err := row.Scan(&u.ID, &u.Email, &u.Username, &u.PasswordHash, &u.Timezone, &u.Language, &u.EmailVerifiedAt, nullTime{&u.DisabledAt},
+		nullTime{&u.DeletionRequestedAt}, nullTime{&u.DeletedAt}, &u.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return u, ErrNotFound
+	}
+	return u, err
+}
+
+func (s *SQLStore) GetUser(ctx context.Context, id string) (model.User, error) {
+	return scanUser(s.queryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, id))
+}
+
+func (s *SQLStore) GetUserByEmail(ctx context.Context, email string) (model.User, error) {
+	return scanUser(s.queryRow(ctx, `SELECT `+userColumns+` FROM users WHERE LOWER(email) = LOWER(?)`, email))
+}
+
+func (s *SQLStore) CreateUser(ctx context.Context, u *model.User) error {
+	u.ID = NewID()
+	_, err := s.exec(ctx, `INSERT INTO users (`+userColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		u.ID, u.Email, u.Username, u.PasswordHash, u.Timezone, u.Language, u.EmailVerifiedAt, u.DisabledAt, u.DeletionRequestedAt, u.DeletedAt, u.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("inserting user: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) UpdateUser(ctx context.Context, u model.User) error {
+	err := s.execOne(ctx, `UPDATE users SET email = ?, username = ?, password_hash = ?, timezone = ?, language = ?,
+		email_verified_at = ?, disabled_at = ?, deletion_requested_at = ?, deleted_at = ? WHERE id = ?`,
+		u.Email, u.Username, u.PasswordHash, u.Timezone, u.Language, u.EmailVerifiedAt, u.DisabledAt, u.DeletionRequestedAt, u.DeletedAt, u.ID)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (s *SQLStore) ListUsers(ctx context.Context, f UserFilter) ([]model.User, error) {
+	q := `SELECT ` + userColumns + ` FROM users`
+	var (
+		conds []string
+		args  []any
+	)
+	if f.Query != "" {
+		pattern := "%" + strings.ToLower(f.Query) + "%"
+		conds = append(conds, `(LOWER(email) LIKE ? OR LOWER(username) LIKE ?)`)
+		args = append(args, pattern, pattern)
+	}
+	if f.DeletionRequestedBefore != nil {
+		conds = append(conds, `deletion_requested_at < ?`)
+		args = append(args, *f.DeletionRequestedBefore)
+	}
+	if len(conds) > 0 {
+		q += ` WHERE ` + strings.Join(conds, ` AND `)
+	}
+	q += ` ORDER BY created_at, id`
+	if f.Limit > 0 {
+		q += ` LIMIT ? OFFSET ?`
+		args = append(args, f.Limit, f.Offset)
+	}
+
+	rows, err := s.query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []model.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning user: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (s *SQLStore) GetIdentity(ctx context.Context, provider, subject string) (model.Identity, error) {
+	var id model.Identity
+	err := s.queryRow(ctx, `SELECT provider, subject, user_id, email, created_at FROM user_identities
+		WHERE provider = ? AND subject = ?`, provider, subject).
+		Scan(&id.Provider, &id.Subject, &id.UserID, &id.Email, &id.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return id, ErrNotFound
+	}
+	return id, err
+}
+
+func (s *SQLStore) CreateIdentity(ctx context.Context, id model.Identity) error {
+	_, err := s.exec(ctx, `INSERT INTO user_identities (provider, subject, user_id, email, created_at) VALUES (?, ?, ?, ?, ?)`,
+		id.Provider, id.Subject, id.UserID, id.Email, id.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("inserting identity: %w", err)
+	}
+	return nil
+}
+
+// isUniqueViolation recognises unique-constraint errors from the supported
+// drivers without importing them.
+func isUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "UNIQUE constraint failed") || strings.Contains(msg, "SQLSTATE 23505")
+}
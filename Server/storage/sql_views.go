@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+const viewColumns = `id, org_id, owner_id, name, filter, created_at, updated_at`
+
+func scanView(row scanner) (model.View, error) {
+	var v model.View
+	err := row.Scan(&v.ID, &v.OrgID, &v.OwnerID, &v.Name, viewFilterColumn{&v.Filter}, &v.CreatedAt, &v.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return v, ErrNotFound
+	}
+	return v, err
+}
+
+// viewFilterColumn stores a ViewFilter as JSON text.
+type viewFilterColumn struct{ f *model.ViewFilter }
+
+func (c viewFilterColumn) Scan(v any) error {
+	var ns sql.NullString
+	if err := ns.Scan(v); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(ns.String), c.f)
+}
+
+func encodeViewFilter(f model.ViewFilter) string {
+	b, err := json.Marshal(f)
+	if err != nil {
+		panic("storage: encoding view filter: " + err.Error())
+	}
+	return string(b)
+}
+
+func (s *SQLStore) ListViews(ctx context.Context, orgID, ownerID string) ([]model.View, error) {
+	rows, err := s.query(ctx, `SELECT `+viewColumns+` FROM views WHERE org_id = ? AND owner_id = ? ORDER BY LOWER(name), id`,
+		orgID, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("listing views: %w", err)
+	}
+	defer rows.Close()
+
+	views := []model.View{}
+	for rows.Next() {
+		v, err := scanView(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning view: %w", err)
+		}
+		views = append(views, v)
+	}
+	return views, rows.Err()
+}
+
+func (s *SQLStore) GetView(ctx context.Context, id string) (model.View, error) {
+	return scanView(s.queryRow(ctx, `SELECT `+viewColumns+` FROM views WHERE id = ?`, id))
+}
+
+func (s *SQLStore) CreateView(ctx context.Context, v *model.View) error {
+	v.ID = NewID()
+	_, err := s.exec(ctx, `INSERT INTO views (`+viewColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		v.ID, v.OrgID, v.OwnerID, v.Name, encodeViewFilter(v.Filter), v.CreatedAt, v.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("inserting view: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) UpdateView(ctx context.Context, v *model.View) error {
+	err := s.execOne(ctx, `UPDATE views SET name = ?, filter = ?, updated_at = ? WHERE id = ?`,
+		v.Name, encodeViewFilter(v.Filter), v.UpdatedAt, v.ID)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (s *SQLStore) DeleteView(ctx context.Context, id string) error {
+	return s.execOne(ctx, `DELETE FROM views WHERE id = ?`, id)
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"starttech-server/model"
+)
+
+func (s *SQLStore) ListWatchers(ctx context.Context, taskID string) ([]model.Watcher, error) {
+	rows, err := s.query(ctx, `SELECT w.task_id, w.user_id, u.username, w.created_at
+		FROM task_watchers w LEFT JOIN users u ON u.id = w.user_id
+		WHERE w.task_id = ? ORDER BY w.created_at, w.user_id`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("listing watchers: %w", err)
+	}
+	defer rows.Close()
+
+	watchers := []model.Watcher{}
+	for rows.Next() {
+		var w model.Watcher
+		var username sql.NullString
+		if err := rows.Scan(&w.TaskID, &w.UserID, &username, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning watcher: %w", err)
+		}
+		w.Username = username.String
+		watchers = append(watchers, w)
+	}
+	return watchers, rows.Err()
+}
+
+func (s *SQLStore) AddWatcher(ctx context.Context, w model.Watcher) error {
+	_, err := s.exec(ctx, `INSERT INTO task_watchers (task_id, user_id, created_at) VALUES (?, ?, ?)
+		ON CONFLICT (task_id, user_id) DO NOTHING`, w.TaskID, w.UserID, w.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting watcher: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) RemoveWatcher(ctx context.Context, taskID, userID string) error {
+	return s.execOne(ctx, `DELETE FROM task_watchers WHERE task_id = ? AND user_id = ?`, taskID, userID)
+}
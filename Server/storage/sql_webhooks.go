@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"starttech-server/model"
+)
+
+const webhookColumns = `id, org_id, owner_id, url, events, active, secret, created_at`
+
+func scanWebhook(row scanner) (model.Webhook, error) {
+	var w model.Webhook
+	var evts string
+	err := row.Scan(&w.ID, &w.OrgID, &w.OwnerID, &w.URL, &evts, &w.Active, &w.Secret, &w.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return w, ErrNotFound
+	}
+	w.Events = []string{}
+	if evts != "" {
+		w.Events = strings.Split(evts, ",")
+	}
+	return w, err
+}
+
+func (s *SQLStore) ListWebhooks(ctx context.Context, orgID, ownerID string) ([]model.Webhook, error) {
+	rows, err := s.query(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE org_id = ? AND owner_id = ?
+		ORDER BY created_at, id`, orgID, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []model.Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning webhook: %w", err)
+		}
+		hooks = append(hooks, w)
+	}
+	return hooks, rows.Err()
+}
+
+func (s *SQLStore) GetWebhook(ctx context.Context, id string) (model.Webhook, error) {
+	return scanWebhook(s.queryRow(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id))
+}
+
+func (s *SQLStore) CreateWebhook(ctx context.Context, w *model.Webhook) error {
+	w.ID = NewID()
+	_, err := s.exec(ctx, `INSERT INTO webhooks (`+webhookColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		w.ID, w.OrgID, w.OwnerID, w.URL, strings.Join(w.Events, ","), w.Active, w.Secret, w.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting webhook: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) UpdateWebhook(ctx context.Context, w *model.Webhook) error {
+	return s.execOne(ctx, `UPDATE webhooks SET url = ?, events = ?, active = ? WHERE id = ?`,
+		w.URL, strings.Join(w.Events, ","), w.Active, w.ID)
+}
+
+func (s *SQLStore) DeleteWebhook(ctx context.Context, id string) error {
+	return s.inTx(ctx, func(tx *SQLStore) error {
+		if _, err := tx.exec(ctx, `DELETE FROM webhook_deliveries WHERE webhook_id = ?`, id); err != nil {
+			return fmt.Errorf("deleting deliveries: %w", err)
+		}
+		return tx.execOne(ctx, `DELETE FROM webhooks WHERE id = ?`, id)
+	})
+}
+
+const deliveryColumns = `id, webhook_id, event, payload, status, attempts, response_status, last_error,
+	next_attempt_at, created_at, completed_at`
+
+func scanDelivery(row scanner) (model.Delivery, error) {
+	var d model.Delivery
+	var payload string
+	err := row.Scan(&d.ID, &d.WebhookID, &d.Event, &payload, &d.Status, &d.Attempts, &d.ResponseStatus, &d.LastError,
+		nullTime{&d.NextAttemptAt}, &d.CreatedAt, nullTime{&d.CompletedAt})
+	if errors.Is(err, sql.ErrNoRows) {
+		return d, ErrNotFound
+	}
+	d.Payload = []byte(payload)
+	return d, err
+}
+
+func (s *SQLStore) listDeliveries(ctx context.Context, where string, args ...any) ([]model.Delivery, error) {
+	rows, err := s.query(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	ds := []model.Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning delivery: %w", err)
+		}
+		ds = append(ds, d)
+	}
+	return ds, rows.Err()
+}
+
+func (s *SQLStore) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]model.Delivery, error) {
+	return s.listDeliveries(ctx, `webhook_id = ? ORDER BY created_at DESC, id DESC LIMIT ?`, webhookID, limit)
+}
+
+func (s *SQLStore) GetDelivery(ctx context.Context, id string) (model.Delivery, error) {
+	return scanDelivery(s.queryRow(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = ?`, id))
+}
+
+func (s *SQLStore) CreateDelivery(ctx context.Context, d *model.Delivery) error {
+	d.ID = NewID()
+	_, err := s.exec(ctx, `INSERT INTO webhook_deliveries (`+deliveryColumns+`) VALUES (`+placeholders(11)+`)`,
+		d.ID, d.WebhookID, d.Event, string(d.Payload), d.Status, d.Attempts, d.ResponseStatus, d.LastError,
+		d.NextAttemptAt, d.CreatedAt, d.CompletedAt)
+	if err != nil {
+		return fmt.Errorf("inserting delivery: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) UpdateDelivery(ctx context.Context, d *model.Delivery) error {
+	return s.execOne(ctx, `UPDATE webhook_deliveries SET status = ?, attempts = ?, response_status = ?, last_error = ?,
+		next_attempt_at = ?, completed_at = ? WHERE id = ?`,
+		d.Status, d.Attempts, d.ResponseStatus, d.LastError, d.NextAttemptAt, d.CompletedAt, d.ID)
+}
@@ -0,0 +1,942 @@
+// Package storage persists API resources.
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"starttech-server/model"
+)
+
+var (
+	// ErrNotFound is returned when the requested record does not exist.
+	ErrNotFound = errors.New("storage: not found")
+	// ErrConflict is returned when a write would violate a uniqueness
+	// constraint.
+	ErrConflict = errors.New("storage: conflict")
+	// ErrStale is returned by UpdateTask when the task has been changed
+	// since the copy being saved was read.
+	ErrStale = errors.New("storage: stale version")
+)
+
+// Sort keys accepted by ListTasks.
+const (
+	SortCreatedAt = "created_at"
+	SortUpdatedAt = "updated_at"
+	SortDueDate   = "due_date"
+	SortTitle     = "title"
+	SortStatus    = "status"
+	SortPosition  = "position"
+	SortDeletedAt = "deleted_at"
+	SortPriority  = "priority"
+	// SortUrgency puts the most urgent tasks first, weighing their
+	// priority, how soon they are due and how old they are.
+	SortUrgency = "urgency"
+)
+
+// TaskSortKeys are the sort keys clients may ask for.
+var TaskSortKeys = []string{
+	SortCreatedAt, SortUpdatedAt, SortDueDate,
+	SortTitle, SortStatus, SortPosition,
+	SortPriority, SortUrgency,
+}
+
+// Sort orders a listing. Ties are always broken by ID.
+type Sort struct {
+	Field string
+	Desc  bool
+	// CustomField, when set, orders tasks by the value of this custom
+	// field instead of Field. Tasks without one sort last in ascending
+	// order.
+	CustomField string
+}
+
+// TaskFilter narrows and pages ListTasks. Zero-valued fields do not filter;
+// a zero Limit returns every match.
+type TaskFilter struct {
+	OrgID   string // only tasks in this organization
+	OwnerID string
+	// VisibleTo keeps the tasks this user may see: their own tasks outside
+	// any project and every task of the projects they are a member of.
+	VisibleTo string
+	// AssigneeID keeps the tasks assigned to this user, and Unassigned
+	// those assigned to nobody.
+	AssigneeID string
+	Unassigned bool
+	ProjectID  string   // only tasks in this project
+	ParentID   string   // only direct subtasks of this task
+	TagIDs     []string // only tasks carrying every one of these tags
+	// Fields keeps the tasks whose custom fields, by ID, hold these
+	// values, in the form Task.Fields holds them.
+	Fields    map[string]any
+	Status    model.Status
+	DueBefore *time.Time
+	DueAfter  *time.Time
+	// Trashed selects the tasks in the trash instead of the others, and
+	// DeletedBefore narrows them to those deleted before then.
+	Trashed       bool
+	DeletedBefore *time.Time
+	// Archived, when set, keeps only the archived tasks or only the
+	// others.
+	Archived *bool
+	// Completed keeps the completed tasks, and UpdatedBefore those last
+	// changed before then.
+	Completed     bool
+	UpdatedBefore *time.Time
+
+	Sort   Sort
+	Limit  int
+	Offset int
+}
+
+// ChangeFilter selects the entries of the change feed of OrgID that
+// VisibleTo gets and that come after After: the tasks it may see now, in
+// the trash or not and archived or not, and tombstones for those it may
+// have seen before but no longer can.
+type ChangeFilter struct {
+	OrgID     string
+	VisibleTo string
+	After     ChangeKey
+	Limit     int
+}
+
+// ChangeKey places a task in the change feed of its organization, which
+// orders tasks by the sequence number of their last change and then by ID.
+// The zero ChangeKey comes before every task.
+type ChangeKey struct {
+	Seq    int64
+	TaskID string
+}
+
+// TaskChange is an entry of the change feed. Task is nil for a tombstone:
+// the task was deleted for good, or the user the feed is for can no longer
+// see it.
+type TaskChange struct {
+	Key  ChangeKey
+	Task *model.Task
+}
+
+// TaskStore persists tasks.
+type TaskStore interface {
+	ListTasks(ctx context.Context, f TaskFilter) ([]model.Task, error)
+	// CountTasks counts the tasks matching f, ignoring its paging fields.
+	CountTasks(ctx context.Context, f TaskFilter) (int, error)
+	// ListChanges returns the entries matching f in the order of the
+	// change feed, each task once. Every write to tasks adds to the feed of
+	// their organization in its own transaction, under a sequence number
+	// that commits in order.
+	ListChanges(ctx context.Context, f ChangeFilter) ([]TaskChange, error)
+	GetTask(ctx context.Context, id string) (model.Task, error)
+	// CreateTask assigns an ID and the first version to t and stores it,
+	// with its first revision.
+	CreateTask(ctx context.Context, t *model.Task) error
+	// UpdateTask saves t if its Version is still the stored one, and then
+	// advances it and saves a revision; otherwise it returns ErrStale.
+	UpdateTask(ctx context.Context, t *model.Task) error
+	// DeleteTask removes the task, its reminders, its comments, its time
+	// entries, its dependencies either way, its checklist, its watchers,
+	// its revisions, the mentions and notifications about it, its link to a GitHub issue,
+	// its share links and the records of its attachments. Their blobs are the caller's to
+	// delete.
+	DeleteTask(ctx context.Context, id string) error
+	// MergeTask moves the comments of the task fromID, with their
+	// mentions, the records of its attachments and its watchers onto the
+	// task intoID.
+	MergeTask(ctx context.Context, fromID, intoID string) error
+}
+
+// SearchStore finds tasks and comments by their text.
+type SearchStore interface {
+	// Search returns hits for the tasks matching f whose title or
+	// description, or one of whose comments, contains every one of terms,
+	// as produced by SearchTerms. The last term also matches longer words
+	// it is the start of. Hits come best first; f.Sort is ignored and
+	// f.Limit and f.Offset page the hits.
+	Search(ctx context.Context, terms []string, f TaskFilter) ([]model.SearchHit, error)
+}
+
+// AttachmentStore persists the metadata of files uploaded to tasks.
+type AttachmentStore interface {
+	// ListAttachments returns the attachments of a task, oldest first.
+	ListAttachments(ctx context.Context, taskID string) ([]model.Attachment, error)
+	GetAttachment(ctx context.Context, id string) (model.Attachment, error)
+	// CreateAttachment stores a, whose ID the caller has already
+	// assigned because it is part of the blob key.
+	CreateAttachment(ctx context.Context, a *model.Attachment) error
+	// UpdateAttachment saves the blob key and scan result of a.
+	UpdateAttachment(ctx context.Context, a model.Attachment) error
+	DeleteAttachment(ctx context.Context, id string) error
+}
+
+// CommentStore persists the comments on tasks and their edit history.
+type CommentStore interface {
+	// ListComments returns the comments on a task, oldest first.
+	ListComments(ctx context.Context, taskID string) ([]model.Comment, error)
+	GetComment(ctx context.Context, id string) (model.Comment, error)
+	// CreateComment assigns an ID to c and stores it.
+	CreateComment(ctx context.Context, c *model.Comment) error
+	// EditComment saves the new Body and EditedAt of c and adds prev to
+	// its history, all at once.
+	EditComment(ctx context.Context, c *model.Comment, prev model.CommentEdit) error
+	// DeleteComment removes the comment, its history and its mentions. Its
+	// replies are kept and answer the comment it replied to instead.
+	DeleteComment(ctx context.Context, id string) error
+	// CommentHistory returns the earlier bodies of a comment, oldest first.
+	CommentHistory(ctx context.Context, commentID string) ([]model.CommentEdit, error)
+}
+
+// DependencyStore persists the dependencies between tasks.
+type DependencyStore interface {
+	// Blockers returns the dependencies of the task, oldest first.
+	Blockers(ctx context.Context, taskID string) ([]model.Dependency, error)
+	// Dependents returns the dependencies on the task, oldest first.
+	Dependents(ctx context.Context, blockerID string) ([]model.Dependency, error)
+	// AddDependency returns ErrConflict if d is already stored.
+	AddDependency(ctx context.Context, d model.Dependency) error
+	RemoveDependency(ctx context.Context, taskID, blockerID string) error
+}
+
+// WatcherStore persists who watches which tasks.
+type WatcherStore interface {
+	// ListWatchers returns the watchers of a task, oldest first.
+	ListWatchers(ctx context.Context, taskID string) ([]model.Watcher, error)
+	// AddWatcher stores w. Watching a task twice is not an error.
+	AddWatcher(ctx context.Context, w model.Watcher) error
+	RemoveWatcher(ctx context.Context, taskID, userID string) error
+}
+
+// RevisionStore reads the revisions of tasks, which TaskStore saves along
+// with every version of a task it stores.
+type RevisionStore interface {
+	// ListRevisions returns the revisions matching f, newest first.
+	ListRevisions(ctx context.Context, f RevisionFilter) ([]model.Revision, error)
+	GetRevision(ctx context.Context, taskID string, version int64) (model.Revision, error)
+}
+
+// RevisionFilter selects the revisions of one task.
+type RevisionFilter struct {
+	TaskID string
+	Limit  int
+	Offset int
+}
+
+// ChecklistStore persists the checklist items of tasks. The counts on the
+// task are saved with it through TaskStore.
+type ChecklistStore interface {
+	// ListChecklist returns the items of the task ordered by position.
+	ListChecklist(ctx context.Context, taskID string) ([]model.ChecklistItem, error)
+	GetChecklistItem(ctx context.Context, id string) (model.ChecklistItem, error)
+	// CreateChecklistItem assigns an ID to c and stores it.
+	CreateChecklistItem(ctx context.Context, c *model.ChecklistItem) error
+	UpdateChecklistItem(ctx context.Context, c *model.ChecklistItem) error
+	DeleteChecklistItem(ctx context.Context, id string) error
+	// ReorderChecklist sets the position of each listed item of the task
+	// to its index in itemIDs, counting from 1.
+	ReorderChecklist(ctx context.Context, taskID string, itemIDs []string) error
+}
+
+// TimeEntryFilter selects time entries. Zero-valued fields do not filter.
+type TimeEntryFilter struct {
+	OrgID  string
+	TaskID string
+	UserID string
+	// VisibleTo keeps the entries on tasks this user may see, as
+	// TaskFilter.VisibleTo does.
+	VisibleTo string
+	ProjectID string
+	// From and To keep the entries overlapping that span; running timers
+	// last until now.
+	From    *time.Time
+	To      *time.Time
+	Running bool // only running timers
+}
+
+// TimeStore persists the time users log on tasks. Entries come with the
+// ProjectID of their task; Seconds is left for the caller to fill in.
+type TimeStore interface {
+	// ListTimeEntries returns the entries matching f, oldest first.
+	ListTimeEntries(ctx context.Context, f TimeEntryFilter) ([]model.TimeEntry, error)
+	GetTimeEntry(ctx context.Context, id string) (model.TimeEntry, error)
+	// CreateTimeEntry assigns an ID to e and stores it. It returns
+	// ErrConflict if e is running and its user already has a running
+	// timer.
+	CreateTimeEntry(ctx context.Context, e *model.TimeEntry) error
+	UpdateTimeEntry(ctx context.Context, e *model.TimeEntry) error
+	DeleteTimeEntry(ctx context.Context, id string) error
+}
+
+// ActivityFilter selects an activity feed. Exactly one of TaskID and
+// ProjectID is set.
+type ActivityFilter struct {
+	TaskID    string
+	ProjectID string
+	Limit     int
+	Offset    int
+}
+
+// ActivityStore persists the activity log. Entries are never changed once
+// recorded.
+type ActivityStore interface {
+	// RecordActivity assigns an ID to a and stores it.
+	RecordActivity(ctx context.Context, a *model.Activity) error
+	// ListActivity returns the entries matching f, newest first.
+	ListActivity(ctx context.Context, f ActivityFilter) ([]model.Activity, error)
+}
+
+// CommandStore keeps the recent changes to tasks that can be undone.
+// Commands never change once saved.
+type CommandStore interface {
+	// SaveCommand assigns an ID to c and stores it.
+	SaveCommand(ctx context.Context, c *model.Command) error
+	// LastCommand returns the newest command userID made in the
+	// organization orgID at or after since, or ErrNotFound.
+	LastCommand(ctx context.Context, orgID, userID string, since time.Time) (model.Command, error)
+	DeleteCommand(ctx context.Context, id string) error
+	// PurgeCommands deletes the commands made before the given time and
+	// returns how many there were.
+	PurgeCommands(ctx context.Context, before time.Time) (int, error)
+	// PurgeableCommands counts the commands PurgeCommands would delete.
+	PurgeableCommands(ctx context.Context, before time.Time) (int, error)
+}
+
+// IdempotencyRecord is a request sent with an Idempotency-Key and, once it
+// has been served, the response it got.
+type IdempotencyRecord struct {
+	UserID string
+	Key    string
+	// Fingerprint identifies the method, path and body of the request.
+	Fingerprint string
+	Done        bool
+	Status      int
+	Header      map[string]string
+	Body        []byte
+	CreatedAt   time.Time
+}
+
+// IdempotencyStore remembers the requests sent with an Idempotency-Key so
+// that retries are answered with the first response instead of being
+// served again.
+type IdempotencyStore interface {
+	// ClaimIdempotencyKey stores r, not yet done, unless r.UserID already
+	// used r.Key; then it returns the earlier record and false.
+	ClaimIdempotencyKey(ctx context.Context, r IdempotencyRecord) (IdempotencyRecord, bool, error)
+	// FinishIdempotencyKey saves the fingerprint and response of a claimed
+	// request and marks it done.
+	FinishIdempotencyKey(ctx context.Context, r IdempotencyRecord) error
+	// ReleaseIdempotencyKey forgets a claim so the request can be tried
+	// again.
+	ReleaseIdempotencyKey(ctx context.Context, userID, key string) error
+	// PurgeIdempotencyKeys deletes the records created before the given
+	// time and returns how many there were.
+	PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int, error)
+	// PurgeableIdempotencyKeys counts the records PurgeIdempotencyKeys would delete.
+	PurgeableIdempotencyKeys(ctx context.Context, before time.Time) (int, error)
+}
+
+// AuditFilter selects audit events. From and To, when set, bound
+// CreatedAt, From inclusive and To exclusive; the other fields match
+// exactly when set.
+type AuditFilter struct {
+	From    *time.Time
+	To      *time.Time
+	Action  string
+	ActorID string
+	OrgID   string
+	// Oldest lists the events oldest first instead of newest first.
+	Oldest bool
+	Limit  int
+	Offset int
+}
+
+// AuditStore persists the audit log.
+type AuditStore interface {
+	// AppendAuditEvent assigns an ID to e, numbers it after the last
+	// stored event and links it to that event: PrevHash is set to the last
+	// event's Hash, then Hash to hash(*e). Concurrent appends are
+	// serialised so the chain never forks.
+	AppendAuditEvent(ctx context.Context, e *model.AuditEvent, hash func(model.AuditEvent) string) error
+	// ListAuditEvents returns the events matching f, ordered by Seq.
+	ListAuditEvents(ctx context.Context, f AuditFilter) ([]model.AuditEvent, error)
+	// PurgeAuditEvents deletes the events created before the given time and
+	// returns how many there were.
+	PurgeAuditEvents(ctx context.Context, before time.Time) (int, error)
+	// PurgeableAuditEvents counts the events PurgeAuditEvents would delete.
+	PurgeableAuditEvents(ctx context.Context, before time.Time) (int, error)
+	// RedactAuditEvents applies AuditEvent.Redact for the user userID with
+	// the given email to every event, relinks the events from the first
+	// one redacted on, rehashing them with hash, and appends e after them
+	// as AppendAuditEvent would, all at once. It returns how many events
+	// were redacted.
+	RedactAuditEvents(ctx context.Context, userID, email string, e *model.AuditEvent, hash func(model.AuditEvent) string) (int, error)
+}
+
+// TagStore persists tags. Task/tag associations are saved with the task
+// through TaskStore.
+type TagStore interface {
+	// ListTags returns the owner's tags in the organization ordered by
+	// name.
+	ListTags(ctx context.Context, orgID, ownerID string) ([]model.Tag, error)
+	GetTag(ctx context.Context, id string) (model.Tag, error)
+	// CreateTag assigns an ID to t and stores it, returning ErrConflict if
+	// the owner already has a tag of that name in the organization.
+	CreateTag(ctx context.Context, t *model.Tag) error
+	UpdateTag(ctx context.Context, t *model.Tag) error
+	// DeleteTag removes the tag and detaches it from every task.
+	DeleteTag(ctx context.Context, id string) error
+}
+
+// FieldStore persists the custom fields of projects. Their values are
+// saved with each task through TaskStore.
+type FieldStore interface {
+	// ListFields returns the fields of the project, oldest first.
+	ListFields(ctx context.Context, projectID string) ([]model.CustomField, error)
+	GetField(ctx context.Context, id string) (model.CustomField, error)
+	// CreateField assigns an ID to f and stores it, returning ErrConflict
+	// if the project already has a field of that name.
+	CreateField(ctx context.Context, f *model.CustomField) error
+	// UpdateField saves f and removes the values of a select field that
+	// are no longer among its options from every task.
+	UpdateField(ctx context.Context, f *model.CustomField) error
+	// DeleteField removes the field and its value from every task.
+	DeleteField(ctx context.Context, id string) error
+}
+
+// RuleStore persists the automation rules of projects, and the tasks each
+// rule has acted on since its trigger last stopped holding for them.
+type RuleStore interface {
+	// ListRules returns the rules of the project, oldest first.
+	ListRules(ctx context.Context, projectID string) ([]model.Rule, error)
+	// ListEnabledRules returns the enabled rules of every project, oldest
+	// first.
+	ListEnabledRules(ctx context.Context) ([]model.Rule, error)
+	GetRule(ctx context.Context, id string) (model.Rule, error)
+	// CreateRule assigns an ID to r and stores it.
+	CreateRule(ctx context.Context, r *model.Rule) error
+	UpdateRule(ctx context.Context, r *model.Rule) error
+	// DeleteRule removes the rule along with its firings.
+	DeleteRule(ctx context.Context, id string) error
+	// RuleFirings returns the IDs of the tasks the rule has acted on.
+	RuleFirings(ctx context.Context, ruleID string) ([]string, error)
+	// AddRuleFiring notes that the rule acted on the task at the given
+	// time. Noting it twice is not an error.
+	AddRuleFiring(ctx context.Context, ruleID, taskID string, at time.Time) error
+	// RemoveRuleFirings forgets that the rule acted on the given tasks.
+	RemoveRuleFirings(ctx context.Context, ruleID string, taskIDs []string) error
+}
+
+// ViewStore persists saved task views.
+type ViewStore interface {
+	// ListViews returns the owner's views in the organization ordered by
+	// name.
+	ListViews(ctx context.Context, orgID, ownerID string) ([]model.View, error)
+	GetView(ctx context.Context, id string) (model.View, error)
+	// CreateView assigns an ID to v and stores it, returning ErrConflict
+	// if the owner already has a view of that name in the organization.
+	CreateView(ctx context.Context, v *model.View) error
+	UpdateView(ctx context.Context, v *model.View) error
+	DeleteView(ctx context.Context, id string) error
+}
+
+// TemplateStore persists task and project templates.
+type TemplateStore interface {
+	// ListTemplates returns the owner's templates in the organization
+	// ordered by name.
+	ListTemplates(ctx context.Context, orgID, ownerID string) ([]model.Template, error)
+	GetTemplate(ctx context.Context, id string) (model.Template, error)
+	// CreateTemplate assigns an ID to t and stores it, returning
+	// ErrConflict if the owner already has a template of that name in the
+	// organization.
+	CreateTemplate(ctx context.Context, t *model.Template) error
+	UpdateTemplate(ctx context.Context, t *model.Template) error
+	DeleteTemplate(ctx context.Context, id string) error
+}
+
+// ProjectStore persists projects and their members.
+type ProjectStore interface {
+	// ListProjects returns the projects of the organization userID is a
+	// member of, ordered by name.
+	ListProjects(ctx context.Context, orgID, userID string) ([]model.Project, error)
+	GetProject(ctx context.Context, id string) (model.Project, error)
+	// CreateProject assigns an ID to p and stores it with p.OwnerID as
+	// its first member, in the owner role.
+	CreateProject(ctx context.Context, p *model.Project) error
+	UpdateProject(ctx context.Context, p *model.Project) error
+	// DeleteProject removes the project, its memberships, its custom
+	// fields and their values, and its links to GitHub and Slack. Its tasks
+	// are kept and no longer belong to any project.
+	DeleteProject(ctx context.Context, id string) error
+
+	// ListMembers returns the members of a project, earliest first.
+	ListMembers(ctx context.Context, projectID string) ([]model.Member, error)
+	// GetMember returns ErrNotFound if userID is not a member.
+	GetMember(ctx context.Context, projectID, userID string) (model.Member, error)
+	// SaveMember adds m.UserID to the project or changes their role.
+	SaveMember(ctx context.Context, m *model.Member) error
+	RemoveMember(ctx context.Context, projectID, userID string) error
+
+	// ReorderTasks sets the position of each listed task of the project to
+	// its index in taskIDs plus one, all at once.
+	ReorderTasks(ctx context.Context, projectID string, taskIDs []string) error
+}
+
+// ReminderStore persists the reminders the scheduler delivers, so they
+// survive restarts.
+type ReminderStore interface {
+	// ScheduleReminders replaces the unsent reminders of a task with rs,
+	// assigning their IDs.
+	ScheduleReminders(ctx context.Context, taskID string, rs []model.Reminder) error
+	// PendingReminders returns up to limit unsent reminders due at or
+	// before now, oldest first.
+	PendingReminders(ctx context.Context, now time.Time, limit int) ([]model.Reminder, error)
+	// MarkReminderSent records delivery. It returns ErrNotFound if the
+	// reminder is gone or was already marked, so that only one caller
+	// delivers it.
+	MarkReminderSent(ctx context.Context, id string, at time.Time) error
+}
+
+// WebhookStore persists webhooks and the log of their deliveries.
+type WebhookStore interface {
+	// ListWebhooks returns the owner's webhooks in the organization,
+	// oldest first.
+	ListWebhooks(ctx context.Context, orgID, ownerID string) ([]model.Webhook, error)
+	GetWebhook(ctx context.Context, id string) (model.Webhook, error)
+	// CreateWebhook assigns an ID to w and stores it.
+	CreateWebhook(ctx context.Context, w *model.Webhook) error
+	UpdateWebhook(ctx context.Context, w *model.Webhook) error
+	// DeleteWebhook removes the webhook and its deliveries.
+	DeleteWebhook(ctx context.Context, id string) error
+
+	// ListDeliveries returns up to limit of the webhook's deliveries, newest
+	// first.
+	ListDeliveries(ctx context.Context, webhookID string, limit int) ([]model.Delivery, error)
+	GetDelivery(ctx context.Context, id string) (model.Delivery, error)
+	// CreateDelivery assigns an ID to d and stores it.
+	CreateDelivery(ctx context.Context, d *model.Delivery) error
+	UpdateDelivery(ctx context.Context, d *model.Delivery) error
+}
+
+// OrgStore persists organizations, their members, the invitations to join
+// them and the email domains they claim.
+type OrgStore interface {
+	// ListOrgs returns the organizations userID belongs to, with Role set
+	// to theirs, oldest membership first.
+	ListOrgs(ctx context.Context, userID string) ([]model.Org, error)
+	GetOrg(ctx context.Context, id string) (model.Org, error)
+	// FindOrgs returns the organizations matching f, oldest first, without
+	// Role.
+	FindOrgs(ctx context.Context, f OrgFilter) ([]model.Org, error)
+	// CreateOrg assigns an ID to o and stores it with ownerID as its first
+	// member, in the owner role.
+	CreateOrg(ctx context.Context, o *model.Org, ownerID string) error
+	UpdateOrg(ctx context.Context, o *model.Org) error
+
+	// ListOrgMembers returns the members of an organization, earliest
+	// first.
+	ListOrgMembers(ctx context.Context, orgID string) ([]model.OrgMembership, error)
+	// GetOrgMember returns ErrNotFound if userID is not a member.
+	GetOrgMember(ctx context.Context, orgID, userID string) (model.OrgMembership, error)
+	// SaveOrgMember adds m.UserID to the organization or changes their
+	// role.
+	SaveOrgMember(ctx context.Context, m *model.OrgMembership) error
+	// RemoveOrgMember takes userID out of the organization and all of its
+	// projects.
+	RemoveOrgMember(ctx context.Context, orgID, userID string) error
+
+	// ListInvitations returns the organization's invitations that have not
+	// been accepted, newest first.
+	ListInvitations(ctx context.Context, orgID string) ([]model.Invitation, error)
+	GetInvitation(ctx context.Context, id string) (model.Invitation, error)
+	// GetInvitationByToken finds an invitation by the hash of its token.
+	GetInvitationByToken(ctx context.Context, tokenHash string) (model.Invitation, error)
+	// CreateInvitation assigns an ID to inv and stores it.
+	CreateInvitation(ctx context.Context, inv *model.Invitation) error
+	// AcceptInvitation marks the invitation accepted at m.CreatedAt and
+	// saves m, all at once. It returns ErrNotFound if the invitation is gone
+	// or was already accepted.
+	AcceptInvitation(ctx context.Context, id string, m *model.OrgMembership) error
+	DeleteInvitation(ctx context.Context, id string) error
+
+	// ListDomains returns the domains an organization claims, oldest
+	// first.
+	ListDomains(ctx context.Context, orgID string) ([]model.Domain, error)
+	// GetDomain returns ErrNotFound if the organization does not claim
+	// domain.
+	GetDomain(ctx context.Context, orgID, domain string) (model.Domain, error)
+	// CreateDomain returns ErrConflict if the organization already claims
+	// d.Domain.
+	CreateDomain(ctx context.Context, d model.Domain) error
+	// VerifyDomain marks the organization's claim to domain verified at
+	// at. It returns ErrConflict if another organization has the domain
+	// verified.
+	VerifyDomain(ctx context.Context, orgID, domain string, at time.Time) error
+	DeleteDomain(ctx context.Context, orgID, domain string) error
+}
+
+// SCIMStore persists the links between organizations and the users
+// identity providers provisioned into them.
+type SCIMStore interface {
+	// ListSCIMLinks returns the links of an organization, oldest first.
+	ListSCIMLinks(ctx context.Context, orgID string) ([]model.SCIMLink, error)
+	// GetSCIMLink returns ErrNotFound if userID was not provisioned into
+	// the organization.
+	GetSCIMLink(ctx context.Context, orgID, userID string) (model.SCIMLink, error)
+	// CreateSCIMLink returns ErrConflict if the user is already linked.
+	CreateSCIMLink(ctx context.Context, l model.SCIMLink) error
+	UpdateSCIMLink(ctx context.Context, l model.SCIMLink) error
+	DeleteSCIMLink(ctx context.Context, orgID, userID string) error
+}
+
+// SSOStore persists the single sign-on settings of organizations.
+type SSOStore interface {
+	// GetSSOConfig returns ErrNotFound if the organization has none.
+	GetSSOConfig(ctx context.Context, orgID string) (model.SSOConfig, error)
+	// GetSSOConfigByDomain returns ErrNotFound if no organization signs in
+	// the people of domain.
+	GetSSOConfigByDomain(ctx context.Context, domain string) (model.SSOConfig, error)
+	// SaveSSOConfig creates or replaces the organization's settings. It
+	// returns ErrConflict if another organization has c.Domain.
+	SaveSSOConfig(ctx context.Context, c model.SSOConfig) error
+	DeleteSSOConfig(ctx context.Context, orgID string) error
+}
+
+// OrgFilter narrows FindOrgs. Query matches part of the name, regardless of
+// case; an empty one matches every organization.
+type OrgFilter struct {
+	Query  string
+	Limit  int
+	Offset int
+}
+
+// UsageStore counts what is stored, for the server's administrators.
+type UsageStore interface {
+	// Usage counts what the organization orgID holds, or, if orgID is
+	// empty, what the whole server does.
+	Usage(ctx context.Context, orgID string) (model.Usage, error)
+}
+
+// BackupStore lists everything an organization holds, whoever owns it,
+// for backups. Each listing is oldest first.
+type BackupStore interface {
+	// OrgProjects returns the projects of the organization, archived or
+	// not.
+	OrgProjects(ctx context.Context, orgID string) ([]model.Project, error)
+	OrgTags(ctx context.Context, orgID string) ([]model.Tag, error)
+	OrgViews(ctx context.Context, orgID string) ([]model.View, error)
+	OrgTemplates(ctx context.Context, orgID string) ([]model.Template, error)
+}
+
+// CalendarStore persists calendar feeds, one per user and organization.
+type CalendarStore interface {
+	GetCalendarFeed(ctx context.Context, orgID, userID string) (model.CalendarFeed, error)
+	GetCalendarFeedByToken(ctx context.Context, tokenHash string) (model.CalendarFeed, error)
+	// SaveCalendarFeed creates f or replaces the user's feed in its
+	// organization, retiring the old token.
+	SaveCalendarFeed(ctx context.Context, f model.CalendarFeed) error
+	DeleteCalendarFeed(ctx context.Context, orgID, userID string) error
+}
+
+// ShareFilter selects the share links of one task, or else of one project.
+type ShareFilter struct {
+	ProjectID string
+	TaskID    string
+}
+
+// ShareStore persists the links that show a task or project to anybody.
+// Deleting the task or project deletes its links.
+type ShareStore interface {
+	// CreateShareLink assigns an ID to l and stores it.
+	CreateShareLink(ctx context.Context, l *model.ShareLink) error
+	GetShareLink(ctx context.Context, id string) (model.ShareLink, error)
+	GetShareLinkByToken(ctx context.Context, tokenHash string) (model.ShareLink, error)
+	// ListShareLinks returns the links matching f, oldest first.
+	ListShareLinks(ctx context.Context, f ShareFilter) ([]model.ShareLink, error)
+	DeleteShareLink(ctx context.Context, id string) error
+	// PurgeShareLinks deletes the links that expired before the given
+	// time and returns how many there were.
+	PurgeShareLinks(ctx context.Context, before time.Time) (int, error)
+	// PurgeableShareLinks counts the links PurgeShareLinks would delete.
+	PurgeableShareLinks(ctx context.Context, before time.Time) (int, error)
+}
+
+// FlagStore persists feature flags, with the organizations and users they
+// are turned on or off for in particular.
+type FlagStore interface {
+	// ListFlags returns every flag stored, by key.
+	ListFlags(ctx context.Context) ([]model.Flag, error)
+	GetFlag(ctx context.Context, key string) (model.Flag, error)
+	// SaveFlag creates f, or replaces the settings of the flag with its
+	// key, keeping its organizations and users.
+	SaveFlag(ctx context.Context, f model.Flag) error
+	// DeleteFlag deletes a flag with its organizations and users.
+	DeleteFlag(ctx context.Context, key string) error
+	// SetFlagOverride turns the flag with the given key on or off for one
+	// organization or user. The flag must exist.
+	SetFlagOverride(ctx context.Context, key string, subject model.FlagSubject, id string, enabled bool) error
+	DeleteFlagOverride(ctx context.Context, key string, subject model.FlagSubject, id string) error
+}
+
+// InboundStore persists the addresses emails become tasks through, one per
+// user and organization.
+type InboundStore interface {
+	GetInboundAddress(ctx context.Context, orgID, userID string) (model.InboundAddress, error)
+	GetInboundAddressByToken(ctx context.Context, tokenHash string) (model.InboundAddress, error)
+	// SaveInboundAddress creates a or replaces the user's address in its
+	// organization, retiring the old token.
+	SaveInboundAddress(ctx context.Context, a model.InboundAddress) error
+	DeleteInboundAddress(ctx context.Context, orgID, userID string) error
+}
+
+// GitHubStore persists the GitHub repositories projects are linked to, at
+// most one each, and which issue each imported task came from.
+type GitHubStore interface {
+	GetGitHubLink(ctx context.Context, projectID string) (model.GitHubLink, error)
+	// SaveGitHubLink creates l or replaces the link of its project.
+	SaveGitHubLink(ctx context.Context, l model.GitHubLink) error
+	// DeleteGitHubLink removes the link of the project and its issue
+	// links. The tasks are kept.
+	DeleteGitHubLink(ctx context.Context, projectID string) error
+	GetIssueLink(ctx context.Context, taskID string) (model.IssueLink, error)
+	// FindIssueLink returns the link of the task imported from issue
+	// number of the repository the project is linked to.
+	FindIssueLink(ctx context.Context, projectID string, number int) (model.IssueLink, error)
+	// SaveIssueLink creates l or replaces the link of its task.
+	SaveIssueLink(ctx context.Context, l model.IssueLink) error
+}
+
+// SlackStore persists the Slack channels projects are linked to, at most
+// one each.
+type SlackStore interface {
+	GetSlackLink(ctx context.Context, projectID string) (model.SlackLink, error)
+	// SaveSlackLink creates l or replaces the link of its project.
+	SaveSlackLink(ctx context.Context, l model.SlackLink) error
+	DeleteSlackLink(ctx context.Context, projectID string) error
+}
+
+// BoardImportStore persists the boards imported from other tools and how
+// far each import has got.
+type BoardImportStore interface {
+	// ListBoardImports returns the imports userID started in the
+	// organization, newest first, without their boards.
+	ListBoardImports(ctx context.Context, orgID, userID string) ([]model.BoardImport, error)
+	GetBoardImport(ctx context.Context, id string) (model.BoardImport, error)
+	// CreateBoardImport assigns an ID to im and stores it.
+	CreateBoardImport(ctx context.Context, im *model.BoardImport) error
+	// UpdateBoardImport saves the progress of im: its status, Done,
+	// summary, error and finish time. The boards are kept unless
+	// im.Boards is nil, which drops them.
+	UpdateBoardImport(ctx context.Context, im *model.BoardImport) error
+}
+
+// NotificationStore persists per-user notification preferences and the
+// notifications in each user's inbox.
+type NotificationStore interface {
+	// GetNotificationPrefs returns the user's preferences, or
+	// model.DefaultNotificationPrefs if none were saved.
+	GetNotificationPrefs(ctx context.Context, userID string) (model.NotificationPrefs, error)
+	// SaveNotificationPrefs creates or replaces the user's preferences.
+	SaveNotificationPrefs(ctx context.Context, p model.NotificationPrefs) error
+
+	// ListNotifications returns the notifications matching f, newest
+	// first.
+	ListNotifications(ctx context.Context, f NotificationFilter) ([]model.Notification, error)
+	// CountUnreadNotifications counts the notifications of userID in the
+	// organization that are not read yet.
+	CountUnreadNotifications(ctx context.Context, orgID, userID string) (int, error)
+	GetNotification(ctx context.Context, id string) (model.Notification, error)
+	// CreateNotification assigns an ID to n and stores it.
+	CreateNotification(ctx context.Context, n *model.Notification) error
+	// MarkNotificationsRead sets the read time of those of the listed
+	// notifications that are unread.
+	MarkNotificationsRead(ctx context.Context, ids []string, at time.Time) error
+}
+
+// NotificationFilter selects the notifications of one user in one
+// organization, only the unread ones if Unread is set.
+type NotificationFilter struct {
+	OrgID  string
+	UserID string
+	Unread bool
+	Limit  int
+	Offset int
+}
+
+// MentionStore persists the mentions made in comments.
+type MentionStore interface {
+	// ListMentions returns the mentions made in the comment, oldest first.
+	ListMentions(ctx context.Context, commentID string) ([]model.Mention, error)
+	// CreateMention assigns an ID to m and stores it.
+	CreateMention(ctx context.Context, m *model.Mention) error
+}
+
+// UserStore persists user accounts. Emails and usernames are unique.
+type UserStore interface {
+	GetUser(ctx context.Context, id string) (model.User, error)
+	GetUserByEmail(ctx context.Context, email string) (model.User, error)
+	// CreateUser assigns an ID to u and stores it, returning ErrConflict if
+	// the email or username is taken.
+	CreateUser(ctx context.Context, u *model.User) error
+	// UpdateUser saves the email, username, password hash, timezone,
+	// verification, disabling and deletion times of u, returning
+	// ErrConflict if the email or username is taken.
+	UpdateUser(ctx context.Context, u model.User) error
+	// ListUsers returns the users matching f, oldest first.
+	ListUsers(ctx context.Context, f UserFilter) ([]model.User, error)
+	// GetIdentity returns the link to the provider account subject, or
+	// ErrNotFound if no user has signed in with it.
+	GetIdentity(ctx context.Context, provider, subject string) (model.Identity, error)
+	// CreateIdentity stores id, returning ErrConflict if the provider
+	// account is already linked.
+	CreateIdentity(ctx context.Context, id model.Identity) error
+}
+
+// TwoFactorStore persists users' authenticator apps and recovery codes.
+// Recovery codes are stored as hashes.
+type TwoFactorStore interface {
+	// GetTwoFactor returns ErrNotFound if userID has not set up an
+	// authenticator app.
+	GetTwoFactor(ctx context.Context, userID string) (model.TwoFactor, error)
+	// SaveTwoFactor stores tf, replacing userID's earlier one.
+	SaveTwoFactor(ctx context.Context, tf model.TwoFactor) error
+	// DeleteTwoFactor forgets userID's authenticator app and recovery
+	// codes.
+	DeleteTwoFactor(ctx context.Context, userID string) error
+	// UseTOTPStep records that userID's code for period step was accepted,
+	// returning ErrConflict if a code of that period or a later one
+	// already was.
+	UseTOTPStep(ctx context.Context, userID string, step int64) error
+	// SetRecoveryCodes replaces userID's recovery codes.
+	SetRecoveryCodes(ctx context.Context, userID string, hashes []string) error
+	// UseRecoveryCode deletes userID's recovery code with the given hash,
+	// returning ErrNotFound if there is none.
+	UseRecoveryCode(ctx context.Context, userID, hash string) error
+	// CountRecoveryCodes returns how many recovery codes userID has left.
+	CountRecoveryCodes(ctx context.Context, userID string) (int, error)
+}
+
+// PersonalDataStore finds and erases what is stored about a user, to
+// export their data and delete their account.
+type PersonalDataStore interface {
+	// ListUserComments returns the comments the user wrote, oldest first.
+	ListUserComments(ctx context.Context, userID string) ([]model.Comment, error)
+	// ListUserAttachments returns the files the user uploaded, oldest
+	// first.
+	ListUserAttachments(ctx context.Context, userID string) ([]model.Attachment, error)
+	// EraseUser deletes, all at once, the tags, views, templates,
+	// reminders, notifications and notification settings, webhooks,
+	// memberships, invitations to the stored email, sign-in methods,
+	// sessions, API keys, feature flag settings and other belongings of
+	// the user u.ID, and saves u, stripped of personal data, in place of
+	// the stored user. Tasks, comments, attachments, time entries and
+	// activity are the caller's to delete or keep.
+	EraseUser(ctx context.Context, u model.User) error
+}
+
+// UserFilter narrows ListUsers. Query matches part of the email or username,
+// regardless of case; an empty one matches every user.
+type UserFilter struct {
+	Query string
+	// DeletionRequestedBefore, if set, keeps the users who asked for their
+	// account to be deleted before then.
+	DeletionRequestedBefore *time.Time
+	Limit                   int
+	Offset                  int
+}
+
+// SessionStore persists the sessions users are signed in with. Sessions
+// are deleted when they are revoked or expire.
+type SessionStore interface {
+	// CreateSession assigns an ID to s and stores it.
+	CreateSession(ctx context.Context, s *model.AuthSession) error
+	GetSession(ctx context.Context, id string) (model.AuthSession, error)
+	// GetSessionByToken returns the session whose current or previous
+	// refresh token has the given hash.
+	GetSessionByToken(ctx context.Context, tokenHash string) (model.AuthSession, error)
+	// ListSessions returns userID's sessions, most recently used first.
+	ListSessions(ctx context.Context, userID string) ([]model.AuthSession, error)
+	// UpdateSession saves s if its stored refresh token still has the hash
+	// tokenHash, and returns ErrNotFound otherwise, so that of two
+	// refreshes with the same token only one succeeds.
+	UpdateSession(ctx context.Context, s model.AuthSession, tokenHash string) error
+	// DeleteSession deletes userID's session id.
+	DeleteSession(ctx context.Context, userID, id string) error
+	// DeleteSessions deletes userID's sessions other than keepID, which may
+	// be empty, and returns how many there were.
+	DeleteSessions(ctx context.Context, userID, keepID string) (int, error)
+	// PurgeSessions deletes the sessions last used before the given time
+	// and returns how many there were.
+	PurgeSessions(ctx context.Context, before time.Time) (int, error)
+	// PurgeableSessions counts the sessions PurgeSessions would delete.
+	PurgeableSessions(ctx context.Context, before time.Time) (int, error)
+}
+
+// APIKeyStore persists the API keys users create. Keys are looked up by the
+// hash of the secret, which is all that is stored of it.
+type APIKeyStore interface {
+	// ListAPIKeys returns the owner's keys in the organization, oldest
+	// first.
+	ListAPIKeys(ctx context.Context, orgID, ownerID string) ([]model.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (model.APIKey, error)
+	// CreateAPIKey assigns an ID to k and stores it.
+	CreateAPIKey(ctx context.Context, k *model.APIKey) error
+	// TouchAPIKey records that the key was used at the given time.
+	TouchAPIKey(ctx context.Context, id string, at time.Time) error
+	// DeleteAPIKey deletes ownerID's key id.
+	DeleteAPIKey(ctx context.Context, ownerID, id string) error
+}
+
+// JobFilter narrows ListJobs. Empty fields match every job.
+type JobFilter struct {
+	Status model.JobStatus
+	Kind   string
+	Limit  int
+}
+
+// JobStore persists the background job queue.
+type JobStore interface {
+	// CreateJob stores j, assigning an ID unless it has one. A job with
+	// the same ID yields ErrConflict, which makes such jobs run once.
+	CreateJob(ctx context.Context, j *model.Job) error
+	GetJob(ctx context.Context, id string) (model.Job, error)
+	// ListJobs returns up to f.Limit jobs, newest first.
+	ListJobs(ctx context.Context, f JobFilter) ([]model.Job, error)
+	// ClaimJobs marks up to limit jobs running until now+lease and returns
+	// them, oldest due first: pending jobs due at or before now, and running
+	// jobs whose lease has run out. A job is only ever claimed by one
+	// caller at a time.
+	ClaimJobs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]model.Job, error)
+	// UpdateJob saves the status, attempts, error and times of j.
+	UpdateJob(ctx context.Context, j *model.Job) error
+	DeleteJob(ctx context.Context, id string) error
+	// PurgeJobs deletes succeeded and dead jobs completed before the given
+	// time and returns how many there were.
+	PurgeJobs(ctx context.Context, before time.Time) (int, error)
+	// PurgeableJobs counts the jobs PurgeJobs would delete.
+	PurgeableJobs(ctx context.Context, before time.Time) (int, error)
+}
+
+// OutboxStore keeps the events of changes until they are delivered. Events
+// are appended in the transaction of the change, so they are stored if and
+// only if it commits.
+type OutboxStore interface {
+	// AppendOutbox stores entries, assigning their IDs.
+	AppendOutbox(ctx context.Context, entries []model.OutboxEntry) error
+	// ClaimOutbox locks up to limit entries until now+lease and returns
+	// them, oldest first: those not locked, and those whose lock has run
+	// out. An entry is only ever claimed by one caller at a time.
+	ClaimOutbox(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]model.OutboxEntry, error)
+	// DeleteOutbox deletes the entries with the given IDs, once they have
+	// been delivered. IDs that are already gone are skipped.
+	DeleteOutbox(ctx context.Context, ids []string) error
+}
+
+// NewID returns a random 128-bit hex identifier.
+func NewID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic("storage: reading random bytes: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
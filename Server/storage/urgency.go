@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"time"
+
+	"starttech-server/model"
+)
+
+// urgencyWeight adds weight to a task whose due date or creation time falls
+// within span of now.
+type urgencyWeight struct {
+	span   time.Duration
+	weight int
+}
+
+// The terms of a task's urgency. Each step of priority counts
+// urgencyPriorityWeight; the first matching urgencyDue band counts for the
+// due date, and the first matching urgencyAge band for the task's age.
+var (
+	urgencyPriorityWeight = 2
+	urgencyDue            = []urgencyWeight{
+		{0, 6}, // overdue
+		{24 * time.Hour, 4},
+		{7 * 24 * time.Hour, 2},
+	}
+	urgencyAge = []urgencyWeight{
+		{30 * 24 * time.Hour, 2},
+		{7 * 24 * time.Hour, 1},
+	}
+)
+
+// urgency scores how pressing t is at now, combining its priority, how soon
+// it is due and how long ago it was created. Higher is more urgent.
+func urgency(t model.Task, now time.Time) int {
+	score := urgencyPriorityWeight * max(t.Priority.Rank(), 0)
+	if t.DueDate != nil {
+		for _, w := range urgencyDue {
+			if t.DueDate.Before(now.Add(w.span)) {
+				score += w.weight
+				break
+			}
+		}
+	}
+	for _, w := range urgencyAge {
+		if t.CreatedAt.Before(now.Add(-w.span)) {
+			score += w.weight
+			break
+		}
+	}
+	return score
+}
@@ -0,0 +1,46 @@
+package thumb
+
+import (
+	"context"
+
+	"starttech-server/jobs"
+	"starttech-server/model"
+)
+
+// KindMake is the kind of the jobs that make the thumbnails of image
+// attachments.
+const KindMake = "attachment.thumbnails"
+
+// Jobs makes thumbnails on the job queue, so that neither uploads nor the
+// gallery wait for them.
+type Jobs struct {
+	jobs *jobs.Queue
+}
+
+// NewJobs returns Jobs that queue on queue.
+func NewJobs(queue *jobs.Queue) *Jobs {
+	return &Jobs{jobs: queue}
+}
+
+// makeJob is the payload of KindMake jobs.
+type makeJob struct {
+	AttachmentID string `json:"attachment_id"`
+}
+
+// Register makes the job queue make thumbnails with run, which makes those
+// of the attachment with the given ID; see service.Attachments.MakeThumbnails.
+func (t *Jobs) Register(run func(ctx context.Context, attachmentID string) error) {
+	t.jobs.Handle(KindMake, 0, func(ctx context.Context, j model.Job) error {
+		var mj makeJob
+		if err := jobs.Decode(j, &mj); err != nil {
+			return err
+		}
+		return run(ctx, mj.AttachmentID)
+	})
+}
+
+// Enqueue queues making the thumbnails of the attachment with the given
+// ID, unless that is queued already.
+func (t *Jobs) Enqueue(ctx context.Context, attachmentID string) error {
+	return t.jobs.EnqueueOnce(ctx, "thumbnails-"+attachmentID, KindMake, makeJob{attachmentID})
+}
@@ -0,0 +1,109 @@
+package thumb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+)
+
+// orientation returns the EXIF orientation of the JPEG image data, from 1
+// (upright) to 8, or 1 if it has none. Cameras store photos as the sensor
+// saw them and record there how to turn them.
+func orientation(data []byte) int {
+	// Walk the segments before the image data, looking for the APP1
+	// segment holding EXIF.
+	for i := 2; i+4 <= len(data) && data[i] == 0xff; {
+		marker := data[i+1]
+		if marker == 0xda {
+			break
+		}
+		n := int(binary.BigEndian.Uint16(data[i+2:]))
+		if n < 2 || i+2+n > len(data) {
+			break
+		}
+		seg := data[i+4 : i+2+n]
+		if marker == 0xe1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			return tiffOrientation(seg[6:])
+		}
+		i += 2 + n
+	}
+	return 1
+}
+
+// tiffOrientation reads the orientation tag from the first IFD of the TIFF
+// structure EXIF data is kept in.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for e := 0; e < entries; e++ {
+		at := ifd + 2 + 12*e
+		if at+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[at:]) == 0x0112 {
+			if o := int(order.Uint16(tiff[at+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			break
+		}
+	}
+	return 1
+}
+
+// swaps reports whether turning an image by the orientation o swaps its
+// width and height.
+func swaps(o int) bool {
+	return o >= 5
+}
+
+// orient turns src upright by the EXIF orientation o.
+func orient(src *image.RGBA, o int) *image.RGBA {
+	if o <= 1 || o > 8 {
+		return src
+	}
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	w, h := sw, sh
+	if swaps(o) {
+		w, h = sh, sw
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var sx, sy int
+			switch o {
+			case 2: // mirror
+				sx, sy = sw-1-x, y
+			case 3: // turn half way round
+				sx, sy = sw-1-x, sh-1-y
+			case 4: // flip
+				sx, sy = x, sh-1-y
+			case 5: // mirror along the main diagonal
+				sx, sy = y, x
+			case 6: // turn a quarter clockwise
+				sx, sy = y, sh-1-x
+			case 7: // mirror along the other diagonal
+				sx, sy = sw-1-y, sh-1-x
+			case 8: // turn a quarter anticlockwise
+				sx, sy = sw-1-y, x
+			}
+			i := src.PixOffset(src.Bounds().Min.X+sx, src.Bounds().Min.Y+sy)
+			copy(dst.Pix[dst.PixOffset(x, y):], src.Pix[i:i+4])
+		}
+	}
+	return dst
+}
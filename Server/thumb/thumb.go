@@ -0,0 +1,165 @@
+// Package thumb makes the small images galleries show in place of image
+// attachments. Thumbnails come in a few fixed Widths, so that each can be
+// made once and kept next to the file, and are always re-encoded, so that
+// what is served is never the uploaded bytes.
+package thumb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"mime"
+)
+
+// Widths are the widths thumbnails are made in, in pixels, smallest first.
+var Widths = []int{64, 128, 256, 512}
+
+// MaxPixels bounds the images thumbnails are made of, since they are
+// decoded whole: a small file may hold a huge picture.
+const MaxPixels = 40_000_000
+
+// ErrTooLarge is returned by Make for images of more than MaxPixels.
+var ErrTooLarge = errors.New("thumb: the image is too large")
+
+// Supported reports whether thumbnails can be made of files of the media
+// type ct.
+func Supported(ct string) bool {
+	switch mediaType(ct) {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
+
+// ContentType returns the media type of the thumbnails of files of type ct:
+// photos stay JPEG, and the rest become PNG, which keeps transparency.
+func ContentType(ct string) string {
+	if mediaType(ct) == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+func mediaType(ct string) string {
+	mt, _, _ := mime.ParseMediaType(ct)
+	return mt
+}
+
+// Fit returns the width of the thumbnail to serve for a request of w
+// pixels: the smallest at least as wide, or the widest.
+func Fit(w int) int {
+	for _, width := range Widths {
+		if width >= w {
+			return width
+		}
+	}
+	return Widths[len(Widths)-1]
+}
+
+// Make decodes data, an image of the media type ct, and returns its
+// thumbnails encoded as ContentType(ct), by width. Images narrower than a
+// width are not enlarged, and JPEG photos are turned upright as their EXIF
+// orientation says.
+func Make(data []byte, ct string) (map[int][]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("thumb: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, errors.New("thumb: the image is empty")
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("thumb: %w", err)
+	}
+	src, ok := img.(*image.RGBA)
+	if !ok {
+		src = image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+		draw.Draw(src, src.Bounds(), img, img.Bounds().Min, draw.Src)
+	}
+	o := 1
+	if mediaType(ct) == "image/jpeg" {
+		o = orientation(data)
+	}
+
+	thumbs := make(map[int][]byte, len(Widths))
+	// Each thumbnail is scaled from the next larger one, which is much
+	// cheaper than going back to the image every time.
+	for i := len(Widths) - 1; i >= 0; i-- {
+		src = scale(src, Widths[i], o)
+		var buf bytes.Buffer
+		if err := encode(&buf, orient(src, o), ct); err != nil {
+			return nil, fmt.Errorf("thumb: %w", err)
+		}
+		thumbs[Widths[i]] = buf.Bytes()
+	}
+	return thumbs, nil
+}
+
+func encode(buf *bytes.Buffer, img image.Image, ct string) error {
+	if ContentType(ct) == "image/jpeg" {
+		return jpeg.Encode(buf, img, &jpeg.Options{Quality: 80})
+	}
+	return png.Encode(buf, img)
+}
+
+// scale shrinks src, as stored, so that it is width pixels wide once
+// turned upright by the orientation o, keeping its aspect ratio. Images
+// that are no wider are returned as they are.
+func scale(src *image.RGBA, width, o int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	uw, uh := sw, sh
+	if swaps(o) {
+		uw, uh = sh, sw
+	}
+	if uw <= width {
+		return src
+	}
+	height := max(1, (uh*width+uw/2)/uw)
+	if swaps(o) {
+		return shrink(src, height, width)
+	}
+	return shrink(src, width, height)
+}
+
+// shrink scales src down to w by h pixels, averaging the pixels each one
+// covers. RGBA pixels are premultiplied, so transparent ones do not darken
+// their neighbours.
+func shrink(src *image.RGBA, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	for y := 0; y < h; y++ {
+		y0 := y * sh / h
+		y1 := max((y+1)*sh/h, y0+1)
+		for x := 0; x < w; x++ {
+			x0 := x * sw / w
+			x1 := max((x+1)*sw/w, x0+1)
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(b.Min.X+x0, b.Min.Y+sy)
+				for sx := x0; sx < x1; sx++ {
+					sum[0] += int(src.Pix[i])
+					sum[1] += int(src.Pix[i+1])
+					sum[2] += int(src.Pix[i+2])
+					sum[3] += int(src.Pix[i+3])
+					i += 4
+				}
+			}
+			n := (x1 - x0) * (y1 - y0)
+			j := dst.PixOffset(x, y)
+			for c := range sum {
+				dst.Pix[j+c] = uint8((sum[c] + n/2) / n)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,262 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"starttech-server/metrics"
+)
+
+const (
+	// Spans are sent in batches of up to batchSize, at least every
+	// flushInterval, and at most queueSize wait to be sent.
+	batchSize     = 512
+	flushInterval = 5 * time.Second
+	queueSize     = 4096
+	// exportTimeout bounds sending one batch.
+	exportTimeout = 10 * time.Second
+)
+
+var spansDropped = metrics.NewCounterVec("tracing_spans_dropped_total",
+	"Spans that were not exported, because the queue was full (queue_full) or the collector failed (export_failed).", "reason")
+
+// Exporter sends ended spans in batches to an OTLP/HTTP collector, as
+// JSON.
+type Exporter struct {
+	// URL is the collector's traces endpoint, such as
+	// http://localhost:4318/v1/traces.
+	URL string
+	// Headers are sent with every export, such as an API key.
+	Headers http.Header
+	// ServiceName is the service.name of the exported spans.
+	ServiceName string
+	// SampleRatio is the share of the traces started here that are
+	// recorded. Traces continued from elsewhere follow the caller's
+	// decision.
+	SampleRatio float64
+	// Client sends the exports.
+	Client *http.Client
+
+	spans chan *Span
+}
+
+// NewExporter returns an Exporter for the collector at endpoint, a base
+// URL to which /v1/traces is added. Call Run to start sending.
+func NewExporter(endpoint, serviceName string, sampleRatio float64) (*Exporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("tracing: invalid collector endpoint %q", endpoint)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/v1/traces"
+	return &Exporter{
+		URL:         u.String(),
+		Headers:     http.Header{},
+		ServiceName: serviceName,
+		SampleRatio: sampleRatio,
+		Client:      &http.Client{Timeout: exportTimeout},
+		spans:       make(chan *Span, queueSize),
+	}, nil
+}
+
+// ParseHeaders parses headers given as key=value, with URL-encoded values,
+// as in OTEL_EXPORTER_OTLP_HEADERS.
+func ParseHeaders(list []string) (http.Header, error) {
+	h := http.Header{}
+	for _, kv := range list {
+		k, v, ok := strings.Cut(kv, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("tracing: header %q is not key=value", kv)
+		}
+		value, err := url.QueryUnescape(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("tracing: header %q: %w", k, err)
+		}
+		h.Add(k, value)
+	}
+	return h, nil
+}
+
+func (e *Exporter) enqueue(s *Span) {
+	select {
+	case e.spans <- s:
+	default:
+		spansDropped.With("queue_full").Inc()
+	}
+}
+
+// Run sends spans until ctx is done, and then the ones still queued.
+func (e *Exporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	var batch []*Span
+	flush := func(ctx context.Context) {
+		if len(batch) > 0 {
+			e.export(ctx, batch)
+			batch = nil
+		}
+	}
+	for {
+		select {
+		case s := <-e.spans:
+			if batch = append(batch, s); len(batch) >= batchSize {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		case <-ctx.Done():
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), exportTimeout)
+			defer cancel()
+			for {
+				select {
+				case s := <-e.spans:
+					if batch = append(batch, s); len(batch) >= batchSize {
+						flush(ctx)
+					}
+				default:
+					flush(ctx)
+					return
+				}
+			}
+		}
+	}
+}
+
+// export sends batch, dropping it if the collector does not take it.
+func (e *Exporter) export(ctx context.Context, batch []*Span) {
+	body, err := json.Marshal(e.request(batch))
+	if err == nil {
+		err = e.post(ctx, body)
+	}
+	if err != nil {
+		spansDropped.With("export_failed").Add(float64(len(batch)))
+		slog.Warn("tracing: exporting spans", "spans", len(batch), "err", err)
+	}
+}
+
+func (e *Exporter) post(ctx context.Context, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, exportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range e.Headers {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector answered %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// The OTLP/HTTP JSON encoding of an export request, with only the fields
+// the server fills in.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID      string         `json:"traceId"`
+		SpanID       string         `json:"spanId"`
+		ParentSpanID string         `json:"parentSpanId,omitempty"`
+		Name         string         `json:"name"`
+		Kind         Kind           `json:"kind"`
+		Start        string         `json:"startTimeUnixNano"`
+		End          string         `json:"endTimeUnixNano"`
+		Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+		Status       *otlpStatus    `json:"status,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	}
+)
+
+// statusError is the OTLP status code of a failed span.
+const statusError = 2
+
+func (e *Exporter) request(batch []*Span) otlpRequest {
+	spans := make([]otlpSpan, len(batch))
+	for i, s := range batch {
+		s.mu.Lock()
+		spans[i] = otlpSpan{
+			TraceID:    s.sc.TraceID.String(),
+			SpanID:     s.sc.SpanID.String(),
+			Name:       s.name,
+			Kind:       s.kind,
+			Start:      strconv.FormatInt(s.start.UnixNano(), 10),
+			End:        strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes: keyValues(s.attrs),
+		}
+		if s.parent != (SpanID{}) {
+			spans[i].ParentSpanID = s.parent.String()
+		}
+		if s.err != "" {
+			spans[i].Status = &otlpStatus{Code: statusError, Message: s.err}
+		}
+		s.mu.Unlock()
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: keyValues([]slog.Attr{slog.String("service.name", e.ServiceName)})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "starttech-server"}, Spans: spans}},
+	}}}
+}
+
+// keyValues encodes attrs as OTLP attributes. 64-bit integers are strings
+// in OTLP JSON.
+func keyValues(attrs []slog.Attr) []otlpKeyValue {
+	out := make([]otlpKeyValue, len(attrs))
+	for i, a := range attrs {
+		v := a.Value.Resolve()
+		var value map[string]any
+		switch v.Kind() {
+		case slog.KindBool:
+			value = map[string]any{"boolValue": v.Bool()}
+		case slog.KindInt64:
+			value = map[string]any{"intValue": strconv.FormatInt(v.Int64(), 10)}
+		case slog.KindUint64:
+			value = map[string]any{"intValue": strconv.FormatUint(v.Uint64(), 10)}
+		case slog.KindFloat64:
+			value = map[string]any{"doubleValue": v.Float64()}
+		default:
+			value = map[string]any{"stringValue": v.String()}
+		}
+		out[i] = otlpKeyValue{Key: a.Key, Value: value}
+	}
+	return out
+}
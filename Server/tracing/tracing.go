@@ -0,0 +1,250 @@
+// Package tracing records OpenTelemetry spans of the work the server does,
+// such as requests, queries, jobs and webhook deliveries, and exports them
+// to an OTLP/HTTP collector. Trace context crosses process boundaries in
+// W3C traceparent headers.
+//
+// Tracing is off until Enable is called; until then Start returns a nil
+// *Span, whose methods do nothing.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TraceIDHeader carries the ID of the trace a request belongs to in the
+// response, so clients can quote it when reporting a problem.
+const TraceIDHeader = "X-Trace-ID"
+
+// TraceID identifies a trace, every span of which shares it.
+type TraceID [16]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+// SpanID identifies a span within its trace.
+type SpanID [8]byte
+
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// SpanContext is what a span passes on to its children, in this process
+// or, as a traceparent, in another.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	// Sampled is whether the trace is recorded. Spans of a trace that is
+	// not still get IDs, which log lines carry.
+	Sampled bool
+}
+
+// Valid reports whether sc identifies a span.
+func (sc SpanContext) Valid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// TraceParent formats sc as a W3C traceparent header value.
+func (sc SpanContext) TraceParent() string {
+	if !sc.Valid() {
+		return ""
+	}
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceParent parses a W3C traceparent header value, reporting
+// whether it was well-formed.
+func ParseTraceParent(s string) (SpanContext, bool) {
+	var sc SpanContext
+	// version-traceid-spanid-flags; later versions may append fields.
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' || (len(s) > 55 && s[55] != '-') {
+		return sc, false
+	}
+	version, err := hex.DecodeString(s[:2])
+	if err != nil || version[0] == 0xff || (version[0] == 0 && len(s) != 55) {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(s[3:35])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(s[36:52])); err != nil {
+		return sc, false
+	}
+	flags, err := hex.DecodeString(s[53:55])
+	if err != nil {
+		return sc, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, sc.Valid()
+}
+
+type spanKey struct{}
+
+// FromContext returns the context of the span ctx is in, which is not
+// Valid if there is none.
+func FromContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(spanKey{}).(SpanContext)
+	return sc
+}
+
+// WithRemoteParent returns ctx with the span of traceparent, started in
+// another process or in another goroutine that has since moved on, such as
+// the one that queued a job, as the parent of the spans started in it. An empty or malformed traceparent leaves ctx as it is.
+func WithRemoteParent(ctx context.Context, traceparent string) context.Context {
+	sc, ok := ParseTraceParent(traceparent)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, sc)
+}
+
+// Kind says what part a span plays in a trace.
+type Kind int
+
+// The kinds, numbered as in OTLP.
+const (
+	KindInternal Kind = iota + 1
+	KindServer
+	KindClient
+	KindProducer
+	KindConsumer
+)
+
+// Span is one timed operation. Its methods are safe for concurrent use
+// and do nothing on a nil *Span.
+type Span struct {
+	exporter *Exporter
+	sc       SpanContext
+	parent   SpanID
+	kind     Kind
+	start    time.Time
+
+	mu    sync.Mutex
+	name  string
+	end   time.Time
+	attrs []slog.Attr
+	err   string
+	ended bool
+}
+
+var active atomic.Pointer[Exporter]
+
+// Enable starts recording spans and handing them to e.
+func Enable(e *Exporter) {
+	active.Store(e)
+}
+
+// Start begins a span named name as a child of the span ctx is in, or as
+// the root of a new trace, and returns a context the span is in. It
+// returns ctx and nil when tracing is off.
+func Start(ctx context.Context, name string, kind Kind, attrs ...slog.Attr) (context.Context, *Span) {
+	e := active.Load()
+	if e == nil {
+		return ctx, nil
+	}
+	parent := FromContext(ctx)
+	s := &Span{exporter: e, name: name, kind: kind, start: time.Now(), attrs: attrs}
+	rand.Read(s.sc.SpanID[:])
+	if parent.Valid() {
+		s.sc.TraceID, s.sc.Sampled, s.parent = parent.TraceID, parent.Sampled, parent.SpanID
+	} else {
+		rand.Read(s.sc.TraceID[:])
+		s.sc.Sampled = e.sample(s.sc.TraceID)
+	}
+	return context.WithValue(ctx, spanKey{}, s.sc), s
+}
+
+// StartChild is Start for operations only worth a span as part of a
+// larger one, such as queries: outside a trace it returns ctx and nil.
+func StartChild(ctx context.Context, name string, kind Kind, attrs ...slog.Attr) (context.Context, *Span) {
+	if !FromContext(ctx).Valid() {
+		return ctx, nil
+	}
+	return Start(ctx, name, kind, attrs...)
+}
+
+// Context returns the span's SpanContext.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// Rename names the span name, as once a request has been routed.
+func (s *Span) Rename(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.name = name
+}
+
+// SetAttributes adds attrs to the span, replacing any of the same keys.
+func (s *Span) SetAttributes(attrs ...slog.Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range attrs {
+		replaced := false
+		for i := range s.attrs {
+			if s.attrs[i].Key == a.Key {
+				s.attrs[i], replaced = a, true
+				break
+			}
+		}
+		if !replaced {
+			s.attrs = append(s.attrs, a)
+		}
+	}
+}
+
+// SetError marks the span as failed with err. A nil err does nothing.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err.Error()
+}
+
+// End ends the span and, if its trace is sampled, queues it for export.
+// Calls after the first do nothing.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended, s.end = true, time.Now()
+	s.mu.Unlock()
+	if s.sc.Sampled {
+		s.exporter.enqueue(s)
+	}
+}
+
+// sample decides whether a new trace is recorded, from the low bits of
+// its random ID, so every process sampling at the same ratio agrees.
+func (e *Exporter) sample(id TraceID) bool {
+	switch {
+	case e.SampleRatio >= 1:
+		return true
+	case e.SampleRatio <= 0:
+		return false
+	}
+	return binary.BigEndian.Uint64(id[8:])>>1 < uint64(e.SampleRatio*(1<<63))
+}
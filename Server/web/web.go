@@ -0,0 +1,103 @@
+// Package web serves the frontend from the binary. The Vite build of the
+// client is copied into dist before the server is built:
+//
+//	cd Client && VITE_API_BASE_URL=/api/v1 npm run build
+//	cp -r dist/. ../Server/web/dist/
+//
+// Without a build, dist holds only a placeholder and the server serves the
+// API alone.
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+//go:embed all:dist
+var dist embed.FS
+
+// Frontend serves the files of a single-page app, answering the paths of
+// its client-side routes with index.html.
+type Frontend struct {
+	files fs.FS
+	index []byte
+}
+
+// Embedded returns the frontend built into the binary, or nil if it was
+// built without one.
+func Embedded() *Frontend {
+	files, err := fs.Sub(dist, "dist")
+	if err != nil {
+		return nil
+	}
+	index, err := fs.ReadFile(files, "index.html")
+	if err != nil {
+		return nil
+	}
+	return &Frontend{files: files, index: index}
+}
+
+// Around serves the app in front of api, which serves the routes of
+// routes. Only GET and HEAD requests that routes has no route for reach the
+// app, and only for a file of the build or, from a browser, a page. Any
+// other request, such as a client asking for an unknown API path, goes to
+// api for its JSON 404.
+func (f *Frontend) Around(routes *http.ServeMux, api http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			api.ServeHTTP(w, r)
+			return
+		}
+		if _, pattern := routes.Handler(r); pattern != "" {
+			api.ServeHTTP(w, r)
+			return
+		}
+		if name, ok := f.file(r.URL.Path); ok {
+			f.serveFile(w, r, name)
+			return
+		}
+		if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+			api.ServeHTTP(w, r)
+			return
+		}
+		f.serveIndex(w)
+	})
+}
+
+// file returns the name in the build of the file at urlPath, if there is
+// one. The root counts as index.html.
+func (f *Frontend) file(urlPath string) (string, bool) {
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" || name == "index.html" {
+		return "index.html", true
+	}
+	st, err := fs.Stat(f.files, name)
+	if err != nil || st.IsDir() {
+		return "", false
+	}
+	return name, true
+}
+
+func (f *Frontend) serveFile(w http.ResponseWriter, r *http.Request, name string) {
+	if name == "index.html" {
+		f.serveIndex(w)
+		return
+	}
+	// Vite puts a hash of their content in the names of the files under
+	// assets, so they never change.
+	if strings.HasPrefix(name, "assets/") {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	}
+	http.ServeFileFS(w, r, f.files, name)
+}
+
+// serveIndex answers with index.html, which browsers must check for a new
+// version of on every load.
+func (f *Frontend) serveIndex(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(f.index)
+}
@@ -0,0 +1,360 @@
+// Package webhooks delivers events to the URLs users register. Every event
+// becomes one persisted delivery per subscribed webhook, sent by a job on
+// the background queue; deliveries are POSTed with an HMAC signature and
+// retried with exponential backoff until they succeed or run out of
+// attempts.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"starttech-server/events"
+	"starttech-server/jobs"
+	"starttech-server/metrics"
+	"starttech-server/model"
+	"starttech-server/storage"
+	"starttech-server/tracing"
+)
+
+// Request headers sent with every delivery.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const queueSize = 256
+
+// KindDeliver is the kind of the jobs that send deliveries.
+const KindDeliver = "webhook.deliver"
+
+var deliveriesTotal = metrics.NewCounterVec("webhook_deliveries_total",
+	"Webhook delivery attempts, by result: succeeded, retrying or failed.", "result")
+
+// Dispatcher implements events.Publisher. Publish records nothing itself;
+// Run turns queued events into deliveries and queues a job for each, which
+// Deliver sends. Record turns an event into deliveries at once, for the
+// outbox relay.
+type Dispatcher struct {
+	store storage.WebhookStore
+	jobs  *jobs.Queue
+	queue chan events.Event
+
+	// Client sends the requests. The default times out after 10 seconds,
+	// does not follow redirects or use a proxy, and only connects to
+	// public addresses unless AllowPrivate is set.
+	Client *http.Client
+	// MaxAttempts before a delivery is marked failed; it defaults to 8.
+	MaxAttempts int
+	// AllowPrivate lets the default Client connect to loopback, private
+	// and link-local addresses, for development.
+	AllowPrivate bool
+}
+
+// NewDispatcher returns a Dispatcher that sends deliveries on queue; call
+// Register to handle them there, and Run to start recording them.
+func NewDispatcher(store storage.WebhookStore, queue *jobs.Queue) *Dispatcher {
+	d := &Dispatcher{
+		store:       store,
+		jobs:        queue,
+		queue:       make(chan events.Event, queueSize),
+		MaxAttempts: 8,
+	}
+	// The address is checked once resolved, as the connection is made, so
+	// that a name cannot resolve to a public address when the webhook is
+	// saved and a private one when it is delivered to.
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: d.checkAddress}
+	d.Client = &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return d
+}
+
+// errPrivateAddress is returned for a delivery to an address that is not
+// public.
+var errPrivateAddress = errors.New("webhooks: address is not public")
+
+// checkAddress refuses a connection to a loopback, private or link-local
+// address unless d.AllowPrivate is set.
+func (d *Dispatcher) checkAddress(network, address string, _ syscall.RawConn) error {
+	if d.AllowPrivate {
+		return nil
+	}
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil || !model.PublicAddr(ap.Addr()) {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+// Register makes the job queue send deliveries with d.
+func (d *Dispatcher) Register() {
+	d.jobs.Handle(KindDeliver, d.MaxAttempts, d.Deliver)
+}
+
+// Publish queues e for its recipients' webhooks. Only the types in
+// events.All are offered to webhooks.
+func (d *Dispatcher) Publish(e events.Event) {
+	if !slices.Contains(events.All, e.Type) {
+		return
+	}
+	select {
+	case d.queue <- e:
+	default:
+		slog.Warn("webhooks: dropping event, queue is full", "type", e.Type)
+	}
+}
+
+// Run records deliveries of published events until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-d.queue:
+			if err := d.enqueue(tracing.WithRemoteParent(ctx, e.TraceParent), e); err != nil {
+				slog.Error("webhooks: recording deliveries", "type", e.Type, "err", err)
+			}
+		}
+	}
+}
+
+// Record stores the deliveries of e at once, rather than on Run, for the
+// outbox to know they are kept. A failure leaves the deliveries recorded
+// before it, so recording e again may deliver it twice.
+func (d *Dispatcher) Record(ctx context.Context, e events.Event) error {
+	if !slices.Contains(events.All, e.Type) {
+		return nil
+	}
+	return d.enqueue(ctx, e)
+}
+
+// enqueue stores a pending delivery of e for every webhook its recipients
+// registered in e's organization that subscribes to it. It carries on past
+// failures, and returns them together.
+func (d *Dispatcher) enqueue(ctx context.Context, e events.Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+	var errs []error
+	now := time.Now().UTC()
+	for _, ownerID := range e.Recipients {
+		hooks, err := d.store.ListWebhooks(ctx, e.OrgID, ownerID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("listing webhooks of %s: %w", ownerID, err))
+			continue
+		}
+		for _, h := range hooks {
+			if !h.Subscribes(string(e.Type)) {
+				continue
+			}
+			dl := model.Delivery{
+				WebhookID:     h.ID,
+				Event:         string(e.Type),
+				Payload:       payload,
+				Status:        model.DeliveryPending,
+				NextAttemptAt: &now,
+				CreatedAt:     now,
+			}
+			if err := d.store.CreateDelivery(ctx, &dl); err != nil {
+				errs = append(errs, fmt.Errorf("recording delivery to webhook %s: %w", h.ID, err))
+				continue
+			}
+			// The job's ID matches the one migrated deliveries were given.
+			if err := d.jobs.EnqueueOnce(ctx, "webhook-"+dl.ID, KindDeliver, deliverJob{dl.ID}); err != nil {
+				errs = append(errs, fmt.Errorf("queueing delivery %s: %w", dl.ID, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deliverJob is the payload of KindDeliver jobs.
+type deliverJob struct {
+	DeliveryID string `json:"delivery_id"`
+}
+
+// Redeliver queues another job to send the delivery with the given ID, as
+// after it was replayed.
+func (d *Dispatcher) Redeliver(ctx context.Context, deliveryID string) error {
+	return d.jobs.Enqueue(ctx, KindDeliver, deliverJob{deliveryID})
+}
+
+// errDisabled fails the deliveries of a webhook that has been disabled.
+var errDisabled = errors.New("webhook is disabled")
+
+// Deliver is the handler of KindDeliver jobs. It sends the delivery once
+// and records the outcome, returning an error for the queue to retry it.
+func (d *Dispatcher) Deliver(ctx context.Context, j model.Job) error {
+	var p deliverJob
+	if err := jobs.Decode(j, &p); err != nil {
+		return err
+	}
+	dl, err := d.store.GetDelivery(ctx, p.DeliveryID)
+	if errors.Is(err, storage.ErrNotFound) {
+		// Deleted since, with its webhook.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("loading delivery: %w", err)
+	}
+	if dl.Status == model.DeliverySucceeded {
+		return nil
+	}
+	// A failed delivery is only attempted again if its dead job is retried.
+	dl.Status, dl.CompletedAt = model.DeliveryPending, nil
+	hook, err := d.store.GetWebhook(ctx, dl.WebhookID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("loading webhook: %w", err)
+	}
+
+	if hook.Active {
+		dl.ResponseStatus, err = d.post(ctx, hook, dl)
+	} else {
+		dl.ResponseStatus, err = 0, jobs.Permanent(errDisabled)
+	}
+	if err != nil && ctx.Err() != nil {
+		// Shutting down; the job is put back as it was.
+		return err
+	}
+	dl.Attempts++
+	now := time.Now().UTC()
+	if err != nil {
+		slog.WarnContext(ctx, "webhooks: delivery failed", "delivery_id", dl.ID, "webhook_id", hook.ID, "err", err)
+	}
+	switch {
+	case err == nil:
+		dl.Status, dl.LastError, dl.NextAttemptAt, dl.CompletedAt = model.DeliverySucceeded, "", nil, &now
+	case j.Attempts >= j.MaxAttempts || !hook.Active:
+		dl.Status, dl.LastError, dl.NextAttemptAt, dl.CompletedAt = model.DeliveryFailed, describe(err), nil, &now
+	default:
+		next := now.Add(jobs.Backoff(j.Attempts))
+		dl.LastError, dl.NextAttemptAt = describe(err), &next
+	}
+	result := string(dl.Status)
+	if dl.Status == model.DeliveryPending {
+		result = "retrying"
+	}
+	deliveriesTotal.With(result).Inc()
+
+	if uerr := d.store.UpdateDelivery(ctx, &dl); uerr != nil && !errors.Is(uerr, storage.ErrNotFound) {
+		slog.Error("webhooks: recording attempt", "delivery_id", dl.ID, "err", uerr)
+	}
+	return err
+}
+
+// errStatus is returned by post for a response status other than 2xx.
+type errStatus int
+
+func (e errStatus) Error() string {
+	return fmt.Sprintf("unexpected response status %d", int(e))
+}
+
+// describe says what went wrong with a delivery in terms that may be shown
+// to the webhook's owner. The error itself is only logged, since it can
+// tell which hosts and ports are open behind the server.
+func describe(err error) string {
+	var status errStatus
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &status):
+		return status.Error()
+	case errors.Is(err, errPrivateAddress):
+		return "the URL does not resolve to a public address"
+	case errors.As(err, &dnsErr):
+		return "the host could not be resolved"
+	case errors.Is(err, context.DeadlineExceeded) || os.IsTimeout(err):
+		return "the request timed out"
+	case errors.Is(err, errDisabled):
+		return errDisabled.Error()
+	}
+	return "the request could not be completed"
+}
+
+// post sends dl to hook and returns the response status. Anything but a 2xx
+// response is an error. The request carries the traceparent of its span,
+// so a receiver that traces can join the trace.
+func (d *Dispatcher) post(ctx context.Context, hook model.Webhook, dl model.Delivery) (status int, err error) {
+	ctx, span := tracing.Start(ctx, "webhook POST", tracing.KindClient,
+		slog.String("webhook.id", hook.ID),
+		slog.String("webhook.event", dl.Event),
+		slog.String("webhook.delivery_id", dl.ID),
+	)
+	defer func() {
+		if status != 0 {
+			span.SetAttributes(slog.Int("http.response.status_code", status))
+		}
+		span.SetError(err)
+		span.End()
+	}()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(dl.Payload))
+	if err != nil {
+		return 0, err
+	}
+	span.SetAttributes(slog.String("server.address", req.URL.Hostname()))
+	if tp := span.Context().TraceParent(); tp != "" {
+		req.Header.Set("traceparent", tp)
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "starttech-webhooks/1")
+	req.Header.Set(HeaderEvent, dl.Event)
+	req.Header.Set(HeaderDelivery, dl.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, ts, dl.Payload))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, errStatus(resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the X-Webhook-Signature value for a payload sent at unix time
+// ts: "sha256=" followed by the hex HMAC-SHA256 of "<ts>.<payload>" keyed
+// with the webhook's secret. Receivers should recompute it and reject
+// requests with stale timestamps.
+func Sign(secret string, ts int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -48,7 +48,7 @@ docker build \
     --build-arg BUILD_DATE=$BUILD_DATE \
     --build-arg ENVIRONMENT=$ENVIRONMENT \
     -f ./Server/Dockerfile \
-    ./Server
+    .
 
 echo " Image built successfully"
 